@@ -0,0 +1,190 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the batch tool, which runs several tool calls in one
+// MCP request.
+package providerv1
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// BatchTool is the name of the batch tool. Providers that serve it set
+// CapabilitiesResponse.Batch.
+const BatchTool = "batch"
+
+// MaxBatchCalls bounds the number of calls in one batch request.
+const MaxBatchCalls = 256
+
+// BatchRequest is the input for the batch tool.
+type BatchRequest struct {
+	// Calls are executed concurrently; their order is kept in the response.
+	Calls []BatchCall `json:"calls"`
+}
+
+// BatchCall is one tool call of a batch.
+type BatchCall struct {
+	// Tool is the name of a resource tool, e.g. "vm_create" or "key_delete".
+	Tool string `json:"tool"`
+	// Input is the JSON object input of the tool, e.g. a VMCreateRequest.
+	Input map[string]any `json:"input,omitempty"`
+}
+
+// BatchResponse is the resource of a successful batch OperationResult.
+type BatchResponse struct {
+	// Results holds one result per call, in request order.
+	Results []OperationResult `json:"results"`
+}
+
+// BatchHandler executes one call of a batch.
+type BatchHandler func(input map[string]any) *OperationResult
+
+// ResourceProvider is implemented by providers serving the standard key,
+// network and VM tools.
+type ResourceProvider interface {
+	KeyCreate(req *KeyCreateRequest) *OperationResult
+	KeyGet(name string) *OperationResult
+	KeyList(filter map[string]any) *OperationResult
+	KeyDelete(name string) *OperationResult
+	NetworkCreate(req *NetworkCreateRequest) *OperationResult
+	NetworkGet(name string) *OperationResult
+	NetworkList(filter map[string]any) *OperationResult
+	NetworkDelete(name string) *OperationResult
+	VMCreate(req *VMCreateRequest) *OperationResult
+	VMGet(name string) *OperationResult
+	VMList(filter map[string]any) *OperationResult
+	VMDelete(name string) *OperationResult
+}
+
+// NewBatchCall encodes input as the input of a batch call.
+func NewBatchCall(tool string, input any) (BatchCall, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return BatchCall{}, fmt.Errorf("failed to marshal input of %s: %w", tool, err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return BatchCall{}, fmt.Errorf("input of %s must be a JSON object: %w", tool, err)
+	}
+	return BatchCall{Tool: tool, Input: m}, nil
+}
+
+// BatchHandlers returns batch handlers for the standard tools of p. The
+// create and delete tools are only included when mutating is true, so that
+// read-only providers cannot be bypassed through a batch.
+func BatchHandlers(p ResourceProvider, mutating bool) map[string]BatchHandler {
+	handlers := map[string]BatchHandler{
+		"key_get":      getHandler(p.KeyGet),
+		"key_list":     listHandler(p.KeyList),
+		"network_get":  getHandler(p.NetworkGet),
+		"network_list": listHandler(p.NetworkList),
+		"vm_get":       getHandler(p.VMGet),
+		"vm_list":      listHandler(p.VMList),
+	}
+	if mutating {
+		handlers["key_create"] = createHandler(p.KeyCreate)
+		handlers["key_delete"] = deleteHandler(p.KeyDelete)
+		handlers["network_create"] = createHandler(p.NetworkCreate)
+		handlers["network_delete"] = deleteHandler(p.NetworkDelete)
+		handlers["vm_create"] = createHandler(p.VMCreate)
+		handlers["vm_delete"] = deleteHandler(p.VMDelete)
+	}
+	return handlers
+}
+
+// RunBatch executes the calls of req concurrently and returns their results
+// in request order. Calls to tools without a handler fail individually with
+// NOT_IMPLEMENTED.
+func RunBatch(req *BatchRequest, handlers map[string]BatchHandler) *OperationResult {
+	if len(req.Calls) > MaxBatchCalls {
+		return ErrorResult(NewInvalidSpecError(
+			fmt.Sprintf("batch has %d calls, at most %d are allowed", len(req.Calls), MaxBatchCalls)))
+	}
+
+	results := make([]OperationResult, len(req.Calls))
+	var wg sync.WaitGroup
+	for i, call := range req.Calls {
+		handler, ok := handlers[call.Tool]
+		if !ok {
+			results[i] = *ErrorResult(NewOperationError(ErrCodeNotImplemented,
+				fmt.Sprintf("tool %q is not available in a batch", call.Tool)))
+			continue
+		}
+		wg.Add(1)
+		go func(i int, input map[string]any) {
+			defer wg.Done()
+			results[i] = *handler(input)
+		}(i, call.Input)
+	}
+	wg.Wait()
+	return SuccessResult(&BatchResponse{Results: results})
+}
+
+// decodeInput decodes the input of a batch call into v.
+func decodeInput(input map[string]any, v any) *OperationResult {
+	if len(input) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(input)
+	if err == nil {
+		err = json.Unmarshal(data, v)
+	}
+	if err != nil {
+		return ErrorResult(NewInvalidSpecError("invalid input: " + err.Error()))
+	}
+	return nil
+}
+
+func createHandler[T any](fn func(*T) *OperationResult) BatchHandler {
+	return func(input map[string]any) *OperationResult {
+		var req T
+		if errResult := decodeInput(input, &req); errResult != nil {
+			return errResult
+		}
+		return fn(&req)
+	}
+}
+
+func getHandler(fn func(string) *OperationResult) BatchHandler {
+	return func(input map[string]any) *OperationResult {
+		var req GetRequest
+		if errResult := decodeInput(input, &req); errResult != nil {
+			return errResult
+		}
+		return fn(req.Name)
+	}
+}
+
+func listHandler(fn func(map[string]any) *OperationResult) BatchHandler {
+	return func(input map[string]any) *OperationResult {
+		var req ListRequest
+		if errResult := decodeInput(input, &req); errResult != nil {
+			return errResult
+		}
+		return fn(req.Filter)
+	}
+}
+
+func deleteHandler(fn func(string) *OperationResult) BatchHandler {
+	return func(input map[string]any) *OperationResult {
+		var req DeleteRequest
+		if errResult := decodeInput(input, &req); errResult != nil {
+			return errResult
+		}
+		return fn(req.Name)
+	}
+}
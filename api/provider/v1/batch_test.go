@@ -0,0 +1,144 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerv1
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// fakeResourceProvider echoes the tool and name of every call.
+type fakeResourceProvider struct{}
+
+func echo(tool, name string) *OperationResult {
+	if name == "" {
+		return ErrorResult(NewInvalidSpecError("name is required"))
+	}
+	return SuccessResult(map[string]any{"tool": tool, "name": name})
+}
+
+func (fakeResourceProvider) KeyCreate(req *KeyCreateRequest) *OperationResult {
+	return echo("key_create", req.Name)
+}
+func (fakeResourceProvider) KeyGet(name string) *OperationResult { return echo("key_get", name) }
+func (fakeResourceProvider) KeyList(map[string]any) *OperationResult {
+	return SuccessResult([]any{})
+}
+func (fakeResourceProvider) KeyDelete(name string) *OperationResult { return echo("key_delete", name) }
+func (fakeResourceProvider) NetworkCreate(req *NetworkCreateRequest) *OperationResult {
+	return echo("network_create", req.Name)
+}
+func (fakeResourceProvider) NetworkGet(name string) *OperationResult {
+	return echo("network_get", name)
+}
+func (fakeResourceProvider) NetworkList(map[string]any) *OperationResult {
+	return SuccessResult([]any{})
+}
+func (fakeResourceProvider) NetworkDelete(name string) *OperationResult {
+	return echo("network_delete", name)
+}
+func (fakeResourceProvider) VMCreate(req *VMCreateRequest) *OperationResult {
+	return echo("vm_create", req.Name)
+}
+func (fakeResourceProvider) VMGet(name string) *OperationResult { return echo("vm_get", name) }
+func (fakeResourceProvider) VMList(map[string]any) *OperationResult {
+	return SuccessResult([]any{})
+}
+func (fakeResourceProvider) VMDelete(name string) *OperationResult { return echo("vm_delete", name) }
+
+func mustBatchCall(t *testing.T, tool string, input any) BatchCall {
+	t.Helper()
+	call, err := NewBatchCall(tool, input)
+	if err != nil {
+		t.Fatalf("NewBatchCall(%s) error = %v", tool, err)
+	}
+	return call
+}
+
+func TestRunBatch(t *testing.T) {
+	req := &BatchRequest{Calls: []BatchCall{
+		mustBatchCall(t, "vm_create", &VMCreateRequest{Name: "vm1"}),
+		mustBatchCall(t, "key_create", &KeyCreateRequest{Name: "key1"}),
+		mustBatchCall(t, "unknown_tool", nil),
+		mustBatchCall(t, "vm_delete", &DeleteRequest{Name: ""}),
+		mustBatchCall(t, "network_get", &GetRequest{Name: "net1"}),
+	}}
+
+	result := RunBatch(req, BatchHandlers(fakeResourceProvider{}, true))
+	if !result.Success {
+		t.Fatalf("RunBatch() failed: %v", result.Error)
+	}
+	resp := result.Resource.(*BatchResponse)
+	if len(resp.Results) != len(req.Calls) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(req.Calls))
+	}
+
+	for i, want := range []struct{ tool, name string }{{"vm_create", "vm1"}, {"key_create", "key1"}} {
+		got := resp.Results[i].Resource.(map[string]any)
+		if got["tool"] != want.tool || got["name"] != want.name {
+			t.Errorf("results[%d] = %v, want %s %s", i, got, want.tool, want.name)
+		}
+	}
+	if resp.Results[2].Success || resp.Results[2].Error.Code != ErrCodeNotImplemented {
+		t.Errorf("unknown tool: got %+v, want NOT_IMPLEMENTED", resp.Results[2])
+	}
+	if resp.Results[3].Success || resp.Results[3].Error.Code != ErrCodeInvalidSpec {
+		t.Errorf("invalid call: got %+v, want INVALID_SPEC", resp.Results[3])
+	}
+	if !resp.Results[4].Success {
+		t.Errorf("network_get failed: %+v", resp.Results[4].Error)
+	}
+}
+
+func TestRunBatch_ReadOnly(t *testing.T) {
+	req := &BatchRequest{Calls: []BatchCall{
+		mustBatchCall(t, "vm_delete", &DeleteRequest{Name: "vm1"}),
+		mustBatchCall(t, "vm_get", &GetRequest{Name: "vm1"}),
+	}}
+
+	result := RunBatch(req, BatchHandlers(fakeResourceProvider{}, false))
+	resp := result.Resource.(*BatchResponse)
+	if resp.Results[0].Success || resp.Results[0].Error.Code != ErrCodeNotImplemented {
+		t.Errorf("vm_delete must not be available read-only, got %+v", resp.Results[0])
+	}
+	if !resp.Results[1].Success {
+		t.Errorf("vm_get failed: %+v", resp.Results[1].Error)
+	}
+}
+
+func TestRunBatch_TooManyCalls(t *testing.T) {
+	req := &BatchRequest{Calls: make([]BatchCall, MaxBatchCalls+1)}
+	result := RunBatch(req, BatchHandlers(fakeResourceProvider{}, true))
+	if result.Success || result.Error.Code != ErrCodeInvalidSpec {
+		t.Errorf("got %+v, want INVALID_SPEC", result)
+	}
+}
+
+func TestBatchRequest_JSONRoundtrip(t *testing.T) {
+	req := BatchRequest{Calls: []BatchCall{mustBatchCall(t, "vm_get", &GetRequest{Name: "vm1"})}}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got BatchRequest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if got.Calls[0].Tool != "vm_get" || got.Calls[0].Input["name"] != "vm1" {
+		t.Errorf("roundtrip = %+v", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the tools capturing the packets of a network or of a
+// VM interface into pcap files.
+package providerv1
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Capture tools. A provider serving them lists the "capture" operation for
+// the network kind.
+const (
+	NetworkCaptureStartTool = "network_capture_start"
+	NetworkCaptureStopTool  = "network_capture_stop"
+)
+
+// Limits of a capture. A capture stops by itself when its pcap file reaches
+// its maximum size or when it ran for its maximum duration.
+const (
+	DefaultCaptureMaxSizeMB      = 100
+	DefaultCaptureMaxDurationSec = 600
+)
+
+// Status of a capture.
+const (
+	CaptureStatusRunning = "running"
+	CaptureStatusStopped = "stopped"
+)
+
+// Reasons a capture stopped.
+const (
+	// CaptureStopRequested means network_capture_stop stopped the capture.
+	CaptureStopRequested = "requested"
+	// CaptureStopMaxSize means the pcap file reached its maximum size.
+	CaptureStopMaxSize = "maxSize"
+	// CaptureStopMaxDuration means the capture ran for its maximum duration.
+	CaptureStopMaxDuration = "maxDuration"
+	// CaptureStopExited means the capture process exited by itself, e.g.
+	// because its interface disappeared.
+	CaptureStopExited = "exited"
+)
+
+// NetworkCaptureStartRequest is the input for the network_capture_start
+// tool. Exactly one of Network and VM is set.
+type NetworkCaptureStartRequest struct {
+	// Network is the name of the network whose bridge is captured.
+	Network string `json:"network,omitempty"`
+	// VM is the name of the VM whose interface is captured.
+	VM string `json:"vm,omitempty"`
+	// MAC selects the interface of VM by its MAC address. Defaults to the
+	// first interface.
+	MAC string `json:"mac,omitempty"`
+	// OutputDir is the absolute directory the pcap file is written to,
+	// usually the artifact directory of the environment.
+	OutputDir string `json:"outputDir"`
+	// Filter is a pcap-filter expression, e.g. "tcp port 443".
+	Filter string `json:"filter,omitempty"`
+	// MaxSizeMB is the maximum size of the pcap file. Defaults to
+	// DefaultCaptureMaxSizeMB.
+	MaxSizeMB int `json:"maxSizeMB,omitempty"`
+	// MaxDurationSec is the maximum duration of the capture. Defaults to
+	// DefaultCaptureMaxDurationSec.
+	MaxDurationSec int `json:"maxDurationSec,omitempty"`
+}
+
+// NetworkCaptureStopRequest is the input for the network_capture_stop tool.
+type NetworkCaptureStopRequest struct {
+	// ID is the capture ID returned by network_capture_start.
+	ID string `json:"id"`
+}
+
+// CaptureState describes a capture. network_capture_start returns it
+// running; network_capture_stop returns it stopped, with the final size of
+// the pcap file.
+type CaptureState struct {
+	// ID identifies the capture in the provider.
+	ID string `json:"id"`
+	// Network or VM is the captured resource, as in the request.
+	Network string `json:"network,omitempty"`
+	VM      string `json:"vm,omitempty"`
+	// Interface is the host device captured, e.g. a bridge or a tap.
+	Interface string `json:"interface"`
+	// Path is the pcap file.
+	Path string `json:"path"`
+	// Filter is the pcap-filter expression of the capture.
+	Filter string `json:"filter,omitempty"`
+	// MaxSizeMB and MaxDurationSec are the limits applied.
+	MaxSizeMB      int `json:"maxSizeMB"`
+	MaxDurationSec int `json:"maxDurationSec"`
+	// Status is running or stopped.
+	Status string `json:"status"`
+	// StopReason tells why a stopped capture stopped.
+	StopReason string `json:"stopReason,omitempty"`
+	// StartedAt and StoppedAt are RFC3339 timestamps.
+	StartedAt string `json:"startedAt"`
+	StoppedAt string `json:"stoppedAt,omitempty"`
+	// SizeBytes is the size of the pcap file.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// Error is the output of a capture process that failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Validate checks a capture request.
+func (r *NetworkCaptureStartRequest) Validate() error {
+	switch {
+	case (r.Network == "") == (r.VM == ""):
+		return errors.New("exactly one of network and vm is required")
+	case r.MAC != "" && r.VM == "":
+		return errors.New("mac requires vm")
+	case !filepath.IsAbs(r.OutputDir):
+		return errors.New("outputDir must be an absolute path")
+	case r.MaxSizeMB < 0 || r.MaxDurationSec < 0:
+		return errors.New("maxSizeMB and maxDurationSec must not be negative")
+	case strings.HasPrefix(strings.TrimSpace(r.Filter), "-"):
+		return errors.New("filter must not start with '-'")
+	}
+	return nil
+}
+
+// Limits returns the maximum size in MB and duration in seconds of a
+// capture, applying the defaults.
+func (r *NetworkCaptureStartRequest) Limits() (maxSizeMB, maxDurationSec int) {
+	maxSizeMB, maxDurationSec = r.MaxSizeMB, r.MaxDurationSec
+	if maxSizeMB <= 0 {
+		maxSizeMB = DefaultCaptureMaxSizeMB
+	}
+	if maxDurationSec <= 0 {
+		maxDurationSec = DefaultCaptureMaxDurationSec
+	}
+	return maxSizeMB, maxDurationSec
+}
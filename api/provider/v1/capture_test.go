@@ -0,0 +1,55 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerv1
+
+import "testing"
+
+func TestNetworkCaptureStartRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     NetworkCaptureStartRequest
+		wantErr bool
+	}{
+		{name: "network", req: NetworkCaptureStartRequest{Network: "net", OutputDir: "/tmp/a"}},
+		{name: "vm with mac", req: NetworkCaptureStartRequest{VM: "vm", MAC: "52:54:00:00:00:01", OutputDir: "/tmp/a"}},
+		{name: "neither", req: NetworkCaptureStartRequest{OutputDir: "/tmp/a"}, wantErr: true},
+		{name: "both", req: NetworkCaptureStartRequest{Network: "net", VM: "vm", OutputDir: "/tmp/a"}, wantErr: true},
+		{name: "mac without vm", req: NetworkCaptureStartRequest{Network: "net", MAC: "52:54:00:00:00:01", OutputDir: "/tmp/a"}, wantErr: true},
+		{name: "relative output", req: NetworkCaptureStartRequest{Network: "net", OutputDir: "captures"}, wantErr: true},
+		{name: "negative limit", req: NetworkCaptureStartRequest{Network: "net", OutputDir: "/tmp/a", MaxSizeMB: -1}, wantErr: true},
+		{name: "filter", req: NetworkCaptureStartRequest{Network: "net", OutputDir: "/tmp/a", Filter: "tcp port 443"}},
+		{name: "option as filter", req: NetworkCaptureStartRequest{Network: "net", OutputDir: "/tmp/a", Filter: " -z /tmp/x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNetworkCaptureStartRequestLimits(t *testing.T) {
+	req := &NetworkCaptureStartRequest{}
+	if size, duration := req.Limits(); size != DefaultCaptureMaxSizeMB || duration != DefaultCaptureMaxDurationSec {
+		t.Errorf("Limits() = %d, %d, want the defaults", size, duration)
+	}
+	req = &NetworkCaptureStartRequest{MaxSizeMB: 5, MaxDurationSec: 30}
+	if size, duration := req.Limits(); size != 5 || duration != 30 {
+		t.Errorf("Limits() = %d, %d, want 5, 30", size, duration)
+	}
+}
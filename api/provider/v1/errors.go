@@ -119,6 +119,26 @@ func NewDependencyFailedError(resource, dependency string) *OperationError {
 	}
 }
 
+// DetailSuggestedCIDR is the detail of a NETWORK_CONFLICT error holding a
+// CIDR free on the host.
+const DetailSuggestedCIDR = "suggestedCidr"
+
+// NewNetworkConflictError creates a NETWORK_CONFLICT error for a network
+// whose CIDR overlaps the networks of the host, suggesting suggestedCIDR
+// instead unless it is empty.
+func NewNetworkConflictError(message, suggestedCIDR string) *OperationError {
+	err := &OperationError{
+		Code:      ErrCodeNetworkConflict,
+		Message:   message,
+		Retryable: false,
+	}
+	if suggestedCIDR != "" {
+		err.Message += "; try " + suggestedCIDR
+		err.Details = map[string]any{DetailSuggestedCIDR: suggestedCIDR}
+	}
+	return err
+}
+
 // IsRetryable checks if an OperationError is retryable.
 func IsRetryable(err *OperationError) bool {
 	if err == nil {
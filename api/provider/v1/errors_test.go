@@ -252,6 +252,28 @@ func TestNewDependencyFailedError(t *testing.T) {
 	}
 }
 
+func TestNewNetworkConflictError(t *testing.T) {
+	err := NewNetworkConflictError(`cidr 192.168.1.0/24 overlaps 192.168.1.0/24 of interface "eth0"`, "192.168.2.0/24")
+
+	if err.Code != ErrCodeNetworkConflict {
+		t.Errorf("Code = %q, want %q", err.Code, ErrCodeNetworkConflict)
+	}
+	if !strings.HasSuffix(err.Message, "; try 192.168.2.0/24") {
+		t.Errorf("Message should suggest 192.168.2.0/24: %q", err.Message)
+	}
+	if err.Details[DetailSuggestedCIDR] != "192.168.2.0/24" {
+		t.Errorf("Details = %v, want the suggested CIDR", err.Details)
+	}
+	if err.Retryable {
+		t.Error("NetworkConflictError should not be retryable")
+	}
+
+	err = NewNetworkConflictError("no free cidr", "")
+	if err.Message != "no free cidr" || err.Details != nil {
+		t.Errorf("NewNetworkConflictError() without suggestion = %+v", err)
+	}
+}
+
 func TestIsRetryable(t *testing.T) {
 	tests := []struct {
 		name string
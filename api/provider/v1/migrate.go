@@ -0,0 +1,45 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the tools moving a running VM between two providers of
+// the same engine.
+package providerv1
+
+// Migration tools. A provider serving them lists the "migrate" and "adopt"
+// operations for the vm kind. The source provider hands the VM over with
+// VMMigrateTool, then the destination provider takes ownership of it with
+// VMAdoptTool.
+const (
+	VMMigrateTool = "vm_migrate"
+	VMAdoptTool   = "vm_adopt"
+)
+
+// VMMigrateRequest is the input for the vm_migrate tool.
+type VMMigrateRequest struct {
+	// Name is the name of the VM to migrate.
+	Name string `json:"name"`
+	// DestinationURI is the connection URI of the destination host, as
+	// reported by the destination provider in CapabilitiesResponse.URI.
+	DestinationURI string `json:"destinationURI"`
+	// CopyStorage copies the disks to the destination host. It is required
+	// unless both hosts share the storage of the VM.
+	CopyStorage bool `json:"copyStorage,omitempty"`
+}
+
+// VMAdoptRequest is the input for the vm_adopt tool.
+type VMAdoptRequest struct {
+	// State is the VM state returned by vm_migrate on the source provider.
+	State VMState `json:"state"`
+}
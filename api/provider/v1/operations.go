@@ -16,6 +16,8 @@
 // This file contains operation types for provider request/response handling.
 package providerv1
 
+import "fmt"
+
 // OperationResult is the standard response for all provider operations.
 type OperationResult struct {
 	// Success indicates if the operation completed successfully.
@@ -50,6 +52,7 @@ const (
 	ErrCodePermissionDenied = "PERMISSION_DENIED" // Insufficient permissions
 	ErrCodeResourceBusy     = "RESOURCE_BUSY"     // Resource is in use
 	ErrCodeDependencyFailed = "DEPENDENCY_FAILED" // Dependency not satisfied
+	ErrCodeNetworkConflict  = "NETWORK_CONFLICT"  // Network overlaps networks of the host
 )
 
 // CapabilitiesResponse describes what a provider supports.
@@ -60,6 +63,28 @@ type CapabilitiesResponse struct {
 	Version string `json:"version"`
 	// Resources lists supported resource types and operations.
 	Resources []ResourceCapability `json:"resources"`
+	// Host describes the capacity of the host backing the provider, if known.
+	Host *HostCapacity `json:"host,omitempty"`
+	// Batch reports that the provider serves BatchTool.
+	Batch bool `json:"batch,omitempty"`
+	// Teardown reports that the provider serves TeardownTool.
+	Teardown bool `json:"teardown,omitempty"`
+	// URI is the connection URI of the host backing the provider, if any.
+	// It is the destination of VMMigrateTool calls from other providers.
+	URI string `json:"uri,omitempty"`
+}
+
+// HostCapacity describes the resources of a provider's host when its
+// capabilities were fetched. Zero values mean unknown.
+type HostCapacity struct {
+	// CPUs is the number of host CPUs.
+	CPUs int `json:"cpus,omitempty"`
+	// MemoryMB is the total host memory.
+	MemoryMB int `json:"memoryMB,omitempty"`
+	// FreeMemoryMB is the memory not in use by the host.
+	FreeMemoryMB int `json:"freeMemoryMB,omitempty"`
+	// FreeDiskMB is the free space where VM disks are stored.
+	FreeDiskMB int64 `json:"freeDiskMB,omitempty"`
 }
 
 // ResourceCapability describes capabilities for a resource type.
@@ -72,10 +97,40 @@ type ResourceCapability struct {
 	NetworkKinds []string `json:"networkKinds,omitempty"`
 	// KeyTypes lists supported key algorithms (for key resource).
 	KeyTypes []string `json:"keyTypes,omitempty"`
-	// VMFeatures lists supported VM features.
+	// VMFeatures lists supported VM features (see VMFeatureVsock and the
+	// other VMFeature constants).
 	VMFeatures []string `json:"vmFeatures,omitempty"`
 }
 
+// VM features advertised in ResourceCapability.VMFeatures. The orchestrator
+// rejects specs using a feature that their provider does not advertise.
+const (
+	// VMFeatureVsock is the virtio-vsock device (VMSpec.Vsock).
+	VMFeatureVsock = "vsock"
+	// VMFeatureSecurity is the security driver confinement (VMSpec.Security).
+	VMFeatureSecurity = "security"
+	// VMFeatureDiskEncryption is disk encryption (DiskSpec.Encryption).
+	VMFeatureDiskEncryption = "diskEncryption"
+	// VMFeatureNICOptions are the NIC model, MTU and offloads (VMSpec.NICs).
+	VMFeatureNICOptions = "nicOptions"
+	// VMFeatureDataDisks are the data disks attached after the boot disk
+	// (VMSpec.Disks).
+	VMFeatureDataDisks = "dataDisks"
+	// VMFeatureStaticIP is the static IP of the first NIC (VMSpec.IP).
+	VMFeatureStaticIP = "staticIP"
+	// VMFeatureRawUserData is verbatim cloud-init user-data
+	// (CloudInitSpec.RawUserData).
+	VMFeatureRawUserData = "rawUserData"
+	// VMFeatureGuestNetwork are the NIC matching, routes, bonds and VLANs
+	// of the guest network config (CloudInitNetworkConfig).
+	VMFeatureGuestNetwork = "guestNetwork"
+	// VMFeaturePCIPassthrough is host PCI device passthrough
+	// (VMSpec.PCIPassthrough).
+	VMFeaturePCIPassthrough = "pciPassthrough"
+	// VMFeatureMdev are mediated devices such as vGPUs (VMSpec.Mdevs).
+	VMFeatureMdev = "mdev"
+)
+
 // GetRequest is the input for get operations.
 type GetRequest struct {
 	Name string `json:"name"`
@@ -86,6 +141,44 @@ type ListRequest struct {
 	Filter map[string]any `json:"filter,omitempty"`
 }
 
+// FilterHost is a list filter. Set to true, it asks the provider for every
+// resource it finds on the host, including those left by earlier provider
+// processes, which it does not track. Providers without host-wide resources
+// list the ones they track.
+const FilterHost = "host"
+
+// FilterLabels is a list filter. Set to a map of label keys to values, it
+// restricts the list to the resources carrying all of them, e.g.
+// {"labels": {"testenv-vm.environment-id": "abc"}}.
+const FilterLabels = "labels"
+
+// LabelSelector returns the labels selected by a list filter (see
+// FilterLabels), or nil if it selects none.
+func LabelSelector(filter map[string]any) map[string]string {
+	switch labels := filter[FilterLabels].(type) {
+	case map[string]string:
+		return labels
+	case map[string]any:
+		selector := make(map[string]string, len(labels))
+		for k, v := range labels {
+			selector[k] = fmt.Sprint(v)
+		}
+		return selector
+	}
+	return nil
+}
+
+// MatchLabels reports whether labels carry every key and value of selector.
+// An empty selector matches everything.
+func MatchLabels(selector, labels map[string]string) bool {
+	for k, v := range selector {
+		if value, ok := labels[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
 // DeleteRequest is the input for delete operations.
 type DeleteRequest struct {
 	Name  string `json:"name"`
@@ -429,6 +429,7 @@ func TestErrorCodes(t *testing.T) {
 		{ErrCodePermissionDenied, "PERMISSION_DENIED"},
 		{ErrCodeResourceBusy, "RESOURCE_BUSY"},
 		{ErrCodeDependencyFailed, "DEPENDENCY_FAILED"},
+		{ErrCodeNetworkConflict, "NETWORK_CONFLICT"},
 	}
 
 	for _, tt := range tests {
@@ -437,3 +438,38 @@ func TestErrorCodes(t *testing.T) {
 		}
 	}
 }
+
+func TestLabelSelector(t *testing.T) {
+	var filter map[string]any
+	if err := json.Unmarshal([]byte(`{"labels": {"team": "storage", "shard": 2}}`), &filter); err != nil {
+		t.Fatalf("failed to unmarshal filter: %v", err)
+	}
+	want := map[string]string{"team": "storage", "shard": "2"}
+	if got := LabelSelector(filter); !reflect.DeepEqual(got, want) {
+		t.Errorf("LabelSelector() = %v, want %v", got, want)
+	}
+	if got := LabelSelector(map[string]any{FilterHost: true}); got != nil {
+		t.Errorf("LabelSelector() = %v, want nil", got)
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{LabelEnvironmentID: "abc", "team": "storage"}
+	tests := []struct {
+		name     string
+		selector map[string]string
+		want     bool
+	}{
+		{"empty selector", nil, true},
+		{"matching", map[string]string{"team": "storage"}, true},
+		{"different value", map[string]string{"team": "network"}, false},
+		{"missing key", map[string]string{LabelStage: "e2e"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchLabels(tt.selector, labels); got != tt.want {
+				t.Errorf("MatchLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the VM power tools, which start, stop, reboot, pause and
+// save existing VMs.
+package providerv1
+
+import (
+	"fmt"
+	"time"
+)
+
+// Names of the VM power tools. Providers that serve them list the matching
+// power action among the operations of the vm resource.
+const (
+	VMStartTool  = "vm_start"
+	VMStopTool   = "vm_stop"
+	VMRebootTool = "vm_reboot"
+	VMPauseTool  = "vm_pause"
+	VMSaveTool   = "vm_save"
+)
+
+// Power actions, as listed in ResourceCapability.Operations.
+const (
+	PowerStart  = "start"
+	PowerStop   = "stop"
+	PowerReboot = "reboot"
+	PowerPause  = "pause"
+	PowerSave   = "save"
+)
+
+// Power statuses of a VM, as reported in VMState.Status.
+const (
+	VMStatusRunning = "running"
+	VMStatusStopped = "stopped"
+	VMStatusPaused  = "paused"
+	VMStatusSaved   = "saved"
+)
+
+// DefaultStopTimeoutSeconds bounds a graceful vm_stop when the request does
+// not set a timeout.
+const DefaultStopTimeoutSeconds = 60
+
+// PowerTools maps power actions to the name of their tool.
+var PowerTools = map[string]string{
+	PowerStart:  VMStartTool,
+	PowerStop:   VMStopTool,
+	PowerReboot: VMRebootTool,
+	PowerPause:  VMPauseTool,
+	PowerSave:   VMSaveTool,
+}
+
+// VMPowerRequest is the input for the VM power tools.
+type VMPowerRequest struct {
+	// Name is the name of the VM.
+	Name string `json:"name"`
+	// Force pulls the plug instead of asking the guest: vm_stop kills the VM
+	// and vm_reboot resets it, as a crash or power loss would. It is ignored
+	// by vm_start, vm_pause and vm_save.
+	Force bool `json:"force,omitempty"`
+	// TimeoutSeconds bounds a graceful vm_stop, after which it fails and
+	// the VM keeps running. Defaults to DefaultStopTimeoutSeconds.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// StopTimeout returns the time a graceful vm_stop waits for the guest to
+// power off.
+func (r *VMPowerRequest) StopTimeout() time.Duration {
+	if r.TimeoutSeconds <= 0 {
+		return DefaultStopTimeoutSeconds * time.Second
+	}
+	return time.Duration(r.TimeoutSeconds) * time.Second
+}
+
+// NextPowerStatus returns the status of a VM in status after a power action.
+// Actions that leave the status unchanged, such as starting a running VM,
+// succeed; vm_start resumes a paused VM and restores a saved one. vm_save
+// writes the memory of a running or paused VM to disk and powers it off. It
+// fails for unknown actions and for rebooting, pausing or saving a VM that is
+// not running.
+func NextPowerStatus(status, action string) (string, *OperationError) {
+	switch action {
+	case PowerStart:
+		return VMStatusRunning, nil
+	case PowerStop:
+		return VMStatusStopped, nil
+	case PowerReboot:
+		if status == VMStatusRunning {
+			return VMStatusRunning, nil
+		}
+	case PowerPause:
+		if status == VMStatusRunning || status == VMStatusPaused {
+			return VMStatusPaused, nil
+		}
+	case PowerSave:
+		if status == VMStatusRunning || status == VMStatusPaused || status == VMStatusSaved {
+			return VMStatusSaved, nil
+		}
+	default:
+		return "", NewInvalidSpecError(fmt.Sprintf("unknown power action %q", action))
+	}
+	return "", NewInvalidSpecError(fmt.Sprintf("cannot %s a %s vm", action, status))
+}
@@ -0,0 +1,71 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerv1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVMPowerRequestStopTimeout(t *testing.T) {
+	if got := (&VMPowerRequest{}).StopTimeout(); got != DefaultStopTimeoutSeconds*time.Second {
+		t.Errorf("StopTimeout() = %s, want default", got)
+	}
+	if got := (&VMPowerRequest{TimeoutSeconds: 5}).StopTimeout(); got != 5*time.Second {
+		t.Errorf("StopTimeout() = %s, want 5s", got)
+	}
+}
+
+func TestNextPowerStatus(t *testing.T) {
+	tests := []struct {
+		status, action string
+		want           string
+		wantErr        bool
+	}{
+		{VMStatusStopped, PowerStart, VMStatusRunning, false},
+		{VMStatusPaused, PowerStart, VMStatusRunning, false},
+		{VMStatusRunning, PowerStart, VMStatusRunning, false},
+		{VMStatusRunning, PowerStop, VMStatusStopped, false},
+		{VMStatusPaused, PowerStop, VMStatusStopped, false},
+		{VMStatusStopped, PowerStop, VMStatusStopped, false},
+		{VMStatusRunning, PowerReboot, VMStatusRunning, false},
+		{VMStatusStopped, PowerReboot, "", true},
+		{VMStatusPaused, PowerReboot, "", true},
+		{VMStatusRunning, PowerPause, VMStatusPaused, false},
+		{VMStatusPaused, PowerPause, VMStatusPaused, false},
+		{VMStatusStopped, PowerPause, "", true},
+		{VMStatusRunning, PowerSave, VMStatusSaved, false},
+		{VMStatusPaused, PowerSave, VMStatusSaved, false},
+		{VMStatusStopped, PowerSave, "", true},
+		{VMStatusSaved, PowerStart, VMStatusRunning, false},
+		{VMStatusRunning, "hibernate", "", true},
+	}
+	for _, tt := range tests {
+		got, err := NextPowerStatus(tt.status, tt.action)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NextPowerStatus(%q, %q) = %q, %v, want %q (error %t)", tt.status, tt.action, got, err, tt.want, tt.wantErr)
+		}
+		if err != nil && err.Code != ErrCodeInvalidSpec {
+			t.Errorf("NextPowerStatus(%q, %q) error code = %s, want %s", tt.status, tt.action, err.Code, ErrCodeInvalidSpec)
+		}
+	}
+	for action, tool := range PowerTools {
+		if _, err := NextPowerStatus(VMStatusRunning, action); err != nil {
+			t.Errorf("NextPowerStatus() rejects the %s action of %s: %v", action, tool, err)
+		}
+	}
+}
@@ -26,7 +26,31 @@ type VMCreateRequest struct {
 	Spec VMSpec `json:"spec"`
 	// ProviderSpec contains provider-specific configuration.
 	ProviderSpec map[string]any `json:"providerSpec,omitempty"`
-}
+	// Labels identify the owner of the VM (see LabelEnvironmentID and
+	// LabelResource) and carry the labels of the spec. Providers should
+	// attach them to the files and objects they create so that leftovers can
+	// be traced back without state.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Labels set by the orchestrator on create requests.
+const (
+	// LabelEnvironmentID is the ID of the environment owning the resource.
+	LabelEnvironmentID = "testenv-vm.environment-id"
+	// LabelResource is the kind and spec name of the resource, e.g. "vm/web".
+	LabelResource = "testenv-vm.resource"
+	// LabelStage is the test stage of the environment, if any.
+	LabelStage = "testenv-vm.stage"
+	// LabelCreatedAt is the creation time of the environment (RFC 3339).
+	LabelCreatedAt = "testenv-vm.created-at"
+	// LabelTenant is the tenant of the orchestrator that created the
+	// resource, if any.
+	LabelTenant = "testenv-vm.tenant"
+	// LabelStateDir identifies the state directory of the orchestrator that
+	// created the resource; garbage collection only deletes resources
+	// carrying that of its own state directory.
+	LabelStateDir = "testenv-vm.state-dir"
+)
 
 // VMSpec is the complete VM specification.
 // It defines all aspects of VM configuration including compute, storage,
@@ -44,12 +68,27 @@ type VMSpec struct {
 	CPU *CPUSpec `json:"cpu,omitempty"`
 	// Disk configuration.
 	Disk DiskSpec `json:"disk"`
+	// Disks are data disks, attached after the boot disk as virtio-blk
+	// devices (vdb, vdc, ...). Their Bus, Cache and Encryption are ignored.
+	Disks []DiskSpec `json:"disks,omitempty"`
 	// Network to attach (reference to network resource name).
 	// Deprecated: use Networks instead.
 	Network string `json:"network,omitempty"`
 	// Networks to attach (list of network resource names).
 	// Takes precedence over Network when set.
 	Networks []string `json:"networks,omitempty"`
+	// MACAddresses of the NICs, in the order of Networks. Empty entries, or
+	// entries past the end, are assigned by the provider.
+	MACAddresses []string `json:"macAddresses,omitempty"`
+	// IP is the static IPv4 address of the first NIC, reserved for its MAC
+	// address in the DHCP server of its network. When set, the provider
+	// reports it instead of waiting for a DHCP lease.
+	IP string `json:"ip,omitempty"`
+	// UUID of the VM. Empty lets the provider assign one.
+	UUID string `json:"uuid,omitempty"`
+	// NICs are the options of the NICs, in the order of Networks. Missing
+	// entries use the provider defaults.
+	NICs []NICSpec `json:"nics,omitempty"`
 	// CloudInit configuration.
 	CloudInit *CloudInitSpec `json:"cloudInit,omitempty"`
 	// Boot configuration.
@@ -64,6 +103,26 @@ type VMSpec struct {
 	GuestAgent bool `json:"guestAgent,omitempty"`
 	// Readiness checks.
 	Readiness *ReadinessSpec `json:"readiness,omitempty"`
+	// Security driver (sVirt) configuration. Nil keeps the hypervisor default.
+	Security *SecuritySpec `json:"security,omitempty"`
+	// Vsock adds a virtio-vsock device. Nil means no vsock device.
+	Vsock *VsockSpec `json:"vsock,omitempty"`
+	// PCIPassthrough lists the host PCI devices passed through to the VM,
+	// by address (e.g. "0000:01:00.0").
+	PCIPassthrough []string `json:"pciPassthrough,omitempty"`
+	// Mdevs lists the mediated devices (vGPUs) attached to the VM.
+	Mdevs []MdevSpec `json:"mdevs,omitempty"`
+}
+
+// NICSpec defines the options of a NIC of a VM.
+type NICSpec struct {
+	// Model: virtio, e1000 - defaults to virtio.
+	Model string `json:"model,omitempty"`
+	// MTU configured on the NIC and in the guest. Zero keeps the default.
+	MTU int `json:"mtu,omitempty"`
+	// DisableOffloads lists the offloads turned off in the guest: tso, gso,
+	// gro, lro.
+	DisableOffloads []string `json:"disableOffloads,omitempty"`
 }
 
 // CPUSpec defines CPU configuration for the VM.
@@ -84,10 +143,24 @@ type DiskSpec struct {
 	BaseImage string `json:"baseImage,omitempty"`
 	// Size is the disk size (e.g., "20G").
 	Size string `json:"size"`
+	// Format of the image: qcow2 (default) or raw. The boot disk is always
+	// qcow2.
+	Format string `json:"format,omitempty"`
 	// Bus is the disk bus type (virtio, scsi, ide) - defaults to virtio.
 	Bus string `json:"bus,omitempty"`
 	// Cache mode (none, writeback, writethrough) - defaults to none.
 	Cache string `json:"cache,omitempty"`
+	// Encryption configures LUKS encryption of the disk.
+	Encryption *DiskEncryptionSpec `json:"encryption,omitempty"`
+}
+
+// DiskEncryptionSpec defines LUKS encryption for a VM disk.
+type DiskEncryptionSpec struct {
+	// Enabled creates the disk as a LUKS-encrypted volume.
+	Enabled bool `json:"enabled"`
+	// PassphraseSecretRef references the passphrase (env:NAME or file:PATH).
+	// Providers resolve it with pkg/secrets; the value never crosses the wire.
+	PassphraseSecretRef string `json:"passphraseSecretRef,omitempty"`
 }
 
 // CloudInitSpec defines cloud-init configuration for the VM.
@@ -105,6 +178,18 @@ type CloudInitSpec struct {
 	// NetworkConfig configures the network via cloud-init's network-config.
 	// If nil, uses DHCP on all ethernet interfaces.
 	NetworkConfig *CloudInitNetworkConfig `json:"networkConfig,omitempty"`
+	// NTPServers configures the time servers of the VM (cloud-init ntp module).
+	NTPServers []string `json:"ntpServers,omitempty"`
+	// SyslogServers are "host:port" addresses the VM forwards its syslog to
+	// over TCP (cloud-init rsyslog module).
+	SyslogServers []string `json:"syslogServers,omitempty"`
+	// CACerts are PEM-encoded CA certificates added to the system trust
+	// store of the VM (cloud-init ca_certs module).
+	CACerts []string `json:"caCerts,omitempty"`
+	// RawUserData, when set, is written verbatim as the user-data of the VM
+	// instead of the one generated from the fields above. Hostname and
+	// NetworkConfig still apply.
+	RawUserData string `json:"rawUserData,omitempty"`
 }
 
 // CloudInitNetworkConfig configures cloud-init network settings.
@@ -112,6 +197,10 @@ type CloudInitSpec struct {
 type CloudInitNetworkConfig struct {
 	// Ethernets configures ethernet interfaces.
 	Ethernets []CloudInitEthernetConfig `json:"ethernets,omitempty"`
+	// Bonds configures bonds of ethernet interfaces.
+	Bonds []CloudInitBondConfig `json:"bonds,omitempty"`
+	// VLANs configures VLAN interfaces on top of ethernet interfaces or bonds.
+	VLANs []CloudInitVLANConfig `json:"vlans,omitempty"`
 }
 
 // CloudInitEthernetConfig configures a single ethernet interface.
@@ -127,12 +216,78 @@ type CloudInitEthernetConfig struct {
 	Gateway4 string `json:"gateway4,omitempty"`
 	// Nameservers configures DNS servers.
 	Nameservers *CloudInitNameservers `json:"nameservers,omitempty"`
+	// Match is an interface name pattern matched instead of Name, which is
+	// then only the ID of the interface.
+	Match string `json:"match,omitempty"`
+	// NIC is the index in Networks of the NIC this interface is, matched by
+	// its MAC address and renamed to Name.
+	NIC *int `json:"nic,omitempty"`
+	// Routes are static routes of the interface.
+	Routes []CloudInitRoute `json:"routes,omitempty"`
+	// MTU of the interface.
+	MTU int `json:"mtu,omitempty"`
+}
+
+// CloudInitBondConfig configures a bond of ethernet interfaces.
+type CloudInitBondConfig struct {
+	// Name of the bond interface (e.g., "bond0").
+	Name string `json:"name"`
+	// Interfaces are the names of the ethernet interfaces of the bond.
+	Interfaces []string `json:"interfaces"`
+	// Mode is the bonding mode (e.g., "active-backup", "802.3ad").
+	Mode string `json:"mode,omitempty"`
+	// DHCP4 enables DHCP for IPv4 if true. Defaults to true if Addresses is empty.
+	DHCP4 *bool `json:"dhcp4,omitempty"`
+	// Addresses is a list of static IP addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Gateway4 is the IPv4 gateway address.
+	Gateway4 string `json:"gateway4,omitempty"`
+	// Routes are static routes of the bond.
+	Routes []CloudInitRoute `json:"routes,omitempty"`
+	// Nameservers configures DNS servers.
+	Nameservers *CloudInitNameservers `json:"nameservers,omitempty"`
+	// MTU of the bond.
+	MTU int `json:"mtu,omitempty"`
+}
+
+// CloudInitVLANConfig configures a VLAN interface.
+type CloudInitVLANConfig struct {
+	// Name of the VLAN interface (e.g., "vlan10").
+	Name string `json:"name"`
+	// ID is the VLAN ID.
+	ID int `json:"id"`
+	// Link is the name of the ethernet interface or bond carrying the VLAN.
+	Link string `json:"link"`
+	// DHCP4 enables DHCP for IPv4 if true. Defaults to true if Addresses is empty.
+	DHCP4 *bool `json:"dhcp4,omitempty"`
+	// Addresses is a list of static IP addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Gateway4 is the IPv4 gateway address.
+	Gateway4 string `json:"gateway4,omitempty"`
+	// Routes are static routes of the VLAN interface.
+	Routes []CloudInitRoute `json:"routes,omitempty"`
+	// Nameservers configures DNS servers.
+	Nameservers *CloudInitNameservers `json:"nameservers,omitempty"`
+	// MTU of the VLAN interface.
+	MTU int `json:"mtu,omitempty"`
+}
+
+// CloudInitRoute configures a static route.
+type CloudInitRoute struct {
+	// To is the destination in CIDR notation, or "default".
+	To string `json:"to"`
+	// Via is the IPv4 address of the next hop.
+	Via string `json:"via"`
+	// Metric of the route.
+	Metric int `json:"metric,omitempty"`
 }
 
 // CloudInitNameservers configures DNS servers.
 type CloudInitNameservers struct {
 	// Addresses is a list of DNS server IP addresses.
 	Addresses []string `json:"addresses,omitempty"`
+	// Search is a list of DNS search domains.
+	Search []string `json:"search,omitempty"`
 }
 
 // UserSpec defines a user to create via cloud-init.
@@ -167,6 +322,9 @@ type WriteFileSpec struct {
 	Content string `json:"content"`
 	// Permissions (e.g., "0644").
 	Permissions string `json:"permissions,omitempty"`
+	// Encoding of Content as understood by cloud-init, e.g. "b64" or
+	// "gz+b64". Empty means plain text.
+	Encoding string `json:"encoding,omitempty"`
 }
 
 // BootSpec defines boot configuration for the VM.
@@ -216,6 +374,41 @@ type VirtioFSSpec struct {
 	Queue int `json:"queue,omitempty"`
 }
 
+// SecuritySpec defines per-VM security driver (sVirt) configuration.
+// Default SELinux/AppArmor confinement can block virtiofs shares or custom
+// firmware paths; these options relax or replace it.
+type SecuritySpec struct {
+	// Disabled turns the security driver off for this VM (debugging only).
+	Disabled bool `json:"disabled,omitempty"`
+	// Model: selinux, apparmor. Empty uses the host driver.
+	Model string `json:"model,omitempty"`
+	// Label is a static security label (SELinux context or AppArmor profile).
+	// Empty means a dynamically generated label.
+	Label string `json:"label,omitempty"`
+	// Relabel relabels image files when a static label is used.
+	Relabel bool `json:"relabel,omitempty"`
+}
+
+// VsockSpec defines the virtio-vsock device of a VM, a host-guest channel
+// that does not depend on guest networking.
+type VsockSpec struct {
+	// CID is the context ID of the guest (at least 3). Zero lets the
+	// provider pick a free one, reported in VMState.VsockCID.
+	CID uint32 `json:"cid,omitempty"`
+}
+
+// MdevSpec defines a mediated device of a VM: an existing one, by UUID, or
+// one of Type the provider creates with the VM and removes with it.
+type MdevSpec struct {
+	// UUID of an existing mediated device.
+	UUID string `json:"uuid,omitempty"`
+	// Type of the mediated device to create (e.g. "nvidia-63").
+	Type string `json:"type,omitempty"`
+	// Parent is the PCI address of the device creating it. Empty lets the
+	// provider pick a parent with an available instance of Type.
+	Parent string `json:"parent,omitempty"`
+}
+
 // ReadinessSpec defines readiness check configuration.
 type ReadinessSpec struct {
 	// SSH readiness check.
@@ -236,6 +429,9 @@ type SSHReadinessSpec struct {
 	User string `json:"user,omitempty"`
 	// PrivateKey path (can use template).
 	PrivateKey string `json:"privateKey,omitempty"`
+	// ProxyJump is an optional jump host in OpenSSH form [user@]host[:port].
+	// The user and private key default to the ones above.
+	ProxyJump string `json:"proxyJump,omitempty"`
 }
 
 // TCPReadinessSpec defines TCP port readiness check configuration.
@@ -262,6 +458,9 @@ type VMState struct {
 	Status string `json:"status"`
 	// IP is the assigned IP address of the first NIC (if available).
 	IP string `json:"ip,omitempty"`
+	// SSHPort is the port SSH is reached on at IP, when it is not 22 (e.g.
+	// a port forwarded to a VM behind user-mode networking).
+	SSHPort int `json:"sshPort,omitempty"`
 	// MAC is the MAC address of the first NIC.
 	MAC string `json:"mac,omitempty"`
 	// IPs maps network name to assigned IP address (for multi-NIC VMs).
@@ -274,14 +473,23 @@ type VMState struct {
 	ConsoleOutput string `json:"consoleOutput,omitempty"`
 	// SSHCommand to connect to this VM.
 	SSHCommand string `json:"sshCommand,omitempty"`
+	// HostKeys are the SSH host public keys of the VM in authorized_keys
+	// format, collected after boot.
+	HostKeys []string `json:"hostKeys,omitempty"`
+	// HostKeyFingerprints are the SHA256 fingerprints of HostKeys.
+	HostKeyFingerprints []string `json:"hostKeyFingerprints,omitempty"`
 	// VNCAddress for VNC connection.
 	VNCAddress string `json:"vncAddress,omitempty"`
 	// SerialDevice path for serial console.
 	SerialDevice string `json:"serialDevice,omitempty"`
 	// DomainXML is the full libvirt domain XML (for debugging).
 	DomainXML string `json:"domainXML,omitempty"`
+	// VsockCID is the context ID of the vsock device, if any.
+	VsockCID uint32 `json:"vsockCID,omitempty"`
 	// QMPSocket path (for QEMU provider direct control).
 	QMPSocket string `json:"qmpSocket,omitempty"`
+	// Labels are the labels of the VM (see VMCreateRequest.Labels).
+	Labels map[string]string `json:"labels,omitempty"`
 	// CreatedAt timestamp.
 	CreatedAt string `json:"createdAt,omitempty"`
 	// ProviderState contains provider-specific state.
@@ -299,6 +507,8 @@ type NetworkCreateRequest struct {
 	Spec NetworkSpec `json:"spec"`
 	// ProviderSpec contains provider-specific configuration.
 	ProviderSpec map[string]any `json:"providerSpec,omitempty"`
+	// Labels are the labels of the network (see VMCreateRequest.Labels).
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // NetworkSpec is the network specification.
@@ -319,6 +529,8 @@ type NetworkSpec struct {
 	TFTP *TFTPSpec `json:"tftp,omitempty"`
 	// IPv6 configuration.
 	IPv6 *IPv6Spec `json:"ipv6,omitempty"`
+	// NTP configuration.
+	NTP *NTPSpec `json:"ntp,omitempty"`
 }
 
 // DHCPSpec defines DHCP configuration for a network.
@@ -361,10 +573,39 @@ type DNSSpec struct {
 	Servers []string `json:"servers,omitempty"`
 	// Hosts for local DNS entries.
 	Hosts []DNSHost `json:"hosts,omitempty"`
+	// Records served by the network DNS server, overriding upstream answers.
+	Records []DNSRecord `json:"records,omitempty"`
 	// Domain for local DNS.
 	Domain string `json:"domain,omitempty"`
 }
 
+// NTPSpec defines the NTP server of a network.
+type NTPSpec struct {
+	// Enabled runs an NTP server on the network gateway.
+	Enabled bool `json:"enabled"`
+	// Servers are the upstream servers to synchronize with. Empty serves the
+	// host clock.
+	Servers []string `json:"servers,omitempty"`
+}
+
+// DNS record types of DNSRecord.
+const (
+	DNSRecordA     = "A"
+	DNSRecordAAAA  = "AAAA"
+	DNSRecordCNAME = "CNAME"
+	DNSRecordTXT   = "TXT"
+)
+
+// DNSRecord defines a record served by the DNS server of a network.
+type DNSRecord struct {
+	// Name is the fully qualified name of the record.
+	Name string `json:"name"`
+	// Type is A, AAAA, CNAME or TXT. Empty means A.
+	Type string `json:"type,omitempty"`
+	// Value is the address, the CNAME target or the text of the record.
+	Value string `json:"value"`
+}
+
 // DNSHost defines a local DNS entry.
 type DNSHost struct {
 	// Hostname for the DNS entry.
@@ -415,8 +656,12 @@ type NetworkState struct {
 	InterfaceName string `json:"interfaceName,omitempty"`
 	// UUID is the libvirt network UUID.
 	UUID string `json:"uuid,omitempty"`
+	// NTPServer is the address of the NTP server of the network, if any.
+	NTPServer string `json:"ntpServer,omitempty"`
 	// PID for dnsmasq process.
 	PID int `json:"pid,omitempty"`
+	// Labels are the labels of the network (see NetworkCreateRequest.Labels).
+	Labels map[string]string `json:"labels,omitempty"`
 	// ProviderState contains provider-specific state.
 	ProviderState map[string]any `json:"providerState,omitempty"`
 }
@@ -429,6 +674,8 @@ type KeyCreateRequest struct {
 	Spec KeySpec `json:"spec"`
 	// ProviderSpec contains provider-specific configuration.
 	ProviderSpec map[string]any `json:"providerSpec,omitempty"`
+	// Labels are the labels of the key (see VMCreateRequest.Labels).
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // KeySpec is the key specification.
@@ -459,6 +706,8 @@ type KeyState struct {
 	Fingerprint string `json:"fingerprint"`
 	// AWSKeyPairID for AWS-managed keys (if applicable).
 	AWSKeyPairID string `json:"awsKeyPairId,omitempty"`
+	// Labels are the labels of the key (see KeyCreateRequest.Labels).
+	Labels map[string]string `json:"labels,omitempty"`
 	// CreatedAt timestamp.
 	CreatedAt string `json:"createdAt,omitempty"`
 	// ProviderState contains provider-specific state.
@@ -0,0 +1,39 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the tool freezing the disk of a running VM so that new
+// VMs can be created on top of it.
+package providerv1
+
+// VMSnapshotTool freezes the disk of a running VM. A provider serving it
+// lists the "snapshot" operation for the vm kind.
+const VMSnapshotTool = "vm_snapshot"
+
+// VMSnapshotRequest is the input for the vm_snapshot tool.
+type VMSnapshotRequest struct {
+	// Name is the name of the VM to snapshot.
+	Name string `json:"name"`
+}
+
+// VMSnapshot is the resource returned by the vm_snapshot tool.
+type VMSnapshot struct {
+	// Name is the name of the snapshotted VM.
+	Name string `json:"name"`
+	// BaseImage is the path of the frozen disk. The VM keeps running on a new
+	// overlay, so the frozen disk is never written again and can be used as
+	// DiskSpec.BaseImage by VMs of the same provider. It is removed with the
+	// VM.
+	BaseImage string `json:"baseImage"`
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the tool reporting the resource usage of a running VM.
+package providerv1
+
+// VMStatsTool is the name of the VM stats tool. A provider serving it lists
+// the "stats" operation for the vm kind.
+const VMStatsTool = "vm_stats"
+
+// Bounds of VMStatsRequest.IntervalMs.
+const (
+	DefaultStatsIntervalMs = 1000
+	MaxStatsIntervalMs     = 10000
+)
+
+// VMStatsRequest is the input for the vm_stats tool.
+type VMStatsRequest struct {
+	// Name is the name of the VM.
+	Name string `json:"name"`
+	// IntervalMs is the time between the two samples CPUPercent is computed
+	// from. Defaults to DefaultStatsIntervalMs, at most MaxStatsIntervalMs.
+	IntervalMs int `json:"intervalMs,omitempty"`
+}
+
+// VMStats is a snapshot of the resource usage of a VM, as seen from the
+// host. Counters are cumulative since the VM started.
+type VMStats struct {
+	// Name is the name of the VM.
+	Name string `json:"name"`
+	// Timestamp is when the snapshot was taken (RFC3339).
+	Timestamp string `json:"timestamp"`
+	// VCPUs is the number of virtual CPUs.
+	VCPUs int `json:"vcpus"`
+	// CPUTimeNs is the CPU time used by the VM.
+	CPUTimeNs uint64 `json:"cpuTimeNs"`
+	// CPUPercent is the CPU usage over the sampling interval, relative to
+	// all vCPUs: 100 means every vCPU was busy.
+	CPUPercent float64 `json:"cpuPercent"`
+	// MemoryMB is the memory allocated to the VM.
+	MemoryMB int `json:"memoryMB"`
+	// MemoryRSSMB is the host memory used by the VM process, if known.
+	MemoryRSSMB int `json:"memoryRSSMB,omitempty"`
+	// MemoryUsedMB is the memory used by the guest, if reported by its
+	// balloon driver.
+	MemoryUsedMB int `json:"memoryUsedMB,omitempty"`
+	// Disks are the statistics of the VM disks.
+	Disks []DiskStats `json:"disks,omitempty"`
+	// Interfaces are the statistics of the VM network interfaces.
+	Interfaces []InterfaceStats `json:"interfaces,omitempty"`
+}
+
+// DiskStats are the statistics of a VM disk.
+type DiskStats struct {
+	// Device is the target device of the disk in the guest, e.g. "vda".
+	Device string `json:"device"`
+	// ReadBytes and WriteBytes are the bytes read from and written to the
+	// disk.
+	ReadBytes  int64 `json:"readBytes"`
+	WriteBytes int64 `json:"writeBytes"`
+	// ReadRequests and WriteRequests are the number of I/O requests.
+	ReadRequests  int64 `json:"readRequests"`
+	WriteRequests int64 `json:"writeRequests"`
+	// AllocationBytes is the host storage used by the disk image.
+	AllocationBytes uint64 `json:"allocationBytes,omitempty"`
+	// CapacityBytes is the size of the disk as seen by the guest.
+	CapacityBytes uint64 `json:"capacityBytes,omitempty"`
+	// PhysicalBytes is the size of the image file, holes included. A sparse
+	// image allocates less than its physical size.
+	PhysicalBytes uint64 `json:"physicalBytes,omitempty"`
+}
+
+// InterfaceStats are the statistics of a VM network interface.
+type InterfaceStats struct {
+	// Device is the host device of the interface, e.g. "vnet0".
+	Device string `json:"device"`
+	// MAC is the MAC address of the interface in the guest.
+	MAC string `json:"mac,omitempty"`
+	// RxBytes and TxBytes are the bytes received and sent by the guest.
+	RxBytes int64 `json:"rxBytes"`
+	TxBytes int64 `json:"txBytes"`
+	// RxPackets and TxPackets are the packets received and sent.
+	RxPackets int64 `json:"rxPackets"`
+	TxPackets int64 `json:"txPackets"`
+	// RxErrors, TxErrors, RxDrops and TxDrops count failed packets.
+	RxErrors int64 `json:"rxErrors,omitempty"`
+	TxErrors int64 `json:"txErrors,omitempty"`
+	RxDrops  int64 `json:"rxDrops,omitempty"`
+	TxDrops  int64 `json:"txDrops,omitempty"`
+}
+
+// StatsInterval returns the sampling interval of a request in
+// milliseconds, applying the default and the maximum.
+func (r *VMStatsRequest) StatsInterval() int {
+	switch {
+	case r.IntervalMs <= 0:
+		return DefaultStatsIntervalMs
+	case r.IntervalMs > MaxStatsIntervalMs:
+		return MaxStatsIntervalMs
+	default:
+		return r.IntervalMs
+	}
+}
@@ -0,0 +1,34 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerv1
+
+import "testing"
+
+func TestVMStatsRequestStatsInterval(t *testing.T) {
+	tests := map[int]int{
+		0:     DefaultStatsIntervalMs,
+		-5:    DefaultStatsIntervalMs,
+		250:   250,
+		60000: MaxStatsIntervalMs,
+	}
+	for in, want := range tests {
+		req := &VMStatsRequest{Name: "vm", IntervalMs: in}
+		if got := req.StatsInterval(); got != want {
+			t.Errorf("StatsInterval() with %d = %d, want %d", in, got, want)
+		}
+	}
+}
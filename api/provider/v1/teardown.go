@@ -0,0 +1,107 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package providerv1 defines resource types for provider communication.
+// This file contains the environment teardown tool, which deletes the
+// resources of an environment in one MCP request.
+package providerv1
+
+import (
+	"fmt"
+	"sync"
+)
+
+// TeardownTool is the name of the environment teardown tool. Providers that
+// serve it set CapabilitiesResponse.Teardown.
+const TeardownTool = "environment_teardown"
+
+// TeardownRequest is the input for the environment teardown tool. It lists
+// the resources to delete by kind; VMs are deleted first, then networks,
+// then keys.
+type TeardownRequest struct {
+	// VMs are deleted concurrently.
+	VMs []string `json:"vms,omitempty"`
+	// Networks are deleted sequentially in the given order, so that a
+	// network attached to another one is listed first.
+	Networks []string `json:"networks,omitempty"`
+	// Keys are deleted concurrently.
+	Keys []string `json:"keys,omitempty"`
+}
+
+// TeardownResponse is the resource of a successful teardown OperationResult.
+type TeardownResponse struct {
+	// Results holds one result per resource, in deletion order.
+	Results []TeardownResult `json:"results"`
+}
+
+// TeardownResult is the outcome of the deletion of one resource.
+type TeardownResult struct {
+	// Kind is the resource type: vm, network or key.
+	Kind string `json:"kind"`
+	// Name is the resource name.
+	Name string `json:"name"`
+	// Error is set when the deletion failed. Resources that did not exist
+	// count as deleted.
+	Error *OperationError `json:"error,omitempty"`
+}
+
+// Len returns the number of resources of the request.
+func (r *TeardownRequest) Len() int {
+	return len(r.VMs) + len(r.Networks) + len(r.Keys)
+}
+
+// RunTeardown deletes the resources of req with p: VMs, then networks, then
+// keys. Teardown is best-effort: a failed deletion does not stop the others,
+// and is reported in its TeardownResult.
+func RunTeardown(req *TeardownRequest, p ResourceProvider) *OperationResult {
+	if n := req.Len(); n > MaxBatchCalls {
+		return ErrorResult(NewInvalidSpecError(
+			fmt.Sprintf("teardown has %d resources, at most %d are allowed", n, MaxBatchCalls)))
+	}
+
+	results := teardownConcurrently("vm", req.VMs, p.VMDelete)
+	for _, name := range req.Networks {
+		results = append(results, teardownResult("network", name, p.NetworkDelete(name)))
+	}
+	results = append(results, teardownConcurrently("key", req.Keys, p.KeyDelete)...)
+	return SuccessResult(&TeardownResponse{Results: results})
+}
+
+// teardownConcurrently deletes the named resources of a kind concurrently
+// and returns their results in order.
+func teardownConcurrently(kind string, names []string, del func(string) *OperationResult) []TeardownResult {
+	results := make([]TeardownResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			results[i] = teardownResult(kind, name, del(name))
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// teardownResult converts the result of a delete tool.
+func teardownResult(kind, name string, result *OperationResult) TeardownResult {
+	r := TeardownResult{Kind: kind, Name: name}
+	if !result.Success && (result.Error == nil || result.Error.Code != ErrCodeNotFound) {
+		r.Error = result.Error
+		if r.Error == nil {
+			r.Error = NewProviderError("unknown error", false)
+		}
+	}
+	return r
+}
@@ -0,0 +1,95 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerv1
+
+import (
+	"sync"
+	"testing"
+)
+
+// teardownProvider records delete calls and fails the configured ones.
+type teardownProvider struct {
+	fakeResourceProvider
+	mu      sync.Mutex
+	deleted []string
+	fail    map[string]*OperationError
+}
+
+func (p *teardownProvider) del(kind, name string) *OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleted = append(p.deleted, kind+"/"+name)
+	if err, ok := p.fail[kind+"/"+name]; ok {
+		return ErrorResult(err)
+	}
+	return SuccessResult(nil)
+}
+
+func (p *teardownProvider) VMDelete(name string) *OperationResult { return p.del("vm", name) }
+func (p *teardownProvider) NetworkDelete(name string) *OperationResult {
+	return p.del("network", name)
+}
+func (p *teardownProvider) KeyDelete(name string) *OperationResult { return p.del("key", name) }
+
+func TestRunTeardownDeletesInDependencyOrder(t *testing.T) {
+	p := &teardownProvider{fail: map[string]*OperationError{
+		"vm/vm2":     NewNotFoundError("vm", "vm2"),
+		"network/n1": NewResourceBusyError("network", "n1"),
+	}}
+	req := &TeardownRequest{
+		VMs:      []string{"vm1", "vm2"},
+		Networks: []string{"n2", "n1"},
+		Keys:     []string{"k1"},
+	}
+
+	result := RunTeardown(req, p)
+	if !result.Success {
+		t.Fatalf("RunTeardown() failed: %v", result.Error)
+	}
+	resp := result.Resource.(*TeardownResponse)
+	if len(resp.Results) != 5 {
+		t.Fatalf("got %d results, want 5", len(resp.Results))
+	}
+
+	// VMs first, then networks in request order, then keys.
+	position := make(map[string]int)
+	for i, d := range p.deleted {
+		position[d] = i
+	}
+	for _, vm := range []string{"vm/vm1", "vm/vm2"} {
+		if position[vm] > position["network/n2"] {
+			t.Errorf("%s deleted after networks: %v", vm, p.deleted)
+		}
+	}
+	if position["network/n2"] > position["network/n1"] || position["network/n1"] > position["key/k1"] {
+		t.Errorf("deletion order = %v", p.deleted)
+	}
+
+	for _, r := range resp.Results {
+		failed := r.Kind == "network" && r.Name == "n1"
+		if (r.Error != nil) != failed {
+			t.Errorf("result %s/%s error = %v, want failure %v", r.Kind, r.Name, r.Error, failed)
+		}
+	}
+}
+
+func TestRunTeardownRejectsTooManyResources(t *testing.T) {
+	req := &TeardownRequest{VMs: make([]string, MaxBatchCalls+1)}
+	if result := RunTeardown(req, &teardownProvider{}); result.Success {
+		t.Error("RunTeardown() succeeded with too many resources")
+	}
+}
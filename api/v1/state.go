@@ -20,16 +20,24 @@ const (
 	StatusPending    = "pending"
 	StatusCreating   = "creating"
 	StatusReady      = "ready"
+	StatusUpdating   = "updating"
 	StatusFailed     = "failed"
 	StatusDestroying = "destroying"
 	StatusDestroyed  = "destroyed"
+	// StatusDrifted and StatusMissing mark resources that reconciliation
+	// found changed or gone at their provider.
+	StatusDrifted = "drifted"
+	StatusMissing = "missing"
 )
 
 // EnvironmentState represents the persisted state of a test environment.
 // It is stored as JSON on disk for reliable cleanup across restarts.
 type EnvironmentState struct {
-	// ID is the unique test environment identifier (testID).
+	// ID is the unique test environment identifier. It equals the forge testID
+	// unless the spec requests an explicit environment ID or naming template.
 	ID string `json:"id"`
+	// TestID is the forge testID that created this environment.
+	TestID string `json:"testID,omitempty"`
 	// Stage is the test stage name.
 	Stage string `json:"stage"`
 	// Status is the current environment status.
@@ -40,6 +48,15 @@ type EnvironmentState struct {
 	UpdatedAt string `json:"updatedAt"`
 	// Spec is the original Spec (for reference during cleanup).
 	Spec *Spec `json:"spec,omitempty"`
+	// SpecSource records where the spec was fetched from when it was created
+	// from spec.specRef.
+	SpecSource *SpecSource `json:"specSource,omitempty"`
+	// Seed derives the MAC addresses and UUIDs of the VMs. It is taken from
+	// spec.seed, or generated, so that passing it back reproduces them.
+	Seed string `json:"seed,omitempty"`
+	// CIDRs maps the networks with cidr auto to the subnets allocated to
+	// them from the CIDR pool of the host.
+	CIDRs map[string]string `json:"cidrs,omitempty"`
 	// Resources contains all resource states organized by type.
 	Resources ResourceMap `json:"resources"`
 	// ExecutionPlan contains the phases for resource creation/deletion.
@@ -50,6 +67,17 @@ type EnvironmentState struct {
 	ArtifactDir string `json:"artifactDir,omitempty"`
 }
 
+// SpecSource identifies the spec an environment was created from when it was
+// fetched from a git reference.
+type SpecSource struct {
+	// Ref is the spec.specRef as requested.
+	Ref string `json:"ref"`
+	// Commit is the commit the ref resolved to.
+	Commit string `json:"commit"`
+	// SHA256 is the hex-encoded digest of the fetched spec file.
+	SHA256 string `json:"sha256"`
+}
+
 // ResourceMap contains all resource states organized by type.
 type ResourceMap struct {
 	// Keys contains key resource states keyed by resource name.
@@ -58,6 +86,8 @@ type ResourceMap struct {
 	Networks map[string]*ResourceState `json:"networks,omitempty"`
 	// VMs contains VM resource states keyed by resource name.
 	VMs map[string]*ResourceState `json:"vms,omitempty"`
+	// Services contains service resource states keyed by resource name.
+	Services map[string]*ResourceState `json:"services,omitempty"`
 }
 
 // ResourceState represents the state of an individual resource.
@@ -74,6 +104,14 @@ type ResourceState struct {
 	UpdatedAt string `json:"updatedAt,omitempty"`
 	// Error contains the last error message if status is failed.
 	Error string `json:"error,omitempty"`
+	// Hashes are content hashes of the rendered resource, keyed by section
+	// (e.g. "cloudInit", "domain"). Plan compares them to detect changed
+	// specs of existing resources.
+	Hashes map[string]string `json:"hashes,omitempty"`
+	// Owner is the ID of the parent environment owning a key or network
+	// this environment uses. Such resources are copied from the parent's
+	// state and are neither created nor deleted with this environment.
+	Owner string `json:"owner,omitempty"`
 }
 
 // ExecutionPlan contains the phases for resource creation/deletion.
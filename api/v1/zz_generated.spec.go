@@ -1,6 +1,6 @@
 // Code generated by forge-dev. DO NOT EDIT.
 // Source: spec.openapi.yaml
-// SourceChecksum: sha256:0c6ad4ca4d559fad64c32bfc433bfb835942c6ddbf253b20d3f3fcc9ffe4c5e1
+// SourceChecksum: sha256:1653181066b46853d741e9ed29dde8b106c5ea0a681b885bbaf5cf6c4b6f9446
 
 package v1
 
@@ -8,6 +8,43 @@ import (
 	"fmt"
 )
 
+// AccessSpec represents the AccessSpec configuration.
+// Access point configuration.
+type AccessSpec struct {
+	// VPN CIDR. The first host is the server, the second the client. Defaults to 10.201.0.0/24.
+	Address string `json:"address,omitempty"`
+	// Host or host:port the client dials, e.g. a port forwarded on the remote hypervisor. Defaults to the server VM IP. Rendered after all resources are created.
+	Endpoint string `json:"endpoint,omitempty"`
+	// UDP port the server listens on. Defaults to 51820.
+	ListenPort int `json:"listenPort,omitempty"`
+	// Network resource made reachable to the client.
+	Network string `json:"network"`
+	// Access type. Only wireguard is supported (default).
+	Type string `json:"type,omitempty"`
+	// VM resource on the network that runs the WireGuard server. Its cloud-init is extended to install and start it.
+	Vm string `json:"vm"`
+}
+
+// BootArtifactSpec represents the BootArtifactSpec configuration.
+// File downloaded into the boot root of a network.
+type BootArtifactSpec struct {
+	// Path of the file relative to the boot root (e.g., images/vmlinuz).
+	Path string `json:"path,omitempty"`
+	// Expected SHA256 checksum of the file. Recommended, as cached downloads are otherwise trusted as is.
+	Sha256 string `json:"sha256,omitempty"`
+	// HTTPS URL the file is downloaded from.
+	Url string `json:"url,omitempty"`
+}
+
+// BootFileSpec represents the BootFileSpec configuration.
+// File rendered into the boot root of a network. Templates get .Network, .Server (the gateway address serving the boot files), .VMs and, for per-VM files, .VM, each VM having .Name, .MAC, .MACHyphen (52-54-00-...) and .IP (static IP, if any).
+type BootFileSpec struct {
+	// Content of the file.
+	Content string `json:"content,omitempty"`
+	// Path of the file relative to the boot root, e.g. pxelinux.cfg/01-[[ .VM.MACHyphen ]] or boot-[[ .VM.Name ]].ipxe.
+	Path string `json:"path,omitempty"`
+}
+
 // BootSpec represents the BootSpec configuration.
 // Boot options configuration.
 type BootSpec struct {
@@ -17,6 +54,36 @@ type BootSpec struct {
 	Order []string `json:"order"`
 }
 
+// CASpec represents the CASpec configuration.
+// Test certificate authority generated by the orchestrator for each environment that declares it or certificates. Its certificate and key are written to certs/ca.crt and certs/ca.key of the artifact directory and exposed as {{ .CA.Cert }}, {{ .CA.Key }}, {{ .CA.CertPath }} and {{ .CA.KeyPath }}.
+type CASpec struct {
+	// Common name of the CA certificate. Defaults to "testenv-vm test CA".
+	CommonName string `json:"commonName,omitempty"`
+	// Validity of the CA certificate as a Go duration (e.g. "720h"). Defaults to 8760h.
+	Validity string `json:"validity,omitempty"`
+	// VMs trusting the CA, which is added to their system trust store through cloud-init. VMs receiving a certificate trust it too.
+	Vms []string `json:"vms,omitempty"`
+}
+
+// CertificateSpec represents the CertificateSpec configuration.
+// Certificate configuration. SANs may use templates, e.g. {{ .VMs.web.IP }}; the certificate is then issued once the VM exists.
+type CertificateSpec struct {
+	// Issue a client certificate (extended key usage clientAuth) instead of a server certificate.
+	Client bool `json:"client,omitempty"`
+	// Common name of the certificate. Defaults to the first DNS name, then the resource name.
+	CommonName string `json:"commonName,omitempty"`
+	// DNS subject alternative names.
+	DnsNames []string `json:"dnsNames,omitempty"`
+	// IP subject alternative names.
+	IpAddresses []string `json:"ipAddresses,omitempty"`
+	// Directory of the files installed in the VM. Defaults to /etc/testenv-vm/certs.
+	Path string `json:"path,omitempty"`
+	// Validity of the certificate as a Go duration (e.g. "24h"). Defaults to 720h.
+	Validity string `json:"validity,omitempty"`
+	// VM the certificate, its key and the CA certificate are installed in through cloud-init, as <name>.crt, <name>.key and ca.crt under path. The certificate then cannot use templates referring to this VM.
+	Vm string `json:"vm,omitempty"`
+}
+
 // CloudInitNameservers represents the CloudInitNameservers configuration.
 // DNS server configuration.
 type CloudInitNameservers struct {
@@ -38,6 +105,8 @@ type CloudInitReadinessSpec struct {
 type UserSpec struct {
 	// Username.
 	Name string `json:"name"`
+	// Login shell (default /bin/bash). Images without bash, such as testenv:minimal, need /bin/sh.
+	Shell string `json:"shell,omitempty"`
 	// Public keys to add to authorized_keys.
 	SshAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
 	// Sudo rules (e.g., ALL=(ALL) NOPASSWD:ALL).
@@ -55,39 +124,110 @@ type WriteFileSpec struct {
 	Permissions string `json:"permissions,omitempty"`
 }
 
-// DHCPSpec represents the DHCPSpec configuration.
-// DHCP server configuration.
-type DHCPSpec struct {
-	// DNS servers to advertise via DHCP.
-	DnsServers []string `json:"dnsServers,omitempty"`
-	// Enables DHCP.
-	Enabled bool `json:"enabled,omitempty"`
-	// Lease time duration (e.g., 12h).
-	LeaseTime string `json:"leaseTime,omitempty"`
-	// Last IP in DHCP range. Required when enabled is true.
-	RangeEnd string `json:"rangeEnd,omitempty"`
-	// First IP in DHCP range. Required when enabled is true.
-	RangeStart string `json:"rangeStart,omitempty"`
-	// Custom gateway/router IP to advertise via DHCP. Defaults to network gateway.
-	Router string `json:"router,omitempty"`
+// DHCPHostSpec represents the DHCPHostSpec configuration.
+// DHCP reservation of an IP for a MAC address.
+type DHCPHostSpec struct {
+	// Hostname handed to the client and resolved by the network DNS.
+	Hostname string `json:"hostname,omitempty"`
+	// IPv4 address handed to the client, within the network cidr.
+	Ip string `json:"ip"`
+	// MAC address of the client (e.g., 52:54:00:12:34:56).
+	Mac string `json:"mac"`
 }
 
-// DNSSpec represents the DNSSpec configuration.
-// DNS forwarding configuration.
-type DNSSpec struct {
-	// Enables DNS forwarding.
-	Enabled bool `json:"enabled,omitempty"`
-	// DNS servers to forward to.
-	Servers []string `json:"servers,omitempty"`
+// DNSRecordSpec represents the DNSRecordSpec configuration.
+// DNS record served by a managed network.
+type DNSRecordSpec struct {
+	// Fully qualified name of the record (e.g., api.example.com).
+	Name string `json:"name,omitempty"`
+	// Record type: A, AAAA, CNAME or TXT. Defaults to A.
+	Type string `json:"type,omitempty"`
+	// IPv4 address (A), IPv6 address (AAAA), target name (CNAME) or text (TXT).
+	Value string `json:"value,omitempty"`
 }
 
-// DiskSpec represents the DiskSpec configuration.
-// VM disk configuration.
-type DiskSpec struct {
+// DataDiskSpec represents the DataDiskSpec configuration.
+// Data disk of a VM, attached after its boot disk.
+type DataDiskSpec struct {
+	// Image the disk starts from, backing a qcow2 disk or copied into a raw one. Unset creates an empty disk.
+	BaseImage string `json:"baseImage,omitempty"`
+	// Image format: qcow2 (default) or raw.
+	Format string `json:"format,omitempty"`
+	// Disk size (e.g., 100G).
+	Size string `json:"size"`
+}
+
+// DiskDefaultsSpec represents the DiskDefaultsSpec configuration.
+// Disk defaults for every VM.
+type DiskDefaultsSpec struct {
 	// Path/URL to base image (QCOW2, AMI, etc.).
 	BaseImage string `json:"baseImage,omitempty"`
 	// Disk size (e.g., 20G).
-	Size string `json:"size"`
+	Size string `json:"size,omitempty"`
+}
+
+// SSHReadinessSpec represents the SSHReadinessSpec configuration.
+// SSH readiness check configuration.
+type SSHReadinessSpec struct {
+	// Enables SSH readiness check.
+	Enabled bool `json:"enabled"`
+	// Private key path (can use template).
+	PrivateKey string `json:"privateKey,omitempty"`
+	// Jump host in OpenSSH ProxyJump form [user@]host[:port] (e.g., "ubuntu@{{ .VMs.gateway.IP }}"). The user and private key default to the VM ones.
+	ProxyJump string `json:"proxyJump,omitempty"`
+	// Timeout for SSH to become available (e.g., 5m).
+	Timeout string `json:"timeout,omitempty"`
+	// User for SSH connection.
+	User string `json:"user,omitempty"`
+}
+
+// TCPReadinessSpec represents the TCPReadinessSpec configuration.
+// TCP port readiness check configuration.
+type TCPReadinessSpec struct {
+	// Port to check for TCP connectivity.
+	Port int `json:"port"`
+	// Timeout for port to become available (e.g., 5m).
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// DiskEncryptionSpec represents the DiskEncryptionSpec configuration.
+// LUKS encryption of the VM disk.
+type DiskEncryptionSpec struct {
+	// Creates the disk as a LUKS-encrypted qcow2 volume.
+	Enabled bool `json:"enabled,omitempty"`
+	// Secret reference for the passphrase: env:NAME or file:PATH. Resolved by the provider; the value is never stored.
+	PassphraseSecretRef string `json:"passphraseSecretRef,omitempty"`
+}
+
+// GuestNameserversSpec represents the GuestNameserversSpec configuration.
+// DNS configuration of a guest interface.
+type GuestNameserversSpec struct {
+	// DNS server IP addresses.
+	Addresses []string `json:"addresses,omitempty"`
+	// DNS search domains.
+	Search []string `json:"search,omitempty"`
+}
+
+// GuestRouteSpec represents the GuestRouteSpec configuration.
+// Static route of a guest interface.
+type GuestRouteSpec struct {
+	// Metric of the route.
+	Metric int `json:"metric,omitempty"`
+	// Destination in CIDR notation, or default.
+	To string `json:"to"`
+	// IPv4 address of the next hop.
+	Via string `json:"via"`
+}
+
+// IdleWakeSpec represents the IdleWakeSpec configuration.
+// Host proxy waking a VM up.
+type IdleWakeSpec struct {
+	// Host address the proxy listens on, e.g. 127.0.0.1:2222.
+	Listen string `json:"listen"`
+	// Guest port connections are forwarded to. Defaults to sshPort.
+	Port int `json:"port,omitempty"`
+	// Name of the VM to wake up.
+	Vm string `json:"vm"`
 }
 
 // ImageCustomizeSpec represents the ImageCustomizeSpec configuration.
@@ -112,15 +252,54 @@ type KeySpec struct {
 	Type string `json:"type"`
 }
 
-// TFTPSpec represents the TFTPSpec configuration.
-// TFTP server configuration for PXE boot.
-type TFTPSpec struct {
-	// Default boot file (e.g., undionly.kpxe). Required when enabled is true.
-	BootFile string `json:"bootFile,omitempty"`
-	// Enables TFTP server.
+// MdevSpec represents the MdevSpec configuration.
+// A mediated device: an existing one, by uuid, or one created from a type when the VM is created and removed with it.
+type MdevSpec struct {
+	// PCI address of the parent device creating a device of type. Defaults to the first parent with an available instance of the type.
+	Parent string `json:"parent,omitempty"`
+	// Mediated device type to create, as listed in /sys/class/mdev_bus/<parent>/mdev_supported_types (e.g. nvidia-63, i915-GVTg_V5_4).
+	Type string `json:"type,omitempty"`
+	// UUID of an existing mediated device, e.g. created with mdevctl. Cannot be combined with type.
+	Uuid string `json:"uuid,omitempty"`
+}
+
+// NTPSpec represents the NTPSpec configuration.
+// NTP server run on the network gateway, giving guests of isolated networks a time source.
+type NTPSpec struct {
+	// Runs an NTP server on the network gateway and points the guests of the network at it.
 	Enabled bool `json:"enabled,omitempty"`
-	// Directory for TFTP files. Required when enabled is true.
-	Root string `json:"root,omitempty"`
+	// Upstream NTP servers the network server synchronizes with. Empty serves the host clock.
+	Servers []string `json:"servers,omitempty"`
+}
+
+// NotifierSpec represents the NotifierSpec configuration.
+// Chat notifier sending a human-readable summary of lifecycle events.
+type NotifierSpec struct {
+	// Matrix only: environment variable holding the access token. Defaults to TESTENV_VM_MATRIX_ACCESS_TOKEN.
+	AccessTokenEnv string `json:"accessTokenEnv,omitempty"`
+	// Link to CI artifacts or logs included in the summary.
+	ArtifactUrl string `json:"artifactUrl,omitempty"`
+	// Events to deliver: queued, ready, failed, destroyed. Defaults to failed.
+	Events []string `json:"events,omitempty"`
+	// Matrix only: homeserver base URL. Defaults to $TESTENV_VM_MATRIX_HOMESERVER.
+	Homeserver string `json:"homeserver,omitempty"`
+	// Matrix only: room ID to post into. Defaults to $TESTENV_VM_MATRIX_ROOM_ID.
+	RoomId string `json:"roomId,omitempty"`
+	// Notifier backend: slack or matrix.
+	Type string `json:"type"`
+	// Slack only: environment variable holding the incoming webhook URL. Defaults to TESTENV_VM_SLACK_WEBHOOK_URL.
+	WebhookUrlEnv string `json:"webhookUrlEnv,omitempty"`
+}
+
+// ParentSpec represents the ParentSpec configuration.
+// Ready environment whose keys and networks this environment uses read-only, e.g. to attach a short-lived VM to a long-lived lab. Listed resources are referenced by name like resources of this environment; they are neither created nor deleted with it, and the parent cannot be deleted while it has children.
+type ParentSpec struct {
+	// ID of the parent environment. It must exist and be ready when this environment is created. Required when keys or networks are listed.
+	EnvironmentId string `json:"environmentId,omitempty"`
+	// Keys of the parent used by this environment. They must not share a name with keys of this environment.
+	Keys []string `json:"keys,omitempty"`
+	// Networks of the parent used by this environment. They must not share a name with networks of this environment.
+	Networks []string `json:"networks,omitempty"`
 }
 
 // ProviderConfig represents the ProviderConfig configuration.
@@ -136,28 +315,6 @@ type ProviderConfig struct {
 	Spec map[string]interface{} `json:"spec,omitempty"`
 }
 
-// SSHReadinessSpec represents the SSHReadinessSpec configuration.
-// SSH readiness check configuration.
-type SSHReadinessSpec struct {
-	// Enables SSH readiness check.
-	Enabled bool `json:"enabled"`
-	// Private key path (can use template).
-	PrivateKey string `json:"privateKey,omitempty"`
-	// Timeout for SSH to become available (e.g., 5m).
-	Timeout string `json:"timeout,omitempty"`
-	// User for SSH connection.
-	User string `json:"user,omitempty"`
-}
-
-// TCPReadinessSpec represents the TCPReadinessSpec configuration.
-// TCP port readiness check configuration.
-type TCPReadinessSpec struct {
-	// Port to check for TCP connectivity.
-	Port int `json:"port"`
-	// Timeout for port to become available (e.g., 5m).
-	Timeout string `json:"timeout,omitempty"`
-}
-
 // ResourceRef represents the ResourceRef configuration.
 // Uniquely identifies a resource.
 type ResourceRef struct {
@@ -169,6 +326,138 @@ type ResourceRef struct {
 	Provider string `json:"provider,omitempty"`
 }
 
+// ServiceSpec represents the ServiceSpec configuration.
+// Service configuration.
+type ServiceSpec struct {
+	// Access key of object-store (at least 3 characters). Generated when empty.
+	AccessKey string `json:"accessKey,omitempty"`
+	// Network resource whose gateway address the service listens on.
+	Network string `json:"network"`
+	// TCP port of the service. Defaults to 5000 for registry-mirror, 3142 for apt-cache, 8080 for http-file-server, 9000 for object-store and 5140 for log-collector.
+	Port int `json:"port,omitempty"`
+	// Absolute path of the host directory served by http-file-server. Required for that type.
+	Root string `json:"root,omitempty"`
+	// Secret key of object-store (at least 8 characters). Generated when empty.
+	SecretKey string `json:"secretKey,omitempty"`
+	// Service type: registry-mirror (distribution registry as a pull-through cache), apt-cache (apt-cacher-ng), http-file-server (python3 http.server), object-store (MinIO, S3-compatible) or log-collector (rsyslogd receiving the syslog of the VMs of the network into guest-logs/<name>.log of the artifact directory).
+	Type string `json:"type"`
+	// Registry mirrored by registry-mirror. Defaults to https://registry-1.docker.io.
+	Upstream string `json:"upstream,omitempty"`
+}
+
+// TunnelSpec represents the TunnelSpec configuration.
+// Tunnel configuration.
+type TunnelSpec struct {
+	// Tunnel transfer CIDR. The first host is the local end, the second the remote end. Defaults to 10.200.0.0/30.
+	Address string `json:"address,omitempty"`
+	// Public host or host:port of the remote gateway (e.g. "203.0.113.10"). When empty, LocalConfig has no Endpoint and callers must add one.
+	Endpoint string `json:"endpoint,omitempty"`
+	// UDP port the remote gateway listens on. Defaults to 51820.
+	ListenPort int `json:"listenPort,omitempty"`
+	// Network resource on the local side (e.g. a libvirt network).
+	LocalNetwork string `json:"localNetwork"`
+	// Keepalive interval in seconds sent by the local end. Defaults to 25.
+	PersistentKeepalive int `json:"persistentKeepalive,omitempty"`
+	// Network resource on the remote side (e.g. a cloud VPC).
+	RemoteNetwork string `json:"remoteNetwork"`
+	// Tunnel type. Only wireguard is supported (default).
+	Type string `json:"type,omitempty"`
+}
+
+// VMAgentSpec represents the VMAgentSpec configuration.
+// Guest agent injected through cloud-init. It runs commands, transfers files and reports resource usage over HTTP without SSH, for minimal images. Requires the agentBinary setting (TESTENV_VM_AGENT_BINARY).
+type VMAgentSpec struct {
+	// Injects and starts the guest agent.
+	Enabled bool `json:"enabled,omitempty"`
+	// TCP port the agent listens on in the guest. Defaults to 10050.
+	Port int `json:"port,omitempty"`
+}
+
+// VsockSpec represents the VsockSpec configuration.
+// virtio-vsock device giving a host-guest channel independent of guest networking. The guest agent also listens on it.
+type VsockSpec struct {
+	// Context ID of the guest, at least 3 and unique on the host. Unset lets the hypervisor pick a free one; the assigned CID is recorded in the VM state.
+	Cid int `json:"cid,omitempty"`
+}
+
+// VMNICSpec represents the VMNICSpec configuration.
+// Options of the NIC of a VM attached to one of its networks.
+type VMNICSpec struct {
+	// Offloads turned off in the guest: tso, gso, gro or lro. With networkConfig, the mtu and offloads apply to the ethernet naming the network, which is required.
+	DisableOffloads []string `json:"disableOffloads,omitempty"`
+	// NIC model: virtio (default) or e1000.
+	Model string `json:"model,omitempty"`
+	// MTU configured in the guest, at most the MTU of the network. Defaults to the MTU of the network.
+	Mtu int `json:"mtu,omitempty"`
+	// Name of the network, one of the networks of the VM.
+	Network string `json:"network"`
+}
+
+// VMSecuritySpec represents the VMSecuritySpec configuration.
+// Security driver (sVirt) options for the VM. Unset keeps the hypervisor default confinement.
+type VMSecuritySpec struct {
+	// Disables the security driver for this VM (seclabel type none). Intended for debugging only.
+	Disabled bool `json:"disabled,omitempty"`
+	// Static security label (SELinux context or AppArmor profile name). Unset uses a dynamic label.
+	Label string `json:"label,omitempty"`
+	// Security driver model: selinux or apparmor. Defaults to the host driver.
+	Model string `json:"model,omitempty"`
+	// Relabels image files with a static label. Ignored for dynamic labels.
+	Relabel bool `json:"relabel,omitempty"`
+}
+
+// WebhookSpec represents the WebhookSpec configuration.
+// HTTP webhook fired on environment lifecycle transitions.
+type WebhookSpec struct {
+	// Events to deliver: queued, ready, failed, destroyed. Empty means all events.
+	Events []string `json:"events,omitempty"`
+	// Extra HTTP headers sent with each request.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Maximum number of retries after a failed delivery. Defaults to 3.
+	MaxRetries int `json:"maxRetries,omitempty"`
+	// Name of the environment variable holding the HMAC-SHA256 signing secret. The signature is sent in the X-Testenv-Signature header.
+	SecretEnv string `json:"secretEnv,omitempty"`
+	// Per-request timeout as a Go duration. Defaults to 10s.
+	Timeout string `json:"timeout,omitempty"`
+	// Endpoint receiving a JSON POST for each event.
+	Url string `json:"url"`
+}
+
+// AccessResource represents the AccessResource configuration.
+// Access resource provisioning a WireGuard server on a VM. The client config is written to the artifact directory as wireguard-<name>.conf.
+type AccessResource struct {
+	// Unique identifier for this access point.
+	Name string     `json:"name"`
+	Spec AccessSpec `json:"spec"`
+}
+
+// TFTPSpec represents the TFTPSpec configuration.
+// TFTP server configuration for PXE boot.
+type TFTPSpec struct {
+	// Files downloaded into the boot root before the network is created, such as kernels, initrds and iPXE binaries. Downloads are cached in the state directory. Setting artifacts or files makes the orchestrator build the boot root in the environment directory, starting from a copy of root when set.
+	Artifacts []BootArtifactSpec `json:"artifacts,omitempty"`
+	// Default boot file (e.g., undionly.kpxe). Required when enabled is true.
+	BootFile string `json:"bootFile,omitempty"`
+	// Boot file of UEFI clients (e.g., ipxe.efi). Defaults to bootFile.
+	BootFileEfi string `json:"bootFileEfi,omitempty"`
+	// Further DHCP options sent to network boot clients, keyed by option code (e.g., "209" for the pxelinux configuration file).
+	DhcpBootOptions map[string]string `json:"dhcpBootOptions,omitempty"`
+	// Enables TFTP server.
+	Enabled bool `json:"enabled,omitempty"`
+	// Files rendered into the boot root once the network exists, such as iPXE scripts and pxelinux configurations. Content and path are Go templates with [[ ]] delimiters; a file whose path is a template is rendered once per VM of the network.
+	Files []BootFileSpec `json:"files,omitempty"`
+	// Directory for TFTP files. Required when enabled is true, unless artifacts or files are set.
+	Root string `json:"root,omitempty"`
+}
+
+// CertificateResource represents the CertificateResource configuration.
+// Leaf certificate resource signed by the test CA of the environment.
+type CertificateResource struct {
+	// Unique identifier for this certificate.
+	Name string          `json:"name"`
+	Spec CertificateSpec `json:"spec"`
+}
+
 // CloudInitEthernetConfig represents the CloudInitEthernetConfig configuration.
 // Single ethernet interface configuration.
 type CloudInitEthernetConfig struct {
@@ -183,6 +472,137 @@ type CloudInitEthernetConfig struct {
 	Nameservers CloudInitNameservers `json:"nameservers,omitempty"`
 }
 
+// DHCPSpec represents the DHCPSpec configuration.
+// DHCP server configuration.
+type DHCPSpec struct {
+	// DNS servers to advertise via DHCP.
+	DnsServers []string `json:"dnsServers,omitempty"`
+	// Enables DHCP.
+	Enabled bool `json:"enabled,omitempty"`
+	// Reservations handing a fixed IP to a MAC address. Addresses are rewritten for isolation like the network cidr. VMs with a static ip are reserved automatically.
+	Hosts []DHCPHostSpec `json:"hosts,omitempty"`
+	// Lease time duration (e.g., 12h).
+	LeaseTime string `json:"leaseTime,omitempty"`
+	// Last IP in DHCP range. Required when enabled is true.
+	RangeEnd string `json:"rangeEnd,omitempty"`
+	// First IP in DHCP range. Required when enabled is true.
+	RangeStart string `json:"rangeStart,omitempty"`
+	// Custom gateway/router IP to advertise via DHCP. Defaults to network gateway.
+	Router string `json:"router,omitempty"`
+}
+
+// DNSSpec represents the DNSSpec configuration.
+// DNS forwarding configuration.
+type DNSSpec struct {
+	// Enables DNS forwarding.
+	Enabled bool `json:"enabled,omitempty"`
+	// Records served by the network DNS server, overriding upstream answers, e.g. to point api.example.com at a mock server of the environment.
+	Records []DNSRecordSpec `json:"records,omitempty"`
+	// DNS servers to forward to.
+	Servers []string `json:"servers,omitempty"`
+}
+
+// ReadinessSpec represents the ReadinessSpec configuration.
+// Readiness checks configuration.
+type ReadinessSpec struct {
+	CloudInit CloudInitReadinessSpec `json:"cloudInit,omitempty"`
+	Ssh       SSHReadinessSpec       `json:"ssh,omitempty"`
+	Tcp       TCPReadinessSpec       `json:"tcp,omitempty"`
+}
+
+// DiskSpec represents the DiskSpec configuration.
+// VM disk configuration.
+type DiskSpec struct {
+	// Path/URL to base image (QCOW2, AMI, etc.).
+	BaseImage  string              `json:"baseImage,omitempty"`
+	Encryption *DiskEncryptionSpec `json:"encryption,omitempty"`
+	// Trims the guest filesystems at the end of cloud-init and enables the weekly fstrim.timer, so that the blocks the guest frees are released from the host image.
+	Fstrim bool `json:"fstrim,omitempty"`
+	// Disk size (e.g., 20G).
+	Size string `json:"size"`
+}
+
+// GuestBondSpec represents the GuestBondSpec configuration.
+// Bond of a guest.
+type GuestBondSpec struct {
+	// Static IP addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Enables DHCP for IPv4. Defaults to true when addresses is empty.
+	Dhcp4 bool `json:"dhcp4,omitempty"`
+	// IPv4 default gateway.
+	Gateway4 string `json:"gateway4,omitempty"`
+	// Names of the ethernet interfaces of the bond.
+	Interfaces []string `json:"interfaces"`
+	// Bonding mode: balance-rr, active-backup (default), balance-xor, broadcast, 802.3ad, balance-tlb or balance-alb.
+	Mode string `json:"mode,omitempty"`
+	// MTU of the bond.
+	Mtu int `json:"mtu,omitempty"`
+	// Name of the bond interface, e.g. bond0.
+	Name        string               `json:"name"`
+	Nameservers GuestNameserversSpec `json:"nameservers,omitempty"`
+	// Static routes.
+	Routes []GuestRouteSpec `json:"routes,omitempty"`
+}
+
+// GuestEthernetSpec represents the GuestEthernetSpec configuration.
+// Ethernet interface of a guest.
+type GuestEthernetSpec struct {
+	// Static IP addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Enables DHCP for IPv4. Defaults to true when addresses is empty, except for bond members and vlan links.
+	Dhcp4 bool `json:"dhcp4,omitempty"`
+	// IPv4 default gateway.
+	Gateway4 string `json:"gateway4,omitempty"`
+	// Interface name pattern matched instead of name, e.g. en*.
+	Match string `json:"match,omitempty"`
+	// MTU of the interface.
+	Mtu int `json:"mtu,omitempty"`
+	// Name of the interface, referenced by bonds and vlans. It matches the interface of that name unless network or match is set.
+	Name        string               `json:"name"`
+	Nameservers GuestNameserversSpec `json:"nameservers,omitempty"`
+	// Name of one of the networks of the VM; the interface is the NIC attached to it, matched by MAC address.
+	Network string `json:"network,omitempty"`
+	// Static routes.
+	Routes []GuestRouteSpec `json:"routes,omitempty"`
+}
+
+// GuestVLANSpec represents the GuestVLANSpec configuration.
+// VLAN interface of a guest.
+type GuestVLANSpec struct {
+	// Static IP addresses in CIDR notation.
+	Addresses []string `json:"addresses,omitempty"`
+	// Enables DHCP for IPv4. Defaults to true when addresses is empty.
+	Dhcp4 bool `json:"dhcp4,omitempty"`
+	// IPv4 default gateway.
+	Gateway4 string `json:"gateway4,omitempty"`
+	// VLAN ID, from 1 to 4094.
+	Id int `json:"id"`
+	// Name of the ethernet interface or bond carrying the VLAN.
+	Link string `json:"link"`
+	// MTU of the VLAN interface.
+	Mtu int `json:"mtu,omitempty"`
+	// Name of the VLAN interface, e.g. vlan10.
+	Name        string               `json:"name"`
+	Nameservers GuestNameserversSpec `json:"nameservers,omitempty"`
+	// Static routes.
+	Routes []GuestRouteSpec `json:"routes,omitempty"`
+}
+
+// IdleSpec represents the IdleSpec configuration.
+// Idle policy powering down the VMs of a sandbox that nobody uses, applied by `testenv-vmctl idle run` or the MCP server started with --idle. A running VM is idle while no SSH session is open to it from the host and its CPU usage stays below cpuPercent.
+type IdleSpec struct {
+	// How idle VMs are powered down: stop shuts the guest down, save writes its memory to disk (the provider must support the save power action) so that it resumes where it was. Defaults to stop.
+	Action string `json:"action,omitempty"`
+	// Time a VM must stay idle before it is powered down, as a Go duration (e.g. 30m). Unset disables the policy.
+	After string `json:"after,omitempty"`
+	// CPU usage, relative to all vCPUs of the VM, under which it may be idle. Defaults to 5.
+	CpuPercent int `json:"cpuPercent,omitempty"`
+	// Guest port whose established connections from the host count as SSH sessions. Defaults to 22.
+	SshPort int `json:"sshPort,omitempty"`
+	// Host proxies starting their VM when a client connects to them, then forwarding the connection. Connections through a proxy count as sessions.
+	Wake []IdleWakeSpec `json:"wake,omitempty"`
+}
+
 // ImageSpec represents the ImageSpec configuration.
 // Image-specific configuration.
 type ImageSpec struct {
@@ -207,35 +627,87 @@ type KeyResource struct {
 	Spec         KeySpec                `json:"spec"`
 }
 
+// ServiceResource represents the ServiceResource configuration.
+// Service run on the host for the lifetime of the environment, listening on the gateway address of a managed network.
+type ServiceResource struct {
+	// Unique identifier for this service.
+	Name string      `json:"name"`
+	Spec ServiceSpec `json:"spec"`
+}
+
+// TunnelResource represents the TunnelResource configuration.
+// Tunnel resource bridging two networks. Keys and configs are generated by the orchestrator and exposed as {{ .Tunnels.<name>.<Field> }}.
+type TunnelResource struct {
+	// Unique identifier for this tunnel.
+	Name string     `json:"name"`
+	Spec TunnelSpec `json:"spec"`
+}
+
+// VMDevicesSpec represents the VMDevicesSpec configuration.
+// Additional devices of the VM.
+type VMDevicesSpec struct {
+	// Mediated devices (vGPUs) attached to the VM, e.g. NVIDIA GRID or Intel GVT-g slices of a host GPU.
+	Mdevs []MdevSpec `json:"mdevs,omitempty"`
+	// Host PCI devices passed through to the VM with VFIO, by address (e.g. 0000:01:00.0 or 01:00.0), such as GPUs or NICs. libvirt detaches them from their host driver while the VM runs. Requires the IOMMU enabled on the host, and a device cannot be passed to two VMs.
+	PciPassthrough []string   `json:"pciPassthrough,omitempty"`
+	Vsock          *VsockSpec `json:"vsock,omitempty"`
+}
+
+// CloudInitNetworkConfig represents the CloudInitNetworkConfig configuration.
+// Cloud-init network settings using netplan version 2 format.
+type CloudInitNetworkConfig struct {
+	// Ethernet interface configurations.
+	Ethernets []CloudInitEthernetConfig `json:"ethernets,omitempty"`
+}
+
+// NetworkDefaultsSpec represents the NetworkDefaultsSpec configuration.
+// Defaults for every network.
+type NetworkDefaultsSpec struct {
+	Dhcp *DHCPSpec `json:"dhcp,omitempty"`
+	Dns  *DNSSpec  `json:"dns,omitempty"`
+	// Network type: bridge, libvirt, dnsmasq, vpc, subnet, security-group.
+	Kind string `json:"kind,omitempty"`
+	// Maximum transmission unit size.
+	Mtu int `json:"mtu,omitempty"`
+}
+
 // NetworkSpec represents the NetworkSpec configuration.
 // Network-specific configuration.
 type NetworkSpec struct {
 	// References another network resource (for layered networks).
 	AttachTo string `json:"attachTo,omitempty"`
-	// Network CIDR (e.g., 192.168.100.1/24).
+	// Network CIDR (e.g., 192.168.100.1/24), or auto to allocate a /24 from the CIDR pool of the host.
 	Cidr string    `json:"cidr,omitempty"`
 	Dhcp *DHCPSpec `json:"dhcp,omitempty"`
 	Dns  *DNSSpec  `json:"dns,omitempty"`
 	// Gateway IP address.
 	Gateway string `json:"gateway,omitempty"`
-	// Maximum transmission unit size.
+	// Maximum transmission unit size, from 68 to 9216 for jumbo frames. Defaults to 1500. The NICs of the VMs attached to the network inherit it.
 	Mtu  int       `json:"mtu,omitempty"`
+	Ntp  *NTPSpec  `json:"ntp,omitempty"`
 	Tftp *TFTPSpec `json:"tftp,omitempty"`
 }
 
-// ReadinessSpec represents the ReadinessSpec configuration.
-// Readiness checks configuration.
-type ReadinessSpec struct {
-	CloudInit CloudInitReadinessSpec `json:"cloudInit,omitempty"`
-	Ssh       SSHReadinessSpec       `json:"ssh,omitempty"`
-	Tcp       TCPReadinessSpec       `json:"tcp,omitempty"`
+// VMDefaultsSpec represents the VMDefaultsSpec configuration.
+// Defaults for every VM.
+type VMDefaultsSpec struct {
+	Disk DiskDefaultsSpec `json:"disk,omitempty"`
+	// Memory in MB.
+	Memory    int           `json:"memory,omitempty"`
+	Readiness ReadinessSpec `json:"readiness,omitempty"`
+	// Number of virtual CPUs.
+	Vcpus int `json:"vcpus,omitempty"`
 }
 
-// CloudInitNetworkConfig represents the CloudInitNetworkConfig configuration.
-// Cloud-init network settings using netplan version 2 format.
-type CloudInitNetworkConfig struct {
-	// Ethernet interface configurations.
-	Ethernets []CloudInitEthernetConfig `json:"ethernets,omitempty"`
+// GuestNetworkConfigSpec represents the GuestNetworkConfigSpec configuration.
+// Network configuration of the guest, written as its cloud-init network-config (netplan version 2) instead of DHCP on all interfaces. Addresses, gateways, routes and nameservers are rewritten for isolation like the network cidr. Cannot be set with cloudInit.networkConfig.
+type GuestNetworkConfigSpec struct {
+	// Bonds aggregating ethernet interfaces.
+	Bonds []GuestBondSpec `json:"bonds,omitempty"`
+	// Ethernet interfaces of the guest.
+	Ethernets []GuestEthernetSpec `json:"ethernets,omitempty"`
+	// VLAN interfaces on top of ethernet interfaces or bonds.
+	Vlans []GuestVLANSpec `json:"vlans,omitempty"`
 }
 
 // ImageResource represents the ImageResource configuration.
@@ -246,49 +718,72 @@ type ImageResource struct {
 	Spec ImageSpec `json:"spec"`
 }
 
-// NetworkResource represents the NetworkResource configuration.
-// Network resource.
-type NetworkResource struct {
-	// Network type: bridge, libvirt, dnsmasq, vpc, subnet, security-group.
-	Kind string `json:"kind"`
-	// Unique identifier for this network.
-	Name string `json:"name"`
-	// Name of the provider to use. If empty, uses default provider.
-	Provider string `json:"provider,omitempty"`
-	// Provider-specific configuration.
-	ProviderSpec map[string]interface{} `json:"providerSpec,omitempty"`
-	Spec         NetworkSpec            `json:"spec"`
-}
-
 // CloudInitSpec represents the CloudInitSpec configuration.
 // Cloud-init configuration.
 type CloudInitSpec struct {
+	// Environment variables appended to /etc/environment in the guest, so every login and SSH session sees them. Values may use templates.
+	Environment map[string]string `json:"environment,omitempty"`
 	// Hostname for the VM.
 	Hostname      string                 `json:"hostname,omitempty"`
 	NetworkConfig CloudInitNetworkConfig `json:"networkConfig,omitempty"`
 	// Packages to install.
 	Packages []string `json:"packages,omitempty"`
+	// User-data passed verbatim to cloud-init after template rendering, instead of the user-data generated from users, packages, writeFiles and runcmd, which cannot be set with it. Any format cloud-init reads: #cloud-config, a #! script or a MIME multi-part archive. hostname and networkConfig still apply.
+	RawUserData string `json:"rawUserData,omitempty"`
 	// Commands to run.
 	Runcmd []string `json:"runcmd,omitempty"`
-	// Users to create.
+	// Environment variables whose values are secret references (env:NAME or file:PATH) resolved by the orchestrator when the VM is created. They are not written to /etc/environment: they are copied to the 0600 ~/.ssh/environment of root and of the declared users (the default user when none is declared), so only their SSH sessions see them. Values are redacted from logs and never stored in state.
+	SecretEnvironment map[string]string `json:"secretEnvironment,omitempty"`
+	// Users to create. Defaults to an ubuntu user with a /bin/bash shell, so images without bash, such as testenv:minimal, require users.
 	Users []UserSpec `json:"users,omitempty"`
 	// Files to write.
 	WriteFiles []WriteFileSpec `json:"writeFiles,omitempty"`
 }
 
+// NetworkResource represents the NetworkResource configuration.
+// Network resource.
+type NetworkResource struct {
+	// Network type: bridge, libvirt, dnsmasq, vpc, subnet, security-group.
+	Kind string `json:"kind"`
+	// Unique identifier for this network.
+	Name string `json:"name"`
+	// Name of the provider to use. If empty, uses default provider.
+	Provider string `json:"provider,omitempty"`
+	// Provider-specific configuration.
+	ProviderSpec map[string]interface{} `json:"providerSpec,omitempty"`
+	Spec         NetworkSpec            `json:"spec"`
+}
+
+// DefaultsSpec represents the DefaultsSpec configuration.
+// Values applied during validation to every VM and network that leaves them unset. Values set on a resource take precedence.
+type DefaultsSpec struct {
+	Network NetworkDefaultsSpec `json:"network,omitempty"`
+	Vm      VMDefaultsSpec      `json:"vm,omitempty"`
+}
+
 // VMSpec represents the VMSpec configuration.
 // VM-specific configuration.
 type VMSpec struct {
+	Agent     *VMAgentSpec  `json:"agent,omitempty"`
 	Boot      BootSpec      `json:"boot"`
 	CloudInit CloudInitSpec `json:"cloudInit,omitempty"`
+	Devices   VMDevicesSpec `json:"devices,omitempty"`
 	Disk      DiskSpec      `json:"disk"`
+	// Data disks attached after the boot disk as virtio-blk devices (vdb, vdc, ...), e.g. for Ceph, ZFS or database tests.
+	Disks []DataDiskSpec `json:"disks,omitempty"`
+	// Static IPv4 address of the VM on its first network, which must have a fixed cidr and DHCP enabled. It is rewritten for isolation like the network cidr and reserved for the MAC address of the VM, so that the provider reports it without waiting for a DHCP lease.
+	Ip string `json:"ip,omitempty"`
 	// Memory in MB.
 	Memory int `json:"memory"`
 	// Name of the network resource to attach. Deprecated in favor of networks.
-	Network string `json:"network,omitempty"`
+	Network       string                 `json:"network,omitempty"`
+	NetworkConfig GuestNetworkConfigSpec `json:"networkConfig,omitempty"`
 	// List of network resource names to attach. Takes precedence over network.
-	Networks  []string      `json:"networks,omitempty"`
-	Readiness ReadinessSpec `json:"readiness,omitempty"`
+	Networks []string `json:"networks,omitempty"`
+	// Options of the NICs attached to the networks of the VM.
+	Nics      []VMNICSpec     `json:"nics,omitempty"`
+	Readiness ReadinessSpec   `json:"readiness,omitempty"`
+	Security  *VMSecuritySpec `json:"security,omitempty"`
 	// Number of virtual CPUs.
 	Vcpus int `json:"vcpus"`
 }
@@ -308,28 +803,187 @@ type VMResource struct {
 // Spec represents the Spec configuration.
 // Top-level specification for a test environment.
 type Spec struct {
+	// WireGuard access points giving developers a client config to reach test VMs from their workstation.
+	Access []AccessResource `json:"access,omitempty"`
 	// Directory for storing artifacts (keys, logs, etc.).
 	ArtifactDir string `json:"artifactDir,omitempty"`
+	Ca          CASpec `json:"ca,omitempty"`
+	// TLS certificates issued by the test CA of the environment. Their PEM contents and artifact files are exposed as {{ .Certificates.<name>.<Field> }}.
+	Certificates []CertificateResource `json:"certificates,omitempty"`
 	// Whether to clean up resources on failure. Defaults to true.
 	CleanupOnFailure bool `json:"cleanupOnFailure,omitempty"`
+	// Maximum duration of the whole creation as a Go duration (e.g. 15m), across all phases and distinct from per-resource timeouts. Once exceeded, no further phase starts, the cleanupOnFailure policy applies and the error reports the time spent per resource. Unset means no deadline.
+	CreateDeadline string `json:"createDeadline,omitempty"`
 	// Default base image to use for VMs. Can be a well-known reference or HTTPS URL.
 	DefaultBaseImage string `json:"defaultBaseImage,omitempty"`
 	// Name of the default provider to use when not specified.
-	DefaultProvider string `json:"defaultProvider,omitempty"`
+	DefaultProvider string       `json:"defaultProvider,omitempty"`
+	Defaults        DefaultsSpec `json:"defaults,omitempty"`
+	// Requested environment ID. Overrides the forge testID for state files and resource naming. Must be unique in the state store.
+	EnvironmentId string `json:"environmentId,omitempty"`
+	// Go template rendered to produce the environment ID (e.g. "{{ .Env.CI_PIPELINE_ID }}-{{ .Stage }}"). Available fields are .Env, .Stage and .TestID. Ignored when environmentId is set.
+	EnvironmentIdTemplate string `json:"environmentIdTemplate,omitempty"`
+	// Age after which the environment is expired, as a Go duration (e.g. 2h). With admission preemption enabled in the configuration, expired environments may be destroyed to make room for creations of higher priority.
+	ExpiresAfter string `json:"expiresAfter,omitempty"`
+	// Once all VMs are ready, writes a hosts file mapping the name of every VM to its IP into the artifact directory and into /etc/hosts of every VM (with sudo), so VMs reach each other by name without a DNS server. It is pushed again on update.
+	HostsFile bool     `json:"hostsFile,omitempty"`
+	Idle      IdleSpec `json:"idle,omitempty"`
 	// Directory for caching downloaded VM base images.
 	ImageCacheDir string `json:"imageCacheDir,omitempty"`
 	// VM base images to download and cache.
 	Images []ImageResource `json:"images,omitempty"`
 	// SSH key pair resources to create.
 	Keys []KeyResource `json:"keys,omitempty"`
+	// Labels attached to every key, network and VM created by the providers, next to the testenv-vm.* labels of the orchestrator (environment ID, resource, stage and creation time), e.g. a team or cost center. Keys are letters, digits, '.', '_', '/' and '-', at most 63 characters; the testenv-vm. prefix is reserved. Values are at most 63 letters, digits, '.', '_' and '-'.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Prefix of the provider-level names of the keys, networks and VMs, ahead of the hash of the environment ID (e.g. "ci-1234" gives "ci-1234-<hash>-web"), so that the resources of a CI job can be told apart and cleaned up by prefix on a shared host. Templates keep using the logical names. Lowercase letters, digits and hyphens, at most 16 characters. It cannot change on update.
+	NamePrefix string `json:"namePrefix,omitempty"`
 	// Network infrastructure resources to create.
 	Networks []NetworkResource `json:"networks,omitempty"`
-	// Available providers for resource provisioning.
-	Providers []ProviderConfig `json:"providers"`
+	// Chat notifiers (Slack, Matrix) receiving compact lifecycle summaries.
+	Notifiers []NotifierSpec `json:"notifiers,omitempty"`
+	Parent    ParentSpec     `json:"parent,omitempty"`
+	// Priority of the environment when hosts lack free capacity. With admission waiting enabled in the configuration, creations queue behind those of higher priority, then in arrival order. Defaults to 0.
+	Priority int `json:"priority,omitempty"`
+	// Available providers for resource provisioning. When empty, the defaultProviders of the testenv-vm config file are used.
+	Providers []ProviderConfig `json:"providers,omitempty"`
+	// Seed from which the MAC addresses and UUIDs of the VMs are derived. When unset a random seed is generated; either way it is recorded in the environment state, so passing it back reproduces the environment. The values only derive from the seed and the VM names, so two environments with the same seed collide on the host; creation fails while another environment uses it. Network CIDRs already derive from the environment ID, except for networks whose cidr is auto, which get the lowest free subnet of the CIDR pool and are not reproduced by the seed.
+	Seed string `json:"seed,omitempty"`
+	// Helper services run on the host and bound to a managed network (registry mirror, apt cache, HTTP file server). Their endpoints are exposed as {{ .Services.<name>.<Field> }}.
+	Services []ServiceResource `json:"services,omitempty"`
+	// Git reference of the spec to create instead of this one, e.g. "git+https://github.com/org/labs.git//envs/ci.yaml?ref=v1.4.0". The ref is required; a branch is resolved to its current commit, and the resolved commit is recorded in the environment state. Only environmentId, environmentIdTemplate and seed may be set alongside it and override the fetched values.
+	SpecRef string `json:"specRef,omitempty"`
 	// Directory for persisting environment state.
 	StateDir string `json:"stateDir,omitempty"`
+	// WireGuard tunnels bridging networks of different providers (e.g. a local network and a cloud VPC).
+	Tunnels []TunnelResource `json:"tunnels,omitempty"`
 	// Virtual machine resources to create.
 	Vms []VMResource `json:"vms,omitempty"`
+	// Webhooks notified on environment lifecycle transitions.
+	Webhooks []WebhookSpec `json:"webhooks,omitempty"`
+}
+
+// AccessSpecFromMap creates a AccessSpec from a map[string]interface{}.
+func AccessSpecFromMap(m map[string]interface{}) (*AccessSpec, error) {
+	if m == nil {
+		return &AccessSpec{}, nil
+	}
+
+	s := &AccessSpec{}
+	// Parse address
+	if v, ok := m["address"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Address = val
+		} else {
+			return nil, fmt.Errorf("field address: expected string, got %T", v)
+		}
+	}
+	// Parse endpoint
+	if v, ok := m["endpoint"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Endpoint = val
+		} else {
+			return nil, fmt.Errorf("field endpoint: expected string, got %T", v)
+		}
+	}
+	// Parse listenPort
+	if v, ok := m["listenPort"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.ListenPort = val
+		case int64:
+			s.ListenPort = int(val)
+		case float64:
+			s.ListenPort = int(val)
+		default:
+			return nil, fmt.Errorf("field listenPort: expected int, got %T", v)
+		}
+	}
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Network = val
+		} else {
+			return nil, fmt.Errorf("field network: expected string, got %T", v)
+		}
+	}
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Type = val
+		} else {
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
+		}
+	}
+	// Parse vm
+	if v, ok := m["vm"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Vm = val
+		} else {
+			return nil, fmt.Errorf("field vm: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// BootArtifactSpecFromMap creates a BootArtifactSpec from a map[string]interface{}.
+func BootArtifactSpecFromMap(m map[string]interface{}) (*BootArtifactSpec, error) {
+	if m == nil {
+		return &BootArtifactSpec{}, nil
+	}
+
+	s := &BootArtifactSpec{}
+	// Parse path
+	if v, ok := m["path"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Path = val
+		} else {
+			return nil, fmt.Errorf("field path: expected string, got %T", v)
+		}
+	}
+	// Parse sha256
+	if v, ok := m["sha256"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Sha256 = val
+		} else {
+			return nil, fmt.Errorf("field sha256: expected string, got %T", v)
+		}
+	}
+	// Parse url
+	if v, ok := m["url"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Url = val
+		} else {
+			return nil, fmt.Errorf("field url: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// BootFileSpecFromMap creates a BootFileSpec from a map[string]interface{}.
+func BootFileSpecFromMap(m map[string]interface{}) (*BootFileSpec, error) {
+	if m == nil {
+		return &BootFileSpec{}, nil
+	}
+
+	s := &BootFileSpec{}
+	// Parse content
+	if v, ok := m["content"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Content = val
+		} else {
+			return nil, fmt.Errorf("field content: expected string, got %T", v)
+		}
+	}
+	// Parse path
+	if v, ok := m["path"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Path = val
+		} else {
+			return nil, fmt.Errorf("field path: expected string, got %T", v)
+		}
+	}
+	return s, nil
 }
 
 // BootSpecFromMap creates a BootSpec from a map[string]interface{}.
@@ -367,6 +1021,133 @@ func BootSpecFromMap(m map[string]interface{}) (*BootSpec, error) {
 	return s, nil
 }
 
+// CASpecFromMap creates a CASpec from a map[string]interface{}.
+func CASpecFromMap(m map[string]interface{}) (*CASpec, error) {
+	if m == nil {
+		return &CASpec{}, nil
+	}
+
+	s := &CASpec{}
+	// Parse commonName
+	if v, ok := m["commonName"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.CommonName = val
+		} else {
+			return nil, fmt.Errorf("field commonName: expected string, got %T", v)
+		}
+	}
+	// Parse validity
+	if v, ok := m["validity"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Validity = val
+		} else {
+			return nil, fmt.Errorf("field validity: expected string, got %T", v)
+		}
+	}
+	// Parse vms
+	if v, ok := m["vms"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Vms = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Vms = append(s.Vms, str)
+				} else {
+					return nil, fmt.Errorf("field vms[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Vms = arr
+		} else {
+			return nil, fmt.Errorf("field vms: expected []string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// CertificateSpecFromMap creates a CertificateSpec from a map[string]interface{}.
+func CertificateSpecFromMap(m map[string]interface{}) (*CertificateSpec, error) {
+	if m == nil {
+		return &CertificateSpec{}, nil
+	}
+
+	s := &CertificateSpec{}
+	// Parse client
+	if v, ok := m["client"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Client = val
+		} else {
+			return nil, fmt.Errorf("field client: expected bool, got %T", v)
+		}
+	}
+	// Parse commonName
+	if v, ok := m["commonName"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.CommonName = val
+		} else {
+			return nil, fmt.Errorf("field commonName: expected string, got %T", v)
+		}
+	}
+	// Parse dnsNames
+	if v, ok := m["dnsNames"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.DnsNames = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.DnsNames = append(s.DnsNames, str)
+				} else {
+					return nil, fmt.Errorf("field dnsNames[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.DnsNames = arr
+		} else {
+			return nil, fmt.Errorf("field dnsNames: expected []string, got %T", v)
+		}
+	}
+	// Parse ipAddresses
+	if v, ok := m["ipAddresses"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.IpAddresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.IpAddresses = append(s.IpAddresses, str)
+				} else {
+					return nil, fmt.Errorf("field ipAddresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.IpAddresses = arr
+		} else {
+			return nil, fmt.Errorf("field ipAddresses: expected []string, got %T", v)
+		}
+	}
+	// Parse path
+	if v, ok := m["path"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Path = val
+		} else {
+			return nil, fmt.Errorf("field path: expected string, got %T", v)
+		}
+	}
+	// Parse validity
+	if v, ok := m["validity"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Validity = val
+		} else {
+			return nil, fmt.Errorf("field validity: expected string, got %T", v)
+		}
+	}
+	// Parse vm
+	if v, ok := m["vm"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Vm = val
+		} else {
+			return nil, fmt.Errorf("field vm: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
 // CloudInitNameserversFromMap creates a CloudInitNameservers from a map[string]interface{}.
 func CloudInitNameserversFromMap(m map[string]interface{}) (*CloudInitNameservers, error) {
 	if m == nil {
@@ -435,6 +1216,14 @@ func UserSpecFromMap(m map[string]interface{}) (*UserSpec, error) {
 			return nil, fmt.Errorf("field name: expected string, got %T", v)
 		}
 	}
+	// Parse shell
+	if v, ok := m["shell"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Shell = val
+		} else {
+			return nil, fmt.Errorf("field shell: expected string, got %T", v)
+		}
+	}
 	// Parse sshAuthorizedKeys
 	if v, ok := m["sshAuthorizedKeys"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
@@ -497,115 +1286,115 @@ func WriteFileSpecFromMap(m map[string]interface{}) (*WriteFileSpec, error) {
 	return s, nil
 }
 
-// DHCPSpecFromMap creates a DHCPSpec from a map[string]interface{}.
-func DHCPSpecFromMap(m map[string]interface{}) (*DHCPSpec, error) {
+// DHCPHostSpecFromMap creates a DHCPHostSpec from a map[string]interface{}.
+func DHCPHostSpecFromMap(m map[string]interface{}) (*DHCPHostSpec, error) {
 	if m == nil {
-		return &DHCPSpec{}, nil
+		return &DHCPHostSpec{}, nil
 	}
 
-	s := &DHCPSpec{}
-	// Parse dnsServers
-	if v, ok := m["dnsServers"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.DnsServers = make([]string, 0, len(arr))
-			for i, item := range arr {
-				if str, ok := item.(string); ok {
-					s.DnsServers = append(s.DnsServers, str)
-				} else {
-					return nil, fmt.Errorf("field dnsServers[%d]: expected string, got %T", i, item)
-				}
-			}
-		} else if arr, ok := v.([]string); ok {
-			s.DnsServers = arr
+	s := &DHCPHostSpec{}
+	// Parse hostname
+	if v, ok := m["hostname"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Hostname = val
 		} else {
-			return nil, fmt.Errorf("field dnsServers: expected []string, got %T", v)
+			return nil, fmt.Errorf("field hostname: expected string, got %T", v)
 		}
 	}
-	// Parse enabled
-	if v, ok := m["enabled"]; ok && v != nil {
-		if val, ok := v.(bool); ok {
-			s.Enabled = val
+	// Parse ip
+	if v, ok := m["ip"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Ip = val
 		} else {
-			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+			return nil, fmt.Errorf("field ip: expected string, got %T", v)
 		}
 	}
-	// Parse leaseTime
-	if v, ok := m["leaseTime"]; ok && v != nil {
+	// Parse mac
+	if v, ok := m["mac"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.LeaseTime = val
+			s.Mac = val
 		} else {
-			return nil, fmt.Errorf("field leaseTime: expected string, got %T", v)
+			return nil, fmt.Errorf("field mac: expected string, got %T", v)
 		}
 	}
-	// Parse rangeEnd
-	if v, ok := m["rangeEnd"]; ok && v != nil {
+	return s, nil
+}
+
+// DNSRecordSpecFromMap creates a DNSRecordSpec from a map[string]interface{}.
+func DNSRecordSpecFromMap(m map[string]interface{}) (*DNSRecordSpec, error) {
+	if m == nil {
+		return &DNSRecordSpec{}, nil
+	}
+
+	s := &DNSRecordSpec{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.RangeEnd = val
+			s.Name = val
 		} else {
-			return nil, fmt.Errorf("field rangeEnd: expected string, got %T", v)
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
 		}
 	}
-	// Parse rangeStart
-	if v, ok := m["rangeStart"]; ok && v != nil {
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.RangeStart = val
+			s.Type = val
 		} else {
-			return nil, fmt.Errorf("field rangeStart: expected string, got %T", v)
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
 		}
 	}
-	// Parse router
-	if v, ok := m["router"]; ok && v != nil {
+	// Parse value
+	if v, ok := m["value"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Router = val
+			s.Value = val
 		} else {
-			return nil, fmt.Errorf("field router: expected string, got %T", v)
+			return nil, fmt.Errorf("field value: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// DNSSpecFromMap creates a DNSSpec from a map[string]interface{}.
-func DNSSpecFromMap(m map[string]interface{}) (*DNSSpec, error) {
+// DataDiskSpecFromMap creates a DataDiskSpec from a map[string]interface{}.
+func DataDiskSpecFromMap(m map[string]interface{}) (*DataDiskSpec, error) {
 	if m == nil {
-		return &DNSSpec{}, nil
+		return &DataDiskSpec{}, nil
 	}
 
-	s := &DNSSpec{}
-	// Parse enabled
-	if v, ok := m["enabled"]; ok && v != nil {
-		if val, ok := v.(bool); ok {
-			s.Enabled = val
-		} else {
-			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+	s := &DataDiskSpec{}
+	// Parse baseImage
+	if v, ok := m["baseImage"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.BaseImage = val
+		} else {
+			return nil, fmt.Errorf("field baseImage: expected string, got %T", v)
 		}
 	}
-	// Parse servers
-	if v, ok := m["servers"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.Servers = make([]string, 0, len(arr))
-			for i, item := range arr {
-				if str, ok := item.(string); ok {
-					s.Servers = append(s.Servers, str)
-				} else {
-					return nil, fmt.Errorf("field servers[%d]: expected string, got %T", i, item)
-				}
-			}
-		} else if arr, ok := v.([]string); ok {
-			s.Servers = arr
+	// Parse format
+	if v, ok := m["format"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Format = val
 		} else {
-			return nil, fmt.Errorf("field servers: expected []string, got %T", v)
+			return nil, fmt.Errorf("field format: expected string, got %T", v)
+		}
+	}
+	// Parse size
+	if v, ok := m["size"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Size = val
+		} else {
+			return nil, fmt.Errorf("field size: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// DiskSpecFromMap creates a DiskSpec from a map[string]interface{}.
-func DiskSpecFromMap(m map[string]interface{}) (*DiskSpec, error) {
+// DiskDefaultsSpecFromMap creates a DiskDefaultsSpec from a map[string]interface{}.
+func DiskDefaultsSpecFromMap(m map[string]interface{}) (*DiskDefaultsSpec, error) {
 	if m == nil {
-		return &DiskSpec{}, nil
+		return &DiskDefaultsSpec{}, nil
 	}
 
-	s := &DiskSpec{}
+	s := &DiskDefaultsSpec{}
 	// Parse baseImage
 	if v, ok := m["baseImage"]; ok && v != nil {
 		if val, ok := v.(string); ok {
@@ -625,6 +1414,235 @@ func DiskSpecFromMap(m map[string]interface{}) (*DiskSpec, error) {
 	return s, nil
 }
 
+// SSHReadinessSpecFromMap creates a SSHReadinessSpec from a map[string]interface{}.
+func SSHReadinessSpecFromMap(m map[string]interface{}) (*SSHReadinessSpec, error) {
+	if m == nil {
+		return &SSHReadinessSpec{}, nil
+	}
+
+	s := &SSHReadinessSpec{}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
+		} else {
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+		}
+	}
+	// Parse privateKey
+	if v, ok := m["privateKey"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.PrivateKey = val
+		} else {
+			return nil, fmt.Errorf("field privateKey: expected string, got %T", v)
+		}
+	}
+	// Parse proxyJump
+	if v, ok := m["proxyJump"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.ProxyJump = val
+		} else {
+			return nil, fmt.Errorf("field proxyJump: expected string, got %T", v)
+		}
+	}
+	// Parse timeout
+	if v, ok := m["timeout"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Timeout = val
+		} else {
+			return nil, fmt.Errorf("field timeout: expected string, got %T", v)
+		}
+	}
+	// Parse user
+	if v, ok := m["user"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.User = val
+		} else {
+			return nil, fmt.Errorf("field user: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// TCPReadinessSpecFromMap creates a TCPReadinessSpec from a map[string]interface{}.
+func TCPReadinessSpecFromMap(m map[string]interface{}) (*TCPReadinessSpec, error) {
+	if m == nil {
+		return &TCPReadinessSpec{}, nil
+	}
+
+	s := &TCPReadinessSpec{}
+	// Parse port
+	if v, ok := m["port"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Port = val
+		case int64:
+			s.Port = int(val)
+		case float64:
+			s.Port = int(val)
+		default:
+			return nil, fmt.Errorf("field port: expected int, got %T", v)
+		}
+	}
+	// Parse timeout
+	if v, ok := m["timeout"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Timeout = val
+		} else {
+			return nil, fmt.Errorf("field timeout: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// DiskEncryptionSpecFromMap creates a DiskEncryptionSpec from a map[string]interface{}.
+func DiskEncryptionSpecFromMap(m map[string]interface{}) (*DiskEncryptionSpec, error) {
+	if m == nil {
+		return &DiskEncryptionSpec{}, nil
+	}
+
+	s := &DiskEncryptionSpec{}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
+		} else {
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+		}
+	}
+	// Parse passphraseSecretRef
+	if v, ok := m["passphraseSecretRef"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.PassphraseSecretRef = val
+		} else {
+			return nil, fmt.Errorf("field passphraseSecretRef: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// GuestNameserversSpecFromMap creates a GuestNameserversSpec from a map[string]interface{}.
+func GuestNameserversSpecFromMap(m map[string]interface{}) (*GuestNameserversSpec, error) {
+	if m == nil {
+		return &GuestNameserversSpec{}, nil
+	}
+
+	s := &GuestNameserversSpec{}
+	// Parse addresses
+	if v, ok := m["addresses"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Addresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Addresses = append(s.Addresses, str)
+				} else {
+					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Addresses = arr
+		} else {
+			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
+		}
+	}
+	// Parse search
+	if v, ok := m["search"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Search = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Search = append(s.Search, str)
+				} else {
+					return nil, fmt.Errorf("field search[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Search = arr
+		} else {
+			return nil, fmt.Errorf("field search: expected []string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// GuestRouteSpecFromMap creates a GuestRouteSpec from a map[string]interface{}.
+func GuestRouteSpecFromMap(m map[string]interface{}) (*GuestRouteSpec, error) {
+	if m == nil {
+		return &GuestRouteSpec{}, nil
+	}
+
+	s := &GuestRouteSpec{}
+	// Parse metric
+	if v, ok := m["metric"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Metric = val
+		case int64:
+			s.Metric = int(val)
+		case float64:
+			s.Metric = int(val)
+		default:
+			return nil, fmt.Errorf("field metric: expected int, got %T", v)
+		}
+	}
+	// Parse to
+	if v, ok := m["to"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.To = val
+		} else {
+			return nil, fmt.Errorf("field to: expected string, got %T", v)
+		}
+	}
+	// Parse via
+	if v, ok := m["via"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Via = val
+		} else {
+			return nil, fmt.Errorf("field via: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// IdleWakeSpecFromMap creates a IdleWakeSpec from a map[string]interface{}.
+func IdleWakeSpecFromMap(m map[string]interface{}) (*IdleWakeSpec, error) {
+	if m == nil {
+		return &IdleWakeSpec{}, nil
+	}
+
+	s := &IdleWakeSpec{}
+	// Parse listen
+	if v, ok := m["listen"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Listen = val
+		} else {
+			return nil, fmt.Errorf("field listen: expected string, got %T", v)
+		}
+	}
+	// Parse port
+	if v, ok := m["port"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Port = val
+		case int64:
+			s.Port = int(val)
+		case float64:
+			s.Port = int(val)
+		default:
+			return nil, fmt.Errorf("field port: expected int, got %T", v)
+		}
+	}
+	// Parse vm
+	if v, ok := m["vm"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Vm = val
+		} else {
+			return nil, fmt.Errorf("field vm: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
 // ImageCustomizeSpecFromMap creates a ImageCustomizeSpec from a map[string]interface{}.
 func ImageCustomizeSpecFromMap(m map[string]interface{}) (*ImageCustomizeSpec, error) {
 	if m == nil {
@@ -716,153 +1734,242 @@ func KeySpecFromMap(m map[string]interface{}) (*KeySpec, error) {
 	return s, nil
 }
 
-// TFTPSpecFromMap creates a TFTPSpec from a map[string]interface{}.
-func TFTPSpecFromMap(m map[string]interface{}) (*TFTPSpec, error) {
+// MdevSpecFromMap creates a MdevSpec from a map[string]interface{}.
+func MdevSpecFromMap(m map[string]interface{}) (*MdevSpec, error) {
 	if m == nil {
-		return &TFTPSpec{}, nil
+		return &MdevSpec{}, nil
 	}
 
-	s := &TFTPSpec{}
-	// Parse bootFile
-	if v, ok := m["bootFile"]; ok && v != nil {
+	s := &MdevSpec{}
+	// Parse parent
+	if v, ok := m["parent"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.BootFile = val
+			s.Parent = val
 		} else {
-			return nil, fmt.Errorf("field bootFile: expected string, got %T", v)
+			return nil, fmt.Errorf("field parent: expected string, got %T", v)
 		}
 	}
-	// Parse enabled
-	if v, ok := m["enabled"]; ok && v != nil {
-		if val, ok := v.(bool); ok {
-			s.Enabled = val
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Type = val
 		} else {
-			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
 		}
 	}
-	// Parse root
-	if v, ok := m["root"]; ok && v != nil {
+	// Parse uuid
+	if v, ok := m["uuid"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Root = val
+			s.Uuid = val
 		} else {
-			return nil, fmt.Errorf("field root: expected string, got %T", v)
+			return nil, fmt.Errorf("field uuid: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// ProviderConfigFromMap creates a ProviderConfig from a map[string]interface{}.
-func ProviderConfigFromMap(m map[string]interface{}) (*ProviderConfig, error) {
+// NTPSpecFromMap creates a NTPSpec from a map[string]interface{}.
+func NTPSpecFromMap(m map[string]interface{}) (*NTPSpec, error) {
 	if m == nil {
-		return &ProviderConfig{}, nil
+		return &NTPSpec{}, nil
 	}
 
-	s := &ProviderConfig{}
-	// Parse default
-	if v, ok := m["default"]; ok && v != nil {
+	s := &NTPSpec{}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
 		if val, ok := v.(bool); ok {
-			s.Default = val
-		} else {
-			return nil, fmt.Errorf("field default: expected bool, got %T", v)
-		}
-	}
-	// Parse engine
-	if v, ok := m["engine"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Engine = val
+			s.Enabled = val
 		} else {
-			return nil, fmt.Errorf("field engine: expected string, got %T", v)
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
 		}
 	}
-	// Parse name
-	if v, ok := m["name"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Name = val
-		} else {
-			return nil, fmt.Errorf("field name: expected string, got %T", v)
-		}
-	}
-	// Parse spec
-	if v, ok := m["spec"]; ok && v != nil {
-		if mapVal, ok := v.(map[string]interface{}); ok {
-			s.Spec = make(map[string]interface{}, len(mapVal))
-			for key, val := range mapVal {
-				s.Spec[key] = val.(interface{})
+	// Parse servers
+	if v, ok := m["servers"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Servers = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Servers = append(s.Servers, str)
+				} else {
+					return nil, fmt.Errorf("field servers[%d]: expected string, got %T", i, item)
+				}
 			}
+		} else if arr, ok := v.([]string); ok {
+			s.Servers = arr
 		} else {
-			return nil, fmt.Errorf("field spec: expected map, got %T", v)
+			return nil, fmt.Errorf("field servers: expected []string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// SSHReadinessSpecFromMap creates a SSHReadinessSpec from a map[string]interface{}.
-func SSHReadinessSpecFromMap(m map[string]interface{}) (*SSHReadinessSpec, error) {
+// NotifierSpecFromMap creates a NotifierSpec from a map[string]interface{}.
+func NotifierSpecFromMap(m map[string]interface{}) (*NotifierSpec, error) {
 	if m == nil {
-		return &SSHReadinessSpec{}, nil
+		return &NotifierSpec{}, nil
 	}
 
-	s := &SSHReadinessSpec{}
-	// Parse enabled
-	if v, ok := m["enabled"]; ok && v != nil {
-		if val, ok := v.(bool); ok {
-			s.Enabled = val
+	s := &NotifierSpec{}
+	// Parse accessTokenEnv
+	if v, ok := m["accessTokenEnv"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.AccessTokenEnv = val
 		} else {
-			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+			return nil, fmt.Errorf("field accessTokenEnv: expected string, got %T", v)
 		}
 	}
-	// Parse privateKey
-	if v, ok := m["privateKey"]; ok && v != nil {
+	// Parse artifactUrl
+	if v, ok := m["artifactUrl"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.PrivateKey = val
+			s.ArtifactUrl = val
 		} else {
-			return nil, fmt.Errorf("field privateKey: expected string, got %T", v)
+			return nil, fmt.Errorf("field artifactUrl: expected string, got %T", v)
 		}
 	}
-	// Parse timeout
-	if v, ok := m["timeout"]; ok && v != nil {
+	// Parse events
+	if v, ok := m["events"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Events = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Events = append(s.Events, str)
+				} else {
+					return nil, fmt.Errorf("field events[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Events = arr
+		} else {
+			return nil, fmt.Errorf("field events: expected []string, got %T", v)
+		}
+	}
+	// Parse homeserver
+	if v, ok := m["homeserver"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Timeout = val
+			s.Homeserver = val
 		} else {
-			return nil, fmt.Errorf("field timeout: expected string, got %T", v)
+			return nil, fmt.Errorf("field homeserver: expected string, got %T", v)
 		}
 	}
-	// Parse user
-	if v, ok := m["user"]; ok && v != nil {
+	// Parse roomId
+	if v, ok := m["roomId"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.User = val
+			s.RoomId = val
 		} else {
-			return nil, fmt.Errorf("field user: expected string, got %T", v)
+			return nil, fmt.Errorf("field roomId: expected string, got %T", v)
+		}
+	}
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Type = val
+		} else {
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
+		}
+	}
+	// Parse webhookUrlEnv
+	if v, ok := m["webhookUrlEnv"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.WebhookUrlEnv = val
+		} else {
+			return nil, fmt.Errorf("field webhookUrlEnv: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// TCPReadinessSpecFromMap creates a TCPReadinessSpec from a map[string]interface{}.
-func TCPReadinessSpecFromMap(m map[string]interface{}) (*TCPReadinessSpec, error) {
+// ParentSpecFromMap creates a ParentSpec from a map[string]interface{}.
+func ParentSpecFromMap(m map[string]interface{}) (*ParentSpec, error) {
 	if m == nil {
-		return &TCPReadinessSpec{}, nil
+		return &ParentSpec{}, nil
 	}
 
-	s := &TCPReadinessSpec{}
-	// Parse port
-	if v, ok := m["port"]; ok && v != nil {
-		switch val := v.(type) {
-		case int:
-			s.Port = val
-		case int64:
-			s.Port = int(val)
-		case float64:
-			s.Port = int(val)
-		default:
-			return nil, fmt.Errorf("field port: expected int, got %T", v)
+	s := &ParentSpec{}
+	// Parse environmentId
+	if v, ok := m["environmentId"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.EnvironmentId = val
+		} else {
+			return nil, fmt.Errorf("field environmentId: expected string, got %T", v)
 		}
 	}
-	// Parse timeout
-	if v, ok := m["timeout"]; ok && v != nil {
+	// Parse keys
+	if v, ok := m["keys"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Keys = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Keys = append(s.Keys, str)
+				} else {
+					return nil, fmt.Errorf("field keys[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Keys = arr
+		} else {
+			return nil, fmt.Errorf("field keys: expected []string, got %T", v)
+		}
+	}
+	// Parse networks
+	if v, ok := m["networks"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Networks = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Networks = append(s.Networks, str)
+				} else {
+					return nil, fmt.Errorf("field networks[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Networks = arr
+		} else {
+			return nil, fmt.Errorf("field networks: expected []string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// ProviderConfigFromMap creates a ProviderConfig from a map[string]interface{}.
+func ProviderConfigFromMap(m map[string]interface{}) (*ProviderConfig, error) {
+	if m == nil {
+		return &ProviderConfig{}, nil
+	}
+
+	s := &ProviderConfig{}
+	// Parse default
+	if v, ok := m["default"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Default = val
+		} else {
+			return nil, fmt.Errorf("field default: expected bool, got %T", v)
+		}
+	}
+	// Parse engine
+	if v, ok := m["engine"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Timeout = val
+			s.Engine = val
 		} else {
-			return nil, fmt.Errorf("field timeout: expected string, got %T", v)
+			return nil, fmt.Errorf("field engine: expected string, got %T", v)
+		}
+	}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.Spec = make(map[string]interface{}, len(mapVal))
+			for key, val := range mapVal {
+				s.Spec[key] = val.(interface{})
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected map, got %T", v)
 		}
 	}
 	return s, nil
@@ -902,221 +2009,237 @@ func ResourceRefFromMap(m map[string]interface{}) (*ResourceRef, error) {
 	return s, nil
 }
 
-// CloudInitEthernetConfigFromMap creates a CloudInitEthernetConfig from a map[string]interface{}.
-func CloudInitEthernetConfigFromMap(m map[string]interface{}) (*CloudInitEthernetConfig, error) {
+// ServiceSpecFromMap creates a ServiceSpec from a map[string]interface{}.
+func ServiceSpecFromMap(m map[string]interface{}) (*ServiceSpec, error) {
 	if m == nil {
-		return &CloudInitEthernetConfig{}, nil
+		return &ServiceSpec{}, nil
 	}
 
-	s := &CloudInitEthernetConfig{}
-	// Parse addresses
-	if v, ok := m["addresses"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.Addresses = make([]string, 0, len(arr))
-			for i, item := range arr {
-				if str, ok := item.(string); ok {
-					s.Addresses = append(s.Addresses, str)
-				} else {
-					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
-				}
-			}
-		} else if arr, ok := v.([]string); ok {
-			s.Addresses = arr
+	s := &ServiceSpec{}
+	// Parse accessKey
+	if v, ok := m["accessKey"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.AccessKey = val
 		} else {
-			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
+			return nil, fmt.Errorf("field accessKey: expected string, got %T", v)
 		}
 	}
-	// Parse dhcp4
-	if v, ok := m["dhcp4"]; ok && v != nil {
-		if val, ok := v.(bool); ok {
-			s.Dhcp4 = val
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Network = val
 		} else {
-			return nil, fmt.Errorf("field dhcp4: expected bool, got %T", v)
+			return nil, fmt.Errorf("field network: expected string, got %T", v)
 		}
 	}
-	// Parse gateway4
-	if v, ok := m["gateway4"]; ok && v != nil {
+	// Parse port
+	if v, ok := m["port"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Port = val
+		case int64:
+			s.Port = int(val)
+		case float64:
+			s.Port = int(val)
+		default:
+			return nil, fmt.Errorf("field port: expected int, got %T", v)
+		}
+	}
+	// Parse root
+	if v, ok := m["root"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Gateway4 = val
+			s.Root = val
 		} else {
-			return nil, fmt.Errorf("field gateway4: expected string, got %T", v)
+			return nil, fmt.Errorf("field root: expected string, got %T", v)
 		}
 	}
-	// Parse name
-	if v, ok := m["name"]; ok && v != nil {
+	// Parse secretKey
+	if v, ok := m["secretKey"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Name = val
+			s.SecretKey = val
 		} else {
-			return nil, fmt.Errorf("field name: expected string, got %T", v)
+			return nil, fmt.Errorf("field secretKey: expected string, got %T", v)
 		}
 	}
-	// Parse nameservers
-	if v, ok := m["nameservers"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := CloudInitNameserversFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field nameservers: %w", err)
-			}
-			if ref != nil {
-				s.Nameservers = *ref
-			}
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Type = val
 		} else {
-			return nil, fmt.Errorf("field nameservers: expected object, got %T", v)
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
+		}
+	}
+	// Parse upstream
+	if v, ok := m["upstream"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Upstream = val
+		} else {
+			return nil, fmt.Errorf("field upstream: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// ImageSpecFromMap creates a ImageSpec from a map[string]interface{}.
-func ImageSpecFromMap(m map[string]interface{}) (*ImageSpec, error) {
+// TunnelSpecFromMap creates a TunnelSpec from a map[string]interface{}.
+func TunnelSpecFromMap(m map[string]interface{}) (*TunnelSpec, error) {
 	if m == nil {
-		return &ImageSpec{}, nil
+		return &TunnelSpec{}, nil
 	}
 
-	s := &ImageSpec{}
-	// Parse alias
-	if v, ok := m["alias"]; ok && v != nil {
+	s := &TunnelSpec{}
+	// Parse address
+	if v, ok := m["address"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Alias = val
+			s.Address = val
 		} else {
-			return nil, fmt.Errorf("field alias: expected string, got %T", v)
+			return nil, fmt.Errorf("field address: expected string, got %T", v)
 		}
 	}
-	// Parse customize
-	if v, ok := m["customize"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := ImageCustomizeSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field customize: %w", err)
-			}
-			s.Customize = ref
+	// Parse endpoint
+	if v, ok := m["endpoint"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Endpoint = val
 		} else {
-			return nil, fmt.Errorf("field customize: expected object, got %T", v)
+			return nil, fmt.Errorf("field endpoint: expected string, got %T", v)
 		}
 	}
-	// Parse sha256
-	if v, ok := m["sha256"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Sha256 = val
-		} else {
-			return nil, fmt.Errorf("field sha256: expected string, got %T", v)
+	// Parse listenPort
+	if v, ok := m["listenPort"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.ListenPort = val
+		case int64:
+			s.ListenPort = int(val)
+		case float64:
+			s.ListenPort = int(val)
+		default:
+			return nil, fmt.Errorf("field listenPort: expected int, got %T", v)
 		}
 	}
-	// Parse source
-	if v, ok := m["source"]; ok && v != nil {
+	// Parse localNetwork
+	if v, ok := m["localNetwork"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Source = val
+			s.LocalNetwork = val
 		} else {
-			return nil, fmt.Errorf("field source: expected string, got %T", v)
+			return nil, fmt.Errorf("field localNetwork: expected string, got %T", v)
 		}
 	}
-	return s, nil
-}
-
-// KeyResourceFromMap creates a KeyResource from a map[string]interface{}.
-func KeyResourceFromMap(m map[string]interface{}) (*KeyResource, error) {
-	if m == nil {
-		return &KeyResource{}, nil
+	// Parse persistentKeepalive
+	if v, ok := m["persistentKeepalive"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.PersistentKeepalive = val
+		case int64:
+			s.PersistentKeepalive = int(val)
+		case float64:
+			s.PersistentKeepalive = int(val)
+		default:
+			return nil, fmt.Errorf("field persistentKeepalive: expected int, got %T", v)
+		}
 	}
-
-	s := &KeyResource{}
-	// Parse name
-	if v, ok := m["name"]; ok && v != nil {
+	// Parse remoteNetwork
+	if v, ok := m["remoteNetwork"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Name = val
+			s.RemoteNetwork = val
 		} else {
-			return nil, fmt.Errorf("field name: expected string, got %T", v)
+			return nil, fmt.Errorf("field remoteNetwork: expected string, got %T", v)
 		}
 	}
-	// Parse provider
-	if v, ok := m["provider"]; ok && v != nil {
+	// Parse type
+	if v, ok := m["type"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Provider = val
+			s.Type = val
 		} else {
-			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+			return nil, fmt.Errorf("field type: expected string, got %T", v)
 		}
 	}
-	// Parse providerSpec
-	if v, ok := m["providerSpec"]; ok && v != nil {
-		if mapVal, ok := v.(map[string]interface{}); ok {
-			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
-			for key, val := range mapVal {
-				s.ProviderSpec[key] = val.(interface{})
-			}
+	return s, nil
+}
+
+// VMAgentSpecFromMap creates a VMAgentSpec from a map[string]interface{}.
+func VMAgentSpecFromMap(m map[string]interface{}) (*VMAgentSpec, error) {
+	if m == nil {
+		return &VMAgentSpec{}, nil
+	}
+
+	s := &VMAgentSpec{}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
 		} else {
-			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
 		}
 	}
-	// Parse spec
-	if v, ok := m["spec"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := KeySpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field spec: %w", err)
-			}
-			if ref != nil {
-				s.Spec = *ref
-			}
-		} else {
-			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+	// Parse port
+	if v, ok := m["port"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Port = val
+		case int64:
+			s.Port = int(val)
+		case float64:
+			s.Port = int(val)
+		default:
+			return nil, fmt.Errorf("field port: expected int, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// NetworkSpecFromMap creates a NetworkSpec from a map[string]interface{}.
-func NetworkSpecFromMap(m map[string]interface{}) (*NetworkSpec, error) {
+// VsockSpecFromMap creates a VsockSpec from a map[string]interface{}.
+func VsockSpecFromMap(m map[string]interface{}) (*VsockSpec, error) {
 	if m == nil {
-		return &NetworkSpec{}, nil
+		return &VsockSpec{}, nil
 	}
 
-	s := &NetworkSpec{}
-	// Parse attachTo
-	if v, ok := m["attachTo"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.AttachTo = val
-		} else {
-			return nil, fmt.Errorf("field attachTo: expected string, got %T", v)
-		}
-	}
-	// Parse cidr
-	if v, ok := m["cidr"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Cidr = val
-		} else {
-			return nil, fmt.Errorf("field cidr: expected string, got %T", v)
+	s := &VsockSpec{}
+	// Parse cid
+	if v, ok := m["cid"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Cid = val
+		case int64:
+			s.Cid = int(val)
+		case float64:
+			s.Cid = int(val)
+		default:
+			return nil, fmt.Errorf("field cid: expected int, got %T", v)
 		}
 	}
-	// Parse dhcp
-	if v, ok := m["dhcp"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := DHCPSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field dhcp: %w", err)
-			}
-			s.Dhcp = ref
-		} else {
-			return nil, fmt.Errorf("field dhcp: expected object, got %T", v)
-		}
+	return s, nil
+}
+
+// VMNICSpecFromMap creates a VMNICSpec from a map[string]interface{}.
+func VMNICSpecFromMap(m map[string]interface{}) (*VMNICSpec, error) {
+	if m == nil {
+		return &VMNICSpec{}, nil
 	}
-	// Parse dns
-	if v, ok := m["dns"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := DNSSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field dns: %w", err)
+
+	s := &VMNICSpec{}
+	// Parse disableOffloads
+	if v, ok := m["disableOffloads"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.DisableOffloads = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.DisableOffloads = append(s.DisableOffloads, str)
+				} else {
+					return nil, fmt.Errorf("field disableOffloads[%d]: expected string, got %T", i, item)
+				}
 			}
-			s.Dns = ref
+		} else if arr, ok := v.([]string); ok {
+			s.DisableOffloads = arr
 		} else {
-			return nil, fmt.Errorf("field dns: expected object, got %T", v)
+			return nil, fmt.Errorf("field disableOffloads: expected []string, got %T", v)
 		}
 	}
-	// Parse gateway
-	if v, ok := m["gateway"]; ok && v != nil {
+	// Parse model
+	if v, ok := m["model"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Gateway = val
+			s.Model = val
 		} else {
-			return nil, fmt.Errorf("field gateway: expected string, got %T", v)
+			return nil, fmt.Errorf("field model: expected string, got %T", v)
 		}
 	}
 	// Parse mtu
@@ -1132,111 +2255,147 @@ func NetworkSpecFromMap(m map[string]interface{}) (*NetworkSpec, error) {
 			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
 		}
 	}
-	// Parse tftp
-	if v, ok := m["tftp"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := TFTPSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field tftp: %w", err)
-			}
-			s.Tftp = ref
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Network = val
 		} else {
-			return nil, fmt.Errorf("field tftp: expected object, got %T", v)
+			return nil, fmt.Errorf("field network: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// ReadinessSpecFromMap creates a ReadinessSpec from a map[string]interface{}.
-func ReadinessSpecFromMap(m map[string]interface{}) (*ReadinessSpec, error) {
+// VMSecuritySpecFromMap creates a VMSecuritySpec from a map[string]interface{}.
+func VMSecuritySpecFromMap(m map[string]interface{}) (*VMSecuritySpec, error) {
 	if m == nil {
-		return &ReadinessSpec{}, nil
+		return &VMSecuritySpec{}, nil
 	}
 
-	s := &ReadinessSpec{}
-	// Parse cloudInit
-	if v, ok := m["cloudInit"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := CloudInitReadinessSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field cloudInit: %w", err)
-			}
-			if ref != nil {
-				s.CloudInit = *ref
-			}
+	s := &VMSecuritySpec{}
+	// Parse disabled
+	if v, ok := m["disabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Disabled = val
 		} else {
-			return nil, fmt.Errorf("field cloudInit: expected object, got %T", v)
+			return nil, fmt.Errorf("field disabled: expected bool, got %T", v)
 		}
 	}
-	// Parse ssh
-	if v, ok := m["ssh"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := SSHReadinessSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field ssh: %w", err)
-			}
-			if ref != nil {
-				s.Ssh = *ref
-			}
+	// Parse label
+	if v, ok := m["label"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Label = val
 		} else {
-			return nil, fmt.Errorf("field ssh: expected object, got %T", v)
+			return nil, fmt.Errorf("field label: expected string, got %T", v)
 		}
 	}
-	// Parse tcp
-	if v, ok := m["tcp"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := TCPReadinessSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field tcp: %w", err)
-			}
-			if ref != nil {
-				s.Tcp = *ref
-			}
+	// Parse model
+	if v, ok := m["model"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Model = val
 		} else {
-			return nil, fmt.Errorf("field tcp: expected object, got %T", v)
+			return nil, fmt.Errorf("field model: expected string, got %T", v)
+		}
+	}
+	// Parse relabel
+	if v, ok := m["relabel"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Relabel = val
+		} else {
+			return nil, fmt.Errorf("field relabel: expected bool, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// CloudInitNetworkConfigFromMap creates a CloudInitNetworkConfig from a map[string]interface{}.
-func CloudInitNetworkConfigFromMap(m map[string]interface{}) (*CloudInitNetworkConfig, error) {
+// WebhookSpecFromMap creates a WebhookSpec from a map[string]interface{}.
+func WebhookSpecFromMap(m map[string]interface{}) (*WebhookSpec, error) {
 	if m == nil {
-		return &CloudInitNetworkConfig{}, nil
+		return &WebhookSpec{}, nil
 	}
 
-	s := &CloudInitNetworkConfig{}
-	// Parse ethernets
-	if v, ok := m["ethernets"]; ok && v != nil {
+	s := &WebhookSpec{}
+	// Parse events
+	if v, ok := m["events"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Ethernets = make([]CloudInitEthernetConfig, 0, len(arr))
+			s.Events = make([]string, 0, len(arr))
 			for i, item := range arr {
-				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := CloudInitEthernetConfigFromMap(obj)
-					if err != nil {
-						return nil, fmt.Errorf("field ethernets[%d]: %w", i, err)
-					}
-					if ref != nil {
-						s.Ethernets = append(s.Ethernets, *ref)
-					}
+				if str, ok := item.(string); ok {
+					s.Events = append(s.Events, str)
 				} else {
-					return nil, fmt.Errorf("field ethernets[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field events[%d]: expected string, got %T", i, item)
 				}
 			}
+		} else if arr, ok := v.([]string); ok {
+			s.Events = arr
 		} else {
-			return nil, fmt.Errorf("field ethernets: expected []object, got %T", v)
+			return nil, fmt.Errorf("field events: expected []string, got %T", v)
+		}
+	}
+	// Parse headers
+	if v, ok := m["headers"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.Headers = make(map[string]string, len(mapVal))
+			for key, val := range mapVal {
+				if str, ok := val.(string); ok {
+					s.Headers[key] = str
+				} else {
+					return nil, fmt.Errorf("field headers[%s]: expected string, got %T", key, val)
+				}
+			}
+		} else if mapVal, ok := v.(map[string]string); ok {
+			s.Headers = mapVal
+		} else {
+			return nil, fmt.Errorf("field headers: expected map[string]string, got %T", v)
+		}
+	}
+	// Parse maxRetries
+	if v, ok := m["maxRetries"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.MaxRetries = val
+		case int64:
+			s.MaxRetries = int(val)
+		case float64:
+			s.MaxRetries = int(val)
+		default:
+			return nil, fmt.Errorf("field maxRetries: expected int, got %T", v)
+		}
+	}
+	// Parse secretEnv
+	if v, ok := m["secretEnv"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.SecretEnv = val
+		} else {
+			return nil, fmt.Errorf("field secretEnv: expected string, got %T", v)
+		}
+	}
+	// Parse timeout
+	if v, ok := m["timeout"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Timeout = val
+		} else {
+			return nil, fmt.Errorf("field timeout: expected string, got %T", v)
+		}
+	}
+	// Parse url
+	if v, ok := m["url"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Url = val
+		} else {
+			return nil, fmt.Errorf("field url: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// ImageResourceFromMap creates a ImageResource from a map[string]interface{}.
-func ImageResourceFromMap(m map[string]interface{}) (*ImageResource, error) {
+// AccessResourceFromMap creates a AccessResource from a map[string]interface{}.
+func AccessResourceFromMap(m map[string]interface{}) (*AccessResource, error) {
 	if m == nil {
-		return &ImageResource{}, nil
+		return &AccessResource{}, nil
 	}
 
-	s := &ImageResource{}
+	s := &AccessResource{}
 	// Parse name
 	if v, ok := m["name"]; ok && v != nil {
 		if val, ok := v.(string); ok {
@@ -1248,7 +2407,7 @@ func ImageResourceFromMap(m map[string]interface{}) (*ImageResource, error) {
 	// Parse spec
 	if v, ok := m["spec"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := ImageSpecFromMap(obj)
+			ref, err := AccessSpecFromMap(obj)
 			if err != nil {
 				return nil, fmt.Errorf("field spec: %w", err)
 			}
@@ -1262,52 +2421,126 @@ func ImageResourceFromMap(m map[string]interface{}) (*ImageResource, error) {
 	return s, nil
 }
 
-// NetworkResourceFromMap creates a NetworkResource from a map[string]interface{}.
-func NetworkResourceFromMap(m map[string]interface{}) (*NetworkResource, error) {
+// TFTPSpecFromMap creates a TFTPSpec from a map[string]interface{}.
+func TFTPSpecFromMap(m map[string]interface{}) (*TFTPSpec, error) {
 	if m == nil {
-		return &NetworkResource{}, nil
+		return &TFTPSpec{}, nil
 	}
 
-	s := &NetworkResource{}
-	// Parse kind
-	if v, ok := m["kind"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Kind = val
+	s := &TFTPSpec{}
+	// Parse artifacts
+	if v, ok := m["artifacts"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Artifacts = make([]BootArtifactSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := BootArtifactSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field artifacts[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Artifacts = append(s.Artifacts, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field artifacts[%d]: expected object, got %T", i, item)
+				}
+			}
 		} else {
-			return nil, fmt.Errorf("field kind: expected string, got %T", v)
+			return nil, fmt.Errorf("field artifacts: expected []object, got %T", v)
 		}
 	}
-	// Parse name
-	if v, ok := m["name"]; ok && v != nil {
+	// Parse bootFile
+	if v, ok := m["bootFile"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Name = val
+			s.BootFile = val
 		} else {
-			return nil, fmt.Errorf("field name: expected string, got %T", v)
+			return nil, fmt.Errorf("field bootFile: expected string, got %T", v)
 		}
 	}
-	// Parse provider
-	if v, ok := m["provider"]; ok && v != nil {
+	// Parse bootFileEfi
+	if v, ok := m["bootFileEfi"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Provider = val
+			s.BootFileEfi = val
 		} else {
-			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+			return nil, fmt.Errorf("field bootFileEfi: expected string, got %T", v)
 		}
 	}
-	// Parse providerSpec
-	if v, ok := m["providerSpec"]; ok && v != nil {
+	// Parse dhcpBootOptions
+	if v, ok := m["dhcpBootOptions"]; ok && v != nil {
 		if mapVal, ok := v.(map[string]interface{}); ok {
-			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
+			s.DhcpBootOptions = make(map[string]string, len(mapVal))
 			for key, val := range mapVal {
-				s.ProviderSpec[key] = val.(interface{})
+				if str, ok := val.(string); ok {
+					s.DhcpBootOptions[key] = str
+				} else {
+					return nil, fmt.Errorf("field dhcpBootOptions[%s]: expected string, got %T", key, val)
+				}
 			}
+		} else if mapVal, ok := v.(map[string]string); ok {
+			s.DhcpBootOptions = mapVal
 		} else {
-			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+			return nil, fmt.Errorf("field dhcpBootOptions: expected map[string]string, got %T", v)
+		}
+	}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
+		} else {
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+		}
+	}
+	// Parse files
+	if v, ok := m["files"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Files = make([]BootFileSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := BootFileSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field files[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Files = append(s.Files, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field files[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field files: expected []object, got %T", v)
+		}
+	}
+	// Parse root
+	if v, ok := m["root"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Root = val
+		} else {
+			return nil, fmt.Errorf("field root: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// CertificateResourceFromMap creates a CertificateResource from a map[string]interface{}.
+func CertificateResourceFromMap(m map[string]interface{}) (*CertificateResource, error) {
+	if m == nil {
+		return &CertificateResource{}, nil
+	}
+
+	s := &CertificateResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
 		}
 	}
 	// Parse spec
 	if v, ok := m["spec"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := NetworkSpecFromMap(obj)
+			ref, err := CertificateSpecFromMap(obj)
 			if err != nil {
 				return nil, fmt.Errorf("field spec: %w", err)
 			}
@@ -1321,139 +2554,226 @@ func NetworkResourceFromMap(m map[string]interface{}) (*NetworkResource, error)
 	return s, nil
 }
 
-// CloudInitSpecFromMap creates a CloudInitSpec from a map[string]interface{}.
-func CloudInitSpecFromMap(m map[string]interface{}) (*CloudInitSpec, error) {
+// CloudInitEthernetConfigFromMap creates a CloudInitEthernetConfig from a map[string]interface{}.
+func CloudInitEthernetConfigFromMap(m map[string]interface{}) (*CloudInitEthernetConfig, error) {
 	if m == nil {
-		return &CloudInitSpec{}, nil
+		return &CloudInitEthernetConfig{}, nil
 	}
 
-	s := &CloudInitSpec{}
-	// Parse hostname
-	if v, ok := m["hostname"]; ok && v != nil {
+	s := &CloudInitEthernetConfig{}
+	// Parse addresses
+	if v, ok := m["addresses"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Addresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Addresses = append(s.Addresses, str)
+				} else {
+					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Addresses = arr
+		} else {
+			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
+		}
+	}
+	// Parse dhcp4
+	if v, ok := m["dhcp4"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Dhcp4 = val
+		} else {
+			return nil, fmt.Errorf("field dhcp4: expected bool, got %T", v)
+		}
+	}
+	// Parse gateway4
+	if v, ok := m["gateway4"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Hostname = val
+			s.Gateway4 = val
 		} else {
-			return nil, fmt.Errorf("field hostname: expected string, got %T", v)
+			return nil, fmt.Errorf("field gateway4: expected string, got %T", v)
 		}
 	}
-	// Parse networkConfig
-	if v, ok := m["networkConfig"]; ok && v != nil {
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse nameservers
+	if v, ok := m["nameservers"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := CloudInitNetworkConfigFromMap(obj)
+			ref, err := CloudInitNameserversFromMap(obj)
 			if err != nil {
-				return nil, fmt.Errorf("field networkConfig: %w", err)
+				return nil, fmt.Errorf("field nameservers: %w", err)
 			}
 			if ref != nil {
-				s.NetworkConfig = *ref
+				s.Nameservers = *ref
 			}
 		} else {
-			return nil, fmt.Errorf("field networkConfig: expected object, got %T", v)
+			return nil, fmt.Errorf("field nameservers: expected object, got %T", v)
 		}
 	}
-	// Parse packages
-	if v, ok := m["packages"]; ok && v != nil {
+	return s, nil
+}
+
+// DHCPSpecFromMap creates a DHCPSpec from a map[string]interface{}.
+func DHCPSpecFromMap(m map[string]interface{}) (*DHCPSpec, error) {
+	if m == nil {
+		return &DHCPSpec{}, nil
+	}
+
+	s := &DHCPSpec{}
+	// Parse dnsServers
+	if v, ok := m["dnsServers"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Packages = make([]string, 0, len(arr))
+			s.DnsServers = make([]string, 0, len(arr))
 			for i, item := range arr {
 				if str, ok := item.(string); ok {
-					s.Packages = append(s.Packages, str)
+					s.DnsServers = append(s.DnsServers, str)
 				} else {
-					return nil, fmt.Errorf("field packages[%d]: expected string, got %T", i, item)
+					return nil, fmt.Errorf("field dnsServers[%d]: expected string, got %T", i, item)
 				}
 			}
 		} else if arr, ok := v.([]string); ok {
-			s.Packages = arr
+			s.DnsServers = arr
 		} else {
-			return nil, fmt.Errorf("field packages: expected []string, got %T", v)
+			return nil, fmt.Errorf("field dnsServers: expected []string, got %T", v)
 		}
 	}
-	// Parse runcmd
-	if v, ok := m["runcmd"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.Runcmd = make([]string, 0, len(arr))
-			for i, item := range arr {
-				if str, ok := item.(string); ok {
-					s.Runcmd = append(s.Runcmd, str)
-				} else {
-					return nil, fmt.Errorf("field runcmd[%d]: expected string, got %T", i, item)
-				}
-			}
-		} else if arr, ok := v.([]string); ok {
-			s.Runcmd = arr
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
 		} else {
-			return nil, fmt.Errorf("field runcmd: expected []string, got %T", v)
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
 		}
 	}
-	// Parse users
-	if v, ok := m["users"]; ok && v != nil {
+	// Parse hosts
+	if v, ok := m["hosts"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Users = make([]UserSpec, 0, len(arr))
+			s.Hosts = make([]DHCPHostSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := UserSpecFromMap(obj)
+					ref, err := DHCPHostSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field users[%d]: %w", i, err)
+						return nil, fmt.Errorf("field hosts[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.Users = append(s.Users, *ref)
+						s.Hosts = append(s.Hosts, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field users[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field hosts[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field users: expected []object, got %T", v)
+			return nil, fmt.Errorf("field hosts: expected []object, got %T", v)
 		}
 	}
-	// Parse writeFiles
-	if v, ok := m["writeFiles"]; ok && v != nil {
+	// Parse leaseTime
+	if v, ok := m["leaseTime"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.LeaseTime = val
+		} else {
+			return nil, fmt.Errorf("field leaseTime: expected string, got %T", v)
+		}
+	}
+	// Parse rangeEnd
+	if v, ok := m["rangeEnd"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.RangeEnd = val
+		} else {
+			return nil, fmt.Errorf("field rangeEnd: expected string, got %T", v)
+		}
+	}
+	// Parse rangeStart
+	if v, ok := m["rangeStart"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.RangeStart = val
+		} else {
+			return nil, fmt.Errorf("field rangeStart: expected string, got %T", v)
+		}
+	}
+	// Parse router
+	if v, ok := m["router"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Router = val
+		} else {
+			return nil, fmt.Errorf("field router: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// DNSSpecFromMap creates a DNSSpec from a map[string]interface{}.
+func DNSSpecFromMap(m map[string]interface{}) (*DNSSpec, error) {
+	if m == nil {
+		return &DNSSpec{}, nil
+	}
+
+	s := &DNSSpec{}
+	// Parse enabled
+	if v, ok := m["enabled"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Enabled = val
+		} else {
+			return nil, fmt.Errorf("field enabled: expected bool, got %T", v)
+		}
+	}
+	// Parse records
+	if v, ok := m["records"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.WriteFiles = make([]WriteFileSpec, 0, len(arr))
+			s.Records = make([]DNSRecordSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := WriteFileSpecFromMap(obj)
+					ref, err := DNSRecordSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field writeFiles[%d]: %w", i, err)
+						return nil, fmt.Errorf("field records[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.WriteFiles = append(s.WriteFiles, *ref)
+						s.Records = append(s.Records, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field writeFiles[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field records[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field writeFiles: expected []object, got %T", v)
+			return nil, fmt.Errorf("field records: expected []object, got %T", v)
 		}
 	}
-	return s, nil
-}
-
-// VMSpecFromMap creates a VMSpec from a map[string]interface{}.
-func VMSpecFromMap(m map[string]interface{}) (*VMSpec, error) {
-	if m == nil {
-		return &VMSpec{}, nil
-	}
-
-	s := &VMSpec{}
-	// Parse boot
-	if v, ok := m["boot"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := BootSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field boot: %w", err)
-			}
-			if ref != nil {
-				s.Boot = *ref
+	// Parse servers
+	if v, ok := m["servers"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Servers = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Servers = append(s.Servers, str)
+				} else {
+					return nil, fmt.Errorf("field servers[%d]: expected string, got %T", i, item)
+				}
 			}
+		} else if arr, ok := v.([]string); ok {
+			s.Servers = arr
 		} else {
-			return nil, fmt.Errorf("field boot: expected object, got %T", v)
+			return nil, fmt.Errorf("field servers: expected []string, got %T", v)
 		}
 	}
+	return s, nil
+}
+
+// ReadinessSpecFromMap creates a ReadinessSpec from a map[string]interface{}.
+func ReadinessSpecFromMap(m map[string]interface{}) (*ReadinessSpec, error) {
+	if m == nil {
+		return &ReadinessSpec{}, nil
+	}
+
+	s := &ReadinessSpec{}
 	// Parse cloudInit
 	if v, ok := m["cloudInit"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := CloudInitSpecFromMap(obj)
+			ref, err := CloudInitReadinessSpecFromMap(obj)
 			if err != nil {
 				return nil, fmt.Errorf("field cloudInit: %w", err)
 			}
@@ -1464,349 +2784,2754 @@ func VMSpecFromMap(m map[string]interface{}) (*VMSpec, error) {
 			return nil, fmt.Errorf("field cloudInit: expected object, got %T", v)
 		}
 	}
-	// Parse disk
-	if v, ok := m["disk"]; ok && v != nil {
+	// Parse ssh
+	if v, ok := m["ssh"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := DiskSpecFromMap(obj)
+			ref, err := SSHReadinessSpecFromMap(obj)
 			if err != nil {
-				return nil, fmt.Errorf("field disk: %w", err)
+				return nil, fmt.Errorf("field ssh: %w", err)
 			}
 			if ref != nil {
-				s.Disk = *ref
-			}
-		} else {
-			return nil, fmt.Errorf("field disk: expected object, got %T", v)
-		}
-	}
-	// Parse memory
-	if v, ok := m["memory"]; ok && v != nil {
-		switch val := v.(type) {
-		case int:
-			s.Memory = val
-		case int64:
-			s.Memory = int(val)
-		case float64:
-			s.Memory = int(val)
-		default:
-			return nil, fmt.Errorf("field memory: expected int, got %T", v)
-		}
-	}
-	// Parse network
-	if v, ok := m["network"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Network = val
-		} else {
-			return nil, fmt.Errorf("field network: expected string, got %T", v)
-		}
-	}
-	// Parse networks
-	if v, ok := m["networks"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.Networks = make([]string, 0, len(arr))
-			for i, item := range arr {
-				if str, ok := item.(string); ok {
-					s.Networks = append(s.Networks, str)
-				} else {
-					return nil, fmt.Errorf("field networks[%d]: expected string, got %T", i, item)
-				}
+				s.Ssh = *ref
 			}
-		} else if arr, ok := v.([]string); ok {
-			s.Networks = arr
 		} else {
-			return nil, fmt.Errorf("field networks: expected []string, got %T", v)
+			return nil, fmt.Errorf("field ssh: expected object, got %T", v)
 		}
 	}
-	// Parse readiness
-	if v, ok := m["readiness"]; ok && v != nil {
+	// Parse tcp
+	if v, ok := m["tcp"]; ok && v != nil {
 		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := ReadinessSpecFromMap(obj)
+			ref, err := TCPReadinessSpecFromMap(obj)
 			if err != nil {
-				return nil, fmt.Errorf("field readiness: %w", err)
+				return nil, fmt.Errorf("field tcp: %w", err)
 			}
 			if ref != nil {
-				s.Readiness = *ref
+				s.Tcp = *ref
 			}
 		} else {
-			return nil, fmt.Errorf("field readiness: expected object, got %T", v)
-		}
-	}
-	// Parse vcpus
-	if v, ok := m["vcpus"]; ok && v != nil {
-		switch val := v.(type) {
-		case int:
-			s.Vcpus = val
-		case int64:
-			s.Vcpus = int(val)
-		case float64:
-			s.Vcpus = int(val)
-		default:
-			return nil, fmt.Errorf("field vcpus: expected int, got %T", v)
+			return nil, fmt.Errorf("field tcp: expected object, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// VMResourceFromMap creates a VMResource from a map[string]interface{}.
-func VMResourceFromMap(m map[string]interface{}) (*VMResource, error) {
+// DiskSpecFromMap creates a DiskSpec from a map[string]interface{}.
+func DiskSpecFromMap(m map[string]interface{}) (*DiskSpec, error) {
 	if m == nil {
-		return &VMResource{}, nil
+		return &DiskSpec{}, nil
 	}
 
-	s := &VMResource{}
-	// Parse name
-	if v, ok := m["name"]; ok && v != nil {
+	s := &DiskSpec{}
+	// Parse baseImage
+	if v, ok := m["baseImage"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.Name = val
+			s.BaseImage = val
 		} else {
-			return nil, fmt.Errorf("field name: expected string, got %T", v)
+			return nil, fmt.Errorf("field baseImage: expected string, got %T", v)
 		}
 	}
-	// Parse provider
-	if v, ok := m["provider"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.Provider = val
+	// Parse encryption
+	if v, ok := m["encryption"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DiskEncryptionSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field encryption: %w", err)
+			}
+			s.Encryption = ref
 		} else {
-			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+			return nil, fmt.Errorf("field encryption: expected object, got %T", v)
 		}
 	}
-	// Parse providerSpec
-	if v, ok := m["providerSpec"]; ok && v != nil {
-		if mapVal, ok := v.(map[string]interface{}); ok {
-			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
-			for key, val := range mapVal {
-				s.ProviderSpec[key] = val.(interface{})
-			}
+	// Parse fstrim
+	if v, ok := m["fstrim"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Fstrim = val
 		} else {
-			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+			return nil, fmt.Errorf("field fstrim: expected bool, got %T", v)
 		}
 	}
-	// Parse spec
-	if v, ok := m["spec"]; ok && v != nil {
-		if obj, ok := v.(map[string]interface{}); ok {
-			ref, err := VMSpecFromMap(obj)
-			if err != nil {
-				return nil, fmt.Errorf("field spec: %w", err)
-			}
-			if ref != nil {
-				s.Spec = *ref
-			}
+	// Parse size
+	if v, ok := m["size"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Size = val
 		} else {
-			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+			return nil, fmt.Errorf("field size: expected string, got %T", v)
 		}
 	}
 	return s, nil
 }
 
-// SpecFromMap creates a Spec from a map[string]interface{}.
-func SpecFromMap(m map[string]interface{}) (*Spec, error) {
+// GuestBondSpecFromMap creates a GuestBondSpec from a map[string]interface{}.
+func GuestBondSpecFromMap(m map[string]interface{}) (*GuestBondSpec, error) {
 	if m == nil {
-		return &Spec{}, nil
+		return &GuestBondSpec{}, nil
 	}
 
-	s := &Spec{}
-	// Parse artifactDir
-	if v, ok := m["artifactDir"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.ArtifactDir = val
+	s := &GuestBondSpec{}
+	// Parse addresses
+	if v, ok := m["addresses"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Addresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Addresses = append(s.Addresses, str)
+				} else {
+					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Addresses = arr
 		} else {
-			return nil, fmt.Errorf("field artifactDir: expected string, got %T", v)
+			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
 		}
 	}
-	// Parse cleanupOnFailure
-	if v, ok := m["cleanupOnFailure"]; ok && v != nil {
+	// Parse dhcp4
+	if v, ok := m["dhcp4"]; ok && v != nil {
 		if val, ok := v.(bool); ok {
-			s.CleanupOnFailure = val
+			s.Dhcp4 = val
 		} else {
-			return nil, fmt.Errorf("field cleanupOnFailure: expected bool, got %T", v)
+			return nil, fmt.Errorf("field dhcp4: expected bool, got %T", v)
 		}
 	}
-	// Parse defaultBaseImage
-	if v, ok := m["defaultBaseImage"]; ok && v != nil {
+	// Parse gateway4
+	if v, ok := m["gateway4"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.DefaultBaseImage = val
+			s.Gateway4 = val
 		} else {
-			return nil, fmt.Errorf("field defaultBaseImage: expected string, got %T", v)
+			return nil, fmt.Errorf("field gateway4: expected string, got %T", v)
 		}
 	}
-	// Parse defaultProvider
-	if v, ok := m["defaultProvider"]; ok && v != nil {
+	// Parse interfaces
+	if v, ok := m["interfaces"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Interfaces = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Interfaces = append(s.Interfaces, str)
+				} else {
+					return nil, fmt.Errorf("field interfaces[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Interfaces = arr
+		} else {
+			return nil, fmt.Errorf("field interfaces: expected []string, got %T", v)
+		}
+	}
+	// Parse mode
+	if v, ok := m["mode"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.DefaultProvider = val
+			s.Mode = val
 		} else {
-			return nil, fmt.Errorf("field defaultProvider: expected string, got %T", v)
+			return nil, fmt.Errorf("field mode: expected string, got %T", v)
 		}
 	}
-	// Parse imageCacheDir
-	if v, ok := m["imageCacheDir"]; ok && v != nil {
+	// Parse mtu
+	if v, ok := m["mtu"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Mtu = val
+		case int64:
+			s.Mtu = int(val)
+		case float64:
+			s.Mtu = int(val)
+		default:
+			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
+		}
+	}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
 		if val, ok := v.(string); ok {
-			s.ImageCacheDir = val
+			s.Name = val
 		} else {
-			return nil, fmt.Errorf("field imageCacheDir: expected string, got %T", v)
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
 		}
 	}
-	// Parse images
-	if v, ok := m["images"]; ok && v != nil {
+	// Parse nameservers
+	if v, ok := m["nameservers"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := GuestNameserversSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field nameservers: %w", err)
+			}
+			if ref != nil {
+				s.Nameservers = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field nameservers: expected object, got %T", v)
+		}
+	}
+	// Parse routes
+	if v, ok := m["routes"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Images = make([]ImageResource, 0, len(arr))
+			s.Routes = make([]GuestRouteSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := ImageResourceFromMap(obj)
+					ref, err := GuestRouteSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field images[%d]: %w", i, err)
+						return nil, fmt.Errorf("field routes[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.Images = append(s.Images, *ref)
+						s.Routes = append(s.Routes, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field images[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field routes[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field images: expected []object, got %T", v)
+			return nil, fmt.Errorf("field routes: expected []object, got %T", v)
 		}
 	}
-	// Parse keys
-	if v, ok := m["keys"]; ok && v != nil {
+	return s, nil
+}
+
+// GuestEthernetSpecFromMap creates a GuestEthernetSpec from a map[string]interface{}.
+func GuestEthernetSpecFromMap(m map[string]interface{}) (*GuestEthernetSpec, error) {
+	if m == nil {
+		return &GuestEthernetSpec{}, nil
+	}
+
+	s := &GuestEthernetSpec{}
+	// Parse addresses
+	if v, ok := m["addresses"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Keys = make([]KeyResource, 0, len(arr))
+			s.Addresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Addresses = append(s.Addresses, str)
+				} else {
+					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Addresses = arr
+		} else {
+			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
+		}
+	}
+	// Parse dhcp4
+	if v, ok := m["dhcp4"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Dhcp4 = val
+		} else {
+			return nil, fmt.Errorf("field dhcp4: expected bool, got %T", v)
+		}
+	}
+	// Parse gateway4
+	if v, ok := m["gateway4"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Gateway4 = val
+		} else {
+			return nil, fmt.Errorf("field gateway4: expected string, got %T", v)
+		}
+	}
+	// Parse match
+	if v, ok := m["match"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Match = val
+		} else {
+			return nil, fmt.Errorf("field match: expected string, got %T", v)
+		}
+	}
+	// Parse mtu
+	if v, ok := m["mtu"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Mtu = val
+		case int64:
+			s.Mtu = int(val)
+		case float64:
+			s.Mtu = int(val)
+		default:
+			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
+		}
+	}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse nameservers
+	if v, ok := m["nameservers"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := GuestNameserversSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field nameservers: %w", err)
+			}
+			if ref != nil {
+				s.Nameservers = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field nameservers: expected object, got %T", v)
+		}
+	}
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Network = val
+		} else {
+			return nil, fmt.Errorf("field network: expected string, got %T", v)
+		}
+	}
+	// Parse routes
+	if v, ok := m["routes"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Routes = make([]GuestRouteSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := KeyResourceFromMap(obj)
+					ref, err := GuestRouteSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field keys[%d]: %w", i, err)
+						return nil, fmt.Errorf("field routes[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.Keys = append(s.Keys, *ref)
+						s.Routes = append(s.Routes, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field keys[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field routes[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field keys: expected []object, got %T", v)
+			return nil, fmt.Errorf("field routes: expected []object, got %T", v)
 		}
 	}
-	// Parse networks
-	if v, ok := m["networks"]; ok && v != nil {
+	return s, nil
+}
+
+// GuestVLANSpecFromMap creates a GuestVLANSpec from a map[string]interface{}.
+func GuestVLANSpecFromMap(m map[string]interface{}) (*GuestVLANSpec, error) {
+	if m == nil {
+		return &GuestVLANSpec{}, nil
+	}
+
+	s := &GuestVLANSpec{}
+	// Parse addresses
+	if v, ok := m["addresses"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Networks = make([]NetworkResource, 0, len(arr))
+			s.Addresses = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Addresses = append(s.Addresses, str)
+				} else {
+					return nil, fmt.Errorf("field addresses[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Addresses = arr
+		} else {
+			return nil, fmt.Errorf("field addresses: expected []string, got %T", v)
+		}
+	}
+	// Parse dhcp4
+	if v, ok := m["dhcp4"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.Dhcp4 = val
+		} else {
+			return nil, fmt.Errorf("field dhcp4: expected bool, got %T", v)
+		}
+	}
+	// Parse gateway4
+	if v, ok := m["gateway4"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Gateway4 = val
+		} else {
+			return nil, fmt.Errorf("field gateway4: expected string, got %T", v)
+		}
+	}
+	// Parse id
+	if v, ok := m["id"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Id = val
+		case int64:
+			s.Id = int(val)
+		case float64:
+			s.Id = int(val)
+		default:
+			return nil, fmt.Errorf("field id: expected int, got %T", v)
+		}
+	}
+	// Parse link
+	if v, ok := m["link"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Link = val
+		} else {
+			return nil, fmt.Errorf("field link: expected string, got %T", v)
+		}
+	}
+	// Parse mtu
+	if v, ok := m["mtu"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Mtu = val
+		case int64:
+			s.Mtu = int(val)
+		case float64:
+			s.Mtu = int(val)
+		default:
+			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
+		}
+	}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse nameservers
+	if v, ok := m["nameservers"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := GuestNameserversSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field nameservers: %w", err)
+			}
+			if ref != nil {
+				s.Nameservers = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field nameservers: expected object, got %T", v)
+		}
+	}
+	// Parse routes
+	if v, ok := m["routes"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Routes = make([]GuestRouteSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := NetworkResourceFromMap(obj)
+					ref, err := GuestRouteSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field networks[%d]: %w", i, err)
+						return nil, fmt.Errorf("field routes[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.Networks = append(s.Networks, *ref)
+						s.Routes = append(s.Routes, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field networks[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field routes[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field networks: expected []object, got %T", v)
+			return nil, fmt.Errorf("field routes: expected []object, got %T", v)
 		}
 	}
-	// Parse providers
-	if v, ok := m["providers"]; ok && v != nil {
+	return s, nil
+}
+
+// IdleSpecFromMap creates a IdleSpec from a map[string]interface{}.
+func IdleSpecFromMap(m map[string]interface{}) (*IdleSpec, error) {
+	if m == nil {
+		return &IdleSpec{}, nil
+	}
+
+	s := &IdleSpec{}
+	// Parse action
+	if v, ok := m["action"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Action = val
+		} else {
+			return nil, fmt.Errorf("field action: expected string, got %T", v)
+		}
+	}
+	// Parse after
+	if v, ok := m["after"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.After = val
+		} else {
+			return nil, fmt.Errorf("field after: expected string, got %T", v)
+		}
+	}
+	// Parse cpuPercent
+	if v, ok := m["cpuPercent"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.CpuPercent = val
+		case int64:
+			s.CpuPercent = int(val)
+		case float64:
+			s.CpuPercent = int(val)
+		default:
+			return nil, fmt.Errorf("field cpuPercent: expected int, got %T", v)
+		}
+	}
+	// Parse sshPort
+	if v, ok := m["sshPort"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.SshPort = val
+		case int64:
+			s.SshPort = int(val)
+		case float64:
+			s.SshPort = int(val)
+		default:
+			return nil, fmt.Errorf("field sshPort: expected int, got %T", v)
+		}
+	}
+	// Parse wake
+	if v, ok := m["wake"]; ok && v != nil {
 		if arr, ok := v.([]interface{}); ok {
-			s.Providers = make([]ProviderConfig, 0, len(arr))
+			s.Wake = make([]IdleWakeSpec, 0, len(arr))
 			for i, item := range arr {
 				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := ProviderConfigFromMap(obj)
+					ref, err := IdleWakeSpecFromMap(obj)
 					if err != nil {
-						return nil, fmt.Errorf("field providers[%d]: %w", i, err)
+						return nil, fmt.Errorf("field wake[%d]: %w", i, err)
 					}
 					if ref != nil {
-						s.Providers = append(s.Providers, *ref)
+						s.Wake = append(s.Wake, *ref)
 					}
 				} else {
-					return nil, fmt.Errorf("field providers[%d]: expected object, got %T", i, item)
+					return nil, fmt.Errorf("field wake[%d]: expected object, got %T", i, item)
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("field providers: expected []object, got %T", v)
+			return nil, fmt.Errorf("field wake: expected []object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// ImageSpecFromMap creates a ImageSpec from a map[string]interface{}.
+func ImageSpecFromMap(m map[string]interface{}) (*ImageSpec, error) {
+	if m == nil {
+		return &ImageSpec{}, nil
+	}
+
+	s := &ImageSpec{}
+	// Parse alias
+	if v, ok := m["alias"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Alias = val
+		} else {
+			return nil, fmt.Errorf("field alias: expected string, got %T", v)
 		}
 	}
-	// Parse stateDir
-	if v, ok := m["stateDir"]; ok && v != nil {
-		if val, ok := v.(string); ok {
-			s.StateDir = val
-		} else {
-			return nil, fmt.Errorf("field stateDir: expected string, got %T", v)
-		}
+	// Parse customize
+	if v, ok := m["customize"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ImageCustomizeSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field customize: %w", err)
+			}
+			s.Customize = ref
+		} else {
+			return nil, fmt.Errorf("field customize: expected object, got %T", v)
+		}
+	}
+	// Parse sha256
+	if v, ok := m["sha256"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Sha256 = val
+		} else {
+			return nil, fmt.Errorf("field sha256: expected string, got %T", v)
+		}
+	}
+	// Parse source
+	if v, ok := m["source"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Source = val
+		} else {
+			return nil, fmt.Errorf("field source: expected string, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// KeyResourceFromMap creates a KeyResource from a map[string]interface{}.
+func KeyResourceFromMap(m map[string]interface{}) (*KeyResource, error) {
+	if m == nil {
+		return &KeyResource{}, nil
+	}
+
+	s := &KeyResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse provider
+	if v, ok := m["provider"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Provider = val
+		} else {
+			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+		}
+	}
+	// Parse providerSpec
+	if v, ok := m["providerSpec"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
+			for key, val := range mapVal {
+				s.ProviderSpec[key] = val.(interface{})
+			}
+		} else {
+			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := KeySpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// ServiceResourceFromMap creates a ServiceResource from a map[string]interface{}.
+func ServiceResourceFromMap(m map[string]interface{}) (*ServiceResource, error) {
+	if m == nil {
+		return &ServiceResource{}, nil
+	}
+
+	s := &ServiceResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ServiceSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// TunnelResourceFromMap creates a TunnelResource from a map[string]interface{}.
+func TunnelResourceFromMap(m map[string]interface{}) (*TunnelResource, error) {
+	if m == nil {
+		return &TunnelResource{}, nil
+	}
+
+	s := &TunnelResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := TunnelSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// VMDevicesSpecFromMap creates a VMDevicesSpec from a map[string]interface{}.
+func VMDevicesSpecFromMap(m map[string]interface{}) (*VMDevicesSpec, error) {
+	if m == nil {
+		return &VMDevicesSpec{}, nil
+	}
+
+	s := &VMDevicesSpec{}
+	// Parse mdevs
+	if v, ok := m["mdevs"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Mdevs = make([]MdevSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := MdevSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field mdevs[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Mdevs = append(s.Mdevs, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field mdevs[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field mdevs: expected []object, got %T", v)
+		}
+	}
+	// Parse pciPassthrough
+	if v, ok := m["pciPassthrough"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.PciPassthrough = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.PciPassthrough = append(s.PciPassthrough, str)
+				} else {
+					return nil, fmt.Errorf("field pciPassthrough[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.PciPassthrough = arr
+		} else {
+			return nil, fmt.Errorf("field pciPassthrough: expected []string, got %T", v)
+		}
+	}
+	// Parse vsock
+	if v, ok := m["vsock"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VsockSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field vsock: %w", err)
+			}
+			s.Vsock = ref
+		} else {
+			return nil, fmt.Errorf("field vsock: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// CloudInitNetworkConfigFromMap creates a CloudInitNetworkConfig from a map[string]interface{}.
+func CloudInitNetworkConfigFromMap(m map[string]interface{}) (*CloudInitNetworkConfig, error) {
+	if m == nil {
+		return &CloudInitNetworkConfig{}, nil
+	}
+
+	s := &CloudInitNetworkConfig{}
+	// Parse ethernets
+	if v, ok := m["ethernets"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Ethernets = make([]CloudInitEthernetConfig, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := CloudInitEthernetConfigFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field ethernets[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Ethernets = append(s.Ethernets, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field ethernets[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field ethernets: expected []object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// NetworkDefaultsSpecFromMap creates a NetworkDefaultsSpec from a map[string]interface{}.
+func NetworkDefaultsSpecFromMap(m map[string]interface{}) (*NetworkDefaultsSpec, error) {
+	if m == nil {
+		return &NetworkDefaultsSpec{}, nil
+	}
+
+	s := &NetworkDefaultsSpec{}
+	// Parse dhcp
+	if v, ok := m["dhcp"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DHCPSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field dhcp: %w", err)
+			}
+			s.Dhcp = ref
+		} else {
+			return nil, fmt.Errorf("field dhcp: expected object, got %T", v)
+		}
+	}
+	// Parse dns
+	if v, ok := m["dns"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DNSSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field dns: %w", err)
+			}
+			s.Dns = ref
+		} else {
+			return nil, fmt.Errorf("field dns: expected object, got %T", v)
+		}
+	}
+	// Parse kind
+	if v, ok := m["kind"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Kind = val
+		} else {
+			return nil, fmt.Errorf("field kind: expected string, got %T", v)
+		}
+	}
+	// Parse mtu
+	if v, ok := m["mtu"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Mtu = val
+		case int64:
+			s.Mtu = int(val)
+		case float64:
+			s.Mtu = int(val)
+		default:
+			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// NetworkSpecFromMap creates a NetworkSpec from a map[string]interface{}.
+func NetworkSpecFromMap(m map[string]interface{}) (*NetworkSpec, error) {
+	if m == nil {
+		return &NetworkSpec{}, nil
+	}
+
+	s := &NetworkSpec{}
+	// Parse attachTo
+	if v, ok := m["attachTo"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.AttachTo = val
+		} else {
+			return nil, fmt.Errorf("field attachTo: expected string, got %T", v)
+		}
+	}
+	// Parse cidr
+	if v, ok := m["cidr"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Cidr = val
+		} else {
+			return nil, fmt.Errorf("field cidr: expected string, got %T", v)
+		}
+	}
+	// Parse dhcp
+	if v, ok := m["dhcp"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DHCPSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field dhcp: %w", err)
+			}
+			s.Dhcp = ref
+		} else {
+			return nil, fmt.Errorf("field dhcp: expected object, got %T", v)
+		}
+	}
+	// Parse dns
+	if v, ok := m["dns"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DNSSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field dns: %w", err)
+			}
+			s.Dns = ref
+		} else {
+			return nil, fmt.Errorf("field dns: expected object, got %T", v)
+		}
+	}
+	// Parse gateway
+	if v, ok := m["gateway"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Gateway = val
+		} else {
+			return nil, fmt.Errorf("field gateway: expected string, got %T", v)
+		}
+	}
+	// Parse mtu
+	if v, ok := m["mtu"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Mtu = val
+		case int64:
+			s.Mtu = int(val)
+		case float64:
+			s.Mtu = int(val)
+		default:
+			return nil, fmt.Errorf("field mtu: expected int, got %T", v)
+		}
+	}
+	// Parse ntp
+	if v, ok := m["ntp"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := NTPSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field ntp: %w", err)
+			}
+			s.Ntp = ref
+		} else {
+			return nil, fmt.Errorf("field ntp: expected object, got %T", v)
+		}
+	}
+	// Parse tftp
+	if v, ok := m["tftp"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := TFTPSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field tftp: %w", err)
+			}
+			s.Tftp = ref
+		} else {
+			return nil, fmt.Errorf("field tftp: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// VMDefaultsSpecFromMap creates a VMDefaultsSpec from a map[string]interface{}.
+func VMDefaultsSpecFromMap(m map[string]interface{}) (*VMDefaultsSpec, error) {
+	if m == nil {
+		return &VMDefaultsSpec{}, nil
+	}
+
+	s := &VMDefaultsSpec{}
+	// Parse disk
+	if v, ok := m["disk"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DiskDefaultsSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field disk: %w", err)
+			}
+			if ref != nil {
+				s.Disk = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field disk: expected object, got %T", v)
+		}
+	}
+	// Parse memory
+	if v, ok := m["memory"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Memory = val
+		case int64:
+			s.Memory = int(val)
+		case float64:
+			s.Memory = int(val)
+		default:
+			return nil, fmt.Errorf("field memory: expected int, got %T", v)
+		}
+	}
+	// Parse readiness
+	if v, ok := m["readiness"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ReadinessSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field readiness: %w", err)
+			}
+			if ref != nil {
+				s.Readiness = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field readiness: expected object, got %T", v)
+		}
+	}
+	// Parse vcpus
+	if v, ok := m["vcpus"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Vcpus = val
+		case int64:
+			s.Vcpus = int(val)
+		case float64:
+			s.Vcpus = int(val)
+		default:
+			return nil, fmt.Errorf("field vcpus: expected int, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// GuestNetworkConfigSpecFromMap creates a GuestNetworkConfigSpec from a map[string]interface{}.
+func GuestNetworkConfigSpecFromMap(m map[string]interface{}) (*GuestNetworkConfigSpec, error) {
+	if m == nil {
+		return &GuestNetworkConfigSpec{}, nil
+	}
+
+	s := &GuestNetworkConfigSpec{}
+	// Parse bonds
+	if v, ok := m["bonds"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Bonds = make([]GuestBondSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := GuestBondSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field bonds[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Bonds = append(s.Bonds, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field bonds[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field bonds: expected []object, got %T", v)
+		}
+	}
+	// Parse ethernets
+	if v, ok := m["ethernets"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Ethernets = make([]GuestEthernetSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := GuestEthernetSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field ethernets[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Ethernets = append(s.Ethernets, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field ethernets[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field ethernets: expected []object, got %T", v)
+		}
+	}
+	// Parse vlans
+	if v, ok := m["vlans"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Vlans = make([]GuestVLANSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := GuestVLANSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field vlans[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Vlans = append(s.Vlans, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field vlans[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field vlans: expected []object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// ImageResourceFromMap creates a ImageResource from a map[string]interface{}.
+func ImageResourceFromMap(m map[string]interface{}) (*ImageResource, error) {
+	if m == nil {
+		return &ImageResource{}, nil
+	}
+
+	s := &ImageResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ImageSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// CloudInitSpecFromMap creates a CloudInitSpec from a map[string]interface{}.
+func CloudInitSpecFromMap(m map[string]interface{}) (*CloudInitSpec, error) {
+	if m == nil {
+		return &CloudInitSpec{}, nil
+	}
+
+	s := &CloudInitSpec{}
+	// Parse environment
+	if v, ok := m["environment"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.Environment = make(map[string]string, len(mapVal))
+			for key, val := range mapVal {
+				if str, ok := val.(string); ok {
+					s.Environment[key] = str
+				} else {
+					return nil, fmt.Errorf("field environment[%s]: expected string, got %T", key, val)
+				}
+			}
+		} else if mapVal, ok := v.(map[string]string); ok {
+			s.Environment = mapVal
+		} else {
+			return nil, fmt.Errorf("field environment: expected map[string]string, got %T", v)
+		}
+	}
+	// Parse hostname
+	if v, ok := m["hostname"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Hostname = val
+		} else {
+			return nil, fmt.Errorf("field hostname: expected string, got %T", v)
+		}
+	}
+	// Parse networkConfig
+	if v, ok := m["networkConfig"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := CloudInitNetworkConfigFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field networkConfig: %w", err)
+			}
+			if ref != nil {
+				s.NetworkConfig = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field networkConfig: expected object, got %T", v)
+		}
+	}
+	// Parse packages
+	if v, ok := m["packages"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Packages = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Packages = append(s.Packages, str)
+				} else {
+					return nil, fmt.Errorf("field packages[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Packages = arr
+		} else {
+			return nil, fmt.Errorf("field packages: expected []string, got %T", v)
+		}
+	}
+	// Parse rawUserData
+	if v, ok := m["rawUserData"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.RawUserData = val
+		} else {
+			return nil, fmt.Errorf("field rawUserData: expected string, got %T", v)
+		}
+	}
+	// Parse runcmd
+	if v, ok := m["runcmd"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Runcmd = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Runcmd = append(s.Runcmd, str)
+				} else {
+					return nil, fmt.Errorf("field runcmd[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Runcmd = arr
+		} else {
+			return nil, fmt.Errorf("field runcmd: expected []string, got %T", v)
+		}
+	}
+	// Parse secretEnvironment
+	if v, ok := m["secretEnvironment"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.SecretEnvironment = make(map[string]string, len(mapVal))
+			for key, val := range mapVal {
+				if str, ok := val.(string); ok {
+					s.SecretEnvironment[key] = str
+				} else {
+					return nil, fmt.Errorf("field secretEnvironment[%s]: expected string, got %T", key, val)
+				}
+			}
+		} else if mapVal, ok := v.(map[string]string); ok {
+			s.SecretEnvironment = mapVal
+		} else {
+			return nil, fmt.Errorf("field secretEnvironment: expected map[string]string, got %T", v)
+		}
+	}
+	// Parse users
+	if v, ok := m["users"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Users = make([]UserSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := UserSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field users[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Users = append(s.Users, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field users[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field users: expected []object, got %T", v)
+		}
+	}
+	// Parse writeFiles
+	if v, ok := m["writeFiles"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.WriteFiles = make([]WriteFileSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := WriteFileSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field writeFiles[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.WriteFiles = append(s.WriteFiles, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field writeFiles[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field writeFiles: expected []object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// NetworkResourceFromMap creates a NetworkResource from a map[string]interface{}.
+func NetworkResourceFromMap(m map[string]interface{}) (*NetworkResource, error) {
+	if m == nil {
+		return &NetworkResource{}, nil
+	}
+
+	s := &NetworkResource{}
+	// Parse kind
+	if v, ok := m["kind"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Kind = val
+		} else {
+			return nil, fmt.Errorf("field kind: expected string, got %T", v)
+		}
+	}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse provider
+	if v, ok := m["provider"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Provider = val
+		} else {
+			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+		}
+	}
+	// Parse providerSpec
+	if v, ok := m["providerSpec"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
+			for key, val := range mapVal {
+				s.ProviderSpec[key] = val.(interface{})
+			}
+		} else {
+			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := NetworkSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// DefaultsSpecFromMap creates a DefaultsSpec from a map[string]interface{}.
+func DefaultsSpecFromMap(m map[string]interface{}) (*DefaultsSpec, error) {
+	if m == nil {
+		return &DefaultsSpec{}, nil
+	}
+
+	s := &DefaultsSpec{}
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := NetworkDefaultsSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field network: %w", err)
+			}
+			if ref != nil {
+				s.Network = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field network: expected object, got %T", v)
+		}
+	}
+	// Parse vm
+	if v, ok := m["vm"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VMDefaultsSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field vm: %w", err)
+			}
+			if ref != nil {
+				s.Vm = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field vm: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// VMSpecFromMap creates a VMSpec from a map[string]interface{}.
+func VMSpecFromMap(m map[string]interface{}) (*VMSpec, error) {
+	if m == nil {
+		return &VMSpec{}, nil
+	}
+
+	s := &VMSpec{}
+	// Parse agent
+	if v, ok := m["agent"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VMAgentSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field agent: %w", err)
+			}
+			s.Agent = ref
+		} else {
+			return nil, fmt.Errorf("field agent: expected object, got %T", v)
+		}
+	}
+	// Parse boot
+	if v, ok := m["boot"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := BootSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field boot: %w", err)
+			}
+			if ref != nil {
+				s.Boot = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field boot: expected object, got %T", v)
+		}
+	}
+	// Parse cloudInit
+	if v, ok := m["cloudInit"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := CloudInitSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field cloudInit: %w", err)
+			}
+			if ref != nil {
+				s.CloudInit = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field cloudInit: expected object, got %T", v)
+		}
+	}
+	// Parse devices
+	if v, ok := m["devices"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VMDevicesSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field devices: %w", err)
+			}
+			if ref != nil {
+				s.Devices = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field devices: expected object, got %T", v)
+		}
+	}
+	// Parse disk
+	if v, ok := m["disk"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DiskSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field disk: %w", err)
+			}
+			if ref != nil {
+				s.Disk = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field disk: expected object, got %T", v)
+		}
+	}
+	// Parse disks
+	if v, ok := m["disks"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Disks = make([]DataDiskSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := DataDiskSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field disks[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Disks = append(s.Disks, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field disks[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field disks: expected []object, got %T", v)
+		}
+	}
+	// Parse ip
+	if v, ok := m["ip"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Ip = val
+		} else {
+			return nil, fmt.Errorf("field ip: expected string, got %T", v)
+		}
+	}
+	// Parse memory
+	if v, ok := m["memory"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Memory = val
+		case int64:
+			s.Memory = int(val)
+		case float64:
+			s.Memory = int(val)
+		default:
+			return nil, fmt.Errorf("field memory: expected int, got %T", v)
+		}
+	}
+	// Parse network
+	if v, ok := m["network"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Network = val
+		} else {
+			return nil, fmt.Errorf("field network: expected string, got %T", v)
+		}
+	}
+	// Parse networkConfig
+	if v, ok := m["networkConfig"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := GuestNetworkConfigSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field networkConfig: %w", err)
+			}
+			if ref != nil {
+				s.NetworkConfig = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field networkConfig: expected object, got %T", v)
+		}
+	}
+	// Parse networks
+	if v, ok := m["networks"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Networks = make([]string, 0, len(arr))
+			for i, item := range arr {
+				if str, ok := item.(string); ok {
+					s.Networks = append(s.Networks, str)
+				} else {
+					return nil, fmt.Errorf("field networks[%d]: expected string, got %T", i, item)
+				}
+			}
+		} else if arr, ok := v.([]string); ok {
+			s.Networks = arr
+		} else {
+			return nil, fmt.Errorf("field networks: expected []string, got %T", v)
+		}
+	}
+	// Parse nics
+	if v, ok := m["nics"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Nics = make([]VMNICSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := VMNICSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field nics[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Nics = append(s.Nics, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field nics[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field nics: expected []object, got %T", v)
+		}
+	}
+	// Parse readiness
+	if v, ok := m["readiness"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ReadinessSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field readiness: %w", err)
+			}
+			if ref != nil {
+				s.Readiness = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field readiness: expected object, got %T", v)
+		}
+	}
+	// Parse security
+	if v, ok := m["security"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VMSecuritySpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field security: %w", err)
+			}
+			s.Security = ref
+		} else {
+			return nil, fmt.Errorf("field security: expected object, got %T", v)
+		}
+	}
+	// Parse vcpus
+	if v, ok := m["vcpus"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Vcpus = val
+		case int64:
+			s.Vcpus = int(val)
+		case float64:
+			s.Vcpus = int(val)
+		default:
+			return nil, fmt.Errorf("field vcpus: expected int, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// VMResourceFromMap creates a VMResource from a map[string]interface{}.
+func VMResourceFromMap(m map[string]interface{}) (*VMResource, error) {
+	if m == nil {
+		return &VMResource{}, nil
+	}
+
+	s := &VMResource{}
+	// Parse name
+	if v, ok := m["name"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Name = val
+		} else {
+			return nil, fmt.Errorf("field name: expected string, got %T", v)
+		}
+	}
+	// Parse provider
+	if v, ok := m["provider"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Provider = val
+		} else {
+			return nil, fmt.Errorf("field provider: expected string, got %T", v)
+		}
+	}
+	// Parse providerSpec
+	if v, ok := m["providerSpec"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.ProviderSpec = make(map[string]interface{}, len(mapVal))
+			for key, val := range mapVal {
+				s.ProviderSpec[key] = val.(interface{})
+			}
+		} else {
+			return nil, fmt.Errorf("field providerSpec: expected map, got %T", v)
+		}
+	}
+	// Parse spec
+	if v, ok := m["spec"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := VMSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field spec: %w", err)
+			}
+			if ref != nil {
+				s.Spec = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field spec: expected object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// SpecFromMap creates a Spec from a map[string]interface{}.
+func SpecFromMap(m map[string]interface{}) (*Spec, error) {
+	if m == nil {
+		return &Spec{}, nil
+	}
+
+	s := &Spec{}
+	// Parse access
+	if v, ok := m["access"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Access = make([]AccessResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := AccessResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field access[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Access = append(s.Access, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field access[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field access: expected []object, got %T", v)
+		}
+	}
+	// Parse artifactDir
+	if v, ok := m["artifactDir"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.ArtifactDir = val
+		} else {
+			return nil, fmt.Errorf("field artifactDir: expected string, got %T", v)
+		}
+	}
+	// Parse ca
+	if v, ok := m["ca"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := CASpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field ca: %w", err)
+			}
+			if ref != nil {
+				s.Ca = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field ca: expected object, got %T", v)
+		}
+	}
+	// Parse certificates
+	if v, ok := m["certificates"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Certificates = make([]CertificateResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := CertificateResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field certificates[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Certificates = append(s.Certificates, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field certificates[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field certificates: expected []object, got %T", v)
+		}
+	}
+	// Parse cleanupOnFailure
+	if v, ok := m["cleanupOnFailure"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.CleanupOnFailure = val
+		} else {
+			return nil, fmt.Errorf("field cleanupOnFailure: expected bool, got %T", v)
+		}
+	}
+	// Parse createDeadline
+	if v, ok := m["createDeadline"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.CreateDeadline = val
+		} else {
+			return nil, fmt.Errorf("field createDeadline: expected string, got %T", v)
+		}
+	}
+	// Parse defaultBaseImage
+	if v, ok := m["defaultBaseImage"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.DefaultBaseImage = val
+		} else {
+			return nil, fmt.Errorf("field defaultBaseImage: expected string, got %T", v)
+		}
+	}
+	// Parse defaultProvider
+	if v, ok := m["defaultProvider"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.DefaultProvider = val
+		} else {
+			return nil, fmt.Errorf("field defaultProvider: expected string, got %T", v)
+		}
+	}
+	// Parse defaults
+	if v, ok := m["defaults"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := DefaultsSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field defaults: %w", err)
+			}
+			if ref != nil {
+				s.Defaults = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field defaults: expected object, got %T", v)
+		}
+	}
+	// Parse environmentId
+	if v, ok := m["environmentId"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.EnvironmentId = val
+		} else {
+			return nil, fmt.Errorf("field environmentId: expected string, got %T", v)
+		}
+	}
+	// Parse environmentIdTemplate
+	if v, ok := m["environmentIdTemplate"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.EnvironmentIdTemplate = val
+		} else {
+			return nil, fmt.Errorf("field environmentIdTemplate: expected string, got %T", v)
+		}
+	}
+	// Parse expiresAfter
+	if v, ok := m["expiresAfter"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.ExpiresAfter = val
+		} else {
+			return nil, fmt.Errorf("field expiresAfter: expected string, got %T", v)
+		}
+	}
+	// Parse hostsFile
+	if v, ok := m["hostsFile"]; ok && v != nil {
+		if val, ok := v.(bool); ok {
+			s.HostsFile = val
+		} else {
+			return nil, fmt.Errorf("field hostsFile: expected bool, got %T", v)
+		}
+	}
+	// Parse idle
+	if v, ok := m["idle"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := IdleSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field idle: %w", err)
+			}
+			if ref != nil {
+				s.Idle = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field idle: expected object, got %T", v)
+		}
+	}
+	// Parse imageCacheDir
+	if v, ok := m["imageCacheDir"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.ImageCacheDir = val
+		} else {
+			return nil, fmt.Errorf("field imageCacheDir: expected string, got %T", v)
+		}
+	}
+	// Parse images
+	if v, ok := m["images"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Images = make([]ImageResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := ImageResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field images[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Images = append(s.Images, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field images[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field images: expected []object, got %T", v)
+		}
+	}
+	// Parse keys
+	if v, ok := m["keys"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Keys = make([]KeyResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := KeyResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field keys[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Keys = append(s.Keys, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field keys[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field keys: expected []object, got %T", v)
+		}
+	}
+	// Parse labels
+	if v, ok := m["labels"]; ok && v != nil {
+		if mapVal, ok := v.(map[string]interface{}); ok {
+			s.Labels = make(map[string]string, len(mapVal))
+			for key, val := range mapVal {
+				if str, ok := val.(string); ok {
+					s.Labels[key] = str
+				} else {
+					return nil, fmt.Errorf("field labels[%s]: expected string, got %T", key, val)
+				}
+			}
+		} else if mapVal, ok := v.(map[string]string); ok {
+			s.Labels = mapVal
+		} else {
+			return nil, fmt.Errorf("field labels: expected map[string]string, got %T", v)
+		}
+	}
+	// Parse namePrefix
+	if v, ok := m["namePrefix"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.NamePrefix = val
+		} else {
+			return nil, fmt.Errorf("field namePrefix: expected string, got %T", v)
+		}
+	}
+	// Parse networks
+	if v, ok := m["networks"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Networks = make([]NetworkResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := NetworkResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field networks[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Networks = append(s.Networks, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field networks[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field networks: expected []object, got %T", v)
+		}
+	}
+	// Parse notifiers
+	if v, ok := m["notifiers"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Notifiers = make([]NotifierSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := NotifierSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field notifiers[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Notifiers = append(s.Notifiers, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field notifiers[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field notifiers: expected []object, got %T", v)
+		}
+	}
+	// Parse parent
+	if v, ok := m["parent"]; ok && v != nil {
+		if obj, ok := v.(map[string]interface{}); ok {
+			ref, err := ParentSpecFromMap(obj)
+			if err != nil {
+				return nil, fmt.Errorf("field parent: %w", err)
+			}
+			if ref != nil {
+				s.Parent = *ref
+			}
+		} else {
+			return nil, fmt.Errorf("field parent: expected object, got %T", v)
+		}
+	}
+	// Parse priority
+	if v, ok := m["priority"]; ok && v != nil {
+		switch val := v.(type) {
+		case int:
+			s.Priority = val
+		case int64:
+			s.Priority = int(val)
+		case float64:
+			s.Priority = int(val)
+		default:
+			return nil, fmt.Errorf("field priority: expected int, got %T", v)
+		}
+	}
+	// Parse providers
+	if v, ok := m["providers"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Providers = make([]ProviderConfig, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := ProviderConfigFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field providers[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Providers = append(s.Providers, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field providers[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field providers: expected []object, got %T", v)
+		}
+	}
+	// Parse seed
+	if v, ok := m["seed"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.Seed = val
+		} else {
+			return nil, fmt.Errorf("field seed: expected string, got %T", v)
+		}
+	}
+	// Parse services
+	if v, ok := m["services"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Services = make([]ServiceResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := ServiceResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field services[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Services = append(s.Services, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field services[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field services: expected []object, got %T", v)
+		}
+	}
+	// Parse specRef
+	if v, ok := m["specRef"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.SpecRef = val
+		} else {
+			return nil, fmt.Errorf("field specRef: expected string, got %T", v)
+		}
+	}
+	// Parse stateDir
+	if v, ok := m["stateDir"]; ok && v != nil {
+		if val, ok := v.(string); ok {
+			s.StateDir = val
+		} else {
+			return nil, fmt.Errorf("field stateDir: expected string, got %T", v)
+		}
+	}
+	// Parse tunnels
+	if v, ok := m["tunnels"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Tunnels = make([]TunnelResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := TunnelResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field tunnels[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Tunnels = append(s.Tunnels, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field tunnels[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field tunnels: expected []object, got %T", v)
+		}
+	}
+	// Parse vms
+	if v, ok := m["vms"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Vms = make([]VMResource, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := VMResourceFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field vms[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Vms = append(s.Vms, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field vms[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field vms: expected []object, got %T", v)
+		}
+	}
+	// Parse webhooks
+	if v, ok := m["webhooks"]; ok && v != nil {
+		if arr, ok := v.([]interface{}); ok {
+			s.Webhooks = make([]WebhookSpec, 0, len(arr))
+			for i, item := range arr {
+				if obj, ok := item.(map[string]interface{}); ok {
+					ref, err := WebhookSpecFromMap(obj)
+					if err != nil {
+						return nil, fmt.Errorf("field webhooks[%d]: %w", i, err)
+					}
+					if ref != nil {
+						s.Webhooks = append(s.Webhooks, *ref)
+					}
+				} else {
+					return nil, fmt.Errorf("field webhooks[%d]: expected object, got %T", i, item)
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("field webhooks: expected []object, got %T", v)
+		}
+	}
+	return s, nil
+}
+
+// ToMap converts a AccessSpec to a map[string]interface{}.
+func (s *AccessSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Address != "" {
+		m["address"] = s.Address
+	}
+	if s.Endpoint != "" {
+		m["endpoint"] = s.Endpoint
+	}
+	if s.ListenPort != 0 {
+		m["listenPort"] = s.ListenPort
+	}
+	if s.Network != "" {
+		m["network"] = s.Network
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.Vm != "" {
+		m["vm"] = s.Vm
+	}
+	return m
+}
+
+// ToMap converts a BootArtifactSpec to a map[string]interface{}.
+func (s *BootArtifactSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Path != "" {
+		m["path"] = s.Path
+	}
+	if s.Sha256 != "" {
+		m["sha256"] = s.Sha256
+	}
+	if s.Url != "" {
+		m["url"] = s.Url
+	}
+	return m
+}
+
+// ToMap converts a BootFileSpec to a map[string]interface{}.
+func (s *BootFileSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Content != "" {
+		m["content"] = s.Content
+	}
+	if s.Path != "" {
+		m["path"] = s.Path
+	}
+	return m
+}
+
+// ToMap converts a BootSpec to a map[string]interface{}.
+func (s *BootSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Firmware != "" {
+		m["firmware"] = s.Firmware
+	}
+	if len(s.Order) > 0 {
+		m["order"] = s.Order
+	}
+	return m
+}
+
+// ToMap converts a CASpec to a map[string]interface{}.
+func (s *CASpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.CommonName != "" {
+		m["commonName"] = s.CommonName
+	}
+	if s.Validity != "" {
+		m["validity"] = s.Validity
+	}
+	if len(s.Vms) > 0 {
+		m["vms"] = s.Vms
+	}
+	return m
+}
+
+// ToMap converts a CertificateSpec to a map[string]interface{}.
+func (s *CertificateSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Client {
+		m["client"] = s.Client
+	}
+	if s.CommonName != "" {
+		m["commonName"] = s.CommonName
+	}
+	if len(s.DnsNames) > 0 {
+		m["dnsNames"] = s.DnsNames
+	}
+	if len(s.IpAddresses) > 0 {
+		m["ipAddresses"] = s.IpAddresses
+	}
+	if s.Path != "" {
+		m["path"] = s.Path
+	}
+	if s.Validity != "" {
+		m["validity"] = s.Validity
+	}
+	if s.Vm != "" {
+		m["vm"] = s.Vm
+	}
+	return m
+}
+
+// ToMap converts a CloudInitNameservers to a map[string]interface{}.
+func (s *CloudInitNameservers) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
+	}
+	return m
+}
+
+// ToMap converts a CloudInitReadinessSpec to a map[string]interface{}.
+func (s *CloudInitReadinessSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if s.Timeout != "" {
+		m["timeout"] = s.Timeout
+	}
+	return m
+}
+
+// ToMap converts a UserSpec to a map[string]interface{}.
+func (s *UserSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	if s.Shell != "" {
+		m["shell"] = s.Shell
+	}
+	if len(s.SshAuthorizedKeys) > 0 {
+		m["sshAuthorizedKeys"] = s.SshAuthorizedKeys
+	}
+	if s.Sudo != "" {
+		m["sudo"] = s.Sudo
+	}
+	return m
+}
+
+// ToMap converts a WriteFileSpec to a map[string]interface{}.
+func (s *WriteFileSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Content != "" {
+		m["content"] = s.Content
+	}
+	if s.Path != "" {
+		m["path"] = s.Path
+	}
+	if s.Permissions != "" {
+		m["permissions"] = s.Permissions
+	}
+	return m
+}
+
+// ToMap converts a DHCPHostSpec to a map[string]interface{}.
+func (s *DHCPHostSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Hostname != "" {
+		m["hostname"] = s.Hostname
+	}
+	if s.Ip != "" {
+		m["ip"] = s.Ip
+	}
+	if s.Mac != "" {
+		m["mac"] = s.Mac
+	}
+	return m
+}
+
+// ToMap converts a DNSRecordSpec to a map[string]interface{}.
+func (s *DNSRecordSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.Value != "" {
+		m["value"] = s.Value
+	}
+	return m
+}
+
+// ToMap converts a DataDiskSpec to a map[string]interface{}.
+func (s *DataDiskSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.BaseImage != "" {
+		m["baseImage"] = s.BaseImage
+	}
+	if s.Format != "" {
+		m["format"] = s.Format
+	}
+	if s.Size != "" {
+		m["size"] = s.Size
+	}
+	return m
+}
+
+// ToMap converts a DiskDefaultsSpec to a map[string]interface{}.
+func (s *DiskDefaultsSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.BaseImage != "" {
+		m["baseImage"] = s.BaseImage
+	}
+	if s.Size != "" {
+		m["size"] = s.Size
+	}
+	return m
+}
+
+// ToMap converts a SSHReadinessSpec to a map[string]interface{}.
+func (s *SSHReadinessSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if s.PrivateKey != "" {
+		m["privateKey"] = s.PrivateKey
+	}
+	if s.ProxyJump != "" {
+		m["proxyJump"] = s.ProxyJump
+	}
+	if s.Timeout != "" {
+		m["timeout"] = s.Timeout
+	}
+	if s.User != "" {
+		m["user"] = s.User
+	}
+	return m
+}
+
+// ToMap converts a TCPReadinessSpec to a map[string]interface{}.
+func (s *TCPReadinessSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Port != 0 {
+		m["port"] = s.Port
+	}
+	if s.Timeout != "" {
+		m["timeout"] = s.Timeout
+	}
+	return m
+}
+
+// ToMap converts a DiskEncryptionSpec to a map[string]interface{}.
+func (s *DiskEncryptionSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if s.PassphraseSecretRef != "" {
+		m["passphraseSecretRef"] = s.PassphraseSecretRef
+	}
+	return m
+}
+
+// ToMap converts a GuestNameserversSpec to a map[string]interface{}.
+func (s *GuestNameserversSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
+	}
+	if len(s.Search) > 0 {
+		m["search"] = s.Search
+	}
+	return m
+}
+
+// ToMap converts a GuestRouteSpec to a map[string]interface{}.
+func (s *GuestRouteSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Metric != 0 {
+		m["metric"] = s.Metric
+	}
+	if s.To != "" {
+		m["to"] = s.To
+	}
+	if s.Via != "" {
+		m["via"] = s.Via
+	}
+	return m
+}
+
+// ToMap converts a IdleWakeSpec to a map[string]interface{}.
+func (s *IdleWakeSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Listen != "" {
+		m["listen"] = s.Listen
+	}
+	if s.Port != 0 {
+		m["port"] = s.Port
+	}
+	if s.Vm != "" {
+		m["vm"] = s.Vm
+	}
+	return m
+}
+
+// ToMap converts a ImageCustomizeSpec to a map[string]interface{}.
+func (s *ImageCustomizeSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Packages) > 0 {
+		m["packages"] = s.Packages
+	}
+	if len(s.Runcmd) > 0 {
+		m["runcmd"] = s.Runcmd
+	}
+	return m
+}
+
+// ToMap converts a KeySpec to a map[string]interface{}.
+func (s *KeySpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Bits != 0 {
+		m["bits"] = s.Bits
+	}
+	if s.Comment != "" {
+		m["comment"] = s.Comment
+	}
+	if s.OutputDir != "" {
+		m["outputDir"] = s.OutputDir
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	return m
+}
+
+// ToMap converts a MdevSpec to a map[string]interface{}.
+func (s *MdevSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Parent != "" {
+		m["parent"] = s.Parent
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.Uuid != "" {
+		m["uuid"] = s.Uuid
+	}
+	return m
+}
+
+// ToMap converts a NTPSpec to a map[string]interface{}.
+func (s *NTPSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if len(s.Servers) > 0 {
+		m["servers"] = s.Servers
+	}
+	return m
+}
+
+// ToMap converts a NotifierSpec to a map[string]interface{}.
+func (s *NotifierSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.AccessTokenEnv != "" {
+		m["accessTokenEnv"] = s.AccessTokenEnv
+	}
+	if s.ArtifactUrl != "" {
+		m["artifactUrl"] = s.ArtifactUrl
+	}
+	if len(s.Events) > 0 {
+		m["events"] = s.Events
+	}
+	if s.Homeserver != "" {
+		m["homeserver"] = s.Homeserver
+	}
+	if s.RoomId != "" {
+		m["roomId"] = s.RoomId
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.WebhookUrlEnv != "" {
+		m["webhookUrlEnv"] = s.WebhookUrlEnv
+	}
+	return m
+}
+
+// ToMap converts a ParentSpec to a map[string]interface{}.
+func (s *ParentSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.EnvironmentId != "" {
+		m["environmentId"] = s.EnvironmentId
+	}
+	if len(s.Keys) > 0 {
+		m["keys"] = s.Keys
+	}
+	if len(s.Networks) > 0 {
+		m["networks"] = s.Networks
+	}
+	return m
+}
+
+// ToMap converts a ProviderConfig to a map[string]interface{}.
+func (s *ProviderConfig) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Default {
+		m["default"] = s.Default
+	}
+	if s.Engine != "" {
+		m["engine"] = s.Engine
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	if len(s.Spec) > 0 {
+		m["spec"] = s.Spec
+	}
+	return m
+}
+
+// ToMap converts a ResourceRef to a map[string]interface{}.
+func (s *ResourceRef) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Kind != "" {
+		m["kind"] = s.Kind
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	if s.Provider != "" {
+		m["provider"] = s.Provider
+	}
+	return m
+}
+
+// ToMap converts a ServiceSpec to a map[string]interface{}.
+func (s *ServiceSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.AccessKey != "" {
+		m["accessKey"] = s.AccessKey
+	}
+	if s.Network != "" {
+		m["network"] = s.Network
+	}
+	if s.Port != 0 {
+		m["port"] = s.Port
+	}
+	if s.Root != "" {
+		m["root"] = s.Root
+	}
+	if s.SecretKey != "" {
+		m["secretKey"] = s.SecretKey
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	if s.Upstream != "" {
+		m["upstream"] = s.Upstream
+	}
+	return m
+}
+
+// ToMap converts a TunnelSpec to a map[string]interface{}.
+func (s *TunnelSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Address != "" {
+		m["address"] = s.Address
+	}
+	if s.Endpoint != "" {
+		m["endpoint"] = s.Endpoint
+	}
+	if s.ListenPort != 0 {
+		m["listenPort"] = s.ListenPort
+	}
+	if s.LocalNetwork != "" {
+		m["localNetwork"] = s.LocalNetwork
+	}
+	if s.PersistentKeepalive != 0 {
+		m["persistentKeepalive"] = s.PersistentKeepalive
+	}
+	if s.RemoteNetwork != "" {
+		m["remoteNetwork"] = s.RemoteNetwork
+	}
+	if s.Type != "" {
+		m["type"] = s.Type
+	}
+	return m
+}
+
+// ToMap converts a VMAgentSpec to a map[string]interface{}.
+func (s *VMAgentSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if s.Port != 0 {
+		m["port"] = s.Port
+	}
+	return m
+}
+
+// ToMap converts a VsockSpec to a map[string]interface{}.
+func (s *VsockSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
 	}
-	// Parse vms
-	if v, ok := m["vms"]; ok && v != nil {
-		if arr, ok := v.([]interface{}); ok {
-			s.Vms = make([]VMResource, 0, len(arr))
-			for i, item := range arr {
-				if obj, ok := item.(map[string]interface{}); ok {
-					ref, err := VMResourceFromMap(obj)
-					if err != nil {
-						return nil, fmt.Errorf("field vms[%d]: %w", i, err)
-					}
-					if ref != nil {
-						s.Vms = append(s.Vms, *ref)
-					}
-				} else {
-					return nil, fmt.Errorf("field vms[%d]: expected object, got %T", i, item)
-				}
-			}
-		} else {
-			return nil, fmt.Errorf("field vms: expected []object, got %T", v)
-		}
+
+	m := make(map[string]interface{})
+	if s.Cid != 0 {
+		m["cid"] = s.Cid
 	}
-	return s, nil
+	return m
 }
 
-// ToMap converts a BootSpec to a map[string]interface{}.
-func (s *BootSpec) ToMap() map[string]interface{} {
+// ToMap converts a VMNICSpec to a map[string]interface{}.
+func (s *VMNICSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Firmware != "" {
-		m["firmware"] = s.Firmware
+	if len(s.DisableOffloads) > 0 {
+		m["disableOffloads"] = s.DisableOffloads
 	}
-	if len(s.Order) > 0 {
-		m["order"] = s.Order
+	if s.Model != "" {
+		m["model"] = s.Model
+	}
+	if s.Mtu != 0 {
+		m["mtu"] = s.Mtu
+	}
+	if s.Network != "" {
+		m["network"] = s.Network
 	}
 	return m
 }
 
-// ToMap converts a CloudInitNameservers to a map[string]interface{}.
-func (s *CloudInitNameservers) ToMap() map[string]interface{} {
+// ToMap converts a VMSecuritySpec to a map[string]interface{}.
+func (s *VMSecuritySpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if len(s.Addresses) > 0 {
-		m["addresses"] = s.Addresses
+	if s.Disabled {
+		m["disabled"] = s.Disabled
+	}
+	if s.Label != "" {
+		m["label"] = s.Label
+	}
+	if s.Model != "" {
+		m["model"] = s.Model
+	}
+	if s.Relabel {
+		m["relabel"] = s.Relabel
 	}
 	return m
 }
 
-// ToMap converts a CloudInitReadinessSpec to a map[string]interface{}.
-func (s *CloudInitReadinessSpec) ToMap() map[string]interface{} {
+// ToMap converts a WebhookSpec to a map[string]interface{}.
+func (s *WebhookSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Enabled {
-		m["enabled"] = s.Enabled
+	if len(s.Events) > 0 {
+		m["events"] = s.Events
+	}
+	if len(s.Headers) > 0 {
+		m["headers"] = s.Headers
+	}
+	if s.MaxRetries != 0 {
+		m["maxRetries"] = s.MaxRetries
+	}
+	if s.SecretEnv != "" {
+		m["secretEnv"] = s.SecretEnv
 	}
 	if s.Timeout != "" {
 		m["timeout"] = s.Timeout
 	}
+	if s.Url != "" {
+		m["url"] = s.Url
+	}
 	return m
 }
 
-// ToMap converts a UserSpec to a map[string]interface{}.
-func (s *UserSpec) ToMap() map[string]interface{} {
+// ToMap converts a AccessResource to a map[string]interface{}.
+func (s *AccessResource) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
@@ -1815,30 +5540,91 @@ func (s *UserSpec) ToMap() map[string]interface{} {
 	if s.Name != "" {
 		m["name"] = s.Name
 	}
-	if len(s.SshAuthorizedKeys) > 0 {
-		m["sshAuthorizedKeys"] = s.SshAuthorizedKeys
+	// Reference type AccessSpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
 	}
-	if s.Sudo != "" {
-		m["sudo"] = s.Sudo
+	return m
+}
+
+// ToMap converts a TFTPSpec to a map[string]interface{}.
+func (s *TFTPSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Artifacts) > 0 {
+		arr := make([]interface{}, 0, len(s.Artifacts))
+		for _, item := range s.Artifacts {
+			arr = append(arr, item.ToMap())
+		}
+		m["artifacts"] = arr
+	}
+	if s.BootFile != "" {
+		m["bootFile"] = s.BootFile
+	}
+	if s.BootFileEfi != "" {
+		m["bootFileEfi"] = s.BootFileEfi
+	}
+	if len(s.DhcpBootOptions) > 0 {
+		m["dhcpBootOptions"] = s.DhcpBootOptions
+	}
+	if s.Enabled {
+		m["enabled"] = s.Enabled
+	}
+	if len(s.Files) > 0 {
+		arr := make([]interface{}, 0, len(s.Files))
+		for _, item := range s.Files {
+			arr = append(arr, item.ToMap())
+		}
+		m["files"] = arr
+	}
+	if s.Root != "" {
+		m["root"] = s.Root
 	}
 	return m
 }
 
-// ToMap converts a WriteFileSpec to a map[string]interface{}.
-func (s *WriteFileSpec) ToMap() map[string]interface{} {
+// ToMap converts a CertificateResource to a map[string]interface{}.
+func (s *CertificateResource) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Content != "" {
-		m["content"] = s.Content
+	if s.Name != "" {
+		m["name"] = s.Name
 	}
-	if s.Path != "" {
-		m["path"] = s.Path
+	// Reference type CertificateSpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
 	}
-	if s.Permissions != "" {
-		m["permissions"] = s.Permissions
+	return m
+}
+
+// ToMap converts a CloudInitEthernetConfig to a map[string]interface{}.
+func (s *CloudInitEthernetConfig) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
+	}
+	if s.Dhcp4 {
+		m["dhcp4"] = s.Dhcp4
+	}
+	if s.Gateway4 != "" {
+		m["gateway4"] = s.Gateway4
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	// Reference type CloudInitNameservers
+	if refMap := s.Nameservers.ToMap(); len(refMap) > 0 {
+		m["nameservers"] = refMap
 	}
 	return m
 }
@@ -1856,6 +5642,13 @@ func (s *DHCPSpec) ToMap() map[string]interface{} {
 	if s.Enabled {
 		m["enabled"] = s.Enabled
 	}
+	if len(s.Hosts) > 0 {
+		arr := make([]interface{}, 0, len(s.Hosts))
+		for _, item := range s.Hosts {
+			arr = append(arr, item.ToMap())
+		}
+		m["hosts"] = arr
+	}
 	if s.LeaseTime != "" {
 		m["leaseTime"] = s.LeaseTime
 	}
@@ -1881,231 +5674,355 @@ func (s *DNSSpec) ToMap() map[string]interface{} {
 	if s.Enabled {
 		m["enabled"] = s.Enabled
 	}
+	if len(s.Records) > 0 {
+		arr := make([]interface{}, 0, len(s.Records))
+		for _, item := range s.Records {
+			arr = append(arr, item.ToMap())
+		}
+		m["records"] = arr
+	}
 	if len(s.Servers) > 0 {
 		m["servers"] = s.Servers
 	}
 	return m
 }
 
-// ToMap converts a DiskSpec to a map[string]interface{}.
-func (s *DiskSpec) ToMap() map[string]interface{} {
+// ToMap converts a ReadinessSpec to a map[string]interface{}.
+func (s *ReadinessSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.BaseImage != "" {
-		m["baseImage"] = s.BaseImage
+	// Reference type CloudInitReadinessSpec
+	if refMap := s.CloudInit.ToMap(); len(refMap) > 0 {
+		m["cloudInit"] = refMap
 	}
-	if s.Size != "" {
-		m["size"] = s.Size
+	// Reference type SSHReadinessSpec
+	if refMap := s.Ssh.ToMap(); len(refMap) > 0 {
+		m["ssh"] = refMap
+	}
+	// Reference type TCPReadinessSpec
+	if refMap := s.Tcp.ToMap(); len(refMap) > 0 {
+		m["tcp"] = refMap
 	}
 	return m
 }
 
-// ToMap converts a ImageCustomizeSpec to a map[string]interface{}.
-func (s *ImageCustomizeSpec) ToMap() map[string]interface{} {
+// ToMap converts a DiskSpec to a map[string]interface{}.
+func (s *DiskSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if len(s.Packages) > 0 {
-		m["packages"] = s.Packages
+	if s.BaseImage != "" {
+		m["baseImage"] = s.BaseImage
 	}
-	if len(s.Runcmd) > 0 {
-		m["runcmd"] = s.Runcmd
+	if s.Encryption != nil {
+		m["encryption"] = s.Encryption.ToMap()
+	}
+	if s.Fstrim {
+		m["fstrim"] = s.Fstrim
+	}
+	if s.Size != "" {
+		m["size"] = s.Size
 	}
 	return m
 }
 
-// ToMap converts a KeySpec to a map[string]interface{}.
-func (s *KeySpec) ToMap() map[string]interface{} {
+// ToMap converts a GuestBondSpec to a map[string]interface{}.
+func (s *GuestBondSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Bits != 0 {
-		m["bits"] = s.Bits
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
 	}
-	if s.Comment != "" {
-		m["comment"] = s.Comment
+	if s.Dhcp4 {
+		m["dhcp4"] = s.Dhcp4
 	}
-	if s.OutputDir != "" {
-		m["outputDir"] = s.OutputDir
+	if s.Gateway4 != "" {
+		m["gateway4"] = s.Gateway4
 	}
-	if s.Type != "" {
-		m["type"] = s.Type
+	if len(s.Interfaces) > 0 {
+		m["interfaces"] = s.Interfaces
+	}
+	if s.Mode != "" {
+		m["mode"] = s.Mode
+	}
+	if s.Mtu != 0 {
+		m["mtu"] = s.Mtu
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	// Reference type GuestNameserversSpec
+	if refMap := s.Nameservers.ToMap(); len(refMap) > 0 {
+		m["nameservers"] = refMap
+	}
+	if len(s.Routes) > 0 {
+		arr := make([]interface{}, 0, len(s.Routes))
+		for _, item := range s.Routes {
+			arr = append(arr, item.ToMap())
+		}
+		m["routes"] = arr
 	}
 	return m
 }
 
-// ToMap converts a TFTPSpec to a map[string]interface{}.
-func (s *TFTPSpec) ToMap() map[string]interface{} {
+// ToMap converts a GuestEthernetSpec to a map[string]interface{}.
+func (s *GuestEthernetSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.BootFile != "" {
-		m["bootFile"] = s.BootFile
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
 	}
-	if s.Enabled {
-		m["enabled"] = s.Enabled
+	if s.Dhcp4 {
+		m["dhcp4"] = s.Dhcp4
 	}
-	if s.Root != "" {
-		m["root"] = s.Root
+	if s.Gateway4 != "" {
+		m["gateway4"] = s.Gateway4
+	}
+	if s.Match != "" {
+		m["match"] = s.Match
+	}
+	if s.Mtu != 0 {
+		m["mtu"] = s.Mtu
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	// Reference type GuestNameserversSpec
+	if refMap := s.Nameservers.ToMap(); len(refMap) > 0 {
+		m["nameservers"] = refMap
+	}
+	if s.Network != "" {
+		m["network"] = s.Network
+	}
+	if len(s.Routes) > 0 {
+		arr := make([]interface{}, 0, len(s.Routes))
+		for _, item := range s.Routes {
+			arr = append(arr, item.ToMap())
+		}
+		m["routes"] = arr
 	}
 	return m
 }
 
-// ToMap converts a ProviderConfig to a map[string]interface{}.
-func (s *ProviderConfig) ToMap() map[string]interface{} {
+// ToMap converts a GuestVLANSpec to a map[string]interface{}.
+func (s *GuestVLANSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Default {
-		m["default"] = s.Default
+	if len(s.Addresses) > 0 {
+		m["addresses"] = s.Addresses
 	}
-	if s.Engine != "" {
-		m["engine"] = s.Engine
+	if s.Dhcp4 {
+		m["dhcp4"] = s.Dhcp4
+	}
+	if s.Gateway4 != "" {
+		m["gateway4"] = s.Gateway4
+	}
+	if s.Id != 0 {
+		m["id"] = s.Id
+	}
+	if s.Link != "" {
+		m["link"] = s.Link
+	}
+	if s.Mtu != 0 {
+		m["mtu"] = s.Mtu
 	}
 	if s.Name != "" {
 		m["name"] = s.Name
 	}
-	if len(s.Spec) > 0 {
-		m["spec"] = s.Spec
+	// Reference type GuestNameserversSpec
+	if refMap := s.Nameservers.ToMap(); len(refMap) > 0 {
+		m["nameservers"] = refMap
+	}
+	if len(s.Routes) > 0 {
+		arr := make([]interface{}, 0, len(s.Routes))
+		for _, item := range s.Routes {
+			arr = append(arr, item.ToMap())
+		}
+		m["routes"] = arr
 	}
 	return m
 }
 
-// ToMap converts a SSHReadinessSpec to a map[string]interface{}.
-func (s *SSHReadinessSpec) ToMap() map[string]interface{} {
+// ToMap converts a IdleSpec to a map[string]interface{}.
+func (s *IdleSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Enabled {
-		m["enabled"] = s.Enabled
+	if s.Action != "" {
+		m["action"] = s.Action
 	}
-	if s.PrivateKey != "" {
-		m["privateKey"] = s.PrivateKey
+	if s.After != "" {
+		m["after"] = s.After
 	}
-	if s.Timeout != "" {
-		m["timeout"] = s.Timeout
+	if s.CpuPercent != 0 {
+		m["cpuPercent"] = s.CpuPercent
 	}
-	if s.User != "" {
-		m["user"] = s.User
+	if s.SshPort != 0 {
+		m["sshPort"] = s.SshPort
+	}
+	if len(s.Wake) > 0 {
+		arr := make([]interface{}, 0, len(s.Wake))
+		for _, item := range s.Wake {
+			arr = append(arr, item.ToMap())
+		}
+		m["wake"] = arr
 	}
 	return m
 }
 
-// ToMap converts a TCPReadinessSpec to a map[string]interface{}.
-func (s *TCPReadinessSpec) ToMap() map[string]interface{} {
+// ToMap converts a ImageSpec to a map[string]interface{}.
+func (s *ImageSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Port != 0 {
-		m["port"] = s.Port
+	if s.Alias != "" {
+		m["alias"] = s.Alias
 	}
-	if s.Timeout != "" {
-		m["timeout"] = s.Timeout
+	if s.Customize != nil {
+		m["customize"] = s.Customize.ToMap()
+	}
+	if s.Sha256 != "" {
+		m["sha256"] = s.Sha256
+	}
+	if s.Source != "" {
+		m["source"] = s.Source
 	}
 	return m
 }
 
-// ToMap converts a ResourceRef to a map[string]interface{}.
-func (s *ResourceRef) ToMap() map[string]interface{} {
+// ToMap converts a KeyResource to a map[string]interface{}.
+func (s *KeyResource) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Kind != "" {
-		m["kind"] = s.Kind
-	}
 	if s.Name != "" {
 		m["name"] = s.Name
 	}
 	if s.Provider != "" {
 		m["provider"] = s.Provider
 	}
+	if len(s.ProviderSpec) > 0 {
+		m["providerSpec"] = s.ProviderSpec
+	}
+	// Reference type KeySpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
+	}
 	return m
 }
 
-// ToMap converts a CloudInitEthernetConfig to a map[string]interface{}.
-func (s *CloudInitEthernetConfig) ToMap() map[string]interface{} {
+// ToMap converts a ServiceResource to a map[string]interface{}.
+func (s *ServiceResource) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if len(s.Addresses) > 0 {
-		m["addresses"] = s.Addresses
+	if s.Name != "" {
+		m["name"] = s.Name
 	}
-	if s.Dhcp4 {
-		m["dhcp4"] = s.Dhcp4
+	// Reference type ServiceSpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
 	}
-	if s.Gateway4 != "" {
-		m["gateway4"] = s.Gateway4
+	return m
+}
+
+// ToMap converts a TunnelResource to a map[string]interface{}.
+func (s *TunnelResource) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
 	}
+
+	m := make(map[string]interface{})
 	if s.Name != "" {
 		m["name"] = s.Name
 	}
-	// Reference type CloudInitNameservers
-	if refMap := s.Nameservers.ToMap(); len(refMap) > 0 {
-		m["nameservers"] = refMap
+	// Reference type TunnelSpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
 	}
 	return m
 }
 
-// ToMap converts a ImageSpec to a map[string]interface{}.
-func (s *ImageSpec) ToMap() map[string]interface{} {
+// ToMap converts a VMDevicesSpec to a map[string]interface{}.
+func (s *VMDevicesSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Alias != "" {
-		m["alias"] = s.Alias
+	if len(s.Mdevs) > 0 {
+		arr := make([]interface{}, 0, len(s.Mdevs))
+		for _, item := range s.Mdevs {
+			arr = append(arr, item.ToMap())
+		}
+		m["mdevs"] = arr
 	}
-	if s.Customize != nil {
-		m["customize"] = s.Customize.ToMap()
+	if len(s.PciPassthrough) > 0 {
+		m["pciPassthrough"] = s.PciPassthrough
 	}
-	if s.Sha256 != "" {
-		m["sha256"] = s.Sha256
+	if s.Vsock != nil {
+		m["vsock"] = s.Vsock.ToMap()
 	}
-	if s.Source != "" {
-		m["source"] = s.Source
+	return m
+}
+
+// ToMap converts a CloudInitNetworkConfig to a map[string]interface{}.
+func (s *CloudInitNetworkConfig) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if len(s.Ethernets) > 0 {
+		arr := make([]interface{}, 0, len(s.Ethernets))
+		for _, item := range s.Ethernets {
+			arr = append(arr, item.ToMap())
+		}
+		m["ethernets"] = arr
 	}
 	return m
 }
 
-// ToMap converts a KeyResource to a map[string]interface{}.
-func (s *KeyResource) ToMap() map[string]interface{} {
+// ToMap converts a NetworkDefaultsSpec to a map[string]interface{}.
+func (s *NetworkDefaultsSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	if s.Name != "" {
-		m["name"] = s.Name
+	if s.Dhcp != nil {
+		m["dhcp"] = s.Dhcp.ToMap()
 	}
-	if s.Provider != "" {
-		m["provider"] = s.Provider
+	if s.Dns != nil {
+		m["dns"] = s.Dns.ToMap()
 	}
-	if len(s.ProviderSpec) > 0 {
-		m["providerSpec"] = s.ProviderSpec
+	if s.Kind != "" {
+		m["kind"] = s.Kind
 	}
-	// Reference type KeySpec
-	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
-		m["spec"] = refMap
+	if s.Mtu != 0 {
+		m["mtu"] = s.Mtu
 	}
 	return m
 }
@@ -2135,41 +6052,53 @@ func (s *NetworkSpec) ToMap() map[string]interface{} {
 	if s.Mtu != 0 {
 		m["mtu"] = s.Mtu
 	}
+	if s.Ntp != nil {
+		m["ntp"] = s.Ntp.ToMap()
+	}
 	if s.Tftp != nil {
 		m["tftp"] = s.Tftp.ToMap()
 	}
 	return m
 }
 
-// ToMap converts a ReadinessSpec to a map[string]interface{}.
-func (s *ReadinessSpec) ToMap() map[string]interface{} {
+// ToMap converts a VMDefaultsSpec to a map[string]interface{}.
+func (s *VMDefaultsSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
-	// Reference type CloudInitReadinessSpec
-	if refMap := s.CloudInit.ToMap(); len(refMap) > 0 {
-		m["cloudInit"] = refMap
+	// Reference type DiskDefaultsSpec
+	if refMap := s.Disk.ToMap(); len(refMap) > 0 {
+		m["disk"] = refMap
 	}
-	// Reference type SSHReadinessSpec
-	if refMap := s.Ssh.ToMap(); len(refMap) > 0 {
-		m["ssh"] = refMap
+	if s.Memory != 0 {
+		m["memory"] = s.Memory
 	}
-	// Reference type TCPReadinessSpec
-	if refMap := s.Tcp.ToMap(); len(refMap) > 0 {
-		m["tcp"] = refMap
+	// Reference type ReadinessSpec
+	if refMap := s.Readiness.ToMap(); len(refMap) > 0 {
+		m["readiness"] = refMap
+	}
+	if s.Vcpus != 0 {
+		m["vcpus"] = s.Vcpus
 	}
 	return m
 }
 
-// ToMap converts a CloudInitNetworkConfig to a map[string]interface{}.
-func (s *CloudInitNetworkConfig) ToMap() map[string]interface{} {
+// ToMap converts a GuestNetworkConfigSpec to a map[string]interface{}.
+func (s *GuestNetworkConfigSpec) ToMap() map[string]interface{} {
 	if s == nil {
 		return nil
 	}
 
 	m := make(map[string]interface{})
+	if len(s.Bonds) > 0 {
+		arr := make([]interface{}, 0, len(s.Bonds))
+		for _, item := range s.Bonds {
+			arr = append(arr, item.ToMap())
+		}
+		m["bonds"] = arr
+	}
 	if len(s.Ethernets) > 0 {
 		arr := make([]interface{}, 0, len(s.Ethernets))
 		for _, item := range s.Ethernets {
@@ -2177,6 +6106,13 @@ func (s *CloudInitNetworkConfig) ToMap() map[string]interface{} {
 		}
 		m["ethernets"] = arr
 	}
+	if len(s.Vlans) > 0 {
+		arr := make([]interface{}, 0, len(s.Vlans))
+		for _, item := range s.Vlans {
+			arr = append(arr, item.ToMap())
+		}
+		m["vlans"] = arr
+	}
 	return m
 }
 
@@ -2197,32 +6133,6 @@ func (s *ImageResource) ToMap() map[string]interface{} {
 	return m
 }
 
-// ToMap converts a NetworkResource to a map[string]interface{}.
-func (s *NetworkResource) ToMap() map[string]interface{} {
-	if s == nil {
-		return nil
-	}
-
-	m := make(map[string]interface{})
-	if s.Kind != "" {
-		m["kind"] = s.Kind
-	}
-	if s.Name != "" {
-		m["name"] = s.Name
-	}
-	if s.Provider != "" {
-		m["provider"] = s.Provider
-	}
-	if len(s.ProviderSpec) > 0 {
-		m["providerSpec"] = s.ProviderSpec
-	}
-	// Reference type NetworkSpec
-	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
-		m["spec"] = refMap
-	}
-	return m
-}
-
 // ToMap converts a CloudInitSpec to a map[string]interface{}.
 func (s *CloudInitSpec) ToMap() map[string]interface{} {
 	if s == nil {
@@ -2230,6 +6140,9 @@ func (s *CloudInitSpec) ToMap() map[string]interface{} {
 	}
 
 	m := make(map[string]interface{})
+	if len(s.Environment) > 0 {
+		m["environment"] = s.Environment
+	}
 	if s.Hostname != "" {
 		m["hostname"] = s.Hostname
 	}
@@ -2240,9 +6153,15 @@ func (s *CloudInitSpec) ToMap() map[string]interface{} {
 	if len(s.Packages) > 0 {
 		m["packages"] = s.Packages
 	}
+	if s.RawUserData != "" {
+		m["rawUserData"] = s.RawUserData
+	}
 	if len(s.Runcmd) > 0 {
 		m["runcmd"] = s.Runcmd
 	}
+	if len(s.SecretEnvironment) > 0 {
+		m["secretEnvironment"] = s.SecretEnvironment
+	}
 	if len(s.Users) > 0 {
 		arr := make([]interface{}, 0, len(s.Users))
 		for _, item := range s.Users {
@@ -2260,6 +6179,50 @@ func (s *CloudInitSpec) ToMap() map[string]interface{} {
 	return m
 }
 
+// ToMap converts a NetworkResource to a map[string]interface{}.
+func (s *NetworkResource) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	if s.Kind != "" {
+		m["kind"] = s.Kind
+	}
+	if s.Name != "" {
+		m["name"] = s.Name
+	}
+	if s.Provider != "" {
+		m["provider"] = s.Provider
+	}
+	if len(s.ProviderSpec) > 0 {
+		m["providerSpec"] = s.ProviderSpec
+	}
+	// Reference type NetworkSpec
+	if refMap := s.Spec.ToMap(); len(refMap) > 0 {
+		m["spec"] = refMap
+	}
+	return m
+}
+
+// ToMap converts a DefaultsSpec to a map[string]interface{}.
+func (s *DefaultsSpec) ToMap() map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+
+	m := make(map[string]interface{})
+	// Reference type NetworkDefaultsSpec
+	if refMap := s.Network.ToMap(); len(refMap) > 0 {
+		m["network"] = refMap
+	}
+	// Reference type VMDefaultsSpec
+	if refMap := s.Vm.ToMap(); len(refMap) > 0 {
+		m["vm"] = refMap
+	}
+	return m
+}
+
 // ToMap converts a VMSpec to a map[string]interface{}.
 func (s *VMSpec) ToMap() map[string]interface{} {
 	if s == nil {
@@ -2267,6 +6230,9 @@ func (s *VMSpec) ToMap() map[string]interface{} {
 	}
 
 	m := make(map[string]interface{})
+	if s.Agent != nil {
+		m["agent"] = s.Agent.ToMap()
+	}
 	// Reference type BootSpec
 	if refMap := s.Boot.ToMap(); len(refMap) > 0 {
 		m["boot"] = refMap
@@ -2275,23 +6241,51 @@ func (s *VMSpec) ToMap() map[string]interface{} {
 	if refMap := s.CloudInit.ToMap(); len(refMap) > 0 {
 		m["cloudInit"] = refMap
 	}
+	// Reference type VMDevicesSpec
+	if refMap := s.Devices.ToMap(); len(refMap) > 0 {
+		m["devices"] = refMap
+	}
 	// Reference type DiskSpec
 	if refMap := s.Disk.ToMap(); len(refMap) > 0 {
 		m["disk"] = refMap
 	}
+	if len(s.Disks) > 0 {
+		arr := make([]interface{}, 0, len(s.Disks))
+		for _, item := range s.Disks {
+			arr = append(arr, item.ToMap())
+		}
+		m["disks"] = arr
+	}
+	if s.Ip != "" {
+		m["ip"] = s.Ip
+	}
 	if s.Memory != 0 {
 		m["memory"] = s.Memory
 	}
 	if s.Network != "" {
 		m["network"] = s.Network
 	}
+	// Reference type GuestNetworkConfigSpec
+	if refMap := s.NetworkConfig.ToMap(); len(refMap) > 0 {
+		m["networkConfig"] = refMap
+	}
 	if len(s.Networks) > 0 {
 		m["networks"] = s.Networks
 	}
+	if len(s.Nics) > 0 {
+		arr := make([]interface{}, 0, len(s.Nics))
+		for _, item := range s.Nics {
+			arr = append(arr, item.ToMap())
+		}
+		m["nics"] = arr
+	}
 	// Reference type ReadinessSpec
 	if refMap := s.Readiness.ToMap(); len(refMap) > 0 {
 		m["readiness"] = refMap
 	}
+	if s.Security != nil {
+		m["security"] = s.Security.ToMap()
+	}
 	if s.Vcpus != 0 {
 		m["vcpus"] = s.Vcpus
 	}
@@ -2328,18 +6322,59 @@ func (s *Spec) ToMap() map[string]interface{} {
 	}
 
 	m := make(map[string]interface{})
+	if len(s.Access) > 0 {
+		arr := make([]interface{}, 0, len(s.Access))
+		for _, item := range s.Access {
+			arr = append(arr, item.ToMap())
+		}
+		m["access"] = arr
+	}
 	if s.ArtifactDir != "" {
 		m["artifactDir"] = s.ArtifactDir
 	}
+	// Reference type CASpec
+	if refMap := s.Ca.ToMap(); len(refMap) > 0 {
+		m["ca"] = refMap
+	}
+	if len(s.Certificates) > 0 {
+		arr := make([]interface{}, 0, len(s.Certificates))
+		for _, item := range s.Certificates {
+			arr = append(arr, item.ToMap())
+		}
+		m["certificates"] = arr
+	}
 	if s.CleanupOnFailure {
 		m["cleanupOnFailure"] = s.CleanupOnFailure
 	}
+	if s.CreateDeadline != "" {
+		m["createDeadline"] = s.CreateDeadline
+	}
 	if s.DefaultBaseImage != "" {
 		m["defaultBaseImage"] = s.DefaultBaseImage
 	}
 	if s.DefaultProvider != "" {
 		m["defaultProvider"] = s.DefaultProvider
 	}
+	// Reference type DefaultsSpec
+	if refMap := s.Defaults.ToMap(); len(refMap) > 0 {
+		m["defaults"] = refMap
+	}
+	if s.EnvironmentId != "" {
+		m["environmentId"] = s.EnvironmentId
+	}
+	if s.EnvironmentIdTemplate != "" {
+		m["environmentIdTemplate"] = s.EnvironmentIdTemplate
+	}
+	if s.ExpiresAfter != "" {
+		m["expiresAfter"] = s.ExpiresAfter
+	}
+	if s.HostsFile {
+		m["hostsFile"] = s.HostsFile
+	}
+	// Reference type IdleSpec
+	if refMap := s.Idle.ToMap(); len(refMap) > 0 {
+		m["idle"] = refMap
+	}
 	if s.ImageCacheDir != "" {
 		m["imageCacheDir"] = s.ImageCacheDir
 	}
@@ -2357,6 +6392,12 @@ func (s *Spec) ToMap() map[string]interface{} {
 		}
 		m["keys"] = arr
 	}
+	if len(s.Labels) > 0 {
+		m["labels"] = s.Labels
+	}
+	if s.NamePrefix != "" {
+		m["namePrefix"] = s.NamePrefix
+	}
 	if len(s.Networks) > 0 {
 		arr := make([]interface{}, 0, len(s.Networks))
 		for _, item := range s.Networks {
@@ -2364,6 +6405,20 @@ func (s *Spec) ToMap() map[string]interface{} {
 		}
 		m["networks"] = arr
 	}
+	if len(s.Notifiers) > 0 {
+		arr := make([]interface{}, 0, len(s.Notifiers))
+		for _, item := range s.Notifiers {
+			arr = append(arr, item.ToMap())
+		}
+		m["notifiers"] = arr
+	}
+	// Reference type ParentSpec
+	if refMap := s.Parent.ToMap(); len(refMap) > 0 {
+		m["parent"] = refMap
+	}
+	if s.Priority != 0 {
+		m["priority"] = s.Priority
+	}
 	if len(s.Providers) > 0 {
 		arr := make([]interface{}, 0, len(s.Providers))
 		for _, item := range s.Providers {
@@ -2371,9 +6426,29 @@ func (s *Spec) ToMap() map[string]interface{} {
 		}
 		m["providers"] = arr
 	}
+	if s.Seed != "" {
+		m["seed"] = s.Seed
+	}
+	if len(s.Services) > 0 {
+		arr := make([]interface{}, 0, len(s.Services))
+		for _, item := range s.Services {
+			arr = append(arr, item.ToMap())
+		}
+		m["services"] = arr
+	}
+	if s.SpecRef != "" {
+		m["specRef"] = s.SpecRef
+	}
 	if s.StateDir != "" {
 		m["stateDir"] = s.StateDir
 	}
+	if len(s.Tunnels) > 0 {
+		arr := make([]interface{}, 0, len(s.Tunnels))
+		for _, item := range s.Tunnels {
+			arr = append(arr, item.ToMap())
+		}
+		m["tunnels"] = arr
+	}
 	if len(s.Vms) > 0 {
 		arr := make([]interface{}, 0, len(s.Vms))
 		for _, item := range s.Vms {
@@ -2381,6 +6456,13 @@ func (s *Spec) ToMap() map[string]interface{} {
 		}
 		m["vms"] = arr
 	}
+	if len(s.Webhooks) > 0 {
+		arr := make([]interface{}, 0, len(s.Webhooks))
+		for _, item := range s.Webhooks {
+			arr = append(arr, item.ToMap())
+		}
+		m["webhooks"] = arr
+	}
 	return m
 }
 
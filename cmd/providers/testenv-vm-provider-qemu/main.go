@@ -0,0 +1,435 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements the qemu provider MCP server binary.
+// This provider manages VMs, networks, and SSH keys by running QEMU directly,
+// without a libvirt daemon.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/qemu"
+)
+
+// Version information (set via ldflags during build)
+var (
+	Version        = ""
+	CommitSHA      = "unknown"
+	BuildTimestamp = "unknown"
+)
+
+func init() {
+	if Version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			Version = info.Main.Version
+		} else {
+			Version = "dev"
+		}
+	}
+}
+
+func main() {
+	mcpFlag := flag.Bool("mcp", false, "Run as MCP server")
+	versionFlag := flag.Bool("version", false, "Show version information")
+	readOnlyFlag := flag.Bool("read-only", false, "Expose only get/list/capabilities tools (also enabled by TESTENV_VM_READ_ONLY=true)")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Printf("testenv-vm-provider-qemu %s (commit: %s, built: %s)\n", Version, CommitSHA, BuildTimestamp)
+		os.Exit(0)
+	}
+
+	if !*mcpFlag {
+		fmt.Fprintln(os.Stderr, "This binary must be run with --mcp flag")
+		fmt.Fprintln(os.Stderr, "Usage: testenv-vm-provider-qemu --mcp")
+		os.Exit(1)
+	}
+
+	// Redirect log output to a debug file so provider logs are visible
+	// (provider stderr is inherited but not captured by MCP client output).
+	debugLogPath := fmt.Sprintf("/tmp/testenv-vm-provider-%d.log", os.Getpid())
+	if f, err := os.OpenFile(debugLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+		log.SetOutput(f)
+		defer func() { _ = f.Close() }()
+	}
+	log.Printf("Provider starting: version=%s pid=%d", Version, os.Getpid())
+
+	readOnly := *readOnlyFlag || os.Getenv("TESTENV_VM_READ_ONLY") == "true"
+	if err := runMCPServer(readOnly); err != nil {
+		log.Fatalf("MCP server failed: %v", err)
+	}
+}
+
+// runMCPServer starts the qemu provider MCP server with stdio transport.
+// In read-only mode only capabilities, get and list tools are registered, so
+// observers cannot create or delete resources.
+func runMCPServer(readOnly bool) error {
+	provider, err := qemu.NewProvider()
+	if err != nil {
+		return fmt.Errorf("failed to create provider: %w", err)
+	}
+	provider.SetVersion(Version)
+	defer func() {
+		_ = provider.Close()
+	}()
+
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "testenv-vm-provider-qemu",
+		Version: Version,
+	}, nil)
+
+	// Register provider_capabilities tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "provider_capabilities",
+		Description: "Get provider capabilities",
+	}, makeCapabilitiesHandler(provider))
+
+	// Register key tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "key_get",
+		Description: "Get an SSH key by name",
+	}, makeKeyGetHandler(provider))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "key_list",
+		Description: "List all SSH keys",
+	}, makeKeyListHandler(provider))
+
+	// Register network tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "network_get",
+		Description: "Get a network by name",
+	}, makeNetworkGetHandler(provider))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "network_list",
+		Description: "List all networks",
+	}, makeNetworkListHandler(provider))
+
+	// Register VM tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_get",
+		Description: "Get a virtual machine by name",
+	}, makeVMGetHandler(provider))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_list",
+		Description: "List all virtual machines",
+	}, makeVMListHandler(provider))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMStatsTool,
+		Description: "Get the CPU, memory, disk and network usage of a running virtual machine",
+	}, makeVMStatsHandler(provider))
+
+	// Register mutating tools unless running in read-only mode
+	if readOnly {
+		log.Printf("Read-only mode: create/delete tools are not exposed")
+	} else {
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "key_create",
+			Description: "Create an SSH key",
+		}, makeKeyCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "key_delete",
+			Description: "Delete an SSH key by name",
+		}, makeKeyDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "network_create",
+			Description: "Create a network",
+		}, makeNetworkCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "network_delete",
+			Description: "Delete a network by name",
+		}, makeNetworkDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "vm_create",
+			Description: "Create a virtual machine",
+		}, makeVMCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "vm_delete",
+			Description: "Delete a virtual machine by name",
+		}, makeVMDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMStartTool,
+			Description: "Start a stopped virtual machine, or resume a paused one",
+		}, makeVMPowerHandler(provider, providerv1.PowerStart))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMStopTool,
+			Description: "Power off a virtual machine, gracefully or with force to simulate a crash",
+		}, makeVMPowerHandler(provider, providerv1.PowerStop))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMRebootTool,
+			Description: "Reboot a running virtual machine, gracefully or with force to simulate a reset",
+		}, makeVMPowerHandler(provider, providerv1.PowerReboot))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMPauseTool,
+			Description: "Pause the vCPUs of a running virtual machine until vm_start resumes it",
+		}, makeVMPowerHandler(provider, providerv1.PowerPause))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.TeardownTool,
+			Description: "Delete VMs, then networks, then keys of an environment in one request, with one result per resource",
+		}, makeTeardownHandler(provider))
+	}
+
+	// Register the batch tool; in read-only mode it only runs get and list calls
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.BatchTool,
+		Description: "Run several key, network and VM tool calls in one request, with one result per call",
+	}, makeBatchHandler(providerv1.BatchHandlers(provider, !readOnly)))
+
+	// Ensure logs go to stderr (not stdout, which is for JSON-RPC)
+	log.SetOutput(os.Stderr)
+	log.Printf("Starting testenv-vm-provider-qemu MCP server (version: %s)", Version)
+
+	return server.Run(context.Background(), &mcp.StdioTransport{})
+}
+
+// EmptyInput is used for tools that don't require input.
+type EmptyInput struct{}
+
+// errorResult creates a standardized MCP error result.
+func errorResult(message string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: message},
+		},
+		IsError: true,
+	}
+}
+
+// toMCPResult converts a provider OperationResult to an MCP CallToolResult.
+func toMCPResult(result *providerv1.OperationResult) (*mcp.CallToolResult, any) {
+	if !result.Success {
+		errMsg := "operation failed"
+		if result.Error != nil {
+			errMsg = result.Error.Message
+		}
+		return errorResult(errMsg), nil
+	}
+
+	// Serialize the full OperationResult to JSON for the response
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return errorResult("failed to serialize result: " + err.Error()), nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: string(resultJSON)},
+		},
+		IsError: false,
+	}, nil
+}
+
+// makeCapabilitiesHandler creates the handler for provider_capabilities tool.
+func makeCapabilitiesHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, EmptyInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input EmptyInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("provider_capabilities called")
+		caps := p.Capabilities()
+
+		result := providerv1.SuccessResult(caps)
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			return errorResult("failed to serialize capabilities: " + err.Error()), nil, nil
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: string(resultJSON)},
+			},
+			IsError: false,
+		}, nil, nil
+	}
+}
+
+// makeKeyCreateHandler creates the handler for key_create tool.
+func makeKeyCreateHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.KeyCreateRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.KeyCreateRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("key_create called: name=%s", input.Name)
+		result := p.KeyCreate(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeKeyGetHandler creates the handler for key_get tool.
+func makeKeyGetHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("key_get called: name=%s", input.Name)
+		result := p.KeyGet(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeKeyListHandler creates the handler for key_list tool.
+func makeKeyListHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("key_list called")
+		result := p.KeyList(input.Filter)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeKeyDeleteHandler creates the handler for key_delete tool.
+func makeKeyDeleteHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("key_delete called: name=%s", input.Name)
+		result := p.KeyDelete(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkCreateHandler creates the handler for network_create tool.
+func makeNetworkCreateHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.NetworkCreateRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.NetworkCreateRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_create called: name=%s, kind=%s", input.Name, input.Kind)
+		result := p.NetworkCreate(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkGetHandler creates the handler for network_get tool.
+func makeNetworkGetHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_get called: name=%s", input.Name)
+		result := p.NetworkGet(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkListHandler creates the handler for network_list tool.
+func makeNetworkListHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_list called")
+		result := p.NetworkList(input.Filter)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkDeleteHandler creates the handler for network_delete tool.
+func makeNetworkDeleteHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_delete called: name=%s", input.Name)
+		result := p.NetworkDelete(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMCreateHandler creates the handler for vm_create tool.
+func makeVMCreateHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.VMCreateRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.VMCreateRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_create called: name=%s", input.Name)
+		result := p.VMCreate(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMGetHandler creates the handler for vm_get tool.
+func makeVMGetHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.GetRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_get called: name=%s", input.Name)
+		result := p.VMGet(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMListHandler creates the handler for vm_list tool.
+func makeVMListHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.ListRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_list called")
+		result := p.VMList(input.Filter)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMDeleteHandler creates the handler for vm_delete tool.
+func makeVMDeleteHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.DeleteRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_delete called: name=%s", input.Name)
+		result := p.VMDelete(input.Name)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMStatsHandler creates the handler for the vm_stats tool.
+func makeVMStatsHandler(p *qemu.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.VMStatsRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.VMStatsRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_stats called: name=%s intervalMs=%d", input.Name, input.IntervalMs)
+		result := p.VMStats(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMPowerHandler creates the handler for the power tool of action.
+func makeVMPowerHandler(p *qemu.Provider, action string) func(context.Context, *mcp.CallToolRequest, providerv1.VMPowerRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.VMPowerRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("%s called: name=%s force=%t", providerv1.PowerTools[action], input.Name, input.Force)
+		result := p.VMPower(action, &input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeTeardownHandler creates the handler for the environment teardown tool.
+func makeTeardownHandler(p providerv1.ResourceProvider) func(context.Context, *mcp.CallToolRequest, providerv1.TeardownRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.TeardownRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("environment_teardown called: vms=%d networks=%d keys=%d", len(input.VMs), len(input.Networks), len(input.Keys))
+		result := providerv1.RunTeardown(&input, p)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeBatchHandler creates the handler for the batch tool.
+func makeBatchHandler(handlers map[string]providerv1.BatchHandler) func(context.Context, *mcp.CallToolRequest, providerv1.BatchRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.BatchRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("batch called: calls=%d", len(input.Calls))
+		result := providerv1.RunBatch(&input, handlers)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
@@ -52,6 +52,7 @@ func init() {
 func main() {
 	mcpFlag := flag.Bool("mcp", false, "Run as MCP server")
 	versionFlag := flag.Bool("version", false, "Show version information")
+	readOnlyFlag := flag.Bool("read-only", false, "Expose only get/list/capabilities tools (also enabled by TESTENV_VM_READ_ONLY=true)")
 	flag.Parse()
 
 	if *versionFlag {
@@ -65,13 +66,16 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := runMCPServer(); err != nil {
+	readOnly := *readOnlyFlag || os.Getenv("TESTENV_VM_READ_ONLY") == "true"
+	if err := runMCPServer(readOnly); err != nil {
 		log.Fatalf("MCP server failed: %v", err)
 	}
 }
 
 // runMCPServer starts the stub provider MCP server with stdio transport.
-func runMCPServer() error {
+// In read-only mode only capabilities, get and list tools are registered, so
+// observers cannot create or delete resources.
+func runMCPServer(readOnly bool) error {
 	provider := stub.NewProvider()
 	provider.SetVersion(Version)
 
@@ -87,11 +91,6 @@ func runMCPServer() error {
 	}, makeCapabilitiesHandler(provider))
 
 	// Register key tools
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "key_create",
-		Description: "Create an SSH key",
-	}, makeKeyCreateHandler(provider))
-
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "key_get",
 		Description: "Get an SSH key by name",
@@ -102,17 +101,7 @@ func runMCPServer() error {
 		Description: "List all SSH keys",
 	}, makeKeyListHandler(provider))
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "key_delete",
-		Description: "Delete an SSH key by name",
-	}, makeKeyDeleteHandler(provider))
-
 	// Register network tools
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "network_create",
-		Description: "Create a network",
-	}, makeNetworkCreateHandler(provider))
-
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "network_get",
 		Description: "Get a network by name",
@@ -123,17 +112,7 @@ func runMCPServer() error {
 		Description: "List all networks",
 	}, makeNetworkListHandler(provider))
 
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "network_delete",
-		Description: "Delete a network by name",
-	}, makeNetworkDeleteHandler(provider))
-
 	// Register VM tools
-	mcp.AddTool(server, &mcp.Tool{
-		Name:        "vm_create",
-		Description: "Create a virtual machine",
-	}, makeVMCreateHandler(provider))
-
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "vm_get",
 		Description: "Get a virtual machine by name",
@@ -145,9 +124,90 @@ func runMCPServer() error {
 	}, makeVMListHandler(provider))
 
 	mcp.AddTool(server, &mcp.Tool{
-		Name:        "vm_delete",
-		Description: "Delete a virtual machine by name",
-	}, makeVMDeleteHandler(provider))
+		Name:        providerv1.VMStatsTool,
+		Description: "Get the CPU, memory, disk and network usage of a running virtual machine",
+	}, makeVMStatsHandler(provider))
+
+	// Register mutating tools unless running in read-only mode
+	if readOnly {
+		log.Printf("Read-only mode: create/delete tools are not exposed")
+	} else {
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "key_create",
+			Description: "Create an SSH key",
+		}, makeKeyCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "key_delete",
+			Description: "Delete an SSH key by name",
+		}, makeKeyDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "network_create",
+			Description: "Create a network",
+		}, makeNetworkCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "network_delete",
+			Description: "Delete a network by name",
+		}, makeNetworkDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "vm_create",
+			Description: "Create a virtual machine",
+		}, makeVMCreateHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        "vm_delete",
+			Description: "Delete a virtual machine by name",
+		}, makeVMDeleteHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.NetworkCaptureStartTool,
+			Description: "Start capturing the packets of a network bridge or VM interface into a pcap file, with size and time limits",
+		}, makeNetworkCaptureStartHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.NetworkCaptureStopTool,
+			Description: "Stop a packet capture and return the path and size of its pcap file",
+		}, makeNetworkCaptureStopHandler(provider))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMStartTool,
+			Description: "Start a stopped virtual machine, or resume a paused one",
+		}, makeVMPowerHandler(provider, providerv1.PowerStart))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMStopTool,
+			Description: "Power off a virtual machine, gracefully or with force to simulate a crash",
+		}, makeVMPowerHandler(provider, providerv1.PowerStop))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMRebootTool,
+			Description: "Reboot a running virtual machine, gracefully or with force to simulate a reset",
+		}, makeVMPowerHandler(provider, providerv1.PowerReboot))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMPauseTool,
+			Description: "Pause the vCPUs of a running virtual machine until vm_start resumes it",
+		}, makeVMPowerHandler(provider, providerv1.PowerPause))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.VMSaveTool,
+			Description: "Save the memory of a running virtual machine to disk and power it off until vm_start restores it",
+		}, makeVMPowerHandler(provider, providerv1.PowerSave))
+
+		mcp.AddTool(server, &mcp.Tool{
+			Name:        providerv1.TeardownTool,
+			Description: "Delete VMs, then networks, then keys of an environment in one request, with one result per resource",
+		}, makeTeardownHandler(provider))
+	}
+
+	// Register the batch tool; in read-only mode it only runs get and list calls
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.BatchTool,
+		Description: "Run several key, network and VM tool calls in one request, with one result per call",
+	}, makeBatchHandler(providerv1.BatchHandlers(provider, !readOnly)))
 
 	// Ensure logs go to stderr (not stdout, which is for JSON-RPC)
 	log.SetOutput(os.Stderr)
@@ -338,3 +398,63 @@ func makeVMDeleteHandler(p *stub.Provider) func(context.Context, *mcp.CallToolRe
 		return mcpResult, artifact, nil
 	}
 }
+
+// makeVMStatsHandler creates the handler for the vm_stats tool.
+func makeVMStatsHandler(p *stub.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.VMStatsRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.VMStatsRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_stats called: name=%s intervalMs=%d", input.Name, input.IntervalMs)
+		result := p.VMStats(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeVMPowerHandler creates the handler for the power tool of action.
+func makeVMPowerHandler(p *stub.Provider, action string) func(context.Context, *mcp.CallToolRequest, providerv1.VMPowerRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.VMPowerRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("%s called: name=%s force=%t", providerv1.PowerTools[action], input.Name, input.Force)
+		result := p.VMPower(action, &input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkCaptureStartHandler creates the handler for the network_capture_start tool.
+func makeNetworkCaptureStartHandler(p *stub.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.NetworkCaptureStartRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.NetworkCaptureStartRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_capture_start called: network=%s vm=%s filter=%q", input.Network, input.VM, input.Filter)
+		result := p.NetworkCaptureStart(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeNetworkCaptureStopHandler creates the handler for the network_capture_stop tool.
+func makeNetworkCaptureStopHandler(p *stub.Provider) func(context.Context, *mcp.CallToolRequest, providerv1.NetworkCaptureStopRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.NetworkCaptureStopRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("network_capture_stop called: id=%s", input.ID)
+		result := p.NetworkCaptureStop(&input)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeTeardownHandler creates the handler for the environment teardown tool.
+func makeTeardownHandler(p providerv1.ResourceProvider) func(context.Context, *mcp.CallToolRequest, providerv1.TeardownRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.TeardownRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("environment_teardown called: vms=%d networks=%d keys=%d", len(input.VMs), len(input.Networks), len(input.Keys))
+		result := providerv1.RunTeardown(&input, p)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
+
+// makeBatchHandler creates the handler for the batch tool.
+func makeBatchHandler(handlers map[string]providerv1.BatchHandler) func(context.Context, *mcp.CallToolRequest, providerv1.BatchRequest) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input providerv1.BatchRequest) (*mcp.CallToolResult, any, error) {
+		log.Printf("batch called: calls=%d", len(input.Calls))
+		result := providerv1.RunBatch(&input, handlers)
+		mcpResult, artifact := toMCPResult(result)
+		return mcpResult, artifact, nil
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements the testenv-vm guest agent. It is injected into
+// VMs that set spec.agent.enabled and serves the API of pkg/agent.
+//
+// Build it as a static binary so that it runs on any guest:
+//
+//	CGO_ENABLED=0 go build -o testenv-vm-agent ./cmd/testenv-vm-agent
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/vsock"
+)
+
+// Version information (set via ldflags during build)
+var (
+	Version        = ""
+	CommitSHA      = "unknown"
+	BuildTimestamp = "unknown"
+)
+
+func init() {
+	if Version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			Version = info.Main.Version
+		} else {
+			Version = "dev"
+		}
+	}
+}
+
+func main() {
+	listenFlag := flag.String("listen", fmt.Sprintf(":%d", agent.DefaultPort), "Address to listen on")
+	vsockPortFlag := flag.Uint("vsock-port", 0, "Also listen on this vsock port (0 disables vsock)")
+	tokenFileFlag := flag.String("token-file", agent.TokenPath, "File holding the token clients must present")
+	versionFlag := flag.Bool("version", false, "Show version information")
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Printf("testenv-vm-agent %s (commit: %s, built: %s)\n", Version, CommitSHA, BuildTimestamp)
+		os.Exit(0)
+	}
+
+	data, err := os.ReadFile(*tokenFileFlag)
+	if err != nil {
+		log.Fatalf("Failed to read token: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		log.Fatalf("Token file %s is empty", *tokenFileFlag)
+	}
+
+	server := &http.Server{
+		Addr:              *listenFlag,
+		Handler:           agent.NewHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if *vsockPortFlag != 0 {
+		// vsock keeps the agent reachable when the guest network is down
+		ln, err := vsock.Listen(uint32(*vsockPortFlag))
+		if err != nil {
+			log.Fatalf("Failed to listen on vsock: %v", err)
+		}
+		log.Printf("testenv-vm-agent %s listening on %s", Version, ln.Addr())
+		go func() {
+			if err := server.Serve(ln); err != nil {
+				log.Fatalf("Agent failed on vsock: %v", err)
+			}
+		}()
+	}
+	log.Printf("testenv-vm-agent %s listening on %s", Version, *listenFlag)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Agent failed: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/config"
+)
+
+var (
+	// cfg is the loaded configuration.
+	cfg     *config.Config
+	cfgOnce sync.Once
+	cfgErr  error
+)
+
+// loadConfig loads the configuration once from --config, TESTENV_VM_CONFIG,
+// or ~/.config/testenv-vm/config.yaml.
+func loadConfig() (*config.Config, error) {
+	cfgOnce.Do(func() {
+		cfg, cfgErr = config.Load(config.PathFromArgs(os.Args[1:]))
+		if cfgErr != nil {
+			cfgErr = fmt.Errorf("failed to load configuration: %w", cfgErr)
+			return
+		}
+
+		// Provider subprocesses inherit the environment and read their state
+		// directory from it, so export the resolved value.
+		if os.Getenv("TESTENV_VM_STATE_DIR") == "" {
+			if err := os.Setenv("TESTENV_VM_STATE_DIR", cfg.StateDir); err != nil {
+				cfgErr = fmt.Errorf("failed to set TESTENV_VM_STATE_DIR: %w", err)
+				return
+			}
+		}
+
+		// The log file stays open for the lifetime of the process.
+		if _, err := cfg.SetupLogging(); err != nil {
+			log.Printf("Logging to file disabled: %v", err)
+		}
+	})
+	return cfg, cfgErr
+}
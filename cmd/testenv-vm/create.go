@@ -23,7 +23,9 @@ import (
 
 	"github.com/alexandremahdhaoui/forge/pkg/engineframework"
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/engine"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 )
 
 var (
@@ -36,34 +38,41 @@ var (
 // getOrchestrator returns the shared orchestrator instance, initializing it if necessary.
 func getOrchestrator() (*orchestrator.Orchestrator, error) {
 	orchOnce.Do(func() {
-		// Configure from environment
-		stateDir := getEnvOrDefault("TESTENV_VM_STATE_DIR", ".forge/testenv-vm/state")
-		cleanupOnFailure := getEnvOrDefault("TESTENV_VM_CLEANUP_ON_FAILURE", "true") == "true"
-		imageCacheDir := os.Getenv("TESTENV_VM_IMAGE_CACHE_DIR")
-
-		orch, orchErr = orchestrator.NewOrchestrator(orchestrator.Config{
-			StateDir:         stateDir,
-			ImageCacheDir:    imageCacheDir,
-			CleanupOnFailure: cleanupOnFailure,
-		})
+		// Configure from the config file, overridden by the environment
+		c, err := loadConfig()
+		if err != nil {
+			orchErr = err
+			return
+		}
+
+		orchConfig, err := c.OrchestratorConfig()
+		if err != nil {
+			orchErr = err
+			return
+		}
+
+		orch, orchErr = orchestrator.NewOrchestrator(orchConfig)
+		if orchErr != nil || c.Metrics.ListenAddress == "" {
+			return
+		}
+		if _, err := orch.ServeMetrics(c.Metrics.ListenAddress); err != nil {
+			log.Printf("Metrics disabled: %v", err)
+		}
 	})
 	return orch, orchErr
 }
 
-// getEnvOrDefault returns the value of the environment variable with the given key,
-// or the default value if the environment variable is not set or empty.
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
 // Create creates a new test environment from the given input.
 // This is the main entry point called by the generated MCP server.
 func Create(ctx context.Context, input engineframework.CreateInput, spec *v1.Spec) (*engineframework.TestEnvArtifact, error) {
 	log.Printf("Handling create request: testID=%s, stage=%s", input.TestID, input.Stage)
 
+	// The typed spec silently drops unknown fields; reject typos such as
+	// "vcups" from the raw input instead.
+	if err := specpkg.CheckUnknownFields(input.Spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+
 	// Propagate spec.StateDir to TESTENV_VM_STATE_DIR env var so both the
 	// orchestrator and provider subprocess (which inherits environment) use
 	// the same state directory. This prevents key pair mismatches where the
@@ -80,20 +89,16 @@ func Create(ctx context.Context, input engineframework.CreateInput, spec *v1.Spe
 		return nil, fmt.Errorf("failed to get orchestrator: %w", err)
 	}
 
-	// Convert engineframework.CreateInput to v1.CreateInput
-	// The orchestrator expects Spec as map[string]any, so we convert using ToMap()
-	v1Input := &v1.CreateInput{
+	// Call the orchestrator through its embedding API
+	env, err := engine.Wrap(o).Create(ctx, engine.CreateOptions{
+		Spec:     spec,
 		TestID:   input.TestID,
 		Stage:    input.Stage,
 		TmpDir:   input.TmpDir,
 		RootDir:  input.RootDir,
 		Metadata: input.Metadata,
-		Spec:     spec.ToMap(),
 		Env:      input.Env,
-	}
-
-	// Call the orchestrator
-	createResult, err := o.Create(ctx, v1Input)
+	})
 	if err != nil {
 		log.Printf("Create failed: %v", err)
 		return nil, err
@@ -102,11 +107,11 @@ func Create(ctx context.Context, input engineframework.CreateInput, spec *v1.Spe
 	// Convert v1.TestEnvArtifact to engineframework.TestEnvArtifact
 	// Note: Provisioner is not returned in MCP response (not JSON-serializable)
 	result := &engineframework.TestEnvArtifact{
-		TestID:           createResult.Artifact.TestID,
-		Files:            normalizeFilesMap(createResult.Artifact.Files),
-		Metadata:         normalizeMetadataMap(createResult.Artifact.Metadata),
-		ManagedResources: createResult.Artifact.ManagedResources,
-		Env:              normalizeEnvMap(createResult.Artifact.Env),
+		TestID:           env.Artifact.TestID,
+		Files:            normalizeFilesMap(env.Artifact.Files),
+		Metadata:         normalizeMetadataMap(env.Artifact.Metadata),
+		ManagedResources: env.Artifact.ManagedResources,
+		Env:              normalizeEnvMap(env.Artifact.Env),
 	}
 
 	log.Printf("Create succeeded: testID=%s", input.TestID)
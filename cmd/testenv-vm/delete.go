@@ -21,6 +21,7 @@ import (
 
 	"github.com/alexandremahdhaoui/forge/pkg/engineframework"
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/engine"
 )
 
 // Delete deletes a test environment identified by testID.
@@ -34,16 +35,12 @@ func Delete(ctx context.Context, input engineframework.DeleteInput, spec *v1.Spe
 		return fmt.Errorf("failed to get orchestrator: %w", err)
 	}
 
-	// Convert engineframework.DeleteInput to v1.DeleteInput
 	// Note: ManagedResources is not available in engineframework.DeleteInput
 	// The orchestrator loads state from disk using TestID instead
-	v1Input := &v1.DeleteInput{
+	if err := engine.Wrap(o).Destroy(ctx, engine.DestroyOptions{
 		TestID:   input.TestID,
 		Metadata: input.Metadata,
-	}
-
-	// Call the orchestrator
-	if err := o.Delete(ctx, v1Input); err != nil {
+	}); err != nil {
 		log.Printf("Delete failed: %v", err)
 		return err
 	}
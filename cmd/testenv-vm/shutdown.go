@@ -0,0 +1,98 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+const (
+	// defaultShutdownTimeout bounds how long in-flight operations may run
+	// after SIGTERM/SIGINT before they are cancelled, if the configuration
+	// cannot be loaded.
+	defaultShutdownTimeout = 2 * time.Minute
+	// abortTimeout bounds how long cancelled operations may take to roll back
+	// and persist their state.
+	abortTimeout = time.Minute
+)
+
+// Exit codes of a signal-triggered shutdown.
+const (
+	// exitShutdownClean means every in-flight operation finished.
+	exitShutdownClean = 0
+	// exitShutdownInterrupted means operations were cancelled or providers
+	// failed to stop; environments may be left in the failed state.
+	exitShutdownInterrupted = 1
+	// exitShutdownForced means a second signal was received.
+	exitShutdownForced = 130
+)
+
+// The generated main has no shutdown hook, so the handler is installed here.
+// It is a no-op for the version and docs subcommands, which exit before any
+// signal is likely to arrive.
+func init() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	go handleShutdown(signals)
+}
+
+// handleShutdown waits for a termination signal, drains the orchestrator,
+// and exits. A second signal exits immediately.
+func handleShutdown(signals <-chan os.Signal) {
+	sig := <-signals
+	timeout := shutdownTimeout()
+	log.Printf("Received %s, shutting down (waiting up to %s for in-flight operations)", sig, timeout)
+
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s again, exiting immediately", sig)
+		os.Exit(exitShutdownForced)
+	}()
+
+	// Prevent lazy initialization from starting providers during shutdown.
+	orchOnce.Do(func() {
+		orchErr = orchestrator.ErrShuttingDown
+	})
+	if orch == nil {
+		os.Exit(exitShutdownClean)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := orch.Shutdown(ctx, abortTimeout); err != nil {
+		log.Printf("Shutdown completed with errors: %v", err)
+		os.Exit(exitShutdownInterrupted)
+	}
+	log.Printf("Shutdown completed")
+	os.Exit(exitShutdownClean)
+}
+
+// shutdownTimeout returns the configured shutdown timeout
+// (TESTENV_VM_SHUTDOWN_TIMEOUT or shutdownTimeout in the config file).
+func shutdownTimeout() time.Duration {
+	c, err := loadConfig()
+	if err != nil {
+		log.Printf("Using default shutdown timeout %s: %v", defaultShutdownTimeout, err)
+		return defaultShutdownTimeout
+	}
+	return c.ShutdownTimeout.Duration
+}
@@ -1,6 +1,6 @@
 // Code generated by forge-dev. DO NOT EDIT.
 // Source: forge-dev.yaml
-// SourceChecksum: sha256:0c6ad4ca4d559fad64c32bfc433bfb835942c6ddbf253b20d3f3fcc9ffe4c5e1
+// SourceChecksum: sha256:1653181066b46853d741e9ed29dde8b106c5ea0a681b885bbaf5cf6c4b6f9446
 
 package main
 
@@ -1,6 +1,6 @@
 // Code generated by forge-dev. DO NOT EDIT.
 // Source: spec.openapi.yaml
-// SourceChecksum: sha256:0c6ad4ca4d559fad64c32bfc433bfb835942c6ddbf253b20d3f3fcc9ffe4c5e1
+// SourceChecksum: sha256:1653181066b46853d741e9ed29dde8b106c5ea0a681b885bbaf5cf6c4b6f9446
 
 package main
 
@@ -11,6 +11,89 @@ import (
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 )
 
+// ValidateAccessSpec validates a AccessSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateAccessSpec(s *v1.AccessSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: network
+	if s.Network == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.network",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: vm
+	if s.Vm == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.vm",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateBootArtifactSpec validates a BootArtifactSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateBootArtifactSpec(s *v1.BootArtifactSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateBootFileSpec validates a BootFileSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateBootFileSpec(s *v1.BootFileSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
 // ValidateBootSpec validates a BootSpec and returns validation results.
 // It checks required fields and validates enum values.
 func ValidateBootSpec(s *v1.BootSpec) *mcptypes.ConfigValidateOutput {
@@ -41,6 +124,52 @@ func ValidateBootSpec(s *v1.BootSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
+// ValidateCASpec validates a CASpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateCASpec(s *v1.CASpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateCertificateSpec validates a CertificateSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateCertificateSpec(s *v1.CertificateSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
 // ValidateCloudInitNameservers validates a CloudInitNameservers and returns validation results.
 // It checks required fields and validates enum values.
 func ValidateCloudInitNameservers(s *v1.CloudInitNameservers) *mcptypes.ConfigValidateOutput {
@@ -154,9 +283,9 @@ func ValidateWriteFileSpec(s *v1.WriteFileSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateDHCPSpec validates a DHCPSpec and returns validation results.
+// ValidateDHCPHostSpec validates a DHCPHostSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateDHCPSpec(s *v1.DHCPSpec) *mcptypes.ConfigValidateOutput {
+func ValidateDHCPHostSpec(s *v1.DHCPHostSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -164,6 +293,20 @@ func ValidateDHCPSpec(s *v1.DHCPSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
+	// Validate required field: ip
+	if s.Ip == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.ip",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: mac
+	if s.Mac == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.mac",
+			Message: "required field is missing",
+		})
+	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -177,9 +320,9 @@ func ValidateDHCPSpec(s *v1.DHCPSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateDNSSpec validates a DNSSpec and returns validation results.
+// ValidateDNSRecordSpec validates a DNSRecordSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateDNSSpec(s *v1.DNSSpec) *mcptypes.ConfigValidateOutput {
+func ValidateDNSRecordSpec(s *v1.DNSRecordSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -200,9 +343,9 @@ func ValidateDNSSpec(s *v1.DNSSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateDiskSpec validates a DiskSpec and returns validation results.
+// ValidateDataDiskSpec validates a DataDiskSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateDiskSpec(s *v1.DiskSpec) *mcptypes.ConfigValidateOutput {
+func ValidateDataDiskSpec(s *v1.DataDiskSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -230,9 +373,9 @@ func ValidateDiskSpec(s *v1.DiskSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateImageCustomizeSpec validates a ImageCustomizeSpec and returns validation results.
+// ValidateDiskDefaultsSpec validates a DiskDefaultsSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateImageCustomizeSpec(s *v1.ImageCustomizeSpec) *mcptypes.ConfigValidateOutput {
+func ValidateDiskDefaultsSpec(s *v1.DiskDefaultsSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -253,9 +396,9 @@ func ValidateImageCustomizeSpec(s *v1.ImageCustomizeSpec) *mcptypes.ConfigValida
 	}
 }
 
-// ValidateKeySpec validates a KeySpec and returns validation results.
+// ValidateSSHReadinessSpec validates a SSHReadinessSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateKeySpec(s *v1.KeySpec) *mcptypes.ConfigValidateOutput {
+func ValidateSSHReadinessSpec(s *v1.SSHReadinessSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -263,13 +406,6 @@ func ValidateKeySpec(s *v1.KeySpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: type
-	if s.Type == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.type",
-			Message: "required field is missing",
-		})
-	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -283,9 +419,9 @@ func ValidateKeySpec(s *v1.KeySpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateTFTPSpec validates a TFTPSpec and returns validation results.
+// ValidateTCPReadinessSpec validates a TCPReadinessSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateTFTPSpec(s *v1.TFTPSpec) *mcptypes.ConfigValidateOutput {
+func ValidateTCPReadinessSpec(s *v1.TCPReadinessSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -306,9 +442,9 @@ func ValidateTFTPSpec(s *v1.TFTPSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateProviderConfig validates a ProviderConfig and returns validation results.
+// ValidateDiskEncryptionSpec validates a DiskEncryptionSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateProviderConfig(s *v1.ProviderConfig) *mcptypes.ConfigValidateOutput {
+func ValidateDiskEncryptionSpec(s *v1.DiskEncryptionSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -316,20 +452,6 @@ func ValidateProviderConfig(s *v1.ProviderConfig) *mcptypes.ConfigValidateOutput
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: engine
-	if s.Engine == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.engine",
-			Message: "required field is missing",
-		})
-	}
-	// Validate required field: name
-	if s.Name == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.name",
-			Message: "required field is missing",
-		})
-	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -343,9 +465,9 @@ func ValidateProviderConfig(s *v1.ProviderConfig) *mcptypes.ConfigValidateOutput
 	}
 }
 
-// ValidateSSHReadinessSpec validates a SSHReadinessSpec and returns validation results.
+// ValidateGuestNameserversSpec validates a GuestNameserversSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateSSHReadinessSpec(s *v1.SSHReadinessSpec) *mcptypes.ConfigValidateOutput {
+func ValidateGuestNameserversSpec(s *v1.GuestNameserversSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -366,9 +488,9 @@ func ValidateSSHReadinessSpec(s *v1.SSHReadinessSpec) *mcptypes.ConfigValidateOu
 	}
 }
 
-// ValidateTCPReadinessSpec validates a TCPReadinessSpec and returns validation results.
+// ValidateGuestRouteSpec validates a GuestRouteSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateTCPReadinessSpec(s *v1.TCPReadinessSpec) *mcptypes.ConfigValidateOutput {
+func ValidateGuestRouteSpec(s *v1.GuestRouteSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -376,6 +498,20 @@ func ValidateTCPReadinessSpec(s *v1.TCPReadinessSpec) *mcptypes.ConfigValidateOu
 	}
 
 	var errors []mcptypes.ValidationError
+	// Validate required field: to
+	if s.To == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.to",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: via
+	if s.Via == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.via",
+			Message: "required field is missing",
+		})
+	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -389,9 +525,9 @@ func ValidateTCPReadinessSpec(s *v1.TCPReadinessSpec) *mcptypes.ConfigValidateOu
 	}
 }
 
-// ValidateResourceRef validates a ResourceRef and returns validation results.
+// ValidateIdleWakeSpec validates a IdleWakeSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateResourceRef(s *v1.ResourceRef) *mcptypes.ConfigValidateOutput {
+func ValidateIdleWakeSpec(s *v1.IdleWakeSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -399,17 +535,17 @@ func ValidateResourceRef(s *v1.ResourceRef) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: kind
-	if s.Kind == "" {
+	// Validate required field: listen
+	if s.Listen == "" {
 		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.kind",
+			Field:   "spec.listen",
 			Message: "required field is missing",
 		})
 	}
-	// Validate required field: name
-	if s.Name == "" {
+	// Validate required field: vm
+	if s.Vm == "" {
 		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.name",
+			Field:   "spec.vm",
 			Message: "required field is missing",
 		})
 	}
@@ -426,9 +562,9 @@ func ValidateResourceRef(s *v1.ResourceRef) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateCloudInitEthernetConfig validates a CloudInitEthernetConfig and returns validation results.
+// ValidateImageCustomizeSpec validates a ImageCustomizeSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateCloudInitEthernetConfig(s *v1.CloudInitEthernetConfig) *mcptypes.ConfigValidateOutput {
+func ValidateImageCustomizeSpec(s *v1.ImageCustomizeSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -436,26 +572,6 @@ func ValidateCloudInitEthernetConfig(s *v1.CloudInitEthernetConfig) *mcptypes.Co
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: name
-	if s.Name == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.name",
-			Message: "required field is missing",
-		})
-	}
-	// Validate nested reference: nameservers
-	{
-		nested := s.Nameservers
-		nestedResult := ValidateCloudInitNameservers(&nested)
-		if !nestedResult.Valid {
-			for _, e := range nestedResult.Errors {
-				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.nameservers." + e.Field,
-					Message: e.Message,
-				})
-			}
-		}
-	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -469,9 +585,9 @@ func ValidateCloudInitEthernetConfig(s *v1.CloudInitEthernetConfig) *mcptypes.Co
 	}
 }
 
-// ValidateImageSpec validates a ImageSpec and returns validation results.
+// ValidateKeySpec validates a KeySpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateImageSpec(s *v1.ImageSpec) *mcptypes.ConfigValidateOutput {
+func ValidateKeySpec(s *v1.KeySpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -479,22 +595,10 @@ func ValidateImageSpec(s *v1.ImageSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate nested reference: customize
-	if s.Customize != nil {
-		nestedResult := ValidateImageCustomizeSpec(s.Customize)
-		if !nestedResult.Valid {
-			for _, e := range nestedResult.Errors {
-				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.customize." + e.Field,
-					Message: e.Message,
-				})
-			}
-		}
-	}
-	// Validate required field: source
-	if s.Source == "" {
+	// Validate required field: type
+	if s.Type == "" {
 		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.source",
+			Field:   "spec.type",
 			Message: "required field is missing",
 		})
 	}
@@ -511,9 +615,9 @@ func ValidateImageSpec(s *v1.ImageSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateKeyResource validates a KeyResource and returns validation results.
+// ValidateMdevSpec validates a MdevSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateKeyResource(s *v1.KeyResource) *mcptypes.ConfigValidateOutput {
+func ValidateMdevSpec(s *v1.MdevSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -521,27 +625,6 @@ func ValidateKeyResource(s *v1.KeyResource) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: name
-	if s.Name == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.name",
-			Message: "required field is missing",
-		})
-	}
-	// Validate required reference field: spec
-	// Validate nested reference: spec
-	{
-		nested := s.Spec
-		nestedResult := ValidateKeySpec(&nested)
-		if !nestedResult.Valid {
-			for _, e := range nestedResult.Errors {
-				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.spec." + e.Field,
-					Message: e.Message,
-				})
-			}
-		}
-	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -555,9 +638,9 @@ func ValidateKeyResource(s *v1.KeyResource) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateNetworkSpec validates a NetworkSpec and returns validation results.
+// ValidateNTPSpec validates a NTPSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateNetworkSpec(s *v1.NetworkSpec) *mcptypes.ConfigValidateOutput {
+func ValidateNTPSpec(s *v1.NTPSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -565,11 +648,1233 @@ func ValidateNetworkSpec(s *v1.NetworkSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate nested reference: dhcp
-	if s.Dhcp != nil {
-		nestedResult := ValidateDHCPSpec(s.Dhcp)
-		if !nestedResult.Valid {
-			for _, e := range nestedResult.Errors {
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateNotifierSpec validates a NotifierSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateNotifierSpec(s *v1.NotifierSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: type
+	if s.Type == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.type",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateParentSpec validates a ParentSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateParentSpec(s *v1.ParentSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateProviderConfig validates a ProviderConfig and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateProviderConfig(s *v1.ProviderConfig) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: engine
+	if s.Engine == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.engine",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateResourceRef validates a ResourceRef and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateResourceRef(s *v1.ResourceRef) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: kind
+	if s.Kind == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.kind",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateServiceSpec validates a ServiceSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateServiceSpec(s *v1.ServiceSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: network
+	if s.Network == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.network",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: type
+	if s.Type == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.type",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateTunnelSpec validates a TunnelSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateTunnelSpec(s *v1.TunnelSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: localNetwork
+	if s.LocalNetwork == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.localNetwork",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: remoteNetwork
+	if s.RemoteNetwork == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.remoteNetwork",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVMAgentSpec validates a VMAgentSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVMAgentSpec(s *v1.VMAgentSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVsockSpec validates a VsockSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVsockSpec(s *v1.VsockSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVMNICSpec validates a VMNICSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVMNICSpec(s *v1.VMNICSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: network
+	if s.Network == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.network",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVMSecuritySpec validates a VMSecuritySpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVMSecuritySpec(s *v1.VMSecuritySpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateWebhookSpec validates a WebhookSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateWebhookSpec(s *v1.WebhookSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: url
+	if s.Url == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.url",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateAccessResource validates a AccessResource and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateAccessResource(s *v1.AccessResource) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateAccessSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.spec." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateTFTPSpec validates a TFTPSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateTFTPSpec(s *v1.TFTPSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: artifacts
+	for i, item := range s.Artifacts {
+		nestedResult := ValidateBootArtifactSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.artifacts[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: files
+	for i, item := range s.Files {
+		nestedResult := ValidateBootFileSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.files[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateCertificateResource validates a CertificateResource and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateCertificateResource(s *v1.CertificateResource) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateCertificateSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.spec." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateCloudInitEthernetConfig validates a CloudInitEthernetConfig and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateCloudInitEthernetConfig(s *v1.CloudInitEthernetConfig) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate nested reference: nameservers
+	{
+		nested := s.Nameservers
+		nestedResult := ValidateCloudInitNameservers(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.nameservers." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateDHCPSpec validates a DHCPSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateDHCPSpec(s *v1.DHCPSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: hosts
+	for i, item := range s.Hosts {
+		nestedResult := ValidateDHCPHostSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.hosts[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateDNSSpec validates a DNSSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateDNSSpec(s *v1.DNSSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: records
+	for i, item := range s.Records {
+		nestedResult := ValidateDNSRecordSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.records[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateReadinessSpec validates a ReadinessSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateReadinessSpec(s *v1.ReadinessSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: cloudInit
+	{
+		nested := s.CloudInit
+		nestedResult := ValidateCloudInitReadinessSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.cloudInit." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: ssh
+	{
+		nested := s.Ssh
+		nestedResult := ValidateSSHReadinessSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.ssh." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: tcp
+	{
+		nested := s.Tcp
+		nestedResult := ValidateTCPReadinessSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.tcp." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateDiskSpec validates a DiskSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateDiskSpec(s *v1.DiskSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: encryption
+	if s.Encryption != nil {
+		nestedResult := ValidateDiskEncryptionSpec(s.Encryption)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.encryption." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate required field: size
+	if s.Size == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.size",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateGuestBondSpec validates a GuestBondSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateGuestBondSpec(s *v1.GuestBondSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: interfaces
+	if len(s.Interfaces) == 0 {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.interfaces",
+			Message: "required field is missing or empty",
+		})
+	}
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate nested reference: nameservers
+	{
+		nested := s.Nameservers
+		nestedResult := ValidateGuestNameserversSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.nameservers." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: routes
+	for i, item := range s.Routes {
+		nestedResult := ValidateGuestRouteSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.routes[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateGuestEthernetSpec validates a GuestEthernetSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateGuestEthernetSpec(s *v1.GuestEthernetSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate nested reference: nameservers
+	{
+		nested := s.Nameservers
+		nestedResult := ValidateGuestNameserversSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.nameservers." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: routes
+	for i, item := range s.Routes {
+		nestedResult := ValidateGuestRouteSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.routes[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateGuestVLANSpec validates a GuestVLANSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateGuestVLANSpec(s *v1.GuestVLANSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: link
+	if s.Link == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.link",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate nested reference: nameservers
+	{
+		nested := s.Nameservers
+		nestedResult := ValidateGuestNameserversSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.nameservers." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: routes
+	for i, item := range s.Routes {
+		nestedResult := ValidateGuestRouteSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.routes[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateIdleSpec validates a IdleSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateIdleSpec(s *v1.IdleSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: wake
+	for i, item := range s.Wake {
+		nestedResult := ValidateIdleWakeSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.wake[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateImageSpec validates a ImageSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateImageSpec(s *v1.ImageSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: customize
+	if s.Customize != nil {
+		nestedResult := ValidateImageCustomizeSpec(s.Customize)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.customize." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate required field: source
+	if s.Source == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.source",
+			Message: "required field is missing",
+		})
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateKeyResource validates a KeyResource and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateKeyResource(s *v1.KeyResource) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateKeySpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.spec." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateServiceResource validates a ServiceResource and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateServiceResource(s *v1.ServiceResource) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateServiceSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.spec." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateTunnelResource validates a TunnelResource and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateTunnelResource(s *v1.TunnelResource) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateTunnelSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.spec." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVMDevicesSpec validates a VMDevicesSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVMDevicesSpec(s *v1.VMDevicesSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: mdevs
+	for i, item := range s.Mdevs {
+		nestedResult := ValidateMdevSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.mdevs[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: vsock
+	if s.Vsock != nil {
+		nestedResult := ValidateVsockSpec(s.Vsock)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.vsock." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateCloudInitNetworkConfig validates a CloudInitNetworkConfig and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateCloudInitNetworkConfig(s *v1.CloudInitNetworkConfig) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate array of references: ethernets
+	for i, item := range s.Ethernets {
+		nestedResult := ValidateCloudInitEthernetConfig(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.ethernets[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateNetworkDefaultsSpec validates a NetworkDefaultsSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateNetworkDefaultsSpec(s *v1.NetworkDefaultsSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: dhcp
+	if s.Dhcp != nil {
+		nestedResult := ValidateDHCPSpec(s.Dhcp)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.dhcp." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: dns
+	if s.Dns != nil {
+		nestedResult := ValidateDNSSpec(s.Dns)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.dns." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateNetworkSpec validates a NetworkSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateNetworkSpec(s *v1.NetworkSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: dhcp
+	if s.Dhcp != nil {
+		nestedResult := ValidateDHCPSpec(s.Dhcp)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
 					Field:   "spec.dhcp." + e.Field,
 					Message: e.Message,
@@ -589,13 +1894,74 @@ func ValidateNetworkSpec(s *v1.NetworkSpec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
-	// Validate nested reference: tftp
-	if s.Tftp != nil {
-		nestedResult := ValidateTFTPSpec(s.Tftp)
+	// Validate nested reference: ntp
+	if s.Ntp != nil {
+		nestedResult := ValidateNTPSpec(s.Ntp)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.ntp." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: tftp
+	if s.Tftp != nil {
+		nestedResult := ValidateTFTPSpec(s.Tftp)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.tftp." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return &mcptypes.ConfigValidateOutput{
+			Valid:  false,
+			Errors: errors,
+		}
+	}
+
+	return &mcptypes.ConfigValidateOutput{
+		Valid: true,
+	}
+}
+
+// ValidateVMDefaultsSpec validates a VMDefaultsSpec and returns validation results.
+// It checks required fields and validates enum values.
+func ValidateVMDefaultsSpec(s *v1.VMDefaultsSpec) *mcptypes.ConfigValidateOutput {
+	if s == nil {
+		return &mcptypes.ConfigValidateOutput{
+			Valid: true,
+		}
+	}
+
+	var errors []mcptypes.ValidationError
+	// Validate nested reference: disk
+	{
+		nested := s.Disk
+		nestedResult := ValidateDiskDefaultsSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.disk." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: readiness
+	{
+		nested := s.Readiness
+		nestedResult := ValidateReadinessSpec(&nested)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.tftp." + e.Field,
+					Field:   "spec.readiness." + e.Field,
 					Message: e.Message,
 				})
 			}
@@ -614,9 +1980,9 @@ func ValidateNetworkSpec(s *v1.NetworkSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateReadinessSpec validates a ReadinessSpec and returns validation results.
+// ValidateGuestNetworkConfigSpec validates a GuestNetworkConfigSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateReadinessSpec(s *v1.ReadinessSpec) *mcptypes.ConfigValidateOutput {
+func ValidateGuestNetworkConfigSpec(s *v1.GuestNetworkConfigSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -624,40 +1990,37 @@ func ValidateReadinessSpec(s *v1.ReadinessSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate nested reference: cloudInit
-	{
-		nested := s.CloudInit
-		nestedResult := ValidateCloudInitReadinessSpec(&nested)
+	// Validate array of references: bonds
+	for i, item := range s.Bonds {
+		nestedResult := ValidateGuestBondSpec(&item)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.cloudInit." + e.Field,
+					Field:   fmt.Sprintf("spec.bonds[%d].%s", i, e.Field),
 					Message: e.Message,
 				})
 			}
 		}
 	}
-	// Validate nested reference: ssh
-	{
-		nested := s.Ssh
-		nestedResult := ValidateSSHReadinessSpec(&nested)
+	// Validate array of references: ethernets
+	for i, item := range s.Ethernets {
+		nestedResult := ValidateGuestEthernetSpec(&item)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.ssh." + e.Field,
+					Field:   fmt.Sprintf("spec.ethernets[%d].%s", i, e.Field),
 					Message: e.Message,
 				})
 			}
 		}
 	}
-	// Validate nested reference: tcp
-	{
-		nested := s.Tcp
-		nestedResult := ValidateTCPReadinessSpec(&nested)
+	// Validate array of references: vlans
+	for i, item := range s.Vlans {
+		nestedResult := ValidateGuestVLANSpec(&item)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.tcp." + e.Field,
+					Field:   fmt.Sprintf("spec.vlans[%d].%s", i, e.Field),
 					Message: e.Message,
 				})
 			}
@@ -676,9 +2039,9 @@ func ValidateReadinessSpec(s *v1.ReadinessSpec) *mcptypes.ConfigValidateOutput {
 	}
 }
 
-// ValidateCloudInitNetworkConfig validates a CloudInitNetworkConfig and returns validation results.
+// ValidateImageResource validates a ImageResource and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateCloudInitNetworkConfig(s *v1.CloudInitNetworkConfig) *mcptypes.ConfigValidateOutput {
+func ValidateImageResource(s *v1.ImageResource) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -686,13 +2049,22 @@ func ValidateCloudInitNetworkConfig(s *v1.CloudInitNetworkConfig) *mcptypes.Conf
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate array of references: ethernets
-	for i, item := range s.Ethernets {
-		nestedResult := ValidateCloudInitEthernetConfig(&item)
+	// Validate required field: name
+	if s.Name == "" {
+		errors = append(errors, mcptypes.ValidationError{
+			Field:   "spec.name",
+			Message: "required field is missing",
+		})
+	}
+	// Validate required reference field: spec
+	// Validate nested reference: spec
+	{
+		nested := s.Spec
+		nestedResult := ValidateImageSpec(&nested)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   fmt.Sprintf("spec.ethernets[%d].%s", i, e.Field),
+					Field:   "spec.spec." + e.Field,
 					Message: e.Message,
 				})
 			}
@@ -711,9 +2083,9 @@ func ValidateCloudInitNetworkConfig(s *v1.CloudInitNetworkConfig) *mcptypes.Conf
 	}
 }
 
-// ValidateImageResource validates a ImageResource and returns validation results.
+// ValidateCloudInitSpec validates a CloudInitSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateImageResource(s *v1.ImageResource) *mcptypes.ConfigValidateOutput {
+func ValidateCloudInitSpec(s *v1.CloudInitSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -721,22 +2093,38 @@ func ValidateImageResource(s *v1.ImageResource) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate required field: name
-	if s.Name == "" {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.name",
-			Message: "required field is missing",
-		})
-	}
-	// Validate required reference field: spec
-	// Validate nested reference: spec
+	// Validate nested reference: networkConfig
 	{
-		nested := s.Spec
-		nestedResult := ValidateImageSpec(&nested)
+		nested := s.NetworkConfig
+		nestedResult := ValidateCloudInitNetworkConfig(&nested)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.spec." + e.Field,
+					Field:   "spec.networkConfig." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: users
+	for i, item := range s.Users {
+		nestedResult := ValidateUserSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.users[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: writeFiles
+	for i, item := range s.WriteFiles {
+		nestedResult := ValidateWriteFileSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.writeFiles[%d].%s", i, e.Field),
 					Message: e.Message,
 				})
 			}
@@ -806,9 +2194,9 @@ func ValidateNetworkResource(s *v1.NetworkResource) *mcptypes.ConfigValidateOutp
 	}
 }
 
-// ValidateCloudInitSpec validates a CloudInitSpec and returns validation results.
+// ValidateDefaultsSpec validates a DefaultsSpec and returns validation results.
 // It checks required fields and validates enum values.
-func ValidateCloudInitSpec(s *v1.CloudInitSpec) *mcptypes.ConfigValidateOutput {
+func ValidateDefaultsSpec(s *v1.DefaultsSpec) *mcptypes.ConfigValidateOutput {
 	if s == nil {
 		return &mcptypes.ConfigValidateOutput{
 			Valid: true,
@@ -816,38 +2204,27 @@ func ValidateCloudInitSpec(s *v1.CloudInitSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
-	// Validate nested reference: networkConfig
+	// Validate nested reference: network
 	{
-		nested := s.NetworkConfig
-		nestedResult := ValidateCloudInitNetworkConfig(&nested)
-		if !nestedResult.Valid {
-			for _, e := range nestedResult.Errors {
-				errors = append(errors, mcptypes.ValidationError{
-					Field:   "spec.networkConfig." + e.Field,
-					Message: e.Message,
-				})
-			}
-		}
-	}
-	// Validate array of references: users
-	for i, item := range s.Users {
-		nestedResult := ValidateUserSpec(&item)
+		nested := s.Network
+		nestedResult := ValidateNetworkDefaultsSpec(&nested)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   fmt.Sprintf("spec.users[%d].%s", i, e.Field),
+					Field:   "spec.network." + e.Field,
 					Message: e.Message,
 				})
 			}
 		}
 	}
-	// Validate array of references: writeFiles
-	for i, item := range s.WriteFiles {
-		nestedResult := ValidateWriteFileSpec(&item)
+	// Validate nested reference: vm
+	{
+		nested := s.Vm
+		nestedResult := ValidateVMDefaultsSpec(&nested)
 		if !nestedResult.Valid {
 			for _, e := range nestedResult.Errors {
 				errors = append(errors, mcptypes.ValidationError{
-					Field:   fmt.Sprintf("spec.writeFiles[%d].%s", i, e.Field),
+					Field:   "spec.vm." + e.Field,
 					Message: e.Message,
 				})
 			}
@@ -876,6 +2253,18 @@ func ValidateVMSpec(s *v1.VMSpec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
+	// Validate nested reference: agent
+	if s.Agent != nil {
+		nestedResult := ValidateVMAgentSpec(s.Agent)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.agent." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
 	// Validate required reference field: boot
 	// Validate nested reference: boot
 	{
@@ -903,6 +2292,19 @@ func ValidateVMSpec(s *v1.VMSpec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
+	// Validate nested reference: devices
+	{
+		nested := s.Devices
+		nestedResult := ValidateVMDevicesSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.devices." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
 	// Validate required reference field: disk
 	// Validate nested reference: disk
 	{
@@ -917,6 +2319,43 @@ func ValidateVMSpec(s *v1.VMSpec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
+	// Validate array of references: disks
+	for i, item := range s.Disks {
+		nestedResult := ValidateDataDiskSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.disks[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: networkConfig
+	{
+		nested := s.NetworkConfig
+		nestedResult := ValidateGuestNetworkConfigSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.networkConfig." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: nics
+	for i, item := range s.Nics {
+		nestedResult := ValidateVMNICSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.nics[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
 	// Validate nested reference: readiness
 	{
 		nested := s.Readiness
@@ -930,6 +2369,18 @@ func ValidateVMSpec(s *v1.VMSpec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
+	// Validate nested reference: security
+	if s.Security != nil {
+		nestedResult := ValidateVMSecuritySpec(s.Security)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.security." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -997,6 +2448,69 @@ func ValidateSpec(s *v1.Spec) *mcptypes.ConfigValidateOutput {
 	}
 
 	var errors []mcptypes.ValidationError
+	// Validate array of references: access
+	for i, item := range s.Access {
+		nestedResult := ValidateAccessResource(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.access[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: ca
+	{
+		nested := s.Ca
+		nestedResult := ValidateCASpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.ca." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: certificates
+	for i, item := range s.Certificates {
+		nestedResult := ValidateCertificateResource(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.certificates[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: defaults
+	{
+		nested := s.Defaults
+		nestedResult := ValidateDefaultsSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.defaults." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: idle
+	{
+		nested := s.Idle
+		nestedResult := ValidateIdleSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.idle." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
+	}
 	// Validate array of references: images
 	for i, item := range s.Images {
 		nestedResult := ValidateImageResource(&item)
@@ -1033,12 +2547,30 @@ func ValidateSpec(s *v1.Spec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
-	// Validate required array of references: providers
-	if len(s.Providers) == 0 {
-		errors = append(errors, mcptypes.ValidationError{
-			Field:   "spec.providers",
-			Message: "required field is missing or empty",
-		})
+	// Validate array of references: notifiers
+	for i, item := range s.Notifiers {
+		nestedResult := ValidateNotifierSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.notifiers[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate nested reference: parent
+	{
+		nested := s.Parent
+		nestedResult := ValidateParentSpec(&nested)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   "spec.parent." + e.Field,
+					Message: e.Message,
+				})
+			}
+		}
 	}
 	// Validate array of references: providers
 	for i, item := range s.Providers {
@@ -1052,6 +2584,30 @@ func ValidateSpec(s *v1.Spec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
+	// Validate array of references: services
+	for i, item := range s.Services {
+		nestedResult := ValidateServiceResource(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.services[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
+	// Validate array of references: tunnels
+	for i, item := range s.Tunnels {
+		nestedResult := ValidateTunnelResource(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.tunnels[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
 	// Validate array of references: vms
 	for i, item := range s.Vms {
 		nestedResult := ValidateVMResource(&item)
@@ -1064,6 +2620,18 @@ func ValidateSpec(s *v1.Spec) *mcptypes.ConfigValidateOutput {
 			}
 		}
 	}
+	// Validate array of references: webhooks
+	for i, item := range s.Webhooks {
+		nestedResult := ValidateWebhookSpec(&item)
+		if !nestedResult.Valid {
+			for _, e := range nestedResult.Errors {
+				errors = append(errors, mcptypes.ValidationError{
+					Field:   fmt.Sprintf("spec.webhooks[%d].%s", i, e.Field),
+					Message: e.Message,
+				})
+			}
+		}
+	}
 
 	if len(errors) > 0 {
 		return &mcptypes.ConfigValidateOutput{
@@ -0,0 +1,155 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// CaptureStartInput is the input of the testenv_capture_start tool.
+type CaptureStartInput struct {
+	// EnvironmentID identifies the environment.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment"`
+	// Network or VM is the resource to capture.
+	Network string `json:"network,omitempty" jsonschema:"Name of the network to capture, as declared in the spec (exclusive with vm)"`
+	VM      string `json:"vm,omitempty" jsonschema:"Name of the VM whose interface is captured, as declared in the spec (exclusive with network)"`
+	// MAC selects the interface of the VM.
+	MAC string `json:"mac,omitempty" jsonschema:"MAC address of the VM interface to capture (default: the first interface)"`
+	// Filter is a pcap-filter expression.
+	Filter string `json:"filter,omitempty" jsonschema:"pcap-filter expression, e.g. 'tcp port 443'"`
+	// MaxSizeMB and MaxDuration are the limits of the capture.
+	MaxSizeMB   int    `json:"maxSizeMB,omitempty" jsonschema:"Maximum size of the pcap file in MB (default 100)"`
+	MaxDuration string `json:"maxDuration,omitempty" jsonschema:"Maximum duration of the capture as a Go duration (default 10m)"`
+}
+
+// CaptureStopInput is the input of the testenv_capture_stop tool.
+type CaptureStopInput struct {
+	// ID is the capture ID returned by testenv_capture_start.
+	ID string `json:"id" jsonschema:"ID of the capture returned by testenv_capture_start"`
+}
+
+// makeCaptureStartHandler creates the handler for the testenv_capture_start tool.
+func makeCaptureStartHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, CaptureStartInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CaptureStartInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_capture_start called: environmentID=%s network=%s vm=%s filter=%q",
+			input.EnvironmentID, input.Network, input.VM, input.Filter)
+		if input.EnvironmentID == "" {
+			return errorResult("environmentID is required"), nil, nil
+		}
+		opts := orchestrator.CaptureOptions{
+			Network:   input.Network,
+			VM:        input.VM,
+			MAC:       input.MAC,
+			Filter:    input.Filter,
+			MaxSizeMB: input.MaxSizeMB,
+		}
+		if input.MaxDuration != "" {
+			d, err := time.ParseDuration(input.MaxDuration)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid maxDuration %q: %v", input.MaxDuration, err)), nil, nil
+			}
+			opts.MaxDuration = d
+		}
+		c, err := o.StartCapture(input.EnvironmentID, opts)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return captureResult(c), nil, nil
+	}
+}
+
+// makeCaptureStopHandler creates the handler for the testenv_capture_stop tool.
+func makeCaptureStopHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, CaptureStopInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CaptureStopInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_capture_stop called: id=%s", input.ID)
+		if input.ID == "" {
+			return errorResult("id is required"), nil, nil
+		}
+		c, err := o.StopCapture(input.ID)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return captureResult(c), nil, nil
+	}
+}
+
+// captureResult returns a capture as a JSON text result.
+func captureResult(c *orchestrator.Capture) *mcp.CallToolResult {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to marshal capture: %v", err))
+	}
+	return textResult(string(data))
+}
+
+// runCapture implements the capture subcommand. It captures in the
+// foreground until the duration elapses or it is interrupted, then prints
+// the pcap file.
+func runCapture(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	network := fs.String("network", "", "Network to capture, as declared in the spec")
+	vm := fs.String("vm", "", "VM whose interface is captured, as declared in the spec")
+	mac := fs.String("mac", "", "MAC address of the VM interface (default: the first interface)")
+	filter := fs.String("filter", "", "pcap-filter expression, e.g. 'tcp port 443'")
+	maxSize := fs.Int("max-size", 0, "Maximum size of the pcap file in MB (default: provider default)")
+	duration := fs.Duration("duration", 0, "Duration of the capture (default: until interrupted, at most the provider default)")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("capture: expected an environment ID")
+	}
+
+	c, err := o.StartCapture(fs.Arg(0), orchestrator.CaptureOptions{
+		Network:     *network,
+		VM:          *vm,
+		MAC:         *mac,
+		Filter:      *filter,
+		MaxSizeMB:   *maxSize,
+		MaxDuration: *duration,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "capturing %s into %s (at most %d MB, %ds), interrupt to stop\n",
+		c.Interface, c.Path, c.MaxSizeMB, c.MaxDurationSec)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Duration(c.MaxDurationSec) * time.Second):
+	}
+
+	c, err = o.StopCapture(c.ID)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s: %d bytes (%s)\n", c.Path, c.SizeBytes, c.StopReason)
+	return err
+}
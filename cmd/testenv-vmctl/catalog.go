@@ -0,0 +1,163 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/catalog"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// Actions of the testenv_catalog tool.
+const (
+	catalogList   = "list"
+	catalogShow   = "show"
+	catalogRender = "render"
+)
+
+// CatalogInput is the input of the testenv_catalog tool.
+type CatalogInput struct {
+	// Action is list (default), show or render.
+	Action string `json:"action,omitempty" jsonschema:"list (default) returns all templates, show returns one template with the JSON Schema of its parameters, render returns a spec ready for create"`
+	// Name is the template name, required by show and render.
+	Name string `json:"name,omitempty" jsonschema:"Template name, e.g. k8s-ha"`
+	// Version selects a template version; empty means the latest.
+	Version string `json:"version,omitempty" jsonschema:"Template version (default: latest)"`
+	// Parameters are the template parameters of render.
+	Parameters map[string]any `json:"parameters,omitempty" jsonschema:"Template parameters for render"`
+}
+
+// catalogTemplate is a template as shown by the show action.
+type catalogTemplate struct {
+	*catalog.Template
+	ParameterSchema map[string]any `json:"parameterSchema"`
+}
+
+// makeCatalogHandler creates the handler for the testenv_catalog tool.
+func makeCatalogHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, CatalogInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CatalogInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_catalog called: action=%s name=%s version=%s", input.Action, input.Name, input.Version)
+		c, err := o.OpenCatalog(ctx)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+
+		var result any
+		switch input.Action {
+		case "", catalogList:
+			result, err = c.List()
+		case catalogShow:
+			result, err = showTemplate(c, input.Name, input.Version)
+		case catalogRender:
+			var data []byte
+			if data, err = c.Render(input.Name, input.Version, input.Parameters); err == nil {
+				return textResult(string(data)), nil, nil
+			}
+		default:
+			err = fmt.Errorf("unknown action %q (expected %s, %s or %s)", input.Action, catalogList, catalogShow, catalogRender)
+		}
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal catalog: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// showTemplate returns a template with the schema of its parameters.
+func showTemplate(c *catalog.Catalog, name, version string) (*catalogTemplate, error) {
+	t, err := c.Get(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return &catalogTemplate{Template: t, ParameterSchema: t.ParameterSchema()}, nil
+}
+
+// runCatalog implements the catalog subcommand:
+//
+//	catalog list
+//	catalog show <name>[@version]
+//	catalog render <name>[@version] [key=value ...]
+func runCatalog(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("catalog: expected %s, %s or %s", catalogList, catalogShow, catalogRender)
+	}
+	c, err := o.OpenCatalog(context.Background())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case catalogList:
+		templates, err := c.List()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVERSION\tDESCRIPTION")
+		for _, t := range templates {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", t.Name, t.Version, t.Description)
+		}
+		return tw.Flush()
+	case catalogShow:
+		if len(args) != 2 {
+			return usageErrorf("catalog show: expected exactly one template")
+		}
+		name, version, _ := strings.Cut(args[1], "@")
+		t, err := showTemplate(c, name, version)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case catalogRender:
+		if len(args) < 2 {
+			return usageErrorf("catalog render: expected a template and key=value parameters")
+		}
+		name, version, _ := strings.Cut(args[1], "@")
+		params := make(map[string]any, len(args)-2)
+		for _, arg := range args[2:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("catalog render: invalid parameter %q, expected key=value", arg)
+			}
+			params[key] = value
+		}
+		data, err := c.Render(name, version, params)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("catalog: unknown action %q (expected %s, %s or %s)", args[0], catalogList, catalogShow, catalogRender)
+	}
+}
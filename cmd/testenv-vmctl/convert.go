@@ -0,0 +1,88 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/convert"
+)
+
+// ConvertInput is the input of the testenv_convert tool.
+type ConvertInput struct {
+	// From is the source format: vagrantfile or cloud-config.
+	From string `json:"from" jsonschema:"Source format: vagrantfile or cloud-config"`
+	// Content is the source document.
+	Content string `json:"content" jsonschema:"Content of the Vagrantfile or cloud-config document"`
+}
+
+// makeConvertHandler creates the handler for the testenv_convert tool.
+func makeConvertHandler() func(context.Context, *mcp.CallToolRequest, ConvertInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ConvertInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_convert called: from=%s", input.From)
+		out, err := convertDocument(input.From, []byte(input.Content))
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(string(out)), nil, nil
+	}
+}
+
+// runConvert implements the convert subcommand. It does not need an
+// orchestrator.
+func runConvert(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	from := fs.String("from", "", "Source format: vagrantfile or cloud-config")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("convert: expected exactly one source file (- for stdin)")
+	}
+
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		return fmt.Errorf("convert: %w", err)
+	}
+
+	out, err := convertDocument(*from, data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// convertDocument converts data into a spec document.
+func convertDocument(from string, data []byte) ([]byte, error) {
+	result, err := convert.Convert(from, data)
+	if err != nil {
+		return nil, err
+	}
+	return result.YAML()
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// VMCopyInput is the input of the vm_copy_to and vm_copy_from tools.
+type VMCopyInput struct {
+	// EnvironmentID identifies the environment owning the VM.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the VM"`
+	// VM is the VM name as declared in the spec.
+	VM         string `json:"vm" jsonschema:"Name of the VM as declared in the spec"`
+	LocalPath  string `json:"localPath" jsonschema:"Path of the file on the host running the server"`
+	RemotePath string `json:"remotePath" jsonschema:"Path of the file on the VM"`
+	// Mode and Owner only apply to vm_copy_to.
+	Mode  string `json:"mode,omitempty" jsonschema:"vm_copy_to only: octal mode of the remote file, e.g. 0644"`
+	Owner string `json:"owner,omitempty" jsonschema:"vm_copy_to only: owner of the remote file, set with sudo chown, e.g. root:root"`
+}
+
+// validate checks the fields both copy tools require.
+func (in VMCopyInput) validate() error {
+	if in.EnvironmentID == "" || in.VM == "" || in.LocalPath == "" || in.RemotePath == "" {
+		return fmt.Errorf("environmentID, vm, localPath and remotePath are required")
+	}
+	return nil
+}
+
+// makeVMCopyToHandler creates the handler for the vm_copy_to tool.
+func makeVMCopyToHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, VMCopyInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input VMCopyInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_copy_to called: environmentID=%s vm=%s remotePath=%s", input.EnvironmentID, input.VM, input.RemotePath)
+		if err := input.validate(); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		opts := orchestrator.CopyOptions{Mode: input.Mode, Owner: input.Owner}
+		if err := o.CopyToVM(ctx, input.EnvironmentID, input.VM, input.LocalPath, input.RemotePath, opts); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(fmt.Sprintf("Copied %s to %s on vm %s", input.LocalPath, input.RemotePath, input.VM)), nil, nil
+	}
+}
+
+// makeVMCopyFromHandler creates the handler for the vm_copy_from tool.
+func makeVMCopyFromHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, VMCopyInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input VMCopyInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_copy_from called: environmentID=%s vm=%s remotePath=%s", input.EnvironmentID, input.VM, input.RemotePath)
+		if err := input.validate(); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		if input.Mode != "" || input.Owner != "" {
+			return errorResult("mode and owner only apply to vm_copy_to"), nil, nil
+		}
+		if err := o.CopyFromVM(ctx, input.EnvironmentID, input.VM, input.RemotePath, input.LocalPath); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(fmt.Sprintf("Copied %s on vm %s to %s", input.RemotePath, input.VM, input.LocalPath)), nil, nil
+	}
+}
+
+// runCopy implements the copy subcommand:
+//
+//	copy to [--mode M] [--owner O] <environment-id> <vm> <local> <remote>
+//	copy from <environment-id> <vm> <remote> <local>
+func runCopy(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("copy: expected to or from")
+	}
+	fs := flag.NewFlagSet("copy "+args[0], flag.ContinueOnError)
+	var mode, owner *string
+	switch args[0] {
+	case "to":
+		mode = fs.String("mode", "", "Octal mode of the remote file, e.g. 0644")
+		owner = fs.String("owner", "", "Owner of the remote file, set with sudo chown")
+	case "from":
+	default:
+		return usageErrorf("copy: unknown direction %q (expected to or from)", args[0])
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 4 {
+		return usageErrorf("copy %s: expected an environment ID, a VM name and two paths", args[0])
+	}
+	envID, vm, src, dst := fs.Arg(0), fs.Arg(1), fs.Arg(2), fs.Arg(3)
+
+	if args[0] == "to" {
+		if err := o.CopyToVM(context.Background(), envID, vm, src, dst,
+			orchestrator.CopyOptions{Mode: *mode, Owner: *owner}); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(w, "Copied %s to %s on vm %s\n", src, dst, vm)
+		return err
+	}
+	if err := o.CopyFromVM(context.Background(), envID, vm, src, dst); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "Copied %s on vm %s to %s\n", src, vm, dst)
+	return err
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Events of the log notifications sent when an asynchronous create ends.
+const (
+	eventCreated      = "testenv.created"
+	eventCreateFailed = "testenv.create_failed"
+)
+
+// notificationLogger is the logger name of the notifications of testenv-vmctl.
+const notificationLogger = "testenv-vm"
+
+// notifyTimeout bounds sending a notification to a client.
+const notifyTimeout = 10 * time.Second
+
+// CreateAsyncInput is the input of the testenv_create_async tool. It carries
+// the fields of the create input of the testenv-vm engine.
+type CreateAsyncInput struct {
+	TestID   string            `json:"testID" jsonschema:"Test ID identifying the environment for testenv_wait and the completion notification"`
+	Stage    string            `json:"stage,omitempty" jsonschema:"Test stage"`
+	TmpDir   string            `json:"tmpDir,omitempty" jsonschema:"Directory receiving the artifact files"`
+	RootDir  string            `json:"rootDir,omitempty" jsonschema:"Root directory of the project"`
+	Metadata map[string]string `json:"metadata,omitempty" jsonschema:"Metadata of the test"`
+	Spec     map[string]any    `json:"spec" jsonschema:"Environment spec, as passed to create"`
+	Env      map[string]string `json:"env,omitempty" jsonschema:"Environment variables available to templates"`
+	Seed     string            `json:"seed,omitempty" jsonschema:"Seed deriving the MAC addresses and UUIDs of the VMs, overriding spec.seed; pass the seed recorded in the state of a failed environment to reproduce it (default: random, recorded in state)"`
+}
+
+// CreateWaitInput is the input of the testenv_wait tool.
+type CreateWaitInput struct {
+	TestID      string `json:"testID,omitempty" jsonschema:"Test ID passed to testenv_create_async"`
+	OperationID string `json:"operationID,omitempty" jsonschema:"Operation ID returned by testenv_create_async, instead of testID"`
+	// Timeout is a Go duration; empty means 5m.
+	Timeout string `json:"timeout,omitempty" jsonschema:"Maximum time to wait as a Go duration (default 5m)"`
+}
+
+// createNotification is the data of the log notification sent to the client
+// that started an asynchronous create, once it ended.
+type createNotification struct {
+	// Event is eventCreated or eventCreateFailed.
+	Event       string `json:"event"`
+	OperationID string `json:"operationID"`
+	TestID      string `json:"testID"`
+	// Status is the final status of the operation.
+	Status string `json:"status"`
+	// Artifact holds the outputs of a successful create.
+	Artifact *v1.TestEnvArtifact `json:"artifact,omitempty"`
+	// Error is the error of a failed create.
+	Error string `json:"error,omitempty"`
+}
+
+// makeCreateAsyncHandler creates the handler for the testenv_create_async
+// tool. It returns as soon as the creation started; the client is notified
+// when it ends.
+func makeCreateAsyncHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, CreateAsyncInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CreateAsyncInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_create_async called: testID=%s stage=%s", input.TestID, input.Stage)
+		if input.TestID == "" || input.Spec == nil {
+			return errorResult("testID and spec are required"), nil, nil
+		}
+		// Report typos now rather than in the notification
+		if err := specpkg.CheckUnknownFields(input.Spec); err != nil {
+			return errorResult(fmt.Sprintf("failed to parse spec: %v", err)), nil, nil
+		}
+		if input.Seed != "" {
+			input.Spec["seed"] = input.Seed
+		}
+
+		session := req.Session
+		op, err := o.CreateAsync(&v1.CreateInput{
+			TestID:   input.TestID,
+			Stage:    input.Stage,
+			TmpDir:   input.TmpDir,
+			RootDir:  input.RootDir,
+			Metadata: input.Metadata,
+			Spec:     input.Spec,
+			Env:      input.Env,
+		}, func(op *orchestrator.Operation) {
+			notifyCreate(session, op)
+		})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return operationResult(op)
+	}
+}
+
+// notifyCreate sends the outcome of an asynchronous create to the client as
+// a log notification. Clients only receive it once they set a logging level.
+func notifyCreate(session *mcp.ServerSession, op *orchestrator.Operation) {
+	data := createNotification{
+		Event:       eventCreated,
+		OperationID: op.ID,
+		TestID:      op.TestID,
+		Status:      op.Status,
+		Artifact:    op.Artifact,
+		Error:       op.Error,
+	}
+	level := mcp.LoggingLevel("info")
+	if op.Status != orchestrator.OperationSucceeded {
+		data.Event, level = eventCreateFailed, "error"
+	}
+	log.Printf("Operation %s (create of testID %s) %s", op.ID, op.TestID, op.Status)
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := session.Log(ctx, &mcp.LoggingMessageParams{
+		Level:  level,
+		Logger: notificationLogger,
+		Data:   data,
+	}); err != nil {
+		log.Printf("Warning: failed to notify the client of operation %s: %v", op.ID, err)
+	}
+}
+
+// makeCreateWaitHandler creates the handler for the testenv_wait tool. It
+// returns the artifact of the creation as JSON, or the record of the
+// operation when waiting by operation ID.
+func makeCreateWaitHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, CreateWaitInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input CreateWaitInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_wait called: testID=%s operationID=%s timeout=%s", input.TestID, input.OperationID, input.Timeout)
+		if (input.TestID == "") == (input.OperationID == "") {
+			return errorResult("exactly one of testID and operationID is required"), nil, nil
+		}
+		timeout := orchestrator.DefaultWaitTimeout
+		if input.Timeout != "" {
+			d, err := time.ParseDuration(input.Timeout)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid timeout %q: %v", input.Timeout, err)), nil, nil
+			}
+			timeout = d
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if input.OperationID != "" {
+			op, err := o.WaitOperation(ctx, input.OperationID)
+			if err != nil {
+				return errorResult(err.Error()), nil, nil
+			}
+			return operationResult(op)
+		}
+		result, err := o.WaitCreate(ctx, input.TestID)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result.Artifact, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal artifact: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
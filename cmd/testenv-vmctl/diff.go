@@ -0,0 +1,131 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// DiffInput is the input of the testenv_diff tool.
+type DiffInput struct {
+	// EnvironmentID identifies the environment.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment"`
+	// Since selects the earlier revision.
+	Since string `json:"since" jsonschema:"Earlier revision to compare the current state with: a revision number (0 for before creation), an RFC3339 timestamp, or a duration before now such as 24h"`
+}
+
+// makeDiffHandler creates the handler for the testenv_diff tool.
+func makeDiffHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, DiffInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input DiffInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_diff called: environmentID=%s since=%s", input.EnvironmentID, input.Since)
+		if input.EnvironmentID == "" || input.Since == "" {
+			return errorResult("environmentID and since are required"), nil, nil
+		}
+		diff, err := o.Diff(input.EnvironmentID, input.Since)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal diff: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runDiff implements the diff subcommand.
+func runDiff(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	since := fs.String("since", "", "Revision number, RFC3339 timestamp, or duration before now (e.g. 24h) to compare with")
+	jsonOutput := fs.Bool("json", false, "Print the diff as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 || *since == "" {
+		return usageErrorf("diff: expected --since and an environment ID")
+	}
+
+	diff, err := o.Diff(fs.Arg(0), *since)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	_, err = io.WriteString(w, formatDiff(diff))
+	return err
+}
+
+// formatDiff prints a diff with one line per added (+), removed (-) or
+// changed (~) resource, followed by its changed fields.
+func formatDiff(diff *orchestrator.StateDiff) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s -> %s\n", diff.EnvironmentID, formatRevision(diff.From), formatRevision(diff.To))
+	writeFieldChanges(&sb, "", diff.Fields)
+	for _, r := range diff.Resources {
+		switch r.Change {
+		case orchestrator.ChangeAdded:
+			fmt.Fprintf(&sb, "+ %s/%s\n", r.Kind, r.Name)
+		case orchestrator.ChangeRemoved:
+			fmt.Fprintf(&sb, "- %s/%s\n", r.Kind, r.Name)
+		default:
+			fmt.Fprintf(&sb, "~ %s/%s\n", r.Kind, r.Name)
+			writeFieldChanges(&sb, "    ", r.Fields)
+		}
+	}
+	if len(diff.Fields) == 0 && len(diff.Resources) == 0 {
+		sb.WriteString("no changes\n")
+	}
+	return sb.String()
+}
+
+// formatRevision describes a revision of a diff.
+func formatRevision(r state.Revision) string {
+	if r.Number == 0 {
+		return "creation"
+	}
+	return fmt.Sprintf("revision %d (%s)", r.Number, r.SavedAt)
+}
+
+// writeFieldChanges prints changed fields, one per line.
+func writeFieldChanges(sb *strings.Builder, indent string, fields []orchestrator.FieldChange) {
+	for _, f := range fields {
+		fmt.Fprintf(sb, "%s~ %s: %s -> %s\n", indent, f.Field, formatFieldValue(f.From), formatFieldValue(f.To))
+	}
+}
+
+// formatFieldValue prints an unset value as (none).
+func formatFieldValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
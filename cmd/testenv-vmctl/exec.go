@@ -0,0 +1,134 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// VMExecInput is the input of the vm_exec tool.
+type VMExecInput struct {
+	// EnvironmentID identifies the environment owning the VM.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the VM"`
+	// VM is the VM name as declared in the spec.
+	VM string `json:"vm" jsonschema:"Name of the VM as declared in the spec"`
+	// Command is run by sh on the VM.
+	Command string `json:"command" jsonschema:"Shell command to run on the VM"`
+	Sudo    bool   `json:"sudo,omitempty" jsonschema:"Run the command with sudo"`
+	// Env sets environment variables of the command.
+	Env map[string]string `json:"env,omitempty" jsonschema:"Environment variables of the command"`
+	Dir string            `json:"dir,omitempty" jsonschema:"Working directory of the command"`
+	// Timeout is a Go duration; empty means 5m.
+	Timeout string `json:"timeout,omitempty" jsonschema:"Maximum run time as a Go duration (default 5m)"`
+}
+
+// makeVMExecHandler creates the handler for the vm_exec tool. It returns the
+// output and exit code of the command as JSON; a non-zero exit code is not a
+// tool error.
+func makeVMExecHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, VMExecInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input VMExecInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_exec called: environmentID=%s vm=%s sudo=%t", input.EnvironmentID, input.VM, input.Sudo)
+		if input.EnvironmentID == "" || input.VM == "" || input.Command == "" {
+			return errorResult("environmentID, vm and command are required"), nil, nil
+		}
+		opts := orchestrator.ExecOptions{Sudo: input.Sudo, Env: input.Env, Dir: input.Dir}
+		if input.Timeout != "" {
+			d, err := time.ParseDuration(input.Timeout)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid timeout %q: %v", input.Timeout, err)), nil, nil
+			}
+			opts.Timeout = d
+		}
+		result, err := o.ExecVM(ctx, input.EnvironmentID, input.VM, input.Command, opts)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal result: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// envFlag collects repeated KEY=VALUE flags.
+type envFlag map[string]string
+
+func (f envFlag) String() string { return "" }
+
+func (f envFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", s)
+	}
+	f[key] = value
+	return nil
+}
+
+// runExec implements the exec subcommand. The command's stdout goes to w
+// and its stderr to stderr; a non-zero exit code fails the subcommand.
+func runExec(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	sudo := fs.Bool("sudo", false, "Run the command with sudo")
+	dir := fs.String("dir", "", "Working directory of the command")
+	timeout := fs.Duration("timeout", orchestrator.DefaultExecTimeout, "Maximum run time")
+	jsonOutput := fs.Bool("json", false, "Print stdout, stderr and the exit code as JSON")
+	env := envFlag{}
+	fs.Var(env, "env", "Environment variable of the command as KEY=VALUE (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() < 3 {
+		return usageErrorf("exec: expected an environment ID, a VM name and a command")
+	}
+
+	result, err := o.ExecVM(context.Background(), fs.Arg(0), fs.Arg(1), strings.Join(fs.Args()[2:], " "),
+		orchestrator.ExecOptions{Sudo: *sudo, Env: env, Dir: *dir, Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	} else {
+		if _, err := io.WriteString(w, result.Stdout); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(os.Stderr, result.Stderr); err != nil {
+			return err
+		}
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("exec: command exited with status %d", result.ExitCode)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Exit codes of the CLI subcommands. They are stable, so CI pipelines can
+// branch on the failure category without parsing logs.
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitUsage      = 2
+	exitValidation = 3
+	exitProvider   = 4
+	exitTimeout    = 5
+	exitPartial    = 6
+)
+
+// Failure categories reported in the failure summary, one per exit code.
+const (
+	categoryFailure    = "error"
+	categoryUsage      = "usage"
+	categoryValidation = "validation"
+	categoryProvider   = "provider"
+	categoryTimeout    = "timeout"
+	categoryPartial    = "partial"
+)
+
+// errPartialFailure is wrapped by errors of commands that completed for some
+// resources only.
+var errPartialFailure = errors.New("partial failure")
+
+// usageError reports a command line that cannot be run.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+func (e *usageError) Unwrap() error { return e.err }
+
+// usageErrorf returns a usageError with a formatted message.
+func usageErrorf(format string, a ...any) error {
+	return &usageError{fmt.Errorf(format, a...)}
+}
+
+// failureSummary is the JSON object printed as the last line of stderr when
+// a subcommand fails.
+type failureSummary struct {
+	// Command is the subcommand that failed, if any.
+	Command string `json:"command,omitempty"`
+	// Category is one of error, usage, validation, provider, timeout and
+	// partial.
+	Category string `json:"category"`
+	// ExitCode is the exit code of the process.
+	ExitCode int `json:"exitCode"`
+	// Error is the error message.
+	Error string `json:"error"`
+	// Tool is the provider tool that failed, for provider errors.
+	Tool string `json:"tool,omitempty"`
+	// ProviderCode is the error code reported by the provider, if any.
+	ProviderCode string `json:"providerCode,omitempty"`
+}
+
+// classify returns the category and exit code of err.
+func classify(err error) (string, int) {
+	var usageErr *usageError
+	var unknownField *spec.UnknownFieldError
+	var providerErr *orchestrator.ProviderError
+	isProviderErr := errors.As(err, &providerErr)
+	switch {
+	case err == nil:
+		return "", exitOK
+	case errors.As(err, &usageErr):
+		return categoryUsage, exitUsage
+	case errors.Is(err, errPartialFailure):
+		return categoryPartial, exitPartial
+	case errors.Is(err, orchestrator.ErrWaitTimeout), errors.Is(err, orchestrator.ErrCreateDeadlineExceeded),
+		errors.Is(err, context.DeadlineExceeded), isProviderErr && providerErr.Code == providerv1.ErrCodeTimeout:
+		return categoryTimeout, exitTimeout
+	case errors.Is(err, orchestrator.ErrInvalidSpec), errors.As(err, &unknownField),
+		isProviderErr && providerErr.Code == providerv1.ErrCodeInvalidSpec:
+		return categoryValidation, exitValidation
+	case isProviderErr:
+		return categoryProvider, exitProvider
+	default:
+		return categoryFailure, exitFailure
+	}
+}
+
+// fail prints err and its failure summary to w and returns the exit code of
+// the failed command.
+func fail(w io.Writer, command string, err error) int {
+	category, code := classify(err)
+	summary := failureSummary{
+		Command:  command,
+		Category: category,
+		ExitCode: code,
+		Error:    err.Error(),
+	}
+	var providerErr *orchestrator.ProviderError
+	if errors.As(err, &providerErr) {
+		summary.Tool = providerErr.Tool
+		summary.ProviderCode = providerErr.Code
+	}
+	fmt.Fprintln(w, err)
+	// Marshalling a struct of strings and ints cannot fail.
+	data, _ := json.Marshal(summary)
+	fmt.Fprintln(w, string(data))
+	return code
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ExportInput is the input of the testenv_export tool.
+type ExportInput struct {
+	// EnvironmentID identifies the environment to export. It is optional
+	// for the inventory formats, which then list every environment.
+	EnvironmentID string `json:"environmentId,omitempty" jsonschema:"ID of the environment to export. Optional for csv and ndjson, which then list every environment of the state directory"`
+	// Format is one of diagram (default), svg, json, terraform, csv, or
+	// ndjson.
+	Format string `json:"format,omitempty" jsonschema:"Export format: diagram (Mermaid, default), svg, json, terraform (OpenTofu/Terraform configuration with import blocks), or csv and ndjson (inventory with one row per resource: environment, kind, name, provider, ip, created, status, error)"`
+}
+
+// makeExportHandler creates the handler for the testenv_export tool.
+func makeExportHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ExportInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ExportInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_export called: environmentId=%s format=%s", input.EnvironmentID, input.Format)
+		if input.EnvironmentID == "" && !inventoryFormat(input.Format) {
+			return errorResult("environmentId is required"), nil, nil
+		}
+		out, err := o.Export(input.EnvironmentID, input.Format)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(out), nil, nil
+	}
+}
+
+// runExport implements the export subcommand.
+func runExport(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", orchestrator.ExportFormatDiagram, "Export format: diagram, svg, json, terraform, csv, or ndjson")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	switch {
+	case inventoryFormat(*format) && fs.NArg() > 1:
+		return usageErrorf("export: expected at most one environment ID with --format %s", *format)
+	case !inventoryFormat(*format) && fs.NArg() != 1:
+		return usageErrorf("export: expected exactly one environment ID")
+	}
+
+	out, err := o.Export(fs.Arg(0), *format)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+// inventoryFormat reports whether format lists the inventory of the state
+// directory rather than a single environment.
+func inventoryFormat(format string) bool {
+	return format == orchestrator.ExportFormatCSV || format == orchestrator.ExportFormatNDJSON
+}
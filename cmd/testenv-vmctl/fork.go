@@ -0,0 +1,121 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ForkInput is the input of the testenv_fork tool.
+type ForkInput struct {
+	// EnvironmentID identifies the environment to fork.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the ready environment to fork"`
+	// Count is the number of forks to create.
+	Count int `json:"count,omitempty" jsonschema:"Number of forks to create (default 1)"`
+	// Env is passed to the creation of each fork.
+	Env map[string]string `json:"env,omitempty" jsonschema:"Environment variables passed to the creation of each fork"`
+}
+
+// makeForkHandler creates the handler for the testenv_fork tool.
+func makeForkHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ForkInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ForkInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_fork called: environmentID=%s count=%d", input.EnvironmentID, input.Count)
+		if input.EnvironmentID == "" {
+			return errorResult("environmentID is required"), nil, nil
+		}
+		count := input.Count
+		if count == 0 {
+			count = 1
+		}
+		result, err := o.Fork(ctx, input.EnvironmentID, count, input.Env)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal forks: %v", err)), nil, nil
+		}
+		if forkError(result) != nil {
+			return errorResult(string(data)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runFork implements the fork subcommand.
+func runFork(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("fork", flag.ContinueOnError)
+	count := fs.Int("count", 1, "Number of forks to create")
+	jsonOutput := fs.Bool("json", false, "Print the forks as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("fork: expected an environment ID")
+	}
+
+	result, err := o.Fork(context.Background(), fs.Arg(0), *count, nil)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "FORK\tSTATUS\tERROR")
+		for _, f := range result.Forks {
+			status := "ready"
+			if f.Error != "" {
+				status = "failed"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.EnvironmentID, status, f.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return forkError(result)
+}
+
+// forkError returns a partial failure when some forks could not be created.
+func forkError(result *orchestrator.ForkResult) error {
+	var failed []string
+	for _, f := range result.Forks {
+		if f.Error != "" {
+			failed = append(failed, f.EnvironmentID)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("fork: forks %s not created: %w", strings.Join(failed, ", "), errPartialFailure)
+}
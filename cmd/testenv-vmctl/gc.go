@@ -0,0 +1,131 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// GCInput is the input of the testenv_gc tool.
+type GCInput struct {
+	// Spec provides providers to search besides those of the recorded
+	// environments.
+	Spec map[string]any `json:"spec,omitempty" jsonschema:"testenv-vm spec whose providers are searched besides those of the recorded environments, e.g. when no environment is left"`
+	// DryRun only reports the orphans.
+	DryRun bool `json:"dryRun,omitempty" jsonschema:"Only report the orphaned resources, without deleting them (allowed in read-only mode)"`
+}
+
+// makeGCHandler creates the handler for the testenv_gc tool.
+func makeGCHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, GCInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input GCInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_gc called: dryRun=%t", input.DryRun)
+		opts := orchestrator.GCOptions{DryRun: input.DryRun}
+		if len(input.Spec) > 0 {
+			s, err := v1.SpecFromMap(input.Spec)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid spec: %v", err)), nil, nil
+			}
+			opts.Providers = s.Providers
+		}
+		result, err := o.GarbageCollect(ctx, opts)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal garbage collection: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runGC implements the gc subcommand. It fails with a partial failure when
+// orphans could not be deleted or providers searched.
+func runGC(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only print the orphaned resources, without deleting them")
+	specFile := fs.String("spec", "", "Also search the providers of this spec")
+	jsonOutput := fs.Bool("json", false, "Print the garbage collection as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("gc: unexpected argument %q", fs.Arg(0))
+	}
+
+	opts := orchestrator.GCOptions{DryRun: *dryRun}
+	if *specFile != "" {
+		data, err := os.ReadFile(*specFile)
+		if err != nil {
+			return fmt.Errorf("failed to read spec: %w", err)
+		}
+		parsed, err := spec.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse %s: %w", orchestrator.ErrInvalidSpec, *specFile, err)
+		}
+		opts.Providers = parsed.Providers
+	}
+
+	result, err := o.GarbageCollect(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	failed := len(result.Errors)
+	for _, orphan := range result.Orphans {
+		if orphan.Error != "" {
+			failed++
+		}
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "RESOURCE\tPROVIDER\tENVIRONMENT\tDELETED\tERROR")
+		for _, orphan := range result.Orphans {
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%t\t%s\n", orphan.Kind, orphan.Name, orphan.Provider, orphan.EnvironmentID, orphan.Deleted, orphan.Error)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, e := range result.Errors {
+			if _, err := fmt.Fprintf(w, "Error: %s\n", e); err != nil {
+				return err
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("gc: %d error(s): %w", failed, errPartialFailure)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// runIdle implements the idle subcommand, whose run action applies the idle
+// policies of the stored environments until interrupted.
+func runIdle(o *orchestrator.Orchestrator, args []string, _ io.Writer) error {
+	if len(args) == 0 || args[0] != "run" {
+		return usageErrorf("idle: expected run")
+	}
+	fs := flag.NewFlagSet("idle run", flag.ContinueOnError)
+	interval := fs.Duration("interval", orchestrator.DefaultIdleInterval, "How often to sample the VMs")
+	if err := fs.Parse(args[1:]); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("idle run: unexpected arguments")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return o.RunIdlePolicies(ctx, *interval)
+}
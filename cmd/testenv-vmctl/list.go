@@ -0,0 +1,87 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ListInput is the input of the testenv_list tool.
+type ListInput struct {
+	// Status only lists environments of this status.
+	Status string `json:"status,omitempty" jsonschema:"Only list environments of this status (creating, updating, ready, failed, destroying)"`
+}
+
+// makeListHandler creates the handler for the testenv_list tool.
+func makeListHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ListInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ListInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_list called: status=%s", input.Status)
+		environments, err := o.ListEnvironments(orchestrator.ListOptions{Status: input.Status})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(environments, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal environments: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runList implements the list subcommand.
+func runList(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	status := fs.String("status", "", "Only list environments of this status")
+	jsonOutput := fs.Bool("json", false, "Print the environments as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 0 {
+		return usageErrorf("list: unexpected argument %q", fs.Arg(0))
+	}
+
+	environments, err := o.ListEnvironments(orchestrator.ListOptions{Status: *status})
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(environments, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tSTAGE\tVMS\tPARENT\tCREATED")
+	for _, e := range environments {
+		if e.Error != "" {
+			fmt.Fprintf(tw, "%s\tunreadable: %s\t\t\t\t\n", e.ID, e.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", e.ID, e.Status, e.Stage, e.VMs, e.Parent, e.CreatedAt)
+	}
+	return tw.Flush()
+}
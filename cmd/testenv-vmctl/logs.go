@@ -0,0 +1,68 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ProviderLogsInput is the input of the provider_logs tool.
+type ProviderLogsInput struct {
+	// Provider is the provider name as declared in the spec.
+	Provider string `json:"provider" jsonschema:"Name of the provider whose logs to return"`
+	// Tail limits the output to the last N lines (0 returns everything).
+	Tail int `json:"tail,omitempty" jsonschema:"Return only the last N lines (0 for all)"`
+}
+
+// makeProviderLogsHandler creates the handler for the provider_logs tool.
+func makeProviderLogsHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ProviderLogsInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ProviderLogsInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("provider_logs called: provider=%s tail=%d", input.Provider, input.Tail)
+		if input.Provider == "" {
+			return errorResult("provider is required"), nil, nil
+		}
+		out, err := o.ProviderLogs(input.Provider, input.Tail)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(out), nil, nil
+	}
+}
+
+// runLogs implements the logs subcommand.
+func runLogs(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	tail := fs.Int("tail", 0, "Show only the last N lines")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("logs: expected exactly one provider name")
+	}
+
+	out, err := o.ProviderLogs(fs.Arg(0), *tail)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, out)
+	return err
+}
@@ -0,0 +1,402 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements testenv-vmctl, which exposes operations on existing
+// testenv-vm environments (export, logs, plan, ...) as MCP tools and CLI subcommands.
+// The testenv-vm engine binary is generated and only serves create/delete;
+// everything else lives here and shares its state directory.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/config"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// Version information (set via ldflags during build)
+var (
+	Version        = ""
+	CommitSHA      = "unknown"
+	BuildTimestamp = "unknown"
+)
+
+func init() {
+	if Version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			Version = info.Main.Version
+		} else {
+			Version = "dev"
+		}
+	}
+}
+
+const usage = `Usage:
+  testenv-vmctl [--config path] --mcp [--read-only] [--schedules] [--idle]
+  testenv-vmctl [--config path] capture [--network N | --vm V [--mac M]] [--filter F] [--max-size MB] [--duration D] <environment-id>
+  testenv-vmctl [--config path] catalog list|show <name>[@version]|render <name>[@version] [key=value ...]
+  testenv-vmctl convert --from vagrantfile|cloud-config <file|->
+  testenv-vmctl [--config path] copy to [--mode M] [--owner O] <environment-id> <vm> <local> <remote>
+  testenv-vmctl [--config path] copy from <environment-id> <vm> <remote> <local>
+  testenv-vmctl [--config path] diff --since <revision|timestamp|duration> [--json] <environment-id>
+  testenv-vmctl [--config path] exec [--sudo] [--dir D] [--env K=V ...] [--timeout 5m] [--json] <environment-id> <vm> <command ...>
+  testenv-vmctl [--config path] export [--format diagram|svg|json|terraform] <environment-id>
+  testenv-vmctl [--config path] export --format csv|ndjson [<environment-id>]
+  testenv-vmctl [--config path] fork [--count N] [--json] <environment-id>
+  testenv-vmctl [--config path] gc [--dry-run] [--spec spec.yaml] [--json]
+  testenv-vmctl [--config path] idle run [--interval 1m]
+  testenv-vmctl [--config path] list [--status S] [--json]
+  testenv-vmctl [--config path] logs [--tail N] <provider>
+  testenv-vmctl [--config path] migrate [--copy-storage] <environment-id> <vm> <provider>
+  testenv-vmctl [--config path] operation list|status <id>|wait [--timeout 5m] <id>
+  testenv-vmctl [--config path] plan [--test-id ID] <spec.yaml>
+  testenv-vmctl [--config path] power [--force] [--timeout 60s] start|stop|reboot|pause|save <environment-id> <vm>
+  testenv-vmctl [--config path] providers update [--json] [<spec.yaml> ...]
+  testenv-vmctl [--config path] reconcile [--recreate] [--json] <environment-id>
+  testenv-vmctl [--config path] rotate-key <environment-id> <key>
+  testenv-vmctl [--config path] schedule add [--stage S] <name> <cron> <spec.yaml>
+  testenv-vmctl [--config path] schedule list|remove <name>|trigger <name>|run [--interval 30s]
+  testenv-vmctl [--config path] stats [--interval 1s] [--json] <environment-id> [<vm> ...]
+  testenv-vmctl [--config path] status [--refresh] [<environment-id>]
+  testenv-vmctl [--config path] update [--dry-run] <environment-id> <spec.yaml>
+  testenv-vmctl validate [--json] <spec.yaml>
+  testenv-vmctl [--config path] wait [--timeout 5m] <environment-id> <vm> <running|ssh|cloud-init-done|port:N|file:PATH>
+
+Exit codes: 1 error, 2 usage, 3 invalid spec, 4 provider error, 5 timeout, 6 partial failure.
+A failed command prints a JSON summary as the last line of stderr.
+`
+
+func main() {
+	mcpFlag := flag.Bool("mcp", false, "Run as MCP server")
+	versionFlag := flag.Bool("version", false, "Show version information")
+	readOnlyFlag := flag.Bool("read-only", false, "Expose only read tools (also enabled by TESTENV_VM_READ_ONLY=true)")
+	schedulesFlag := flag.Bool("schedules", false, "Run due schedules in the background of the MCP server")
+	idleFlag := flag.Bool("idle", false, "Apply the idle policies of the environments in the background of the MCP server")
+	configFlag := flag.String("config", "", "Path to the config file (default: TESTENV_VM_CONFIG or ~/.config/testenv-vm/config.yaml)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if *versionFlag {
+		fmt.Printf("testenv-vmctl %s (commit: %s, built: %s)\n", Version, CommitSHA, BuildTimestamp)
+		os.Exit(0)
+	}
+
+	// convert and validate only read their input, so they run without
+	// configuration
+	if args := flag.Args(); !*mcpFlag && len(args) > 0 && (args[0] == "convert" || args[0] == "validate") {
+		run := runConvert
+		if args[0] == "validate" {
+			run = runValidate
+		}
+		if err := run(args[1:], os.Stdout); err != nil {
+			os.Exit(fail(os.Stderr, args[0], err))
+		}
+		return
+	}
+
+	o, err := newOrchestrator(*configFlag, *readOnlyFlag)
+	if err != nil {
+		if *mcpFlag {
+			log.Fatalf("Failed to create orchestrator: %v", err)
+		}
+		os.Exit(fail(os.Stderr, flag.Arg(0), fmt.Errorf("failed to create orchestrator: %w", err)))
+	}
+	defer func() {
+		_ = o.Close()
+	}()
+
+	if *mcpFlag {
+		if *schedulesFlag {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				if err := o.RunSchedules(ctx, orchestrator.DefaultScheduleInterval); err != nil {
+					log.Printf("Scheduler stopped: %v", err)
+				}
+			}()
+		}
+		if *idleFlag {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				if err := o.RunIdlePolicies(ctx, orchestrator.DefaultIdleInterval); err != nil {
+					log.Printf("Idle policies stopped: %v", err)
+				}
+			}()
+		}
+		if err := runMCPServer(o); err != nil {
+			log.Fatalf("MCP server failed: %v", err)
+		}
+		return
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		_ = o.Close()
+		os.Exit(fail(os.Stderr, "", usageErrorf("no command given")))
+	}
+	switch args[0] {
+	case "capture":
+		err = runCapture(o, args[1:], os.Stdout)
+	case "catalog":
+		err = runCatalog(o, args[1:], os.Stdout)
+	case "copy":
+		err = runCopy(o, args[1:], os.Stdout)
+	case "diff":
+		err = runDiff(o, args[1:], os.Stdout)
+	case "exec":
+		err = runExec(o, args[1:], os.Stdout)
+	case "export":
+		err = runExport(o, args[1:], os.Stdout)
+	case "fork":
+		err = runFork(o, args[1:], os.Stdout)
+	case "gc":
+		err = runGC(o, args[1:], os.Stdout)
+	case "idle":
+		err = runIdle(o, args[1:], os.Stdout)
+	case "list":
+		err = runList(o, args[1:], os.Stdout)
+	case "logs":
+		err = runLogs(o, args[1:], os.Stdout)
+	case "migrate":
+		err = runMigrate(o, args[1:], os.Stdout)
+	case "operation":
+		err = runOperation(o, args[1:], os.Stdout)
+	case "plan":
+		err = runPlan(o, args[1:], os.Stdout)
+	case "power":
+		err = runPower(o, args[1:], os.Stdout)
+	case "providers":
+		err = runProviders(o, args[1:], os.Stdout)
+	case "reconcile":
+		err = runReconcile(o, args[1:], os.Stdout)
+	case "rotate-key":
+		err = runRotateKey(o, args[1:], os.Stdout)
+	case "schedule":
+		err = runSchedule(o, args[1:], os.Stdout)
+	case "stats":
+		err = runStats(o, args[1:], os.Stdout)
+	case "status":
+		err = runStatus(o, args[1:], os.Stdout)
+	case "update":
+		err = runUpdate(o, args[1:], os.Stdout)
+	case "wait":
+		err = runWait(o, args[1:], os.Stdout)
+	default:
+		flag.Usage()
+		err = usageErrorf("unknown command %q", args[0])
+	}
+	if err != nil {
+		_ = o.Close()
+		os.Exit(fail(os.Stderr, args[0], err))
+	}
+}
+
+// newOrchestrator configures an orchestrator from the same config file and
+// environment variables as the testenv-vm engine, so both see the same
+// environments.
+func newOrchestrator(configPath string, readOnly bool) (*orchestrator.Orchestrator, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if _, err := cfg.SetupLogging(); err != nil {
+		log.Printf("Logging to file disabled: %v", err)
+	}
+
+	orchConfig, err := cfg.OrchestratorConfig()
+	if err != nil {
+		return nil, err
+	}
+	orchConfig.ReadOnly = orchConfig.ReadOnly || readOnly
+	return orchestrator.NewOrchestrator(orchConfig)
+}
+
+// runMCPServer starts the MCP server with stdio transport.
+func runMCPServer(o *orchestrator.Orchestrator) error {
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "testenv-vmctl",
+		Version: Version,
+	}, nil)
+
+	// Register read tools
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_export",
+		Description: "Export an environment as a topology diagram (Mermaid), SVG, JSON state, or Terraform/OpenTofu configuration, or the inventory of every environment as CSV or NDJSON",
+	}, makeExportHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "provider_logs",
+		Description: "Get the captured stderr of a provider, attributed by provider name and pid",
+	}, makeProviderLogsHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_plan",
+		Description: "Validate a spec and show its execution phases, requested memory/vCPU/disk, whether it fits on each provider's host, and which existing VMs would be updated, rebooted or replaced",
+	}, makePlanHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_convert",
+		Description: "Translate a Vagrantfile or cloud-config document into a testenv-vm spec skeleton, with warnings for settings that were not translated",
+	}, makeConvertHandler())
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_catalog",
+		Description: "Discover the configured catalog of named, versioned spec templates (e.g. k8s-ha, pxe-lab), show the parameter schema of one, or render it into a spec ready for create",
+	}, makeCatalogHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_validate",
+		Description: "Validate a spec and report every error and lint warning at once (unused images and networks, ignored fields), each with its path, code and message",
+	}, makeValidateHandler())
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_status",
+		Description: "Describe the providers of an environment, or the configured default providers, with their status, version and capabilities (resource kinds, operations, host capacity); for an environment, also return its full state and live VM health: the status reported by the provider and an SSH reachability probe",
+	}, makeStatusHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "provider_refresh_capabilities",
+		Description: "Fetch the capabilities of a provider again instead of using the ones cached when it started",
+	}, makeRefreshCapabilitiesHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_wait",
+		Description: "Block until a VM of an existing environment is running, accepts SSH, finished cloud-init, listens on port:<n>, or has file:<path>",
+	}, makeVMWaitHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_stats",
+		Description: "Snapshot the CPU, memory, disk and network usage of the VMs of an environment as seen from the host, with totals, to correlate with application metrics",
+	}, makeStatsHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_wait",
+		Description: "Block until an environment started by testenv_create_async is created and return its artifact (files, metadata, env), or its error; by operationID, return the operation record",
+	}, makeCreateWaitHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "operation_status",
+		Description: "Get the record of an asynchronous operation such as testenv_create_async: its status (running, succeeded, failed, cancelled), artifact or error",
+	}, makeOperationStatusHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_list",
+		Description: "List the environments of the state directory, oldest first, with their status, stage, resource counts and parent; optionally only those of one status",
+	}, makeListHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_diff",
+		Description: "Show what changed in the state of an environment since an earlier revision of its history, by revision number or time: resources added or removed, status transitions, IP and other state changes",
+	}, makeDiffHandler(o))
+
+	// Register mutating tools; the orchestrator rejects them in read-only mode
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_migrate_vm",
+		Description: "Live-migrate a VM of an existing environment to another provider of the same engine (e.g. a second libvirt host) and record its new host and IPs",
+	}, makeMigrateVMHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_capture_start",
+		Description: "Start capturing the packets of a network or VM interface of an environment into a pcap file under its artifact directory, with size and time limits",
+	}, makeCaptureStartHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_capture_stop",
+		Description: "Stop a packet capture started by testenv_capture_start and return its pcap file and size",
+	}, makeCaptureStopHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMStartTool,
+		Description: "Start a stopped VM of an existing environment, or resume a paused one",
+	}, makeVMPowerHandler(o, providerv1.PowerStart))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMStopTool,
+		Description: "Power off a VM of an existing environment, gracefully or with force to simulate a crash; its disks are kept for vm_start",
+	}, makeVMPowerHandler(o, providerv1.PowerStop))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMRebootTool,
+		Description: "Reboot a running VM of an existing environment, gracefully or with force to simulate a hard reset",
+	}, makeVMPowerHandler(o, providerv1.PowerReboot))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMPauseTool,
+		Description: "Pause the vCPUs of a running VM of an existing environment, e.g. to simulate a hung node, until vm_start resumes it",
+	}, makeVMPowerHandler(o, providerv1.PowerPause))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        providerv1.VMSaveTool,
+		Description: "Save the memory of a running VM of an existing environment to disk and power it off, freeing its host memory until vm_start restores it",
+	}, makeVMPowerHandler(o, providerv1.PowerSave))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_rotate_key",
+		Description: "Replace the key pair of a key of an existing environment: push the new public key over SSH to the VM users that authorized the old one, update the state and templates, then retire the old key",
+	}, makeRotateKeyHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_create_async",
+		Description: "Start creating an environment and return its operation ID immediately; the client gets a testenv.created or testenv.create_failed log notification with the artifact when it ends, or can call testenv_wait or operation_status",
+	}, makeCreateAsyncHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "operation_cancel",
+		Description: "Cancel a running asynchronous operation of this server; a cancelled create rolls back what it created",
+	}, makeOperationCancelHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_exec",
+		Description: "Run a shell command on a VM of an existing environment, through its guest agent or over SSH, and return its stdout, stderr and exit code",
+	}, makeVMExecHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_copy_to",
+		Description: "Copy a local file to a VM of an existing environment, through its guest agent or over SSH, optionally setting its mode and owner",
+	}, makeVMCopyToHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vm_copy_from",
+		Description: "Copy a file of a VM of an existing environment to a local path, through its guest agent or over SSH",
+	}, makeVMCopyFromHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_fork",
+		Description: "Create N copy-on-write forks of a ready environment for parallel test shards: VM disks become overlays of the parent's frozen disks and networks get new subnets; the parent cannot be deleted while forks exist",
+	}, makeForkHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_update",
+		Description: "Apply a new spec to an existing environment: diff it against the recorded state and create, replace or delete only the resources that changed, keeping untouched VMs; dryRun only reports the changes",
+	}, makeUpdateHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_reconcile",
+		Description: "Detect drift of an environment: compare the recorded keys, networks and VMs with what their providers list (key_list, network_list, vm_list), mark drifted and missing resources in the state, and optionally recreate them with what depends on them",
+	}, makeReconcileHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_providers_update",
+		Description: "Resolve the provider engines of specs and of the default providers to exact versions and digests, and record them in the provider lockfile (testenv-vm.lock) that pins providers when they start",
+	}, makeProvidersUpdateHandler(o))
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "testenv_gc",
+		Description: "Delete the keys, networks and VMs left on provider hosts by crashed runs, found by their labels or the hash of the environment ID in their names without any recorded environment owning them, and release their CIDR pool subnets; dryRun only reports them",
+	}, makeGCHandler(o))
+
+	// Logs go to stderr (and the configured log file), never to stdout,
+	// which is for JSON-RPC.
+	log.Printf("Starting testenv-vmctl MCP server (version: %s)", Version)
+
+	return server.Run(context.Background(), &mcp.StdioTransport{})
+}
+
+// errorResult creates a standardized MCP error result.
+func errorResult(message string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: message},
+		},
+		IsError: true,
+	}
+}
+
+// textResult creates an MCP result with a single text content.
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}
+}
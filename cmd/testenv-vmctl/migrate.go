@@ -0,0 +1,85 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// MigrateVMInput is the input of the testenv_migrate_vm tool.
+type MigrateVMInput struct {
+	// EnvironmentID identifies the environment owning the VM.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the VM"`
+	// VM is the VM name as declared in the spec.
+	VM string `json:"vm" jsonschema:"Name of the VM as declared in the spec"`
+	// Provider is the destination provider.
+	Provider string `json:"provider" jsonschema:"Destination provider, declared in the spec with the same engine as the VM's provider"`
+	// CopyStorage copies the VM disks to the destination host.
+	CopyStorage bool `json:"copyStorage,omitempty" jsonschema:"Copy the VM disks to the destination host (required unless storage is shared)"`
+}
+
+// makeMigrateVMHandler creates the handler for the testenv_migrate_vm tool.
+func makeMigrateVMHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, MigrateVMInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input MigrateVMInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_migrate_vm called: environmentID=%s vm=%s provider=%s copyStorage=%t",
+			input.EnvironmentID, input.VM, input.Provider, input.CopyStorage)
+		if input.EnvironmentID == "" || input.VM == "" || input.Provider == "" {
+			return errorResult("environmentID, vm and provider are required"), nil, nil
+		}
+		vmState, err := o.MigrateVM(ctx, input.EnvironmentID, input.VM, input.Provider,
+			orchestrator.MigrateOptions{CopyStorage: input.CopyStorage})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(migratedMessage(input.VM, vmState)), nil, nil
+	}
+}
+
+// runMigrate implements the migrate subcommand.
+func runMigrate(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	copyStorage := fs.Bool("copy-storage", false, "Copy the VM disks to the destination host")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 3 {
+		return usageErrorf("migrate: expected an environment ID, a VM name and a destination provider")
+	}
+
+	vmState, err := o.MigrateVM(context.Background(), fs.Arg(0), fs.Arg(1), fs.Arg(2), orchestrator.MigrateOptions{CopyStorage: *copyStorage})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, migratedMessage(fs.Arg(1), vmState))
+	return err
+}
+
+// migratedMessage describes where a migrated VM now runs.
+func migratedMessage(vmName string, vmState *v1.ResourceState) string {
+	msg := fmt.Sprintf("vm %s: migrated to provider %s", vmName, vmState.Provider)
+	if ip, _ := vmState.State["ip"].(string); ip != "" {
+		msg += fmt.Sprintf(" (ip %s)", ip)
+	}
+	return msg
+}
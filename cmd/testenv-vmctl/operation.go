@@ -0,0 +1,150 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// Subcommands of the operation subcommand.
+const (
+	operationList   = "list"
+	operationStatus = "status"
+	operationWait   = "wait"
+)
+
+// OperationInput is the input of the operation_status and operation_cancel
+// tools.
+type OperationInput struct {
+	OperationID string `json:"operationID" jsonschema:"ID of the operation, as returned by testenv_create_async"`
+}
+
+// makeOperationStatusHandler creates the handler for the operation_status
+// tool.
+func makeOperationStatusHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, OperationInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input OperationInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("operation_status called: operationID=%s", input.OperationID)
+		if input.OperationID == "" {
+			return errorResult("operationID is required"), nil, nil
+		}
+		op, err := o.Operation(input.OperationID)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return operationResult(op)
+	}
+}
+
+// makeOperationCancelHandler creates the handler for the operation_cancel
+// tool.
+func makeOperationCancelHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, OperationInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input OperationInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("operation_cancel called: operationID=%s", input.OperationID)
+		if input.OperationID == "" {
+			return errorResult("operationID is required"), nil, nil
+		}
+		op, err := o.CancelOperation(input.OperationID)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(fmt.Sprintf("operation %s: cancelling; its status becomes %s once rolled back",
+			op.ID, orchestrator.OperationCancelled)), nil, nil
+	}
+}
+
+// operationResult returns an operation record as a JSON tool result.
+func operationResult(op *orchestrator.Operation) (*mcp.CallToolResult, any, error) {
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return errorResult(fmt.Sprintf("failed to marshal operation: %v", err)), nil, nil
+	}
+	return textResult(string(data)), nil, nil
+}
+
+// runOperation implements the operation subcommand. Operations are started
+// and cancelled by the MCP server running them; the CLI reads their records.
+func runOperation(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("operation: expected %s, %s or %s", operationList, operationStatus, operationWait)
+	}
+	switch args[0] {
+	case operationList:
+		ops, err := o.Operations()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tKIND\tTEST ID\tSTATUS\tSTARTED\tERROR")
+		for _, op := range ops {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", op.ID, op.Kind, op.TestID, op.Status,
+				op.StartedAt.Local().Format(time.RFC3339), op.Error)
+		}
+		return tw.Flush()
+	case operationStatus:
+		if len(args) != 2 {
+			return usageErrorf("operation status: expected exactly one operation ID")
+		}
+		op, err := o.Operation(args[1])
+		if err != nil {
+			return err
+		}
+		return writeOperation(op, w)
+	case operationWait:
+		fs := flag.NewFlagSet("operation wait", flag.ContinueOnError)
+		timeout := fs.Duration("timeout", orchestrator.DefaultWaitTimeout, "Maximum time to wait")
+		if err := fs.Parse(args[1:]); err != nil {
+			return &usageError{err}
+		}
+		if fs.NArg() != 1 {
+			return usageErrorf("operation wait: expected exactly one operation ID")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		op, err := o.WaitOperation(ctx, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := writeOperation(op, w); err != nil {
+			return err
+		}
+		if op.Status != orchestrator.OperationSucceeded {
+			return fmt.Errorf("operation %s %s: %s", op.ID, op.Status, op.Error)
+		}
+		return nil
+	default:
+		return usageErrorf("operation: unknown subcommand %q", args[0])
+	}
+}
+
+// writeOperation prints an operation record as JSON.
+func writeOperation(op *orchestrator.Operation, w io.Writer) error {
+	data, err := json.MarshalIndent(op, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
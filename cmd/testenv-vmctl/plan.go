@@ -0,0 +1,140 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// PlanInput is the input of the testenv_plan tool.
+type PlanInput struct {
+	// Spec is the testenv-vm spec to plan, as passed to create.
+	Spec map[string]any `json:"spec" jsonschema:"testenv-vm spec to plan, as passed to create"`
+	// TestID locates an existing environment whose spec has no environment ID.
+	TestID string `json:"testID,omitempty" jsonschema:"forge testID of an existing environment to compare against"`
+}
+
+// makePlanHandler creates the handler for the testenv_plan tool.
+func makePlanHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, PlanInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input PlanInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_plan called")
+		if len(input.Spec) == 0 {
+			return errorResult("spec is required"), nil, nil
+		}
+		result, err := o.Plan(&v1.CreateInput{Spec: input.Spec, TestID: input.TestID})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal plan: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runPlan implements the plan subcommand. It fails when the spec does not fit.
+func runPlan(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	testID := fs.String("test-id", "", "forge testID of an existing environment to compare against")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("plan: expected exactly one spec file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	// Parse strictly first so typos are reported with their line number.
+	parsed, err := spec.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%w: failed to parse %s: %w", orchestrator.ErrInvalidSpec, fs.Arg(0), err)
+	}
+
+	result, err := o.Plan(&v1.CreateInput{Spec: parsed.ToMap(), TestID: *testID})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, formatPlan(result)); err != nil {
+		return err
+	}
+	if !result.Fits {
+		return orchestrator.ErrInsufficientCapacity
+	}
+	return nil
+}
+
+// formatPlan renders a plan as text: phases, requested resources and
+// capacity findings per provider, then changes to an existing environment.
+func formatPlan(result *orchestrator.PlanResult) string {
+	var sb strings.Builder
+	if result.Plan != nil {
+		for i, phase := range result.Plan.Phases {
+			refs := make([]string, 0, len(phase.Resources))
+			for _, r := range phase.Resources {
+				refs = append(refs, r.Kind+"/"+r.Name)
+			}
+			fmt.Fprintf(&sb, "Phase %d: %s\n", i+1, strings.Join(refs, ", "))
+		}
+	}
+	for _, c := range result.Capacity {
+		r := c.Requested
+		fmt.Fprintf(&sb, "Provider %s: %d VMs, %d vCPUs, %d MB memory, %d MB disk",
+			c.Provider, r.VMs, r.VCPUs, r.MemoryMB, r.DiskMB)
+		if h := c.Host; h != nil {
+			fmt.Fprintf(&sb, " (host: %d CPUs, %d MB memory, %d MB free, %d MB disk free)",
+				h.CPUs, h.MemoryMB, h.FreeMemoryMB, h.FreeDiskMB)
+		}
+		sb.WriteString("\n")
+		for _, e := range c.Errors {
+			fmt.Fprintf(&sb, "  error: %s\n", e)
+		}
+		for _, warn := range c.Warnings {
+			fmt.Fprintf(&sb, "  warning: %s\n", warn)
+		}
+	}
+	sb.WriteString(formatChanges(result.Changes))
+	return sb.String()
+}
+
+// formatChanges renders the changes to an existing environment as text, one
+// resource per line.
+func formatChanges(changes []orchestrator.ResourceChange) string {
+	var sb strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "%s/%s: %s", c.Resource.Kind, c.Resource.Name, c.Action)
+		if len(c.Reasons) > 0 {
+			fmt.Fprintf(&sb, " (%s)", strings.Join(c.Reasons, "; "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
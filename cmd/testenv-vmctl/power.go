@@ -0,0 +1,94 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// VMPowerInput is the input of the vm_start, vm_stop, vm_reboot, vm_pause
+// and vm_save tools.
+type VMPowerInput struct {
+	// EnvironmentID identifies the environment owning the VM.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the VM"`
+	// VM is the VM name as declared in the spec.
+	VM string `json:"vm" jsonschema:"Name of the VM as declared in the spec"`
+	// Force kills or resets the VM instead of asking the guest.
+	Force bool `json:"force,omitempty" jsonschema:"Kill the VM on stop or reset it on reboot, as a crash or power loss would"`
+	// Timeout is a Go duration bounding a graceful stop.
+	Timeout string `json:"timeout,omitempty" jsonschema:"Maximum time a graceful stop waits for the guest to power off, as a Go duration (default 60s)"`
+}
+
+// makeVMPowerHandler creates the handler for the power tool of action.
+func makeVMPowerHandler(o *orchestrator.Orchestrator, action string) func(context.Context, *mcp.CallToolRequest, VMPowerInput) (*mcp.CallToolResult, any, error) {
+	tool := providerv1.PowerTools[action]
+	return func(ctx context.Context, req *mcp.CallToolRequest, input VMPowerInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("%s called: environmentID=%s vm=%s force=%t timeout=%s",
+			tool, input.EnvironmentID, input.VM, input.Force, input.Timeout)
+		if input.EnvironmentID == "" || input.VM == "" {
+			return errorResult("environmentID and vm are required"), nil, nil
+		}
+		opts := orchestrator.PowerOptions{Force: input.Force}
+		if input.Timeout != "" {
+			d, err := time.ParseDuration(input.Timeout)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid timeout %q: %v", input.Timeout, err)), nil, nil
+			}
+			opts.Timeout = d
+		}
+		vmState, err := o.PowerVM(ctx, input.EnvironmentID, input.VM, action, opts)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(poweredMessage(input.VM, vmState)), nil, nil
+	}
+}
+
+// runPower implements the power subcommand.
+func runPower(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("power", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Kill the VM on stop or reset it on reboot")
+	timeout := fs.Duration("timeout", 0, "Maximum time a graceful stop waits for the guest (default 60s)")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 3 {
+		return usageErrorf("power: expected an action (start, stop, reboot, pause or save), an environment ID and a VM name")
+	}
+
+	vmState, err := o.PowerVM(context.Background(), fs.Arg(1), fs.Arg(2), fs.Arg(0), orchestrator.PowerOptions{Force: *force, Timeout: *timeout})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, poweredMessage(fs.Arg(2), vmState))
+	return err
+}
+
+// poweredMessage describes the power status of a VM.
+func poweredMessage(vmName string, vmState *v1.ResourceState) string {
+	status, _ := vmState.State["status"].(string)
+	return fmt.Sprintf("vm %s: %s", vmName, status)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// ProvidersUpdateInput is the input of the testenv_providers_update tool.
+type ProvidersUpdateInput struct {
+	// Specs are the specs whose provider engines are locked.
+	Specs []map[string]any `json:"specs,omitempty" jsonschema:"testenv-vm specs whose provider engines are locked, besides the configured default providers"`
+}
+
+// makeProvidersUpdateHandler creates the handler for the
+// testenv_providers_update tool.
+func makeProvidersUpdateHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ProvidersUpdateInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ProvidersUpdateInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_providers_update called: specs=%d", len(input.Specs))
+		specs := make([]*v1.Spec, 0, len(input.Specs))
+		for i, m := range input.Specs {
+			s, err := v1.SpecFromMap(m)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid spec %d: %v", i, err)), nil, nil
+			}
+			specs = append(specs, s)
+		}
+		lock, err := o.UpdateLock(specs)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(lock, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal lockfile: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runProviders implements the providers subcommand.
+func runProviders(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	if len(args) == 0 || args[0] != "update" {
+		return usageErrorf("providers: expected update")
+	}
+	fs := flag.NewFlagSet("providers update", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Print the lockfile as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return &usageError{err}
+	}
+
+	specs := make([]*v1.Spec, 0, fs.NArg())
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read spec: %w", err)
+		}
+		parsed, err := spec.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse %s: %w", orchestrator.ErrInvalidSpec, path, err)
+		}
+		specs = append(specs, parsed)
+	}
+
+	lock, err := o.UpdateLock(specs)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(lock, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	return formatLock(w, lock)
+}
+
+// formatLock prints the locked providers as a table.
+func formatLock(w io.Writer, lock *provider.Lock) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENGINE\tVERSION\tDIGEST")
+	for _, p := range lock.Providers {
+		version := p.Version
+		if version == "" {
+			version = "-"
+		}
+		digest := p.Digest
+		if digest == "" {
+			digest = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Engine, version, digest)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ReconcileInput is the input of the testenv_reconcile tool.
+type ReconcileInput struct {
+	// EnvironmentID identifies the environment to reconcile.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the ready or failed environment to reconcile"`
+	// Recreate recreates the drifted and missing resources.
+	Recreate bool `json:"recreate,omitempty" jsonschema:"Delete and create again the drifted and missing resources and those depending on them (rejected in read-only mode)"`
+	// Env is used to render the templates of the recreated resources.
+	Env map[string]string `json:"env,omitempty" jsonschema:"Environment variables used to render the templates of the recreated resources"`
+}
+
+// makeReconcileHandler creates the handler for the testenv_reconcile tool.
+func makeReconcileHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, ReconcileInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ReconcileInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_reconcile called: environmentID=%s recreate=%t", input.EnvironmentID, input.Recreate)
+		if input.EnvironmentID == "" {
+			return errorResult("environmentID is required"), nil, nil
+		}
+		result, err := o.Reconcile(ctx, input.EnvironmentID,
+			orchestrator.ReconcileOptions{Recreate: input.Recreate, Env: input.Env})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal reconciliation: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runReconcile implements the reconcile subcommand. It fails with a partial
+// failure when drift remains, so that scripts can detect it.
+func runReconcile(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	recreate := fs.Bool("recreate", false, "Recreate the drifted and missing resources and those depending on them")
+	jsonOutput := fs.Bool("json", false, "Print the reconciliation as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("reconcile: expected an environment ID")
+	}
+
+	result, err := o.Reconcile(context.Background(), fs.Arg(0), orchestrator.ReconcileOptions{Recreate: *recreate})
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "RESOURCE\tPROVIDER\tDRIFT\tDETAIL")
+		for _, d := range result.Resources {
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", d.Resource.Kind, d.Resource.Name, d.Resource.Provider, d.Drift, d.Detail)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, ref := range result.Recreated {
+			if _, err := fmt.Fprintf(w, "Recreated %s/%s\n", ref.Kind, ref.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if result.Drifted > 0 && len(result.Recreated) == 0 {
+		return fmt.Errorf("reconcile: %d resource(s) drifted: %w", result.Drifted, errPartialFailure)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// RotateKeyInput is the input of the testenv_rotate_key tool.
+type RotateKeyInput struct {
+	// EnvironmentID identifies the environment owning the key.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the key"`
+	// Key is the key name as declared in the spec.
+	Key string `json:"key" jsonschema:"Name of the key as declared in the spec"`
+}
+
+// makeRotateKeyHandler creates the handler for the testenv_rotate_key tool.
+func makeRotateKeyHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, RotateKeyInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input RotateKeyInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_rotate_key called: environmentID=%s key=%s", input.EnvironmentID, input.Key)
+		if input.EnvironmentID == "" || input.Key == "" {
+			return errorResult("environmentID and key are required"), nil, nil
+		}
+		result, err := o.RotateKey(ctx, input.EnvironmentID, input.Key)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(rotatedMessage(input.Key, result)), nil, nil
+	}
+}
+
+// runRotateKey implements the rotate-key subcommand.
+func runRotateKey(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("rotate-key", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf("rotate-key: expected an environment ID and a key name")
+	}
+
+	result, err := o.RotateKey(context.Background(), fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, rotatedMessage(fs.Arg(1), result)); err != nil {
+		return err
+	}
+	if len(result.Warnings) > 0 {
+		return fmt.Errorf("rotate-key: old key not removed from %d vm(s): %w", len(result.Warnings), errPartialFailure)
+	}
+	return nil
+}
+
+// rotatedMessage describes a key rotation.
+func rotatedMessage(keyName string, result *orchestrator.RotateKeyResult) string {
+	fingerprint, _ := result.Key.State["fingerprint"].(string)
+	msg := fmt.Sprintf("key %s: rotated from %s to %s", keyName, result.OldFingerprint, fingerprint)
+	if len(result.VMs) > 0 {
+		msg += fmt.Sprintf(" on vms %s", strings.Join(result.VMs, ", "))
+	}
+	for _, warning := range result.Warnings {
+		msg += fmt.Sprintf("\nwarning: old key not removed from %s", warning)
+	}
+	return msg
+}
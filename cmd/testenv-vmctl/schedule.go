@@ -0,0 +1,151 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/schedule"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// runSchedule implements the schedule subcommand and its add, list, remove,
+// trigger and run actions.
+func runSchedule(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return usageErrorf("schedule: expected add, list, remove, trigger or run")
+	}
+	store := o.Schedules()
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "add":
+		return runScheduleAdd(store, args, w)
+	case "list":
+		if len(args) != 0 {
+			return fmt.Errorf("schedule list: unexpected arguments")
+		}
+		return writeSchedules(store, w)
+	case "remove":
+		if len(args) != 1 {
+			return usageErrorf("schedule remove: expected exactly one schedule name")
+		}
+		return store.Delete(args[0])
+	case "trigger":
+		if len(args) != 1 {
+			return usageErrorf("schedule trigger: expected exactly one schedule name")
+		}
+		s, err := store.Load(args[0])
+		if err != nil {
+			return err
+		}
+		envID, runErr := o.RunSchedule(context.Background(), s, time.Now())
+		if err := store.Save(s); err != nil {
+			return err
+		}
+		if runErr != nil {
+			return runErr
+		}
+		_, err = fmt.Fprintf(w, "schedule %s: environment %s is ready\n", s.Name, envID)
+		return err
+	case "run":
+		fs := flag.NewFlagSet("schedule run", flag.ContinueOnError)
+		interval := fs.Duration("interval", orchestrator.DefaultScheduleInterval, "How often to look for due schedules")
+		if err := fs.Parse(args); err != nil {
+			return &usageError{err}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return o.RunSchedules(ctx, *interval)
+	default:
+		return fmt.Errorf("schedule: unknown action %q (expected add, list, remove, trigger or run)", action)
+	}
+}
+
+// runScheduleAdd validates a spec file and saves a schedule for it.
+func runScheduleAdd(store *schedule.Store, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("schedule add", flag.ContinueOnError)
+	stage := fs.String("stage", "", "Test stage passed to create")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 3 {
+		return usageErrorf("schedule add: expected a name, a cron expression and a spec file")
+	}
+
+	specFile, err := filepath.Abs(fs.Arg(2))
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	if _, err := spec.Parse(data); err != nil {
+		return fmt.Errorf("failed to parse spec %q: %w", specFile, err)
+	}
+
+	s := &schedule.Schedule{
+		Name:      fs.Arg(0),
+		Cron:      fs.Arg(1),
+		SpecFile:  specFile,
+		Stage:     *stage,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	// Keep the environment of an existing schedule so that it is replaced
+	// by the next run.
+	if existing, err := store.Load(s.Name); err == nil {
+		s.CreatedAt = existing.CreatedAt
+		s.EnvironmentID = existing.EnvironmentID
+	}
+	if err := store.Save(s); err != nil {
+		return err
+	}
+	next, _ := s.Next(time.Now())
+	_, err = fmt.Fprintf(w, "schedule %s: next run at %s\n", s.Name, next.Format(time.RFC3339))
+	return err
+}
+
+// writeSchedules prints the stored schedules as a table.
+func writeSchedules(store *schedule.Store, w io.Writer) error {
+	schedules, err := store.List()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tCRON\tNEXT RUN\tENVIRONMENT\tLAST ERROR")
+	for _, s := range schedules {
+		next := "-"
+		if t, err := s.Next(time.Now()); err == nil && !t.IsZero() {
+			next = t.Format(time.RFC3339)
+		}
+		envID := s.EnvironmentID
+		if envID == "" {
+			envID = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Cron, next, envID, s.LastError)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// StatsInput is the input of the testenv_stats tool.
+type StatsInput struct {
+	// EnvironmentID identifies the environment.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment"`
+	// VMs restricts the snapshot to some VMs; empty means all.
+	VMs []string `json:"vms,omitempty" jsonschema:"Names of the VMs as declared in the spec (default: all)"`
+	// Interval is a Go duration; empty means the provider default.
+	Interval string `json:"interval,omitempty" jsonschema:"Interval the CPU usage is measured over as a Go duration (default 1s, at most 10s)"`
+}
+
+// makeStatsHandler creates the handler for the testenv_stats tool.
+func makeStatsHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, StatsInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input StatsInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_stats called: environmentID=%s vms=%v interval=%s", input.EnvironmentID, input.VMs, input.Interval)
+		if input.EnvironmentID == "" {
+			return errorResult("environmentID is required"), nil, nil
+		}
+		var interval time.Duration
+		if input.Interval != "" {
+			d, err := time.ParseDuration(input.Interval)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid interval %q: %v", input.Interval, err)), nil, nil
+			}
+			interval = d
+		}
+		stats, err := o.Stats(input.EnvironmentID, input.VMs, interval)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal stats: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runStats implements the stats subcommand.
+func runStats(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	interval := fs.Duration("interval", 0, "Interval the CPU usage is measured over (default: provider default)")
+	jsonOutput := fs.Bool("json", false, "Print the snapshot as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() < 1 {
+		return usageErrorf("stats: expected an environment ID and optional VM names")
+	}
+
+	stats, err := o.Stats(fs.Arg(0), fs.Args()[1:], *interval)
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(data)); err != nil {
+			return err
+		}
+	} else if err := writeStats(stats, w); err != nil {
+		return err
+	}
+	return statsError(stats)
+}
+
+// statsError returns a partial failure when some VMs could not be sampled.
+func statsError(stats *orchestrator.EnvironmentStats) error {
+	var failed []string
+	for _, r := range stats.VMs {
+		if r.Error != "" {
+			failed = append(failed, r.VM)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("stats: no stats for vms %s: %w", strings.Join(failed, ", "), errPartialFailure)
+}
+
+// writeStats prints a snapshot as a table, one row per VM and a total row.
+func writeStats(stats *orchestrator.EnvironmentStats, w io.Writer) error {
+	const mib = 1 << 20
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VM\tVCPUS\tCPU%\tMEMORY MB\tRSS MB\tDISK READ MB\tDISK WRITE MB\tDISK USED MB\tDISK SIZE MB\tRX MB\tTX MB\tERROR")
+	for _, r := range stats.VMs {
+		s := r.Stats
+		if s == nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\t-\t-\t-\t-\t-\t-\t%s\n", r.VM, r.Error)
+			continue
+		}
+		var read, write, rx, tx int64
+		var used, size uint64
+		for _, d := range s.Disks {
+			read, write = read+d.ReadBytes, write+d.WriteBytes
+			used, size = used+d.AllocationBytes, size+d.CapacityBytes
+		}
+		for _, i := range s.Interfaces {
+			rx, tx = rx+i.RxBytes, tx+i.TxBytes
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", r.VM, s.VCPUs, s.CPUPercent, s.MemoryMB,
+			s.MemoryRSSMB, read/mib, write/mib, used/mib, size/mib, rx/mib, tx/mib)
+	}
+	t := stats.Totals
+	fmt.Fprintf(tw, "TOTAL\t%d\t%.1f\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", t.VCPUs, t.CPUPercent, t.MemoryMB,
+		t.MemoryRSSMB, t.DiskReadBytes/mib, t.DiskWriteBytes/mib, t.DiskAllocationBytes/mib, t.DiskCapacityBytes/mib,
+		t.RxBytes/mib, t.TxBytes/mib)
+	return tw.Flush()
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// StatusInput is the input of the testenv_status tool.
+type StatusInput struct {
+	// EnvironmentID selects the providers of an environment; empty selects
+	// the default providers of the configuration.
+	EnvironmentID string `json:"environmentID,omitempty" jsonschema:"Environment to describe with its state, VM health and providers (default: the configured default providers only)"`
+}
+
+// statusOutput is the output of the testenv_status tool.
+type statusOutput struct {
+	EnvironmentID string                        `json:"environmentID,omitempty"`
+	Providers     []orchestrator.ProviderStatus `json:"providers"`
+	// Environment is the state and VM health of the environment, when one
+	// is given.
+	Environment *orchestrator.EnvironmentHealth `json:"environment,omitempty"`
+}
+
+// RefreshCapabilitiesInput is the input of the provider_refresh_capabilities
+// tool.
+type RefreshCapabilitiesInput struct {
+	// EnvironmentID selects the environment declaring the provider.
+	EnvironmentID string `json:"environmentID,omitempty" jsonschema:"Environment declaring the provider (default: the configured default providers)"`
+	// Provider is the provider name.
+	Provider string `json:"provider" jsonschema:"Name of the provider whose capabilities to fetch again"`
+}
+
+// makeStatusHandler creates the handler for the testenv_status tool.
+func makeStatusHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, StatusInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input StatusInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_status called: environmentID=%s", input.EnvironmentID)
+		providers, err := o.ProviderStatuses(input.EnvironmentID)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		output := statusOutput{EnvironmentID: input.EnvironmentID, Providers: providers}
+		if input.EnvironmentID != "" {
+			if output.Environment, err = o.EnvironmentHealth(ctx, input.EnvironmentID); err != nil {
+				return errorResult(err.Error()), nil, nil
+			}
+		}
+		data, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal status: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// makeRefreshCapabilitiesHandler creates the handler for the
+// provider_refresh_capabilities tool.
+func makeRefreshCapabilitiesHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, RefreshCapabilitiesInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input RefreshCapabilitiesInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("provider_refresh_capabilities called: environmentID=%s provider=%s", input.EnvironmentID, input.Provider)
+		if input.Provider == "" {
+			return errorResult("provider is required"), nil, nil
+		}
+		caps, err := o.RefreshCapabilities(input.EnvironmentID, input.Provider)
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(caps, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal capabilities: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runStatus implements the status subcommand. With an environment ID, the
+// health of its VMs follows the providers.
+func runStatus(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	refresh := fs.Bool("refresh", false, "Fetch the capabilities of every provider again")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() > 1 {
+		return usageErrorf("status: expected at most one environment ID")
+	}
+	envID := fs.Arg(0)
+
+	providers, err := o.ProviderStatuses(envID)
+	if err != nil {
+		return err
+	}
+	if *refresh {
+		for i, p := range providers {
+			if p.Error != "" {
+				continue
+			}
+			if caps, err := o.RefreshCapabilities(envID, p.Name); err != nil {
+				providers[i].Error = err.Error()
+			} else {
+				providers[i].Capabilities = caps
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tENGINE\tSTATUS\tVERSION\tRESOURCES\tERROR")
+	for _, p := range providers {
+		version, resources := "-", "-"
+		if p.Capabilities != nil {
+			version = p.Capabilities.Version
+			var kinds []string
+			for _, r := range p.Capabilities.Resources {
+				kinds = append(kinds, r.Kind+"("+strings.Join(r.Operations, ",")+")")
+			}
+			if len(kinds) > 0 {
+				resources = strings.Join(kinds, " ")
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Name, p.Engine, p.Status, version, resources, p.Error)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if envID == "" {
+		return nil
+	}
+
+	health, err := o.EnvironmentHealth(context.Background(), envID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nEnvironment %s: %s (healthy: %t)\n", envID, health.State.Status, health.Healthy)
+	if health.State.Seed != "" {
+		fmt.Fprintf(w, "Seed: %s\n", health.State.Seed)
+	}
+	fmt.Fprintln(tw, "VM\tPROVIDER\tRECORDED\tPROVIDER STATUS\tSSH\tERROR")
+	for _, h := range health.VMs {
+		providerStatus, ssh := h.ProviderStatus, "unreachable"
+		if providerStatus == "" {
+			providerStatus = "-"
+		}
+		if h.SSHReachable {
+			ssh = "reachable"
+		}
+		errMsg := h.ProviderError
+		if h.SSHError != "" {
+			errMsg = strings.TrimPrefix(errMsg+"; ", "; ") + "ssh: " + h.SSHError
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", h.Name, h.Provider, h.RecordedStatus, providerStatus, ssh, errMsg)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// UpdateInput is the input of the testenv_update tool.
+type UpdateInput struct {
+	// EnvironmentID identifies the environment to update.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the ready or failed environment to update"`
+	// Spec is the new testenv-vm spec.
+	Spec map[string]any `json:"spec" jsonschema:"New testenv-vm spec of the environment, as passed to create"`
+	// Env is used to render the templates of the spec.
+	Env map[string]string `json:"env,omitempty" jsonschema:"Environment variables used to render the templates of the spec"`
+	// DryRun only reports the changes.
+	DryRun bool `json:"dryRun,omitempty" jsonschema:"Only report the changes, without applying them (allowed in read-only mode)"`
+}
+
+// makeUpdateHandler creates the handler for the testenv_update tool.
+func makeUpdateHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, UpdateInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input UpdateInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_update called: environmentID=%s dryRun=%t", input.EnvironmentID, input.DryRun)
+		if input.EnvironmentID == "" || len(input.Spec) == 0 {
+			return errorResult("environmentID and spec are required"), nil, nil
+		}
+		result, err := o.Update(ctx, input.EnvironmentID, &v1.CreateInput{Spec: input.Spec, Env: input.Env},
+			orchestrator.UpdateOptions{DryRun: input.DryRun})
+		if err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal update: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runUpdate implements the update subcommand.
+func runUpdate(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only print the changes, without applying them")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 2 {
+		return usageErrorf("update: expected an environment ID and a spec file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	// Parse strictly first so typos are reported with their line number.
+	parsed, err := spec.Parse(data)
+	if err != nil {
+		return fmt.Errorf("%w: failed to parse %s: %w", orchestrator.ErrInvalidSpec, fs.Arg(1), err)
+	}
+
+	result, err := o.Update(context.Background(), fs.Arg(0), &v1.CreateInput{Spec: parsed.ToMap()},
+		orchestrator.UpdateOptions{DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, formatChanges(result.Changes))
+	return err
+}
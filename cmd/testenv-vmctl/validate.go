@@ -0,0 +1,108 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// ValidateInput is the input of the testenv_validate tool.
+type ValidateInput struct {
+	// Spec is the testenv-vm spec to validate, as passed to create.
+	Spec map[string]any `json:"spec,omitempty" jsonschema:"testenv-vm spec to validate, as passed to create"`
+	// Content is a YAML spec; unknown fields are then reported with their line.
+	Content string `json:"content,omitempty" jsonschema:"YAML spec to validate instead of spec; issues then include line numbers"`
+}
+
+// makeValidateHandler creates the handler for the testenv_validate tool.
+func makeValidateHandler() func(context.Context, *mcp.CallToolRequest, ValidateInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input ValidateInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("testenv_validate called")
+		var report *spec.Report
+		switch {
+		case input.Content != "" && len(input.Spec) > 0:
+			return errorResult("spec and content are mutually exclusive"), nil, nil
+		case input.Content != "":
+			report = spec.ParseReport([]byte(input.Content))
+		case len(input.Spec) > 0:
+			report = spec.ReportFromMap(input.Spec)
+		default:
+			return errorResult("spec or content is required"), nil, nil
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return errorResult(fmt.Sprintf("failed to marshal report: %v", err)), nil, nil
+		}
+		return textResult(string(data)), nil, nil
+	}
+}
+
+// runValidate implements the validate subcommand. It prints every issue of
+// the spec and fails when one of them is an error. It does not need an
+// orchestrator.
+func runValidate(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("validate: expected exactly one spec file")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read spec: %w", err)
+	}
+	report := spec.ParseReport(data)
+
+	if *jsonOutput {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w, string(out)); err != nil {
+			return err
+		}
+	} else {
+		for _, i := range report.Issues {
+			location := fs.Arg(0)
+			if i.Line > 0 {
+				location = fmt.Sprintf("%s:%d:%d", location, i.Line, i.Column)
+			}
+			if i.Path != "" {
+				location += ": " + i.Path
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s [%s] %s\n", i.Severity, location, i.Code, i.Message); err != nil {
+				return err
+			}
+		}
+	}
+	if !report.Valid {
+		return fmt.Errorf("%w: %s has %d error(s)", orchestrator.ErrInvalidSpec, fs.Arg(0), len(report.Errors()))
+	}
+	return nil
+}
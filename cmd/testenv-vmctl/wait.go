@@ -0,0 +1,81 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// VMWaitInput is the input of the vm_wait tool.
+type VMWaitInput struct {
+	// EnvironmentID identifies the environment owning the VM.
+	EnvironmentID string `json:"environmentID" jsonschema:"ID of the environment owning the VM"`
+	// VM is the VM name as declared in the spec.
+	VM string `json:"vm" jsonschema:"Name of the VM as declared in the spec"`
+	// Condition is the condition to wait for.
+	Condition string `json:"condition" jsonschema:"running, ssh, cloud-init-done, port:<n> or file:<absolute path>"`
+	// Timeout is a Go duration; empty means 5m.
+	Timeout string `json:"timeout,omitempty" jsonschema:"Maximum time to wait as a Go duration (default 5m)"`
+}
+
+// makeVMWaitHandler creates the handler for the vm_wait tool.
+func makeVMWaitHandler(o *orchestrator.Orchestrator) func(context.Context, *mcp.CallToolRequest, VMWaitInput) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, input VMWaitInput) (*mcp.CallToolResult, any, error) {
+		log.Printf("vm_wait called: environmentID=%s vm=%s condition=%s timeout=%s",
+			input.EnvironmentID, input.VM, input.Condition, input.Timeout)
+		if input.EnvironmentID == "" || input.VM == "" || input.Condition == "" {
+			return errorResult("environmentID, vm and condition are required"), nil, nil
+		}
+		var timeout time.Duration
+		if input.Timeout != "" {
+			d, err := time.ParseDuration(input.Timeout)
+			if err != nil {
+				return errorResult(fmt.Sprintf("invalid timeout %q: %v", input.Timeout, err)), nil, nil
+			}
+			timeout = d
+		}
+		if err := o.WaitVM(ctx, input.EnvironmentID, input.VM, input.Condition, timeout); err != nil {
+			return errorResult(err.Error()), nil, nil
+		}
+		return textResult(fmt.Sprintf("vm %s: %s", input.VM, input.Condition)), nil, nil
+	}
+}
+
+// runWait implements the wait subcommand.
+func runWait(o *orchestrator.Orchestrator, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", orchestrator.DefaultWaitTimeout, "Maximum time to wait")
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
+	}
+	if fs.NArg() != 3 {
+		return usageErrorf("wait: expected an environment ID, a VM name and a condition")
+	}
+
+	if err := o.WaitVM(context.Background(), fs.Arg(0), fs.Arg(1), fs.Arg(2), *timeout); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "vm %s: %s\n", fs.Arg(1), fs.Arg(2))
+	return err
+}
@@ -0,0 +1,610 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// CloudInitConfig holds configuration for cloud-init.
+type CloudInitConfig struct {
+	VMName          string
+	Hostname        string
+	Users           []providerv1.UserSpec
+	Packages        []string
+	WriteFiles      []providerv1.WriteFileSpec
+	Runcmd          []string
+	NetworkConfig   *providerv1.CloudInitNetworkConfig
+	NTPServers      []string
+	SyslogServers   []string
+	CACerts         []string
+	RawUserData     string   // Replaces the generated user-data when set
+	MatchedKeyNames []string // Names of provider keys that match SSH authorized keys
+	// NICs are the NICs configured in the guest when no custom network
+	// config is given.
+	NICs []GuestNIC
+	// MACAddresses are the MAC addresses of the NICs, matched by the
+	// ethernets of the custom network config naming a NIC.
+	MACAddresses []string
+}
+
+// GuestNIC is a NIC configured by its MAC address in the guest network
+// config.
+type GuestNIC struct {
+	MAC             string
+	MTU             int
+	DisableOffloads []string
+}
+
+// offloadKeys maps the offloads of NICSpec.DisableOffloads to their netplan
+// keys.
+var offloadKeys = map[string][]string{
+	"tso": {"tcp-segmentation-offload", "tcp6-segmentation-offload"},
+	"gso": {"generic-segmentation-offload"},
+	"gro": {"generic-receive-offload"},
+	"lro": {"large-receive-offload"},
+}
+
+// generateMetaData generates the cloud-init meta-data file content.
+func generateMetaData(config *CloudInitConfig) string {
+	hostname := config.Hostname
+	if hostname == "" {
+		hostname = config.VMName
+	}
+	return fmt.Sprintf(`instance-id: %s
+hostname: %s
+local-hostname: %s
+`, config.VMName, hostname, hostname)
+}
+
+// generateUserData generates the cloud-init user-data file content, or
+// returns the raw user-data of the config verbatim.
+func generateUserData(config *CloudInitConfig) string {
+	if config.RawUserData != "" {
+		return config.RawUserData
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("#cloud-config\n\n")
+
+	// User configuration
+	if len(config.Users) > 0 {
+		sb.WriteString("users:\n")
+		for _, user := range config.Users {
+			sb.WriteString(fmt.Sprintf("  - name: %s\n", user.Name))
+			// Sudo (default: ALL=(ALL) NOPASSWD:ALL)
+			sudo := user.Sudo
+			if sudo == "" {
+				sudo = "ALL=(ALL) NOPASSWD:ALL"
+			}
+			sb.WriteString(fmt.Sprintf("    sudo: ['%s']\n", sudo))
+			// Shell (default: /bin/bash)
+			shell := user.Shell
+			if shell == "" {
+				shell = "/bin/bash"
+			}
+			sb.WriteString(fmt.Sprintf("    shell: %s\n", shell))
+			// SSH authorized keys
+			if len(user.SSHAuthorizedKeys) > 0 {
+				sb.WriteString("    ssh-authorized-keys:\n")
+				for _, key := range user.SSHAuthorizedKeys {
+					sb.WriteString(fmt.Sprintf("      - %s\n", strings.TrimSpace(key)))
+				}
+			}
+		}
+	} else {
+		// Default user if none specified. Images without bash, such as
+		// testenv:minimal, need explicit users.
+		sb.WriteString("users:\n")
+		sb.WriteString("  - name: ubuntu\n")
+		sb.WriteString("    sudo: ['ALL=(ALL) NOPASSWD:ALL']\n")
+		sb.WriteString("    shell: /bin/bash\n")
+	}
+
+	// Packages
+	if len(config.Packages) > 0 {
+		sb.WriteString("\npackages:\n")
+		for _, pkg := range config.Packages {
+			sb.WriteString(fmt.Sprintf("  - %s\n", pkg))
+		}
+	}
+
+	// NTP servers
+	if len(config.NTPServers) > 0 {
+		sb.WriteString("\nntp:\n")
+		sb.WriteString("  enabled: true\n")
+		sb.WriteString("  servers:\n")
+		for _, server := range config.NTPServers {
+			sb.WriteString(fmt.Sprintf("    - %s\n", server))
+		}
+	}
+
+	// Remote syslog servers, over TCP
+	if len(config.SyslogServers) > 0 {
+		sb.WriteString("\nrsyslog:\n")
+		sb.WriteString("  remotes:\n")
+		for i, server := range config.SyslogServers {
+			sb.WriteString(fmt.Sprintf("    testenv-%d: \"@@%s\"\n", i, server))
+		}
+	}
+
+	// Trusted CA certificates
+	if len(config.CACerts) > 0 {
+		sb.WriteString("\nca_certs:\n")
+		sb.WriteString("  trusted:\n")
+		for _, cert := range config.CACerts {
+			sb.WriteString("    - |\n")
+			for _, line := range strings.Split(strings.TrimSpace(cert), "\n") {
+				sb.WriteString(fmt.Sprintf("      %s\n", line))
+			}
+		}
+	}
+
+	// Write files
+	if len(config.WriteFiles) > 0 {
+		sb.WriteString("\nwrite_files:\n")
+		for _, wf := range config.WriteFiles {
+			sb.WriteString(fmt.Sprintf("  - path: %s\n", wf.Path))
+			sb.WriteString("    content: |\n")
+			// Indent each line of content by 6 spaces
+			for _, line := range strings.Split(wf.Content, "\n") {
+				sb.WriteString(fmt.Sprintf("      %s\n", line))
+			}
+			if wf.Permissions != "" {
+				sb.WriteString(fmt.Sprintf("    permissions: '%s'\n", wf.Permissions))
+			}
+			if wf.Encoding != "" {
+				sb.WriteString(fmt.Sprintf("    encoding: %s\n", wf.Encoding))
+			}
+		}
+	}
+
+	// Run commands (always include boot-finished marker)
+	sb.WriteString("\nruncmd:\n")
+	for _, cmd := range config.Runcmd {
+		if strings.Contains(cmd, "\n") {
+			// Multiline commands need YAML literal block scalar syntax
+			sb.WriteString("  - |\n")
+			for _, line := range strings.Split(strings.TrimRight(cmd, "\n"), "\n") {
+				sb.WriteString(fmt.Sprintf("    %s\n", line))
+			}
+		} else {
+			sb.WriteString(fmt.Sprintf("  - %s\n", cmd))
+		}
+	}
+	sb.WriteString("  - touch /var/lib/cloud/instance/boot-finished\n")
+
+	return sb.String()
+}
+
+// generateNetworkConfig generates the cloud-init network-config file content.
+// Uses netplan version 2 format with broad interface matching for reliability.
+// If a custom network config is provided, it will be used instead of the default DHCP config.
+// Ethernets naming a NIC are matched by its MAC address in macs, and take the
+// MTU and disabled offloads of the NIC in nics unless they set their own MTU.
+func generateNetworkConfig(config *providerv1.CloudInitNetworkConfig, macs []string, nics []GuestNIC) string {
+	// If no custom config provided, use default DHCP on all ethernet interfaces
+	if !hasNetworkConfig(config) {
+		return `version: 2
+ethernets:
+  all-en:
+    match:
+      name: "en*"
+    dhcp4: true
+  all-eth:
+    match:
+      name: "eth*"
+    dhcp4: true
+`
+	}
+
+	// Bond members and VLAN links do not use DHCP by default
+	linked := make(map[string]bool)
+	for _, bond := range config.Bonds {
+		for _, member := range bond.Interfaces {
+			linked[member] = true
+		}
+	}
+	for _, vlan := range config.VLANs {
+		linked[vlan.Link] = true
+	}
+
+	// Generate custom network config
+	var sb strings.Builder
+	sb.WriteString("version: 2\n")
+	if len(config.Ethernets) > 0 {
+		sb.WriteString("ethernets:\n")
+	}
+
+	for i, eth := range config.Ethernets {
+		// Use interface name or generate a unique identifier
+		ifaceName := eth.Name
+		if ifaceName == "" {
+			ifaceName = fmt.Sprintf("eth%d", i)
+		}
+
+		iface := netplanInterface{
+			dhcp4:       eth.DHCP4,
+			addresses:   eth.Addresses,
+			gateway4:    eth.Gateway4,
+			routes:      eth.Routes,
+			nameservers: eth.Nameservers,
+			mtu:         eth.MTU,
+		}
+		switch {
+		case eth.NIC != nil && *eth.NIC < len(macs) && macs[*eth.NIC] != "":
+			// Match the NIC by MAC address and give it the name of the interface
+			sb.WriteString(fmt.Sprintf("  %s:\n", ifaceName))
+			sb.WriteString("    match:\n")
+			sb.WriteString(fmt.Sprintf("      macaddress: \"%s\"\n", macs[*eth.NIC]))
+			sb.WriteString(fmt.Sprintf("    set-name: %s\n", ifaceName))
+			if *eth.NIC < len(nics) {
+				if iface.mtu == 0 {
+					iface.mtu = nics[*eth.NIC].MTU
+				}
+				iface.disableOffloads = nics[*eth.NIC].DisableOffloads
+			}
+		case eth.Match != "":
+			sb.WriteString(fmt.Sprintf("  %s:\n", ifaceName))
+			sb.WriteString("    match:\n")
+			sb.WriteString(fmt.Sprintf("      name: \"%s\"\n", eth.Match))
+		case strings.Contains(ifaceName, "*"):
+			// Use match syntax for wildcard patterns
+			sb.WriteString(fmt.Sprintf("  %s:\n", sanitizeInterfaceName(ifaceName)))
+			sb.WriteString("    match:\n")
+			sb.WriteString(fmt.Sprintf("      name: \"%s\"\n", ifaceName))
+		default:
+			// Direct interface name
+			sb.WriteString(fmt.Sprintf("  %s:\n", ifaceName))
+		}
+
+		writeInterfaceConfig(&sb, iface, !linked[eth.Name])
+	}
+
+	if len(config.Bonds) > 0 {
+		sb.WriteString("bonds:\n")
+	}
+	for _, bond := range config.Bonds {
+		sb.WriteString(fmt.Sprintf("  %s:\n", bond.Name))
+		sb.WriteString("    interfaces:\n")
+		for _, member := range bond.Interfaces {
+			sb.WriteString(fmt.Sprintf("      - %s\n", member))
+		}
+		if bond.Mode != "" {
+			sb.WriteString("    parameters:\n")
+			sb.WriteString(fmt.Sprintf("      mode: %s\n", bond.Mode))
+		}
+		writeInterfaceConfig(&sb, netplanInterface{
+			dhcp4:       bond.DHCP4,
+			addresses:   bond.Addresses,
+			gateway4:    bond.Gateway4,
+			routes:      bond.Routes,
+			nameservers: bond.Nameservers,
+			mtu:         bond.MTU,
+		}, !linked[bond.Name])
+	}
+
+	if len(config.VLANs) > 0 {
+		sb.WriteString("vlans:\n")
+	}
+	for _, vlan := range config.VLANs {
+		sb.WriteString(fmt.Sprintf("  %s:\n", vlan.Name))
+		sb.WriteString(fmt.Sprintf("    id: %d\n", vlan.ID))
+		sb.WriteString(fmt.Sprintf("    link: %s\n", vlan.Link))
+		writeInterfaceConfig(&sb, netplanInterface{
+			dhcp4:       vlan.DHCP4,
+			addresses:   vlan.Addresses,
+			gateway4:    vlan.Gateway4,
+			routes:      vlan.Routes,
+			nameservers: vlan.Nameservers,
+			mtu:         vlan.MTU,
+		}, true)
+	}
+
+	return sb.String()
+}
+
+// netplanInterface is the addressing of an ethernet, bond or VLAN interface.
+type netplanInterface struct {
+	dhcp4       *bool
+	addresses   []string
+	gateway4    string
+	routes      []providerv1.CloudInitRoute
+	nameservers *providerv1.CloudInitNameservers
+	mtu         int
+	// disableOffloads are the offloads of NICSpec.DisableOffloads turned
+	// off on the interface.
+	disableOffloads []string
+}
+
+// writeInterfaceConfig writes the addressing of an interface. Interfaces
+// without addresses use DHCP when dhcpByDefault is set.
+func writeInterfaceConfig(sb *strings.Builder, iface netplanInterface, dhcpByDefault bool) {
+	// DHCP or static
+	dhcp := (iface.dhcp4 != nil && *iface.dhcp4) || (len(iface.addresses) == 0 && dhcpByDefault)
+	sb.WriteString(fmt.Sprintf("    dhcp4: %t\n", dhcp))
+	if len(iface.addresses) > 0 {
+		sb.WriteString("    addresses:\n")
+		for _, addr := range iface.addresses {
+			sb.WriteString(fmt.Sprintf("      - %s\n", addr))
+		}
+	}
+
+	// Routes/gateway
+	if iface.gateway4 != "" || len(iface.routes) > 0 {
+		sb.WriteString("    routes:\n")
+	}
+	if iface.gateway4 != "" {
+		sb.WriteString("      - to: default\n")
+		sb.WriteString(fmt.Sprintf("        via: %s\n", iface.gateway4))
+	}
+	for _, route := range iface.routes {
+		sb.WriteString(fmt.Sprintf("      - to: %s\n", route.To))
+		sb.WriteString(fmt.Sprintf("        via: %s\n", route.Via))
+		if route.Metric > 0 {
+			sb.WriteString(fmt.Sprintf("        metric: %d\n", route.Metric))
+		}
+	}
+
+	// Nameservers
+	if ns := iface.nameservers; ns != nil && (len(ns.Addresses) > 0 || len(ns.Search) > 0) {
+		sb.WriteString("    nameservers:\n")
+		if len(ns.Addresses) > 0 {
+			sb.WriteString("      addresses:\n")
+			for _, addr := range ns.Addresses {
+				sb.WriteString(fmt.Sprintf("        - %s\n", addr))
+			}
+		}
+		if len(ns.Search) > 0 {
+			sb.WriteString("      search:\n")
+			for _, domain := range ns.Search {
+				sb.WriteString(fmt.Sprintf("        - %s\n", domain))
+			}
+		}
+	}
+
+	if iface.mtu > 0 {
+		sb.WriteString(fmt.Sprintf("    mtu: %d\n", iface.mtu))
+	}
+	writeOffloads(sb, iface.disableOffloads)
+}
+
+// writeOffloads turns off the offloads of NICSpec.DisableOffloads.
+func writeOffloads(sb *strings.Builder, offloads []string) {
+	for _, offload := range offloads {
+		for _, key := range offloadKeys[offload] {
+			sb.WriteString(fmt.Sprintf("    %s: false\n", key))
+		}
+	}
+}
+
+// hasNetworkConfig reports whether a custom network config configures some
+// interface.
+func hasNetworkConfig(config *providerv1.CloudInitNetworkConfig) bool {
+	return config != nil && (len(config.Ethernets) > 0 || len(config.Bonds) > 0 || len(config.VLANs) > 0)
+}
+
+// MatchesNICs reports whether the custom network config of a VM matches
+// some ethernet by the MAC address of a NIC.
+func MatchesNICs(ci *providerv1.CloudInitSpec) bool {
+	if ci == nil || ci.NetworkConfig == nil {
+		return false
+	}
+	for _, eth := range ci.NetworkConfig.Ethernets {
+		if eth.NIC != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateNICNetworkConfig generates a netplan config matching each NIC by
+// its MAC address, with its MTU and disabled offloads.
+func generateNICNetworkConfig(nics []GuestNIC) string {
+	var sb strings.Builder
+	sb.WriteString("version: 2\n")
+	sb.WriteString("ethernets:\n")
+	for i, nic := range nics {
+		sb.WriteString(fmt.Sprintf("  nic%d:\n", i))
+		sb.WriteString("    match:\n")
+		sb.WriteString(fmt.Sprintf("      macaddress: \"%s\"\n", nic.MAC))
+		sb.WriteString("    dhcp4: true\n")
+		if nic.MTU > 0 {
+			sb.WriteString(fmt.Sprintf("    mtu: %d\n", nic.MTU))
+		}
+		writeOffloads(&sb, nic.DisableOffloads)
+	}
+	return sb.String()
+}
+
+// networkConfigData returns the network-config of a VM: its custom network
+// config if any, with the settings of the NICs its ethernets match, else one
+// entry per NIC when NICs are set, else DHCP on all ethernet interfaces.
+func networkConfigData(config *CloudInitConfig) string {
+	if !hasNetworkConfig(config.NetworkConfig) && len(config.NICs) > 0 {
+		return generateNICNetworkConfig(config.NICs)
+	}
+	return generateNetworkConfig(config.NetworkConfig, config.MACAddresses, config.NICs)
+}
+
+// sanitizeInterfaceName creates a valid netplan key from an interface pattern
+func sanitizeInterfaceName(name string) string {
+	// Replace wildcards with descriptive text
+	result := strings.ReplaceAll(name, "*", "all")
+	// Replace other invalid chars
+	result = strings.ReplaceAll(result, "-", "_")
+	return result
+}
+
+// generateCloudInitISO generates a cloud-init ISO file. A non-empty
+// applicationID is recorded in the ISO header to identify its owner.
+func generateCloudInitISO(config *CloudInitConfig, outputPath, isoTool, applicationID string) error {
+	// Create temp directory
+	tmpDir, err := os.MkdirTemp("", "cidata-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	// Write meta-data
+	metaDataPath := filepath.Join(tmpDir, "meta-data")
+	if err := os.WriteFile(metaDataPath, []byte(generateMetaData(config)), 0644); err != nil {
+		return fmt.Errorf("failed to write meta-data: %w", err)
+	}
+
+	// Write user-data
+	userDataPath := filepath.Join(tmpDir, "user-data")
+	if err := os.WriteFile(userDataPath, []byte(generateUserData(config)), 0644); err != nil {
+		return fmt.Errorf("failed to write user-data: %w", err)
+	}
+
+	// Write network-config
+	networkConfigPath := filepath.Join(tmpDir, "network-config")
+	if err := os.WriteFile(networkConfigPath, []byte(networkConfigData(config)), 0644); err != nil {
+		return fmt.Errorf("failed to write network-config: %w", err)
+	}
+
+	// Generate ISO
+	// Different tools have slightly different invocations
+	args := []string{"-output", outputPath, "-volid", "cidata", "-joliet", "-rock"}
+	if applicationID != "" {
+		args = append(args, "-appid", applicationID)
+	}
+	args = append(args, metaDataPath, userDataPath, networkConfigPath)
+	if strings.Contains(isoTool, "xorriso") {
+		args = append([]string{"-as", "genisoimage"}, args...)
+	}
+	cmd := exec.Command(isoTool, args...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to generate ISO: %w, output: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// cloudInitConfigFromVMSpec extracts cloud-init configuration from a VM spec.
+func cloudInitConfigFromVMSpec(vmName string, spec *providerv1.VMSpec, keys map[string]*providerv1.KeyState) *CloudInitConfig {
+	config := &CloudInitConfig{
+		VMName:          vmName,
+		MatchedKeyNames: make([]string, 0),
+	}
+
+	if spec.CloudInit != nil {
+		config.Hostname = spec.CloudInit.Hostname
+		config.Users = spec.CloudInit.Users
+		config.Packages = spec.CloudInit.Packages
+		config.WriteFiles = spec.CloudInit.WriteFiles
+		config.Runcmd = spec.CloudInit.Runcmd
+		config.NetworkConfig = spec.CloudInit.NetworkConfig
+		config.NTPServers = spec.CloudInit.NTPServers
+		config.SyslogServers = spec.CloudInit.SyslogServers
+		config.CACerts = spec.CloudInit.CACerts
+		config.RawUserData = spec.CloudInit.RawUserData
+	}
+	config.MACAddresses = spec.MACAddresses
+
+	// Configure the NICs in the guest when some NIC has guest settings and
+	// all of them have a known MAC address
+	guest := false
+	for _, nic := range spec.NICs {
+		guest = guest || nic.MTU > 0 || len(nic.DisableOffloads) > 0
+	}
+	if guest && len(spec.MACAddresses) >= len(spec.NICs) {
+		for i, nic := range spec.NICs {
+			if spec.MACAddresses[i] == "" {
+				config.NICs = nil
+				break
+			}
+			config.NICs = append(config.NICs, GuestNIC{MAC: spec.MACAddresses[i], MTU: nic.MTU, DisableOffloads: nic.DisableOffloads})
+		}
+	}
+
+	// Match SSH authorized keys against provider keys
+	for _, user := range config.Users {
+		for _, sshKey := range user.SSHAuthorizedKeys {
+			sshKeyTrimmed := strings.TrimSpace(sshKey)
+			for keyName, keyState := range keys {
+				if strings.TrimSpace(keyState.PublicKey) == sshKeyTrimmed {
+					config.MatchedKeyNames = append(config.MatchedKeyNames, keyName)
+					break // Each SSH key matches at most one provider key
+				}
+			}
+		}
+	}
+
+	return config
+}
+
+// FindISOTool finds an available ISO generation tool.
+func FindISOTool() (string, error) {
+	tools := []string{"genisoimage", "mkisofs", "xorriso"}
+	for _, tool := range tools {
+		path, err := exec.LookPath(tool)
+		if err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("cloud-init ISO generation requires genisoimage, mkisofs, or xorriso")
+}
+
+// ExtractStaticIP extracts the first static IP address from CloudInit network
+// configuration. This is used as a last-resort fallback when neither DHCP leases
+// nor ARP resolution can provide the IP (e.g., the VM hasn't generated any
+// network traffic yet but has a known static IP).
+func ExtractStaticIP(ci *providerv1.CloudInitSpec) string {
+	if ci == nil || ci.NetworkConfig == nil {
+		return ""
+	}
+	var addresses []string
+	for _, eth := range ci.NetworkConfig.Ethernets {
+		addresses = append(addresses, eth.Addresses...)
+	}
+	for _, bond := range ci.NetworkConfig.Bonds {
+		addresses = append(addresses, bond.Addresses...)
+	}
+	for _, vlan := range ci.NetworkConfig.VLANs {
+		addresses = append(addresses, vlan.Addresses...)
+	}
+	for _, addr := range addresses {
+		// Addresses are in CIDR notation (e.g., "192.168.100.10/24")
+		ip, _, _ := strings.Cut(addr, "/")
+		if ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// GenerateCloudInitSeed writes the cloud-init ISO of a VM to outputPath and
+// returns the configuration it holds, whose MatchedKeyNames are the provider
+// keys authorized in the guest.
+func GenerateCloudInitSeed(vmName string, spec *providerv1.VMSpec, keys map[string]*providerv1.KeyState, outputPath, isoTool string, labels map[string]string) (*CloudInitConfig, error) {
+	config := cloudInitConfigFromVMSpec(vmName, spec, keys)
+	if err := generateCloudInitISO(config, outputPath, isoTool, isoApplicationID(labels)); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
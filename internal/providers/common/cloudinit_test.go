@@ -14,7 +14,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package libvirt
+package common
 
 import (
 	"strings"
@@ -156,6 +156,60 @@ func TestGenerateUserData_WithPackages(t *testing.T) {
 	}
 }
 
+func TestGenerateUserData_WithNTPServers(t *testing.T) {
+	config := &CloudInitConfig{
+		VMName:     "test-vm",
+		NTPServers: []string{"10.0.0.1"},
+	}
+
+	userData := generateUserData(config)
+
+	want := "\nntp:\n  enabled: true\n  servers:\n    - 10.0.0.1\n"
+	if !strings.Contains(userData, want) {
+		t.Errorf("user-data should contain ntp section %q, got:\n%s", want, userData)
+	}
+
+	if strings.Contains(generateUserData(&CloudInitConfig{VMName: "test-vm"}), "ntp:") {
+		t.Error("user-data should not contain ntp section without servers")
+	}
+}
+
+func TestGenerateUserData_WithSyslogServers(t *testing.T) {
+	config := &CloudInitConfig{
+		VMName:        "test-vm",
+		SyslogServers: []string{"10.0.0.1:5140", "10.0.1.1:5140"},
+	}
+
+	userData := generateUserData(config)
+
+	want := "\nrsyslog:\n  remotes:\n    testenv-0: \"@@10.0.0.1:5140\"\n    testenv-1: \"@@10.0.1.1:5140\"\n"
+	if !strings.Contains(userData, want) {
+		t.Errorf("user-data should contain rsyslog section %q, got:\n%s", want, userData)
+	}
+
+	if strings.Contains(generateUserData(&CloudInitConfig{VMName: "test-vm"}), "rsyslog:") {
+		t.Error("user-data should not contain rsyslog section without servers")
+	}
+}
+
+func TestGenerateUserData_WithCACerts(t *testing.T) {
+	config := &CloudInitConfig{
+		VMName:  "test-vm",
+		CACerts: []string{"-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"},
+	}
+
+	userData := generateUserData(config)
+
+	want := "\nca_certs:\n  trusted:\n    - |\n      -----BEGIN CERTIFICATE-----\n      MIIB\n      -----END CERTIFICATE-----\n"
+	if !strings.Contains(userData, want) {
+		t.Errorf("user-data should contain ca_certs section %q, got:\n%s", want, userData)
+	}
+
+	if strings.Contains(generateUserData(&CloudInitConfig{VMName: "test-vm"}), "ca_certs:") {
+		t.Error("user-data should not contain ca_certs section without certificates")
+	}
+}
+
 func TestGenerateUserData_WithRuncmd(t *testing.T) {
 	config := &CloudInitConfig{
 		VMName: "test-vm",
@@ -178,8 +232,22 @@ func TestGenerateUserData_WithRuncmd(t *testing.T) {
 	}
 }
 
+func TestGenerateUserData_Raw(t *testing.T) {
+	raw := "#cloud-config\ngrowpart:\n  mode: auto\n"
+	config := cloudInitConfigFromVMSpec("test-vm", &providerv1.VMSpec{
+		CloudInit: &providerv1.CloudInitSpec{Hostname: "web", RawUserData: raw},
+	}, nil)
+
+	if userData := generateUserData(config); userData != raw {
+		t.Errorf("user-data = %q, want the raw user-data verbatim", userData)
+	}
+	if metaData := generateMetaData(config); !strings.Contains(metaData, "hostname: web") {
+		t.Errorf("meta-data should keep the hostname, got %q", metaData)
+	}
+}
+
 func TestGenerateNetworkConfig(t *testing.T) {
-	networkConfig := generateNetworkConfig(nil)
+	networkConfig := generateNetworkConfig(nil, nil, nil)
 
 	// Should have version 2
 	if !strings.Contains(networkConfig, "version: 2") {
@@ -205,6 +273,93 @@ func TestGenerateNetworkConfig(t *testing.T) {
 	}
 }
 
+func TestNetworkConfigData_NICs(t *testing.T) {
+	spec := &providerv1.VMSpec{
+		MACAddresses: []string{"52:54:00:00:00:01", "52:54:00:00:00:02"},
+		NICs:         []providerv1.NICSpec{{MTU: 9000, DisableOffloads: []string{"tso", "gro"}}, {}},
+	}
+	networkConfig := networkConfigData(cloudInitConfigFromVMSpec("test-vm", spec, nil))
+
+	for _, want := range []string{
+		"  nic0:\n    match:\n      macaddress: \"52:54:00:00:00:01\"\n    dhcp4: true\n    mtu: 9000\n",
+		"    tcp-segmentation-offload: false\n    tcp6-segmentation-offload: false\n    generic-receive-offload: false\n",
+		"  nic1:\n    match:\n      macaddress: \"52:54:00:00:00:02\"\n    dhcp4: true\n",
+	} {
+		if !strings.Contains(networkConfig, want) {
+			t.Errorf("network-config = %s\nmissing %q", networkConfig, want)
+		}
+	}
+	if strings.Contains(networkConfig, "all-en") {
+		t.Errorf("network-config should not match all interfaces\n%s", networkConfig)
+	}
+
+	// NICs without a MAC address cannot be matched
+	spec.MACAddresses = []string{"52:54:00:00:00:01", ""}
+	if networkConfig := networkConfigData(cloudInitConfigFromVMSpec("test-vm", spec, nil)); !strings.Contains(networkConfig, "all-en") {
+		t.Errorf("network-config should default to DHCP on all interfaces\n%s", networkConfig)
+	}
+
+	// A custom network config wins
+	spec.MACAddresses = []string{"52:54:00:00:00:01", "52:54:00:00:00:02"}
+	spec.CloudInit = &providerv1.CloudInitSpec{NetworkConfig: &providerv1.CloudInitNetworkConfig{
+		Ethernets: []providerv1.CloudInitEthernetConfig{{Name: "eth0"}},
+	}}
+	if networkConfig := networkConfigData(cloudInitConfigFromVMSpec("test-vm", spec, nil)); strings.Contains(networkConfig, "macaddress") {
+		t.Errorf("network-config should be the custom one\n%s", networkConfig)
+	}
+
+	// Ethernets matching a NIC take its settings
+	nic0 := 0
+	spec.CloudInit.NetworkConfig.Ethernets[0].NIC = &nic0
+	want := "  eth0:\n    match:\n      macaddress: \"52:54:00:00:00:01\"\n    set-name: eth0\n    dhcp4: true\n    mtu: 9000\n" +
+		"    tcp-segmentation-offload: false\n    tcp6-segmentation-offload: false\n    generic-receive-offload: false\n"
+	if networkConfig := networkConfigData(cloudInitConfigFromVMSpec("test-vm", spec, nil)); !strings.Contains(networkConfig, want) {
+		t.Errorf("network-config = %s\nmissing %q", networkConfig, want)
+	}
+}
+
+func TestNetworkConfigData_BondsAndVLANs(t *testing.T) {
+	nic0, nic1 := 0, 1
+	spec := &providerv1.VMSpec{
+		MACAddresses: []string{"52:54:00:00:00:01", "52:54:00:00:00:02"},
+		CloudInit: &providerv1.CloudInitSpec{NetworkConfig: &providerv1.CloudInitNetworkConfig{
+			Ethernets: []providerv1.CloudInitEthernetConfig{
+				{Name: "lan0", NIC: &nic0},
+				{Name: "lan1", NIC: &nic1},
+				{Name: "mgmt", Match: "enp9s*", Addresses: []string{"10.0.0.2/24"}, Gateway4: "10.0.0.1",
+					Routes:      []providerv1.CloudInitRoute{{To: "172.16.0.0/12", Via: "10.0.0.254", Metric: 100}},
+					Nameservers: &providerv1.CloudInitNameservers{Addresses: []string{"10.0.0.1"}, Search: []string{"lab.test"}}},
+			},
+			Bonds: []providerv1.CloudInitBondConfig{{Name: "bond0", Interfaces: []string{"lan0", "lan1"}, Mode: "802.3ad", MTU: 9000}},
+			VLANs: []providerv1.CloudInitVLANConfig{{Name: "vlan10", ID: 10, Link: "bond0", Addresses: []string{"192.168.10.2/24"}}},
+		}},
+	}
+	if !MatchesNICs(spec.CloudInit) {
+		t.Error("MatchesNICs() = false, want true")
+	}
+	networkConfig := networkConfigData(cloudInitConfigFromVMSpec("test-vm", spec, nil))
+
+	for _, want := range []string{
+		"  lan0:\n    match:\n      macaddress: \"52:54:00:00:00:01\"\n    set-name: lan0\n    dhcp4: false\n",
+		"  lan1:\n    match:\n      macaddress: \"52:54:00:00:00:02\"\n    set-name: lan1\n    dhcp4: false\n",
+		"  mgmt:\n    match:\n      name: \"enp9s*\"\n    dhcp4: false\n    addresses:\n      - 10.0.0.2/24\n" +
+			"    routes:\n      - to: default\n        via: 10.0.0.1\n      - to: 172.16.0.0/12\n        via: 10.0.0.254\n        metric: 100\n" +
+			"    nameservers:\n      addresses:\n        - 10.0.0.1\n      search:\n        - lab.test\n",
+		"bonds:\n  bond0:\n    interfaces:\n      - lan0\n      - lan1\n    parameters:\n      mode: 802.3ad\n    dhcp4: false\n    mtu: 9000\n",
+		"vlans:\n  vlan10:\n    id: 10\n    link: bond0\n    dhcp4: false\n    addresses:\n      - 192.168.10.2/24\n",
+	} {
+		if !strings.Contains(networkConfig, want) {
+			t.Errorf("network-config = %s\nmissing %q", networkConfig, want)
+		}
+	}
+
+	if ip := ExtractStaticIP(&providerv1.CloudInitSpec{NetworkConfig: &providerv1.CloudInitNetworkConfig{
+		VLANs: spec.CloudInit.NetworkConfig.VLANs,
+	}}); ip != "192.168.10.2" {
+		t.Errorf("ExtractStaticIP() = %q, want the VLAN address", ip)
+	}
+}
+
 func TestCloudInitConfigFromVMSpec_Defaults(t *testing.T) {
 	vmName := "test-vm"
 	spec := &providerv1.VMSpec{}
@@ -479,6 +634,24 @@ func TestGenerateUserData_WithWriteFiles(t *testing.T) {
 	}
 }
 
+func TestGenerateUserData_WriteFilesEncoding(t *testing.T) {
+	config := &CloudInitConfig{
+		VMName: "test-vm",
+		WriteFiles: []providerv1.WriteFileSpec{
+			{Path: "/usr/local/bin/agent", Content: "H4sIAAAA\nAAAA", Permissions: "0755", Encoding: "gz+b64"},
+			{Path: "/etc/motd", Content: "plain"},
+		},
+	}
+	userData := generateUserData(config)
+
+	if !strings.Contains(userData, "      H4sIAAAA\n      AAAA\n    permissions: '0755'\n    encoding: gz+b64\n") {
+		t.Errorf("user-data should contain the encoded file, got:\n%s", userData)
+	}
+	if strings.Count(userData, "encoding:") != 1 {
+		t.Error("only encoded files should declare an encoding")
+	}
+}
+
 func TestGenerateUserData_WriteFilesMultiline(t *testing.T) {
 	config := &CloudInitConfig{
 		VMName: "test-vm",
@@ -576,3 +749,77 @@ func TestGenerateUserData_WriteFilesNoPermissions(t *testing.T) {
 		}
 	}
 }
+
+func TestExtractStaticIP_NilCloudInit(t *testing.T) {
+	ip := ExtractStaticIP(nil)
+	if ip != "" {
+		t.Errorf("expected empty string for nil CloudInit, got %q", ip)
+	}
+}
+
+func TestExtractStaticIP_NilNetworkConfig(t *testing.T) {
+	ci := &providerv1.CloudInitSpec{}
+	ip := ExtractStaticIP(ci)
+	if ip != "" {
+		t.Errorf("expected empty string for nil NetworkConfig, got %q", ip)
+	}
+}
+
+func TestExtractStaticIP_EmptyEthernets(t *testing.T) {
+	ci := &providerv1.CloudInitSpec{
+		NetworkConfig: &providerv1.CloudInitNetworkConfig{},
+	}
+	ip := ExtractStaticIP(ci)
+	if ip != "" {
+		t.Errorf("expected empty string for empty ethernets, got %q", ip)
+	}
+}
+
+func TestExtractStaticIP_CIDRNotation(t *testing.T) {
+	ci := &providerv1.CloudInitSpec{
+		NetworkConfig: &providerv1.CloudInitNetworkConfig{
+			Ethernets: []providerv1.CloudInitEthernetConfig{
+				{
+					Name:      "ens2",
+					Addresses: []string{"192.168.100.10/24"},
+				},
+			},
+		},
+	}
+	ip := ExtractStaticIP(ci)
+	if ip != "192.168.100.10" {
+		t.Errorf("expected 192.168.100.10, got %q", ip)
+	}
+}
+
+func TestExtractStaticIP_BareIP(t *testing.T) {
+	ci := &providerv1.CloudInitSpec{
+		NetworkConfig: &providerv1.CloudInitNetworkConfig{
+			Ethernets: []providerv1.CloudInitEthernetConfig{
+				{
+					Name:      "ens2",
+					Addresses: []string{"10.0.0.5"},
+				},
+			},
+		},
+	}
+	ip := ExtractStaticIP(ci)
+	if ip != "10.0.0.5" {
+		t.Errorf("expected 10.0.0.5, got %q", ip)
+	}
+}
+
+func TestExtractStaticIP_MultipleEthernets(t *testing.T) {
+	ci := &providerv1.CloudInitSpec{
+		NetworkConfig: &providerv1.CloudInitNetworkConfig{
+			Ethernets: []providerv1.CloudInitEthernetConfig{
+				{Name: "ens2", Addresses: []string{}},
+				{Name: "ens3", Addresses: []string{"172.16.0.1/16"}},
+			},
+		},
+	}
+	ip := ExtractStaticIP(ci)
+	if ip != "172.16.0.1" {
+		t.Errorf("expected 172.16.0.1, got %q", ip)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// diskSecretID is the qemu object ID used to pass the LUKS passphrase to qemu-img.
+const diskSecretID = "sec0"
+
+// CreateDisk creates a QCOW2 disk image and returns the backend used.
+// If baseImage is provided, the disk is created from it with backend: a disk
+// with the base image as a backing store, or a reflink clone of it.
+// If baseImage is empty, it creates a standalone disk.
+func CreateDisk(backend DiskBackend, baseImage, outputPath, size, qemuImgPath string) (DiskBackend, error) {
+	if size == "" {
+		size = "20G"
+	}
+	return createFromBase(backend, baseImage, outputPath, size, qemuImgPath, func() error {
+		return CreateEncryptedDisk(baseImage, outputPath, size, "", qemuImgPath)
+	})
+}
+
+// CreateEncryptedDisk creates a QCOW2 disk image, LUKS-encrypted when passphrase
+// is non-empty. The passphrase is handed to qemu-img through a temporary 0600
+// file so it never shows up in the process list.
+func CreateEncryptedDisk(baseImage, outputPath, size, passphrase, qemuImgPath string) error {
+	// Apply default size if not specified
+	if size == "" {
+		size = "20G"
+	}
+
+	if baseImage != "" {
+		// Verify base image exists
+		if _, err := os.Stat(baseImage); err != nil {
+			return fmt.Errorf("base image not found: %s", baseImage)
+		}
+	}
+
+	secretFile := ""
+	if passphrase != "" {
+		f, err := os.CreateTemp(filepath.Dir(outputPath), ".passphrase-*")
+		if err != nil {
+			return fmt.Errorf("failed to create passphrase file: %w", err)
+		}
+		secretFile = f.Name()
+		defer func() { _ = os.Remove(secretFile) }()
+
+		_, writeErr := f.WriteString(passphrase)
+		closeErr := f.Close()
+		if writeErr != nil || closeErr != nil {
+			return fmt.Errorf("failed to write passphrase file: %w", errors.Join(writeErr, closeErr))
+		}
+	}
+
+	cmd := exec.Command(qemuImgPath, qemuImgCreateArgs(baseImage, outputPath, size, secretFile)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to create disk: %w, output: %s", err, string(output))
+	}
+
+	return nil
+}
+
+// qemuImgCreateArgs builds the qemu-img create arguments. When secretFile is
+// set, the image is created with LUKS encryption keyed from that file.
+func qemuImgCreateArgs(baseImage, outputPath, size, secretFile string) []string {
+	args := []string{"create", "-f", "qcow2"}
+
+	if secretFile != "" {
+		args = append(args,
+			"--object", fmt.Sprintf("secret,id=%s,file=%s,format=raw", diskSecretID, secretFile),
+			"-o", "encrypt.format=luks,encrypt.key-secret="+diskSecretID)
+	}
+
+	if baseImage != "" {
+		// Create disk with backing store
+		args = append(args, "-F", "qcow2", "-b", baseImage)
+	}
+
+	return append(args, outputPath, size)
+}
+
+// CreateDataDisk creates a data disk image: a qcow2 image created from
+// d.BaseImage with backend when it is set, or a raw image, into which
+// d.BaseImage is copied when it is set.
+func CreateDataDisk(backend DiskBackend, d providerv1.DiskSpec, outputPath, qemuImgPath string) error {
+	if d.BaseImage != "" {
+		if _, err := os.Stat(d.BaseImage); err != nil {
+			return fmt.Errorf("base image not found: %s", d.BaseImage)
+		}
+	}
+	if DataDiskFormat(d) == "raw" {
+		backend = DiskBackendQcow2
+	}
+	_, err := createFromBase(backend, d.BaseImage, outputPath, d.Size, qemuImgPath, func() error {
+		for _, args := range qemuImgDataDiskArgs(d, outputPath) {
+			output, err := exec.Command(qemuImgPath, args...).CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("failed to create data disk: %w, output: %s", err, string(output))
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// qemuImgDataDiskArgs builds the qemu-img commands creating a data disk. A
+// raw disk from a base image is converted from it, then grown to its size.
+func qemuImgDataDiskArgs(d providerv1.DiskSpec, outputPath string) [][]string {
+	if DataDiskFormat(d) != "raw" {
+		return [][]string{qemuImgCreateArgs(d.BaseImage, outputPath, d.Size, "")}
+	}
+	if d.BaseImage == "" {
+		return [][]string{{"create", "-f", "raw", outputPath, d.Size}}
+	}
+	return [][]string{
+		{"convert", "-O", "raw", d.BaseImage, outputPath},
+		{"resize", "-f", "raw", outputPath, d.Size},
+	}
+}
+
+// DataDiskFormat returns the image format of a data disk.
+func DataDiskFormat(d providerv1.DiskSpec) string {
+	if d.Format == "" {
+		return "qcow2"
+	}
+	return d.Format
+}
@@ -0,0 +1,112 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestQemuImgCreateArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		baseImage  string
+		secretFile string
+		want       []string
+	}{
+		{
+			name: "standalone disk",
+			want: []string{"create", "-f", "qcow2", "/disks/vm.qcow2", "20G"},
+		},
+		{
+			name:      "backing image",
+			baseImage: "/images/base.qcow2",
+			want:      []string{"create", "-f", "qcow2", "-F", "qcow2", "-b", "/images/base.qcow2", "/disks/vm.qcow2", "20G"},
+		},
+		{
+			name:       "encrypted overlay",
+			baseImage:  "/images/base.qcow2",
+			secretFile: "/disks/.passphrase-1",
+			want: []string{
+				"create", "-f", "qcow2",
+				"--object", "secret,id=sec0,file=/disks/.passphrase-1,format=raw",
+				"-o", "encrypt.format=luks,encrypt.key-secret=sec0",
+				"-F", "qcow2", "-b", "/images/base.qcow2",
+				"/disks/vm.qcow2", "20G",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := qemuImgCreateArgs(tt.baseImage, "/disks/vm.qcow2", "20G", tt.secretFile)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qemuImgCreateArgs() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateEncryptedDisk_MissingBaseImage(t *testing.T) {
+	err := CreateEncryptedDisk("/nonexistent/base.qcow2", t.TempDir()+"/vm.qcow2", "1G", "pass", "qemu-img")
+	if err == nil {
+		t.Fatal("expected error for missing base image")
+	}
+}
+
+func TestQemuImgDataDiskArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		disk providerv1.DiskSpec
+		want [][]string
+	}{
+		{
+			name: "empty qcow2 disk",
+			disk: providerv1.DiskSpec{Size: "100G"},
+			want: [][]string{{"create", "-f", "qcow2", "/disks/vm.data1.qcow2", "100G"}},
+		},
+		{
+			name: "qcow2 disk from an image",
+			disk: providerv1.DiskSpec{Size: "100G", BaseImage: "/images/seed.qcow2"},
+			want: [][]string{{"create", "-f", "qcow2", "-F", "qcow2", "-b", "/images/seed.qcow2", "/disks/vm.data1.qcow2", "100G"}},
+		},
+		{
+			name: "empty raw disk",
+			disk: providerv1.DiskSpec{Size: "10G", Format: "raw"},
+			want: [][]string{{"create", "-f", "raw", "/disks/vm.data1.qcow2", "10G"}},
+		},
+		{
+			name: "raw disk from an image",
+			disk: providerv1.DiskSpec{Size: "10G", Format: "raw", BaseImage: "/images/seed.img"},
+			want: [][]string{
+				{"convert", "-O", "raw", "/images/seed.img", "/disks/vm.data1.qcow2"},
+				{"resize", "-f", "raw", "/disks/vm.data1.qcow2", "10G"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := qemuImgDataDiskArgs(tt.disk, "/disks/vm.data1.qcow2")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("qemuImgDataDiskArgs() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
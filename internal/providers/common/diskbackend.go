@@ -0,0 +1,116 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+// DiskBackend selects how a disk is created from its base image.
+type DiskBackend string
+
+const (
+	// DiskBackendQcow2 creates a qcow2 overlay backed by the base image.
+	DiskBackendQcow2 DiskBackend = "qcow2"
+	// DiskBackendReflink clones the base image with a reflink: the disk
+	// shares the extents of the image until the guest writes to them, and
+	// does not depend on the base image afterwards. It requires the base
+	// image and the disk on the same btrfs, XFS or ZFS (2.2 or later, with
+	// block cloning) filesystem.
+	DiskBackendReflink DiskBackend = "reflink"
+	// DiskBackendAuto clones with a reflink when the filesystem supports it
+	// and falls back to a qcow2 overlay otherwise.
+	DiskBackendAuto DiskBackend = "auto"
+)
+
+// errReflinkUnsupported is returned when a file cannot be cloned with a
+// reflink: the filesystem does not support them, or the source lives on
+// another filesystem.
+var errReflinkUnsupported = errors.New("reflinks are not supported between these files")
+
+// ParseDiskBackend parses the disk backend named s. An empty name selects
+// DiskBackendQcow2.
+func ParseDiskBackend(s string) (DiskBackend, error) {
+	switch b := DiskBackend(s); b {
+	case "":
+		return DiskBackendQcow2, nil
+	case DiskBackendQcow2, DiskBackendReflink, DiskBackendAuto:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown disk backend %q: want %s, %s or %s", s, DiskBackendQcow2, DiskBackendReflink, DiskBackendAuto)
+}
+
+// createFromBase creates the disk at outputPath from baseImage with backend
+// and returns the backend used. Disks without a base image, and qcow2
+// overlays, are created by create. An empty backend is DiskBackendQcow2.
+func createFromBase(backend DiskBackend, baseImage, outputPath, size, qemuImgPath string, create func() error) (DiskBackend, error) {
+	if (backend == DiskBackendReflink || backend == DiskBackendAuto) && baseImage != "" {
+		err := cloneDisk(baseImage, outputPath, size, qemuImgPath)
+		if err == nil {
+			return DiskBackendReflink, nil
+		}
+		if backend != DiskBackendAuto || !errors.Is(err, errReflinkUnsupported) {
+			return "", err
+		}
+	}
+	return DiskBackendQcow2, create()
+}
+
+// cloneDisk clones the qcow2 image baseImage to outputPath with a reflink,
+// then grows it to size.
+func cloneDisk(baseImage, outputPath, size, qemuImgPath string) error {
+	if err := cloneFile(baseImage, outputPath); err != nil {
+		return err
+	}
+	output, err := exec.Command(qemuImgPath, "resize", "-f", "qcow2", outputPath, size).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(outputPath)
+		return fmt.Errorf("failed to resize cloned disk: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// cloneFile creates dst as a reflink of src. dst must not exist.
+func cloneFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("base image not found: %s", src)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create disk: %w", err)
+	}
+	err = unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+		switch {
+		case errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.EXDEV), errors.Is(err, unix.EINVAL),
+			errors.Is(err, unix.ENOTTY), errors.Is(err, unix.ENOSYS):
+			return fmt.Errorf("failed to clone %s: %w: %w", src, errReflinkUnsupported, err)
+		}
+		return fmt.Errorf("failed to clone %s: %w", src, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseDiskBackend(t *testing.T) {
+	for in, want := range map[string]DiskBackend{
+		"":        DiskBackendQcow2,
+		"qcow2":   DiskBackendQcow2,
+		"reflink": DiskBackendReflink,
+		"auto":    DiskBackendAuto,
+	} {
+		if got, err := ParseDiskBackend(in); err != nil || got != want {
+			t.Errorf("ParseDiskBackend(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDiskBackend("zvol"); err == nil {
+		t.Error("ParseDiskBackend(zvol) should fail")
+	}
+}
+
+func TestCreateFromBase(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.qcow2")
+	if err := os.WriteFile(base, []byte("qcow2 image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The resize of cloned disks is skipped: true stands in for qemu-img
+	const qemuImg = "true"
+
+	created := 0
+	create := func() error { created++; return nil }
+
+	for _, backend := range []DiskBackend{"", DiskBackendQcow2} {
+		used, err := createFromBase(backend, base, filepath.Join(dir, "overlay.qcow2"), "1G", qemuImg, create)
+		if err != nil || used != DiskBackendQcow2 || created != 1 {
+			t.Errorf("createFromBase(%q) = %q, %v with %d creations, want a qcow2 overlay", backend, used, err, created)
+		}
+		created = 0
+	}
+
+	used, err := createFromBase(DiskBackendAuto, "", filepath.Join(dir, "empty.qcow2"), "1G", qemuImg, create)
+	if err != nil || used != DiskBackendQcow2 || created != 1 {
+		t.Errorf("createFromBase() without base image = %q, %v, want a standalone disk", used, err)
+	}
+	created = 0
+
+	// Whether the temporary directory supports reflinks depends on the host
+	out := filepath.Join(dir, "auto.qcow2")
+	used, err = createFromBase(DiskBackendAuto, base, out, "1G", qemuImg, create)
+	switch {
+	case err != nil:
+		t.Fatalf("createFromBase(auto) failed: %v", err)
+	case used == DiskBackendReflink:
+		if data, err := os.ReadFile(out); err != nil || string(data) != "qcow2 image" {
+			t.Errorf("cloned disk = %q, %v, want the base image", data, err)
+		}
+		if created != 0 {
+			t.Error("a cloned disk must not be created again")
+		}
+	case created != 1:
+		t.Errorf("createFromBase(auto) = %q without falling back to a qcow2 overlay", used)
+	}
+
+	reflinks := used == DiskBackendReflink
+	created = 0
+	used, err = createFromBase(DiskBackendReflink, base, filepath.Join(dir, "reflink.qcow2"), "1G", qemuImg, create)
+	if reflinks {
+		if err != nil || used != DiskBackendReflink {
+			t.Errorf("createFromBase(reflink) = %q, %v, want a clone", used, err)
+		}
+	} else if !errors.Is(err, errReflinkUnsupported) || created != 0 {
+		t.Errorf("createFromBase(reflink) error = %v, want errReflinkUnsupported without fallback", err)
+	}
+
+	if _, err := createFromBase(DiskBackendAuto, filepath.Join(dir, "missing.qcow2"), filepath.Join(dir, "x.qcow2"), "1G", qemuImg, create); err == nil {
+		t.Error("createFromBase() should fail for a missing base image")
+	}
+}
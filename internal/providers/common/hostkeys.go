@@ -0,0 +1,86 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"log"
+	"net"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeyAlgorithms are the host key types collected from a VM, one SSH
+// handshake each, like ssh-keyscan.
+var hostKeyAlgorithms = []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoRSASHA512}
+
+// errHostKeyCollected aborts a handshake once the host key was recorded.
+var errHostKeyCollected = errors.New("host key collected")
+
+// CollectHostKeys returns the SSH host keys of the VM at ip in
+// authorized_keys format. It is best-effort: it requires SSH readiness to be
+// configured, and key types the VM does not offer are skipped.
+func CollectHostKeys(spec *providerv1.ReadinessSpec, ip string) []string {
+	if spec == nil || spec.SSH == nil || !spec.SSH.Enabled || ip == "" {
+		return nil
+	}
+	sshConfig, _, opErr := buildSSHClientConfig(spec.SSH)
+	if opErr != nil {
+		return nil
+	}
+	addr := sshAddr(ip)
+
+	var keys []string
+	for _, algo := range hostKeyAlgorithms {
+		var collected ssh.PublicKey
+		config := *sshConfig
+		config.HostKeyAlgorithms = []string{algo}
+		config.HostKeyCallback = func(hostname string, _ net.Addr, key ssh.PublicKey) error {
+			if hostname != addr {
+				return nil // jump host
+			}
+			collected = key
+			return errHostKeyCollected
+		}
+
+		conn, err := dialSSH(&config, spec.SSH, addr)
+		if err == nil {
+			_ = conn.Close()
+		}
+		if collected == nil {
+			continue
+		}
+		keys = append(keys, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(collected))))
+	}
+
+	log.Printf("Collected %d SSH host key(s) from %s", len(keys), ip)
+	return keys
+}
+
+// HostKeyFingerprints returns the SHA256 fingerprints of authorized_keys
+// formatted host keys, skipping unparsable entries.
+func HostKeyFingerprints(hostKeys []string) []string {
+	fingerprints := make([]string, 0, len(hostKeys))
+	for _, hk := range hostKeys {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(hk))
+		if err != nil {
+			continue
+		}
+		fingerprints = append(fingerprints, ssh.FingerprintSHA256(key))
+	}
+	return fingerprints
+}
@@ -0,0 +1,57 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestCollectHostKeys_RequiresSSHReadiness(t *testing.T) {
+	specs := []*providerv1.ReadinessSpec{
+		nil,
+		{},
+		{SSH: &providerv1.SSHReadinessSpec{Enabled: false}},
+	}
+	for _, spec := range specs {
+		if keys := CollectHostKeys(spec, "192.0.2.1"); keys != nil {
+			t.Errorf("CollectHostKeys(%+v) = %v, want nil", spec, keys)
+		}
+	}
+}
+
+func TestHostKeyFingerprints(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	hostKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+
+	got := HostKeyFingerprints([]string{hostKey, "not a key"})
+	if len(got) != 1 || got[0] != ssh.FingerprintSHA256(sshPub) {
+		t.Errorf("HostKeyFingerprints() = %v, want [%s]", got, ssh.FingerprintSHA256(sshPub))
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// generateED25519Key generates an ED25519 key pair.
+func generateED25519Key() ([]byte, ssh.PublicKey, error) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshPubKey, err := ssh.NewPublicKey(pubKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Marshal private key to OpenSSH format
+	pemBlock, err := ssh.MarshalPrivateKey(privKey, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(pemBlock), sshPubKey, nil
+}
+
+// generateRSAKey generates an RSA key pair with the specified bit size.
+func generateRSAKey(bits int) ([]byte, ssh.PublicKey, error) {
+	privKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshPubKey, err := ssh.NewPublicKey(&privKey.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Marshal private key to OpenSSH format
+	pemBlock, err := ssh.MarshalPrivateKey(privKey, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(pemBlock), sshPubKey, nil
+}
+
+// GenerateKey generates an SSH key pair of keyType, "ed25519" or "rsa", and
+// returns the private key in OpenSSH PEM format. bits only applies to RSA
+// keys and defaults to 4096.
+func GenerateKey(keyType string, bits int) ([]byte, ssh.PublicKey, error) {
+	switch keyType {
+	case "ed25519":
+		return generateED25519Key()
+	case "rsa":
+		if bits <= 0 {
+			bits = 4096
+		}
+		return generateRSAKey(bits)
+	}
+	return nil, nil, fmt.Errorf("unsupported key type: %s", keyType)
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// labelXattrPrefix namespaces owner labels stored as extended attributes.
+	// qcow2 has no free-form metadata, so disk images (and ISOs) are labeled
+	// through the file system instead.
+	labelXattrPrefix = "user."
+	// labelKeyPrefix is the prefix of every label set by the orchestrator.
+	labelKeyPrefix = "testenv-vm."
+	// isoApplicationIDMax is the length of the ISO 9660 application identifier.
+	isoApplicationIDMax = 128
+)
+
+// Label is a key/value pair identifying the owner of a provider object.
+type Label struct {
+	Key   string
+	Value string
+}
+
+// SortedLabels returns labels ordered by key.
+func SortedLabels(labels map[string]string) []Label {
+	result := make([]Label, 0, len(labels))
+	for k, v := range labels {
+		result = append(result, Label{Key: k, Value: v})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// LabelFile stores labels as user.* extended attributes of path. File systems
+// without xattr support (e.g. tmpfs on old kernels) are tolerated.
+func LabelFile(path string, labels map[string]string) error {
+	for _, l := range SortedLabels(labels) {
+		err := unix.Setxattr(path, labelXattrPrefix+l.Key, []byte(l.Value), 0)
+		if errors.Is(err, unix.ENOTSUP) {
+			log.Printf("Cannot label %s: extended attributes are not supported", path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to label %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// ReadFileLabels returns the testenv-vm labels stored on path by LabelFile.
+func ReadFileLabels(path string) (map[string]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attributes of %s: %w", path, err)
+	}
+	buf := make([]byte, size)
+	if size > 0 {
+		if size, err = unix.Listxattr(path, buf); err != nil {
+			return nil, fmt.Errorf("failed to list attributes of %s: %w", path, err)
+		}
+	}
+
+	labels := make(map[string]string)
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		attr := string(name)
+		if !strings.HasPrefix(attr, labelXattrPrefix+labelKeyPrefix) {
+			continue
+		}
+		n, err := unix.Getxattr(path, attr, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attribute %s of %s: %w", attr, path, err)
+		}
+		value := make([]byte, n)
+		if n, err = unix.Getxattr(path, attr, value); err != nil {
+			return nil, fmt.Errorf("failed to read attribute %s of %s: %w", attr, path, err)
+		}
+		labels[strings.TrimPrefix(attr, labelXattrPrefix)] = string(value[:n])
+	}
+	return labels, nil
+}
+
+// isoApplicationID renders labels as an ISO 9660 application identifier,
+// e.g. "testenv-vm environment-id=abc resource=vm/web". The volume ID must
+// stay "cidata" for cloud-init to find the ISO, so ownership goes here. Only
+// the testenv-vm labels fit.
+func isoApplicationID(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := []string{"testenv-vm"}
+	for _, l := range SortedLabels(labels) {
+		if key, ok := strings.CutPrefix(l.Key, labelKeyPrefix); ok {
+			parts = append(parts, key+"="+l.Value)
+		}
+	}
+	id := strings.Join(parts, " ")
+	if len(id) > isoApplicationIDMax {
+		id = id[:isoApplicationIDMax]
+	}
+	return id
+}
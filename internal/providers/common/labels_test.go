@@ -0,0 +1,77 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestLabelFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.qcow2")
+	if err := os.WriteFile(path, []byte("qcow2"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := unix.Setxattr(path, "user.probe", []byte("1"), 0); errors.Is(err, unix.ENOTSUP) {
+		t.Skip("extended attributes are not supported by the temp file system")
+	}
+
+	labels := map[string]string{
+		"testenv-vm.environment-id": "abc123",
+		"testenv-vm.resource":       "vm/web",
+	}
+	if err := LabelFile(path, labels); err != nil {
+		t.Fatalf("LabelFile failed: %v", err)
+	}
+
+	got, err := ReadFileLabels(path)
+	if err != nil {
+		t.Fatalf("ReadFileLabels failed: %v", err)
+	}
+	if len(got) != len(labels) {
+		t.Fatalf("ReadFileLabels() = %v, want %v", got, labels)
+	}
+	for k, v := range labels {
+		if got[k] != v {
+			t.Errorf("label %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestIsoApplicationID(t *testing.T) {
+	if got := isoApplicationID(nil); got != "" {
+		t.Errorf("isoApplicationID(nil) = %q, want empty", got)
+	}
+
+	got := isoApplicationID(map[string]string{
+		"testenv-vm.resource":       "vm/web",
+		"testenv-vm.environment-id": "abc123",
+	})
+	if want := "testenv-vm environment-id=abc123 resource=vm/web"; got != want {
+		t.Errorf("isoApplicationID() = %q, want %q", got, want)
+	}
+
+	long := isoApplicationID(map[string]string{"testenv-vm.resource": strings.Repeat("x", 200)})
+	if len(long) != isoApplicationIDMax {
+		t.Errorf("len(isoApplicationID()) = %d, want %d", len(long), isoApplicationIDMax)
+	}
+}
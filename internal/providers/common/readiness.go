@@ -12,7 +12,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package libvirt
+package common
 
 import (
 	"bytes"
@@ -20,16 +20,17 @@ import (
 	"log"
 	"net"
 	"os"
+	"strings"
 	"time"
 
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 	"golang.org/x/crypto/ssh"
 )
 
-// waitForReadiness performs readiness checks on a VM after it has been started.
+// WaitForReadiness performs readiness checks on a VM after it has been started.
 // It checks SSH connectivity and cloud-init completion based on the readiness spec.
 // Returns nil if all enabled checks pass, or an OperationError on failure.
-func waitForReadiness(spec *providerv1.ReadinessSpec, ip string) *providerv1.OperationError {
+func WaitForReadiness(spec *providerv1.ReadinessSpec, ip string) *providerv1.OperationError {
 	if spec == nil {
 		return nil
 	}
@@ -77,8 +78,8 @@ func waitForReadiness(spec *providerv1.ReadinessSpec, ip string) *providerv1.Ope
 		log.Printf("SSH readiness check passed for %s (fingerprint=%s)", ip, fingerprint)
 
 		// Immediately verify auth still works before entering cloud-init phase.
-		addr := net.JoinHostPort(ip, "22")
-		verifyConn, dialErr := ssh.Dial("tcp", addr, sshConfig)
+		addr := sshAddr(ip)
+		verifyConn, dialErr := dialSSH(sshConfig, spec.SSH, addr)
 		if dialErr != nil {
 			log.Printf("WARNING: SSH verification dial failed immediately after waitForSSH for %s: %v", ip, dialErr)
 		} else {
@@ -119,7 +120,7 @@ func waitForSSH(sshConfig *ssh.ClientConfig, spec *providerv1.SSHReadinessSpec,
 		return providerv1.NewInvalidSpecError(fmt.Sprintf("invalid SSH readiness timeout %q: %v", spec.Timeout, err))
 	}
 
-	addr := net.JoinHostPort(ip, "22")
+	addr := sshAddr(ip)
 	deadline := time.Now().Add(timeout)
 	pollInterval := 5 * time.Second
 
@@ -127,7 +128,7 @@ func waitForSSH(sshConfig *ssh.ClientConfig, spec *providerv1.SSHReadinessSpec,
 	attempt := 0
 	for time.Now().Before(deadline) {
 		attempt++
-		conn, dialErr := ssh.Dial("tcp", addr, sshConfig)
+		conn, dialErr := dialSSH(sshConfig, spec, addr)
 		if dialErr == nil {
 			// Verify auth by running a command.
 			session, sessErr := conn.NewSession()
@@ -174,7 +175,7 @@ func waitForCloudInit(sshConfig *ssh.ClientConfig, fingerprint string, ciSpec *p
 
 	log.Printf("waitForCloudInit: user=%s, key=%s, fingerprint=%s, ip=%s", sshSpec.User, sshSpec.PrivateKey, fingerprint, ip)
 
-	addr := net.JoinHostPort(ip, "22")
+	addr := sshAddr(ip)
 	deadline := time.Now().Add(timeout)
 	pollInterval := 10 * time.Second
 
@@ -187,7 +188,7 @@ func waitForCloudInit(sshConfig *ssh.ClientConfig, fingerprint string, ciSpec *p
 	attempt := 0
 	for time.Now().Before(deadline) {
 		attempt++
-		conn, dialErr := ssh.Dial("tcp", addr, sshConfig)
+		conn, dialErr := dialSSH(sshConfig, sshSpec, addr)
 		if dialErr != nil {
 			log.Printf("Cloud-init check attempt %d: SSH dial failed for %s: %v", attempt, ip, dialErr)
 			lastErr = dialErr
@@ -227,6 +228,76 @@ func waitForCloudInit(sshConfig *ssh.ClientConfig, fingerprint string, ciSpec *p
 	)
 }
 
+// sshAddr returns the SSH address of a VM. ip is either a bare address,
+// reached on port 22, or a host:port pair for a VM behind a port forward.
+func sshAddr(ip string) string {
+	if _, _, err := net.SplitHostPort(ip); err == nil {
+		return ip
+	}
+	return net.JoinHostPort(ip, "22")
+}
+
+// dialSSH connects to addr, tunneling through spec.ProxyJump when set. The
+// jump host accepts the same key as the VM; it is closed with the returned client.
+func dialSSH(sshConfig *ssh.ClientConfig, spec *providerv1.SSHReadinessSpec, addr string) (*ssh.Client, error) {
+	if spec == nil || spec.ProxyJump == "" {
+		return ssh.Dial("tcp", addr, sshConfig)
+	}
+
+	jumpUser, jumpAddr, err := parseProxyJump(spec.ProxyJump, sshConfig.User)
+	if err != nil {
+		return nil, err
+	}
+	jumpConfig := *sshConfig
+	jumpConfig.User = jumpUser
+	// Host key algorithms restricted for the VM do not apply to the jump host.
+	jumpConfig.HostKeyAlgorithms = nil
+	jumpConn, err := ssh.Dial("tcp", jumpAddr, &jumpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("jump host %s: %w", jumpAddr, err)
+	}
+
+	netConn, err := jumpConn.Dial("tcp", addr)
+	if err != nil {
+		_ = jumpConn.Close()
+		return nil, fmt.Errorf("dial %s via jump host %s: %w", addr, jumpAddr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(netConn, addr, sshConfig)
+	if err != nil {
+		_ = netConn.Close()
+		_ = jumpConn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s via jump host %s: %w", addr, jumpAddr, err)
+	}
+	conn := ssh.NewClient(c, chans, reqs)
+	go func() {
+		_ = conn.Wait()
+		_ = jumpConn.Close()
+	}()
+	return conn, nil
+}
+
+// parseProxyJump splits an OpenSSH ProxyJump value [user@]host[:port] into a
+// user (defaulting to defaultUser) and a host:port address (defaulting to port 22).
+func parseProxyJump(value, defaultUser string) (string, string, error) {
+	if strings.Contains(value, ",") {
+		return "", "", fmt.Errorf("proxyJump %q: multiple jump hosts are not supported", value)
+	}
+	user := defaultUser
+	hostPort := value
+	if i := strings.LastIndex(value, "@"); i >= 0 {
+		user = value[:i]
+		hostPort = value[i+1:]
+	}
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = strings.Trim(hostPort, "[]"), "22"
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("proxyJump %q: host is required", value)
+	}
+	return user, net.JoinHostPort(host, port), nil
+}
+
 // buildSSHClientConfig builds an ssh.ClientConfig from an SSHReadinessSpec.
 // Returns the config, the key fingerprint, and an optional error.
 func buildSSHClientConfig(spec *providerv1.SSHReadinessSpec) (*ssh.ClientConfig, string, *providerv1.OperationError) {
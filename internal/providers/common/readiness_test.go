@@ -14,7 +14,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package libvirt
+package common
 
 import (
 	"crypto/ed25519"
@@ -52,7 +52,7 @@ func generateTestKey(t *testing.T) string {
 }
 
 func TestWaitForReadiness_NilSpec(t *testing.T) {
-	err := waitForReadiness(nil, "192.168.1.1")
+	err := WaitForReadiness(nil, "192.168.1.1")
 	if err != nil {
 		t.Errorf("expected nil error for nil spec, got: %v", err)
 	}
@@ -66,7 +66,7 @@ func TestWaitForReadiness_NoIP(t *testing.T) {
 			User:    "ubuntu",
 		},
 	}
-	err := waitForReadiness(spec, "")
+	err := WaitForReadiness(spec, "")
 	if err == nil {
 		t.Fatal("expected error for empty IP")
 	}
@@ -81,7 +81,7 @@ func TestWaitForReadiness_SSHDisabled(t *testing.T) {
 			Enabled: false,
 		},
 	}
-	err := waitForReadiness(spec, "192.168.1.1")
+	err := WaitForReadiness(spec, "192.168.1.1")
 	if err != nil {
 		t.Errorf("expected nil error when SSH disabled, got: %v", err)
 	}
@@ -94,7 +94,7 @@ func TestWaitForReadiness_CloudInitWithoutSSH(t *testing.T) {
 			Timeout: "1s",
 		},
 	}
-	err := waitForReadiness(spec, "192.168.1.1")
+	err := WaitForReadiness(spec, "192.168.1.1")
 	if err == nil {
 		t.Fatal("expected error for cloud-init without SSH")
 	}
@@ -260,3 +260,54 @@ func TestWaitForCloudInit_InvalidTimeout(t *testing.T) {
 		t.Errorf("expected INVALID_SPEC error code, got: %s", err.Code)
 	}
 }
+
+func TestParseProxyJump(t *testing.T) {
+	tests := []struct {
+		value    string
+		wantUser string
+		wantAddr string
+		wantErr  bool
+	}{
+		{value: "10.0.0.1", wantUser: "ubuntu", wantAddr: "10.0.0.1:22"},
+		{value: "admin@10.0.0.1", wantUser: "admin", wantAddr: "10.0.0.1:22"},
+		{value: "admin@bastion.example.com:2222", wantUser: "admin", wantAddr: "bastion.example.com:2222"},
+		{value: "[fd00::1]:2222", wantUser: "ubuntu", wantAddr: "[fd00::1]:2222"},
+		{value: "a@b,c@d", wantErr: true},
+		{value: "admin@", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			user, addr, err := parseProxyJump(tt.value, "ubuntu")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProxyJump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if user != tt.wantUser || addr != tt.wantAddr {
+				t.Errorf("parseProxyJump() = %q, %q, want %q, %q", user, addr, tt.wantUser, tt.wantAddr)
+			}
+		})
+	}
+}
+
+func TestSSHAddr(t *testing.T) {
+	tests := map[string]string{
+		"192.168.1.10":    "192.168.1.10:22",
+		"127.0.0.1:40022": "127.0.0.1:40022",
+		"fd00::10":        "[fd00::10]:22",
+		"[fd00::10]:2222": "[fd00::10]:2222",
+	}
+	for ip, want := range tests {
+		if got := sshAddr(ip); got != want {
+			t.Errorf("sshAddr(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestDialSSH_UnreachableJumpHost(t *testing.T) {
+	config := &ssh.ClientConfig{User: "ubuntu", HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+	spec := &providerv1.SSHReadinessSpec{ProxyJump: "127.0.0.1:1"}
+	_, err := dialSSH(config, spec, "10.0.0.2:22")
+	if err == nil || !strings.Contains(err.Error(), "jump host 127.0.0.1:1") {
+		t.Errorf("dialSSH() error = %v, want jump host error", err)
+	}
+}
@@ -15,6 +15,10 @@
 package libvirt
 
 import (
+	"log"
+
+	"golang.org/x/sys/unix"
+
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 )
 
@@ -31,12 +35,54 @@ func (p *Provider) Capabilities() *providerv1.CapabilitiesResponse {
 			},
 			{
 				Kind:       "network",
-				Operations: []string{"create", "get", "list", "delete"},
+				Operations: []string{"create", "get", "list", "delete", "capture"},
 			},
 			{
 				Kind:       "vm",
-				Operations: []string{"create", "get", "list", "delete"},
+				Operations: []string{"create", "get", "list", "delete", "migrate", "adopt", "stats", "start", "stop", "reboot", "pause", "save", "snapshot"},
+				VMFeatures: []string{
+					providerv1.VMFeatureVsock,
+					providerv1.VMFeatureSecurity,
+					providerv1.VMFeatureDiskEncryption,
+					providerv1.VMFeatureNICOptions,
+					providerv1.VMFeatureDataDisks,
+					providerv1.VMFeatureStaticIP,
+					providerv1.VMFeatureRawUserData,
+					providerv1.VMFeatureGuestNetwork,
+					providerv1.VMFeaturePCIPassthrough,
+					providerv1.VMFeatureMdev,
+				},
 			},
 		},
+		Host:     p.hostCapacity(),
+		Batch:    true,
+		Teardown: true,
+		URI:      p.config.URI,
+	}
+}
+
+// hostCapacity reports the CPUs and memory of the libvirt host and the free
+// space of the state directory. It returns nil without a connection.
+func (p *Provider) hostCapacity() *providerv1.HostCapacity {
+	if p.conn == nil {
+		return nil
+	}
+
+	_, memoryKiB, cpus, _, _, _, _, _, err := p.conn.NodeGetInfo()
+	if err != nil {
+		log.Printf("Failed to get host info: %v", err)
+		return nil
+	}
+	host := &providerv1.HostCapacity{
+		CPUs:     int(cpus),
+		MemoryMB: int(memoryKiB / 1024),
+	}
+	if free, err := p.conn.NodeGetFreeMemory(); err == nil {
+		host.FreeMemoryMB = int(free / (1024 * 1024))
+	}
+	var fs unix.Statfs_t
+	if err := unix.Statfs(p.config.StateDir, &fs); err == nil {
+		host.FreeDiskMB = int64(fs.Bavail) * int64(fs.Bsize) / (1024 * 1024)
 	}
+	return host
 }
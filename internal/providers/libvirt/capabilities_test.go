@@ -37,6 +37,11 @@ func TestCapabilities(t *testing.T) {
 		t.Error("Version should not be empty")
 	}
 
+	// Host capacity is unknown without a connection
+	if caps.Host != nil {
+		t.Errorf("Expected no host capacity without a connection, got %+v", caps.Host)
+	}
+
 	// Verify we have 3 resources: key, network, vm
 	if len(caps.Resources) != 3 {
 		t.Errorf("Expected 3 resources, got %d", len(caps.Resources))
@@ -45,8 +50,8 @@ func TestCapabilities(t *testing.T) {
 	// Verify each resource type and operations
 	expectedResources := map[string][]string{
 		"key":     {"create", "get", "list", "delete"},
-		"network": {"create", "get", "list", "delete"},
-		"vm":      {"create", "get", "list", "delete"},
+		"network": {"create", "get", "list", "delete", "capture"},
+		"vm":      {"create", "get", "list", "delete", "migrate", "adopt", "stats", "start", "stop", "reboot", "pause", "save", "snapshot"},
 	}
 
 	for _, res := range caps.Resources {
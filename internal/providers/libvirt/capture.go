@@ -0,0 +1,312 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// Timings of the tcpdump processes run by captures.
+const (
+	// captureStartTimeout bounds the wait for tcpdump to open its output.
+	captureStartTimeout = 2 * time.Second
+	// capturePollInterval is how often the size of a pcap file is checked.
+	capturePollInterval = 250 * time.Millisecond
+	// captureStopTimeout is how long tcpdump may take to flush its output
+	// after SIGTERM before it is killed.
+	captureStopTimeout = 5 * time.Second
+)
+
+// capture is a tcpdump process writing the packets of a host device to a
+// pcap file, stopped when it reaches its limits.
+type capture struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	done   chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	state providerv1.CaptureState
+	err   error
+}
+
+// NetworkCaptureStart starts capturing the bridge of a network, or the tap
+// device of a VM interface, into a pcap file of the requested directory.
+func (p *Provider) NetworkCaptureStart(req *providerv1.NetworkCaptureStartRequest) *providerv1.OperationResult {
+	if err := req.Validate(); err != nil {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+	}
+	device, errResult := p.captureDevice(req)
+	if errResult != nil {
+		return errResult
+	}
+	tcpdump, err := exec.LookPath("tcpdump")
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("packet capture requires tcpdump: "+err.Error(), false))
+	}
+	if err := os.MkdirAll(req.OutputDir, 0o755); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to create capture directory: "+err.Error(), false))
+	}
+
+	target := req.Network
+	if req.VM != "" {
+		target = req.VM
+	}
+	id := fmt.Sprintf("%s-%s", target, time.Now().UTC().Format("20060102-150405.000"))
+	maxSizeMB, maxDurationSec := req.Limits()
+	c, err := startCapture(tcpdump, providerv1.CaptureState{
+		ID:             id,
+		Network:        req.Network,
+		VM:             req.VM,
+		Interface:      device,
+		Path:           filepath.Join(req.OutputDir, id+".pcap"),
+		Filter:         req.Filter,
+		MaxSizeMB:      maxSizeMB,
+		MaxDurationSec: maxDurationSec,
+	})
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+
+	p.mu.Lock()
+	if p.captures == nil {
+		p.captures = make(map[string]*capture)
+	}
+	p.captures[id] = c
+	p.mu.Unlock()
+	return providerv1.SuccessResult(c.snapshot())
+}
+
+// NetworkCaptureStop stops a capture, unless it already stopped at one of
+// its limits, and returns its final state.
+func (p *Provider) NetworkCaptureStop(req *providerv1.NetworkCaptureStopRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	c, exists := p.captures[req.ID]
+	delete(p.captures, req.ID)
+	p.mu.Unlock()
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("capture", req.ID))
+	}
+	c.stop(providerv1.CaptureStopRequested)
+	return providerv1.SuccessResult(c.snapshot())
+}
+
+// stopCaptures stops all the captures of the provider.
+func (p *Provider) stopCaptures() {
+	p.mu.Lock()
+	captures := p.captures
+	p.captures = nil
+	p.mu.Unlock()
+	for _, c := range captures {
+		c.stop(providerv1.CaptureStopRequested)
+	}
+}
+
+// captureDevice returns the host device captured by a request: the bridge
+// of the network, or the tap device of the VM interface.
+func (p *Provider) captureDevice(req *providerv1.NetworkCaptureStartRequest) (string, *providerv1.OperationResult) {
+	if req.Network != "" {
+		p.mu.RLock()
+		network, exists := p.networks[req.Network]
+		p.mu.RUnlock()
+		if !exists {
+			return "", providerv1.ErrorResult(providerv1.NewNotFoundError("network", req.Network))
+		}
+		if network.InterfaceName == "" {
+			return "", providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("network %s has no bridge", req.Network), false))
+		}
+		return network.InterfaceName, nil
+	}
+
+	p.mu.RLock()
+	_, exists := p.vms[req.VM]
+	p.mu.RUnlock()
+	if !exists {
+		return "", providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.VM))
+	}
+	dom, err := p.conn.DomainLookupByName(req.VM)
+	if err != nil {
+		return "", providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.VM))
+	}
+	// The tap devices are only in the XML of running domains
+	domainXML, err := p.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return "", providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain XML: "+err.Error(), true))
+	}
+	_, ifaces, err := parseDomainDevices(domainXML)
+	if err != nil {
+		return "", providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	device, err := selectInterface(ifaces, req.MAC)
+	if err != nil {
+		return "", providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("vm %s: %v", req.VM, err), false))
+	}
+	return device, nil
+}
+
+// selectInterface returns the host device of the interface with the MAC
+// address, or of the first interface if mac is empty.
+func selectInterface(ifaces []domainInterface, mac string) (string, error) {
+	for _, iface := range ifaces {
+		if iface.dev == "" {
+			continue
+		}
+		if mac == "" || strings.EqualFold(iface.mac, mac) {
+			return iface.dev, nil
+		}
+	}
+	if mac != "" {
+		return "", fmt.Errorf("no running interface with MAC %s", mac)
+	}
+	return "", errors.New("no running interface")
+}
+
+// tcpdumpArgs returns the arguments of tcpdump writing the packets of
+// device to path. Packets are written as they arrive (-U) so that the file
+// is usable while the capture runs. The filter follows "--" so that it is
+// never parsed as an option.
+func tcpdumpArgs(device, path, filter string) []string {
+	args := []string{"-i", device, "-w", path, "-U", "-n"}
+	if filter != "" {
+		args = append(args, "--", filter)
+	}
+	return args
+}
+
+// startCapture starts tcpdump and the goroutine enforcing the limits of the
+// capture. It fails if tcpdump exits before opening the pcap file, e.g.
+// because of an invalid filter or missing privileges.
+func startCapture(tcpdump string, state providerv1.CaptureState) (*capture, error) {
+	c := &capture{done: make(chan struct{}), state: state}
+	c.cmd = exec.Command(tcpdump, tcpdumpArgs(state.Interface, state.Path, state.Filter)...)
+	c.cmd.Stderr = &c.stderr
+	// Keep terminal signals of the provider away from tcpdump
+	c.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tcpdump: %w", err)
+	}
+	c.state.Status = providerv1.CaptureStatusRunning
+	c.state.StartedAt = time.Now().UTC().Format(time.RFC3339)
+	go func() {
+		err := c.cmd.Wait()
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	deadline := time.After(captureStartTimeout)
+	for {
+		if _, err := os.Stat(state.Path); err == nil {
+			break
+		}
+		select {
+		case <-c.done:
+			return nil, fmt.Errorf("tcpdump failed on %s: %s", state.Interface, c.output())
+		case <-deadline:
+			c.stop(providerv1.CaptureStopRequested)
+			return nil, fmt.Errorf("tcpdump did not open %s within %s: %s", state.Path, captureStartTimeout, c.output())
+		case <-time.After(capturePollInterval / 5):
+		}
+	}
+
+	go c.watch(int64(state.MaxSizeMB)<<20, time.Duration(state.MaxDurationSec)*time.Second)
+	return c, nil
+}
+
+// watch stops the capture when the pcap file reaches maxSize bytes or after
+// maxDuration, and records when tcpdump exits by itself.
+func (c *capture) watch(maxSize int64, maxDuration time.Duration) {
+	timer := time.NewTimer(maxDuration)
+	defer timer.Stop()
+	ticker := time.NewTicker(capturePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			c.stop(providerv1.CaptureStopExited)
+			return
+		case <-timer.C:
+			c.stop(providerv1.CaptureStopMaxDuration)
+			return
+		case <-ticker.C:
+			if info, err := os.Stat(c.state.Path); err == nil && info.Size() >= maxSize {
+				c.stop(providerv1.CaptureStopMaxSize)
+				return
+			}
+		}
+	}
+}
+
+// stop terminates tcpdump, letting it flush the pcap file, and records the
+// reason of the first stop. Concurrent calls return once it stopped.
+func (c *capture) stop(reason string) {
+	c.once.Do(func() {
+		_ = c.cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-c.done:
+		case <-time.After(captureStopTimeout):
+			_ = c.cmd.Process.Kill()
+			<-c.done
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.state.Status = providerv1.CaptureStatusStopped
+		c.state.StopReason = reason
+		c.state.StoppedAt = time.Now().UTC().Format(time.RFC3339)
+		if info, err := os.Stat(c.state.Path); err == nil {
+			c.state.SizeBytes = info.Size()
+		}
+		if reason == providerv1.CaptureStopExited && c.err != nil {
+			c.state.Error = fmt.Sprintf("%v: %s", c.err, c.outputLocked())
+		}
+	})
+}
+
+// snapshot returns a copy of the state of the capture.
+func (c *capture) snapshot() *providerv1.CaptureState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := c.state
+	if state.Status == providerv1.CaptureStatusRunning {
+		if info, err := os.Stat(state.Path); err == nil {
+			state.SizeBytes = info.Size()
+		}
+	}
+	return &state
+}
+
+// output returns the trimmed stderr of tcpdump.
+func (c *capture) output() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.outputLocked()
+}
+
+func (c *capture) outputLocked() string {
+	return strings.TrimSpace(c.stderr.String())
+}
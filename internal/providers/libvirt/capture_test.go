@@ -0,0 +1,177 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// fakeTcpdump writes a shell script standing for tcpdump. The script body
+// can use $out, the pcap file given with -w.
+func fakeTcpdump(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tcpdump")
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = -w ] && out=$2; shift; done\n" + body + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newCaptureState returns the state of a capture of vnet0 into a temporary
+// directory.
+func newCaptureState(t *testing.T, maxSizeMB, maxDurationSec int) providerv1.CaptureState {
+	return providerv1.CaptureState{
+		ID:             "vm1-test",
+		VM:             "vm1",
+		Interface:      "vnet0",
+		Path:           filepath.Join(t.TempDir(), "vm1-test.pcap"),
+		MaxSizeMB:      maxSizeMB,
+		MaxDurationSec: maxDurationSec,
+	}
+}
+
+func TestTcpdumpArgs(t *testing.T) {
+	got := tcpdumpArgs("virbr-1234", "/tmp/a.pcap", "")
+	want := []string{"-i", "virbr-1234", "-w", "/tmp/a.pcap", "-U", "-n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tcpdumpArgs() = %q, want %q", got, want)
+	}
+	got = tcpdumpArgs("vnet0", "/tmp/a.pcap", "tcp port 443")
+	if got[len(got)-2] != "--" || got[len(got)-1] != "tcp port 443" {
+		t.Errorf("tcpdumpArgs() = %q, want the filter last, after --", got)
+	}
+}
+
+func TestSelectInterface(t *testing.T) {
+	ifaces := []domainInterface{{mac: "52:54:00:00:00:09"}, {dev: "vnet3", mac: "52:54:00:00:00:01"}, {dev: "vnet4", mac: "52:54:00:00:00:02"}}
+	if dev, err := selectInterface(ifaces, ""); err != nil || dev != "vnet3" {
+		t.Errorf("selectInterface() = %q, %v, want the first running interface", dev, err)
+	}
+	if dev, err := selectInterface(ifaces, "52:54:00:00:00:02"); err != nil || dev != "vnet4" {
+		t.Errorf("selectInterface(mac) = %q, %v, want vnet4", dev, err)
+	}
+	if _, err := selectInterface(ifaces, "52:54:00:00:00:09"); err == nil {
+		t.Error("selectInterface() expected error for an interface without device")
+	}
+	if _, err := selectInterface(nil, ""); err == nil {
+		t.Error("selectInterface() expected error without interfaces")
+	}
+}
+
+func TestStartCapture_Stop(t *testing.T) {
+	tcpdump := fakeTcpdump(t, `: > "$out"; trap 'exit 0' TERM; while true; do echo packet >> "$out"; sleep 0.05; done`)
+	c, err := startCapture(tcpdump, newCaptureState(t, 100, 60))
+	if err != nil {
+		t.Fatalf("startCapture() error = %v", err)
+	}
+	if s := c.snapshot(); s.Status != providerv1.CaptureStatusRunning || s.StartedAt == "" {
+		t.Errorf("snapshot() = %+v, want a running capture", s)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	c.stop(providerv1.CaptureStopRequested)
+	s := c.snapshot()
+	if s.Status != providerv1.CaptureStatusStopped || s.StopReason != providerv1.CaptureStopRequested || s.StoppedAt == "" || s.SizeBytes == 0 {
+		t.Errorf("snapshot() = %+v, want a requested stop with data", s)
+	}
+	// Stopping twice keeps the first reason
+	c.stop(providerv1.CaptureStopMaxSize)
+	if s := c.snapshot(); s.StopReason != providerv1.CaptureStopRequested {
+		t.Errorf("StopReason = %q after a second stop", s.StopReason)
+	}
+}
+
+func TestStartCapture_Limits(t *testing.T) {
+	grow := fakeTcpdump(t, `: > "$out"; trap 'exit 0' TERM; while true; do head -c 262144 /dev/zero >> "$out"; sleep 0.05; done`)
+	c, err := startCapture(grow, newCaptureState(t, 1, 60))
+	if err != nil {
+		t.Fatalf("startCapture() error = %v", err)
+	}
+	waitStopped(t, c)
+	if s := c.snapshot(); s.StopReason != providerv1.CaptureStopMaxSize || s.SizeBytes < 1<<20 {
+		t.Errorf("snapshot() = %+v, want a stop at the maximum size", s)
+	}
+
+	idle := fakeTcpdump(t, `: > "$out"; trap 'exit 0' TERM; while true; do sleep 0.05; done`)
+	c, err = startCapture(idle, newCaptureState(t, 1, 1))
+	if err != nil {
+		t.Fatalf("startCapture() error = %v", err)
+	}
+	waitStopped(t, c)
+	if s := c.snapshot(); s.StopReason != providerv1.CaptureStopMaxDuration {
+		t.Errorf("snapshot() = %+v, want a stop at the maximum duration", s)
+	}
+}
+
+func TestStartCapture_Failures(t *testing.T) {
+	// tcpdump rejecting its arguments fails the start
+	failing := fakeTcpdump(t, `echo "tcpdump: syntax error in filter expression" >&2; exit 1`)
+	if _, err := startCapture(failing, newCaptureState(t, 1, 60)); err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("startCapture() error = %v, want the tcpdump output", err)
+	}
+
+	// tcpdump exiting later stops the capture with its output
+	exiting := fakeTcpdump(t, `: > "$out"; sleep 0.2; echo "tcpdump: device vnet0 went down" >&2; exit 1`)
+	c, err := startCapture(exiting, newCaptureState(t, 1, 60))
+	if err != nil {
+		t.Fatalf("startCapture() error = %v", err)
+	}
+	waitStopped(t, c)
+	if s := c.snapshot(); s.StopReason != providerv1.CaptureStopExited || !strings.Contains(s.Error, "went down") {
+		t.Errorf("snapshot() = %+v, want an exited capture with its error", s)
+	}
+}
+
+func TestNetworkCapture_Errors(t *testing.T) {
+	p := &Provider{networks: map[string]*providerv1.NetworkState{}}
+
+	result := p.NetworkCaptureStart(&providerv1.NetworkCaptureStartRequest{Network: "net"})
+	if result.Success || result.Error.Code != providerv1.ErrCodeInvalidSpec {
+		t.Errorf("NetworkCaptureStart() without outputDir = %+v, want invalid spec", result.Error)
+	}
+	result = p.NetworkCaptureStart(&providerv1.NetworkCaptureStartRequest{Network: "net", OutputDir: t.TempDir()})
+	if result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("NetworkCaptureStart() of an unknown network = %+v, want not found", result.Error)
+	}
+	result = p.NetworkCaptureStop(&providerv1.NetworkCaptureStopRequest{ID: "missing"})
+	if result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("NetworkCaptureStop() of an unknown capture = %+v, want not found", result.Error)
+	}
+}
+
+// waitStopped waits for a capture to stop by itself.
+func waitStopped(t *testing.T, c *capture) {
+	t.Helper()
+	select {
+	case <-c.done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("capture did not stop")
+	}
+	// The watcher records the reason after tcpdump exited
+	deadline := time.Now().Add(5 * time.Second)
+	for c.snapshot().Status != providerv1.CaptureStatusStopped && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// hostPrefix is a network found on the host, by the pre-flight check of
+// network creation.
+type hostPrefix struct {
+	// Source describes where the network was found, e.g. `interface "eth0"`.
+	Source string
+	Prefix netip.Prefix
+}
+
+// privateRanges are the ranges suggested CIDRs are taken from.
+var privateRanges = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+}
+
+// maxSuggestionCandidates bounds the CIDRs tried for a suggestion.
+const maxSuggestionCandidates = 1 << 16
+
+// checkNetworkConflict returns a NETWORK_CONFLICT error when cidr overlaps
+// the addresses of the interfaces of the host, its routes or the addresses
+// served by a dnsmasq running on it, which would leave the network silently
+// unreachable. It checks nothing when disabled or when libvirt runs on
+// another host.
+func (p *Provider) checkNetworkConflict(cidr string) *providerv1.OperationError {
+	if p.config.SkipNetworkPreflight || !isLocalURI(p.config.URI) {
+		return nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return providerv1.NewInvalidSpecError("invalid CIDR: " + err.Error())
+	}
+	host, err := hostPrefixes("/proc")
+	if err != nil {
+		return providerv1.NewProviderError("failed to inspect the networks of the host: "+err.Error(), false)
+	}
+	return networkConflict(prefix.Masked(), host)
+}
+
+// isLocalURI reports whether a libvirt URI connects to the local host.
+func isLocalURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Host == ""
+}
+
+// networkConflict returns the NETWORK_CONFLICT error of prefix overlapping
+// one of host, if any.
+func networkConflict(prefix netip.Prefix, host []hostPrefix) *providerv1.OperationError {
+	for _, h := range host {
+		if !h.Prefix.Overlaps(prefix) {
+			continue
+		}
+		suggested := ""
+		if s, ok := suggestCIDR(prefix, host); ok {
+			suggested = s.String()
+		}
+		return providerv1.NewNetworkConflictError(fmt.Sprintf("cidr %s overlaps %s of %s", prefix, h.Prefix, h.Source), suggested)
+	}
+	return nil
+}
+
+// suggestCIDR returns the first CIDR of the size of prefix, after it in the
+// private range holding it, that overlaps none of host.
+func suggestCIDR(prefix netip.Prefix, host []hostPrefix) (netip.Prefix, bool) {
+	if !prefix.Addr().Is4() {
+		return netip.Prefix{}, false
+	}
+	for _, r := range privateRanges {
+		if !r.Contains(prefix.Addr()) || prefix.Bits() < r.Bits() {
+			continue
+		}
+		base := ipv4ToUint(r.Addr())
+		size := uint32(1) << (32 - prefix.Bits())
+		count := uint64(1) << (prefix.Bits() - r.Bits())
+		first := uint64((ipv4ToUint(prefix.Addr()) - base) / size)
+		for i := uint64(1); i < count && i <= maxSuggestionCandidates; i++ {
+			index := (first + i) % count
+			candidate := netip.PrefixFrom(uintToIPv4(base+uint32(index)*size), prefix.Bits())
+			free := true
+			for _, h := range host {
+				if h.Prefix.Overlaps(candidate) {
+					free = false
+					break
+				}
+			}
+			if free {
+				return candidate, true
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// hostPrefixes returns the IPv4 networks of the interfaces of the host, its
+// routes and its dnsmasq instances, read from procDir.
+func hostPrefixes(procDir string) ([]hostPrefix, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var prefixes []hostPrefix
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+			addr, _ := netip.AddrFromSlice(ipNet.IP.To4())
+			ones, _ := ipNet.Mask.Size()
+			prefixes = append(prefixes, hostPrefix{
+				Source: fmt.Sprintf("interface %q", iface.Name),
+				Prefix: netip.PrefixFrom(addr, ones).Masked(),
+			})
+		}
+	}
+
+	f, err := os.Open(filepath.Join(procDir, "net", "route"))
+	switch {
+	case err == nil:
+		routes, err := parseRoutes(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, routes...)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	dnsmasq, err := dnsmasqPrefixes(procDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(prefixes, dnsmasq...), nil
+}
+
+// parseRoutes returns the routes of a /proc/net/route table but the default
+// route.
+func parseRoutes(r io.Reader) ([]hostPrefix, error) {
+	var routes []hostPrefix
+	scanner := bufio.NewScanner(r)
+	for first := true; scanner.Scan(); first = false {
+		fields := strings.Fields(scanner.Text())
+		// The first line holds the names of the columns
+		if first || len(fields) < 8 {
+			continue
+		}
+		dest, err1 := parseRouteAddr(fields[1])
+		mask, err2 := parseRouteAddr(fields[7])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid route %q", scanner.Text())
+		}
+		ones := bits.OnesCount32(ipv4ToUint(mask))
+		if ones == 0 {
+			continue
+		}
+		routes = append(routes, hostPrefix{
+			Source: fmt.Sprintf("a route via %q", fields[0]),
+			Prefix: netip.PrefixFrom(dest, ones).Masked(),
+		})
+	}
+	return routes, scanner.Err()
+}
+
+// parseRouteAddr parses an address of /proc/net/route, hex-encoded in host
+// byte order.
+func parseRouteAddr(s string) (netip.Addr, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", s)
+	}
+	var addr [4]byte
+	binary.BigEndian.PutUint32(addr[:], binary.LittleEndian.Uint32(b))
+	return netip.AddrFrom4(addr), nil
+}
+
+// dnsmasqPrefixes returns the addresses dnsmasq processes listen on or hand
+// out, from their command line and configuration file, as /32 prefixes. The
+// processes whose command line or configuration cannot be read are skipped.
+func dnsmasqPrefixes(procDir string) ([]hostPrefix, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var prefixes []hostPrefix
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		comm, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "comm"))
+		if err != nil || strings.TrimSpace(string(comm)) != "dnsmasq" {
+			continue
+		}
+		cmdline, err := os.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		var options []string
+		for _, arg := range strings.Split(string(cmdline), "\x00") {
+			if conf, ok := strings.CutPrefix(arg, "--conf-file="); ok {
+				data, err := os.ReadFile(conf)
+				if err != nil {
+					continue
+				}
+				options = append(options, strings.Split(string(data), "\n")...)
+				continue
+			}
+			options = append(options, strings.TrimPrefix(arg, "--"))
+		}
+		for _, addr := range dnsmasqAddrs(options) {
+			prefixes = append(prefixes, hostPrefix{
+				Source: fmt.Sprintf("dnsmasq (pid %d)", pid),
+				Prefix: netip.PrefixFrom(addr, 32),
+			})
+		}
+	}
+	return prefixes, nil
+}
+
+// dnsmasqAddrs returns the IPv4 addresses of the listen-address and
+// dhcp-range options of dnsmasq. The netmask of a range is returned too,
+// which overlaps no usable network.
+func dnsmasqAddrs(options []string) []netip.Addr {
+	var addrs []netip.Addr
+	for _, option := range options {
+		key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok || (key != "listen-address" && key != "dhcp-range") {
+			continue
+		}
+		for _, field := range strings.Split(value, ",") {
+			addr, err := netip.ParseAddr(strings.TrimSpace(field))
+			if err != nil || !addr.Is4() {
+				continue
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// ipv4ToUint returns an IPv4 address as an integer.
+func ipv4ToUint(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+// uintToIPv4 returns the IPv4 address of an integer.
+func uintToIPv4(n uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	return netip.AddrFrom4(b)
+}
@@ -0,0 +1,185 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestNetworkConflict(t *testing.T) {
+	host := []hostPrefix{
+		{Source: `interface "eth0"`, Prefix: netip.MustParsePrefix("192.168.1.0/24")},
+		{Source: `a route via "wg0"`, Prefix: netip.MustParsePrefix("192.168.2.0/23")},
+		{Source: "dnsmasq (pid 42)", Prefix: netip.MustParsePrefix("192.168.4.50/32")},
+	}
+	tests := []struct {
+		name          string
+		cidr          string
+		wantSubstr    string
+		wantSuggested string
+	}{
+		{name: "free cidr passes", cidr: "192.168.100.0/24"},
+		{name: "interface", cidr: "192.168.1.0/24", wantSubstr: `overlaps 192.168.1.0/24 of interface "eth0"`, wantSuggested: "192.168.5.0/24"},
+		{name: "enclosing cidr", cidr: "192.168.0.0/16", wantSubstr: `of interface "eth0"`},
+		{name: "route", cidr: "192.168.3.0/24", wantSubstr: `overlaps 192.168.2.0/23 of a route via "wg0"`, wantSuggested: "192.168.5.0/24"},
+		{name: "dnsmasq", cidr: "192.168.4.0/24", wantSubstr: "of dnsmasq (pid 42)", wantSuggested: "192.168.5.0/24"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := networkConflict(netip.MustParsePrefix(tt.cidr), host)
+			if tt.wantSubstr == "" {
+				if err != nil {
+					t.Errorf("networkConflict() = %+v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Code != providerv1.ErrCodeNetworkConflict || !strings.Contains(err.Message, tt.wantSubstr) {
+				t.Fatalf("networkConflict() = %+v, want a NETWORK_CONFLICT error containing %q", err, tt.wantSubstr)
+			}
+			if tt.wantSuggested != "" && err.Details[providerv1.DetailSuggestedCIDR] != tt.wantSuggested {
+				t.Errorf("suggested cidr = %v, want %s", err.Details[providerv1.DetailSuggestedCIDR], tt.wantSuggested)
+			}
+		})
+	}
+}
+
+func TestSuggestCIDR(t *testing.T) {
+	taken := func(cidrs ...string) []hostPrefix {
+		var host []hostPrefix
+		for _, c := range cidrs {
+			host = append(host, hostPrefix{Prefix: netip.MustParsePrefix(c)})
+		}
+		return host
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		host   []hostPrefix
+		want   string
+	}{
+		{name: "next block", prefix: "10.0.0.0/24", host: taken("10.0.0.0/24"), want: "10.0.1.0/24"},
+		{name: "wraps around", prefix: "192.168.255.0/24", host: taken("192.168.255.0/24", "192.168.0.0/24"), want: "192.168.1.0/24"},
+		{name: "larger block", prefix: "172.16.0.0/20", host: taken("172.16.0.0/16"), want: "172.17.0.0/20"},
+		{name: "public range", prefix: "8.8.8.0/24", host: taken("8.8.8.0/24")},
+		{name: "full range", prefix: "192.168.0.0/16", host: taken("192.168.0.0/16")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := suggestCIDR(netip.MustParsePrefix(tt.prefix), tt.host)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("suggestCIDR() = %s, want none", got)
+				}
+				return
+			}
+			if !ok || got.String() != tt.want {
+				t.Errorf("suggestCIDR() = %s, %v, want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	table := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t0101A8C0\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+		"eth0\t0001A8C0\t00000000\t0001\t0\t0\t100\t00FFFFFF\t0\t0\t0\n" +
+		"wg0\t0000000A\t00000000\t0001\t0\t0\t0\t0000FFFF\t0\t0\t0\n"
+	routes, err := parseRoutes(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("parseRoutes() error = %v", err)
+	}
+	want := []hostPrefix{
+		{Source: `a route via "eth0"`, Prefix: netip.MustParsePrefix("192.168.1.0/24")},
+		{Source: `a route via "wg0"`, Prefix: netip.MustParsePrefix("10.0.0.0/16")},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("parseRoutes() = %v, want %v", routes, want)
+	}
+
+	if _, err := parseRoutes(strings.NewReader(table + "eth1\tnothex\t00000000\t0001\t0\t0\t0\t00FFFFFF\n")); err == nil {
+		t.Error("parseRoutes() with an invalid route error = nil")
+	}
+}
+
+func TestDnsmasqPrefixes(t *testing.T) {
+	proc := t.TempDir()
+	conf := filepath.Join(t.TempDir(), "default.conf")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(conf, "strict-order\nlisten-address=192.168.122.1\ndhcp-range=192.168.122.2,192.168.122.254,255.255.255.0\n")
+	write(filepath.Join(proc, "100", "comm"), "dnsmasq\n")
+	write(filepath.Join(proc, "100", "cmdline"), "/usr/sbin/dnsmasq\x00--conf-file="+conf+"\x00")
+	write(filepath.Join(proc, "200", "comm"), "dnsmasq\n")
+	write(filepath.Join(proc, "200", "cmdline"), "dnsmasq\x00--listen-address=10.0.3.1\x00--dhcp-range=set:lan,10.0.3.50,10.0.3.99,12h\x00")
+	write(filepath.Join(proc, "300", "comm"), "sshd\n")
+	write(filepath.Join(proc, "300", "cmdline"), "sshd\x00--listen-address=172.16.0.1\x00")
+	write(filepath.Join(proc, "self", "comm"), "dnsmasq\n")
+
+	prefixes, err := dnsmasqPrefixes(proc)
+	if err != nil {
+		t.Fatalf("dnsmasqPrefixes() error = %v", err)
+	}
+	var got []string
+	for _, p := range prefixes {
+		got = append(got, p.Source+" "+p.Prefix.String())
+	}
+	want := []string{
+		"dnsmasq (pid 100) 192.168.122.1/32",
+		"dnsmasq (pid 100) 192.168.122.2/32",
+		"dnsmasq (pid 100) 192.168.122.254/32",
+		"dnsmasq (pid 100) 255.255.255.0/32",
+		"dnsmasq (pid 200) 10.0.3.1/32",
+		"dnsmasq (pid 200) 10.0.3.50/32",
+		"dnsmasq (pid 200) 10.0.3.99/32",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dnsmasqPrefixes() = %v, want %v", got, want)
+	}
+
+	if prefixes, err := dnsmasqPrefixes(filepath.Join(proc, "missing")); err != nil || prefixes != nil {
+		t.Errorf("dnsmasqPrefixes() without proc = %v, %v, want none", prefixes, err)
+	}
+}
+
+func TestCheckNetworkConflictSkipped(t *testing.T) {
+	for _, config := range []ProviderConfig{
+		{URI: "qemu:///system", SkipNetworkPreflight: true},
+		{URI: "qemu+ssh://root@lab/system"},
+	} {
+		p := &Provider{config: config}
+		if err := p.checkNetworkConflict("0.0.0.0/0"); err != nil {
+			t.Errorf("checkNetworkConflict() with %+v = %+v, want nil", config, err)
+		}
+	}
+	if !isLocalURI("qemu:///system") || isLocalURI("qemu+tcp://10.0.0.1/system") {
+		t.Error("isLocalURI() does not tell local URIs from remote ones")
+	}
+}
@@ -16,45 +16,18 @@ package libvirt
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
 )
 
-// createDisk creates a QCOW2 disk image.
-// If baseImage is provided, it creates a disk with the base image as a backing store.
-// If baseImage is empty, it creates a standalone disk.
-func createDisk(baseImage, outputPath, size, qemuImgPath string) error {
-	// Apply default size if not specified
-	if size == "" {
-		size = "20G"
-	}
-
-	var cmd *exec.Cmd
-	if baseImage != "" {
-		// Verify base image exists
-		if _, err := os.Stat(baseImage); err != nil {
-			return fmt.Errorf("base image not found: %s", baseImage)
-		}
-
-		// Create disk with backing store
-		cmd = exec.Command(qemuImgPath, "create",
-			"-f", "qcow2",
-			"-F", "qcow2",
-			"-b", baseImage,
-			outputPath,
-			size)
-	} else {
-		// Create standalone disk
-		cmd = exec.Command(qemuImgPath, "create",
-			"-f", "qcow2",
-			outputPath,
-			size)
-	}
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create disk: %w, output: %s", err, string(output))
-	}
+// dataDiskPath returns the path of the data disk at index of the VM whose
+// main disk is at diskPath, e.g. web.data1.qcow2 next to web.qcow2.
+func dataDiskPath(diskPath string, index int, format string) string {
+	return fmt.Sprintf("%s.data%d.%s", strings.TrimSuffix(diskPath, filepath.Ext(diskPath)), index+1, format)
+}
 
-	return nil
+// dataDiskTarget returns the guest device name of the data disk at index:
+// vdb for the first one, the main disk being vda.
+func dataDiskTarget(index int) string {
+	return "vd" + string(rune('b'+index))
 }
@@ -0,0 +1,31 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import "testing"
+
+func TestDataDiskPath(t *testing.T) {
+	if got := dataDiskPath("/envs/abc/disks/web.qcow2", 0, "qcow2"); got != "/envs/abc/disks/web.data1.qcow2" {
+		t.Errorf("dataDiskPath() = %q", got)
+	}
+	if got := dataDiskPath("/envs/abc/disks/web.qcow2", 2, "raw"); got != "/envs/abc/disks/web.data3.raw" {
+		t.Errorf("dataDiskPath() = %q", got)
+	}
+	if got := dataDiskTarget(1); got != "vdc" {
+		t.Errorf("dataDiskTarget(1) = %q, want vdc", got)
+	}
+}
@@ -15,12 +15,20 @@
 package libvirt
 
 import (
+	"crypto/rand"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/digitalocean/go-libvirt"
+
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/secrets"
 )
 
 // VMCreate creates a VM via libvirt.
@@ -58,9 +66,27 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 			return providerv1.ErrorResult(providerv1.NewNotFoundError("network", netName))
 		}
 	}
+	for _, mac := range req.Spec.MACAddresses {
+		if _, err := net.ParseMAC(mac); mac != "" && err != nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("invalid MAC address %q", mac)))
+		}
+	}
+	// A static IP is reserved by the orchestrator for the MAC address of the
+	// first NIC, which must therefore be known
+	if req.Spec.IP != "" {
+		if net.ParseIP(req.Spec.IP).To4() == nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("invalid static IP %q", req.Spec.IP)))
+		}
+		if len(req.Spec.MACAddresses) == 0 || req.Spec.MACAddresses[0] == "" {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("a static IP requires the MAC address of the first NIC"))
+		}
+	}
+	hostDevices, err := newHostDevices(req.Name, req.Spec)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+	}
 
 	// Track created resources for rollback
-	var diskPath, isoPath string
 	var cleanupFuncs []func()
 	defer func() {
 		// Execute cleanup in reverse order if we exit with an error
@@ -69,26 +95,92 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		}
 	}()
 
+	// Resolve the disk image and cloud-init ISO paths
+	diskPath, isoPath, err := p.vmFilePaths(req.Name, req.Labels)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+
 	// Create disk image
-	diskPath = filepath.Join(p.config.StateDir, "disks", req.Name+".qcow2")
 	baseImage := req.Spec.Disk.BaseImage
 	diskSize := req.Spec.Disk.Size
 	if diskSize == "" {
 		diskSize = "20G"
 	}
 
-	if err := createDisk(baseImage, diskPath, diskSize, p.config.QemuImgPath); err != nil {
+	// Resolve the LUKS passphrase, if any, before touching the disk
+	passphrase := ""
+	if enc := req.Spec.Disk.Encryption; enc != nil && enc.Enabled {
+		var err error
+		passphrase, err = secrets.Resolve(enc.PassphraseSecretRef)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("disk encryption: " + err.Error()))
+		}
+	}
+
+	// Encrypted disks are always qcow2 overlays: a clone would not be
+	// encrypted
+	diskBackend := common.DiskBackendQcow2
+	if passphrase != "" {
+		err = common.CreateEncryptedDisk(baseImage, diskPath, diskSize, passphrase, p.config.QemuImgPath)
+	} else {
+		diskBackend, err = common.CreateDisk(p.config.DiskBackend, baseImage, diskPath, diskSize, p.config.QemuImgPath)
+	}
+	if err != nil {
 		return providerv1.ErrorResult(providerv1.NewProviderError("failed to create disk: "+err.Error(), false))
 	}
 	cleanupFuncs = append(cleanupFuncs, func() { _ = os.Remove(diskPath) })
+	if err := common.LabelFile(diskPath, req.Labels); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+
+	// Create the data disks next to the main disk
+	var dataDisks []DataDisk
+	for i, d := range req.Spec.Disks {
+		disk := DataDisk{Format: common.DataDiskFormat(d), Target: dataDiskTarget(i)}
+		disk.Path = dataDiskPath(diskPath, i, disk.Format)
+		if err := common.CreateDataDisk(p.config.DiskBackend, d, disk.Path, p.config.QemuImgPath); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("failed to create data disk %d: %s", i+1, err.Error()), false))
+		}
+		cleanupFuncs = append(cleanupFuncs, func() { _ = os.Remove(disk.Path) })
+		if err := common.LabelFile(disk.Path, req.Labels); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+		}
+		dataDisks = append(dataDisks, disk)
+	}
+
+	// Register the passphrase with libvirt so QEMU can unlock the disk
+	diskSecretUUID := ""
+	if passphrase != "" {
+		uuid, err := p.defineDiskSecret(req.Name, diskPath, passphrase)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to define disk secret: "+err.Error(), false))
+		}
+		diskSecretUUID = uuid
+		cleanupFuncs = append(cleanupFuncs, func() { p.undefineDiskSecret(diskPath) })
+	}
+
+	// The NICs configured in the guest are matched by MAC address
+	if len(req.Spec.NICs) > 0 || common.MatchesNICs(req.Spec.CloudInit) {
+		macs := make([]string, len(networkNames))
+		copy(macs, req.Spec.MACAddresses)
+		for i := range macs {
+			if macs[i] == "" {
+				macs[i] = randomMAC()
+			}
+		}
+		req.Spec.MACAddresses = macs
+	}
 
 	// Generate cloud-init ISO
-	isoPath = filepath.Join(p.config.StateDir, "cloudinit", req.Name+".iso")
-	ciConfig := cloudInitConfigFromVMSpec(req.Name, &req.Spec, p.keys)
-	if err := generateCloudInitISO(ciConfig, isoPath, p.config.ISOTool); err != nil {
+	ciConfig, err := common.GenerateCloudInitSeed(req.Name, &req.Spec, p.keys, isoPath, p.config.ISOTool, req.Labels)
+	if err != nil {
 		return providerv1.ErrorResult(providerv1.NewProviderError("failed to generate cloud-init ISO: "+err.Error(), false))
 	}
 	cleanupFuncs = append(cleanupFuncs, func() { _ = os.Remove(isoPath) })
+	if err := common.LabelFile(isoPath, req.Labels); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
 
 	// Build domain config
 	memoryMB := 2048
@@ -109,17 +201,36 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		}
 	}
 
-	// Build NetworkInterface list. Only the first NIC gets PXE ROM.
+	// Build NetworkInterface list. Only the first NIC gets PXE ROM. NICs
+	// without a requested MAC address get one from libvirt.
 	nics := make([]NetworkInterface, len(networkNames))
 	for i, netName := range networkNames {
 		nics[i] = NetworkInterface{
 			Name:           netName,
 			HasNetworkBoot: hasNetworkBoot && i == 0,
 		}
+		if i < len(req.Spec.MACAddresses) {
+			nics[i].MAC = req.Spec.MACAddresses[i]
+		}
+		if i < len(req.Spec.NICs) {
+			nics[i].Model = req.Spec.NICs[i].Model
+			// libvirt only sets the MTU of virtio NICs
+			if nics[i].Model == "" || nics[i].Model == "virtio" {
+				nics[i].MTU = req.Spec.NICs[i].MTU
+			}
+		}
 	}
 
+	// Mediated devices given by type exist before the domain using them
+	mdevs, err := createMdevs(req.Name, req.Spec.Mdevs)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	cleanupFuncs = append(cleanupFuncs, func() { removeMdevs(mdevs) })
+
 	domainConfig := DomainConfig{
 		Name:         req.Name,
+		UUID:         req.Spec.UUID,
 		MemoryMB:     memoryMB,
 		VCPU:         vcpu,
 		DiskPath:     diskPath,
@@ -127,6 +238,13 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		Networks:     nics,
 		BootOrder:    req.Spec.Boot.Order,
 		Firmware:     req.Spec.Boot.Firmware,
+		Security:     newSecurityLabel(req.Spec.Security),
+		Vsock:        newVsockDevice(req.Spec.Vsock),
+		DataDisks:    dataDisks,
+		HostDevices:  hostDevices,
+
+		DiskSecretUUID: diskSecretUUID,
+		Labels:         common.SortedLabels(req.Labels),
 	}
 
 	// Generate domain XML
@@ -192,29 +310,36 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		// Best-effort: log and continue without boot verification
 	}
 
-	// Resolve IP for the first NIC (primary) using remaining budget
-	remaining := ipTimeout - time.Since(bootStart)
-	if remaining < 30*time.Second {
-		remaining = 30 * time.Second // minimum 30s for DHCP
-	}
-	ip, err := resolveIP(p.conn, networkNames[0], mac, remaining)
+	var ip string
+	if req.Spec.IP != "" {
+		// A static IP is reserved in the network: there is no lease to wait for
+		ip, err = req.Spec.IP, nil
+	} else {
+		// Resolve IP for the first NIC (primary) using remaining budget
+		remaining := ipTimeout - time.Since(bootStart)
+		if remaining < 30*time.Second {
+			remaining = 30 * time.Second // minimum 30s for DHCP
+		}
+		ip, err = resolveIP(p.conn, networkNames[0], p.netbootLeaseFile(networkNames[0]), mac, remaining)
 
-	// Fallback: try ARP resolution for VMs with static IPs (no DHCP lease)
-	if err != nil || ip == "" {
-		if arpIP := resolveIPFromARP(p.conn, dom); arpIP != "" {
-			ip = arpIP
-			err = nil
+		// Fallback: try ARP resolution for VMs with static IPs (no DHCP lease)
+		if err != nil || ip == "" {
+			if arpIP := resolveIPFromARP(p.conn, dom); arpIP != "" {
+				ip = arpIP
+				err = nil
+			}
 		}
-	}
 
-	// Fallback: extract static IP from CloudInit networkConfig
-	if (err != nil || ip == "") && req.Spec.CloudInit != nil {
-		if staticIP := extractStaticIP(req.Spec.CloudInit); staticIP != "" {
-			ip = staticIP
-			err = nil
+		// Fallback: extract static IP from CloudInit networkConfig
+		if (err != nil || ip == "") && req.Spec.CloudInit != nil {
+			if staticIP := common.ExtractStaticIP(req.Spec.CloudInit); staticIP != "" {
+				ip = staticIP
+				err = nil
+			}
 		}
 	}
 
+	var hostKeys []string
 	if sshReadiness {
 		if err != nil || ip == "" {
 			return providerv1.ErrorResult(providerv1.NewTimeoutError("ip-resolution"))
@@ -223,17 +348,19 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 			return providerv1.ErrorResult(providerv1.NewProviderError(
 				fmt.Sprintf("VM %s: resolved empty IP without error", req.Name), true))
 		}
-		// Validate IP reachability via TCP probe to SSH port
-		if err := validateIPReachability(ip, 22, 10*time.Second); err != nil {
+		// Validate IP reachability via TCP probe to SSH port. The guest may
+		// not have its static IP yet; the readiness checks below wait for it
+		if err := validateIPReachability(ip, 22, 10*time.Second); err != nil && req.Spec.IP == "" {
 			return providerv1.ErrorResult(providerv1.NewProviderError(
 				fmt.Sprintf("VM %s IP %s not reachable: %s", req.Name, ip, err.Error()), true))
 		}
 
 		// Run SSH and cloud-init readiness checks if configured
 		if req.Spec.Readiness != nil {
-			if opErr := waitForReadiness(req.Spec.Readiness, ip); opErr != nil {
+			if opErr := common.WaitForReadiness(req.Spec.Readiness, ip); opErr != nil {
 				return providerv1.ErrorResult(opErr)
 			}
+			hostKeys = common.CollectHostKeys(req.Spec.Readiness, ip)
 		}
 	} else {
 		// Best-effort: use resolved IP if available, empty string otherwise
@@ -254,7 +381,7 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		if nicMAC == "" {
 			continue
 		}
-		nicIP, nicErr := resolveIP(p.conn, netName, nicMAC, 5*time.Second)
+		nicIP, nicErr := resolveIP(p.conn, netName, p.netbootLeaseFile(netName), nicMAC, 5*time.Second)
 		if nicErr == nil && nicIP != "" {
 			ipsByNet[netName] = nicIP
 		}
@@ -285,6 +412,8 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		UUID:       formatUUID(dom.UUID),
 		SSHCommand: sshCommand,
 		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		HostKeys:   hostKeys,
+		Labels:     req.Labels,
 		ProviderState: map[string]any{
 			"diskPath":     diskPath,
 			"cloudInitISO": isoPath,
@@ -292,6 +421,32 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 			"keys":         ciConfig.MatchedKeyNames,
 		},
 	}
+	if diskSecretUUID != "" {
+		state.ProviderState["diskSecretUUID"] = diskSecretUUID
+	}
+	if diskBackend == common.DiskBackendReflink {
+		state.ProviderState["diskBackend"] = string(diskBackend)
+	}
+	if len(dataDisks) > 0 {
+		paths := make([]string, len(dataDisks))
+		for i, d := range dataDisks {
+			paths[i] = d.Path
+		}
+		state.ProviderState["dataDisks"] = paths
+	}
+	if len(mdevs) > 0 {
+		state.ProviderState["mdevs"] = mdevs
+	}
+	// The live XML holds the CID libvirt assigned
+	if req.Spec.Vsock != nil {
+		state.VsockCID = req.Spec.Vsock.CID
+		if cid, err := parseVsockCID(xmlDesc); err == nil && cid != 0 {
+			state.VsockCID = cid
+		}
+	}
+	if len(hostKeys) > 0 {
+		state.HostKeyFingerprints = common.HostKeyFingerprints(hostKeys)
+	}
 
 	p.vms[req.Name] = state
 	return providerv1.SuccessResult(state)
@@ -310,14 +465,26 @@ func (p *Provider) VMGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(vm)
 }
 
-// VMList lists all VMs.
+// VMList lists all VMs, or all domains of libvirt with the host filter,
+// restricted to those carrying the labels of the labels filter.
 func (p *Provider) VMList(filter map[string]any) *providerv1.OperationResult {
+	if listHost(filter) {
+		vms, err := p.hostVMs(providerv1.LabelSelector(filter))
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to list domains: "+err.Error(), true))
+		}
+		return providerv1.SuccessResult(vms)
+	}
+
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	vms := make([]*providerv1.VMState, 0, len(p.vms))
 	for _, vm := range p.vms {
-		vms = append(vms, vm)
+		if providerv1.MatchLabels(selector, vm.Labels) {
+			vms = append(vms, vm)
+		}
 	}
 
 	return providerv1.SuccessResult(vms)
@@ -347,28 +514,42 @@ func (p *Provider) VMDelete(name string) *providerv1.OperationResult {
 		deleted = true
 	}
 
-	// Clean up disk file if we have state
+	// Clean up disk files and cloud-init ISO if we have state
 	if vm != nil {
-		if diskPath, ok := vm.ProviderState["diskPath"].(string); ok {
-			_ = os.Remove(diskPath)
-		}
-
-		// Clean up cloud-init ISO
-		if isoPath, ok := vm.ProviderState["cloudInitISO"].(string); ok {
-			_ = os.Remove(isoPath)
-		}
+		p.removeVMFiles(vm)
+		removeMdevs(providerStateStrings(vm, "mdevs"))
 	}
 
 	// Also try to clean up files by convention if no state exists
 	// This handles cases where state was lost but files remain
 	if vm == nil {
-		diskPath := filepath.Join(p.config.StateDir, "disks", name+".qcow2")
-		_ = os.Remove(diskPath)
-
-		isoPath := filepath.Join(p.config.StateDir, "cloudinit", name+".iso")
-		_ = os.Remove(isoPath)
+		diskPaths := []string{filepath.Join(p.config.StateDir, "disks", name+".qcow2")}
+		isoPaths := []string{filepath.Join(p.config.StateDir, "cloudinit", name+".iso")}
+		layout := paths.New(p.config.StateDir)
+		if envIDs, err := layout.Environments(); err == nil {
+			for _, envID := range envIDs {
+				diskPaths = append(diskPaths, layout.Env(envID).Disk(name))
+				isoPaths = append(isoPaths, layout.Env(envID).CloudInitISO(name))
+			}
+		}
+		for _, diskPath := range diskPaths {
+			if _, err := os.Stat(diskPath); err == nil {
+				_ = os.Remove(diskPath)
+				p.undefineDiskSecret(diskPath)
+			}
+			dataDisks, _ := filepath.Glob(strings.TrimSuffix(diskPath, ".qcow2") + ".data*")
+			for _, dataDisk := range dataDisks {
+				_ = os.Remove(dataDisk)
+			}
+		}
+		for _, isoPath := range isoPaths {
+			_ = os.Remove(isoPath)
+		}
+		removeMdevs(orphanMdevs(name))
 	}
 
+	_ = os.Remove(p.savePath(name))
+	_ = os.Remove(p.stoppedPath(name))
 	delete(p.vms, name)
 
 	// Return success if we deleted anything or if nothing existed
@@ -380,3 +561,71 @@ func (p *Provider) VMDelete(name string) *providerv1.OperationResult {
 	// If nothing was found anywhere, still return success (idempotent)
 	return providerv1.SuccessResult(nil)
 }
+
+// defineDiskSecret registers a private libvirt volume secret holding the LUKS
+// passphrase for diskPath and returns its UUID. Any stale secret left for the
+// same volume by a previous run is removed first.
+func (p *Provider) defineDiskSecret(vmName, diskPath, passphrase string) (string, error) {
+	p.undefineDiskSecret(diskPath)
+
+	secretXML, err := generateSecretXML(SecretConfig{
+		Description: "testenv-vm disk passphrase for " + vmName,
+		VolumePath:  diskPath,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := p.conn.SecretDefineXML(secretXML, 0)
+	if err != nil {
+		return "", err
+	}
+	if err := p.conn.SecretSetValue(secret, []byte(passphrase), 0); err != nil {
+		_ = p.conn.SecretUndefine(secret)
+		return "", err
+	}
+
+	return formatUUID(secret.UUID), nil
+}
+
+// vmFiles returns the disks and cloud-init ISO recorded in the provider
+// state of a VM.
+func vmFiles(vm *providerv1.VMState) []string {
+	var files []string
+	if diskPath, ok := vm.ProviderState["diskPath"].(string); ok {
+		files = append(files, diskPath)
+	}
+	files = append(files, frozenDisks(vm)...)
+	files = append(files, providerStateStrings(vm, "dataDisks")...)
+	if isoPath, ok := vm.ProviderState["cloudInitISO"].(string); ok {
+		files = append(files, isoPath)
+	}
+	return files
+}
+
+// removeVMFiles removes the disks and cloud-init ISO of a VM, and the
+// libvirt secret of its encrypted disk.
+func (p *Provider) removeVMFiles(vm *providerv1.VMState) {
+	for _, file := range vmFiles(vm) {
+		_ = os.Remove(file)
+	}
+	if diskPath, ok := vm.ProviderState["diskPath"].(string); ok {
+		p.undefineDiskSecret(diskPath)
+	}
+}
+
+// undefineDiskSecret removes the volume secret for diskPath, if any.
+func (p *Provider) undefineDiskSecret(diskPath string) {
+	secret, err := p.conn.SecretLookupByUsage(int32(libvirt.SecretUsageTypeVolume), diskPath)
+	if err != nil {
+		return
+	}
+	_ = p.conn.SecretUndefine(secret)
+}
+
+// randomMAC returns a random MAC address in the range libvirt uses.
+func randomMAC() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2])
+}
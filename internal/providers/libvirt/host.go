@@ -0,0 +1,136 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+// hostStatus is the status of the resources found on the host that the
+// provider does not track.
+const hostStatus = "unknown"
+
+// listHost reports whether a list filter asks for the resources of the host
+// (see providerv1.FilterHost).
+func listHost(filter map[string]any) bool {
+	host, _ := filter[providerv1.FilterHost].(bool)
+	return host
+}
+
+// hostKeys returns the keys found in the keys directory of the state
+// directory, with the testenv-vm labels of their public key file, that carry
+// the labels of selector. Keys written to an output directory are only
+// listed while tracked.
+func (p *Provider) hostKeys(selector map[string]string) ([]*providerv1.KeyState, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	dir := filepath.Join(p.config.StateDir, "keys")
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	keys := make([]*providerv1.KeyState, 0, len(entries)+len(p.keys))
+	for _, key := range p.keys {
+		if providerv1.MatchLabels(selector, key.Labels) {
+			keys = append(keys, key)
+		}
+	}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".pub")
+		if !ok || entry.IsDir() || p.keys[name] != nil {
+			continue
+		}
+		key := &providerv1.KeyState{
+			Name:           name,
+			PublicKeyPath:  filepath.Join(dir, entry.Name()),
+			PrivateKeyPath: filepath.Join(dir, name),
+		}
+		if labels, err := common.ReadFileLabels(key.PublicKeyPath); err == nil && len(labels) > 0 {
+			key.Labels = labels
+		}
+		if providerv1.MatchLabels(selector, key.Labels) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// hostNetworks returns the networks defined in libvirt, with the labels
+// recorded in their metadata, that carry the labels of selector.
+func (p *Provider) hostNetworks(selector map[string]string) ([]*providerv1.NetworkState, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	nets, _, err := p.conn.ConnectListAllNetworks(1, 0)
+	if err != nil {
+		return nil, err
+	}
+	networks := make([]*providerv1.NetworkState, 0, len(nets))
+	for _, n := range nets {
+		network, ok := p.networks[n.Name]
+		if !ok {
+			network = &providerv1.NetworkState{
+				Name:   n.Name,
+				Status: hostStatus,
+				UUID:   formatUUID(n.UUID),
+			}
+			if networkXML, err := p.conn.NetworkGetXMLDesc(n, 0); err == nil {
+				network.Labels = parseLabels(networkXML)
+			}
+		}
+		if providerv1.MatchLabels(selector, network.Labels) {
+			networks = append(networks, network)
+		}
+	}
+	return networks, nil
+}
+
+// hostVMs returns the domains defined in libvirt, with the labels recorded
+// in their metadata, that carry the labels of selector.
+func (p *Provider) hostVMs(selector map[string]string) ([]*providerv1.VMState, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	doms, _, err := p.conn.ConnectListAllDomains(1, 0)
+	if err != nil {
+		return nil, err
+	}
+	vms := make([]*providerv1.VMState, 0, len(doms))
+	for _, dom := range doms {
+		vm, ok := p.vms[dom.Name]
+		if !ok {
+			vm = &providerv1.VMState{
+				Name:   dom.Name,
+				Status: hostStatus,
+				UUID:   formatUUID(dom.UUID),
+			}
+			if domainXML, err := p.conn.DomainGetXMLDesc(dom, libvirt.DomainXMLInactive); err == nil {
+				vm.Labels = parseLabels(domainXML)
+			}
+		}
+		if providerv1.MatchLabels(selector, vm.Labels) {
+			vms = append(vms, vm)
+		}
+	}
+	return vms, nil
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// sysfsRoot is where sysfs is mounted, replaced by tests.
+var sysfsRoot = "/sys"
+
+// pciAddressPattern matches a PCI address, with or without its domain.
+var pciAddressPattern = regexp.MustCompile(`^(?:([0-9a-fA-F]{4}):)?([0-9a-fA-F]{2}):([0-9a-fA-F]{2})\.([0-7])$`)
+
+// mdevUUIDPattern matches the UUID of a mediated device.
+var mdevUUIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// HostDevice describes a <hostdev> element of a domain: a host PCI device
+// or a mediated device.
+type HostDevice struct {
+	// PCI is the address of a host PCI device. Nil means a mediated device.
+	PCI *PCIAddress
+	// MdevUUID is the UUID of a mediated device.
+	MdevUUID string
+}
+
+// PCIAddress is a PCI address, in the hexadecimal fields of libvirt.
+type PCIAddress struct {
+	Domain   string
+	Bus      string
+	Slot     string
+	Function string
+}
+
+// parsePCIAddress parses a PCI address such as "0000:01:00.0" or "01:00.0".
+func parsePCIAddress(s string) (*PCIAddress, error) {
+	m := pciAddressPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid PCI address %q", s)
+	}
+	domain := m[1]
+	if domain == "" {
+		domain = "0000"
+	}
+	return &PCIAddress{
+		Domain:   strings.ToLower(domain),
+		Bus:      strings.ToLower(m[2]),
+		Slot:     strings.ToLower(m[3]),
+		Function: m[4],
+	}, nil
+}
+
+// String returns the address in sysfs form, e.g. "0000:01:00.0".
+func (a *PCIAddress) String() string {
+	return fmt.Sprintf("%s:%s:%s.%s", a.Domain, a.Bus, a.Slot, a.Function)
+}
+
+// newHostDevices converts the PCI devices and mediated devices of a VM into
+// HostDevices. Mediated devices created from a type get the UUID mdevUUID
+// gives them, so that they are found again when the VM is deleted.
+func newHostDevices(vmName string, spec providerv1.VMSpec) ([]HostDevice, error) {
+	var devices []HostDevice
+	for _, addr := range spec.PCIPassthrough {
+		pci, err := parsePCIAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, HostDevice{PCI: pci})
+	}
+	for i, m := range spec.Mdevs {
+		switch {
+		case m.UUID != "" && m.Type != "":
+			return nil, fmt.Errorf("mdev %d: uuid and type are mutually exclusive", i)
+		case m.UUID != "":
+			if !mdevUUIDPattern.MatchString(m.UUID) {
+				return nil, fmt.Errorf("mdev %d: invalid uuid %q", i, m.UUID)
+			}
+			devices = append(devices, HostDevice{MdevUUID: strings.ToLower(m.UUID)})
+		case m.Type != "":
+			if m.Parent != "" {
+				if _, err := parsePCIAddress(m.Parent); err != nil {
+					return nil, fmt.Errorf("mdev %d: %w", i, err)
+				}
+			}
+			devices = append(devices, HostDevice{MdevUUID: mdevUUID(vmName, i)})
+		default:
+			return nil, fmt.Errorf("mdev %d: uuid or type is required", i)
+		}
+	}
+	return devices, nil
+}
+
+// mdevUUID returns the UUID of the mediated device the provider creates for
+// the index-th mdev of a VM.
+func mdevUUID(vmName string, index int) string {
+	sum := sha256.Sum256([]byte("testenv-vm/mdev/" + vmName + "/" + strconv.Itoa(index)))
+	// Version 4 and RFC 4122 variant bits
+	sum[6] = sum[6]&0x0f | 0x40
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// createMdevs creates the mediated devices of a VM given by type and returns
+// the UUIDs of those it created. A device left by a previous run of the VM is
+// reused. Devices created before a failure are removed.
+func createMdevs(vmName string, mdevs []providerv1.MdevSpec) ([]string, error) {
+	var created []string
+	for i, m := range mdevs {
+		if m.Type == "" {
+			continue
+		}
+		uuid := mdevUUID(vmName, i)
+		if err := createMdev(uuid, m.Type, m.Parent); err != nil {
+			removeMdevs(created)
+			return nil, fmt.Errorf("mdev %d: %w", i, err)
+		}
+		created = append(created, uuid)
+	}
+	return created, nil
+}
+
+// createMdev creates the mediated device uuid of mdevType on parent, or on
+// the first parent with an available instance of mdevType, unless it exists.
+func createMdev(uuid, mdevType, parent string) error {
+	if _, err := os.Stat(filepath.Join(sysfsRoot, "bus", "mdev", "devices", uuid)); err == nil {
+		return nil
+	}
+	if parent == "" {
+		var err error
+		if parent, err = findMdevParent(mdevType); err != nil {
+			return err
+		}
+	} else {
+		pci, err := parsePCIAddress(parent)
+		if err != nil {
+			return err
+		}
+		parent = pci.String()
+	}
+	create := filepath.Join(sysfsRoot, "class", "mdev_bus", parent, "mdev_supported_types", mdevType, "create")
+	if err := writeSysfs(create, uuid); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("device %s does not support mdev type %q", parent, mdevType)
+		}
+		return fmt.Errorf("failed to create mdev of type %q on %s: %w", mdevType, parent, err)
+	}
+	return nil
+}
+
+// findMdevParent returns the first device, in address order, with an
+// available instance of mdevType.
+func findMdevParent(mdevType string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(sysfsRoot, "class", "mdev_bus", "*", "mdev_supported_types", mdevType, "available_instances"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(matches)
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && n > 0 {
+			return filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(path)))), nil
+		}
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no device supports mdev type %q", mdevType)
+	}
+	return "", fmt.Errorf("no available instance of mdev type %q", mdevType)
+}
+
+// removeMdevs removes mediated devices, ignoring those already gone.
+func removeMdevs(uuids []string) {
+	for _, uuid := range uuids {
+		_ = writeSysfs(filepath.Join(sysfsRoot, "bus", "mdev", "devices", uuid, "remove"), "1")
+	}
+}
+
+// orphanMdevs returns the mediated devices created for a VM that are still
+// on the host, for VMs deleted without state.
+func orphanMdevs(vmName string) []string {
+	entries, err := os.ReadDir(filepath.Join(sysfsRoot, "bus", "mdev", "devices"))
+	if err != nil {
+		return nil
+	}
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		present[e.Name()] = true
+	}
+	// A VM has at most as many mdevs as the host
+	var uuids []string
+	for i := range entries {
+		if uuid := mdevUUID(vmName, i); present[uuid] {
+			uuids = append(uuids, uuid)
+		}
+	}
+	return uuids
+}
+
+// writeSysfs writes value to an existing sysfs attribute.
+func writeSysfs(path, value string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(value); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
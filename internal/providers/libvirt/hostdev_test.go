@@ -0,0 +1,173 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestNewHostDevices(t *testing.T) {
+	devices, err := newHostDevices("gpu", providerv1.VMSpec{
+		PCIPassthrough: []string{"0000:3B:00.0", "02:00.1"},
+		Mdevs:          []providerv1.MdevSpec{{UUID: "4B20D080-1B54-4048-85B3-A6A62D165C01"}, {Type: "nvidia-63"}},
+	})
+	if err != nil {
+		t.Fatalf("newHostDevices() error = %v", err)
+	}
+	want := []HostDevice{
+		{PCI: &PCIAddress{Domain: "0000", Bus: "3b", Slot: "00", Function: "0"}},
+		{PCI: &PCIAddress{Domain: "0000", Bus: "02", Slot: "00", Function: "1"}},
+		{MdevUUID: "4b20d080-1b54-4048-85b3-a6a62d165c01"},
+		{MdevUUID: mdevUUID("gpu", 1)},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("newHostDevices() = %+v, want %+v", devices, want)
+	}
+
+	for _, spec := range []providerv1.VMSpec{
+		{PCIPassthrough: []string{"3b:00"}},
+		{Mdevs: []providerv1.MdevSpec{{}}},
+		{Mdevs: []providerv1.MdevSpec{{UUID: "slice"}}},
+		{Mdevs: []providerv1.MdevSpec{{UUID: "4b20d080-1b54-4048-85b3-a6a62d165c01", Type: "nvidia-63"}}},
+		{Mdevs: []providerv1.MdevSpec{{Type: "nvidia-63", Parent: "gpu0"}}},
+	} {
+		if _, err := newHostDevices("gpu", spec); err == nil {
+			t.Errorf("newHostDevices(%+v) error = nil", spec)
+		}
+	}
+}
+
+func TestMdevUUID(t *testing.T) {
+	uuid := mdevUUID("gpu", 0)
+	if !mdevUUIDPattern.MatchString(uuid) || uuid[14] != '4' {
+		t.Errorf("mdevUUID() = %q, want a version 4 UUID", uuid)
+	}
+	if uuid != mdevUUID("gpu", 0) || uuid == mdevUUID("gpu", 1) || uuid == mdevUUID("gpu2", 0) {
+		t.Error("mdevUUID() is not unique and stable per VM and index")
+	}
+}
+
+// fakeMdevSysfs replaces sysfsRoot by a directory where parent devices
+// offer mdev types with a number of available instances. Writing to their
+// create attribute adds the device, as the kernel does.
+func fakeMdevSysfs(t *testing.T, types map[string]string) string {
+	t.Helper()
+	old := sysfsRoot
+	sysfsRoot = t.TempDir()
+	t.Cleanup(func() { sysfsRoot = old })
+	if err := os.MkdirAll(filepath.Join(sysfsRoot, "bus", "mdev", "devices"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for key, available := range types {
+		parent, mdevType, _ := strings.Cut(key, "/")
+		dir := filepath.Join(sysfsRoot, "class", "mdev_bus", parent, "mdev_supported_types", mdevType)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "available_instances"), []byte(available+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "create"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sysfsRoot
+}
+
+// created returns the UUID written to the create attribute of a type.
+func created(t *testing.T, root, parent, mdevType string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, "class", "mdev_bus", parent, "mdev_supported_types", mdevType, "create"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateMdevs(t *testing.T) {
+	root := fakeMdevSysfs(t, map[string]string{
+		"0000:3b:00.0/nvidia-63": "0",
+		"0000:af:00.0/nvidia-63": "2",
+		"0000:af:00.0/nvidia-64": "1",
+	})
+
+	uuids, err := createMdevs("gpu", []providerv1.MdevSpec{
+		{UUID: "4b20d080-1b54-4048-85b3-a6a62d165c01"},
+		{Type: "nvidia-63"},
+		{Type: "nvidia-64", Parent: "af:00.0"},
+	})
+	if err != nil {
+		t.Fatalf("createMdevs() error = %v", err)
+	}
+	if want := []string{mdevUUID("gpu", 1), mdevUUID("gpu", 2)}; !reflect.DeepEqual(uuids, want) {
+		t.Errorf("createMdevs() = %v, want %v", uuids, want)
+	}
+	if got := created(t, root, "0000:af:00.0", "nvidia-63"); got != mdevUUID("gpu", 1) {
+		t.Errorf("nvidia-63 created %q on 0000:af:00.0, want %q", got, mdevUUID("gpu", 1))
+	}
+	if got := created(t, root, "0000:3b:00.0", "nvidia-63"); got != "" {
+		t.Errorf("nvidia-63 created %q on the parent without available instances", got)
+	}
+	if got := created(t, root, "0000:af:00.0", "nvidia-64"); got != mdevUUID("gpu", 2) {
+		t.Errorf("nvidia-64 created %q, want %q", got, mdevUUID("gpu", 2))
+	}
+
+	for _, mdevs := range [][]providerv1.MdevSpec{
+		{{Type: "nvidia-65"}},
+		{{Type: "nvidia-63", Parent: "0000:3b:00.1"}},
+	} {
+		if _, err := createMdevs("other", mdevs); err == nil {
+			t.Errorf("createMdevs(%+v) error = nil", mdevs)
+		}
+	}
+}
+
+func TestCreateMdevsReusesAndRemoves(t *testing.T) {
+	root := fakeMdevSysfs(t, map[string]string{"0000:af:00.0/nvidia-63": "0"})
+
+	// A device left by a previous run is reused, without an instance
+	uuid := mdevUUID("gpu", 0)
+	device := filepath.Join(root, "bus", "mdev", "devices", uuid)
+	if err := os.MkdirAll(device, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(device, "remove"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	uuids, err := createMdevs("gpu", []providerv1.MdevSpec{{Type: "nvidia-63"}})
+	if err != nil || !reflect.DeepEqual(uuids, []string{uuid}) {
+		t.Fatalf("createMdevs() = %v, %v, want the existing device", uuids, err)
+	}
+
+	if got := orphanMdevs("gpu"); !reflect.DeepEqual(got, []string{uuid}) {
+		t.Errorf("orphanMdevs() = %v, want %v", got, []string{uuid})
+	}
+	if got := orphanMdevs("other"); got != nil {
+		t.Errorf("orphanMdevs() of another vm = %v, want none", got)
+	}
+
+	removeMdevs([]string{uuid, mdevUUID("gone", 0)})
+	if data, err := os.ReadFile(filepath.Join(device, "remove")); err != nil || string(data) != "1" {
+		t.Errorf("remove attribute = %q, %v, want 1", data, err)
+	}
+}
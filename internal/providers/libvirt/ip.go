@@ -19,13 +19,14 @@ import (
 	"strings"
 	"time"
 
-	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/netboot"
 	"github.com/digitalocean/go-libvirt"
 )
 
-// resolveIP attempts to resolve the IP address for a VM by polling DHCP leases.
+// resolveIP attempts to resolve the IP address for a VM by polling DHCP leases,
+// those of libvirt and those of the built-in DHCP server in leaseFile.
 // It returns an error if the IP cannot be resolved within the timeout.
-func resolveIP(conn *libvirt.Libvirt, networkName, macAddress string, timeout time.Duration) (string, error) {
+func resolveIP(conn *libvirt.Libvirt, networkName, leaseFile, macAddress string, timeout time.Duration) (string, error) {
 	// Look up the network
 	net, err := conn.NetworkLookupByName(networkName)
 	if err != nil {
@@ -53,6 +54,11 @@ func resolveIP(conn *libvirt.Libvirt, networkName, macAddress string, timeout ti
 			}
 		}
 
+		// Strategy 2: Check the leases of the built-in DHCP server
+		if ip, err := netboot.LeaseOf(leaseFile, macAddress, time.Now()); err == nil && ip != "" {
+			return ip, nil
+		}
+
 		time.Sleep(pollInterval)
 	}
 
@@ -78,26 +84,6 @@ func resolveIPFromARP(conn *libvirt.Libvirt, dom libvirt.Domain) string {
 	return ""
 }
 
-// extractStaticIP extracts the first static IP address from CloudInit network
-// configuration. This is used as a last-resort fallback when neither DHCP leases
-// nor ARP resolution can provide the IP (e.g., the VM hasn't generated any
-// network traffic yet but has a known static IP).
-func extractStaticIP(ci *providerv1.CloudInitSpec) string {
-	if ci == nil || ci.NetworkConfig == nil {
-		return ""
-	}
-	for _, eth := range ci.NetworkConfig.Ethernets {
-		for _, addr := range eth.Addresses {
-			// Addresses are in CIDR notation (e.g., "192.168.100.10/24")
-			ip, _, _ := strings.Cut(addr, "/")
-			if ip != "" {
-				return ip
-			}
-		}
-	}
-	return ""
-}
-
 // extractAllMACsFromDomainXML extracts all MAC addresses from a domain's XML.
 // Returns MACs in interface order, which matches the NIC attachment order.
 func extractAllMACsFromDomainXML(xml string) []string {
@@ -15,10 +15,6 @@
 package libvirt
 
 import (
-	"crypto/ed25519"
-	"crypto/rand"
-	"crypto/rsa"
-	"encoding/pem"
 	"os"
 	"path/filepath"
 	"time"
@@ -26,6 +22,7 @@ import (
 	"golang.org/x/crypto/ssh"
 
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
 )
 
 // KeyCreate creates an SSH key and stores it in the state directory.
@@ -42,25 +39,10 @@ func (p *Provider) KeyCreate(req *providerv1.KeyCreateRequest) *providerv1.Opera
 		keyType = "ed25519"
 	}
 
-	var (
-		privateKeyPEM []byte
-		publicKey     ssh.PublicKey
-		err           error
-	)
-
-	switch keyType {
-	case "ed25519":
-		privateKeyPEM, publicKey, err = generateED25519Key()
-	case "rsa":
-		bits := 4096
-		if req.Spec.Bits > 0 {
-			bits = req.Spec.Bits
-		}
-		privateKeyPEM, publicKey, err = generateRSAKey(bits)
-	default:
+	if keyType != "ed25519" && keyType != "rsa" {
 		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("unsupported key type: " + keyType))
 	}
-
+	privateKeyPEM, publicKey, err := common.GenerateKey(keyType, req.Spec.Bits)
 	if err != nil {
 		return providerv1.ErrorResult(providerv1.NewProviderError("failed to generate key: "+err.Error(), true))
 	}
@@ -93,6 +75,11 @@ func (p *Provider) KeyCreate(req *providerv1.KeyCreateRequest) *providerv1.Opera
 		_ = os.Remove(privateKeyPath)
 		return providerv1.ErrorResult(providerv1.NewProviderError("failed to write public key: "+err.Error(), false))
 	}
+	if err := common.LabelFile(publicKeyPath, req.Labels); err != nil {
+		_ = os.Remove(privateKeyPath)
+		_ = os.Remove(publicKeyPath)
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
 
 	state := &providerv1.KeyState{
 		Name:           req.Name,
@@ -102,6 +89,7 @@ func (p *Provider) KeyCreate(req *providerv1.KeyCreateRequest) *providerv1.Opera
 		PrivateKeyPath: privateKeyPath,
 		Fingerprint:    fingerprint,
 		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		Labels:         req.Labels,
 	}
 
 	p.keys[req.Name] = state
@@ -121,14 +109,26 @@ func (p *Provider) KeyGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(key)
 }
 
-// KeyList lists all SSH keys.
+// KeyList lists all SSH keys, or all keys of the state directory with the
+// host filter, restricted to those carrying the labels of the labels filter.
 func (p *Provider) KeyList(filter map[string]any) *providerv1.OperationResult {
+	if listHost(filter) {
+		keys, err := p.hostKeys(providerv1.LabelSelector(filter))
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to list keys: "+err.Error(), false))
+		}
+		return providerv1.SuccessResult(keys)
+	}
+
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	keys := make([]*providerv1.KeyState, 0, len(p.keys))
 	for _, key := range p.keys {
-		keys = append(keys, key)
+		if providerv1.MatchLabels(selector, key.Labels) {
+			keys = append(keys, key)
+		}
 	}
 
 	return providerv1.SuccessResult(keys)
@@ -177,45 +177,3 @@ func (p *Provider) KeyDelete(name string) *providerv1.OperationResult {
 	// Always return success for idempotent delete
 	return providerv1.SuccessResult(nil)
 }
-
-// generateED25519Key generates an ED25519 key pair.
-func generateED25519Key() ([]byte, ssh.PublicKey, error) {
-	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	sshPubKey, err := ssh.NewPublicKey(pubKey)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Marshal private key to OpenSSH format
-	pemBlock, err := ssh.MarshalPrivateKey(privKey, "")
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return pem.EncodeToMemory(pemBlock), sshPubKey, nil
-}
-
-// generateRSAKey generates an RSA key pair with the specified bit size.
-func generateRSAKey(bits int) ([]byte, ssh.PublicKey, error) {
-	privKey, err := rsa.GenerateKey(rand.Reader, bits)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	sshPubKey, err := ssh.NewPublicKey(&privKey.PublicKey)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Marshal private key to OpenSSH format
-	pemBlock, err := ssh.MarshalPrivateKey(privKey, "")
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return pem.EncodeToMemory(pemBlock), sshPubKey, nil
-}
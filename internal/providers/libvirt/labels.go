@@ -0,0 +1,36 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import "encoding/xml"
+
+// parseLabels returns the labels recorded in the metadata of a domain or
+// network XML by labelsMetadataTemplate, or nil if there are none.
+func parseLabels(objectXML string) map[string]string {
+	var object struct {
+		Labels []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"metadata>labels>label"`
+	}
+	if err := xml.Unmarshal([]byte(objectXML), &object); err != nil || len(object.Labels) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(object.Labels))
+	for _, l := range object.Labels {
+		labels[l.Key] = l.Value
+	}
+	return labels
+}
@@ -0,0 +1,77 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+func TestParseDomainLabels(t *testing.T) {
+	labels := map[string]string{
+		"testenv-vm.environment-id": "env<1>",
+		"testenv-vm.resource":       "vm/web",
+	}
+	domainXML, err := generateDomainXML(DomainConfig{
+		Name:     "test-vm",
+		DiskPath: "/var/lib/libvirt/images/test.qcow2",
+		Labels:   common.SortedLabels(labels),
+	})
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	got := parseLabels(domainXML)
+	if len(got) != len(labels) {
+		t.Fatalf("parseLabels() = %v, want %v", got, labels)
+	}
+	for k, v := range labels {
+		if got[k] != v {
+			t.Errorf("parseLabels()[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+
+	if got := parseLabels("<domain type='kvm'><name>other</name></domain>"); got != nil {
+		t.Errorf("parseLabels() = %v, want nil without metadata", got)
+	}
+}
+
+func TestGenerateNetworkXML_Labels(t *testing.T) {
+	labels := map[string]string{providerv1.LabelEnvironmentID: "abc123", "team": "storage"}
+	config := NetworkConfig{
+		Name:       "lan",
+		BridgeName: "virbr-lan",
+		Gateway:    "192.168.100.1",
+		Netmask:    "255.255.255.0",
+		Labels:     common.SortedLabels(labels),
+	}
+	for name, generate := range map[string]func(NetworkConfig) (string, error){
+		"nat":      generateNATNetworkXML,
+		"isolated": generateIsolatedNetworkXML,
+		"bridge":   generateBridgeNetworkXML,
+	} {
+		networkXML, err := generate(config)
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		if got := parseLabels(networkXML); !reflect.DeepEqual(got, labels) {
+			t.Errorf("%s: parseLabels() = %v, want %v\nXML:\n%s", name, got, labels, networkXML)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// adoptIPTimeout bounds the resolution of the primary IP of an adopted VM.
+const adoptIPTimeout = 60 * time.Second
+
+// migrateFlags returns the libvirt flags of a live peer-to-peer migration.
+// VMs are transient domains, so there is no definition to persist on the
+// destination or to undefine on the source.
+func migrateFlags(req *providerv1.VMMigrateRequest) libvirt.DomainMigrateFlags {
+	flags := libvirt.MigrateLive | libvirt.MigratePeer2peer | libvirt.MigrateAutoConverge
+	if req.CopyStorage {
+		flags |= libvirt.MigrateNonSharedDisk
+	}
+	return flags
+}
+
+// VMMigrate live-migrates a running VM to the libvirt host at
+// req.DestinationURI and forgets it. The returned state is the one the VM had
+// on this host; the destination provider refreshes it with VMAdopt. With
+// req.CopyStorage, the destination runs on copies of the disks, so the local
+// disks and cloud-init ISO are removed once the migration completed;
+// otherwise they are on storage shared with the destination and kept.
+func (p *Provider) VMMigrate(req *providerv1.VMMigrateRequest) *providerv1.OperationResult {
+	if req.Name == "" || req.DestinationURI == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name and destinationURI are required"))
+	}
+	if req.DestinationURI == p.config.URI {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("destination is the source host " + p.config.URI))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	dom, err := p.conn.DomainLookupByName(req.Name)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+
+	if _, err := p.conn.DomainMigratePerform3Params(dom, libvirt.OptString{req.DestinationURI}, nil, nil, migrateFlags(req)); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("failed to migrate vm %s to %s: %s", req.Name, req.DestinationURI, err.Error()), true))
+	}
+
+	if req.CopyStorage {
+		p.removeVMFiles(vm)
+	}
+	delete(p.vms, req.Name)
+	return providerv1.SuccessResult(vm)
+}
+
+// VMAdopt takes ownership of a VM migrated to this host by another provider.
+// The domain must be running here; its IPs are resolved again from the DHCP
+// leases of its networks, as they may differ from the source host.
+func (p *Provider) VMAdopt(req *providerv1.VMAdoptRequest) *providerv1.OperationResult {
+	vm := req.State
+	if vm.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("state.name is required"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	dom, err := p.conn.DomainLookupByName(vm.Name)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", vm.Name))
+	}
+	domState, _, err := p.conn.DomainGetState(dom, 0)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain state: "+err.Error(), true))
+	}
+	if libvirt.DomainState(domState) != libvirt.DomainRunning {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("vm %s is not running on %s", vm.Name, p.config.URI), true))
+	}
+
+	oldIP := vm.IP
+	ips := make(map[string]string, len(vm.MACs))
+	for i, netName := range vmNetworks(&vm) {
+		mac := vm.MACs[netName]
+		if mac == "" && i == 0 {
+			mac = vm.MAC
+		}
+		if mac == "" {
+			continue
+		}
+		timeout := 5 * time.Second
+		if i == 0 {
+			timeout = adoptIPTimeout
+		}
+		if ip, err := resolveIP(p.conn, netName, p.netbootLeaseFile(netName), mac, timeout); err == nil && ip != "" {
+			ips[netName] = ip
+			if i == 0 {
+				vm.IP = ip
+			}
+		}
+	}
+	if len(ips) == 0 {
+		if ip := resolveIPFromARP(p.conn, dom); ip != "" {
+			vm.IP = ip
+		}
+	}
+	if len(ips) > 0 {
+		vm.IPs = ips
+	}
+	if oldIP != "" && vm.IP != oldIP {
+		vm.SSHCommand = strings.Replace(vm.SSHCommand, "@"+oldIP, "@"+vm.IP, 1)
+	}
+
+	vm.Status = "running"
+	vm.UUID = formatUUID(dom.UUID)
+	if vm.ProviderState == nil {
+		vm.ProviderState = map[string]any{}
+	}
+	vm.ProviderState["uri"] = p.config.URI
+	p.vms[vm.Name] = &vm
+
+	return providerv1.SuccessResult(&vm)
+}
+
+// vmNetworks returns the networks of a VM in NIC order, the first one being
+// the primary network.
+func vmNetworks(vm *providerv1.VMState) []string {
+	var names []string
+	switch v := vm.ProviderState["networks"].(type) {
+	case []string:
+		names = append(names, v...)
+	case []any:
+		for _, n := range v {
+			if s, ok := n.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	if len(names) == 0 {
+		if netName, ok := vm.ProviderState["network"].(string); ok {
+			names = append(names, netName)
+		}
+	}
+	return names
+}
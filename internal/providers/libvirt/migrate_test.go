@@ -0,0 +1,96 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestMigrateFlags(t *testing.T) {
+	shared := migrateFlags(&providerv1.VMMigrateRequest{})
+	if shared&libvirt.MigrateLive == 0 || shared&libvirt.MigratePeer2peer == 0 {
+		t.Errorf("migrateFlags() = %d, want live peer-to-peer", shared)
+	}
+	if shared&libvirt.MigrateNonSharedDisk != 0 {
+		t.Errorf("migrateFlags() = %d, storage should not be copied", shared)
+	}
+	if copied := migrateFlags(&providerv1.VMMigrateRequest{CopyStorage: true}); copied&libvirt.MigrateNonSharedDisk == 0 {
+		t.Errorf("migrateFlags() = %d, want non-shared disk copy", copied)
+	}
+}
+
+func TestVMFiles(t *testing.T) {
+	vm := &providerv1.VMState{ProviderState: map[string]any{
+		"diskPath":     "/disks/web.qcow2",
+		"frozenDisks":  []any{"/disks/web.qcow2.snap1"},
+		"dataDisks":    []string{"/disks/web.data0.qcow2"},
+		"cloudInitISO": "/cloudinit/web.iso",
+	}}
+	want := []string{"/disks/web.qcow2", "/disks/web.qcow2.snap1", "/disks/web.data0.qcow2", "/cloudinit/web.iso"}
+	if got := vmFiles(vm); !reflect.DeepEqual(got, want) {
+		t.Errorf("vmFiles() = %q, want %q", got, want)
+	}
+	if got := vmFiles(&providerv1.VMState{}); len(got) != 0 {
+		t.Errorf("vmFiles() without provider state = %q, want none", got)
+	}
+}
+
+func TestVMNetworks(t *testing.T) {
+	tests := []struct {
+		name  string
+		state map[string]any
+		want  []string
+	}{
+		{name: "strings", state: map[string]any{"networks": []string{"a", "b"}}, want: []string{"a", "b"}},
+		{name: "json", state: map[string]any{"networks": []any{"a", "b"}}, want: []string{"a", "b"}},
+		{name: "legacy", state: map[string]any{"network": "a"}, want: []string{"a"}},
+		{name: "none", state: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vmNetworks(&providerv1.VMState{ProviderState: tt.state})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("vmNetworks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMMigrateValidation(t *testing.T) {
+	p := &Provider{config: ProviderConfig{URI: "qemu:///system"}, vms: map[string]*providerv1.VMState{}}
+
+	for _, req := range []*providerv1.VMMigrateRequest{
+		{DestinationURI: "qemu+ssh://host-b/system"},
+		{Name: "web"},
+		{Name: "web", DestinationURI: "qemu:///system"},
+	} {
+		result := p.VMMigrate(req)
+		if result.Success || result.Error.Code != providerv1.ErrCodeInvalidSpec {
+			t.Errorf("VMMigrate(%+v) = %+v, want invalid spec", req, result.Error)
+		}
+	}
+
+	result := p.VMMigrate(&providerv1.VMMigrateRequest{Name: "web", DestinationURI: "qemu+ssh://host-b/system"})
+	if result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("VMMigrate() of an unknown vm = %+v, want not found", result.Error)
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/netboot"
+)
+
+// DHCPBackend selects the server handing out addresses and boot files on
+// dnsmasq networks.
+type DHCPBackend string
+
+const (
+	// DHCPBackendDnsmasq serves DHCP and TFTP from the dnsmasq libvirt
+	// runs for the network.
+	DHCPBackendDnsmasq DHCPBackend = "dnsmasq"
+	// DHCPBackendBuiltin serves DHCP and TFTP from the netboot servers of
+	// the provider binary, run as a daemon per network. libvirt runs no
+	// dnsmasq for the network, which has no DNS server then.
+	DHCPBackendBuiltin DHCPBackend = "builtin"
+	// DHCPBackendAuto uses dnsmasq when it is installed and the built-in
+	// servers otherwise.
+	DHCPBackendAuto DHCPBackend = "auto"
+)
+
+// ParseDHCPBackend parses the DHCP backend named s. An empty name selects
+// DHCPBackendDnsmasq.
+func ParseDHCPBackend(s string) (DHCPBackend, error) {
+	switch b := DHCPBackend(s); b {
+	case "":
+		return DHCPBackendDnsmasq, nil
+	case DHCPBackendDnsmasq, DHCPBackendBuiltin, DHCPBackendAuto:
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown DHCP backend %q: want %s, %s or %s", s, DHCPBackendDnsmasq, DHCPBackendBuiltin, DHCPBackendAuto)
+}
+
+// resolve returns the backend serving a network: DHCPBackendAuto is
+// resolved with lookPath. An empty backend is DHCPBackendDnsmasq.
+func (b DHCPBackend) resolve(lookPath func(string) (string, error)) DHCPBackend {
+	switch b {
+	case DHCPBackendBuiltin:
+		return DHCPBackendBuiltin
+	case DHCPBackendAuto:
+		if _, err := lookPath("dnsmasq"); err != nil {
+			return DHCPBackendBuiltin
+		}
+	}
+	return DHCPBackendDnsmasq
+}
+
+const (
+	// netbootSubdir is the directory of the state directory holding the
+	// configuration, pid, lease and log files of built-in netboot servers.
+	netbootSubdir = "netboot"
+	// netbootStartTimeout is how long a netboot server has to listen.
+	netbootStartTimeout = 5 * time.Second
+)
+
+// executable returns the path of the provider binary, which serves the
+// netboot daemons; tests replace it.
+var executable = os.Executable
+
+// netbootConfig configures the netboot servers of a network. It is
+// written as JSON for the daemon serving them.
+type netbootConfig struct {
+	// Interface is the bridge the DHCP server binds to.
+	Interface string `json:"interface"`
+	// DHCP configures the DHCP server, if any.
+	DHCP *netboot.DHCPConfig `json:"dhcp,omitempty"`
+	// TFTPRoot is served over TFTP from TFTPAddress, when set.
+	TFTPRoot    string `json:"tftpRoot,omitempty"`
+	TFTPAddress string `json:"tftpAddress,omitempty"`
+	// PIDFile is written once the servers listen.
+	PIDFile string `json:"pidFile"`
+}
+
+// netbootFiles returns the configuration, pid, lease and log files of the
+// netboot servers of a network.
+func (p *Provider) netbootFiles(network string) (confPath, pidPath, leasePath, logPath string) {
+	base := filepath.Join(p.config.StateDir, netbootSubdir, network)
+	return base + ".json", base + ".pid", base + ".leases", base + ".log"
+}
+
+// netbootLeaseFile returns the lease file of the built-in DHCP server of a
+// network, which does not exist on other networks.
+func (p *Provider) netbootLeaseFile(network string) string {
+	_, _, leasePath, _ := p.netbootFiles(network)
+	return leasePath
+}
+
+// newNetbootConfig returns the netboot configuration of a network: DHCP on
+// its bridge with the range, reservations and NTP server of config and the
+// options of spec, and TFTP from its gateway.
+func newNetbootConfig(config NetworkConfig, cidr string, spec providerv1.NetworkSpec) (netbootConfig, error) {
+	nc := netbootConfig{Interface: config.BridgeName}
+	tftp := spec.TFTP
+	if tftp != nil && tftp.Enabled {
+		root, err := filepath.Abs(tftp.Root)
+		if err != nil || tftp.Root == "" {
+			return netbootConfig{}, fmt.Errorf("tftp requires a root directory")
+		}
+		nc.TFTPRoot, nc.TFTPAddress = root, config.Gateway
+	}
+	if !config.DHCPEnabled {
+		return nc, nil
+	}
+
+	dhcp := &netboot.DHCPConfig{
+		ServerIP:   config.Gateway,
+		CIDR:       cidr,
+		RangeStart: config.DHCPStart,
+		RangeEnd:   config.DHCPEnd,
+		Router:     config.Gateway,
+	}
+	if config.NTPServer != "" {
+		dhcp.NTPServers = []string{config.NTPServer}
+	}
+	for _, h := range config.DHCPHosts {
+		dhcp.Hosts = append(dhcp.Hosts, netboot.Host{MAC: h.MAC, IP: h.IP, Hostname: h.Name})
+	}
+	if d := spec.DHCP; d != nil {
+		if d.Router != "" {
+			dhcp.Router = d.Router
+		}
+		if d.LeaseTime != "" {
+			leaseTime, err := time.ParseDuration(d.LeaseTime)
+			if err != nil || leaseTime <= 0 {
+				return netbootConfig{}, fmt.Errorf("invalid dhcp leaseTime %q", d.LeaseTime)
+			}
+			dhcp.LeaseTime = leaseTime
+		}
+		dhcp.DNSServers, dhcp.Domain, dhcp.NextServer = d.DNSServers, d.Domain, d.NextServer
+	}
+	if nc.TFTPRoot != "" {
+		if dhcp.NextServer == "" {
+			dhcp.NextServer = config.Gateway
+		}
+		dhcp.BootFile, dhcp.BootFileEFI = tftp.BootFile, tftp.BootFileEFI
+		options, err := dhcpBootOptions(tftp.DHCPBootOptions)
+		if err != nil {
+			return netbootConfig{}, err
+		}
+		dhcp.Options = options
+	}
+	nc.DHCP = dhcp
+	return nc, nil
+}
+
+// dhcpBootOptions parses the tftp.dhcpBootOptions of a network, whose keys
+// are DHCP option codes.
+func dhcpBootOptions(options map[string]string) (map[byte]string, error) {
+	if len(options) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[byte]string, len(options))
+	for key, value := range options {
+		code, err := strconv.ParseUint(key, 10, 8)
+		if err != nil || code == 0 || code == 255 {
+			return nil, fmt.Errorf("dhcpBootOptions key %q is not a DHCP option code", key)
+		}
+		parsed[byte(code)] = value
+	}
+	return parsed, nil
+}
+
+// startNetbootServer runs the netboot servers of a network. They are served
+// by the provider binary started with --netboot as a daemon of its own
+// session: like the network, it outlives the provider process, and is
+// found again through its pid file by stopNetbootServer.
+func (p *Provider) startNetbootServer(network string, config netbootConfig) error {
+	exe, err := executable()
+	if err != nil {
+		return fmt.Errorf("failed to find provider binary: %w", err)
+	}
+	confPath, pidPath, leasePath, logPath := p.netbootFiles(network)
+	if err := os.MkdirAll(filepath.Dir(confPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create netboot directory: %w", err)
+	}
+	// A server left over by a crashed run would hold the ports
+	p.stopNetbootServer(network)
+	config.PIDFile = pidPath
+	if config.DHCP != nil {
+		config.DHCP.LeaseFile = leasePath
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal netboot configuration: %w", err)
+	}
+	if err := os.WriteFile(confPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write netboot configuration: %w", err)
+	}
+
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return fmt.Errorf("failed to create netboot log: %w", err)
+	}
+	defer func() { _ = logFile.Close() }()
+	cmd := exec.Command(exe, "--netboot", confPath)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start netboot server: %w", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	deadline := time.After(netbootStartTimeout)
+	for {
+		if _, err := readPIDFile(pidPath); err == nil {
+			return nil
+		}
+		select {
+		case <-exited:
+			err := fmt.Errorf("netboot server exited: %s", logTail(logPath))
+			p.stopNetbootServer(network)
+			return err
+		case <-deadline:
+			_ = cmd.Process.Kill()
+			err := fmt.Errorf("netboot server not listening after %v: %s", netbootStartTimeout, logTail(logPath))
+			p.stopNetbootServer(network)
+			return err
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// logTail returns the last line of a log file, for error messages.
+func logTail(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "no log"
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return lines[len(lines)-1]
+}
+
+// stopNetbootServer stops the netboot servers of a network, if any, and
+// removes their files. It is best-effort, as deleting the network must not
+// fail on it.
+func (p *Provider) stopNetbootServer(network string) {
+	confPath, pidPath, leasePath, logPath := p.netbootFiles(network)
+	if pid, err := readPIDFile(pidPath); err == nil {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+	for _, path := range []string{confPath, pidPath, leasePath, logPath} {
+		_ = os.Remove(path)
+	}
+}
+
+// ServeNetboot serves the netboot configuration at configPath until ctx is
+// done. It is the daemon started by startNetbootServer; the pid file is
+// written once the servers listen.
+func ServeNetboot(ctx context.Context, configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read netboot configuration: %w", err)
+	}
+	var config netbootConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse netboot configuration: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var serve []func() error
+	if config.DHCP != nil {
+		server, err := netboot.NewDHCPServer(*config.DHCP)
+		if err != nil {
+			return err
+		}
+		conn, err := netboot.ListenDHCP(ctx, config.Interface)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = conn.Close() }()
+		serve = append(serve, func() error { return server.Serve(ctx, conn) })
+	}
+	if config.TFTPRoot != "" {
+		server, err := netboot.NewTFTPServer(config.TFTPRoot)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = server.Close() }()
+		conn, err := net.ListenPacket("udp4", net.JoinHostPort(config.TFTPAddress, strconv.Itoa(netboot.TFTPPort)))
+		if err != nil {
+			return fmt.Errorf("failed to listen for TFTP: %w", err)
+		}
+		defer func() { _ = conn.Close() }()
+		serve = append(serve, func() error { return server.Serve(ctx, conn) })
+	}
+	if len(serve) == 0 {
+		return errors.New("netboot configuration serves neither DHCP nor TFTP")
+	}
+	if err := os.WriteFile(config.PIDFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+
+	errs := make(chan error, len(serve))
+	for _, fn := range serve {
+		go func() { errs <- fn() }()
+	}
+	// A failing server stops the others, so the network is not left half
+	// served
+	var first error
+	for range serve {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
@@ -0,0 +1,202 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/netboot"
+)
+
+func TestParseDHCPBackend(t *testing.T) {
+	for in, want := range map[string]DHCPBackend{
+		"":        DHCPBackendDnsmasq,
+		"dnsmasq": DHCPBackendDnsmasq,
+		"builtin": DHCPBackendBuiltin,
+		"auto":    DHCPBackendAuto,
+	} {
+		if got, err := ParseDHCPBackend(in); err != nil || got != want {
+			t.Errorf("ParseDHCPBackend(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseDHCPBackend("kea"); err == nil {
+		t.Error("ParseDHCPBackend(kea) should fail")
+	}
+}
+
+func TestDHCPBackend_resolve(t *testing.T) {
+	installed := func(string) (string, error) { return "/usr/sbin/dnsmasq", nil }
+	missing := func(string) (string, error) { return "", exec.ErrNotFound }
+	tests := []struct {
+		backend  DHCPBackend
+		lookPath func(string) (string, error)
+		want     DHCPBackend
+	}{
+		{"", missing, DHCPBackendDnsmasq},
+		{DHCPBackendDnsmasq, missing, DHCPBackendDnsmasq},
+		{DHCPBackendBuiltin, installed, DHCPBackendBuiltin},
+		{DHCPBackendAuto, installed, DHCPBackendDnsmasq},
+		{DHCPBackendAuto, missing, DHCPBackendBuiltin},
+	}
+	for _, tt := range tests {
+		if got := tt.backend.resolve(tt.lookPath); got != tt.want {
+			t.Errorf("%q.resolve() = %q, want %q", tt.backend, got, tt.want)
+		}
+	}
+}
+
+func TestNewNetbootConfig(t *testing.T) {
+	config := NetworkConfig{
+		BridgeName:  "virbr-pxe",
+		Gateway:     "10.0.0.1",
+		DHCPEnabled: true,
+		DHCPStart:   "10.0.0.10",
+		DHCPEnd:     "10.0.0.20",
+		DHCPHosts:   []DHCPHost{{MAC: "52:54:00:00:00:01", IP: "10.0.0.5", Name: "node"}},
+		NTPServer:   "10.0.0.1",
+	}
+	spec := providerv1.NetworkSpec{
+		DHCP: &providerv1.DHCPSpec{Enabled: true, LeaseTime: "12h", DNSServers: []string{"1.1.1.1"}, Domain: "lab"},
+		TFTP: &providerv1.TFTPSpec{
+			Enabled:         true,
+			Root:            "/srv/tftp",
+			BootFile:        "undionly.kpxe",
+			BootFileEFI:     "ipxe.efi",
+			DHCPBootOptions: map[string]string{"209": "pxelinux.cfg/default"},
+		},
+	}
+	got, err := newNetbootConfig(config, "10.0.0.0/24", spec)
+	if err != nil {
+		t.Fatalf("newNetbootConfig() error = %v", err)
+	}
+	want := netbootConfig{
+		Interface:   "virbr-pxe",
+		TFTPRoot:    "/srv/tftp",
+		TFTPAddress: "10.0.0.1",
+		DHCP: &netboot.DHCPConfig{
+			ServerIP:    "10.0.0.1",
+			CIDR:        "10.0.0.0/24",
+			RangeStart:  "10.0.0.10",
+			RangeEnd:    "10.0.0.20",
+			LeaseTime:   12 * time.Hour,
+			Router:      "10.0.0.1",
+			DNSServers:  []string{"1.1.1.1"},
+			NTPServers:  []string{"10.0.0.1"},
+			Domain:      "lab",
+			Hosts:       []netboot.Host{{MAC: "52:54:00:00:00:01", IP: "10.0.0.5", Hostname: "node"}},
+			NextServer:  "10.0.0.1",
+			BootFile:    "undionly.kpxe",
+			BootFileEFI: "ipxe.efi",
+			Options:     map[byte]string{209: "pxelinux.cfg/default"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newNetbootConfig() = %+v\nwant %+v", got, want)
+	}
+	// The built-in servers must accept the configuration
+	if _, err := netboot.NewDHCPServer(*got.DHCP); err != nil {
+		t.Errorf("NewDHCPServer() error = %v", err)
+	}
+
+	config.DHCPEnabled = false
+	got, err = newNetbootConfig(config, "10.0.0.0/24", spec)
+	if err != nil || got.DHCP != nil || got.TFTPRoot == "" {
+		t.Errorf("newNetbootConfig() without DHCP = %+v, %v, want TFTP only", got, err)
+	}
+
+	spec.DHCP.LeaseTime = "forever"
+	config.DHCPEnabled = true
+	if _, err := newNetbootConfig(config, "10.0.0.0/24", spec); err == nil {
+		t.Error("newNetbootConfig() with an invalid lease time should fail")
+	}
+}
+
+func TestStartNetbootServer(t *testing.T) {
+	// The fake server writes its pid file like the real one once it listens
+	bin := filepath.Join(t.TempDir(), "provider")
+	script := "#!/bin/sh\necho $$ > \"${2%.json}.pid\"\nexec sleep 60\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	original := executable
+	executable = func() (string, error) { return bin, nil }
+	t.Cleanup(func() { executable = original })
+
+	p := &Provider{config: ProviderConfig{StateDir: t.TempDir()}}
+	config := netbootConfig{Interface: "virbr-pxe", DHCP: &netboot.DHCPConfig{CIDR: "10.0.0.0/24"}}
+	if err := p.startNetbootServer("pxe", config); err != nil {
+		t.Fatalf("startNetbootServer() error = %v", err)
+	}
+	confPath, pidPath, leasePath, _ := p.netbootFiles("pxe")
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		t.Fatalf("netboot configuration not written: %v", err)
+	}
+	var written netbootConfig
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.PIDFile != pidPath || written.DHCP.LeaseFile != leasePath {
+		t.Errorf("configuration files = %q, %q, want %q, %q", written.PIDFile, written.DHCP.LeaseFile, pidPath, leasePath)
+	}
+	pid, err := readPIDFile(pidPath)
+	if err != nil {
+		t.Fatalf("readPIDFile() error = %v", err)
+	}
+
+	p.stopNetbootServer("pxe")
+	// The server is reaped by startNetbootServer once it exits
+	deadline := time.Now().Add(5 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatal("stopNetbootServer() did not stop the server")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, path := range []string{confPath, pidPath} {
+		if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s should be removed, stat error = %v", path, err)
+		}
+	}
+}
+
+func TestStartNetbootServer_Exits(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "provider")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho 'cannot bind port 67' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	original := executable
+	executable = func() (string, error) { return bin, nil }
+	t.Cleanup(func() { executable = original })
+
+	p := &Provider{config: ProviderConfig{StateDir: t.TempDir()}}
+	err := p.startNetbootServer("pxe", netbootConfig{Interface: "virbr-pxe"})
+	if err == nil || !strings.Contains(err.Error(), "cannot bind port 67") {
+		t.Errorf("startNetbootServer() error = %v, want the log of the server", err)
+	}
+}
@@ -16,10 +16,12 @@ package libvirt
 
 import (
 	"fmt"
+	"os/exec"
 
 	"github.com/digitalocean/go-libvirt"
 
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
 )
 
 // NetworkCreate creates a network via libvirt.
@@ -48,7 +50,7 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 
 	// Validate kind
 	switch kind {
-	case "nat", "isolated", "bridge":
+	case "nat", "isolated", "bridge", "dnsmasq":
 		// Valid kinds
 	default:
 		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("unsupported network kind: " + kind))
@@ -66,6 +68,14 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid CIDR: " + err.Error()))
 	}
 
+	// Bridge networks attach to a bridge of the host rather than routing
+	// their CIDR through it
+	if kind != "bridge" {
+		if opErr := p.checkNetworkConflict(cidr); opErr != nil {
+			return providerv1.ErrorResult(opErr)
+		}
+	}
+
 	// Generate bridge name
 	bridgeName := generateBridgeName(req.Name)
 
@@ -91,12 +101,73 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 		DHCPEnabled: dhcpEnabled,
 		DHCPStart:   dhcpStart,
 		DHCPEnd:     dhcpEnd,
+		MTU:         req.Spec.MTU,
+		Labels:      common.SortedLabels(req.Labels),
+	}
+	if req.Spec.DHCP != nil && len(req.Spec.DHCP.StaticLeases) > 0 {
+		// Reservations are served by the DHCP server of NAT, isolated and
+		// dnsmasq networks
+		if kind == "bridge" || !dhcpEnabled {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("dhcp hosts require DHCP on a nat, isolated or dnsmasq network"))
+		}
+		config.DHCPHosts, err = newDHCPHosts(req.Spec.DHCP.StaticLeases)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+		}
+	}
+	if req.Spec.DNS != nil && len(req.Spec.DNS.Records) > 0 {
+		// Bridge networks have no dnsmasq to serve the records
+		if kind == "bridge" {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("dns records are not supported on bridge networks"))
+		}
+		config.DNSHosts, config.DNSTXT, config.CNAMEs, err = newDNSRecords(req.Spec.DNS.Records)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+		}
+	}
+
+	ntpEnabled := req.Spec.NTP != nil && req.Spec.NTP.Enabled
+	if ntpEnabled {
+		// Bridge networks have no gateway address on the host to serve from
+		if kind == "bridge" {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("ntp is not supported on bridge networks"))
+		}
+		config.NTPServer = gateway
+	}
+
+	// dnsmasq networks are NAT networks whose DHCP server also hands out
+	// network boot files, from libvirt's dnsmasq or the built-in servers
+	var backend DHCPBackend
+	var netbootCfg *netbootConfig
+	if kind == "dnsmasq" {
+		backend = p.config.DHCPBackend.resolve(exec.LookPath)
+		if backend == DHCPBackendBuiltin {
+			if len(config.DNSHosts) > 0 || len(config.DNSTXT) > 0 || len(config.CNAMEs) > 0 {
+				return providerv1.ErrorResult(providerv1.NewInvalidSpecError("dns records require the dnsmasq DHCP backend"))
+			}
+			nc, err := newNetbootConfig(config, cidr, req.Spec)
+			if err != nil {
+				return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+			}
+			netbootCfg = &nc
+			// libvirt must not run a dnsmasq competing with the built-in
+			// servers
+			config.DHCPEnabled, config.DHCPHosts, config.NTPServer, config.DNSDisabled = false, nil, "", true
+		} else {
+			nextServer := ""
+			if req.Spec.DHCP != nil {
+				nextServer = req.Spec.DHCP.NextServer
+			}
+			if err := config.setNetboot(req.Spec.TFTP, nextServer); err != nil {
+				return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+			}
+		}
 	}
 
 	// Generate network XML based on kind
 	var networkXML string
 	switch kind {
-	case "nat":
+	case "nat", "dnsmasq":
 		networkXML, err = generateNATNetworkXML(config)
 	case "isolated":
 		networkXML, err = generateIsolatedNetworkXML(config)
@@ -120,6 +191,23 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 		return providerv1.ErrorResult(providerv1.NewProviderError("failed to start network: "+err.Error(), true))
 	}
 
+	if ntpEnabled {
+		if err := p.startNTPServer(req.Name, cidr, gateway, req.Spec.NTP); err != nil {
+			_ = p.conn.NetworkDestroy(net)
+			_ = p.conn.NetworkUndefine(net)
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to start NTP server: "+err.Error(), false))
+		}
+	}
+
+	if netbootCfg != nil {
+		if err := p.startNetbootServer(req.Name, *netbootCfg); err != nil {
+			p.stopNTPServer(req.Name)
+			_ = p.conn.NetworkDestroy(net)
+			_ = p.conn.NetworkUndefine(net)
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to start netboot server: "+err.Error(), false))
+		}
+	}
+
 	// Get network UUID
 	uuid := formatUUID(net.UUID)
 
@@ -131,6 +219,13 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 		CIDR:          cidr,
 		InterfaceName: bridgeName,
 		UUID:          uuid,
+		Labels:        req.Labels,
+	}
+	if ntpEnabled {
+		state.NTPServer = gateway
+	}
+	if backend != "" {
+		state.ProviderState = map[string]any{"dhcpBackend": string(backend)}
 	}
 
 	p.networks[req.Name] = state
@@ -150,14 +245,26 @@ func (p *Provider) NetworkGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(network)
 }
 
-// NetworkList lists all networks.
+// NetworkList lists all networks, or all networks of libvirt with the host
+// filter, restricted to those carrying the labels of the labels filter.
 func (p *Provider) NetworkList(filter map[string]any) *providerv1.OperationResult {
+	if listHost(filter) {
+		networks, err := p.hostNetworks(providerv1.LabelSelector(filter))
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to list networks: "+err.Error(), true))
+		}
+		return providerv1.SuccessResult(networks)
+	}
+
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	networks := make([]*providerv1.NetworkState, 0, len(p.networks))
 	for _, network := range p.networks {
-		networks = append(networks, network)
+		if providerv1.MatchLabels(selector, network.Labels) {
+			networks = append(networks, network)
+		}
 	}
 
 	return providerv1.SuccessResult(networks)
@@ -194,6 +301,8 @@ func (p *Provider) NetworkDelete(name string) *providerv1.OperationResult {
 		_ = p.conn.NetworkUndefine(net)
 	}
 
+	p.stopNTPServer(name)
+	p.stopNetbootServer(name)
 	delete(p.networks, name)
 
 	// Always return success for idempotent delete
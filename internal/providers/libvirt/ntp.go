@@ -0,0 +1,119 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// ntpSubdir is the directory of the state directory holding the chrony
+// configuration, pid and drift files of network NTP servers.
+const ntpSubdir = "ntp"
+
+// ntpFiles returns the chrony configuration and pid files of a network.
+func (p *Provider) ntpFiles(network string) (confPath, pidPath string) {
+	dir := filepath.Join(p.config.StateDir, ntpSubdir)
+	return filepath.Join(dir, network+".conf"), filepath.Join(dir, network+".pid")
+}
+
+// chronyConfig renders the configuration of a chronyd serving time to a
+// network from its gateway address. Without upstream servers the host clock
+// is served.
+func chronyConfig(network, cidr, gateway string, servers []string, pidPath string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# NTP server of network %s, generated by testenv-vm.\n", network))
+	for _, server := range servers {
+		sb.WriteString(fmt.Sprintf("server %s iburst\n", server))
+	}
+	sb.WriteString("local stratum 10\n")
+	sb.WriteString(fmt.Sprintf("allow %s\n", ipNet.String()))
+	sb.WriteString(fmt.Sprintf("bindaddress %s\n", gateway))
+	sb.WriteString("cmdport 0\n")
+	sb.WriteString(fmt.Sprintf("pidfile %s\n", pidPath))
+	sb.WriteString(fmt.Sprintf("driftfile %s\n", strings.TrimSuffix(pidPath, ".pid")+".drift"))
+	return sb.String(), nil
+}
+
+// startNTPServer runs a chronyd serving time to a network from its gateway.
+// chronyd is started with -x so that it never adjusts the host clock, and
+// daemonizes: it outlives the provider process like the network itself, and
+// is found again through its pid file by stopNTPServer.
+func (p *Provider) startNTPServer(network, cidr, gateway string, spec *providerv1.NTPSpec) error {
+	chronyd, err := exec.LookPath("chronyd")
+	if err != nil {
+		return fmt.Errorf("ntp requires chronyd: %w", err)
+	}
+	confPath, pidPath := p.ntpFiles(network)
+	config, err := chronyConfig(network, cidr, gateway, spec.Servers, pidPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(confPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create ntp directory: %w", err)
+	}
+	// A server left over by a crashed run would hold the port
+	p.stopNTPServer(network)
+	if err := os.WriteFile(confPath, []byte(config), 0o644); err != nil {
+		return fmt.Errorf("failed to write chrony configuration: %w", err)
+	}
+
+	if out, err := exec.Command(chronyd, "-f", confPath, "-x").CombinedOutput(); err != nil {
+		_ = os.Remove(confPath)
+		return fmt.Errorf("failed to start chronyd: %w, output: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// stopNTPServer stops the chronyd of a network, if any, and removes its
+// files. It is best-effort, as deleting the network must not fail on it.
+func (p *Provider) stopNTPServer(network string) {
+	confPath, pidPath := p.ntpFiles(network)
+	if pid, err := readPIDFile(pidPath); err == nil {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+	_ = os.Remove(confPath)
+	_ = os.Remove(pidPath)
+	_ = os.Remove(strings.TrimSuffix(pidPath, ".pid") + ".drift")
+}
+
+// readPIDFile reads the process ID written to a pid file.
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %w", path, err)
+	}
+	if pid <= 0 {
+		return 0, fmt.Errorf("invalid pid %d in %s", pid, path)
+	}
+	return pid, nil
+}
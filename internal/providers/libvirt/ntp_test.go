@@ -0,0 +1,130 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestChronyConfig(t *testing.T) {
+	config, err := chronyConfig("airgap", "10.0.0.1/24", "10.0.0.1", []string{"ntp.example.com"}, "/state/ntp/airgap.pid")
+	if err != nil {
+		t.Fatalf("chronyConfig() error = %v", err)
+	}
+	for _, want := range []string{
+		"server ntp.example.com iburst\n",
+		"local stratum 10\n",
+		"allow 10.0.0.0/24\n",
+		"bindaddress 10.0.0.1\n",
+		"cmdport 0\n",
+		"pidfile /state/ntp/airgap.pid\n",
+		"driftfile /state/ntp/airgap.drift\n",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("chronyConfig() should contain %q, got:\n%s", want, config)
+		}
+	}
+
+	config, err = chronyConfig("airgap", "10.0.0.0/24", "10.0.0.1", nil, "/state/ntp/airgap.pid")
+	if err != nil {
+		t.Fatalf("chronyConfig() error = %v", err)
+	}
+	if strings.Contains(config, "\nserver ") {
+		t.Errorf("chronyConfig() without servers should serve the local clock, got:\n%s", config)
+	}
+
+	if _, err := chronyConfig("airgap", "10.0.0.0", "10.0.0.1", nil, "/state/ntp/airgap.pid"); err == nil {
+		t.Error("chronyConfig() expected error for an invalid CIDR")
+	}
+}
+
+func TestStartNTPServer(t *testing.T) {
+	// The fake chronyd records its arguments next to the configuration
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > \"$2.args\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "chronyd"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	p := &Provider{config: ProviderConfig{StateDir: t.TempDir()}}
+	if err := p.startNTPServer("airgap", "10.0.0.0/24", "10.0.0.1", &providerv1.NTPSpec{Enabled: true}); err != nil {
+		t.Fatalf("startNTPServer() error = %v", err)
+	}
+	confPath, _ := p.ntpFiles("airgap")
+	if _, err := os.Stat(confPath); err != nil {
+		t.Fatalf("chrony configuration not written: %v", err)
+	}
+	args, err := os.ReadFile(confPath + ".args")
+	if err != nil {
+		t.Fatalf("chronyd not run: %v", err)
+	}
+	if want := "-f " + confPath + " -x"; strings.TrimSpace(string(args)) != want {
+		t.Errorf("chronyd args = %q, want %q", strings.TrimSpace(string(args)), want)
+	}
+}
+
+func TestStopNTPServer(t *testing.T) {
+	p := &Provider{config: ProviderConfig{StateDir: t.TempDir()}}
+	confPath, pidPath := p.ntpFiles("airgap")
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{confPath: "", pidPath: strconv.Itoa(cmd.Process.Pid) + "\n"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.stopNTPServer("airgap")
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("stopNTPServer() did not stop chronyd")
+	}
+	for _, path := range []string{confPath, pidPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed, stat error = %v", path, err)
+		}
+	}
+
+	// Stopping a network without NTP server is a no-op
+	p.stopNTPServer("other")
+}
+
+func TestStartNTPServer_MissingChronyd(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	p := &Provider{config: ProviderConfig{StateDir: t.TempDir()}}
+	if err := p.startNTPServer("airgap", "10.0.0.0/24", "10.0.0.1", &providerv1.NTPSpec{Enabled: true}); err == nil {
+		t.Error("startNTPServer() expected error without chronyd")
+	}
+}
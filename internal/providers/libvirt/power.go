@@ -0,0 +1,228 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// shutoffPollInterval is the interval between checks of a domain asked to
+// power off.
+const shutoffPollInterval = 500 * time.Millisecond
+
+// VMPower applies a power action to a VM. VMs are transient domains, which
+// libvirt forgets once they power off, so vm_stop keeps the definition of the
+// domain in the state directory and vm_start creates it again from it. Disks
+// are kept in between, so the guest boots from where it stopped. vm_save
+// writes the memory of the domain to a save file under the state directory,
+// from which vm_start restores it.
+func (p *Provider) VMPower(action string, req *providerv1.VMPowerRequest) *providerv1.OperationResult {
+	if req.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name is required"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	status, opErr := providerv1.NextPowerStatus(vm.Status, action)
+	if opErr != nil {
+		return providerv1.ErrorResult(opErr)
+	}
+
+	var err error
+	switch action {
+	case providerv1.PowerStart:
+		err = p.startDomain(vm)
+	case providerv1.PowerStop:
+		err = p.stopDomain(vm.Name, req)
+	case providerv1.PowerReboot:
+		err = p.rebootDomain(vm.Name, req.Force)
+	case providerv1.PowerPause:
+		err = p.pauseDomain(vm.Name)
+	case providerv1.PowerSave:
+		err = p.saveDomain(vm.Name)
+	}
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("failed to %s vm %s: %s", action, vm.Name, err.Error()), true))
+	}
+	// The lock is released while a guest shuts down: the VM may have been
+	// deleted meanwhile
+	if p.vms[req.Name] != vm {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+
+	vm.Status = status
+	return providerv1.SuccessResult(vm)
+}
+
+// startDomain resumes the domain of a paused VM, restores the domain of a
+// saved VM from its save file, or creates the domain of a stopped VM again
+// from the definition kept by stopDomain.
+func (p *Provider) startDomain(vm *providerv1.VMState) error {
+	if dom, err := p.conn.DomainLookupByName(vm.Name); err == nil {
+		state, _, err := p.conn.DomainGetState(dom, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get domain state: %w", err)
+		}
+		if libvirt.DomainState(state) == libvirt.DomainPaused {
+			return p.conn.DomainResume(dom)
+		}
+		return nil
+	}
+
+	savePath := p.savePath(vm.Name)
+	if _, err := os.Stat(savePath); err == nil {
+		if err := p.conn.DomainRestoreFlags(savePath, nil, uint32(libvirt.DomainSaveRunning)); err != nil {
+			return fmt.Errorf("failed to restore domain: %w", err)
+		}
+		_ = os.Remove(savePath)
+		if dom, err := p.conn.DomainLookupByName(vm.Name); err == nil {
+			vm.UUID = formatUUID(dom.UUID)
+		}
+		return nil
+	}
+
+	domainXML, err := os.ReadFile(p.stoppedPath(vm.Name))
+	if err != nil {
+		return fmt.Errorf("no domain definition was kept when it stopped: %w", err)
+	}
+	dom, err := p.conn.DomainCreateXML(string(domainXML), 0)
+	if err != nil {
+		return fmt.Errorf("failed to create domain: %w", err)
+	}
+	_ = os.Remove(p.stoppedPath(vm.Name))
+	vm.UUID = formatUUID(dom.UUID)
+	return nil
+}
+
+// stopDomain powers off the domain of a VM, asking the guest first unless
+// req.Force is set, and keeps its definition for startDomain. It is called
+// with p.mu held, and releases it while the guest shuts down.
+func (p *Provider) stopDomain(name string, req *providerv1.VMPowerRequest) error {
+	dom, err := p.conn.DomainLookupByName(name)
+	if err != nil {
+		// The guest may have powered off by itself
+		if _, statErr := os.Stat(p.stoppedPath(name)); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("domain not found: %w", err)
+	}
+	domainXML, err := p.conn.DomainGetXMLDesc(dom, libvirt.DomainXMLSecure|libvirt.DomainXMLInactive)
+	if err != nil {
+		return fmt.Errorf("failed to get domain XML: %w", err)
+	}
+	// The definition is kept before the domain goes away; it may hold
+	// secrets, e.g. a console password
+	stoppedPath := p.stoppedPath(name)
+	if err := os.WriteFile(stoppedPath, []byte(domainXML), 0o600); err != nil {
+		return fmt.Errorf("failed to keep domain definition: %w", err)
+	}
+
+	if req.Force {
+		err = p.conn.DomainDestroy(dom)
+	} else if err = p.conn.DomainShutdown(dom); err == nil {
+		p.mu.Unlock()
+		err = p.waitShutoff(dom, req.StopTimeout())
+		p.mu.Lock()
+	}
+	if err != nil {
+		_ = os.Remove(stoppedPath)
+		return err
+	}
+	return nil
+}
+
+// waitShutoff waits until a domain powered off, which also removes a
+// transient domain.
+func (p *Provider) waitShutoff(dom libvirt.Domain, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		state, _, err := p.conn.DomainGetState(dom, 0)
+		if err != nil || libvirt.DomainState(state) == libvirt.DomainShutoff {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("guest did not power off within %s, stop with force to kill it", timeout)
+		}
+		time.Sleep(shutoffPollInterval)
+	}
+}
+
+// rebootDomain reboots the domain of a running VM through the guest, or
+// resets it when force is set.
+func (p *Provider) rebootDomain(name string, force bool) error {
+	dom, err := p.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("domain not found: %w", err)
+	}
+	if force {
+		return p.conn.DomainReset(dom, 0)
+	}
+	return p.conn.DomainReboot(dom, 0)
+}
+
+// pauseDomain suspends the vCPUs of the domain of a running VM.
+func (p *Provider) pauseDomain(name string) error {
+	dom, err := p.conn.DomainLookupByName(name)
+	if err != nil {
+		return fmt.Errorf("domain not found: %w", err)
+	}
+	state, _, err := p.conn.DomainGetState(dom, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get domain state: %w", err)
+	}
+	if libvirt.DomainState(state) == libvirt.DomainPaused {
+		return nil
+	}
+	return p.conn.DomainSuspend(dom)
+}
+
+// saveDomain writes the memory of the domain of a running or paused VM to its
+// save file and powers the domain off. A saved VM consumes no host memory or
+// CPU until startDomain restores it.
+func (p *Provider) saveDomain(name string) error {
+	savePath := p.savePath(name)
+	dom, err := p.conn.DomainLookupByName(name)
+	if err != nil {
+		if _, statErr := os.Stat(savePath); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("domain not found: %w", err)
+	}
+	return p.conn.DomainSave(dom, savePath)
+}
+
+// stoppedPath returns the path of the domain definition kept for a stopped
+// VM.
+func (p *Provider) stoppedPath(name string) string {
+	return filepath.Join(p.config.StateDir, "stopped", name+".xml")
+}
+
+// savePath returns the path of the save file of a VM.
+func (p *Provider) savePath(name string) string {
+	return filepath.Join(p.config.StateDir, "saves", name+".save")
+}
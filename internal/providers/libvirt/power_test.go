@@ -0,0 +1,53 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestVMPowerValidation(t *testing.T) {
+	p := &Provider{vms: map[string]*providerv1.VMState{
+		"web": {Name: "web", Status: providerv1.VMStatusStopped},
+	}}
+
+	tests := []struct {
+		name   string
+		action string
+		req    *providerv1.VMPowerRequest
+		want   string
+	}{
+		{name: "missing name", action: providerv1.PowerStart, req: &providerv1.VMPowerRequest{}, want: providerv1.ErrCodeInvalidSpec},
+		{name: "unknown vm", action: providerv1.PowerStart, req: &providerv1.VMPowerRequest{Name: "db"}, want: providerv1.ErrCodeNotFound},
+		{name: "reboot stopped vm", action: providerv1.PowerReboot, req: &providerv1.VMPowerRequest{Name: "web"}, want: providerv1.ErrCodeInvalidSpec},
+		{name: "pause stopped vm", action: providerv1.PowerPause, req: &providerv1.VMPowerRequest{Name: "web"}, want: providerv1.ErrCodeInvalidSpec},
+		{name: "unknown action", action: "hibernate", req: &providerv1.VMPowerRequest{Name: "web"}, want: providerv1.ErrCodeInvalidSpec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.VMPower(tt.action, tt.req)
+			if result.Success || result.Error.Code != tt.want {
+				t.Errorf("VMPower() = %+v, want %s", result.Error, tt.want)
+			}
+		})
+	}
+	if p.vms["web"].Status != providerv1.VMStatusStopped {
+		t.Errorf("rejected actions changed the status to %q", p.vms["web"].Status)
+	}
+}
@@ -29,6 +29,8 @@ import (
 	"github.com/digitalocean/go-libvirt"
 
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
 )
 
 // ProviderConfig holds configuration for the libvirt provider.
@@ -41,6 +43,13 @@ type ProviderConfig struct {
 	ISOTool string
 	// QemuImgPath is the path to qemu-img binary
 	QemuImgPath string
+	// DiskBackend selects how disks are created from their base image
+	DiskBackend common.DiskBackend
+	// DHCPBackend selects the DHCP and TFTP server of dnsmasq networks
+	DHCPBackend DHCPBackend
+	// SkipNetworkPreflight disables the check of the CIDRs of new networks
+	// against the networks of the host
+	SkipNetworkPreflight bool
 }
 
 // Provider is a libvirt-based provider that manages VMs, networks, and SSH keys.
@@ -51,6 +60,7 @@ type Provider struct {
 	keys     map[string]*providerv1.KeyState
 	networks map[string]*providerv1.NetworkState
 	vms      map[string]*providerv1.VMState
+	captures map[string]*capture
 	version  string
 }
 
@@ -58,6 +68,8 @@ type Provider struct {
 // It reads configuration from environment variables:
 //   - TESTENV_VM_LIBVIRT_URI: libvirt connection URI (default: qemu:///system)
 //   - TESTENV_VM_STATE_DIR: state directory (default: /var/lib/testenv-vm or ~/.testenv-vm)
+//   - TESTENV_VM_DISK_BACKEND: disk backend, "qcow2", "reflink" or "auto" (default: qcow2)
+//   - TESTENV_VM_DHCP_BACKEND: DHCP backend, "dnsmasq", "builtin" or "auto" (default: dnsmasq)
 //
 // It checks for required dependencies (genisoimage/mkisofs/xorriso, qemu-img)
 // and creates the necessary state directories.
@@ -68,7 +80,7 @@ func NewProvider() (*Provider, error) {
 	}
 
 	// Check for ISO generation tool
-	isoTool, err := findISOTool()
+	isoTool, err := common.FindISOTool()
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +127,7 @@ func NewProviderWithConfig(config ProviderConfig, conn *libvirt.Libvirt) *Provid
 
 // Close closes the libvirt connection.
 func (p *Provider) Close() error {
+	p.stopCaptures()
 	if p.conn != nil {
 		return p.conn.Disconnect()
 	}
@@ -161,22 +174,23 @@ func loadConfig() (ProviderConfig, error) {
 		}
 	}
 
-	return ProviderConfig{
-		URI:      uri,
-		StateDir: stateDir,
-	}, nil
-}
+	diskBackend, err := common.ParseDiskBackend(os.Getenv("TESTENV_VM_DISK_BACKEND"))
+	if err != nil {
+		return ProviderConfig{}, err
+	}
 
-// findISOTool finds an available ISO generation tool.
-func findISOTool() (string, error) {
-	tools := []string{"genisoimage", "mkisofs", "xorriso"}
-	for _, tool := range tools {
-		path, err := exec.LookPath(tool)
-		if err == nil {
-			return path, nil
-		}
+	dhcpBackend, err := ParseDHCPBackend(os.Getenv("TESTENV_VM_DHCP_BACKEND"))
+	if err != nil {
+		return ProviderConfig{}, err
 	}
-	return "", fmt.Errorf("cloud-init ISO generation requires genisoimage, mkisofs, or xorriso")
+
+	return ProviderConfig{
+		URI:                  uri,
+		StateDir:             stateDir,
+		DiskBackend:          diskBackend,
+		DHCPBackend:          dhcpBackend,
+		SkipNetworkPreflight: os.Getenv("TESTENV_VM_SKIP_NETWORK_PREFLIGHT") == "true",
+	}, nil
 }
 
 // createStateDirs creates the required state directories with proper permissions
@@ -187,6 +201,8 @@ func createStateDirs(stateDir string) error {
 		filepath.Join(stateDir, "keys"),
 		filepath.Join(stateDir, "disks"),
 		filepath.Join(stateDir, "cloudinit"),
+		filepath.Join(stateDir, "saves"),
+		filepath.Join(stateDir, "stopped"),
 	}
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
@@ -206,6 +222,35 @@ func createStateDirs(stateDir string) error {
 	return nil
 }
 
+// vmFilePaths returns the disk image and cloud-init ISO paths of a VM. VMs
+// labelled with an environment ID are placed in the directory of that
+// environment (see paths.Env), which is created on demand; others go directly
+// below the state directory.
+func (p *Provider) vmFilePaths(name string, labels map[string]string) (diskPath, isoPath string, err error) {
+	envID := labels[providerv1.LabelEnvironmentID]
+	if envID == "" {
+		return filepath.Join(p.config.StateDir, "disks", name+".qcow2"),
+			filepath.Join(p.config.StateDir, "cloudinit", name+".iso"), nil
+	}
+	if filepath.Base(envID) != envID || envID == "." || envID == ".." {
+		return "", "", fmt.Errorf("invalid environment ID %q", envID)
+	}
+
+	env := paths.New(p.config.StateDir).Env(envID)
+	layout := paths.New(p.config.StateDir)
+	for _, dir := range []string{layout.EnvsDir(), env.Dir, env.DisksDir(), env.CloudInitDir()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		if err := os.Chmod(dir, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to chmod directory %s: %w", dir, err)
+		}
+	}
+	setLibvirtACLs(env.DisksDir())
+	setLibvirtACLs(env.CloudInitDir())
+	return env.Disk(name), env.CloudInitISO(name), nil
+}
+
 // setLibvirtACLs sets ACLs on a directory for libvirt groups.
 // This allows the libvirt daemon to access disk files created by the provider.
 func setLibvirtACLs(dir string) {
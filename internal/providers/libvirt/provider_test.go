@@ -21,6 +21,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 )
 
 func TestNewProviderWithConfig(t *testing.T) {
@@ -117,6 +119,7 @@ func TestCreateStateDirs(t *testing.T) {
 		filepath.Join(stateDir, "keys"),
 		filepath.Join(stateDir, "disks"),
 		filepath.Join(stateDir, "cloudinit"),
+		filepath.Join(stateDir, "saves"),
 	}
 
 	for _, dir := range expectedDirs {
@@ -222,3 +225,35 @@ func TestLoadConfig_CustomEnvVars(t *testing.T) {
 		t.Errorf("Expected custom StateDir, got %s", config.StateDir)
 	}
 }
+
+func TestVMFilePaths(t *testing.T) {
+	stateDir := t.TempDir()
+	p := &Provider{config: ProviderConfig{StateDir: stateDir}}
+
+	disk, iso, err := p.vmFilePaths("web", nil)
+	if err != nil {
+		t.Fatalf("vmFilePaths() error = %v", err)
+	}
+	if disk != filepath.Join(stateDir, "disks", "web.qcow2") || iso != filepath.Join(stateDir, "cloudinit", "web.iso") {
+		t.Errorf("unlabelled VM: got %s, %s", disk, iso)
+	}
+
+	labels := map[string]string{providerv1.LabelEnvironmentID: "env-1"}
+	disk, iso, err = p.vmFilePaths("web", labels)
+	if err != nil {
+		t.Fatalf("vmFilePaths() error = %v", err)
+	}
+	if disk != filepath.Join(stateDir, "envs", "env-1", "disks", "web.qcow2") ||
+		iso != filepath.Join(stateDir, "envs", "env-1", "cloudinit", "web.iso") {
+		t.Errorf("labelled VM: got %s, %s", disk, iso)
+	}
+	for _, path := range []string{disk, iso} {
+		if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+			t.Errorf("%s was not created: %v", filepath.Dir(path), err)
+		}
+	}
+
+	if _, _, err := p.vmFilePaths("web", map[string]string{providerv1.LabelEnvironmentID: "../x"}); err == nil {
+		t.Error("expected an error for an environment ID with a path separator")
+	}
+}
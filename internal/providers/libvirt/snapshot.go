@@ -0,0 +1,127 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+// snapshotFlags creates an external, disk-only snapshot without libvirt
+// metadata: the current disk becomes read-only and the domain continues on a
+// new overlay. The frozen disk is crash-consistent.
+const snapshotFlags = libvirt.DomainSnapshotCreateDiskOnly |
+	libvirt.DomainSnapshotCreateNoMetadata |
+	libvirt.DomainSnapshotCreateAtomic
+
+// snapshotOverlayPath returns the path of the n-th overlay created on top of
+// diskPath, e.g. vm.snap1.qcow2 for vm.qcow2.
+func snapshotOverlayPath(diskPath string, n int) string {
+	return fmt.Sprintf("%s.snap%d.qcow2", strings.TrimSuffix(diskPath, ".qcow2"), n)
+}
+
+// snapshotXML returns the domain snapshot moving the disk at diskPath to an
+// external overlay at overlayPath.
+func snapshotXML(name, diskPath, overlayPath string) string {
+	return fmt.Sprintf(`<domainsnapshot>
+  <name>%s</name>
+  <disks>
+    <disk name='%s' snapshot='external'>
+      <driver type='qcow2'/>
+      <source file='%s'/>
+    </disk>
+  </disks>
+</domainsnapshot>`, xmlEscape(name), xmlEscape(diskPath), xmlEscape(overlayPath))
+}
+
+// frozenDisks returns the disks frozen by VMSnapshot, oldest first.
+func frozenDisks(vm *providerv1.VMState) []string {
+	return providerStateStrings(vm, "frozenDisks")
+}
+
+// providerStateStrings returns a string list of the provider state of a VM,
+// which is a []any once the state went through JSON.
+func providerStateStrings(vm *providerv1.VMState, key string) []string {
+	var paths []string
+	switch v := vm.ProviderState[key].(type) {
+	case []string:
+		paths = append(paths, v...)
+	case []any:
+		for _, p := range v {
+			if s, ok := p.(string); ok {
+				paths = append(paths, s)
+			}
+		}
+	}
+	return paths
+}
+
+// VMSnapshot freezes the disk of a running VM and returns its path. The VM
+// keeps running on a new overlay; the frozen disk is kept until the VM is
+// deleted. Encrypted disks are not supported.
+func (p *Provider) VMSnapshot(req *providerv1.VMSnapshotRequest) *providerv1.OperationResult {
+	if req.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name is required"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	if _, encrypted := vm.ProviderState["diskSecretUUID"]; encrypted {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("vm " + req.Name + " has an encrypted disk and cannot be snapshotted"))
+	}
+	diskPath, _ := vm.ProviderState["diskPath"].(string)
+	if diskPath == "" {
+		return providerv1.ErrorResult(providerv1.NewProviderError("vm "+req.Name+" has no recorded disk", false))
+	}
+	dom, err := p.conn.DomainLookupByName(req.Name)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	domState, _, err := p.conn.DomainGetState(dom, 0)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain state: "+err.Error(), true))
+	}
+	if libvirt.DomainState(domState) != libvirt.DomainRunning {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("vm %s is not running", req.Name), true))
+	}
+
+	frozen := frozenDisks(vm)
+	overlayPath := snapshotOverlayPath(diskPath, len(frozen)+1)
+	snapName := fmt.Sprintf("%s-snap%d", req.Name, len(frozen)+1)
+	if _, err := p.conn.DomainSnapshotCreateXML(dom, snapshotXML(snapName, diskPath, overlayPath), uint32(snapshotFlags)); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("failed to snapshot vm %s: %s", req.Name, err.Error()), true))
+	}
+	if labels, err := common.ReadFileLabels(diskPath); err == nil {
+		if err := common.LabelFile(overlayPath, labels); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+		}
+	}
+
+	vm.ProviderState["diskPath"] = overlayPath
+	vm.ProviderState["frozenDisks"] = append(frozen, diskPath)
+	return providerv1.SuccessResult(providerv1.VMSnapshot{Name: req.Name, BaseImage: diskPath})
+}
@@ -0,0 +1,92 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestSnapshotOverlayPath(t *testing.T) {
+	if got := snapshotOverlayPath("/var/lib/disks/vm.qcow2", 1); got != "/var/lib/disks/vm.snap1.qcow2" {
+		t.Errorf("snapshotOverlayPath() = %q", got)
+	}
+	if got := snapshotOverlayPath("/var/lib/disks/vm.snap1.qcow2", 2); got != "/var/lib/disks/vm.snap1.snap2.qcow2" {
+		t.Errorf("snapshotOverlayPath() = %q", got)
+	}
+}
+
+func TestSnapshotXML(t *testing.T) {
+	var snap struct {
+		Name  string `xml:"name"`
+		Disks []struct {
+			Name     string `xml:"name,attr"`
+			Snapshot string `xml:"snapshot,attr"`
+			Source   struct {
+				File string `xml:"file,attr"`
+			} `xml:"source"`
+		} `xml:"disks>disk"`
+	}
+	if err := xml.Unmarshal([]byte(snapshotXML("vm-snap1", "/d/vm&1.qcow2", "/d/vm&1.snap1.qcow2")), &snap); err != nil {
+		t.Fatalf("snapshotXML() is not valid XML: %v", err)
+	}
+	if snap.Name != "vm-snap1" || len(snap.Disks) != 1 {
+		t.Fatalf("snapshotXML() = %+v", snap)
+	}
+	d := snap.Disks[0]
+	if d.Name != "/d/vm&1.qcow2" || d.Snapshot != "external" || d.Source.File != "/d/vm&1.snap1.qcow2" {
+		t.Errorf("snapshotXML() disk = %+v", d)
+	}
+}
+
+func TestFrozenDisks(t *testing.T) {
+	tests := []struct {
+		name  string
+		state map[string]any
+		want  []string
+	}{
+		{name: "strings", state: map[string]any{"frozenDisks": []string{"a", "b"}}, want: []string{"a", "b"}},
+		{name: "json", state: map[string]any{"frozenDisks": []any{"a", "b"}}, want: []string{"a", "b"}},
+		{name: "none", state: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := frozenDisks(&providerv1.VMState{ProviderState: tt.state})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("frozenDisks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMSnapshot_Validation(t *testing.T) {
+	p := &Provider{vms: map[string]*providerv1.VMState{
+		"enc": {Name: "enc", ProviderState: map[string]any{"diskPath": "/d/enc.qcow2", "diskSecretUUID": "u"}},
+	}}
+	if res := p.VMSnapshot(&providerv1.VMSnapshotRequest{}); res.Success {
+		t.Error("VMSnapshot() without name should fail")
+	}
+	if res := p.VMSnapshot(&providerv1.VMSnapshotRequest{Name: "missing"}); res.Success || res.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("VMSnapshot() of unknown vm = %+v, want not found", res.Error)
+	}
+	if res := p.VMSnapshot(&providerv1.VMSnapshotRequest{Name: "enc"}); res.Success || res.Error.Code != providerv1.ErrCodeInvalidSpec {
+		t.Errorf("VMSnapshot() of encrypted vm = %+v, want invalid spec", res.Error)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// domainDevices are the devices of a domain XML that have statistics, and
+// its vsock device.
+type domainDevices struct {
+	Disks []struct {
+		Device string `xml:"device,attr"`
+		Target struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"target"`
+	} `xml:"devices>disk"`
+	Interfaces []struct {
+		MAC struct {
+			Address string `xml:"address,attr"`
+		} `xml:"mac"`
+		Target struct {
+			Dev string `xml:"dev,attr"`
+		} `xml:"target"`
+	} `xml:"devices>interface"`
+	Vsock struct {
+		CID struct {
+			Address uint32 `xml:"address,attr"`
+		} `xml:"cid"`
+	} `xml:"devices>vsock"`
+}
+
+// domainInterface is a network interface of a running domain.
+type domainInterface struct {
+	dev string
+	mac string
+}
+
+// parseDomainDevices returns the target devices of the disks, excluding
+// CD-ROMs such as the cloud-init ISO, and the network interfaces of a
+// running domain's XML, in device order.
+func parseDomainDevices(domainXML string) (disks []string, ifaces []domainInterface, err error) {
+	var d domainDevices
+	if err := xml.Unmarshal([]byte(domainXML), &d); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse domain XML: %w", err)
+	}
+	for _, disk := range d.Disks {
+		if disk.Device == "disk" && disk.Target.Dev != "" {
+			disks = append(disks, disk.Target.Dev)
+		}
+	}
+	for _, iface := range d.Interfaces {
+		if iface.Target.Dev != "" {
+			ifaces = append(ifaces, domainInterface{dev: iface.Target.Dev, mac: iface.MAC.Address})
+		}
+	}
+	return disks, ifaces, nil
+}
+
+// parseVsockCID returns the context ID of the vsock device of a running
+// domain's XML, or zero if it has none.
+func parseVsockCID(domainXML string) (uint32, error) {
+	var d domainDevices
+	if err := xml.Unmarshal([]byte(domainXML), &d); err != nil {
+		return 0, fmt.Errorf("failed to parse domain XML: %w", err)
+	}
+	return d.Vsock.CID.Address, nil
+}
+
+// cpuPercent returns the CPU usage of cpuTimeNs over elapsed, relative to
+// all vCPUs.
+func cpuPercent(cpuTimeNs uint64, elapsed time.Duration, vcpus int) float64 {
+	if elapsed <= 0 || vcpus <= 0 {
+		return 0
+	}
+	return float64(cpuTimeNs) / float64(elapsed.Nanoseconds()) / float64(vcpus) * 100
+}
+
+// applyMemoryStats sets the memory fields of stats from the memory
+// statistics of a domain, in KiB.
+func applyMemoryStats(stats *providerv1.VMStats, memStats []libvirt.DomainMemoryStat) {
+	values := make(map[libvirt.DomainMemoryStatTags]uint64, len(memStats))
+	for _, s := range memStats {
+		values[libvirt.DomainMemoryStatTags(s.Tag)] = s.Val
+	}
+	if rss, ok := values[libvirt.DomainMemoryStatRss]; ok {
+		stats.MemoryRSSMB = int(rss / 1024)
+	}
+	available, ok := values[libvirt.DomainMemoryStatAvailable]
+	if !ok {
+		return
+	}
+	free, ok := values[libvirt.DomainMemoryStatUsable]
+	if !ok {
+		free, ok = values[libvirt.DomainMemoryStatUnused]
+	}
+	if ok && free <= available {
+		stats.MemoryUsedMB = int((available - free) / 1024)
+	}
+}
+
+// VMStats returns a snapshot of the resource usage of a running VM. The CPU
+// usage is measured over the interval of the request, during which the call
+// blocks.
+func (p *Provider) VMStats(req *providerv1.VMStatsRequest) *providerv1.OperationResult {
+	if req.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name is required"))
+	}
+	p.mu.RLock()
+	_, exists := p.vms[req.Name]
+	p.mu.RUnlock()
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	dom, err := p.conn.DomainLookupByName(req.Name)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+
+	domainXML, err := p.conn.DomainGetXMLDesc(dom, 0)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain XML: "+err.Error(), true))
+	}
+	disks, ifaces, err := parseDomainDevices(domainXML)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+
+	state, _, _, _, startCPU, err := p.conn.DomainGetInfo(dom)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain info: "+err.Error(), true))
+	}
+	if libvirt.DomainState(state) != libvirt.DomainRunning {
+		return providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("vm %s is not running", req.Name), true))
+	}
+	start := time.Now()
+	time.Sleep(time.Duration(req.StatsInterval()) * time.Millisecond)
+	_, _, memoryKiB, vcpus, cpuTime, err := p.conn.DomainGetInfo(dom)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get domain info: "+err.Error(), true))
+	}
+	now := time.Now()
+
+	stats := &providerv1.VMStats{
+		Name:      req.Name,
+		Timestamp: now.UTC().Format(time.RFC3339),
+		VCPUs:     int(vcpus),
+		CPUTimeNs: cpuTime,
+		MemoryMB:  int(memoryKiB / 1024),
+	}
+	if cpuTime >= startCPU {
+		stats.CPUPercent = cpuPercent(cpuTime-startCPU, now.Sub(start), int(vcpus))
+	}
+	// Memory statistics need the balloon driver; without it, only the RSS
+	// may be known.
+	if memStats, err := p.conn.DomainMemoryStats(dom, uint32(libvirt.DomainMemoryStatNr), 0); err == nil {
+		applyMemoryStats(stats, memStats)
+	}
+
+	for _, dev := range disks {
+		rdReq, rdBytes, wrReq, wrBytes, _, err := p.conn.DomainBlockStats(dom, dev)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(
+				fmt.Sprintf("failed to get statistics of disk %s: %s", dev, err.Error()), true))
+		}
+		disk := providerv1.DiskStats{
+			Device:        dev,
+			ReadBytes:     rdBytes,
+			WriteBytes:    wrBytes,
+			ReadRequests:  rdReq,
+			WriteRequests: wrReq,
+		}
+		if allocation, capacity, physical, err := p.conn.DomainGetBlockInfo(dom, dev, 0); err == nil {
+			disk.AllocationBytes, disk.CapacityBytes, disk.PhysicalBytes = allocation, capacity, physical
+		}
+		stats.Disks = append(stats.Disks, disk)
+	}
+
+	for _, iface := range ifaces {
+		rxBytes, rxPackets, rxErrs, rxDrop, txBytes, txPackets, txErrs, txDrop, err := p.conn.DomainInterfaceStats(dom, iface.dev)
+		if err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(
+				fmt.Sprintf("failed to get statistics of interface %s: %s", iface.dev, err.Error()), true))
+		}
+		stats.Interfaces = append(stats.Interfaces, providerv1.InterfaceStats{
+			Device:    iface.dev,
+			MAC:       iface.mac,
+			RxBytes:   rxBytes,
+			TxBytes:   txBytes,
+			RxPackets: rxPackets,
+			TxPackets: txPackets,
+			RxErrors:  rxErrs,
+			TxErrors:  txErrs,
+			RxDrops:   rxDrop,
+			TxDrops:   txDrop,
+		})
+	}
+	return providerv1.SuccessResult(stats)
+}
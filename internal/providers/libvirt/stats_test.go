@@ -0,0 +1,103 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package libvirt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/digitalocean/go-libvirt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestParseDomainDevices(t *testing.T) {
+	domainXML := `<domain type='kvm'>
+  <devices>
+    <disk type='file' device='disk'><source file='/d/vm.qcow2'/><target dev='vda' bus='virtio'/></disk>
+    <disk type='file' device='cdrom'><source file='/d/cidata.iso'/><target dev='sda' bus='sata'/></disk>
+    <disk type='file' device='disk'><source file='/d/data.qcow2'/><target dev='vdb' bus='virtio'/></disk>
+    <interface type='network'><mac address='52:54:00:00:00:01'/><target dev='vnet3'/></interface>
+    <interface type='network'><mac address='52:54:00:00:00:02'/><target dev='vnet4'/></interface>
+  </devices>
+</domain>`
+
+	disks, ifaces, err := parseDomainDevices(domainXML)
+	if err != nil {
+		t.Fatalf("parseDomainDevices() error = %v", err)
+	}
+	if len(disks) != 2 || disks[0] != "vda" || disks[1] != "vdb" {
+		t.Errorf("disks = %v, want [vda vdb]", disks)
+	}
+	want := []domainInterface{{dev: "vnet3", mac: "52:54:00:00:00:01"}, {dev: "vnet4", mac: "52:54:00:00:00:02"}}
+	if len(ifaces) != 2 || ifaces[0] != want[0] || ifaces[1] != want[1] {
+		t.Errorf("interfaces = %+v, want %+v", ifaces, want)
+	}
+
+	if _, _, err := parseDomainDevices("<domain"); err == nil {
+		t.Error("parseDomainDevices() expected error for invalid XML")
+	}
+}
+
+func TestParseVsockCID(t *testing.T) {
+	domainXML := `<domain type='kvm'>
+  <devices>
+    <vsock model='virtio'><cid auto='yes' address='5'/></vsock>
+  </devices>
+</domain>`
+
+	cid, err := parseVsockCID(domainXML)
+	if err != nil {
+		t.Fatalf("parseVsockCID() error = %v", err)
+	}
+	if cid != 5 {
+		t.Errorf("cid = %d, want 5", cid)
+	}
+
+	if cid, err := parseVsockCID("<domain><devices/></domain>"); err != nil || cid != 0 {
+		t.Errorf("parseVsockCID() without vsock = %d, %v, want 0, nil", cid, err)
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	// One second of CPU time over one second on 2 vCPUs is half the capacity.
+	if got := cpuPercent(uint64(time.Second), time.Second, 2); got != 50 {
+		t.Errorf("cpuPercent() = %v, want 50", got)
+	}
+	if got := cpuPercent(100, 0, 2); got != 0 {
+		t.Errorf("cpuPercent() with no elapsed time = %v, want 0", got)
+	}
+}
+
+func TestApplyMemoryStats(t *testing.T) {
+	stats := &providerv1.VMStats{}
+	applyMemoryStats(stats, []libvirt.DomainMemoryStat{
+		{Tag: int32(libvirt.DomainMemoryStatRss), Val: 800 * 1024},
+		{Tag: int32(libvirt.DomainMemoryStatAvailable), Val: 1000 * 1024},
+		{Tag: int32(libvirt.DomainMemoryStatUnused), Val: 600 * 1024},
+		{Tag: int32(libvirt.DomainMemoryStatUsable), Val: 700 * 1024},
+	})
+	if stats.MemoryRSSMB != 800 || stats.MemoryUsedMB != 300 {
+		t.Errorf("stats = %+v, want 800 MB RSS and 300 MB used", stats)
+	}
+
+	stats = &providerv1.VMStats{}
+	applyMemoryStats(stats, []libvirt.DomainMemoryStat{{Tag: int32(libvirt.DomainMemoryStatRss), Val: 2048}})
+	if stats.MemoryRSSMB != 2 || stats.MemoryUsedMB != 0 {
+		t.Errorf("stats without balloon = %+v", stats)
+	}
+}
@@ -18,9 +18,17 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/xml"
 	"fmt"
+	"maps"
 	"net"
+	"path/filepath"
+	"slices"
+	"strings"
 	"text/template"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
 )
 
 // NetworkConfig holds configuration for generating network XML.
@@ -32,6 +40,130 @@ type NetworkConfig struct {
 	DHCPEnabled bool
 	DHCPStart   string
 	DHCPEnd     string
+	// DHCPHosts are the reservations of the DHCP server.
+	DHCPHosts []DHCPHost
+	// DNSHosts, DNSTXT and CNAMEs are the records served by dnsmasq.
+	DNSHosts []DNSHostEntry
+	DNSTXT   []providerv1.DNSRecord
+	// CNAMEs are dnsmasq cname options ("alias,target"), as libvirt has no
+	// CNAME element.
+	CNAMEs []string
+	// NTPServer is advertised to DHCP clients (option 42) when set.
+	NTPServer string
+	// DNSDisabled turns the network DNS server off, so that libvirt runs no
+	// dnsmasq for a network without DHCP.
+	DNSDisabled bool
+	// TFTPRoot is served over TFTP by dnsmasq, and BootFile handed out to
+	// DHCP clients with BootServer as next server, when set.
+	TFTPRoot   string
+	BootFile   string
+	BootServer string
+	// BootOptions are further dnsmasq options of network boot, as libvirt
+	// has a single boot file and no DHCP option element.
+	BootOptions []string
+	// MTU of the bridge. Zero keeps the default.
+	MTU int
+	// Labels are recorded in the network metadata.
+	Labels []common.Label
+}
+
+// DNSHostEntry is a <host> element of the network DNS: the names resolving
+// to one address.
+type DNSHostEntry struct {
+	IP        string
+	Hostnames []string
+}
+
+// DHCPHost is a <host> element of the network DHCP: the IP reserved for a
+// MAC address.
+type DHCPHost struct {
+	MAC  string
+	IP   string
+	Name string
+}
+
+// newDHCPHosts validates static leases and converts them into DHCP hosts.
+func newDHCPHosts(leases []providerv1.StaticLease) ([]DHCPHost, error) {
+	hosts := make([]DHCPHost, 0, len(leases))
+	for _, l := range leases {
+		mac, err := net.ParseMAC(l.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("dhcp host %q: invalid MAC address %q", l.IP, l.MAC)
+		}
+		ip := net.ParseIP(l.IP).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("dhcp host %q: invalid IPv4 address", l.IP)
+		}
+		hosts = append(hosts, DHCPHost{MAC: mac.String(), IP: ip.String(), Name: l.Hostname})
+	}
+	return hosts, nil
+}
+
+// setNetboot sets the TFTP root, boot files and boot options of tftp in a
+// config served by dnsmasq. UEFI clients (architectures 6 to 11) are
+// tagged to get the EFI boot file.
+func (c *NetworkConfig) setNetboot(tftp *providerv1.TFTPSpec, nextServer string) error {
+	if tftp == nil || !tftp.Enabled {
+		return nil
+	}
+	root, err := filepath.Abs(tftp.Root)
+	if err != nil || tftp.Root == "" {
+		return fmt.Errorf("tftp requires a root directory")
+	}
+	c.TFTPRoot, c.BootFile, c.BootServer = root, tftp.BootFile, nextServer
+	if tftp.BootFileEFI != "" {
+		for arch := 6; arch <= 11; arch++ {
+			c.BootOptions = append(c.BootOptions, fmt.Sprintf("dhcp-match=set:efi,option:client-arch,%d", arch))
+		}
+		boot := "dhcp-boot=tag:efi," + tftp.BootFileEFI
+		if nextServer != "" {
+			boot += ",," + nextServer
+		}
+		c.BootOptions = append(c.BootOptions, boot)
+	}
+	options, err := dhcpBootOptions(tftp.DHCPBootOptions)
+	if err != nil {
+		return err
+	}
+	for _, code := range slices.Sorted(maps.Keys(options)) {
+		c.BootOptions = append(c.BootOptions, fmt.Sprintf("dhcp-option=%d,%s", code, options[code]))
+	}
+	return nil
+}
+
+// newDNSRecords groups DNS records into the host entries, TXT records and
+// CNAME options of a network.
+func newDNSRecords(records []providerv1.DNSRecord) (hosts []DNSHostEntry, txt []providerv1.DNSRecord, cnames []string, err error) {
+	index := make(map[string]int)
+	for _, r := range records {
+		if r.Name == "" || r.Value == "" {
+			return nil, nil, nil, fmt.Errorf("dns record %q: name and value are required", r.Name)
+		}
+		switch r.Type {
+		case "", providerv1.DNSRecordA, providerv1.DNSRecordAAAA:
+			ip := net.ParseIP(r.Value)
+			if ip == nil || (r.Type == providerv1.DNSRecordAAAA) == (ip.To4() != nil) {
+				return nil, nil, nil, fmt.Errorf("dns record %q: invalid address %q", r.Name, r.Value)
+			}
+			i, ok := index[ip.String()]
+			if !ok {
+				i = len(hosts)
+				index[ip.String()] = i
+				hosts = append(hosts, DNSHostEntry{IP: ip.String()})
+			}
+			hosts[i].Hostnames = append(hosts[i].Hostnames, r.Name)
+		case providerv1.DNSRecordTXT:
+			txt = append(txt, r)
+		case providerv1.DNSRecordCNAME:
+			if strings.ContainsAny(r.Name+r.Value, ",\n") {
+				return nil, nil, nil, fmt.Errorf("dns record %q: invalid CNAME", r.Name)
+			}
+			cnames = append(cnames, r.Name+","+r.Value)
+		default:
+			return nil, nil, nil, fmt.Errorf("dns record %q: unsupported type %q", r.Name, r.Type)
+		}
+	}
+	return hosts, txt, cnames, nil
 }
 
 // NetworkInterface describes a single NIC to attach to a domain.
@@ -40,11 +172,18 @@ type NetworkInterface struct {
 	Name string
 	// HasNetworkBoot enables PXE ROM on this interface.
 	HasNetworkBoot bool
+	// MAC is the MAC address of the interface. Empty lets libvirt assign one.
+	MAC string
+	// Model is the NIC model. Empty means virtio.
+	Model string
+	// MTU of the interface. Zero keeps the MTU of the network.
+	MTU int
 }
 
 // DomainConfig holds configuration for generating domain XML.
 type DomainConfig struct {
 	Name         string
+	UUID         string // Empty lets libvirt assign one.
 	MemoryMB     int
 	VCPU         int
 	DiskPath     string
@@ -52,6 +191,79 @@ type DomainConfig struct {
 	Networks     []NetworkInterface // One or more NICs to attach.
 	BootOrder    []string           // Boot device order: "network", "hd", "cdrom"
 	Firmware     string             // "bios" or "uefi"
+	Security     *SecurityLabel     // nil keeps the hypervisor default seclabel
+	// DiskSecretUUID is the libvirt secret holding the LUKS passphrase of the
+	// main disk. Empty means the disk is not encrypted.
+	DiskSecretUUID string
+	// Labels are recorded in the domain <metadata> to identify its owner.
+	Labels []common.Label
+	// Vsock adds a virtio-vsock device. Nil means none.
+	Vsock *VsockDevice
+	// DataDisks are attached after the main disk.
+	DataDisks []DataDisk
+	// HostDevices are the host PCI devices and mediated devices passed
+	// through to the domain.
+	HostDevices []HostDevice
+}
+
+// DataDisk describes a data disk of a domain.
+type DataDisk struct {
+	// Path of the image.
+	Path string
+	// Format of the image: qcow2 or raw.
+	Format string
+	// Target is the virtio-blk device name in the guest, e.g. vdb.
+	Target string
+}
+
+// VsockDevice describes the <vsock> element of a domain.
+type VsockDevice struct {
+	// CID is the guest context ID. Zero lets libvirt assign a free one.
+	CID uint32
+}
+
+// newVsockDevice converts the provider API vsock spec into a VsockDevice.
+// It returns nil when no vsock device is requested.
+func newVsockDevice(spec *providerv1.VsockSpec) *VsockDevice {
+	if spec == nil {
+		return nil
+	}
+	return &VsockDevice{CID: spec.CID}
+}
+
+// SecretConfig holds configuration for generating a volume secret XML.
+type SecretConfig struct {
+	// Description is a human-readable description of the secret.
+	Description string
+	// VolumePath is the disk path the secret unlocks (usage type volume).
+	VolumePath string
+}
+
+// SecurityLabel describes the <seclabel> element of a domain.
+type SecurityLabel struct {
+	// Disabled renders <seclabel type='none'/>, turning confinement off.
+	Disabled bool
+	// Model is the security driver: "selinux" or "apparmor". Empty lets libvirt pick.
+	Model string
+	// Label is a static label (SELinux context or AppArmor profile name).
+	// Empty renders a dynamic label.
+	Label string
+	// Relabel controls relabeling of image files for static labels.
+	Relabel bool
+}
+
+// newSecurityLabel converts the provider API security spec into a SecurityLabel.
+// It returns nil when no security options are set.
+func newSecurityLabel(spec *providerv1.SecuritySpec) *SecurityLabel {
+	if spec == nil {
+		return nil
+	}
+	return &SecurityLabel{
+		Disabled: spec.Disabled,
+		Model:    spec.Model,
+		Label:    spec.Label,
+		Relabel:  spec.Relabel,
+	}
 }
 
 // generateBridgeName generates a unique bridge name from the network name.
@@ -111,37 +323,111 @@ func parseCIDR(cidr string) (gateway, netmask, dhcpStart, dhcpEnd string, err er
 }
 
 // Network XML templates
-const natNetworkTemplate = `<network>
-    <name>{{.Name}}</name>
+const (
+	networkOpenTemplate = `<network{{if or .CNAMEs .NTPServer .BootOptions}} xmlns:dnsmasq='http://libvirt.org/schemas/network/dnsmasq/1.0'{{end}}>`
+
+	networkDNSTemplate = `
+{{- if .DNSDisabled}}
+    <dns enable='no'/>
+{{- else if or .DNSHosts .DNSTXT}}
+    <dns>
+{{- range .DNSHosts}}
+        <host ip='{{.IP}}'>
+{{- range .Hostnames}}
+            <hostname>{{xml .}}</hostname>
+{{- end}}
+        </host>
+{{- end}}
+{{- range .DNSTXT}}
+        <txt name='{{xml .Name}}' value='{{xml .Value}}'/>
+{{- end}}
+    </dns>
+{{- end}}`
+
+	// labelsMetadataTemplate records the labels of a domain or network in
+	// its metadata, where parseLabels reads them back.
+	labelsMetadataTemplate = `
+{{- if .Labels}}
+    <metadata>
+        <testenv:labels xmlns:testenv='https://github.com/alexandremahdhaoui/testenv-vm'>
+{{- range .Labels}}
+            <testenv:label key='{{xml .Key}}'>{{xml .Value}}</testenv:label>
+{{- end}}
+        </testenv:labels>
+    </metadata>
+{{- end}}`
+
+	networkOptionsTemplate = `
+{{- if or .CNAMEs .NTPServer .BootOptions}}
+    <dnsmasq:options>
+{{- range .CNAMEs}}
+        <dnsmasq:option value='cname={{xml .}}'/>
+{{- end}}
+{{- if .NTPServer}}
+        <dnsmasq:option value='dhcp-option=option:ntp-server,{{.NTPServer}}'/>
+{{- end}}
+{{- range .BootOptions}}
+        <dnsmasq:option value='{{xml .}}'/>
+{{- end}}
+    </dnsmasq:options>
+{{- end}}`
+)
+
+const natNetworkTemplate = networkOpenTemplate + `
+    <name>{{.Name}}</name>` + labelsMetadataTemplate + `
     <bridge name='{{.BridgeName}}'/>
+{{- if .MTU}}
+    <mtu size='{{.MTU}}'/>
+{{- end}}
     <forward mode='nat'>
         <nat>
             <port start='1024' end='65535'/>
         </nat>
-    </forward>
+    </forward>` + networkDNSTemplate + `
     <ip address='{{.Gateway}}' netmask='{{.Netmask}}'>
+{{- if .TFTPRoot}}
+        <tftp root='{{xml .TFTPRoot}}'/>
+{{- end}}
 {{- if .DHCPEnabled}}
         <dhcp>
             <range start='{{.DHCPStart}}' end='{{.DHCPEnd}}'/>
+{{- range .DHCPHosts}}
+            <host mac='{{.MAC}}'{{if .Name}} name='{{xml .Name}}'{{end}} ip='{{.IP}}'/>
+{{- end}}
+{{- if .BootFile}}
+            <bootp file='{{xml .BootFile}}'{{if .BootServer}} server='{{.BootServer}}'{{end}}/>
+{{- end}}
         </dhcp>
 {{- end}}
-    </ip>
+    </ip>` + networkOptionsTemplate + `
 </network>`
 
-const isolatedNetworkTemplate = `<network>
-    <name>{{.Name}}</name>
+const isolatedNetworkTemplate = networkOpenTemplate + `
+    <name>{{.Name}}</name>` + labelsMetadataTemplate + `
     <bridge name='{{.BridgeName}}'/>
+{{- if .MTU}}
+    <mtu size='{{.MTU}}'/>
+{{- end}}` + networkDNSTemplate + `
     <ip address='{{.Gateway}}' netmask='{{.Netmask}}'>
+{{- if .TFTPRoot}}
+        <tftp root='{{xml .TFTPRoot}}'/>
+{{- end}}
 {{- if .DHCPEnabled}}
         <dhcp>
             <range start='{{.DHCPStart}}' end='{{.DHCPEnd}}'/>
+{{- range .DHCPHosts}}
+            <host mac='{{.MAC}}'{{if .Name}} name='{{xml .Name}}'{{end}} ip='{{.IP}}'/>
+{{- end}}
+{{- if .BootFile}}
+            <bootp file='{{xml .BootFile}}'{{if .BootServer}} server='{{.BootServer}}'{{end}}/>
+{{- end}}
         </dhcp>
 {{- end}}
-    </ip>
+    </ip>` + networkOptionsTemplate + `
 </network>`
 
 const bridgeNetworkTemplate = `<network>
-    <name>{{.Name}}</name>
+    <name>{{.Name}}</name>` + labelsMetadataTemplate + `
     <forward mode='bridge'/>
     <bridge name='{{.BridgeName}}'/>
 </network>`
@@ -164,6 +450,9 @@ func generateBridgeNetworkXML(config NetworkConfig) (string, error) {
 // Domain XML template
 const domainTemplate = `<domain type='kvm'>
     <name>{{.Name}}</name>
+{{- if .UUID}}
+    <uuid>{{.UUID}}</uuid>
+{{- end}}` + labelsMetadataTemplate + `
     <memory unit='MiB'>{{.MemoryMB}}</memory>
     <vcpu>{{.VCPU}}</vcpu>
     <os>
@@ -183,10 +472,23 @@ const domainTemplate = `<domain type='kvm'>
     <devices>
         <!-- Main disk -->
         <disk type='file' device='disk'>
-            <driver name='qemu' type='qcow2'/>
+            <driver name='qemu' type='qcow2' discard='unmap' detect_zeroes='unmap'/>
             <source file='{{.DiskPath}}'/>
             <target dev='vda' bus='virtio'/>
+{{- if .DiskSecretUUID}}
+            <encryption format='luks'>
+                <secret type='passphrase' uuid='{{.DiskSecretUUID}}'/>
+            </encryption>
+{{- end}}
         </disk>
+{{- range .DataDisks}}
+        <!-- Data disk, left out of disk-only snapshots -->
+        <disk type='file' device='disk' snapshot='no'>
+            <driver name='qemu' type='{{.Format}}' discard='unmap' detect_zeroes='unmap'/>
+            <source file='{{.Path}}'/>
+            <target dev='{{.Target}}' bus='virtio'/>
+        </disk>
+{{- end}}
 {{if .CloudInitISO}}
         <!-- Cloud-init ISO -->
         <disk type='file' device='cdrom'>
@@ -199,8 +501,14 @@ const domainTemplate = `<domain type='kvm'>
         <!-- Network interfaces -->
 {{- range .Networks}}
         <interface type='network'>
+{{- if .MAC}}
+            <mac address='{{.MAC}}'/>
+{{- end}}
             <source network='{{.Name}}'/>
-            <model type='virtio'/>
+            <model type='{{or .Model "virtio"}}'/>
+{{- if .MTU}}
+            <mtu size='{{.MTU}}'/>
+{{- end}}
 {{- if .HasNetworkBoot}}
             <rom bar='on'/>
 {{- end}}
@@ -214,7 +522,48 @@ const domainTemplate = `<domain type='kvm'>
         <console type='pty'>
             <target type='serial' port='0'/>
         </console>
+{{- with .Vsock}}
+
+        <!-- Host-guest channel -->
+        <vsock model='virtio'>
+{{- if .CID}}
+            <cid auto='no' address='{{.CID}}'/>
+{{- else}}
+            <cid auto='yes'/>
+{{- end}}
+        </vsock>
+{{- end}}
+{{- if .HostDevices}}
+
+        <!-- Passthrough devices -->
+{{- range .HostDevices}}
+{{- if .PCI}}
+        <hostdev mode='subsystem' type='pci' managed='yes'>
+            <source>
+                <address domain='0x{{.PCI.Domain}}' bus='0x{{.PCI.Bus}}' slot='0x{{.PCI.Slot}}' function='0x{{.PCI.Function}}'/>
+            </source>
+        </hostdev>
+{{- else}}
+        <hostdev mode='subsystem' type='mdev' model='vfio-pci'>
+            <source>
+                <address uuid='{{.MdevUUID}}'/>
+            </source>
+        </hostdev>
+{{- end}}
+{{- end}}
+{{- end}}
     </devices>
+{{- with .Security}}
+{{- if .Disabled}}
+    <seclabel type='none'/>
+{{- else if .Label}}
+    <seclabel type='static'{{if .Model}} model='{{.Model}}'{{end}} relabel='{{if .Relabel}}yes{{else}}no{{end}}'>
+        <label>{{.Label}}</label>
+    </seclabel>
+{{- else}}
+    <seclabel type='dynamic'{{if .Model}} model='{{.Model}}'{{end}}/>
+{{- end}}
+{{- end}}
 </domain>`
 
 // generateDomainXML generates XML for a domain (VM).
@@ -230,9 +579,23 @@ func generateDomainXML(config DomainConfig) (string, error) {
 	return executeTemplate(domainTemplate, config)
 }
 
+// Secret XML template for LUKS disk passphrases. The secret is private so
+// its value cannot be read back through the libvirt API.
+const secretTemplate = `<secret ephemeral='no' private='yes'>
+    <description>{{.Description}}</description>
+    <usage type='volume'>
+        <volume>{{.VolumePath}}</volume>
+    </usage>
+</secret>`
+
+// generateSecretXML generates XML for a volume secret.
+func generateSecretXML(config SecretConfig) (string, error) {
+	return executeTemplate(secretTemplate, config)
+}
+
 // executeTemplate executes a template with the given data.
 func executeTemplate(tmpl string, data interface{}) (string, error) {
-	t, err := template.New("xml").Parse(tmpl)
+	t, err := template.New("xml").Funcs(template.FuncMap{"xml": xmlEscape}).Parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -244,3 +607,10 @@ func executeTemplate(tmpl string, data interface{}) (string, error) {
 
 	return buf.String(), nil
 }
+
+// xmlEscape escapes s for use in XML text and attribute values.
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -17,8 +17,12 @@
 package libvirt
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
 )
 
 func TestParseCIDR(t *testing.T) {
@@ -172,6 +176,110 @@ func TestGenerateNATNetworkXML(t *testing.T) {
 	}
 }
 
+func TestNewDNSRecords(t *testing.T) {
+	hosts, txt, cnames, err := newDNSRecords([]providerv1.DNSRecord{
+		{Name: "api.example.com", Value: "192.168.100.50"},
+		{Name: "auth.example.com", Type: "A", Value: "192.168.100.50"},
+		{Name: "api6.example.com", Type: "AAAA", Value: "fd00::50"},
+		{Name: "www.example.com", Type: "CNAME", Value: "api.example.com"},
+		{Name: "_acme.example.com", Type: "TXT", Value: "token=abc"},
+	})
+	if err != nil {
+		t.Fatalf("newDNSRecords() error = %v", err)
+	}
+	wantHosts := []DNSHostEntry{
+		{IP: "192.168.100.50", Hostnames: []string{"api.example.com", "auth.example.com"}},
+		{IP: "fd00::50", Hostnames: []string{"api6.example.com"}},
+	}
+	if !reflect.DeepEqual(hosts, wantHosts) {
+		t.Errorf("hosts = %+v, want %+v", hosts, wantHosts)
+	}
+	if len(txt) != 1 || txt[0].Value != "token=abc" {
+		t.Errorf("txt = %+v", txt)
+	}
+	if len(cnames) != 1 || cnames[0] != "www.example.com,api.example.com" {
+		t.Errorf("cnames = %q", cnames)
+	}
+
+	for _, r := range []providerv1.DNSRecord{
+		{Name: "api.example.com", Value: "fd00::50"},
+		{Name: "api.example.com", Type: "AAAA", Value: "192.168.100.50"},
+		{Name: "api.example.com", Type: "MX", Value: "mail.example.com"},
+		{Name: "api.example.com", Type: "CNAME", Value: "a,b"},
+		{Name: "api.example.com"},
+	} {
+		if _, _, _, err := newDNSRecords([]providerv1.DNSRecord{r}); err == nil {
+			t.Errorf("newDNSRecords(%+v) expected error", r)
+		}
+	}
+}
+
+func TestGenerateNetworkXML_DNSRecords(t *testing.T) {
+	config := NetworkConfig{
+		Name:       "dns-net",
+		BridgeName: "virbr-dns",
+		Gateway:    "192.168.100.1",
+		Netmask:    "255.255.255.0",
+		DNSHosts:   []DNSHostEntry{{IP: "192.168.100.50", Hostnames: []string{"api.example.com"}}},
+		DNSTXT:     []providerv1.DNSRecord{{Name: "_acme.example.com", Type: "TXT", Value: "a'b"}},
+		CNAMEs:     []string{"www.example.com,api.example.com"},
+	}
+
+	for name, generate := range map[string]func(NetworkConfig) (string, error){
+		"nat":      generateNATNetworkXML,
+		"isolated": generateIsolatedNetworkXML,
+	} {
+		xml, err := generate(config)
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		for _, want := range []string{
+			"<network xmlns:dnsmasq='http://libvirt.org/schemas/network/dnsmasq/1.0'>",
+			"<host ip='192.168.100.50'>",
+			"<hostname>api.example.com</hostname>",
+			"<txt name='_acme.example.com' value='a&#39;b'/>",
+			"<dnsmasq:option value='cname=www.example.com,api.example.com'/>",
+		} {
+			if !strings.Contains(xml, want) {
+				t.Errorf("%s network XML should contain %q\nXML:\n%s", name, want, xml)
+			}
+		}
+	}
+
+	xml, err := generateNATNetworkXML(NetworkConfig{Name: "plain", BridgeName: "virbr-plain", Gateway: "192.168.100.1", Netmask: "255.255.255.0"})
+	if err != nil {
+		t.Fatalf("generateNATNetworkXML failed: %v", err)
+	}
+	if !strings.HasPrefix(xml, "<network>") || strings.Contains(xml, "<dns>") || strings.Contains(xml, "dnsmasq") {
+		t.Errorf("network XML without records should have no DNS elements\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateNetworkXML_NTPServer(t *testing.T) {
+	config := NetworkConfig{
+		Name:        "ntp-net",
+		BridgeName:  "virbr-ntp",
+		Gateway:     "10.0.0.1",
+		Netmask:     "255.255.255.0",
+		DHCPEnabled: true,
+		DHCPStart:   "10.0.0.2",
+		DHCPEnd:     "10.0.0.254",
+		NTPServer:   "10.0.0.1",
+	}
+	xml, err := generateIsolatedNetworkXML(config)
+	if err != nil {
+		t.Fatalf("generateIsolatedNetworkXML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<network xmlns:dnsmasq='http://libvirt.org/schemas/network/dnsmasq/1.0'>",
+		"<dnsmasq:option value='dhcp-option=option:ntp-server,10.0.0.1'/>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("network XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+}
+
 func TestGenerateNATNetworkXML_DHCPDisabled(t *testing.T) {
 	config := NetworkConfig{
 		Name:        "no-dhcp",
@@ -223,6 +331,53 @@ func TestGenerateNATNetworkXML_CustomDHCPRange(t *testing.T) {
 	}
 }
 
+func TestGenerateNATNetworkXML_DHCPHosts(t *testing.T) {
+	hosts, err := newDHCPHosts([]providerv1.StaticLease{
+		{MAC: "52:54:00:AB:CD:EF", IP: "192.168.100.10", Hostname: "web"},
+		{MAC: "52:54:00:12:34:56", IP: "192.168.100.11"},
+	})
+	if err != nil {
+		t.Fatalf("newDHCPHosts() error = %v", err)
+	}
+	config := NetworkConfig{
+		Name:        "reserved",
+		BridgeName:  "virbr-reserved",
+		Gateway:     "192.168.100.1",
+		Netmask:     "255.255.255.0",
+		DHCPEnabled: true,
+		DHCPStart:   "192.168.100.2",
+		DHCPEnd:     "192.168.100.254",
+		DHCPHosts:   hosts,
+	}
+
+	for name, generate := range map[string]func(NetworkConfig) (string, error){
+		"nat":      generateNATNetworkXML,
+		"isolated": generateIsolatedNetworkXML,
+	} {
+		xml, err := generate(config)
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		for _, want := range []string{
+			"<host mac='52:54:00:ab:cd:ef' name='web' ip='192.168.100.10'/>",
+			"<host mac='52:54:00:12:34:56' ip='192.168.100.11'/>",
+		} {
+			if !strings.Contains(xml, want) {
+				t.Errorf("%s network XML should contain %s, got:\n%s", name, want, xml)
+			}
+		}
+	}
+
+	for _, l := range []providerv1.StaticLease{
+		{MAC: "52:54:00", IP: "192.168.100.10"},
+		{MAC: "52:54:00:12:34:56", IP: "fd00::10"},
+	} {
+		if _, err := newDHCPHosts([]providerv1.StaticLease{l}); err == nil {
+			t.Errorf("newDHCPHosts(%+v) should fail", l)
+		}
+	}
+}
+
 func TestGenerateIsolatedNetworkXML(t *testing.T) {
 	config := NetworkConfig{
 		Name:       "test-isolated",
@@ -788,3 +943,405 @@ func TestGenerateDomainXML_NoNetworks(t *testing.T) {
 		t.Errorf("Domain XML with no networks should not contain interface blocks\nXML:\n%s", xml)
 	}
 }
+
+func TestGenerateDomainXML_Security(t *testing.T) {
+	tests := []struct {
+		name     string
+		security *SecurityLabel
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:    "default has no seclabel",
+			notWant: []string{"<seclabel"},
+		},
+		{
+			name:     "disabled",
+			security: &SecurityLabel{Disabled: true},
+			want:     []string{"<seclabel type='none'/>"},
+			notWant:  []string{"<label>"},
+		},
+		{
+			name:     "static apparmor profile",
+			security: &SecurityLabel{Model: "apparmor", Label: "libvirt-virtiofs"},
+			want: []string{
+				"<seclabel type='static' model='apparmor' relabel='no'>",
+				"<label>libvirt-virtiofs</label>",
+			},
+		},
+		{
+			name:     "static selinux label with relabel",
+			security: &SecurityLabel{Model: "selinux", Label: "system_u:system_r:svirt_t:s0:c1,c2", Relabel: true},
+			want: []string{
+				"<seclabel type='static' model='selinux' relabel='yes'>",
+				"<label>system_u:system_r:svirt_t:s0:c1,c2</label>",
+			},
+		},
+		{
+			name:     "dynamic with model",
+			security: &SecurityLabel{Model: "selinux"},
+			want:     []string{"<seclabel type='dynamic' model='selinux'/>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xml, err := generateDomainXML(DomainConfig{
+				Name:     "sec-vm",
+				DiskPath: "/tmp/test.qcow2",
+				Security: tt.security,
+			})
+			if err != nil {
+				t.Fatalf("generateDomainXML failed: %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(xml, w) {
+					t.Errorf("Domain XML should contain %q\nXML:\n%s", w, xml)
+				}
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(xml, nw) {
+					t.Errorf("Domain XML should not contain %q\nXML:\n%s", nw, xml)
+				}
+			}
+			if !strings.HasSuffix(strings.TrimSpace(xml), "</domain>") {
+				t.Errorf("Domain XML should end with </domain>\nXML:\n%s", xml)
+			}
+		})
+	}
+}
+
+func TestGenerateDomainXML_EncryptedDisk(t *testing.T) {
+	config := DomainConfig{
+		Name:           "luks-vm",
+		DiskPath:       "/tmp/luks.qcow2",
+		DiskSecretUUID: "0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0",
+	}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"<encryption format='luks'>",
+		"<secret type='passphrase' uuid='0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0'/>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+
+	config.DiskSecretUUID = ""
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if strings.Contains(xml, "<encryption") {
+		t.Errorf("Unencrypted disk should not contain encryption block\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateDomainXML_Vsock(t *testing.T) {
+	config := DomainConfig{Name: "vsock-vm", DiskPath: "/tmp/vsock.qcow2"}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if strings.Contains(xml, "<vsock") {
+		t.Errorf("Domain without vsock should not contain vsock device\nXML:\n%s", xml)
+	}
+
+	config.Vsock = &VsockDevice{CID: 42}
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	for _, want := range []string{"<vsock model='virtio'>", "<cid auto='no' address='42'/>"} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+
+	config.Vsock = &VsockDevice{}
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if !strings.Contains(xml, "<cid auto='yes'/>") {
+		t.Errorf("Domain XML should let libvirt assign the CID\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateDomainXML_HostDevices(t *testing.T) {
+	config := DomainConfig{Name: "gpu-vm", DiskPath: "/tmp/gpu.qcow2"}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if strings.Contains(xml, "<hostdev") {
+		t.Errorf("Domain without host devices should not contain hostdev\nXML:\n%s", xml)
+	}
+
+	config.HostDevices = []HostDevice{
+		{PCI: &PCIAddress{Domain: "0000", Bus: "3b", Slot: "00", Function: "0"}},
+		{MdevUUID: "4b20d080-1b54-4048-85b3-a6a62d165c01"},
+	}
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<hostdev mode='subsystem' type='pci' managed='yes'>",
+		"<address domain='0x0000' bus='0x3b' slot='0x00' function='0x0'/>",
+		"<hostdev mode='subsystem' type='mdev' model='vfio-pci'>",
+		"<address uuid='4b20d080-1b54-4048-85b3-a6a62d165c01'/>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+}
+
+func TestGenerateDomainXML_DataDisks(t *testing.T) {
+	config := DomainConfig{
+		Name:     "osd-vm",
+		DiskPath: "/tmp/osd.qcow2",
+		DataDisks: []DataDisk{
+			{Path: "/tmp/osd.data1.qcow2", Format: "qcow2", Target: "vdb"},
+			{Path: "/tmp/osd.data2.raw", Format: "raw", Target: "vdc"},
+		},
+	}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<target dev='vda' bus='virtio'/>",
+		"<driver name='qemu' type='qcow2' discard='unmap' detect_zeroes='unmap'/>\n            <source file='/tmp/osd.data1.qcow2'/>\n            <target dev='vdb' bus='virtio'/>",
+		"<driver name='qemu' type='raw' discard='unmap' detect_zeroes='unmap'/>\n            <source file='/tmp/osd.data2.raw'/>\n            <target dev='vdc' bus='virtio'/>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+	if got := strings.Count(xml, "snapshot='no'"); got != 2 {
+		t.Errorf("Data disks should be left out of snapshots, got %d snapshot='no' attributes\nXML:\n%s", got, xml)
+	}
+}
+
+func TestGenerateDomainXML_Discard(t *testing.T) {
+	xml, err := generateDomainXML(DomainConfig{Name: "sparse-vm", DiskPath: "/tmp/sparse.qcow2"})
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	want := "<driver name='qemu' type='qcow2' discard='unmap' detect_zeroes='unmap'/>\n            <source file='/tmp/sparse.qcow2'/>"
+	if !strings.Contains(xml, want) {
+		t.Errorf("Boot disk should pass discards to the image\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateDomainXML_SeededIdentifiers(t *testing.T) {
+	config := DomainConfig{
+		Name:     "seeded-vm",
+		DiskPath: "/tmp/seeded.qcow2",
+		Networks: []NetworkInterface{{Name: "net1", MAC: "52:54:00:0a:0b:0c"}, {Name: "net2"}},
+	}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if strings.Contains(xml, "<uuid>") {
+		t.Errorf("Domain without UUID should let libvirt assign one\nXML:\n%s", xml)
+	}
+	if n := strings.Count(xml, "<mac address="); n != 1 {
+		t.Errorf("Domain XML has %d MAC addresses, want 1 for the NIC that sets one\nXML:\n%s", n, xml)
+	}
+	if !strings.Contains(xml, "<mac address='52:54:00:0a:0b:0c'/>") {
+		t.Errorf("Domain XML should contain the requested MAC address\nXML:\n%s", xml)
+	}
+
+	config.UUID = "0b1c2d3e-4f50-8172-8394-a5b6c7d8e9f0"
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if !strings.Contains(xml, "<uuid>0b1c2d3e-4f50-8172-8394-a5b6c7d8e9f0</uuid>") {
+		t.Errorf("Domain XML should contain the requested UUID\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateDomainXML_NICOptions(t *testing.T) {
+	config := DomainConfig{
+		Name:     "jumbo-vm",
+		DiskPath: "/tmp/jumbo.qcow2",
+		Networks: []NetworkInterface{{Name: "net1", MTU: 9000}, {Name: "net2", Model: "e1000"}},
+	}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	for _, want := range []string{"<model type='virtio'/>", "<mtu size='9000'/>", "<model type='e1000'/>"} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %s\nXML:\n%s", want, xml)
+		}
+	}
+	if n := strings.Count(xml, "<mtu "); n != 1 {
+		t.Errorf("Domain XML has %d MTUs, want 1\nXML:\n%s", n, xml)
+	}
+}
+
+func TestGenerateNetworkXML_MTU(t *testing.T) {
+	config := NetworkConfig{
+		Name:       "jumbo",
+		BridgeName: "virbr-jumbo",
+		Gateway:    "192.168.100.1",
+		Netmask:    "255.255.255.0",
+	}
+	for name, generate := range map[string]func(NetworkConfig) (string, error){
+		"nat":      generateNATNetworkXML,
+		"isolated": generateIsolatedNetworkXML,
+	} {
+		xml, err := generate(config)
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		if strings.Contains(xml, "<mtu ") {
+			t.Errorf("%s: network without MTU should keep the default\nXML:\n%s", name, xml)
+		}
+
+		config.MTU = 9000
+		xml, err = generate(config)
+		config.MTU = 0
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", name, err)
+		}
+		if !strings.Contains(xml, "<mtu size='9000'/>") {
+			t.Errorf("%s: network XML should contain the MTU\nXML:\n%s", name, xml)
+		}
+	}
+}
+
+func TestGenerateSecretXML(t *testing.T) {
+	xml, err := generateSecretXML(SecretConfig{
+		Description: "disk passphrase for vm1",
+		VolumePath:  "/var/lib/testenv-vm/disks/vm1.qcow2",
+	})
+	if err != nil {
+		t.Fatalf("generateSecretXML failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"<secret ephemeral='no' private='yes'>",
+		"<description>disk passphrase for vm1</description>",
+		"<usage type='volume'>",
+		"<volume>/var/lib/testenv-vm/disks/vm1.qcow2</volume>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Secret XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+}
+
+func TestGenerateDomainXMLLabels(t *testing.T) {
+	config := DomainConfig{
+		Name:     "test-vm",
+		DiskPath: "/var/lib/libvirt/images/test.qcow2",
+		Networks: []NetworkInterface{{Name: "default"}},
+		Labels: common.SortedLabels(map[string]string{
+			"testenv-vm.resource":       "vm/web",
+			"testenv-vm.environment-id": "env<1>",
+		}),
+	}
+
+	xml, err := generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<testenv:labels xmlns:testenv='https://github.com/alexandremahdhaoui/testenv-vm'>",
+		"<testenv:label key='testenv-vm.environment-id'>env&lt;1&gt;</testenv:label>",
+		"<testenv:label key='testenv-vm.resource'>vm/web</testenv:label>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("Domain XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+
+	config.Labels = nil
+	xml, err = generateDomainXML(config)
+	if err != nil {
+		t.Fatalf("generateDomainXML failed: %v", err)
+	}
+	if strings.Contains(xml, "<metadata>") {
+		t.Errorf("Domain XML without labels should not contain metadata\nXML:\n%s", xml)
+	}
+}
+
+func TestGenerateNATNetworkXML_Netboot(t *testing.T) {
+	config := NetworkConfig{
+		Name:        "pxe",
+		BridgeName:  "virbr-pxe",
+		Gateway:     "10.0.0.1",
+		Netmask:     "255.255.255.0",
+		DHCPEnabled: true,
+		DHCPStart:   "10.0.0.2",
+		DHCPEnd:     "10.0.0.254",
+	}
+	err := config.setNetboot(&providerv1.TFTPSpec{
+		Enabled:         true,
+		Root:            "/srv/tftp",
+		BootFile:        "undionly.kpxe",
+		BootFileEFI:     "ipxe.efi",
+		DHCPBootOptions: map[string]string{"209": "pxelinux.cfg/default"},
+	}, "10.0.0.5")
+	if err != nil {
+		t.Fatalf("setNetboot() error = %v", err)
+	}
+	xml, err := generateNATNetworkXML(config)
+	if err != nil {
+		t.Fatalf("generateNATNetworkXML failed: %v", err)
+	}
+	for _, want := range []string{
+		"<network xmlns:dnsmasq='http://libvirt.org/schemas/network/dnsmasq/1.0'>",
+		"<tftp root='/srv/tftp'/>",
+		"<bootp file='undionly.kpxe' server='10.0.0.5'/>",
+		"<dnsmasq:option value='dhcp-match=set:efi,option:client-arch,7'/>",
+		"<dnsmasq:option value='dhcp-boot=tag:efi,ipxe.efi,,10.0.0.5'/>",
+		"<dnsmasq:option value='dhcp-option=209,pxelinux.cfg/default'/>",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("network XML should contain %q\nXML:\n%s", want, xml)
+		}
+	}
+
+	if err := (&NetworkConfig{}).setNetboot(&providerv1.TFTPSpec{Enabled: true}, ""); err == nil {
+		t.Error("setNetboot() without root should fail")
+	}
+	if err := (&NetworkConfig{}).setNetboot(&providerv1.TFTPSpec{Enabled: true, Root: "/srv", DHCPBootOptions: map[string]string{"vendor": "x"}}, ""); err == nil {
+		t.Error("setNetboot() with a boot option that is not a code should fail")
+	}
+}
+
+func TestGenerateNATNetworkXML_DNSDisabled(t *testing.T) {
+	config := NetworkConfig{
+		Name:        "builtin",
+		BridgeName:  "virbr-builtin",
+		Gateway:     "10.0.0.1",
+		Netmask:     "255.255.255.0",
+		DNSDisabled: true,
+		DNSHosts:    []DNSHostEntry{{IP: "10.0.0.9", Hostnames: []string{"ignored"}}},
+	}
+	xml, err := generateNATNetworkXML(config)
+	if err != nil {
+		t.Fatalf("generateNATNetworkXML failed: %v", err)
+	}
+	if !strings.Contains(xml, "<dns enable='no'/>") || strings.Contains(xml, "<dhcp>") || strings.Contains(xml, "<host ") {
+		t.Errorf("network XML should disable DNS without DHCP\nXML:\n%s", xml)
+	}
+}
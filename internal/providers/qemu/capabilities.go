@@ -0,0 +1,68 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"runtime"
+
+	"golang.org/x/sys/unix"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// Capabilities returns the capabilities of the qemu provider.
+func (p *Provider) Capabilities() *providerv1.CapabilitiesResponse {
+	return &providerv1.CapabilitiesResponse{
+		ProviderName: "qemu",
+		Version:      p.Version(),
+		Resources: []providerv1.ResourceCapability{
+			{
+				Kind:       "key",
+				Operations: []string{"create", "get", "list", "delete"},
+			},
+			{
+				Kind:       "network",
+				Operations: []string{"create", "get", "list", "delete"},
+			},
+			{
+				// Not supported: capture, snapshot, save, migrate and adopt
+				Kind:       "vm",
+				Operations: []string{"create", "get", "list", "delete", "stats", "start", "stop", "reboot", "pause"},
+				// See unsupportedFeature for the others
+				VMFeatures: []string{providerv1.VMFeatureNICOptions, providerv1.VMFeatureDataDisks, providerv1.VMFeatureRawUserData, providerv1.VMFeatureGuestNetwork},
+			},
+		},
+		Host:     p.hostCapacity(),
+		Batch:    true,
+		Teardown: true,
+	}
+}
+
+// hostCapacity reports the CPUs and memory of the host and the free space
+// of the state directory.
+func (p *Provider) hostCapacity() *providerv1.HostCapacity {
+	host := &providerv1.HostCapacity{CPUs: runtime.NumCPU()}
+	var info unix.Sysinfo_t
+	if err := unix.Sysinfo(&info); err == nil {
+		unit := uint64(info.Unit)
+		host.MemoryMB = int(uint64(info.Totalram) * unit / (1024 * 1024))
+		host.FreeMemoryMB = int(uint64(info.Freeram) * unit / (1024 * 1024))
+	}
+	var fs unix.Statfs_t
+	if err := unix.Statfs(p.config.StateDir, &fs); err == nil {
+		host.FreeDiskMB = int64(fs.Bavail) * int64(fs.Bsize) / (1024 * 1024)
+	}
+	return host
+}
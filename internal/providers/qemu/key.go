@@ -0,0 +1,145 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+// KeyCreate creates an SSH key and stores it in the state directory.
+func (p *Provider) KeyCreate(req *providerv1.KeyCreateRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(req.Name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid key name: " + req.Name))
+	}
+	if _, exists := p.keys[req.Name]; exists {
+		return providerv1.ErrorResult(providerv1.NewAlreadyExistsError("key", req.Name))
+	}
+
+	keyType := req.Spec.Type
+	if keyType == "" {
+		keyType = "ed25519"
+	}
+	if keyType != "ed25519" && keyType != "rsa" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("unsupported key type: " + keyType))
+	}
+	privateKeyPEM, publicKey, err := common.GenerateKey(keyType, req.Spec.Bits)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to generate key: "+err.Error(), true))
+	}
+	publicKeyStr := string(ssh.MarshalAuthorizedKey(publicKey))
+
+	// Use OutputDir from spec if set, otherwise the provider's state dir
+	keyDir := filepath.Join(p.config.StateDir, "keys")
+	if req.Spec.OutputDir != "" {
+		keyDir = req.Spec.OutputDir
+		if err := os.MkdirAll(keyDir, 0o755); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError("failed to create key output directory: "+err.Error(), false))
+		}
+	}
+	privateKeyPath := filepath.Join(keyDir, req.Name)
+	publicKeyPath := filepath.Join(keyDir, req.Name+".pub")
+
+	if err := os.WriteFile(privateKeyPath, privateKeyPEM, 0o600); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to write private key: "+err.Error(), false))
+	}
+	if err := os.WriteFile(publicKeyPath, []byte(publicKeyStr), 0o644); err != nil {
+		_ = os.Remove(privateKeyPath)
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to write public key: "+err.Error(), false))
+	}
+
+	state := &providerv1.KeyState{
+		Name:           req.Name,
+		Type:           keyType,
+		PublicKey:      publicKeyStr,
+		PublicKeyPath:  publicKeyPath,
+		PrivateKeyPath: privateKeyPath,
+		Fingerprint:    ssh.FingerprintSHA256(publicKey),
+		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		Labels:         req.Labels,
+	}
+	if err := writeJSON(p.keyStatePath(req.Name), state); err != nil {
+		_ = os.Remove(privateKeyPath)
+		_ = os.Remove(publicKeyPath)
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+
+	p.keys[req.Name] = state
+	return providerv1.SuccessResult(state)
+}
+
+// KeyGet retrieves an SSH key by name.
+func (p *Provider) KeyGet(name string) *providerv1.OperationResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, exists := p.keys[name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("key", name))
+	}
+	return providerv1.SuccessResult(key)
+}
+
+// KeyList lists all SSH keys carrying the labels of the labels filter.
+func (p *Provider) KeyList(filter map[string]any) *providerv1.OperationResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	selector := providerv1.LabelSelector(filter)
+	keys := make([]*providerv1.KeyState, 0, len(p.keys))
+	for _, key := range p.keys {
+		if providerv1.MatchLabels(selector, key.Labels) {
+			keys = append(keys, key)
+		}
+	}
+	return providerv1.SuccessResult(keys)
+}
+
+// KeyDelete deletes an SSH key by name. It fails while a VM authorizes the
+// key, and succeeds when the key does not exist.
+func (p *Provider) KeyDelete(name string) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid key name: " + name))
+	}
+	for _, v := range p.vms {
+		if containsString(stringList(v.State.ProviderState["keys"]), name) {
+			return providerv1.ErrorResult(providerv1.NewResourceBusyError("key", name))
+		}
+	}
+
+	if key, exists := p.keys[name]; exists {
+		_ = os.Remove(key.PrivateKeyPath)
+		_ = os.Remove(key.PublicKeyPath)
+	} else {
+		_ = os.Remove(filepath.Join(p.config.StateDir, "keys", name))
+		_ = os.Remove(filepath.Join(p.config.StateDir, "keys", name+".pub"))
+	}
+	_ = os.Remove(p.keyStatePath(name))
+
+	delete(p.keys, name)
+	return providerv1.SuccessResult(nil)
+}
@@ -0,0 +1,154 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// Network kinds of the qemu provider.
+const (
+	// KindUser is user-mode networking: each VM gets a private NAT network
+	// with the CIDR of the network, and SSH is forwarded from a port of
+	// 127.0.0.1. It needs no privileges, but VMs cannot reach each other.
+	KindUser = "user"
+	// KindBridge attaches VMs to an existing host bridge through tap
+	// devices created by qemu-bridge-helper.
+	KindBridge = "bridge"
+)
+
+// defaultCIDR is the CIDR of user-mode networks without one.
+const defaultCIDR = "10.0.2.0/24"
+
+// NetworkCreate records a network. User-mode networks exist only in the QEMU
+// processes of their VMs; bridge networks must name an existing host bridge
+// with providerSpec.bridge. The "nat" kind is an alias of "user", so that
+// specs written for the libvirt provider work unchanged.
+func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(req.Name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid network name: " + req.Name))
+	}
+	if _, exists := p.networks[req.Name]; exists {
+		return providerv1.ErrorResult(providerv1.NewAlreadyExistsError("network", req.Name))
+	}
+
+	state := &providerv1.NetworkState{
+		Name:   req.Name,
+		Status: "ready",
+		CIDR:   req.Spec.CIDR,
+		Labels: req.Labels,
+	}
+	switch req.Kind {
+	case "", "nat", KindUser:
+		cidr := req.Spec.CIDR
+		if cidr == "" {
+			cidr = defaultCIDR
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil || ipNet.IP.To4() == nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("invalid IPv4 CIDR %q", cidr)))
+		}
+		// The guest address is the 15th of the network
+		if ones, _ := ipNet.Mask.Size(); ones > 27 {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("user networks need at least a /27, got %q", cidr)))
+		}
+		state.Kind = KindUser
+		state.CIDR = ipNet.String()
+		state.IP = hostAddress(ipNet, 2).String()
+	case KindBridge:
+		bridge, _ := req.ProviderSpec["bridge"].(string)
+		if bridge == "" {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError("bridge networks require providerSpec.bridge"))
+		}
+		if _, err := os.Stat(filepath.Join("/sys/class/net", bridge, "bridge")); err != nil {
+			return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("%s is not a host bridge", bridge)))
+		}
+		state.Kind = KindBridge
+		state.InterfaceName = bridge
+		state.IP = req.Spec.Gateway
+	default:
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("unsupported network kind: " + req.Kind))
+	}
+
+	if err := writeJSON(p.networkStatePath(req.Name), state); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	p.networks[req.Name] = state
+	return providerv1.SuccessResult(state)
+}
+
+// NetworkGet retrieves a network by name.
+func (p *Provider) NetworkGet(name string) *providerv1.OperationResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	network, exists := p.networks[name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("network", name))
+	}
+	return providerv1.SuccessResult(network)
+}
+
+// NetworkList lists all networks carrying the labels of the labels filter.
+func (p *Provider) NetworkList(filter map[string]any) *providerv1.OperationResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	selector := providerv1.LabelSelector(filter)
+	networks := make([]*providerv1.NetworkState, 0, len(p.networks))
+	for _, network := range p.networks {
+		if providerv1.MatchLabels(selector, network.Labels) {
+			networks = append(networks, network)
+		}
+	}
+	return providerv1.SuccessResult(networks)
+}
+
+// NetworkDelete deletes a network by name. It fails while a VM is attached
+// to the network, and succeeds when the network does not exist.
+func (p *Provider) NetworkDelete(name string) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid network name: " + name))
+	}
+	for _, v := range p.vms {
+		if containsString(stringList(v.State.ProviderState["networks"]), name) {
+			return providerv1.ErrorResult(providerv1.NewResourceBusyError("network", name))
+		}
+	}
+
+	_ = os.Remove(p.networkStatePath(name))
+	delete(p.networks, name)
+	return providerv1.SuccessResult(nil)
+}
+
+// hostAddress returns the address at offset n in ipNet, e.g. 10.0.2.2 for
+// offset 2 of 10.0.2.0/24.
+func hostAddress(ipNet *net.IPNet, n byte) net.IP {
+	ip := make(net.IP, len(ipNet.IP.To4()))
+	copy(ip, ipNet.IP.To4())
+	ip[3] += n
+	return ip
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"fmt"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// VMPower applies a power action to a VM through its QMP socket. QEMU runs
+// with -no-shutdown, so a guest powering off leaves its process in the
+// "shutdown" state, where vm_stop ends it. vm_start launches the process
+// again from the same command line; disks are kept in between, so the
+// guest boots from where it stopped.
+func (p *Provider) VMPower(action string, req *providerv1.VMPowerRequest) *providerv1.OperationResult {
+	if req.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name is required"))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	v, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	p.refresh(v)
+	status, opErr := providerv1.NextPowerStatus(v.State.Status, action)
+	if opErr != nil {
+		return providerv1.ErrorResult(opErr)
+	}
+
+	var err error
+	switch action {
+	case providerv1.PowerStart:
+		err = p.startVM(v)
+	case providerv1.PowerStop:
+		err = p.stopVM(v.State.Name, req)
+	case providerv1.PowerReboot:
+		err = p.rebootVM(v.State.Name, req)
+	case providerv1.PowerPause:
+		_, err = qmpExecute(p.qmpSocket(v.State.Name), "stop", nil)
+	}
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("failed to %s vm %s: %s", action, v.State.Name, err.Error()), true))
+	}
+
+	v.State.Status = status
+	if action == providerv1.PowerStart {
+		v.State.ProviderState["pid"] = p.pid(v.State.Name)
+	}
+	if err := p.saveVM(v); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	return providerv1.SuccessResult(v.State)
+}
+
+// startVM resumes a paused VM, boots a VM whose guest powered off, or
+// launches the QEMU process of a stopped VM again.
+func (p *Provider) startVM(v *vm) error {
+	if p.pid(v.State.Name) == 0 {
+		return p.launch(v)
+	}
+	socket := p.qmpSocket(v.State.Name)
+	status, err := queryStatus(socket)
+	if err != nil {
+		return err
+	}
+	if status == "shutdown" {
+		if _, err := qmpExecute(socket, "system_reset", nil); err != nil {
+			return err
+		}
+	}
+	if status != "running" {
+		_, err = qmpExecute(socket, "cont", nil)
+	}
+	return err
+}
+
+// stopVM powers off a VM, asking the guest first unless req.Force is set,
+// and ends its QEMU process.
+func (p *Provider) stopVM(name string, req *providerv1.VMPowerRequest) error {
+	if p.pid(name) == 0 {
+		return nil
+	}
+	if !req.Force {
+		if err := p.powerdown(name, req.StopTimeout()); err != nil {
+			return err
+		}
+	}
+	p.kill(name)
+	if p.pid(name) != 0 {
+		return fmt.Errorf("QEMU process did not exit")
+	}
+	return nil
+}
+
+// rebootVM reboots a running VM through the guest, or resets it when
+// req.Force is set.
+func (p *Provider) rebootVM(name string, req *providerv1.VMPowerRequest) error {
+	socket := p.qmpSocket(name)
+	if !req.Force {
+		if err := p.powerdown(name, req.StopTimeout()); err != nil {
+			return err
+		}
+	}
+	if _, err := qmpExecute(socket, "system_reset", nil); err != nil {
+		return err
+	}
+	status, err := queryStatus(socket)
+	if err != nil {
+		return err
+	}
+	if status != "running" {
+		_, err = qmpExecute(socket, "cont", nil)
+	}
+	return err
+}
+
+// powerdown asks the guest of a VM to power off and waits until it did.
+func (p *Provider) powerdown(name string, timeout time.Duration) error {
+	socket := p.qmpSocket(name)
+	if _, err := qmpExecute(socket, "system_powerdown", nil); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := queryStatus(socket)
+		if err != nil || status == "shutdown" {
+			// The process is gone or the guest powered off
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("guest did not power off within %s, stop with force to kill it", timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
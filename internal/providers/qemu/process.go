@@ -0,0 +1,102 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Timeouts of the QEMU process of a VM.
+const (
+	// exitTimeout is how long a QEMU process asked to quit may take before
+	// it is killed.
+	exitTimeout = 5 * time.Second
+	// pollInterval is the interval between checks of a VM changing state.
+	pollInterval = 500 * time.Millisecond
+)
+
+// qmpSocket returns the QMP socket of a VM.
+func (p *Provider) qmpSocket(name string) string {
+	return filepath.Join(p.vmDir(name), qmpFile)
+}
+
+// pid returns the PID of the running QEMU process of a VM, or zero. The
+// command line of the process must reference the directory of the VM, so
+// that a reused PID is never mistaken for the VM.
+func (p *Provider) pid(name string) int {
+	data, err := os.ReadFile(filepath.Join(p.vmDir(name), pidFile))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || !bytes.Contains(cmdline, []byte(p.vmDir(name))) {
+		return 0
+	}
+	return pid
+}
+
+// launch starts the QEMU process of a VM. QEMU daemonizes once the VM is
+// set up, so it keeps running after the provider exits.
+func (p *Provider) launch(v *vm) error {
+	_ = os.Remove(filepath.Join(p.vmDir(v.State.Name), pidFile))
+	output, err := exec.Command(p.config.QemuPath, v.Args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w, output: %s", err, strings.TrimSpace(string(output)))
+	}
+	if p.pid(v.State.Name) == 0 {
+		return fmt.Errorf("QEMU exited without leaving a running process")
+	}
+	return nil
+}
+
+// kill stops the QEMU process of a VM, asking it to quit through QMP first.
+// It does nothing when the VM has no process.
+func (p *Provider) kill(name string) {
+	pid := p.pid(name)
+	if pid == 0 {
+		return
+	}
+	_, _ = qmpExecute(p.qmpSocket(name), "quit", nil)
+	if p.waitExit(name, exitTimeout) {
+		return
+	}
+	_ = syscall.Kill(pid, syscall.SIGKILL)
+	p.waitExit(name, exitTimeout)
+}
+
+// waitExit waits until the QEMU process of a VM exited and reports whether
+// it did within timeout.
+func (p *Provider) waitExit(name string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for p.pid(name) != 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(pollInterval)
+	}
+	return true
+}
@@ -0,0 +1,331 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package qemu provides a provider that runs VMs by executing
+// qemu-system-x86_64 directly, without a libvirt daemon, so that testenv-vm
+// works where only QEMU and /dev/kvm are available (e.g., unprivileged CI
+// containers). VMs use user-mode networking, or tap devices on an existing
+// host bridge. QEMU processes outlive the provider: their PID file, QMP
+// socket and state are stored below the state directory, where a later
+// provider process finds them.
+package qemu
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+// Files of a VM in its directory below the state directory.
+const (
+	diskFile    = "disk.qcow2"
+	seedFile    = "seed.iso"
+	pidFile     = "qemu.pid"
+	qmpFile     = "qmp.sock"
+	qgaFile     = "qga.sock"
+	consoleFile = "console.log"
+	vmStateFile = "vm.json"
+)
+
+// maxSocketPath is the longest path of a unix socket.
+const maxSocketPath = 107
+
+// ProviderConfig holds configuration for the qemu provider.
+type ProviderConfig struct {
+	// StateDir is the directory where keys, networks and VMs are stored
+	StateDir string
+	// QemuPath is the path to the qemu-system-x86_64 binary
+	QemuPath string
+	// QemuImgPath is the path to the qemu-img binary
+	QemuImgPath string
+	// ISOTool is the path to the ISO generation tool (genisoimage, mkisofs or xorriso)
+	ISOTool string
+	// Accel is the QEMU accelerator: "kvm" when /dev/kvm is usable, "tcg" otherwise
+	Accel string
+	// DiskBackend selects how disks are created from their base image
+	DiskBackend common.DiskBackend
+}
+
+// Provider is a provider that manages QEMU processes, networks and SSH keys.
+// Its state is kept in memory and persisted to the state directory.
+type Provider struct {
+	config   ProviderConfig
+	mu       sync.RWMutex
+	keys     map[string]*providerv1.KeyState
+	networks map[string]*providerv1.NetworkState
+	vms      map[string]*vm
+	version  string
+}
+
+// vm is a VM launched by the provider, with the arguments to launch it again
+// after it was stopped.
+type vm struct {
+	State    *providerv1.VMState `json:"state"`
+	Args     []string            `json:"args"`
+	VCPUs    int                 `json:"vcpus"`
+	MemoryMB int                 `json:"memoryMB"`
+}
+
+// NewProvider creates a new qemu provider. It reads configuration from
+// environment variables:
+//   - TESTENV_VM_STATE_DIR: state directory (default: $TMPDIR/testenv-vm-qemu-<uid>)
+//   - TESTENV_VM_QEMU_BINARY: QEMU system emulator (default: qemu-system-x86_64)
+//   - TESTENV_VM_QEMU_ACCEL: accelerator, "kvm" or "tcg" (default: kvm when /dev/kvm is usable)
+//   - TESTENV_VM_DISK_BACKEND: disk backend, "qcow2", "reflink" or "auto" (default: qcow2)
+//
+// It checks for required dependencies (QEMU, qemu-img and
+// genisoimage/mkisofs/xorriso) and loads the state left by previous
+// provider processes.
+func NewProvider() (*Provider, error) {
+	config := loadConfig()
+
+	diskBackend, err := common.ParseDiskBackend(os.Getenv("TESTENV_VM_DISK_BACKEND"))
+	if err != nil {
+		return nil, err
+	}
+	config.DiskBackend = diskBackend
+
+	qemuPath, err := exec.LookPath(config.QemuPath)
+	if err != nil {
+		return nil, fmt.Errorf("running VMs requires %s: %w", config.QemuPath, err)
+	}
+	config.QemuPath = qemuPath
+
+	qemuImgPath, err := exec.LookPath("qemu-img")
+	if err != nil {
+		return nil, fmt.Errorf("disk image creation requires qemu-img: %w", err)
+	}
+	config.QemuImgPath = qemuImgPath
+
+	isoTool, err := common.FindISOTool()
+	if err != nil {
+		return nil, err
+	}
+	config.ISOTool = isoTool
+
+	return NewProviderWithConfig(config)
+}
+
+// NewProviderWithConfig creates a provider with explicit configuration and
+// loads the state stored in its state directory.
+func NewProviderWithConfig(config ProviderConfig) (*Provider, error) {
+	if config.Accel == "" {
+		config.Accel = defaultAccel()
+	}
+	for _, dir := range []string{"keys", "networks", "vms"} {
+		if err := os.MkdirAll(filepath.Join(config.StateDir, dir), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+
+	p := &Provider{
+		config:   config,
+		keys:     make(map[string]*providerv1.KeyState),
+		networks: make(map[string]*providerv1.NetworkState),
+		vms:      make(map[string]*vm),
+	}
+	if err := p.loadState(); err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return p, nil
+}
+
+// Close releases the provider. QEMU processes keep running.
+func (p *Provider) Close() error {
+	return nil
+}
+
+// SetVersion sets the provider version for capabilities reporting.
+func (p *Provider) SetVersion(version string) {
+	p.version = version
+}
+
+// Version returns the provider version.
+func (p *Provider) Version() string {
+	if p.version == "" {
+		return "dev"
+	}
+	return p.version
+}
+
+// Config returns the provider configuration.
+func (p *Provider) Config() ProviderConfig {
+	return p.config
+}
+
+// loadConfig loads provider configuration from environment variables.
+func loadConfig() ProviderConfig {
+	stateDir := os.Getenv("TESTENV_VM_STATE_DIR")
+	if stateDir == "" {
+		stateDir = filepath.Join(os.TempDir(), fmt.Sprintf("testenv-vm-qemu-%d", os.Getuid()))
+	}
+	qemuPath := os.Getenv("TESTENV_VM_QEMU_BINARY")
+	if qemuPath == "" {
+		qemuPath = "qemu-system-x86_64"
+	}
+	return ProviderConfig{
+		StateDir: stateDir,
+		QemuPath: qemuPath,
+		Accel:    os.Getenv("TESTENV_VM_QEMU_ACCEL"),
+	}
+}
+
+// defaultAccel returns "kvm" when the current user can open /dev/kvm, and
+// "tcg" (software emulation) otherwise.
+func defaultAccel() string {
+	if unix.Access("/dev/kvm", unix.R_OK|unix.W_OK) == nil {
+		return "kvm"
+	}
+	return "tcg"
+}
+
+// validName reports whether name can be used as a file name in the state
+// directory.
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
+
+// vmDir returns the directory holding the files of a VM.
+func (p *Provider) vmDir(name string) string {
+	return filepath.Join(p.config.StateDir, "vms", name)
+}
+
+// keyStatePath returns the state file of a key.
+func (p *Provider) keyStatePath(name string) string {
+	return filepath.Join(p.config.StateDir, "keys", name+".json")
+}
+
+// networkStatePath returns the state file of a network.
+func (p *Provider) networkStatePath(name string) string {
+	return filepath.Join(p.config.StateDir, "networks", name+".json")
+}
+
+// loadState reads the keys, networks and VMs stored by previous provider
+// processes. VMs whose QEMU process exited in between are marked stopped.
+func (p *Provider) loadState() error {
+	keyFiles, err := filepath.Glob(filepath.Join(p.config.StateDir, "keys", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range keyFiles {
+		var key providerv1.KeyState
+		if err := readJSON(path, &key); err != nil {
+			return err
+		}
+		p.keys[key.Name] = &key
+	}
+
+	networkFiles, err := filepath.Glob(filepath.Join(p.config.StateDir, "networks", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range networkFiles {
+		var network providerv1.NetworkState
+		if err := readJSON(path, &network); err != nil {
+			return err
+		}
+		p.networks[network.Name] = &network
+	}
+
+	vmFiles, err := filepath.Glob(filepath.Join(p.config.StateDir, "vms", "*", vmStateFile))
+	if err != nil {
+		return err
+	}
+	for _, path := range vmFiles {
+		var v vm
+		if err := readJSON(path, &v); err != nil {
+			return err
+		}
+		if v.State == nil {
+			continue
+		}
+		if v.State.Status != "stopped" && p.pid(v.State.Name) == 0 {
+			log.Printf("QEMU process of vm %s is gone, marking it stopped", v.State.Name)
+			v.State.Status = "stopped"
+		}
+		p.vms[v.State.Name] = &v
+	}
+	return nil
+}
+
+// saveVM persists the state of a VM.
+func (p *Provider) saveVM(v *vm) error {
+	return writeJSON(filepath.Join(p.vmDir(v.State.Name), vmStateFile), v)
+}
+
+// writeJSON atomically writes v as JSON to path.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// readJSON reads the JSON file at path into v.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// stringList returns a string list stored in provider state. Lists read back
+// from JSON are []any.
+func stringList(v any) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []any:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// containsString reports whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,314 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// newTestProvider returns a provider storing its state in a temporary
+// directory. It does not need QEMU.
+func newTestProvider(t *testing.T) *Provider {
+	t.Helper()
+	p, err := NewProviderWithConfig(ProviderConfig{StateDir: t.TempDir(), Accel: "tcg"})
+	if err != nil {
+		t.Fatalf("NewProviderWithConfig() error = %v", err)
+	}
+	return p
+}
+
+// addVM records a VM without a QEMU process.
+func addVM(t *testing.T, p *Provider, name string, networks, keys []string) {
+	t.Helper()
+	v := &vm{State: &providerv1.VMState{
+		Name:          name,
+		Status:        "running",
+		ProviderState: map[string]any{"networks": networks, "keys": keys},
+	}}
+	if err := os.MkdirAll(p.vmDir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.saveVM(v); err != nil {
+		t.Fatal(err)
+	}
+	p.vms[name] = v
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := newTestProvider(t).Capabilities()
+
+	if caps.ProviderName != "qemu" {
+		t.Errorf("ProviderName = %q, want qemu", caps.ProviderName)
+	}
+	if !caps.Batch || !caps.Teardown {
+		t.Errorf("Batch = %t, Teardown = %t, want both", caps.Batch, caps.Teardown)
+	}
+	if caps.Host == nil || caps.Host.CPUs == 0 {
+		t.Errorf("Host = %+v, want the host CPUs", caps.Host)
+	}
+
+	expected := map[string][]string{
+		"key":     {"create", "get", "list", "delete"},
+		"network": {"create", "get", "list", "delete"},
+		"vm":      {"create", "get", "list", "delete", "stats", "start", "stop", "reboot", "pause"},
+	}
+	if len(caps.Resources) != len(expected) {
+		t.Fatalf("got %d resources, want %d", len(caps.Resources), len(expected))
+	}
+	for _, res := range caps.Resources {
+		want := expected[res.Kind]
+		if len(res.Operations) != len(want) {
+			t.Errorf("%s operations = %v, want %v", res.Kind, res.Operations, want)
+			continue
+		}
+		for i := range want {
+			if res.Operations[i] != want[i] {
+				t.Errorf("%s operations = %v, want %v", res.Kind, res.Operations, want)
+				break
+			}
+		}
+	}
+}
+
+func TestKeyLifecycle(t *testing.T) {
+	p := newTestProvider(t)
+
+	result := p.KeyCreate(&providerv1.KeyCreateRequest{Name: "k"})
+	if !result.Success {
+		t.Fatalf("KeyCreate() error = %+v", result.Error)
+	}
+	key := result.Resource.(*providerv1.KeyState)
+	if key.Type != "ed25519" || key.Fingerprint == "" {
+		t.Errorf("key = %+v, want an ed25519 key with a fingerprint", key)
+	}
+	for _, path := range []string{key.PrivateKeyPath, key.PublicKeyPath} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("key file: %v", err)
+		}
+	}
+	if result := p.KeyCreate(&providerv1.KeyCreateRequest{Name: "k"}); result.Success || result.Error.Code != providerv1.ErrCodeAlreadyExists {
+		t.Errorf("KeyCreate() of an existing key = %+v, want ALREADY_EXISTS", result.Error)
+	}
+	if result := p.KeyCreate(&providerv1.KeyCreateRequest{Name: "dsa", Spec: providerv1.KeySpec{Type: "dsa"}}); result.Success || result.Error.Code != providerv1.ErrCodeInvalidSpec {
+		t.Errorf("KeyCreate() of a dsa key = %+v, want INVALID_SPEC", result.Error)
+	}
+
+	addVM(t, p, "web", nil, []string{"k"})
+	if result := p.KeyDelete("k"); result.Success || result.Error.Code != providerv1.ErrCodeResourceBusy {
+		t.Errorf("KeyDelete() of a key in use = %+v, want RESOURCE_BUSY", result.Error)
+	}
+	delete(p.vms, "web")
+
+	if result := p.KeyDelete("k"); !result.Success {
+		t.Fatalf("KeyDelete() error = %+v", result.Error)
+	}
+	if _, err := os.Stat(key.PrivateKeyPath); !os.IsNotExist(err) {
+		t.Errorf("private key still exists after delete: %v", err)
+	}
+	if result := p.KeyDelete("k"); !result.Success {
+		t.Errorf("KeyDelete() of a missing key error = %+v, want success", result.Error)
+	}
+}
+
+func TestNetworkCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      providerv1.NetworkCreateRequest
+		wantCode string
+		wantCIDR string
+		wantIP   string
+	}{
+		{
+			name:     "default user network",
+			req:      providerv1.NetworkCreateRequest{Name: "n"},
+			wantCIDR: "10.0.2.0/24",
+			wantIP:   "10.0.2.2",
+		},
+		{
+			name:     "nat is an alias of user",
+			req:      providerv1.NetworkCreateRequest{Name: "n", Kind: "nat", Spec: providerv1.NetworkSpec{CIDR: "192.168.100.1/24"}},
+			wantCIDR: "192.168.100.0/24",
+			wantIP:   "192.168.100.2",
+		},
+		{
+			name:     "user network too small",
+			req:      providerv1.NetworkCreateRequest{Name: "n", Spec: providerv1.NetworkSpec{CIDR: "192.168.100.0/28"}},
+			wantCode: providerv1.ErrCodeInvalidSpec,
+		},
+		{
+			name:     "bridge without bridge",
+			req:      providerv1.NetworkCreateRequest{Name: "n", Kind: KindBridge},
+			wantCode: providerv1.ErrCodeInvalidSpec,
+		},
+		{
+			name:     "bridge that does not exist",
+			req:      providerv1.NetworkCreateRequest{Name: "n", Kind: KindBridge, ProviderSpec: map[string]any{"bridge": "testenv-missing0"}},
+			wantCode: providerv1.ErrCodeInvalidSpec,
+		},
+		{
+			name:     "unsupported kind",
+			req:      providerv1.NetworkCreateRequest{Name: "n", Kind: "isolated"},
+			wantCode: providerv1.ErrCodeInvalidSpec,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := newTestProvider(t).NetworkCreate(&tt.req)
+			if tt.wantCode != "" {
+				if result.Success || result.Error.Code != tt.wantCode {
+					t.Fatalf("NetworkCreate() = %+v, want %s", result.Error, tt.wantCode)
+				}
+				return
+			}
+			if !result.Success {
+				t.Fatalf("NetworkCreate() error = %+v", result.Error)
+			}
+			network := result.Resource.(*providerv1.NetworkState)
+			if network.Kind != KindUser || network.CIDR != tt.wantCIDR || network.IP != tt.wantIP {
+				t.Errorf("network = %+v, want kind user, CIDR %s and IP %s", network, tt.wantCIDR, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestNetworkDelete_Busy(t *testing.T) {
+	p := newTestProvider(t)
+	if result := p.NetworkCreate(&providerv1.NetworkCreateRequest{Name: "n"}); !result.Success {
+		t.Fatalf("NetworkCreate() error = %+v", result.Error)
+	}
+	addVM(t, p, "web", []string{"n"}, nil)
+
+	if result := p.NetworkDelete("n"); result.Success || result.Error.Code != providerv1.ErrCodeResourceBusy {
+		t.Fatalf("NetworkDelete() of a network in use = %+v, want RESOURCE_BUSY", result.Error)
+	}
+	if result := p.VMDelete("web"); !result.Success {
+		t.Fatalf("VMDelete() error = %+v", result.Error)
+	}
+	if result := p.NetworkDelete("n"); !result.Success {
+		t.Fatalf("NetworkDelete() error = %+v", result.Error)
+	}
+	if _, err := os.Stat(p.networkStatePath("n")); !os.IsNotExist(err) {
+		t.Errorf("network state still exists after delete: %v", err)
+	}
+}
+
+func TestNewProviderWithConfig_LoadsState(t *testing.T) {
+	p := newTestProvider(t)
+	if result := p.KeyCreate(&providerv1.KeyCreateRequest{Name: "k"}); !result.Success {
+		t.Fatalf("KeyCreate() error = %+v", result.Error)
+	}
+	if result := p.NetworkCreate(&providerv1.NetworkCreateRequest{Name: "n"}); !result.Success {
+		t.Fatalf("NetworkCreate() error = %+v", result.Error)
+	}
+	addVM(t, p, "web", []string{"n"}, []string{"k"})
+
+	// A later provider process finds the resources; the VM has no QEMU
+	// process, so it is stopped
+	reloaded, err := NewProviderWithConfig(p.Config())
+	if err != nil {
+		t.Fatalf("NewProviderWithConfig() error = %v", err)
+	}
+	if result := reloaded.KeyGet("k"); !result.Success {
+		t.Errorf("KeyGet() error = %+v", result.Error)
+	}
+	if result := reloaded.NetworkGet("n"); !result.Success {
+		t.Errorf("NetworkGet() error = %+v", result.Error)
+	}
+	result := reloaded.VMGet("web")
+	if !result.Success {
+		t.Fatalf("VMGet() error = %+v", result.Error)
+	}
+	if status := result.Resource.(*providerv1.VMState).Status; status != "stopped" {
+		t.Errorf("status = %q, want stopped", status)
+	}
+	if result := reloaded.NetworkDelete("n"); result.Success {
+		t.Error("NetworkDelete() of a network of a reloaded VM succeeded, want RESOURCE_BUSY")
+	}
+}
+
+func TestVMCreate_InvalidSpec(t *testing.T) {
+	p := newTestProvider(t)
+	if result := p.NetworkCreate(&providerv1.NetworkCreateRequest{Name: "n"}); !result.Success {
+		t.Fatalf("NetworkCreate() error = %+v", result.Error)
+	}
+
+	tests := []struct {
+		name     string
+		req      providerv1.VMCreateRequest
+		wantCode string
+	}{
+		{"no network", providerv1.VMCreateRequest{Name: "web"}, providerv1.ErrCodeInvalidSpec},
+		{"missing network", providerv1.VMCreateRequest{Name: "web", Spec: providerv1.VMSpec{Network: "missing"}}, providerv1.ErrCodeNotFound},
+		{"invalid name", providerv1.VMCreateRequest{Name: "../web", Spec: providerv1.VMSpec{Network: "n"}}, providerv1.ErrCodeInvalidSpec},
+		{"vsock", providerv1.VMCreateRequest{Name: "web", Spec: providerv1.VMSpec{Network: "n", Vsock: &providerv1.VsockSpec{}}}, providerv1.ErrCodeInvalidSpec},
+		{"pci passthrough", providerv1.VMCreateRequest{Name: "web", Spec: providerv1.VMSpec{Network: "n", PCIPassthrough: []string{"0000:01:00.0"}}}, providerv1.ErrCodeInvalidSpec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.VMCreate(&tt.req)
+			if result.Success || result.Error.Code != tt.wantCode {
+				t.Fatalf("VMCreate() = %+v, want %s", result.Error, tt.wantCode)
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(filepath.Join(p.config.StateDir, "vms")); len(entries) != 0 {
+		t.Errorf("VMCreate() left %d vm directories", len(entries))
+	}
+}
+
+func TestVMDelete_Idempotent(t *testing.T) {
+	p := newTestProvider(t)
+	if result := p.VMDelete("missing"); !result.Success {
+		t.Errorf("VMDelete() of a missing vm error = %+v, want success", result.Error)
+	}
+
+	addVM(t, p, "web", nil, nil)
+	if result := p.VMDelete("web"); !result.Success {
+		t.Fatalf("VMDelete() error = %+v", result.Error)
+	}
+	if _, err := os.Stat(p.vmDir("web")); !os.IsNotExist(err) {
+		t.Errorf("vm directory still exists after delete: %v", err)
+	}
+	if result := p.VMGet("web"); result.Success {
+		t.Error("VMGet() after delete succeeded, want NOT_FOUND")
+	}
+}
+
+func TestVMPower(t *testing.T) {
+	p := newTestProvider(t)
+	if result := p.VMPower(providerv1.PowerStop, &providerv1.VMPowerRequest{Name: "missing"}); result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("VMPower() of a missing vm = %+v, want NOT_FOUND", result.Error)
+	}
+
+	// Without a QEMU process the VM is stopped, so it cannot be paused, and
+	// stopping it again is a no-op
+	addVM(t, p, "web", nil, nil)
+	if result := p.VMPower(providerv1.PowerPause, &providerv1.VMPowerRequest{Name: "web"}); result.Success {
+		t.Error("VMPower(pause) of a stopped vm succeeded")
+	}
+	result := p.VMPower(providerv1.PowerStop, &providerv1.VMPowerRequest{Name: "web"})
+	if !result.Success {
+		t.Fatalf("VMPower(stop) error = %+v", result.Error)
+	}
+	if status := result.Resource.(*providerv1.VMState).Status; status != "stopped" {
+		t.Errorf("status = %q, want stopped", status)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+)
+
+// qmpTimeout bounds a QMP exchange.
+const qmpTimeout = 10 * time.Second
+
+// qmpResponse is a reply or an asynchronous event read from a QMP socket.
+type qmpResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+	Event string `json:"event"`
+}
+
+// qmpStatus is the reply of the query-status command.
+type qmpStatus struct {
+	Running bool   `json:"running"`
+	Status  string `json:"status"`
+}
+
+// qmpExecute connects to the QMP socket at path, negotiates capabilities
+// and runs command with args, which may be nil. It returns the result of the
+// command. Events received in between are skipped.
+func qmpExecute(path, command string, args map[string]any) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", path, qmpTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to QMP socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(qmpTimeout))
+
+	dec := json.NewDecoder(conn)
+	var greeting struct {
+		QMP json.RawMessage `json:"QMP"`
+	}
+	if err := dec.Decode(&greeting); err != nil || greeting.QMP == nil {
+		return nil, fmt.Errorf("failed to read QMP greeting: %v", err)
+	}
+	if _, err := qmpCall(conn, dec, "qmp_capabilities", nil); err != nil {
+		return nil, err
+	}
+	return qmpCall(conn, dec, command, args)
+}
+
+// qmpCall sends one command on an established QMP connection and waits for
+// its reply.
+func qmpCall(conn net.Conn, dec *json.Decoder, command string, args map[string]any) (json.RawMessage, error) {
+	req := map[string]any{"execute": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send QMP command %s: %w", command, err)
+	}
+	for {
+		var resp qmpResponse
+		if err := dec.Decode(&resp); err != nil {
+			return nil, fmt.Errorf("failed to read reply to QMP command %s: %w", command, err)
+		}
+		if resp.Event != "" {
+			continue
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("QMP command %s failed: %s", command, resp.Error.Desc)
+		}
+		return resp.Return, nil
+	}
+}
+
+// queryStatus returns the run state of the VM behind a QMP socket, e.g.
+// "running", "paused" or "shutdown".
+func queryStatus(path string) (string, error) {
+	raw, err := qmpExecute(path, "query-status", nil)
+	if err != nil {
+		return "", err
+	}
+	var status qmpStatus
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return "", fmt.Errorf("failed to parse query-status reply: %w", err)
+	}
+	return status.Status, nil
+}
@@ -0,0 +1,119 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeQMP serves the QMP protocol on a unix socket: it greets, accepts
+// qmp_capabilities, emits an event, then replies to commands from replies,
+// where a missing command is an error. The returned function lists the
+// commands it ran.
+func fakeQMP(t *testing.T, replies map[string]string) (string, func() []string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "qmp.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	var (
+		mu       sync.Mutex
+		commands []string
+	)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			w := bufio.NewWriter(conn)
+			_, _ = w.WriteString(`{"QMP": {"version": {}, "capabilities": []}}` + "\n")
+			_ = w.Flush()
+			dec := json.NewDecoder(conn)
+			for {
+				var req struct {
+					Execute string `json:"execute"`
+				}
+				if err := dec.Decode(&req); err != nil {
+					break
+				}
+				mu.Lock()
+				commands = append(commands, req.Execute)
+				mu.Unlock()
+				_, _ = w.WriteString(`{"event": "RESUME", "data": {}}` + "\n")
+				switch reply, ok := replies[req.Execute]; {
+				case req.Execute == "qmp_capabilities":
+					_, _ = w.WriteString(`{"return": {}}` + "\n")
+				case ok:
+					_, _ = w.WriteString(`{"return": ` + reply + "}\n")
+				default:
+					_, _ = w.WriteString(`{"error": {"class": "CommandNotFound", "desc": "The command ` + req.Execute + ` has not been found"}}` + "\n")
+				}
+				_ = w.Flush()
+			}
+			_ = conn.Close()
+		}
+	}()
+	return path, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func TestQMPExecute(t *testing.T) {
+	path, commands := fakeQMP(t, map[string]string{"stop": "{}"})
+
+	if _, err := qmpExecute(path, "stop", nil); err != nil {
+		t.Fatalf("qmpExecute(stop) error = %v", err)
+	}
+	if got := strings.Join(commands(), ","); got != "qmp_capabilities,stop" {
+		t.Errorf("commands = %s, want qmp_capabilities,stop", got)
+	}
+
+	_, err := qmpExecute(path, "bogus", nil)
+	if err == nil || !strings.Contains(err.Error(), "has not been found") {
+		t.Errorf("qmpExecute(bogus) error = %v, want the QMP error", err)
+	}
+}
+
+func TestQMPExecute_NoSocket(t *testing.T) {
+	if _, err := qmpExecute(filepath.Join(t.TempDir(), "missing.sock"), "stop", nil); err == nil {
+		t.Error("qmpExecute() without a socket succeeded")
+	}
+}
+
+func TestQueryStatus(t *testing.T) {
+	path, _ := fakeQMP(t, map[string]string{"query-status": `{"running": false, "status": "shutdown"}`})
+
+	status, err := queryStatus(path)
+	if err != nil {
+		t.Fatalf("queryStatus() error = %v", err)
+	}
+	if status != "shutdown" {
+		t.Errorf("queryStatus() = %q, want shutdown", status)
+	}
+}
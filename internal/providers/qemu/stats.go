@@ -0,0 +1,180 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// clockTickNs is the duration of a clock tick of /proc/<pid>/stat (USER_HZ
+// is 100 on Linux).
+const clockTickNs = 10_000_000
+
+// blockStats is an entry of the reply of the query-blockstats command.
+type blockStats struct {
+	Device string `json:"device"`
+	Stats  struct {
+		ReadBytes       int64 `json:"rd_bytes"`
+		WriteBytes      int64 `json:"wr_bytes"`
+		ReadOperations  int64 `json:"rd_operations"`
+		WriteOperations int64 `json:"wr_operations"`
+	} `json:"stats"`
+}
+
+// VMStats returns a snapshot of the resource usage of a running VM, from
+// the CPU time and RSS of its QEMU process and the block statistics of QMP.
+// The CPU usage is measured over the interval of the request, during which
+// the call blocks. The boot disk reports the usage of its image file; its
+// guest capacity and interface statistics are not reported.
+func (p *Provider) VMStats(req *providerv1.VMStatsRequest) *providerv1.OperationResult {
+	if req.Name == "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("name is required"))
+	}
+	p.mu.RLock()
+	v, exists := p.vms[req.Name]
+	var vcpus, memoryMB int
+	if exists {
+		vcpus, memoryMB = v.VCPUs, v.MemoryMB
+	}
+	p.mu.RUnlock()
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	pid := p.pid(req.Name)
+	if pid == 0 {
+		return providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("vm %s is not running", req.Name), true))
+	}
+
+	startCPU, err := processCPUTime(pid)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), true))
+	}
+	start := time.Now()
+	time.Sleep(time.Duration(req.StatsInterval()) * time.Millisecond)
+	cpuTime, err := processCPUTime(pid)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), true))
+	}
+	now := time.Now()
+
+	stats := &providerv1.VMStats{
+		Name:      req.Name,
+		Timestamp: now.UTC().Format(time.RFC3339),
+		VCPUs:     vcpus,
+		CPUTimeNs: cpuTime,
+		MemoryMB:  memoryMB,
+	}
+	if cpuTime >= startCPU && vcpus > 0 {
+		stats.CPUPercent = float64(cpuTime-startCPU) / float64(now.Sub(start).Nanoseconds()*int64(vcpus)) * 100
+	}
+	if status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid)); err == nil {
+		stats.MemoryRSSMB = parseRSSMB(status)
+	}
+
+	raw, err := qmpExecute(p.qmpSocket(req.Name), "query-blockstats", nil)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to get block statistics: "+err.Error(), true))
+	}
+	var blocks []blockStats
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to parse block statistics: "+err.Error(), false))
+	}
+	for _, b := range blocks {
+		// Skip the cloud-init seed
+		if b.Device != "vda" {
+			continue
+		}
+		disk := providerv1.DiskStats{
+			Device:        b.Device,
+			ReadBytes:     b.Stats.ReadBytes,
+			WriteBytes:    b.Stats.WriteBytes,
+			ReadRequests:  b.Stats.ReadOperations,
+			WriteRequests: b.Stats.WriteOperations,
+		}
+		disk.AllocationBytes, disk.PhysicalBytes = imageUsage(filepath.Join(p.vmDir(req.Name), diskFile))
+		stats.Disks = append(stats.Disks, disk)
+	}
+	return providerv1.SuccessResult(stats)
+}
+
+// imageUsage returns the host storage allocated to an image file and its
+// size, holes included. Both are zero when the file cannot be read.
+func imageUsage(path string) (allocation, physical uint64) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, 0
+	}
+	return uint64(st.Blocks) * 512, uint64(st.Size)
+}
+
+// processCPUTime returns the user and system CPU time of a process.
+func processCPUTime(pid int) (uint64, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CPU time of QEMU: %w", err)
+	}
+	return parseCPUTime(data)
+}
+
+// parseCPUTime returns the user and system CPU time in a /proc/<pid>/stat
+// line. The command name may contain spaces, so fields are counted from
+// its closing parenthesis.
+func parseCPUTime(stat []byte) (uint64, error) {
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed process stat")
+	}
+	// Fields after the name start with the state (field 3); utime and stime
+	// are fields 14 and 15.
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed process stat")
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed process stat: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed process stat: %w", err)
+	}
+	return (utime + stime) * clockTickNs, nil
+}
+
+// parseRSSMB returns the resident memory in a /proc/<pid>/status file, or
+// zero.
+func parseRSSMB(status []byte) int {
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "VmRSS:" {
+			kb, _ := strconv.Atoi(fields[1])
+			return kb / 1024
+		}
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCPUTime(t *testing.T) {
+	// The command name contains a space and a parenthesis
+	stat := []byte("4242 (qemu-system (x)) S 1 4242 4242 0 -1 4194560 100 0 0 0 250 50 0 0 20 0 5 0 100 0 0")
+	got, err := parseCPUTime(stat)
+	if err != nil {
+		t.Fatalf("parseCPUTime() error = %v", err)
+	}
+	if want := uint64(300 * clockTickNs); got != want {
+		t.Errorf("parseCPUTime() = %d, want %d", got, want)
+	}
+
+	if _, err := parseCPUTime([]byte("4242 (qemu) S 1")); err == nil {
+		t.Error("parseCPUTime() of a short line succeeded")
+	}
+}
+
+func TestParseRSSMB(t *testing.T) {
+	status := []byte("Name:\tqemu-system-x86\nVmPeak:\t 4000000 kB\nVmRSS:\t  524288 kB\n")
+	if got := parseRSSMB(status); got != 512 {
+		t.Errorf("parseRSSMB() = %d, want 512", got)
+	}
+	if got := parseRSSMB([]byte("Name:\tqemu\n")); got != 0 {
+		t.Errorf("parseRSSMB() without VmRSS = %d, want 0", got)
+	}
+}
+
+func TestImageUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.qcow2")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(64 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	allocation, physical := imageUsage(path)
+	if physical != 64<<20 {
+		t.Errorf("physical = %d, want %d", physical, 64<<20)
+	}
+	if allocation >= physical {
+		t.Errorf("allocation = %d, want less than the size of a sparse file", allocation)
+	}
+	if allocation, physical := imageUsage(filepath.Join(t.TempDir(), "missing")); allocation != 0 || physical != 0 {
+		t.Errorf("imageUsage() of a missing file = %d, %d, want zeroes", allocation, physical)
+	}
+}
@@ -0,0 +1,545 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/internal/providers/common"
+)
+
+// Defaults of VMs whose spec leaves them unset.
+const (
+	defaultMemoryMB = 2048
+	defaultVCPUs    = 2
+	defaultDiskSize = "20G"
+)
+
+// virtioDiscard passes the discards of the guest to the image and turns
+// written zeroes into holes, keeping virtio disks sparse on the host.
+const virtioDiscard = ",discard=unmap,detect-zeroes=unmap"
+
+// arpTimeout bounds the wait for the address of a VM on a bridge network
+// when readiness checks need it.
+const arpTimeout = 2 * time.Minute
+
+// nic is a network interface of a VM.
+type nic struct {
+	network *providerv1.NetworkState
+	mac     string
+	// guestIP is the address of the VM on a user-mode network.
+	guestIP string
+	// sshPort is the host port forwarded to the SSH port of the VM, if any.
+	sshPort int
+}
+
+// launchConfig is what the QEMU command line of a VM is built from.
+type launchConfig struct {
+	name  string
+	dir   string
+	accel string
+	spec  *providerv1.VMSpec
+	nics  []nic
+}
+
+// VMCreate creates the disk and cloud-init seed of a VM and starts its QEMU
+// process. Files left by a previous VM of the same name are removed first.
+func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(req.Name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid vm name: " + req.Name))
+	}
+	if _, exists := p.vms[req.Name]; exists {
+		return providerv1.ErrorResult(providerv1.NewAlreadyExistsError("vm", req.Name))
+	}
+	if feature := unsupportedFeature(&req.Spec); feature != "" {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError(feature + " is not supported by the qemu provider"))
+	}
+
+	// Resolve network names: Networks takes precedence over Network.
+	networkNames := req.Spec.Networks
+	if len(networkNames) == 0 && req.Spec.Network != "" {
+		networkNames = []string{req.Spec.Network}
+	}
+	if len(networkNames) == 0 {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("VM requires at least one network (set network or networks)"))
+	}
+	nics := make([]nic, 0, len(networkNames))
+	for i, netName := range networkNames {
+		network, exists := p.networks[netName]
+		if !exists {
+			return providerv1.ErrorResult(providerv1.NewNotFoundError("network", netName))
+		}
+		n := nic{network: network, mac: randomMAC()}
+		if i < len(req.Spec.MACAddresses) && req.Spec.MACAddresses[i] != "" {
+			if _, err := net.ParseMAC(req.Spec.MACAddresses[i]); err != nil {
+				return providerv1.ErrorResult(providerv1.NewInvalidSpecError(fmt.Sprintf("invalid MAC address %q", req.Spec.MACAddresses[i])))
+			}
+			n.mac = req.Spec.MACAddresses[i]
+		}
+		if network.Kind == KindUser {
+			_, ipNet, err := net.ParseCIDR(network.CIDR)
+			if err != nil {
+				return providerv1.ErrorResult(providerv1.NewProviderError("invalid CIDR of network "+netName+": "+err.Error(), false))
+			}
+			n.guestIP = hostAddress(ipNet, 15).String()
+			if i == 0 {
+				port, err := freePort()
+				if err != nil {
+					return providerv1.ErrorResult(providerv1.NewProviderError("failed to pick an SSH port: "+err.Error(), true))
+				}
+				n.sshPort = port
+			}
+		}
+		nics = append(nics, n)
+	}
+
+	dir := p.vmDir(req.Name)
+	if len(filepath.Join(dir, qmpFile)) > maxSocketPath {
+		return providerv1.ErrorResult(providerv1.NewProviderError(
+			fmt.Sprintf("state directory %s is too long to hold the QMP socket of vm %s", p.config.StateDir, req.Name), false))
+	}
+
+	// Remove what a previous run may have left, then roll back on failure
+	p.kill(req.Name)
+	_ = os.RemoveAll(dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to create vm directory: "+err.Error(), false))
+	}
+	created := false
+	defer func() {
+		if !created {
+			p.kill(req.Name)
+			_ = os.RemoveAll(dir)
+		}
+	}()
+
+	diskPath := filepath.Join(dir, diskFile)
+	diskSize := req.Spec.Disk.Size
+	if diskSize == "" {
+		diskSize = defaultDiskSize
+	}
+	if _, err := common.CreateDisk(p.config.DiskBackend, req.Spec.Disk.BaseImage, diskPath, diskSize, p.config.QemuImgPath); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to create disk: "+err.Error(), false))
+	}
+	if err := common.LabelFile(diskPath, req.Labels); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	for i, d := range req.Spec.Disks {
+		path := filepath.Join(dir, dataDiskFile(i, d))
+		if err := common.CreateDataDisk(p.config.DiskBackend, d, path, p.config.QemuImgPath); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(fmt.Sprintf("failed to create data disk %d: %s", i+1, err.Error()), false))
+		}
+		if err := common.LabelFile(path, req.Labels); err != nil {
+			return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+		}
+	}
+
+	// The NICs configured in the guest are matched by MAC address
+	req.Spec.MACAddresses = make([]string, len(nics))
+	for i, n := range nics {
+		req.Spec.MACAddresses[i] = n.mac
+	}
+
+	seedPath := filepath.Join(dir, seedFile)
+	ciConfig, err := common.GenerateCloudInitSeed(req.Name, &req.Spec, p.keys, seedPath, p.config.ISOTool, req.Labels)
+	if err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to generate cloud-init ISO: "+err.Error(), false))
+	}
+
+	v := &vm{
+		Args:     qemuArgs(launchConfig{name: req.Name, dir: dir, accel: p.config.Accel, spec: &req.Spec, nics: nics}),
+		VCPUs:    orDefault(req.Spec.VCPUs, defaultVCPUs),
+		MemoryMB: orDefault(req.Spec.Memory, defaultMemoryMB),
+		State: &providerv1.VMState{
+			Name:          req.Name,
+			Status:        "creating",
+			IPs:           make(map[string]string, len(nics)),
+			MACs:          make(map[string]string, len(nics)),
+			ConsoleOutput: filepath.Join(dir, consoleFile),
+			QMPSocket:     filepath.Join(dir, qmpFile),
+			CreatedAt:     time.Now().UTC().Format(time.RFC3339),
+			Labels:        req.Labels,
+			ProviderState: map[string]any{
+				"diskPath":     diskPath,
+				"cloudInitISO": seedPath,
+				"networks":     networkNames,
+				"keys":         ciConfig.MatchedKeyNames,
+				"accel":        p.config.Accel,
+			},
+		},
+	}
+	if err := p.launch(v); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to start QEMU: "+err.Error(), true))
+	}
+	v.State.ProviderState["pid"] = p.pid(req.Name)
+
+	// Resolve the addresses of the NICs. The first one is used to connect.
+	first := nics[0]
+	v.State.MAC = first.mac
+	for i, n := range nics {
+		v.State.MACs[n.network.Name] = n.mac
+		switch {
+		case n.guestIP != "":
+			v.State.IPs[n.network.Name] = n.guestIP
+		case i == 0:
+			timeout := time.Duration(0)
+			if req.Spec.Readiness != nil {
+				timeout = arpTimeout
+			}
+			if ip := resolveBridgeIP(req.Spec.CloudInit, n.mac, timeout); ip != "" {
+				v.State.IPs[n.network.Name] = ip
+			}
+		default:
+			if ip := resolveBridgeIP(nil, n.mac, 0); ip != "" {
+				v.State.IPs[n.network.Name] = ip
+			}
+		}
+	}
+	addr := v.State.IPs[first.network.Name]
+	if first.sshPort != 0 {
+		v.State.IP = "127.0.0.1"
+		v.State.SSHPort = first.sshPort
+		addr = net.JoinHostPort(v.State.IP, strconv.Itoa(first.sshPort))
+	} else {
+		v.State.IP = addr
+	}
+
+	// Run SSH and cloud-init readiness checks if configured
+	if req.Spec.Readiness != nil {
+		if addr == "" {
+			return providerv1.ErrorResult(providerv1.NewTimeoutError("ip-resolution"))
+		}
+		if opErr := common.WaitForReadiness(req.Spec.Readiness, addr); opErr != nil {
+			return providerv1.ErrorResult(opErr)
+		}
+		if hostKeys := common.CollectHostKeys(req.Spec.Readiness, addr); len(hostKeys) > 0 {
+			v.State.HostKeys, v.State.HostKeyFingerprints = hostKeys, common.HostKeyFingerprints(hostKeys)
+		}
+	}
+	v.State.SSHCommand = p.sshCommand(v.State, ciConfig)
+
+	v.State.Status = "running"
+	if err := p.saveVM(v); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	created = true
+	p.vms[req.Name] = v
+	return providerv1.SuccessResult(v.State)
+}
+
+// VMGet retrieves a VM by name.
+func (p *Provider) VMGet(name string) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	v, exists := p.vms[name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", name))
+	}
+	p.refresh(v)
+	return providerv1.SuccessResult(v.State)
+}
+
+// VMList lists all VMs carrying the labels of the labels filter.
+func (p *Provider) VMList(filter map[string]any) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	selector := providerv1.LabelSelector(filter)
+	vms := make([]*providerv1.VMState, 0, len(p.vms))
+	for _, v := range p.vms {
+		if !providerv1.MatchLabels(selector, v.State.Labels) {
+			continue
+		}
+		p.refresh(v)
+		vms = append(vms, v.State)
+	}
+	return providerv1.SuccessResult(vms)
+}
+
+// VMDelete stops the QEMU process of a VM and removes its files. It
+// succeeds when the VM does not exist.
+func (p *Provider) VMDelete(name string) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !validName(name) {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError("invalid vm name: " + name))
+	}
+	p.kill(name)
+	if err := os.RemoveAll(p.vmDir(name)); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError("failed to remove vm files: "+err.Error(), true))
+	}
+	delete(p.vms, name)
+	return providerv1.SuccessResult(nil)
+}
+
+// refresh marks a VM stopped when its QEMU process is gone, e.g. after the
+// host rebooted.
+func (p *Provider) refresh(v *vm) {
+	if v.State.Status == "stopped" || p.pid(v.State.Name) != 0 {
+		return
+	}
+	log.Printf("QEMU process of vm %s is gone, marking it stopped", v.State.Name)
+	v.State.Status = "stopped"
+	_ = p.saveVM(v)
+}
+
+// sshCommand returns the command connecting to a VM with the first provider
+// key authorized for its first cloud-init user, or "" without one.
+func (p *Provider) sshCommand(state *providerv1.VMState, ciConfig *common.CloudInitConfig) string {
+	if state.IP == "" || len(ciConfig.Users) == 0 || len(ciConfig.MatchedKeyNames) == 0 {
+		return ""
+	}
+	key, exists := p.keys[ciConfig.MatchedKeyNames[0]]
+	if !exists {
+		return ""
+	}
+	port := ""
+	if state.SSHPort != 0 {
+		port = fmt.Sprintf(" -p %d", state.SSHPort)
+	}
+	return fmt.Sprintf("ssh -i %s%s -o StrictHostKeyChecking=no %s@%s",
+		key.PrivateKeyPath, port, ciConfig.Users[0].Name, state.IP)
+}
+
+// dataDiskFile returns the file name of the data disk at index in the
+// directory of its VM, e.g. data1.qcow2.
+func dataDiskFile(index int, d providerv1.DiskSpec) string {
+	return fmt.Sprintf("data%d.%s", index+1, common.DataDiskFormat(d))
+}
+
+// unsupportedFeature returns the first feature of spec the provider cannot
+// run, or "".
+func unsupportedFeature(spec *providerv1.VMSpec) string {
+	switch {
+	case spec.Architecture != "" && spec.Architecture != "x86_64":
+		return "architecture " + spec.Architecture
+	case spec.Disk.Encryption != nil && spec.Disk.Encryption.Enabled:
+		return "disk encryption"
+	case spec.Disk.Bus != "" && spec.Disk.Bus != "virtio" && spec.Disk.Bus != "ide" && spec.Disk.Bus != "sata":
+		return "disk bus " + spec.Disk.Bus
+	case len(spec.VirtioFS) > 0:
+		return "virtioFS"
+	case spec.MemoryBacking != nil:
+		return "memoryBacking"
+	case spec.Security != nil:
+		return "security"
+	case spec.Vsock != nil:
+		return "vsock"
+	case len(spec.PCIPassthrough) > 0:
+		return "pciPassthrough"
+	case len(spec.Mdevs) > 0:
+		return "mdevs"
+	case spec.Console != nil && (spec.Console.VNC || spec.Console.Spice):
+		return "graphical console"
+	case spec.Boot.SecureBoot:
+		return "secure boot"
+	case spec.Boot.Firmware == "uefi" && spec.Boot.OVMFPath == "":
+		return "uefi without ovmfPath"
+	}
+	return ""
+}
+
+// qemuArgs returns the command line of the QEMU process of a VM. QEMU
+// daemonizes and keeps running when the guest powers off (-no-shutdown), so
+// that graceful stops and reboots can be observed through QMP.
+func qemuArgs(cfg launchConfig) []string {
+	spec := cfg.spec
+	machine := spec.MachineType
+	if machine == "" {
+		machine = "q35"
+	}
+	vcpus := orDefault(spec.VCPUs, defaultVCPUs)
+	smp := strconv.Itoa(vcpus)
+	cpu := "max"
+	if cfg.accel == "kvm" {
+		cpu = "host"
+	}
+	if spec.CPU != nil {
+		if spec.CPU.Model != "" {
+			cpu = spec.CPU.Model
+		}
+		if spec.CPU.Sockets > 0 {
+			smp += ",sockets=" + strconv.Itoa(spec.CPU.Sockets)
+		}
+		if spec.CPU.Cores > 0 {
+			smp += ",cores=" + strconv.Itoa(spec.CPU.Cores)
+		}
+	}
+
+	diskIf := "virtio"
+	if spec.Disk.Bus == "ide" || spec.Disk.Bus == "sata" {
+		diskIf = "ide"
+	}
+	disk := fmt.Sprintf("file=%s,if=%s,id=vda,format=qcow2", escape(filepath.Join(cfg.dir, diskFile)), diskIf)
+	if diskIf == "virtio" {
+		disk += virtioDiscard
+	}
+	if spec.Disk.Cache != "" {
+		disk += ",cache=" + spec.Disk.Cache
+	}
+
+	args := []string{
+		"-name", cfg.name,
+		"-machine", machine + ",accel=" + cfg.accel,
+		"-cpu", cpu,
+		"-smp", smp,
+		"-m", strconv.Itoa(orDefault(spec.Memory, defaultMemoryMB)),
+		"-drive", disk,
+		"-drive", fmt.Sprintf("file=%s,id=cidata,media=cdrom,readonly=on", escape(filepath.Join(cfg.dir, seedFile))),
+	}
+	for i, d := range spec.Disks {
+		args = append(args, "-drive", fmt.Sprintf("file=%s,if=virtio,id=data%d,format=%s%s",
+			escape(filepath.Join(cfg.dir, dataDiskFile(i, d))), i+1, common.DataDiskFormat(d), virtioDiscard))
+	}
+	if spec.UUID != "" {
+		args = append(args, "-uuid", spec.UUID)
+	}
+
+	for i, n := range cfg.nics {
+		netdev := fmt.Sprintf("bridge,id=net%d,br=%s", i, n.network.InterfaceName)
+		if n.network.Kind == KindUser {
+			netdev = fmt.Sprintf("user,id=net%d,net=%s,dhcpstart=%s", i, n.network.CIDR, n.guestIP)
+			if n.sshPort != 0 {
+				netdev += fmt.Sprintf(",hostfwd=tcp:127.0.0.1:%d-:22", n.sshPort)
+			}
+		}
+		device := fmt.Sprintf("virtio-net-pci,netdev=net%d,mac=%s", i, n.mac)
+		if i < len(spec.NICs) {
+			switch nicSpec := spec.NICs[i]; {
+			case nicSpec.Model == "e1000":
+				device = fmt.Sprintf("e1000,netdev=net%d,mac=%s", i, n.mac)
+			case nicSpec.MTU > 0:
+				device += fmt.Sprintf(",host_mtu=%d", nicSpec.MTU)
+			}
+		}
+		args = append(args,
+			"-netdev", netdev,
+			"-device", device)
+	}
+
+	if order := bootOrder(spec.Boot.Order); order != "" {
+		args = append(args, "-boot", "order="+order)
+	}
+	if spec.Boot.Firmware == "uefi" {
+		args = append(args, "-bios", spec.Boot.OVMFPath)
+	}
+	if spec.GuestAgent {
+		args = append(args,
+			"-chardev", fmt.Sprintf("socket,id=qga0,path=%s,server=on,wait=off", escape(filepath.Join(cfg.dir, qgaFile))),
+			"-device", "virtio-serial",
+			"-device", "virtserialport,chardev=qga0,name=org.qemu.guest_agent.0")
+	}
+
+	return append(args,
+		"-display", "none",
+		"-serial", "file:"+filepath.Join(cfg.dir, consoleFile),
+		"-qmp", fmt.Sprintf("unix:%s,server=on,wait=off", escape(filepath.Join(cfg.dir, qmpFile))),
+		"-pidfile", filepath.Join(cfg.dir, pidFile),
+		"-no-shutdown",
+		"-daemonize",
+	)
+}
+
+// bootOrder converts boot devices ("hd", "cdrom", "network") to the drive
+// letters of the QEMU -boot option.
+func bootOrder(devices []string) string {
+	letters := map[string]string{"hd": "c", "cdrom": "d", "network": "n"}
+	var order strings.Builder
+	for _, device := range devices {
+		order.WriteString(letters[device])
+	}
+	return order.String()
+}
+
+// escape escapes the commas of a value of a QEMU option list.
+func escape(value string) string {
+	return strings.ReplaceAll(value, ",", ",,")
+}
+
+// orDefault returns value, or def when value is zero.
+func orDefault(value, def int) int {
+	if value == 0 {
+		return def
+	}
+	return value
+}
+
+// randomMAC returns a random MAC address in the range QEMU uses.
+func randomMAC() string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2])
+}
+
+// freePort returns a TCP port of 127.0.0.1 that is free at the time of the
+// call.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// resolveBridgeIP returns the address of a VM on a bridge network: the
+// static address of its cloud-init network configuration, or the address
+// the host ARP table holds for mac, waiting up to timeout for it.
+func resolveBridgeIP(ci *providerv1.CloudInitSpec, mac string, timeout time.Duration) string {
+	if ip := common.ExtractStaticIP(ci); ip != "" {
+		return ip
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		if data, err := os.ReadFile("/proc/net/arp"); err == nil {
+			if ip := arpLookup(data, mac); ip != "" {
+				return ip
+			}
+		}
+		if time.Now().After(deadline) {
+			return ""
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// arpLookup returns the address of mac in the contents of /proc/net/arp.
+func arpLookup(data []byte, mac string) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// IP address, HW type, Flags, HW address, Mask, Device
+		if len(fields) >= 4 && strings.EqualFold(fields[3], mac) && fields[2] != "0x0" {
+			return fields[0]
+		}
+	}
+	return ""
+}
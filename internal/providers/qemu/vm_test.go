@@ -0,0 +1,169 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package qemu
+
+import (
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestQemuArgs(t *testing.T) {
+	user := &providerv1.NetworkState{Name: "default", Kind: KindUser, CIDR: "10.0.2.0/24"}
+	bridge := &providerv1.NetworkState{Name: "lan", Kind: KindBridge, InterfaceName: "br0"}
+	cfg := launchConfig{
+		name:  "web",
+		dir:   "/state/vms/web",
+		accel: "kvm",
+		spec: &providerv1.VMSpec{
+			Memory:     4096,
+			VCPUs:      4,
+			Disk:       providerv1.DiskSpec{Cache: "writeback"},
+			Disks:      []providerv1.DiskSpec{{Size: "10G"}, {Size: "1G", Format: "raw"}},
+			Boot:       providerv1.BootSpec{Order: []string{"network", "hd"}},
+			GuestAgent: true,
+			UUID:       "0b1c2d3e-4f50-8172-8394-a5b6c7d8e9f0",
+		},
+		nics: []nic{
+			{network: user, mac: "52:54:00:00:00:01", guestIP: "10.0.2.15", sshPort: 40022},
+			{network: bridge, mac: "52:54:00:00:00:02"},
+		},
+	}
+	args := strings.Join(qemuArgs(cfg), " ")
+
+	for _, want := range []string{
+		"-name web",
+		"-uuid 0b1c2d3e-4f50-8172-8394-a5b6c7d8e9f0",
+		"-machine q35,accel=kvm",
+		"-cpu host",
+		"-smp 4",
+		"-m 4096",
+		"-drive file=/state/vms/web/disk.qcow2,if=virtio,id=vda,format=qcow2,discard=unmap,detect-zeroes=unmap,cache=writeback",
+		"-drive file=/state/vms/web/seed.iso,id=cidata,media=cdrom,readonly=on",
+		"-drive file=/state/vms/web/data1.qcow2,if=virtio,id=data1,format=qcow2,discard=unmap,detect-zeroes=unmap",
+		"-drive file=/state/vms/web/data2.raw,if=virtio,id=data2,format=raw,discard=unmap,detect-zeroes=unmap",
+		"-netdev user,id=net0,net=10.0.2.0/24,dhcpstart=10.0.2.15,hostfwd=tcp:127.0.0.1:40022-:22",
+		"-device virtio-net-pci,netdev=net0,mac=52:54:00:00:00:01",
+		"-netdev bridge,id=net1,br=br0",
+		"-device virtio-net-pci,netdev=net1,mac=52:54:00:00:00:02",
+		"-boot order=nc",
+		"name=org.qemu.guest_agent.0",
+		"-serial file:/state/vms/web/console.log",
+		"-qmp unix:/state/vms/web/qmp.sock,server=on,wait=off",
+		"-pidfile /state/vms/web/qemu.pid",
+		"-no-shutdown",
+		"-daemonize",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("qemuArgs() = %s\nmissing %q", args, want)
+		}
+	}
+}
+
+func TestQemuArgs_Defaults(t *testing.T) {
+	cfg := launchConfig{
+		name:  "web",
+		dir:   "/state,dir/vms/web",
+		accel: "tcg",
+		spec:  &providerv1.VMSpec{Disk: providerv1.DiskSpec{Bus: "sata"}},
+	}
+	args := strings.Join(qemuArgs(cfg), " ")
+
+	for _, want := range []string{
+		"-cpu max",
+		"-smp 2",
+		"-m 2048",
+		// Commas of paths are escaped in option lists
+		"-drive file=/state,,dir/vms/web/disk.qcow2,if=ide,",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("qemuArgs() = %s\nmissing %q", args, want)
+		}
+	}
+	for _, unwanted := range []string{"-boot", "-bios", "guest_agent", "-uuid"} {
+		if strings.Contains(args, unwanted) {
+			t.Errorf("qemuArgs() = %s\nunexpected %q", args, unwanted)
+		}
+	}
+}
+
+func TestQemuArgs_NICs(t *testing.T) {
+	bridge := &providerv1.NetworkState{Name: "lan", Kind: KindBridge, InterfaceName: "br0"}
+	cfg := launchConfig{
+		name: "web",
+		dir:  "/state/vms/web",
+		spec: &providerv1.VMSpec{NICs: []providerv1.NICSpec{{MTU: 9000}, {Model: "e1000", MTU: 9000}}},
+		nics: []nic{
+			{network: bridge, mac: "52:54:00:00:00:01"},
+			{network: bridge, mac: "52:54:00:00:00:02"},
+		},
+	}
+	args := strings.Join(qemuArgs(cfg), " ")
+
+	for _, want := range []string{
+		"-device virtio-net-pci,netdev=net0,mac=52:54:00:00:00:01,host_mtu=9000",
+		"-device e1000,netdev=net1,mac=52:54:00:00:00:02 ",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("qemuArgs() = %s\nmissing %q", args, want)
+		}
+	}
+}
+
+func TestUnsupportedFeature(t *testing.T) {
+	tests := []struct {
+		name string
+		spec providerv1.VMSpec
+		want string
+	}{
+		{"supported", providerv1.VMSpec{GuestAgent: true, Boot: providerv1.BootSpec{Firmware: "uefi", OVMFPath: "/ovmf.fd"}}, ""},
+		{"encryption", providerv1.VMSpec{Disk: providerv1.DiskSpec{Encryption: &providerv1.DiskEncryptionSpec{Enabled: true}}}, "disk encryption"},
+		{"scsi", providerv1.VMSpec{Disk: providerv1.DiskSpec{Bus: "scsi"}}, "disk bus scsi"},
+		{"vnc", providerv1.VMSpec{Console: &providerv1.ConsoleSpec{VNC: true}}, "graphical console"},
+		{"uefi", providerv1.VMSpec{Boot: providerv1.BootSpec{Firmware: "uefi"}}, "uefi without ovmfPath"},
+		{"arm", providerv1.VMSpec{Architecture: "aarch64"}, "architecture aarch64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unsupportedFeature(&tt.spec); got != tt.want {
+				t.Errorf("unsupportedFeature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArpLookup(t *testing.T) {
+	arp := []byte(`IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.20     0x1         0x2         52:54:00:aa:bb:cc     *        br0
+192.168.1.21     0x1         0x0         52:54:00:dd:ee:ff     *        br0
+`)
+	if ip := arpLookup(arp, "52:54:00:AA:BB:CC"); ip != "192.168.1.20" {
+		t.Errorf("arpLookup() = %q, want 192.168.1.20", ip)
+	}
+	// Incomplete entries are skipped
+	if ip := arpLookup(arp, "52:54:00:dd:ee:ff"); ip != "" {
+		t.Errorf("arpLookup() of an incomplete entry = %q, want none", ip)
+	}
+}
+
+func TestRandomMAC(t *testing.T) {
+	mac := randomMAC()
+	if !strings.HasPrefix(mac, "52:54:00:") || len(mac) != 17 {
+		t.Errorf("randomMAC() = %q, want a 52:54:00 address", mac)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stub
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// pcapHeader is the global header of an empty pcap file with Ethernet
+// link type, which stub captures write so that callers can open them.
+var pcapHeader = func() []byte {
+	b := make([]byte, 24)
+	binary.LittleEndian.PutUint32(b[0:], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(b[4:], 2)
+	binary.LittleEndian.PutUint16(b[6:], 4)
+	binary.LittleEndian.PutUint32(b[16:], 262144)
+	binary.LittleEndian.PutUint32(b[20:], 1)
+	return b
+}()
+
+// NetworkCaptureStart records a capture of a known network or VM and writes
+// an empty pcap file, so that callers of network_capture_start can be
+// tested without tcpdump.
+func (p *Provider) NetworkCaptureStart(req *providerv1.NetworkCaptureStartRequest) *providerv1.OperationResult {
+	if err := req.Validate(); err != nil {
+		return providerv1.ErrorResult(providerv1.NewInvalidSpecError(err.Error()))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	target, device := req.Network, "virbr-stub"
+	if req.VM != "" {
+		target, device = req.VM, "vnet0"
+		if _, exists := p.vms[req.VM]; !exists {
+			return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.VM))
+		}
+	} else if _, exists := p.networks[req.Network]; !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("network", req.Network))
+	}
+
+	id := fmt.Sprintf("%s-%d", target, len(p.captures)+1)
+	path := filepath.Join(req.OutputDir, id+".pcap")
+	if err := os.MkdirAll(req.OutputDir, 0o755); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	if err := os.WriteFile(path, pcapHeader, 0o644); err != nil {
+		return providerv1.ErrorResult(providerv1.NewProviderError(err.Error(), false))
+	}
+	maxSizeMB, maxDurationSec := req.Limits()
+	state := &providerv1.CaptureState{
+		ID:             id,
+		Network:        req.Network,
+		VM:             req.VM,
+		Interface:      device,
+		Path:           path,
+		Filter:         req.Filter,
+		MaxSizeMB:      maxSizeMB,
+		MaxDurationSec: maxDurationSec,
+		Status:         providerv1.CaptureStatusRunning,
+		StartedAt:      time.Now().UTC().Format(time.RFC3339),
+		SizeBytes:      int64(len(pcapHeader)),
+	}
+	p.captures[id] = state
+	return providerv1.SuccessResult(state)
+}
+
+// NetworkCaptureStop stops a recorded capture.
+func (p *Provider) NetworkCaptureStop(req *providerv1.NetworkCaptureStopRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, exists := p.captures[req.ID]
+	if !exists || state.Status == providerv1.CaptureStatusStopped {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("capture", req.ID))
+	}
+	state.Status = providerv1.CaptureStatusStopped
+	state.StopReason = providerv1.CaptureStopRequested
+	state.StoppedAt = time.Now().UTC().Format(time.RFC3339)
+	return providerv1.SuccessResult(state)
+}
@@ -31,6 +31,7 @@ type Provider struct {
 	keys     map[string]*providerv1.KeyState
 	networks map[string]*providerv1.NetworkState
 	vms      map[string]*providerv1.VMState
+	captures map[string]*providerv1.CaptureState
 	version  string
 }
 
@@ -40,6 +41,7 @@ func NewProvider() *Provider {
 		keys:     make(map[string]*providerv1.KeyState),
 		networks: make(map[string]*providerv1.NetworkState),
 		vms:      make(map[string]*providerv1.VMState),
+		captures: make(map[string]*providerv1.CaptureState),
 	}
 }
 
@@ -63,9 +65,26 @@ func (p *Provider) Capabilities() *providerv1.CapabilitiesResponse {
 		Version:      p.Version(),
 		Resources: []providerv1.ResourceCapability{
 			{Kind: "key", Operations: []string{"create", "get", "list", "delete"}},
-			{Kind: "network", Operations: []string{"create", "get", "list", "delete"}},
-			{Kind: "vm", Operations: []string{"create", "get", "list", "delete"}},
+			{Kind: "network", Operations: []string{"create", "get", "list", "delete", "capture"}},
+			{
+				Kind:       "vm",
+				Operations: []string{"create", "get", "list", "delete", "stats", "start", "stop", "reboot", "pause", "save"},
+				VMFeatures: []string{
+					providerv1.VMFeatureVsock,
+					providerv1.VMFeatureSecurity,
+					providerv1.VMFeatureDiskEncryption,
+					providerv1.VMFeatureNICOptions,
+					providerv1.VMFeatureDataDisks,
+					providerv1.VMFeatureStaticIP,
+					providerv1.VMFeatureRawUserData,
+					providerv1.VMFeatureGuestNetwork,
+					providerv1.VMFeaturePCIPassthrough,
+					providerv1.VMFeatureMdev,
+				},
+			},
 		},
+		Batch:    true,
+		Teardown: true,
 	}
 }
 
@@ -91,6 +110,7 @@ func (p *Provider) KeyCreate(req *providerv1.KeyCreateRequest) *providerv1.Opera
 		PrivateKeyPath: fmt.Sprintf("/tmp/stub-keys/%s", req.Name),
 		Fingerprint:    fmt.Sprintf("SHA256:MOCK%s", req.Name),
 		CreatedAt:      time.Now().UTC().Format(time.RFC3339),
+		Labels:         req.Labels,
 	}
 
 	p.keys[req.Name] = state
@@ -110,14 +130,17 @@ func (p *Provider) KeyGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(key)
 }
 
-// KeyList lists all keys, optionally filtered.
+// KeyList lists all keys carrying the labels of the labels filter.
 func (p *Provider) KeyList(filter map[string]any) *providerv1.OperationResult {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	keys := make([]*providerv1.KeyState, 0, len(p.keys))
 	for _, key := range p.keys {
-		keys = append(keys, key)
+		if providerv1.MatchLabels(selector, key.Labels) {
+			keys = append(keys, key)
+		}
 	}
 
 	return providerv1.SuccessResult(keys)
@@ -158,12 +181,16 @@ func (p *Provider) NetworkCreate(req *providerv1.NetworkCreateRequest) *provider
 		CIDR:          req.Spec.CIDR,
 		InterfaceName: fmt.Sprintf("stub-br-%s", req.Name),
 		UUID:          fmt.Sprintf("stub-net-%s", req.Name),
+		Labels:        req.Labels,
 	}
 
 	// Use CIDR from spec if provided
 	if state.CIDR == "" {
 		state.CIDR = "192.168.100.0/24"
 	}
+	if req.Spec.NTP != nil && req.Spec.NTP.Enabled {
+		state.NTPServer = state.IP
+	}
 
 	p.networks[req.Name] = state
 	return providerv1.SuccessResult(state)
@@ -182,14 +209,17 @@ func (p *Provider) NetworkGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(network)
 }
 
-// NetworkList lists all networks, optionally filtered.
+// NetworkList lists all networks carrying the labels of the labels filter.
 func (p *Provider) NetworkList(filter map[string]any) *providerv1.OperationResult {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	networks := make([]*providerv1.NetworkState, 0, len(p.networks))
 	for _, network := range p.networks {
-		networks = append(networks, network)
+		if providerv1.MatchLabels(selector, network.Labels) {
+			networks = append(networks, network)
+		}
 	}
 
 	return providerv1.SuccessResult(networks)
@@ -225,6 +255,18 @@ func (p *Provider) VMCreate(req *providerv1.VMCreateRequest) *providerv1.Operati
 		UUID:       fmt.Sprintf("stub-vm-%s", req.Name),
 		SSHCommand: "ssh -i /tmp/key user@192.168.100.10",
 		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Labels:     req.Labels,
+	}
+	if req.Spec.IP != "" {
+		state.IP = req.Spec.IP
+		state.SSHCommand = "ssh -i /tmp/key user@" + req.Spec.IP
+	}
+	if req.Spec.Vsock != nil {
+		// Mimic libvirt assigning the first free guest CID
+		state.VsockCID = req.Spec.Vsock.CID
+		if state.VsockCID == 0 {
+			state.VsockCID = uint32(3 + len(p.vms))
+		}
 	}
 
 	p.vms[req.Name] = state
@@ -244,14 +286,60 @@ func (p *Provider) VMGet(name string) *providerv1.OperationResult {
 	return providerv1.SuccessResult(vm)
 }
 
-// VMList lists all VMs, optionally filtered.
+// VMStats returns fixed resource usage for a VM, so that callers of
+// vm_stats can be tested without a hypervisor.
+func (p *Provider) VMStats(req *providerv1.VMStatsRequest) *providerv1.OperationResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	vm, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+
+	return providerv1.SuccessResult(&providerv1.VMStats{
+		Name:       vm.Name,
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+		VCPUs:      1,
+		CPUPercent: 5,
+		MemoryMB:   1024,
+		Disks: []providerv1.DiskStats{{
+			Device: "vda", AllocationBytes: 1 << 30, CapacityBytes: 10 << 30, PhysicalBytes: 2 << 30,
+		}},
+		Interfaces: []providerv1.InterfaceStats{{Device: "vnet0", MAC: vm.MAC}},
+	})
+}
+
+// VMPower applies a power action to a VM by changing its status, so that
+// power-cycle scenarios can be tested without a hypervisor.
+func (p *Provider) VMPower(action string, req *providerv1.VMPowerRequest) *providerv1.OperationResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	vm, exists := p.vms[req.Name]
+	if !exists {
+		return providerv1.ErrorResult(providerv1.NewNotFoundError("vm", req.Name))
+	}
+	status, opErr := providerv1.NextPowerStatus(vm.Status, action)
+	if opErr != nil {
+		return providerv1.ErrorResult(opErr)
+	}
+
+	vm.Status = status
+	return providerv1.SuccessResult(vm)
+}
+
+// VMList lists all VMs carrying the labels of the labels filter.
 func (p *Provider) VMList(filter map[string]any) *providerv1.OperationResult {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
+	selector := providerv1.LabelSelector(filter)
 	vms := make([]*providerv1.VMState, 0, len(p.vms))
 	for _, vm := range p.vms {
-		vms = append(vms, vm)
+		if providerv1.MatchLabels(selector, vm.Labels) {
+			vms = append(vms, vm)
+		}
 	}
 
 	return providerv1.SuccessResult(vms)
@@ -19,6 +19,8 @@
 package stub
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -70,8 +72,8 @@ func TestCapabilities(t *testing.T) {
 	// Verify each resource capability
 	expectedResources := map[string][]string{
 		"key":     {"create", "get", "list", "delete"},
-		"network": {"create", "get", "list", "delete"},
-		"vm":      {"create", "get", "list", "delete"},
+		"network": {"create", "get", "list", "delete", "capture"},
+		"vm":      {"create", "get", "list", "delete", "stats", "start", "stop", "reboot", "pause", "save"},
 	}
 
 	for _, rc := range caps.Resources {
@@ -406,6 +408,22 @@ func TestNetworkCreate_DefaultCIDR(t *testing.T) {
 	}
 }
 
+func TestNetworkCreate_NTP(t *testing.T) {
+	p := NewProvider()
+	result := p.NetworkCreate(&providerv1.NetworkCreateRequest{
+		Name: "airgap",
+		Kind: "isolated",
+		Spec: providerv1.NetworkSpec{NTP: &providerv1.NTPSpec{Enabled: true}},
+	})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	netState := result.Resource.(*providerv1.NetworkState)
+	if netState.NTPServer != netState.IP {
+		t.Errorf("expected NTP server %q, got %q", netState.IP, netState.NTPServer)
+	}
+}
+
 func TestNetworkCreate_AlreadyExists(t *testing.T) {
 	p := NewProvider()
 	req := &providerv1.NetworkCreateRequest{
@@ -611,6 +629,43 @@ func TestVMCreate_Success(t *testing.T) {
 	}
 }
 
+func TestVMCreate_Vsock(t *testing.T) {
+	p := NewProvider()
+
+	result := p.VMCreate(&providerv1.VMCreateRequest{Name: "fixed", Spec: providerv1.VMSpec{Vsock: &providerv1.VsockSpec{CID: 42}}})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if cid := result.Resource.(*providerv1.VMState).VsockCID; cid != 42 {
+		t.Errorf("expected vsock CID 42, got %d", cid)
+	}
+
+	result = p.VMCreate(&providerv1.VMCreateRequest{Name: "auto", Spec: providerv1.VMSpec{Vsock: &providerv1.VsockSpec{}}})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if cid := result.Resource.(*providerv1.VMState).VsockCID; cid < 3 {
+		t.Errorf("expected an assigned guest CID, got %d", cid)
+	}
+
+	result = p.VMCreate(&providerv1.VMCreateRequest{Name: "none"})
+	if cid := result.Resource.(*providerv1.VMState).VsockCID; cid != 0 {
+		t.Errorf("expected no vsock CID, got %d", cid)
+	}
+}
+
+func TestVMCreate_StaticIP(t *testing.T) {
+	p := NewProvider()
+
+	result := p.VMCreate(&providerv1.VMCreateRequest{Name: "web", Spec: providerv1.VMSpec{IP: "10.0.0.10"}})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if ip := result.Resource.(*providerv1.VMState).IP; ip != "10.0.0.10" {
+		t.Errorf("expected the static IP 10.0.0.10, got %q", ip)
+	}
+}
+
 func TestVMCreate_AlreadyExists(t *testing.T) {
 	p := NewProvider()
 	req := &providerv1.VMCreateRequest{
@@ -679,6 +734,64 @@ func TestVMGet_NotFound(t *testing.T) {
 	}
 }
 
+func TestVMStats(t *testing.T) {
+	p := NewProvider()
+	p.VMCreate(&providerv1.VMCreateRequest{Name: "test-vm"})
+
+	result := p.VMStats(&providerv1.VMStatsRequest{Name: "test-vm"})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	stats, ok := result.Resource.(*providerv1.VMStats)
+	if !ok {
+		t.Fatalf("expected *VMStats, got %T", result.Resource)
+	}
+	if stats.Name != "test-vm" || stats.VCPUs == 0 || len(stats.Interfaces) != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	result = p.VMStats(&providerv1.VMStatsRequest{Name: "nonexistent-vm"})
+	if result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("expected NOT_FOUND for nonexistent VM, got %+v", result)
+	}
+}
+
+func TestVMPower(t *testing.T) {
+	p := NewProvider()
+	p.VMCreate(&providerv1.VMCreateRequest{Name: "test-vm"})
+	req := &providerv1.VMPowerRequest{Name: "test-vm"}
+
+	steps := []struct {
+		action string
+		want   string
+	}{
+		{providerv1.PowerPause, providerv1.VMStatusPaused},
+		{providerv1.PowerStart, providerv1.VMStatusRunning},
+		{providerv1.PowerReboot, providerv1.VMStatusRunning},
+		{providerv1.PowerStop, providerv1.VMStatusStopped},
+		{providerv1.PowerStart, providerv1.VMStatusRunning},
+		{providerv1.PowerSave, providerv1.VMStatusSaved},
+		{providerv1.PowerStart, providerv1.VMStatusRunning},
+	}
+	for _, step := range steps {
+		result := p.VMPower(step.action, req)
+		if !result.Success {
+			t.Fatalf("VMPower(%s) error: %v", step.action, result.Error)
+		}
+		if vm := result.Resource.(*providerv1.VMState); vm.Status != step.want {
+			t.Errorf("VMPower(%s) status = %q, want %q", step.action, vm.Status, step.want)
+		}
+	}
+
+	p.VMPower(providerv1.PowerStop, req)
+	if result := p.VMPower(providerv1.PowerReboot, req); result.Success || result.Error.Code != providerv1.ErrCodeInvalidSpec {
+		t.Errorf("rebooting a stopped VM should fail with INVALID_SPEC, got %+v", result)
+	}
+	if result := p.VMPower(providerv1.PowerStart, &providerv1.VMPowerRequest{Name: "nonexistent-vm"}); result.Success || result.Error.Code != providerv1.ErrCodeNotFound {
+		t.Errorf("expected NOT_FOUND for nonexistent VM, got %+v", result)
+	}
+}
+
 func TestVMList_Empty(t *testing.T) {
 	p := NewProvider()
 
@@ -723,6 +836,34 @@ func TestVMList_WithVMs(t *testing.T) {
 	}
 }
 
+func TestVMList_Labels(t *testing.T) {
+	p := NewProvider()
+
+	for _, name := range []string{"vm1", "vm2"} {
+		p.VMCreate(&providerv1.VMCreateRequest{
+			Name:   name,
+			Spec:   providerv1.VMSpec{},
+			Labels: map[string]string{providerv1.LabelResource: "vm/" + name, "team": "storage"},
+		})
+	}
+
+	result := p.VMList(map[string]any{providerv1.FilterLabels: map[string]any{providerv1.LabelResource: "vm/vm2"}})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	vms := result.Resource.([]*providerv1.VMState)
+	if len(vms) != 1 || vms[0].Name != "vm2" {
+		t.Errorf("expected vm2, got %v", vms)
+	}
+	if vms[0].Labels["team"] != "storage" {
+		t.Errorf("expected the labels of the request, got %v", vms[0].Labels)
+	}
+
+	result = p.VMList(map[string]any{providerv1.FilterLabels: map[string]any{"team": "network"}})
+	if vms := result.Resource.([]*providerv1.VMState); len(vms) != 0 {
+		t.Errorf("expected no VMs, got %d", len(vms))
+	}
+}
 func TestVMDelete_Success(t *testing.T) {
 	p := NewProvider()
 
@@ -1139,3 +1280,40 @@ func TestVMOperations_TableDriven(t *testing.T) {
 		})
 	}
 }
+
+// ----------------------------------------------------------------------------
+// Capture tests
+// ----------------------------------------------------------------------------
+
+func TestNetworkCapture(t *testing.T) {
+	p := NewProvider()
+	p.NetworkCreate(&providerv1.NetworkCreateRequest{Name: "net", Kind: "bridge"})
+	dir := t.TempDir()
+
+	result := p.NetworkCaptureStart(&providerv1.NetworkCaptureStartRequest{Network: "net", OutputDir: dir, MaxSizeMB: 5})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	started := *result.Resource.(*providerv1.CaptureState)
+	if started.Status != providerv1.CaptureStatusRunning || started.MaxSizeMB != 5 || started.MaxDurationSec != providerv1.DefaultCaptureMaxDurationSec {
+		t.Errorf("unexpected capture state: %+v", started)
+	}
+	if data, err := os.ReadFile(started.Path); err != nil || len(data) != 24 || filepath.Dir(started.Path) != dir {
+		t.Errorf("expected an empty pcap file in %s, got %d bytes, %v", dir, len(data), err)
+	}
+
+	result = p.NetworkCaptureStop(&providerv1.NetworkCaptureStopRequest{ID: started.ID})
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+	if stopped := result.Resource.(*providerv1.CaptureState); stopped.Status != providerv1.CaptureStatusStopped || stopped.StopReason != providerv1.CaptureStopRequested {
+		t.Errorf("unexpected capture state: %+v", stopped)
+	}
+	if result := p.NetworkCaptureStop(&providerv1.NetworkCaptureStopRequest{ID: started.ID}); result.Success {
+		t.Error("expected error stopping a stopped capture")
+	}
+
+	if result := p.NetworkCaptureStart(&providerv1.NetworkCaptureStartRequest{VM: "missing", OutputDir: dir}); result.Success {
+		t.Error("expected error capturing an unknown VM")
+	}
+}
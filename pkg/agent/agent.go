@@ -0,0 +1,96 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package agent implements the testenv-vm guest agent and its host-side
+// client. The agent is a static binary injected into VMs through cloud-init
+// (see the agent field of VMSpec). It serves an HTTP API, authenticated by a
+// per-VM bearer token, that runs commands, reads and writes files and reports
+// resource usage, so that VMs of minimal images without an SSH server can be
+// driven like the others.
+package agent
+
+import "fmt"
+
+// Well-known locations of the agent in the guest.
+const (
+	// DefaultPort is the TCP port the agent listens on by default.
+	DefaultPort = 10050
+	// BinaryPath is where cloud-init installs the agent binary.
+	BinaryPath = "/usr/local/bin/testenv-vm-agent"
+	// TokenPath is where cloud-init writes the token clients must present.
+	TokenPath = "/etc/testenv-vm-agent/token"
+	// UnitPath is the systemd unit running the agent.
+	UnitPath = "/etc/systemd/system/testenv-vm-agent.service"
+	// LogPath receives the agent output on guests without systemd.
+	LogPath = "/var/log/testenv-vm-agent.log"
+)
+
+// Paths of the agent API.
+const (
+	healthPath  = "/v1/health"
+	execPath    = "/v1/exec"
+	filePath    = "/v1/file"
+	metricsPath = "/v1/metrics"
+)
+
+// ExecRequest is the body of POST /v1/exec.
+type ExecRequest struct {
+	// Command is run with "sh -c".
+	Command string `json:"command"`
+	// Stdin is written to the standard input of the command.
+	Stdin string `json:"stdin,omitempty"`
+}
+
+// ExecResponse is the result of a command run by the agent. A command that
+// ran returns a response whatever its exit code.
+type ExecResponse struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// Args returns the command-line arguments of the agent listening on the TCP
+// port, and on the same vsock port when vsock is set.
+func Args(port int, vsock bool) string {
+	args := fmt.Sprintf("--listen :%d --token-file %s", port, TokenPath)
+	if vsock {
+		args += fmt.Sprintf(" --vsock-port %d", port)
+	}
+	return args
+}
+
+// Unit returns the systemd unit running the agent with Args(port, vsock).
+func Unit(port int, vsock bool) string {
+	return fmt.Sprintf(`[Unit]
+Description=testenv-vm guest agent
+After=network.target
+
+[Service]
+ExecStart=%s %s
+Restart=always
+RestartSec=1
+
+[Install]
+WantedBy=multi-user.target
+`, BinaryPath, Args(port, vsock))
+}
+
+// StartCommand returns the cloud-init command starting the agent with
+// Args(port, vsock): through its systemd unit when systemd runs, in the
+// background otherwise.
+func StartCommand(port int, vsock bool) string {
+	return fmt.Sprintf("if [ -d /run/systemd/system ]; then systemctl daemon-reload && systemctl enable --now %s; "+
+		"else nohup %s %s >>%s 2>&1 & fi",
+		"testenv-vm-agent.service", BinaryPath, Args(port, vsock), LogPath)
+}
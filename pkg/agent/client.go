@@ -0,0 +1,191 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// maxErrorBytes bounds the error messages read from the agent.
+const maxErrorBytes = 4096
+
+// Client calls the agent of a VM.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the HTTP client used to reach the agent, e.g. with a
+// custom dialer. Timeouts are better set on the contexts of calls.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient returns a client of the agent listening on address (host:port)
+// and accepting token.
+func NewClient(address, token string, opts ...ClientOption) *Client {
+	c := &Client{
+		baseURL:    "http://" + address,
+		token:      token,
+		httpClient: &http.Client{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Address returns the host:port of an agent from the host and port
+// recorded for a VM.
+func Address(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// Ping checks that the agent is up and accepts the token.
+func (c *Client) Ping(ctx context.Context) error {
+	resp, err := c.do(ctx, http.MethodGet, healthPath, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Exec runs a command with "sh -c" in the guest. A command that ran is not
+// an error, whatever its exit code.
+func (c *Client) Exec(ctx context.Context, command string) (*ExecResponse, error) {
+	body, err := json.Marshal(ExecRequest{Command: command})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, execPath, nil, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result ExecResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("agent: invalid exec response: %w", err)
+	}
+	return &result, nil
+}
+
+// ReadFile returns the content of a file of the guest. The error wraps
+// fs.ErrNotExist if the file does not exist.
+func (c *Client) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	resp, err := c.do(ctx, http.MethodGet, filePath, url.Values{"path": {path}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("agent: failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// WriteFile writes content to a file of the guest, creating its parent
+// directories. A zero mode keeps the permissions of an existing file.
+func (c *Client) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	return c.writeFile(ctx, path, content, mode, false)
+}
+
+// AppendFile appends content to a file of the guest, creating it if needed.
+func (c *Client) AppendFile(ctx context.Context, path string, content []byte) error {
+	return c.writeFile(ctx, path, content, 0, true)
+}
+
+// writeFile implements WriteFile and AppendFile.
+func (c *Client) writeFile(ctx context.Context, path string, content []byte, mode os.FileMode, appendOnly bool) error {
+	query := url.Values{"path": {path}}
+	if mode != 0 {
+		query.Set("mode", strconv.FormatUint(uint64(mode.Perm()), 8))
+	}
+	if appendOnly {
+		query.Set("append", "true")
+	}
+	resp, err := c.do(ctx, http.MethodPut, filePath, query, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Metrics returns the resource usage of the guest, with the CPU usage
+// sampled over interval (the vm_stats default if zero). Name is left empty.
+func (c *Client) Metrics(ctx context.Context, interval time.Duration) (*providerv1.VMStats, error) {
+	var query url.Values
+	if interval > 0 {
+		query = url.Values{"intervalMs": {strconv.FormatInt(interval.Milliseconds(), 10)}}
+	}
+	resp, err := c.do(ctx, http.MethodGet, metricsPath, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var stats providerv1.VMStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("agent: invalid metrics response: %w", err)
+	}
+	return &stats, nil
+}
+
+// do sends an authenticated request. Responses other than 2xx are returned
+// as errors carrying the agent message.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("agent: %w", err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer func() { _ = resp.Body.Close() }()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+	err = fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode == http.StatusNotFound && path == filePath {
+		err = fmt.Errorf("%w: %v", fs.ErrNotExist, err)
+	}
+	return nil, fmt.Errorf("agent: %w", err)
+}
@@ -0,0 +1,241 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// Units of the proc filesystem.
+const (
+	// nsPerTick converts USER_HZ clock ticks, 100 per second on Linux.
+	nsPerTick = uint64(time.Second / 100)
+	// sectorBytes is the unit of sector counters in /proc/diskstats.
+	sectorBytes = 512
+)
+
+// cpuSample is a reading of the aggregate line of /proc/stat.
+type cpuSample struct {
+	busy, total uint64
+	cpus        int
+}
+
+// collectMetrics reads the resource usage of the guest from procDir, with
+// the CPU usage computed from two samples taken interval apart. The result
+// mirrors what providers report from the host, so that it can stand in for
+// vm_stats: disk and interface counters are the guest's.
+func collectMetrics(ctx context.Context, procDir string, interval time.Duration) (*providerv1.VMStats, error) {
+	first, err := readCPU(procDir)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(interval):
+	}
+	second, err := readCPU(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &providerv1.VMStats{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		VCPUs:     second.cpus,
+		CPUTimeNs: second.busy * nsPerTick,
+	}
+	if second.total > first.total {
+		stats.CPUPercent = float64(second.busy-first.busy) / float64(second.total-first.total) * 100
+	}
+	if stats.MemoryMB, stats.MemoryUsedMB, err = readMemory(procDir); err != nil {
+		return nil, err
+	}
+	if stats.Disks, err = readDiskStats(procDir); err != nil {
+		return nil, err
+	}
+	if stats.Interfaces, err = readNetDev(procDir); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// readCPU reads the CPU time counters of /proc/stat. Busy time excludes
+// idle and iowait; guest time is already part of user time.
+func readCPU(procDir string) (cpuSample, error) {
+	var sample cpuSample
+	err := scanLines(filepath.Join(procDir, "stat"), func(fields []string) error {
+		switch {
+		case fields[0] == "cpu":
+			for i, f := range fields[1:min(len(fields), 9)] {
+				v, err := strconv.ParseUint(f, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid cpu counter %q", f)
+				}
+				sample.total += v
+				if i != 3 && i != 4 {
+					sample.busy += v
+				}
+			}
+		case strings.HasPrefix(fields[0], "cpu"):
+			sample.cpus++
+		}
+		return nil
+	})
+	return sample, err
+}
+
+// readMemory returns the total and used memory of /proc/meminfo in MB. Used
+// memory is what is not available to new allocations.
+func readMemory(procDir string) (totalMB, usedMB int, err error) {
+	values := make(map[string]int)
+	err = scanLines(filepath.Join(procDir, "meminfo"), func(fields []string) error {
+		if len(fields) >= 2 {
+			if v, err := strconv.Atoi(fields[1]); err == nil {
+				values[strings.TrimSuffix(fields[0], ":")] = v
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	total, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, fmt.Errorf("MemTotal missing from meminfo")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return total / 1024, (total - available) / 1024, nil
+}
+
+// ignoredDisks are the prefixes of block devices that are not VM disks.
+var ignoredDisks = []string{"loop", "ram", "zram", "sr", "fd", "dm-", "md"}
+
+// readDiskStats reads the I/O counters of the disks of /proc/diskstats.
+// Partitions are skipped: they follow their disk and share its name as a
+// prefix.
+func readDiskStats(procDir string) ([]providerv1.DiskStats, error) {
+	var disks []providerv1.DiskStats
+	err := scanLines(filepath.Join(procDir, "diskstats"), func(fields []string) error {
+		if len(fields) < 10 {
+			return nil
+		}
+		name := fields[2]
+		for _, prefix := range ignoredDisks {
+			if strings.HasPrefix(name, prefix) {
+				return nil
+			}
+		}
+		for _, d := range disks {
+			if strings.HasPrefix(name, d.Device) {
+				return nil
+			}
+		}
+		counters, err := parseCounters(fields[3:10])
+		if err != nil {
+			return err
+		}
+		disks = append(disks, providerv1.DiskStats{
+			Device:        name,
+			ReadRequests:  counters[0],
+			ReadBytes:     counters[2] * sectorBytes,
+			WriteRequests: counters[4],
+			WriteBytes:    counters[6] * sectorBytes,
+		})
+		return nil
+	})
+	return disks, err
+}
+
+// readNetDev reads the counters of the network interfaces of /proc/net/dev,
+// except loopback.
+func readNetDev(procDir string) ([]providerv1.InterfaceStats, error) {
+	var interfaces []providerv1.InterfaceStats
+	err := scanLines(filepath.Join(procDir, "net", "dev"), func(fields []string) error {
+		name, ok := strings.CutSuffix(fields[0], ":")
+		if !ok {
+			// Header lines, or "eth0:123" when the counter is wide
+			var first string
+			if name, first, ok = strings.Cut(fields[0], ":"); !ok || first == "" {
+				return nil
+			}
+			fields = append([]string{name + ":", first}, fields[1:]...)
+		}
+		if name == "lo" || len(fields) < 17 {
+			return nil
+		}
+		counters, err := parseCounters(fields[1:17])
+		if err != nil {
+			return err
+		}
+		interfaces = append(interfaces, providerv1.InterfaceStats{
+			Device:    name,
+			RxBytes:   counters[0],
+			RxPackets: counters[1],
+			RxErrors:  counters[2],
+			RxDrops:   counters[3],
+			TxBytes:   counters[8],
+			TxPackets: counters[9],
+			TxErrors:  counters[10],
+			TxDrops:   counters[11],
+		})
+		return nil
+	})
+	return interfaces, err
+}
+
+// parseCounters parses decimal counters.
+func parseCounters(fields []string) ([]int64, error) {
+	counters := make([]int64, len(fields))
+	for i, f := range fields {
+		v, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter %q", f)
+		}
+		counters[i] = v
+	}
+	return counters, nil
+}
+
+// scanLines calls fn with the fields of every non-empty line of a file.
+func scanLines(path string, fn func(fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,148 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+const (
+	testStat = `cpu  100 0 50 800 50 0 0 0 0 0
+cpu0 50 0 25 400 25 0 0 0 0 0
+cpu1 50 0 25 400 25 0 0 0 0 0
+intr 12345
+ctxt 678
+`
+	testMeminfo = `MemTotal:        2048000 kB
+MemFree:          100000 kB
+MemAvailable:    1536000 kB
+Buffers:           10000 kB
+`
+	testDiskstats = `   7       0 loop0 10 0 20 0 0 0 0 0 0 0 0
+ 252       0 vda 100 5 2000 30 50 10 800 40 0 60 70
+ 252       1 vda1 90 5 1800 25 50 10 800 40 0 55 65
+  11       0 sr0 3 0 24 1 0 0 0 0 0 1 1
+`
+	testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0: 5000 50 1 2 0 0 0 0 3000 30 3 4 0 0 0 0
+`
+)
+
+// writeProc writes a fake proc filesystem.
+func writeProc(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"stat":      testStat,
+		"meminfo":   testMeminfo,
+		"diskstats": testDiskstats,
+		"net/dev":   testNetDev,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestReadCPU(t *testing.T) {
+	sample, err := readCPU(writeProc(t))
+	if err != nil {
+		t.Fatalf("readCPU() error = %v", err)
+	}
+	if sample.busy != 150 || sample.total != 1000 || sample.cpus != 2 {
+		t.Errorf("readCPU() = %+v, want busy 150, total 1000, 2 cpus", sample)
+	}
+}
+
+func TestReadMemory(t *testing.T) {
+	total, used, err := readMemory(writeProc(t))
+	if err != nil {
+		t.Fatalf("readMemory() error = %v", err)
+	}
+	if total != 2000 || used != 500 {
+		t.Errorf("readMemory() = %d, %d, want 2000, 500", total, used)
+	}
+}
+
+func TestReadDiskStats(t *testing.T) {
+	disks, err := readDiskStats(writeProc(t))
+	if err != nil {
+		t.Fatalf("readDiskStats() error = %v", err)
+	}
+	want := []providerv1.DiskStats{{
+		Device: "vda", ReadRequests: 100, ReadBytes: 2000 * 512, WriteRequests: 50, WriteBytes: 800 * 512,
+	}}
+	if len(disks) != 1 || disks[0] != want[0] {
+		t.Errorf("readDiskStats() = %+v, want %+v", disks, want)
+	}
+}
+
+func TestReadNetDev(t *testing.T) {
+	interfaces, err := readNetDev(writeProc(t))
+	if err != nil {
+		t.Fatalf("readNetDev() error = %v", err)
+	}
+	want := providerv1.InterfaceStats{
+		Device: "eth0", RxBytes: 5000, RxPackets: 50, RxErrors: 1, RxDrops: 2,
+		TxBytes: 3000, TxPackets: 30, TxErrors: 3, TxDrops: 4,
+	}
+	if len(interfaces) != 1 || interfaces[0] != want {
+		t.Errorf("readNetDev() = %+v, want %+v", interfaces, want)
+	}
+}
+
+func TestReadNetDev_WideCounter(t *testing.T) {
+	dir := writeProc(t)
+	line := "eth1:4294967296 1 0 0 0 0 0 0 7 1 0 0 0 0 0 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "net", "dev"), []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	interfaces, err := readNetDev(dir)
+	if err != nil {
+		t.Fatalf("readNetDev() error = %v", err)
+	}
+	if len(interfaces) != 1 || interfaces[0].Device != "eth1" || interfaces[0].RxBytes != 4294967296 || interfaces[0].TxBytes != 7 {
+		t.Errorf("readNetDev() = %+v", interfaces)
+	}
+}
+
+func TestCollectMetrics_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := collectMetrics(ctx, writeProc(t), time.Second); err == nil {
+		t.Error("collectMetrics() expected error for a canceled context")
+	}
+}
+
+func TestCollectMetrics_MissingProc(t *testing.T) {
+	if _, err := collectMetrics(context.Background(), t.TempDir(), time.Millisecond); err == nil {
+		t.Error("collectMetrics() expected error without proc files")
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// maxExecRequestBytes bounds the body of exec requests.
+const maxExecRequestBytes = 16 << 20
+
+// execWaitDelay bounds the wait for the output of a killed command.
+const execWaitDelay = 5 * time.Second
+
+// handler serves the agent API.
+type handler struct {
+	token string
+	// procDir is the proc filesystem metrics are read from.
+	procDir string
+}
+
+// NewHandler returns the handler of the agent API. Every request must carry
+// token as a bearer token.
+func NewHandler(token string) http.Handler {
+	return newHandler(token, "/proc")
+}
+
+// newHandler returns the handler of the agent API reading metrics from
+// procDir.
+func newHandler(token, procDir string) http.Handler {
+	h := &handler{token: token, procDir: procDir}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+healthPath, func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST "+execPath, h.exec)
+	mux.HandleFunc("GET "+filePath, h.readFile)
+	mux.HandleFunc("PUT "+filePath, h.writeFile)
+	mux.HandleFunc("GET "+metricsPath, h.metrics)
+	return h.authenticate(mux)
+}
+
+// authenticate rejects requests without the agent token.
+func (h *handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exec runs a command with "sh -c". The command is killed when the client
+// goes away.
+func (h *handler) exec(w http.ResponseWriter, r *http.Request) {
+	var req ExecRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxExecRequestBytes)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Command == "" {
+		http.Error(w, "command is required", http.StatusBadRequest)
+		return
+	}
+
+	cmd := exec.CommandContext(r.Context(), "sh", "-c", req.Command)
+	// Kill the whole process group: children of the shell would otherwise
+	// keep the output pipes, and the request, open.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = execWaitDelay
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(req.Stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	resp := ExecResponse{}
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			http.Error(w, fmt.Sprintf("failed to run command: %v", err), http.StatusInternalServerError)
+			return
+		}
+		resp.ExitCode = exitErr.ExitCode()
+	}
+	resp.Stdout = stdout.String()
+	resp.Stderr = stderr.String()
+	writeJSON(w, resp)
+}
+
+// readFile returns the content of the file in the path parameter.
+func (h *handler) readFile(w http.ResponseWriter, r *http.Request) {
+	path, ok := fileParam(w, r)
+	if !ok {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+	defer func() { _ = f.Close() }()
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		http.Error(w, fmt.Sprintf("%s is a directory", path), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = io.Copy(w, f)
+}
+
+// writeFile writes the body to the file in the path parameter, creating
+// parent directories. With append=true the body is appended. A mode
+// parameter (octal) sets the permissions; otherwise existing permissions
+// are kept and new files get 0644.
+func (h *handler) writeFile(w http.ResponseWriter, r *http.Request) {
+	path, ok := fileParam(w, r)
+	if !ok {
+		return
+	}
+	var mode os.FileMode
+	if s := r.URL.Query().Get("mode"); s != "" {
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil || m > 0o7777 {
+			http.Error(w, fmt.Sprintf("invalid mode %q", s), http.StatusBadRequest)
+			return
+		}
+		mode = os.FileMode(m)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if r.URL.Query().Get("append") == "true" {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		writeFileError(w, err)
+		return
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+	_, err = io.Copy(f, r.Body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && mode != 0 {
+		err = os.Chmod(path, mode)
+	}
+	if err != nil {
+		writeFileError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// metrics reports the resource usage of the guest, sampling CPU usage over
+// the intervalMs parameter.
+func (h *handler) metrics(w http.ResponseWriter, r *http.Request) {
+	req := providerv1.VMStatsRequest{}
+	if s := r.URL.Query().Get("intervalMs"); s != "" {
+		ms, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid intervalMs %q", s), http.StatusBadRequest)
+			return
+		}
+		req.IntervalMs = ms
+	}
+	interval := time.Duration(req.StatsInterval()) * time.Millisecond
+
+	stats, err := collectMetrics(r.Context(), h.procDir, interval)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, stats)
+}
+
+// fileParam returns the absolute path parameter of a file request.
+func fileParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	path := r.URL.Query().Get("path")
+	if !filepath.IsAbs(path) {
+		http.Error(w, fmt.Sprintf("path must be absolute (got %q)", path), http.StatusBadRequest)
+		return "", false
+	}
+	return filepath.Clean(path), true
+}
+
+// writeFileError maps a file system error to an HTTP status.
+func writeFileError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
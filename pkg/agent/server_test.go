@@ -0,0 +1,156 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "secret-token"
+
+// newTestClient serves the agent API with metrics read from procDir and
+// returns a client of it.
+func newTestClient(t *testing.T, procDir, token string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(newHandler(testToken, procDir))
+	t.Cleanup(srv.Close)
+	return NewClient(strings.TrimPrefix(srv.URL, "http://"), token)
+}
+
+func TestHandler_RejectsInvalidToken(t *testing.T) {
+	for _, token := range []string{"", "wrong"} {
+		c := newTestClient(t, "/proc", token)
+		err := c.Ping(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Errorf("Ping() with token %q error = %v, want 401", token, err)
+		}
+	}
+	if err := newTestClient(t, "/proc", testToken).Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestHandler_RejectsEmptyServerToken(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(""))
+	defer srv.Close()
+	c := NewClient(strings.TrimPrefix(srv.URL, "http://"), "")
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("Ping() expected error when the agent has no token")
+	}
+}
+
+func TestClient_Exec(t *testing.T) {
+	c := newTestClient(t, "/proc", testToken)
+	ctx := context.Background()
+
+	resp, err := c.Exec(ctx, "echo out; echo err >&2; exit 3")
+	if err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if resp.Stdout != "out\n" || resp.Stderr != "err\n" || resp.ExitCode != 3 {
+		t.Errorf("Exec() = %+v", resp)
+	}
+
+	if _, err := c.Exec(ctx, ""); err == nil || !strings.Contains(err.Error(), "command is required") {
+		t.Errorf("Exec(\"\") error = %v, want command is required", err)
+	}
+}
+
+func TestClient_ExecCanceled(t *testing.T) {
+	c := newTestClient(t, "/proc", testToken)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.Exec(ctx, "sleep 10"); err == nil {
+		t.Error("Exec() expected error on timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Exec() returned after %s, want the command killed", elapsed)
+	}
+}
+
+func TestClient_Files(t *testing.T) {
+	c := newTestClient(t, "/proc", testToken)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sub", "file.txt")
+
+	if _, err := c.ReadFile(ctx, path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("ReadFile() of a missing file error = %v, want fs.ErrNotExist", err)
+	}
+	if err := c.WriteFile(ctx, path, []byte("hello\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := c.AppendFile(ctx, path, []byte("world\n")); err != nil {
+		t.Fatalf("AppendFile() error = %v", err)
+	}
+	data, err := c.ReadFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "hello\nworld\n" {
+		t.Errorf("ReadFile() = %q", data)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %o, want 600", info.Mode().Perm())
+	}
+
+	// A zero mode keeps the permissions
+	if err := c.WriteFile(ctx, path, []byte("replaced"), 0); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %o after rewrite, want 600", info.Mode().Perm())
+	}
+
+	if err := c.WriteFile(ctx, "relative.txt", nil, 0); err == nil || !strings.Contains(err.Error(), "must be absolute") {
+		t.Errorf("WriteFile() of a relative path error = %v", err)
+	}
+	if _, err := c.ReadFile(ctx, filepath.Dir(path)); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("ReadFile() of a directory error = %v", err)
+	}
+}
+
+func TestClient_Metrics(t *testing.T) {
+	procDir := writeProc(t)
+	c := newTestClient(t, procDir, testToken)
+
+	stats, err := c.Metrics(context.Background(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Metrics() error = %v", err)
+	}
+	if stats.VCPUs != 2 || stats.MemoryMB != 2000 || stats.MemoryUsedMB != 500 {
+		t.Errorf("Metrics() = %+v", stats)
+	}
+	if len(stats.Disks) != 1 || len(stats.Interfaces) != 1 {
+		t.Errorf("Metrics() disks = %+v, interfaces = %+v", stats.Disks, stats.Interfaces)
+	}
+	if stats.Timestamp == "" {
+		t.Error("Metrics() timestamp is empty")
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package catalog serves named, versioned spec templates from a directory
+// or a git repository:
+//
+//	<root>/<name>/<version>/template.yaml   description and parameters
+//	<root>/<name>/<version>/spec.yaml       spec with [[ ]] parameter actions
+//
+// Template specs are Go templates with "[[" and "]]" delimiters, so that the
+// "{{ }}" references resolved at creation time are kept as they are.
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/gitsource"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// File names of a template version.
+const (
+	TemplateFile = "template.yaml"
+	SpecFile     = "spec.yaml"
+)
+
+// Parameter types.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeBoolean = "boolean"
+)
+
+// namePattern restricts template names and versions, which are directory
+// names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// Template describes one version of a template.
+type Template struct {
+	// Name is the template name, e.g. "k8s-ha".
+	Name string `json:"name" yaml:"-"`
+	// Version is the template version, e.g. "1.2.0".
+	Version string `json:"version" yaml:"-"`
+	// Description explains the topology.
+	Description string `json:"description,omitempty" yaml:"description"`
+	// Tags help finding templates, e.g. "kubernetes".
+	Tags []string `json:"tags,omitempty" yaml:"tags"`
+	// Parameters are the values the spec template accepts.
+	Parameters []Parameter `json:"parameters,omitempty" yaml:"parameters"`
+}
+
+// Parameter is a template parameter.
+type Parameter struct {
+	// Name is referenced in the spec template as [[ .name ]].
+	Name string `json:"name" yaml:"name"`
+	// Type is string (default), integer or boolean.
+	Type string `json:"type,omitempty" yaml:"type"`
+	// Description explains the parameter.
+	Description string `json:"description,omitempty" yaml:"description"`
+	// Default is used when the parameter is not given.
+	Default any `json:"default,omitempty" yaml:"default"`
+	// Required parameters have no default and must be given.
+	Required bool `json:"required,omitempty" yaml:"required"`
+	// Enum lists the allowed values, if restricted.
+	Enum []any `json:"enum,omitempty" yaml:"enum"`
+}
+
+// Catalog is a directory of templates.
+type Catalog struct {
+	// Dir is the root directory of the catalog.
+	Dir string
+	// Commit is the resolved commit of a git catalog.
+	Commit string
+}
+
+// Open opens the catalog at source: a local directory or a git source (see
+// package gitsource), fetched into cacheDir.
+func Open(ctx context.Context, source, cacheDir string) (*Catalog, error) {
+	if source == "" {
+		return nil, errors.New("no catalog configured")
+	}
+	if !gitsource.IsSource(source) {
+		return &Catalog{Dir: source}, nil
+	}
+	src, err := gitsource.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	checkout, err := gitsource.Fetch(ctx, src, cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog: %w", err)
+	}
+	return &Catalog{Dir: checkout.File(src), Commit: checkout.Commit}, nil
+}
+
+// List returns every version of every template, sorted by name and by
+// descending version.
+func (c *Catalog) List() ([]*Template, error) {
+	names, err := subdirs(c.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	var templates []*Template
+	for _, name := range names {
+		versions, err := c.versions(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versions {
+			t, err := c.load(name, version)
+			if err != nil {
+				return nil, err
+			}
+			templates = append(templates, t)
+		}
+	}
+	return templates, nil
+}
+
+// Get returns a template version; an empty version selects the latest.
+func (c *Catalog) Get(name, version string) (*Template, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q", name)
+	}
+	if version == "" {
+		versions, err := c.versions(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("template %q not found", name)
+		}
+		version = versions[0]
+	} else if !namePattern.MatchString(version) {
+		return nil, fmt.Errorf("invalid template version %q", version)
+	}
+	return c.load(name, version)
+}
+
+// Render renders a template version with params and validates the result as
+// a spec. Defaults fill missing parameters; unknown, missing required, and
+// mistyped parameters are errors.
+func (c *Catalog) Render(name, version string, params map[string]any) ([]byte, error) {
+	t, err := c.Get(name, version)
+	if err != nil {
+		return nil, err
+	}
+	values, err := t.Resolve(params)
+	if err != nil {
+		return nil, err
+	}
+
+	specPath := filepath.Join(c.Dir, t.Name, t.Version, SpecFile)
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s@%s: %w", t.Name, t.Version, err)
+	}
+	tmpl, err := template.New(t.Name).Delims("[[", "]]").Option("missingkey=error").
+		Funcs(template.FuncMap{"seq": seq}).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %s@%s: %w", t.Name, t.Version, err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, values); err != nil {
+		return nil, fmt.Errorf("failed to render template %s@%s: %w", t.Name, t.Version, err)
+	}
+	if _, err := spec.Parse(out.Bytes()); err != nil {
+		return nil, fmt.Errorf("template %s@%s rendered an invalid spec: %w", t.Name, t.Version, err)
+	}
+	return out.Bytes(), nil
+}
+
+// Resolve checks params against the parameters of the template and returns
+// them with defaults applied.
+func (t *Template) Resolve(params map[string]any) (map[string]any, error) {
+	declared := make(map[string]bool, len(t.Parameters))
+	values := make(map[string]any, len(t.Parameters))
+	for _, p := range t.Parameters {
+		declared[p.Name] = true
+		v, ok := params[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("parameter %q is required", p.Name)
+			}
+			v = p.Default
+		}
+		if v == nil {
+			values[p.Name] = zero(p.Type)
+			continue
+		}
+		converted, err := convert(v, p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", p.Name, err)
+		}
+		if len(p.Enum) > 0 && !inEnum(converted, p.Enum, p.Type) {
+			return nil, fmt.Errorf("parameter %q: %v is not one of %v", p.Name, converted, p.Enum)
+		}
+		values[p.Name] = converted
+	}
+	for name := range params {
+		if !declared[name] {
+			return nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return values, nil
+}
+
+// ParameterSchema returns the JSON Schema of the parameters object.
+func (t *Template) ParameterSchema() map[string]any {
+	properties := make(map[string]any, len(t.Parameters))
+	var required []string
+	for _, p := range t.Parameters {
+		prop := map[string]any{"type": typeOf(p)}
+		if p.Description != "" {
+			prop["description"] = p.Description
+		}
+		if p.Default != nil {
+			prop["default"] = p.Default
+		}
+		if len(p.Enum) > 0 {
+			prop["enum"] = p.Enum
+		}
+		properties[p.Name] = prop
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// load reads the metadata of a template version.
+func (c *Catalog) load(name, version string) (*Template, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, name, version, TemplateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("template %s@%s not found", name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s@%s: %w", name, version, err)
+	}
+	t := &Template{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(t); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid %s of template %s@%s: %w", TemplateFile, name, version, err)
+	}
+	t.Name, t.Version = name, version
+	for i, p := range t.Parameters {
+		if p.Name == "" {
+			return nil, fmt.Errorf("template %s@%s: parameters[%d] has no name", name, version, i)
+		}
+		switch p.Type {
+		case "", TypeString, TypeInteger, TypeBoolean:
+		default:
+			return nil, fmt.Errorf("template %s@%s: parameter %q has unsupported type %q", name, version, p.Name, p.Type)
+		}
+	}
+	return t, nil
+}
+
+// versions returns the versions of a template, latest first.
+func (c *Catalog) versions(name string) ([]string, error) {
+	versions, err := subdirs(filepath.Join(c.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %q: %w", name, err)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) > 0
+	})
+	return versions, nil
+}
+
+// subdirs returns the sorted names of the directories in dir, skipping
+// hidden ones such as .git.
+func subdirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && namePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// compareVersions compares dot-separated versions with an optional "v"
+// prefix, numerically where both parts are numbers.
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.Atoi(pa[i])
+		nb, errB := strconv.Atoi(pb[i])
+		switch {
+		case errA == nil && errB == nil:
+			if na != nb {
+				return na - nb
+			}
+		case pa[i] != pb[i]:
+			return strings.Compare(pa[i], pb[i])
+		}
+	}
+	return len(pa) - len(pb)
+}
+
+// typeOf returns the JSON Schema type of a parameter.
+func typeOf(p Parameter) string {
+	if p.Type == "" {
+		return TypeString
+	}
+	return p.Type
+}
+
+// zero returns the zero value of a parameter type.
+func zero(typ string) any {
+	switch typ {
+	case TypeInteger:
+		return 0
+	case TypeBoolean:
+		return false
+	default:
+		return ""
+	}
+}
+
+// convert converts a YAML or JSON value to a parameter type. Strings are
+// parsed so that values can be given on the command line.
+func convert(v any, typ string) (any, error) {
+	switch typ {
+	case TypeInteger:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case int64:
+			return int(n), nil
+		case float64:
+			if n != float64(int(n)) {
+				return nil, fmt.Errorf("%v is not an integer", n)
+			}
+			return int(n), nil
+		case string:
+			i, err := strconv.Atoi(n)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", n)
+			}
+			return i, nil
+		}
+	case TypeBoolean:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", b)
+			}
+			return parsed, nil
+		}
+	default:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case int, int64, float64, bool:
+			return fmt.Sprint(s), nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not a %s", v, typeOf(Parameter{Type: typ}))
+}
+
+// inEnum reports whether v is one of enum after conversion to typ.
+func inEnum(v any, enum []any, typ string) bool {
+	for _, e := range enum {
+		if converted, err := convert(e, typ); err == nil && converted == v {
+			return true
+		}
+	}
+	return false
+}
+
+// seq returns 0..n-1, for ranges such as [[ range $i := seq .workers ]].
+func seq(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+const labTemplate = `description: Control plane and workers
+tags: [kubernetes]
+parameters:
+  - name: workers
+    type: integer
+    default: 2
+  - name: memory
+    type: integer
+    default: 2048
+  - name: flavor
+    enum: [kubeadm, k3s]
+    default: kubeadm
+  - name: prefix
+    required: true
+`
+
+const labSpec = `providers:
+  - name: stub
+    engine: go://stub
+keys:
+  - name: ssh
+    spec:
+      type: ed25519
+vms:
+  - name: [[ .prefix ]]-cp
+    spec:
+      memory: [[ .memory ]]
+      cloudInit:
+        hostname: [[ .flavor ]]
+        users:
+          - name: admin
+            sshAuthorizedKeys:
+              - "{{ .Keys.ssh.PublicKey }}"
+[[- range $i := seq .workers ]]
+  - name: [[ $.prefix ]]-worker-[[ $i ]]
+    spec:
+      memory: [[ $.memory ]]
+[[- end ]]
+`
+
+// newCatalog writes templates given as name/version to their template and
+// spec files.
+func newCatalog(t *testing.T, templates map[string][2]string) *Catalog {
+	t.Helper()
+	dir := t.TempDir()
+	for nameVersion, files := range templates {
+		versionDir := filepath.Join(dir, filepath.FromSlash(nameVersion))
+		if err := os.MkdirAll(versionDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(versionDir, TemplateFile), []byte(files[0]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(versionDir, SpecFile), []byte(files[1]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Catalog{Dir: dir}
+}
+
+func TestCatalogList(t *testing.T) {
+	c := newCatalog(t, map[string][2]string{
+		"k8s-ha/1.2.0":  {labTemplate, labSpec},
+		"k8s-ha/1.10.0": {labTemplate, labSpec},
+		"k8s-ha/1.9.0":  {labTemplate, labSpec},
+		"pxe-lab/v1":    {"description: PXE boot lab\n", labSpec},
+	})
+	templates, err := c.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var got []string
+	for _, tmpl := range templates {
+		got = append(got, tmpl.Name+"@"+tmpl.Version)
+	}
+	want := []string{"k8s-ha@1.10.0", "k8s-ha@1.9.0", "k8s-ha@1.2.0", "pxe-lab@v1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+
+	latest, err := c.Get("k8s-ha", "")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if latest.Version != "1.10.0" || latest.Description != "Control plane and workers" || len(latest.Parameters) != 4 {
+		t.Errorf("Get() = %+v", latest)
+	}
+	for _, tt := range [][2]string{{"missing", ""}, {"k8s-ha", "2.0.0"}, {"../k8s-ha", ""}} {
+		if _, err := c.Get(tt[0], tt[1]); err == nil {
+			t.Errorf("Get(%q, %q) should fail", tt[0], tt[1])
+		}
+	}
+}
+
+func TestCatalogRender(t *testing.T) {
+	c := newCatalog(t, map[string][2]string{"k8s-ha/1.0.0": {labTemplate, labSpec}})
+
+	data, err := c.Render("k8s-ha", "", map[string]any{"prefix": "lab", "workers": "3", "flavor": "k3s"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	s, err := spec.Parse(data)
+	if err != nil {
+		t.Fatalf("rendered spec does not parse: %v\n%s", err, data)
+	}
+	if len(s.Vms) != 4 || s.Vms[0].Name != "lab-cp" || s.Vms[3].Name != "lab-worker-2" {
+		t.Errorf("unexpected VMs: %+v", s.Vms)
+	}
+	if s.Vms[0].Spec.Memory != 2048 || s.Vms[0].Spec.CloudInit.Hostname != "k3s" {
+		t.Errorf("defaults or parameters not applied: %+v", s.Vms[0].Spec)
+	}
+	if !strings.Contains(string(data), "{{ .Keys.ssh.PublicKey }}") {
+		t.Error("creation-time template references should be kept")
+	}
+}
+
+func TestCatalogRenderErrors(t *testing.T) {
+	c := newCatalog(t, map[string][2]string{
+		"k8s-ha/1.0.0":  {labTemplate, labSpec},
+		"broken/1.0.0":  {"", "vms: [[ .missing ]]\n"},
+		"invalid/1.0.0": {"", "unknownField: true\n"},
+	})
+	tests := map[string]struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		"missing required": {name: "k8s-ha", params: map[string]any{}, want: `"prefix" is required`},
+		"unknown":          {name: "k8s-ha", params: map[string]any{"prefix": "a", "gpus": 1}, want: `unknown parameter "gpus"`},
+		"mistyped":         {name: "k8s-ha", params: map[string]any{"prefix": "a", "workers": "many"}, want: "not an integer"},
+		"not in enum":      {name: "k8s-ha", params: map[string]any{"prefix": "a", "flavor": "rke2"}, want: "is not one of"},
+		"undeclared key":   {name: "broken", want: "failed to render"},
+		"invalid spec":     {name: "invalid", want: "invalid spec"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.Render(tt.name, "", tt.params)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Render() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParameterSchema(t *testing.T) {
+	tmpl := &Template{Parameters: []Parameter{
+		{Name: "workers", Type: TypeInteger, Default: 2, Description: "Worker count"},
+		{Name: "prefix", Required: true},
+	}}
+	want := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"workers": map[string]any{"type": "integer", "default": 2, "description": "Worker count"},
+			"prefix":  map[string]any{"type": "string"},
+		},
+		"additionalProperties": false,
+		"required":             []string{"prefix"},
+	}
+	if got := tmpl.ParameterSchema(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParameterSchema() = %v, want %v", got, want)
+	}
+}
+
+func TestOpenLocalDirectory(t *testing.T) {
+	c, err := Open(context.Background(), "/srv/catalog", t.TempDir())
+	if err != nil || c.Dir != "/srv/catalog" || c.Commit != "" {
+		t.Errorf("Open() = %+v, %v", c, err)
+	}
+	if _, err := Open(context.Background(), "", t.TempDir()); err == nil {
+		t.Error("Open() without a source should fail")
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+// AgentRunner is an SSHRunner that runs commands through the guest agent of
+// a VM (see pkg/agent), for VMs without an SSH server. The VMInfo passed to
+// Run is ignored: the agent client already addresses the VM.
+type AgentRunner struct {
+	agent *agent.Client
+}
+
+// NewAgentRunner returns a runner using the given agent client, e.g.
+//
+//	runner := client.NewAgentRunner(agent.NewClient(
+//		os.Getenv("TESTENV_VM_WEB_AGENT"), os.Getenv("TESTENV_VM_WEB_AGENT_TOKEN")))
+//	c, err := client.NewClient(provider, "web", client.WithSSHRunner(runner))
+func NewAgentRunner(c *agent.Client) *AgentRunner {
+	return &AgentRunner{agent: c}
+}
+
+// Run implements SSHRunner. A non-zero exit code is returned as an
+// *ExitError along with the output.
+func (r *AgentRunner) Run(ctx context.Context, _ *VMInfo, cmd string) (stdout, stderr string, err error) {
+	resp, err := r.agent.Exec(ctx, cmd)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.ExitCode != 0 {
+		return resp.Stdout, resp.Stderr, &ExitError{Status: resp.ExitCode}
+	}
+	return resp.Stdout, resp.Stderr, nil
+}
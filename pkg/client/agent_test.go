@@ -0,0 +1,85 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+// newAgentClient returns a client running commands through a local agent.
+func newAgentClient(t *testing.T) *Client {
+	t.Helper()
+	srv := httptest.NewServer(agent.NewHandler("token"))
+	t.Cleanup(srv.Close)
+	ac := agent.NewClient(strings.TrimPrefix(srv.URL, "http://"), "token")
+	return newRetryClient(t, NewAgentRunner(ac))
+}
+
+func TestAgentRunner_Run(t *testing.T) {
+	c := newAgentClient(t)
+	ctx := context.Background()
+
+	stdout, _, err := c.Run(ctx, "echo", "hello world")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stdout != "hello world\n" {
+		t.Errorf("Run() stdout = %q", stdout)
+	}
+
+	_, stderr, err := c.Run(ctx, "sh", "-c", "echo failed >&2; exit 4")
+	if code, ok := ExitCode(err); !ok || code != 4 {
+		t.Errorf("Run() error = %v, want exit code 4", err)
+	}
+	if stderr != "failed\n" {
+		t.Errorf("Run() stderr = %q", stderr)
+	}
+}
+
+func TestAgentRunner_Files(t *testing.T) {
+	c := newAgentClient(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "file.txt")
+
+	if err := c.WriteFile(ctx, path, []byte("content"), 0o640); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	data, err := c.ReadFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "content" {
+		t.Errorf("ReadFile() = %q", data)
+	}
+}
+
+func TestAgentRunner_Unreachable(t *testing.T) {
+	c := newRetryClient(t, NewAgentRunner(agent.NewClient("127.0.0.1:1", "token")))
+	_, _, err := c.Run(context.Background(), "true")
+	if err == nil {
+		t.Fatal("Run() expected error for an unreachable agent")
+	}
+	if _, ok := ExitCode(err); ok {
+		t.Errorf("Run() error = %v, should not carry an exit code", err)
+	}
+}
@@ -16,14 +16,20 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 )
 
+// ErrStdinUnsupported is returned when a command needs a privilege escalation
+// password but the SSH runner of the client does not implement StdinRunner.
+var ErrStdinUnsupported = errors.New("client: SSH runner cannot write the privilege escalation password to standard input")
+
 // Client provides high-level operations on a VM via SSH.
 type Client struct {
 	provider       ClientProvider
@@ -95,7 +101,42 @@ func (c *Client) RunWithContext(ctx context.Context, execCtx *ExecutionContext,
 	}
 
 	formattedCmd := FormatCmd(execCtx, cmd...)
-	return c.sshRunner.Run(ctx, vmInfo, formattedCmd)
+	return c.run(ctx, vmInfo, execCtx, formattedCmd)
+}
+
+// run runs a command formatted with execCtx. The privilege escalation
+// password, if any, is written to the standard input of the session rather
+// than being part of cmd, so it does not show in the process list of the VM.
+func (c *Client) run(ctx context.Context, vmInfo *VMInfo, execCtx *ExecutionContext, cmd string) (string, string, error) {
+	if !needsPassword(execCtx) {
+		return c.sshRunner.Run(ctx, vmInfo, cmd)
+	}
+	runner, ok := c.sshRunner.(StdinRunner)
+	if !ok {
+		return "", "", ErrStdinUnsupported
+	}
+	return runner.RunWithStdin(ctx, vmInfo, cmd, strings.NewReader(execCtx.PrivilegeEscalation().Password+"\n"))
+}
+
+// connect returns a client running its commands over one connection when
+// the SSH runner is a Connector, and c otherwise, together with the function
+// closing the connection.
+func (c *Client) connect(ctx context.Context) (*Client, func(), error) {
+	connector, ok := c.sshRunner.(Connector)
+	if !ok {
+		return c, func() {}, nil
+	}
+	vmInfo, err := c.getVMInfo()
+	if err != nil {
+		return nil, nil, fmt.Errorf("client: failed to get VM info: %w", err)
+	}
+	conn, err := connector.Connect(ctx, vmInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client: %w", err)
+	}
+	connected := *c
+	connected.sshRunner = conn
+	return &connected, func() { _ = conn.Close() }, nil
 }
 
 // CopyTo copies a local file to the VM.
@@ -114,16 +155,16 @@ func (c *Client) CopyTo(ctx context.Context, localPath, remotePath string) error
 	// Create parent directory on remote
 	parentDir := filepath.Dir(remotePath)
 	mkdirCommand := mkdirCmd(parentDir)
-	formattedMkdir := FormatCmd(c.defaultExecCtx, mkdirCommand)
-	_, stderr, err := c.sshRunner.Run(ctx, vmInfo, formattedMkdir)
+	formattedMkdir := shellCmd(c.defaultExecCtx, mkdirCommand)
+	_, stderr, err := c.run(ctx, vmInfo, c.defaultExecCtx, formattedMkdir)
 	if err != nil {
 		return fmt.Errorf("client: failed to create remote directory %s: %w (stderr: %s)", parentDir, err, stderr)
 	}
 
 	// Copy file content using base64
 	copyCommand := copyToCmd(content, remotePath)
-	formattedCopy := FormatCmd(c.defaultExecCtx, copyCommand)
-	_, stderr, err = c.sshRunner.Run(ctx, vmInfo, formattedCopy)
+	formattedCopy := shellCmd(c.defaultExecCtx, copyCommand)
+	_, stderr, err = c.run(ctx, vmInfo, c.defaultExecCtx, formattedCopy)
 	if err != nil {
 		return fmt.Errorf("client: failed to copy file to %s: %w (stderr: %s)", remotePath, err, stderr)
 	}
@@ -140,8 +181,8 @@ func (c *Client) CopyFrom(ctx context.Context, remotePath, localPath string) err
 
 	// Get file content from remote via base64
 	copyCommand := copyFromCmd(remotePath)
-	formattedCmd := FormatCmd(c.defaultExecCtx, copyCommand)
-	stdout, stderr, err := c.sshRunner.Run(ctx, vmInfo, formattedCmd)
+	formattedCmd := shellCmd(c.defaultExecCtx, copyCommand)
+	stdout, stderr, err := c.run(ctx, vmInfo, c.defaultExecCtx, formattedCmd)
 	if err != nil {
 		return fmt.Errorf("client: failed to read remote file %s: %w (stderr: %s)", remotePath, err, stderr)
 	}
@@ -174,8 +215,8 @@ func (c *Client) FileExists(ctx context.Context, path string) (bool, error) {
 	}
 
 	existsCommand := fileExistsCmd(path)
-	formattedCmd := FormatCmd(c.defaultExecCtx, existsCommand)
-	stdout, _, err := c.sshRunner.Run(ctx, vmInfo, formattedCmd)
+	formattedCmd := shellCmd(c.defaultExecCtx, existsCommand)
+	stdout, _, err := c.run(ctx, vmInfo, c.defaultExecCtx, formattedCmd)
 	if err != nil {
 		return false, fmt.Errorf("client: failed to check file existence: %w", err)
 	}
@@ -191,8 +232,8 @@ func (c *Client) MkdirAll(ctx context.Context, path string) error {
 	}
 
 	mkdirCommand := mkdirCmd(path)
-	formattedCmd := FormatCmd(c.defaultExecCtx, mkdirCommand)
-	_, stderr, err := c.sshRunner.Run(ctx, vmInfo, formattedCmd)
+	formattedCmd := shellCmd(c.defaultExecCtx, mkdirCommand)
+	_, stderr, err := c.run(ctx, vmInfo, c.defaultExecCtx, formattedCmd)
 	if err != nil {
 		return fmt.Errorf("client: failed to create directory %s: %w (stderr: %s)", path, err, stderr)
 	}
@@ -208,8 +249,8 @@ func (c *Client) Chmod(ctx context.Context, path, mode string) error {
 	}
 
 	chmodCommand := chmodCmd(path, mode)
-	formattedCmd := FormatCmd(c.defaultExecCtx, chmodCommand)
-	_, stderr, err := c.sshRunner.Run(ctx, vmInfo, formattedCmd)
+	formattedCmd := shellCmd(c.defaultExecCtx, chmodCommand)
+	_, stderr, err := c.run(ctx, vmInfo, c.defaultExecCtx, formattedCmd)
 	if err != nil {
 		return fmt.Errorf("client: failed to chmod %s %s: %w (stderr: %s)", mode, path, err, stderr)
 	}
@@ -217,6 +258,77 @@ func (c *Client) Chmod(ctx context.Context, path, mode string) error {
 	return nil
 }
 
+// ReadFile returns the content of a file on the VM, following symlinks.
+func (c *Client) ReadFile(ctx context.Context, path string) ([]byte, error) {
+	stdout, err := c.runShell(ctx, copyFromCmd(path))
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to read remote file %s: %w", path, err)
+	}
+	content, err := decodeBase64(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to decode base64 content: %w", err)
+	}
+	return content, nil
+}
+
+// WriteFile writes content to a file on the VM, creating it if needed. The
+// file is written in place: a symlink is followed rather than replaced, and
+// an existing file keeps its owner. If mode is non-zero, the file's
+// permissions are set to it; otherwise an existing file keeps its
+// permissions and a new one gets the default of the umask.
+func (c *Client) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	return c.writeFile(ctx, path, content, mode, false)
+}
+
+// AppendFile appends content to a file on the VM, creating it if needed.
+// Like WriteFile, symlinks are followed and permissions are preserved.
+func (c *Client) AppendFile(ctx context.Context, path string, content []byte) error {
+	return c.writeFile(ctx, path, content, 0, true)
+}
+
+// writeFile implements WriteFile and AppendFile.
+func (c *Client) writeFile(ctx context.Context, path string, content []byte, mode os.FileMode, appendOnly bool) error {
+	for _, cmd := range writeFileCmds(content, path, appendOnly) {
+		if _, err := c.runShell(ctx, cmd); err != nil {
+			return fmt.Errorf("client: failed to write remote file %s: %w", path, err)
+		}
+	}
+	if mode != 0 {
+		if _, err := c.runShell(ctx, chmodCmd(path, fmt.Sprintf("%04o", mode.Perm()))); err != nil {
+			return fmt.Errorf("client: failed to chmod %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Sha256 returns the hex-encoded SHA-256 checksum of a file on the VM,
+// following symlinks, e.g. to assert a file matches a local one.
+func (c *Client) Sha256(ctx context.Context, path string) (string, error) {
+	stdout, err := c.runShell(ctx, sha256Cmd(path))
+	if err != nil {
+		return "", fmt.Errorf("client: failed to checksum remote file %s: %w", path, err)
+	}
+	sum, err := parseSha256(stdout)
+	if err != nil {
+		return "", fmt.Errorf("client: failed to checksum remote file %s: %w", path, err)
+	}
+	return sum, nil
+}
+
+// runShell runs a shell command line with the default execution context and
+// returns its stdout. The error includes stderr.
+func (c *Client) runShell(ctx context.Context, script string) (string, error) {
+	vmInfo, err := c.getVMInfo()
+	if err != nil {
+		return "", fmt.Errorf("failed to get VM info: %w", err)
+	}
+	stdout, stderr, err := c.run(ctx, vmInfo, c.defaultExecCtx, shellCmd(c.defaultExecCtx, script))
+	if err != nil {
+		return stdout, fmt.Errorf("%w (stderr: %s)", err, strings.TrimSpace(stderr))
+	}
+	return stdout, nil
+}
+
 // WaitReady waits for the VM to be ready (SSH + cloud-init).
 // Phase 1: Poll for VM IP and SSH until connection succeeds (every 5s).
 // Phase 2: Run `timeout 60 cloud-init status --wait || test -f /var/lib/cloud/instance/boot-finished`
@@ -213,6 +213,38 @@ func TestRunWithContextUsesCustomContext(t *testing.T) {
 	}
 }
 
+// TestRunWithContextWritesPasswordToStdin verifies that the privilege
+// escalation password is written to the session rather than the command.
+func TestRunWithContextWritesPasswordToStdin(t *testing.T) {
+	provider := newTestProvider()
+	provider.AddVM("test-vm", validVMInfo())
+
+	mockRunner := NewMockSSHRunner()
+	client, err := NewClient(provider, "test-vm", WithSSHRunner(mockRunner))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	execCtx := NewExecutionContext().WithPrivilegeEscalation(PrivilegeEscalationSudoPassword("s3cret"))
+	if _, _, err := client.RunWithContext(context.Background(), execCtx, "whoami"); err != nil {
+		t.Fatalf("RunWithContext failed: %v", err)
+	}
+	if commands := mockRunner.GetCommands(); len(commands) != 1 || strings.Contains(commands[0], "s3cret") {
+		t.Errorf("the password must not be part of the command, got %q", commands)
+	}
+	if len(mockRunner.Inputs) != 1 || mockRunner.Inputs[0] != "s3cret\n" {
+		t.Errorf("inputs = %q, want the password", mockRunner.Inputs)
+	}
+
+	plain, err := NewClient(provider, "test-vm", WithSSHRunner(plainRunner{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := plain.RunWithContext(context.Background(), execCtx, "whoami"); !errors.Is(err, ErrStdinUnsupported) {
+		t.Errorf("RunWithContext() error = %v, want ErrStdinUnsupported", err)
+	}
+}
+
 // TestRunWithContextWithEnvs verifies RunWithContext applies environment variables.
 func TestRunWithContextWithEnvs(t *testing.T) {
 	provider := newTestProvider()
@@ -782,3 +814,124 @@ func TestVMInfoValidationFailure(t *testing.T) {
 		t.Errorf("expected error to contain 'invalid VM info', got: %v", err)
 	}
 }
+
+func TestWriteFileReadFileRoundTrip(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "it's a file")
+
+	// Larger than a write chunk, with bytes the shell would interpret.
+	content := []byte(strings.Repeat("$HOME `id` 'quoted'\n\x00\xff", 4*1024))
+	if err := c.WriteFile(ctx, path, content, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	got, err := c.ReadFile(ctx, path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(got) != string(content) {
+		t.Errorf("ReadFile() returned %d bytes, want %d", len(got), len(content))
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// Overwriting truncates the file.
+	if err := c.WriteFile(ctx, path, []byte("short"), 0); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "short" {
+		t.Errorf("content = %q, want %q", got, "short")
+	}
+}
+
+func TestWriteFilePreservesModeAndSymlinks(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "link")
+	if err := os.WriteFile(target, []byte("old"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(target, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.WriteFile(ctx, link, []byte("new"), 0); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link was replaced: %v, %v", fi, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	if got, _ := os.ReadFile(target); string(got) != "new" {
+		t.Errorf("target content = %q, want %q", got, "new")
+	}
+	if got, err := c.ReadFile(ctx, link); err != nil || string(got) != "new" {
+		t.Errorf("ReadFile(link) = %q, %v", got, err)
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "log")
+
+	for _, line := range []string{"one\n", "two\n"} {
+		if err := c.AppendFile(ctx, path, []byte(line)); err != nil {
+			t.Fatalf("AppendFile() error = %v", err)
+		}
+	}
+	if got, _ := os.ReadFile(path); string(got) != "one\ntwo\n" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestSha256(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := c.Sha256(ctx, path)
+	if err != nil {
+		t.Fatalf("Sha256() error = %v", err)
+	}
+	// sha256 of "hello\n".
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	if sum != want {
+		t.Errorf("Sha256() = %s, want %s", sum, want)
+	}
+}
+
+func TestFileHelpersReturnErrorForMissingFile(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "missing", "file")
+
+	if _, err := c.ReadFile(ctx, path); err == nil {
+		t.Error("ReadFile() expected error")
+	}
+	if _, err := c.Sha256(ctx, path); err == nil {
+		t.Error("Sha256() expected error")
+	}
+	if err := c.WriteFile(ctx, path, []byte("x"), 0o644); err == nil {
+		t.Error("WriteFile() expected error for missing parent directory")
+	}
+}
@@ -16,6 +16,7 @@ package client
 
 import (
 	"fmt"
+	"os"
 	"strings"
 )
 
@@ -27,8 +28,29 @@ type PrivilegeEscalation struct {
 	// Cmd is the command prefix for privilege escalation
 	// Example: []string{"sudo"} or []string{"sudo", "-E"}
 	Cmd []string
+	// Wrap passes the whole command, environment variables included, as a
+	// single argument after Cmd, as su -c expects.
+	Wrap bool
+	// Password is written by the client to the standard input of the
+	// session when set, so it is never part of the command line. Cmd must
+	// read it from there, e.g. sudo -S. Commands run with it get no other
+	// standard input.
+	Password string
+	// NoPrompt is a shell condition true when Cmd will not ask for
+	// Password, e.g. when the user is root. The password is then read and
+	// discarded before Cmd runs so that the command does not get it.
+	NoPrompt string
 }
 
+// Names of the privilege escalation methods accepted by
+// ParsePrivilegeEscalation.
+const (
+	PrivilegeEscalationMethodNone = "none"
+	PrivilegeEscalationMethodSudo = "sudo"
+	PrivilegeEscalationMethodDoas = "doas"
+	PrivilegeEscalationMethodSu   = "su"
+)
+
 // PrivilegeEscalationNone returns a PrivilegeEscalation with Enabled=false.
 func PrivilegeEscalationNone() *PrivilegeEscalation {
 	return &PrivilegeEscalation{
@@ -54,11 +76,96 @@ func PrivilegeEscalationSudoPreserveEnv() *PrivilegeEscalation {
 	}
 }
 
-// ExecutionContext contains environment variables and privilege escalation settings.
+// PrivilegeEscalationSudoPassword returns a PrivilegeEscalation configured
+// for sudo reading the password from stdin (-S flag), for users without
+// NOPASSWD. Cached credentials are ignored (-k flag) so that sudo always
+// consumes the password instead of leaving it to the command.
+func PrivilegeEscalationSudoPassword(password string) *PrivilegeEscalation {
+	return &PrivilegeEscalation{
+		Enabled:  true,
+		Cmd:      []string{"sudo", "-S", "-k", "-p", ""},
+		Password: password,
+		NoPrompt: "sudo -n -k true 2>/dev/null",
+	}
+}
+
+// PrivilegeEscalationDoas returns a PrivilegeEscalation configured for doas,
+// e.g. on Alpine. doas cannot read a password from stdin: the user needs a
+// nopass rule in doas.conf.
+func PrivilegeEscalationDoas() *PrivilegeEscalation {
+	return &PrivilegeEscalation{
+		Enabled: true,
+		Cmd:     []string{"doas"},
+	}
+}
+
+// PrivilegeEscalationSu returns a PrivilegeEscalation running commands with
+// su -c, for images without sudo or doas. Environment variables are set
+// within the su shell since su does not preserve them.
+func PrivilegeEscalationSu() *PrivilegeEscalation {
+	return &PrivilegeEscalation{
+		Enabled: true,
+		Cmd:     []string{"su", "-c"},
+		Wrap:    true,
+	}
+}
+
+// PrivilegeEscalationSuPassword is like PrivilegeEscalationSu but writes the
+// root password to the standard input of su. Only su implementations that
+// read it from a non-terminal stdin, like BusyBox, accept it.
+func PrivilegeEscalationSuPassword(password string) *PrivilegeEscalation {
+	pe := PrivilegeEscalationSu()
+	pe.Password = password
+	pe.NoPrompt = `[ "$(id -u)" = 0 ]`
+	return pe
+}
+
+// needsPassword reports whether commands of ctx expect the privilege
+// escalation password on their standard input.
+func needsPassword(ctx *ExecutionContext) bool {
+	if ctx == nil {
+		return false
+	}
+	pe := ctx.PrivilegeEscalation()
+	return pe != nil && pe.Enabled && pe.Password != ""
+}
+
+// ParsePrivilegeEscalation returns the privilege escalation of a method
+// name: none, sudo, doas or su. password is used by sudo and su; an empty
+// password means none is needed.
+func ParsePrivilegeEscalation(method, password string) (*PrivilegeEscalation, error) {
+	switch method {
+	case "", PrivilegeEscalationMethodNone:
+		return PrivilegeEscalationNone(), nil
+	case PrivilegeEscalationMethodSudo:
+		if password != "" {
+			return PrivilegeEscalationSudoPassword(password), nil
+		}
+		return PrivilegeEscalationSudo(), nil
+	case PrivilegeEscalationMethodDoas:
+		if password != "" {
+			return nil, fmt.Errorf("privilege escalation %q does not support passwords", method)
+		}
+		return PrivilegeEscalationDoas(), nil
+	case PrivilegeEscalationMethodSu:
+		if password != "" {
+			return PrivilegeEscalationSuPassword(password), nil
+		}
+		return PrivilegeEscalationSu(), nil
+	default:
+		return nil, fmt.Errorf("unknown privilege escalation %q (expected none, sudo, doas or su)", method)
+	}
+}
+
+// ExecutionContext contains environment variables, privilege escalation,
+// working directory and umask settings.
 // It follows an immutable builder pattern where With* methods return new instances.
 type ExecutionContext struct {
 	envs                map[string]string
 	privilegeEscalation *PrivilegeEscalation
+	workingDir          string
+	umask               os.FileMode
+	hasUmask            bool
 }
 
 // NewExecutionContext creates a new ExecutionContext with no environment variables
@@ -87,6 +194,24 @@ func (c *ExecutionContext) PrivilegeEscalation() *PrivilegeEscalation {
 	return c.privilegeEscalation
 }
 
+// WorkingDir returns the directory commands run in, or "" for the login
+// directory.
+func (c *ExecutionContext) WorkingDir() string {
+	return c.workingDir
+}
+
+// Umask returns the umask commands run with, and whether one is set.
+func (c *ExecutionContext) Umask() (os.FileMode, bool) {
+	return c.umask, c.hasUmask
+}
+
+// clone returns a copy of the context with its own environment map.
+func (c *ExecutionContext) clone() *ExecutionContext {
+	out := *c
+	out.envs = c.Envs()
+	return &out
+}
+
 // WithEnvs returns a new ExecutionContext with the given environment variables,
 // replacing any existing environment variables.
 func (c *ExecutionContext) WithEnvs(envs map[string]string) *ExecutionContext {
@@ -94,37 +219,42 @@ func (c *ExecutionContext) WithEnvs(envs map[string]string) *ExecutionContext {
 	for k, v := range envs {
 		newEnvs[k] = v
 	}
-	return &ExecutionContext{
-		envs:                newEnvs,
-		privilegeEscalation: c.privilegeEscalation,
-	}
+	out := c.clone()
+	out.envs = newEnvs
+	return out
 }
 
 // WithEnv returns a new ExecutionContext with the given environment variable added.
 // Existing environment variables are preserved.
 func (c *ExecutionContext) WithEnv(key, value string) *ExecutionContext {
-	newEnvs := make(map[string]string, len(c.envs)+1)
-	for k, v := range c.envs {
-		newEnvs[k] = v
-	}
-	newEnvs[key] = value
-	return &ExecutionContext{
-		envs:                newEnvs,
-		privilegeEscalation: c.privilegeEscalation,
-	}
+	out := c.clone()
+	out.envs[key] = value
+	return out
 }
 
 // WithPrivilegeEscalation returns a new ExecutionContext with the given
 // privilege escalation configuration.
 func (c *ExecutionContext) WithPrivilegeEscalation(pe *PrivilegeEscalation) *ExecutionContext {
-	newEnvs := make(map[string]string, len(c.envs))
-	for k, v := range c.envs {
-		newEnvs[k] = v
-	}
-	return &ExecutionContext{
-		envs:                newEnvs,
-		privilegeEscalation: pe,
-	}
+	out := c.clone()
+	out.privilegeEscalation = pe
+	return out
+}
+
+// WithWorkingDir returns a new ExecutionContext running commands in path.
+// The command fails without running if the directory does not exist.
+func (c *ExecutionContext) WithWorkingDir(path string) *ExecutionContext {
+	out := c.clone()
+	out.workingDir = path
+	return out
+}
+
+// WithUmask returns a new ExecutionContext running commands with the given
+// umask, e.g. 0o022. With sudo, the umask of sudoers may further restrict it.
+func (c *ExecutionContext) WithUmask(mode os.FileMode) *ExecutionContext {
+	out := c.clone()
+	out.umask = mode.Perm()
+	out.hasUmask = true
+	return out
 }
 
 // unquotable contains shell operators that should not be quoted.
@@ -146,9 +276,13 @@ func safelyAppendToCmd(cmd string, s string) string {
 }
 
 // FormatCmd formats a command with environment variables and privilege escalation.
-// It prepends environment variables in the form KEY="value", then adds privilege
+// It changes to the working directory and sets the umask if configured, then
+// prepends environment variables in the form KEY="value", then adds privilege
 // escalation commands if enabled, then adds the actual command arguments.
 // Shell operators (&&, ||, ;, :, &) are not quoted.
+// With a wrapping escalation such as su -c, environment variables and
+// arguments are passed to it as one quoted argument. A password is not part
+// of the command: the client writes it to the standard input of the session.
 func FormatCmd(ctx *ExecutionContext, cmd ...string) string {
 	if ctx == nil {
 		ctx = NewExecutionContext()
@@ -156,6 +290,36 @@ func FormatCmd(ctx *ExecutionContext, cmd ...string) string {
 
 	out := ""
 
+	// Set up the shell first: it applies to every command of cmd
+	if dir := ctx.WorkingDir(); dir != "" && len(cmd) > 0 {
+		out = fmt.Sprintf("cd %q && ", dir)
+	}
+	if mode, ok := ctx.Umask(); ok && len(cmd) > 0 {
+		out = fmt.Sprintf("%sumask %04o && ", out, mode)
+	}
+
+	pe := ctx.PrivilegeEscalation()
+	if pe == nil || !pe.Enabled {
+		return strings.TrimSpace(out + formatEnvsAndArgs(ctx, nil, cmd))
+	}
+
+	if pe.Password != "" && pe.NoPrompt != "" {
+		out = fmt.Sprintf("%s{ ! %s || read -r _; } && ", out, pe.NoPrompt)
+	}
+	if pe.Wrap {
+		for _, s := range pe.Cmd {
+			out = safelyAppendToCmd(out, s)
+		}
+		return strings.TrimSpace(fmt.Sprintf("%s%q", out, formatEnvsAndArgs(ctx, nil, cmd)))
+	}
+	return strings.TrimSpace(out + formatEnvsAndArgs(ctx, pe.Cmd, cmd))
+}
+
+// formatEnvsAndArgs formats environment variables, then the prefix, then
+// the command arguments.
+func formatEnvsAndArgs(ctx *ExecutionContext, prefix, cmd []string) string {
+	out := ""
+
 	// Add environment variables first
 	for k, v := range ctx.Envs() {
 		envStr := fmt.Sprintf("%s=%q", k, v)
@@ -163,11 +327,8 @@ func FormatCmd(ctx *ExecutionContext, cmd ...string) string {
 	}
 
 	// Add privilege escalation command (if enabled)
-	pe := ctx.PrivilegeEscalation()
-	if pe != nil && pe.Enabled {
-		for _, s := range pe.Cmd {
-			out = safelyAppendToCmd(out, s)
-		}
+	for _, s := range prefix {
+		out = safelyAppendToCmd(out, s)
 	}
 
 	// Add the actual command
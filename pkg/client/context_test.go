@@ -337,3 +337,162 @@ func TestEnvsWithNilMap(t *testing.T) {
 		t.Errorf("expected empty envs map, got %v", envs)
 	}
 }
+
+// TestFormatCmdWithWorkingDirAndUmask verifies that FormatCmd changes to the
+// working directory and sets the umask before anything else.
+func TestFormatCmdWithWorkingDirAndUmask(t *testing.T) {
+	base := NewExecutionContext().
+		WithEnv("KEY", "value").
+		WithPrivilegeEscalation(PrivilegeEscalationSudo())
+
+	tests := []struct {
+		name     string
+		ctx      *ExecutionContext
+		expected string
+	}{
+		{
+			name:     "working directory",
+			ctx:      base.WithWorkingDir("/opt/app dir"),
+			expected: `cd "/opt/app dir" && KEY="value" "sudo" "make" && "make" "install"`,
+		},
+		{
+			name:     "umask",
+			ctx:      base.WithUmask(0o022),
+			expected: `umask 0022 && KEY="value" "sudo" "make" && "make" "install"`,
+		},
+		{
+			name:     "both",
+			ctx:      base.WithUmask(0o77).WithWorkingDir("/srv"),
+			expected: `cd "/srv" && umask 0077 && KEY="value" "sudo" "make" && "make" "install"`,
+		},
+		{
+			name:     "zero umask",
+			ctx:      base.WithUmask(0),
+			expected: `umask 0000 && KEY="value" "sudo" "make" && "make" "install"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatCmd(tt.ctx, "make", "&&", "make", "install"); got != tt.expected {
+				t.Errorf("FormatCmd() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	// The base context is unchanged.
+	if base.WorkingDir() != "" {
+		t.Errorf("WorkingDir() = %q, want empty", base.WorkingDir())
+	}
+	if _, ok := base.Umask(); ok {
+		t.Error("Umask() is set on the base context")
+	}
+	if got := FormatCmd(NewExecutionContext().WithWorkingDir("/srv")); got != "" {
+		t.Errorf("FormatCmd() without command = %q, want empty", got)
+	}
+}
+
+// TestWithWorkingDirPreservesSettings verifies that the With* methods keep
+// the working directory and umask.
+func TestWithWorkingDirPreservesSettings(t *testing.T) {
+	ctx := NewExecutionContext().
+		WithWorkingDir("/srv").
+		WithUmask(0o027).
+		WithEnvs(map[string]string{"A": "1"}).
+		WithEnv("B", "2").
+		WithPrivilegeEscalation(PrivilegeEscalationSudo())
+
+	if ctx.WorkingDir() != "/srv" {
+		t.Errorf("WorkingDir() = %q, want /srv", ctx.WorkingDir())
+	}
+	if mode, ok := ctx.Umask(); !ok || mode != 0o027 {
+		t.Errorf("Umask() = %o, %v, want 027, true", mode, ok)
+	}
+	if len(ctx.Envs()) != 2 {
+		t.Errorf("Envs() = %v, want A and B", ctx.Envs())
+	}
+}
+
+// TestFormatCmdWithDoasAndSu verifies the doas and su escalations, with and
+// without password.
+func TestFormatCmdWithDoasAndSu(t *testing.T) {
+	tests := []struct {
+		name     string
+		pe       *PrivilegeEscalation
+		expected string
+	}{
+		{
+			name:     "doas",
+			pe:       PrivilegeEscalationDoas(),
+			expected: `KEY="value" "doas" "apk" "add" "curl"`,
+		},
+		{
+			name:     "su",
+			pe:       PrivilegeEscalationSu(),
+			expected: `"su" "-c" "KEY=\"value\" \"apk\" \"add\" \"curl\""`,
+		},
+		{
+			name:     "su with password",
+			pe:       PrivilegeEscalationSuPassword("s3cret"),
+			expected: `{ ! [ "$(id -u)" = 0 ] || read -r _; } && "su" "-c" "KEY=\"value\" \"apk\" \"add\" \"curl\""`,
+		},
+		{
+			name:     "sudo with password",
+			pe:       PrivilegeEscalationSudoPassword("s3cret"),
+			expected: `{ ! sudo -n -k true 2>/dev/null || read -r _; } && KEY="value" "sudo" "-S" "-k" "-p" "" "apk" "add" "curl"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewExecutionContext().WithEnv("KEY", "value").WithPrivilegeEscalation(tt.pe)
+			if got := FormatCmd(ctx, "apk", "add", "curl"); got != tt.expected {
+				t.Errorf("FormatCmd() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestFormatCmdSuWrapsShellOperators verifies that su runs every command of
+// a compound command, after the working directory is set.
+func TestFormatCmdSuWrapsShellOperators(t *testing.T) {
+	ctx := NewExecutionContext().WithWorkingDir("/srv").WithPrivilegeEscalation(PrivilegeEscalationSu())
+	expected := `cd "/srv" && "su" "-c" "\"apk\" \"update\" && \"apk\" \"add\" \"curl\""`
+	if got := FormatCmd(ctx, "apk", "update", "&&", "apk", "add", "curl"); got != expected {
+		t.Errorf("FormatCmd() = %q, want %q", got, expected)
+	}
+}
+
+// TestParsePrivilegeEscalation verifies the escalation selected by name.
+func TestParsePrivilegeEscalation(t *testing.T) {
+	tests := []struct {
+		method   string
+		password string
+		wantCmd  []string
+		wantErr  bool
+	}{
+		{method: "", wantCmd: nil},
+		{method: "none", wantCmd: nil},
+		{method: "sudo", wantCmd: []string{"sudo"}},
+		{method: "sudo", password: "pw", wantCmd: []string{"sudo", "-S", "-k", "-p", ""}},
+		{method: "doas", wantCmd: []string{"doas"}},
+		{method: "doas", password: "pw", wantErr: true},
+		{method: "su", wantCmd: []string{"su", "-c"}},
+		{method: "su", password: "pw", wantCmd: []string{"su", "-c"}},
+		{method: "pkexec", wantErr: true},
+	}
+	for _, tt := range tests {
+		pe, err := ParsePrivilegeEscalation(tt.method, tt.password)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePrivilegeEscalation(%q) error = %v, wantErr %v", tt.method, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if strings.Join(pe.Cmd, " ") != strings.Join(tt.wantCmd, " ") || pe.Password != tt.password {
+			t.Errorf("ParsePrivilegeEscalation(%q) = %+v, want Cmd %q", tt.method, pe, tt.wantCmd)
+		}
+		if pe.Enabled != (tt.wantCmd != nil) {
+			t.Errorf("ParsePrivilegeEscalation(%q).Enabled = %v", tt.method, pe.Enabled)
+		}
+	}
+}
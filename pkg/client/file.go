@@ -76,6 +76,53 @@ func decodeBase64(encoded string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
 }
 
+// writeChunkSize is the size of the content written by a single command.
+// Its base64 encoding stays below the 128 KiB Linux limit of a single
+// command-line argument.
+const writeChunkSize = 48 * 1024
+
+// writeFileCmds generates the commands writing content to a remote file,
+// truncating it first unless appending. Content is written in chunks; the
+// file is written in place, so symlinks are followed and an existing file
+// keeps its owner and permissions.
+func writeFileCmds(content []byte, remotePath string, appendOnly bool) []string {
+	var cmds []string
+	for first := true; first || len(content) > 0; first = false {
+		chunk := content[:min(len(content), writeChunkSize)]
+		content = content[len(chunk):]
+		redirect := ">>"
+		if first && !appendOnly {
+			redirect = ">"
+		}
+		cmds = append(cmds, fmt.Sprintf("echo %s | base64 -d %s %s",
+			base64.StdEncoding.EncodeToString(chunk), redirect, quotePath(remotePath)))
+	}
+	return cmds
+}
+
+// sha256Cmd generates the command printing the SHA-256 checksum of a file.
+// Uses: sha256sum < <path>, which fails if the file does not exist.
+func sha256Cmd(path string) string {
+	return fmt.Sprintf("sha256sum < %s", quotePath(path))
+}
+
+// parseSha256 parses the output of sha256Cmd.
+func parseSha256(stdout string) (string, error) {
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("unexpected sha256sum output %q", stdout)
+	}
+	return fields[0], nil
+}
+
+// shellCmd formats a shell command line, such as the ones generated in
+// this file, with an execution context. The command line is run by sh -c
+// so that its pipes and redirections are not quoted by FormatCmd, and run
+// with the privileges of the context.
+func shellCmd(execCtx *ExecutionContext, script string) string {
+	return FormatCmd(execCtx, "sh", "-c", script)
+}
+
 // quotePath quotes a path for safe shell usage.
 // This ensures paths with spaces or special characters are handled correctly.
 //
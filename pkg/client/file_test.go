@@ -337,3 +337,37 @@ func TestQuotePath(t *testing.T) {
 		}
 	})
 }
+
+func TestWriteFileCmdsChunksContent(t *testing.T) {
+	content := make([]byte, 2*writeChunkSize+1)
+	cmds := writeFileCmds(content, "/tmp/f", false)
+	if len(cmds) != 3 {
+		t.Fatalf("got %d commands, want 3", len(cmds))
+	}
+	if !strings.HasSuffix(cmds[0], "> '/tmp/f'") || strings.Contains(cmds[0], ">>") {
+		t.Errorf("first command should truncate: %s", cmds[0][len(cmds[0])-20:])
+	}
+	for _, cmd := range cmds[1:] {
+		if !strings.HasSuffix(cmd, ">> '/tmp/f'") {
+			t.Errorf("later commands should append: %s", cmd[len(cmd)-20:])
+		}
+	}
+
+	if cmds := writeFileCmds(nil, "/tmp/f", false); len(cmds) != 1 {
+		t.Errorf("empty content: got %d commands, want 1", len(cmds))
+	}
+	if cmds := writeFileCmds([]byte("x"), "/tmp/f", true); !strings.HasSuffix(cmds[0], ">> '/tmp/f'") {
+		t.Errorf("append command = %s", cmds[0])
+	}
+}
+
+func TestParseSha256(t *testing.T) {
+	sum := strings.Repeat("a", 64)
+	got, err := parseSha256(sum + "  -\n")
+	if err != nil || got != sum {
+		t.Errorf("parseSha256() = %q, %v", got, err)
+	}
+	if _, err := parseSha256(""); err == nil {
+		t.Error("parseSha256(\"\") expected error")
+	}
+}
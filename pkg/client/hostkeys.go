@@ -0,0 +1,83 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ParseHostKey parses a host public key in authorized_keys format
+// (e.g. "ssh-ed25519 AAAA...").
+func ParseHostKey(authorizedKey string) (ssh.PublicKey, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host key %q: %w", authorizedKey, err)
+	}
+	return key, nil
+}
+
+// KnownHostsLine formats a known_hosts entry for a host key of host:port.
+func KnownHostsLine(host, port, authorizedKey string) (string, error) {
+	key, err := ParseHostKey(authorizedKey)
+	if err != nil {
+		return "", err
+	}
+	return knownhosts.Line([]string{knownhosts.Normalize(net.JoinHostPort(host, port))}, key), nil
+}
+
+// hostKeyCallback returns a callback accepting only the given host keys, and
+// the host key algorithms to negotiate so the server presents one of them.
+// Without keys, host keys are not verified.
+func hostKeyCallback(hostKeys []string) (ssh.HostKeyCallback, []string, error) {
+	if len(hostKeys) == 0 {
+		return ssh.InsecureIgnoreHostKey(), nil, nil // For testing
+	}
+
+	keys := make([]ssh.PublicKey, 0, len(hostKeys))
+	var algorithms []string
+	for _, hk := range hostKeys {
+		key, err := ParseHostKey(hk)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		algorithms = append(algorithms, hostKeyAlgorithmsFor(key.Type())...)
+	}
+
+	callback := func(hostname string, _ net.Addr, presented ssh.PublicKey) error {
+		for _, key := range keys {
+			if bytes.Equal(key.Marshal(), presented.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key mismatch for %s: got %s, expected one of the recorded host keys",
+			hostname, ssh.FingerprintSHA256(presented))
+	}
+	return callback, algorithms, nil
+}
+
+// hostKeyAlgorithmsFor returns the signature algorithms usable with a host
+// key type. RSA keys are negotiated with SHA-2 signatures first.
+func hostKeyAlgorithmsFor(keyType string) []string {
+	if keyType == ssh.KeyAlgoRSA {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
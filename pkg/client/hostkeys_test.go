@@ -0,0 +1,114 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newHostKey returns a fresh ed25519 public key and its authorized_keys form.
+func newHostKey(t *testing.T) (ssh.PublicKey, string) {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	return key, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+}
+
+func TestHostKeyCallbackVerifiesRecordedKeys(t *testing.T) {
+	recorded, authorized := newHostKey(t)
+	other, _ := newHostKey(t)
+
+	callback, algorithms, err := hostKeyCallback([]string{authorized})
+	if err != nil {
+		t.Fatalf("hostKeyCallback failed: %v", err)
+	}
+	if len(algorithms) != 1 || algorithms[0] != ssh.KeyAlgoED25519 {
+		t.Errorf("algorithms = %v, want [%s]", algorithms, ssh.KeyAlgoED25519)
+	}
+	if err := callback("10.0.0.2:22", nil, recorded); err != nil {
+		t.Errorf("recorded key rejected: %v", err)
+	}
+	if err := callback("10.0.0.2:22", nil, other); err == nil {
+		t.Error("expected mismatching key to be rejected")
+	}
+}
+
+func TestHostKeyCallbackWithoutKeysIsInsecure(t *testing.T) {
+	other, _ := newHostKey(t)
+	callback, algorithms, err := hostKeyCallback(nil)
+	if err != nil {
+		t.Fatalf("hostKeyCallback failed: %v", err)
+	}
+	if algorithms != nil {
+		t.Errorf("algorithms = %v, want nil", algorithms)
+	}
+	if err := callback("10.0.0.2:22", nil, other); err != nil {
+		t.Errorf("expected any key to be accepted, got %v", err)
+	}
+}
+
+func TestHostKeyCallbackInvalidKey(t *testing.T) {
+	if _, _, err := hostKeyCallback([]string{"not a key"}); err == nil {
+		t.Error("expected error for invalid host key")
+	}
+}
+
+func TestHostKeyAlgorithmsForRSA(t *testing.T) {
+	got := hostKeyAlgorithmsFor(ssh.KeyAlgoRSA)
+	want := []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("hostKeyAlgorithmsFor(ssh-rsa) = %v, want %v", got, want)
+	}
+}
+
+func TestKnownHostsLine(t *testing.T) {
+	_, authorized := newHostKey(t)
+
+	line, err := KnownHostsLine("10.0.0.2", "22", authorized)
+	if err != nil {
+		t.Fatalf("KnownHostsLine failed: %v", err)
+	}
+	if line != "10.0.0.2 "+authorized {
+		t.Errorf("line = %q, want %q", line, "10.0.0.2 "+authorized)
+	}
+
+	line, err = KnownHostsLine("10.0.0.2", "2222", authorized)
+	if err != nil {
+		t.Fatalf("KnownHostsLine failed: %v", err)
+	}
+	if !strings.HasPrefix(line, "[10.0.0.2]:2222 ") {
+		t.Errorf("line = %q, want [10.0.0.2]:2222 prefix", line)
+	}
+}
+
+func TestVMInfoValidateRejectsInvalidHostKey(t *testing.T) {
+	v := &VMInfo{Host: "10.0.0.2", Port: "22", User: "root", PrivateKey: []byte("key"), HostKeys: []string{"bogus"}}
+	if err := v.Validate(); err == nil {
+		t.Error("expected error for invalid host key")
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrProcessGone is returned by Process.Wait when the process ended without
+// recording an exit status, typically because it was killed by a signal.
+var ErrProcessGone = errors.New("process ended without exit status")
+
+// processPollInterval is how often Process.Wait checks the process.
+var processPollInterval = time.Second
+
+// processRunning is printed by the status command while the process runs.
+const processRunning = "running"
+
+// Process is a command started in the background on a VM by
+// StartBackground. Its state lives in a directory on the VM holding its
+// pid, combined output and exit status, so it survives SSH disconnects.
+type Process struct {
+	client  *Client
+	execCtx *ExecutionContext
+	pid     int
+	dir     string
+}
+
+// StartBackground starts a command in the background with the default
+// execution context and returns without waiting for it. The command runs
+// in its own session with nohup, so it outlives the SSH connection, and
+// its stdout and stderr are captured for Logs.
+func (c *Client) StartBackground(ctx context.Context, cmd ...string) (*Process, error) {
+	return c.StartBackgroundWithContext(ctx, c.defaultExecCtx, cmd...)
+}
+
+// StartBackgroundWithContext is StartBackground with a custom execution
+// context. Signal runs kill with the same privilege escalation.
+func (c *Client) StartBackgroundWithContext(ctx context.Context, execCtx *ExecutionContext, cmd ...string) (*Process, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("client: StartBackground requires a command")
+	}
+	vmInfo, err := c.getVMInfo()
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to get VM info: %w", err)
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, fmt.Errorf("client: failed to name process directory: %w", err)
+	}
+	p := &Process{client: c, execCtx: execCtx, dir: "/tmp/testenv-vm-process-" + hex.EncodeToString(suffix)}
+
+	stdout, stderr, err := c.run(ctx, vmInfo, execCtx, p.startCmd(FormatCmd(execCtx, cmd...)))
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to start background process: %w (stderr: %s)", err, stderr)
+	}
+	p.pid, err = strconv.Atoi(strings.TrimSpace(stdout))
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to read pid of background process: %q", stdout)
+	}
+	return p, nil
+}
+
+// startCmd returns the command launching formatted in the background. The
+// shell running it records its exit status, even when formatted calls exit,
+// and renames the file so it is never read half-written; setsid makes it the leader of
+// a process group, so signals reach its children too. The privilege
+// escalation password, if any, is read from the session and piped to the
+// background shell with printf, a shell builtin, so it is not in any
+// command line.
+func (p *Process) startCmd(formatted string) string {
+	script := fmt.Sprintf("(%s); echo $? > %s && mv %s %s", formatted,
+		quotePath(p.file("exit.tmp")), quotePath(p.file("exit.tmp")), quotePath(p.file("exit")))
+	start := fmt.Sprintf("setsid nohup sh -c %s > %s 2>&1 < /dev/null", quotePath(script), quotePath(p.file("log")))
+	if needsPassword(p.execCtx) {
+		start = fmt.Sprintf("IFS= read -r p; printf '%%s\\n' \"$p\" | setsid nohup sh -c %s > %s 2>&1",
+			quotePath(script), quotePath(p.file("log")))
+	}
+	return fmt.Sprintf("mkdir -p %s || exit 1; %s & echo $! > %s; echo $!",
+		quotePath(p.dir), start, quotePath(p.file("pid")))
+}
+
+// file returns the path of a state file of the process.
+func (p *Process) file(name string) string {
+	return path.Join(p.dir, name)
+}
+
+// PID returns the process ID on the VM.
+func (p *Process) PID() int {
+	return p.pid
+}
+
+// Dir returns the directory on the VM holding the pid, log and exit status
+// files of the process.
+func (p *Process) Dir() string {
+	return p.dir
+}
+
+// Signal sends a signal, e.g. "TERM", "INT" or "KILL", to the process and
+// its children.
+func (p *Process) Signal(ctx context.Context, signal string) error {
+	vmInfo, err := p.client.getVMInfo()
+	if err != nil {
+		return fmt.Errorf("client: failed to get VM info: %w", err)
+	}
+	_, stderr, err := p.client.run(ctx, vmInfo, p.execCtx, FormatCmd(p.execCtx, "kill", "-"+signal, "-"+strconv.Itoa(p.pid)))
+	if err != nil {
+		return fmt.Errorf("client: failed to send %s to process %d: %w (stderr: %s)", signal, p.pid, err, stderr)
+	}
+	return nil
+}
+
+// Logs returns the combined stdout and stderr of the process so far.
+func (p *Process) Logs(ctx context.Context) (string, error) {
+	stdout, stderr, err := p.run(ctx, "cat "+quotePath(p.file("log")))
+	if err != nil {
+		return "", fmt.Errorf("client: failed to read logs of process %d: %w (stderr: %s)", p.pid, err, stderr)
+	}
+	return stdout, nil
+}
+
+// Running reports whether the process is still running.
+func (p *Process) Running(ctx context.Context) (bool, error) {
+	status, err := p.status(ctx)
+	if err != nil {
+		return false, err
+	}
+	return status == processRunning, nil
+}
+
+// Wait blocks until the process exits. It returns nil if it exited with
+// status 0, an *ExitError for other statuses, and ErrProcessGone if it was
+// killed.
+func (p *Process) Wait(ctx context.Context) error {
+	for {
+		status, err := p.status(ctx)
+		if ctx.Err() != nil {
+			return fmt.Errorf("client: context cancelled while waiting for process %d: %w", p.pid, ctx.Err())
+		}
+		if err != nil {
+			return err
+		}
+		switch status {
+		case processRunning:
+		case "":
+			return fmt.Errorf("client: process %d: %w", p.pid, ErrProcessGone)
+		default:
+			code, err := strconv.Atoi(status)
+			if err != nil {
+				return fmt.Errorf("client: process %d: invalid exit status %q", p.pid, status)
+			}
+			if code != 0 {
+				return fmt.Errorf("client: process %d: %w", p.pid, &ExitError{Status: code})
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("client: context cancelled while waiting for process %d: %w", p.pid, ctx.Err())
+		case <-time.After(processPollInterval):
+		}
+	}
+}
+
+// Stop sends TERM to the process, then KILL if it still runs after grace.
+// It returns once the process is gone.
+func (p *Process) Stop(ctx context.Context, grace time.Duration) error {
+	if running, err := p.Running(ctx); err != nil || !running {
+		return err
+	}
+	if err := p.Signal(ctx, "TERM"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if running, err := p.Running(ctx); err != nil || !running {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("client: context cancelled while stopping process %d: %w", p.pid, ctx.Err())
+		case <-time.After(min(processPollInterval, time.Until(deadline))):
+		}
+	}
+	if running, err := p.Running(ctx); err != nil || !running {
+		return err
+	}
+	return p.Signal(ctx, "KILL")
+}
+
+// status returns the exit status of the process, processRunning, or "" when
+// it is gone without status. /proc is checked rather than kill -0, which
+// fails on processes of other users; zombies count as gone.
+func (p *Process) status(ctx context.Context) (string, error) {
+	cmd := fmt.Sprintf("cat %s 2>/dev/null || { test -d /proc/%d && ! grep -qs '^State:[[:space:]]*Z' /proc/%d/status && echo %s; } || true",
+		quotePath(p.file("exit")), p.pid, p.pid, processRunning)
+	stdout, stderr, err := p.run(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("client: failed to check process %d: %w (stderr: %s)", p.pid, err, stderr)
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// run runs an already formatted command on the VM of the process.
+func (p *Process) run(ctx context.Context, cmd string) (string, string, error) {
+	vmInfo, err := p.client.getVMInfo()
+	if err != nil {
+		return "", "", fmt.Errorf("client: failed to get VM info: %w", err)
+	}
+	return p.client.sshRunner.Run(ctx, vmInfo, cmd)
+}
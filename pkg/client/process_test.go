@@ -0,0 +1,167 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// localRunner is an SSHRunner running commands with the local shell, so the
+// shell scripts of background processes are exercised for real.
+type localRunner struct{}
+
+func (localRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	var stdout, stderr strings.Builder
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	err := c.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// removeProcessDir removes the state directory of a local process.
+func removeProcessDir(t *testing.T, p *Process) {
+	t.Cleanup(func() {
+		_ = os.RemoveAll(p.Dir())
+	})
+}
+
+func newLocalClient(t *testing.T) *Client {
+	t.Helper()
+	if _, err := exec.LookPath("setsid"); err != nil {
+		t.Skip("setsid not available")
+	}
+	processPollInterval = 10 * time.Millisecond
+	return newRetryClient(t, localRunner{})
+}
+
+func TestStartBackgroundWait(t *testing.T) {
+	c := newLocalClient(t)
+	ctx := context.Background()
+
+	p, err := c.StartBackground(ctx, "echo", "it's", "&&", "echo", "oops", ">&2", "&&", "exit", "3")
+	if err != nil {
+		t.Fatalf("StartBackground() error = %v", err)
+	}
+	removeProcessDir(t, p)
+	if p.PID() <= 0 {
+		t.Errorf("PID() = %d", p.PID())
+	}
+
+	err = p.Wait(ctx)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Status != 3 {
+		t.Fatalf("Wait() error = %v, want exit status 3", err)
+	}
+	logs, err := p.Logs(ctx)
+	if err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if !strings.Contains(logs, "it's") {
+		t.Errorf("Logs() = %q, want the output of the command", logs)
+	}
+	if running, err := p.Running(ctx); err != nil || running {
+		t.Errorf("Running() = %v, %v, want false", running, err)
+	}
+}
+
+func TestStartBackgroundStop(t *testing.T) {
+	c := newLocalClient(t)
+	ctx := context.Background()
+
+	p, err := c.StartBackground(ctx, "sleep", "60")
+	if err != nil {
+		t.Fatalf("StartBackground() error = %v", err)
+	}
+	removeProcessDir(t, p)
+	if running, err := p.Running(ctx); err != nil || !running {
+		t.Fatalf("Running() = %v, %v, want true", running, err)
+	}
+
+	if err := p.Stop(ctx, 5*time.Second); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if running, _ := p.Running(ctx); running {
+		t.Error("process still running after Stop()")
+	}
+	if err := p.Wait(ctx); !errors.Is(err, ErrProcessGone) {
+		t.Errorf("Wait() error = %v, want ErrProcessGone", err)
+	}
+}
+
+func TestStartBackgroundWaitCancelled(t *testing.T) {
+	c := newLocalClient(t)
+	p, err := c.StartBackground(context.Background(), "sleep", "60")
+	if err != nil {
+		t.Fatalf("StartBackground() error = %v", err)
+	}
+	removeProcessDir(t, p)
+	defer func() {
+		_ = p.Signal(context.Background(), "KILL")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestStartBackgroundCommand(t *testing.T) {
+	mock := NewMockSSHRunner()
+	mock.DefaultStdout = "4242\n"
+	provider := newTestProvider()
+	provider.AddVM("vm", validVMInfo())
+	c, err := NewClient(provider, "vm", WithSSHRunner(mock),
+		WithDefaultExecutionContext(NewExecutionContext().WithPrivilegeEscalation(PrivilegeEscalationSudo())))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	p, err := c.StartBackground(context.Background(), "nginx")
+	if err != nil {
+		t.Fatalf("StartBackground() error = %v", err)
+	}
+	if p.PID() != 4242 {
+		t.Errorf("PID() = %d, want 4242", p.PID())
+	}
+	if err := p.Signal(context.Background(), "HUP"); err != nil {
+		t.Fatalf("Signal() error = %v", err)
+	}
+
+	commands := mock.GetCommands()
+	if !strings.Contains(commands[0], `setsid nohup sh -c '("sudo" "nginx"); echo $? > `) {
+		t.Errorf("start command = %q", commands[0])
+	}
+	if commands[1] != `"sudo" "kill" "-HUP" "-4242"` {
+		t.Errorf("signal command = %q, want kill with sudo", commands[1])
+	}
+
+	if _, err := c.StartBackground(context.Background()); err == nil {
+		t.Error("StartBackground() without command expected error")
+	}
+	mock.DefaultStdout = "not a pid"
+	if _, err := c.StartBackground(context.Background(), "nginx"); err == nil {
+		t.Error("StartBackground() expected error for invalid pid")
+	}
+}
@@ -28,6 +28,8 @@ type ArtifactProvider struct {
 	artifact    *v1.TestEnvArtifact
 	defaultUser string // Default is "root" if WithDefaultUser not called
 	defaultPort string // Default is "22"
+	// verifyHostKeys requires recorded host keys for every VM.
+	verifyHostKeys bool
 }
 
 // Compile-time check that ArtifactProvider implements client.ClientProvider
@@ -52,6 +54,15 @@ func WithDefaultPort(port string) ArtifactProviderOption {
 	}
 }
 
+// WithHostKeyVerification makes returned VMInfos verify the VM's SSH host
+// key against the keys recorded in the artifact. GetVMInfo fails for VMs
+// without recorded host keys.
+func WithHostKeyVerification() ArtifactProviderOption {
+	return func(p *ArtifactProvider) {
+		p.verifyHostKeys = true
+	}
+}
+
 // NewArtifactProvider creates a provider from a TestEnvArtifact.
 // Default user is "root", default port is "22".
 func NewArtifactProvider(artifact *v1.TestEnvArtifact, opts ...ArtifactProviderOption) *ArtifactProvider {
@@ -73,7 +84,11 @@ func NewArtifactProvider(artifact *v1.TestEnvArtifact, opts ...ArtifactProviderO
 // 1. Look up IP from artifact.Metadata["testenv-vm.vm.<vmName>.ip"]
 // 2. Look up key path from artifact.Files["testenv-vm.key.<vmName>"] or first key
 // 3. Read private key from file
-// 4. Return VMInfo with user (default "root") and port (default "22")
+// 4. Parse the jump host from artifact.Metadata["testenv-vm.vm.<vmName>.proxyJump"], if any
+// 5. With host key verification, read host keys from
+// artifact.Metadata["testenv-vm.vm.<vmName>.hostKeys"] (one per line)
+// 6. Return VMInfo with user (default "root") and port (default "22", or
+// artifact.Metadata["testenv-vm.vm.<vmName>.sshPort"] when recorded)
 func (p *ArtifactProvider) GetVMInfo(vmName string) (*client.VMInfo, error) {
 	// Step 1: Look up IP from metadata
 	ipKey := fmt.Sprintf("testenv-vm.vm.%s.ip", vmName)
@@ -106,11 +121,38 @@ func (p *ArtifactProvider) GetVMInfo(vmName string) (*client.VMInfo, error) {
 		return nil, fmt.Errorf("artifact provider: failed to read SSH key from %s: %w", keyPath, err)
 	}
 
-	// Step 4: Return VMInfo
+	// Step 4: Parse the optional jump host from metadata
+	proxyJump, err := client.ParseProxyJump(
+		p.artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.proxyJump", vmName)], p.defaultUser, keyContent)
+	if err != nil {
+		return nil, fmt.Errorf("artifact provider: VM %q: %w", vmName, err)
+	}
+
+	// Step 5: Look up recorded host keys when verification is enabled
+	var hostKeys []string
+	if p.verifyHostKeys {
+		hostKeysKey := fmt.Sprintf("testenv-vm.vm.%s.hostKeys", vmName)
+		for _, line := range strings.Split(p.artifact.Metadata[hostKeysKey], "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				hostKeys = append(hostKeys, line)
+			}
+		}
+		if len(hostKeys) == 0 {
+			return nil, fmt.Errorf("artifact provider: no host keys recorded for VM %q (key: %s)", vmName, hostKeysKey)
+		}
+	}
+
+	// Step 6: Return VMInfo, on the port recorded for VMs not reached on 22
+	port := p.defaultPort
+	if recorded := p.artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.sshPort", vmName)]; recorded != "" {
+		port = recorded
+	}
 	return &client.VMInfo{
 		Host:       ip,
-		Port:       p.defaultPort,
+		Port:       port,
 		User:       p.defaultUser,
 		PrivateKey: keyContent,
+		ProxyJump:  proxyJump,
+		HostKeys:   hostKeys,
 	}, nil
 }
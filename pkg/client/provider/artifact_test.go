@@ -246,3 +246,77 @@ func TestGetVMInfoErrorUnreadableKeyFile(t *testing.T) {
 		t.Fatal("expected error for unreadable key file, got nil")
 	}
 }
+
+// TestGetVMInfoProxyJump verifies the jump host is read from metadata
+func TestGetVMInfoProxyJump(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "test-key")
+	if err := os.WriteFile(keyPath, []byte("test-private-key-content"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+
+	artifact := &v1.TestEnvArtifact{
+		Metadata: map[string]string{
+			"testenv-vm.vm.test-vm.ip":        "10.0.0.2",
+			"testenv-vm.vm.test-vm.proxyJump": "admin@192.168.100.5",
+		},
+		Files: map[string]string{
+			"testenv-vm.key.test-key": keyPath,
+		},
+	}
+
+	vmInfo, err := NewArtifactProvider(artifact).GetVMInfo("test-vm")
+	if err != nil {
+		t.Fatalf("GetVMInfo failed: %v", err)
+	}
+	if vmInfo.ProxyJump == nil {
+		t.Fatal("expected ProxyJump to be set")
+	}
+	if vmInfo.ProxyJump.Host != "192.168.100.5" || vmInfo.ProxyJump.Port != "22" || vmInfo.ProxyJump.User != "admin" {
+		t.Errorf("unexpected ProxyJump: %+v", vmInfo.ProxyJump)
+	}
+	if string(vmInfo.ProxyJump.PrivateKey) != "test-private-key-content" {
+		t.Error("expected ProxyJump to reuse the VM private key")
+	}
+}
+
+// TestGetVMInfoHostKeyVerification verifies recorded host keys are returned
+// and required when verification is enabled
+func TestGetVMInfoHostKeyVerification(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "test-key")
+	if err := os.WriteFile(keyPath, []byte("test-private-key-content"), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	hostKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+
+	artifact := &v1.TestEnvArtifact{
+		Metadata: map[string]string{
+			"testenv-vm.vm.web.ip":       "192.168.100.10",
+			"testenv-vm.vm.web.hostKeys": hostKey + "\n",
+			"testenv-vm.vm.db.ip":        "192.168.100.11",
+		},
+		Files: map[string]string{
+			"testenv-vm.key.test-key": keyPath,
+		},
+	}
+
+	vmInfo, err := NewArtifactProvider(artifact).GetVMInfo("web")
+	if err != nil {
+		t.Fatalf("GetVMInfo failed: %v", err)
+	}
+	if len(vmInfo.HostKeys) != 0 {
+		t.Errorf("expected no host keys without verification, got %v", vmInfo.HostKeys)
+	}
+
+	p := NewArtifactProvider(artifact, WithHostKeyVerification())
+	vmInfo, err = p.GetVMInfo("web")
+	if err != nil {
+		t.Fatalf("GetVMInfo failed: %v", err)
+	}
+	if len(vmInfo.HostKeys) != 1 || vmInfo.HostKeys[0] != hostKey {
+		t.Errorf("expected host keys [%s], got %v", hostKey, vmInfo.HostKeys)
+	}
+
+	if _, err := p.GetVMInfo("db"); err == nil {
+		t.Error("expected error for VM without recorded host keys")
+	}
+}
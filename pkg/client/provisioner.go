@@ -26,6 +26,7 @@ import (
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
 )
@@ -160,11 +161,19 @@ func (rp *RuntimeProvisioner) GetVMInfo(vmName string) (*VMInfo, error) {
 		return nil, fmt.Errorf("RuntimeProvisioner: VM %q failed to read private key from %q: %w", vmName, privateKeyPath, err)
 	}
 
+	// Extract the optional jump host (stored when the VM declares one)
+	proxyJumpValue, _ := state["proxyJump"].(string)
+	proxyJump, err := ParseProxyJump(proxyJumpValue, sshUser, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("RuntimeProvisioner: VM %q: %w", vmName, err)
+	}
+
 	return &VMInfo{
 		Host:       ip,
 		Port:       "22",
 		User:       sshUser,
 		PrivateKey: privateKey,
+		ProxyJump:  proxyJump,
 	}, nil
 }
 
@@ -285,10 +294,27 @@ func convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 				Timeout:    spec.Readiness.Ssh.Timeout,
 				User:       spec.Readiness.Ssh.User,
 				PrivateKey: spec.Readiness.Ssh.PrivateKey,
+				ProxyJump:  spec.Readiness.Ssh.ProxyJump,
 			},
 		}
 	}
 
+	if spec.Security != nil {
+		result.Security = &providerv1.SecuritySpec{
+			Disabled: spec.Security.Disabled,
+			Model:    spec.Security.Model,
+			Label:    spec.Security.Label,
+			Relabel:  spec.Security.Relabel,
+		}
+	}
+
+	if spec.Disk.Encryption != nil {
+		result.Disk.Encryption = &providerv1.DiskEncryptionSpec{
+			Enabled:             spec.Disk.Encryption.Enabled,
+			PassphraseSecretRef: spec.Disk.Encryption.PassphraseSecretRef,
+		}
+	}
+
 	return result
 }
 
@@ -339,6 +365,7 @@ func (rp *RuntimeProvisioner) CreateVM(ctx context.Context, name string, vmSpec
 		Spec:         convertVMSpec(renderedSpec),
 		ProviderSpec: nil, // Runtime VMs don't support ProviderSpec
 	}
+	seed.Apply(rp.envState.Seed, name, &request.Spec)
 
 	result, err := rp.manager.Call(rp.defaultProv, "vm_create", request)
 
@@ -413,6 +440,9 @@ func (rp *RuntimeProvisioner) CreateVM(ctx context.Context, name string, vmSpec
 	}
 	resourceState["sshUser"] = sshUser
 	resourceState["privateKeyPath"] = privateKeyPath
+	if renderedSpec.Readiness.Ssh.ProxyJump != "" {
+		resourceState["proxyJump"] = renderedSpec.Readiness.Ssh.ProxyJump
+	}
 
 	// Update state with success
 	now := time.Now().UTC().Format(time.RFC3339)
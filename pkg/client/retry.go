@@ -0,0 +1,173 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Stderr patterns of common transient guest failures, for
+// RetryPolicy.StderrPatterns.
+var (
+	// AptLockPatterns match apt and dpkg failing because another process,
+	// typically cloud-init or unattended-upgrades, holds their lock.
+	AptLockPatterns = []string{
+		`Could not get lock`,
+		`Unable to acquire the dpkg frontend lock`,
+		`dpkg was interrupted`,
+	}
+	// DNSFailurePatterns match name resolution failures of a network that
+	// is not ready yet.
+	DNSFailurePatterns = []string{
+		`Temporary failure in name resolution`,
+		`Could not resolve host`,
+		`Name or service not known`,
+	}
+)
+
+// RetryPolicy decides which failed commands RunWithRetry runs again, and
+// how long it waits in between. The zero value retries nothing.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of runs, including the first one.
+	// Defaults to 3.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second run. Defaults to 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between runs. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Multiplier grows the wait after each run. Defaults to 2.
+	Multiplier float64
+	// ExitCodes are the exit statuses of the remote command to retry on.
+	ExitCodes []int
+	// StderrPatterns are regular expressions matched against the stderr of
+	// failed runs; a match is retried whatever the exit status.
+	StderrPatterns []string
+	// RetryConnectionErrors retries failures without an exit status, such
+	// as SSH connection errors.
+	RetryConnectionErrors bool
+}
+
+// DefaultRetryPolicy returns a policy retrying apt lock contention, DNS
+// failures and SSH connection errors up to 5 times.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:           5,
+		StderrPatterns:        slices.Concat(AptLockPatterns, DNSFailurePatterns),
+		RetryConnectionErrors: true,
+	}
+}
+
+// ExitError is the error of a remote command that exited with a non-zero
+// status. SSH runners other than the native one, such as mocks, may return
+// it so that ExitCode and RetryPolicy.ExitCodes see the status.
+type ExitError struct {
+	Status int
+}
+
+// Error implements error.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exited with status %d", e.Status)
+}
+
+// ExitStatus returns the exit status of the command.
+func (e *ExitError) ExitStatus() int {
+	return e.Status
+}
+
+// ExitCode returns the exit status of the remote command that caused err.
+// It returns false for other errors, e.g. when the VM was not reachable.
+func ExitCode(err error) (int, bool) {
+	var status interface{ ExitStatus() int }
+	if errors.As(err, &status) {
+		return status.ExitStatus(), true
+	}
+	var code interface{ ExitCode() int }
+	if errors.As(err, &code) {
+		return code.ExitCode(), true
+	}
+	return 0, false
+}
+
+// RunWithRetry runs a command like Run, and runs it again with exponential
+// backoff while it fails in a way the policy retries. It returns the output
+// of the last run. The error of the last run is wrapped with the number of
+// attempts when all of them failed.
+func (c *Client) RunWithRetry(ctx context.Context, policy RetryPolicy, cmd ...string) (stdout, stderr string, err error) {
+	patterns := make([]*regexp.Regexp, 0, len(policy.StderrPatterns))
+	for _, p := range policy.StderrPatterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return "", "", fmt.Errorf("client: invalid retry pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+	policy = policy.withDefaults()
+
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		stdout, stderr, err = c.Run(ctx, cmd...)
+		if err == nil || !policy.retryable(err, stderr, patterns) {
+			return stdout, stderr, err
+		}
+		if attempt >= policy.MaxAttempts {
+			return stdout, stderr, fmt.Errorf("client: command failed after %d attempts: %w", attempt, err)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return stdout, stderr, fmt.Errorf("client: context cancelled while retrying: %w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		backoff = min(time.Duration(float64(backoff)*policy.Multiplier), policy.MaxBackoff)
+	}
+}
+
+// withDefaults returns the policy with unset fields defaulted.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = time.Second
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	return p
+}
+
+// retryable reports whether a failed run is retried.
+func (p RetryPolicy) retryable(err error, stderr string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(stderr) {
+			return true
+		}
+	}
+	code, ok := ExitCode(err)
+	if !ok {
+		return p.RetryConnectionErrors
+	}
+	return slices.Contains(p.ExitCodes, code)
+}
@@ -0,0 +1,197 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedRunner is an SSHRunner returning one response per call, then the
+// last one.
+type scriptedRunner struct {
+	responses []MockResponse
+	calls     int
+}
+
+func (r *scriptedRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	resp := r.responses[min(r.calls, len(r.responses)-1)]
+	r.calls++
+	return resp.Stdout, resp.Stderr, resp.Err
+}
+
+func newRetryClient(t *testing.T, runner SSHRunner) *Client {
+	t.Helper()
+	provider := newTestProvider()
+	provider.AddVM("vm", validVMInfo())
+	c, err := NewClient(provider, "vm", WithSSHRunner(runner))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestExitCode(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 7").Run()
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantOK   bool
+	}{
+		{name: "exit error", err: &ExitError{Status: 100}, wantCode: 100, wantOK: true},
+		{name: "wrapped", err: fmt.Errorf("remote command failed: %w", &ExitError{Status: 2}), wantCode: 2, wantOK: true},
+		{name: "exec exit error", err: fmt.Errorf("remote command failed: %w", exitErr), wantCode: 7, wantOK: true},
+		{name: "connection error", err: errors.New("unable to connect"), wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := ExitCode(tt.err)
+			if code != tt.wantCode || ok != tt.wantOK {
+				t.Errorf("ExitCode() = %d, %v, want %d, %v", code, ok, tt.wantCode, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRunWithRetry(t *testing.T) {
+	aptLocked := MockResponse{Stderr: "E: Could not get lock /var/lib/dpkg/lock-frontend", Err: &ExitError{Status: 100}}
+	exit1 := MockResponse{Err: &ExitError{Status: 1}}
+	unreachable := MockResponse{Err: errors.New("unable to connect")}
+	ok := MockResponse{Stdout: "done"}
+	fast := RetryPolicy{InitialBackoff: time.Millisecond, MaxAttempts: 3}
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		responses []MockResponse
+		wantCalls int
+		wantErr   string
+	}{
+		{
+			name:      "success needs no retry",
+			policy:    fast,
+			responses: []MockResponse{ok},
+			wantCalls: 1,
+		},
+		{
+			name:      "stderr pattern is retried",
+			policy:    withPatterns(fast, AptLockPatterns),
+			responses: []MockResponse{aptLocked, aptLocked, ok},
+			wantCalls: 3,
+		},
+		{
+			name:      "exit code is retried",
+			policy:    withExitCodes(fast, 1),
+			responses: []MockResponse{exit1, ok},
+			wantCalls: 2,
+		},
+		{
+			name:      "other exit codes are not retried",
+			policy:    withExitCodes(fast, 2),
+			responses: []MockResponse{exit1, ok},
+			wantCalls: 1,
+			wantErr:   "exited with status 1",
+		},
+		{
+			name:      "connection errors are retried when enabled",
+			policy:    RetryPolicy{InitialBackoff: time.Millisecond, RetryConnectionErrors: true},
+			responses: []MockResponse{unreachable, ok},
+			wantCalls: 2,
+		},
+		{
+			name:      "connection errors are not retried by default",
+			policy:    fast,
+			responses: []MockResponse{unreachable, ok},
+			wantCalls: 1,
+			wantErr:   "unable to connect",
+		},
+		{
+			name:      "attempts are bounded",
+			policy:    withPatterns(fast, AptLockPatterns),
+			responses: []MockResponse{aptLocked},
+			wantCalls: 3,
+			wantErr:   "after 3 attempts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &scriptedRunner{responses: tt.responses}
+			stdout, _, err := newRetryClient(t, runner).RunWithRetry(context.Background(), tt.policy, "apt-get", "install", "-y", "curl")
+			if runner.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", runner.calls, tt.wantCalls)
+			}
+			if tt.wantErr == "" {
+				if err != nil || stdout != "done" {
+					t.Errorf("RunWithRetry() = %q, %v, want done", stdout, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RunWithRetry() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWithRetryStopsOnContextCancel(t *testing.T) {
+	runner := &scriptedRunner{responses: []MockResponse{{Err: &ExitError{Status: 1}}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	policy := RetryPolicy{ExitCodes: []int{1}, InitialBackoff: time.Hour, MaxAttempts: 10}
+	_, _, err := newRetryClient(t, runner).RunWithRetry(ctx, policy, "false")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunWithRetry() error = %v, want context.DeadlineExceeded", err)
+	}
+	if runner.calls != 1 {
+		t.Errorf("calls = %d, want 1", runner.calls)
+	}
+}
+
+func TestRunWithRetryInvalidPattern(t *testing.T) {
+	runner := &scriptedRunner{responses: []MockResponse{{}}}
+	_, _, err := newRetryClient(t, runner).RunWithRetry(context.Background(), RetryPolicy{StderrPatterns: []string{"("}}, "true")
+	if err == nil || runner.calls != 0 {
+		t.Errorf("RunWithRetry() error = %v after %d calls, want an error before running", err, runner.calls)
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}.withDefaults()
+	if p.MaxAttempts != 3 || p.Multiplier != 2 {
+		t.Errorf("withDefaults() = %+v", p)
+	}
+	if d := DefaultRetryPolicy(); d.MaxAttempts != 5 || !d.RetryConnectionErrors || len(d.StderrPatterns) != len(AptLockPatterns)+len(DNSFailurePatterns) {
+		t.Errorf("DefaultRetryPolicy() = %+v", d)
+	}
+}
+
+func withPatterns(p RetryPolicy, patterns []string) RetryPolicy {
+	p.StderrPatterns = patterns
+	return p
+}
+
+func withExitCodes(p RetryPolicy, codes ...int) RetryPolicy {
+	p.ExitCodes = codes
+	return p
+}
@@ -0,0 +1,147 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sync"
+)
+
+// Set runs commands on several VMs of an environment concurrently, e.g.
+// to restart a service on every worker.
+type Set struct {
+	names   []string
+	clients map[string]*Client
+}
+
+// SetResult is the outcome of a command on one VM of a Set.
+type SetResult struct {
+	VM     string
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// Selector selects VMs of a Set by name.
+type Selector func(vmName string) bool
+
+// SelectNames selects the VMs with the given names.
+func SelectNames(names ...string) Selector {
+	return func(vmName string) bool {
+		return slices.Contains(names, vmName)
+	}
+}
+
+// SelectGlob selects the VMs whose name matches a shell pattern, e.g.
+// "worker-*".
+func SelectGlob(pattern string) Selector {
+	return func(vmName string) bool {
+		ok, _ := path.Match(pattern, vmName)
+		return ok
+	}
+}
+
+// NewSet creates a Set of clients for the given VMs.
+func NewSet(provider ClientProvider, vmNames ...string) (*Set, error) {
+	return NewSetWithOptions(provider, vmNames)
+}
+
+// NewSetWithOptions creates a Set whose clients are created with opts.
+func NewSetWithOptions(provider ClientProvider, vmNames []string, opts ...ClientOption) (*Set, error) {
+	if len(vmNames) == 0 {
+		return nil, fmt.Errorf("client: set requires at least one VM")
+	}
+	s := &Set{clients: make(map[string]*Client, len(vmNames))}
+	for _, name := range vmNames {
+		if _, ok := s.clients[name]; ok {
+			return nil, fmt.Errorf("client: duplicate VM %q in set", name)
+		}
+		c, err := NewClient(provider, name, opts...)
+		if err != nil {
+			return nil, err
+		}
+		s.names = append(s.names, name)
+		s.clients[name] = c
+	}
+	return s, nil
+}
+
+// Names returns the VM names of the set, in creation order.
+func (s *Set) Names() []string {
+	return slices.Clone(s.names)
+}
+
+// Client returns the client of a VM of the set, or nil.
+func (s *Set) Client(vmName string) *Client {
+	return s.clients[vmName]
+}
+
+// RunAll runs a command on every VM of the set concurrently.
+// See RunOn.
+func (s *Set) RunAll(ctx context.Context, cmd ...string) ([]SetResult, error) {
+	return s.run(ctx, s.names, cmd)
+}
+
+// RunOn runs a command concurrently on the VMs matching selector. Results
+// are in set order, one per selected VM. The error joins the errors of the
+// VMs the command failed on; it is also returned when no VM is selected.
+func (s *Set) RunOn(ctx context.Context, selector Selector, cmd ...string) ([]SetResult, error) {
+	var names []string
+	for _, name := range s.names {
+		if selector(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("client: no VM of the set matches the selector")
+	}
+	return s.run(ctx, names, cmd)
+}
+
+// run runs cmd on the named VMs concurrently.
+func (s *Set) run(ctx context.Context, names []string, cmd []string) ([]SetResult, error) {
+	results := make([]SetResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			stdout, stderr, err := s.clients[name].Run(ctx, cmd...)
+			results[i] = SetResult{VM: name, Stdout: stdout, Stderr: stderr, Err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("vm %s: %w", r.VM, r.Err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// Close closes the clients of the set.
+func (s *Set) Close() error {
+	var errs []error
+	for _, name := range s.names {
+		errs = append(errs, s.clients[name].Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hostRunner is an SSHRunner answering with the host the command ran on,
+// and failing on the hosts in fail. It records the hosts running
+// concurrently.
+type hostRunner struct {
+	fail map[string]bool
+
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+}
+
+func (r *hostRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	r.mu.Lock()
+	r.running++
+	r.maxRunning = max(r.maxRunning, r.running)
+	r.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	r.mu.Lock()
+	r.running--
+	r.mu.Unlock()
+
+	if r.fail[vmInfo.Host] {
+		return "", "boom", &ExitError{Status: 1}
+	}
+	return vmInfo.Host + ": " + cmd, "", nil
+}
+
+func newTestSet(t *testing.T, runner SSHRunner, names ...string) *Set {
+	t.Helper()
+	provider := newTestProvider()
+	for _, name := range names {
+		info := validVMInfo()
+		info.Host = name
+		provider.AddVM(name, info)
+	}
+	s, err := NewSetWithOptions(provider, names, WithSSHRunner(runner))
+	if err != nil {
+		t.Fatalf("NewSetWithOptions() error = %v", err)
+	}
+	return s
+}
+
+func TestSetRunAll(t *testing.T) {
+	runner := &hostRunner{}
+	s := newTestSet(t, runner, "cp-0", "worker-0", "worker-1")
+
+	results, err := s.RunAll(context.Background(), "systemctl", "restart", "kubelet")
+	if err != nil {
+		t.Fatalf("RunAll() error = %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("RunAll() returned %d results, want 3", len(results))
+	}
+	for i, name := range s.Names() {
+		if results[i].VM != name || !strings.HasPrefix(results[i].Stdout, name+": ") {
+			t.Errorf("result %d = %+v, want the output of %s", i, results[i], name)
+		}
+	}
+	if runner.maxRunning != 3 {
+		t.Errorf("commands ran %d at a time, want 3", runner.maxRunning)
+	}
+}
+
+func TestSetRunOn(t *testing.T) {
+	runner := &hostRunner{fail: map[string]bool{"worker-1": true}}
+	s := newTestSet(t, runner, "cp-0", "worker-0", "worker-1")
+
+	results, err := s.RunOn(context.Background(), SelectGlob("worker-*"), "uptime")
+	if len(results) != 2 || results[0].VM != "worker-0" || results[1].VM != "worker-1" {
+		t.Fatalf("RunOn() results = %+v, want the workers", results)
+	}
+	if results[0].Err != nil || results[1].Err == nil || results[1].Stderr != "boom" {
+		t.Errorf("RunOn() results = %+v, want worker-1 to fail", results)
+	}
+	var exitErr *ExitError
+	if err == nil || !strings.Contains(err.Error(), "vm worker-1") || !errors.As(err, &exitErr) {
+		t.Errorf("RunOn() error = %v, want the error of worker-1", err)
+	}
+
+	results, err = s.RunOn(context.Background(), SelectNames("cp-0"), "uptime")
+	if err != nil || len(results) != 1 || results[0].VM != "cp-0" {
+		t.Errorf("RunOn(cp-0) = %+v, %v", results, err)
+	}
+	if _, err := s.RunOn(context.Background(), SelectNames("typo"), "uptime"); err == nil {
+		t.Error("RunOn() expected error when no VM matches")
+	}
+}
+
+func TestNewSetErrors(t *testing.T) {
+	provider := newTestProvider()
+	if _, err := NewSet(provider); err == nil {
+		t.Error("NewSet() without VMs expected error")
+	}
+	if _, err := NewSet(provider, "a", "a"); err == nil {
+		t.Error("NewSet() with duplicate VMs expected error")
+	}
+	if _, err := NewSet(provider, ""); err == nil {
+		t.Error("NewSet() with empty VM name expected error")
+	}
+	s, err := NewSet(provider, "a", "b")
+	if err != nil {
+		t.Fatalf("NewSet() error = %v", err)
+	}
+	if s.Client("a") == nil || s.Client("c") != nil {
+		t.Error("Client() returned unexpected clients")
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
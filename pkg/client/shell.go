@@ -0,0 +1,114 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ErrInteractiveUnsupported is returned by Shell and RunTTY when the SSH
+// runner of the client does not implement InteractiveRunner.
+var ErrInteractiveUnsupported = errors.New("SSH runner does not support interactive sessions")
+
+// Default pseudo-terminal settings, used when the input is not a terminal.
+const (
+	defaultTerm   = "xterm-256color"
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// PTY describes the pseudo-terminal allocated for an interactive session.
+type PTY struct {
+	// Term is the terminal type, e.g. "xterm-256color".
+	Term string
+	// Width and Height are the size of the terminal in characters.
+	Width  int
+	Height int
+}
+
+// InteractiveRunner is implemented by SSH runners able to run interactive
+// sessions in a pseudo-terminal. NewSSHRunner, NewExecSSHRunner and
+// NewMockSSHRunner implement it.
+type InteractiveRunner interface {
+	// RunInteractive runs cmd, or a login shell if cmd is empty, in a
+	// pseudo-terminal until it exits. The terminal output, stdout and stderr
+	// combined, is written to stdout.
+	RunInteractive(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader, stdout io.Writer, pty PTY) error
+}
+
+// Shell opens an interactive login shell on the VM, e.g. for debugging:
+//
+//	err := c.Shell(ctx, os.Stdin, os.Stdout)
+//
+// When stdin is a terminal, it is put in raw mode for the session and the
+// remote terminal gets its size and the TERM of the environment.
+func (c *Client) Shell(ctx context.Context, stdin io.Reader, stdout io.Writer) error {
+	return c.runInteractive(ctx, "", stdin, stdout)
+}
+
+// RunTTY runs a command in a pseudo-terminal with the default execution
+// context, for commands refusing to run without a TTY. Like Shell, it
+// reads stdin and writes the terminal output to stdout. Privilege
+// escalation with a password is not supported, since stdin belongs to the
+// terminal.
+func (c *Client) RunTTY(ctx context.Context, stdin io.Reader, stdout io.Writer, cmd ...string) error {
+	if len(cmd) == 0 {
+		return fmt.Errorf("client: RunTTY requires a command")
+	}
+	if needsPassword(c.defaultExecCtx) {
+		return fmt.Errorf("client: RunTTY does not support privilege escalation with a password")
+	}
+	return c.runInteractive(ctx, FormatCmd(c.defaultExecCtx, cmd...), stdin, stdout)
+}
+
+// runInteractive implements Shell and RunTTY.
+func (c *Client) runInteractive(ctx context.Context, cmd string, stdin io.Reader, stdout io.Writer) error {
+	runner, ok := c.sshRunner.(InteractiveRunner)
+	if !ok {
+		return fmt.Errorf("client: %w", ErrInteractiveUnsupported)
+	}
+	vmInfo, err := c.getVMInfo()
+	if err != nil {
+		return fmt.Errorf("client: failed to get VM info: %w", err)
+	}
+
+	pty := PTY{Term: os.Getenv("TERM"), Width: defaultWidth, Height: defaultHeight}
+	if pty.Term == "" || pty.Term == "dumb" {
+		pty.Term = defaultTerm
+	}
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		if width, height, err := term.GetSize(int(f.Fd())); err == nil {
+			pty.Width, pty.Height = width, height
+		}
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return fmt.Errorf("client: failed to set terminal to raw mode: %w", err)
+		}
+		defer func() {
+			_ = term.Restore(int(f.Fd()), state)
+		}()
+	}
+
+	if err := runner.RunInteractive(ctx, vmInfo, cmd, stdin, stdout, pty); err != nil {
+		return fmt.Errorf("client: interactive session failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestShellRunsLoginShell(t *testing.T) {
+	runner := NewMockSSHRunner()
+	runner.DefaultStdout = "$ "
+	runner.DefaultStderr = "bye\n"
+	c := newRetryClient(t, runner)
+
+	var out bytes.Buffer
+	if err := c.Shell(context.Background(), strings.NewReader("exit\n"), &out); err != nil {
+		t.Fatalf("Shell() error = %v", err)
+	}
+	if got := runner.GetCommands(); len(got) != 1 || got[0] != "" {
+		t.Errorf("commands = %q, want a login shell", got)
+	}
+	if len(runner.Inputs) != 1 || runner.Inputs[0] != "exit\n" {
+		t.Errorf("inputs = %q, want the input of the session", runner.Inputs)
+	}
+	if out.String() != "$ bye\n" {
+		t.Errorf("output = %q, want stdout and stderr", out.String())
+	}
+}
+
+func TestRunTTYFormatsCommand(t *testing.T) {
+	runner := NewMockSSHRunner()
+	provider := newTestProvider()
+	provider.AddVM("vm", validVMInfo())
+	c, err := NewClient(provider, "vm", WithSSHRunner(runner),
+		WithDefaultExecutionContext(NewExecutionContext().WithPrivilegeEscalation(PrivilegeEscalationSudo())))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RunTTY(context.Background(), nil, &bytes.Buffer{}, "journalctl", "-f"); err != nil {
+		t.Fatalf("RunTTY() error = %v", err)
+	}
+	if got := runner.GetCommands(); len(got) != 1 || got[0] != `"sudo" "journalctl" "-f"` {
+		t.Errorf("commands = %q", got)
+	}
+
+	if err := c.RunTTY(context.Background(), nil, &bytes.Buffer{}); err == nil {
+		t.Error("RunTTY() without a command expected error")
+	}
+}
+
+func TestRunTTYRejectsPassword(t *testing.T) {
+	runner := NewMockSSHRunner()
+	provider := newTestProvider()
+	provider.AddVM("vm", validVMInfo())
+	c, err := NewClient(provider, "vm", WithSSHRunner(runner),
+		WithDefaultExecutionContext(NewExecutionContext().WithPrivilegeEscalation(PrivilegeEscalationSuPassword("s3cret"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RunTTY(context.Background(), nil, &bytes.Buffer{}, "journalctl", "-f"); err == nil {
+		t.Error("RunTTY() with a password expected error")
+	}
+	if len(runner.GetCommands()) != 0 {
+		t.Errorf("commands = %q, want none", runner.GetCommands())
+	}
+}
+
+func TestShellReturnsSessionError(t *testing.T) {
+	runner := NewMockSSHRunner()
+	runner.DefaultErr = errors.New("exit status 1")
+	c := newRetryClient(t, runner)
+
+	err := c.Shell(context.Background(), nil, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "interactive session failed") {
+		t.Errorf("Shell() error = %v", err)
+	}
+}
+
+// plainRunner is an SSHRunner without interactive sessions.
+type plainRunner struct{}
+
+func (plainRunner) Run(context.Context, *VMInfo, string) (string, string, error) {
+	return "", "", nil
+}
+
+func TestShellRequiresInteractiveRunner(t *testing.T) {
+	c := newRetryClient(t, plainRunner{})
+	if err := c.Shell(context.Background(), nil, &bytes.Buffer{}); !errors.Is(err, ErrInteractiveUnsupported) {
+		t.Errorf("Shell() error = %v, want ErrInteractiveUnsupported", err)
+	}
+}
+
+func TestRunnersImplementInteractiveRunner(t *testing.T) {
+	var _ InteractiveRunner = (*sshRunner)(nil)
+	var _ InteractiveRunner = (*execSSHRunner)(nil)
+	var _ InteractiveRunner = (*MockSSHRunner)(nil)
+	var _ StdinRunner = (*sshRunner)(nil)
+	var _ StdinRunner = (*execSSHRunner)(nil)
+	var _ StdinRunner = (*MockSSHRunner)(nil)
+}
@@ -18,35 +18,84 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"regexp"
 	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
-// SSHRunner executes commands on a remote VM via SSH.
+// SSHRunner executes commands on a remote VM via SSH. It is the extension
+// point of Client for transports: this package provides a native runner
+// (NewSSHRunner), one running the OpenSSH client binary (NewExecSSHRunner)
+// and a mock for tests (NewMockSSHRunner); others are set with
+// WithSSHRunner.
+//
+// Implementations must be safe for concurrent use. When the remote command
+// fails, Run returns its stdout and stderr together with the error.
 type SSHRunner interface {
 	// Run executes a command on the remote VM.
 	// cmd is the already-formatted command string (from FormatCmd).
 	Run(ctx context.Context, vmInfo *VMInfo, cmd string) (stdout, stderr string, err error)
 }
 
+// StdinRunner is implemented by SSH runners able to write to the standard
+// input of a command. The client requires it to run commands with a
+// privilege escalation password. NewSSHRunner, NewExecSSHRunner and
+// NewMockSSHRunner implement it.
+type StdinRunner interface {
+	// RunWithStdin is Run with stdin as the standard input of the command.
+	RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (stdout, stderr string, err error)
+}
+
+// Connector is implemented by SSH runners able to run several commands over
+// one connection. The client uses it for operations running many commands,
+// such as Sync. NewSSHRunner implements it.
+type Connector interface {
+	// Connect opens a connection to the VM.
+	Connect(ctx context.Context, vmInfo *VMInfo) (Conn, error)
+}
+
+// Conn runs commands over one connection to a VM, whatever the vmInfo they
+// are given. It must be closed.
+type Conn interface {
+	SSHRunner
+	StdinRunner
+	Close() error
+}
+
 // MockResponse holds a mock response for a command.
 type MockResponse struct {
 	Stdout string
 	Stderr string
 	Err    error
+	// Latency delays the response, e.g. to exercise timeouts. The mock
+	// returns the context error if ctx is done first.
+	Latency time.Duration
+}
+
+// mockPattern is a response for the commands matching a regular expression.
+type mockPattern struct {
+	re       *regexp.Regexp
+	response MockResponse
 }
 
 // MockSSHRunner is a mock implementation of SSHRunner for testing.
+// Responses are looked up by exact command first, then by the regular
+// expressions of AddRegexpResponse in the order they were added.
 type MockSSHRunner struct {
 	mu            sync.Mutex
 	Commands      []string                // Records all commands executed
+	Inputs        []string                // Records the input of interactive sessions and RunWithStdin
 	Responses     map[string]MockResponse // Maps command patterns to responses
 	DefaultStdout string
 	DefaultStderr string
 	DefaultErr    error
+	// Latency delays every response without a latency of its own.
+	Latency  time.Duration
+	patterns []mockPattern
 }
 
 // NewMockSSHRunner creates a new MockSSHRunner with initialized maps.
@@ -60,18 +109,76 @@ func NewMockSSHRunner() *MockSSHRunner {
 // Run records the command and returns the configured response.
 func (m *MockSSHRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	// Record the command
 	m.Commands = append(m.Commands, cmd)
+	response := m.lookup(cmd)
+	m.mu.Unlock()
 
-	// Check for a matching response
-	if response, ok := m.Responses[cmd]; ok {
-		return response.Stdout, response.Stderr, response.Err
+	if response.Latency > 0 {
+		timer := time.NewTimer(response.Latency)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		case <-timer.C:
+		}
 	}
+	return response.Stdout, response.Stderr, response.Err
+}
 
-	// Return default response
-	return m.DefaultStdout, m.DefaultStderr, m.DefaultErr
+// RunWithStdin records the command and the whole input, then returns the
+// configured response.
+func (m *MockSSHRunner) RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (string, string, error) {
+	input, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", "", err
+	}
+	m.mu.Lock()
+	m.Inputs = append(m.Inputs, string(input))
+	m.mu.Unlock()
+	return m.Run(ctx, vmInfo, cmd)
+}
+
+// RunInteractive records the command, or "" for a shell, and the whole
+// input, then writes the stdout and stderr of the configured response to
+// stdout.
+func (m *MockSSHRunner) RunInteractive(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader, stdout io.Writer, pty PTY) error {
+	var input []byte
+	if stdin != nil {
+		var err error
+		if input, err = io.ReadAll(stdin); err != nil {
+			return err
+		}
+	}
+	stdoutStr, stderrStr, err := m.Run(ctx, vmInfo, cmd)
+	m.mu.Lock()
+	m.Inputs = append(m.Inputs, string(input))
+	m.mu.Unlock()
+	if _, werr := io.WriteString(stdout, stdoutStr+stderrStr); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// lookup returns the response for cmd. m.mu must be held.
+func (m *MockSSHRunner) lookup(cmd string) MockResponse {
+	response, ok := m.Responses[cmd]
+	if !ok {
+		for _, p := range m.patterns {
+			if p.re.MatchString(cmd) {
+				response, ok = p.response, true
+				break
+			}
+		}
+	}
+	if !ok {
+		// Return default response
+		response = MockResponse{Stdout: m.DefaultStdout, Stderr: m.DefaultStderr, Err: m.DefaultErr}
+	}
+	if response.Latency == 0 {
+		response.Latency = m.Latency
+	}
+	return response
 }
 
 // AddResponse adds a response for a specific command pattern.
@@ -81,6 +188,15 @@ func (m *MockSSHRunner) AddResponse(cmdPattern string, response MockResponse) {
 	m.Responses[cmdPattern] = response
 }
 
+// AddRegexpResponse adds a response for the commands matching a regular
+// expression. It panics if pattern does not compile.
+func (m *MockSSHRunner) AddRegexpResponse(pattern string, response MockResponse) {
+	re := regexp.MustCompile(pattern)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.patterns = append(m.patterns, mockPattern{re: re, response: response})
+}
+
 // GetCommands returns all commands that were executed.
 func (m *MockSSHRunner) GetCommands() []string {
 	m.mu.Lock()
@@ -92,15 +208,58 @@ func (m *MockSSHRunner) GetCommands() []string {
 	return commands
 }
 
+// CallCount returns the number of executed commands matching a regular
+// expression. It panics if pattern does not compile.
+func (m *MockSSHRunner) CallCount(pattern string) int {
+	re := regexp.MustCompile(pattern)
+	count := 0
+	for _, cmd := range m.GetCommands() {
+		if re.MatchString(cmd) {
+			count++
+		}
+	}
+	return count
+}
+
+// VerifyOrder checks that commands matching each regular expression were
+// executed in the given order. Other commands may run in between. It
+// returns an error naming the first pattern without a match after the
+// previous one, or a pattern that does not compile.
+func (m *MockSSHRunner) VerifyOrder(patterns ...string) error {
+	commands := m.GetCommands()
+	next := 0
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		found := false
+		for ; next < len(commands); next++ {
+			if re.MatchString(commands[next]) {
+				found = true
+				next++
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no command matching %q after the previous pattern (commands: %q)", pattern, commands)
+		}
+	}
+	return nil
+}
+
 // Reset clears recorded commands and responses.
 func (m *MockSSHRunner) Reset() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.Commands = make([]string, 0)
+	m.Inputs = nil
 	m.Responses = make(map[string]MockResponse)
+	m.patterns = nil
 	m.DefaultStdout = ""
 	m.DefaultStderr = ""
 	m.DefaultErr = nil
+	m.Latency = 0
 }
 
 // sshRunner is the default SSHRunner implementation using golang.org/x/crypto/ssh.
@@ -123,33 +282,54 @@ func NewSSHRunner(timeout time.Duration) SSHRunner {
 // Note: Context is used for timeout only, not per-command cancellation.
 // The SSH session will run to completion or until the context deadline.
 func (r *sshRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
-	// 1. Parse private key
-	signer, err := ssh.ParsePrivateKey(vmInfo.PrivateKey)
-	if err != nil {
-		return "", "", fmt.Errorf("unable to parse private key: %w", err)
-	}
-
-	// 2. Create SSH client config
-	config := &ssh.ClientConfig{
-		User: vmInfo.User,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // For testing
-		Timeout:         r.timeout,
-	}
+	return r.RunWithStdin(ctx, vmInfo, cmd, nil)
+}
 
-	// 3. Dial TCP connection
-	addr := net.JoinHostPort(vmInfo.Host, vmInfo.Port)
-	conn, err := ssh.Dial("tcp", addr, config)
+// RunWithStdin is Run with stdin as the standard input of the command.
+func (r *sshRunner) RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (string, string, error) {
+	// 1. Connect, through the jump host if any
+	conn, err := r.dial(vmInfo)
 	if err != nil {
-		return "", "", fmt.Errorf("unable to connect to %s: %w", addr, err)
+		return "", "", err
 	}
 	defer func() {
 		_ = conn.Close()
 	}()
+	return runSession(conn, cmd, stdin)
+}
+
+// Connect opens a connection to the VM, through the jump host if any.
+func (r *sshRunner) Connect(ctx context.Context, vmInfo *VMInfo) (Conn, error) {
+	conn, err := r.dial(vmInfo)
+	if err != nil {
+		return nil, err
+	}
+	return &sshConn{conn: conn}, nil
+}
+
+// sshConn is the Conn of sshRunner.
+type sshConn struct {
+	conn *ssh.Client
+}
+
+// Run executes a command in a new session of the connection.
+func (c *sshConn) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	return runSession(c.conn, cmd, nil)
+}
+
+// RunWithStdin is Run with stdin as the standard input of the command.
+func (c *sshConn) RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (string, string, error) {
+	return runSession(c.conn, cmd, stdin)
+}
+
+// Close closes the connection.
+func (c *sshConn) Close() error {
+	return c.conn.Close()
+}
 
-	// 4. Create session
+// runSession runs a command in a new session of conn.
+func runSession(conn *ssh.Client, cmd string, stdin io.Reader) (string, string, error) {
+	// 2. Create session
 	session, err := conn.NewSession()
 	if err != nil {
 		return "", "", fmt.Errorf("unable to create SSH session: %w", err)
@@ -158,15 +338,144 @@ func (r *sshRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string
 		_ = session.Close()
 	}()
 
-	// 5. Capture stdout/stderr
+	// 3. Capture stdout/stderr
 	var stdoutBuf, stderrBuf bytes.Buffer
+	session.Stdin = stdin
 	session.Stdout = &stdoutBuf
 	session.Stderr = &stderrBuf
 
-	// 6. Run command
+	// 4. Run command
 	if err := session.Run(cmd); err != nil {
 		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("remote command failed: %w", err)
 	}
 
 	return stdoutBuf.String(), stderrBuf.String(), nil
 }
+
+// RunInteractive runs cmd, or a login shell if cmd is empty, in a
+// pseudo-terminal. The session is closed when ctx is done.
+func (r *sshRunner) RunInteractive(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader, stdout io.Writer, pty PTY) error {
+	conn, err := r.dial(vmInfo)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return fmt.Errorf("unable to create SSH session: %w", err)
+	}
+	defer func() {
+		_ = session.Close()
+	}()
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty(pty.Term, pty.Height, pty.Width, modes); err != nil {
+		return fmt.Errorf("unable to allocate a pseudo-terminal: %w", err)
+	}
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stdout
+
+	if cmd == "" {
+		err = session.Shell()
+	} else {
+		err = session.Start(cmd)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to start interactive session: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Close()
+		case <-done:
+		}
+	}()
+	if err := session.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("remote command failed: %w", err)
+	}
+	return nil
+}
+
+// dial opens an SSH connection to the VM. When vmInfo.ProxyJump is set, the
+// connection is tunneled through the jump host, which is closed together with
+// the returned client.
+func (r *sshRunner) dial(vmInfo *VMInfo) (*ssh.Client, error) {
+	config, err := r.clientConfig(vmInfo.User, vmInfo.PrivateKey, vmInfo.HostKeys)
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(vmInfo.Host, vmInfo.Port)
+
+	if vmInfo.ProxyJump == nil {
+		conn, err := ssh.Dial("tcp", addr, config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to %s: %w", addr, err)
+		}
+		return conn, nil
+	}
+
+	jump := vmInfo.ProxyJump
+	jumpConfig, err := r.clientConfig(jump.User, jump.PrivateKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jump host: %w", err)
+	}
+	jumpAddr := net.JoinHostPort(jump.Host, jump.Port)
+	jumpConn, err := ssh.Dial("tcp", jumpAddr, jumpConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to jump host %s: %w", jumpAddr, err)
+	}
+
+	netConn, err := jumpConn.Dial("tcp", addr)
+	if err != nil {
+		_ = jumpConn.Close()
+		return nil, fmt.Errorf("unable to reach %s via jump host %s: %w", addr, jumpAddr, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if err != nil {
+		_ = netConn.Close()
+		_ = jumpConn.Close()
+		return nil, fmt.Errorf("unable to connect to %s via jump host %s: %w", addr, jumpAddr, err)
+	}
+	conn := ssh.NewClient(c, chans, reqs)
+	go func() {
+		_ = conn.Wait()
+		_ = jumpConn.Close()
+	}()
+	return conn, nil
+}
+
+// clientConfig builds an SSH client config for public key authentication.
+// The server must present one of hostKeys, unless hostKeys is empty.
+func (r *sshRunner) clientConfig(user string, privateKey []byte, hostKeys []string) (*ssh.ClientConfig, error) {
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+	callback, algorithms, err := hostKeyCallback(hostKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User: user,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeys(signer),
+		},
+		HostKeyCallback:   callback,
+		HostKeyAlgorithms: algorithms,
+		Timeout:           r.timeout,
+	}, nil
+}
@@ -18,10 +18,15 @@ package client
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
 	"errors"
 	"sync"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 // TestMockSSHRunnerImplementsInterface verifies MockSSHRunner implements SSHRunner interface.
@@ -441,6 +446,35 @@ func TestSSHRunnerInvalidKeyFormat(t *testing.T) {
 	}
 }
 
+func TestSSHRunnerJumpHostInvalidKeyFormat(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	vmInfo := &VMInfo{
+		Host:       "10.0.0.2",
+		Port:       "22",
+		User:       "test",
+		PrivateKey: pem.EncodeToMemory(block),
+		ProxyJump: &JumpHost{
+			Host:       "127.0.0.1",
+			Port:       "22",
+			User:       "test",
+			PrivateKey: []byte("invalid-key-format"),
+		},
+	}
+
+	_, _, err = NewSSHRunner(0).Run(context.Background(), vmInfo, "echo test")
+	if err == nil || !contains(err.Error(), "jump host: unable to parse private key") {
+		t.Errorf("expected jump host key error, got: %v", err)
+	}
+}
+
 // contains is a helper function to check if a string contains a substring.
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
@@ -455,3 +489,78 @@ func hasSubstring(s, substr string) bool {
 	}
 	return false
 }
+
+func TestMockSSHRunnerRegexpResponse(t *testing.T) {
+	mock := NewMockSSHRunner()
+	mock.DefaultStdout = "default"
+	mock.AddRegexpResponse(`^apt-get install .*nginx`, MockResponse{Stdout: "installed"})
+	mock.AddRegexpResponse(`^apt-get`, MockResponse{Stdout: "apt"})
+	mock.AddResponse("apt-get install -y nginx", MockResponse{Stdout: "exact"})
+	vmInfo := &VMInfo{Host: "127.0.0.1", Port: "22", User: "test", PrivateKey: []byte("test-key")}
+
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{cmd: "apt-get install -y nginx", want: "exact"},
+		{cmd: "apt-get install -q nginx-full", want: "installed"},
+		{cmd: "apt-get update", want: "apt"},
+		{cmd: "uname -a", want: "default"},
+	}
+	for _, tt := range tests {
+		stdout, _, _ := mock.Run(context.Background(), vmInfo, tt.cmd)
+		if stdout != tt.want {
+			t.Errorf("Run(%q) stdout = %q, want %q", tt.cmd, stdout, tt.want)
+		}
+	}
+
+	mock.Reset()
+	if stdout, _, _ := mock.Run(context.Background(), vmInfo, "apt-get update"); stdout != "" {
+		t.Errorf("stdout after Reset() = %q, want empty", stdout)
+	}
+}
+
+func TestMockSSHRunnerVerifyOrder(t *testing.T) {
+	mock := NewMockSSHRunner()
+	vmInfo := &VMInfo{Host: "127.0.0.1", Port: "22", User: "test", PrivateKey: []byte("test-key")}
+	for _, cmd := range []string{"apt-get update", "echo step", "apt-get install -y nginx", "systemctl start nginx"} {
+		_, _, _ = mock.Run(context.Background(), vmInfo, cmd)
+	}
+
+	if err := mock.VerifyOrder("update", "install", "systemctl start"); err != nil {
+		t.Errorf("VerifyOrder() error = %v", err)
+	}
+	if err := mock.VerifyOrder("install", "update"); err == nil {
+		t.Error("VerifyOrder() expected error for reversed order")
+	}
+	if err := mock.VerifyOrder("("); err == nil {
+		t.Error("VerifyOrder() expected error for invalid pattern")
+	}
+	if got := mock.CallCount("^apt-get"); got != 2 {
+		t.Errorf("CallCount() = %d, want 2", got)
+	}
+}
+
+func TestMockSSHRunnerLatency(t *testing.T) {
+	mock := NewMockSSHRunner()
+	mock.Latency = 20 * time.Millisecond
+	mock.AddResponse("slow", MockResponse{Stdout: "done", Latency: time.Hour})
+	vmInfo := &VMInfo{Host: "127.0.0.1", Port: "22", User: "test", PrivateKey: []byte("test-key")}
+
+	start := time.Now()
+	if _, _, err := mock.Run(context.Background(), vmInfo, "fast"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < mock.Latency {
+		t.Errorf("Run() took %v, want at least %v", elapsed, mock.Latency)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, _, err := mock.Run(ctx, vmInfo, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+	if got := mock.GetCommands(); len(got) != 2 {
+		t.Errorf("GetCommands() = %q, want both commands recorded", got)
+	}
+}
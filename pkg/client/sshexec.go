@@ -0,0 +1,213 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sshUnreachable is the exit status of the OpenSSH client when the
+// connection fails, as opposed to the exit status of the remote command.
+// Runs exiting with it fail with a connection error without exit status
+// (see ExitCode), even though the remote command may itself exit with 255.
+const sshUnreachable = 255
+
+// execSSHRunner is an SSHRunner running the OpenSSH client binary. Unlike
+// the native runner, it honors the ssh features of the host, e.g. GSSAPI or
+// hardware keys configured through extra arguments.
+type execSSHRunner struct {
+	binary  string
+	timeout time.Duration
+	args    []string
+}
+
+// ExecSSHOption configures the runner returned by NewExecSSHRunner.
+type ExecSSHOption func(*execSSHRunner)
+
+// WithSSHBinary sets the path of the ssh binary (default: "ssh" in PATH).
+func WithSSHBinary(path string) ExecSSHOption {
+	return func(r *execSSHRunner) {
+		r.binary = path
+	}
+}
+
+// WithSSHArgs appends arguments passed to ssh before the destination,
+// e.g. "-o", "Compression=yes".
+func WithSSHArgs(args ...string) ExecSSHOption {
+	return func(r *execSSHRunner) {
+		r.args = append(r.args, args...)
+	}
+}
+
+// NewExecSSHRunner creates an SSH runner executing the OpenSSH client with
+// the given connection timeout. If timeout is 0, defaults to 10 seconds.
+// The user's ssh configuration is ignored; keys and known hosts are written
+// to a temporary directory removed after each command.
+func NewExecSSHRunner(timeout time.Duration, opts ...ExecSSHOption) SSHRunner {
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	r := &execSSHRunner{binary: "ssh", timeout: timeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run executes a command on the remote VM via the ssh binary. The command
+// is killed when ctx is done.
+func (r *execSSHRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	return r.RunWithStdin(ctx, vmInfo, cmd, nil)
+}
+
+// RunWithStdin is Run with stdin as the standard input of the command.
+func (r *execSSHRunner) RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (string, string, error) {
+	dir, err := os.MkdirTemp("", "testenv-vm-ssh-")
+	if err != nil {
+		return "", "", fmt.Errorf("unable to create ssh directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	args, err := r.buildArgs(dir, vmInfo)
+	if err != nil {
+		return "", "", err
+	}
+	args = append(args, "--", cmd)
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	c := exec.CommandContext(ctx, r.binary, args...)
+	c.Stdin = stdin
+	c.Stdout = &stdoutBuf
+	c.Stderr = &stderrBuf
+	err = c.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return stdoutBuf.String(), stderrBuf.String(), nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sshUnreachable:
+		// Not wrapped, so that ExitCode does not take it for the status of
+		// the remote command
+		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("unable to connect to %s: %v: %s",
+			vmInfo.Host, err, strings.TrimSpace(stderrBuf.String()))
+	default:
+		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("remote command failed: %w", err)
+	}
+}
+
+// RunInteractive runs cmd, or a login shell if cmd is empty, with ssh -tt
+// so that a pseudo-terminal is allocated even when stdin is not a terminal.
+// ssh takes the terminal size from stdin when it is a terminal.
+func (r *execSSHRunner) RunInteractive(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader, stdout io.Writer, pty PTY) error {
+	dir, err := os.MkdirTemp("", "testenv-vm-ssh-")
+	if err != nil {
+		return fmt.Errorf("unable to create ssh directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	args, err := r.buildArgs(dir, vmInfo)
+	if err != nil {
+		return err
+	}
+	args = append([]string{"-tt"}, args...)
+	if cmd != "" {
+		args = append(args, "--", cmd)
+	}
+
+	c := exec.CommandContext(ctx, r.binary, args...)
+	c.Env = append(os.Environ(), "TERM="+pty.Term)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stdout
+	err = c.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sshUnreachable:
+		return fmt.Errorf("unable to connect to %s: %v", vmInfo.Host, err)
+	default:
+		return fmt.Errorf("remote command failed: %w", err)
+	}
+}
+
+// buildArgs writes the keys and known hosts of vmInfo to dir and returns the
+// ssh arguments up to the destination.
+func (r *execSSHRunner) buildArgs(dir string, vmInfo *VMInfo) ([]string, error) {
+	keyFile := filepath.Join(dir, "id")
+	if err := os.WriteFile(keyFile, vmInfo.PrivateKey, 0o600); err != nil {
+		return nil, fmt.Errorf("unable to write private key: %w", err)
+	}
+
+	args := []string{
+		"-F", "none",
+		"-i", keyFile,
+		"-p", vmInfo.Port,
+		"-l", vmInfo.User,
+		"-o", "BatchMode=yes",
+		"-o", "IdentitiesOnly=yes",
+		"-o", "LogLevel=ERROR",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", int(r.timeout.Seconds())),
+	}
+
+	// Without host keys, host keys are not verified, like the native runner.
+	if len(vmInfo.HostKeys) == 0 {
+		args = append(args, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+	} else {
+		var lines []string
+		for _, hk := range vmInfo.HostKeys {
+			line, err := KnownHostsLine(vmInfo.Host, vmInfo.Port, hk)
+			if err != nil {
+				return nil, err
+			}
+			lines = append(lines, line)
+		}
+		knownHosts := filepath.Join(dir, "known_hosts")
+		if err := os.WriteFile(knownHosts, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+			return nil, fmt.Errorf("unable to write known hosts: %w", err)
+		}
+		args = append(args, "-o", "StrictHostKeyChecking=yes", "-o", "UserKnownHostsFile="+knownHosts)
+	}
+
+	if jump := vmInfo.ProxyJump; jump != nil {
+		jumpKey := filepath.Join(dir, "jump")
+		if err := os.WriteFile(jumpKey, jump.PrivateKey, 0o600); err != nil {
+			return nil, fmt.Errorf("jump host: unable to write private key: %w", err)
+		}
+		// ProxyCommand rather than ProxyJump, which cannot take the key of
+		// the jump host without a config file.
+		proxy := fmt.Sprintf("%s -F none -i %s -p %s -l %s -o BatchMode=yes -o IdentitiesOnly=yes -o LogLevel=ERROR"+
+			" -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -W %%h:%%p %s",
+			r.binary, jumpKey, jump.Port, jump.User, jump.Host)
+		args = append(args, "-o", "ProxyCommand="+proxy)
+	}
+
+	args = append(args, r.args...)
+	return append(args, vmInfo.Host), nil
+}
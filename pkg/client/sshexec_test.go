@@ -0,0 +1,164 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSSH writes a script standing in for ssh: it prints its arguments one
+// per line, then the content of the known hosts file if any, and exits
+// with the given status.
+func fakeSSH(t *testing.T, exitCode int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ssh")
+	script := `#!/bin/sh
+for arg in "$@"; do
+  echo "$arg"
+  case "$arg" in
+    UserKnownHostsFile=/dev/null) ;;
+    UserKnownHostsFile=*) cat "${arg#UserKnownHostsFile=}" ;;
+  esac
+done
+echo "failure" >&2
+exit ` + strconv.Itoa(exitCode) + "\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write fake ssh: %v", err)
+	}
+	return path
+}
+
+func TestExecSSHRunnerImplementsInterface(t *testing.T) {
+	var _ SSHRunner = (*execSSHRunner)(nil)
+}
+
+func TestExecSSHRunnerArgs(t *testing.T) {
+	runner := NewExecSSHRunner(5*time.Second, WithSSHBinary(fakeSSH(t, 0)), WithSSHArgs("-o", "Compression=yes"))
+	vmInfo := &VMInfo{
+		Host:       "192.168.1.10",
+		Port:       "2222",
+		User:       "ubuntu",
+		PrivateKey: []byte("key"),
+		HostKeys:   []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"},
+		ProxyJump:  &JumpHost{Host: "bastion", Port: "22", User: "jump", PrivateKey: []byte("jump-key")},
+	}
+
+	stdout, _, err := runner.Run(context.Background(), vmInfo, `echo "hello"`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	joined := strings.Join(lines, " ")
+	for _, want := range []string{
+		"-p 2222",
+		"-l ubuntu",
+		"ConnectTimeout=5",
+		"StrictHostKeyChecking=yes",
+		"-o Compression=yes 192.168.1.10 -- echo \"hello\"",
+		"[192.168.1.10]:2222 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl",
+		"-l jump",
+		"-W %h:%p bastion",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("ssh arguments %q do not contain %q", lines, want)
+		}
+	}
+}
+
+func TestExecSSHRunnerWithoutHostKeys(t *testing.T) {
+	runner := NewExecSSHRunner(0, WithSSHBinary(fakeSSH(t, 0)))
+	vmInfo := &VMInfo{Host: "10.0.0.1", Port: "22", User: "test", PrivateKey: []byte("key")}
+
+	stdout, _, err := runner.Run(context.Background(), vmInfo, "true")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(stdout, "StrictHostKeyChecking=no") || !strings.Contains(stdout, "ConnectTimeout=10") {
+		t.Errorf("ssh arguments = %q, want host keys not verified and the default timeout", stdout)
+	}
+}
+
+func TestExecSSHRunnerErrors(t *testing.T) {
+	vmInfo := &VMInfo{Host: "10.0.0.1", Port: "22", User: "test", PrivateKey: []byte("key")}
+
+	tests := []struct {
+		name       string
+		exitCode   int
+		errSubstr  string
+		exitStatus bool
+	}{
+		{name: "remote command fails", exitCode: 3, errSubstr: "remote command failed", exitStatus: true},
+		{name: "connection fails", exitCode: sshUnreachable, errSubstr: "unable to connect to 10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewExecSSHRunner(0, WithSSHBinary(fakeSSH(t, tt.exitCode)))
+			stdout, stderr, err := runner.Run(context.Background(), vmInfo, "false")
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("Run() error = %v, want it to contain %q", err, tt.errSubstr)
+			}
+			if stdout == "" || strings.TrimSpace(stderr) != "failure" {
+				t.Errorf("Run() = %q, %q, want the output of the failed command", stdout, stderr)
+			}
+			if code, ok := ExitCode(err); ok != tt.exitStatus || (ok && code != tt.exitCode) {
+				t.Errorf("ExitCode(%v) = %d, %v, want %d, %v", err, code, ok, tt.exitCode, tt.exitStatus)
+			}
+		})
+	}
+}
+
+func TestExecSSHRunnerInvalidHostKey(t *testing.T) {
+	runner := NewExecSSHRunner(0, WithSSHBinary(fakeSSH(t, 0)))
+	vmInfo := &VMInfo{Host: "10.0.0.1", Port: "22", User: "test", PrivateKey: []byte("key"), HostKeys: []string{"garbage"}}
+	if _, _, err := runner.Run(context.Background(), vmInfo, "true"); err == nil || !strings.Contains(err.Error(), "invalid host key") {
+		t.Errorf("Run() error = %v, want invalid host key", err)
+	}
+}
+
+func TestExecSSHRunnerRunInteractive(t *testing.T) {
+	runner := NewExecSSHRunner(0, WithSSHBinary(fakeSSH(t, 0))).(InteractiveRunner)
+	vmInfo := &VMInfo{Host: "10.0.0.1", Port: "22", User: "test", PrivateKey: []byte("key")}
+
+	var out bytes.Buffer
+	if err := runner.RunInteractive(context.Background(), vmInfo, "", strings.NewReader(""), &out, PTY{Term: "vt100"}); err != nil {
+		t.Fatalf("RunInteractive() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if lines[0] != "-tt" || slices.Contains(lines, "--") {
+		t.Errorf("shell arguments = %q, want -tt and no command", lines)
+	}
+	// stderr is written to the same output.
+	if lines[len(lines)-1] != "failure" {
+		t.Errorf("output = %q, want stderr included", lines)
+	}
+
+	out.Reset()
+	if err := runner.RunInteractive(context.Background(), vmInfo, "top", nil, &out, PTY{Term: "vt100"}); err != nil {
+		t.Fatalf("RunInteractive() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "10.0.0.1\n--\ntop\n") {
+		t.Errorf("command arguments = %q, want the command after --", out.String())
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// syncDeleteBatch is the number of files removed by a single command.
+const syncDeleteBatch = 100
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// Delete removes remote files that do not exist locally.
+	Delete bool
+	// Exclude lists path.Match patterns of files and directories to skip.
+	// A pattern without a slash matches a base name at any depth, e.g.
+	// "*.log" or ".git"; a pattern with a slash matches the path relative to
+	// the synced directory, e.g. "build/*.o". Excluded remote files are
+	// never deleted.
+	Exclude []string
+}
+
+// SyncResult lists the files changed by Sync, as slash-separated paths
+// relative to the synced directory.
+type SyncResult struct {
+	// Transferred are the files that were missing or differed on the VM.
+	Transferred []string
+	// Deleted are the remote files removed because of SyncOptions.Delete.
+	Deleted []string
+	// Unchanged is the number of files whose checksum already matched.
+	Unchanged int
+}
+
+// Sync makes remoteDir on the VM match localDir, transferring only the
+// files whose SHA-256 checksum differs, e.g. to push a rebuilt binary
+// before rerunning a test. Transferred files get the permissions of the
+// local file and replace the remote one atomically, so running binaries
+// can be updated. Symlinks in localDir are followed; empty directories are
+// not created. All commands run over one SSH connection when the SSH runner
+// is a Connector.
+func (c *Client) Sync(ctx context.Context, localDir, remoteDir string, opts SyncOptions) (*SyncResult, error) {
+	for _, pattern := range opts.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("client: invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	local, err := localChecksums(localDir, opts.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to list %s: %w", localDir, err)
+	}
+	conn, disconnect, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer disconnect()
+	return conn.sync(ctx, local, remoteDir, opts)
+}
+
+// sync implements Sync once the local files are listed.
+func (c *Client) sync(ctx context.Context, local map[string]localFile, remoteDir string, opts SyncOptions) (*SyncResult, error) {
+	stdout, err := c.runShell(ctx, remoteChecksumsCmd(remoteDir))
+	if err != nil {
+		return nil, fmt.Errorf("client: failed to list remote directory %s: %w", remoteDir, err)
+	}
+	remote := parseChecksums(stdout)
+
+	result := &SyncResult{}
+	for _, rel := range slices.Sorted(maps.Keys(local)) {
+		if remote[rel] == local[rel].sum {
+			result.Unchanged++
+			continue
+		}
+		if err := c.syncFile(ctx, local[rel].path, path.Join(remoteDir, rel)); err != nil {
+			return result, err
+		}
+		result.Transferred = append(result.Transferred, rel)
+	}
+
+	if !opts.Delete {
+		return result, nil
+	}
+	var stale []string
+	for _, rel := range slices.Sorted(maps.Keys(remote)) {
+		if _, ok := local[rel]; !ok && !excluded(rel, opts.Exclude) {
+			stale = append(stale, rel)
+		}
+	}
+	for len(stale) > 0 {
+		batch := stale[:min(len(stale), syncDeleteBatch)]
+		stale = stale[len(batch):]
+		quoted := make([]string, len(batch))
+		for i, rel := range batch {
+			quoted[i] = quotePath(path.Join(remoteDir, rel))
+		}
+		if _, err := c.runShell(ctx, "rm -f -- "+strings.Join(quoted, " ")); err != nil {
+			return result, fmt.Errorf("client: failed to delete remote files: %w", err)
+		}
+		result.Deleted = append(result.Deleted, batch...)
+	}
+	return result, nil
+}
+
+// syncFile transfers a local file to remotePath through a temporary file
+// renamed over the destination: writing in place fails for a running
+// binary.
+func (c *Client) syncFile(ctx context.Context, localPath, remotePath string) error {
+	content, err := os.ReadFile(localPath)
+	if err != nil {
+		return fmt.Errorf("client: failed to read local file %s: %w", localPath, err)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("client: failed to stat local file %s: %w", localPath, err)
+	}
+	tmp := path.Join(path.Dir(remotePath), "."+path.Base(remotePath)+".sync")
+	if _, err := c.runShell(ctx, mkdirCmd(path.Dir(remotePath))); err != nil {
+		return fmt.Errorf("client: failed to create remote directory %s: %w", path.Dir(remotePath), err)
+	}
+	cmds := writeFileCmds(content, tmp, false)
+	cmds = append(cmds, fmt.Sprintf("%s && mv -f %s %s",
+		chmodCmd(tmp, fmt.Sprintf("%04o", info.Mode().Perm())), quotePath(tmp), quotePath(remotePath)))
+	for _, cmd := range cmds {
+		if _, err := c.runShell(ctx, cmd); err != nil {
+			return fmt.Errorf("client: failed to sync %s: %w", remotePath, err)
+		}
+	}
+	return nil
+}
+
+// localFile is a file of the local directory of Sync.
+type localFile struct {
+	path string
+	sum  string
+}
+
+// localChecksums returns the files of dir that are not excluded, by
+// slash-separated relative path.
+func localChecksums(dir string, exclude []string) (map[string]localFile, error) {
+	files := make(map[string]localFile)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if excluded(rel, exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			// Directories are walked; dangling symlinks, symlinks to
+			// directories and special files are skipped.
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		files[rel] = localFile{path: p, sum: hex.EncodeToString(sum[:])}
+		return nil
+	})
+	return files, err
+}
+
+// remoteChecksumsCmd generates the command listing the checksums of the
+// regular files of a remote directory, which is created if missing.
+func remoteChecksumsCmd(dir string) string {
+	return fmt.Sprintf("mkdir -p %s && cd %s && find . -type f -exec sha256sum {} +",
+		quotePath(dir), quotePath(dir))
+}
+
+// parseChecksums parses the output of remoteChecksumsCmd into checksums by
+// slash-separated relative path.
+func parseChecksums(stdout string) map[string]string {
+	sums := make(map[string]string)
+	for _, line := range strings.Split(stdout, "\n") {
+		// sha256sum escapes names containing a newline or a backslash,
+		// marking the line with a leading backslash.
+		escaped := strings.HasPrefix(line, `\`)
+		line = strings.TrimPrefix(line, `\`)
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok || len(sum) != 64 {
+			continue
+		}
+		if escaped {
+			name = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(name)
+		}
+		sums[strings.TrimPrefix(name, "./")] = sum
+	}
+	return sums
+}
+
+// excluded reports whether a relative path, or one of its parent
+// directories, matches an exclude pattern.
+func excluded(rel string, exclude []string) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		prefix := strings.Join(parts[:i+1], "/")
+		for _, pattern := range exclude {
+			name := parts[i]
+			if strings.Contains(pattern, "/") {
+				name = prefix
+			}
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
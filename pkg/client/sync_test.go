@@ -0,0 +1,206 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files with content under dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSyncTransfersOnlyChangedFiles(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	local, remote := t.TempDir(), filepath.Join(t.TempDir(), "it's remote")
+	writeTree(t, local, map[string]string{
+		"bin/app":       "v1",
+		"conf/app.yaml": "a: 1",
+		"app.log":       "noise",
+		".git/HEAD":     "ref",
+	})
+	if err := os.Chmod(filepath.Join(local, "bin/app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	opts := SyncOptions{Exclude: []string{"*.log", ".git"}}
+
+	result, err := c.Sync(ctx, local, remote, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if want := []string{"bin/app", "conf/app.yaml"}; !slices.Equal(result.Transferred, want) {
+		t.Errorf("Transferred = %v, want %v", result.Transferred, want)
+	}
+	info, err := os.Stat(filepath.Join(remote, "bin/app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	for _, name := range []string{"app.log", ".git"} {
+		if _, err := os.Stat(filepath.Join(remote, name)); !os.IsNotExist(err) {
+			t.Errorf("excluded %s was transferred", name)
+		}
+	}
+
+	writeTree(t, local, map[string]string{"bin/app": "v2"})
+	result, err = c.Sync(ctx, local, remote, opts)
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !slices.Equal(result.Transferred, []string{"bin/app"}) || result.Unchanged != 1 {
+		t.Errorf("result = %+v, want bin/app transferred and 1 unchanged", result)
+	}
+	if got, _ := os.ReadFile(filepath.Join(remote, "bin/app")); string(got) != "v2" {
+		t.Errorf("remote content = %q, want %q", got, "v2")
+	}
+}
+
+func TestSyncDelete(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	ctx := context.Background()
+	local, remote := t.TempDir(), t.TempDir()
+	writeTree(t, local, map[string]string{"keep": "k"})
+	writeTree(t, remote, map[string]string{"keep": "k", "stale/file": "s", "cache.log": "c"})
+
+	result, err := c.Sync(ctx, local, remote, SyncOptions{Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(result.Deleted) != 0 {
+		t.Errorf("Deleted = %v without Delete", result.Deleted)
+	}
+
+	result, err = c.Sync(ctx, local, remote, SyncOptions{Delete: true, Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if !slices.Equal(result.Deleted, []string{"stale/file"}) {
+		t.Errorf("Deleted = %v, want [stale/file]", result.Deleted)
+	}
+	if _, err := os.Stat(filepath.Join(remote, "cache.log")); err != nil {
+		t.Errorf("excluded remote file was deleted: %v", err)
+	}
+}
+
+// connectingRunner is a localRunner counting the commands run outside of
+// the connections it opens.
+type connectingRunner struct {
+	localRunner
+	connects, unconnected, closed int
+}
+
+func (r *connectingRunner) Run(ctx context.Context, vmInfo *VMInfo, cmd string) (string, string, error) {
+	r.unconnected++
+	return r.localRunner.Run(ctx, vmInfo, cmd)
+}
+
+func (r *connectingRunner) Connect(ctx context.Context, vmInfo *VMInfo) (Conn, error) {
+	r.connects++
+	return &localConn{closed: &r.closed}, nil
+}
+
+// localConn is the Conn of connectingRunner.
+type localConn struct {
+	localRunner
+	closed *int
+}
+
+func (c *localConn) RunWithStdin(ctx context.Context, vmInfo *VMInfo, cmd string, stdin io.Reader) (string, string, error) {
+	return "", "", ErrStdinUnsupported
+}
+
+func (c *localConn) Close() error {
+	*c.closed++
+	return nil
+}
+
+func TestSyncReusesConnection(t *testing.T) {
+	runner := &connectingRunner{}
+	c := newRetryClient(t, runner)
+	local, remote := t.TempDir(), t.TempDir()
+	// Files larger than a write chunk take several commands.
+	big := strings.Repeat("x", 3*writeChunkSize)
+	writeTree(t, local, map[string]string{"a": big, "b": big, "c/d": "d"})
+
+	result, err := c.Sync(context.Background(), local, remote, SyncOptions{Delete: true})
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(result.Transferred) != 3 {
+		t.Errorf("Transferred = %v, want 3 files", result.Transferred)
+	}
+	if runner.connects != 1 || runner.closed != 1 || runner.unconnected != 0 {
+		t.Errorf("connects = %d, closed = %d, unconnected commands = %d, want 1, 1, 0",
+			runner.connects, runner.closed, runner.unconnected)
+	}
+	if data, err := os.ReadFile(filepath.Join(remote, "a")); err != nil || string(data) != big {
+		t.Errorf("remote file a = %d bytes, %v", len(data), err)
+	}
+}
+
+func TestSyncRejectsInvalidExclude(t *testing.T) {
+	c := newRetryClient(t, localRunner{})
+	if _, err := c.Sync(context.Background(), t.TempDir(), t.TempDir(), SyncOptions{Exclude: []string{"["}}); err == nil {
+		t.Error("Sync() expected error for invalid pattern")
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	got := parseChecksums(sum + "  ./a/b\n\\" + sum + "  ./new\\nline\ngarbage\n")
+	if got["a/b"] != sum || got["new\nline"] != sum || len(got) != 2 {
+		t.Errorf("parseChecksums() = %v", got)
+	}
+}
+
+func TestExcluded(t *testing.T) {
+	exclude := []string{"*.log", "build/*.o", ".git"}
+	tests := map[string]bool{
+		"app.log":         true,
+		"logs/app.log":    true,
+		"build/main.o":    true,
+		"src/build/x.o":   false,
+		".git/HEAD":       true,
+		"src/main.go":     false,
+		"build/main.go":   false,
+		"a/.git/objects/": true,
+	}
+	for rel, want := range tests {
+		if got := excluded(rel, exclude); got != want {
+			t.Errorf("excluded(%q) = %v, want %v", rel, got, want)
+		}
+	}
+}
@@ -14,7 +14,12 @@
 
 package client
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
 
 // VMInfo holds connection information for a VM.
 type VMInfo struct {
@@ -26,6 +31,53 @@ type VMInfo struct {
 	User string
 	// PrivateKey is the SSH private key content ([]byte for crypto/ssh)
 	PrivateKey []byte
+	// ProxyJump is an optional bastion the VM is reached through.
+	// Nil means the VM is dialed directly.
+	ProxyJump *JumpHost
+	// HostKeys are the VM's SSH host public keys in authorized_keys format.
+	// When set, the VM must present one of them; when empty, host keys are
+	// not verified.
+	HostKeys []string
+}
+
+// JumpHost holds connection information for an SSH bastion.
+type JumpHost struct {
+	// Host is the IP address or hostname of the bastion
+	Host string
+	// Port is the SSH port of the bastion (default "22")
+	Port string
+	// User is the SSH username on the bastion
+	User string
+	// PrivateKey is the SSH private key content for the bastion
+	PrivateKey []byte
+}
+
+// ParseProxyJump parses an OpenSSH ProxyJump value of the form
+// [user@]host[:port]. Missing parts default to defaultUser, port 22, and
+// privateKey. An empty value returns nil.
+func ParseProxyJump(value, defaultUser string, privateKey []byte) (*JumpHost, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if strings.Contains(value, ",") {
+		return nil, fmt.Errorf("proxyJump %q: multiple jump hosts are not supported", value)
+	}
+
+	jump := &JumpHost{User: defaultUser, Port: "22", PrivateKey: privateKey}
+	hostPort := value
+	if i := strings.LastIndex(value, "@"); i >= 0 {
+		jump.User = value[:i]
+		hostPort = value[i+1:]
+	}
+	if host, port, err := net.SplitHostPort(hostPort); err == nil {
+		jump.Host, jump.Port = host, port
+	} else {
+		jump.Host = strings.Trim(hostPort, "[]")
+	}
+	if jump.Host == "" {
+		return nil, fmt.Errorf("proxyJump %q: host is required", value)
+	}
+	return jump, nil
 }
 
 // Validate returns an error if required fields are missing.
@@ -43,5 +95,18 @@ func (v *VMInfo) Validate() error {
 	if len(v.PrivateKey) == 0 {
 		return errors.New("vminfo: PrivateKey is required")
 	}
+	if j := v.ProxyJump; j != nil {
+		if j.Host == "" || j.Port == "" || j.User == "" {
+			return errors.New("vminfo: ProxyJump requires Host, Port and User")
+		}
+		if len(j.PrivateKey) == 0 {
+			return errors.New("vminfo: ProxyJump PrivateKey is required")
+		}
+	}
+	for _, hk := range v.HostKeys {
+		if _, err := ParseHostKey(hk); err != nil {
+			return fmt.Errorf("vminfo: %w", err)
+		}
+	}
 	return nil
 }
@@ -125,3 +125,64 @@ func TestVMInfoValidate_EmptyPrivateKey(t *testing.T) {
 		t.Errorf("expected error message to contain 'PrivateKey is required', got: %v", err)
 	}
 }
+
+// TestVMInfoValidate_ProxyJump tests validation of the jump host.
+func TestVMInfoValidate_ProxyJump(t *testing.T) {
+	vmInfo := &VMInfo{
+		Host:       "10.0.0.2",
+		Port:       "22",
+		User:       "testuser",
+		PrivateKey: []byte("test-private-key-content"),
+		ProxyJump:  &JumpHost{Host: "10.0.0.1", Port: "22", User: "admin", PrivateKey: []byte("key")},
+	}
+	if err := vmInfo.Validate(); err != nil {
+		t.Errorf("expected no error for valid ProxyJump, got: %v", err)
+	}
+
+	vmInfo.ProxyJump.User = ""
+	if err := vmInfo.Validate(); err == nil || !strings.Contains(err.Error(), "ProxyJump requires") {
+		t.Errorf("expected ProxyJump error, got: %v", err)
+	}
+
+	vmInfo.ProxyJump.User = "admin"
+	vmInfo.ProxyJump.PrivateKey = nil
+	if err := vmInfo.Validate(); err == nil || !strings.Contains(err.Error(), "ProxyJump PrivateKey is required") {
+		t.Errorf("expected ProxyJump PrivateKey error, got: %v", err)
+	}
+}
+
+// TestParseProxyJump tests parsing of OpenSSH ProxyJump values.
+func TestParseProxyJump(t *testing.T) {
+	key := []byte("key")
+	tests := []struct {
+		value   string
+		want    *JumpHost
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "10.0.0.1", want: &JumpHost{Host: "10.0.0.1", Port: "22", User: "ubuntu", PrivateKey: key}},
+		{value: "admin@10.0.0.1:2222", want: &JumpHost{Host: "10.0.0.1", Port: "2222", User: "admin", PrivateKey: key}},
+		{value: "[fd00::1]", want: &JumpHost{Host: "fd00::1", Port: "22", User: "ubuntu", PrivateKey: key}},
+		{value: "a@b,c@d", wantErr: true},
+		{value: "admin@", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseProxyJump(tt.value, "ubuntu", key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProxyJump() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("ParseProxyJump() = %+v, want nil", got)
+				}
+				return
+			}
+			if got.Host != tt.want.Host || got.Port != tt.want.Port || got.User != tt.want.User ||
+				string(got.PrivateKey) != string(tt.want.PrivateKey) {
+				t.Errorf("ParseProxyJump() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
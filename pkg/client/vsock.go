@@ -0,0 +1,56 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/vsock"
+)
+
+// DialVsock connects to a port of a VM over vsock, from the host running
+// the hypervisor. cid is the context ID of the VM (devices.vsock.cid, also
+// exported as TESTENV_VM_<VM>_VSOCK_CID). The channel does not go through
+// the guest network, so it keeps working when a test breaks it.
+func DialVsock(ctx context.Context, cid, port uint32) (net.Conn, error) {
+	conn, err := vsock.Dial(ctx, cid, port)
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	return conn, nil
+}
+
+// VsockDialContext returns a dial function connecting to the VM with the
+// given CID, for use as the DialContext of an http.Transport. The host of
+// the dialed address is ignored; its port is the vsock port, e.g.
+//
+//	transport := &http.Transport{DialContext: client.VsockDialContext(cid)}
+//	resp, err := (&http.Client{Transport: transport}).Get("http://vsock:8080/healthz")
+func VsockDialContext(cid uint32) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		_, portStr, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("client: invalid vsock address %q: %w", address, err)
+		}
+		port, err := strconv.ParseUint(portStr, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("client: invalid vsock port %q", portStr)
+		}
+		return DialVsock(ctx, cid, uint32(port))
+	}
+}
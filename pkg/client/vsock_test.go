@@ -0,0 +1,41 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVsockDialContext_InvalidAddress(t *testing.T) {
+	dial := VsockDialContext(3)
+	for _, address := range []string{"no-port", "vsock:http", "vsock:-1"} {
+		if _, err := dial(context.Background(), "tcp", address); err == nil || !strings.Contains(err.Error(), "invalid vsock") {
+			t.Errorf("dial(%q) error = %v, want invalid vsock address", address, err)
+		}
+	}
+}
+
+func TestDialVsock_Unreachable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if _, err := VsockDialContext(0xfffffff0)(ctx, "tcp", "vsock:1"); err == nil || !strings.HasPrefix(err.Error(), "client: ") {
+		t.Errorf("dial() error = %v, want a client error", err)
+	}
+}
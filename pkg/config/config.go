@@ -0,0 +1,581 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package config loads the testenv-vm configuration file shared by the
+// testenv-vm and testenv-vmctl binaries. Every setting can be overridden by
+// its TESTENV_VM_* environment variable.
+package config
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/policy"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/secrets"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// EnvConfigPath names the environment variable holding the config file path.
+const EnvConfigPath = "TESTENV_VM_CONFIG"
+
+// EnvTenantToken names the environment variable holding the token selecting
+// a tenant (see Tenant.Token).
+const EnvTenantToken = "TESTENV_VM_TENANT_TOKEN"
+
+// tenantNamePattern matches tenant names, which prefix provider-level names
+// like spec name prefixes.
+var tenantNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,14}[a-z0-9])?$`)
+
+const (
+	// defaultStateDir is used when neither the file nor the environment set one.
+	defaultStateDir = ".forge/testenv-vm/state"
+	// defaultShutdownTimeout is used when no shutdown timeout is configured.
+	defaultShutdownTimeout = 2 * time.Minute
+)
+
+// Config is the content of the configuration file.
+type Config struct {
+	// StateDir is the directory for state files (TESTENV_VM_STATE_DIR).
+	StateDir string `yaml:"stateDir"`
+	// StateKey is a secret reference (env:NAME, file:PATH or keyring:NAME)
+	// to a base64-encoded 32-byte key encrypting state files
+	// (TESTENV_VM_STATE_KEY).
+	StateKey string `yaml:"stateKey"`
+	// StateHistory is the number of revisions of the state of each
+	// environment kept for diffs (TESTENV_VM_STATE_HISTORY). Defaults to
+	// 10; -1 disables the history.
+	StateHistory int `yaml:"stateHistory"`
+	// ArtifactDir overrides the parent of artifact directories (TESTENV_VM_ARTIFACT_DIR).
+	ArtifactDir string `yaml:"artifactDir"`
+	// ImageCacheDir is the VM base image cache (TESTENV_VM_IMAGE_CACHE_DIR).
+	ImageCacheDir string `yaml:"imageCacheDir"`
+	// CleanupOnFailure rolls back failed creations (TESTENV_VM_CLEANUP_ON_FAILURE).
+	// Defaults to true.
+	CleanupOnFailure *bool `yaml:"cleanupOnFailure"`
+	// ReadOnly rejects create and delete (TESTENV_VM_READ_ONLY).
+	ReadOnly bool `yaml:"readOnly"`
+	// PolicyURL is an OPA data API endpoint for admission (TESTENV_VM_POLICY_URL).
+	PolicyURL string `yaml:"policyURL"`
+	// Catalog is the directory or git source of spec templates (TESTENV_VM_CATALOG).
+	Catalog string `yaml:"catalog"`
+	// AgentBinary is the guest agent injected into VMs enabling it (TESTENV_VM_AGENT_BINARY).
+	AgentBinary string `yaml:"agentBinary"`
+	// CIDRPool is the IPv4 prefix networks with cidr auto are allocated a
+	// /24 from (TESTENV_VM_CIDR_POOL). Defaults to 10.201.0.0/16.
+	CIDRPool string `yaml:"cidrPool"`
+	// LockFile pins providers to the versions and digests it records, when
+	// it exists (TESTENV_VM_LOCK_FILE). Defaults to testenv-vm.lock.
+	LockFile string `yaml:"lockFile"`
+	// ShutdownTimeout bounds in-flight operations on SIGTERM
+	// (TESTENV_VM_SHUTDOWN_TIMEOUT). Defaults to 2m; 0 cancels them at once.
+	ShutdownTimeout *Duration `yaml:"shutdownTimeout"`
+	// DefaultProviders are used by specs that declare no providers.
+	DefaultProviders []Provider `yaml:"defaultProviders"`
+	// Logging configures log output.
+	Logging Logging `yaml:"logging"`
+	// Metrics configures the metrics endpoint.
+	Metrics Metrics `yaml:"metrics"`
+	// Quotas limits what the orchestrator creates.
+	Quotas Quotas `yaml:"quotas"`
+	// Admission queues creations that do not fit the free capacity of a host.
+	Admission Admission `yaml:"admission"`
+	// Tenant namespaces the state directory, provider-level names, quotas
+	// and GC scope of the process on a host shared by several teams
+	// (TESTENV_VM_TENANT). It is selected by TESTENV_VM_TENANT_TOKEN when
+	// tenants have tokens.
+	Tenant string `yaml:"tenant"`
+	// Tenants are the tenants of the host. When set, Tenant must be one of
+	// them.
+	Tenants []Tenant `yaml:"tenants"`
+}
+
+// Tenant is a team sharing the host. Its state directory is
+// <stateDir>/tenants/<name>, its provider-level names start with
+// "<name>-", and GC only collects its own resources.
+type Tenant struct {
+	// Name is at most 16 lowercase letters, digits and hyphens.
+	Name string `yaml:"name"`
+	// Token is a secret reference (env:NAME, file:PATH or keyring:NAME) to
+	// the token selecting the tenant through TESTENV_VM_TENANT_TOKEN. A
+	// tenant with a token cannot be selected by name.
+	Token string `yaml:"token"`
+	// Quotas, if set, replace the top-level quotas for the tenant.
+	Quotas *Quotas `yaml:"quotas"`
+}
+
+// Provider mirrors v1.ProviderConfig with YAML field names.
+type Provider struct {
+	Name    string                 `yaml:"name"`
+	Engine  string                 `yaml:"engine"`
+	Default bool                   `yaml:"default"`
+	Spec    map[string]interface{} `yaml:"spec"`
+}
+
+// Logging configures log output.
+type Logging struct {
+	// File receives a copy of every log line (TESTENV_VM_LOG_FILE). Logs are
+	// always written to stderr as well, never to stdout.
+	File string `yaml:"file"`
+}
+
+// Metrics configures the metrics endpoint.
+type Metrics struct {
+	// ListenAddress serves Prometheus metrics on /metrics when set
+	// (TESTENV_VM_METRICS_ADDRESS), e.g. "127.0.0.1:9464".
+	ListenAddress string `yaml:"listenAddress"`
+}
+
+// Quotas limits what the orchestrator creates. Zero values mean unlimited.
+type Quotas struct {
+	MaxEnvironments int `yaml:"maxEnvironments"`
+	MaxVMs          int `yaml:"maxVMs"`
+	MaxVCPUs        int `yaml:"maxVCPUs"`
+	MaxMemoryMB     int `yaml:"maxMemoryMB"`
+}
+
+// Admission configures how creations wait for host capacity.
+type Admission struct {
+	// Wait is how long a creation that does not fit the free memory of a host
+	// queues, by spec priority, before failing (TESTENV_VM_ADMISSION_WAIT).
+	// Zero admits it at once with a warning.
+	Wait Duration `yaml:"wait"`
+	// Preempt destroys expired environments of lower priority to make room
+	// for queued creations (TESTENV_VM_ADMISSION_PREEMPT).
+	Preempt bool `yaml:"preempt"`
+	// MaxConcurrent limits the creations a server runs at once; further ones
+	// wait, taking turns between clients (TESTENV_VM_ADMISSION_MAX_CONCURRENT).
+	// Zero means no limit.
+	MaxConcurrent int `yaml:"maxConcurrent"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("90s", "5m").
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalYAML parses a Go duration string.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// DefaultPath returns the per-user config file path,
+// e.g. ~/.config/testenv-vm/config.yaml.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "testenv-vm", "config.yaml")
+}
+
+// PathFromArgs returns the value of a --config flag in args ("--config path"
+// or "--config=path"), or "" if absent. The testenv-vm engine does not use the
+// flag package, so the flag is read directly from os.Args.
+func PathFromArgs(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--config" || arg == "-config":
+			if i+1 < len(args) {
+				return args[i+1]
+			}
+		case strings.HasPrefix(arg, "--config="):
+			return strings.TrimPrefix(arg, "--config=")
+		case strings.HasPrefix(arg, "-config="):
+			return strings.TrimPrefix(arg, "-config=")
+		}
+	}
+	return ""
+}
+
+// Load reads the config file at path, applies environment overrides, and
+// fills defaults. If path is empty, TESTENV_VM_CONFIG and then DefaultPath
+// are used; a missing file is only an error when the path was given
+// explicitly.
+func Load(path string) (*Config, error) {
+	explicit := true
+	if path == "" {
+		path = os.Getenv(EnvConfigPath)
+	}
+	if path == "" {
+		path = DefaultPath()
+		explicit = false
+	}
+
+	cfg := &Config{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := decode(data, cfg); err != nil {
+				return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+			}
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		default:
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := cfg.selectTenant(os.Getenv(EnvTenantToken)); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decode strictly decodes YAML so that misspelled keys are reported.
+func decode(data []byte, cfg *Config) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// applyEnv overrides file settings with TESTENV_VM_* environment variables.
+func (c *Config) applyEnv() error {
+	setString := func(key string, dst *string) {
+		if v := os.Getenv(key); v != "" {
+			*dst = v
+		}
+	}
+	setString("TESTENV_VM_STATE_DIR", &c.StateDir)
+	setString("TESTENV_VM_STATE_KEY", &c.StateKey)
+	setString("TESTENV_VM_ARTIFACT_DIR", &c.ArtifactDir)
+	setString("TESTENV_VM_IMAGE_CACHE_DIR", &c.ImageCacheDir)
+	setString("TESTENV_VM_POLICY_URL", &c.PolicyURL)
+	setString("TESTENV_VM_CATALOG", &c.Catalog)
+	setString("TESTENV_VM_AGENT_BINARY", &c.AgentBinary)
+	setString("TESTENV_VM_CIDR_POOL", &c.CIDRPool)
+	setString("TESTENV_VM_LOCK_FILE", &c.LockFile)
+	setString("TESTENV_VM_LOG_FILE", &c.Logging.File)
+	setString("TESTENV_VM_METRICS_ADDRESS", &c.Metrics.ListenAddress)
+	setString("TESTENV_VM_TENANT", &c.Tenant)
+
+	if v := os.Getenv("TESTENV_VM_CLEANUP_ON_FAILURE"); v != "" {
+		cleanup := v == "true"
+		c.CleanupOnFailure = &cleanup
+	}
+	if v := os.Getenv("TESTENV_VM_READ_ONLY"); v != "" {
+		c.ReadOnly = v == "true"
+	}
+	if v := os.Getenv("TESTENV_VM_SHUTDOWN_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid TESTENV_VM_SHUTDOWN_TIMEOUT %q: %w", v, err)
+		}
+		c.ShutdownTimeout = &Duration{Duration: d}
+	}
+	if v := os.Getenv("TESTENV_VM_ADMISSION_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid TESTENV_VM_ADMISSION_WAIT %q: %w", v, err)
+		}
+		c.Admission.Wait.Duration = d
+	}
+	if v := os.Getenv("TESTENV_VM_ADMISSION_PREEMPT"); v != "" {
+		c.Admission.Preempt = v == "true"
+	}
+	if v := os.Getenv("TESTENV_VM_STATE_HISTORY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid TESTENV_VM_STATE_HISTORY %q: %w", v, err)
+		}
+		c.StateHistory = n
+	}
+	if v := os.Getenv("TESTENV_VM_ADMISSION_MAX_CONCURRENT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid TESTENV_VM_ADMISSION_MAX_CONCURRENT %q: %w", v, err)
+		}
+		c.Admission.MaxConcurrent = n
+	}
+	return nil
+}
+
+// applyDefaults fills unset settings.
+func (c *Config) applyDefaults() {
+	if c.StateDir == "" {
+		c.StateDir = defaultStateDir
+	}
+	if c.CleanupOnFailure == nil {
+		cleanup := true
+		c.CleanupOnFailure = &cleanup
+	}
+	if c.ShutdownTimeout == nil {
+		c.ShutdownTimeout = &Duration{Duration: defaultShutdownTimeout}
+	}
+	if c.LockFile == "" {
+		c.LockFile = provider.LockFileName
+	}
+}
+
+// Validate checks settings that cannot be verified while decoding.
+func (c *Config) Validate() error {
+	if c.ShutdownTimeout != nil && c.ShutdownTimeout.Duration < 0 {
+		return fmt.Errorf("shutdownTimeout must not be negative")
+	}
+	if c.Admission.Wait.Duration < 0 {
+		return fmt.Errorf("admission.wait must not be negative")
+	}
+	if c.StateKey != "" {
+		if _, err := secrets.ParseRef(c.StateKey); err != nil {
+			return fmt.Errorf("stateKey: %w", err)
+		}
+	}
+	if c.Admission.MaxConcurrent < 0 {
+		return fmt.Errorf("admission.maxConcurrent must not be negative")
+	}
+	if c.StateHistory < -1 {
+		return fmt.Errorf("stateHistory must be -1 (disabled) or more")
+	}
+	if c.CIDRPool != "" {
+		pool, err := netip.ParsePrefix(c.CIDRPool)
+		if err != nil || !pool.Addr().Is4() || pool.Bits() > 24 {
+			return fmt.Errorf("cidrPool %q must be an IPv4 prefix of /24 or larger", c.CIDRPool)
+		}
+	}
+	if err := c.Quotas.validate("quotas"); err != nil {
+		return err
+	}
+	if err := c.validateTenants(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for i, p := range c.DefaultProviders {
+		if p.Name == "" || p.Engine == "" {
+			return fmt.Errorf("defaultProviders[%d]: name and engine are required", i)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("defaultProviders[%d]: duplicate provider name %q", i, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return nil
+}
+
+// validate checks that no quota is negative.
+func (q Quotas) validate(field string) error {
+	quotas := map[string]int{
+		"maxEnvironments": q.MaxEnvironments,
+		"maxVMs":          q.MaxVMs,
+		"maxVCPUs":        q.MaxVCPUs,
+		"maxMemoryMB":     q.MaxMemoryMB,
+	}
+	for name, v := range quotas {
+		if v < 0 {
+			return fmt.Errorf("%s.%s must not be negative", field, name)
+		}
+	}
+	return nil
+}
+
+// validateTenants checks the tenants and the selected one.
+func (c *Config) validateTenants() error {
+	seen := make(map[string]bool)
+	for i, t := range c.Tenants {
+		if !tenantNamePattern.MatchString(t.Name) {
+			return fmt.Errorf("tenants[%d]: name %q must be at most 16 lowercase letters, digits and hyphens, starting and ending with a letter or digit", i, t.Name)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("tenants[%d]: duplicate tenant name %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		if t.Token != "" {
+			if _, err := secrets.ParseRef(t.Token); err != nil {
+				return fmt.Errorf("tenants[%d].token: %w", i, err)
+			}
+		}
+		if t.Quotas != nil {
+			if err := t.Quotas.validate(fmt.Sprintf("tenants[%d].quotas", i)); err != nil {
+				return err
+			}
+		}
+	}
+	if c.Tenant == "" {
+		return nil
+	}
+	if !tenantNamePattern.MatchString(c.Tenant) {
+		return fmt.Errorf("tenant %q must be at most 16 lowercase letters, digits and hyphens, starting and ending with a letter or digit", c.Tenant)
+	}
+	if len(c.Tenants) > 0 && !seen[c.Tenant] {
+		return fmt.Errorf("tenant %q is not one of tenants", c.Tenant)
+	}
+	return nil
+}
+
+// findTenant returns the tenant of the given name, or nil.
+func (c *Config) findTenant(name string) *Tenant {
+	for i := range c.Tenants {
+		if c.Tenants[i].Name == name {
+			return &c.Tenants[i]
+		}
+	}
+	return nil
+}
+
+// selectTenant sets Tenant to the tenant whose token is token, if set, and
+// rejects tenants with a token selected by name only.
+func (c *Config) selectTenant(token string) error {
+	if token == "" {
+		if t := c.findTenant(c.Tenant); t != nil && t.Token != "" {
+			return fmt.Errorf("tenant %q requires %s", c.Tenant, EnvTenantToken)
+		}
+		return nil
+	}
+	for _, t := range c.Tenants {
+		if t.Token == "" {
+			continue
+		}
+		want, err := secrets.Resolve(t.Token)
+		if err != nil {
+			return fmt.Errorf("failed to resolve the token of tenant %q: %w", t.Name, err)
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+			continue
+		}
+		if c.Tenant != "" && c.Tenant != t.Name {
+			return fmt.Errorf("%s selects tenant %q, not %q", EnvTenantToken, t.Name, c.Tenant)
+		}
+		c.Tenant = t.Name
+		return nil
+	}
+	return fmt.Errorf("%s matches no tenant", EnvTenantToken)
+}
+
+// OrchestratorConfig converts the configuration into an orchestrator.Config.
+func (c *Config) OrchestratorConfig() (orchestrator.Config, error) {
+	var admitter policy.Admitter
+	if c.PolicyURL != "" {
+		opa, err := policy.NewOPA(c.PolicyURL)
+		if err != nil {
+			return orchestrator.Config{}, fmt.Errorf("failed to configure admission policy: %w", err)
+		}
+		admitter = opa
+	}
+
+	var stateKey []byte
+	if c.StateKey != "" {
+		encoded, err := secrets.Resolve(c.StateKey)
+		if err != nil {
+			return orchestrator.Config{}, fmt.Errorf("failed to resolve state key: %w", err)
+		}
+		if stateKey, err = state.ParseKey(encoded); err != nil {
+			return orchestrator.Config{}, err
+		}
+	}
+
+	// A tenant has its own state directory and quotas, and shares the CIDR
+	// pool and admission queue of the host state directory.
+	stateDir, hostStateDir, quotas := c.StateDir, "", c.Quotas
+	if c.Tenant != "" {
+		stateDir, hostStateDir = paths.New(c.StateDir).Tenant(c.Tenant).Root, c.StateDir
+		if t := c.findTenant(c.Tenant); t != nil && t.Quotas != nil {
+			quotas = *t.Quotas
+		}
+	}
+	tenants := make([]string, 0, len(c.Tenants))
+	for _, t := range c.Tenants {
+		tenants = append(tenants, t.Name)
+	}
+
+	providers := make([]v1.ProviderConfig, 0, len(c.DefaultProviders))
+	for _, p := range c.DefaultProviders {
+		providers = append(providers, v1.ProviderConfig{
+			Name:    p.Name,
+			Engine:  p.Engine,
+			Default: p.Default,
+			Spec:    p.Spec,
+		})
+	}
+
+	return orchestrator.Config{
+		StateDir:         stateDir,
+		StateKey:         stateKey,
+		StateHistory:     c.StateHistory,
+		ImageCacheDir:    c.ImageCacheDir,
+		CleanupOnFailure: c.CleanupOnFailure == nil || *c.CleanupOnFailure,
+		Admitter:         admitter,
+		ReadOnly:         c.ReadOnly,
+		ArtifactDir:      c.ArtifactDir,
+		Catalog:          c.Catalog,
+		AgentBinary:      c.AgentBinary,
+		CIDRPool:         c.CIDRPool,
+		LockFile:         c.LockFile,
+		DefaultProviders: providers,
+		Tenant:           c.Tenant,
+		Tenants:          tenants,
+		HostStateDir:     hostStateDir,
+		Quotas: orchestrator.Quotas{
+			MaxEnvironments: quotas.MaxEnvironments,
+			MaxVMs:          quotas.MaxVMs,
+			MaxVCPUs:        quotas.MaxVCPUs,
+			MaxMemoryMB:     quotas.MaxMemoryMB,
+		},
+		Admission: orchestrator.Admission{
+			Wait:          c.Admission.Wait.Duration,
+			Preempt:       c.Admission.Preempt,
+			MaxConcurrent: c.Admission.MaxConcurrent,
+		},
+	}, nil
+}
+
+// SetupLogging copies log output to Logging.File, if set. The returned
+// closer must be called on exit.
+func (c *Config) SetupLogging() (io.Closer, error) {
+	if c.Logging.File == "" {
+		return io.NopCloser(nil), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Logging.File), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(c.Logging.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, f))
+	return f, nil
+}
@@ -0,0 +1,365 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+// isolateEnv clears every variable Load reads so tests do not depend on the
+// host environment or the user's config file.
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, key := range []string{
+		EnvConfigPath,
+		"TESTENV_VM_STATE_DIR",
+		"TESTENV_VM_ARTIFACT_DIR",
+		"TESTENV_VM_IMAGE_CACHE_DIR",
+		"TESTENV_VM_POLICY_URL",
+		"TESTENV_VM_CATALOG",
+		"TESTENV_VM_AGENT_BINARY",
+		"TESTENV_VM_CIDR_POOL",
+		"TESTENV_VM_LOCK_FILE",
+		"TESTENV_VM_LOG_FILE",
+		"TESTENV_VM_METRICS_ADDRESS",
+		"TESTENV_VM_CLEANUP_ON_FAILURE",
+		"TESTENV_VM_READ_ONLY",
+		"TESTENV_VM_SHUTDOWN_TIMEOUT",
+		"TESTENV_VM_ADMISSION_WAIT",
+		"TESTENV_VM_ADMISSION_PREEMPT",
+		"TESTENV_VM_ADMISSION_MAX_CONCURRENT",
+		"TESTENV_VM_STATE_KEY",
+		"TESTENV_VM_STATE_HISTORY",
+		"TESTENV_VM_TENANT",
+		EnvTenantToken,
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	isolateEnv(t)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StateDir != defaultStateDir {
+		t.Errorf("StateDir = %q, want %q", cfg.StateDir, defaultStateDir)
+	}
+	if cfg.CleanupOnFailure == nil || !*cfg.CleanupOnFailure {
+		t.Error("CleanupOnFailure should default to true")
+	}
+	if cfg.ShutdownTimeout.Duration != defaultShutdownTimeout {
+		t.Errorf("ShutdownTimeout = %s, want %s", cfg.ShutdownTimeout, defaultShutdownTimeout)
+	}
+	if cfg.LockFile != provider.LockFileName {
+		t.Errorf("LockFile = %q, want %q", cfg.LockFile, provider.LockFileName)
+	}
+}
+
+func TestLoad_File(t *testing.T) {
+	isolateEnv(t)
+	path := writeConfig(t, `
+stateDir: /var/lib/testenv-vm
+artifactDir: /tmp/artifacts
+cleanupOnFailure: false
+shutdownTimeout: 30s
+defaultProviders:
+  - name: libvirt
+    engine: go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-libvirt
+    default: true
+logging:
+  file: /tmp/testenv-vm.log
+metrics:
+  listenAddress: 127.0.0.1:9464
+quotas:
+  maxVMs: 4
+  maxMemoryMB: 8192
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StateDir != "/var/lib/testenv-vm" || cfg.ArtifactDir != "/tmp/artifacts" {
+		t.Errorf("unexpected dirs: state=%q artifact=%q", cfg.StateDir, cfg.ArtifactDir)
+	}
+	if *cfg.CleanupOnFailure {
+		t.Error("CleanupOnFailure = true, want false")
+	}
+	if cfg.ShutdownTimeout.Duration != 30*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want 30s", cfg.ShutdownTimeout)
+	}
+	if cfg.Logging.File != "/tmp/testenv-vm.log" || cfg.Metrics.ListenAddress != "127.0.0.1:9464" {
+		t.Errorf("unexpected logging/metrics: %+v %+v", cfg.Logging, cfg.Metrics)
+	}
+
+	orchConfig, err := cfg.OrchestratorConfig()
+	if err != nil {
+		t.Fatalf("OrchestratorConfig() error = %v", err)
+	}
+	if orchConfig.CleanupOnFailure {
+		t.Error("orchestrator CleanupOnFailure = true, want false")
+	}
+	if len(orchConfig.DefaultProviders) != 1 || !orchConfig.DefaultProviders[0].Default {
+		t.Errorf("unexpected default providers: %+v", orchConfig.DefaultProviders)
+	}
+	if orchConfig.Quotas.MaxVMs != 4 || orchConfig.Quotas.MaxMemoryMB != 8192 {
+		t.Errorf("unexpected quotas: %+v", orchConfig.Quotas)
+	}
+}
+
+func TestLoad_ZeroShutdownTimeout(t *testing.T) {
+	isolateEnv(t)
+
+	cfg, err := Load(writeConfig(t, "shutdownTimeout: 0s\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ShutdownTimeout.Duration != 0 {
+		t.Errorf("ShutdownTimeout = %s, want 0s as configured", cfg.ShutdownTimeout)
+	}
+
+	t.Setenv("TESTENV_VM_SHUTDOWN_TIMEOUT", "0")
+	cfg, err = Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ShutdownTimeout.Duration != 0 {
+		t.Errorf("ShutdownTimeout = %s, want 0s from the environment", cfg.ShutdownTimeout)
+	}
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	isolateEnv(t)
+	path := writeConfig(t, "stateDir: /from/file\nreadOnly: false\n")
+	t.Setenv(EnvConfigPath, path)
+	t.Setenv("TESTENV_VM_STATE_DIR", "/from/env")
+	t.Setenv("TESTENV_VM_READ_ONLY", "true")
+	t.Setenv("TESTENV_VM_SHUTDOWN_TIMEOUT", "5s")
+	t.Setenv("TESTENV_VM_CATALOG", "git+https://example.com/labs.git//catalog")
+	t.Setenv("TESTENV_VM_AGENT_BINARY", "/opt/testenv-vm-agent")
+	t.Setenv("TESTENV_VM_ADMISSION_WAIT", "10m")
+	t.Setenv("TESTENV_VM_ADMISSION_PREEMPT", "true")
+	t.Setenv("TESTENV_VM_ADMISSION_MAX_CONCURRENT", "4")
+	t.Setenv("TESTENV_VM_CIDR_POOL", "10.64.0.0/20")
+	t.Setenv("TESTENV_VM_LOCK_FILE", "/ci/testenv-vm.lock")
+	t.Setenv("TESTENV_VM_TENANT", "storage")
+	t.Setenv("TESTENV_VM_STATE_HISTORY", "-1")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StateDir != "/from/env" {
+		t.Errorf("StateDir = %q, want /from/env", cfg.StateDir)
+	}
+	if !cfg.ReadOnly {
+		t.Error("ReadOnly = false, want true")
+	}
+	if cfg.ShutdownTimeout.Duration != 5*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want 5s", cfg.ShutdownTimeout)
+	}
+	if cfg.Catalog != "git+https://example.com/labs.git//catalog" {
+		t.Errorf("Catalog = %q", cfg.Catalog)
+	}
+	if cfg.AgentBinary != "/opt/testenv-vm-agent" {
+		t.Errorf("AgentBinary = %q", cfg.AgentBinary)
+	}
+	if cfg.Admission.Wait.Duration != 10*time.Minute || !cfg.Admission.Preempt || cfg.Admission.MaxConcurrent != 4 {
+		t.Errorf("Admission = %+v, want 10m with preemption and 4 concurrent creations", cfg.Admission)
+	}
+	if cfg.CIDRPool != "10.64.0.0/20" {
+		t.Errorf("CIDRPool = %q", cfg.CIDRPool)
+	}
+	if cfg.LockFile != "/ci/testenv-vm.lock" {
+		t.Errorf("LockFile = %q", cfg.LockFile)
+	}
+	if cfg.Tenant != "storage" {
+		t.Errorf("Tenant = %q", cfg.Tenant)
+	}
+	if cfg.StateHistory != -1 {
+		t.Errorf("StateHistory = %d, want -1", cfg.StateHistory)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown field", content: "stateDirectory: /tmp\n", wantErr: "stateDirectory"},
+		{name: "invalid duration", content: "shutdownTimeout: soon\n", wantErr: "invalid duration"},
+		{name: "negative quota", content: "quotas:\n  maxVMs: -1\n", wantErr: "quotas.maxVMs"},
+		{name: "negative admission wait", content: "admission:\n  wait: -1m\n", wantErr: "admission.wait"},
+		{name: "negative concurrent creations", content: "admission:\n  maxConcurrent: -1\n", wantErr: "admission.maxConcurrent"},
+		{name: "CIDR pool smaller than a /24", content: "cidrPool: 10.200.0.0/25\n", wantErr: "cidrPool"},
+		{name: "IPv6 CIDR pool", content: "cidrPool: fd00::/48\n", wantErr: "cidrPool"},
+		{name: "invalid state key reference", content: "stateKey: c2VjcmV0\n", wantErr: "stateKey"},
+		{name: "invalid state history", content: "stateHistory: -2\n", wantErr: "stateHistory"},
+		{name: "provider without engine", content: "defaultProviders:\n  - name: stub\n", wantErr: "engine"},
+		{name: "invalid tenant", content: "tenant: Storage\n", wantErr: "tenant"},
+		{name: "invalid tenant name", content: "tenants:\n  - name: storage_team\n", wantErr: "tenants[0]"},
+		{name: "duplicate tenant", content: "tenants:\n  - name: storage\n  - name: storage\n", wantErr: "duplicate tenant"},
+		{name: "unknown tenant", content: "tenant: network\ntenants:\n  - name: storage\n", wantErr: "not one of tenants"},
+		{name: "invalid tenant token reference", content: "tenants:\n  - name: storage\n    token: secret\n", wantErr: "tenants[0].token"},
+		{name: "negative tenant quota", content: "tenants:\n  - name: storage\n    quotas:\n      maxVMs: -1\n", wantErr: "tenants[0].quotas.maxVMs"},
+		{name: "tenant selected without its token", content: "tenant: storage\ntenants:\n  - name: storage\n    token: env:TEST_UNSET_TOKEN\n", wantErr: EnvTenantToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateEnv(t)
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		isolateEnv(t)
+		if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+			t.Error("expected error for missing explicit config file")
+		}
+	})
+}
+
+func TestOrchestratorConfig_StateKey(t *testing.T) {
+	isolateEnv(t)
+	key := strings.Repeat("k", 32)
+	t.Setenv("TEST_STATE_KEY", base64.StdEncoding.EncodeToString([]byte(key)))
+	t.Setenv("TESTENV_VM_STATE_KEY", "env:TEST_STATE_KEY")
+	cfg, err := Load(writeConfig(t, ""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	orchConfig, err := cfg.OrchestratorConfig()
+	if err != nil {
+		t.Fatalf("OrchestratorConfig() error = %v", err)
+	}
+	if string(orchConfig.StateKey) != key {
+		t.Errorf("StateKey = %q, want the decoded key", orchConfig.StateKey)
+	}
+
+	t.Setenv("TEST_STATE_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	if _, err := cfg.OrchestratorConfig(); err == nil || !strings.Contains(err.Error(), "want 32") {
+		t.Errorf("OrchestratorConfig() error = %v, want invalid key size", err)
+	}
+	cfg.StateKey = "env:TEST_STATE_KEY_UNSET"
+	if _, err := cfg.OrchestratorConfig(); err == nil || !strings.Contains(err.Error(), "state key") {
+		t.Errorf("OrchestratorConfig() error = %v, want unresolved state key", err)
+	}
+}
+
+func TestOrchestratorConfig_Tenant(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("TEST_STORAGE_TOKEN", "s3cret")
+	t.Setenv("TEST_NETWORK_TOKEN", "other")
+	path := writeConfig(t, `stateDir: /var/lib/testenv-vm
+quotas:
+  maxVMs: 10
+tenants:
+  - name: storage
+    token: env:TEST_STORAGE_TOKEN
+    quotas:
+      maxVMs: 4
+  - name: network
+    token: env:TEST_NETWORK_TOKEN
+  - name: sandbox
+`)
+
+	t.Setenv(EnvTenantToken, "s3cret")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tenant != "storage" {
+		t.Errorf("Tenant = %q, want the tenant of the token", cfg.Tenant)
+	}
+	orchConfig, err := cfg.OrchestratorConfig()
+	if err != nil {
+		t.Fatalf("OrchestratorConfig() error = %v", err)
+	}
+	if orchConfig.StateDir != "/var/lib/testenv-vm/tenants/storage" || orchConfig.HostStateDir != "/var/lib/testenv-vm" {
+		t.Errorf("StateDir = %q, HostStateDir = %q, want the tenant state directory below the host one", orchConfig.StateDir, orchConfig.HostStateDir)
+	}
+	if orchConfig.Tenant != "storage" || !reflect.DeepEqual(orchConfig.Tenants, []string{"storage", "network", "sandbox"}) {
+		t.Errorf("Tenant = %q, Tenants = %v", orchConfig.Tenant, orchConfig.Tenants)
+	}
+	if orchConfig.Quotas.MaxVMs != 4 {
+		t.Errorf("Quotas.MaxVMs = %d, want the tenant quota 4", orchConfig.Quotas.MaxVMs)
+	}
+
+	t.Setenv("TESTENV_VM_TENANT", "network")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `selects tenant "storage"`) {
+		t.Errorf("Load() error = %v, want a token of another tenant rejected", err)
+	}
+	t.Setenv(EnvTenantToken, "guess")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "matches no tenant") {
+		t.Errorf("Load() error = %v, want an unknown token rejected", err)
+	}
+
+	// Tenants without a token are selected by name and keep the top-level quotas
+	t.Setenv(EnvTenantToken, "")
+	t.Setenv("TESTENV_VM_TENANT", "sandbox")
+	if cfg, err = Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if orchConfig, err = cfg.OrchestratorConfig(); err != nil {
+		t.Fatalf("OrchestratorConfig() error = %v", err)
+	}
+	if orchConfig.StateDir != "/var/lib/testenv-vm/tenants/sandbox" || orchConfig.Quotas.MaxVMs != 10 {
+		t.Errorf("StateDir = %q, Quotas.MaxVMs = %d", orchConfig.StateDir, orchConfig.Quotas.MaxVMs)
+	}
+}
+
+func TestPathFromArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--mcp"}, want: ""},
+		{args: []string{"--mcp", "--config", "/etc/tvm.yaml"}, want: "/etc/tvm.yaml"},
+		{args: []string{"--config=/etc/tvm.yaml", "--mcp"}, want: "/etc/tvm.yaml"},
+		{args: []string{"--config"}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := PathFromArgs(tt.args); got != tt.want {
+			t.Errorf("PathFromArgs(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
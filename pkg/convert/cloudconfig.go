@@ -0,0 +1,191 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// cloudConfig is the subset of cloud-config that maps to v1.CloudInitSpec.
+type cloudConfig struct {
+	Hostname          string            `yaml:"hostname"`
+	Packages          []any             `yaml:"packages"`
+	Runcmd            []any             `yaml:"runcmd"`
+	WriteFiles        []cloudConfigFile `yaml:"write_files"`
+	Users             []any             `yaml:"users"`
+	SSHAuthorizedKeys []string          `yaml:"ssh_authorized_keys"`
+}
+
+// cloudConfigFile is one write_files entry.
+type cloudConfigFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Permissions string `yaml:"permissions"`
+	Encoding    string `yaml:"encoding"`
+}
+
+// cloudConfigUser is one users entry.
+type cloudConfigUser struct {
+	Name                    string   `yaml:"name"`
+	Sudo                    any      `yaml:"sudo"`
+	SSHAuthorizedKeys       []string `yaml:"ssh_authorized_keys"`
+	SSHAuthorizedKeysDashed []string `yaml:"ssh-authorized-keys"`
+}
+
+// cloudConfigKeys are the top-level keys translated by FromCloudConfig.
+var cloudConfigKeys = map[string]bool{
+	"hostname": true, "packages": true, "runcmd": true, "write_files": true,
+	"users": true, "ssh_authorized_keys": true,
+}
+
+// FromCloudConfig translates a cloud-config document into a single VM. Its
+// users keep their authorized keys and are also given the generated key.
+func FromCloudConfig(data []byte) (*Result, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid cloud-config: %w", err)
+	}
+	var cfg cloudConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid cloud-config: %w", err)
+	}
+
+	var warnings []string
+	var ignored []string
+	for key := range raw {
+		if !cloudConfigKeys[key] {
+			ignored = append(ignored, key)
+		}
+	}
+	sort.Strings(ignored)
+	for _, key := range ignored {
+		warnings = append(warnings, fmt.Sprintf("%s is not translated", key))
+	}
+
+	var users []v1.UserSpec
+	for _, entry := range cfg.Users {
+		user, warning := convertCloudConfigUser(entry)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+		if user != nil {
+			users = append(users, *user)
+		}
+	}
+	if len(cfg.SSHAuthorizedKeys) > 0 {
+		if len(users) == 0 {
+			users = []v1.UserSpec{{Name: defaultUser, Sudo: "ALL=(ALL) NOPASSWD:ALL"}}
+		}
+		users[0].SshAuthorizedKeys = append(users[0].SshAuthorizedKeys, cfg.SSHAuthorizedKeys...)
+	}
+
+	name := "vm"
+	if cfg.Hostname != "" {
+		name = resourceName(cfg.Hostname)
+	}
+	spec := newSkeleton()
+	vm := newVM(name, addImage(spec, defaultImage), users)
+	vm.Spec.Networks = []string{defaultNetwork}
+	spec.Networks = []v1.NetworkResource{{Name: defaultNetwork, Kind: "nat", Spec: v1.NetworkSpec{Cidr: defaultCIDR}}}
+	warnings = append(warnings, fmt.Sprintf("the base image is not part of cloud-config, using %s", defaultImage))
+
+	ci := &vm.Spec.CloudInit
+	ci.Hostname = cfg.Hostname
+	for _, p := range cfg.Packages {
+		switch p := p.(type) {
+		case string:
+			ci.Packages = append(ci.Packages, p)
+		case []any:
+			// [name, version] pins are kept as name=version (apt syntax)
+			parts := make([]string, 0, len(p))
+			for _, part := range p {
+				parts = append(parts, fmt.Sprint(part))
+			}
+			ci.Packages = append(ci.Packages, strings.Join(parts, "="))
+		}
+	}
+	for _, cmd := range cfg.Runcmd {
+		switch cmd := cmd.(type) {
+		case string:
+			ci.Runcmd = append(ci.Runcmd, cmd)
+		case []any:
+			// Argument lists are executed without a shell; quote them
+			args := make([]string, 0, len(cmd))
+			for _, arg := range cmd {
+				args = append(args, shellQuote(fmt.Sprint(arg)))
+			}
+			ci.Runcmd = append(ci.Runcmd, strings.Join(args, " "))
+		}
+	}
+	for _, f := range cfg.WriteFiles {
+		if f.Encoding != "" {
+			warnings = append(warnings, fmt.Sprintf("write_files %s: encoding %q is not translated", f.Path, f.Encoding))
+			continue
+		}
+		ci.WriteFiles = append(ci.WriteFiles, v1.WriteFileSpec{Path: f.Path, Content: f.Content, Permissions: f.Permissions})
+	}
+
+	spec.Vms = []v1.VMResource{vm}
+	return &Result{Spec: spec, Warnings: warnings}, nil
+}
+
+// convertCloudConfigUser translates a users entry. The "default" entry and
+// entries without a name are dropped.
+func convertCloudConfigUser(entry any) (*v1.UserSpec, string) {
+	if s, ok := entry.(string); ok {
+		if s == "default" {
+			return nil, "users: the distribution default user is not translated"
+		}
+		return &v1.UserSpec{Name: s}, ""
+	}
+
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Sprintf("users: %v", err)
+	}
+	var u cloudConfigUser
+	if err := yaml.Unmarshal(data, &u); err != nil || u.Name == "" {
+		return nil, "users: entry without a name is not translated"
+	}
+
+	user := &v1.UserSpec{Name: u.Name}
+	user.SshAuthorizedKeys = append(append(user.SshAuthorizedKeys, u.SSHAuthorizedKeys...), u.SSHAuthorizedKeysDashed...)
+	switch sudo := u.Sudo.(type) {
+	case string:
+		user.Sudo = sudo
+	case []any:
+		if len(sudo) > 0 {
+			user.Sudo = fmt.Sprint(sudo[0])
+		}
+		if len(sudo) > 1 {
+			return user, fmt.Sprintf("users %s: only the first sudo rule is kept", u.Name)
+		}
+	}
+	return user, ""
+}
+
+// shellQuote quotes s for a POSIX shell when needed.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`;&|<>*?()[]{}!#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
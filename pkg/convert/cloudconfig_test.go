@@ -0,0 +1,97 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+const testCloudConfig = `#cloud-config
+hostname: app.example
+package_update: true
+packages:
+  - nginx
+  - [libpython3, "3.12"]
+users:
+  - default
+  - name: ops
+    sudo: ["ALL=(ALL) NOPASSWD:ALL"]
+    ssh_authorized_keys:
+      - ssh-ed25519 AAAA ops
+write_files:
+  - path: /etc/motd
+    content: hello
+    permissions: "0644"
+  - path: /etc/blob
+    encoding: b64
+    content: aGVsbG8=
+runcmd:
+  - systemctl restart nginx
+  - [sh, -c, "echo $HOME"]
+`
+
+func TestFromCloudConfig(t *testing.T) {
+	result, err := FromCloudConfig([]byte(testCloudConfig))
+	if err != nil {
+		t.Fatalf("FromCloudConfig() error = %v", err)
+	}
+	if len(result.Spec.Vms) != 1 {
+		t.Fatalf("got %d VMs, want 1", len(result.Spec.Vms))
+	}
+	vm := result.Spec.Vms[0]
+	ci := vm.Spec.CloudInit
+
+	if vm.Name != "app-example" || ci.Hostname != "app.example" {
+		t.Errorf("name %q hostname %q", vm.Name, ci.Hostname)
+	}
+	if want := []string{"nginx", "libpython3=3.12"}; !reflect.DeepEqual(ci.Packages, want) {
+		t.Errorf("packages = %q, want %q", ci.Packages, want)
+	}
+	if want := []string{"systemctl restart nginx", `sh -c 'echo $HOME'`}; !reflect.DeepEqual(ci.Runcmd, want) {
+		t.Errorf("runcmd = %q, want %q", ci.Runcmd, want)
+	}
+	if want := []v1.WriteFileSpec{{Path: "/etc/motd", Content: "hello", Permissions: "0644"}}; !reflect.DeepEqual(ci.WriteFiles, want) {
+		t.Errorf("writeFiles = %+v, want %+v", ci.WriteFiles, want)
+	}
+
+	if len(ci.Users) != 1 || ci.Users[0].Name != "ops" || ci.Users[0].Sudo != "ALL=(ALL) NOPASSWD:ALL" {
+		t.Fatalf("users = %+v", ci.Users)
+	}
+	if want := []string{"ssh-ed25519 AAAA ops", "{{ .Keys.ssh.PublicKey }}"}; !reflect.DeepEqual(ci.Users[0].SshAuthorizedKeys, want) {
+		t.Errorf("authorized keys = %q, want %q", ci.Users[0].SshAuthorizedKeys, want)
+	}
+	if vm.Spec.Readiness.Ssh.User != "ops" {
+		t.Errorf("readiness user = %q, want ops", vm.Spec.Readiness.Ssh.User)
+	}
+
+	warnings := strings.Join(result.Warnings, "\n")
+	for _, want := range []string{"package_update is not translated", `encoding "b64"`, "default user"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings do not mention %q:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestFromCloudConfig_Invalid(t *testing.T) {
+	if _, err := FromCloudConfig([]byte("runcmd: [")); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package convert translates existing VM definitions (Vagrantfiles and
+// cloud-config documents) into a testenv-vm spec skeleton. Translation is
+// best-effort: settings without an equivalent are reported as warnings so that
+// they can be ported by hand.
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Source formats accepted by Convert.
+const (
+	// FormatVagrantfile is a Ruby Vagrantfile.
+	FormatVagrantfile = "vagrantfile"
+	// FormatCloudConfig is a "#cloud-config" user-data document.
+	FormatCloudConfig = "cloud-config"
+)
+
+// Defaults of the generated skeleton.
+const (
+	libvirtProvider = "libvirt"
+	libvirtEngine   = "go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-libvirt"
+	sshKeyName      = "ssh"
+	defaultUser     = "testenv"
+	defaultNetwork  = "net"
+	defaultCIDR     = "192.168.100.0/24"
+	defaultImage    = "ubuntu:24.04"
+	defaultMemory   = 1024
+	defaultVCPUs    = 1
+	defaultDisk     = "20G"
+)
+
+// Result is a converted spec with the settings that could not be translated.
+type Result struct {
+	// Spec is the generated skeleton.
+	Spec *v1.Spec
+	// Warnings describe the source settings that were dropped or approximated.
+	Warnings []string
+}
+
+// Convert translates data in the given source format.
+func Convert(format string, data []byte) (*Result, error) {
+	switch format {
+	case FormatVagrantfile:
+		return FromVagrantfile(data)
+	case FormatCloudConfig:
+		return FromCloudConfig(data)
+	default:
+		return nil, fmt.Errorf("unsupported source format %q (supported: %s, %s)", format, FormatVagrantfile, FormatCloudConfig)
+	}
+}
+
+// YAML renders the result as a spec document. Warnings are written as leading
+// comments.
+func (r *Result) YAML() ([]byte, error) {
+	data, err := json.Marshal(r.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec: %w", err)
+	}
+
+	// Keep the sections in dependency order; yaml.v3 would sort them.
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range []string{"providers", "images", "keys", "networks", "vms"} {
+		value := prune(fields[key])
+		if value == nil {
+			continue
+		}
+		node := &yaml.Node{}
+		if err := node.Encode(value); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("# Generated by testenv-vmctl convert. Review before use.\n")
+	for _, w := range r.Warnings {
+		fmt.Fprintf(&b, "# WARNING: %s\n", w)
+	}
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// prune drops nil, zero and empty values so that the skeleton only shows
+// settings that differ from the defaults.
+func prune(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, value := range v {
+			if pruned := prune(value); pruned == nil {
+				delete(v, k)
+			} else {
+				v[k] = pruned
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+		return v
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, value := range v {
+			if pruned := prune(value); pruned != nil {
+				out = append(out, pruned)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case bool:
+		if !v {
+			return nil
+		}
+	case float64:
+		if v == 0 {
+			return nil
+		}
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return v
+}
+
+// newSkeleton returns a spec with the libvirt provider and one SSH key.
+func newSkeleton() *v1.Spec {
+	return &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: libvirtProvider, Engine: libvirtEngine, Default: true}},
+		Keys:      []v1.KeyResource{{Name: sshKeyName, Spec: v1.KeySpec{Type: "ed25519"}}},
+	}
+}
+
+// addImage declares the image source, once, and returns the template
+// referencing its path.
+func addImage(spec *v1.Spec, source string) string {
+	name := resourceName(source)
+	found := false
+	for _, img := range spec.Images {
+		if img.Name == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		spec.Images = append(spec.Images, v1.ImageResource{Name: name, Spec: v1.ImageSpec{Source: source}})
+	}
+	return fmt.Sprintf("{{ .Images.%s.Path }}", name)
+}
+
+// newVM returns a VM reachable over SSH with the generated key. users are
+// the cloud-init users; the key is authorized for each of them, and the
+// first one is used by the readiness check.
+func newVM(name, baseImage string, users []v1.UserSpec) v1.VMResource {
+	if len(users) == 0 {
+		users = []v1.UserSpec{{Name: defaultUser, Sudo: "ALL=(ALL) NOPASSWD:ALL"}}
+	}
+	key := fmt.Sprintf("{{ .Keys.%s.PublicKey }}", sshKeyName)
+	for i := range users {
+		users[i].SshAuthorizedKeys = append(users[i].SshAuthorizedKeys, key)
+	}
+	return v1.VMResource{
+		Name: name,
+		Spec: v1.VMSpec{
+			Memory: defaultMemory,
+			Vcpus:  defaultVCPUs,
+			Disk:   v1.DiskSpec{BaseImage: baseImage, Size: defaultDisk},
+			CloudInit: v1.CloudInitSpec{
+				Users: users,
+			},
+			Readiness: v1.ReadinessSpec{
+				Ssh: v1.SSHReadinessSpec{
+					Enabled:    true,
+					User:       users[0].Name,
+					PrivateKey: fmt.Sprintf("{{ .Keys.%s.PrivateKeyPath }}", sshKeyName),
+				},
+			},
+		},
+	}
+}
+
+// resourceNamePattern matches characters that are not valid in resource names.
+var resourceNamePattern = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// resourceName turns an arbitrary string into a resource name, e.g.
+// "ubuntu:24.04" into "ubuntu-24-04".
+func resourceName(s string) string {
+	name := strings.Trim(resourceNamePattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if name == "" {
+		return "vm"
+	}
+	return name
+}
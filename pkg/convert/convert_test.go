@@ -0,0 +1,63 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestResultYAML(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		input  string
+	}{
+		{FormatVagrantfile, testVagrantfile},
+		{FormatCloudConfig, testCloudConfig},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := Convert(tt.format, []byte(tt.input))
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			out, err := result.YAML()
+			if err != nil {
+				t.Fatalf("YAML() error = %v", err)
+			}
+			if !strings.HasPrefix(string(out), "# Generated by testenv-vmctl convert") {
+				t.Errorf("missing header:\n%s", out)
+			}
+
+			// The skeleton must be a valid spec
+			parsed, err := spec.Parse(out)
+			if err != nil {
+				t.Fatalf("Parse() error = %v\n%s", err, out)
+			}
+			if _, err := spec.ValidateEarly(parsed); err != nil {
+				t.Errorf("ValidateEarly() error = %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+func TestConvert_UnknownFormat(t *testing.T) {
+	if _, err := Convert("dockerfile", nil); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
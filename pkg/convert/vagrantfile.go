@@ -0,0 +1,270 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Vagrantfile statements recognized line by line. The Vagrantfile is Ruby, so
+// anything computed (loops, variables) is not evaluated.
+var (
+	vagrantDefine   = regexp.MustCompile(`^config\.vm\.define\s+[:"']?([\w.-]+)["']?`)
+	vagrantBox      = regexp.MustCompile(`^\w+\.vm\.box\s*=\s*["']([^"']+)["']`)
+	vagrantHostname = regexp.MustCompile(`^\w+\.vm\.hostname\s*=\s*["']([^"']+)["']`)
+	vagrantNetwork  = regexp.MustCompile(`^\w+\.vm\.network\s+[:"']?(\w+)["']?(.*)$`)
+	vagrantIP       = regexp.MustCompile(`\bip:\s*["']([^"']+)["']`)
+	vagrantMemory   = regexp.MustCompile(`^\w+\.memory\s*=\s*["']?(\d+)`)
+	vagrantCPUs     = regexp.MustCompile(`^\w+\.cpus\s*=\s*["']?(\d+)`)
+	vagrantShell    = regexp.MustCompile(`^\w+\.vm\.provision\s+[:"']?shell["']?\s*,\s*(.*)$`)
+	vagrantInline   = regexp.MustCompile(`inline:\s*(["'])(.*)["']`)
+	vagrantHeredoc  = regexp.MustCompile(`inline:\s*<<[-~]?["']?(\w+)`)
+	vagrantProvider = regexp.MustCompile(`^\w+\.vm\.provider\b`)
+	vagrantOpener   = regexp.MustCompile(`(^(if|unless|case|while|until|begin)\b)|\bdo\b`)
+	vagrantEnd      = regexp.MustCompile(`^end\b`)
+)
+
+// Private DHCP networks of all machines become one network, using the
+// default subnet of VirtualBox host-only DHCP servers.
+const (
+	vagrantDHCPNetwork = "private-dhcp"
+	vagrantDHCPCIDR    = "172.28.128.0/24"
+)
+
+// vagrantBoxImages maps well-known boxes to image references.
+var vagrantBoxImages = map[string]string{
+	"ubuntu/jammy64":        "ubuntu:22.04",
+	"generic/ubuntu2204":    "ubuntu:22.04",
+	"bento/ubuntu-22.04":    "ubuntu:22.04",
+	"ubuntu/noble64":        "ubuntu:24.04",
+	"generic/ubuntu2404":    "ubuntu:24.04",
+	"bento/ubuntu-24.04":    "ubuntu:24.04",
+	"debian/bookworm64":     "debian:12",
+	"generic/debian12":      "debian:12",
+	"bento/debian-12":       "debian:12",
+	"cloud-image/debian-12": "debian:12",
+}
+
+// vagrantMachine collects the settings of one machine, or of the config
+// block when it applies to all machines.
+type vagrantMachine struct {
+	name     string
+	box      string
+	hostname string
+	memory   int
+	cpus     int
+	ips      []string
+	dhcp     bool
+	scripts  []string
+}
+
+// FromVagrantfile translates the machines of a Vagrantfile. Settings of the
+// top-level config block apply to every machine; without config.vm.define a
+// single machine named "default" is created.
+func FromVagrantfile(data []byte) (*Result, error) {
+	global := &vagrantMachine{}
+	var machines []*vagrantMachine
+	var current *vagrantMachine
+	var warnings []string
+	depth, defineDepth := 0, 0
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		target := global
+		if current != nil {
+			target = current
+		}
+
+		switch {
+		case vagrantDefine.MatchString(line):
+			current = &vagrantMachine{name: vagrantDefine.FindStringSubmatch(line)[1]}
+			machines = append(machines, current)
+			defineDepth = depth + 1
+		case vagrantBox.MatchString(line):
+			target.box = vagrantBox.FindStringSubmatch(line)[1]
+		case vagrantHostname.MatchString(line):
+			target.hostname = vagrantHostname.FindStringSubmatch(line)[1]
+		case vagrantMemory.MatchString(line):
+			target.memory, _ = strconv.Atoi(vagrantMemory.FindStringSubmatch(line)[1])
+		case vagrantCPUs.MatchString(line):
+			target.cpus, _ = strconv.Atoi(vagrantCPUs.FindStringSubmatch(line)[1])
+		case vagrantNetwork.MatchString(line):
+			sub := vagrantNetwork.FindStringSubmatch(line)
+			switch {
+			case sub[1] == "private_network" && vagrantIP.MatchString(sub[2]):
+				target.ips = append(target.ips, vagrantIP.FindStringSubmatch(sub[2])[1])
+			case sub[1] == "private_network":
+				target.dhcp = true
+			default:
+				warnings = append(warnings, fmt.Sprintf("line %d: %s is not translated: %s", i+1, sub[1], line))
+			}
+		case vagrantShell.MatchString(line):
+			args := vagrantShell.FindStringSubmatch(line)[1]
+			switch {
+			case vagrantHeredoc.MatchString(args):
+				terminator := vagrantHeredoc.FindStringSubmatch(args)[1]
+				var script []string
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != terminator; i++ {
+					script = append(script, lines[i])
+				}
+				target.scripts = append(target.scripts, dedent(script))
+			case vagrantInline.MatchString(args):
+				target.scripts = append(target.scripts, vagrantInline.FindStringSubmatch(args)[2])
+			default:
+				warnings = append(warnings, fmt.Sprintf("line %d: only inline shell provisioners are translated: %s", i+1, line))
+			}
+		case vagrantProvider.MatchString(line):
+			// Provider blocks hold memory and cpus, matched above
+		case strings.Contains(line, ".vm.") || strings.Contains(line, ".customize"):
+			warnings = append(warnings, fmt.Sprintf("line %d: not translated: %s", i+1, line))
+		}
+
+		depth += len(vagrantOpener.FindAllString(line, -1))
+		if vagrantEnd.MatchString(line) {
+			depth--
+			if current != nil && depth < defineDepth {
+				current = nil
+			}
+		}
+	}
+
+	if len(machines) == 0 {
+		machines = []*vagrantMachine{{name: "default"}}
+	}
+
+	spec := newSkeleton()
+	networks := map[string]string{}
+	for _, machine := range machines {
+		merged := machine.merge(global)
+		image, known := vagrantBoxImages[merged.box]
+		switch {
+		case merged.box == "":
+			image = defaultImage
+			warnings = append(warnings, fmt.Sprintf("machine %s: no box, using %s", machine.name, defaultImage))
+		case !known:
+			image = defaultImage
+			warnings = append(warnings, fmt.Sprintf("machine %s: box %q has no known image, using %s", machine.name, merged.box, defaultImage))
+		}
+
+		vm := newVM(resourceName(machine.name), addImage(spec, image), nil)
+		vm.Spec.CloudInit.Hostname = merged.hostname
+		vm.Spec.CloudInit.Runcmd = merged.scripts
+		if merged.memory > 0 {
+			vm.Spec.Memory = merged.memory
+		}
+		if merged.cpus > 0 {
+			vm.Spec.Vcpus = merged.cpus
+		}
+		for _, ip := range merged.ips {
+			cidr, err := networkCIDR(ip)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("machine %s: %v", machine.name, err))
+				continue
+			}
+			name, ok := networks[cidr]
+			if !ok {
+				name = fmt.Sprintf("private-%d", len(networks)+1)
+				networks[cidr] = name
+				spec.Networks = append(spec.Networks, v1.NetworkResource{Name: name, Kind: "nat", Spec: v1.NetworkSpec{Cidr: cidr}})
+			}
+			vm.Spec.Networks = append(vm.Spec.Networks, name)
+			warnings = append(warnings, fmt.Sprintf("machine %s: static IP %s is not kept, the VM gets a DHCP address on %s", machine.name, ip, cidr))
+		}
+		if merged.dhcp {
+			if _, ok := networks[vagrantDHCPCIDR]; !ok {
+				networks[vagrantDHCPCIDR] = vagrantDHCPNetwork
+				spec.Networks = append(spec.Networks, v1.NetworkResource{Name: vagrantDHCPNetwork, Kind: "nat", Spec: v1.NetworkSpec{Cidr: vagrantDHCPCIDR}})
+			}
+			if name := networks[vagrantDHCPCIDR]; !slices.Contains(vm.Spec.Networks, name) {
+				vm.Spec.Networks = append(vm.Spec.Networks, name)
+			}
+		}
+		if len(vm.Spec.Networks) == 0 {
+			vm.Spec.Networks = []string{defaultNetwork}
+		}
+		spec.Vms = append(spec.Vms, vm)
+	}
+	for _, vm := range spec.Vms {
+		if vm.Spec.Networks[0] == defaultNetwork {
+			spec.Networks = append(spec.Networks, v1.NetworkResource{Name: defaultNetwork, Kind: "nat", Spec: v1.NetworkSpec{Cidr: defaultCIDR}})
+			break
+		}
+	}
+	return &Result{Spec: spec, Warnings: warnings}, nil
+}
+
+// merge returns the machine settings completed by the global ones.
+func (m *vagrantMachine) merge(global *vagrantMachine) vagrantMachine {
+	merged := *m
+	if merged.box == "" {
+		merged.box = global.box
+	}
+	if merged.hostname == "" {
+		merged.hostname = global.hostname
+	}
+	if merged.memory == 0 {
+		merged.memory = global.memory
+	}
+	if merged.cpus == 0 {
+		merged.cpus = global.cpus
+	}
+	merged.dhcp = m.dhcp || global.dhcp
+	merged.ips = append(append([]string(nil), global.ips...), m.ips...)
+	merged.scripts = append(append([]string(nil), global.scripts...), m.scripts...)
+	return merged
+}
+
+// networkCIDR returns the /24 containing ip.
+func networkCIDR(ip string) (string, error) {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IPv4 address %q", ip)
+	}
+	network := &net.IPNet{IP: parsed.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}
+	return network.String(), nil
+}
+
+// dedent removes the indentation common to all non-blank lines.
+func dedent(lines []string) string {
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		out = append(out, strings.TrimRight(l, " \t"))
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
@@ -0,0 +1,138 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testVagrantfile = `
+Vagrant.configure("2") do |config|
+  config.vm.box = "ubuntu/jammy64"
+  config.vm.provider "virtualbox" do |vb|
+    vb.memory = 2048
+  end
+
+  config.vm.define "web" do |web|
+    web.vm.hostname = "web"
+    web.vm.network "private_network", ip: "192.168.56.10"
+    web.vm.network "forwarded_port", guest: 80, host: 8080
+    web.vm.provision "shell", inline: <<-SHELL
+      apt-get update
+      apt-get install -y nginx
+    SHELL
+  end
+
+  config.vm.define :db do |db|
+    db.vm.box = "debian/bookworm64"
+    db.vm.network "private_network", ip: "192.168.56.11"
+    db.vm.provider "virtualbox" do |v|
+      v.cpus = 2
+    end
+    db.vm.provision "shell", inline: "echo hello"
+  end
+end
+`
+
+func TestFromVagrantfile(t *testing.T) {
+	result, err := FromVagrantfile([]byte(testVagrantfile))
+	if err != nil {
+		t.Fatalf("FromVagrantfile() error = %v", err)
+	}
+	spec := result.Spec
+	if len(spec.Vms) != 2 {
+		t.Fatalf("got %d VMs, want 2", len(spec.Vms))
+	}
+
+	web, db := spec.Vms[0], spec.Vms[1]
+	if web.Name != "web" || db.Name != "db" {
+		t.Errorf("VM names = %s, %s", web.Name, db.Name)
+	}
+	if web.Spec.Memory != 2048 || web.Spec.Vcpus != 1 {
+		t.Errorf("web: memory %d vcpus %d, want the global 2048 and 1", web.Spec.Memory, web.Spec.Vcpus)
+	}
+	if db.Spec.Memory != 2048 || db.Spec.Vcpus != 2 {
+		t.Errorf("db: memory %d vcpus %d, want 2048 and 2", db.Spec.Memory, db.Spec.Vcpus)
+	}
+	if want := []string{"apt-get update\napt-get install -y nginx"}; !reflect.DeepEqual(web.Spec.CloudInit.Runcmd, want) {
+		t.Errorf("web runcmd = %q, want %q", web.Spec.CloudInit.Runcmd, want)
+	}
+	if want := []string{"echo hello"}; !reflect.DeepEqual(db.Spec.CloudInit.Runcmd, want) {
+		t.Errorf("db runcmd = %q, want %q", db.Spec.CloudInit.Runcmd, want)
+	}
+	if web.Spec.Disk.BaseImage != "{{ .Images.ubuntu-22-04.Path }}" || db.Spec.Disk.BaseImage != "{{ .Images.debian-12.Path }}" {
+		t.Errorf("base images = %s, %s", web.Spec.Disk.BaseImage, db.Spec.Disk.BaseImage)
+	}
+
+	// Both private IPs are in one /24, so the machines share a network
+	if len(spec.Networks) != 1 || spec.Networks[0].Spec.Cidr != "192.168.56.0/24" {
+		t.Errorf("networks = %+v, want one 192.168.56.0/24 network", spec.Networks)
+	}
+	if !reflect.DeepEqual(web.Spec.Networks, db.Spec.Networks) {
+		t.Errorf("networks differ: %v, %v", web.Spec.Networks, db.Spec.Networks)
+	}
+
+	warnings := strings.Join(result.Warnings, "\n")
+	for _, want := range []string{"forwarded_port is not translated", "static IP 192.168.56.10 is not kept"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings do not mention %q:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestFromVagrantfile_SingleMachine(t *testing.T) {
+	result, err := FromVagrantfile([]byte(`Vagrant.configure("2") do |config|
+  config.vm.box = "some/custom-box"
+end`))
+	if err != nil {
+		t.Fatalf("FromVagrantfile() error = %v", err)
+	}
+	if len(result.Spec.Vms) != 1 || result.Spec.Vms[0].Name != "default" {
+		t.Fatalf("VMs = %+v, want a single default VM", result.Spec.Vms)
+	}
+	if got := result.Spec.Vms[0].Spec.Networks; !reflect.DeepEqual(got, []string{defaultNetwork}) {
+		t.Errorf("networks = %v, want the default network", got)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `box "some/custom-box" has no known image`) {
+		t.Errorf("warnings = %q", result.Warnings)
+	}
+}
+
+func TestFromVagrantfile_DHCP(t *testing.T) {
+	result, err := FromVagrantfile([]byte(`Vagrant.configure("2") do |config|
+  config.vm.box = "ubuntu/jammy64"
+  config.vm.define "a" do |a|
+    a.vm.network "private_network", type: "dhcp"
+  end
+  config.vm.define "b" do |b|
+    b.vm.network "private_network", type: "dhcp"
+  end
+end`))
+	if err != nil {
+		t.Fatalf("FromVagrantfile() error = %v", err)
+	}
+	if len(result.Spec.Networks) != 1 || result.Spec.Networks[0].Name != vagrantDHCPNetwork || result.Spec.Networks[0].Spec.Cidr != vagrantDHCPCIDR {
+		t.Errorf("networks = %+v, want one %s network", result.Spec.Networks, vagrantDHCPCIDR)
+	}
+	for _, vm := range result.Spec.Vms {
+		if !reflect.DeepEqual(vm.Spec.Networks, []string{vagrantDHCPNetwork}) {
+			t.Errorf("vm %s networks = %v, want the DHCP network", vm.Name, vm.Spec.Networks)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package engine is the supported API for embedding testenv-vm in Go
+// programs, e.g. test harnesses or CI services that create environments
+// without going through forge or MCP. Its Orchestrator interface and option
+// structs are kept stable: fields are only ever added, and the zero value of
+// a new field keeps the previous behavior. The testenv-vm MCP server is a
+// thin adapter over it.
+//
+// The rest of pkg/orchestrator remains available for the operations this
+// package does not cover (exec, copy, power, snapshots...), without the
+// same stability guarantee.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"sync"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/notify"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+// ErrNotFound is returned by Status and Outputs for unknown environments.
+var ErrNotFound = errors.New("environment not found")
+
+// defaultEventBuffer is the default capacity of the channels returned by
+// Events.
+const defaultEventBuffer = 64
+
+// Config configures the orchestrator created by New.
+type Config = orchestrator.Config
+
+// Event is a lifecycle event of an environment: queued, ready, failed or
+// destroyed.
+type Event = notify.Event
+
+// Orchestrator creates and destroys test environments.
+type Orchestrator interface {
+	// Create creates an environment and returns it once it is ready. On
+	// failure the partially created resources are rolled back unless the
+	// spec disables cleanupOnFailure.
+	Create(ctx context.Context, opts CreateOptions) (*Environment, error)
+	// Destroy deletes an environment and its resources. Destroying an
+	// environment that does not exist succeeds.
+	Destroy(ctx context.Context, opts DestroyOptions) error
+	// Status returns the recorded status of an environment.
+	Status(ctx context.Context, environmentID string) (*Status, error)
+	// Outputs returns the artifact of an environment, as returned by
+	// Create: its files, metadata and environment variables.
+	Outputs(ctx context.Context, environmentID string) (*v1.TestEnvArtifact, error)
+	// Events returns the lifecycle events of the environments created and
+	// destroyed through this orchestrator from now on. The channel is
+	// closed when ctx is done.
+	Events(ctx context.Context, opts EventsOptions) (<-chan Event, error)
+	// Close stops the providers started by the orchestrator.
+	Close() error
+}
+
+// CreateOptions configures Create.
+type CreateOptions struct {
+	// Spec is the environment spec.
+	Spec *v1.Spec
+	// TestID identifies the test the environment is created for. Unless
+	// the spec names the environment, it also derives the environment ID.
+	TestID string
+	// Stage is the test stage name.
+	Stage string
+	// TmpDir is the directory where artifact files are written.
+	TmpDir string
+	// RootDir resolves the relative paths of the spec.
+	RootDir string
+	// Metadata is passed to templates and policies as the forge input
+	// metadata.
+	Metadata map[string]string
+	// Env is available to templates as {{ .Env.NAME }}.
+	Env map[string]string
+}
+
+// DestroyOptions configures Destroy.
+type DestroyOptions struct {
+	// EnvironmentID identifies the environment. If empty, it is derived
+	// from TestID as Create does.
+	EnvironmentID string
+	// TestID identifies the test the environment was created for.
+	TestID string
+	// Metadata is the forge input metadata.
+	Metadata map[string]string
+}
+
+// EventsOptions filters Events.
+type EventsOptions struct {
+	// EnvironmentID, if set, only returns the events of this environment.
+	EnvironmentID string
+	// Types, if set, only returns events of these types.
+	Types []notify.EventType
+	// Buffer is the capacity of the returned channel, 64 by default.
+	// Events are dropped rather than delaying the operations when the
+	// channel is full.
+	Buffer int
+}
+
+// Environment is a created environment.
+type Environment struct {
+	// ID is the environment ID.
+	ID string
+	// Artifact holds the files, metadata and environment variables of the
+	// environment.
+	Artifact *v1.TestEnvArtifact
+	// Provisioner creates and deletes VMs in the environment at runtime.
+	Provisioner *client.RuntimeProvisioner
+}
+
+// Status is the recorded status of an environment.
+type Status struct {
+	// ID is the environment ID.
+	ID string
+	// TestID is the test the environment was created for.
+	TestID string
+	// Stage is the test stage name.
+	Stage string
+	// Phase is the environment status, one of the v1.Status constants.
+	Phase string
+	// Parent is the environment whose keys and networks it uses.
+	Parent string
+	// CreatedAt and UpdatedAt are RFC 3339 timestamps.
+	CreatedAt string
+	UpdatedAt string
+	// Keys, Networks and VMs count the recorded resources.
+	Keys     int
+	Networks int
+	VMs      int
+}
+
+// New creates an Orchestrator from config.
+func New(config Config) (Orchestrator, error) {
+	o, err := orchestrator.NewOrchestrator(config)
+	if err != nil {
+		return nil, err
+	}
+	return Wrap(o), nil
+}
+
+// Wrap returns the Orchestrator interface of an existing orchestrator, for
+// programs that also use the rest of its API. Closing the result closes o.
+func Wrap(o *orchestrator.Orchestrator) Orchestrator {
+	return &engine{o: o}
+}
+
+type engine struct {
+	o *orchestrator.Orchestrator
+}
+
+func (e *engine) Create(ctx context.Context, opts CreateOptions) (*Environment, error) {
+	if opts.Spec == nil {
+		return nil, errors.New("create requires a spec")
+	}
+	result, err := e.o.Create(ctx, &v1.CreateInput{
+		TestID:   opts.TestID,
+		Stage:    opts.Stage,
+		TmpDir:   opts.TmpDir,
+		RootDir:  opts.RootDir,
+		Metadata: opts.Metadata,
+		Spec:     opts.Spec.ToMap(),
+		Env:      opts.Env,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Environment{
+		ID:          result.Artifact.Metadata[orchestrator.MetadataEnvironmentID],
+		Artifact:    result.Artifact,
+		Provisioner: result.Provisioner,
+	}, nil
+}
+
+func (e *engine) Destroy(ctx context.Context, opts DestroyOptions) error {
+	return e.o.Delete(ctx, deleteInput(opts))
+}
+
+// deleteInput converts opts, passing EnvironmentID as the metadata that
+// Delete resolves the environment from.
+func deleteInput(opts DestroyOptions) *v1.DeleteInput {
+	metadata := opts.Metadata
+	if opts.EnvironmentID != "" {
+		metadata = maps.Clone(opts.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string, 1)
+		}
+		metadata[orchestrator.MetadataEnvironmentID] = opts.EnvironmentID
+	}
+	return &v1.DeleteInput{TestID: opts.TestID, Metadata: metadata}
+}
+
+func (e *engine) Status(_ context.Context, environmentID string) (*Status, error) {
+	summary, err := e.o.Environment(environmentID)
+	if err != nil {
+		return nil, notFound(environmentID, err)
+	}
+	return &Status{
+		ID:        summary.ID,
+		TestID:    summary.TestID,
+		Stage:     summary.Stage,
+		Phase:     summary.Status,
+		Parent:    summary.Parent,
+		CreatedAt: summary.CreatedAt,
+		UpdatedAt: summary.UpdatedAt,
+		Keys:      summary.Keys,
+		Networks:  summary.Networks,
+		VMs:       summary.VMs,
+	}, nil
+}
+
+func (e *engine) Outputs(_ context.Context, environmentID string) (*v1.TestEnvArtifact, error) {
+	artifact, err := e.o.Outputs(environmentID)
+	if err != nil {
+		return nil, notFound(environmentID, err)
+	}
+	return artifact, nil
+}
+
+// notFound wraps the errors of missing environments with ErrNotFound.
+func notFound(environmentID string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrNotFound, environmentID)
+	}
+	return err
+}
+
+func (e *engine) Events(ctx context.Context, opts EventsOptions) (<-chan Event, error) {
+	if opts.Buffer < 0 {
+		return nil, fmt.Errorf("invalid event buffer %d", opts.Buffer)
+	}
+	buffer := opts.Buffer
+	if buffer == 0 {
+		buffer = defaultEventBuffer
+	}
+	events := newEventStream(buffer, opts)
+	unsubscribe := e.o.Subscribe(events.send)
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+		events.close()
+	}()
+	return events.ch, nil
+}
+
+func (e *engine) Close() error {
+	return e.o.Close()
+}
+
+// eventStream forwards the events matching its filter to a channel.
+type eventStream struct {
+	opts EventsOptions
+	// mu orders sends before closing ch: subscribers may still be called
+	// after they unsubscribed.
+	mu     sync.RWMutex
+	closed bool
+	ch     chan Event
+}
+
+func newEventStream(buffer int, opts EventsOptions) *eventStream {
+	return &eventStream{opts: opts, ch: make(chan Event, buffer)}
+}
+
+func (s *eventStream) matches(event Event) bool {
+	if s.opts.EnvironmentID != "" && event.EnvironmentID != s.opts.EnvironmentID {
+		return false
+	}
+	return len(s.opts.Types) == 0 || slices.Contains(s.opts.Types, event.Type)
+}
+
+func (s *eventStream) send(event Event) {
+	if !s.matches(event) {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- event:
+	default:
+		// The consumer is behind; dropping keeps the operation going
+	}
+}
+
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
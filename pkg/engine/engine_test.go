@@ -0,0 +1,141 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/notify"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/orchestrator"
+)
+
+func newTestEngine(t *testing.T) Orchestrator {
+	t.Helper()
+	tmpDir := t.TempDir()
+	e, err := New(Config{
+		StateDir:      filepath.Join(tmpDir, "state"),
+		ImageCacheDir: filepath.Join(tmpDir, "images"),
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func TestEngine_UnknownEnvironment(t *testing.T) {
+	e := newTestEngine(t)
+	ctx := context.Background()
+
+	if _, err := e.Status(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Status() error = %v, want ErrNotFound", err)
+	}
+	if _, err := e.Outputs(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Outputs() error = %v, want ErrNotFound", err)
+	}
+	if err := e.Destroy(ctx, DestroyOptions{EnvironmentID: "missing"}); err != nil {
+		t.Errorf("Destroy() of a missing environment error = %v", err)
+	}
+	if _, err := e.Create(ctx, CreateOptions{TestID: "t"}); err == nil {
+		t.Error("Create() without a spec should fail")
+	}
+}
+
+func TestDeleteInput(t *testing.T) {
+	tests := []struct {
+		name string
+		opts DestroyOptions
+		want *v1.DeleteInput
+	}{
+		{
+			name: "test ID only",
+			opts: DestroyOptions{TestID: "t1"},
+			want: &v1.DeleteInput{TestID: "t1"},
+		},
+		{
+			name: "environment ID",
+			opts: DestroyOptions{TestID: "t1", EnvironmentID: "env-1"},
+			want: &v1.DeleteInput{TestID: "t1", Metadata: map[string]string{orchestrator.MetadataEnvironmentID: "env-1"}},
+		},
+		{
+			name: "environment ID overrides metadata",
+			opts: DestroyOptions{EnvironmentID: "env-1", Metadata: map[string]string{
+				"other": "x", orchestrator.MetadataEnvironmentID: "env-0",
+			}},
+			want: &v1.DeleteInput{Metadata: map[string]string{"other": "x", orchestrator.MetadataEnvironmentID: "env-1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deleteInput(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deleteInput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	metadata := map[string]string{"other": "x"}
+	deleteInput(DestroyOptions{EnvironmentID: "env-1", Metadata: metadata})
+	if len(metadata) != 1 {
+		t.Errorf("deleteInput() modified the caller's metadata: %v", metadata)
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	s := newEventStream(2, EventsOptions{
+		EnvironmentID: "env-1",
+		Types:         []notify.EventType{notify.EventReady, notify.EventDestroyed},
+	})
+	s.send(Event{Type: notify.EventReady, EnvironmentID: "env-2"})
+	s.send(Event{Type: notify.EventQueued, EnvironmentID: "env-1"})
+	s.send(Event{Type: notify.EventReady, EnvironmentID: "env-1"})
+	s.send(Event{Type: notify.EventDestroyed, EnvironmentID: "env-1"})
+	// Dropped: the buffer is full
+	s.send(Event{Type: notify.EventReady, EnvironmentID: "env-1"})
+	s.close()
+	// Ignored once closed
+	s.send(Event{Type: notify.EventReady, EnvironmentID: "env-1"})
+
+	var got []notify.EventType
+	for event := range s.ch {
+		got = append(got, event.Type)
+	}
+	if want := []notify.EventType{notify.EventReady, notify.EventDestroyed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestEngine_Events(t *testing.T) {
+	e := newTestEngine(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if _, err := e.Events(ctx, EventsOptions{Buffer: -1}); err == nil {
+		t.Error("Events() with a negative buffer should fail")
+	}
+	events, err := e.Events(ctx, EventsOptions{})
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("Events() channel should be closed once the context is done")
+	}
+}
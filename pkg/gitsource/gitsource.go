@@ -0,0 +1,220 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitsource fetches files from git repositories addressed as
+//
+//	git+<repository URL>[//<path>][?ref=<branch, tag or commit>]
+//
+// e.g. "git+https://github.com/org/labs.git//envs/ci.yaml?ref=v1.4.0".
+// Repositories are fetched with the git binary into a cache directory, one
+// shallow clone per repository URL.
+package gitsource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Prefix marks a git source.
+const Prefix = "git+"
+
+// Source is a parsed git source.
+type Source struct {
+	// Repository is the URL passed to git, e.g. "https://host/org/repo.git".
+	Repository string
+	// Path is the slash-separated path within the repository; empty for the
+	// repository root.
+	Path string
+	// Ref is a branch, tag or commit; empty for the default branch.
+	Ref string
+}
+
+// IsSource reports whether s is a git source.
+func IsSource(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Parse parses a git source.
+func Parse(s string) (*Source, error) {
+	if !IsSource(s) {
+		return nil, fmt.Errorf("invalid git source %q: must start with %q", s, Prefix)
+	}
+	rest := strings.TrimPrefix(s, Prefix)
+
+	src := &Source{}
+	if base, query, ok := strings.Cut(rest, "?"); ok {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid git source %q: %w", s, err)
+		}
+		for key := range values {
+			if key != "ref" {
+				return nil, fmt.Errorf("invalid git source %q: unknown parameter %q", s, key)
+			}
+		}
+		src.Ref = values.Get("ref")
+		rest = base
+	}
+
+	// The path separator "//" is searched after the scheme's "://".
+	offset := 0
+	if i := strings.Index(rest, "://"); i >= 0 {
+		offset = i + len("://")
+	}
+	if i := strings.Index(rest[offset:], "//"); i >= 0 {
+		src.Path = rest[offset+i+2:]
+		rest = rest[:offset+i]
+	}
+	src.Repository = rest
+
+	if src.Repository == "" {
+		return nil, fmt.Errorf("invalid git source %q: missing repository", s)
+	}
+	if strings.HasPrefix(src.Ref, "-") {
+		return nil, fmt.Errorf("invalid git source %q: invalid ref %q", s, src.Ref)
+	}
+	if src.Path != "" {
+		cleaned := path.Clean(src.Path)
+		if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return nil, fmt.Errorf("invalid git source %q: path must stay within the repository", s)
+		}
+		src.Path = cleaned
+	}
+	return src, nil
+}
+
+// String formats the source as parsed by Parse.
+func (s *Source) String() string {
+	out := Prefix + s.Repository
+	if s.Path != "" {
+		out += "//" + s.Path
+	}
+	if s.Ref != "" {
+		out += "?ref=" + url.QueryEscape(s.Ref)
+	}
+	return out
+}
+
+// IsCommit reports whether ref is a full commit hash. Commits are immutable:
+// fetches of a commit already in the cache need no network access.
+func IsCommit(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(ref)
+	return err == nil
+}
+
+// Checkout is a fetched source.
+type Checkout struct {
+	// Dir is the worktree of the repository. It is shared by the fetches of
+	// the repository: a later fetch may check out another commit.
+	Dir string
+	// Commit is the commit the ref resolved to when fetched.
+	Commit string
+}
+
+// ReadFile reads the file at the slash-separated path of the source from
+// Commit, unaffected by later checkouts of the worktree.
+func (c *Checkout) ReadFile(ctx context.Context, src *Source) ([]byte, error) {
+	return gitOutput(ctx, c.Dir, "show", c.Commit+":"+src.Path)
+}
+
+// File returns the local path of the source's Path.
+func (c *Checkout) File(src *Source) string {
+	return filepath.Join(c.Dir, filepath.FromSlash(src.Path))
+}
+
+// fetchMu serializes fetches of this process into the shared cache.
+var fetchMu sync.Mutex
+
+// Fetch fetches the ref of a source into cacheDir and checks it out. The
+// clone is reused by later fetches of the same repository.
+func Fetch(ctx context.Context, src *Source, cacheDir string) (*Checkout, error) {
+	fetchMu.Lock()
+	defer fetchMu.Unlock()
+
+	sum := sha256.Sum256([]byte(src.Repository))
+	dir := filepath.Join(cacheDir, hex.EncodeToString(sum[:8]))
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create git cache: %w", err)
+		}
+		if _, err := git(ctx, dir, "init", "-q"); err != nil {
+			return nil, err
+		}
+		if _, err := git(ctx, dir, "remote", "add", "origin", src.Repository); err != nil {
+			return nil, err
+		}
+	}
+
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	target, cached := "FETCH_HEAD", false
+	if IsCommit(ref) {
+		ref = strings.ToLower(ref)
+		target = ref
+		_, err := git(ctx, dir, "cat-file", "-e", ref+"^{commit}")
+		cached = err == nil
+	}
+	if !cached {
+		if _, err := git(ctx, dir, "fetch", "-q", "--depth", "1", "origin", ref); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", src, err)
+		}
+	}
+	if _, err := git(ctx, dir, "checkout", "-q", "--force", target); err != nil {
+		return nil, err
+	}
+	commit, err := git(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if IsCommit(ref) && commit != ref {
+		return nil, fmt.Errorf("failed to fetch %s: checked out commit %s", src, commit)
+	}
+	return &Checkout{Dir: dir, Commit: commit}, nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := gitOutput(ctx, dir, args...)
+	return strings.TrimSpace(string(out)), err
+}
+
+// gitOutput runs a git command in dir and returns its output.
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never prompt for credentials.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,189 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitsource
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Source
+	}{
+		{
+			in:   "git+https://github.com/org/labs.git//envs/ci.yaml?ref=v1.4.0",
+			want: Source{Repository: "https://github.com/org/labs.git", Path: "envs/ci.yaml", Ref: "v1.4.0"},
+		},
+		{
+			in:   "git+https://github.com/org/labs.git",
+			want: Source{Repository: "https://github.com/org/labs.git"},
+		},
+		{
+			in:   "git+git@github.com:org/labs.git//catalog",
+			want: Source{Repository: "git@github.com:org/labs.git", Path: "catalog"},
+		},
+		{
+			in:   "git+file:///srv/labs//catalog/?ref=main",
+			want: Source{Repository: "file:///srv/labs", Path: "catalog", Ref: "main"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, in := range []string{
+		"https://github.com/org/labs.git",
+		"git+",
+		"git+https://github.com/org/labs.git//../etc/passwd",
+		"git+https://github.com/org/labs.git?branch=main",
+		"git+https://github.com/org/labs.git?ref=--upload-pack=sh",
+	} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	in := "git+https://github.com/org/labs.git//envs/ci.yaml?ref=v1.4.0"
+	src, err := Parse(in)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := src.String(); got != in {
+		t.Errorf("String() = %q, want %q", got, in)
+	}
+}
+
+// newRepo creates a repository with one commit per content of file and
+// returns its path.
+func newRepo(t *testing.T, file string, contents ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	for i, content := range contents {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		run("add", "-A")
+		run("commit", "-q", "-m", "commit")
+		run("tag", "v"+string(rune('1'+i)))
+	}
+	return dir
+}
+
+func TestFetch(t *testing.T) {
+	repo := newRepo(t, "envs/ci.yaml", "first", "second")
+	cacheDir := t.TempDir()
+
+	var checkouts []*Checkout
+	for _, tt := range []struct{ ref, want string }{
+		{ref: "v1", want: "first"},
+		{ref: "", want: "second"},
+		{ref: "v2", want: "second"},
+	} {
+		src := &Source{Repository: "file://" + repo, Path: "envs/ci.yaml", Ref: tt.ref}
+		checkout, err := Fetch(context.Background(), src, cacheDir)
+		if err != nil {
+			t.Fatalf("Fetch(%q) error = %v", tt.ref, err)
+		}
+		data, err := os.ReadFile(checkout.File(src))
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Fetch(%q) content = %q, want %q", tt.ref, data, tt.want)
+		}
+		checkouts = append(checkouts, checkout)
+		if len(checkout.Commit) != 40 {
+			t.Errorf("Fetch(%q) commit = %q", tt.ref, checkout.Commit)
+		}
+	}
+
+	if _, err := Fetch(context.Background(), &Source{Repository: "file://" + repo, Ref: "missing"}, cacheDir); err == nil {
+		t.Error("Fetch() of a missing ref should fail")
+	}
+
+	// Files are read from the fetched commit, not from the shared worktree
+	// that later fetches checked out.
+	data, err := checkouts[0].ReadFile(context.Background(), &Source{Path: "envs/ci.yaml"})
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "first" {
+		t.Errorf("ReadFile() = %q, want %q", data, "first")
+	}
+}
+
+func TestFetchCommit(t *testing.T) {
+	repo := newRepo(t, "ci.yaml", "first", "second")
+	cacheDir := t.TempDir()
+
+	head, err := Fetch(context.Background(), &Source{Repository: "file://" + repo, Path: "ci.yaml"}, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if !IsCommit(head.Commit) || IsCommit("v1") {
+		t.Errorf("IsCommit() misclassifies %q or v1", head.Commit)
+	}
+
+	// The commit is in the cache: an unreachable remote does not matter.
+	src := &Source{Repository: "file://" + repo, Path: "ci.yaml", Ref: head.Commit}
+	if err := os.RemoveAll(repo); err != nil {
+		t.Fatal(err)
+	}
+	checkout, err := Fetch(context.Background(), src, cacheDir)
+	if err != nil {
+		t.Fatalf("Fetch(commit) error = %v", err)
+	}
+	if checkout.Commit != head.Commit {
+		t.Errorf("Fetch(commit) commit = %q, want %q", checkout.Commit, head.Commit)
+	}
+}
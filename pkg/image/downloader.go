@@ -17,6 +17,7 @@ package image
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -33,6 +34,8 @@ import (
 const (
 	defaultMaxRetries  = 3
 	defaultBaseBackoff = 1 * time.Second
+	// maxChecksumFileSize bounds the checksum files read by VerifySHA512URL.
+	maxChecksumFileSize = 4096
 )
 
 // Downloader handles HTTP downloads with retry logic and checksum verification.
@@ -234,6 +237,46 @@ func (d *Downloader) VerifyChecksum(filePath, expectedSHA256 string) error {
 	return nil
 }
 
+// VerifySHA512URL verifies that the file at filePath matches the SHA-512
+// checksum published at checksumURL, in the format of sha512sum.
+func (d *Downloader) VerifySHA512URL(ctx context.Context, filePath, checksumURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return &httpError{StatusCode: resp.StatusCode, Status: resp.Status, URL: checksumURL}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file: %w", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != 2*sha512.Size {
+		return fmt.Errorf("invalid SHA-512 checksum file %s", checksumURL)
+	}
+	expected := fields[0]
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file for checksum verification: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return &checksumMismatchError{FilePath: filePath, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
 // httpError represents an HTTP error response.
 type httpError struct {
 	StatusCode int
@@ -283,18 +283,24 @@ func (m *CacheManager) EnsureImage(ctx context.Context, name string, spec v1.Ima
 		return nil, fmt.Errorf("failed to download image: %w", err)
 	}
 
-	// Verify checksum if provided
-	if expectedSHA256 != "" {
-		if err := m.downloader.VerifyChecksum(localPath, expectedSHA256); err != nil {
-			// Remove corrupted file
-			_ = os.Remove(localPath)
-			// Update metadata to failed status
-			m.mu.Lock()
-			m.metadata.Images[key].Status = StatusFailed
-			_ = m.saveMetadata()
-			m.mu.Unlock()
-			return nil, fmt.Errorf("checksum verification failed: %w", err)
-		}
+	// Verify checksum if provided, else the checksum published with a
+	// well-known image
+	var verifyErr error
+	switch {
+	case expectedSHA256 != "":
+		verifyErr = m.downloader.VerifyChecksum(localPath, expectedSHA256)
+	case isWellKnown && wellKnown.SHA512URL != "":
+		verifyErr = m.downloader.VerifySHA512URL(ctx, localPath, wellKnown.SHA512URL)
+	}
+	if verifyErr != nil {
+		// Remove corrupted file
+		_ = os.Remove(localPath)
+		// Update metadata to failed status
+		m.mu.Lock()
+		m.metadata.Images[key].Status = StatusFailed
+		_ = m.saveMetadata()
+		m.mu.Unlock()
+		return nil, fmt.Errorf("checksum verification failed: %w", verifyErr)
 	}
 
 	// Get file info
@@ -19,6 +19,7 @@ package image
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"net/http"
@@ -367,6 +368,54 @@ func TestEnsureImage_WellKnown(t *testing.T) {
 	}
 }
 
+func TestEnsureImage_WellKnownSHA512URL(t *testing.T) {
+	t.Cleanup(ResetRegistry)
+
+	imageContent := "alpine cloud image content"
+	h := sha512.Sum512([]byte(imageContent))
+	checksum := hex.EncodeToString(h[:])
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/alpine.qcow2":
+			_, _ = w.Write([]byte(imageContent))
+		case "/alpine.qcow2.sha512":
+			_, _ = w.Write([]byte(checksum + "  alpine.qcow2\n"))
+		default:
+			_, _ = w.Write([]byte(strings.Repeat("0", 2*sha512.Size) + "  alpine.qcow2\n"))
+		}
+	}))
+	defer server.Close()
+
+	SetRegistry(map[string]WellKnownImage{
+		"alpine": {Reference: "alpine", URL: server.URL + "/alpine.qcow2", SHA512URL: server.URL + "/alpine.qcow2.sha512"},
+		"broken": {Reference: "broken", URL: server.URL + "/alpine.qcow2", SHA512URL: server.URL + "/wrong.sha512"},
+	})
+
+	downloader := NewDownloader(
+		WithHTTPClient(server.Client()),
+		WithMaxRetries(1),
+		WithBaseBackoff(1*time.Millisecond),
+	)
+	m, err := NewCacheManager(filepath.Join(t.TempDir(), "cache"), WithDownloader(downloader))
+	if err != nil {
+		t.Fatalf("NewCacheManager() unexpected error: %v", err)
+	}
+
+	state, err := m.EnsureImage(context.Background(), "alpine", v1.ImageSpec{Source: "alpine"})
+	if err != nil {
+		t.Fatalf("EnsureImage() unexpected error: %v", err)
+	}
+	if state.Status != StatusReady {
+		t.Errorf("EnsureImage() status = %q, want %q", state.Status, StatusReady)
+	}
+
+	_, err = m.EnsureImage(context.Background(), "broken", v1.ImageSpec{Source: "broken"})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("EnsureImage() error = %v, want a checksum mismatch", err)
+	}
+}
+
 func TestEnsureImage_DirectURL(t *testing.T) {
 	t.Cleanup(ResetRegistry)
 
@@ -33,10 +33,30 @@ type WellKnownImage struct {
 	// is skipped unless the user explicitly provides a SHA256 override in their spec.
 	// For custom URLs, SHA256 is REQUIRED and enforced by the validator.
 	SHA256 string
+	// SHA512URL is the URL of the SHA-512 checksum published with an image
+	// that never changes, such as a release image. The image is verified
+	// against it when SHA256 is empty.
+	SHA512URL string
 	// Description is a human-readable description of the image.
 	Description string
 }
 
+// MinimalImage references a small image booting in seconds, for tests of
+// the VM lifecycle that do not need a full distribution: Alpine Linux with
+// cloud-init and sshd. It has no bash and no sudo; its users need a
+// /bin/sh shell and doas for privileged commands.
+const MinimalImage = "testenv:minimal"
+
+// Alpine Linux release behind MinimalImage. Unlike the cloud images of the
+// other distributions, Alpine cloud images are published per release, so
+// the release is bumped here. Release images never change, so the image is
+// verified against the SHA-512 checksum Alpine publishes next to it.
+const (
+	minimalAlpineBranch  = "3.21"
+	minimalAlpineRelease = minimalAlpineBranch + ".0"
+	minimalImageURL      = "https://dl-cdn.alpinelinux.org/alpine/v" + minimalAlpineBranch + "/releases/cloud/nocloud_alpine-" + minimalAlpineRelease + "-x86_64-bios-cloudinit-r0.qcow2"
+)
+
 // defaultRegistry contains the built-in well-known images.
 // This is the canonical source of truth for well-known image references.
 var defaultRegistry = map[string]WellKnownImage{
@@ -58,6 +78,12 @@ var defaultRegistry = map[string]WellKnownImage{
 		SHA256:      "", // Intentionally empty - cloud images update periodically
 		Description: "Debian 12 (Bookworm) Generic Cloud Image",
 	},
+	MinimalImage: {
+		Reference:   MinimalImage,
+		URL:         minimalImageURL,
+		SHA512URL:   minimalImageURL + ".sha512",
+		Description: "Alpine Linux " + minimalAlpineRelease + " NoCloud image with cloud-init and sshd, booting in seconds (no bash or sudo)",
+	},
 }
 
 // activeRegistry is the currently active registry used for lookups.
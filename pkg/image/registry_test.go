@@ -54,6 +54,14 @@ func TestResolve_WellKnownImage(t *testing.T) {
 			wantURLPrefix: "https://cloud.debian.org/images/cloud/bookworm/",
 			wantSHA256:    "", // Intentionally empty for well-known images
 		},
+		{
+			name:          "testenv minimal resolves to alpine",
+			source:        "testenv:minimal",
+			wantFound:     true,
+			wantReference: "testenv:minimal",
+			wantURLPrefix: "https://dl-cdn.alpinelinux.org/alpine/",
+			wantSHA256:    "", // Intentionally empty for well-known images
+		},
 	}
 
 	for _, tt := range tests {
@@ -129,6 +137,18 @@ func TestResolve_NotFound(t *testing.T) {
 	}
 }
 
+func TestResolve_MinimalImageIsVerified(t *testing.T) {
+	t.Cleanup(ResetRegistry)
+
+	img, ok := Resolve(MinimalImage)
+	if !ok {
+		t.Fatalf("Resolve(%q) not found", MinimalImage)
+	}
+	if img.SHA512URL != img.URL+".sha512" {
+		t.Errorf("Resolve(%q).SHA512URL = %q, want the checksum published with %q", MinimalImage, img.SHA512URL, img.URL)
+	}
+}
+
 func TestIsWellKnown_True(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -137,6 +157,7 @@ func TestIsWellKnown_True(t *testing.T) {
 		{name: "ubuntu 24.04", source: "ubuntu:24.04"},
 		{name: "ubuntu 22.04", source: "ubuntu:22.04"},
 		{name: "debian 12", source: "debian:12"},
+		{name: "testenv minimal", source: MinimalImage},
 	}
 
 	for _, tt := range tests {
@@ -179,8 +200,8 @@ func TestListWellKnown(t *testing.T) {
 	refs := ListWellKnown()
 
 	// Verify we get the expected count
-	if len(refs) != 3 {
-		t.Errorf("ListWellKnown() returned %d refs, want 3", len(refs))
+	if len(refs) != 4 {
+		t.Errorf("ListWellKnown() returned %d refs, want 4", len(refs))
 	}
 
 	// Verify the list is sorted
@@ -189,7 +210,7 @@ func TestListWellKnown(t *testing.T) {
 	}
 
 	// Verify all expected images are present
-	expected := []string{"debian:12", "ubuntu:22.04", "ubuntu:24.04"}
+	expected := []string{"debian:12", "testenv:minimal", "ubuntu:22.04", "ubuntu:24.04"}
 	for _, exp := range expected {
 		found := false
 		for _, ref := range refs {
@@ -328,10 +349,10 @@ func TestResetRegistry(t *testing.T) {
 		t.Error("Resolve('debian:12') should find default image after ResetRegistry")
 	}
 
-	// Verify count is back to 3
+	// Verify count is back to 4
 	refs := ListWellKnown()
-	if len(refs) != 3 {
-		t.Errorf("ListWellKnown() returned %d refs after reset, want 3", len(refs))
+	if len(refs) != 4 {
+		t.Errorf("ListWellKnown() returned %d refs after reset, want 4", len(refs))
 	}
 }
 
@@ -0,0 +1,176 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imagetest serves tiny generated qcow2 images over HTTPS, with
+// their checksums, so that tests of the image cache and the orchestrator
+// pipeline do not download multi-GB cloud images. The images are valid
+// qcow2 disks and backing files but do not boot; tests that need a guest
+// still use a real image.
+package imagetest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+)
+
+// DefaultVirtualSize is the virtual size of the images added by Add.
+const DefaultVirtualSize = 1 << 30
+
+// Image is an image served by a Server.
+type Image struct {
+	// Name is the file name of the image, e.g. "tiny.qcow2".
+	Name string
+	// URL is the HTTPS URL of the image.
+	URL string
+	// SHA256 is the hex-encoded checksum of the image.
+	SHA256 string
+	// Data is the content of the image.
+	Data []byte
+}
+
+// Spec returns an image spec downloading the image and verifying its
+// checksum.
+func (i Image) Spec() v1.ImageSpec {
+	return v1.ImageSpec{Source: i.URL, Sha256: i.SHA256}
+}
+
+// Server is an HTTPS server of generated images. Its certificate is only
+// trusted by Client, so components under test must use it, e.g. through
+// Downloader or orchestrator.Config.HTTPClient.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	images   map[string]Image
+	requests map[string]int
+	failures map[string]int
+}
+
+// NewServer starts a Server closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		images:   make(map[string]Image),
+		requests: make(map[string]int),
+		failures: make(map[string]int),
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Add generates an image of DefaultVirtualSize served as name, e.g.
+// "tiny.qcow2", and returns it.
+func (s *Server) Add(t testing.TB, name string) Image {
+	t.Helper()
+	return s.AddSized(t, name, DefaultVirtualSize)
+}
+
+// AddSized generates an image of virtualSize bytes served as name and
+// returns it.
+func (s *Server) AddSized(t testing.TB, name string, virtualSize int64) Image {
+	t.Helper()
+	data, err := QCOW2(virtualSize)
+	if err != nil {
+		t.Fatalf("imagetest: %v", err)
+	}
+	return s.AddData(name, data)
+}
+
+// AddData serves data as name and returns the image, for tests that need
+// other content, such as a corrupted image.
+func (s *Server) AddData(name string, data []byte) Image {
+	sum := sha256.Sum256(data)
+	img := Image{
+		Name:   name,
+		URL:    s.URL + "/" + name,
+		SHA256: hex.EncodeToString(sum[:]),
+		Data:   data,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.images[name] = img
+	return img
+}
+
+// Fail makes the next n requests of name fail with 503 Service
+// Unavailable, which downloaders retry.
+func (s *Server) Fail(name string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[name] = n
+}
+
+// Requests returns the number of requests of name, failed ones included.
+func (s *Server) Requests(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[name]
+}
+
+// Downloader returns an image downloader trusting the server, retrying
+// without backoff.
+func (s *Server) Downloader(opts ...image.DownloaderOption) *image.Downloader {
+	return image.NewDownloader(append([]image.DownloaderOption{
+		image.WithHTTPClient(s.Client()),
+		image.WithBaseBackoff(0),
+	}, opts...)...)
+}
+
+// CACertFile writes the certificate of the server to a PEM file in a
+// temporary directory of the test and returns its path, for processes
+// such as providers that trust the certificates of SSL_CERT_FILE.
+func (s *Server) CACertFile(t testing.TB) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "imagetest-ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("imagetest: failed to write CA certificate: %v", err)
+	}
+	return path
+}
+
+// serve serves the images by name.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	s.mu.Lock()
+	s.requests[name]++
+	img, ok := s.images[name]
+	fail := s.failures[name] > 0
+	if fail {
+		s.failures[name]--
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok:
+		http.NotFound(w, r)
+	case fail:
+		http.Error(w, "imagetest: injected failure", http.StatusServiceUnavailable)
+	default:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(img.Data)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer(t)
+	img := server.Add(t, "tiny.qcow2")
+	if !strings.HasPrefix(img.URL, "https://") {
+		t.Errorf("URL = %q, want HTTPS", img.URL)
+	}
+	mgr, err := image.NewCacheManager(t.TempDir(), image.WithDownloader(server.Downloader()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	server.Fail(img.Name, 1)
+	for range 2 {
+		state, err := mgr.EnsureImage(ctx, "tiny", img.Spec())
+		if err != nil {
+			t.Fatalf("EnsureImage() error = %v", err)
+		}
+		if state.SHA256 != img.SHA256 {
+			t.Errorf("SHA256 = %s, want %s", state.SHA256, img.SHA256)
+		}
+		if data, err := os.ReadFile(state.LocalPath); err != nil || len(data) != len(img.Data) {
+			t.Errorf("cached image = %d bytes, %v, want %d bytes", len(data), err, len(img.Data))
+		}
+	}
+	// One failed and one successful download; the second call hits the cache
+	if n := server.Requests(img.Name); n != 2 {
+		t.Errorf("Requests() = %d, want 2", n)
+	}
+
+	corrupted := server.AddData("corrupted.qcow2", []byte("not an image"))
+	spec := v1.ImageSpec{Source: corrupted.URL, Sha256: img.SHA256}
+	if _, err := mgr.EnsureImage(ctx, "corrupted", spec); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("EnsureImage() of a corrupted image error = %v, want a checksum error", err)
+	}
+	missing := v1.ImageSpec{Source: server.URL + "/missing.qcow2"}
+	if _, err := mgr.EnsureImage(ctx, "missing", missing); err == nil {
+		t.Error("EnsureImage() of a missing image succeeded")
+	}
+}
+
+func TestServer_CACertFile(t *testing.T) {
+	server := NewServer(t)
+	data, err := os.ReadFile(server.CACertFile(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		t.Fatal("CA certificate file holds no PEM block")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil || !cert.Equal(server.Certificate()) {
+		t.Errorf("CA certificate = %v, %v, want the server certificate", cert, err)
+	}
+}
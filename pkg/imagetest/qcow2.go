@@ -0,0 +1,79 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// qcow2 settings of the generated images.
+const (
+	qcow2Magic        = 0x514649fb // "QFI\xfb"
+	qcow2Version      = 3
+	qcow2ClusterBits  = 16
+	qcow2ClusterSize  = 1 << qcow2ClusterBits
+	qcow2HeaderLength = 104
+	// qcow2RefcountOrder gives 16-bit refcounts.
+	qcow2RefcountOrder = 4
+	// qcow2L2Coverage is the guest size covered by one L2 table.
+	qcow2L2Coverage = qcow2ClusterSize / 8 * qcow2ClusterSize
+)
+
+// Clusters of the generated images, in file order. The images have no data
+// clusters, so every guest sector reads as zero.
+const (
+	headerCluster = iota
+	l1Cluster
+	refcountTableCluster
+	refcountBlockCluster
+	qcow2Clusters
+)
+
+// MaxVirtualSize is the largest virtual size of a generated image, bounded
+// by the L1 table fitting in one cluster.
+const MaxVirtualSize = qcow2ClusterSize / 8 * qcow2L2Coverage
+
+// QCOW2 generates an empty qcow2 (version 3) image of virtualSize bytes. It
+// is 256 KiB whatever its virtual size, is accepted by qemu-img and QEMU as
+// a disk or a backing file, and does not boot.
+func QCOW2(virtualSize int64) ([]byte, error) {
+	if virtualSize <= 0 || virtualSize > MaxVirtualSize {
+		return nil, fmt.Errorf("virtual size %d out of range (1 to %d bytes)", virtualSize, int64(MaxVirtualSize))
+	}
+	data := make([]byte, qcow2Clusters*qcow2ClusterSize)
+	be := binary.BigEndian
+
+	header := data[headerCluster*qcow2ClusterSize:]
+	be.PutUint32(header[0:], qcow2Magic)
+	be.PutUint32(header[4:], qcow2Version)
+	be.PutUint32(header[20:], qcow2ClusterBits)
+	be.PutUint64(header[24:], uint64(virtualSize))
+	be.PutUint32(header[36:], uint32((virtualSize+qcow2L2Coverage-1)/qcow2L2Coverage))
+	be.PutUint64(header[40:], l1Cluster*qcow2ClusterSize)
+	be.PutUint64(header[48:], refcountTableCluster*qcow2ClusterSize)
+	be.PutUint32(header[56:], 1)
+	be.PutUint32(header[96:], qcow2RefcountOrder)
+	be.PutUint32(header[100:], qcow2HeaderLength)
+	// The header extensions following the header end with an all-zero
+	// marker, and the L1 table is all zero: no L2 table is allocated.
+
+	be.PutUint64(data[refcountTableCluster*qcow2ClusterSize:], refcountBlockCluster*qcow2ClusterSize)
+	refcounts := data[refcountBlockCluster*qcow2ClusterSize:]
+	for cluster := range qcow2Clusters {
+		be.PutUint16(refcounts[cluster*2:], 1)
+	}
+	return data, nil
+}
@@ -0,0 +1,67 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagetest
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestQCOW2(t *testing.T) {
+	const size = 3 << 30
+	data, err := QCOW2(size)
+	if err != nil {
+		t.Fatalf("QCOW2() error = %v", err)
+	}
+	if len(data) != 256<<10 {
+		t.Errorf("image is %d bytes, want 256 KiB", len(data))
+	}
+	be := binary.BigEndian
+	for _, f := range []struct {
+		name      string
+		got, want uint64
+	}{
+		{"magic", uint64(be.Uint32(data[0:])), qcow2Magic},
+		{"version", uint64(be.Uint32(data[4:])), 3},
+		{"cluster bits", uint64(be.Uint32(data[20:])), 16},
+		{"size", be.Uint64(data[24:]), size},
+		{"l1 size", uint64(be.Uint32(data[36:])), 6},
+		{"l1 table offset", be.Uint64(data[40:]), 1 << 16},
+		{"refcount table offset", be.Uint64(data[48:]), 2 << 16},
+		{"refcount block offset", be.Uint64(data[2<<16:]), 3 << 16},
+		{"header length", uint64(be.Uint32(data[100:])), 104},
+	} {
+		if f.got != f.want {
+			t.Errorf("%s = %d, want %d", f.name, f.got, f.want)
+		}
+	}
+	for cluster := range 5 {
+		want := uint16(1)
+		if cluster == 4 {
+			want = 0
+		}
+		if got := be.Uint16(data[3<<16+cluster*2:]); got != want {
+			t.Errorf("refcount of cluster %d = %d, want %d", cluster, got, want)
+		}
+	}
+
+	for _, size := range []int64{0, MaxVirtualSize + 1} {
+		if _, err := QCOW2(size); err == nil {
+			t.Errorf("QCOW2(%d) succeeded", size)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netboot implements minimal DHCP (RFC 2131) and TFTP (RFC 1350)
+// servers: enough to hand out addresses, reservations and network boot
+// files to the VMs of a network, so that networks can be fully managed on
+// hosts without dnsmasq.
+package netboot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// ErrUnsupported is returned on platforms where the servers cannot bind to
+// a network interface.
+var ErrUnsupported = errors.New("netboot is only supported on linux")
+
+// DHCP ports.
+const (
+	DHCPServerPort = 67
+	DHCPClientPort = 68
+)
+
+// DefaultLeaseTime is the lifetime of leases when DHCPConfig.LeaseTime is
+// zero.
+const DefaultLeaseTime = time.Hour
+
+// offerTimeout is how long an offered address is held for the client
+// before it may be offered to another one.
+const offerTimeout = time.Minute
+
+// DHCP message types (option 53).
+const (
+	msgDiscover byte = 1
+	msgOffer    byte = 2
+	msgRequest  byte = 3
+	msgDecline  byte = 4
+	msgAck      byte = 5
+	msgNak      byte = 6
+	msgRelease  byte = 7
+	msgInform   byte = 8
+)
+
+// DHCP options.
+const (
+	optPad         byte = 0
+	optSubnetMask  byte = 1
+	optRouter      byte = 3
+	optDNSServers  byte = 6
+	optHostname    byte = 12
+	optDomainName  byte = 15
+	optNTPServers  byte = 42
+	optRequestedIP byte = 50
+	optLeaseTime   byte = 51
+	optMessageType byte = 53
+	optServerID    byte = 54
+	optTFTPServer  byte = 66
+	optBootFile    byte = 67
+	optClientArch  byte = 93
+	optEnd         byte = 255
+)
+
+// Host is an address reserved for a MAC address.
+type Host struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// DHCPConfig configures a DHCP server.
+type DHCPConfig struct {
+	// ServerIP is the address of the server on the network, sent as DHCP
+	// server identifier.
+	ServerIP string `json:"serverIP"`
+	// CIDR is the IPv4 subnet of the network.
+	CIDR string `json:"cidr"`
+	// RangeStart and RangeEnd bound the addresses handed out to clients
+	// without a reservation.
+	RangeStart string `json:"rangeStart"`
+	RangeEnd   string `json:"rangeEnd"`
+	// LeaseTime is the lifetime of leases. Zero means DefaultLeaseTime.
+	LeaseTime time.Duration `json:"leaseTime,omitempty"`
+	// Router is advertised as the default gateway, if set.
+	Router string `json:"router,omitempty"`
+	// DNSServers, NTPServers and Domain are advertised when set.
+	DNSServers []string `json:"dnsServers,omitempty"`
+	NTPServers []string `json:"ntpServers,omitempty"`
+	Domain     string   `json:"domain,omitempty"`
+	// Hosts are the addresses reserved for MAC addresses. They may lie
+	// outside of the range.
+	Hosts []Host `json:"hosts,omitempty"`
+	// NextServer is the TFTP server of network boot clients, which load
+	// BootFile, or BootFileEFI on UEFI firmware when set.
+	NextServer  string `json:"nextServer,omitempty"`
+	BootFile    string `json:"bootFile,omitempty"`
+	BootFileEFI string `json:"bootFileEfi,omitempty"`
+	// Options are further options sent to every client, by option code.
+	Options map[byte]string `json:"options,omitempty"`
+	// LeaseFile keeps the leases across restarts and tells them to other
+	// processes (see ReadLeases), if set.
+	LeaseFile string `json:"leaseFile,omitempty"`
+}
+
+// DHCPServer hands out the addresses of a subnet.
+type DHCPServer struct {
+	config     DHCPConfig
+	serverIP   netip.Addr
+	subnet     netip.Prefix
+	rangeStart netip.Addr
+	rangeEnd   netip.Addr
+	router     netip.Addr
+	nextServer netip.Addr
+	dnsServers []netip.Addr
+	ntpServers []netip.Addr
+	leaseTime  time.Duration
+	// hosts are the reservations, by MAC address.
+	hosts map[string]Host
+	// now returns the current time; tests replace it.
+	now func() time.Time
+
+	mu sync.Mutex
+	// leases are the addresses offered or acknowledged, by MAC address.
+	leases map[string]*Lease
+}
+
+// NewDHCPServer validates config and returns a server, with the leases of
+// its lease file.
+func NewDHCPServer(config DHCPConfig) (*DHCPServer, error) {
+	s := &DHCPServer{
+		config:    config,
+		leaseTime: config.LeaseTime,
+		hosts:     make(map[string]Host),
+		leases:    make(map[string]*Lease),
+		now:       time.Now,
+	}
+	if s.leaseTime <= 0 {
+		s.leaseTime = DefaultLeaseTime
+	}
+
+	var err error
+	if s.subnet, err = netip.ParsePrefix(config.CIDR); err != nil || !s.subnet.Addr().Is4() {
+		return nil, fmt.Errorf("invalid CIDR %q: want an IPv4 prefix", config.CIDR)
+	}
+	s.subnet = s.subnet.Masked()
+	if s.serverIP, err = s.parseAddr("serverIP", config.ServerIP); err != nil {
+		return nil, err
+	}
+	if s.rangeStart, err = s.parseAddr("rangeStart", config.RangeStart); err != nil {
+		return nil, err
+	}
+	if s.rangeEnd, err = s.parseAddr("rangeEnd", config.RangeEnd); err != nil {
+		return nil, err
+	}
+	if s.rangeEnd.Less(s.rangeStart) {
+		return nil, fmt.Errorf("rangeEnd %s is before rangeStart %s", s.rangeEnd, s.rangeStart)
+	}
+	if config.Router != "" {
+		if s.router, err = parseIPv4("router", config.Router); err != nil {
+			return nil, err
+		}
+	}
+	if config.NextServer != "" {
+		if s.nextServer, err = parseIPv4("nextServer", config.NextServer); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range config.DNSServers {
+		addr, err := parseIPv4("dnsServers", a)
+		if err != nil {
+			return nil, err
+		}
+		s.dnsServers = append(s.dnsServers, addr)
+	}
+	for _, a := range config.NTPServers {
+		addr, err := parseIPv4("ntpServers", a)
+		if err != nil {
+			return nil, err
+		}
+		s.ntpServers = append(s.ntpServers, addr)
+	}
+	for _, h := range config.Hosts {
+		mac, err := net.ParseMAC(h.MAC)
+		if err != nil || len(mac) != 6 {
+			return nil, fmt.Errorf("host %q: invalid MAC address %q", h.IP, h.MAC)
+		}
+		if _, err := s.parseAddr("host", h.IP); err != nil {
+			return nil, err
+		}
+		s.hosts[mac.String()] = h
+	}
+	for code := range config.Options {
+		if code == optPad || code == optEnd || code == optMessageType || code == optServerID {
+			return nil, fmt.Errorf("option %d cannot be set", code)
+		}
+	}
+
+	if config.LeaseFile != "" {
+		leases, err := ReadLeases(config.LeaseFile)
+		if err != nil {
+			return nil, err
+		}
+		for i := range leases {
+			s.leases[leases[i].MAC] = &leases[i]
+		}
+	}
+	return s, nil
+}
+
+// parseAddr parses an IPv4 address of the subnet.
+func (s *DHCPServer) parseAddr(field, value string) (netip.Addr, error) {
+	addr, err := parseIPv4(field, value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if !s.subnet.Contains(addr) {
+		return netip.Addr{}, fmt.Errorf("%s %s is outside of %s", field, addr, s.subnet)
+	}
+	return addr, nil
+}
+
+// parseIPv4 parses an IPv4 address.
+func parseIPv4(field, value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, fmt.Errorf("invalid %s %q: want an IPv4 address", field, value)
+	}
+	return addr, nil
+}
+
+// handle answers a DHCP request. It returns the reply, if any, and where to
+// send it: the client address when it has one, the broadcast address
+// otherwise, as clients without an address cannot be reached by unicast
+// without ARP.
+func (s *DHCPServer) handle(packet []byte) ([]byte, *net.UDPAddr, error) {
+	req, err := parseMessage(packet)
+	if err != nil {
+		return nil, nil, err
+	}
+	dst := &net.UDPAddr{IP: net.IPv4bcast, Port: DHCPClientPort}
+	if req.ciaddr.IsValid() && !req.ciaddr.IsUnspecified() {
+		dst.IP = req.ciaddr.AsSlice()
+	}
+
+	mac := req.chaddr.String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	switch req.messageType() {
+	case msgDiscover:
+		ip, ok := s.allocate(mac, req.addrOption(optRequestedIP), now)
+		if !ok {
+			return nil, nil, fmt.Errorf("no free address for %s", mac)
+		}
+		lease := s.leases[mac]
+		if lease == nil || lease.IP != ip.String() || lease.Expires.Before(now.Add(offerTimeout)) {
+			s.leases[mac] = &Lease{MAC: mac, IP: ip.String(), Expires: now.Add(offerTimeout), offered: true}
+		}
+		return s.reply(req, msgOffer, ip), dst, nil
+
+	case msgRequest:
+		if id := req.addrOption(optServerID); id.IsValid() && id != s.serverIP {
+			// The client took the offer of another server
+			if lease := s.leases[mac]; lease != nil && lease.offered {
+				delete(s.leases, mac)
+			}
+			return nil, nil, nil
+		}
+		ip := req.addrOption(optRequestedIP)
+		if !ip.IsValid() {
+			ip = req.ciaddr
+		}
+		if !s.owns(mac, ip, now) {
+			return s.reply(req, msgNak, netip.Addr{}), &net.UDPAddr{IP: net.IPv4bcast, Port: DHCPClientPort}, nil
+		}
+		s.leases[mac] = &Lease{MAC: mac, IP: ip.String(), Hostname: req.hostname(), Expires: now.Add(s.leaseTime)}
+		if err := s.save(now); err != nil {
+			return nil, nil, err
+		}
+		return s.reply(req, msgAck, ip), dst, nil
+
+	case msgRelease, msgDecline:
+		if lease := s.leases[mac]; lease != nil {
+			delete(s.leases, mac)
+			return nil, nil, s.save(now)
+		}
+		return nil, nil, nil
+
+	case msgInform:
+		return s.reply(req, msgAck, netip.Addr{}), dst, nil
+	}
+	return nil, nil, nil
+}
+
+// allocate returns the address to offer to a client: its reservation, its
+// previous address, the address it requests, or the lowest free address of
+// the range, in that order.
+func (s *DHCPServer) allocate(mac string, requested netip.Addr, now time.Time) (netip.Addr, bool) {
+	if h, ok := s.hosts[mac]; ok {
+		return netip.MustParseAddr(h.IP), true
+	}
+	if lease := s.leases[mac]; lease != nil {
+		if ip, err := netip.ParseAddr(lease.IP); err == nil && s.inRange(ip) && s.free(ip, mac, now) {
+			return ip, true
+		}
+	}
+	if requested.IsValid() && s.inRange(requested) && s.free(requested, mac, now) {
+		return requested, true
+	}
+	for ip := s.rangeStart; ip.IsValid() && !s.rangeEnd.Less(ip); ip = ip.Next() {
+		if s.free(ip, mac, now) {
+			return ip, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// owns reports whether a client may use ip: its reservation, its lease, or
+// a free address of the range, e.g. requested again after a restart.
+func (s *DHCPServer) owns(mac string, ip netip.Addr, now time.Time) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	if h, ok := s.hosts[mac]; ok {
+		return h.IP == ip.String()
+	}
+	if lease := s.leases[mac]; lease != nil && lease.IP == ip.String() {
+		return true
+	}
+	return s.inRange(ip) && s.free(ip, mac, now)
+}
+
+// inRange reports whether ip lies in the dynamic range.
+func (s *DHCPServer) inRange(ip netip.Addr) bool {
+	return !ip.Less(s.rangeStart) && !s.rangeEnd.Less(ip)
+}
+
+// free reports whether ip can be handed out to mac: it is not an address
+// of the server, reserved for another MAC, or leased to another client.
+func (s *DHCPServer) free(ip netip.Addr, mac string, now time.Time) bool {
+	if ip == s.serverIP || ip == s.router || ip == s.nextServer {
+		return false
+	}
+	for hostMAC, h := range s.hosts {
+		if h.IP == ip.String() && hostMAC != mac {
+			return false
+		}
+	}
+	for leaseMAC, lease := range s.leases {
+		if lease.IP == ip.String() && leaseMAC != mac && lease.Expires.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// reply builds a reply of the given type to req, handing out yiaddr when
+// valid.
+func (s *DHCPServer) reply(req *message, msgType byte, yiaddr netip.Addr) []byte {
+	resp := &message{
+		op:     bootReply,
+		xid:    req.xid,
+		flags:  req.flags,
+		ciaddr: req.ciaddr,
+		yiaddr: yiaddr,
+		chaddr: req.chaddr,
+	}
+	resp.addOption(optMessageType, msgType)
+	resp.addOption(optServerID, s.serverIP.AsSlice()...)
+	if msgType == msgNak {
+		return resp.marshal()
+	}
+	if yiaddr.IsValid() {
+		resp.addOption(optLeaseTime, uint32Bytes(uint32(s.leaseTime/time.Second))...)
+	}
+	mask := net.CIDRMask(s.subnet.Bits(), 32)
+	resp.addOption(optSubnetMask, mask...)
+	if s.router.IsValid() {
+		resp.addOption(optRouter, s.router.AsSlice()...)
+	}
+	if len(s.dnsServers) > 0 {
+		resp.addOption(optDNSServers, addrBytes(s.dnsServers)...)
+	}
+	if s.config.Domain != "" {
+		resp.addOption(optDomainName, []byte(s.config.Domain)...)
+	}
+	if len(s.ntpServers) > 0 {
+		resp.addOption(optNTPServers, addrBytes(s.ntpServers)...)
+	}
+	if h, ok := s.hosts[req.chaddr.String()]; ok && h.Hostname != "" {
+		resp.addOption(optHostname, []byte(h.Hostname)...)
+	}
+	if bootFile := s.bootFile(req); bootFile != "" {
+		resp.file = bootFile
+		resp.addOption(optBootFile, []byte(bootFile)...)
+		if s.nextServer.IsValid() {
+			resp.siaddr = s.nextServer
+			resp.addOption(optTFTPServer, []byte(s.nextServer.String())...)
+		}
+	}
+	for _, code := range sortedCodes(s.config.Options) {
+		resp.addOption(code, []byte(s.config.Options[code])...)
+	}
+	return resp.marshal()
+}
+
+// bootFile returns the boot file of a client: BootFileEFI for UEFI
+// firmware (client architectures 6 to 11 of RFC 4578 and its registry),
+// BootFile otherwise.
+func (s *DHCPServer) bootFile(req *message) string {
+	if arch := req.options[optClientArch]; len(arch) >= 2 && s.config.BootFileEFI != "" {
+		if code := int(arch[0])<<8 | int(arch[1]); code >= 6 && code <= 11 {
+			return s.config.BootFileEFI
+		}
+	}
+	return s.config.BootFile
+}
+
+// Leases returns the acknowledged leases that have not expired, sorted by
+// MAC address.
+func (s *DHCPServer) Leases() []Lease {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeLeases(s.now())
+}
+
+// Serve answers the DHCP requests read from conn until ctx is done. conn
+// must be able to send broadcasts on the network, as returned by
+// ListenDHCP.
+func (s *DHCPServer) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read DHCP request: %w", err)
+		}
+		reply, dst, err := s.handle(buf[:n])
+		if err != nil {
+			log.Printf("DHCP: %v", err)
+			continue
+		}
+		if reply == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(reply, dst); err != nil {
+			log.Printf("DHCP: failed to reply to %s: %v", dst, err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netboot
+
+import (
+	"net"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// request builds a DHCP request from mac with the given options.
+func request(t *testing.T, mac string, msgType byte, options ...option) []byte {
+	t.Helper()
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		t.Fatalf("ParseMAC() error = %v", err)
+	}
+	m := &message{op: bootRequest, xid: [4]byte{1, 2, 3, 4}, chaddr: hw}
+	m.addOption(optMessageType, msgType)
+	for _, o := range options {
+		m.addOption(o.code, o.value...)
+	}
+	return m.marshal()
+}
+
+// parseReply parses a reply of the server as a request, which has the same
+// format but for the operation.
+func parseReply(t *testing.T, b []byte) *message {
+	t.Helper()
+	if b == nil {
+		t.Fatal("no reply")
+	}
+	if b[0] != bootReply {
+		t.Fatalf("op = %d, want a BOOTP reply", b[0])
+	}
+	b[0] = bootRequest
+	m, err := parseMessage(b)
+	if err != nil {
+		t.Fatalf("parseMessage() error = %v", err)
+	}
+	return m
+}
+
+func newTestDHCPServer(t *testing.T, config DHCPConfig) *DHCPServer {
+	t.Helper()
+	if config.CIDR == "" {
+		config.CIDR = "192.168.50.0/24"
+		config.ServerIP = "192.168.50.1"
+		config.Router = "192.168.50.1"
+		config.RangeStart = "192.168.50.10"
+		config.RangeEnd = "192.168.50.11"
+	}
+	s, err := NewDHCPServer(config)
+	if err != nil {
+		t.Fatalf("NewDHCPServer() error = %v", err)
+	}
+	return s
+}
+
+func TestDHCPServer_lease(t *testing.T) {
+	leaseFile := filepath.Join(t.TempDir(), "net.leases")
+	s := newTestDHCPServer(t, DHCPConfig{})
+	s.config.LeaseFile = leaseFile
+	s.config.DNSServers = []string{"192.168.50.1"}
+	s.dnsServers = []netip.Addr{netip.MustParseAddr("192.168.50.1")}
+
+	const mac = "52:54:00:00:00:01"
+	reply, dst, err := s.handle(request(t, mac, msgDiscover))
+	if err != nil {
+		t.Fatalf("handle(DISCOVER) error = %v", err)
+	}
+	offer := parseReply(t, reply)
+	if offer.messageType() != msgOffer || offer.yiaddr.String() != "192.168.50.10" {
+		t.Fatalf("reply = type %d with %s, want an OFFER of 192.168.50.10", offer.messageType(), offer.yiaddr)
+	}
+	if !dst.IP.Equal(net.IPv4bcast) || dst.Port != DHCPClientPort {
+		t.Errorf("destination = %s, want the broadcast address", dst)
+	}
+	if got := offer.addrOption(optRouter); got.String() != "192.168.50.1" {
+		t.Errorf("router = %s", got)
+	}
+	if got := net.IP(offer.options[optSubnetMask]).String(); got != "255.255.255.0" {
+		t.Errorf("subnet mask = %s", got)
+	}
+	if got := offer.addrOption(optDNSServers); got.String() != "192.168.50.1" {
+		t.Errorf("DNS servers = %s", got)
+	}
+
+	// Another client is not offered the address held for the first one
+	other, _, err := s.handle(request(t, "52:54:00:00:00:02", msgDiscover))
+	if err != nil {
+		t.Fatalf("handle(DISCOVER) error = %v", err)
+	}
+	if got := parseReply(t, other).yiaddr.String(); got != "192.168.50.11" {
+		t.Errorf("second offer = %s, want 192.168.50.11", got)
+	}
+	if _, _, err := s.handle(request(t, "52:54:00:00:00:03", msgDiscover)); err == nil {
+		t.Error("handle(DISCOVER) of an exhausted range succeeded")
+	}
+
+	reply, _, err = s.handle(request(t, mac, msgRequest,
+		option{optServerID, []byte{192, 168, 50, 1}},
+		option{optRequestedIP, []byte{192, 168, 50, 10}},
+		option{optHostname, []byte("pxe-client")}))
+	if err != nil {
+		t.Fatalf("handle(REQUEST) error = %v", err)
+	}
+	ack := parseReply(t, reply)
+	if ack.messageType() != msgAck || ack.yiaddr.String() != "192.168.50.10" {
+		t.Fatalf("reply = type %d with %s, want an ACK of 192.168.50.10", ack.messageType(), ack.yiaddr)
+	}
+	if ip, err := LeaseOf(leaseFile, "52:54:00:00:00:01", time.Now()); err != nil || ip != "192.168.50.10" {
+		t.Errorf("LeaseOf() = %q, %v, want the acknowledged address", ip, err)
+	}
+
+	// A restarted server keeps the lease
+	restarted := newTestDHCPServer(t, DHCPConfig{
+		CIDR: "192.168.50.0/24", ServerIP: "192.168.50.1", RangeStart: "192.168.50.10", RangeEnd: "192.168.50.11", LeaseFile: leaseFile,
+	})
+	if leases := restarted.Leases(); len(leases) != 1 || leases[0].Hostname != "pxe-client" {
+		t.Errorf("Leases() = %+v, want the lease of pxe-client", leases)
+	}
+
+	if _, _, err := s.handle(request(t, mac, msgRelease)); err != nil {
+		t.Fatalf("handle(RELEASE) error = %v", err)
+	}
+	if ip, err := LeaseOf(leaseFile, mac, time.Now()); err != nil || ip != "" {
+		t.Errorf("LeaseOf() after release = %q, %v, want none", ip, err)
+	}
+}
+
+func TestDHCPServer_nak(t *testing.T) {
+	s := newTestDHCPServer(t, DHCPConfig{})
+	// An address of the range leased to another client
+	s.leases["52:54:00:00:00:02"] = &Lease{MAC: "52:54:00:00:00:02", IP: "192.168.50.10", Expires: time.Now().Add(time.Hour)}
+
+	for name, ip := range map[string][]byte{
+		"leased to another client": {192, 168, 50, 10},
+		"outside of the range":     {192, 168, 50, 200},
+		"of another subnet":        {10, 0, 0, 5},
+	} {
+		reply, _, err := s.handle(request(t, "52:54:00:00:00:01", msgRequest, option{optRequestedIP, ip}))
+		if err != nil {
+			t.Fatalf("%s: handle(REQUEST) error = %v", name, err)
+		}
+		if got := parseReply(t, reply).messageType(); got != msgNak {
+			t.Errorf("%s: reply type = %d, want NAK", name, got)
+		}
+	}
+
+	// Requests to another server are left to it
+	reply, _, err := s.handle(request(t, "52:54:00:00:00:01", msgRequest,
+		option{optServerID, []byte{192, 168, 50, 2}}, option{optRequestedIP, []byte{192, 168, 50, 11}}))
+	if err != nil || reply != nil {
+		t.Errorf("handle(REQUEST) for another server = %v, %v, want no reply", reply, err)
+	}
+}
+
+func TestDHCPServer_hostsAndBoot(t *testing.T) {
+	s := newTestDHCPServer(t, DHCPConfig{
+		CIDR:        "192.168.50.0/24",
+		ServerIP:    "192.168.50.1",
+		RangeStart:  "192.168.50.10",
+		RangeEnd:    "192.168.50.20",
+		Hosts:       []Host{{MAC: "52:54:00:AA:BB:CC", IP: "192.168.50.5", Hostname: "node"}},
+		NextServer:  "192.168.50.1",
+		BootFile:    "undionly.kpxe",
+		BootFileEFI: "ipxe.efi",
+		Options:     map[byte]string{17: "/srv/root"},
+	})
+
+	reply, _, err := s.handle(request(t, "52:54:00:aa:bb:cc", msgDiscover, option{optClientArch, []byte{0, 0}}))
+	if err != nil {
+		t.Fatalf("handle(DISCOVER) error = %v", err)
+	}
+	offer := parseReply(t, reply)
+	if offer.yiaddr.String() != "192.168.50.5" || string(offer.options[optHostname]) != "node" {
+		t.Errorf("offer = %s named %q, want the reservation 192.168.50.5 named node", offer.yiaddr, offer.options[optHostname])
+	}
+	if offer.siaddr.String() != "192.168.50.1" || string(offer.options[optBootFile]) != "undionly.kpxe" {
+		t.Errorf("boot = %s %q, want undionly.kpxe from 192.168.50.1", offer.siaddr, offer.options[optBootFile])
+	}
+	if string(offer.options[17]) != "/srv/root" {
+		t.Errorf("option 17 = %q", offer.options[17])
+	}
+
+	reply, _, err = s.handle(request(t, "52:54:00:00:00:09", msgDiscover, option{optClientArch, []byte{0, 7}}))
+	if err != nil {
+		t.Fatalf("handle(DISCOVER) error = %v", err)
+	}
+	if got := string(parseReply(t, reply).options[optBootFile]); got != "ipxe.efi" {
+		t.Errorf("boot file of a UEFI client = %q, want ipxe.efi", got)
+	}
+
+	// The reservation is not handed out to others, nor requested by them
+	reply, _, err = s.handle(request(t, "52:54:00:00:00:09", msgRequest, option{optRequestedIP, []byte{192, 168, 50, 5}}))
+	if err != nil {
+		t.Fatalf("handle(REQUEST) error = %v", err)
+	}
+	if got := parseReply(t, reply).messageType(); got != msgNak {
+		t.Errorf("reply to a request of another reservation = %d, want NAK", got)
+	}
+}
+
+func TestNewDHCPServer_invalid(t *testing.T) {
+	valid := DHCPConfig{CIDR: "192.168.50.0/24", ServerIP: "192.168.50.1", RangeStart: "192.168.50.10", RangeEnd: "192.168.50.20"}
+	tests := map[string]func(c *DHCPConfig){
+		"IPv6 CIDR":          func(c *DHCPConfig) { c.CIDR = "fd00::/64" },
+		"server outside":     func(c *DHCPConfig) { c.ServerIP = "10.0.0.1" },
+		"reversed range":     func(c *DHCPConfig) { c.RangeStart, c.RangeEnd = c.RangeEnd, c.RangeStart },
+		"invalid host MAC":   func(c *DHCPConfig) { c.Hosts = []Host{{MAC: "nope", IP: "192.168.50.5"}} },
+		"host outside":       func(c *DHCPConfig) { c.Hosts = []Host{{MAC: "52:54:00:00:00:01", IP: "10.0.0.5"}} },
+		"reserved option":    func(c *DHCPConfig) { c.Options = map[byte]string{optServerID: "x"} },
+		"invalid DNS server": func(c *DHCPConfig) { c.DNSServers = []string{"dns"} },
+	}
+	for name, mutate := range tests {
+		config := valid
+		mutate(&config)
+		if _, err := NewDHCPServer(config); err == nil {
+			t.Errorf("%s: NewDHCPServer() succeeded, want error", name)
+		}
+	}
+}
+
+func TestParseMessage_invalid(t *testing.T) {
+	valid := request(t, "52:54:00:00:00:01", msgDiscover)
+	tests := map[string][]byte{
+		"short":           valid[:100],
+		"reply":           append([]byte{bootReply}, valid[1:]...),
+		"no cookie":       append(append(append([]byte(nil), valid[:headerLen]...), 0, 0, 0, 0), valid[headerLen+4:]...),
+		"truncated":       append(append([]byte(nil), valid[:headerLen+4]...), optHostname, 10, 'a'),
+		"no message type": append(append([]byte(nil), valid[:headerLen+4]...), optEnd),
+	}
+	for name, b := range tests {
+		if _, err := parseMessage(b); err == nil {
+			t.Errorf("%s: parseMessage() succeeded, want error", name)
+		}
+	}
+}
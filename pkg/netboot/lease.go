@@ -0,0 +1,113 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netboot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Lease is an address handed out to a client.
+type Lease struct {
+	MAC      string    `json:"mac"`
+	IP       string    `json:"ip"`
+	Hostname string    `json:"hostname,omitempty"`
+	Expires  time.Time `json:"expires"`
+	// offered marks addresses offered but not yet acknowledged, which are
+	// not persisted.
+	offered bool
+}
+
+// activeLeases returns the acknowledged leases expiring after now, sorted
+// by MAC address.
+func (s *DHCPServer) activeLeases(now time.Time) []Lease {
+	leases := make([]Lease, 0, len(s.leases))
+	for _, lease := range s.leases {
+		if !lease.offered && lease.Expires.After(now) {
+			leases = append(leases, *lease)
+		}
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].MAC < leases[j].MAC })
+	return leases
+}
+
+// save writes the active leases to the lease file, if any.
+func (s *DHCPServer) save(now time.Time) error {
+	if s.config.LeaseFile == "" {
+		return nil
+	}
+	return WriteLeases(s.config.LeaseFile, s.activeLeases(now))
+}
+
+// ReadLeases reads a lease file. A missing file has no leases.
+func ReadLeases(path string) ([]Lease, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leases: %w", err)
+	}
+	var leases []Lease
+	if err := json.Unmarshal(data, &leases); err != nil {
+		return nil, fmt.Errorf("invalid lease file %s: %w", path, err)
+	}
+	return leases, nil
+}
+
+// WriteLeases writes a lease file atomically, so that readers never see a
+// partial file.
+func WriteLeases(path string, leases []Lease) error {
+	data, err := json.MarshalIndent(leases, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create lease directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write leases: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write leases: %w", err)
+	}
+	return nil
+}
+
+// LeaseOf returns the address leased to a MAC address in a lease file, or
+// an empty string.
+func LeaseOf(path, mac string, now time.Time) (string, error) {
+	if hw, err := net.ParseMAC(mac); err == nil {
+		mac = hw.String()
+	}
+	leases, err := ReadLeases(path)
+	if err != nil {
+		return "", err
+	}
+	for _, lease := range leases {
+		if lease.MAC == mac && lease.Expires.After(now) {
+			return lease.IP, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,209 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netboot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// BOOTP operations.
+const (
+	bootRequest byte = 1
+	bootReply   byte = 2
+)
+
+const (
+	// headerLen is the size of the fixed part of a DHCP message, before
+	// the magic cookie.
+	headerLen = 236
+	// minMessageLen is the minimum size of a BOOTP message, which some
+	// clients expect replies to be padded to.
+	minMessageLen = 300
+	// hardwareEthernet is the htype of Ethernet MAC addresses.
+	hardwareEthernet = 1
+)
+
+// magicCookie starts the options of a DHCP message.
+var magicCookie = [4]byte{99, 130, 83, 99}
+
+// message is a DHCP message.
+type message struct {
+	op     byte
+	xid    [4]byte
+	flags  [2]byte
+	ciaddr netip.Addr
+	yiaddr netip.Addr
+	siaddr netip.Addr
+	chaddr net.HardwareAddr
+	file   string
+	// options are the options of a request, by code.
+	options map[byte][]byte
+	// order are the options of a reply, in order.
+	order []option
+}
+
+// option is a DHCP option.
+type option struct {
+	code  byte
+	value []byte
+}
+
+// parseMessage parses a DHCP request from an Ethernet client.
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < headerLen+len(magicCookie) {
+		return nil, fmt.Errorf("DHCP message too short: %d bytes", len(b))
+	}
+	if b[0] != bootRequest {
+		return nil, errors.New("not a BOOTP request")
+	}
+	if b[1] != hardwareEthernet || b[2] != 6 {
+		return nil, fmt.Errorf("unsupported hardware type %d with address length %d", b[1], b[2])
+	}
+	if [4]byte(b[headerLen:headerLen+4]) != magicCookie {
+		return nil, errors.New("missing DHCP magic cookie")
+	}
+	m := &message{
+		op:      b[0],
+		xid:     [4]byte(b[4:8]),
+		flags:   [2]byte(b[10:12]),
+		ciaddr:  netip.AddrFrom4([4]byte(b[12:16])),
+		yiaddr:  netip.AddrFrom4([4]byte(b[16:20])),
+		siaddr:  netip.AddrFrom4([4]byte(b[20:24])),
+		chaddr:  net.HardwareAddr(append([]byte(nil), b[28:34]...)),
+		options: make(map[byte][]byte),
+	}
+	opts := b[headerLen+4:]
+	for i := 0; i < len(opts); {
+		code := opts[i]
+		if code == optEnd {
+			break
+		}
+		if code == optPad {
+			i++
+			continue
+		}
+		if i+1 >= len(opts) || i+2+int(opts[i+1]) > len(opts) {
+			return nil, fmt.Errorf("truncated DHCP option %d", code)
+		}
+		n := int(opts[i+1])
+		// Long options are split into several instances (RFC 3396)
+		m.options[code] = append(m.options[code], opts[i+2:i+2+n]...)
+		i += 2 + n
+	}
+	if _, ok := m.options[optMessageType]; !ok {
+		return nil, errors.New("missing DHCP message type")
+	}
+	return m, nil
+}
+
+// messageType returns the DHCP message type of a request.
+func (m *message) messageType() byte {
+	if v := m.options[optMessageType]; len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+// addrOption returns the IPv4 address held by an option, if valid.
+func (m *message) addrOption(code byte) netip.Addr {
+	if v := m.options[code]; len(v) == 4 {
+		return netip.AddrFrom4([4]byte(v))
+	}
+	return netip.Addr{}
+}
+
+// hostname returns the host name sent by a client, if any.
+func (m *message) hostname() string {
+	return strings.TrimRight(string(m.options[optHostname]), "\x00")
+}
+
+// addOption appends an option to a reply, split into instances of at most
+// 255 bytes.
+func (m *message) addOption(code byte, value ...byte) {
+	for {
+		n := min(len(value), 255)
+		m.order = append(m.order, option{code: code, value: value[:n]})
+		value = value[n:]
+		if len(value) == 0 {
+			return
+		}
+	}
+}
+
+// marshal encodes a reply.
+func (m *message) marshal() []byte {
+	b := make([]byte, headerLen, minMessageLen)
+	b[0] = m.op
+	b[1] = hardwareEthernet
+	b[2] = byte(len(m.chaddr))
+	copy(b[4:8], m.xid[:])
+	copy(b[10:12], m.flags[:])
+	putAddr(b[12:16], m.ciaddr)
+	putAddr(b[16:20], m.yiaddr)
+	putAddr(b[20:24], m.siaddr)
+	copy(b[28:44], m.chaddr)
+	// A boot file name too long for the file field is only sent as option 67
+	if len(m.file) < 128 {
+		copy(b[108:236], m.file)
+	}
+	b = append(b, magicCookie[:]...)
+	for _, o := range m.order {
+		b = append(b, o.code, byte(len(o.value)))
+		b = append(b, o.value...)
+	}
+	b = append(b, optEnd)
+	for len(b) < minMessageLen {
+		b = append(b, optPad)
+	}
+	return b
+}
+
+// putAddr writes an IPv4 address, or zeros for an invalid one.
+func putAddr(b []byte, addr netip.Addr) {
+	if addr.Is4() {
+		a := addr.As4()
+		copy(b, a[:])
+	}
+}
+
+// addrBytes encodes a list of IPv4 addresses.
+func addrBytes(addrs []netip.Addr) []byte {
+	b := make([]byte, 0, 4*len(addrs))
+	for _, a := range addrs {
+		b = append(b, a.AsSlice()...)
+	}
+	return b
+}
+
+// uint32Bytes encodes a 32-bit option value.
+func uint32Bytes(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+// sortedCodes returns the codes of options in ascending order.
+func sortedCodes(options map[byte]string) []byte {
+	codes := make([]byte, 0, len(options))
+	for code := range options {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
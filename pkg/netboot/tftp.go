@@ -0,0 +1,296 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netboot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TFTPPort is the port of TFTP servers.
+const TFTPPort = 69
+
+// TFTP opcodes.
+const (
+	tftpRRQ   uint16 = 1
+	tftpWRQ   uint16 = 2
+	tftpDATA  uint16 = 3
+	tftpACK   uint16 = 4
+	tftpERROR uint16 = 5
+	tftpOACK  uint16 = 6
+)
+
+// TFTP error codes.
+const (
+	tftpErrUndefined uint16 = 0
+	tftpErrNotFound  uint16 = 1
+	tftpErrAccess    uint16 = 2
+	tftpErrIllegal   uint16 = 4
+)
+
+const (
+	// defaultBlockSize is the block size of transfers without the blksize
+	// option.
+	defaultBlockSize = 512
+	// maxBlockSize is the largest block size of RFC 2348.
+	maxBlockSize = 65464
+	// defaultTFTPTimeout is how long a block waits for its acknowledgment
+	// before it is sent again.
+	defaultTFTPTimeout = 3 * time.Second
+	// tftpRetries is how many times a block is sent before the transfer is
+	// abandoned.
+	tftpRetries = 5
+)
+
+// TFTPServer serves the files below a directory, read-only, with the
+// blksize (RFC 2348), tsize and timeout (RFC 2349) options network boot
+// loaders use. Paths cannot escape the directory, even through symbolic
+// links.
+type TFTPServer struct {
+	root *os.Root
+	// timeout is the default retransmission timeout.
+	timeout time.Duration
+}
+
+// requestOption is an option of a read request.
+type requestOption struct {
+	name  string
+	value string
+}
+
+// NewTFTPServer returns a server of the files below dir.
+func NewTFTPServer(dir string) (*TFTPServer, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open TFTP root: %w", err)
+	}
+	return &TFTPServer{root: root, timeout: defaultTFTPTimeout}, nil
+}
+
+// Close releases the root directory.
+func (s *TFTPServer) Close() error {
+	return s.root.Close()
+}
+
+// Serve answers the requests read from conn until ctx is done. Each
+// transfer runs from a port of its own, as the protocol requires.
+func (s *TFTPServer) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	localIP := net.IPv4zero
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		localIP = addr.IP
+	}
+	buf := make([]byte, 1500)
+	for {
+		n, client, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read TFTP request: %w", err)
+		}
+		req := bytes.Clone(buf[:n])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.transfer(ctx, localIP, client, req); err != nil {
+				log.Printf("TFTP: %s: %v", client, err)
+			}
+		}()
+	}
+}
+
+// transfer answers a request from a new port.
+func (s *TFTPServer) transfer(ctx context.Context, localIP net.IP, client net.Addr, req []byte) error {
+	conn, err := net.ListenPacket("udp4", net.JoinHostPort(localIP.String(), "0"))
+	if err != nil {
+		return fmt.Errorf("failed to open transfer port: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+	return s.send(conn, client, req)
+}
+
+// send answers a request on conn: it sends the requested file, or an error.
+func (s *TFTPServer) send(conn net.PacketConn, client net.Addr, req []byte) error {
+	opcode, name, options, err := parseRequest(req)
+	if err != nil {
+		sendError(conn, client, tftpErrIllegal, err.Error())
+		return err
+	}
+	if opcode == tftpWRQ {
+		sendError(conn, client, tftpErrAccess, "read-only server")
+		return fmt.Errorf("rejected write of %s", name)
+	}
+
+	// Clients send absolute and DOS-style paths alike
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, `\`, "/")), "/")
+	f, err := s.root.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			sendError(conn, client, tftpErrNotFound, "file not found")
+		} else {
+			sendError(conn, client, tftpErrAccess, "access violation")
+		}
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		sendError(conn, client, tftpErrNotFound, "file not found")
+		return fmt.Errorf("%s is not a regular file", name)
+	}
+
+	blockSize, timeout := defaultBlockSize, s.timeout
+	var accepted []requestOption
+	for _, o := range options {
+		n, err := strconv.Atoi(o.value)
+		switch {
+		case o.name == "blksize" && err == nil && n >= 8:
+			blockSize = min(n, maxBlockSize)
+			accepted = append(accepted, requestOption{o.name, strconv.Itoa(blockSize)})
+		case o.name == "timeout" && err == nil && n >= 1 && n <= 255:
+			timeout = time.Duration(n) * time.Second
+			accepted = append(accepted, o)
+		case o.name == "tsize":
+			accepted = append(accepted, requestOption{o.name, strconv.FormatInt(info.Size(), 10)})
+		}
+	}
+	if len(accepted) > 0 {
+		oack := binary.BigEndian.AppendUint16(nil, tftpOACK)
+		for _, o := range accepted {
+			oack = append(oack, o.name...)
+			oack = append(oack, 0)
+			oack = append(oack, o.value...)
+			oack = append(oack, 0)
+		}
+		if err := exchange(conn, client, oack, 0, timeout); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, blockSize)
+	for block := uint16(1); ; block++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			sendError(conn, client, tftpErrUndefined, "read error")
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		data := binary.BigEndian.AppendUint16(nil, tftpDATA)
+		data = binary.BigEndian.AppendUint16(data, block)
+		data = append(data, buf[:n]...)
+		if err := exchange(conn, client, data, block, timeout); err != nil {
+			return err
+		}
+		if n < blockSize {
+			return nil
+		}
+	}
+}
+
+// exchange sends a packet until the client acknowledges block. Block
+// numbers wrap around in files of more than 65535 blocks.
+func exchange(conn net.PacketConn, client net.Addr, packet []byte, block uint16, timeout time.Duration) error {
+	buf := make([]byte, 516)
+	for try := 0; try < tftpRetries; try++ {
+		if _, err := conn.WriteTo(packet, client); err != nil {
+			return fmt.Errorf("failed to send block %d: %w", block, err)
+		}
+		deadline := time.Now().Add(timeout)
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read acknowledgment: %w", err)
+			}
+			if addr.String() != client.String() || n < 4 {
+				continue
+			}
+			switch binary.BigEndian.Uint16(buf[:2]) {
+			case tftpACK:
+				if binary.BigEndian.Uint16(buf[2:4]) == block {
+					return nil
+				}
+				// Duplicate acknowledgments are not answered, which would
+				// duplicate every following block
+			case tftpERROR:
+				return fmt.Errorf("client aborted the transfer: %s", strings.TrimRight(string(buf[4:n]), "\x00"))
+			}
+		}
+	}
+	return fmt.Errorf("block %d not acknowledged after %d tries", block, tftpRetries)
+}
+
+// parseRequest parses a read or write request: the opcode, the file name
+// and the options, whose names are lowercased. Every transfer mode is
+// served as octet.
+func parseRequest(b []byte) (uint16, string, []requestOption, error) {
+	if len(b) < 2 {
+		return 0, "", nil, errors.New("request too short")
+	}
+	opcode := binary.BigEndian.Uint16(b[:2])
+	if opcode != tftpRRQ && opcode != tftpWRQ {
+		return 0, "", nil, fmt.Errorf("unexpected opcode %d", opcode)
+	}
+	fields := strings.Split(string(b[2:]), "\x00")
+	// The request ends with a NUL, leaving an empty last field
+	if len(fields) < 3 || fields[len(fields)-1] != "" || fields[0] == "" {
+		return 0, "", nil, errors.New("malformed request")
+	}
+	fields = fields[:len(fields)-1]
+	switch strings.ToLower(fields[1]) {
+	case "octet", "netascii":
+	default:
+		return 0, "", nil, fmt.Errorf("unsupported mode %q", fields[1])
+	}
+	var options []requestOption
+	for i := 2; i+1 < len(fields); i += 2 {
+		options = append(options, requestOption{name: strings.ToLower(fields[i]), value: fields[i+1]})
+	}
+	return opcode, fields[0], options, nil
+}
+
+// sendError sends an error packet, ending the transfer.
+func sendError(conn net.PacketConn, client net.Addr, code uint16, msg string) {
+	packet := binary.BigEndian.AppendUint16(nil, tftpERROR)
+	packet = binary.BigEndian.AppendUint16(packet, code)
+	packet = append(packet, msg...)
+	packet = append(packet, 0)
+	_, _ = conn.WriteTo(packet, client)
+}
@@ -0,0 +1,169 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netboot
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTFTPServer serves dir on a loopback port.
+func startTFTPServer(t *testing.T, dir string) net.Addr {
+	t.Helper()
+	s, err := NewTFTPServer(dir)
+	if err != nil {
+		t.Fatalf("NewTFTPServer() error = %v", err)
+	}
+	s.timeout = 200 * time.Millisecond
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, conn) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+		_ = s.Close()
+	})
+	return conn.LocalAddr()
+}
+
+// tftpRequest builds a request of name followed by options, alternating
+// names and values.
+func tftpRequest(opcode uint16, name string, options ...string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, opcode)
+	for _, field := range append([]string{name, "octet"}, options...) {
+		b = append(b, field...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// get sends req as a client reading blocks of blockSize bytes. It returns
+// the content and the acknowledged options, or the error message of the
+// server.
+func get(t *testing.T, server net.Addr, req []byte, blockSize int) ([]byte, string, string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.WriteTo(req, server); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	var content bytes.Buffer
+	var oack string
+	next := uint16(1)
+	buf := make([]byte, blockSize+4)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom() error = %v", err)
+		}
+		var block uint16
+		switch binary.BigEndian.Uint16(buf[:2]) {
+		case tftpERROR:
+			return nil, "", strings.TrimRight(string(buf[4:n]), "\x00")
+		case tftpOACK:
+			oack = strings.ReplaceAll(strings.TrimRight(string(buf[2:n]), "\x00"), "\x00", " ")
+		case tftpDATA:
+			block = binary.BigEndian.Uint16(buf[2:4])
+			if block == next {
+				content.Write(buf[4:n])
+				next++
+			}
+		}
+		ack := binary.BigEndian.AppendUint16(nil, tftpACK)
+		if _, err := conn.WriteTo(binary.BigEndian.AppendUint16(ack, block), addr); err != nil {
+			t.Fatalf("WriteTo() error = %v", err)
+		}
+		if block != 0 && n-4 < blockSize {
+			return content.Bytes(), oack, ""
+		}
+	}
+}
+
+func TestTFTPServer(t *testing.T) {
+	dir := t.TempDir()
+	kernel := bytes.Repeat([]byte("0123456789abcdef"), 200) // 3200 bytes
+	if err := os.MkdirAll(filepath.Join(dir, "pxelinux.cfg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vmlinuz"), kernel, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pxelinux.cfg", "default"), []byte("DEFAULT linux\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Exactly one block, followed by an empty one
+	if err := os.WriteFile(filepath.Join(dir, "block"), bytes.Repeat([]byte{1}, defaultBlockSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	server := startTFTPServer(t, dir)
+
+	content, oack, msg := get(t, server, tftpRequest(tftpRRQ, "vmlinuz"), defaultBlockSize)
+	if msg != "" || !bytes.Equal(content, kernel) || oack != "" {
+		t.Errorf("get(vmlinuz) = %d bytes, %q, %q, want the kernel without options", len(content), oack, msg)
+	}
+	content, oack, msg = get(t, server, tftpRequest(tftpRRQ, "/vmlinuz", "BLKSIZE", "1024", "tsize", "0"), 1024)
+	if msg != "" || !bytes.Equal(content, kernel) || oack != "blksize 1024 tsize 3200" {
+		t.Errorf("get(/vmlinuz) with options = %d bytes, %q, %q, want the kernel and both options", len(content), oack, msg)
+	}
+	content, _, msg = get(t, server, tftpRequest(tftpRRQ, `pxelinux.cfg\default`), defaultBlockSize)
+	if msg != "" || string(content) != "DEFAULT linux\n" {
+		t.Errorf("get(DOS path) = %q, %q", content, msg)
+	}
+	content, _, msg = get(t, server, tftpRequest(tftpRRQ, "block"), defaultBlockSize)
+	if msg != "" || len(content) != defaultBlockSize {
+		t.Errorf("get(block) = %d bytes, %q, want one full block", len(content), msg)
+	}
+
+	for name, req := range map[string][]byte{
+		"missing file":   tftpRequest(tftpRRQ, "missing"),
+		"directory":      tftpRequest(tftpRRQ, "pxelinux.cfg"),
+		"parent":         tftpRequest(tftpRRQ, "../../etc/passwd"),
+		"escaping link":  tftpRequest(tftpRRQ, "link"),
+		"write":          tftpRequest(tftpWRQ, "vmlinuz"),
+		"malformed":      {0, byte(tftpRRQ), 'x'},
+		"unknown opcode": tftpRequest(tftpDATA, "vmlinuz"),
+	} {
+		if _, _, msg := get(t, server, req, defaultBlockSize); msg == "" {
+			t.Errorf("%s: got a file, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Notifier backend types accepted in NotifierSpec.Type.
+const (
+	NotifierTypeSlack  = "slack"
+	NotifierTypeMatrix = "matrix"
+)
+
+// Environment variables used to configure chat notifiers without a spec entry.
+const (
+	EnvSlackWebhookURL   = "TESTENV_VM_SLACK_WEBHOOK_URL"
+	EnvMatrixHomeserver  = "TESTENV_VM_MATRIX_HOMESERVER"
+	EnvMatrixRoomID      = "TESTENV_VM_MATRIX_ROOM_ID"
+	EnvMatrixAccessToken = "TESTENV_VM_MATRIX_ACCESS_TOKEN"
+	// EnvArtifactURL provides a default artifact link (e.g., the CI job URL).
+	EnvArtifactURL = "TESTENV_VM_ARTIFACT_URL"
+)
+
+// maxSummaryErrorLength caps the error text included in chat summaries.
+const maxSummaryErrorLength = 500
+
+// NewNotifier creates a chat notifier from its spec.
+func NewNotifier(spec v1.NotifierSpec, opts ...Option) (Notifier, error) {
+	switch spec.Type {
+	case NotifierTypeSlack:
+		return NewSlack(spec, opts...)
+	case NotifierTypeMatrix:
+		return NewMatrix(spec, opts...)
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q (valid: slack, matrix)", spec.Type)
+	}
+}
+
+// NotifiersFromEnv returns chat notifiers configured purely via environment
+// variables, keyed by notifier type. Slack is enabled when
+// TESTENV_VM_SLACK_WEBHOOK_URL is set; Matrix when the homeserver, room ID
+// and access token variables are all set. Both default to failed events only.
+// It fails if the variables of an enabled notifier are invalid.
+func NotifiersFromEnv(opts ...Option) (map[string]Notifier, error) {
+	out := make(map[string]Notifier)
+	if os.Getenv(EnvSlackWebhookURL) != "" {
+		n, err := NewSlack(v1.NotifierSpec{Type: NotifierTypeSlack}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		out[NotifierTypeSlack] = n
+	}
+	if os.Getenv(EnvMatrixHomeserver) != "" && os.Getenv(EnvMatrixRoomID) != "" && os.Getenv(EnvMatrixAccessToken) != "" {
+		n, err := NewMatrix(v1.NotifierSpec{Type: NotifierTypeMatrix}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		out[NotifierTypeMatrix] = n
+	}
+	return out, nil
+}
+
+// envOr returns the value of the environment variable named by name, or the
+// value of fallback when name is empty.
+func envOr(name, fallback string) (string, string) {
+	if name == "" {
+		name = fallback
+	}
+	return name, os.Getenv(name)
+}
+
+// FormatSummary renders a compact, human-readable summary of event suitable
+// for chat messages: environment ID, stage, failed resource, first error and
+// artifact links.
+func FormatSummary(event Event, artifactURL string) string {
+	var sb strings.Builder
+
+	switch event.Type {
+	case EventQueued:
+		fmt.Fprintf(&sb, "testenv-vm: environment creation queued (position %d)", event.Position)
+	case EventFailed:
+		sb.WriteString("testenv-vm: environment creation FAILED")
+	case EventReady:
+		sb.WriteString("testenv-vm: environment ready")
+	case EventDestroyed:
+		sb.WriteString("testenv-vm: environment destroyed")
+	default:
+		sb.WriteString("testenv-vm: " + string(event.Type))
+	}
+	sb.WriteString("\n")
+
+	fmt.Fprintf(&sb, "Environment: %s\n", event.EnvironmentID)
+	if event.TestID != "" && event.TestID != event.EnvironmentID {
+		fmt.Fprintf(&sb, "Test ID: %s\n", event.TestID)
+	}
+	if event.Stage != "" {
+		fmt.Fprintf(&sb, "Stage: %s\n", event.Stage)
+	}
+	if event.FailedResource != "" {
+		fmt.Fprintf(&sb, "Failed resource: %s\n", event.FailedResource)
+	}
+	if len(event.Errors) > 0 {
+		msg := event.Errors[0]
+		if len(msg) > maxSummaryErrorLength {
+			msg = msg[:maxSummaryErrorLength] + "..."
+		}
+		fmt.Fprintf(&sb, "Error: %s\n", msg)
+		if len(event.Errors) > 1 {
+			fmt.Fprintf(&sb, "(+%d more errors)\n", len(event.Errors)-1)
+		}
+	}
+	if event.ArtifactDir != "" {
+		fmt.Fprintf(&sb, "Artifacts: %s\n", event.ArtifactDir)
+	}
+	if artifactURL == "" {
+		artifactURL = os.Getenv(EnvArtifactURL)
+	}
+	if artifactURL != "" {
+		fmt.Fprintf(&sb, "Link: %s\n", artifactURL)
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
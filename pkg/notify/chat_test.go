@@ -0,0 +1,228 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestFormatSummary(t *testing.T) {
+	event := Event{
+		Type:           EventFailed,
+		EnvironmentID:  "ci-4242-e2e",
+		TestID:         "test-xyz",
+		Stage:          "e2e",
+		FailedResource: "vm/web",
+		Errors:         []string{"vm_create failed: timeout", "second error"},
+		ArtifactDir:    "/tmp/artifacts/ci-4242-e2e",
+	}
+
+	got := FormatSummary(event, "https://ci.example.com/jobs/4242")
+
+	for _, want := range []string{
+		"FAILED",
+		"Environment: ci-4242-e2e",
+		"Test ID: test-xyz",
+		"Stage: e2e",
+		"Failed resource: vm/web",
+		"Error: vm_create failed: timeout",
+		"(+1 more errors)",
+		"Artifacts: /tmp/artifacts/ci-4242-e2e",
+		"Link: https://ci.example.com/jobs/4242",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatSummary() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFormatSummary_Queued(t *testing.T) {
+	got := FormatSummary(Event{Type: EventQueued, EnvironmentID: "ci-4243-e2e", Position: 3}, "")
+	if !strings.HasPrefix(got, "testenv-vm: environment creation queued (position 3)") {
+		t.Errorf("FormatSummary() = %q, want the queue position", got)
+	}
+}
+
+func TestFormatSummary_TruncatesLongErrors(t *testing.T) {
+	event := Event{Type: EventFailed, EnvironmentID: "x", Errors: []string{strings.Repeat("e", 2*maxSummaryErrorLength)}}
+	got := FormatSummary(event, "")
+	if len(got) > 3*maxSummaryErrorLength/2 {
+		t.Errorf("summary not truncated: %d bytes", len(got))
+	}
+	if !strings.Contains(got, "...") {
+		t.Error("truncated summary should end error with ellipsis")
+	}
+}
+
+func TestNewNotifier_UnknownType(t *testing.T) {
+	if _, err := NewNotifier(v1.NotifierSpec{Type: "irc"}); err == nil {
+		t.Error("NewNotifier() expected error for unknown type")
+	}
+}
+
+func TestSlack_Notify(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	t.Setenv("MY_SLACK_HOOK", server.URL)
+
+	s, err := NewSlack(v1.NotifierSpec{Type: NotifierTypeSlack, WebhookUrlEnv: "MY_SLACK_HOOK"})
+	if err != nil {
+		t.Fatalf("NewSlack() error = %v", err)
+	}
+	if s.Accepts(EventReady) {
+		t.Error("slack should default to failed events only")
+	}
+	if err := s.Notify(context.Background(), Event{Type: EventFailed, EnvironmentID: "ci-1"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !strings.Contains(payload["text"], "Environment: ci-1") {
+		t.Errorf("slack text = %q", payload["text"])
+	}
+}
+
+func TestSlack_Notify_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	hook := server.URL + "/services/T000/B000/secret-token"
+	t.Setenv(EnvSlackWebhookURL, hook)
+
+	s, err := NewSlack(v1.NotifierSpec{Type: NotifierTypeSlack})
+	if err != nil {
+		t.Fatalf("NewSlack() error = %v", err)
+	}
+	err = s.Notify(context.Background(), Event{Type: EventFailed, EnvironmentID: "ci-1"})
+	if err == nil {
+		t.Fatal("Notify() expected error for a 404 response")
+	}
+	if !strings.Contains(err.Error(), "404") {
+		t.Errorf("Notify() error = %v, want the response status", err)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Notify() error = %v, leaks the webhook URL", err)
+	}
+}
+
+func TestNotifiersFromEnv(t *testing.T) {
+	t.Setenv(EnvSlackWebhookURL, "")
+	t.Setenv(EnvMatrixHomeserver, "https://matrix.example.org")
+	t.Setenv(EnvMatrixRoomID, "!room:example.org")
+	t.Setenv(EnvMatrixAccessToken, "tok")
+
+	notifiers, err := NotifiersFromEnv()
+	if err != nil {
+		t.Fatalf("NotifiersFromEnv() error = %v", err)
+	}
+	if _, ok := notifiers[NotifierTypeMatrix]; !ok || len(notifiers) != 1 {
+		t.Errorf("NotifiersFromEnv() = %v, want the matrix notifier only", notifiers)
+	}
+
+	t.Setenv(EnvMatrixHomeserver, "http://[::1")
+	if _, err := NotifiersFromEnv(); err == nil {
+		t.Error("NotifiersFromEnv() expected error for an invalid homeserver")
+	}
+}
+
+func TestNewSlack_MissingURL(t *testing.T) {
+	t.Setenv(EnvSlackWebhookURL, "")
+	if _, err := NewSlack(v1.NotifierSpec{Type: NotifierTypeSlack}); err == nil {
+		t.Error("NewSlack() expected error when webhook URL is unset")
+	}
+}
+
+func TestMatrix_Notify(t *testing.T) {
+	var gotPath, gotAuth, gotMethod string
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotMethod = r.Method
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &payload)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvMatrixAccessToken, "tok")
+
+	m, err := NewMatrix(v1.NotifierSpec{
+		Type:       NotifierTypeMatrix,
+		Homeserver: server.URL + "/",
+		RoomId:     "!room:example.org",
+		Events:     []string{"failed", "ready"},
+	})
+	if err != nil {
+		t.Fatalf("NewMatrix() error = %v", err)
+	}
+	if !m.Accepts(EventReady) {
+		t.Error("matrix should accept explicitly configured ready events")
+	}
+	if err := m.Notify(context.Background(), Event{Type: EventFailed, EnvironmentID: "ci-1"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if gotMethod != http.MethodPut {
+		t.Errorf("method = %s, want PUT", gotMethod)
+	}
+	if !strings.HasPrefix(gotPath, "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+		t.Errorf("path = %s", gotPath)
+	}
+	if gotAuth != "Bearer tok" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if payload["msgtype"] != "m.text" || !strings.Contains(payload["body"], "ci-1") {
+		t.Errorf("payload = %v", payload)
+	}
+}
+
+func TestNewMatrix_MissingConfig(t *testing.T) {
+	t.Setenv(EnvMatrixHomeserver, "")
+	t.Setenv(EnvMatrixRoomID, "")
+	t.Setenv(EnvMatrixAccessToken, "")
+
+	tests := []struct {
+		name    string
+		spec    v1.NotifierSpec
+		wantErr string
+	}{
+		{name: "no homeserver", spec: v1.NotifierSpec{}, wantErr: "homeserver is required"},
+		{name: "no room", spec: v1.NotifierSpec{Homeserver: "https://m.org"}, wantErr: "roomId is required"},
+		{name: "no token", spec: v1.NotifierSpec{Homeserver: "https://m.org", RoomId: "!r:m.org"}, wantErr: "is not set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMatrix(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMatrix() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
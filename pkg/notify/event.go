@@ -0,0 +1,71 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import "fmt"
+
+// EventType identifies an environment lifecycle transition.
+type EventType string
+
+// Lifecycle events emitted by the orchestrator.
+const (
+	// EventQueued is emitted when a creation waits for a slot of the server,
+	// and again each time its position in the queue changes.
+	EventQueued EventType = "queued"
+	// EventReady is emitted when all resources of an environment are created.
+	EventReady EventType = "ready"
+	// EventFailed is emitted when environment creation fails.
+	EventFailed EventType = "failed"
+	// EventDestroyed is emitted when an environment has been deleted.
+	EventDestroyed EventType = "destroyed"
+)
+
+// AllEvents lists every event type in emission order.
+var AllEvents = []EventType{EventQueued, EventReady, EventFailed, EventDestroyed}
+
+// Event is the JSON payload delivered for a lifecycle transition.
+type Event struct {
+	// Type is the lifecycle transition.
+	Type EventType `json:"type"`
+	// EnvironmentID is the environment identifier (state file ID).
+	EnvironmentID string `json:"environmentId"`
+	// TestID is the forge testID that owns the environment.
+	TestID string `json:"testId,omitempty"`
+	// Stage is the test stage name.
+	Stage string `json:"stage,omitempty"`
+	// Timestamp is the RFC3339 time at which the transition happened.
+	Timestamp string `json:"timestamp"`
+	// Errors lists error messages for failed events.
+	Errors []string `json:"errors,omitempty"`
+	// FailedResource identifies the first resource that failed ("kind/name").
+	FailedResource string `json:"failedResource,omitempty"`
+	// ArtifactDir is the directory holding environment artifacts.
+	ArtifactDir string `json:"artifactDir,omitempty"`
+	// Client is the client that requested a queued creation.
+	Client string `json:"client,omitempty"`
+	// Position is the 1-based position of a queued creation: 1 is the next
+	// one to start.
+	Position int `json:"position,omitempty"`
+}
+
+// ParseEventType validates s and converts it to an EventType.
+func ParseEventType(s string) (EventType, error) {
+	for _, e := range AllEvents {
+		if string(e) == s {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q (valid: queued, ready, failed, destroyed)", s)
+}
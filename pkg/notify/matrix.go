@@ -0,0 +1,114 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Matrix posts event summaries into a Matrix room via the client-server API.
+type Matrix struct {
+	homeserver  string
+	roomID      string
+	accessToken string
+	artifactURL string
+	events      eventFilter
+	sender      sender
+}
+
+// NewMatrix creates a Matrix notifier. Homeserver and room ID fall back to
+// TESTENV_VM_MATRIX_HOMESERVER and TESTENV_VM_MATRIX_ROOM_ID; the access token
+// is read from the environment variable named by spec.AccessTokenEnv
+// (default TESTENV_VM_MATRIX_ACCESS_TOKEN).
+func NewMatrix(spec v1.NotifierSpec, opts ...Option) (*Matrix, error) {
+	homeserver := spec.Homeserver
+	if homeserver == "" {
+		homeserver = os.Getenv(EnvMatrixHomeserver)
+	}
+	if homeserver == "" {
+		return nil, fmt.Errorf("matrix notifier: homeserver is required (set homeserver or %s)", EnvMatrixHomeserver)
+	}
+	if _, err := url.Parse(homeserver); err != nil {
+		return nil, fmt.Errorf("matrix notifier: invalid homeserver %q: %w", homeserver, err)
+	}
+
+	roomID := spec.RoomId
+	if roomID == "" {
+		roomID = os.Getenv(EnvMatrixRoomID)
+	}
+	if roomID == "" {
+		return nil, fmt.Errorf("matrix notifier: roomId is required (set roomId or %s)", EnvMatrixRoomID)
+	}
+
+	envName, token := envOr(spec.AccessTokenEnv, EnvMatrixAccessToken)
+	if token == "" {
+		return nil, fmt.Errorf("matrix notifier: environment variable %s is not set", envName)
+	}
+
+	events, err := newEventFilter(spec.Events, EventFailed)
+	if err != nil {
+		return nil, fmt.Errorf("matrix notifier: %w", err)
+	}
+
+	return &Matrix{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		roomID:      roomID,
+		accessToken: token,
+		artifactURL: spec.ArtifactUrl,
+		events:      events,
+		sender:      newSender(buildOptions(opts)),
+	}, nil
+}
+
+// Accepts reports whether the notifier is subscribed to the given event type.
+func (m *Matrix) Accepts(t EventType) bool {
+	return m.events.accepts(t)
+}
+
+// Notify sends the event summary as an m.text message. Each call uses a fresh
+// transaction ID; retries reuse it so the homeserver deduplicates them.
+func (m *Matrix) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(map[string]string{
+		"msgtype": "m.text",
+		"body":    FormatSummary(event, m.artifactURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal matrix message: %w", err)
+	}
+
+	txnBytes := make([]byte, 8)
+	if _, err := rand.Read(txnBytes); err != nil {
+		return fmt.Errorf("failed to generate matrix transaction ID: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.homeserver, url.PathEscape(m.roomID), hex.EncodeToString(txnBytes))
+	headers := map[string]string{"Authorization": "Bearer " + m.accessToken}
+
+	if err := m.sender.do(ctx, http.MethodPut, endpoint, headers, body); err != nil {
+		return fmt.Errorf("matrix notifier: %w", err)
+	}
+	return nil
+}
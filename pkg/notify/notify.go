@@ -0,0 +1,302 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Default delivery settings shared by all HTTP-based notifiers.
+const (
+	defaultMaxRetries = 3
+	defaultTimeout    = 10 * time.Second
+	defaultBackoff    = 1 * time.Second
+	// defaultDeliveryTimeout bounds the delivery of one event to all
+	// notifiers, retries included, so that an unreachable endpoint does not
+	// hold up the operation that emitted the event.
+	defaultDeliveryTimeout = 15 * time.Second
+)
+
+// Notifier delivers lifecycle events to an external system.
+type Notifier interface {
+	// Accepts reports whether the notifier is subscribed to the event type.
+	Accepts(t EventType) bool
+	// Notify delivers the event.
+	Notify(ctx context.Context, event Event) error
+}
+
+// options holds settings applied to every notifier built by this package.
+type options struct {
+	httpClient      *http.Client
+	baseBackoff     time.Duration
+	deliveryTimeout time.Duration
+}
+
+// Option is a functional option for configuring notifiers.
+type Option func(*options)
+
+// WithHTTPClient sets a custom HTTP client.
+// This is primarily used for testing with httptest servers.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithBaseBackoff sets the base backoff between retries.
+// The actual backoff is baseBackoff * 2^(attempt-1).
+func WithBaseBackoff(backoff time.Duration) Option {
+	return func(o *options) {
+		o.baseBackoff = backoff
+	}
+}
+
+// WithDeliveryTimeout sets how long a Dispatcher delivers one event, retries
+// included, before giving up on the notifiers that have not succeeded yet.
+func WithDeliveryTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.deliveryTimeout = timeout
+	}
+}
+
+// buildOptions applies opts over the defaults.
+func buildOptions(opts []Option) options {
+	o := options{
+		httpClient:      http.DefaultClient,
+		baseBackoff:     defaultBackoff,
+		deliveryTimeout: defaultDeliveryTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// eventFilter is a set of subscribed event types. A nil filter accepts all events.
+type eventFilter map[EventType]bool
+
+// newEventFilter parses event names. An empty list yields the given defaults
+// (nil defaults meaning "all events").
+func newEventFilter(events []string, defaults ...EventType) (eventFilter, error) {
+	if len(events) == 0 {
+		if len(defaults) == 0 {
+			return nil, nil
+		}
+		f := make(eventFilter, len(defaults))
+		for _, e := range defaults {
+			f[e] = true
+		}
+		return f, nil
+	}
+	f := make(eventFilter, len(events))
+	for _, e := range events {
+		et, err := ParseEventType(e)
+		if err != nil {
+			return nil, err
+		}
+		f[et] = true
+	}
+	return f, nil
+}
+
+// accepts reports whether t passes the filter.
+func (f eventFilter) accepts(t EventType) bool {
+	return f == nil || f[t]
+}
+
+// sender performs HTTP deliveries with per-request timeout and exponential
+// backoff retry on network errors and 5xx responses.
+type sender struct {
+	httpClient  *http.Client
+	maxRetries  int
+	timeout     time.Duration
+	baseBackoff time.Duration
+}
+
+// newSender creates a sender with package defaults and the given options.
+func newSender(o options) sender {
+	return sender{
+		httpClient:  o.httpClient,
+		maxRetries:  defaultMaxRetries,
+		timeout:     defaultTimeout,
+		baseBackoff: o.baseBackoff,
+	}
+}
+
+// do sends the request, retrying transient failures. 4xx responses are not retried.
+func (s sender) do(ctx context.Context, method, url string, headers map[string]string, body []byte) error {
+	var lastErr error
+	attempts := s.maxRetries + 1
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			backoff := s.baseBackoff * time.Duration(1<<(attempt-1))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		retryable, err := s.doOnce(ctx, method, url, headers, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			return err
+		}
+	}
+
+	return fmt.Errorf("delivery to %s failed after %d attempts: %w", url, attempts, lastErr)
+}
+
+// doOnce performs a single attempt and reports whether a failure is worth retrying.
+func (s sender) doOnce(ctx context.Context, method, url string, headers map[string]string, body []byte) (bool, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		// Network errors are transient unless the parent context is done.
+		return ctx.Err() == nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	return resp.StatusCode >= 500, fmt.Errorf("%s %s returned %s", method, url, resp.Status)
+}
+
+// Dispatcher fans events out to all configured notifiers.
+type Dispatcher struct {
+	notifiers []Notifier
+	// timeout bounds the delivery of one event.
+	timeout time.Duration
+}
+
+// NewDispatcher creates a Dispatcher from the spec's webhooks and notifiers,
+// plus any chat notifiers configured purely through environment variables
+// (see NotifiersFromEnv). It fails if any entry is invalid so that
+// misconfiguration surfaces before resources are created.
+func NewDispatcher(spec *v1.Spec, opts ...Option) (*Dispatcher, error) {
+	d := &Dispatcher{timeout: buildOptions(opts).deliveryTimeout}
+	if spec == nil {
+		return d, nil
+	}
+
+	for i, ws := range spec.Webhooks {
+		w, err := NewWebhook(ws, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		d.notifiers = append(d.notifiers, w)
+	}
+
+	configured := make(map[string]bool)
+	for i, ns := range spec.Notifiers {
+		n, err := NewNotifier(ns, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+		configured[ns.Type] = true
+		d.notifiers = append(d.notifiers, n)
+	}
+
+	// Environment-only configuration applies when the spec does not already
+	// declare a notifier of the same type.
+	fromEnv, err := NotifiersFromEnv(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("notifiers from environment: %w", err)
+	}
+	for typ, n := range fromEnv {
+		if !configured[typ] {
+			d.notifiers = append(d.notifiers, n)
+		}
+	}
+
+	return d, nil
+}
+
+// NewDispatcherFromNotifiers creates a Dispatcher from pre-built notifiers,
+// with the default delivery timeout.
+func NewDispatcherFromNotifiers(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers, timeout: defaultDeliveryTimeout}
+}
+
+// Add subscribes more notifiers to the dispatcher.
+func (d *Dispatcher) Add(notifiers ...Notifier) {
+	d.notifiers = append(d.notifiers, notifiers...)
+}
+
+// Notify delivers event to every subscribed notifier in parallel and returns
+// the delivery errors. Deliveries still running when the delivery timeout
+// (see WithDeliveryTimeout) expires are cancelled and reported as errors. A
+// nil Dispatcher is a no-op.
+func (d *Dispatcher) Notify(ctx context.Context, event Event) []error {
+	if d == nil {
+		return nil
+	}
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, n := range d.notifiers {
+		if !n.Accepts(event.Type) {
+			continue
+		}
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			if err := n.Notify(ctx, event); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		log.Printf("Lifecycle notification failed: %v", err)
+	}
+	return errs
+}
@@ -0,0 +1,176 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// fakeNotifier records delivered events.
+type fakeNotifier struct {
+	accept EventType
+	calls  atomic.Int32
+	err    error
+}
+
+func (f *fakeNotifier) Accepts(t EventType) bool { return t == f.accept }
+
+func (f *fakeNotifier) Notify(_ context.Context, _ Event) error {
+	f.calls.Add(1)
+	return f.err
+}
+
+func TestDispatcher_Notify(t *testing.T) {
+	var readyCalls, failedCalls atomic.Int32
+	ready := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		readyCalls.Add(1)
+	}))
+	defer ready.Close()
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failedCalls.Add(1)
+	}))
+	defer failed.Close()
+
+	d, err := NewDispatcher(&v1.Spec{Webhooks: []v1.WebhookSpec{
+		{Url: ready.URL, Events: []string{"ready"}},
+		{Url: failed.URL, Events: []string{"failed"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+
+	if errs := d.Notify(context.Background(), Event{Type: EventReady}); len(errs) != 0 {
+		t.Fatalf("Notify() errors = %v", errs)
+	}
+	if readyCalls.Load() != 1 || failedCalls.Load() != 0 {
+		t.Errorf("ready=%d failed=%d, want 1/0", readyCalls.Load(), failedCalls.Load())
+	}
+
+	var nilDispatcher *Dispatcher
+	if errs := nilDispatcher.Notify(context.Background(), Event{Type: EventReady}); errs != nil {
+		t.Errorf("nil dispatcher Notify() = %v, want nil", errs)
+	}
+}
+
+func TestDispatcher_NotifyCollectsErrors(t *testing.T) {
+	ok := &fakeNotifier{accept: EventFailed}
+	bad := &fakeNotifier{accept: EventFailed, err: errors.New("boom")}
+	skipped := &fakeNotifier{accept: EventReady}
+
+	d := NewDispatcherFromNotifiers(ok, bad, skipped)
+	errs := d.Notify(context.Background(), Event{Type: EventFailed})
+
+	if len(errs) != 1 {
+		t.Fatalf("Notify() errors = %v, want 1", errs)
+	}
+	if ok.calls.Load() != 1 || bad.calls.Load() != 1 || skipped.calls.Load() != 0 {
+		t.Errorf("calls ok=%d bad=%d skipped=%d, want 1/1/0", ok.calls.Load(), bad.calls.Load(), skipped.calls.Load())
+	}
+}
+
+func TestDispatcher_NotifyDeliveryTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	d, err := NewDispatcher(&v1.Spec{Webhooks: []v1.WebhookSpec{{Url: hanging.URL}}},
+		WithDeliveryTimeout(100*time.Millisecond), WithBaseBackoff(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	start := time.Now()
+	if errs := d.Notify(context.Background(), Event{Type: EventReady}); len(errs) != 1 {
+		t.Errorf("Notify() errors = %v, want 1", errs)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify() took %s, want it bounded by the delivery timeout", elapsed)
+	}
+}
+
+func TestNewDispatcher_InvalidSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    *v1.Spec
+		wantErr string
+	}{
+		{
+			name:    "invalid webhook",
+			spec:    &v1.Spec{Webhooks: []v1.WebhookSpec{{Url: "https://ok"}, {Url: "://bad"}}},
+			wantErr: "webhooks[1]",
+		},
+		{
+			name:    "unknown notifier type",
+			spec:    &v1.Spec{Notifiers: []v1.NotifierSpec{{Type: "irc"}}},
+			wantErr: "notifiers[0]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDispatcher(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewDispatcher() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewDispatcher_NilSpec(t *testing.T) {
+	d, err := NewDispatcher(nil)
+	if err != nil {
+		t.Fatalf("NewDispatcher(nil) error = %v", err)
+	}
+	if errs := d.Notify(context.Background(), Event{Type: EventFailed}); len(errs) != 0 {
+		t.Errorf("Notify() errors = %v", errs)
+	}
+}
+
+func TestNewDispatcher_EnvNotifiers(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvSlackWebhookURL, server.URL)
+
+	d, err := NewDispatcher(&v1.Spec{})
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+
+	d.Notify(context.Background(), Event{Type: EventReady})
+	if calls.Load() != 0 {
+		t.Errorf("env-configured slack should default to failed events only, got %d calls", calls.Load())
+	}
+	d.Notify(context.Background(), Event{Type: EventFailed})
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, want 1", calls.Load())
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// redactedWebhookURL replaces the Slack webhook URL in errors.
+const redactedWebhookURL = "<slack webhook>"
+
+// Slack posts event summaries to a Slack incoming webhook.
+type Slack struct {
+	webhookURL  string
+	artifactURL string
+	events      eventFilter
+	sender      sender
+}
+
+// NewSlack creates a Slack notifier. The incoming webhook URL is read from the
+// environment variable named by spec.WebhookUrlEnv (default
+// TESTENV_VM_SLACK_WEBHOOK_URL) so that it never appears in specs or state.
+func NewSlack(spec v1.NotifierSpec, opts ...Option) (*Slack, error) {
+	envName, webhookURL := envOr(spec.WebhookUrlEnv, EnvSlackWebhookURL)
+	if webhookURL == "" {
+		return nil, fmt.Errorf("slack notifier: environment variable %s is not set", envName)
+	}
+
+	events, err := newEventFilter(spec.Events, EventFailed)
+	if err != nil {
+		return nil, fmt.Errorf("slack notifier: %w", err)
+	}
+
+	return &Slack{
+		webhookURL:  webhookURL,
+		artifactURL: spec.ArtifactUrl,
+		events:      events,
+		sender:      newSender(buildOptions(opts)),
+	}, nil
+}
+
+// Accepts reports whether the notifier is subscribed to the given event type.
+func (s *Slack) Accepts(t EventType) bool {
+	return s.events.accepts(t)
+}
+
+// Notify posts the event summary as a Slack message.
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(map[string]string{
+		"text": FormatSummary(event, s.artifactURL),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+	if err := s.sender.do(ctx, http.MethodPost, s.webhookURL, nil, body); err != nil {
+		// Avoid leaking the webhook URL (which embeds a secret token) in logs.
+		return fmt.Errorf("slack notifier: %s", s.redact(err.Error()))
+	}
+	return nil
+}
+
+// redact replaces the webhook URL in msg, as given and as re-encoded by
+// net/url, with a placeholder.
+func (s *Slack) redact(msg string) string {
+	msg = strings.ReplaceAll(msg, s.webhookURL, redactedWebhookURL)
+	if u, err := url.Parse(s.webhookURL); err == nil {
+		msg = strings.ReplaceAll(msg, u.String(), redactedWebhookURL)
+	}
+	return msg
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// HTTP headers set on every webhook delivery.
+const (
+	// HeaderSignature carries "sha256=<hex HMAC-SHA256 of the body>".
+	HeaderSignature = "X-Testenv-Signature"
+	// HeaderEvent carries the event type.
+	HeaderEvent = "X-Testenv-Event"
+)
+
+// Webhook delivers raw JSON events to a single HTTP endpoint.
+type Webhook struct {
+	url     string
+	headers map[string]string
+	events  eventFilter
+	secret  []byte
+	sender  sender
+}
+
+// NewWebhook creates a Webhook from its spec. The signing secret is read from
+// the environment variable named by spec.SecretEnv.
+func NewWebhook(spec v1.WebhookSpec, opts ...Option) (*Webhook, error) {
+	parsedURL, err := url.Parse(spec.Url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL %q: %w", spec.Url, err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("webhook URL %q must use http or https", spec.Url)
+	}
+
+	events, err := newEventFilter(spec.Events)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %q: %w", spec.Url, err)
+	}
+
+	w := &Webhook{
+		url:     spec.Url,
+		headers: spec.Headers,
+		events:  events,
+		sender:  newSender(buildOptions(opts)),
+	}
+
+	if spec.SecretEnv != "" {
+		secret := os.Getenv(spec.SecretEnv)
+		if secret == "" {
+			return nil, fmt.Errorf("webhook %q: secret environment variable %s is not set", spec.Url, spec.SecretEnv)
+		}
+		w.secret = []byte(secret)
+	}
+
+	if spec.MaxRetries > 0 {
+		w.sender.maxRetries = spec.MaxRetries
+	}
+
+	if spec.Timeout != "" {
+		d, err := time.ParseDuration(spec.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %q: invalid timeout %q: %w", spec.Url, spec.Timeout, err)
+		}
+		w.sender.timeout = d
+	}
+
+	return w, nil
+}
+
+// Accepts reports whether the webhook is subscribed to the given event type.
+func (w *Webhook) Accepts(t EventType) bool {
+	return w.events.accepts(t)
+}
+
+// Notify POSTs the event as JSON, retrying with exponential backoff on
+// network errors and 5xx responses. 4xx responses are not retried.
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	headers := make(map[string]string, len(w.headers)+2)
+	for k, v := range w.headers {
+		headers[k] = v
+	}
+	headers[HeaderEvent] = string(event.Type)
+	if w.secret != nil {
+		headers[HeaderSignature] = Sign(w.secret, body)
+	}
+
+	if err := w.sender.do(ctx, http.MethodPost, w.url, headers, body); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex-encoded HMAC-SHA256 of body keyed with secret. Receivers should compute
+// the same value and compare with hmac.Equal.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
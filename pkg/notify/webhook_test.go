@@ -0,0 +1,214 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestNewWebhook_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1.WebhookSpec
+		wantErr string
+	}{
+		{
+			name: "valid minimal",
+			spec: v1.WebhookSpec{Url: "https://example.com/hook"},
+		},
+		{
+			name:    "unsupported scheme",
+			spec:    v1.WebhookSpec{Url: "ftp://example.com/hook"},
+			wantErr: "must use http or https",
+		},
+		{
+			name:    "unknown event",
+			spec:    v1.WebhookSpec{Url: "https://example.com", Events: []string{"exploded"}},
+			wantErr: "unknown event type",
+		},
+		{
+			name:    "missing secret env",
+			spec:    v1.WebhookSpec{Url: "https://example.com", SecretEnv: "TESTENV_VM_TEST_UNSET_SECRET"},
+			wantErr: "is not set",
+		},
+		{
+			name:    "invalid timeout",
+			spec:    v1.WebhookSpec{Url: "https://example.com", Timeout: "soon"},
+			wantErr: "invalid timeout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWebhook(tt.spec)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewWebhook() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewWebhook() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhook_Accepts(t *testing.T) {
+	all, err := NewWebhook(v1.WebhookSpec{Url: "https://example.com"})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	for _, e := range AllEvents {
+		if !all.Accepts(e) {
+			t.Errorf("webhook without filter should accept %s", e)
+		}
+	}
+
+	failedOnly, err := NewWebhook(v1.WebhookSpec{Url: "https://example.com", Events: []string{"failed"}})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	if failedOnly.Accepts(EventReady) {
+		t.Error("filtered webhook should not accept ready")
+	}
+	if !failedOnly.Accepts(EventFailed) {
+		t.Error("filtered webhook should accept failed")
+	}
+}
+
+func TestWebhook_Notify_SignsPayload(t *testing.T) {
+	t.Setenv("TESTENV_VM_TEST_SECRET", "s3cret")
+
+	var gotBody []byte
+	var gotSig, gotEvent, gotCustom string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSig = r.Header.Get(HeaderSignature)
+		gotEvent = r.Header.Get(HeaderEvent)
+		gotCustom = r.Header.Get("X-Custom")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(v1.WebhookSpec{
+		Url:       server.URL,
+		SecretEnv: "TESTENV_VM_TEST_SECRET",
+		Headers:   map[string]string{"X-Custom": "yes"},
+	})
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+
+	event := Event{Type: EventReady, EnvironmentID: "ci-1", Timestamp: "2025-01-01T00:00:00Z"}
+	if err := w.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if !hmac.Equal([]byte(gotSig), []byte(Sign([]byte("s3cret"), gotBody))) {
+		t.Errorf("signature %q does not match body", gotSig)
+	}
+	if gotEvent != "ready" {
+		t.Errorf("event header = %q, want ready", gotEvent)
+	}
+	if gotCustom != "yes" {
+		t.Errorf("custom header = %q, want yes", gotCustom)
+	}
+
+	var decoded Event
+	if err := json.Unmarshal(gotBody, &decoded); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if decoded.EnvironmentID != "ci-1" {
+		t.Errorf("EnvironmentID = %q, want ci-1", decoded.EnvironmentID)
+	}
+}
+
+func TestWebhook_Notify_RetriesOnServerError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(v1.WebhookSpec{Url: server.URL}, WithBaseBackoff(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	if err := w.Notify(context.Background(), Event{Type: EventFailed}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
+
+func TestWebhook_Notify_NoRetryOnClientError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(v1.WebhookSpec{Url: server.URL}, WithBaseBackoff(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	if err := w.Notify(context.Background(), Event{Type: EventFailed}); err == nil {
+		t.Fatal("Notify() expected error on 400")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestWebhook_Notify_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	w, err := NewWebhook(v1.WebhookSpec{Url: server.URL, MaxRetries: 2}, WithBaseBackoff(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewWebhook() error = %v", err)
+	}
+	err = w.Notify(context.Background(), Event{Type: EventFailed})
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Notify() error = %v, want exhausted retries", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("calls = %d, want 3", got)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/wireguard"
+)
+
+const (
+	// defaultAccessAddress is the VPN network used when spec.address is unset.
+	defaultAccessAddress = "10.201.0.0/24"
+	// accessInterface is the WireGuard interface created on the server VM.
+	accessInterface = "wgaccess"
+)
+
+// accessClientConfigFile returns the artifact file name of an access client config.
+func accessClientConfigFile(name string) string {
+	return fmt.Sprintf("wireguard-%s.conf", name)
+}
+
+// buildAccess generates the server and client key pairs of an access point and
+// the wg-quick config of its server. The server forwards and masquerades
+// client traffic so that VMs on the network need no route back to the VPN.
+func buildAccess(
+	access *v1.AccessResource,
+	spec *v1.Spec,
+	templateCtx *specpkg.TemplateContext,
+) (specpkg.AccessTemplateData, error) {
+	// The network CIDR is only needed by the client config, but fail early.
+	if _, err := tunnelNetworkCIDR(access.Spec.Network, spec, templateCtx); err != nil {
+		return specpkg.AccessTemplateData{}, err
+	}
+
+	address := access.Spec.Address
+	if address == "" {
+		address = defaultAccessAddress
+	}
+	ends, err := wireguard.HostAddresses(address, 2)
+	if err != nil {
+		return specpkg.AccessTemplateData{}, err
+	}
+	clientRoute, _ := wireguard.HostRoute(ends[1])
+
+	listenPort := access.Spec.ListenPort
+	if listenPort == 0 {
+		listenPort = wireguard.DefaultListenPort
+	}
+
+	server, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		return specpkg.AccessTemplateData{}, err
+	}
+	client, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		return specpkg.AccessTemplateData{}, err
+	}
+
+	masquerade := fmt.Sprintf("iptables -t nat %%s POSTROUTING -s %s -j MASQUERADE", clientRoute)
+	serverConfig := wireguard.Config{
+		PrivateKey: server.PrivateKey,
+		Address:    ends[0],
+		ListenPort: listenPort,
+		PostUp:     []string{"sysctl -w net.ipv4.ip_forward=1", fmt.Sprintf(masquerade, "-A")},
+		PostDown:   []string{fmt.Sprintf(masquerade, "-D")},
+		Peers: []wireguard.Peer{{
+			PublicKey:  client.PublicKey,
+			AllowedIPs: []string{clientRoute},
+		}},
+	}
+
+	return specpkg.AccessTemplateData{
+		ServerPublicKey:  server.PublicKey,
+		ClientPrivateKey: client.PrivateKey,
+		ClientPublicKey:  client.PublicKey,
+		ServerAddress:    ends[0],
+		ClientAddress:    ends[1],
+		ListenPort:       listenPort,
+		ServerConfig:     serverConfig.Render(),
+	}, nil
+}
+
+// injectAccessServer extends the cloud-init of a VM that serves an access
+// point so that it installs WireGuard and brings the server interface up.
+func injectAccessServer(vmSpec *providerv1.VMSpec, vmName string, spec *v1.Spec, templateCtx *specpkg.TemplateContext) {
+	for _, access := range spec.Access {
+		if access.Spec.Vm != vmName {
+			continue
+		}
+		data, ok := templateCtx.Access[access.Name]
+		if !ok {
+			continue
+		}
+		if vmSpec.CloudInit == nil {
+			vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+		}
+		ci := vmSpec.CloudInit
+		ci.Packages = append(ci.Packages, "wireguard-tools")
+		ci.WriteFiles = append(ci.WriteFiles, providerv1.WriteFileSpec{
+			Path:        "/etc/wireguard/" + accessInterface + ".conf",
+			Content:     data.ServerConfig,
+			Permissions: "0600",
+		})
+		ci.Runcmd = append(ci.Runcmd, "systemctl enable --now wg-quick@"+accessInterface)
+	}
+}
+
+// writeAccessClientConfigs writes one wg-quick client config per access point
+// into the artifact directory. Endpoints are rendered here, once every
+// resource exists, and default to the server VM IP.
+func writeAccessClientConfigs(spec *v1.Spec, templateCtx *specpkg.TemplateContext, artifactDir string) error {
+	for i := range spec.Access {
+		access, err := renderAccessSpec(&spec.Access[i], templateCtx)
+		if err != nil {
+			return fmt.Errorf("access %q: failed to render spec: %w", spec.Access[i].Name, err)
+		}
+		data, ok := templateCtx.Access[access.Name]
+		if !ok {
+			return fmt.Errorf("access %q: not created", access.Name)
+		}
+		networkCIDR, err := tunnelNetworkCIDR(access.Spec.Network, spec, templateCtx)
+		if err != nil {
+			return fmt.Errorf("access %q: %w", access.Name, err)
+		}
+		serverRoute, _ := wireguard.HostRoute(data.ServerAddress)
+
+		endpoint := access.Spec.Endpoint
+		if endpoint == "" {
+			endpoint = templateCtx.VMs[access.Spec.Vm].IP
+		}
+		if endpoint == "" {
+			return fmt.Errorf("access %q: vm %q has no IP to use as endpoint", access.Name, access.Spec.Vm)
+		}
+		if _, _, splitErr := net.SplitHostPort(endpoint); splitErr != nil {
+			endpoint = net.JoinHostPort(endpoint, strconv.Itoa(data.ListenPort))
+		}
+
+		clientConfig := wireguard.Config{
+			PrivateKey: data.ClientPrivateKey,
+			Address:    data.ClientAddress,
+			Peers: []wireguard.Peer{{
+				PublicKey:           data.ServerPublicKey,
+				AllowedIPs:          []string{networkCIDR, serverRoute},
+				Endpoint:            endpoint,
+				PersistentKeepalive: defaultPersistentKeepalive,
+			}},
+		}
+
+		path := filepath.Join(artifactDir, accessClientConfigFile(access.Name))
+		if err := os.WriteFile(path, []byte(clientConfig.Render()), 0o600); err != nil {
+			return fmt.Errorf("access %q: failed to write client config: %w", access.Name, err)
+		}
+	}
+	return nil
+}
+
+// renderAccessSpec creates a deep copy and renders templates in an access spec.
+func renderAccessSpec(original *v1.AccessResource, templateCtx *specpkg.TemplateContext) (*v1.AccessResource, error) {
+	// Deep copy via JSON marshaling
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	var copy v1.AccessResource
+	if err := json.Unmarshal(data, &copy); err != nil {
+		return nil, err
+	}
+
+	if err := specpkg.RenderSpec(&copy, templateCtx); err != nil {
+		return nil, err
+	}
+
+	return &copy, nil
+}
@@ -0,0 +1,176 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func newAccessTestSpec(endpoint string) *v1.Spec {
+	return &v1.Spec{
+		Networks: []v1.NetworkResource{
+			{Name: "lab", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: "192.168.100.1/24"}},
+		},
+		Vms: []v1.VMResource{
+			{Name: "vpn", Spec: v1.VMSpec{Network: "lab"}},
+		},
+		Access: []v1.AccessResource{
+			{Name: "dev", Spec: v1.AccessSpec{Network: "lab", Vm: "vpn", Endpoint: endpoint}},
+		},
+	}
+}
+
+func TestBuildAccess(t *testing.T) {
+	spec := newAccessTestSpec("")
+	data, err := buildAccess(&spec.Access[0], spec, specpkg.NewTemplateContext())
+	if err != nil {
+		t.Fatalf("buildAccess() error = %v", err)
+	}
+
+	if data.ServerAddress != "10.201.0.1/24" || data.ClientAddress != "10.201.0.2/24" {
+		t.Errorf("addresses = %q, %q", data.ServerAddress, data.ClientAddress)
+	}
+	if data.ListenPort != 51820 {
+		t.Errorf("ListenPort = %d, want 51820", data.ListenPort)
+	}
+	for _, want := range []string{
+		"Address = 10.201.0.1/24",
+		"ListenPort = 51820",
+		"PostUp = iptables -t nat -A POSTROUTING -s 10.201.0.2/32 -j MASQUERADE",
+		"PostDown = iptables -t nat -D POSTROUTING -s 10.201.0.2/32 -j MASQUERADE",
+		"PublicKey = " + data.ClientPublicKey,
+		"AllowedIPs = 10.201.0.2/32",
+	} {
+		if !strings.Contains(data.ServerConfig, want) {
+			t.Errorf("ServerConfig missing %q:\n%s", want, data.ServerConfig)
+		}
+	}
+}
+
+func TestInjectAccessServer(t *testing.T) {
+	spec := newAccessTestSpec("")
+	ctx := specpkg.NewTemplateContext()
+	ctx.Access["dev"] = specpkg.AccessTemplateData{ServerConfig: "[Interface]\n"}
+
+	vmSpec := providerv1.VMSpec{}
+	injectAccessServer(&vmSpec, "vpn", spec, ctx)
+	if vmSpec.CloudInit == nil {
+		t.Fatal("expected cloud-init to be created")
+	}
+	ci := vmSpec.CloudInit
+	if len(ci.Packages) != 1 || ci.Packages[0] != "wireguard-tools" {
+		t.Errorf("Packages = %v", ci.Packages)
+	}
+	if len(ci.WriteFiles) != 1 || ci.WriteFiles[0].Path != "/etc/wireguard/wgaccess.conf" ||
+		ci.WriteFiles[0].Content != "[Interface]\n" || ci.WriteFiles[0].Permissions != "0600" {
+		t.Errorf("WriteFiles = %+v", ci.WriteFiles)
+	}
+	if len(ci.Runcmd) != 1 || ci.Runcmd[0] != "systemctl enable --now wg-quick@wgaccess" {
+		t.Errorf("Runcmd = %v", ci.Runcmd)
+	}
+
+	other := providerv1.VMSpec{}
+	injectAccessServer(&other, "web", spec, ctx)
+	if other.CloudInit != nil {
+		t.Error("non-server VM should not be modified")
+	}
+}
+
+func TestWriteAccessClientConfigs(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		wantEndpoint string
+	}{
+		{name: "defaults to server VM IP", wantEndpoint: "192.168.100.10:51820"},
+		{name: "templated endpoint", endpoint: "{{ .Env.HYPERVISOR }}", wantEndpoint: "hv.example.com:51820"},
+		{name: "endpoint with port", endpoint: "hv.example.com:4500", wantEndpoint: "hv.example.com:4500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newAccessTestSpec(tt.endpoint)
+			ctx := specpkg.NewTemplateContext()
+			ctx.Env["HYPERVISOR"] = "hv.example.com"
+			ctx.VMs["vpn"] = specpkg.VMTemplateData{IP: "192.168.100.10"}
+			data, err := buildAccess(&spec.Access[0], spec, ctx)
+			if err != nil {
+				t.Fatalf("buildAccess() error = %v", err)
+			}
+			ctx.Access["dev"] = data
+
+			dir := t.TempDir()
+			if err := writeAccessClientConfigs(spec, ctx, dir); err != nil {
+				t.Fatalf("writeAccessClientConfigs() error = %v", err)
+			}
+
+			path := filepath.Join(dir, "wireguard-dev.conf")
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("client config not written: %v", err)
+			}
+			if info.Mode().Perm() != 0o600 {
+				t.Errorf("client config mode = %v, want 0600", info.Mode().Perm())
+			}
+			content, _ := os.ReadFile(path)
+			for _, want := range []string{
+				"PrivateKey = " + data.ClientPrivateKey,
+				"Address = 10.201.0.2/24",
+				"PublicKey = " + data.ServerPublicKey,
+				"AllowedIPs = 192.168.100.0/24, 10.201.0.1/32",
+				"Endpoint = " + tt.wantEndpoint,
+			} {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("client config missing %q:\n%s", want, content)
+				}
+			}
+		})
+	}
+
+	t.Run("missing server IP", func(t *testing.T) {
+		spec := newAccessTestSpec("")
+		ctx := specpkg.NewTemplateContext()
+		ctx.Access["dev"] = specpkg.AccessTemplateData{ServerAddress: "10.201.0.1/24"}
+		if err := writeAccessClientConfigs(spec, ctx, t.TempDir()); err == nil {
+			t.Error("expected error when the server VM has no IP")
+		}
+	})
+}
+
+func TestBuildDAG_Access(t *testing.T) {
+	spec := newAccessTestSpec("{{ .VMs.vpn.IP }}")
+
+	dag, err := BuildDAG(spec)
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+
+	accessRef := v1.ResourceRef{Kind: "access", Name: "dev"}
+	if !dag.DependsOn(accessRef, v1.ResourceRef{Kind: "network", Name: "lab"}) {
+		t.Error("access should depend on its network")
+	}
+	if !dag.DependsOn(v1.ResourceRef{Kind: "vm", Name: "vpn"}, accessRef) {
+		t.Error("server VM should depend on its access point")
+	}
+}
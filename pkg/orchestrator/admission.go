@@ -0,0 +1,314 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Admission configures how creations wait for host capacity. The zero value
+// admits creations that do not fit the free memory of a host with a warning.
+type Admission struct {
+	// Wait is how long a creation that does not fit the free memory of a
+	// host queues for capacity before failing with ErrInsufficientCapacity.
+	// Queued creations are admitted by priority, then in arrival order.
+	Wait time.Duration
+	// Preempt destroys expired environments of lower priority, lowest
+	// first, to make room for the next queued creation.
+	Preempt bool
+	// MaxConcurrent limits the creations a server runs at once. Further
+	// creations wait for a slot, taking turns between clients (see
+	// MetadataClient). Zero means no limit.
+	MaxConcurrent int
+}
+
+// admissionPollInterval is how often queued creations sample host capacity.
+var admissionPollInterval = 5 * time.Second
+
+// queueEntry is a creation queued for host capacity. Entries are files of
+// the state directory, so that every orchestrator of a host shares the
+// queue.
+type queueEntry struct {
+	EnvironmentID string    `json:"environmentId"`
+	Tenant        string    `json:"tenant,omitempty"`
+	Priority      int       `json:"priority"`
+	EnqueuedAt    time.Time `json:"enqueuedAt"`
+	// PID is the process creating the environment. Entries of processes
+	// that exited are left over by interrupted creations and ignored.
+	PID int `json:"pid"`
+}
+
+// live reports whether the process of an entry still runs.
+func (e queueEntry) live() bool {
+	if e.PID <= 0 {
+		return false
+	}
+	err := syscall.Kill(e.PID, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// before reports whether e is admitted before other.
+func (e queueEntry) before(other queueEntry) bool {
+	if e.Priority != other.Priority {
+		return e.Priority > other.Priority
+	}
+	if !e.EnqueuedAt.Equal(other.EnqueuedAt) {
+		return e.EnqueuedAt.Before(other.EnqueuedAt)
+	}
+	return e.EnvironmentID < other.EnvironmentID
+}
+
+// admissionQueue stores queue entries in a directory, those of a tenant in
+// a subdirectory named after it since the environment IDs of tenants may
+// collide.
+type admissionQueue struct {
+	dir    string
+	tenant string
+}
+
+// path returns the file of the entry of an environment.
+func (q admissionQueue) path(envID string) string {
+	return filepath.Join(q.dir, q.tenant, envID+".json")
+}
+
+// add writes an entry atomically, replacing any entry of the same
+// environment.
+func (q admissionQueue) add(e queueEntry) error {
+	if err := os.MkdirAll(filepath.Dir(q.path(e.EnvironmentID)), 0o755); err != nil {
+		return fmt.Errorf("failed to create admission directory: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := q.path(e.EnvironmentID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to queue environment %q: %w", e.EnvironmentID, err)
+	}
+	if err := os.Rename(tmp, q.path(e.EnvironmentID)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to queue environment %q: %w", e.EnvironmentID, err)
+	}
+	return nil
+}
+
+// remove deletes the entry of an environment.
+func (q admissionQueue) remove(envID string) {
+	if err := os.Remove(q.path(envID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Failed to remove admission queue entry of %s: %v", envID, err)
+	}
+}
+
+// ahead returns the number of live entries admitted before e, whatever
+// their tenant.
+func (q admissionQueue) ahead(e queueEntry) (int, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", filepath.Join("*", "*.json")} {
+		matches, err := filepath.Glob(filepath.Join(q.dir, pattern))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read admission queue: %w", err)
+		}
+		files = append(files, matches...)
+	}
+	n := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			// Removed since listed
+			continue
+		}
+		var other queueEntry
+		if err := json.Unmarshal(data, &other); err != nil {
+			continue
+		}
+		self := other.Tenant == e.Tenant && other.EnvironmentID == e.EnvironmentID
+		if !self && other.before(e) && other.live() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// contention describes the providers whose host lacks the free memory
+// requested from them.
+func contention(capacity []ProviderCapacity) []string {
+	var msgs []string
+	for _, c := range capacity {
+		if c.Host != nil && c.Host.FreeMemoryMB > 0 && c.Requested.MemoryMB > c.Host.FreeMemoryMB {
+			msgs = append(msgs, fmt.Sprintf("provider %q: %d MB of memory requested, host has %d MB free",
+				c.Provider, c.Requested.MemoryMB, c.Host.FreeMemoryMB))
+		}
+	}
+	return msgs
+}
+
+// admit queues the creation of an environment while the hosts of its
+// providers lack the free memory it requests, or while creations of higher
+// priority are queued before it. It returns a function releasing the place
+// of the environment in the queue, to be called once it is created, so that
+// the next creation samples capacity after this one allocated its VMs.
+// Without Admission.Wait, creations are admitted at once.
+func (o *Orchestrator) admit(ctx context.Context, envID string, testenvSpec *v1.Spec, capacity []ProviderCapacity) (func(), error) {
+	wait := o.config.Admission.Wait
+	if wait <= 0 {
+		return func() {}, nil
+	}
+	queue := admissionQueue{dir: o.hostLayout().AdmissionDir(), tenant: o.config.Tenant}
+	entry := queueEntry{
+		EnvironmentID: envID,
+		Tenant:        o.config.Tenant,
+		Priority:      testenvSpec.Priority,
+		EnqueuedAt:    time.Now(),
+		PID:           os.Getpid(),
+	}
+	if err := queue.add(entry); err != nil {
+		return nil, err
+	}
+	release := func() { queue.remove(envID) }
+
+	lastReason := ""
+	for {
+		ahead, err := queue.ahead(entry)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		reason := fmt.Sprintf("%d creation(s) queued ahead", ahead)
+		if ahead == 0 {
+			contended := contention(capacity)
+			if len(contended) == 0 {
+				return release, nil
+			}
+			if o.config.Admission.Preempt {
+				preempted, err := o.preempt(ctx, envID, testenvSpec.Priority)
+				if err != nil {
+					log.Printf("Preemption failed: %v", err)
+				}
+				if preempted {
+					capacity, err = planCapacity(testenvSpec, o.refreshHostCapacities())
+					if err != nil {
+						release()
+						return nil, err
+					}
+					continue
+				}
+			}
+			reason = strings.Join(contended, "; ")
+		}
+
+		if time.Since(entry.EnqueuedAt) > wait {
+			release()
+			return nil, fmt.Errorf("%w: queued for %s: %s", ErrInsufficientCapacity, wait, reason)
+		}
+		if reason != lastReason {
+			log.Printf("Environment %s (priority %d) queued for host capacity: %s", envID, entry.Priority, reason)
+			lastReason = reason
+		}
+		select {
+		case <-ctx.Done():
+			release()
+			return nil, fmt.Errorf("admission interrupted: %w", context.Cause(ctx))
+		case <-time.After(admissionPollInterval):
+		}
+		capacity, err = planCapacity(testenvSpec, o.refreshHostCapacities())
+		if err != nil {
+			release()
+			return nil, err
+		}
+	}
+}
+
+// refreshHostCapacities samples the host capacity of every running provider
+// again.
+func (o *Orchestrator) refreshHostCapacities() map[string]*providerv1.HostCapacity {
+	for _, name := range o.manager.List() {
+		if _, err := o.manager.RefreshCapabilities(name); err != nil {
+			log.Printf("Failed to refresh capacity of provider %q: %v", name, err)
+		}
+	}
+	return hostCapacities(o.manager)
+}
+
+// preempt destroys the expired environment of lowest priority below
+// priority, oldest first, to make room for envID. It reports whether an
+// environment was destroyed.
+func (o *Orchestrator) preempt(ctx context.Context, envID string, priority int) (bool, error) {
+	ids, err := o.store.List()
+	if err != nil {
+		return false, fmt.Errorf("failed to list environments: %w", err)
+	}
+	now := time.Now()
+	var candidates []*v1.EnvironmentState
+	for _, id := range ids {
+		if id == envID {
+			continue
+		}
+		envState, err := o.store.Load(id)
+		if err != nil || envState.Spec == nil || envState.Spec.Priority >= priority {
+			continue
+		}
+		if envState.Status != v1.StatusReady && envState.Status != v1.StatusFailed {
+			continue
+		}
+		if expired(envState, now) {
+			candidates = append(candidates, envState)
+		}
+	}
+	if len(candidates) == 0 {
+		return false, nil
+	}
+	slices.SortFunc(candidates, func(a, b *v1.EnvironmentState) int {
+		if c := cmp.Compare(a.Spec.Priority, b.Spec.Priority); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.CreatedAt, b.CreatedAt)
+	})
+
+	victim := candidates[0]
+	log.Printf("Preempting expired environment %s (priority %d) to admit %s (priority %d)",
+		victim.ID, victim.Spec.Priority, envID, priority)
+	if err := o.Delete(ctx, &v1.DeleteInput{TestID: victim.ID}); err != nil {
+		return false, fmt.Errorf("failed to preempt environment %q: %w", victim.ID, err)
+	}
+	return true, nil
+}
+
+// expired reports whether an environment is older than its expiresAfter.
+func expired(envState *v1.EnvironmentState, now time.Time) bool {
+	expiresAfter, err := spec.ExpiresAfter(envState.Spec)
+	if err != nil || expiresAfter == 0 {
+		return false
+	}
+	createdAt, err := time.Parse(time.RFC3339, envState.CreatedAt)
+	if err != nil {
+		return false
+	}
+	return now.After(createdAt.Add(expiresAfter))
+}
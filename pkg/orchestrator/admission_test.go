@@ -0,0 +1,209 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+func TestAdmissionQueue_ahead(t *testing.T) {
+	q := admissionQueue{dir: t.TempDir()}
+	now := time.Now()
+	pid := os.Getpid()
+	entries := []queueEntry{
+		{EnvironmentID: "high", Priority: 10, EnqueuedAt: now, PID: pid},
+		{EnvironmentID: "early", Priority: 5, EnqueuedAt: now.Add(-time.Minute), PID: pid},
+		{EnvironmentID: "late", Priority: 5, EnqueuedAt: now.Add(time.Minute), PID: pid},
+		{EnvironmentID: "low", Priority: 0, EnqueuedAt: now.Add(-time.Hour), PID: pid},
+		// Left over by a process that exited
+		{EnvironmentID: "stale", Priority: 99, EnqueuedAt: now.Add(-time.Hour), PID: exitedPID(t)},
+	}
+	for _, e := range entries {
+		if err := q.add(e); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+
+	self := queueEntry{EnvironmentID: "self", Priority: 5, EnqueuedAt: now, PID: pid}
+	if err := q.add(self); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	// high and early are ahead; late and low are behind, stale is ignored
+	if n, err := q.ahead(self); err != nil || n != 2 {
+		t.Errorf("ahead() = %d, %v, want 2", n, err)
+	}
+
+	q.remove("high")
+	q.remove("early")
+	if n, err := q.ahead(self); err != nil || n != 0 {
+		t.Errorf("ahead() = %d, %v, want 0 once the entries ahead are removed", n, err)
+	}
+	if n, err := (admissionQueue{dir: t.TempDir() + "/missing"}).ahead(self); err != nil || n != 0 {
+		t.Errorf("ahead() of a missing queue = %d, %v, want 0", n, err)
+	}
+}
+
+func TestAdmissionQueue_aheadTenants(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	pid := os.Getpid()
+	storage := admissionQueue{dir: dir, tenant: "storage"}
+	network := admissionQueue{dir: dir, tenant: "network"}
+
+	// Tenants queue environments of the same ID in the same queue
+	first := queueEntry{EnvironmentID: "env-1", Tenant: "storage", Priority: 5, EnqueuedAt: now.Add(-time.Minute), PID: pid}
+	second := queueEntry{EnvironmentID: "env-1", Tenant: "network", Priority: 5, EnqueuedAt: now, PID: pid}
+	if err := storage.add(first); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if err := network.add(second); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if n, err := network.ahead(second); err != nil || n != 1 {
+		t.Errorf("ahead() = %d, %v, want 1", n, err)
+	}
+	if n, err := storage.ahead(first); err != nil || n != 0 {
+		t.Errorf("ahead() = %d, %v, want 0", n, err)
+	}
+
+	storage.remove("env-1")
+	if n, err := network.ahead(second); err != nil || n != 0 {
+		t.Errorf("ahead() = %d, %v, want 0 once the entry of the other tenant is removed", n, err)
+	}
+}
+
+// exitedPID returns the process ID of a process that exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("true not available: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestContention(t *testing.T) {
+	capacity := []ProviderCapacity{
+		{Provider: "busy", Requested: ResourceTotals{MemoryMB: 4096}, Host: &providerv1.HostCapacity{MemoryMB: 16384, FreeMemoryMB: 2048}},
+		{Provider: "idle", Requested: ResourceTotals{MemoryMB: 4096}, Host: &providerv1.HostCapacity{MemoryMB: 16384, FreeMemoryMB: 8192}},
+		{Provider: "unknown", Requested: ResourceTotals{MemoryMB: 4096}},
+	}
+	got := contention(capacity)
+	if len(got) != 1 || !strings.Contains(got[0], `provider "busy"`) {
+		t.Errorf("contention() = %v, want only the busy provider", got)
+	}
+}
+
+func TestOrchestrator_admit(t *testing.T) {
+	defer func(d time.Duration) { admissionPollInterval = d }(admissionPollInterval)
+	admissionPollInterval = 10 * time.Millisecond
+
+	config := newTestConfig(t)
+	config.Admission.Wait = 100 * time.Millisecond
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+	queue := admissionQueue{dir: paths.New(config.StateDir).AdmissionDir()}
+
+	// Admitted at once, keeping its place until released
+	release, err := o.admit(context.Background(), "first", &v1.Spec{}, nil)
+	if err != nil {
+		t.Fatalf("admit() error = %v", err)
+	}
+	if _, err := os.Stat(queue.path("first")); err != nil {
+		t.Errorf("admitted environment should keep its queue entry: %v", err)
+	}
+
+	// Queued behind it until the wait expires
+	_, err = o.admit(context.Background(), "second", &v1.Spec{}, nil)
+	if !errors.Is(err, ErrInsufficientCapacity) || !strings.Contains(err.Error(), "1 creation(s) queued ahead") {
+		t.Errorf("admit() error = %v, want ErrInsufficientCapacity with a queue ahead", err)
+	}
+	if _, err := os.Stat(queue.path("second")); !os.IsNotExist(err) {
+		t.Errorf("rejected environment should leave the queue: %v", err)
+	}
+
+	// Higher priorities go first
+	release2, err := o.admit(context.Background(), "urgent", &v1.Spec{Priority: 10}, nil)
+	if err != nil {
+		t.Fatalf("admit() of a higher priority error = %v", err)
+	}
+	release2()
+	release()
+	if _, err := os.Stat(queue.path("first")); !os.IsNotExist(err) {
+		t.Errorf("release should remove the queue entry: %v", err)
+	}
+
+	// Without a wait, nothing is queued
+	o.config.Admission.Wait = 0
+	if _, err := o.admit(context.Background(), "third", &v1.Spec{}, nil); err != nil {
+		t.Errorf("admit() without wait error = %v", err)
+	}
+}
+
+func TestOrchestrator_preempt(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	states := []*v1.EnvironmentState{
+		{ID: "expired-low", Status: v1.StatusReady, CreatedAt: old, Spec: &v1.Spec{Priority: 1, ExpiresAfter: "1h"}},
+		{ID: "expired-lowest", Status: v1.StatusReady, CreatedAt: old, Spec: &v1.Spec{ExpiresAfter: "1h"}},
+		{ID: "fresh", Status: v1.StatusReady, CreatedAt: old, Spec: &v1.Spec{ExpiresAfter: "3h"}},
+		{ID: "expired-high", Status: v1.StatusReady, CreatedAt: old, Spec: &v1.Spec{Priority: 10, ExpiresAfter: "1h"}},
+		{ID: "no-expiry", Status: v1.StatusReady, CreatedAt: old, Spec: &v1.Spec{}},
+	}
+	for _, s := range states {
+		if err := o.store.Save(s); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	for _, want := range []string{"expired-lowest", "expired-low"} {
+		preempted, err := o.preempt(context.Background(), "new", 5)
+		if err != nil || !preempted {
+			t.Fatalf("preempt() = %v, %v, want %s destroyed", preempted, err, want)
+		}
+		if o.store.Exists(want) {
+			t.Errorf("preempt() should have destroyed %s", want)
+		}
+	}
+	preempted, err := o.preempt(context.Background(), "new", 5)
+	if err != nil || preempted {
+		t.Errorf("preempt() = %v, %v, want nothing left to preempt", preempted, err)
+	}
+	for _, id := range []string{"fresh", "expired-high", "no-expiry"} {
+		if !o.store.Exists(id) {
+			t.Errorf("preempt() should not destroy %s", id)
+		}
+	}
+}
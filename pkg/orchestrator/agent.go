@@ -0,0 +1,176 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/vsock"
+)
+
+// Keys of the guest agent in the state of a VM.
+const (
+	agentPortKey  = "agentPort"
+	agentTokenKey = "agentToken"
+	vsockCIDKey   = "vsockCID"
+)
+
+// vsockDialTimeout bounds the vsock attempt of agent clients before they
+// fall back to TCP.
+const vsockDialTimeout = 2 * time.Second
+
+// agentLineLength wraps the encoded agent binary in the user-data.
+const agentLineLength = 76
+
+// injectAgent installs and starts the guest agent through cloud-init when
+// the VM enables it. The agent starts before the commands of the spec, so
+// that it is reachable even if they fail. It also listens on vsock when the
+// VM has a vsock device. It returns the port and the
+// generated token to record in the VM state, or nil if the agent is not
+// enabled.
+func (e *Executor) injectAgent(vmSpec *providerv1.VMSpec, vmName string, agentSpec *v1.VMAgentSpec) (map[string]any, error) {
+	if agentSpec == nil || !agentSpec.Enabled {
+		return nil, nil
+	}
+	if e.agentBinary == "" {
+		return nil, fmt.Errorf("vm %q enables the guest agent but no agent binary is configured (agentBinary or TESTENV_VM_AGENT_BINARY)", vmName)
+	}
+	binary, err := os.ReadFile(e.agentBinary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent binary: %w", err)
+	}
+	encoded, err := encodeAgentBinary(binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode agent binary: %w", err)
+	}
+	token, err := newAgentToken()
+	if err != nil {
+		return nil, err
+	}
+	port := agentSpec.Port
+	if port == 0 {
+		port = agent.DefaultPort
+	}
+
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	cloudInit := vmSpec.CloudInit
+	cloudInit.WriteFiles = append(cloudInit.WriteFiles,
+		providerv1.WriteFileSpec{Path: agent.BinaryPath, Content: encoded, Permissions: "0755", Encoding: "gz+b64"},
+		providerv1.WriteFileSpec{Path: agent.TokenPath, Content: token, Permissions: "0600"},
+		providerv1.WriteFileSpec{Path: agent.UnitPath, Content: agent.Unit(port, vmSpec.Vsock != nil), Permissions: "0644"},
+	)
+	cloudInit.Runcmd = append([]string{agent.StartCommand(port, vmSpec.Vsock != nil)}, cloudInit.Runcmd...)
+	return map[string]any{agentPortKey: strconv.Itoa(port), agentTokenKey: token}, nil
+}
+
+// encodeAgentBinary compresses and base64-encodes the agent binary for the
+// gz+b64 encoding of cloud-init, wrapped in lines.
+func encodeAgentBinary(binary []byte) (string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(binary); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var sb strings.Builder
+	for len(encoded) > agentLineLength {
+		sb.WriteString(encoded[:agentLineLength])
+		sb.WriteByte('\n')
+		encoded = encoded[agentLineLength:]
+	}
+	sb.WriteString(encoded)
+	return sb.String(), nil
+}
+
+// newAgentToken generates the token of a VM agent.
+func newAgentToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate agent token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// agentClient returns a client of the guest agent of a VM, or nil if the VM
+// has no agent or no recorded IP. When the VM has a vsock device, the client
+// reaches the agent over vsock first, so that it keeps working while the
+// guest network is broken.
+func agentClient(vmState *v1.ResourceState) *agent.Client {
+	if vmState == nil {
+		return nil
+	}
+	ip := getString(vmState.State, "ip")
+	token := getString(vmState.State, agentTokenKey)
+	port, err := strconv.Atoi(getString(vmState.State, agentPortKey))
+	if ip == "" || token == "" || err != nil {
+		return nil
+	}
+	cid := getUint32(vmState.State, vsockCIDKey)
+	if cid == 0 {
+		return agent.NewClient(agent.Address(ip, port), token)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = vsockFirstDialer(cid, uint32(port))
+	return agent.NewClient(agent.Address(ip, port), token, agent.WithHTTPClient(&http.Client{Transport: transport}))
+}
+
+// vsockFirstDialer returns a DialContext that connects to the vsock port of
+// a guest and falls back to the TCP address when vsock is unreachable.
+func vsockFirstDialer(cid, port uint32) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		vctx, cancel := context.WithTimeout(ctx, vsockDialTimeout)
+		conn, err := vsock.Dial(vctx, cid, port)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// getUint32 returns a numeric value of a state map, which is a float64 once
+// the state went through JSON, or zero.
+func getUint32(m map[string]any, key string) uint32 {
+	switch v := m[key].(type) {
+	case float64:
+		return uint32(v)
+	case uint32:
+		return v
+	case int:
+		return uint32(v)
+	}
+	return 0
+}
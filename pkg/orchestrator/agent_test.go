@@ -0,0 +1,279 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+func TestInjectAgent_Disabled(t *testing.T) {
+	e := &Executor{}
+	for _, spec := range []*v1.VMAgentSpec{nil, {Enabled: false}} {
+		vmSpec := &providerv1.VMSpec{}
+		state, err := e.injectAgent(vmSpec, "web", spec)
+		if err != nil || state != nil || vmSpec.CloudInit != nil {
+			t.Errorf("injectAgent(%+v) = %v, %v, cloud-init %+v, want no change", spec, state, err, vmSpec.CloudInit)
+		}
+	}
+}
+
+func TestInjectAgent_NoBinary(t *testing.T) {
+	e := &Executor{}
+	_, err := e.injectAgent(&providerv1.VMSpec{}, "web", &v1.VMAgentSpec{Enabled: true})
+	if err == nil || !strings.Contains(err.Error(), "TESTENV_VM_AGENT_BINARY") {
+		t.Errorf("injectAgent() error = %v, want missing agent binary", err)
+	}
+
+	e.agentBinary = filepath.Join(t.TempDir(), "missing")
+	if _, err := e.injectAgent(&providerv1.VMSpec{}, "web", &v1.VMAgentSpec{Enabled: true}); err == nil {
+		t.Error("injectAgent() expected error for a missing binary")
+	}
+}
+
+func TestInjectAgent(t *testing.T) {
+	binary := filepath.Join(t.TempDir(), "testenv-vm-agent")
+	if err := os.WriteFile(binary, []byte("agent binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	e := &Executor{agentBinary: binary}
+	vmSpec := &providerv1.VMSpec{CloudInit: &providerv1.CloudInitSpec{Runcmd: []string{"echo user"}}}
+
+	state, err := e.injectAgent(vmSpec, "web", &v1.VMAgentSpec{Enabled: true, Port: 2222})
+	if err != nil {
+		t.Fatalf("injectAgent() error = %v", err)
+	}
+	token := getString(state, agentTokenKey)
+	if len(token) != 64 || getString(state, agentPortKey) != "2222" {
+		t.Errorf("injectAgent() state = %v", state)
+	}
+
+	files := make(map[string]providerv1.WriteFileSpec)
+	for _, wf := range vmSpec.CloudInit.WriteFiles {
+		files[wf.Path] = wf
+	}
+	if wf := files[agent.BinaryPath]; wf.Encoding != "gz+b64" || wf.Permissions != "0755" || decodeGzipBase64(t, wf.Content) != "agent binary" {
+		t.Errorf("binary file = %+v", wf)
+	}
+	if wf := files[agent.TokenPath]; wf.Content != token || wf.Permissions != "0600" {
+		t.Errorf("token file = %+v", wf)
+	}
+	if wf := files[agent.UnitPath]; !strings.Contains(wf.Content, "--listen :2222") {
+		t.Errorf("unit file = %+v", wf)
+	}
+	runcmd := vmSpec.CloudInit.Runcmd
+	if len(runcmd) != 2 || runcmd[0] != agent.StartCommand(2222, false) || runcmd[1] != "echo user" {
+		t.Errorf("runcmd = %q, want the agent started first", runcmd)
+	}
+
+	// VMs with a vsock device also serve the agent on vsock
+	vmSpec = &providerv1.VMSpec{Vsock: &providerv1.VsockSpec{}}
+	if _, err := e.injectAgent(vmSpec, "vsock", &v1.VMAgentSpec{Enabled: true}); err != nil {
+		t.Fatalf("injectAgent() error = %v", err)
+	}
+	if runcmd := vmSpec.CloudInit.Runcmd; len(runcmd) != 1 || !strings.Contains(runcmd[0], "--vsock-port 10050") {
+		t.Errorf("runcmd = %q, want the agent listening on vsock", runcmd)
+	}
+
+	// Tokens are generated per VM
+	other, err := e.injectAgent(&providerv1.VMSpec{}, "db", &v1.VMAgentSpec{Enabled: true})
+	if err != nil {
+		t.Fatalf("injectAgent() error = %v", err)
+	}
+	if getString(other, agentTokenKey) == token || getString(other, agentPortKey) != "10050" {
+		t.Errorf("injectAgent() state = %v, want a new token and the default port", other)
+	}
+}
+
+func TestEncodeAgentBinary(t *testing.T) {
+	binary := bytes.Repeat([]byte{0, 1, 2, 255}, 1000)
+	encoded, err := encodeAgentBinary(binary)
+	if err != nil {
+		t.Fatalf("encodeAgentBinary() error = %v", err)
+	}
+	for _, line := range strings.Split(encoded, "\n") {
+		if len(line) > agentLineLength {
+			t.Fatalf("line of %d characters, want at most %d", len(line), agentLineLength)
+		}
+	}
+	if got := decodeGzipBase64(t, encoded); got != string(binary) {
+		t.Error("encodeAgentBinary() does not round-trip")
+	}
+}
+
+func TestAgentClient(t *testing.T) {
+	for _, vmState := range []*v1.ResourceState{
+		nil,
+		{State: map[string]any{"ip": "10.0.0.2"}},
+		{State: map[string]any{"ip": "10.0.0.2", agentTokenKey: "t"}},
+		{State: map[string]any{agentPortKey: "10050", agentTokenKey: "t"}},
+	} {
+		if agentClient(vmState) != nil {
+			t.Errorf("agentClient(%+v) should be nil", vmState)
+		}
+	}
+	vmState := &v1.ResourceState{State: map[string]any{"ip": "10.0.0.2", agentPortKey: "10050", agentTokenKey: "t"}}
+	if agentClient(vmState) == nil {
+		t.Error("agentClient() should return a client")
+	}
+}
+
+func TestAgentClient_VsockFallback(t *testing.T) {
+	srv := httptest.NewServer(agent.NewHandler("token"))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No guest owns the CID, so the client falls back to TCP
+	c := agentClient(&v1.ResourceState{State: map[string]any{
+		"ip": host, agentPortKey: port, agentTokenKey: "token", vsockCIDKey: float64(0xfffffffe),
+	}})
+	if c == nil {
+		t.Fatal("agentClient() should return a client")
+	}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v, want the TCP fallback to succeed", err)
+	}
+}
+
+func TestOrchestrator_StatsFromAgent(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	srv := httptest.NewServer(agent.NewHandler("token"))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID: "env-agent",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{
+			"web": {Provider: "undeclared", State: map[string]any{
+				"name": "env-agent-web", "ip": host, agentPortKey: port, agentTokenKey: "token",
+			}},
+			"db": {Provider: "undeclared", State: map[string]any{
+				"name": "env-agent-db", "ip": host, agentPortKey: port, agentTokenKey: "wrong",
+			}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := o.Stats("env-agent", nil, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	db, web := stats.VMs[0], stats.VMs[1]
+	if web.Source != StatsSourceAgent || web.Stats == nil || web.Stats.Name != "env-agent-web" || web.Stats.VCPUs == 0 {
+		t.Errorf("Stats() web = %+v, want stats from the agent", web)
+	}
+	// A failing agent falls back to the provider
+	if db.Stats != nil || !strings.Contains(db.Error, "undeclared") {
+		t.Errorf("Stats() db = %+v, want the provider error", db)
+	}
+}
+
+func TestStateClientProvider_AgentWithoutKey(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	envState := &v1.EnvironmentState{
+		ID: "env-agent",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{
+			"web": {State: map[string]any{"ip": "10.0.0.2", agentPortKey: "10050", agentTokenKey: "t"}},
+			"db":  {State: map[string]any{"ip": "10.0.0.3"}},
+		}},
+	}
+	p := &stateClientProvider{executor: o.executor, envState: envState}
+	info, err := p.GetVMInfo("web")
+	if err != nil || info.Host != "10.0.0.2" {
+		t.Errorf("GetVMInfo(web) = %+v, %v, want the VM address", info, err)
+	}
+	if _, err := p.GetVMInfo("db"); err == nil || !strings.Contains(err.Error(), "no SSH key") {
+		t.Errorf("GetVMInfo(db) error = %v, want no SSH key", err)
+	}
+}
+
+func TestOrchestrator_buildArtifact_Agent(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	artifact := o.buildArtifact("test-1", &v1.EnvironmentState{
+		ID: "test-1",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{
+			"web-1": {State: map[string]any{"ip": "192.168.1.10", agentPortKey: "10050", agentTokenKey: "t", vsockCIDKey: float64(7)}},
+			"db":    {State: map[string]any{"ip": "192.168.1.11"}},
+		}},
+	}, nil)
+
+	if got := artifact.Metadata["testenv-vm.vm.web-1.agent"]; got != "192.168.1.10:10050" {
+		t.Errorf("agent metadata = %q", got)
+	}
+	if artifact.Env["TESTENV_VM_WEB_1_AGENT"] != "192.168.1.10:10050" || artifact.Env["TESTENV_VM_WEB_1_AGENT_TOKEN"] != "t" {
+		t.Errorf("agent env = %v", artifact.Env)
+	}
+	if artifact.Env["TESTENV_VM_WEB_1_VSOCK_CID"] != "7" || artifact.Metadata["testenv-vm.vm.web-1.vsockCID"] != "7" {
+		t.Errorf("vsock CID env = %v", artifact.Env)
+	}
+	if _, ok := artifact.Env["TESTENV_VM_DB_AGENT"]; ok {
+		t.Error("VMs without agent should have no agent env")
+	}
+}
+
+// decodeGzipBase64 decodes content of the gz+b64 cloud-init encoding.
+func decodeGzipBase64(t *testing.T, content string) string {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content, "\n", ""))
+	if err != nil {
+		t.Fatalf("invalid base64: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	return string(out)
+}
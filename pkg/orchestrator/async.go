@@ -0,0 +1,166 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ErrCreateInProgress is returned by CreateAsync when a creation for the same
+// testID is still running.
+var ErrCreateInProgress = errors.New("create already in progress")
+
+// asyncCreateRetention is how long the outcome of a creation is kept for
+// WaitCreate when no call collects it.
+const asyncCreateRetention = time.Hour
+
+// asyncCreate is a creation started by CreateAsync. result, err and op are
+// final once done is closed.
+type asyncCreate struct {
+	op              *Operation
+	cancel          context.CancelFunc
+	cancelRequested atomic.Bool
+	done            chan struct{}
+	result          *CreateResult
+	err             error
+}
+
+// CreateAsync starts Create in the background and returns its operation once
+// it started, so that clients with short tool-call timeouts can create
+// environments that take minutes to build. The operation is recorded in the
+// state directory and updated when the creation ends. onDone, if not nil, is
+// then called with the final record. The outcome is also kept, keyed by the
+// testID of input, until WaitCreate collects it or for asyncCreateRetention.
+// Shutdown drains or cancels the creation like any other.
+func (o *Orchestrator) CreateAsync(input *v1.CreateInput, onDone func(*Operation)) (*Operation, error) {
+	if input.TestID == "" {
+		return nil, errors.New("testID is required")
+	}
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("create rejected: %w", ErrReadOnly)
+	}
+	o.pruneAsyncCreates()
+	id, err := newOperationID()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &asyncCreate{
+		op: &Operation{
+			ID:        id,
+			Kind:      OperationKindCreate,
+			TestID:    input.TestID,
+			Status:    OperationRunning,
+			StartedAt: time.Now().UTC(),
+			PID:       os.Getpid(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if prev, loaded := o.asyncCreates.LoadOrStore(input.TestID, a); loaded {
+		select {
+		case <-prev.(*asyncCreate).done:
+		default:
+			cancel()
+			return nil, fmt.Errorf("testID %q: %w", input.TestID, ErrCreateInProgress)
+		}
+		// The previous creation ended; replace its outcome unless another
+		// call did so first.
+		if !o.asyncCreates.CompareAndSwap(input.TestID, prev, a) {
+			cancel()
+			return nil, fmt.Errorf("testID %q: %w", input.TestID, ErrCreateInProgress)
+		}
+	}
+	store := o.operationStore()
+	if err := store.save(a.op); err != nil {
+		o.asyncCreates.CompareAndDelete(input.TestID, a)
+		cancel()
+		return nil, err
+	}
+	o.asyncOps.Store(id, a)
+	started := *a.op
+
+	go func() {
+		defer cancel()
+		a.result, a.err = o.Create(ctx, input)
+
+		ended := time.Now().UTC()
+		a.op.EndedAt = &ended
+		switch {
+		case a.err == nil:
+			a.op.Status, a.op.Artifact = OperationSucceeded, a.result.Artifact
+		case a.cancelRequested.Load():
+			a.op.Status, a.op.Error = OperationCancelled, a.err.Error()
+		default:
+			a.op.Status, a.op.Error = OperationFailed, a.err.Error()
+		}
+		if err := store.save(a.op); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		close(a.done)
+		o.asyncOps.Delete(id)
+		if onDone != nil {
+			onDone(a.op)
+		}
+	}()
+	return &started, nil
+}
+
+// pruneAsyncCreates drops the outcomes of the creations that ended more than
+// asyncCreateRetention ago.
+func (o *Orchestrator) pruneAsyncCreates() {
+	o.asyncCreates.Range(func(testID, v any) bool {
+		a := v.(*asyncCreate)
+		select {
+		case <-a.done:
+			if a.op.EndedAt != nil && time.Since(*a.op.EndedAt) > asyncCreateRetention {
+				o.asyncCreates.CompareAndDelete(testID, a)
+			}
+		default:
+		}
+		return true
+	})
+}
+
+// WaitCreate blocks until the creation started by CreateAsync for testID
+// ends and returns its outcome. It returns ErrWaitTimeout if ctx expires
+// first. The outcome of the last creation of a testID can be collected once
+// after the creation ended, within asyncCreateRetention.
+func (o *Orchestrator) WaitCreate(ctx context.Context, testID string) (*CreateResult, error) {
+	v, ok := o.asyncCreates.Load(testID)
+	if !ok {
+		return nil, fmt.Errorf("no asynchronous create started for testID %q", testID)
+	}
+	a := v.(*asyncCreate)
+	select {
+	case <-a.done:
+		o.asyncCreates.CompareAndDelete(testID, a)
+		return a.result, a.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("create of testID %q: %w", testID, ErrWaitTimeout)
+		}
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOrchestrator_CreateAsync(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	done := make(chan *Operation, 1)
+	input := &v1.CreateInput{TestID: "test-async", TmpDir: t.TempDir()}
+	started, err := o.CreateAsync(input, func(op *Operation) { done <- op })
+	if err != nil {
+		t.Fatalf("CreateAsync() error = %v", err)
+	}
+	if started.ID == "" || started.Status != OperationRunning || started.TestID != "test-async" {
+		t.Errorf("CreateAsync() = %+v", started)
+	}
+	select {
+	case op := <-done:
+		if op.Status != OperationFailed || op.Error == "" || op.EndedAt == nil {
+			t.Fatalf("create without a spec should fail, got %+v", op)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("onDone was not called")
+	}
+	if _, err := o.WaitCreate(context.Background(), "test-async"); err == nil || strings.Contains(err.Error(), "no asynchronous create") {
+		t.Errorf("WaitCreate() error = %v, want the error of the creation", err)
+	}
+	if _, err := o.WaitCreate(context.Background(), "test-async"); err == nil || !strings.Contains(err.Error(), "no asynchronous create") {
+		t.Errorf("WaitCreate() of a collected outcome error = %v, want none started", err)
+	}
+	op, err := o.Operation(started.ID)
+	if err != nil || op.Status != OperationFailed {
+		t.Errorf("Operation() = %+v, %v", op, err)
+	}
+
+	// A finished creation can be started again
+	if _, err := o.CreateAsync(input, nil); err != nil {
+		t.Errorf("CreateAsync() after the previous create ended error = %v", err)
+	}
+	if _, err := o.WaitCreate(context.Background(), "test-async"); err == nil {
+		t.Error("WaitCreate() should return the error of the second creation")
+	}
+	if _, err := o.WaitCreate(context.Background(), "unknown"); err == nil {
+		t.Error("WaitCreate() of an unknown testID should fail")
+	}
+	if _, err := o.CreateAsync(&v1.CreateInput{}, nil); err == nil {
+		t.Error("CreateAsync() without a testID should fail")
+	}
+}
+
+func TestOrchestrator_CreateAsyncInProgress(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	o.asyncCreates.Store("test-running", &asyncCreate{done: make(chan struct{})})
+	_, err = o.CreateAsync(&v1.CreateInput{TestID: "test-running"}, nil)
+	if !errors.Is(err, ErrCreateInProgress) {
+		t.Errorf("CreateAsync() error = %v, want %v", err, ErrCreateInProgress)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := o.WaitCreate(ctx, "test-running"); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitCreate() error = %v, want %v", err, ErrWaitTimeout)
+	}
+}
+
+func TestOrchestrator_pruneAsyncCreates(t *testing.T) {
+	o := &Orchestrator{}
+	old := time.Now().Add(-asyncCreateRetention - time.Minute)
+	recent := time.Now()
+	for testID, ended := range map[string]*time.Time{"test-old": &old, "test-recent": &recent, "test-running": nil} {
+		a := &asyncCreate{op: &Operation{EndedAt: ended}, done: make(chan struct{})}
+		if ended != nil {
+			close(a.done)
+		}
+		o.asyncCreates.Store(testID, a)
+	}
+
+	o.pruneAsyncCreates()
+	for testID, want := range map[string]bool{"test-old": false, "test-recent": true, "test-running": true} {
+		if _, ok := o.asyncCreates.Load(testID); ok != want {
+			t.Errorf("%s kept = %v, want %v", testID, ok, want)
+		}
+	}
+}
+
+func TestOrchestrator_CreateAsyncReadOnly(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, err := o.CreateAsync(&v1.CreateInput{TestID: "test-1"}, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CreateAsync() error = %v, want %v", err, ErrReadOnly)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// vmFeatures maps the VM features advertised by providers to the spec fields
+// using them.
+var vmFeatures = []struct {
+	feature string
+	field   string
+	used    func(spec *v1.VMSpec) bool
+}{
+	{providerv1.VMFeatureVsock, "devices.vsock", func(spec *v1.VMSpec) bool { return spec.Devices.Vsock != nil }},
+	{providerv1.VMFeatureSecurity, "security", func(spec *v1.VMSpec) bool { return spec.Security != nil }},
+	{providerv1.VMFeatureDiskEncryption, "disk.encryption", func(spec *v1.VMSpec) bool {
+		return spec.Disk.Encryption != nil && spec.Disk.Encryption.Enabled
+	}},
+	{providerv1.VMFeatureNICOptions, "nics", func(spec *v1.VMSpec) bool { return len(spec.Nics) > 0 }},
+	{providerv1.VMFeatureDataDisks, "disks", func(spec *v1.VMSpec) bool { return len(spec.Disks) > 0 }},
+	{providerv1.VMFeatureStaticIP, "ip", func(spec *v1.VMSpec) bool { return spec.Ip != "" }},
+	{providerv1.VMFeatureRawUserData, "cloudInit.rawUserData", func(spec *v1.VMSpec) bool { return spec.CloudInit.RawUserData != "" }},
+	{providerv1.VMFeatureGuestNetwork, "networkConfig", func(spec *v1.VMSpec) bool { return hasGuestNetworkConfig(spec) }},
+	{providerv1.VMFeaturePCIPassthrough, "devices.pciPassthrough", func(spec *v1.VMSpec) bool { return len(spec.Devices.PciPassthrough) > 0 }},
+	{providerv1.VMFeatureMdev, "devices.mdevs", func(spec *v1.VMSpec) bool { return len(spec.Devices.Mdevs) > 0 }},
+}
+
+// verifyProviderCapabilities checks, once providers are running, that the
+// provider of every key, network, and VM advertises the create operation for
+// that kind, and the provider of every VM the features its spec uses. Both
+// ends of a cross-provider template reference are covered, so a value can
+// only be promised to a consumer if its producer can create it.
+func verifyProviderCapabilities(manager *provider.Manager, testenvSpec *v1.Spec) error {
+	// Index cross-provider consumers by producer for clearer errors.
+	consumers := make(map[string][]spec.CrossProviderRef)
+	for _, ref := range spec.FindCrossProviderRefs(testenvSpec) {
+		log.Printf("Cross-provider reference: %s", ref)
+		key := ref.Producer.Kind + ":" + ref.Producer.Name
+		consumers[key] = append(consumers[key], ref)
+	}
+
+	check := func(kind, name, declaredProvider string) error {
+		providerName := spec.ResolveResourceProvider(testenvSpec, declaredProvider)
+		if providerName == "" || manager.SupportsOperation(providerName, kind, "create") {
+			return nil
+		}
+		if refs := consumers[kind+":"+name]; len(refs) > 0 {
+			return fmt.Errorf("%s %q: provider %q does not support creating %s resources (required by cross-provider reference %s)",
+				kind, name, providerName, kind, refs[0])
+		}
+		return fmt.Errorf("%s %q: provider %q does not support creating %s resources", kind, name, providerName, kind)
+	}
+
+	for _, k := range testenvSpec.Keys {
+		if err := check("key", k.Name, k.Provider); err != nil {
+			return err
+		}
+	}
+	for _, n := range testenvSpec.Networks {
+		if err := check("network", n.Name, n.Provider); err != nil {
+			return err
+		}
+	}
+	for _, vm := range testenvSpec.Vms {
+		if err := check("vm", vm.Name, vm.Provider); err != nil {
+			return err
+		}
+		providerName := spec.ResolveResourceProvider(testenvSpec, vm.Provider)
+		if providerName == "" {
+			continue
+		}
+		for _, f := range vmFeatures {
+			if f.used(&vm.Spec) && !manager.SupportsVMFeature(providerName, f.feature) {
+				return fmt.Errorf("vm %q: provider %q version %s lacks the %s vm feature used by %s; upgrade it to a version advertising %q in its vm capabilities",
+					vm.Name, providerName, manager.Version(providerName), f.feature, f.field, f.feature)
+			}
+		}
+		if testenvSpec.Idle.After != "" && spec.IdleAction(testenvSpec) == spec.IdleActionSave &&
+			!manager.SupportsOperation(providerName, "vm", providerv1.PowerSave) {
+			return fmt.Errorf("vm %q: provider %q does not support saving vm resources (required by idle.action %q)",
+				vm.Name, providerName, spec.IdleActionSave)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+func TestVerifyProviderCapabilities(t *testing.T) {
+	createOnly := []string{"create"}
+	newManager := func(localKinds ...string) *provider.Manager {
+		m := provider.NewManager()
+		local := &providerv1.CapabilitiesResponse{ProviderName: "local"}
+		for _, kind := range localKinds {
+			local.Resources = append(local.Resources, providerv1.ResourceCapability{Kind: kind, Operations: createOnly})
+		}
+		m.RegisterCapabilities("local", local)
+		m.RegisterCapabilities("aws", &providerv1.CapabilitiesResponse{
+			ProviderName: "aws",
+			Resources: []providerv1.ResourceCapability{
+				{Kind: "network", Operations: createOnly},
+				{Kind: "vm", Operations: createOnly},
+			},
+		})
+		return m
+	}
+
+	testenvSpec := &v1.Spec{
+		Providers: []v1.ProviderConfig{
+			{Name: "local", Engine: "go://local", Default: true},
+			{Name: "aws", Engine: "go://aws"},
+		},
+		Keys: []v1.KeyResource{{Name: "deploy-key", Spec: v1.KeySpec{Type: "ed25519"}}},
+		Networks: []v1.NetworkResource{
+			{Name: "vpc", Kind: "vpc", Provider: "aws"},
+		},
+		Vms: []v1.VMResource{{
+			Name:     "cloud-vm",
+			Provider: "aws",
+			Spec: v1.VMSpec{
+				Network: "vpc",
+				CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "ci", SshAuthorizedKeys: []string{"{{ .Keys.deploy-key.PublicKey }}"}}},
+				},
+			},
+		}},
+	}
+
+	if err := verifyProviderCapabilities(newManager("key"), testenvSpec); err != nil {
+		t.Fatalf("verifyProviderCapabilities() error = %v", err)
+	}
+
+	err := verifyProviderCapabilities(newManager(), testenvSpec)
+	if err == nil {
+		t.Fatal("verifyProviderCapabilities() expected error when producer cannot create keys")
+	}
+	for _, want := range []string{`key "deploy-key"`, `provider "local"`, "cross-provider reference", "cloud-vm"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err.Error(), want)
+		}
+	}
+}
+
+func TestVerifyProviderCapabilities_VMFeatures(t *testing.T) {
+	m := provider.NewManager()
+	m.RegisterCapabilities("qemu", &providerv1.CapabilitiesResponse{
+		ProviderName: "qemu",
+		Version:      "v1.0.0",
+		Resources: []providerv1.ResourceCapability{
+			{Kind: "vm", Operations: []string{"create"}, VMFeatures: []string{providerv1.VMFeatureNICOptions}},
+		},
+	})
+	testenvSpec := &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "qemu", Engine: "go://qemu", Default: true}},
+		Vms: []v1.VMResource{{
+			Name: "web",
+			Spec: v1.VMSpec{Nics: []v1.VMNICSpec{{Network: "lan", Mtu: 9000}}},
+		}},
+	}
+	if err := verifyProviderCapabilities(m, testenvSpec); err != nil {
+		t.Fatalf("verifyProviderCapabilities() error = %v", err)
+	}
+
+	testenvSpec.Vms[0].Spec.Devices.Vsock = &v1.VsockSpec{}
+	err := verifyProviderCapabilities(m, testenvSpec)
+	if err == nil {
+		t.Fatal("verifyProviderCapabilities() expected error when the provider lacks vsock")
+	}
+	want := `vm "web": provider "qemu" version v1.0.0 lacks the vsock vm feature used by devices.vsock`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q missing %q", err.Error(), want)
+	}
+
+	testenvSpec.Vms[0].Spec.Devices = v1.VMDevicesSpec{PciPassthrough: []string{"0000:01:00.0"}}
+	err = verifyProviderCapabilities(m, testenvSpec)
+	want = "lacks the pciPassthrough vm feature used by devices.pciPassthrough"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("verifyProviderCapabilities() error = %v, want %q", err, want)
+	}
+}
+
+func TestExecutor_convertHostDevices(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	vm := executor.convertVMSpec(v1.VMSpec{Memory: 1024, Vcpus: 1, Devices: v1.VMDevicesSpec{
+		PciPassthrough: []string{"0000:01:00.0"},
+		Mdevs:          []v1.MdevSpec{{Uuid: "4b20d080-1b54-4048-85b3-a6a62d165c01"}, {Type: "nvidia-63", Parent: "0000:3b:00.0"}},
+	}})
+	if !reflect.DeepEqual(vm.PCIPassthrough, []string{"0000:01:00.0"}) {
+		t.Errorf("VMSpec.PCIPassthrough = %v, want [0000:01:00.0]", vm.PCIPassthrough)
+	}
+	wantMdevs := []providerv1.MdevSpec{{UUID: "4b20d080-1b54-4048-85b3-a6a62d165c01"}, {Type: "nvidia-63", Parent: "0000:3b:00.0"}}
+	if !reflect.DeepEqual(vm.Mdevs, wantMdevs) {
+		t.Errorf("VMSpec.Mdevs = %+v, want %+v", vm.Mdevs, wantMdevs)
+	}
+}
+
+func TestVerifyProviderCapabilities_IdleSave(t *testing.T) {
+	m := provider.NewManager()
+	m.RegisterCapabilities("qemu", &providerv1.CapabilitiesResponse{
+		ProviderName: "qemu",
+		Resources: []providerv1.ResourceCapability{
+			{Kind: "vm", Operations: []string{"create", "stop"}},
+		},
+	})
+	testenvSpec := &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "qemu", Engine: "go://qemu", Default: true}},
+		Vms:       []v1.VMResource{{Name: "web"}},
+		Idle:      v1.IdleSpec{After: "30m"},
+	}
+	if err := verifyProviderCapabilities(m, testenvSpec); err != nil {
+		t.Fatalf("verifyProviderCapabilities() error = %v", err)
+	}
+
+	testenvSpec.Idle.Action = "save"
+	err := verifyProviderCapabilities(m, testenvSpec)
+	if err == nil || !strings.Contains(err.Error(), `provider "qemu" does not support saving vm resources`) {
+		t.Errorf("verifyProviderCapabilities() error = %v, want the missing save operation", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// ErrInsufficientCapacity is returned when a spec cannot fit on the host of
+// one of its providers.
+var ErrInsufficientCapacity = errors.New("insufficient host capacity")
+
+// Defaults applied by VM providers when the spec leaves a value unset.
+const (
+	defaultVMMemoryMB = 2048
+	defaultVMVCPUs    = 2
+	defaultVMDiskSize = "20G"
+)
+
+// ResourceTotals sums the resources requested by VMs.
+type ResourceTotals struct {
+	VMs      int   `json:"vms"`
+	VCPUs    int   `json:"vcpus"`
+	MemoryMB int   `json:"memoryMB"`
+	DiskMB   int64 `json:"diskMB"`
+}
+
+// ProviderCapacity compares the resources requested from a provider with the
+// capacity its host reported.
+type ProviderCapacity struct {
+	// Provider is the provider name.
+	Provider string `json:"provider"`
+	// Requested sums the VMs managed by the provider.
+	Requested ResourceTotals `json:"requested"`
+	// Host is the capacity reported by the provider, nil if unknown.
+	Host *providerv1.HostCapacity `json:"host,omitempty"`
+	// Warnings describe requests that may not fit (e.g. overcommitted vCPUs).
+	Warnings []string `json:"warnings,omitempty"`
+	// Errors describe requests that cannot fit.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// requestedResources sums the VM resources of testenvSpec per provider.
+func requestedResources(testenvSpec *v1.Spec) (map[string]*ResourceTotals, error) {
+	totals := make(map[string]*ResourceTotals)
+	for _, vm := range testenvSpec.Vms {
+		providerName := spec.ResolveResourceProvider(testenvSpec, vm.Provider)
+		t, ok := totals[providerName]
+		if !ok {
+			t = &ResourceTotals{}
+			totals[providerName] = t
+		}
+
+		memory, vcpus, size := vm.Spec.Memory, vm.Spec.Vcpus, vm.Spec.Disk.Size
+		if memory == 0 {
+			memory = defaultVMMemoryMB
+		}
+		if vcpus == 0 {
+			vcpus = defaultVMVCPUs
+		}
+		if size == "" {
+			size = defaultVMDiskSize
+		}
+		diskMB, err := parseSizeMB(size)
+		if err != nil {
+			return nil, fmt.Errorf("vm %q: %w", vm.Name, err)
+		}
+
+		t.VMs++
+		t.VCPUs += vcpus
+		t.MemoryMB += memory
+		t.DiskMB += diskMB
+	}
+	return totals, nil
+}
+
+// planCapacity compares requested resources with host capacities, keyed by
+// provider name. Memory beyond the host total cannot fit; memory beyond free
+// memory, overcommitted vCPUs, and disks larger than the free space (they are
+// thin-provisioned) only warn.
+func planCapacity(testenvSpec *v1.Spec, hosts map[string]*providerv1.HostCapacity) ([]ProviderCapacity, error) {
+	totals, err := requestedResources(testenvSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]ProviderCapacity, 0, len(names))
+	for _, name := range names {
+		c := ProviderCapacity{Provider: name, Requested: *totals[name], Host: hosts[name]}
+		if host := c.Host; host != nil {
+			req := c.Requested
+			if host.MemoryMB > 0 && req.MemoryMB > host.MemoryMB {
+				c.Errors = append(c.Errors, fmt.Sprintf("%d MB of memory requested, host has %d MB", req.MemoryMB, host.MemoryMB))
+			} else if host.FreeMemoryMB > 0 && req.MemoryMB > host.FreeMemoryMB {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%d MB of memory requested, host has %d MB free", req.MemoryMB, host.FreeMemoryMB))
+			}
+			if host.CPUs > 0 && req.VCPUs > host.CPUs {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%d vCPUs requested, host has %d CPUs", req.VCPUs, host.CPUs))
+			}
+			if host.FreeDiskMB > 0 && req.DiskMB > host.FreeDiskMB {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("%d MB of disk requested, host has %d MB free", req.DiskMB, host.FreeDiskMB))
+			}
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// hostCapacities returns the host capacity reported by each running provider.
+func hostCapacities(manager *provider.Manager) map[string]*providerv1.HostCapacity {
+	hosts := make(map[string]*providerv1.HostCapacity)
+	for _, name := range manager.List() {
+		if info, ok := manager.GetInfo(name); ok && info.Capabilities != nil {
+			hosts[name] = info.Capabilities.Host
+		}
+	}
+	return hosts
+}
+
+// capacityError joins the capacity errors of every provider, or returns nil.
+func capacityError(capacity []ProviderCapacity) error {
+	var msgs []string
+	for _, c := range capacity {
+		for _, e := range c.Errors {
+			msgs = append(msgs, fmt.Sprintf("provider %q: %s", c.Provider, e))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInsufficientCapacity, strings.Join(msgs, "; "))
+}
+
+// parseSizeMB parses a disk size such as "20G" or "512M" (binary units, as
+// qemu-img) into megabytes. A bare number is in bytes.
+func parseSizeMB(size string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	multiplier := map[byte]float64{'K': 1.0 / 1024, 'M': 1, 'G': 1024, 'T': 1024 * 1024}
+	factor := 1.0 / (1024 * 1024)
+	if s != "" {
+		if m, ok := multiplier[s[len(s)-1]]; ok {
+			factor = m
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid disk size %q", size)
+	}
+	return int64(n * factor), nil
+}
@@ -0,0 +1,122 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestParseSizeMB(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int64
+		wantErr bool
+	}{
+		{size: "20G", want: 20480},
+		{size: "512M", want: 512},
+		{size: "1T", want: 1024 * 1024},
+		{size: "2048K", want: 2},
+		{size: "10GB", want: 10240},
+		{size: "1073741824", want: 1024},
+		{size: "big", wantErr: true},
+		{size: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSizeMB(tt.size)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSizeMB(%q) error = %v, wantErr %v", tt.size, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSizeMB(%q) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestPlanCapacity(t *testing.T) {
+	testenvSpec := &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "local", Default: true}, {Name: "cloud"}},
+		Vms: []v1.VMResource{
+			{Name: "a", Spec: v1.VMSpec{Memory: 4096, Vcpus: 4, Disk: v1.DiskSpec{Size: "10G"}}},
+			{Name: "b", Spec: v1.VMSpec{}},
+			{Name: "c", Provider: "cloud", Spec: v1.VMSpec{Memory: 1024, Vcpus: 1}},
+		},
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		capacity, err := planCapacity(testenvSpec, map[string]*providerv1.HostCapacity{
+			"local": {CPUs: 16, MemoryMB: 32768, FreeMemoryMB: 16384, FreeDiskMB: 100000},
+		})
+		if err != nil {
+			t.Fatalf("planCapacity failed: %v", err)
+		}
+		if len(capacity) != 2 || capacity[0].Provider != "cloud" || capacity[1].Provider != "local" {
+			t.Fatalf("unexpected capacity: %+v", capacity)
+		}
+		want := ResourceTotals{VMs: 2, VCPUs: 6, MemoryMB: 6144, DiskMB: 30720}
+		if capacity[1].Requested != want {
+			t.Errorf("local requested = %+v, want %+v", capacity[1].Requested, want)
+		}
+		if capacity[0].Host != nil {
+			t.Errorf("cloud host = %+v, want nil", capacity[0].Host)
+		}
+		if err := capacityError(capacity); err != nil {
+			t.Errorf("capacityError() = %v, want nil", err)
+		}
+		if len(capacity[1].Warnings) != 0 {
+			t.Errorf("unexpected warnings: %v", capacity[1].Warnings)
+		}
+	})
+
+	t.Run("warns", func(t *testing.T) {
+		capacity, err := planCapacity(testenvSpec, map[string]*providerv1.HostCapacity{
+			"local": {CPUs: 4, MemoryMB: 8192, FreeMemoryMB: 4096, FreeDiskMB: 1024},
+		})
+		if err != nil {
+			t.Fatalf("planCapacity failed: %v", err)
+		}
+		if got := len(capacity[1].Warnings); got != 3 {
+			t.Errorf("got %d warnings, want 3: %v", got, capacity[1].Warnings)
+		}
+		if err := capacityError(capacity); err != nil {
+			t.Errorf("capacityError() = %v, want nil", err)
+		}
+	})
+
+	t.Run("does not fit", func(t *testing.T) {
+		capacity, err := planCapacity(testenvSpec, map[string]*providerv1.HostCapacity{
+			"local": {CPUs: 16, MemoryMB: 4096},
+		})
+		if err != nil {
+			t.Fatalf("planCapacity failed: %v", err)
+		}
+		if err := capacityError(capacity); !errors.Is(err, ErrInsufficientCapacity) {
+			t.Errorf("capacityError() = %v, want ErrInsufficientCapacity", err)
+		}
+	})
+
+	t.Run("invalid disk size", func(t *testing.T) {
+		bad := &v1.Spec{Vms: []v1.VMResource{{Name: "x", Spec: v1.VMSpec{Disk: v1.DiskSpec{Size: "lots"}}}}}
+		if _, err := planCapacity(bad, nil); err == nil {
+			t.Error("expected error for invalid disk size")
+		}
+	})
+}
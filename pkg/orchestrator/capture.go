@@ -0,0 +1,188 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// CaptureDir is the directory of the artifact directory receiving pcap files.
+const CaptureDir = "captures"
+
+// CaptureOptions configures StartCapture. Exactly one of Network and VM is
+// set.
+type CaptureOptions struct {
+	// Network is the name of the network to capture, as declared in the spec.
+	Network string
+	// VM is the name of the VM whose interface is captured, as declared in
+	// the spec.
+	VM string
+	// MAC selects the interface of VM. Defaults to its first interface.
+	MAC string
+	// Filter is a pcap-filter expression, e.g. "tcp port 443".
+	Filter string
+	// MaxSizeMB is the maximum size of the pcap file. Zero uses the
+	// provider default.
+	MaxSizeMB int
+	// MaxDuration is the maximum duration of the capture. Zero uses the
+	// provider default.
+	MaxDuration time.Duration
+}
+
+// Capture is a packet capture run by a provider.
+type Capture struct {
+	// Provider is the provider running the capture.
+	Provider string `json:"provider"`
+	providerv1.CaptureState
+}
+
+// StartCapture starts capturing the packets of a network or VM interface of
+// a stored environment into a pcap file under the captures directory of its
+// artifact directory. The capture runs in the provider until StopCapture,
+// or until it reaches its size or time limit; it stops with the provider,
+// so it must be stopped by the same orchestrator.
+func (o *Orchestrator) StartCapture(environmentID string, opts CaptureOptions) (*Capture, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("capture rejected: %w", ErrReadOnly)
+	}
+	// Provider calls take no context: the capture is only registered so
+	// that Shutdown waits for it
+	_, end, err := o.ops.begin(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("capture rejected: %w", err)
+	}
+	defer end()
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.ArtifactDir == "" {
+		return nil, fmt.Errorf("environment %q has no artifact directory", environmentID)
+	}
+	providerName, req, err := captureRequest(envState, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.ensureProvider(envState, providerName); err != nil {
+		return nil, err
+	}
+	if !o.manager.SupportsOperation(providerName, "network", "capture") {
+		return nil, fmt.Errorf("provider %q does not support packet capture", providerName)
+	}
+
+	result, err := o.manager.Call(providerName, providerv1.NetworkCaptureStartTool, req)
+	if err := operationError(providerv1.NetworkCaptureStartTool, result, err); err != nil {
+		return nil, err
+	}
+	state, err := decodeCaptureState(result.Resource)
+	if err != nil {
+		return nil, err
+	}
+	o.captures.Store(state.ID, providerName)
+	log.Printf("Capturing %s of environment %q into %s (capture %s)", state.Interface, environmentID, state.Path, state.ID)
+	return &Capture{Provider: providerName, CaptureState: *state}, nil
+}
+
+// StopCapture stops a capture started by StartCapture and returns its final
+// state. Captures that reached a limit are already stopped; stopping them
+// returns why.
+func (o *Orchestrator) StopCapture(id string) (*Capture, error) {
+	_, end, err := o.ops.begin(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("stopping capture rejected: %w", err)
+	}
+	defer end()
+	value, ok := o.captures.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("capture %q not found", id)
+	}
+	providerName := value.(string)
+	result, err := o.manager.Call(providerName, providerv1.NetworkCaptureStopTool, &providerv1.NetworkCaptureStopRequest{ID: id})
+	if err := operationError(providerv1.NetworkCaptureStopTool, result, err); err != nil {
+		return nil, err
+	}
+	o.captures.Delete(id)
+	state, err := decodeCaptureState(result.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return &Capture{Provider: providerName, CaptureState: *state}, nil
+}
+
+// captureRequest resolves the provider and the network_capture_start input
+// of a capture of an environment.
+func captureRequest(envState *v1.EnvironmentState, opts CaptureOptions) (string, *providerv1.NetworkCaptureStartRequest, error) {
+	if (opts.Network == "") == (opts.VM == "") {
+		return "", nil, errors.New("exactly one of network and vm is required")
+	}
+	if opts.MaxSizeMB < 0 || opts.MaxDuration < 0 {
+		return "", nil, errors.New("capture limits must not be negative")
+	}
+	req := &providerv1.NetworkCaptureStartRequest{
+		MAC:       opts.MAC,
+		OutputDir: filepath.Join(envState.ArtifactDir, CaptureDir),
+		Filter:    opts.Filter,
+		MaxSizeMB: opts.MaxSizeMB,
+	}
+	if opts.MaxDuration > 0 {
+		// Round up so that sub-second durations still capture
+		req.MaxDurationSec = int((opts.MaxDuration + time.Second - 1) / time.Second)
+	}
+
+	kind, name, resources := "network", opts.Network, envState.Resources.Networks
+	if opts.VM != "" {
+		kind, name, resources = "vm", opts.VM, envState.Resources.VMs
+	}
+	resource := resources[name]
+	if resource == nil {
+		return "", nil, fmt.Errorf("%s %q not found in environment %q", kind, name, envState.ID)
+	}
+	// Providers know resources by the name they reported
+	providerName := name
+	if n := getString(resource.State, "name"); n != "" {
+		providerName = n
+	}
+	if opts.VM != "" {
+		req.VM = providerName
+	} else {
+		req.Network = providerName
+	}
+	return resource.Provider, req, nil
+}
+
+// decodeCaptureState converts the resource returned by a capture tool.
+func decodeCaptureState(resource any) (*providerv1.CaptureState, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var state providerv1.CaptureState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid capture state: %w", err)
+	}
+	if state.ID == "" {
+		return nil, errors.New("invalid capture state: missing id")
+	}
+	return &state, nil
+}
@@ -0,0 +1,103 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestCaptureRequest(t *testing.T) {
+	envState := &v1.EnvironmentState{
+		ID:          "env-1",
+		ArtifactDir: "/tmp/artifacts",
+		Resources: v1.ResourceMap{
+			Networks: map[string]*v1.ResourceState{"lan": {Provider: "stub", State: map[string]any{"name": "env-1-lan"}}},
+			VMs:      map[string]*v1.ResourceState{"web": {Provider: "libvirt", State: map[string]any{}}},
+		},
+	}
+
+	provider, req, err := captureRequest(envState, CaptureOptions{Network: "lan", Filter: "icmp", MaxDuration: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("captureRequest() error = %v", err)
+	}
+	if provider != "stub" || req.Network != "env-1-lan" || req.OutputDir != "/tmp/artifacts/captures" || req.Filter != "icmp" || req.MaxDurationSec != 2 {
+		t.Errorf("captureRequest() = %q, %+v", provider, req)
+	}
+
+	provider, req, err = captureRequest(envState, CaptureOptions{VM: "web", MAC: "52:54:00:00:00:01"})
+	if err != nil {
+		t.Fatalf("captureRequest() error = %v", err)
+	}
+	if provider != "libvirt" || req.VM != "web" || req.MAC != "52:54:00:00:00:01" || req.MaxDurationSec != 0 {
+		t.Errorf("captureRequest() = %q, %+v", provider, req)
+	}
+
+	for _, opts := range []CaptureOptions{{}, {Network: "lan", VM: "web"}, {Network: "lan", MaxSizeMB: -1}} {
+		if _, _, err := captureRequest(envState, opts); err == nil {
+			t.Errorf("captureRequest(%+v) expected error", opts)
+		}
+	}
+	if _, _, err := captureRequest(envState, CaptureOptions{VM: "db"}); err == nil || !strings.Contains(err.Error(), `vm "db" not found`) {
+		t.Errorf("captureRequest() error = %v, want vm not found", err)
+	}
+}
+
+func TestDecodeCaptureState(t *testing.T) {
+	state, err := decodeCaptureState(map[string]any{"id": "lan-1", "path": "/tmp/lan-1.pcap", "sizeBytes": 24})
+	if err != nil || state.ID != "lan-1" || state.Path != "/tmp/lan-1.pcap" || state.SizeBytes != 24 {
+		t.Errorf("decodeCaptureState() = %+v, %v", state, err)
+	}
+	if _, err := decodeCaptureState(map[string]any{}); err == nil {
+		t.Error("decodeCaptureState() expected error without id")
+	}
+}
+
+func TestOrchestrator_CaptureErrors(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	if _, err := o.StartCapture("env", CaptureOptions{Network: "lan"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("StartCapture() error = %v, want ErrReadOnly", err)
+	}
+	_ = o.Close()
+
+	o, err = NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+	if _, err := o.StartCapture("missing", CaptureOptions{Network: "lan"}); err == nil {
+		t.Error("StartCapture() expected error for a missing environment")
+	}
+	if err := o.store.Save(&v1.EnvironmentState{ID: "env-capture"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.StartCapture("env-capture", CaptureOptions{Network: "lan"}); err == nil || !strings.Contains(err.Error(), "artifact directory") {
+		t.Errorf("StartCapture() error = %v, want no artifact directory", err)
+	}
+	if _, err := o.StopCapture("lan-1"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("StopCapture() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/catalog"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+// OpenCatalog opens the configured template catalog. Git catalogs are
+// fetched again on every call into the git cache of the state directory.
+func (o *Orchestrator) OpenCatalog(ctx context.Context) (*catalog.Catalog, error) {
+	return catalog.Open(ctx, o.config.Catalog, paths.New(o.config.StateDir).GitCacheDir())
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/pki"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// CertsDir is the artifact subdirectory holding the test CA and the
+// certificates of an environment.
+const CertsDir = "certs"
+
+// defaultCertificatePath is the guest directory certificates are installed
+// in when spec.path is unset.
+const defaultCertificatePath = "/etc/testenv-vm/certs"
+
+// needsCA reports whether a spec declares a CA or certificates.
+func needsCA(spec *v1.Spec) bool {
+	ca := spec.Ca
+	return len(spec.Certificates) > 0 || ca.CommonName != "" || ca.Validity != "" || len(ca.Vms) > 0
+}
+
+// buildCA creates the test CA of an environment and writes its certificate
+// and key into the certs directory of the artifact directory.
+func buildCA(spec *v1.Spec, artifactDir string) (specpkg.CATemplateData, error) {
+	var validity time.Duration
+	if spec.Ca.Validity != "" {
+		d, err := time.ParseDuration(spec.Ca.Validity)
+		if err != nil {
+			return specpkg.CATemplateData{}, fmt.Errorf("invalid validity: %w", err)
+		}
+		validity = d
+	}
+	ca, err := pki.NewCA(spec.Ca.CommonName, validity)
+	if err != nil {
+		return specpkg.CATemplateData{}, err
+	}
+
+	dir := filepath.Join(artifactDir, CertsDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return specpkg.CATemplateData{}, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	data := specpkg.CATemplateData{
+		Cert:     string(ca.CertPEM),
+		Key:      string(ca.KeyPEM),
+		CertPath: filepath.Join(dir, "ca.crt"),
+		KeyPath:  filepath.Join(dir, "ca.key"),
+	}
+	if err := writeCertFiles(data.CertPath, ca.CertPEM, data.KeyPath, ca.KeyPEM); err != nil {
+		return specpkg.CATemplateData{}, err
+	}
+	return data, nil
+}
+
+// buildCertificate issues a certificate from its rendered spec with the CA
+// of the template context, and writes it and its key next to the CA. The
+// common name defaults to the first DNS name, then to the resource name.
+func buildCertificate(cert *v1.CertificateResource, templateCtx *specpkg.TemplateContext) (specpkg.CertificateTemplateData, error) {
+	if templateCtx.CA.Cert == "" {
+		return specpkg.CertificateTemplateData{}, errors.New("no CA")
+	}
+	ca, err := pki.ParseCA([]byte(templateCtx.CA.Cert), []byte(templateCtx.CA.Key))
+	if err != nil {
+		return specpkg.CertificateTemplateData{}, err
+	}
+
+	req := pki.Request{
+		CommonName: cert.Spec.CommonName,
+		DNSNames:   cert.Spec.DnsNames,
+		Client:     cert.Spec.Client,
+	}
+	if req.CommonName == "" && len(req.DNSNames) == 0 {
+		req.CommonName = cert.Name
+	}
+	for _, s := range cert.Spec.IpAddresses {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return specpkg.CertificateTemplateData{}, fmt.Errorf("invalid IP address %q", s)
+		}
+		req.IPAddresses = append(req.IPAddresses, ip)
+	}
+	if cert.Spec.Validity != "" {
+		d, err := time.ParseDuration(cert.Spec.Validity)
+		if err != nil {
+			return specpkg.CertificateTemplateData{}, fmt.Errorf("invalid validity: %w", err)
+		}
+		req.Validity = d
+	}
+	issued, err := ca.Issue(req)
+	if err != nil {
+		return specpkg.CertificateTemplateData{}, err
+	}
+
+	dir := filepath.Dir(templateCtx.CA.CertPath)
+	data := specpkg.CertificateTemplateData{
+		Cert:     string(issued.CertPEM),
+		Key:      string(issued.KeyPEM),
+		CACert:   templateCtx.CA.Cert,
+		CertPath: filepath.Join(dir, cert.Name+".crt"),
+		KeyPath:  filepath.Join(dir, cert.Name+".key"),
+	}
+	if err := writeCertFiles(data.CertPath, issued.CertPEM, data.KeyPath, issued.KeyPEM); err != nil {
+		return specpkg.CertificateTemplateData{}, err
+	}
+	return data, nil
+}
+
+// writeCertFiles writes a certificate, readable by everyone, and its key,
+// readable by its owner only.
+func writeCertFiles(certPath string, certPEM []byte, keyPath string, keyPEM []byte) error {
+	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		return fmt.Errorf("failed to write key: %w", err)
+	}
+	return nil
+}
+
+// loadCertificates restores the CA and the certificates of an existing
+// environment from its artifact directory. Missing files are skipped.
+func loadCertificates(spec *v1.Spec, artifactDir string, templateCtx *specpkg.TemplateContext) {
+	dir := filepath.Join(artifactDir, CertsDir)
+	caPath := filepath.Join(dir, "ca.crt")
+	caCert, err := os.ReadFile(caPath)
+	if err != nil {
+		return
+	}
+	caKey, _ := os.ReadFile(filepath.Join(dir, "ca.key"))
+	templateCtx.CA = specpkg.CATemplateData{
+		Cert:     string(caCert),
+		Key:      string(caKey),
+		CertPath: caPath,
+		KeyPath:  filepath.Join(dir, "ca.key"),
+	}
+	for _, c := range spec.Certificates {
+		data := specpkg.CertificateTemplateData{
+			CACert:   string(caCert),
+			CertPath: filepath.Join(dir, c.Name+".crt"),
+			KeyPath:  filepath.Join(dir, c.Name+".key"),
+		}
+		cert, err := os.ReadFile(data.CertPath)
+		if err != nil {
+			continue
+		}
+		key, _ := os.ReadFile(data.KeyPath)
+		data.Cert, data.Key = string(cert), string(key)
+		templateCtx.Certificates[c.Name] = data
+	}
+}
+
+// injectCertificates extends the cloud-init of a VM so that it trusts the
+// test CA, when listed in ca.vms or receiving a certificate, and installs
+// the certificates, keys and CA certificate issued for it.
+func injectCertificates(vmSpec *providerv1.VMSpec, vmName string, spec *v1.Spec, templateCtx *specpkg.TemplateContext) {
+	if templateCtx.CA.Cert == "" {
+		return
+	}
+	trust := slices.Contains(spec.Ca.Vms, vmName)
+	for _, cert := range spec.Certificates {
+		if cert.Spec.Vm != vmName {
+			continue
+		}
+		data, ok := templateCtx.Certificates[cert.Name]
+		if !ok {
+			continue
+		}
+		dir := cert.Spec.Path
+		if dir == "" {
+			dir = defaultCertificatePath
+		}
+		if vmSpec.CloudInit == nil {
+			vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+		}
+		vmSpec.CloudInit.WriteFiles = append(vmSpec.CloudInit.WriteFiles,
+			providerv1.WriteFileSpec{Path: path.Join(dir, cert.Name+".crt"), Content: data.Cert, Permissions: "0644"},
+			providerv1.WriteFileSpec{Path: path.Join(dir, cert.Name+".key"), Content: data.Key, Permissions: "0600"},
+			providerv1.WriteFileSpec{Path: path.Join(dir, "ca.crt"), Content: data.CACert, Permissions: "0644"},
+		)
+		trust = true
+	}
+	if !trust {
+		return
+	}
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	vmSpec.CloudInit.CACerts = append(vmSpec.CloudInit.CACerts, templateCtx.CA.Cert)
+}
+
+// renderCertificateSpec creates a deep copy and renders templates in a
+// certificate spec.
+func renderCertificateSpec(original *v1.CertificateResource, templateCtx *specpkg.TemplateContext) (*v1.CertificateResource, error) {
+	// Deep copy via JSON marshaling
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	var copy v1.CertificateResource
+	if err := json.Unmarshal(data, &copy); err != nil {
+		return nil, err
+	}
+
+	if err := specpkg.RenderSpec(&copy, templateCtx); err != nil {
+		return nil, err
+	}
+
+	return &copy, nil
+}
+
+// findCertificateSpec finds a certificate resource by name in the spec.
+func findCertificateSpec(spec *v1.Spec, name string) (*v1.CertificateResource, error) {
+	for i := range spec.Certificates {
+		if spec.Certificates[i].Name == name {
+			return &spec.Certificates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("certificate resource %q not found in spec", name)
+}
@@ -0,0 +1,157 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func newCertificateTestSpec() *v1.Spec {
+	return &v1.Spec{
+		Ca: v1.CASpec{Vms: []string{"client"}},
+		Certificates: []v1.CertificateResource{
+			{Name: "web", Spec: v1.CertificateSpec{DnsNames: []string{"web.test"}, IpAddresses: []string{"{{ .VMs.db.IP }}"}, Vm: "web"}},
+		},
+		Vms: []v1.VMResource{{Name: "web"}, {Name: "db"}, {Name: "client"}},
+	}
+}
+
+func TestBuildCertificate(t *testing.T) {
+	spec := newCertificateTestSpec()
+	artifactDir := t.TempDir()
+	if !needsCA(spec) || needsCA(&v1.Spec{}) {
+		t.Error("needsCA() should only hold with a CA or certificates")
+	}
+
+	ca, err := buildCA(spec, artifactDir)
+	if err != nil {
+		t.Fatalf("buildCA() error = %v", err)
+	}
+	if ca.CertPath != filepath.Join(artifactDir, CertsDir, "ca.crt") {
+		t.Errorf("CertPath = %q", ca.CertPath)
+	}
+	templateCtx := specpkg.NewTemplateContext()
+	templateCtx.CA = ca
+	templateCtx.VMs["db"] = specpkg.VMTemplateData{IP: "192.168.100.20"}
+
+	rendered, err := renderCertificateSpec(&spec.Certificates[0], templateCtx)
+	if err != nil {
+		t.Fatalf("renderCertificateSpec() error = %v", err)
+	}
+	data, err := buildCertificate(rendered, templateCtx)
+	if err != nil {
+		t.Fatalf("buildCertificate() error = %v", err)
+	}
+
+	block, _ := pem.Decode([]byte(data.Cert))
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caBlock, _ := pem.Decode([]byte(ca.Cert))
+	caCert, err := x509.ParseCertificate(caBlock.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	for _, host := range []string{"web.test", "192.168.100.20"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("Verify(%s) error = %v", host, err)
+		}
+	}
+	if info, err := os.Stat(data.KeyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("key file: %v, %v", info, err)
+	}
+
+	// The CA and certificates are restored from the artifact directory
+	restored := specpkg.NewTemplateContext()
+	loadCertificates(spec, artifactDir, restored)
+	if restored.CA != ca || restored.Certificates["web"] != data {
+		t.Error("loadCertificates() did not restore the CA and certificates")
+	}
+
+	o := &Orchestrator{}
+	artifact := o.buildArtifact("test", &v1.EnvironmentState{Spec: spec, ArtifactDir: artifactDir}, nil)
+	if artifact.Env["TESTENV_CA_CERT"] != ca.CertPath || artifact.Env["TESTENV_CERT_WEB_KEY"] != data.KeyPath {
+		t.Errorf("artifact Env = %v", artifact.Env)
+	}
+	if artifact.Files["testenv-vm.certificate.web.cert"] != filepath.Join(CertsDir, "web.crt") {
+		t.Errorf("artifact Files = %v", artifact.Files)
+	}
+
+	if _, err := buildCertificate(rendered, specpkg.NewTemplateContext()); err == nil {
+		t.Error("buildCertificate() without a CA succeeded")
+	}
+}
+
+func TestInjectCertificates(t *testing.T) {
+	spec := newCertificateTestSpec()
+	spec.Certificates[0].Spec.Path = "/etc/nginx/tls"
+	templateCtx := specpkg.NewTemplateContext()
+	templateCtx.CA = specpkg.CATemplateData{Cert: "CA"}
+	templateCtx.Certificates["web"] = specpkg.CertificateTemplateData{Cert: "CERT", Key: "KEY", CACert: "CA"}
+
+	web := &providerv1.VMSpec{}
+	injectCertificates(web, "web", spec, templateCtx)
+	want := []providerv1.WriteFileSpec{
+		{Path: "/etc/nginx/tls/web.crt", Content: "CERT", Permissions: "0644"},
+		{Path: "/etc/nginx/tls/web.key", Content: "KEY", Permissions: "0600"},
+		{Path: "/etc/nginx/tls/ca.crt", Content: "CA", Permissions: "0644"},
+	}
+	if got := web.CloudInit.WriteFiles; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("WriteFiles = %v, want %v", got, want)
+	}
+	if len(web.CloudInit.CACerts) != 1 {
+		t.Errorf("web CACerts = %v, want the CA", web.CloudInit.CACerts)
+	}
+
+	client := &providerv1.VMSpec{}
+	injectCertificates(client, "client", spec, templateCtx)
+	if client.CloudInit == nil || len(client.CloudInit.WriteFiles) != 0 || len(client.CloudInit.CACerts) != 1 {
+		t.Errorf("client should only trust the CA, got %+v", client.CloudInit)
+	}
+
+	db := &providerv1.VMSpec{}
+	injectCertificates(db, "db", spec, templateCtx)
+	if db.CloudInit != nil {
+		t.Errorf("db should be untouched, got %+v", db.CloudInit)
+	}
+}
+
+func TestBuildDAG_Certificates(t *testing.T) {
+	dag, err := BuildDAG(newCertificateTestSpec())
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+
+	certRef := v1.ResourceRef{Kind: "certificate", Name: "web"}
+	if !dag.DependsOn(certRef, v1.ResourceRef{Kind: "vm", Name: "db"}) {
+		t.Error("certificate should depend on the VM referenced in its SANs")
+	}
+	if !dag.DependsOn(v1.ResourceRef{Kind: "vm", Name: "web"}, certRef) {
+		t.Error("VM should depend on the certificate installed in it")
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Actions reported by Plan for the VMs of an existing environment, from the
+// least to the most disruptive.
+const (
+	// ActionCreate means the VM does not exist yet.
+	ActionCreate = "create"
+	// ActionNone means the rendered VM is unchanged.
+	ActionNone = "none"
+	// ActionUpdate means only orchestrator-side settings changed.
+	ActionUpdate = "update"
+	// ActionReboot means the domain must be redefined and rebooted.
+	ActionReboot = "reboot"
+	// ActionReplace means the VM must be destroyed and created again.
+	ActionReplace = "replace"
+	// ActionDelete means the VM is no longer in the spec.
+	ActionDelete = "delete"
+)
+
+// Content hash sections of a VM, recorded in v1.ResourceState.Hashes.
+const (
+	// HashCloudInit covers the cloud-init user data, network config, NIC
+	// options and guest environment.
+	HashCloudInit = "cloudInit"
+	// HashDisk covers the disk, architecture, machine type, boot settings
+	// and provider spec.
+	HashDisk = "disk"
+	// HashDomain covers the remaining domain settings: memory, CPUs,
+	// networks, static IP, consoles, shares and security.
+	HashDomain = "domain"
+	// HashReadiness covers the readiness checks.
+	HashReadiness = "readiness"
+)
+
+// vmHashSections maps each hash section to the action its change requires.
+// cloud-init only runs on first boot, so changing it requires a new VM.
+var vmHashSections = []struct {
+	name   string
+	action string
+	reason string
+}{
+	{HashCloudInit, ActionReplace, "cloud-init changed"},
+	{HashDisk, ActionReplace, "disk or boot configuration changed"},
+	{HashDomain, ActionReboot, "domain configuration changed"},
+	{HashReadiness, ActionUpdate, "readiness checks changed"},
+}
+
+// actionSeverity orders actions so that the most disruptive one wins.
+var actionSeverity = map[string]int{
+	ActionNone:    0,
+	ActionUpdate:  1,
+	ActionReboot:  2,
+	ActionReplace: 3,
+}
+
+// ResourceChange is the action Plan expects for one resource of an existing
+// environment.
+type ResourceChange struct {
+	// Resource is the changed resource.
+	Resource v1.ResourceRef `json:"resource"`
+	// Action is one of the Action* constants.
+	Action string `json:"action"`
+	// Reasons explain the action.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// contentHash returns the SHA-256 of the JSON encoding of v as "sha256:<hex>".
+func contentHash(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// vmContentHashes hashes the sections of a rendered VM create request. It is
+// computed before access servers and the guest environment are injected:
+// their generated keys and secret values are replaced by the access point
+// names and the declared (unresolved) environment.
+func vmContentHashes(req *providerv1.VMCreateRequest, vmName string, spec *v1.Spec, ci v1.CloudInitSpec) (map[string]string, error) {
+	var accessPoints []string
+	for _, access := range spec.Access {
+		if access.Spec.Vm == vmName {
+			accessPoints = append(accessPoints, access.Name)
+		}
+	}
+	sort.Strings(accessPoints)
+
+	s := req.Spec
+	sections := map[string]any{
+		HashCloudInit: struct {
+			CloudInit         *providerv1.CloudInitSpec
+			Environment       map[string]string
+			SecretEnvironment map[string]string
+			AccessPoints      []string
+			// NICs are configured in the guest on first boot, and omitted
+			// when unset to keep the hashes recorded before they existed
+			NICs []providerv1.NICSpec `json:",omitempty"`
+		}{s.CloudInit, ci.Environment, ci.SecretEnvironment, accessPoints, s.NICs},
+		HashDisk: struct {
+			Disk         providerv1.DiskSpec
+			Architecture string
+			MachineType  string
+			Boot         providerv1.BootSpec
+			ProviderSpec map[string]any
+			// Data disks are omitted when unset to keep the hashes
+			// recorded before they existed
+			Disks []providerv1.DiskSpec `json:",omitempty"`
+		}{s.Disk, s.Architecture, s.MachineType, s.Boot, req.ProviderSpec, s.Disks},
+		HashDomain: struct {
+			Memory        int
+			VCPUs         int
+			CPU           *providerv1.CPUSpec
+			Network       string
+			Networks      []string
+			Console       *providerv1.ConsoleSpec
+			MemoryBacking *providerv1.MemoryBackingSpec
+			VirtioFS      []providerv1.VirtioFSSpec
+			GuestAgent    bool
+			Security      *providerv1.SecuritySpec
+			// The static IP and the devices are omitted when unset to keep
+			// the hashes recorded before they existed
+			IP             string                `json:",omitempty"`
+			PCIPassthrough []string              `json:",omitempty"`
+			Mdevs          []providerv1.MdevSpec `json:",omitempty"`
+			Vsock          *providerv1.VsockSpec `json:",omitempty"`
+		}{s.Memory, s.VCPUs, s.CPU, s.Network, s.Networks, s.Console, s.MemoryBacking, s.VirtioFS, s.GuestAgent, s.Security, s.IP, s.PCIPassthrough, s.Mdevs, s.Vsock},
+		HashReadiness: s.Readiness,
+	}
+
+	hashes := make(map[string]string, len(sections))
+	for name, section := range sections {
+		h, err := contentHash(section)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", name, err)
+		}
+		hashes[name] = h
+	}
+	return hashes, nil
+}
+
+// compareVMHashes returns the action required to go from the recorded hashes
+// to the desired ones, with one reason per changed section.
+func compareVMHashes(recorded, desired map[string]string) (string, []string) {
+	if len(recorded) == 0 {
+		return ActionReplace, []string{"no content hashes recorded for the existing VM"}
+	}
+	action := ActionNone
+	var reasons []string
+	for _, section := range vmHashSections {
+		if recorded[section.name] == desired[section.name] {
+			continue
+		}
+		reasons = append(reasons, section.reason)
+		if actionSeverity[section.action] > actionSeverity[action] {
+			action = section.action
+		}
+	}
+	return action, reasons
+}
+
+// planVMChanges renders every VM of spec against the template context of an
+// existing environment and compares the result with the recorded hashes.
+// VMs that cannot be rendered are reported as replaced, with the render error
+// as reason.
+func (e *Executor) planVMChanges(spec *v1.Spec, envState *v1.EnvironmentState, env map[string]string, templatedFields *specpkg.TemplatedFields) []ResourceChange {
+	templateCtx := e.templateContextFromState(spec, envState, env)
+	isoConfig := newIsolationConfig(e.tenant, envState.ID, spec.NamePrefix, spec.Networks)
+	inheritNetworks(isoConfig, envState.Resources.Networks)
+
+	var changes []ResourceChange
+	inSpec := make(map[string]bool, len(spec.Vms))
+	for _, vm := range spec.Vms {
+		inSpec[vm.Name] = true
+		ref := v1.ResourceRef{Kind: "vm", Name: vm.Name, Provider: vm.Provider}
+		existing := envState.Resources.VMs[vm.Name]
+		if existing == nil || existing.Status != v1.StatusReady {
+			changes = append(changes, ResourceChange{Resource: ref, Action: ActionCreate})
+			continue
+		}
+
+		change := ResourceChange{Resource: ref}
+		desired, err := e.desiredVMHashes(ref, spec, templateCtx, templatedFields, isoConfig)
+		if err != nil {
+			change.Action = ActionReplace
+			change.Reasons = []string{fmt.Sprintf("cannot render vm: %v", err)}
+		} else {
+			change.Action, change.Reasons = compareVMHashes(existing.Hashes, desired)
+		}
+		changes = append(changes, change)
+	}
+
+	var removed []string
+	for name := range envState.Resources.VMs {
+		if !inSpec[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, ResourceChange{
+			Resource: v1.ResourceRef{Kind: "vm", Name: name, Provider: envState.Resources.VMs[name].Provider},
+			Action:   ActionDelete,
+		})
+	}
+	return changes
+}
+
+// desiredVMHashes renders a VM of spec and returns its content hashes.
+func (e *Executor) desiredVMHashes(
+	ref v1.ResourceRef,
+	spec *v1.Spec,
+	templateCtx *specpkg.TemplateContext,
+	templatedFields *specpkg.TemplatedFields,
+	isoConfig *IsolationConfig,
+) (map[string]string, error) {
+	req, rendered, err := e.buildVMRequest(ref, spec, templateCtx, nil, templatedFields, isoConfig)
+	if err != nil {
+		return nil, err
+	}
+	return vmContentHashes(req, ref.Name, spec, rendered.Spec.CloudInit)
+}
+
+// templateContextFromState rebuilds the template context of an existing
+// environment from its recorded resources, the image cache and the
+// certificates of its artifact directory. Tunnels and access points are not
+// recorded, so references to them fail to render.
+func (e *Executor) templateContextFromState(spec *v1.Spec, envState *v1.EnvironmentState, env map[string]string) *specpkg.TemplateContext {
+	templateCtx := specpkg.NewTemplateContext()
+	for k, v := range env {
+		templateCtx.Env[k] = v
+	}
+	if e.imageMgr != nil {
+		for _, img := range spec.Images {
+			path, ok := e.imageMgr.GetImagePath(img.Name)
+			if !ok {
+				continue
+			}
+			data := specpkg.ImageTemplateData{Path: path, Name: img.Name}
+			templateCtx.Images[img.Name] = data
+			if img.Spec.Alias != "" {
+				templateCtx.Images[img.Spec.Alias] = data
+			}
+		}
+	}
+	for kind, resources := range map[string]map[string]*v1.ResourceState{
+		"key":     envState.Resources.Keys,
+		"network": envState.Resources.Networks,
+		"vm":      envState.Resources.VMs,
+		"service": envState.Resources.Services,
+	} {
+		for name, rs := range resources {
+			e.updateTemplateContext(templateCtx, v1.ResourceRef{Kind: kind, Name: name}, rs.State)
+		}
+	}
+	if envState.ArtifactDir != "" {
+		loadCertificates(spec, envState.ArtifactDir, templateCtx)
+	}
+	return templateCtx
+}
@@ -0,0 +1,183 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestVMContentHashes(t *testing.T) {
+	req := &providerv1.VMCreateRequest{
+		Name: "vm1",
+		Spec: providerv1.VMSpec{
+			Memory:    1024,
+			Disk:      providerv1.DiskSpec{Size: "10G"},
+			CloudInit: &providerv1.CloudInitSpec{Runcmd: []string{"true"}},
+		},
+	}
+	ci := v1.CloudInitSpec{SecretEnvironment: map[string]string{"TOKEN": "env:TOKEN"}}
+	base, err := vmContentHashes(req, "vm1", &v1.Spec{}, ci)
+	if err != nil {
+		t.Fatalf("vmContentHashes() error = %v", err)
+	}
+	for _, section := range vmHashSections {
+		if !strings.HasPrefix(base[section.name], "sha256:") {
+			t.Errorf("section %q: unexpected hash %q", section.name, base[section.name])
+		}
+	}
+
+	again, _ := vmContentHashes(req, "vm1", &v1.Spec{}, ci)
+	if action, _ := compareVMHashes(base, again); action != ActionNone {
+		t.Errorf("identical requests must hash equally, got %q", action)
+	}
+
+	// Secret values are not resolved, so they cannot change the hash
+	t.Setenv("TOKEN", "changed")
+	secret, _ := vmContentHashes(req, "vm1", &v1.Spec{}, ci)
+	if secret[HashCloudInit] != base[HashCloudInit] {
+		t.Error("secret values must not be hashed")
+	}
+
+	// Serving an access point changes cloud-init
+	spec := &v1.Spec{Access: []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Vm: "vm1"}}}}
+	access, _ := vmContentHashes(req, "vm1", spec, ci)
+	if access[HashCloudInit] == base[HashCloudInit] {
+		t.Error("access points must be part of the cloud-init hash")
+	}
+
+	// Passed-through and virtual devices change the domain
+	devices := []func(s *providerv1.VMSpec){
+		func(s *providerv1.VMSpec) { s.PCIPassthrough = []string{"0000:01:00.0"} },
+		func(s *providerv1.VMSpec) {
+			s.Mdevs = []providerv1.MdevSpec{{UUID: "4b20d080-1b54-4048-85b3-a6a62d165c01"}}
+		},
+		func(s *providerv1.VMSpec) { s.Vsock = &providerv1.VsockSpec{} },
+	}
+	for i, set := range devices {
+		changed := *req
+		set(&changed.Spec)
+		hashes, _ := vmContentHashes(&changed, "vm1", &v1.Spec{}, ci)
+		if hashes[HashDomain] == base[HashDomain] {
+			t.Errorf("device %d must be part of the domain hash", i)
+		}
+	}
+}
+
+func TestCompareVMHashes(t *testing.T) {
+	recorded := map[string]string{HashCloudInit: "a", HashDisk: "b", HashDomain: "c", HashReadiness: "d"}
+	with := func(section, value string) map[string]string {
+		desired := make(map[string]string, len(recorded))
+		for k, v := range recorded {
+			desired[k] = v
+		}
+		desired[section] = value
+		return desired
+	}
+
+	tests := []struct {
+		name     string
+		recorded map[string]string
+		desired  map[string]string
+		action   string
+	}{
+		{"unchanged", recorded, with(HashDomain, "c"), ActionNone},
+		{"readiness", recorded, with(HashReadiness, "x"), ActionUpdate},
+		{"domain", recorded, with(HashDomain, "x"), ActionReboot},
+		{"disk", recorded, with(HashDisk, "x"), ActionReplace},
+		{"cloud-init", recorded, with(HashCloudInit, "x"), ActionReplace},
+		{"no recorded hashes", nil, recorded, ActionReplace},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			action, reasons := compareVMHashes(tt.recorded, tt.desired)
+			if action != tt.action {
+				t.Errorf("action = %q, want %q", action, tt.action)
+			}
+			if action != ActionNone && len(reasons) == 0 {
+				t.Error("changes must have a reason")
+			}
+		})
+	}
+}
+
+func TestPlanVMChanges(t *testing.T) {
+	executor := newTestExecutor(t)
+	vm := func(name string, memory int, runcmd string) v1.VMResource {
+		return v1.VMResource{
+			Name: name,
+			Spec: v1.VMSpec{
+				Memory: memory,
+				Vcpus:  1,
+				Disk:   v1.DiskSpec{Size: "10G"},
+				CloudInit: v1.CloudInitSpec{
+					Users:  []v1.UserSpec{{Name: "test", SshAuthorizedKeys: []string{"{{ .Keys.ssh.PublicKey }}"}}},
+					Runcmd: []string{runcmd},
+				},
+			},
+		}
+	}
+	keyState := &v1.ResourceState{Status: v1.StatusReady, State: map[string]any{"publicKey": "ssh-ed25519 AAAA"}}
+
+	// Record hashes as Create would for the original spec
+	original := &v1.Spec{Vms: []v1.VMResource{vm("web", 1024, "a"), vm("db", 1024, "a"), vm("old", 1024, "a")}}
+	envState := &v1.EnvironmentState{
+		ID: "env-1",
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"ssh": keyState},
+			VMs:  map[string]*v1.ResourceState{},
+		},
+	}
+	templateCtx := executor.templateContextFromState(original, envState, nil)
+	isoConfig := newIsolationConfig("", envState.ID, "", original.Networks)
+	for _, r := range original.Vms {
+		req, rendered, err := executor.buildVMRequest(v1.ResourceRef{Kind: "vm", Name: r.Name}, original, templateCtx, nil, nil, isoConfig)
+		if err != nil {
+			t.Fatalf("buildVMRequest(%s) error = %v", r.Name, err)
+		}
+		hashes, err := vmContentHashes(req, r.Name, original, rendered.Spec.CloudInit)
+		if err != nil {
+			t.Fatalf("vmContentHashes(%s) error = %v", r.Name, err)
+		}
+		envState.Resources.VMs[r.Name] = &v1.ResourceState{Status: v1.StatusReady, Hashes: hashes}
+	}
+
+	// web gets more memory, db a new command, old is removed and new is added
+	desired := &v1.Spec{Vms: []v1.VMResource{vm("web", 2048, "a"), vm("db", 1024, "b"), vm("new", 1024, "a")}}
+	changes := executor.planVMChanges(desired, envState, nil, nil)
+
+	got := make(map[string]string, len(changes))
+	for _, c := range changes {
+		got[c.Resource.Name] = c.Action
+	}
+	want := map[string]string{"web": ActionReboot, "db": ActionReplace, "new": ActionCreate, "old": ActionDelete}
+	for name, action := range want {
+		if got[name] != action {
+			t.Errorf("%s: action = %q, want %q", name, got[name], action)
+		}
+	}
+
+	unchanged := executor.planVMChanges(original, envState, nil, nil)
+	for _, c := range unchanged {
+		if c.Action != ActionNone {
+			t.Errorf("%s: unchanged spec planned %q (%v)", c.Resource.Name, c.Action, c.Reasons)
+		}
+	}
+}
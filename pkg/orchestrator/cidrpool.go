@@ -0,0 +1,223 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+// CIDRAuto is the cidr of networks whose subnet is allocated from the
+// CIDR pool of the host.
+const CIDRAuto = "auto"
+
+// DefaultCIDRPool is the CIDR pool used when Config.CIDRPool is empty. It
+// does not overlap the default transfer network of tunnels.
+const DefaultCIDRPool = "10.201.0.0/16"
+
+// ErrCIDRPoolExhausted is returned when every /24 of the CIDR pool is
+// allocated.
+var ErrCIDRPoolExhausted = errors.New("CIDR pool exhausted")
+
+// cidrAllocation records a subnet allocated to a network.
+type cidrAllocation struct {
+	CIDR          string `json:"cidr"`
+	EnvironmentID string `json:"environmentId"`
+	Network       string `json:"network"`
+	Tenant        string `json:"tenant,omitempty"`
+	AllocatedAt   string `json:"allocatedAt"`
+}
+
+// cidrPool allocates /24 subnets of a pool, one file per subnet in a
+// directory shared by every orchestrator of the host. Creating the file
+// exclusively is the allocation, so concurrent orchestrators never hand
+// out the same subnet. Tenants share the pool, and only see their own
+// allocations, since their environment IDs may collide.
+type cidrPool struct {
+	dir    string
+	pool   string
+	tenant string
+}
+
+// hostLayout returns the layout of the state directory shared by the tenants
+// of the host.
+func (o *Orchestrator) hostLayout() paths.Layout {
+	if o.config.HostStateDir != "" {
+		return paths.New(o.config.HostStateDir)
+	}
+	return paths.New(o.config.StateDir)
+}
+
+// cidrPool returns the CIDR pool of the orchestrator.
+func (o *Orchestrator) cidrPool() cidrPool {
+	pool := o.config.CIDRPool
+	if pool == "" {
+		pool = DefaultCIDRPool
+	}
+	return cidrPool{dir: o.hostLayout().IPAMDir(), pool: pool, tenant: o.config.Tenant}
+}
+
+// path returns the file of the allocation of a subnet.
+func (p cidrPool) path(subnet netip.Prefix) string {
+	return filepath.Join(p.dir, strings.ReplaceAll(subnet.String(), "/", "_")+".json")
+}
+
+// list returns the allocations of the pool.
+func (p cidrPool) list() ([]cidrAllocation, error) {
+	files, err := os.ReadDir(p.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CIDR pool: %w", err)
+	}
+	var allocations []cidrAllocation
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(p.dir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CIDR allocation: %w", err)
+		}
+		var a cidrAllocation
+		if err := json.Unmarshal(data, &a); err != nil {
+			return nil, fmt.Errorf("failed to parse CIDR allocation %s: %w", f.Name(), err)
+		}
+		allocations = append(allocations, a)
+	}
+	return allocations, nil
+}
+
+// allocate returns the subnet allocated to a network of an environment,
+// allocating the lowest free /24 of the pool if it has none.
+func (p cidrPool) allocate(envID, network string) (string, error) {
+	pool, err := netip.ParsePrefix(p.pool)
+	if err != nil || !pool.Addr().Is4() || pool.Bits() > 24 {
+		return "", fmt.Errorf("invalid CIDR pool %q: want an IPv4 prefix of /24 or larger", p.pool)
+	}
+	pool = pool.Masked()
+
+	allocations, err := p.list()
+	if err != nil {
+		return "", err
+	}
+	for _, a := range allocations {
+		if a.EnvironmentID == envID && a.Network == network && a.Tenant == p.tenant {
+			return a.CIDR, nil
+		}
+	}
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create CIDR pool directory: %w", err)
+	}
+	base := pool.Addr().As4()
+	for i := 0; i < 1<<(24-pool.Bits()); i++ {
+		addr := base
+		addr[1] += byte(i >> 8)
+		addr[2] += byte(i)
+		subnet := netip.PrefixFrom(netip.AddrFrom4(addr), 24)
+		f, err := os.OpenFile(p.path(subnet), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to allocate %s: %w", subnet, err)
+		}
+		data, err := json.Marshal(cidrAllocation{
+			CIDR:          subnet.String(),
+			EnvironmentID: envID,
+			Network:       network,
+			Tenant:        p.tenant,
+			AllocatedAt:   time.Now().UTC().Format(time.RFC3339),
+		})
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(p.path(subnet))
+			return "", fmt.Errorf("failed to allocate %s: %w", subnet, err)
+		}
+		return subnet.String(), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrCIDRPoolExhausted, p.pool)
+}
+
+// release frees the subnets of an environment, except those of the
+// networks in keep.
+func (p cidrPool) release(envID string, keep map[string]string) error {
+	allocations, err := p.list()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, a := range allocations {
+		if a.EnvironmentID != envID || a.Tenant != p.tenant {
+			continue
+		}
+		if _, ok := keep[a.Network]; ok {
+			continue
+		}
+		subnet, err := netip.ParsePrefix(a.CIDR)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid CIDR allocation %q: %w", a.CIDR, err))
+			continue
+		}
+		if err := os.Remove(p.path(subnet)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to release %s: %w", a.CIDR, err))
+			continue
+		}
+		log.Printf("Released %s of network %q of environment %s", a.CIDR, a.Network, envID)
+	}
+	return errors.Join(errs...)
+}
+
+// allocateCIDRs allocates a subnet to every network of an environment with
+// cidr auto and releases the subnets of its other networks. It returns the
+// subnets of the networks, to be recorded in state.
+func (o *Orchestrator) allocateCIDRs(envID string, networks []v1.NetworkResource) (map[string]string, error) {
+	pool := o.cidrPool()
+	var cidrs map[string]string
+	for _, n := range networks {
+		if n.Spec.Cidr != CIDRAuto {
+			continue
+		}
+		cidr, err := pool.allocate(envID, n.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate a subnet to network %q: %w", n.Name, err)
+		}
+		if cidrs == nil {
+			cidrs = make(map[string]string)
+		}
+		cidrs[n.Name] = cidr
+	}
+	if err := pool.release(envID, cidrs); err != nil {
+		return nil, err
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,125 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+	"reflect"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestCIDRPool_allocate(t *testing.T) {
+	p := cidrPool{dir: t.TempDir(), pool: "10.200.0.0/23"}
+
+	web, err := p.allocate("env1", "web")
+	if err != nil || web != "10.200.0.0/24" {
+		t.Fatalf("allocate() = %q, %v, want 10.200.0.0/24", web, err)
+	}
+	if again, err := p.allocate("env1", "web"); err != nil || again != web {
+		t.Errorf("allocate() again = %q, %v, want the same subnet %s", again, err, web)
+	}
+	db, err := p.allocate("env2", "web")
+	if err != nil || db != "10.200.1.0/24" {
+		t.Fatalf("allocate() for another environment = %q, %v, want 10.200.1.0/24", db, err)
+	}
+	if _, err := p.allocate("env3", "web"); !errors.Is(err, ErrCIDRPoolExhausted) {
+		t.Errorf("allocate() of a full pool error = %v, want ErrCIDRPoolExhausted", err)
+	}
+
+	if err := p.release("env1", nil); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if cidr, err := p.allocate("env3", "web"); err != nil || cidr != web {
+		t.Errorf("allocate() after release = %q, %v, want the released %s", cidr, err, web)
+	}
+}
+
+func TestCIDRPool_tenants(t *testing.T) {
+	dir := t.TempDir()
+	storage := cidrPool{dir: dir, pool: "10.200.0.0/23", tenant: "storage"}
+	network := cidrPool{dir: dir, pool: "10.200.0.0/23", tenant: "network"}
+
+	first, err := storage.allocate("env1", "web")
+	if err != nil {
+		t.Fatalf("allocate() error = %v", err)
+	}
+	second, err := network.allocate("env1", "web")
+	if err != nil || second == first {
+		t.Fatalf("allocate() for the same environment ID of another tenant = %q, %v, want a subnet other than %s", second, err, first)
+	}
+
+	if err := network.release("env1", nil); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if again, err := storage.allocate("env1", "web"); err != nil || again != first {
+		t.Errorf("allocate() after the other tenant released = %q, %v, want %s", again, err, first)
+	}
+}
+
+func TestCIDRPool_allocateInvalidPool(t *testing.T) {
+	for _, pool := range []string{"10.200.0.0/25", "fd00::/48", "not-a-cidr"} {
+		p := cidrPool{dir: t.TempDir(), pool: pool}
+		if _, err := p.allocate("env1", "web"); err == nil {
+			t.Errorf("allocate() from %q succeeded, want error", pool)
+		}
+	}
+}
+
+func TestDefaultCIDRPool_tunnelAddress(t *testing.T) {
+	pool := netip.MustParsePrefix(DefaultCIDRPool)
+	tunnel := netip.MustParsePrefix(defaultTunnelAddress)
+	if pool.Overlaps(tunnel) {
+		t.Errorf("DefaultCIDRPool %s overlaps the default tunnel address %s", pool, tunnel)
+	}
+}
+
+func TestOrchestrator_allocateCIDRs(t *testing.T) {
+	o := &Orchestrator{config: Config{StateDir: t.TempDir()}}
+	networks := []v1.NetworkResource{
+		{Name: "lan", Spec: v1.NetworkSpec{Cidr: CIDRAuto}},
+		{Name: "fixed", Spec: v1.NetworkSpec{Cidr: "192.168.100.0/24"}},
+		{Name: "wan", Spec: v1.NetworkSpec{Cidr: CIDRAuto}},
+	}
+	cidrs, err := o.allocateCIDRs("env1", networks)
+	if err != nil {
+		t.Fatalf("allocateCIDRs() error = %v", err)
+	}
+	want := map[string]string{"lan": "10.201.0.0/24", "wan": "10.201.1.0/24"}
+	if !reflect.DeepEqual(cidrs, want) {
+		t.Fatalf("allocateCIDRs() = %v, want %v", cidrs, want)
+	}
+
+	// Dropping a network releases its subnet
+	cidrs, err = o.allocateCIDRs("env1", networks[:2])
+	if err != nil {
+		t.Fatalf("allocateCIDRs() error = %v", err)
+	}
+	if want := map[string]string{"lan": "10.201.0.0/24"}; !reflect.DeepEqual(cidrs, want) {
+		t.Errorf("allocateCIDRs() without wan = %v, want %v", cidrs, want)
+	}
+	if _, err := os.Stat(o.cidrPool().path(netip.MustParsePrefix("10.201.1.0/24"))); !os.IsNotExist(err) {
+		t.Errorf("subnet of the dropped network is still allocated: %v", err)
+	}
+
+	if cidrs, err := o.allocateCIDRs("env2", networks[1:2]); err != nil || cidrs != nil {
+		t.Errorf("allocateCIDRs() without auto networks = %v, %v, want none", cidrs, err)
+	}
+}
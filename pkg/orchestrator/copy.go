@@ -0,0 +1,123 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+)
+
+// fileModePattern matches an octal file mode such as 644 or 0755.
+var fileModePattern = regexp.MustCompile(`^[0-7]{3,4}$`)
+
+// CopyOptions configure CopyToVM.
+type CopyOptions struct {
+	// Mode is the octal permission of the remote file, e.g. "0644". Empty
+	// keeps the default of the umask.
+	Mode string
+	// Owner is passed to chown with sudo, e.g. "root" or "app:app". Empty
+	// keeps the VM user as owner.
+	Owner string
+}
+
+// CopyToVM copies a local file to a VM of a stored environment, creating the
+// remote parent directory, through its guest agent when it has one and over
+// SSH otherwise. The file is written as the VM user; Owner then changes its
+// owner with sudo. It is rejected in read-only mode.
+func (o *Orchestrator) CopyToVM(ctx context.Context, environmentID, vmName, localPath, remotePath string, opts CopyOptions) error {
+	if o.config.ReadOnly {
+		return fmt.Errorf("copy rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("copy rejected: %w", err)
+	}
+	defer end()
+	if localPath == "" || remotePath == "" {
+		return errors.New("local and remote paths are required")
+	}
+	if opts.Mode != "" && !fileModePattern.MatchString(opts.Mode) {
+		return fmt.Errorf("invalid mode %q: expected an octal mode such as 0644", opts.Mode)
+	}
+	c, err := o.copyClient(environmentID, vmName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	log.Printf("Copying %s to %s on vm %q of environment %q", localPath, remotePath, vmName, environmentID)
+	if err := c.CopyTo(ctx, localPath, remotePath); err != nil {
+		return fmt.Errorf("failed to copy to vm %q: %w", vmName, err)
+	}
+	// The mode is set before the owner, while the VM user still owns the file.
+	if opts.Mode != "" {
+		if err := c.Chmod(ctx, remotePath, opts.Mode); err != nil {
+			return fmt.Errorf("failed to set mode of %s on vm %q: %w", remotePath, vmName, err)
+		}
+	}
+	if opts.Owner != "" {
+		execCtx := client.NewExecutionContext().WithPrivilegeEscalation(client.PrivilegeEscalationSudo())
+		if _, stderr, err := c.RunWithContext(ctx, execCtx, "chown", opts.Owner, remotePath); err != nil {
+			return fmt.Errorf("failed to set owner of %s on vm %q: %w (stderr: %s)", remotePath, vmName, err, stderr)
+		}
+	}
+	return nil
+}
+
+// CopyFromVM copies a file of a VM of a stored environment to a local path,
+// creating the local parent directory. It is rejected in read-only mode, as
+// it writes to the local filesystem.
+func (o *Orchestrator) CopyFromVM(ctx context.Context, environmentID, vmName, remotePath, localPath string) error {
+	if o.config.ReadOnly {
+		return fmt.Errorf("copy rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("copy rejected: %w", err)
+	}
+	defer end()
+	if localPath == "" || remotePath == "" {
+		return errors.New("local and remote paths are required")
+	}
+	c, err := o.copyClient(environmentID, vmName)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	log.Printf("Copying %s from vm %q of environment %q to %s", remotePath, vmName, environmentID, localPath)
+	if err := c.CopyFrom(ctx, remotePath, localPath); err != nil {
+		return fmt.Errorf("failed to copy from vm %q: %w", vmName, err)
+	}
+	return nil
+}
+
+// copyClient returns a client of a VM of a stored environment, preferring its
+// guest agent.
+func (o *Orchestrator) copyClient(environmentID, vmName string) (*client.Client, error) {
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.Resources.VMs[vmName] == nil {
+		return nil, fmt.Errorf("vm %q not found in environment %q", vmName, environmentID)
+	}
+	return o.vmClient(envState, vmName, true)
+}
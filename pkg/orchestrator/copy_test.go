@@ -0,0 +1,114 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+func TestOrchestrator_CopyToFromVM(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	// The guest agent runs the commands locally, so the "remote" paths are
+	// local too.
+	srv := httptest.NewServer(agent.NewHandler("token"))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyPath, []byte("unused by the agent"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID: "env-copy",
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"key": {State: map[string]any{"privateKeyPath": keyPath}}},
+			VMs: map[string]*v1.ResourceState{"vm": {State: map[string]any{
+				"ip":          "127.0.0.1",
+				agentPortKey:  port,
+				agentTokenKey: "token",
+			}}},
+		},
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	local := filepath.Join(dir, "local.txt")
+	if err := os.WriteFile(local, []byte("hello\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	remote := filepath.Join(dir, "remote", "dir", "file.txt")
+	if err := o.CopyToVM(context.Background(), "env-copy", "vm", local, remote, CopyOptions{Mode: "0640"}); err != nil {
+		t.Fatalf("CopyToVM() error = %v", err)
+	}
+	info, err := os.Stat(remote)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %o, want 640", info.Mode().Perm())
+	}
+
+	back := filepath.Join(dir, "back", "file.txt")
+	if err := o.CopyFromVM(context.Background(), "env-copy", "vm", remote, back); err != nil {
+		t.Fatalf("CopyFromVM() error = %v", err)
+	}
+	if data, err := os.ReadFile(back); err != nil || string(data) != "hello\n" {
+		t.Errorf("copied back %q, %v", data, err)
+	}
+
+	if err := o.CopyToVM(context.Background(), "env-copy", "vm", local, remote, CopyOptions{Mode: "u+x; reboot"}); err == nil {
+		t.Error("CopyToVM() with an invalid mode should fail")
+	}
+	if err := o.CopyToVM(context.Background(), "env-copy", "missing", local, remote, CopyOptions{}); err == nil {
+		t.Error("CopyToVM() on an unknown vm should fail")
+	}
+	if err := o.CopyFromVM(context.Background(), "env-copy", "vm", filepath.Join(dir, "absent"), back); err == nil {
+		t.Error("CopyFromVM() of a missing file should fail")
+	}
+}
+
+func TestOrchestrator_CopyReadOnly(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if err := o.CopyToVM(context.Background(), "env-copy", "vm", "a", "b", CopyOptions{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CopyToVM() error = %v, want %v", err, ErrReadOnly)
+	}
+	if err := o.CopyFromVM(context.Background(), "env-copy", "vm", "a", "b"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CopyFromVM() error = %v, want %v", err, ErrReadOnly)
+	}
+}
@@ -17,9 +17,11 @@ package orchestrator
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/service"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 )
 
@@ -60,6 +62,16 @@ func NewDAG() *DAG {
 func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 	dag := NewDAG()
 
+	// Keys and networks of the parent environment already exist, so edges
+	// to them are dropped.
+	inherited := spec.ParentResourceNames(testenvSpec)
+	addEdge := func(from, to v1.ResourceRef) error {
+		if inherited[to.Kind][to.Name] {
+			return nil
+		}
+		return dag.AddEdge(from, to)
+	}
+
 	// Add image nodes first (Phase 0 - no dependencies)
 	// Images are downloaded before any other resources.
 	for _, image := range testenvSpec.Images {
@@ -98,13 +110,33 @@ func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 		dag.AddNode(ref)
 	}
 
+	// Tunnels are handled by the orchestrator, not by providers
+	for _, tunnel := range testenvSpec.Tunnels {
+		dag.AddNode(v1.ResourceRef{Kind: "tunnel", Name: tunnel.Name})
+	}
+
+	// Access points are handled by the orchestrator, not by providers
+	for _, access := range testenvSpec.Access {
+		dag.AddNode(v1.ResourceRef{Kind: "access", Name: access.Name})
+	}
+
+	// Services are run on the host by the orchestrator, not by providers
+	for _, svc := range testenvSpec.Services {
+		dag.AddNode(v1.ResourceRef{Kind: "service", Name: svc.Name})
+	}
+
+	// Certificates are issued by the orchestrator, not by providers
+	for _, cert := range testenvSpec.Certificates {
+		dag.AddNode(v1.ResourceRef{Kind: "certificate", Name: cert.Name})
+	}
+
 	// Scan resources for template dependencies and build edges
 	// Keys typically have no dependencies
 	for _, key := range testenvSpec.Keys {
 		fromRef := v1.ResourceRef{Kind: "key", Name: key.Name, Provider: key.Provider}
 		deps := spec.ExtractTemplateRefs(key)
 		for _, dep := range deps {
-			if err := dag.AddEdge(fromRef, dep); err != nil {
+			if err := addEdge(fromRef, dep); err != nil {
 				return nil, fmt.Errorf("failed to add edge from key %q: %w", key.Name, err)
 			}
 		}
@@ -115,7 +147,7 @@ func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 		fromRef := v1.ResourceRef{Kind: "network", Name: network.Name, Provider: network.Provider}
 		deps := spec.ExtractTemplateRefs(network)
 		for _, dep := range deps {
-			if err := dag.AddEdge(fromRef, dep); err != nil {
+			if err := addEdge(fromRef, dep); err != nil {
 				return nil, fmt.Errorf("failed to add edge from network %q: %w", network.Name, err)
 			}
 		}
@@ -133,7 +165,7 @@ func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 			}
 			// attachTo is a literal network name
 			attachToRef := v1.ResourceRef{Kind: "network", Name: network.Spec.AttachTo}
-			if err := dag.AddEdge(fromRef, attachToRef); err != nil {
+			if err := addEdge(fromRef, attachToRef); err != nil {
 				return nil, fmt.Errorf("failed to add attachTo edge from network %q: %w", network.Name, err)
 			}
 		}
@@ -144,7 +176,7 @@ func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 		fromRef := v1.ResourceRef{Kind: "vm", Name: vm.Name, Provider: vm.Provider}
 		deps := spec.ExtractTemplateRefs(vm)
 		for _, dep := range deps {
-			if err := dag.AddEdge(fromRef, dep); err != nil {
+			if err := addEdge(fromRef, dep); err != nil {
 				return nil, fmt.Errorf("failed to add edge from vm %q: %w", vm.Name, err)
 			}
 		}
@@ -170,12 +202,96 @@ func BuildDAG(testenvSpec *v1.Spec) (*DAG, error) {
 			}
 			// network is a literal network name
 			networkRef := v1.ResourceRef{Kind: "network", Name: netName}
-			if err := dag.AddEdge(fromRef, networkRef); err != nil {
+			if err := addEdge(fromRef, networkRef); err != nil {
 				return nil, fmt.Errorf("failed to add network edge from vm %q: %w", vm.Name, err)
 			}
 		}
 	}
 
+	// Tunnels depend on both networks they bridge (their CIDRs become
+	// AllowedIPs) and on anything referenced by templates (e.g. the endpoint).
+	for _, tunnel := range testenvSpec.Tunnels {
+		fromRef := v1.ResourceRef{Kind: "tunnel", Name: tunnel.Name}
+		deps := spec.ExtractTemplateRefs(tunnel)
+		for _, netName := range []string{tunnel.Spec.LocalNetwork, tunnel.Spec.RemoteNetwork} {
+			if netName != "" && !spec.IsTemplated(netName) {
+				deps = append(deps, v1.ResourceRef{Kind: "network", Name: netName})
+			}
+		}
+		for _, dep := range deps {
+			if err := addEdge(fromRef, dep); err != nil {
+				return nil, fmt.Errorf("failed to add edge from tunnel %q: %w", tunnel.Name, err)
+			}
+		}
+	}
+
+	// Access points depend on the network they expose (its CIDR is routed to
+	// the client), and the server VM depends on its access point since its
+	// cloud-init carries the server config. The endpoint is rendered once all
+	// resources exist, so it adds no edges.
+	for _, access := range testenvSpec.Access {
+		fromRef := v1.ResourceRef{Kind: "access", Name: access.Name}
+		if access.Spec.Network != "" {
+			if err := addEdge(fromRef, v1.ResourceRef{Kind: "network", Name: access.Spec.Network}); err != nil {
+				return nil, fmt.Errorf("failed to add network edge from access %q: %w", access.Name, err)
+			}
+		}
+		if access.Spec.Vm != "" {
+			if err := addEdge(v1.ResourceRef{Kind: "vm", Name: access.Spec.Vm}, fromRef); err != nil {
+				return nil, fmt.Errorf("failed to add edge from vm %q to access %q: %w", access.Spec.Vm, access.Name, err)
+			}
+		}
+	}
+
+	// Services listen on the gateway of their network, and depend on
+	// anything referenced by templates (e.g. the root directory).
+	for _, svc := range testenvSpec.Services {
+		fromRef := v1.ResourceRef{Kind: "service", Name: svc.Name}
+		deps := spec.ExtractTemplateRefs(svc)
+		if svc.Spec.Network != "" {
+			deps = append(deps, v1.ResourceRef{Kind: "network", Name: svc.Spec.Network})
+		}
+		for _, dep := range deps {
+			if err := addEdge(fromRef, dep); err != nil {
+				return nil, fmt.Errorf("failed to add edge from service %q: %w", svc.Name, err)
+			}
+		}
+	}
+
+	// Certificates depend on anything referenced by templates (e.g. the IP
+	// of a VM in their SANs), and the VM they are installed in depends on
+	// them since its cloud-init carries them.
+	for _, cert := range testenvSpec.Certificates {
+		fromRef := v1.ResourceRef{Kind: "certificate", Name: cert.Name}
+		for _, dep := range spec.ExtractTemplateRefs(cert) {
+			if err := addEdge(fromRef, dep); err != nil {
+				return nil, fmt.Errorf("failed to add edge from certificate %q: %w", cert.Name, err)
+			}
+		}
+		if cert.Spec.Vm != "" {
+			if err := addEdge(v1.ResourceRef{Kind: "vm", Name: cert.Spec.Vm}, fromRef); err != nil {
+				return nil, fmt.Errorf("failed to add edge from vm %q to certificate %q: %w", cert.Spec.Vm, cert.Name, err)
+			}
+		}
+	}
+
+	// VMs forward their syslog to the log collectors of their networks, so
+	// they are created once the collectors listen
+	for _, svc := range testenvSpec.Services {
+		if svc.Spec.Type != service.TypeLogCollector || svc.Spec.Network == "" {
+			continue
+		}
+		for _, vm := range testenvSpec.Vms {
+			if !slices.Contains(vmNetworks(vm.Spec), svc.Spec.Network) {
+				continue
+			}
+			fromRef := v1.ResourceRef{Kind: "vm", Name: vm.Name, Provider: vm.Provider}
+			if err := addEdge(fromRef, v1.ResourceRef{Kind: "service", Name: svc.Name}); err != nil {
+				return nil, fmt.Errorf("failed to add edge from vm %q to service %q: %w", vm.Name, svc.Name, err)
+			}
+		}
+	}
+
 	// Check for cycles
 	if dag.HasCycle() {
 		return nil, fmt.Errorf("circular dependency detected in resource graph")
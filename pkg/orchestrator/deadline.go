@@ -0,0 +1,59 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrCreateDeadlineExceeded is the cause of the cancellation of a creation
+// that exceeded the createDeadline of its spec.
+var ErrCreateDeadlineExceeded = errors.New("create deadline exceeded")
+
+// maxBudgetEntries is the number of resources listed by deadlineError.
+const maxBudgetEntries = 5
+
+// deadlineError reports a creation that exceeded its deadline, with the
+// resources that consumed most of it, longest first.
+func deadlineError(deadline time.Duration, durations map[string]time.Duration) error {
+	resources := make([]string, 0, len(durations))
+	for resource := range durations {
+		resources = append(resources, resource)
+	}
+	slices.SortFunc(resources, func(a, b string) int {
+		if c := cmp.Compare(durations[b], durations[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	var spent []string
+	for i, resource := range resources {
+		if i == maxBudgetEntries {
+			spent = append(spent, fmt.Sprintf("%d more", len(resources)-i))
+			break
+		}
+		spent = append(spent, fmt.Sprintf("%s %s", resource, durations[resource].Round(time.Millisecond)))
+	}
+	if len(spent) == 0 {
+		return fmt.Errorf("%w (%s): no resource was created", ErrCreateDeadlineExceeded, deadline)
+	}
+	return fmt.Errorf("%w (%s): time spent by %s", ErrCreateDeadlineExceeded, deadline, strings.Join(spent, ", "))
+}
@@ -0,0 +1,86 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestDeadlineError(t *testing.T) {
+	err := deadlineError(15*time.Minute, map[string]time.Duration{
+		"key/k":     20 * time.Millisecond,
+		"image/img": 4 * time.Minute,
+		"vm/a":      9 * time.Minute,
+		"vm/b":      9 * time.Minute,
+		"network/n": time.Second,
+		"service/s": 2 * time.Second,
+	})
+	if !errors.Is(err, ErrCreateDeadlineExceeded) {
+		t.Errorf("deadlineError() = %v, want ErrCreateDeadlineExceeded", err)
+	}
+	want := "create deadline exceeded (15m0s): time spent by vm/a 9m0s, vm/b 9m0s, image/img 4m0s, service/s 2s, network/n 1s, 1 more"
+	if err.Error() != want {
+		t.Errorf("deadlineError() = %q, want %q", err, want)
+	}
+
+	if got := deadlineError(time.Minute, nil).Error(); got != "create deadline exceeded (1m0s): no resource was created" {
+		t.Errorf("deadlineError(nil) = %q", got)
+	}
+}
+
+func TestExecuteCreate_RecordsDurations(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	envState := &v1.EnvironmentState{ID: "test-durations", Status: v1.StatusCreating}
+	plan := [][]v1.ResourceRef{{{Kind: "widget", Name: "w1"}}, {{Kind: "widget", Name: "w2"}}}
+	result, err := executor.ExecuteCreate(context.Background(), &v1.Spec{}, plan, nil, envState, nil, nil)
+	if err != nil {
+		t.Fatalf("ExecuteCreate() error = %v", err)
+	}
+	if result.Success {
+		t.Fatal("ExecuteCreate() succeeded with an unknown resource kind")
+	}
+	if _, ok := result.Durations["widget/w1"]; !ok {
+		t.Errorf("Durations = %v, want an entry for the failed resource", result.Durations)
+	}
+	if _, ok := result.Durations["widget/w2"]; ok {
+		t.Errorf("Durations = %v, should not contain resources of phases never started", result.Durations)
+	}
+}
+
+func TestExecuteCreate_StopsAtDeadline(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	envState := &v1.EnvironmentState{ID: "test-deadline", Status: v1.StatusCreating}
+	plan := [][]v1.ResourceRef{{{Kind: "key", Name: "k1"}}}
+	ctx, cancel := context.WithTimeoutCause(context.Background(), time.Nanosecond, ErrCreateDeadlineExceeded)
+	defer cancel()
+	<-ctx.Done()
+
+	result, err := executor.ExecuteCreate(ctx, &v1.Spec{}, plan, nil, envState, nil, nil)
+	if err != nil {
+		t.Fatalf("ExecuteCreate() error = %v", err)
+	}
+	if result.Success || len(result.Durations) != 0 {
+		t.Errorf("ExecuteCreate() = %+v, want a failure before creating anything", result)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// Changes of a resource between two revisions of an environment.
+const (
+	// ChangeAdded means the resource only exists in the later revision.
+	ChangeAdded = "added"
+	// ChangeRemoved means the resource only exists in the earlier revision.
+	ChangeRemoved = "removed"
+	// ChangeChanged means some fields of the resource differ.
+	ChangeChanged = "changed"
+)
+
+// FieldChange is a field whose value differs between two revisions. Values
+// are strings as-is and compact JSON otherwise; an empty value means unset.
+type FieldChange struct {
+	// Field is the path of the field, e.g. "status" or "state.ip".
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// ResourceDiff is a resource added, removed or changed between two
+// revisions of an environment.
+type ResourceDiff struct {
+	// Kind is key, network, vm or service.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Change is ChangeAdded, ChangeRemoved or ChangeChanged.
+	Change string `json:"change"`
+	// Fields are the changed fields of a changed resource.
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// StateDiff is what changed in the state of an environment since an
+// earlier revision.
+type StateDiff struct {
+	EnvironmentID string `json:"environmentID"`
+	// From is the earlier revision; revision 0 means before the
+	// environment was created.
+	From state.Revision `json:"from"`
+	// To is the current revision.
+	To state.Revision `json:"to"`
+	// Fields are the changed fields of the environment itself.
+	Fields []FieldChange `json:"fields,omitempty"`
+	// Resources are the changed resources, by kind and name.
+	Resources []ResourceDiff `json:"resources,omitempty"`
+}
+
+// Diff compares the current state of a stored environment with an earlier
+// revision of its history. since is a revision number, an RFC3339
+// timestamp, or a Go duration before now such as 24h; a time selects the
+// last revision saved at or before it.
+func (o *Orchestrator) Diff(environmentID, since string) (*StateDiff, error) {
+	current, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	history, err := o.store.History(environmentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("environment %q has no state history", environmentID)
+	}
+
+	from, err := diffBaseline(history, since, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("environment %q: %w", environmentID, err)
+	}
+	earlier := &v1.EnvironmentState{}
+	if from.Number > 0 {
+		if earlier, err = o.store.LoadRevision(environmentID, from.Number); err != nil {
+			return nil, err
+		}
+	}
+	return diffStates(environmentID, from, history[len(history)-1], earlier, current), nil
+}
+
+// diffBaseline returns the revision of history, oldest first, selected by
+// since (see Diff). It returns revision 0 for a time before the first save
+// of the environment.
+func diffBaseline(history []state.Revision, since string, now time.Time) (state.Revision, error) {
+	oldest, latest := history[0], history[len(history)-1]
+	if n, err := strconv.Atoi(since); err == nil {
+		if n < 0 || n > latest.Number {
+			return state.Revision{}, fmt.Errorf("revision %d does not exist, the latest is %d", n, latest.Number)
+		}
+		if n == 0 {
+			return state.Revision{}, nil
+		}
+		for _, r := range history {
+			if r.Number == n {
+				return r, nil
+			}
+		}
+		return state.Revision{}, fmt.Errorf("revision %d is no longer kept, the oldest is %d", n, oldest.Number)
+	}
+
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		d, derr := time.ParseDuration(since)
+		if derr != nil || d < 0 {
+			return state.Revision{}, fmt.Errorf("invalid since %q: expected a revision number, an RFC3339 timestamp or a duration", since)
+		}
+		t = now.Add(-d)
+	}
+	var baseline *state.Revision
+	for i, r := range history {
+		savedAt, err := time.Parse(time.RFC3339, r.SavedAt)
+		if err != nil || savedAt.After(t) {
+			break
+		}
+		baseline = &history[i]
+	}
+	switch {
+	case baseline != nil:
+		return *baseline, nil
+	case oldest.Number == 1:
+		return state.Revision{}, nil
+	default:
+		return state.Revision{}, fmt.Errorf("the oldest revision kept, %d, was saved at %s, after %s",
+			oldest.Number, oldest.SavedAt, t.UTC().Format(time.RFC3339))
+	}
+}
+
+// diffStates compares two states of an environment saved as revisions
+// from and to.
+func diffStates(environmentID string, from, to state.Revision, earlier, later *v1.EnvironmentState) *StateDiff {
+	diff := &StateDiff{EnvironmentID: environmentID, From: from, To: to}
+	diff.Fields = diffFields(environmentFields(earlier), environmentFields(later))
+	for _, kind := range []struct {
+		name             string
+		earlier, current map[string]*v1.ResourceState
+	}{
+		{"key", earlier.Resources.Keys, later.Resources.Keys},
+		{"network", earlier.Resources.Networks, later.Resources.Networks},
+		{"vm", earlier.Resources.VMs, later.Resources.VMs},
+		{"service", earlier.Resources.Services, later.Resources.Services},
+	} {
+		names := slices.Sorted(maps.Keys(kind.earlier))
+		for name := range kind.current {
+			if _, ok := kind.earlier[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			before, after := kind.earlier[name], kind.current[name]
+			change := ResourceDiff{Kind: kind.name, Name: name}
+			switch {
+			case before == nil && after == nil:
+				continue
+			case before == nil:
+				change.Change = ChangeAdded
+			case after == nil:
+				change.Change = ChangeRemoved
+			default:
+				change.Fields = diffFields(resourceFields(before), resourceFields(after))
+				if len(change.Fields) == 0 {
+					continue
+				}
+				change.Change = ChangeChanged
+			}
+			diff.Resources = append(diff.Resources, change)
+		}
+	}
+	return diff
+}
+
+// environmentFields returns the compared fields of an environment by path.
+func environmentFields(envState *v1.EnvironmentState) map[string]string {
+	fields := map[string]string{
+		"status": envState.Status,
+		"stage":  envState.Stage,
+	}
+	for network, cidr := range envState.CIDRs {
+		fields["cidrs."+network] = cidr
+	}
+	if envState.SpecSource != nil {
+		fields["specSource.commit"] = envState.SpecSource.Commit
+		fields["specSource.sha256"] = envState.SpecSource.SHA256
+	}
+	return fields
+}
+
+// resourceFields returns the compared fields of a resource by path:
+// everything but its timestamps.
+func resourceFields(rs *v1.ResourceState) map[string]string {
+	fields := map[string]string{
+		"provider": rs.Provider,
+		"status":   rs.Status,
+		"error":    rs.Error,
+		"owner":    rs.Owner,
+	}
+	for section, hash := range rs.Hashes {
+		fields["hashes."+section] = hash
+	}
+	for key, value := range rs.State {
+		fields["state."+key] = diffValue(value)
+	}
+	return fields
+}
+
+// diffFields returns the fields whose value differs, sorted by path.
+func diffFields(before, after map[string]string) []FieldChange {
+	var changes []FieldChange
+	for field, value := range before {
+		if after[field] != value {
+			changes = append(changes, FieldChange{Field: field, From: value, To: after[field]})
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok && value != "" {
+			changes = append(changes, FieldChange{Field: field, To: value})
+		}
+	}
+	slices.SortFunc(changes, func(a, b FieldChange) int {
+		return cmp.Compare(a.Field, b.Field)
+	})
+	return changes
+}
+
+// diffValue renders a state value for a FieldChange.
+func diffValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
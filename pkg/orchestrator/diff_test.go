@@ -0,0 +1,145 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+func TestDiffBaseline(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	history := []state.Revision{
+		{Number: 1, SavedAt: "2025-03-01T09:00:00Z"},
+		{Number: 2, SavedAt: "2025-03-01T10:00:00Z"},
+		{Number: 3, SavedAt: "2025-03-01T11:00:00Z"},
+	}
+	tests := []struct {
+		name    string
+		history []state.Revision
+		since   string
+		want    int
+		wantErr string
+	}{
+		{name: "revision", history: history, since: "2", want: 2},
+		{name: "revision zero", history: history, since: "0", want: 0},
+		{name: "future revision", history: history, since: "4", wantErr: "latest is 3"},
+		{name: "pruned revision", history: history[1:], since: "1", wantErr: "oldest is 2"},
+		{name: "timestamp", history: history, since: "2025-03-01T10:30:00Z", want: 2},
+		{name: "timestamp of a save", history: history, since: "2025-03-01T11:00:00Z", want: 3},
+		{name: "duration", history: history, since: "90m", want: 2},
+		{name: "before creation", history: history, since: "2025-03-01T08:00:00Z", want: 0},
+		{name: "before pruned revisions", history: history[1:], since: "2025-03-01T09:30:00Z", wantErr: "oldest revision kept, 2"},
+		{name: "invalid", history: history, since: "yesterday", wantErr: "invalid since"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffBaseline(tt.history, tt.since, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("diffBaseline() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Number != tt.want {
+				t.Errorf("diffBaseline() = %+v, %v, want revision %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffStates(t *testing.T) {
+	earlier := &v1.EnvironmentState{
+		Status: v1.StatusReady,
+		Resources: v1.ResourceMap{
+			Networks: map[string]*v1.ResourceState{
+				"net": {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"ip": "10.0.0.1"}},
+			},
+			VMs: map[string]*v1.ResourceState{
+				"web": {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"ip": "10.0.0.10", "macs": []any{"52:54:00:00:00:01"}}},
+				"db":  {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"ip": "10.0.0.11"}},
+			},
+		},
+	}
+	later := &v1.EnvironmentState{
+		Status: v1.StatusFailed,
+		Resources: v1.ResourceMap{
+			Networks: map[string]*v1.ResourceState{
+				"net": {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"ip": "10.0.0.1"}, UpdatedAt: "later"},
+			},
+			VMs: map[string]*v1.ResourceState{
+				"web":   {Provider: "stub", Status: v1.StatusFailed, Error: "boot timeout", State: map[string]any{"ip": "10.0.0.20", "macs": []any{"52:54:00:00:00:01"}}},
+				"cache": {Provider: "stub", Status: v1.StatusReady},
+			},
+		},
+	}
+
+	diff := diffStates("env", state.Revision{Number: 1}, state.Revision{Number: 4}, earlier, later)
+	if want := []FieldChange{{Field: "status", From: v1.StatusReady, To: v1.StatusFailed}}; !reflect.DeepEqual(diff.Fields, want) {
+		t.Errorf("Fields = %+v, want %+v", diff.Fields, want)
+	}
+	want := []ResourceDiff{
+		{Kind: "vm", Name: "cache", Change: ChangeAdded},
+		{Kind: "vm", Name: "db", Change: ChangeRemoved},
+		{Kind: "vm", Name: "web", Change: ChangeChanged, Fields: []FieldChange{
+			{Field: "error", To: "boot timeout"},
+			{Field: "state.ip", From: "10.0.0.10", To: "10.0.0.20"},
+			{Field: "status", From: v1.StatusReady, To: v1.StatusFailed},
+		}},
+	}
+	if !reflect.DeepEqual(diff.Resources, want) {
+		t.Errorf("Resources = %+v, want %+v", diff.Resources, want)
+	}
+}
+
+func TestOrchestrator_Diff(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, err := o.Diff("missing", "1"); err == nil {
+		t.Error("Diff() of a missing environment succeeded")
+	}
+	envState := &v1.EnvironmentState{ID: "env", Status: v1.StatusCreating}
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+	envState.Status = v1.StatusReady
+	envState.Resources.VMs = map[string]*v1.ResourceState{"web": {Provider: "stub", Status: v1.StatusReady}}
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := o.Diff("env", "1")
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if diff.From.Number != 1 || diff.To.Number != 2 || len(diff.Fields) != 1 || len(diff.Resources) != 1 ||
+		diff.Resources[0].Change != ChangeAdded {
+		t.Errorf("Diff() = %+v, want the status change and the added VM", diff)
+	}
+	if diff, err := o.Diff("env", "0"); err != nil || len(diff.Fields) != 1 || diff.Fields[0].From != "" {
+		t.Errorf("Diff() since creation = %+v, %v", diff, err)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"log"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// injectGuestEnvironment renders cloudInit.environment and
+// cloudInit.secretEnvironment of a rendered VM spec into cloud-init before any
+// user command runs: plain variables are appended to /etc/environment, secret
+// variables are only copied to the 0600 ~/.ssh/environment of root and of the
+// declared users. Secrets are resolved here, so only the provider request
+// carries their values.
+func injectGuestEnvironment(vmSpec *providerv1.VMSpec, vmName string, ci v1.CloudInitSpec) error {
+	env, err := specpkg.RenderGuestEnvironment(ci)
+	if err != nil {
+		return err
+	}
+	if env.Content == "" && env.Secret == "" {
+		return nil
+	}
+	log.Printf("Injecting environment into vm %q: %s", vmName, strings.ReplaceAll(strings.TrimSpace(env.Redacted), "\n", " "))
+
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	cloudInit := vmSpec.CloudInit
+	var runcmd []string
+	if env.Content != "" {
+		cloudInit.WriteFiles = append(cloudInit.WriteFiles, providerv1.WriteFileSpec{
+			Path:        specpkg.GuestEnvironmentFile,
+			Content:     env.Content,
+			Permissions: "0600",
+		})
+		runcmd = append(runcmd, specpkg.GuestEnvironmentCommand)
+	}
+	if env.Secret != "" {
+		cloudInit.WriteFiles = append(cloudInit.WriteFiles, providerv1.WriteFileSpec{
+			Path:        specpkg.GuestSecretEnvironmentFile,
+			Content:     env.Secret,
+			Permissions: "0600",
+		})
+		users := make([]string, 0, len(cloudInit.Users))
+		for _, user := range cloudInit.Users {
+			users = append(users, user.Name)
+		}
+		runcmd = append(runcmd, specpkg.GuestSecretEnvironmentCommands(env, users)...)
+	}
+	cloudInit.Runcmd = append(runcmd, cloudInit.Runcmd...)
+	return nil
+}
@@ -0,0 +1,101 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestInjectGuestEnvironment(t *testing.T) {
+	vmSpec := providerv1.VMSpec{
+		CloudInit: &providerv1.CloudInitSpec{Runcmd: []string{"run-tests"}},
+	}
+
+	err := injectGuestEnvironment(&vmSpec, "vm1", v1.CloudInitSpec{
+		Environment: map[string]string{"APP_ENV": "test"},
+	})
+	if err != nil {
+		t.Fatalf("injectGuestEnvironment() error = %v", err)
+	}
+
+	ci := vmSpec.CloudInit
+	if len(ci.WriteFiles) != 1 {
+		t.Fatalf("expected 1 write_files entry, got %d", len(ci.WriteFiles))
+	}
+	wf := ci.WriteFiles[0]
+	if wf.Path != specpkg.GuestEnvironmentFile || wf.Permissions != "0600" || wf.Content != "APP_ENV=\"test\"\n" {
+		t.Errorf("unexpected write_files entry: %+v", wf)
+	}
+	if len(ci.Runcmd) != 2 || ci.Runcmd[0] != specpkg.GuestEnvironmentCommand || ci.Runcmd[1] != "run-tests" {
+		t.Errorf("environment must be exported before user commands, got %v", ci.Runcmd)
+	}
+}
+
+func TestInjectGuestEnvironment_Secrets(t *testing.T) {
+	t.Setenv("TESTENV_VM_TEST_API_TOKEN", "s3cr3t")
+	vmSpec := providerv1.VMSpec{
+		CloudInit: &providerv1.CloudInitSpec{
+			Users:  []providerv1.UserSpec{{Name: "tester"}},
+			Runcmd: []string{"run-tests"},
+		},
+	}
+
+	err := injectGuestEnvironment(&vmSpec, "vm1", v1.CloudInitSpec{
+		SecretEnvironment: map[string]string{"API_TOKEN": "env:TESTENV_VM_TEST_API_TOKEN"},
+	})
+	if err != nil {
+		t.Fatalf("injectGuestEnvironment() error = %v", err)
+	}
+
+	ci := vmSpec.CloudInit
+	if len(ci.WriteFiles) != 1 {
+		t.Fatalf("expected 1 write_files entry, got %d", len(ci.WriteFiles))
+	}
+	wf := ci.WriteFiles[0]
+	if wf.Path != specpkg.GuestSecretEnvironmentFile || wf.Permissions != "0600" || wf.Content != "API_TOKEN=s3cr3t\n" {
+		t.Errorf("unexpected write_files entry: %+v", wf)
+	}
+	for _, cmd := range ci.Runcmd {
+		if strings.Contains(cmd, "/etc/environment") || strings.Contains(cmd, "s3cr3t") {
+			t.Errorf("secrets must not reach /etc/environment or runcmd, got %q", cmd)
+		}
+	}
+	if !strings.Contains(ci.Runcmd[0], "for u in root 'tester';") || !strings.Contains(ci.Runcmd[0], ".ssh/environment") {
+		t.Errorf("secrets must be copied to the declared users, got %q", ci.Runcmd[0])
+	}
+	if !strings.Contains(ci.Runcmd[1], "PermitUserEnvironment API_TOKEN") {
+		t.Errorf("sshd must only accept the secret variables, got %q", ci.Runcmd[1])
+	}
+	if ci.Runcmd[len(ci.Runcmd)-1] != "run-tests" {
+		t.Errorf("secrets must be installed before user commands, got %v", ci.Runcmd)
+	}
+}
+
+func TestInjectGuestEnvironment_NoVariables(t *testing.T) {
+	vmSpec := providerv1.VMSpec{}
+	if err := injectGuestEnvironment(&vmSpec, "vm1", v1.CloudInitSpec{}); err != nil {
+		t.Fatalf("injectGuestEnvironment() error = %v", err)
+	}
+	if vmSpec.CloudInit != nil {
+		t.Error("cloud-init must not be created when no variables are declared")
+	}
+}
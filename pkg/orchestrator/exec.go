@@ -0,0 +1,121 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+)
+
+// DefaultExecTimeout bounds a command run by ExecVM when no timeout is given.
+const DefaultExecTimeout = 5 * time.Minute
+
+// ExecOptions configure ExecVM.
+type ExecOptions struct {
+	// Sudo runs the command with sudo.
+	Sudo bool
+	// Env sets environment variables of the command.
+	Env map[string]string
+	// Dir is the working directory of the command.
+	Dir string
+	// Timeout bounds the command. Zero means DefaultExecTimeout.
+	Timeout time.Duration
+}
+
+// ExecResult is the outcome of a command run by ExecVM.
+type ExecResult struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	// ExitCode is the exit status of the command.
+	ExitCode int `json:"exitCode"`
+}
+
+// ExecVM runs a shell command on a VM of a stored environment, through its
+// guest agent when it has one and over SSH otherwise, with the same user, key
+// and host keys as vm_wait. A command exiting with a non-zero status is not
+// an error: its status is returned in the result. It is rejected in read-only
+// mode.
+func (o *Orchestrator) ExecVM(ctx context.Context, environmentID, vmName, command string, opts ExecOptions) (*ExecResult, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("exec rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("exec rejected: %w", err)
+	}
+	defer end()
+	if command == "" {
+		return nil, errors.New("command is required")
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.Resources.VMs[vmName] == nil {
+		return nil, fmt.Errorf("vm %q not found in environment %q", vmName, environmentID)
+	}
+
+	c, err := o.vmClient(envState, vmName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = c.Close() }()
+
+	execCtx := client.NewExecutionContext().WithEnvs(opts.Env)
+	if opts.Dir != "" {
+		execCtx = execCtx.WithWorkingDir(opts.Dir)
+	}
+	switch {
+	case opts.Sudo && len(opts.Env) > 0:
+		execCtx = execCtx.WithPrivilegeEscalation(client.PrivilegeEscalationSudoPreserveEnv())
+	case opts.Sudo:
+		execCtx = execCtx.WithPrivilegeEscalation(client.PrivilegeEscalationSudo())
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	log.Printf("Running a command on vm %q of environment %q (sudo: %t)", vmName, environmentID, opts.Sudo)
+	stdout, stderr, err := c.RunWithContext(ctx, execCtx, shellScript(command)...)
+	result := &ExecResult{Stdout: stdout, Stderr: stderr}
+	if err != nil {
+		code, ok := client.ExitCode(err)
+		if !ok {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("command on vm %q did not finish within %s: %w", vmName, timeout, ErrWaitTimeout)
+			}
+			return nil, fmt.Errorf("failed to run command on vm %q: %w", vmName, err)
+		}
+		result.ExitCode = code
+	}
+	return result, nil
+}
+
+// shellScript returns the arguments of a command running script with sh. The
+// script travels base64-encoded, so that the shell running the formatted
+// command neither expands nor unquotes it.
+func shellScript(script string) []string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+	return []string{"sh", "-c", fmt.Sprintf("$(echo %s | base64 -d)", encoded)}
+}
@@ -0,0 +1,91 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+func TestOrchestrator_ExecVM(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	// The guest agent runs the commands locally
+	srv := httptest.NewServer(agent.NewHandler("token"))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	keyPath := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyPath, []byte("unused by the agent"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID: "env-exec",
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"key": {State: map[string]any{"privateKeyPath": keyPath}}},
+			VMs: map[string]*v1.ResourceState{"vm": {State: map[string]any{
+				"ip":          "127.0.0.1",
+				agentPortKey:  port,
+				agentTokenKey: "token",
+			}}},
+		},
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	result, err := o.ExecVM(context.Background(), "env-exec", "vm", "echo \"$GREETING\"\necho oops >&2; exit 3",
+		ExecOptions{Env: map[string]string{"GREETING": "hello"}})
+	if err != nil {
+		t.Fatalf("ExecVM() error = %v", err)
+	}
+	if result.Stdout != "hello\n" || result.Stderr != "oops\n" || result.ExitCode != 3 {
+		t.Errorf("ExecVM() = %+v", result)
+	}
+
+	if _, err := o.ExecVM(context.Background(), "env-exec", "missing", "true", ExecOptions{}); err == nil {
+		t.Error("ExecVM() on an unknown vm should fail")
+	}
+	if _, err := o.ExecVM(context.Background(), "env-exec", "vm", "", ExecOptions{}); err == nil {
+		t.Error("ExecVM() without a command should fail")
+	}
+}
+
+func TestOrchestrator_ExecVMReadOnly(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, err := o.ExecVM(context.Background(), "env-exec", "vm", "true", ExecOptions{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("ExecVM() error = %v, want %v", err, ErrReadOnly)
+	}
+}
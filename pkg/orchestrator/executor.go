@@ -19,15 +19,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"maps"
 	"strings"
 	"sync"
 	"time"
 
-	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
 	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
 )
@@ -38,6 +40,17 @@ type Executor struct {
 	store    *state.Store
 	imageMgr *image.CacheManager
 	mu       sync.Mutex // Protects state modifications during parallel execution
+
+	// downloader fetches the boot artifacts of networks.
+	downloader *image.Downloader
+
+	// agentBinary is the guest agent injected into VMs enabling it.
+	agentBinary string
+	// tenant namespaces the names and labels of the resources created.
+	tenant string
+	// stateDir is the providerv1.LabelStateDir label of the resources
+	// created.
+	stateDir string
 }
 
 // ExecutionResult contains the result of an execution operation.
@@ -48,14 +61,18 @@ type ExecutionResult struct {
 	Errors []error
 	// State is the updated environment state.
 	State *v1.EnvironmentState
+	// Durations is the time spent creating each resource, keyed by
+	// "kind/name", whether it succeeded or not.
+	Durations map[string]time.Duration
 }
 
 // NewExecutor creates a new Executor with the given provider manager, state store, and image cache manager.
 func NewExecutor(manager *provider.Manager, store *state.Store, imageMgr *image.CacheManager) *Executor {
 	return &Executor{
-		manager:  manager,
-		store:    store,
-		imageMgr: imageMgr,
+		manager:    manager,
+		store:      store,
+		imageMgr:   imageMgr,
+		downloader: image.NewDownloader(),
 	}
 }
 
@@ -80,9 +97,10 @@ func (e *Executor) ExecuteCreate(
 	}
 
 	result := &ExecutionResult{
-		Success: true,
-		Errors:  []error{},
-		State:   envState,
+		Success:   true,
+		Errors:    []error{},
+		State:     envState,
+		Durations: make(map[string]time.Duration),
 	}
 
 	// Execute phases sequentially
@@ -91,7 +109,14 @@ func (e *Executor) ExecuteCreate(
 			continue
 		}
 
-		phaseErrors := e.executePhase(ctx, phase, spec, templateCtx, envState, templatedFields, isoConfig)
+		// Stop at phase boundaries once cancelled; resources already
+		// being created are left to finish.
+		var phaseErrors []error
+		if err := ctx.Err(); err != nil {
+			phaseErrors = []error{fmt.Errorf("creation interrupted before phase %d: %w", phaseIdx, err)}
+		} else {
+			phaseErrors = e.executePhase(ctx, phase, spec, templateCtx, envState, templatedFields, isoConfig, result.Durations)
+		}
 		if len(phaseErrors) > 0 {
 			result.Errors = append(result.Errors, phaseErrors...)
 			result.Success = false
@@ -145,6 +170,11 @@ func (e *Executor) ExecuteDelete(ctx context.Context, envState *v1.EnvironmentSt
 		phases[i], phases[j] = phases[j], phases[i]
 	}
 
+	// Providers serving the teardown tool delete everything in one call
+	if done, err := e.executeTeardown(phases, envState, isoConfig); done {
+		return err
+	}
+
 	var allErrors []error
 
 	// Execute deletion phases sequentially
@@ -183,7 +213,86 @@ func (e *Executor) ExecuteDelete(ctx context.Context, envState *v1.EnvironmentSt
 	return nil
 }
 
-// executePhase executes all resources in a phase in parallel.
+// executeTeardown deletes the resources of phases, in deletion order, with
+// one environment teardown call when they all belong to a single provider
+// serving the teardown tool. Services run on the host, so they are stopped
+// before the call. It reports whether it handled the deletion; otherwise,
+// the caller deletes resources one by one.
+func (e *Executor) executeTeardown(
+	phases [][]v1.ResourceRef,
+	envState *v1.EnvironmentState,
+	isoConfig *IsolationConfig,
+) (bool, error) {
+	providerName := ""
+	req := &providerv1.TeardownRequest{}
+	refs := make(map[string]v1.ResourceRef)
+	var services []v1.ResourceRef
+	for _, phase := range phases {
+		for _, ref := range phase {
+			resourceState := e.getResourceState(envState, ref)
+			if resourceState == nil {
+				continue
+			}
+			if ref.Kind == "service" {
+				services = append(services, ref)
+				continue
+			}
+			if providerName == "" {
+				providerName = resourceState.Provider
+			}
+			if resourceState.Provider != providerName {
+				return false, nil
+			}
+			name := prefixedName(isoConfig, ref.Name)
+			switch ref.Kind {
+			case "vm":
+				req.VMs = append(req.VMs, name)
+			case "network":
+				req.Networks = append(req.Networks, name)
+			case "key":
+				req.Keys = append(req.Keys, name)
+			}
+			refs[ref.Kind+"/"+name] = ref
+		}
+	}
+	if req.Len() == 0 || req.Len() > providerv1.MaxBatchCalls || !e.manager.SupportsTeardown(providerName) {
+		return false, nil
+	}
+
+	var allErrors []error
+	for _, ref := range services {
+		if err := e.deleteService(ref, e.getResourceState(envState, ref), envState); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("failed to delete %s/%s: %w", ref.Kind, ref.Name, err))
+		}
+	}
+
+	results, err := e.manager.Teardown(providerName, req)
+	if err != nil {
+		// Resources are deleted one by one, reporting their own errors
+		return false, nil
+	}
+	for _, result := range results {
+		ref, ok := refs[result.Kind+"/"+result.Name]
+		if !ok {
+			continue
+		}
+		if result.Error != nil {
+			allErrors = append(allErrors, fmt.Errorf("failed to delete %s/%s: provider returned error: %s",
+				ref.Kind, ref.Name, result.Error.Message))
+			continue
+		}
+		e.mu.Lock()
+		e.updateResourceState(envState, ref, providerName, v1.StatusDestroyed, nil, "")
+		e.mu.Unlock()
+	}
+	if len(allErrors) > 0 {
+		return true, fmt.Errorf("delete completed with %d errors: %v", len(allErrors), allErrors)
+	}
+	return true, nil
+}
+
+// executePhase executes all resources in a phase in parallel, recording the
+// time spent on each in durations.
 // Returns errors for any failed resources.
 func (e *Executor) executePhase(
 	ctx context.Context,
@@ -193,6 +302,7 @@ func (e *Executor) executePhase(
 	envState *v1.EnvironmentState,
 	templatedFields *specpkg.TemplatedFields,
 	isoConfig *IsolationConfig,
+	durations map[string]time.Duration,
 ) []error {
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -203,11 +313,14 @@ func (e *Executor) executePhase(
 		go func(r v1.ResourceRef) {
 			defer wg.Done()
 
-			if err := e.createResource(ctx, r, spec, templateCtx, envState, templatedFields, isoConfig); err != nil {
-				mu.Lock()
+			start := time.Now()
+			err := e.createResource(ctx, r, spec, templateCtx, envState, templatedFields, isoConfig)
+			mu.Lock()
+			durations[r.Kind+"/"+r.Name] = time.Since(start)
+			if err != nil {
 				errors = append(errors, fmt.Errorf("failed to create %s/%s: %w", r.Kind, r.Name, err))
-				mu.Unlock()
 			}
+			mu.Unlock()
 		}(ref)
 	}
 
@@ -226,6 +339,18 @@ func prefixedName(isoConfig *IsolationConfig, name string) string {
 	return isoConfig.NamePrefix + "-" + name
 }
 
+// networkName returns the provider-level name of a network a VM attaches
+// to: the recorded name of a network of the parent environment, the
+// prefixed name otherwise.
+func networkName(isoConfig *IsolationConfig, name string) string {
+	if isoConfig != nil {
+		if inherited, ok := isoConfig.InheritedNetworks[name]; ok {
+			return inherited
+		}
+	}
+	return prefixedName(isoConfig, name)
+}
+
 func (e *Executor) createResource(
 	ctx context.Context,
 	ref v1.ResourceRef,
@@ -244,6 +369,10 @@ func (e *Executor) createResource(
 	// Get the appropriate tool name and request based on resource kind
 	var tool string
 	var request interface{}
+	var proxyJump string
+	var agentState map[string]any
+	var hashes map[string]string
+	var boot *bootFiles
 
 	switch ref.Kind {
 	case "key":
@@ -257,12 +386,17 @@ func (e *Executor) createResource(
 		if err != nil {
 			return fmt.Errorf("failed to render key spec: %w", err)
 		}
-		// Default OutputDir to spec.StateDir + "/keys" when not explicitly set.
-		// This ensures key files end up in the spec-defined state directory
-		// rather than the provider's own default state directory.
+		// Default OutputDir to the keys directory of the environment below
+		// spec.StateDir, or below the orchestrator state directory, so that
+		// key files are removed with the environment rather than left in the
+		// provider's own default state directory.
 		outputDir := renderedSpec.Spec.OutputDir
-		if outputDir == "" && spec.StateDir != "" {
-			outputDir = filepath.Join(spec.StateDir, "keys")
+		if outputDir == "" {
+			layout := e.store.Layout()
+			if spec.StateDir != "" {
+				layout = paths.New(spec.StateDir)
+			}
+			outputDir = layout.Env(envState.ID).KeysDir()
 		}
 		request = &providerv1.KeyCreateRequest{
 			Name: prefixedName(isoConfig, ref.Name),
@@ -273,6 +407,7 @@ func (e *Executor) createResource(
 				OutputDir: outputDir,
 			},
 			ProviderSpec: renderedSpec.ProviderSpec,
+			Labels:       resourceLabels(spec, envState, ref, e.tenant, e.stateDir),
 		}
 		if providerName == "" {
 			providerName = renderedSpec.Provider
@@ -294,8 +429,11 @@ func (e *Executor) createResource(
 			return fmt.Errorf("phase 2 validation failed: %w", err)
 		}
 		convertedSpec := e.convertNetworkSpec(renderedSpec.Spec)
-		// Rewrite CIDR and gateway for isolation
-		if isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
+		// Networks with cidr auto use the subnet allocated to them, which is
+		// already unique; the others are rewritten for isolation
+		if cidr, ok := envState.CIDRs[ref.Name]; ok {
+			convertedSpec.CIDR = cidr
+		} else if isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
 			convertedSpec.CIDR = strings.ReplaceAll(convertedSpec.CIDR, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
 			convertedSpec.Gateway = strings.ReplaceAll(convertedSpec.Gateway, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
 			if convertedSpec.DHCP != nil {
@@ -303,11 +441,28 @@ func (e *Executor) createResource(
 				convertedSpec.DHCP.RangeEnd = strings.ReplaceAll(convertedSpec.DHCP.RangeEnd, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
 			}
 		}
+		if err := reserveStaticIPs(&convertedSpec, ref.Name, spec, envState, templateCtx, isoConfig); err != nil {
+			return err
+		}
+		// Boot artifacts and files are served from a boot root in the
+		// environment directory, in place of the TFTP root
+		layout := e.store.Layout()
+		if spec.StateDir != "" {
+			layout = paths.New(spec.StateDir)
+		}
+		boot, err = prepareBootRoot(ctx, e.downloader, layout, ref.Name, spec, envState, templateCtx, isoConfig, renderedSpec.Spec.Tftp)
+		if err != nil {
+			return fmt.Errorf("failed to prepare boot root: %w", err)
+		}
+		if boot != nil {
+			convertedSpec.TFTP.Root = boot.root
+		}
 		request = &providerv1.NetworkCreateRequest{
 			Name:         prefixedName(isoConfig, ref.Name),
 			Kind:         renderedSpec.Kind,
 			Spec:         convertedSpec,
 			ProviderSpec: renderedSpec.ProviderSpec,
+			Labels:       resourceLabels(spec, envState, ref, e.tenant, e.stateDir),
 		}
 		if providerName == "" {
 			providerName = renderedSpec.Provider
@@ -315,54 +470,38 @@ func (e *Executor) createResource(
 
 	case "vm":
 		tool = "vm_create"
-		vmSpec, err := e.findVMSpec(spec, ref.Name)
+		vmRequest, renderedSpec, err := e.buildVMRequest(ref, spec, templateCtx, resourceLabels(spec, envState, ref, e.tenant, e.stateDir), templatedFields, isoConfig)
 		if err != nil {
 			return err
 		}
-		// Deep copy and render templates
-		renderedSpec, err := e.renderVMSpec(vmSpec, templateCtx)
+		// Hash before the access and environment injections below: they
+		// carry generated keys and secret values that must not reach state
+		hashes, err = vmContentHashes(vmRequest, ref.Name, spec, renderedSpec.Spec.CloudInit)
 		if err != nil {
-			return fmt.Errorf("failed to render vm spec: %w", err)
-		}
-		// Phase 2 validation for templated fields
-		if err := specpkg.ValidateResourceRefsLate("vm", ref.Name, renderedSpec, spec, templatedFields); err != nil {
-			return fmt.Errorf("phase 2 validation failed: %w", err)
+			return fmt.Errorf("failed to hash vm spec: %w", err)
 		}
-		convertedVMSpec := e.convertVMSpec(renderedSpec.Spec)
-		// Prefix network references for isolation
-		if isoConfig != nil && isoConfig.NamePrefix != "" {
-			if len(convertedVMSpec.Networks) > 0 {
-				for i, n := range convertedVMSpec.Networks {
-					convertedVMSpec.Networks[i] = prefixedName(isoConfig, n)
-				}
-			}
-			if convertedVMSpec.Network != "" {
-				convertedVMSpec.Network = prefixedName(isoConfig, convertedVMSpec.Network)
-			}
+		convertedVMSpec := &vmRequest.Spec
+		if convertedVMSpec.Readiness != nil && convertedVMSpec.Readiness.SSH != nil {
+			proxyJump = convertedVMSpec.Readiness.SSH.ProxyJump
 		}
-		if isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
-			if convertedVMSpec.CloudInit != nil {
-				for i, wf := range convertedVMSpec.CloudInit.WriteFiles {
-					convertedVMSpec.CloudInit.WriteFiles[i].Content = strings.ReplaceAll(wf.Content, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
-				}
-				for i, cmd := range convertedVMSpec.CloudInit.Runcmd {
-					convertedVMSpec.CloudInit.Runcmd[i] = strings.ReplaceAll(cmd, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
-				}
-				if convertedVMSpec.CloudInit.NetworkConfig != nil {
-					for i, eth := range convertedVMSpec.CloudInit.NetworkConfig.Ethernets {
-						for j, addr := range eth.Addresses {
-							convertedVMSpec.CloudInit.NetworkConfig.Ethernets[i].Addresses[j] = strings.ReplaceAll(addr, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
-						}
-						convertedVMSpec.CloudInit.NetworkConfig.Ethernets[i].Gateway4 = strings.ReplaceAll(eth.Gateway4, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
-					}
-				}
-			}
+		seed.Apply(envState.Seed, ref.Name, convertedVMSpec)
+		// Servers of access points get WireGuard installed through cloud-init
+		e.mu.Lock()
+		injectAccessServer(convertedVMSpec, ref.Name, spec, templateCtx)
+		injectCertificates(convertedVMSpec, ref.Name, spec, templateCtx)
+		injectNTP(convertedVMSpec, renderedSpec.Spec, templateCtx)
+		injectLogShipping(convertedVMSpec, renderedSpec.Spec, spec, templateCtx)
+		e.mu.Unlock()
+		// Export cloudInit.environment in the guest, after the isolation
+		// rewrite so that secret values are passed through untouched
+		if err := injectGuestEnvironment(convertedVMSpec, ref.Name, renderedSpec.Spec.CloudInit); err != nil {
+			return fmt.Errorf("failed to render guest environment: %w", err)
 		}
-		request = &providerv1.VMCreateRequest{
-			Name:         prefixedName(isoConfig, ref.Name),
-			Spec:         convertedVMSpec,
-			ProviderSpec: renderedSpec.ProviderSpec,
+		agentState, err = e.injectAgent(convertedVMSpec, ref.Name, renderedSpec.Spec.Agent)
+		if err != nil {
+			return err
 		}
+		request = vmRequest
 		if providerName == "" {
 			providerName = renderedSpec.Provider
 		}
@@ -383,17 +522,91 @@ func (e *Executor) createResource(
 			templateCtx.Images = make(map[string]specpkg.ImageTemplateData)
 		}
 		templateCtx.Images[ref.Name] = specpkg.ImageTemplateData{
-			Path: imgState.LocalPath,
-			Name: ref.Name,
+			Path:   imgState.LocalPath,
+			Name:   ref.Name,
+			SHA256: imgState.SHA256,
 		}
 		// Also register alias if set
 		if imageRes.Spec.Alias != "" {
 			templateCtx.Images[imageRes.Spec.Alias] = specpkg.ImageTemplateData{
-				Path: imgState.LocalPath,
-				Name: ref.Name,
+				Path:   imgState.LocalPath,
+				Name:   ref.Name,
+				SHA256: imgState.SHA256,
+			}
+		}
+		e.mu.Unlock()
+		return nil
+
+	case "tunnel":
+		// Tunnels are generated by the orchestrator, not by providers
+		tunnelRes, err := e.findTunnelSpec(spec, ref.Name)
+		if err != nil {
+			return err
+		}
+		e.mu.Lock()
+		renderedSpec, err := e.renderTunnelSpec(tunnelRes, templateCtx)
+		var data specpkg.TunnelTemplateData
+		if err == nil {
+			data, err = buildTunnel(renderedSpec, spec, templateCtx)
+		}
+		if err == nil {
+			if templateCtx.Tunnels == nil {
+				templateCtx.Tunnels = make(map[string]specpkg.TunnelTemplateData)
+			}
+			templateCtx.Tunnels[ref.Name] = data
+		}
+		e.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to build tunnel %q: %w", ref.Name, err)
+		}
+		return nil
+
+	case "access":
+		// Access points are generated by the orchestrator, not by providers
+		accessRes, err := e.findAccessSpec(spec, ref.Name)
+		if err != nil {
+			return err
+		}
+		e.mu.Lock()
+		data, err := buildAccess(accessRes, spec, templateCtx)
+		if err == nil {
+			if templateCtx.Access == nil {
+				templateCtx.Access = make(map[string]specpkg.AccessTemplateData)
+			}
+			templateCtx.Access[ref.Name] = data
+		}
+		e.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to build access %q: %w", ref.Name, err)
+		}
+		return nil
+
+	case "service":
+		// Services are run on the host by the orchestrator, not by providers
+		return e.createService(ref, spec, templateCtx, envState)
+
+	case "certificate":
+		// Certificates are issued by the orchestrator, not by providers
+		certRes, err := findCertificateSpec(spec, ref.Name)
+		if err != nil {
+			return err
+		}
+		e.mu.Lock()
+		renderedSpec, err := renderCertificateSpec(certRes, templateCtx)
+		var data specpkg.CertificateTemplateData
+		if err == nil {
+			data, err = buildCertificate(renderedSpec, templateCtx)
+		}
+		if err == nil {
+			if templateCtx.Certificates == nil {
+				templateCtx.Certificates = make(map[string]specpkg.CertificateTemplateData)
 			}
+			templateCtx.Certificates[ref.Name] = data
 		}
 		e.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to issue certificate %q: %w", ref.Name, err)
+		}
 		return nil
 
 	default:
@@ -431,7 +644,8 @@ func (e *Executor) createResource(
 		e.mu.Lock()
 		e.updateResourceState(envState, ref, providerName, v1.StatusFailed, nil, errMsg)
 		e.mu.Unlock()
-		return fmt.Errorf("provider returned error: %s", errMsg)
+		// The code of the error, e.g. NETWORK_CONFLICT, is kept for callers
+		return fmt.Errorf("provider returned error: %w", operationError(tool, result, nil))
 	}
 
 	// Update state with the result
@@ -440,9 +654,40 @@ func (e *Executor) createResource(
 		return fmt.Errorf("failed to convert resource state: %w", err)
 	}
 
+	// Record the jump host so clients can reach the VM later
+	if proxyJump != "" {
+		if resourceState == nil {
+			resourceState = make(map[string]any)
+		}
+		resourceState["proxyJump"] = proxyJump
+	}
+	// Record how to reach the guest agent
+	if agentState != nil {
+		if resourceState == nil {
+			resourceState = make(map[string]any)
+		}
+		maps.Copy(resourceState, agentState)
+	}
+	// Render the boot files now that the gateway serving them is known
+	if boot != nil {
+		if resourceState == nil {
+			resourceState = make(map[string]any)
+		}
+		resourceState["bootRoot"] = boot.root
+		if err := boot.render(getString(resourceState, "ip")); err != nil {
+			e.mu.Lock()
+			e.updateResourceState(envState, ref, providerName, v1.StatusFailed, resourceState, err.Error())
+			e.mu.Unlock()
+			return fmt.Errorf("failed to render boot files: %w", err)
+		}
+	}
+
 	// Lock to protect state modifications during parallel execution
 	e.mu.Lock()
 	e.updateResourceState(envState, ref, providerName, v1.StatusReady, resourceState, "")
+	if hashes != nil {
+		e.getResourceState(envState, ref).Hashes = hashes
+	}
 
 	// Update template context with the new resource data
 	e.updateTemplateContext(templateCtx, ref, resourceState)
@@ -459,6 +704,64 @@ func (e *Executor) createResource(
 	return nil
 }
 
+// buildVMRequest renders the spec of a VM and converts it into a create
+// request carrying labels, with network names and addresses rewritten for
+// isolation. Access servers and the guest environment are not injected yet.
+func (e *Executor) buildVMRequest(
+	ref v1.ResourceRef,
+	spec *v1.Spec,
+	templateCtx *specpkg.TemplateContext,
+	labels map[string]string,
+	templatedFields *specpkg.TemplatedFields,
+	isoConfig *IsolationConfig,
+) (*providerv1.VMCreateRequest, *v1.VMResource, error) {
+	vmSpec, err := e.findVMSpec(spec, ref.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Deep copy and render templates
+	renderedSpec, err := e.renderVMSpec(vmSpec, templateCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to render vm spec: %w", err)
+	}
+	// Phase 2 validation for templated fields
+	if err := specpkg.ValidateResourceRefsLate("vm", ref.Name, renderedSpec, spec, templatedFields); err != nil {
+		return nil, nil, fmt.Errorf("phase 2 validation failed: %w", err)
+	}
+	convertedVMSpec := e.convertVMSpec(renderedSpec.Spec)
+	convertedVMSpec.NICs = nicSpecs(renderedSpec.Spec, convertedVMSpec.Networks, spec.Networks)
+	// Prefix network references for isolation
+	if isoConfig != nil && isoConfig.NamePrefix != "" {
+		if len(convertedVMSpec.Networks) > 0 {
+			for i, n := range convertedVMSpec.Networks {
+				convertedVMSpec.Networks[i] = networkName(isoConfig, n)
+			}
+		}
+		if convertedVMSpec.Network != "" {
+			convertedVMSpec.Network = networkName(isoConfig, convertedVMSpec.Network)
+		}
+	}
+	if isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
+		convertedVMSpec.IP = strings.ReplaceAll(convertedVMSpec.IP, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+		if convertedVMSpec.CloudInit != nil {
+			for i, wf := range convertedVMSpec.CloudInit.WriteFiles {
+				convertedVMSpec.CloudInit.WriteFiles[i].Content = strings.ReplaceAll(wf.Content, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+			}
+			for i, cmd := range convertedVMSpec.CloudInit.Runcmd {
+				convertedVMSpec.CloudInit.Runcmd[i] = strings.ReplaceAll(cmd, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+			}
+			convertedVMSpec.CloudInit.RawUserData = strings.ReplaceAll(convertedVMSpec.CloudInit.RawUserData, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+			rewriteNetworkConfig(convertedVMSpec.CloudInit.NetworkConfig, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+		}
+	}
+	return &providerv1.VMCreateRequest{
+		Name:         prefixedName(isoConfig, ref.Name),
+		Spec:         convertedVMSpec,
+		ProviderSpec: renderedSpec.ProviderSpec,
+		Labels:       labels,
+	}, renderedSpec, nil
+}
+
 // deleteResource deletes a single resource using the appropriate provider.
 func (e *Executor) deleteResource(
 	ctx context.Context,
@@ -475,6 +778,9 @@ func (e *Executor) deleteResource(
 		// Resource doesn't exist in state, nothing to delete
 		return nil
 	}
+	if ref.Kind == "service" {
+		return e.deleteService(ref, resourceState, envState)
+	}
 
 	providerName := resourceState.Provider
 	if providerName == "" {
@@ -570,6 +876,46 @@ func (e *Executor) findImageSpec(spec *v1.Spec, name string) (*v1.ImageResource,
 	return nil, fmt.Errorf("image resource %q not found in spec", name)
 }
 
+// findTunnelSpec finds a tunnel resource by name in the spec.
+func (e *Executor) findTunnelSpec(spec *v1.Spec, name string) (*v1.TunnelResource, error) {
+	for i := range spec.Tunnels {
+		if spec.Tunnels[i].Name == name {
+			return &spec.Tunnels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("tunnel resource %q not found in spec", name)
+}
+
+// findAccessSpec finds an access resource by name in the spec.
+func (e *Executor) findAccessSpec(spec *v1.Spec, name string) (*v1.AccessResource, error) {
+	for i := range spec.Access {
+		if spec.Access[i].Name == name {
+			return &spec.Access[i], nil
+		}
+	}
+	return nil, fmt.Errorf("access resource %q not found in spec", name)
+}
+
+// renderTunnelSpec creates a deep copy and renders templates in a tunnel spec.
+func (e *Executor) renderTunnelSpec(original *v1.TunnelResource, templateCtx *specpkg.TemplateContext) (*v1.TunnelResource, error) {
+	// Deep copy via JSON marshaling
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	var copy v1.TunnelResource
+	if err := json.Unmarshal(data, &copy); err != nil {
+		return nil, err
+	}
+
+	// Render templates
+	if err := specpkg.RenderSpec(&copy, templateCtx); err != nil {
+		return nil, err
+	}
+
+	return &copy, nil
+}
+
 // renderKeySpec creates a deep copy and renders templates in a key spec.
 func (e *Executor) renderKeySpec(original *v1.KeyResource, templateCtx *specpkg.TemplateContext) (*v1.KeyResource, error) {
 	// Deep copy via JSON marshaling
@@ -639,7 +985,7 @@ func (e *Executor) convertNetworkSpec(spec v1.NetworkSpec) providerv1.NetworkSpe
 		MTU:      spec.Mtu,
 	}
 
-	// Only pass DHCP/DNS/TFTP config when explicitly configured.
+	// Only pass DHCP/DNS/TFTP/NTP config when explicitly configured.
 	// The generated spec uses pointer types for nullable sub-specs;
 	// nil means "unconfigured" and lets the provider apply its defaults
 	// (e.g. libvirt defaults DHCP to enabled).
@@ -652,6 +998,9 @@ func (e *Executor) convertNetworkSpec(spec v1.NetworkSpec) providerv1.NetworkSpe
 			Router:     spec.Dhcp.Router,
 			DNSServers: spec.Dhcp.DnsServers,
 		}
+		for _, h := range spec.Dhcp.Hosts {
+			result.DHCP.StaticLeases = append(result.DHCP.StaticLeases, providerv1.StaticLease{MAC: h.Mac, IP: h.Ip, Hostname: h.Hostname})
+		}
 	}
 
 	if spec.Dns != nil {
@@ -659,19 +1008,60 @@ func (e *Executor) convertNetworkSpec(spec v1.NetworkSpec) providerv1.NetworkSpe
 			Enabled: spec.Dns.Enabled,
 			Servers: spec.Dns.Servers,
 		}
+		for _, r := range spec.Dns.Records {
+			result.DNS.Records = append(result.DNS.Records, providerv1.DNSRecord{Name: r.Name, Type: r.Type, Value: r.Value})
+		}
 	}
 
 	if spec.Tftp != nil {
 		result.TFTP = &providerv1.TFTPSpec{
-			Enabled:  spec.Tftp.Enabled,
-			Root:     spec.Tftp.Root,
-			BootFile: spec.Tftp.BootFile,
+			Enabled:         spec.Tftp.Enabled,
+			Root:            spec.Tftp.Root,
+			BootFile:        spec.Tftp.BootFile,
+			BootFileEFI:     spec.Tftp.BootFileEfi,
+			DHCPBootOptions: spec.Tftp.DhcpBootOptions,
+		}
+	}
+
+	if spec.Ntp != nil {
+		result.NTP = &providerv1.NTPSpec{
+			Enabled: spec.Ntp.Enabled,
+			Servers: spec.Ntp.Servers,
 		}
 	}
 
 	return result
 }
 
+// nicSpecs returns the options of the NICs of a VM, in the order of its
+// networks. NICs without an MTU of their own get the MTU of their network,
+// so that the guest uses it. It returns nil when no NIC has options.
+func nicSpecs(vm v1.VMSpec, networks []string, specNetworks []v1.NetworkResource) []providerv1.NICSpec {
+	mtus := make(map[string]int, len(specNetworks))
+	for _, n := range specNetworks {
+		mtus[n.Name] = n.Spec.Mtu
+	}
+	options := make(map[string]v1.VMNICSpec, len(vm.Nics))
+	for _, nic := range vm.Nics {
+		options[nic.Network] = nic
+	}
+
+	nics := make([]providerv1.NICSpec, len(networks))
+	set := false
+	for i, name := range networks {
+		opt := options[name]
+		nics[i] = providerv1.NICSpec{Model: opt.Model, MTU: opt.Mtu, DisableOffloads: opt.DisableOffloads}
+		if nics[i].MTU == 0 {
+			nics[i].MTU = mtus[name]
+		}
+		set = set || nics[i].Model != "" || nics[i].MTU != 0 || len(nics[i].DisableOffloads) > 0
+	}
+	if !set {
+		return nil
+	}
+	return nics
+}
+
 // convertVMSpec converts v1.VMSpec to providerv1.VMSpec.
 func (e *Executor) convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 	// Ensure boot order is non-nil (JSON serialization requires array, not null)
@@ -694,6 +1084,7 @@ func (e *Executor) convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 		VCPUs:    spec.Vcpus,
 		Network:  network,
 		Networks: networks,
+		IP:       spec.Ip,
 		Disk: providerv1.DiskSpec{
 			BaseImage: spec.Disk.BaseImage,
 			Size:      spec.Disk.Size,
@@ -706,16 +1097,19 @@ func (e *Executor) convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 
 	// CloudInit is a value type in generated code, check if any fields are set
 	if spec.CloudInit.Hostname != "" || len(spec.CloudInit.Users) > 0 || len(spec.CloudInit.Packages) > 0 ||
-		len(spec.CloudInit.Runcmd) > 0 || len(spec.CloudInit.WriteFiles) > 0 || len(spec.CloudInit.NetworkConfig.Ethernets) > 0 {
+		len(spec.CloudInit.Runcmd) > 0 || len(spec.CloudInit.WriteFiles) > 0 || len(spec.CloudInit.NetworkConfig.Ethernets) > 0 ||
+		spec.CloudInit.RawUserData != "" {
 		result.CloudInit = &providerv1.CloudInitSpec{
-			Hostname: spec.CloudInit.Hostname,
-			Packages: spec.CloudInit.Packages,
-			Runcmd:   spec.CloudInit.Runcmd,
+			Hostname:    spec.CloudInit.Hostname,
+			Packages:    spec.CloudInit.Packages,
+			Runcmd:      spec.CloudInit.Runcmd,
+			RawUserData: spec.CloudInit.RawUserData,
 		}
 		for _, u := range spec.CloudInit.Users {
 			result.CloudInit.Users = append(result.CloudInit.Users, providerv1.UserSpec{
 				Name:              u.Name,
 				Sudo:              u.Sudo,
+				Shell:             u.Shell,
 				SSHAuthorizedKeys: u.SshAuthorizedKeys,
 			})
 		}
@@ -760,6 +1154,7 @@ func (e *Executor) convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 			Timeout:    spec.Readiness.Ssh.Timeout,
 			User:       spec.Readiness.Ssh.User,
 			PrivateKey: spec.Readiness.Ssh.PrivateKey,
+			ProxyJump:  spec.Readiness.Ssh.ProxyJump,
 		}
 	}
 
@@ -777,6 +1172,50 @@ func (e *Executor) convertVMSpec(spec v1.VMSpec) providerv1.VMSpec {
 		}
 	}
 
+	// Security is nullable; nil keeps the provider's default confinement.
+	if spec.Security != nil {
+		result.Security = &providerv1.SecuritySpec{
+			Disabled: spec.Security.Disabled,
+			Model:    spec.Security.Model,
+			Label:    spec.Security.Label,
+			Relabel:  spec.Security.Relabel,
+		}
+	}
+
+	if spec.Devices.Vsock != nil {
+		result.Vsock = &providerv1.VsockSpec{CID: uint32(spec.Devices.Vsock.Cid)}
+	}
+	result.PCIPassthrough = spec.Devices.PciPassthrough
+	for _, m := range spec.Devices.Mdevs {
+		result.Mdevs = append(result.Mdevs, providerv1.MdevSpec{UUID: m.Uuid, Type: m.Type, Parent: m.Parent})
+	}
+
+	for _, d := range spec.Disks {
+		result.Disks = append(result.Disks, providerv1.DiskSpec{
+			BaseImage: d.BaseImage,
+			Size:      d.Size,
+			Format:    d.Format,
+		})
+	}
+
+	if spec.Disk.Encryption != nil {
+		result.Disk.Encryption = &providerv1.DiskEncryptionSpec{
+			Enabled:             spec.Disk.Encryption.Enabled,
+			PassphraseSecretRef: spec.Disk.Encryption.PassphraseSecretRef,
+		}
+	}
+
+	if nc := guestNetworkConfig(&spec, networks); nc != nil {
+		if result.CloudInit == nil {
+			result.CloudInit = &providerv1.CloudInitSpec{}
+		}
+		result.CloudInit.NetworkConfig = nc
+	}
+
+	if spec.Disk.Fstrim {
+		injectFstrim(&result)
+	}
+
 	return result
 }
 
@@ -836,6 +1275,9 @@ func (e *Executor) updateResourceState(
 	if envState.Resources.VMs == nil {
 		envState.Resources.VMs = make(map[string]*v1.ResourceState)
 	}
+	if envState.Resources.Services == nil {
+		envState.Resources.Services = make(map[string]*v1.ResourceState)
+	}
 
 	switch ref.Kind {
 	case "key":
@@ -844,6 +1286,8 @@ func (e *Executor) updateResourceState(
 		envState.Resources.Networks[ref.Name] = state
 	case "vm":
 		envState.Resources.VMs[ref.Name] = state
+	case "service":
+		envState.Resources.Services[ref.Name] = state
 	}
 }
 
@@ -862,6 +1306,10 @@ func (e *Executor) getResourceState(envState *v1.EnvironmentState, ref v1.Resour
 		if envState.Resources.VMs != nil {
 			return envState.Resources.VMs[ref.Name]
 		}
+	case "service":
+		if envState.Resources.Services != nil {
+			return envState.Resources.Services[ref.Name]
+		}
 	}
 	return nil
 }
@@ -894,6 +1342,8 @@ func (e *Executor) updateTemplateContext(templateCtx *specpkg.TemplateContext, r
 			CIDR:          getString(resourceData, "cidr"),
 			InterfaceName: getString(resourceData, "interfaceName"),
 			UUID:          getString(resourceData, "uuid"),
+			NTPServer:     getString(resourceData, "ntpServer"),
+			BootRoot:      getString(resourceData, "bootRoot"),
 		}
 
 	case "vm":
@@ -906,6 +1356,18 @@ func (e *Executor) updateTemplateContext(templateCtx *specpkg.TemplateContext, r
 			MAC:        getString(resourceData, "mac"),
 			SSHCommand: getString(resourceData, "sshCommand"),
 		}
+
+	case "service":
+		if templateCtx.Services == nil {
+			templateCtx.Services = make(map[string]specpkg.ServiceTemplateData)
+		}
+		templateCtx.Services[ref.Name] = specpkg.ServiceTemplateData{
+			Address:   getString(resourceData, serviceAddressKey),
+			Port:      int(getUint32(resourceData, servicePortKey)),
+			Endpoint:  getString(resourceData, serviceEndpointKey),
+			AccessKey: getString(resourceData, serviceAccessKeyKey),
+			SecretKey: getString(resourceData, serviceSecretKeyKey),
+		}
 	}
 }
 
@@ -19,8 +19,10 @@ package orchestrator
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
@@ -786,6 +788,7 @@ func TestExecutor_convertNetworkSpec(t *testing.T) {
 		Dns: &v1.DNSSpec{
 			Enabled: true,
 			Servers: []string{"8.8.8.8"},
+			Records: []v1.DNSRecordSpec{{Name: "api.example.com", Type: "A", Value: "192.168.1.50"}},
 		},
 		Tftp: &v1.TFTPSpec{
 			Enabled:  true,
@@ -817,6 +820,9 @@ func TestExecutor_convertNetworkSpec(t *testing.T) {
 	if result.DNS == nil {
 		t.Fatal("DNS is nil")
 	}
+	if len(result.DNS.Records) != 1 || result.DNS.Records[0].Name != "api.example.com" || result.DNS.Records[0].Value != "192.168.1.50" {
+		t.Errorf("DNS.Records = %+v, want the api.example.com record", result.DNS.Records)
+	}
 	if result.TFTP == nil {
 		t.Fatal("TFTP is nil")
 	}
@@ -892,6 +898,7 @@ func TestExecutor_convertVMSpec(t *testing.T) {
 				{
 					Name: "admin",
 					Sudo: "ALL=(ALL) NOPASSWD:ALL",
+					Shell: "/bin/sh",
 					SshAuthorizedKeys: []string{"ssh-ed25519 AAAA..."},
 				},
 			},
@@ -934,11 +941,25 @@ func TestExecutor_convertVMSpec(t *testing.T) {
 	if result.CloudInit.Users[0].Name != "admin" {
 		t.Errorf("CloudInit.Users[0].Name = %s, want admin", result.CloudInit.Users[0].Name)
 	}
+	if result.CloudInit.Users[0].Shell != "/bin/sh" {
+		t.Errorf("CloudInit.Users[0].Shell = %s, want /bin/sh", result.CloudInit.Users[0].Shell)
+	}
 	if result.Readiness == nil || result.Readiness.SSH == nil {
 		t.Fatal("Readiness or Readiness.SSH is nil")
 	}
 }
 
+func TestExecutor_convertVMSpec_RawUserData(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	result := executor.convertVMSpec(v1.VMSpec{
+		CloudInit: v1.CloudInitSpec{RawUserData: "#!/bin/sh\necho hi\n"},
+	})
+	if result.CloudInit == nil || result.CloudInit.RawUserData != "#!/bin/sh\necho hi\n" {
+		t.Errorf("CloudInit = %+v, want the raw user-data", result.CloudInit)
+	}
+}
+
 func TestExecutor_convertVMSpec_NilSubspecs(t *testing.T) {
 	executor := newTestExecutor(t)
 
@@ -959,6 +980,111 @@ func TestExecutor_convertVMSpec_NilSubspecs(t *testing.T) {
 	if result.Readiness != nil {
 		t.Error("Readiness should be nil")
 	}
+	if result.Security != nil {
+		t.Error("Security should be nil")
+	}
+}
+
+func TestExecutor_convertVMSpec_Security(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	vmSpec := v1.VMSpec{
+		Memory: 1024,
+		Vcpus:  1,
+		Disk:   v1.DiskSpec{Size: "10G"},
+		Boot:   v1.BootSpec{Order: []string{"hd"}},
+		Security: &v1.VMSecuritySpec{
+			Model:   "apparmor",
+			Label:   "libvirt-virtiofs",
+			Relabel: true,
+		},
+	}
+
+	result := executor.convertVMSpec(vmSpec)
+
+	if result.Security == nil {
+		t.Fatal("Security is nil")
+	}
+	if result.Security.Model != "apparmor" || result.Security.Label != "libvirt-virtiofs" || !result.Security.Relabel {
+		t.Errorf("Security = %+v, want apparmor/libvirt-virtiofs/relabel", *result.Security)
+	}
+	if result.Security.Disabled {
+		t.Error("Security.Disabled should be false")
+	}
+}
+
+func TestExecutor_convertVMSpec_DiskEncryption(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	vmSpec := v1.VMSpec{
+		Memory: 1024,
+		Vcpus:  1,
+		Disk: v1.DiskSpec{
+			Size:       "10G",
+			Encryption: &v1.DiskEncryptionSpec{Enabled: true, PassphraseSecretRef: "env:DISK_PASSPHRASE"},
+		},
+		Boot: v1.BootSpec{Order: []string{"hd"}},
+	}
+
+	result := executor.convertVMSpec(vmSpec)
+
+	if result.Disk.Encryption == nil {
+		t.Fatal("Disk.Encryption is nil")
+	}
+	if !result.Disk.Encryption.Enabled || result.Disk.Encryption.PassphraseSecretRef != "env:DISK_PASSPHRASE" {
+		t.Errorf("Disk.Encryption = %+v", *result.Disk.Encryption)
+	}
+}
+
+func TestExecutor_convertVMSpec_DataDisks(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	vmSpec := v1.VMSpec{
+		Memory: 1024,
+		Vcpus:  1,
+		Disk:   v1.DiskSpec{Size: "10G"},
+		Disks: []v1.DataDiskSpec{
+			{Size: "100G"},
+			{Size: "1G", Format: "raw", BaseImage: "/images/seed.img"},
+		},
+		Boot: v1.BootSpec{Order: []string{"hd"}},
+	}
+
+	result := executor.convertVMSpec(vmSpec)
+
+	want := []providerv1.DiskSpec{
+		{Size: "100G"},
+		{Size: "1G", Format: "raw", BaseImage: "/images/seed.img"},
+	}
+	if !reflect.DeepEqual(result.Disks, want) {
+		t.Errorf("Disks = %+v, want %+v", result.Disks, want)
+	}
+}
+
+func TestNICSpecs(t *testing.T) {
+	networks := []v1.NetworkResource{
+		{Name: "jumbo", Spec: v1.NetworkSpec{Mtu: 9000}},
+		{Name: "lan"},
+	}
+
+	if nics := nicSpecs(v1.VMSpec{}, []string{"lan"}, networks); nics != nil {
+		t.Errorf("nicSpecs() = %+v, want nil without options", nics)
+	}
+
+	vm := v1.VMSpec{Nics: []v1.VMNICSpec{{Network: "lan", Model: "e1000", DisableOffloads: []string{"tso"}}}}
+	want := []providerv1.NICSpec{
+		{Model: "e1000", DisableOffloads: []string{"tso"}},
+		{MTU: 9000},
+	}
+	if nics := nicSpecs(vm, []string{"lan", "jumbo"}, networks); !reflect.DeepEqual(nics, want) {
+		t.Errorf("nicSpecs() = %+v, want %+v", nics, want)
+	}
+
+	// The MTU of a NIC overrides the MTU of its network
+	vm = v1.VMSpec{Nics: []v1.VMNICSpec{{Network: "jumbo", Mtu: 1400}}}
+	if nics := nicSpecs(vm, []string{"jumbo"}, networks); len(nics) != 1 || nics[0].MTU != 1400 {
+		t.Errorf("nicSpecs() = %+v, want MTU 1400", nics)
+	}
 }
 
 func TestExecutor_ExecuteCreate_SkipsEmptyPhases(t *testing.T) {
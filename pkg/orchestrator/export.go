@@ -0,0 +1,108 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/terraform"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/topology"
+)
+
+// Export formats supported by Orchestrator.Export.
+const (
+	// ExportFormatDiagram renders the topology as a Mermaid flowchart.
+	ExportFormatDiagram = "diagram"
+	// ExportFormatSVG renders the topology as an SVG document.
+	ExportFormatSVG = "svg"
+	// ExportFormatJSON returns the environment state as indented JSON.
+	ExportFormatJSON = "json"
+	// ExportFormatTerraform renders the libvirt resources as Terraform or
+	// OpenTofu configuration with import blocks.
+	ExportFormatTerraform = "terraform"
+	// ExportFormatCSV lists the resources of the inventory as CSV, with a
+	// header row.
+	ExportFormatCSV = "csv"
+	// ExportFormatNDJSON lists the resources of the inventory as one JSON
+	// object per line.
+	ExportFormatNDJSON = "ndjson"
+)
+
+// Topology artifact file names, relative to the artifact directory.
+const (
+	topologyMermaidFile = "topology.mmd"
+	topologySVGFile     = "topology.svg"
+)
+
+// Export renders a stored environment in the given format. The csv and
+// ndjson formats list the inventory instead, of every environment when
+// environmentID is empty. It only reads state and is therefore available in
+// read-only mode.
+func (o *Orchestrator) Export(environmentID, format string) (string, error) {
+	if format == ExportFormatCSV || format == ExportFormatNDJSON {
+		records, err := o.Inventory(environmentID)
+		if err != nil {
+			return "", err
+		}
+		return exportInventory(records, format)
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	return exportState(envState, format)
+}
+
+// exportState renders an environment state in the given format.
+func exportState(envState *v1.EnvironmentState, format string) (string, error) {
+	switch format {
+	case ExportFormatDiagram, "":
+		return topology.FromState(envState).Mermaid(), nil
+	case ExportFormatSVG:
+		return topology.FromState(envState).SVG(), nil
+	case ExportFormatJSON:
+		data, err := json.MarshalIndent(envState, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal state: %w", err)
+		}
+		return string(data), nil
+	case ExportFormatTerraform:
+		return terraform.FromState(envState), nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q (supported: %s, %s, %s, %s, %s, %s)",
+			format, ExportFormatDiagram, ExportFormatSVG, ExportFormatJSON, ExportFormatTerraform, ExportFormatCSV, ExportFormatNDJSON)
+	}
+}
+
+// writeTopology records the environment topology as Mermaid and SVG files in
+// the artifact directory.
+func writeTopology(envState *v1.EnvironmentState) error {
+	topo := topology.FromState(envState)
+	files := map[string]string{
+		topologyMermaidFile: topo.Mermaid(),
+		topologySVGFile:     topo.SVG(),
+	}
+	for name, content := range files {
+		path := filepath.Join(envState.ArtifactDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func newExportTestState(artifactDir string) *v1.EnvironmentState {
+	return &v1.EnvironmentState{
+		ID:          "env-export",
+		Status:      v1.StatusReady,
+		ArtifactDir: artifactDir,
+		Spec: &v1.Spec{
+			Networks: []v1.NetworkResource{{Name: "net", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: "192.168.100.1/24"}}},
+			Vms:      []v1.VMResource{{Name: "vm", Spec: v1.VMSpec{Network: "net"}}},
+		},
+		Resources: v1.ResourceMap{
+			VMs: map[string]*v1.ResourceState{"vm": {State: map[string]any{"ip": "192.168.100.10"}}},
+		},
+	}
+}
+
+func TestOrchestrator_Export(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	if err := orchestrator.store.Save(newExportTestState("")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: "flowchart TB"},
+		{format: ExportFormatDiagram, want: "vm_vm --- net_net"},
+		{format: ExportFormatSVG, want: "<svg"},
+		{format: ExportFormatJSON, want: `"id": "env-export"`},
+		{format: ExportFormatTerraform, want: `source = "dmacvicar/libvirt"`},
+		{format: "png", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := orchestrator.Export("env-export", tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Export() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("Export() = %q, want substring %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := orchestrator.Export("missing", ExportFormatDiagram); err == nil {
+		t.Error("Export() expected error for unknown environment")
+	}
+
+	out, _ := orchestrator.Export("env-export", ExportFormatJSON)
+	var decoded v1.EnvironmentState
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Errorf("JSON export is not valid state: %v", err)
+	}
+}
+
+func TestWriteTopology(t *testing.T) {
+	dir := t.TempDir()
+	envState := newExportTestState(dir)
+	if err := writeTopology(envState); err != nil {
+		t.Fatalf("writeTopology() error = %v", err)
+	}
+
+	for _, name := range []string{topologyMermaidFile, topologySVGFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	o := &Orchestrator{}
+	artifact := o.buildArtifact("test", envState, nil)
+	if artifact.Files["testenv-vm.topology.mermaid"] != topologyMermaidFile ||
+		artifact.Files["testenv-vm.topology.svg"] != topologySVGFile {
+		t.Errorf("artifact files = %v, want topology entries", artifact.Files)
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// MaxForks bounds the number of forks created by one Fork call.
+const MaxForks = 64
+
+// ForkResult is the outcome of Fork.
+type ForkResult struct {
+	// ParentID is the ID of the forked environment.
+	ParentID string `json:"parentId"`
+	// BaseImages maps the VMs of the parent to their frozen disks, which
+	// back the disks of the forks.
+	BaseImages map[string]string `json:"baseImages"`
+	// Forks lists the forks in the order of their IDs.
+	Forks []ForkOutcome `json:"forks"`
+}
+
+// ForkOutcome is the outcome of the creation of one fork.
+type ForkOutcome struct {
+	// EnvironmentID is the ID of the fork.
+	EnvironmentID string `json:"environmentId"`
+	// Artifact is the artifact of the fork when it was created.
+	Artifact *v1.TestEnvArtifact `json:"artifact,omitempty"`
+	// Error is the creation error of the fork, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// Fork creates count copy-on-write copies of a ready environment, e.g. to
+// run test shards in parallel. The disk of every VM of the parent is frozen
+// with the vm_snapshot tool of its provider, and each fork is created from
+// the parent's spec with its VMs booting from overlays of the frozen disks.
+// Forks get their own networks, remapped to their own subnets like any
+// environment, and record the parent as their parent environment, which
+// keeps the parent, and the frozen disks, from being deleted while they
+// exist. Forks are created concurrently, and a fork that cannot be created
+// is reported with its error in the result.
+func (o *Orchestrator) Fork(ctx context.Context, environmentID string, count int, env map[string]string) (*ForkResult, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("fork rejected: %w", ErrReadOnly)
+	}
+	if count < 1 || count > MaxForks {
+		return nil, fmt.Errorf("fork count must be between 1 and %d, got %d", MaxForks, count)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fork rejected: %w", err)
+	}
+	defer end()
+	unlock, err := o.store.Lock(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	parent, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if parent.Status != v1.StatusReady {
+		return nil, fmt.Errorf("environment %q is %s, not %s", environmentID, parent.Status, v1.StatusReady)
+	}
+	if parent.Spec == nil {
+		return nil, fmt.Errorf("environment %q has no recorded spec", environmentID)
+	}
+	if parent.Spec.Parent.EnvironmentId != "" {
+		return nil, fmt.Errorf("environment %q has a parent environment and cannot be forked", environmentID)
+	}
+	ids, err := forkIDs(o.store, environmentID, count)
+	if err != nil {
+		return nil, err
+	}
+
+	baseImages, err := o.snapshotVMs(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ForkResult{ParentID: environmentID, BaseImages: baseImages, Forks: make([]ForkOutcome, len(ids))}
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		result.Forks[i].EnvironmentID = id
+		forkSpec, err := newForkSpec(parent.Spec, environmentID, id, baseImages)
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func(out *ForkOutcome, forkSpec *v1.Spec) {
+			defer wg.Done()
+			created, err := o.Create(ctx, &v1.CreateInput{
+				TestID: out.EnvironmentID,
+				Stage:  parent.Stage,
+				Spec:   forkSpec.ToMap(),
+				Env:    env,
+			})
+			if err != nil {
+				out.Error = err.Error()
+				return
+			}
+			out.Artifact = created.Artifact
+		}(&result.Forks[i], forkSpec)
+	}
+	wg.Wait()
+	return result, nil
+}
+
+// forkIDs returns count free environment IDs for forks of parentID, named
+// <parent>-fork-<n> with the lowest free n.
+func forkIDs(store *state.Store, parentID string, count int) ([]string, error) {
+	ids := make([]string, 0, count)
+	for n := 1; len(ids) < count; n++ {
+		id := fmt.Sprintf("%s-fork-%d", parentID, n)
+		if store.Exists(id) {
+			continue
+		}
+		if err := ValidateEnvironmentID(id); err != nil {
+			return nil, fmt.Errorf("cannot name forks of %q: %w", parentID, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// snapshotVMs freezes the disks of the VMs of a spec and returns their paths
+// by VM name. The new disk paths reported by the providers are recorded in
+// the environment state, so the frozen disks are deleted with the VMs.
+func (o *Orchestrator) snapshotVMs(envState *v1.EnvironmentState) (map[string]string, error) {
+	vms := make([]string, 0, len(envState.Spec.Vms))
+	for _, vm := range envState.Spec.Vms {
+		vmState := envState.Resources.VMs[vm.Name]
+		if vmState == nil || vmState.Status != v1.StatusReady {
+			return nil, fmt.Errorf("vm %q of environment %q is not ready", vm.Name, envState.ID)
+		}
+		if err := o.ensureProvider(envState, vmState.Provider); err != nil {
+			return nil, err
+		}
+		if !o.manager.SupportsOperation(vmState.Provider, "vm", "snapshot") {
+			return nil, fmt.Errorf("provider %q does not support snapshotting vm resources", vmState.Provider)
+		}
+		vms = append(vms, vm.Name)
+	}
+	sort.Strings(vms)
+
+	baseImages := make(map[string]string, len(vms))
+	var err error
+	for _, name := range vms {
+		vmState := envState.Resources.VMs[name]
+		log.Printf("Snapshotting vm %q of environment %q", name, envState.ID)
+		var result *providerv1.OperationResult
+		result, err = o.manager.Call(vmState.Provider, providerv1.VMSnapshotTool, &providerv1.VMSnapshotRequest{
+			Name: getString(vmState.State, "name"),
+		})
+		if err = operationError(providerv1.VMSnapshotTool, result, err); err != nil {
+			err = fmt.Errorf("failed to snapshot vm %q: %w", name, err)
+			break
+		}
+		var baseImage string
+		if baseImage, err = decodeVMSnapshot(result.Resource); err != nil {
+			err = fmt.Errorf("failed to snapshot vm %q: %w", name, err)
+			break
+		}
+		baseImages[name] = baseImage
+
+		// Record the overlay the VM now runs on
+		result, err = o.manager.Call(vmState.Provider, "vm_get", &providerv1.GetRequest{Name: getString(vmState.State, "name")})
+		if err = operationError("vm_get", result, err); err == nil {
+			var state map[string]any
+			if state, err = o.executor.convertResourceToMap(result.Resource); err == nil {
+				vmState.State = state
+				vmState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to refresh vm %q after its snapshot: %w", name, err)
+			break
+		}
+	}
+
+	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if saveErr := o.store.Save(envState); saveErr != nil && err == nil {
+		err = fmt.Errorf("failed to save state: %w", saveErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return baseImages, nil
+}
+
+// decodeVMSnapshot returns the frozen disk of a vm_snapshot result.
+func decodeVMSnapshot(resource any) (string, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return "", err
+	}
+	var snap providerv1.VMSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return "", fmt.Errorf("invalid vm snapshot: %w", err)
+	}
+	if snap.BaseImage == "" {
+		return "", errors.New("invalid vm snapshot: missing baseImage")
+	}
+	return snap.BaseImage, nil
+}
+
+// newForkSpec returns the spec of a fork: a copy of the parent's spec with
+// the fork's ID, the parent as parent environment and the VMs booting from
+// the frozen disks of the parent.
+func newForkSpec(parentSpec *v1.Spec, parentID, forkID string, baseImages map[string]string) (*v1.Spec, error) {
+	forkSpec, err := v1.SpecFromMap(parentSpec.ToMap())
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy spec of %q: %w", parentID, err)
+	}
+	forkSpec.EnvironmentId = forkID
+	forkSpec.EnvironmentIdTemplate = ""
+	forkSpec.SpecRef = ""
+	// Forks run beside the parent and each other: their VMs need UUIDs and
+	// MAC addresses of their own.
+	forkSpec.Seed = ""
+	forkSpec.Parent = v1.ParentSpec{EnvironmentId: parentID}
+	for i := range forkSpec.Vms {
+		baseImage, ok := baseImages[forkSpec.Vms[i].Name]
+		if !ok {
+			return nil, fmt.Errorf("vm %q of %q has no frozen disk", forkSpec.Vms[i].Name, parentID)
+		}
+		forkSpec.Vms[i].Spec.Disk.BaseImage = baseImage
+	}
+	return forkSpec, nil
+}
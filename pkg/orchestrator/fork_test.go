@@ -0,0 +1,141 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestForkIDs(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if err := o.store.Save(&v1.EnvironmentState{ID: "lab-fork-2", Status: v1.StatusReady}); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := forkIDs(o.store, "lab", 3)
+	if err != nil {
+		t.Fatalf("forkIDs() error = %v", err)
+	}
+	if want := []string{"lab-fork-1", "lab-fork-3", "lab-fork-4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("forkIDs() = %v, want %v", ids, want)
+	}
+	if _, err := forkIDs(o.store, strings.Repeat("a", maxEnvironmentIDLength), 1); err == nil {
+		t.Error("forkIDs() should reject IDs longer than the limit")
+	}
+}
+
+func TestNewForkSpec(t *testing.T) {
+	parentSpec := &v1.Spec{
+		EnvironmentIdTemplate: "lab-{{ .TestID }}",
+		Seed:                  "42",
+		Providers:             []v1.ProviderConfig{{Name: "libvirt", Engine: "go://libvirt", Default: true}},
+		Networks:              []v1.NetworkResource{{Name: "net", Spec: v1.NetworkSpec{Cidr: "10.10.0.0/24"}}},
+		Vms: []v1.VMResource{{
+			Name: "node",
+			Spec: v1.VMSpec{Network: "net", Disk: v1.DiskSpec{BaseImage: "{{ .Images.ubuntu.Path }}", Size: "10G"}},
+		}},
+	}
+	forkSpec, err := newForkSpec(parentSpec, "lab", "lab-fork-1", map[string]string{"node": "/disks/node.qcow2"})
+	if err != nil {
+		t.Fatalf("newForkSpec() error = %v", err)
+	}
+	if forkSpec.EnvironmentId != "lab-fork-1" || forkSpec.EnvironmentIdTemplate != "" || forkSpec.Seed != "" {
+		t.Errorf("newForkSpec() ID = %q, template = %q, seed = %q", forkSpec.EnvironmentId, forkSpec.EnvironmentIdTemplate, forkSpec.Seed)
+	}
+	if forkSpec.Parent.EnvironmentId != "lab" || len(forkSpec.Parent.Keys)+len(forkSpec.Parent.Networks) != 0 {
+		t.Errorf("newForkSpec() parent = %+v", forkSpec.Parent)
+	}
+	if disk := forkSpec.Vms[0].Spec.Disk; disk.BaseImage != "/disks/node.qcow2" || disk.Size != "10G" {
+		t.Errorf("newForkSpec() disk = %+v", disk)
+	}
+	if parentSpec.Vms[0].Spec.Disk.BaseImage != "{{ .Images.ubuntu.Path }}" {
+		t.Error("newForkSpec() modified the parent spec")
+	}
+
+	if _, err := newForkSpec(parentSpec, "lab", "lab-fork-1", nil); err == nil {
+		t.Error("newForkSpec() should fail without a frozen disk for each VM")
+	}
+}
+
+func TestDecodeVMSnapshot(t *testing.T) {
+	got, err := decodeVMSnapshot(providerv1.VMSnapshot{Name: "node", BaseImage: "/disks/node.qcow2"})
+	if err != nil || got != "/disks/node.qcow2" {
+		t.Errorf("decodeVMSnapshot() = %q, %v", got, err)
+	}
+	if _, err := decodeVMSnapshot(map[string]any{"name": "node"}); err == nil {
+		t.Error("decodeVMSnapshot() should fail without baseImage")
+	}
+}
+
+func TestOrchestrator_ForkRejected(t *testing.T) {
+	cfg := newTestConfig(t)
+	o, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	saveParent(t, o, v1.StatusReady)
+	if err := o.store.Save(&v1.EnvironmentState{ID: "failed-lab", Status: v1.StatusFailed, Spec: &v1.Spec{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.store.Save(&v1.EnvironmentState{ID: "child", Status: v1.StatusReady, Spec: childSpec()}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		id    string
+		count int
+		want  string
+	}{
+		{name: "zero count", id: "base-lab", count: 0, want: "fork count"},
+		{name: "too many", id: "base-lab", count: MaxForks + 1, want: "fork count"},
+		{name: "missing", id: "missing", count: 1, want: "failed to load"},
+		{name: "not ready", id: "failed-lab", count: 1, want: "not ready"},
+		{name: "nested", id: "child", count: 1, want: "has a parent environment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.Fork(context.Background(), tt.id, tt.count, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Fork() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	cfg.ReadOnly = true
+	ro, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer ro.Close()
+	if _, err := ro.Fork(context.Background(), "base-lab", 1, nil); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Fork() error = %v, want %v", err, ErrReadOnly)
+	}
+}
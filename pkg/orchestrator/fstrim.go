@@ -0,0 +1,40 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"slices"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// fstrimCommands trim the guest filesystems once cloud-init has installed
+// its packages, then keep them trimmed with the weekly timer of util-linux.
+// Failures are ignored: guests without fstrim boot as before.
+var fstrimCommands = []string{
+	"fstrim --all --verbose || true",
+	"systemctl enable --now fstrim.timer || true",
+}
+
+// injectFstrim appends fstrimCommands to the cloud-init of a VM. Disks are
+// attached with discard=unmap, so the blocks trimmed in the guest are
+// released from the sparse image on the host. The user commands are copied
+// as they may share their array with the spec.
+func injectFstrim(vmSpec *providerv1.VMSpec) {
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	vmSpec.CloudInit.Runcmd = slices.Concat(vmSpec.CloudInit.Runcmd, fstrimCommands)
+}
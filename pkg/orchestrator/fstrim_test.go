@@ -0,0 +1,53 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"slices"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestExecutor_convertVMSpec_Fstrim(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	plain := executor.convertVMSpec(v1.VMSpec{Disk: v1.DiskSpec{Size: "10G"}})
+	if plain.CloudInit != nil {
+		t.Errorf("CloudInit = %+v, want nil without fstrim", plain.CloudInit)
+	}
+
+	runcmd := make([]string, 1, 2)
+	runcmd[0] = "echo user"
+	vmSpec := v1.VMSpec{
+		Disk:      v1.DiskSpec{Size: "10G", Fstrim: true},
+		CloudInit: v1.CloudInitSpec{Runcmd: runcmd},
+	}
+	result := executor.convertVMSpec(vmSpec)
+	want := append([]string{"echo user"}, fstrimCommands...)
+	if result.CloudInit == nil || !slices.Equal(result.CloudInit.Runcmd, want) {
+		t.Fatalf("CloudInit = %+v, want runcmd %q", result.CloudInit, want)
+	}
+	if spare := runcmd[:2][1]; spare != "" {
+		t.Errorf("spec runcmd array modified: %q", spare)
+	}
+
+	noCloudInit := executor.convertVMSpec(v1.VMSpec{Disk: v1.DiskSpec{Size: "10G", Fstrim: true}})
+	if noCloudInit.CloudInit == nil || !slices.Equal(noCloudInit.CloudInit.Runcmd, fstrimCommands) {
+		t.Errorf("CloudInit = %+v, want the fstrim commands", noCloudInit.CloudInit)
+	}
+}
@@ -0,0 +1,321 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// GCOptions configures GarbageCollect.
+type GCOptions struct {
+	// Providers are searched for orphans besides the providers of the specs
+	// of the recorded environments, e.g. when no environment is left.
+	Providers []v1.ProviderConfig
+	// DryRun reports the orphans without deleting them.
+	DryRun bool
+}
+
+// Orphan is a resource no environment owns.
+type Orphan struct {
+	// Provider is the provider of the resource, empty for subnets of the
+	// CIDR pool.
+	Provider string `json:"provider,omitempty"`
+	// Kind is key, network, vm or cidr.
+	Kind string `json:"kind"`
+	// Name is the provider-level name of the resource, or the subnet.
+	Name string `json:"name"`
+	// EnvironmentID is the environment the resource was created for.
+	EnvironmentID string `json:"environmentId,omitempty"`
+	// Deleted reports whether the resource was deleted.
+	Deleted bool `json:"deleted"`
+	// Error is why the resource could not be deleted.
+	Error string `json:"error,omitempty"`
+}
+
+// GCResult is the result of GarbageCollect.
+type GCResult struct {
+	// Orphans are the resources found without owner, VMs first.
+	Orphans []Orphan `json:"orphans"`
+	// DryRun reports whether the orphans were left in place.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Errors are the providers that could not be searched.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// GarbageCollect deletes the resources left by crashed runs: the keys,
+// networks and VMs that providers find on the host (see
+// providerv1.FilterHost) without any environment owning them, and the
+// subnets of the CIDR pool allocated to environments that no longer exist.
+// Only resources labeled with the state directory of the orchestrator (see
+// providerv1.LabelStateDir) are candidates: those of other state
+// directories, other tenants, and resources that are not testenv-vm's are
+// left alone. A candidate is owned when it is recorded in the state of an
+// environment, or when its environment label points to an environment with
+// state or whose lock is held, such as one being created. The owners are
+// read again once the providers are searched, so that resources of
+// environments created meanwhile are not deleted.
+func (o *Orchestrator) GarbageCollect(ctx context.Context, opts GCOptions) (*GCResult, error) {
+	if o.config.ReadOnly && !opts.DryRun {
+		return nil, fmt.Errorf("garbage collection rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("garbage collection rejected: %w", err)
+	}
+	defer end()
+
+	owners, err := o.resourceOwners()
+	if err != nil {
+		return nil, err
+	}
+	providers := opts.Providers
+	for _, p := range owners.providers {
+		if !slices.ContainsFunc(providers, func(q v1.ProviderConfig) bool { return q.Name == p.Name }) {
+			providers = append(providers, p)
+		}
+	}
+
+	result := &GCResult{Orphans: []Orphan{}, DryRun: opts.DryRun}
+	var orphans []Orphan
+	for _, providerCfg := range providers {
+		found, err := o.providerOrphans(providerCfg, owners)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("provider %q: %v", providerCfg.Name, err))
+			continue
+		}
+		orphans = append(orphans, found...)
+	}
+	// An environment may have been created or recorded while the providers
+	// were searched: read the owners again so that its resources are kept
+	owners, err = o.resourceOwners()
+	if err != nil {
+		return nil, err
+	}
+	orphans = slices.DeleteFunc(orphans, owners.owns)
+	// VMs use networks and keys, so they go first. The orphans of a kind
+	// are deleted with one batch call per provider.
+	for _, kind := range []string{"vm", "network", "key"} {
+		var providerNames []string
+		byProvider := make(map[string][]Orphan)
+		for _, orphan := range orphans {
+			if orphan.Kind != kind {
+				continue
+			}
+			if _, ok := byProvider[orphan.Provider]; !ok {
+				providerNames = append(providerNames, orphan.Provider)
+			}
+			byProvider[orphan.Provider] = append(byProvider[orphan.Provider], orphan)
+		}
+		for _, name := range providerNames {
+			batch := byProvider[name]
+			if !opts.DryRun {
+				o.deleteOrphans(ctx, name, batch)
+			}
+			result.Orphans = append(result.Orphans, batch...)
+		}
+	}
+
+	cidrs, err := o.orphanedCIDRs(owners, opts.DryRun)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+	}
+	result.Orphans = append(result.Orphans, cidrs...)
+	return result, nil
+}
+
+// resourceOwners records who owns the resources of the host.
+type resourceOwners struct {
+	// envs are the IDs of the environments with state or a held lock.
+	envs map[string]bool
+	// stateDir is the providerv1.LabelStateDir label of the resources of
+	// the state directory.
+	stateDir string
+	// names are the provider-level names recorded in the states, by kind.
+	names map[string]map[string]bool
+	// providers are the providers of the recorded specs, by first use.
+	providers []v1.ProviderConfig
+	// tenant is the tenant of the state directory, if any.
+	tenant string
+}
+
+// resourceOwners reads the environments of the state directory.
+func (o *Orchestrator) resourceOwners() (*resourceOwners, error) {
+	ids, err := o.store.List()
+	if err != nil {
+		return nil, err
+	}
+	locked, err := o.store.Locked()
+	if err != nil {
+		return nil, err
+	}
+	owners := &resourceOwners{
+		envs:     make(map[string]bool),
+		stateDir: stateDirLabel(o.config.StateDir),
+		names:    map[string]map[string]bool{"key": {}, "network": {}, "vm": {}},
+		tenant:   o.config.Tenant,
+	}
+	for _, id := range append(ids, locked...) {
+		owners.envs[id] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		envState, err := o.store.Load(id)
+		if err != nil {
+			// The environment is still known by its ID
+			log.Printf("Failed to load environment %q: %v", id, err)
+			continue
+		}
+		for kind, resources := range map[string]map[string]*v1.ResourceState{
+			"key":     envState.Resources.Keys,
+			"network": envState.Resources.Networks,
+			"vm":      envState.Resources.VMs,
+		} {
+			for _, rs := range resources {
+				if name := getString(rs.State, "name"); name != "" {
+					owners.names[kind][name] = true
+				}
+			}
+		}
+		if envState.Spec == nil {
+			continue
+		}
+		for _, p := range envState.Spec.Providers {
+			if !seen[p.Name] {
+				seen[p.Name] = true
+				owners.providers = append(owners.providers, p)
+			}
+		}
+	}
+	return owners, nil
+}
+
+// orphaned reports whether a resource is an orphan, and the environment it
+// was created for.
+func (owners *resourceOwners) orphaned(kind, name string, labels map[string]string) (string, bool) {
+	envID := labels[providerv1.LabelEnvironmentID]
+	if envID == "" || labels[providerv1.LabelStateDir] != owners.stateDir || owners.names[kind][name] {
+		return "", false
+	}
+	return envID, !owners.envs[envID]
+}
+
+// owns reports whether an orphan found earlier has since been recorded in
+// the state of an environment, or its environment has state or a held lock.
+func (owners *resourceOwners) owns(orphan Orphan) bool {
+	return owners.names[orphan.Kind][orphan.Name] || owners.envs[orphan.EnvironmentID]
+}
+
+// providerOrphans lists the keys, networks and VMs a provider finds on the
+// host and returns those without owner.
+func (o *Orchestrator) providerOrphans(providerCfg v1.ProviderConfig, owners *resourceOwners) ([]Orphan, error) {
+	if _, exists := o.manager.GetInfo(providerCfg.Name); !exists {
+		if err := o.manager.Start(providerCfg); err != nil {
+			return nil, fmt.Errorf("failed to start provider: %w", err)
+		}
+	}
+
+	var orphans []Orphan
+	for _, kind := range []string{"key", "network", "vm"} {
+		tool := kind + "_list"
+		result, err := o.manager.Call(providerCfg.Name, tool, &providerv1.ListRequest{
+			Filter: map[string]any{providerv1.FilterHost: true},
+		})
+		if err := operationError(tool, result, err); err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(result.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("invalid result of %s: %w", tool, err)
+		}
+		var items []struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, fmt.Errorf("invalid result of %s: %w", tool, err)
+		}
+		for _, item := range items {
+			if envID, orphan := owners.orphaned(kind, item.Name, item.Labels); orphan {
+				orphans = append(orphans, Orphan{Provider: providerCfg.Name, Kind: kind, Name: item.Name, EnvironmentID: envID})
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// deleteOrphans deletes orphans of one provider with provider.Manager.CallBatch
+// and records the outcome of each in it.
+func (o *Orchestrator) deleteOrphans(ctx context.Context, providerName string, orphans []Orphan) {
+	if ctx.Err() != nil {
+		for i := range orphans {
+			orphans[i].Error = ctx.Err().Error()
+		}
+		return
+	}
+	calls := make([]providerv1.BatchCall, len(orphans))
+	for i, orphan := range orphans {
+		calls[i] = providerv1.BatchCall{
+			Tool:  orphan.Kind + "_delete",
+			Input: map[string]any{"name": orphan.Name, "force": true},
+		}
+	}
+	results, err := o.manager.CallBatch(providerName, calls)
+	for i := range orphans {
+		var res *providerv1.OperationResult
+		if err == nil {
+			res = &results[i]
+		}
+		if err := operationError(calls[i].Tool, res, err); err != nil {
+			orphans[i].Error = err.Error()
+			continue
+		}
+		orphans[i].Deleted = true
+		log.Printf("Deleted orphaned %s %q of provider %q", orphans[i].Kind, orphans[i].Name, providerName)
+	}
+}
+
+// orphanedCIDRs releases the subnets of the CIDR pool allocated to unknown
+// environments of the tenant, unless dryRun is set.
+func (o *Orchestrator) orphanedCIDRs(owners *resourceOwners, dryRun bool) ([]Orphan, error) {
+	pool := o.cidrPool()
+	allocations, err := pool.list()
+	if err != nil {
+		return nil, err
+	}
+	var orphans []Orphan
+	for _, a := range allocations {
+		if a.Tenant != owners.tenant || owners.envs[a.EnvironmentID] {
+			continue
+		}
+		orphan := Orphan{Kind: "cidr", Name: a.CIDR, EnvironmentID: a.EnvironmentID}
+		if !dryRun {
+			if err := pool.release(a.EnvironmentID, nil); err != nil {
+				orphan.Error = err.Error()
+			} else {
+				orphan.Deleted = true
+			}
+		}
+		orphans = append(orphans, orphan)
+	}
+	return orphans, nil
+}
@@ -0,0 +1,207 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestResourceOwners_orphaned(t *testing.T) {
+	owners := &resourceOwners{
+		envs:     map[string]bool{"env-1": true},
+		stateDir: "0123abcd",
+		names:    map[string]map[string]bool{"vm": {"recorded": true}},
+	}
+	labels := func(envID, stateDir string) map[string]string {
+		return map[string]string{providerv1.LabelEnvironmentID: envID, providerv1.LabelStateDir: stateDir}
+	}
+
+	tests := []struct {
+		name       string
+		resource   string
+		labels     map[string]string
+		wantEnv    string
+		wantOrphan bool
+	}{
+		{"recorded", "recorded", labels("env-2", "0123abcd"), "", false},
+		{"known environment", "web", labels("env-1", "0123abcd"), "env-1", false},
+		{"unknown environment", "web", labels("env-2", "0123abcd"), "env-2", true},
+		{"other state directory", "web", labels("env-2", "89abcdef"), "", false},
+		{"without state directory", "web", map[string]string{providerv1.LabelEnvironmentID: "env-2"}, "", false},
+		{"without environment", "web", map[string]string{providerv1.LabelStateDir: "0123abcd"}, "", false},
+		{"unlabeled", shortHash("env-2") + "-web", nil, "", false},
+		{"dated name", "db-20240101-primary", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envID, orphan := owners.orphaned("vm", tt.resource, tt.labels)
+			if envID != tt.wantEnv || orphan != tt.wantOrphan {
+				t.Errorf("orphaned(%q) = %q, %v, want %q, %v", tt.resource, envID, orphan, tt.wantEnv, tt.wantOrphan)
+			}
+		})
+	}
+}
+
+func TestResourceOwners_owns(t *testing.T) {
+	owners := &resourceOwners{
+		envs:  map[string]bool{"env-1": true},
+		names: map[string]map[string]bool{"vm": {"recorded": true}},
+	}
+
+	tests := []struct {
+		name   string
+		orphan Orphan
+		want   bool
+	}{
+		{"recorded since", Orphan{Kind: "vm", Name: "recorded", EnvironmentID: "env-2"}, true},
+		{"environment created since", Orphan{Kind: "vm", Name: "web", EnvironmentID: "env-1"}, true},
+		{"still unknown", Orphan{Kind: "vm", Name: "web", EnvironmentID: "env-2"}, false},
+		{"recorded name of another kind", Orphan{Kind: "key", Name: "recorded", EnvironmentID: "env-2"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := owners.owns(tt.orphan); got != tt.want {
+				t.Errorf("owns(%+v) = %v, want %v", tt.orphan, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_GarbageCollect(t *testing.T) {
+	config := newTestConfig(t)
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if err := o.store.Save(&v1.EnvironmentState{ID: "env-1", Status: v1.StatusReady}); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := o.store.Lock(context.Background(), "creating")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+	pool := o.cidrPool()
+	for _, envID := range []string{"env-1", "creating", "gone"} {
+		if _, err := pool.allocate(envID, "lan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := o.GarbageCollect(context.Background(), GCOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("GarbageCollect() error = %v", err)
+	}
+	if len(result.Orphans) != 1 || result.Orphans[0].EnvironmentID != "gone" || result.Orphans[0].Deleted {
+		t.Fatalf("GarbageCollect(dry run) orphans = %+v, want the subnet of gone, kept", result.Orphans)
+	}
+	if allocations, _ := pool.list(); len(allocations) != 3 {
+		t.Errorf("dry run released subnets: %+v", allocations)
+	}
+
+	result, err = o.GarbageCollect(context.Background(), GCOptions{})
+	if err != nil {
+		t.Fatalf("GarbageCollect() error = %v", err)
+	}
+	if len(result.Orphans) != 1 || !result.Orphans[0].Deleted {
+		t.Fatalf("GarbageCollect() orphans = %+v, want the subnet of gone, deleted", result.Orphans)
+	}
+	allocations, _ := pool.list()
+	if len(allocations) != 2 {
+		t.Errorf("allocations after GarbageCollect() = %+v, want those of env-1 and creating", allocations)
+	}
+
+	config.ReadOnly = true
+	readOnly, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer readOnly.Close()
+	if _, err := readOnly.GarbageCollect(context.Background(), GCOptions{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("GarbageCollect() in read-only mode error = %v, want ErrReadOnly", err)
+	}
+	if _, err := readOnly.GarbageCollect(context.Background(), GCOptions{DryRun: true}); err != nil {
+		t.Errorf("GarbageCollect(dry run) in read-only mode error = %v", err)
+	}
+}
+
+func TestOrchestrator_GarbageCollect_ProviderResources(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	engine := filepath.Join(t.TempDir(), "testenv-vm-provider-stub")
+	if out, err := exec.Command("go", "build", "-o", engine, "../../cmd/providers/testenv-vm-provider-stub").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build the stub provider: %v\n%s", err, out)
+	}
+	config := newTestConfig(t)
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	providerCfg := v1.ProviderConfig{Name: "stub", Engine: engine}
+	if err := o.manager.Start(providerCfg); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := o.store.Save(&v1.EnvironmentState{ID: "env-1", Status: v1.StatusReady}); err != nil {
+		t.Fatal(err)
+	}
+	stateDir := stateDirLabel(config.StateDir)
+	for name, envID := range map[string]string{"kept": "env-1", "gone-1": "gone", "gone-2": "gone"} {
+		_, err := o.manager.Call("stub", "key_create", &providerv1.KeyCreateRequest{
+			Name:   name,
+			Spec:   providerv1.KeySpec{Type: "ed25519"},
+			Labels: map[string]string{providerv1.LabelEnvironmentID: envID, providerv1.LabelStateDir: stateDir},
+		})
+		if err != nil {
+			t.Fatalf("key_create error = %v", err)
+		}
+	}
+
+	result, err := o.GarbageCollect(context.Background(), GCOptions{Providers: []v1.ProviderConfig{providerCfg}})
+	if err != nil {
+		t.Fatalf("GarbageCollect() error = %v", err)
+	}
+	deleted := map[string]bool{}
+	for _, orphan := range result.Orphans {
+		if orphan.Kind == "key" && orphan.Deleted {
+			deleted[orphan.Name] = true
+		}
+	}
+	if len(deleted) != 2 || !deleted["gone-1"] || !deleted["gone-2"] {
+		t.Errorf("GarbageCollect() orphans = %+v, want the keys of gone deleted", result.Orphans)
+	}
+	for name, wantExists := range map[string]bool{"kept": true, "gone-1": false, "gone-2": false} {
+		res, err := o.manager.Call("stub", "key_get", &providerv1.GetRequest{Name: name})
+		if err != nil {
+			t.Fatalf("key_get error = %v", err)
+		}
+		if res.Success != wantExists {
+			t.Errorf("key %q exists = %v, want %v", name, res.Success, wantExists)
+		}
+	}
+}
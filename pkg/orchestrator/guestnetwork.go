@@ -0,0 +1,168 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"slices"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// hasGuestNetworkConfig reports whether a VM sets vms[].spec.networkConfig.
+func hasGuestNetworkConfig(spec *v1.VMSpec) bool {
+	nc := spec.NetworkConfig
+	return len(nc.Ethernets) > 0 || len(nc.Bonds) > 0 || len(nc.Vlans) > 0
+}
+
+// guestNetworkConfig converts the networkConfig of a VM to the cloud-init
+// network config of the provider, resolving the network of each ethernet
+// interface to the index of its NIC. It returns nil when the VM has no
+// networkConfig. DHCP4 is only set when enabled, so that providers apply
+// their defaults to interfaces without addresses.
+func guestNetworkConfig(spec *v1.VMSpec, networks []string) *providerv1.CloudInitNetworkConfig {
+	if !hasGuestNetworkConfig(spec) {
+		return nil
+	}
+	nc := &providerv1.CloudInitNetworkConfig{}
+	for _, eth := range spec.NetworkConfig.Ethernets {
+		c := providerv1.CloudInitEthernetConfig{
+			Name:        eth.Name,
+			Match:       eth.Match,
+			DHCP4:       enabled(eth.Dhcp4),
+			Addresses:   eth.Addresses,
+			Gateway4:    eth.Gateway4,
+			Routes:      guestRoutes(eth.Routes),
+			Nameservers: guestNameservers(eth.Nameservers),
+			MTU:         eth.Mtu,
+		}
+		if i := slices.Index(networks, eth.Network); eth.Network != "" && i >= 0 {
+			c.NIC = &i
+		}
+		nc.Ethernets = append(nc.Ethernets, c)
+	}
+	for _, bond := range spec.NetworkConfig.Bonds {
+		nc.Bonds = append(nc.Bonds, providerv1.CloudInitBondConfig{
+			Name:        bond.Name,
+			Interfaces:  bond.Interfaces,
+			Mode:        bond.Mode,
+			DHCP4:       enabled(bond.Dhcp4),
+			Addresses:   bond.Addresses,
+			Gateway4:    bond.Gateway4,
+			Routes:      guestRoutes(bond.Routes),
+			Nameservers: guestNameservers(bond.Nameservers),
+			MTU:         bond.Mtu,
+		})
+	}
+	for _, vlan := range spec.NetworkConfig.Vlans {
+		nc.VLANs = append(nc.VLANs, providerv1.CloudInitVLANConfig{
+			Name:        vlan.Name,
+			ID:          vlan.Id,
+			Link:        vlan.Link,
+			DHCP4:       enabled(vlan.Dhcp4),
+			Addresses:   vlan.Addresses,
+			Gateway4:    vlan.Gateway4,
+			Routes:      guestRoutes(vlan.Routes),
+			Nameservers: guestNameservers(vlan.Nameservers),
+			MTU:         vlan.Mtu,
+		})
+	}
+	return nc
+}
+
+// enabled returns a pointer to true when b is set, else nil.
+func enabled(b bool) *bool {
+	if !b {
+		return nil
+	}
+	return &b
+}
+
+func guestRoutes(routes []v1.GuestRouteSpec) []providerv1.CloudInitRoute {
+	var result []providerv1.CloudInitRoute
+	for _, r := range routes {
+		result = append(result, providerv1.CloudInitRoute{To: r.To, Via: r.Via, Metric: r.Metric})
+	}
+	return result
+}
+
+func guestNameservers(ns v1.GuestNameserversSpec) *providerv1.CloudInitNameservers {
+	if len(ns.Addresses) == 0 && len(ns.Search) == 0 {
+		return nil
+	}
+	return &providerv1.CloudInitNameservers{Addresses: ns.Addresses, Search: ns.Search}
+}
+
+// rewriteNetworkConfig rewrites the addresses, gateways, routes and
+// nameservers of a network config from the original CIDR prefix of an
+// isolated environment to its new one. Slices are copied as they may share
+// their array with the spec.
+func rewriteNetworkConfig(nc *providerv1.CloudInitNetworkConfig, oldPrefix, newPrefix string) {
+	if nc == nil {
+		return
+	}
+	rewrite := func(s string) string { return strings.ReplaceAll(s, oldPrefix, newPrefix) }
+	for i := range nc.Ethernets {
+		eth := &nc.Ethernets[i]
+		eth.Addresses = rewriteAll(eth.Addresses, rewrite)
+		eth.Gateway4 = rewrite(eth.Gateway4)
+		eth.Routes = rewriteRoutes(eth.Routes, rewrite)
+		eth.Nameservers = rewriteNameservers(eth.Nameservers, rewrite)
+	}
+	for i := range nc.Bonds {
+		bond := &nc.Bonds[i]
+		bond.Addresses = rewriteAll(bond.Addresses, rewrite)
+		bond.Gateway4 = rewrite(bond.Gateway4)
+		bond.Routes = rewriteRoutes(bond.Routes, rewrite)
+		bond.Nameservers = rewriteNameservers(bond.Nameservers, rewrite)
+	}
+	for i := range nc.VLANs {
+		vlan := &nc.VLANs[i]
+		vlan.Addresses = rewriteAll(vlan.Addresses, rewrite)
+		vlan.Gateway4 = rewrite(vlan.Gateway4)
+		vlan.Routes = rewriteRoutes(vlan.Routes, rewrite)
+		vlan.Nameservers = rewriteNameservers(vlan.Nameservers, rewrite)
+	}
+}
+
+func rewriteAll(values []string, rewrite func(string) string) []string {
+	if values == nil {
+		return nil
+	}
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = rewrite(v)
+	}
+	return result
+}
+
+func rewriteRoutes(routes []providerv1.CloudInitRoute, rewrite func(string) string) []providerv1.CloudInitRoute {
+	if routes == nil {
+		return nil
+	}
+	result := make([]providerv1.CloudInitRoute, len(routes))
+	for i, r := range routes {
+		result[i] = providerv1.CloudInitRoute{To: rewrite(r.To), Via: rewrite(r.Via), Metric: r.Metric}
+	}
+	return result
+}
+
+func rewriteNameservers(ns *providerv1.CloudInitNameservers, rewrite func(string) string) *providerv1.CloudInitNameservers {
+	if ns == nil {
+		return nil
+	}
+	return &providerv1.CloudInitNameservers{Addresses: rewriteAll(ns.Addresses, rewrite), Search: ns.Search}
+}
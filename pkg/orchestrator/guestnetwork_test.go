@@ -0,0 +1,84 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestExecutor_convertVMSpec_GuestNetwork(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	plain := executor.convertVMSpec(v1.VMSpec{Disk: v1.DiskSpec{Size: "10G"}})
+	if plain.CloudInit != nil {
+		t.Errorf("CloudInit = %+v, want nil without networkConfig", plain.CloudInit)
+	}
+
+	addresses := []string{"192.168.100.10/24"}
+	result := executor.convertVMSpec(v1.VMSpec{
+		Networks: []string{"lan", "storage"},
+		NetworkConfig: v1.GuestNetworkConfigSpec{
+			Ethernets: []v1.GuestEthernetSpec{
+				{Name: "lan0", Network: "lan", Addresses: addresses, Gateway4: "192.168.100.1",
+					Nameservers: v1.GuestNameserversSpec{Addresses: []string{"192.168.100.1"}}},
+				{Name: "storage0", Network: "storage", Dhcp4: true},
+			},
+			Bonds: []v1.GuestBondSpec{{Name: "bond0", Interfaces: []string{"storage0"}, Mode: "active-backup"}},
+			Vlans: []v1.GuestVLANSpec{{Name: "vlan10", Id: 10, Link: "bond0",
+				Routes: []v1.GuestRouteSpec{{To: "10.0.0.0/8", Via: "192.168.100.254", Metric: 50}}}},
+		},
+	})
+	if result.CloudInit == nil || result.CloudInit.NetworkConfig == nil {
+		t.Fatalf("CloudInit = %+v, want a network config", result.CloudInit)
+	}
+	nc := result.CloudInit.NetworkConfig
+	if len(nc.Ethernets) != 2 || len(nc.Bonds) != 1 || len(nc.VLANs) != 1 {
+		t.Fatalf("network config = %+v, want 2 ethernets, 1 bond and 1 vlan", nc)
+	}
+	lan, storage := nc.Ethernets[0], nc.Ethernets[1]
+	if lan.NIC == nil || *lan.NIC != 0 || storage.NIC == nil || *storage.NIC != 1 {
+		t.Errorf("NICs = %v, %v, want 0 and 1", lan.NIC, storage.NIC)
+	}
+	if lan.DHCP4 != nil || storage.DHCP4 == nil || !*storage.DHCP4 {
+		t.Errorf("DHCP4 = %v, %v, want nil and true", lan.DHCP4, storage.DHCP4)
+	}
+	if lan.Nameservers == nil || lan.Nameservers.Addresses[0] != "192.168.100.1" {
+		t.Errorf("Nameservers = %+v", lan.Nameservers)
+	}
+	if storage.Nameservers != nil {
+		t.Errorf("Nameservers = %+v, want nil", storage.Nameservers)
+	}
+	if nc.Bonds[0].Mode != "active-backup" || nc.VLANs[0].ID != 10 || nc.VLANs[0].Routes[0].Metric != 50 {
+		t.Errorf("bond = %+v, vlan = %+v", nc.Bonds[0], nc.VLANs[0])
+	}
+
+	rewriteNetworkConfig(nc, "192.168.100.", "10.200.3.")
+	if nc.Ethernets[0].Addresses[0] != "10.200.3.10/24" || nc.Ethernets[0].Gateway4 != "10.200.3.1" {
+		t.Errorf("rewritten ethernet = %+v", nc.Ethernets[0])
+	}
+	if ns := nc.Ethernets[0].Nameservers.Addresses[0]; ns != "10.200.3.1" {
+		t.Errorf("rewritten nameserver = %q", ns)
+	}
+	if via := nc.VLANs[0].Routes[0].Via; via != "10.200.3.254" {
+		t.Errorf("rewritten route via = %q", via)
+	}
+	if addresses[0] != "192.168.100.10/24" {
+		t.Errorf("spec addresses modified: %q", addresses[0])
+	}
+}
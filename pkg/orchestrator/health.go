@@ -0,0 +1,131 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// healthProbeTimeout bounds the SSH probe of each VM.
+const healthProbeTimeout = 10 * time.Second
+
+// EnvironmentHealth is a stored environment with live checks of its VMs.
+type EnvironmentHealth struct {
+	// State is the stored state of the environment.
+	State *v1.EnvironmentState `json:"state"`
+	// VMs are the checks of each VM, sorted by name.
+	VMs []VMHealth `json:"vms"`
+	// Healthy is true when every VM is healthy.
+	Healthy bool `json:"healthy"`
+}
+
+// VMHealth compares what the state says about a VM with what its provider
+// and the VM itself answer.
+type VMHealth struct {
+	// Name is the VM name as declared in the spec.
+	Name string `json:"name"`
+	// Provider is the provider managing the VM.
+	Provider string `json:"provider"`
+	// RecordedStatus is the status of the VM in the state.
+	RecordedStatus string `json:"recordedStatus"`
+	// ProviderStatus is the status reported by vm_get, e.g. the libvirt
+	// domain state; empty when the provider could not be asked.
+	ProviderStatus string `json:"providerStatus,omitempty"`
+	ProviderError  string `json:"providerError,omitempty"`
+	// SSHReachable is true when a command ran over SSH.
+	SSHReachable bool   `json:"sshReachable"`
+	SSHError     string `json:"sshError,omitempty"`
+	// Healthy is true when the provider reports the VM running and SSH
+	// answers.
+	Healthy bool `json:"healthy"`
+}
+
+// EnvironmentHealth returns the state of a stored environment with live
+// checks of its VMs: the status reported by their provider and an SSH probe,
+// so that callers can tell a VM recorded as ready from one that is actually
+// reachable. VMs are checked concurrently. It only reads state and is
+// therefore available in read-only mode.
+func (o *Orchestrator) EnvironmentHealth(ctx context.Context, environmentID string) (*EnvironmentHealth, error) {
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+
+	health := &EnvironmentHealth{State: envState, VMs: make([]VMHealth, 0, len(envState.Resources.VMs))}
+	for name, vmState := range envState.Resources.VMs {
+		health.VMs = append(health.VMs, VMHealth{Name: name, Provider: vmState.Provider, RecordedStatus: vmState.Status})
+	}
+	sort.Slice(health.VMs, func(i, j int) bool { return health.VMs[i].Name < health.VMs[j].Name })
+
+	// Providers are started once, before the VMs are checked concurrently
+	providerErrs := make(map[string]error)
+	for _, h := range health.VMs {
+		if _, ok := providerErrs[h.Provider]; !ok {
+			providerErrs[h.Provider] = o.ensureProvider(envState, h.Provider)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := range health.VMs {
+		wg.Add(1)
+		go func(h *VMHealth) {
+			defer wg.Done()
+			o.checkVMHealth(ctx, envState, h, providerErrs[h.Provider])
+		}(&health.VMs[i])
+	}
+	wg.Wait()
+
+	health.Healthy = true
+	for _, h := range health.VMs {
+		health.Healthy = health.Healthy && h.Healthy
+	}
+	return health, nil
+}
+
+// checkVMHealth fills the live checks of h. providerErr is the error
+// starting its provider, if any.
+func (o *Orchestrator) checkVMHealth(ctx context.Context, envState *v1.EnvironmentState, h *VMHealth, providerErr error) {
+	if providerErr != nil {
+		h.ProviderError = providerErr.Error()
+	} else {
+		status, err := o.providerVMStatus(envState.Resources.VMs[h.Name])
+		if err != nil {
+			h.ProviderError = err.Error()
+		}
+		h.ProviderStatus = status
+	}
+
+	// The probe tests SSH itself, not the guest agent
+	c, err := o.vmClient(envState, h.Name, false)
+	if err != nil {
+		h.SSHError = err.Error()
+	} else {
+		defer func() { _ = c.Close() }()
+		probeCtx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+		defer cancel()
+		if _, _, err := c.Run(probeCtx, "true"); err != nil {
+			h.SSHError = err.Error()
+		} else {
+			h.SSHReachable = true
+		}
+	}
+
+	h.Healthy = h.ProviderStatus == "running" && h.SSHReachable
+}
@@ -0,0 +1,74 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOrchestrator_EnvironmentHealth(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, err := o.EnvironmentHealth(context.Background(), "missing"); err == nil {
+		t.Error("EnvironmentHealth() of a missing environment should fail")
+	}
+
+	// The state says ready, but the provider is unknown and there is no key
+	// to reach the VMs with.
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID:     "env-health",
+		Status: v1.StatusReady,
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{
+			"web": {Provider: "gone", Status: v1.StatusReady, State: map[string]any{"ip": "127.0.0.1"}},
+			"db":  {Provider: "gone", Status: v1.StatusReady, State: map[string]any{"ip": "127.0.0.1"}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	health, err := o.EnvironmentHealth(context.Background(), "env-health")
+	if err != nil {
+		t.Fatalf("EnvironmentHealth() error = %v", err)
+	}
+	if health.State == nil || health.State.ID != "env-health" {
+		t.Errorf("State = %+v, want the stored state", health.State)
+	}
+	if health.Healthy {
+		t.Error("Healthy = true, want false")
+	}
+	if len(health.VMs) != 2 || health.VMs[0].Name != "db" || health.VMs[1].Name != "web" {
+		t.Fatalf("VMs = %+v, want db and web", health.VMs)
+	}
+	for _, h := range health.VMs {
+		if h.RecordedStatus != v1.StatusReady || h.Healthy || h.SSHReachable {
+			t.Errorf("%s = %+v", h.Name, h)
+		}
+		if !strings.Contains(h.ProviderError, "not found") {
+			t.Errorf("%s ProviderError = %q", h.Name, h.ProviderError)
+		}
+		if h.SSHError == "" {
+			t.Errorf("%s SSHError is empty", h.Name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+)
+
+// hostsFile is the hosts file written to the artifact directory when the
+// spec sets hostsFile.
+const hostsFile = "hosts"
+
+// Markers of the block of /etc/hosts managed by pushHosts, replaced on every
+// push so that the other entries of the guest are kept.
+const (
+	hostsBlockBegin = "# BEGIN testenv-vm"
+	hostsBlockEnd   = "# END testenv-vm"
+)
+
+// renderHosts returns the hosts file of an environment: one line per VM with
+// a recorded IP, mapping it to the VM name, sorted by name.
+func renderHosts(envState *v1.EnvironmentState) string {
+	names := make([]string, 0, len(envState.Resources.VMs))
+	for name := range envState.Resources.VMs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if ip := getString(envState.Resources.VMs[name].State, "ip"); ip != "" {
+			fmt.Fprintf(&b, "%s %s\n", ip, name)
+		}
+	}
+	return b.String()
+}
+
+// hostsScript returns the shell script replacing the managed block of the
+// hosts file at path with entries.
+func hostsScript(path, entries string) string {
+	return fmt.Sprintf("sed -i '/^%s$/,/^%s$/d' %s && cat >> %s <<'TESTENV_VM_HOSTS'\n%s\n%s%s\nTESTENV_VM_HOSTS\n",
+		hostsBlockBegin, hostsBlockEnd, path, path, hostsBlockBegin, entries, hostsBlockEnd)
+}
+
+// pushHosts writes the hosts file of an environment to its artifact
+// directory and into /etc/hosts of all its VMs with an IP, concurrently. It
+// fails if any VM could not be updated.
+func (o *Orchestrator) pushHosts(ctx context.Context, envState *v1.EnvironmentState) error {
+	entries := renderHosts(envState)
+	if envState.ArtifactDir != "" {
+		path := filepath.Join(envState.ArtifactDir, hostsFile)
+		if err := os.WriteFile(path, []byte(entries), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	script := shellScript(hostsScript("/etc/hosts", entries))
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for name, vmState := range envState.Resources.VMs {
+		if getString(vmState.State, "ip") == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := o.pushHostsToVM(ctx, envState, name, script)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("vm %q: failed to update /etc/hosts: %w", name, err))
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// pushHostsToVM runs the script updating /etc/hosts on a VM.
+func (o *Orchestrator) pushHostsToVM(ctx context.Context, envState *v1.EnvironmentState, vmName string, script []string) error {
+	c, err := o.vmClient(envState, vmName, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultExecTimeout)
+	defer cancel()
+	execCtx := client.NewExecutionContext().WithPrivilegeEscalation(client.PrivilegeEscalationSudo())
+	log.Printf("Updating /etc/hosts of vm %q", vmName)
+	if _, stderr, err := c.RunWithContext(ctx, execCtx, script...); err != nil {
+		if stderr != "" {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr))
+		}
+		return err
+	}
+	return nil
+}
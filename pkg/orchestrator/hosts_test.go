@@ -0,0 +1,63 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestRenderHosts(t *testing.T) {
+	envState := &v1.EnvironmentState{}
+	envState.Resources.VMs = map[string]*v1.ResourceState{
+		"web":     {State: map[string]any{"ip": "192.168.100.11"}},
+		"db":      {State: map[string]any{"ip": "192.168.100.10"}},
+		"pending": {State: map[string]any{}},
+	}
+
+	want := "192.168.100.10 db\n192.168.100.11 web\n"
+	if got := renderHosts(envState); got != want {
+		t.Errorf("renderHosts() = %q, want %q", got, want)
+	}
+}
+
+func TestHostsScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("127.0.0.1 localhost\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second push replaces the block of the first one
+	for _, entries := range []string{"10.0.0.1 old\n", "10.0.0.2 db\n10.0.0.3 web\n"} {
+		if out, err := exec.Command("sh", "-c", hostsScript(path, entries)).CombinedOutput(); err != nil {
+			t.Fatalf("hosts script failed: %v: %s", err, out)
+		}
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "127.0.0.1 localhost\n# BEGIN testenv-vm\n10.0.0.2 db\n10.0.0.3 web\n# END testenv-vm\n"
+	if string(got) != want {
+		t.Errorf("hosts file = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,386 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// DefaultIdleInterval is how often RunIdlePolicies samples the VMs.
+const DefaultIdleInterval = time.Minute
+
+// wakeTimeout bounds the time a wake proxy waits for a VM it started to
+// accept connections.
+const wakeTimeout = 2 * time.Minute
+
+// wakePollInterval is the interval between connection attempts to a VM
+// being woken up.
+const wakePollInterval = time.Second
+
+// procNetTCPFiles list the TCP sockets of the host, read to count the SSH
+// sessions to a VM.
+var procNetTCPFiles = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// idleTracker records since when the VMs of the idle policies are idle, and
+// the connections their wake proxies forward, by environment ID and VM name.
+type idleTracker struct {
+	mu     sync.Mutex
+	since  map[string]time.Time
+	active map[string]int
+}
+
+// newIdleTracker returns an empty tracker.
+func newIdleTracker() *idleTracker {
+	return &idleTracker{since: make(map[string]time.Time), active: make(map[string]int)}
+}
+
+// observe records whether a VM is busy at now and returns for how long it
+// has been idle. A VM with proxied connections is always busy.
+func (t *idleTracker) observe(key string, busy bool, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if busy || t.active[key] > 0 {
+		delete(t.since, key)
+		return 0
+	}
+	since, ok := t.since[key]
+	if !ok {
+		t.since[key] = now
+		return 0
+	}
+	return now.Sub(since)
+}
+
+// reset forgets the idle time of a VM.
+func (t *idleTracker) reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.since, key)
+}
+
+// connect records a proxied connection to a VM and returns the function
+// recording its end.
+func (t *idleTracker) connect(key string) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.active[key]++
+	delete(t.since, key)
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.active[key]--; t.active[key] <= 0 {
+			delete(t.active, key)
+		}
+	}
+}
+
+// RunIdlePolicies applies the idle policies of the stored environments until
+// ctx is done, sampling their VMs every interval (DefaultIdleInterval if
+// zero). A running VM without SSH sessions from this host, with a CPU usage
+// below idle.cpuPercent and without proxied connections is idle; once idle
+// for idle.after, it is stopped or saved as idle.action says. The wake
+// proxies of the policies listen meanwhile, and start their VM on the next
+// connection before forwarding it.
+func (o *Orchestrator) RunIdlePolicies(ctx context.Context, interval time.Duration) error {
+	if o.config.ReadOnly {
+		return fmt.Errorf("idle policies rejected: %w", ErrReadOnly)
+	}
+	if interval <= 0 {
+		interval = DefaultIdleInterval
+	}
+	tracker := newIdleTracker()
+	proxies := make(map[string]net.Listener)
+	defer func() {
+		for _, l := range proxies {
+			_ = l.Close()
+		}
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := o.store.List()
+		if err != nil {
+			log.Printf("Failed to list environments: %v", err)
+		}
+		wanted := make(map[string]bool)
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				break
+			}
+			envState, err := o.store.Load(id)
+			if err != nil || envState.Status != v1.StatusReady || envState.Spec == nil || envState.Spec.Idle.After == "" {
+				continue
+			}
+			for _, w := range envState.Spec.Idle.Wake {
+				key := envState.ID + " " + w.Listen
+				wanted[key] = true
+				if proxies[key] != nil {
+					continue
+				}
+				l, err := o.listenWake(ctx, envState.ID, w, envState.Spec, tracker)
+				if err != nil {
+					log.Printf("Environment %s: failed to start wake proxy for vm %q: %v", envState.ID, w.Vm, err)
+					continue
+				}
+				proxies[key] = l
+			}
+			o.applyIdlePolicy(ctx, envState, tracker, time.Now())
+		}
+		// Proxies of deleted environments are closed
+		for key, l := range proxies {
+			if !wanted[key] {
+				_ = l.Close()
+				delete(proxies, key)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyIdlePolicy samples the running VMs of an environment and powers down
+// those idle for longer than its policy allows.
+func (o *Orchestrator) applyIdlePolicy(ctx context.Context, envState *v1.EnvironmentState, tracker *idleTracker, now time.Time) {
+	after, err := spec.IdleAfter(envState.Spec)
+	if err != nil || after <= 0 {
+		return
+	}
+	threshold := float64(spec.DefaultIdleCPUPercent)
+	if envState.Spec.Idle.CpuPercent > 0 {
+		threshold = float64(envState.Spec.Idle.CpuPercent)
+	}
+	sshPort := envState.Spec.Idle.SshPort
+	if sshPort == 0 {
+		sshPort = spec.DefaultIdleSSHPort
+	}
+
+	var running []string
+	for name, vmState := range envState.Resources.VMs {
+		if getString(vmState.State, "status") == providerv1.VMStatusRunning {
+			running = append(running, name)
+		} else {
+			tracker.reset(envState.ID + "/" + name)
+		}
+	}
+	if len(running) == 0 {
+		return
+	}
+	stats, err := o.Stats(envState.ID, running, 0)
+	if err != nil {
+		log.Printf("Environment %s: failed to sample idle vms: %v", envState.ID, err)
+		return
+	}
+
+	action := spec.IdleAction(envState.Spec)
+	for _, r := range stats.VMs {
+		key := envState.ID + "/" + r.VM
+		// VMs that cannot be sampled are left alone
+		busy := r.Stats == nil || r.Stats.CPUPercent >= threshold ||
+			sshSessions(getString(envState.Resources.VMs[r.VM].State, "ip"), sshPort) > 0
+		if tracker.observe(key, busy, now) < after {
+			continue
+		}
+		log.Printf("Environment %s: vm %q idle for %s, applying %s", envState.ID, r.VM, after, action)
+		if _, err := o.PowerVM(ctx, envState.ID, r.VM, action, PowerOptions{}); err != nil {
+			log.Printf("Environment %s: failed to %s idle vm %q: %v", envState.ID, action, r.VM, err)
+			continue
+		}
+		tracker.reset(key)
+	}
+}
+
+// listenWake starts the wake proxy of a VM, which accepts connections on
+// w.Listen until ctx is done or the returned listener is closed.
+func (o *Orchestrator) listenWake(ctx context.Context, envID string, w v1.IdleWakeSpec, testenvSpec *v1.Spec, tracker *idleTracker) (net.Listener, error) {
+	port := w.Port
+	if port == 0 {
+		port = testenvSpec.Idle.SshPort
+	}
+	if port == 0 {
+		port = spec.DefaultIdleSSHPort
+	}
+	l, err := net.Listen("tcp", w.Listen)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Environment %s: wake proxy for vm %q listening on %s", envID, w.Vm, w.Listen)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("Environment %s: wake proxy on %s stopped: %v", envID, w.Listen, err)
+				}
+				return
+			}
+			go o.wakeAndForward(ctx, envID, w.Vm, port, conn, tracker)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+	return l, nil
+}
+
+// wakeAndForward starts a VM unless it runs, waits until it accepts
+// connections on port, and forwards conn to it.
+func (o *Orchestrator) wakeAndForward(ctx context.Context, envID, vmName string, port int, conn net.Conn, tracker *idleTracker) {
+	defer func() { _ = conn.Close() }()
+	defer tracker.connect(envID + "/" + vmName)()
+
+	envState, err := o.store.Load(envID)
+	if err != nil {
+		log.Printf("Environment %s: wake proxy failed to load state: %v", envID, err)
+		return
+	}
+	vmState := envState.Resources.VMs[vmName]
+	if vmState == nil {
+		log.Printf("Environment %s: wake proxy vm %q not found", envID, vmName)
+		return
+	}
+	if getString(vmState.State, "status") != providerv1.VMStatusRunning {
+		log.Printf("Environment %s: waking vm %q for a connection from %s", envID, vmName, conn.RemoteAddr())
+		if vmState, err = o.PowerVM(ctx, envID, vmName, providerv1.PowerStart, PowerOptions{}); err != nil {
+			log.Printf("Environment %s: failed to wake vm %q: %v", envID, vmName, err)
+			return
+		}
+	}
+
+	target := net.JoinHostPort(getString(vmState.State, "ip"), strconv.Itoa(port))
+	upstream, err := dialUntil(ctx, target, wakeTimeout)
+	if err != nil {
+		log.Printf("Environment %s: vm %q did not accept connections on %s: %v", envID, vmName, target, err)
+		return
+	}
+	defer func() { _ = upstream.Close() }()
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		if tcp, ok := upstream.(*net.TCPConn); ok {
+			_ = tcp.CloseWrite()
+		}
+		close(done)
+	}()
+	_, _ = io.Copy(conn, upstream)
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.CloseWrite()
+	}
+	<-done
+}
+
+// dialUntil connects to address, retrying until it succeeds, timeout
+// elapses or ctx is done.
+func dialUntil(ctx context.Context, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err == nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(wakePollInterval):
+		}
+	}
+}
+
+// sshSessions counts the established TCP connections from this host to
+// ip:port, which are the SSH sessions to a VM. It returns 0 when ip is empty
+// or the socket tables cannot be read.
+func sshSessions(ip string, port int) int {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return 0
+	}
+	n := 0
+	for _, path := range procNetTCPFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		n += countEstablished(string(data), addr, port)
+	}
+	return n
+}
+
+// tcpEstablished is the state of an established socket in /proc/net/tcp.
+const tcpEstablished = "01"
+
+// countEstablished counts the established sockets of a /proc/net/tcp or
+// /proc/net/tcp6 table whose remote address is ip:port.
+func countEstablished(table string, ip net.IP, port int) int {
+	n := 0
+	for _, line := range strings.Split(table, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[3] != tcpEstablished {
+			continue
+		}
+		remoteIP, remotePort, err := parseProcNetAddr(fields[2])
+		if err != nil {
+			continue
+		}
+		if remotePort == port && remoteIP.Equal(ip) {
+			n++
+		}
+	}
+	return n
+}
+
+// parseProcNetAddr parses an address of /proc/net/tcp, such as
+// "0100007F:0016", whose IP is written as little-endian 32-bit words.
+func parseProcNetAddr(s string) (net.IP, int, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port in address %q", s)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip, int(port), nil
+}
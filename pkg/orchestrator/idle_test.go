@@ -0,0 +1,95 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCountEstablished(t *testing.T) {
+	// 192.168.122.10:22 is 0A7AA8C0:0016
+	table := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 017AA8C0:B2C4 0A7AA8C0:0016 01 00000000:00000000 02:000A1B2C 00000000  1000        0 2 1 0000000000000000 20 4 30 10 -1
+   2: 017AA8C0:B2C6 0A7AA8C0:0016 01 00000000:00000000 02:000A1B2C 00000000  1000        0 3 1 0000000000000000 20 4 30 10 -1
+   3: 017AA8C0:B2C8 0A7AA8C0:0016 06 00000000:00000000 03:00001234 00000000     0        0 0 3 0000000000000000
+   4: 017AA8C0:B2CA 0A7AA8C0:0050 01 00000000:00000000 02:000A1B2C 00000000  1000        0 4 1 0000000000000000 20 4 30 10 -1
+`
+	ip := net.ParseIP("192.168.122.10")
+	if got := countEstablished(table, ip, 22); got != 2 {
+		t.Errorf("countEstablished(:22) = %d, want 2", got)
+	}
+	if got := countEstablished(table, ip, 80); got != 1 {
+		t.Errorf("countEstablished(:80) = %d, want 1", got)
+	}
+	if got := countEstablished(table, net.ParseIP("192.168.122.11"), 22); got != 0 {
+		t.Errorf("countEstablished(other ip) = %d, want 0", got)
+	}
+}
+
+func TestParseProcNetAddr(t *testing.T) {
+	tests := []struct {
+		in   string
+		ip   string
+		port int
+	}{
+		{"0100007F:0016", "127.0.0.1", 22},
+		{"00000000000000000000000001000000:1F90", "::1", 8080},
+		{"B80D0120000000000000000001000000:0016", "2001:db8::1", 22},
+	}
+	for _, tt := range tests {
+		ip, port, err := parseProcNetAddr(tt.in)
+		if err != nil {
+			t.Fatalf("parseProcNetAddr(%q) error = %v", tt.in, err)
+		}
+		if !ip.Equal(net.ParseIP(tt.ip)) || port != tt.port {
+			t.Errorf("parseProcNetAddr(%q) = %s, %d, want %s, %d", tt.in, ip, port, tt.ip, tt.port)
+		}
+	}
+	if _, _, err := parseProcNetAddr("zz:0016"); err == nil {
+		t.Error("parseProcNetAddr() expected error for an invalid address")
+	}
+}
+
+func TestIdleTracker(t *testing.T) {
+	tracker := newIdleTracker()
+	start := time.Now()
+
+	if got := tracker.observe("env/web", false, start); got != 0 {
+		t.Errorf("first idle observation = %s, want 0", got)
+	}
+	if got := tracker.observe("env/web", false, start.Add(10*time.Minute)); got != 10*time.Minute {
+		t.Errorf("idle time = %s, want 10m", got)
+	}
+	if got := tracker.observe("env/web", true, start.Add(11*time.Minute)); got != 0 {
+		t.Errorf("busy observation = %s, want 0", got)
+	}
+
+	// A proxied connection keeps the VM busy until it ends
+	done := tracker.connect("env/web")
+	tracker.observe("env/web", false, start.Add(12*time.Minute))
+	if got := tracker.observe("env/web", false, start.Add(20*time.Minute)); got != 0 {
+		t.Errorf("idle time with a proxied connection = %s, want 0", got)
+	}
+	done()
+	tracker.observe("env/web", false, start.Add(21*time.Minute))
+	if got := tracker.observe("env/web", false, start.Add(26*time.Minute)); got != 5*time.Minute {
+		t.Errorf("idle time after the connection ended = %s, want 5m", got)
+	}
+}
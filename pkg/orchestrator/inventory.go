@@ -0,0 +1,148 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// InventoryRecord is a resource of an environment of the state directory,
+// as listed by the csv and ndjson export formats.
+type InventoryRecord struct {
+	// Environment is the ID of the environment owning the resource.
+	Environment string `json:"environment"`
+	// Kind is key, network, vm or service, or environment for an
+	// environment whose state cannot be read.
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+	// IP is the address of a VM, or the gateway address of a network.
+	IP string `json:"ip,omitempty"`
+	// CreatedAt is an RFC 3339 timestamp.
+	CreatedAt string `json:"createdAt,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// inventoryColumns is the header row of the csv export format.
+var inventoryColumns = []string{"environment", "kind", "name", "provider", "ip", "created", "status", "error"}
+
+// statusUnreadable is the status of the record of an environment whose
+// state cannot be read.
+const statusUnreadable = "unreadable"
+
+// Inventory returns one record per resource of the environments of the state
+// directory, or of environmentID when it is set, sorted by environment,
+// kind and name. Keys and networks owned by a parent environment are only
+// listed under the parent. An environment whose state cannot be read is
+// listed as a single record of kind environment with its error.
+func (o *Orchestrator) Inventory(environmentID string) ([]InventoryRecord, error) {
+	ids := []string{environmentID}
+	if environmentID == "" {
+		var err error
+		if ids, err = o.store.List(); err != nil {
+			return nil, err
+		}
+		sort.Strings(ids)
+	}
+
+	var records []InventoryRecord
+	for _, id := range ids {
+		envState, err := o.store.Load(id)
+		if err != nil {
+			if environmentID != "" {
+				return nil, fmt.Errorf("failed to load environment %q: %w", id, err)
+			}
+			records = append(records, InventoryRecord{
+				Environment: id, Kind: "environment", Name: id, Status: statusUnreadable, Error: err.Error(),
+			})
+			continue
+		}
+		records = append(records, inventoryRecords(envState)...)
+	}
+	return records, nil
+}
+
+// inventoryRecords returns the records of the resources of an environment.
+func inventoryRecords(envState *v1.EnvironmentState) []InventoryRecord {
+	var records []InventoryRecord
+	for _, kind := range []struct {
+		name      string
+		resources map[string]*v1.ResourceState
+	}{
+		{"key", envState.Resources.Keys},
+		{"network", envState.Resources.Networks},
+		{"vm", envState.Resources.VMs},
+		{"service", envState.Resources.Services},
+	} {
+		names := make([]string, 0, len(kind.resources))
+		for name, rs := range kind.resources {
+			if rs != nil && rs.Owner == "" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rs := kind.resources[name]
+			record := InventoryRecord{
+				Environment: envState.ID,
+				Kind:        kind.name,
+				Name:        name,
+				Provider:    rs.Provider,
+				CreatedAt:   rs.CreatedAt,
+				Status:      rs.Status,
+				Error:       rs.Error,
+			}
+			if kind.name == "vm" || kind.name == "network" {
+				record.IP, _ = rs.State["ip"].(string)
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// exportInventory renders inventory records in the csv or ndjson format.
+func exportInventory(records []InventoryRecord, format string) (string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case ExportFormatCSV:
+		w := csv.NewWriter(&buf)
+		_ = w.Write(inventoryColumns)
+		for _, r := range records {
+			_ = w.Write([]string{r.Environment, r.Kind, r.Name, r.Provider, r.IP, r.CreatedAt, r.Status, r.Error})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", fmt.Errorf("failed to write csv: %w", err)
+		}
+	case ExportFormatNDJSON:
+		enc := json.NewEncoder(&buf)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return "", fmt.Errorf("failed to marshal inventory: %w", err)
+			}
+		}
+	default:
+		return "", fmt.Errorf("unsupported inventory format %q (supported: %s, %s)", format, ExportFormatCSV, ExportFormatNDJSON)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,105 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOrchestrator_Inventory(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	const created = "2025-01-01T00:00:00Z"
+	lab := &v1.EnvironmentState{ID: "lab", Status: v1.StatusReady, Resources: v1.ResourceMap{
+		Keys: map[string]*v1.ResourceState{"ssh": {Provider: "libvirt", Status: v1.StatusReady, CreatedAt: created}},
+		Networks: map[string]*v1.ResourceState{"net": {
+			Provider: "libvirt", Status: v1.StatusReady, CreatedAt: created, State: map[string]any{"ip": "192.168.100.1"},
+		}},
+		VMs: map[string]*v1.ResourceState{
+			"web": {Provider: "libvirt", Status: v1.StatusReady, CreatedAt: created, State: map[string]any{"ip": "192.168.100.10"}},
+			"db":  {Provider: "libvirt", Status: v1.StatusFailed, Error: "boot failed, retry"},
+		},
+	}}
+	shard := &v1.EnvironmentState{ID: "shard", Status: v1.StatusReady, Resources: v1.ResourceMap{
+		Networks: map[string]*v1.ResourceState{"net": {Provider: "libvirt", Status: v1.StatusReady, Owner: "lab"}},
+		VMs:      map[string]*v1.ResourceState{"runner": {Provider: "libvirt", Status: v1.StatusReady}},
+	}}
+	for _, s := range []*v1.EnvironmentState{shard, lab} {
+		if err := o.store.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(o.store.Layout().StateFile("broken"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := o.Export("", ExportFormatCSV)
+	if err != nil {
+		t.Fatalf("Export(csv) error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	want := []string{
+		"environment,kind,name,provider,ip,created,status,error",
+		"broken,environment,broken,,,,unreadable,",
+		"lab,key,ssh,libvirt,," + created + ",ready,",
+		"lab,network,net,libvirt,192.168.100.1," + created + ",ready,",
+		`lab,vm,db,libvirt,,,failed,"boot failed, retry"`,
+		"lab,vm,web,libvirt,192.168.100.10," + created + ",ready,",
+		"shard,vm,runner,libvirt,,,ready,",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("Export(csv) =\n%s\nwant %d lines", out, len(want))
+	}
+	for i, line := range lines {
+		// The error of the unreadable state depends on the JSON decoder
+		if i == 1 {
+			line = line[:strings.LastIndex(line, ",")+1]
+		}
+		if line != want[i] {
+			t.Errorf("Export(csv) line %d = %q, want %q", i, line, want[i])
+		}
+	}
+
+	out, err = o.Export("lab", ExportFormatNDJSON)
+	if err != nil {
+		t.Fatalf("Export(ndjson) error = %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Export(ndjson) of lab =\n%s\nwant 4 records", out)
+	}
+	var web InventoryRecord
+	if err := json.Unmarshal([]byte(lines[3]), &web); err != nil {
+		t.Fatal(err)
+	}
+	if web != (InventoryRecord{Environment: "lab", Kind: "vm", Name: "web", Provider: "libvirt", IP: "192.168.100.10", CreatedAt: created, Status: v1.StatusReady}) {
+		t.Errorf("Export(ndjson) record = %+v", web)
+	}
+
+	if _, err := o.Export("missing", ExportFormatCSV); err == nil {
+		t.Error("Export(csv) of a missing environment should fail")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+)
+
+// knownHostsFile is the known_hosts file written to the artifact directory.
+const knownHostsFile = "known_hosts"
+
+// writeKnownHosts records the SSH host keys collected by providers as a
+// known_hosts file in the artifact directory, so that tests can connect with
+// StrictHostKeyChecking=yes. Nothing is written when no VM reported host keys.
+func writeKnownHosts(envState *v1.EnvironmentState) error {
+	names := make([]string, 0, len(envState.Resources.VMs))
+	for name := range envState.Resources.VMs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		state := envState.Resources.VMs[name].State
+		ip := getString(state, "ip")
+		if ip == "" {
+			continue
+		}
+		for _, hostKey := range stateStrings(state, "hostKeys") {
+			line, err := client.KnownHostsLine(ip, vmSSHPort(state), hostKey)
+			if err != nil {
+				return fmt.Errorf("vm %q: %w", name, err)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(envState.ArtifactDir, knownHostsFile)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// stateStrings returns a string list stored in resource state. Lists read
+// back from JSON are []any.
+func stateStrings(state map[string]any, key string) []string {
+	switch v := state[key].(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
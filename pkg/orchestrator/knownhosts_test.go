@@ -0,0 +1,94 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestWriteKnownHosts(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to convert key: %v", err)
+	}
+	hostKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	dir := t.TempDir()
+	envState := &v1.EnvironmentState{
+		ArtifactDir: dir,
+		Resources: v1.ResourceMap{
+			VMs: map[string]*v1.ResourceState{
+				// Lists read back from the state file are []any.
+				"web": {State: map[string]any{"ip": "10.0.0.2", "hostKeys": []any{hostKey}}},
+				"db":  {State: map[string]any{"ip": "10.0.0.3"}},
+			},
+		},
+	}
+
+	if err := writeKnownHosts(envState); err != nil {
+		t.Fatalf("writeKnownHosts failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, knownHostsFile))
+	if err != nil {
+		t.Fatalf("failed to read known_hosts: %v", err)
+	}
+	if want := "10.0.0.2 " + hostKey + "\n"; string(data) != want {
+		t.Errorf("known_hosts = %q, want %q", data, want)
+	}
+
+	o := &Orchestrator{}
+	artifact := o.buildArtifact("test", envState, nil)
+	if artifact.Files["testenv-vm.known_hosts"] != knownHostsFile {
+		t.Errorf("Files[testenv-vm.known_hosts] = %q", artifact.Files["testenv-vm.known_hosts"])
+	}
+	if artifact.Env["TESTENV_VM_KNOWN_HOSTS"] != filepath.Join(dir, knownHostsFile) {
+		t.Errorf("Env[TESTENV_VM_KNOWN_HOSTS] = %q", artifact.Env["TESTENV_VM_KNOWN_HOSTS"])
+	}
+	if artifact.Metadata["testenv-vm.vm.web.hostKeys"] != hostKey {
+		t.Errorf("Metadata[testenv-vm.vm.web.hostKeys] = %q", artifact.Metadata["testenv-vm.vm.web.hostKeys"])
+	}
+}
+
+func TestWriteKnownHostsWithoutHostKeys(t *testing.T) {
+	dir := t.TempDir()
+	envState := &v1.EnvironmentState{
+		ArtifactDir: dir,
+		Resources: v1.ResourceMap{
+			VMs: map[string]*v1.ResourceState{
+				"web": {State: map[string]any{"ip": "10.0.0.2"}},
+			},
+		},
+	}
+	if err := writeKnownHosts(envState); err != nil {
+		t.Fatalf("writeKnownHosts failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, knownHostsFile)); !os.IsNotExist(err) {
+		t.Errorf("expected no known_hosts file, got err=%v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"maps"
+	"path/filepath"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// resourceLabels returns the labels of the create request of a resource: the
+// labels of the spec, then those identifying its state directory, its
+// tenant, its environment and itself.
+func resourceLabels(spec *v1.Spec, envState *v1.EnvironmentState, ref v1.ResourceRef, tenant, stateDir string) map[string]string {
+	labels := make(map[string]string, len(spec.Labels)+6)
+	maps.Copy(labels, spec.Labels)
+	labels[providerv1.LabelStateDir] = stateDir
+	labels[providerv1.LabelEnvironmentID] = envState.ID
+	labels[providerv1.LabelResource] = ref.Kind + "/" + ref.Name
+	if envState.Stage != "" {
+		labels[providerv1.LabelStage] = envState.Stage
+	}
+	if envState.CreatedAt != "" {
+		labels[providerv1.LabelCreatedAt] = envState.CreatedAt
+	}
+	if tenant != "" {
+		labels[providerv1.LabelTenant] = tenant
+	}
+	return labels
+}
+
+// stateDirLabel returns the providerv1.LabelStateDir label of the resources
+// created with stateDir: the hash of its absolute path, so that checkouts
+// using the same relative state directory are told apart.
+func stateDirLabel(stateDir string) string {
+	if abs, err := filepath.Abs(stateDir); err == nil {
+		stateDir = abs
+	}
+	return shortHash(filepath.Clean(stateDir))
+}
@@ -0,0 +1,70 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestResourceLabels(t *testing.T) {
+	spec := &v1.Spec{Labels: map[string]string{"team": "storage"}}
+	envState := &v1.EnvironmentState{ID: "env-1", Stage: "e2e", CreatedAt: "2025-01-02T03:04:05Z"}
+
+	got := resourceLabels(spec, envState, v1.ResourceRef{Kind: "network", Name: "lan"}, "platform", "0123abcd")
+	want := map[string]string{
+		"team":                        "storage",
+		providerv1.LabelStateDir:      "0123abcd",
+		providerv1.LabelEnvironmentID: "env-1",
+		providerv1.LabelResource:      "network/lan",
+		providerv1.LabelStage:         "e2e",
+		providerv1.LabelCreatedAt:     "2025-01-02T03:04:05Z",
+		providerv1.LabelTenant:        "platform",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resourceLabels() = %v, want %v", got, want)
+	}
+
+	got = resourceLabels(&v1.Spec{}, &v1.EnvironmentState{ID: "env-1"}, v1.ResourceRef{Kind: "vm", Name: "web"}, "", "0123abcd")
+	want = map[string]string{
+		providerv1.LabelStateDir:      "0123abcd",
+		providerv1.LabelEnvironmentID: "env-1",
+		providerv1.LabelResource:      "vm/web",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resourceLabels() = %v, want %v", got, want)
+	}
+	if len(spec.Labels) != 1 {
+		t.Errorf("resourceLabels() modified the spec labels: %v", spec.Labels)
+	}
+}
+
+func TestStateDirLabel(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	if got, want := stateDirLabel("state"), stateDirLabel(filepath.Join(dir, "state")); got != want {
+		t.Errorf("stateDirLabel(relative) = %q, want that of the absolute path %q", got, want)
+	}
+	if stateDirLabel("state") == stateDirLabel(filepath.Join(t.TempDir(), "state")) {
+		t.Error("stateDirLabel() is the same for the state directories of two checkouts")
+	}
+}
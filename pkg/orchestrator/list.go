@@ -0,0 +1,131 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// EnvironmentSummary describes an environment of the state directory.
+type EnvironmentSummary struct {
+	// ID is the environment ID.
+	ID string `json:"id"`
+	// TestID is the forge testID that created the environment.
+	TestID string `json:"testID,omitempty"`
+	// Stage is the test stage name.
+	Stage string `json:"stage,omitempty"`
+	// Status is the environment status.
+	Status string `json:"status,omitempty"`
+	// Parent is the environment whose keys and networks it uses.
+	Parent string `json:"parent,omitempty"`
+	// CreatedAt and UpdatedAt are RFC 3339 timestamps.
+	CreatedAt string `json:"createdAt,omitempty"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+	// Keys, Networks and VMs count the recorded resources, including those
+	// owned by the parent.
+	Keys     int `json:"keys"`
+	Networks int `json:"networks"`
+	VMs      int `json:"vms"`
+	// Error is set instead of the other fields when the state cannot be
+	// read, e.g. when it is encrypted with another key.
+	Error string `json:"error,omitempty"`
+}
+
+// ListOptions filters ListEnvironments.
+type ListOptions struct {
+	// Status, if set, only lists environments of this status.
+	Status string
+}
+
+// ListEnvironments returns the environments of the state directory, oldest
+// first. Environments whose state cannot be read are listed with their
+// error, unless filtered by status.
+func (o *Orchestrator) ListEnvironments(opts ListOptions) ([]EnvironmentSummary, error) {
+	ids, err := o.store.List()
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]EnvironmentSummary, 0, len(ids))
+	for _, id := range ids {
+		envState, err := o.store.Load(id)
+		if err != nil {
+			if opts.Status == "" {
+				summaries = append(summaries, EnvironmentSummary{ID: id, Error: err.Error()})
+			}
+			continue
+		}
+		if opts.Status != "" && envState.Status != opts.Status {
+			continue
+		}
+		summaries = append(summaries, summarize(envState))
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].CreatedAt != summaries[j].CreatedAt {
+			return summaries[i].CreatedAt < summaries[j].CreatedAt
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries, nil
+}
+
+// Environment returns the summary of an environment of the state directory.
+// The error matches fs.ErrNotExist if there is no such environment.
+func (o *Orchestrator) Environment(environmentID string) (*EnvironmentSummary, error) {
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, err
+	}
+	summary := summarize(envState)
+	return &summary, nil
+}
+
+// Outputs returns the artifact of an environment of the state directory, as
+// returned by Create. The error matches fs.ErrNotExist if there is no such
+// environment.
+func (o *Orchestrator) Outputs(environmentID string) (*v1.TestEnvArtifact, error) {
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, err
+	}
+	if envState.Spec == nil {
+		return nil, fmt.Errorf("environment %q has no recorded spec", environmentID)
+	}
+	isoConfig, err := o.existingIsolation(envState, envState.Spec)
+	if err != nil {
+		return nil, err
+	}
+	return o.buildArtifact(envState.TestID, envState, isoConfig), nil
+}
+
+func summarize(envState *v1.EnvironmentState) EnvironmentSummary {
+	summary := EnvironmentSummary{
+		ID:        envState.ID,
+		TestID:    envState.TestID,
+		Stage:     envState.Stage,
+		Status:    envState.Status,
+		CreatedAt: envState.CreatedAt,
+		UpdatedAt: envState.UpdatedAt,
+		Keys:      len(envState.Resources.Keys),
+		Networks:  len(envState.Resources.Networks),
+		VMs:       len(envState.Resources.VMs),
+	}
+	if envState.Spec != nil {
+		summary.Parent = envState.Spec.Parent.EnvironmentId
+	}
+	return summary
+}
@@ -0,0 +1,117 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOrchestrator_ListEnvironments(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if got, err := o.ListEnvironments(ListOptions{}); err != nil || len(got) != 0 {
+		t.Fatalf("ListEnvironments() of an empty state directory = %v, %v", got, err)
+	}
+
+	spec := updateSpec("10.0.0.0/24", map[string]int{"web": 1024, "db": 1024})
+	ready := updatedEnvironment(t, o.executor, spec)
+	ready.CreatedAt = "2025-01-02T00:00:00Z"
+	failed := updatedEnvironment(t, o.executor, spec)
+	failed.ID, failed.Status, failed.CreatedAt = "env-0", v1.StatusFailed, "2025-01-01T00:00:00Z"
+	failed.Spec = &v1.Spec{Parent: v1.ParentSpec{EnvironmentId: "env-1"}}
+	for _, s := range []*v1.EnvironmentState{ready, failed} {
+		if err := o.store.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(o.store.Layout().StateFile("broken"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := o.ListEnvironments(ListOptions{})
+	if err != nil {
+		t.Fatalf("ListEnvironments() error = %v", err)
+	}
+	var ids []string
+	for _, s := range got {
+		ids = append(ids, s.ID)
+	}
+	// Unreadable states have no creation time and come first
+	if want := []string{"broken", "env-0", "env-1"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ListEnvironments() IDs = %v, want %v", ids, want)
+	}
+	if got[0].Error == "" {
+		t.Error("unreadable state listed without its error")
+	}
+	if got[1].Parent != "env-1" || got[1].Status != v1.StatusFailed {
+		t.Errorf("ListEnvironments()[1] = %+v, want a failed child of env-1", got[1])
+	}
+	if got[2].VMs != 2 || got[2].Networks != len(ready.Resources.Networks) {
+		t.Errorf("ListEnvironments()[2] = %+v, want 2 VMs", got[2])
+	}
+
+	got, err = o.ListEnvironments(ListOptions{Status: v1.StatusReady})
+	if err != nil || len(got) != 1 || got[0].ID != "env-1" {
+		t.Errorf("ListEnvironments(ready) = %+v, %v, want env-1", got, err)
+	}
+}
+
+func TestOrchestrator_EnvironmentAndOutputs(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	spec := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+	ready := updatedEnvironment(t, o.executor, spec)
+	if err := o.store.Save(ready); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := o.Environment(ready.ID)
+	if err != nil {
+		t.Fatalf("Environment() error = %v", err)
+	}
+	if summary.Status != v1.StatusReady || summary.VMs != 1 {
+		t.Errorf("Environment() = %+v, want a ready environment with 1 VM", summary)
+	}
+	artifact, err := o.Outputs(ready.ID)
+	if err != nil {
+		t.Fatalf("Outputs() error = %v", err)
+	}
+	if got := artifact.Metadata[MetadataEnvironmentID]; got != ready.ID {
+		t.Errorf("Outputs() environment ID = %q, want %q", got, ready.ID)
+	}
+
+	if _, err := o.Environment("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Environment(missing) error = %v, want fs.ErrNotExist", err)
+	}
+	if _, err := o.Outputs("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Outputs(missing) error = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+// UpdateLock resolves the provider engines of specs and of the default
+// providers to the exact versions and digests they run now, and records them
+// in the lockfile. Entries of other engines are kept. It returns the updated
+// lock.
+func (o *Orchestrator) UpdateLock(specs []*v1.Spec) (*provider.Lock, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("lockfile update rejected: %w", ErrReadOnly)
+	}
+	if o.config.LockFile == "" {
+		return nil, errors.New("no provider lockfile configured")
+	}
+	lock, err := provider.ReadLock(o.config.LockFile)
+	if errors.Is(err, os.ErrNotExist) {
+		lock = &provider.Lock{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	var engines []string
+	for _, p := range o.config.DefaultProviders {
+		engines = append(engines, p.Engine)
+	}
+	for _, s := range specs {
+		for _, p := range s.Providers {
+			engines = append(engines, p.Engine)
+		}
+	}
+	slices.Sort(engines)
+	for _, engine := range slices.Compact(engines) {
+		locked, err := provider.LockEngine(engine)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock engine %q: %w", engine, err)
+		}
+		lock.Set(locked)
+	}
+
+	if err := provider.WriteLock(o.config.LockFile, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
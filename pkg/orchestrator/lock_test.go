@@ -0,0 +1,74 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+func TestOrchestrator_UpdateLock(t *testing.T) {
+	dir := t.TempDir()
+	binaries := map[string]string{}
+	for _, name := range []string{"default", "libvirt", "stale"} {
+		binaries[name] = filepath.Join(dir, name)
+		if err := os.WriteFile(binaries[name], []byte(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := newTestConfig(t)
+	config.LockFile = filepath.Join(dir, provider.LockFileName)
+	config.DefaultProviders = []v1.ProviderConfig{{Name: "default", Engine: binaries["default"]}}
+	stale := provider.LockedProvider{Engine: binaries["stale"], Path: binaries["stale"], Digest: "sha256:old"}
+	if err := provider.WriteLock(config.LockFile, &provider.Lock{Providers: []provider.LockedProvider{stale}}); err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	specs := []*v1.Spec{
+		{Providers: []v1.ProviderConfig{{Name: "libvirt", Engine: binaries["libvirt"]}}},
+		{Providers: []v1.ProviderConfig{{Name: "libvirt", Engine: binaries["libvirt"]}}},
+	}
+	if _, err := o.UpdateLock(specs); err != nil {
+		t.Fatalf("UpdateLock() error = %v", err)
+	}
+
+	lock, err := provider.ReadLock(config.LockFile)
+	if err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	if len(lock.Providers) != 3 {
+		t.Fatalf("locked providers = %+v, want default, libvirt and the kept stale entry", lock.Providers)
+	}
+	for _, name := range []string{"default", "libvirt"} {
+		if _, err := lock.Pin(binaries[name]); err != nil {
+			t.Errorf("Pin(%s) error = %v", name, err)
+		}
+	}
+	if p, _ := lock.Find(binaries["stale"]); p != stale {
+		t.Errorf("stale entry = %+v, want it kept", p)
+	}
+}
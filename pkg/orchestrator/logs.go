@@ -0,0 +1,27 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+// ProviderLogs returns the captured stderr of the named provider. When tail is
+// positive only the last tail lines are returned. Logs are read from the state
+// directory, so they are available to any process sharing it.
+func (o *Orchestrator) ProviderLogs(name string, tail int) (string, error) {
+	return provider.ReadLogs(paths.New(o.config.StateDir).LogsDir(), name, tail)
+}
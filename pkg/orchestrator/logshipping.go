@@ -0,0 +1,62 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"net"
+	"slices"
+	"strconv"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/service"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// injectLogShipping points the cloud-init of a VM at the log collectors of
+// the networks it is attached to, so that its syslog lands in the guest log
+// bundle of the environment. Networks are the names of the rendered VM spec,
+// before the isolation prefix is applied.
+func injectLogShipping(vmSpec *providerv1.VMSpec, rendered v1.VMSpec, spec *v1.Spec, templateCtx *specpkg.TemplateContext) {
+	networks := vmNetworks(rendered)
+	var servers []string
+	for _, svc := range spec.Services {
+		if svc.Spec.Type != service.TypeLogCollector || !slices.Contains(networks, svc.Spec.Network) {
+			continue
+		}
+		data, ok := templateCtx.Services[svc.Name]
+		if !ok || data.Address == "" {
+			continue
+		}
+		servers = append(servers, net.JoinHostPort(data.Address, strconv.Itoa(data.Port)))
+	}
+	if len(servers) == 0 {
+		return
+	}
+
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	vmSpec.CloudInit.SyslogServers = append(vmSpec.CloudInit.SyslogServers, servers...)
+}
+
+// vmNetworks returns the networks a VM is attached to. Networks takes
+// precedence over Network.
+func vmNetworks(vm v1.VMSpec) []string {
+	if len(vm.Networks) == 0 && vm.Network != "" {
+		return []string{vm.Network}
+	}
+	return vm.Networks
+}
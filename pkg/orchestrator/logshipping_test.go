@@ -0,0 +1,77 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestInjectLogShipping(t *testing.T) {
+	spec := &v1.Spec{Services: []v1.ServiceResource{
+		{Name: "logs", Spec: v1.ServiceSpec{Type: "log-collector", Network: "lab"}},
+		{Name: "mirror", Spec: v1.ServiceSpec{Type: "registry-mirror", Network: "lab"}},
+		{Name: "storage-logs", Spec: v1.ServiceSpec{Type: "log-collector", Network: "storage"}},
+	}}
+	ctx := specpkg.NewTemplateContext()
+	ctx.Services["logs"] = specpkg.ServiceTemplateData{Address: "10.0.0.1", Port: 5140}
+	ctx.Services["mirror"] = specpkg.ServiceTemplateData{Address: "10.0.0.1", Port: 5000}
+	ctx.Services["storage-logs"] = specpkg.ServiceTemplateData{Address: "10.1.0.1", Port: 1514}
+
+	// Provider-level names carry the isolation prefix, the rendered ones not
+	vmSpec := providerv1.VMSpec{Networks: []string{"p-lab", "p-storage"}}
+	injectLogShipping(&vmSpec, v1.VMSpec{Networks: []string{"lab", "storage"}}, spec, ctx)
+	if vmSpec.CloudInit == nil {
+		t.Fatal("CloudInit = nil, want syslog servers")
+	}
+	want := []string{"10.0.0.1:5140", "10.1.0.1:1514"}
+	if !reflect.DeepEqual(vmSpec.CloudInit.SyslogServers, want) {
+		t.Errorf("SyslogServers = %v, want %v", vmSpec.CloudInit.SyslogServers, want)
+	}
+
+	var plain providerv1.VMSpec
+	injectLogShipping(&plain, v1.VMSpec{Network: "nat"}, spec, ctx)
+	if plain.CloudInit != nil {
+		t.Errorf("CloudInit = %+v, want nil for a network without log collector", plain.CloudInit)
+	}
+}
+
+func TestBuildDAG_LogCollector(t *testing.T) {
+	spec := &v1.Spec{
+		Networks: []v1.NetworkResource{{Name: "lab"}, {Name: "nat"}},
+		Vms: []v1.VMResource{
+			{Name: "node", Spec: v1.VMSpec{Network: "lab"}},
+			{Name: "other", Spec: v1.VMSpec{Network: "nat"}},
+		},
+		Services: []v1.ServiceResource{{Name: "logs", Spec: v1.ServiceSpec{Type: "log-collector", Network: "lab"}}},
+	}
+	dag, err := BuildDAG(spec)
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+	logsRef := v1.ResourceRef{Kind: "service", Name: "logs"}
+	if !dag.DependsOn(v1.ResourceRef{Kind: "vm", Name: "node"}, logsRef) {
+		t.Error("vm on the network of a log collector should depend on it")
+	}
+	if dag.DependsOn(v1.ResourceRef{Kind: "vm", Name: "other"}, logsRef) {
+		t.Error("vm on another network should not depend on the log collector")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Manifest artifact file names, relative to the artifact directory. The
+// digest file uses the sha256sum format, so "sha256sum -c" verifies it.
+const (
+	manifestFile       = "manifest.json"
+	manifestDigestFile = "manifest.json.sha256"
+)
+
+// ManifestVersion is the format version of Manifest.
+const ManifestVersion = "v1"
+
+// Manifest records what a ready environment was built from. Entries are
+// sorted so that the same inputs produce the same document.
+type Manifest struct {
+	// Version is the manifest format version.
+	Version string `json:"version"`
+	// EnvironmentID is the environment identifier.
+	EnvironmentID string `json:"environmentId"`
+	// TestID is the forge testID that created the environment.
+	TestID string `json:"testId,omitempty"`
+	// Stage is the test stage name.
+	Stage string `json:"stage,omitempty"`
+	// CreatedAt is the ISO8601 timestamp of creation.
+	CreatedAt string `json:"createdAt"`
+	// SpecSHA256 is the digest of the JSON-encoded spec.
+	SpecSHA256 string `json:"specSha256"`
+	// SpecSource is set when the spec was fetched from spec.specRef.
+	SpecSource *v1.SpecSource `json:"specSource,omitempty"`
+	// Seed is the seed the MAC addresses and UUIDs of the VMs derive from.
+	Seed string `json:"seed,omitempty"`
+	// Images are the base images with the digests of the cached files.
+	Images []ManifestImage `json:"images,omitempty"`
+	// Providers are the providers with the versions they reported.
+	Providers []ManifestProvider `json:"providers"`
+	// Resources is the inventory of keys, networks and VMs.
+	Resources []ManifestResource `json:"resources"`
+}
+
+// ManifestImage is a base image of a manifest.
+type ManifestImage struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+}
+
+// ManifestProvider is a provider of a manifest.
+type ManifestProvider struct {
+	Name    string `json:"name"`
+	Engine  string `json:"engine"`
+	Version string `json:"version"`
+}
+
+// ManifestResource is a resource of a manifest.
+type ManifestResource struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Status   string `json:"status"`
+	// Fingerprint is the SSH fingerprint of a key.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// HostKeyFingerprints are the SSH host key fingerprints of a VM.
+	HostKeyFingerprints []string `json:"hostKeyFingerprints,omitempty"`
+	// Hashes are the content hashes of the rendered resource.
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// writeManifest records the manifest of a ready environment in its artifact
+// directory.
+func (o *Orchestrator) writeManifest(envState *v1.EnvironmentState, templateCtx *spec.TemplateContext) error {
+	versions := make(map[string]string)
+	if envState.Spec != nil {
+		for _, p := range envState.Spec.Providers {
+			if info, ok := o.manager.GetInfo(p.Name); ok && info.Capabilities != nil {
+				versions[p.Name] = info.Capabilities.Version
+			}
+		}
+	}
+	m, err := buildManifest(envState, templateCtx.Images, versions)
+	if err != nil {
+		return err
+	}
+	_, err = writeManifest(envState.ArtifactDir, m)
+	return err
+}
+
+// buildManifest builds the manifest of an environment from its state, the
+// images resolved during creation and the provider versions keyed by name.
+func buildManifest(envState *v1.EnvironmentState, images map[string]spec.ImageTemplateData, versions map[string]string) (*Manifest, error) {
+	specData, err := json.Marshal(envState.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal spec: %w", err)
+	}
+	specSum := sha256.Sum256(specData)
+	m := &Manifest{
+		Version:       ManifestVersion,
+		EnvironmentID: envState.ID,
+		TestID:        envState.TestID,
+		Stage:         envState.Stage,
+		CreatedAt:     envState.CreatedAt,
+		SpecSHA256:    hex.EncodeToString(specSum[:]),
+		SpecSource:    envState.SpecSource,
+		Seed:          envState.Seed,
+		Providers:     []ManifestProvider{},
+		Resources:     []ManifestResource{},
+	}
+
+	if envState.Spec != nil {
+		for _, img := range envState.Spec.Images {
+			// Aliases point to the same data; only resolved names are listed.
+			if data, ok := images[img.Name]; ok {
+				m.Images = append(m.Images, ManifestImage{Name: img.Name, Source: img.Spec.Source, SHA256: data.SHA256})
+			}
+		}
+		for _, p := range envState.Spec.Providers {
+			m.Providers = append(m.Providers, ManifestProvider{Name: p.Name, Engine: p.Engine, Version: versions[p.Name]})
+		}
+	}
+	sort.Slice(m.Images, func(i, j int) bool { return m.Images[i].Name < m.Images[j].Name })
+	sort.Slice(m.Providers, func(i, j int) bool { return m.Providers[i].Name < m.Providers[j].Name })
+
+	for kind, resources := range map[string]map[string]*v1.ResourceState{
+		"key":     envState.Resources.Keys,
+		"network": envState.Resources.Networks,
+		"vm":      envState.Resources.VMs,
+	} {
+		for name, rs := range resources {
+			m.Resources = append(m.Resources, ManifestResource{
+				Kind:                kind,
+				Name:                name,
+				Provider:            rs.Provider,
+				Status:              rs.Status,
+				Fingerprint:         getString(rs.State, "fingerprint"),
+				HostKeyFingerprints: stateStrings(rs.State, "hostKeyFingerprints"),
+				Hashes:              rs.Hashes,
+			})
+		}
+	}
+	sort.Slice(m.Resources, func(i, j int) bool {
+		if m.Resources[i].Kind != m.Resources[j].Kind {
+			return m.Resources[i].Kind < m.Resources[j].Kind
+		}
+		return m.Resources[i].Name < m.Resources[j].Name
+	})
+	return m, nil
+}
+
+// writeManifest writes the manifest and its SHA-256 digest to the artifact
+// directory and returns the digest.
+func writeManifest(artifactDir string, m *Manifest) (string, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	data = append(data, '\n')
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	path := filepath.Join(artifactDir, manifestFile)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	digestPath := filepath.Join(artifactDir, manifestDigestFile)
+	if err := os.WriteFile(digestPath, []byte(digest+"  "+manifestFile+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", digestPath, err)
+	}
+	return digest, nil
+}
+
+// readManifestDigest returns the digest recorded next to the manifest of an
+// artifact directory.
+func readManifestDigest(artifactDir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(artifactDir, manifestDigestFile))
+	if err != nil {
+		return "", err
+	}
+	digest, _, _ := strings.Cut(string(data), " ")
+	return digest, nil
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func newManifestState() *v1.EnvironmentState {
+	return &v1.EnvironmentState{
+		ID:        "env-1",
+		TestID:    "test-1",
+		Stage:     "e2e",
+		CreatedAt: "2025-01-15T00:00:00Z",
+		Spec: &v1.Spec{
+			Providers: []v1.ProviderConfig{{Name: "stub", Engine: "go://stub"}},
+			Images:    []v1.ImageResource{{Name: "ubuntu", Spec: v1.ImageSpec{Source: "ubuntu:24.04", Alias: "base"}}},
+		},
+		SpecSource: &v1.SpecSource{Ref: "git+https://example.com/labs.git//ci.yaml?ref=v1", Commit: "abc"},
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{
+				"ssh": {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"fingerprint": "SHA256:key"}},
+			},
+			VMs: map[string]*v1.ResourceState{
+				"web": {Provider: "stub", Status: v1.StatusReady, State: map[string]any{"hostKeyFingerprints": []any{"SHA256:host"}}, Hashes: map[string]string{"domain": "d"}},
+				"db":  {Provider: "stub", Status: v1.StatusReady},
+			},
+		},
+	}
+}
+
+func TestBuildManifest(t *testing.T) {
+	envState := newManifestState()
+	images := map[string]spec.ImageTemplateData{
+		"ubuntu": {Name: "ubuntu", SHA256: "img"},
+		"base":   {Name: "ubuntu", SHA256: "img"},
+	}
+	m, err := buildManifest(envState, images, map[string]string{"stub": "1.2.3"})
+	if err != nil {
+		t.Fatalf("buildManifest() error = %v", err)
+	}
+
+	if len(m.SpecSHA256) != 64 || m.SpecSource.Commit != "abc" {
+		t.Errorf("spec = %q, %+v", m.SpecSHA256, m.SpecSource)
+	}
+	if want := []ManifestImage{{Name: "ubuntu", Source: "ubuntu:24.04", SHA256: "img"}}; !reflect.DeepEqual(m.Images, want) {
+		t.Errorf("Images = %+v, want %+v", m.Images, want)
+	}
+	if want := []ManifestProvider{{Name: "stub", Engine: "go://stub", Version: "1.2.3"}}; !reflect.DeepEqual(m.Providers, want) {
+		t.Errorf("Providers = %+v, want %+v", m.Providers, want)
+	}
+	var names []string
+	for _, r := range m.Resources {
+		names = append(names, r.Kind+"/"+r.Name)
+	}
+	if want := []string{"key/ssh", "vm/db", "vm/web"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Resources = %v, want %v", names, want)
+	}
+	if m.Resources[0].Fingerprint != "SHA256:key" || !reflect.DeepEqual(m.Resources[2].HostKeyFingerprints, []string{"SHA256:host"}) {
+		t.Errorf("fingerprints missing from %+v", m.Resources)
+	}
+
+	// The same state always yields the same spec digest.
+	again, _ := buildManifest(newManifestState(), images, map[string]string{"stub": "1.2.3"})
+	if again.SpecSHA256 != m.SpecSHA256 {
+		t.Errorf("SpecSHA256 is not stable: %s != %s", again.SpecSHA256, m.SpecSHA256)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	m, err := buildManifest(newManifestState(), nil, nil)
+	if err != nil {
+		t.Fatalf("buildManifest() error = %v", err)
+	}
+	digest, err := writeManifest(dir, m)
+	if err != nil {
+		t.Fatalf("writeManifest() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if digest != hex.EncodeToString(sum[:]) {
+		t.Errorf("digest = %s, want the SHA-256 of %s", digest, manifestFile)
+	}
+	if got, err := readManifestDigest(dir); err != nil || got != digest {
+		t.Errorf("readManifestDigest() = %q, %v, want %q", got, err, digest)
+	}
+	line, _ := os.ReadFile(filepath.Join(dir, manifestDigestFile))
+	if want := digest + "  " + manifestFile + "\n"; string(line) != want {
+		t.Errorf("%s = %q, want %q", manifestDigestFile, line, want)
+	}
+}
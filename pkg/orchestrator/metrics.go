@@ -0,0 +1,99 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// metrics holds orchestrator counters. The zero value is ready to use.
+type metrics struct {
+	createSucceeded atomic.Int64
+	createFailed    atomic.Int64
+	deleteTotal     atomic.Int64
+	inFlight        atomic.Int64
+}
+
+// observeCreate records the outcome of a Create call.
+func (m *metrics) observeCreate(err error) {
+	if err != nil {
+		m.createFailed.Add(1)
+		return
+	}
+	m.createSucceeded.Add(1)
+}
+
+// MetricsHandler serves orchestrator metrics in the Prometheus text format.
+func (o *Orchestrator) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		o.writeMetrics(w)
+	})
+}
+
+// writeMetrics writes the metrics exposition to w.
+func (o *Orchestrator) writeMetrics(w io.Writer) {
+	environments := -1
+	if ids, err := o.store.List(); err == nil {
+		environments = len(ids)
+	}
+
+	fmt.Fprintln(w, "# HELP testenv_vm_create_total Create calls by result.")
+	fmt.Fprintln(w, "# TYPE testenv_vm_create_total counter")
+	fmt.Fprintf(w, "testenv_vm_create_total{result=\"success\"} %d\n", o.metrics.createSucceeded.Load())
+	fmt.Fprintf(w, "testenv_vm_create_total{result=\"failure\"} %d\n", o.metrics.createFailed.Load())
+	fmt.Fprintln(w, "# HELP testenv_vm_delete_total Delete calls.")
+	fmt.Fprintln(w, "# TYPE testenv_vm_delete_total counter")
+	fmt.Fprintf(w, "testenv_vm_delete_total %d\n", o.metrics.deleteTotal.Load())
+	fmt.Fprintln(w, "# HELP testenv_vm_operations_in_flight Create and Delete calls in progress.")
+	fmt.Fprintln(w, "# TYPE testenv_vm_operations_in_flight gauge")
+	fmt.Fprintf(w, "testenv_vm_operations_in_flight %d\n", o.metrics.inFlight.Load())
+	fmt.Fprintln(w, "# HELP testenv_vm_creates_queued Create calls waiting for a slot.")
+	fmt.Fprintln(w, "# TYPE testenv_vm_creates_queued gauge")
+	fmt.Fprintf(w, "testenv_vm_creates_queued %d\n", o.slots.queued())
+	if environments >= 0 {
+		fmt.Fprintln(w, "# HELP testenv_vm_environments Environments with state on disk.")
+		fmt.Fprintln(w, "# TYPE testenv_vm_environments gauge")
+		fmt.Fprintf(w, "testenv_vm_environments %d\n", environments)
+	}
+}
+
+// ServeMetrics serves MetricsHandler on addr at /metrics in the background.
+// Binding errors are returned immediately; later serving errors are logged.
+func (o *Orchestrator) ServeMetrics(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %q: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", o.MetricsHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics server failed: %v", err)
+		}
+	}()
+	log.Printf("Serving metrics on http://%s/metrics", listener.Addr())
+	return server, nil
+}
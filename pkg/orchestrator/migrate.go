@@ -0,0 +1,225 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// MigrateOptions configures MigrateVM.
+type MigrateOptions struct {
+	// CopyStorage copies the VM disks to the destination host. It is
+	// required unless both hosts share the storage of the VM.
+	CopyStorage bool
+}
+
+// MigrateVM live-migrates a VM of a stored environment to another provider of
+// the same engine, e.g. a second libvirt host. The source provider hands the
+// VM over with vm_migrate and the destination provider adopts it with
+// vm_adopt. The VM is then recorded under the destination provider with the
+// state it reports, so the new IPs are used by later template rendering, SSH
+// clients and deletion. The returned state is the recorded one. Waiting for
+// the lock of the environment stops when ctx is done.
+func (o *Orchestrator) MigrateVM(ctx context.Context, environmentID, vmName, destination string, opts MigrateOptions) (*v1.ResourceState, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("migrate rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate rejected: %w", err)
+	}
+	defer end()
+	unlock, err := o.store.Lock(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	vmState := envState.Resources.VMs[vmName]
+	if vmState == nil {
+		return nil, fmt.Errorf("vm %q not found in environment %q", vmName, environmentID)
+	}
+	if err := validateMigration(envState, vmState.Provider, destination); err != nil {
+		return nil, fmt.Errorf("cannot migrate vm %q: %w", vmName, err)
+	}
+
+	for _, name := range []string{vmState.Provider, destination} {
+		if err := o.ensureProvider(envState, name); err != nil {
+			return nil, err
+		}
+	}
+	if !o.manager.SupportsOperation(vmState.Provider, "vm", "migrate") {
+		return nil, fmt.Errorf("provider %q does not support migrating vm resources", vmState.Provider)
+	}
+	if !o.manager.SupportsOperation(destination, "vm", "adopt") {
+		return nil, fmt.Errorf("provider %q does not support adopting vm resources", destination)
+	}
+	var uri string
+	if info, ok := o.manager.GetInfo(destination); ok && info.Capabilities != nil {
+		uri = info.Capabilities.URI
+	}
+	if uri == "" {
+		return nil, fmt.Errorf("provider %q does not report a connection URI", destination)
+	}
+
+	log.Printf("Migrating vm %q of environment %q from provider %q to %q (%s)",
+		vmName, environmentID, vmState.Provider, destination, uri)
+	result, err := o.manager.Call(vmState.Provider, providerv1.VMMigrateTool, &providerv1.VMMigrateRequest{
+		Name:           getString(vmState.State, "name"),
+		DestinationURI: uri,
+		CopyStorage:    opts.CopyStorage,
+	})
+	if err := operationError(providerv1.VMMigrateTool, result, err); err != nil {
+		return nil, err
+	}
+
+	// From here on the VM runs on the destination host, so it is recorded
+	// there even if the adoption fails; deletion looks domains up by name.
+	source := vmState.Provider
+	vmState.Provider = destination
+	vmState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	adoptReq, err := adoptRequest(result.Resource)
+	if err == nil {
+		result, err = o.manager.Call(destination, providerv1.VMAdoptTool, adoptReq)
+		err = operationError(providerv1.VMAdoptTool, result, err)
+	}
+	if err == nil {
+		var state map[string]any
+		if state, err = o.executor.convertResourceToMap(result.Resource); err == nil {
+			vmState.State = state
+			vmState.Error = ""
+		}
+	}
+	if err != nil {
+		vmState.Error = fmt.Sprintf("migrated from provider %q but not adopted: %v", source, err)
+	}
+
+	envState.UpdatedAt = vmState.UpdatedAt
+	if saveErr := o.store.Save(envState); saveErr != nil {
+		return nil, fmt.Errorf("failed to save state: %w", saveErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vm %q migrated to provider %q but not adopted: %w", vmName, destination, err)
+	}
+
+	if err := writeTopology(envState); err != nil {
+		log.Printf("Failed to write topology diagram: %v", err)
+	}
+	if err := writeKnownHosts(envState); err != nil {
+		log.Printf("Failed to write known_hosts: %v", err)
+	}
+	return vmState, nil
+}
+
+// validateMigration checks that a VM can move from the source to the
+// destination provider of an environment: both must be declared, distinct,
+// and run the same engine.
+func validateMigration(envState *v1.EnvironmentState, source, destination string) error {
+	if destination == "" {
+		return errors.New("destination provider is required")
+	}
+	if source == destination {
+		return fmt.Errorf("vm is already managed by provider %q", destination)
+	}
+	engines := make(map[string]string)
+	if envState.Spec != nil {
+		for _, p := range envState.Spec.Providers {
+			engines[p.Name] = p.Engine
+		}
+	}
+	for _, name := range []string{source, destination} {
+		if _, ok := engines[name]; !ok {
+			return fmt.Errorf("provider %q not found in environment %q", name, envState.ID)
+		}
+	}
+	if engines[source] != engines[destination] {
+		return fmt.Errorf("providers %q and %q use different engines (%s, %s)",
+			source, destination, engines[source], engines[destination])
+	}
+	return nil
+}
+
+// adoptRequest builds the vm_adopt input from the resource returned by
+// vm_migrate.
+func adoptRequest(resource any) (*providerv1.VMAdoptRequest, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	req := &providerv1.VMAdoptRequest{}
+	if err := json.Unmarshal(data, &req.State); err != nil {
+		return nil, fmt.Errorf("invalid vm state: %w", err)
+	}
+	if req.State.Name == "" {
+		return nil, errors.New("invalid vm state: missing name")
+	}
+	return req, nil
+}
+
+// ProviderError is returned when a provider call fails, either because the
+// provider could not be reached or because it reported an error.
+type ProviderError struct {
+	// Tool is the provider tool that failed.
+	Tool string
+	// Code is the error code reported by the provider, if any.
+	Code string
+	// Message is the error message reported by the provider, if any.
+	Message string
+	// Err is the error of the call itself, if any.
+	Err error
+}
+
+// Error implements error.
+func (e *ProviderError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s failed: %v", e.Tool, e.Err)
+	case e.Message != "":
+		return fmt.Sprintf("%s failed: %s", e.Tool, e.Message)
+	default:
+		return e.Tool + " failed"
+	}
+}
+
+// Unwrap returns the error of the call itself.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// operationError returns the error of a provider call, if any.
+func operationError(tool string, result *providerv1.OperationResult, err error) error {
+	if err != nil {
+		return &ProviderError{Tool: tool, Err: err}
+	}
+	if !result.Success {
+		if result.Error != nil {
+			return &ProviderError{Tool: tool, Code: result.Error.Code, Message: result.Error.Message}
+		}
+		return &ProviderError{Tool: tool}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateMigration(t *testing.T) {
+	envState := &v1.EnvironmentState{
+		ID: "env-migrate",
+		Spec: &v1.Spec{Providers: []v1.ProviderConfig{
+			{Name: "host-a", Engine: "go://cmd/providers/testenv-vm-provider-libvirt"},
+			{Name: "host-b", Engine: "go://cmd/providers/testenv-vm-provider-libvirt"},
+			{Name: "stub", Engine: "go://cmd/providers/testenv-vm-provider-stub"},
+		}},
+	}
+	tests := []struct {
+		name        string
+		destination string
+		wantErr     string
+	}{
+		{name: "same engine", destination: "host-b"},
+		{name: "missing destination", destination: "", wantErr: "required"},
+		{name: "same provider", destination: "host-a", wantErr: "already managed"},
+		{name: "unknown provider", destination: "host-c", wantErr: "not found"},
+		{name: "different engine", destination: "stub", wantErr: "different engines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMigration(envState, "host-a", tt.destination)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateMigration() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("validateMigration() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAdoptRequest(t *testing.T) {
+	req, err := adoptRequest(map[string]any{
+		"name": "web",
+		"ip":   "192.168.100.10",
+		"macs": map[string]any{"net": "52:54:00:00:00:01"},
+		"providerState": map[string]any{
+			"networks": []any{"net"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("adoptRequest() error = %v", err)
+	}
+	if req.State.Name != "web" || req.State.IP != "192.168.100.10" || req.State.MACs["net"] != "52:54:00:00:00:01" {
+		t.Errorf("adoptRequest() state = %+v", req.State)
+	}
+
+	if _, err := adoptRequest(map[string]any{"ip": "192.168.100.10"}); err == nil {
+		t.Error("adoptRequest() without a name should fail")
+	}
+}
+
+func TestOperationError(t *testing.T) {
+	if err := operationError("vm_get", providerv1.SuccessResult(nil), nil); err != nil {
+		t.Errorf("operationError() on success = %v", err)
+	}
+
+	callErr := errors.New("broken pipe")
+	err := operationError("vm_get", nil, callErr)
+	var perr *ProviderError
+	if !errors.As(err, &perr) || !errors.Is(err, callErr) || err.Error() != "vm_get failed: broken pipe" {
+		t.Errorf("operationError() on call failure = %v", err)
+	}
+
+	err = operationError("vm_stop", providerv1.ErrorResult(providerv1.NewOperationError(providerv1.ErrCodeTimeout, "guest did not power off")), nil)
+	if !errors.As(err, &perr) || perr.Code != providerv1.ErrCodeTimeout || err.Error() != "vm_stop failed: guest did not power off" {
+		t.Errorf("operationError() on provider error = %v", err)
+	}
+
+	if err := operationError("vm_stop", &providerv1.OperationResult{}, nil); err == nil || err.Error() != "vm_stop failed" {
+		t.Errorf("operationError() without error details = %v", err)
+	}
+}
+
+func TestOrchestrator_MigrateVMNotFound(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID:        "env-migrate",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{}},
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := o.MigrateVM(context.Background(), "env-migrate", "web", "host-b", MigrateOptions{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("MigrateVM() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// MetadataEnvironmentID is the artifact metadata key holding the resolved
+// environment ID. Delete reads it back to locate state when the environment ID
+// differs from the forge testID.
+const MetadataEnvironmentID = "testenv-vm.environmentId"
+
+// maxEnvironmentIDLength bounds environment IDs so that derived state file
+// names stay well within filesystem limits.
+const maxEnvironmentIDLength = 128
+
+// environmentIDPattern restricts environment IDs to characters that are safe
+// in file names, libvirt object names, and environment variable values.
+var environmentIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// EnvironmentIDTemplateData is the data passed to spec.environmentIdTemplate.
+type EnvironmentIDTemplateData struct {
+	// Env contains the environment variables passed in CreateInput.Env.
+	Env map[string]string
+	// Stage is the test stage name.
+	Stage string
+	// TestID is the testID assigned by forge.
+	TestID string
+}
+
+// ResolveEnvironmentID determines the environment ID for a create request.
+// Precedence: spec.environmentId, then spec.environmentIdTemplate, then the
+// forge testID. The returned bool reports whether the ID was requested by the
+// caller (as opposed to falling back to the testID).
+func ResolveEnvironmentID(input *v1.CreateInput, testenvSpec *v1.Spec) (string, bool, error) {
+	var id string
+	switch {
+	case testenvSpec.EnvironmentId != "":
+		id = testenvSpec.EnvironmentId
+	case testenvSpec.EnvironmentIdTemplate != "":
+		rendered, err := renderEnvironmentIDTemplate(testenvSpec.EnvironmentIdTemplate, input)
+		if err != nil {
+			return "", false, err
+		}
+		id = rendered
+	default:
+		return input.TestID, false, nil
+	}
+
+	if err := ValidateEnvironmentID(id); err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+// renderEnvironmentIDTemplate renders the naming template against the create input.
+// Missing keys are treated as errors so that an unset CI variable does not
+// silently produce a truncated or colliding ID.
+func renderEnvironmentIDTemplate(tmpl string, input *v1.CreateInput) (string, error) {
+	t, err := template.New("environmentId").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid environmentIdTemplate: %w", err)
+	}
+
+	data := EnvironmentIDTemplateData{
+		Env:    input.Env,
+		Stage:  input.Stage,
+		TestID: input.TestID,
+	}
+	if data.Env == nil {
+		data.Env = map[string]string{}
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render environmentIdTemplate: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// ValidateEnvironmentID checks that id is non-empty, bounded in length, and
+// only contains characters in [a-zA-Z0-9._-], starting with an alphanumeric.
+func ValidateEnvironmentID(id string) error {
+	if id == "" {
+		return fmt.Errorf("environment ID must not be empty")
+	}
+	if len(id) > maxEnvironmentIDLength {
+		return fmt.Errorf("environment ID %q exceeds %d characters", id, maxEnvironmentIDLength)
+	}
+	if !environmentIDPattern.MatchString(id) {
+		return fmt.Errorf("environment ID %q is invalid: must start with an alphanumeric character and contain only [a-zA-Z0-9._-]", id)
+	}
+	return nil
+}
+
+// ensureEnvironmentIDAvailable returns an error if the store already holds
+// state for id. Destroyed environments do not count as conflicts.
+func ensureEnvironmentIDAvailable(store *state.Store, id string) error {
+	if !store.Exists(id) {
+		return nil
+	}
+	existing, err := store.Load(id)
+	if err != nil {
+		return fmt.Errorf("environment ID %q already exists in state store: %w", id, err)
+	}
+	if existing.Status == v1.StatusDestroyed {
+		return nil
+	}
+	return fmt.Errorf("environment ID %q already exists in state store (status=%s, testID=%s)",
+		id, existing.Status, existing.TestID)
+}
+
+// environmentIDFromDeleteInput returns the environment ID recorded in the
+// artifact metadata, falling back to the forge testID.
+func environmentIDFromDeleteInput(input *v1.DeleteInput) string {
+	if input.Metadata != nil {
+		if id := input.Metadata[MetadataEnvironmentID]; id != "" {
+			return id
+		}
+	}
+	return input.TestID
+}
@@ -0,0 +1,280 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/specbuilder"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+func TestResolveEnvironmentID(t *testing.T) {
+	input := &v1.CreateInput{
+		TestID: "test-abc123",
+		Stage:  "e2e",
+		Env:    map[string]string{"CI_PIPELINE_ID": "4242"},
+	}
+
+	tests := []struct {
+		name          string
+		spec          *v1.Spec
+		want          string
+		wantRequested bool
+		wantErr       string
+	}{
+		{
+			name: "falls back to testID",
+			spec: &v1.Spec{},
+			want: "test-abc123",
+		},
+		{
+			name:          "explicit environment ID",
+			spec:          &v1.Spec{EnvironmentId: "ci-run-1"},
+			want:          "ci-run-1",
+			wantRequested: true,
+		},
+		{
+			name: "explicit ID takes precedence over template",
+			spec: &v1.Spec{
+				EnvironmentId:         "ci-run-1",
+				EnvironmentIdTemplate: "{{ .Stage }}",
+			},
+			want:          "ci-run-1",
+			wantRequested: true,
+		},
+		{
+			name:          "template with env and stage",
+			spec:          &v1.Spec{EnvironmentIdTemplate: "{{ .Env.CI_PIPELINE_ID }}-{{ .Stage }}"},
+			want:          "4242-e2e",
+			wantRequested: true,
+		},
+		{
+			name:          "template with testID",
+			spec:          &v1.Spec{EnvironmentIdTemplate: "ci-{{ .TestID }}"},
+			want:          "ci-test-abc123",
+			wantRequested: true,
+		},
+		{
+			name:    "template with missing env var",
+			spec:    &v1.Spec{EnvironmentIdTemplate: "{{ .Env.MISSING }}-{{ .Stage }}"},
+			wantErr: "failed to render",
+		},
+		{
+			name:    "template with invalid syntax",
+			spec:    &v1.Spec{EnvironmentIdTemplate: "{{ .Stage "},
+			wantErr: "invalid environmentIdTemplate",
+		},
+		{
+			name:    "explicit ID with invalid characters",
+			spec:    &v1.Spec{EnvironmentId: "ci/run 1"},
+			wantErr: "is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, requested, err := ResolveEnvironmentID(input, tt.spec)
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("ResolveEnvironmentID() expected error containing %q, got nil", tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ResolveEnvironmentID() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveEnvironmentID() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveEnvironmentID() = %q, want %q", got, tt.want)
+			}
+			if requested != tt.wantRequested {
+				t.Errorf("ResolveEnvironmentID() requested = %v, want %v", requested, tt.wantRequested)
+			}
+		})
+	}
+}
+
+func TestValidateEnvironmentID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id: "ci-4242-e2e", wantErr: false},
+		{id: "run_1.2", wantErr: false},
+		{id: "", wantErr: true},
+		{id: "-leading-dash", wantErr: true},
+		{id: "has space", wantErr: true},
+		{id: "has/slash", wantErr: true},
+		{id: strings.Repeat("a", maxEnvironmentIDLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			err := ValidateEnvironmentID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEnvironmentID(%q) error = %v, wantErr %v", tt.id, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnsureEnvironmentIDAvailable(t *testing.T) {
+	store := state.NewStore(t.TempDir())
+
+	if err := ensureEnvironmentIDAvailable(store, "ci-1"); err != nil {
+		t.Fatalf("unexpected error for unused ID: %v", err)
+	}
+
+	if err := store.Save(&v1.EnvironmentState{ID: "ci-1", TestID: "test-a", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	err := ensureEnvironmentIDAvailable(store, "ci-1")
+	if err == nil {
+		t.Fatal("expected error for ID already in use")
+	}
+	if !strings.Contains(err.Error(), "test-a") {
+		t.Errorf("error should mention owning testID, got: %v", err)
+	}
+
+	if err := store.Save(&v1.EnvironmentState{ID: "ci-2", Status: v1.StatusDestroyed}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := ensureEnvironmentIDAvailable(store, "ci-2"); err != nil {
+		t.Errorf("destroyed environment should not block reuse: %v", err)
+	}
+}
+
+func TestOrchestrator_Create_DuplicateEnvironmentID(t *testing.T) {
+	config := newTestConfig(t)
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	if err := orchestrator.store.Save(&v1.EnvironmentState{ID: "ci-dup", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	_, err = orchestrator.Create(context.Background(), &v1.CreateInput{
+		TestID: "test-new",
+		Stage:  "e2e",
+		TmpDir: t.TempDir(),
+		Spec: map[string]any{
+			"environmentId": "ci-dup",
+			"providers": []any{
+				map[string]any{"name": "stub", "engine": "go://does-not-matter", "default": true},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("Create() expected error for duplicate environment ID")
+	}
+	if !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Create() error = %v, want duplicate ID error", err)
+	}
+}
+
+func TestOrchestrator_Create_BuiltSpec(t *testing.T) {
+	config := newTestConfig(t)
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	if err := orchestrator.store.Save(&v1.EnvironmentState{ID: "ci-built", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The environment ID of a spec built with specbuilder is honored
+	_, err = orchestrator.Create(context.Background(), &v1.CreateInput{
+		TestID: "test-built",
+		Stage:  "e2e",
+		TmpDir: t.TempDir(),
+		Spec: specbuilder.New().
+			WithEnvironmentID("ci-built").
+			WithProvider("stub", "go://does-not-matter", specbuilder.ProviderDefault()).
+			MustBuild().
+			ToMap(),
+	})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Create() error = %v, want duplicate ID error", err)
+	}
+}
+
+func TestOrchestrator_Delete_UsesEnvironmentIDFromMetadata(t *testing.T) {
+	config := newTestConfig(t)
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	envState := &v1.EnvironmentState{
+		ID:     "ci-4242-e2e",
+		TestID: "test-xyz",
+		Status: v1.StatusReady,
+	}
+	if err := orchestrator.store.Save(envState); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	err = orchestrator.Delete(context.Background(), &v1.DeleteInput{
+		TestID:   "test-xyz",
+		Metadata: map[string]string{MetadataEnvironmentID: "ci-4242-e2e"},
+	})
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if orchestrator.store.Exists("ci-4242-e2e") {
+		t.Errorf("state file %s should be deleted", filepath.Join(config.StateDir, "state", "testenv-ci-4242-e2e.json"))
+	}
+}
+
+func TestOrchestrator_buildArtifact_EnvironmentID(t *testing.T) {
+	config := newTestConfig(t)
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+
+	envState := &v1.EnvironmentState{ID: "ci-4242-e2e", TestID: "test-xyz"}
+	artifact := orchestrator.buildArtifact("test-xyz", envState, nil)
+
+	if artifact.TestID != "test-xyz" {
+		t.Errorf("TestID = %s, want test-xyz", artifact.TestID)
+	}
+	if got := artifact.Metadata[MetadataEnvironmentID]; got != "ci-4242-e2e" {
+		t.Errorf("Metadata[%s] = %q, want ci-4242-e2e", MetadataEnvironmentID, got)
+	}
+	if got := artifact.Env["TESTENV_VM_ENVIRONMENT_ID"]; got != "ci-4242-e2e" {
+		t.Errorf("Env[TESTENV_VM_ENVIRONMENT_ID] = %q, want ci-4242-e2e", got)
+	}
+}
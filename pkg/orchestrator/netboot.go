@@ -0,0 +1,268 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// bootVM is a VM of a network as its boot files see it.
+type bootVM struct {
+	// Name is the name of the VM in the spec.
+	Name string
+	// MAC is the MAC address of the NIC of the VM on the network, and
+	// MACHyphen the same with hyphens, as pxelinux names configuration
+	// files and iPXE formats ${mac:hexhyp}.
+	MAC       string
+	MACHyphen string
+	// IP is the static IP of the VM, if any.
+	IP string
+}
+
+// bootFileData is the data of boot file templates.
+type bootFileData struct {
+	// Network is the name of the network in the spec, and Server the
+	// address of its gateway, serving the boot files.
+	Network string
+	Server  string
+	// VM is the VM a per-VM file is rendered for; VMs are every VM of the
+	// network.
+	VM  bootVM
+	VMs []bootVM
+}
+
+// bootFiles are the files rendered into the boot root of a network once it
+// exists and its gateway address is known.
+type bootFiles struct {
+	root  string
+	files []v1.BootFileSpec
+	data  bootFileData
+}
+
+// prepareBootRoot builds the boot root of a network whose TFTP server
+// declares artifacts or files, in the environment directory: a copy of the
+// TFTP root, if any, and the artifacts, downloaded once into the boot cache
+// of layout. The files are rendered by render once the network exists. It
+// returns nil for other networks.
+func prepareBootRoot(
+	ctx context.Context,
+	downloader *image.Downloader,
+	layout paths.Layout,
+	network string,
+	spec *v1.Spec,
+	envState *v1.EnvironmentState,
+	templateCtx *specpkg.TemplateContext,
+	isoConfig *IsolationConfig,
+	tftp *v1.TFTPSpec,
+) (*bootFiles, error) {
+	if tftp == nil || !tftp.Enabled || (len(tftp.Artifacts) == 0 && len(tftp.Files) == 0) {
+		return nil, nil
+	}
+	vms, err := bootVMs(spec, network, envState, templateCtx, isoConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	root := layout.Env(envState.ID).BootRoot(network)
+	// A replaced network starts from a fresh root
+	if err := os.RemoveAll(root); err != nil {
+		return nil, fmt.Errorf("failed to clear boot root: %w", err)
+	}
+	if tftp.Root != "" {
+		if err := os.CopyFS(root, os.DirFS(tftp.Root)); err != nil {
+			return nil, fmt.Errorf("failed to copy tftp root %s: %w", tftp.Root, err)
+		}
+	} else if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create boot root: %w", err)
+	}
+	for _, a := range tftp.Artifacts {
+		cached, err := fetchBootArtifact(ctx, downloader, layout.BootCacheDir(), a, envState.ID+"-"+network)
+		if err != nil {
+			return nil, err
+		}
+		if err := linkFile(cached, filepath.Join(root, a.Path)); err != nil {
+			return nil, fmt.Errorf("artifact %s: %w", a.Path, err)
+		}
+	}
+	return &bootFiles{root: root, files: tftp.Files, data: bootFileData{Network: network, VMs: vms}}, nil
+}
+
+// bootVMs returns the VMs of spec attached to network, with the MAC address
+// the seed of the environment gives their NIC on it (see seed.Apply) and
+// their static IP rewritten for isolation like the network CIDR.
+func bootVMs(
+	spec *v1.Spec,
+	network string,
+	envState *v1.EnvironmentState,
+	templateCtx *specpkg.TemplateContext,
+	isoConfig *IsolationConfig,
+) ([]bootVM, error) {
+	var vms []bootVM
+	for _, vm := range spec.Vms {
+		for i, n := range vmNetworks(vm.Spec) {
+			if n != network {
+				continue
+			}
+			if envState.Seed == "" {
+				return nil, fmt.Errorf("vm %q: boot files need the MAC addresses derived from an environment seed, which this environment predates", vm.Name)
+			}
+			mac := seed.MAC(envState.Seed, vm.Name, i)
+			b := bootVM{Name: vm.Name, MAC: mac, MACHyphen: strings.ReplaceAll(mac, ":", "-")}
+			if vm.Spec.Ip != "" && i == 0 {
+				ip, err := specpkg.RenderString(vm.Spec.Ip, templateCtx)
+				if err != nil {
+					return nil, fmt.Errorf("vm %q: failed to render ip: %w", vm.Name, err)
+				}
+				if isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
+					ip = strings.ReplaceAll(ip, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+				}
+				b.IP = ip
+			}
+			vms = append(vms, b)
+		}
+	}
+	return vms, nil
+}
+
+// fetchBootArtifact returns the path of an artifact in the boot cache,
+// downloading it unless it is cached already with the expected checksum.
+// Downloads go through a file named after owner, so that environments
+// fetching the same artifact do not collide.
+func fetchBootArtifact(ctx context.Context, downloader *image.Downloader, cacheDir string, a v1.BootArtifactSpec, owner string) (string, error) {
+	sum := sha256.Sum256([]byte(a.Url))
+	cached := filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+	if _, err := os.Stat(cached); err == nil {
+		if err := downloader.VerifyChecksum(cached, a.Sha256); err == nil {
+			return cached, nil
+		}
+	}
+
+	tmp := cached + "." + owner
+	if err := downloader.Download(ctx, a.Url, tmp); err != nil {
+		return "", fmt.Errorf("failed to download artifact %s: %w", a.Path, err)
+	}
+	if err := downloader.VerifyChecksum(tmp, a.Sha256); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("artifact %s: %w", a.Path, err)
+	}
+	if err := os.Rename(tmp, cached); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("failed to cache artifact %s: %w", a.Path, err)
+	}
+	return cached, nil
+}
+
+// linkFile hard-links src to dst, replacing dst, and copies it when both
+// are on different file systems.
+func linkFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return out.Close()
+}
+
+// render writes the boot files into the boot root for the gateway address
+// server. Files whose path is a template are written once per VM.
+func (b *bootFiles) render(server string) error {
+	data := b.data
+	data.Server = server
+	for _, f := range b.files {
+		if !specpkg.IsPerVMBootFile(f.Path) {
+			if err := writeBootFile(b.root, f.Path, f.Content, data); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, vm := range data.VMs {
+			vmData := data
+			vmData.VM = vm
+			if err := writeBootFile(b.root, f.Path, f.Content, vmData); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBootFile renders the path and content of a boot file with data and
+// writes it below root.
+func writeBootFile(root, pathTemplate, contentTemplate string, data bootFileData) error {
+	name, err := executeBootTemplate(pathTemplate, pathTemplate, data)
+	if err != nil {
+		return err
+	}
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("boot file %q: path must be relative to the boot root", name)
+	}
+	content, err := executeBootTemplate(name, contentTemplate, data)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory of boot file %s: %w", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write boot file %s: %w", name, err)
+	}
+	return nil
+}
+
+// executeBootTemplate renders a boot file template.
+func executeBootTemplate(name, text string, data bootFileData) (string, error) {
+	t, err := specpkg.ParseBootTemplate(name, text)
+	if err != nil {
+		return "", fmt.Errorf("boot file %q: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("boot file %q: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,171 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestPrepareBootRoot(t *testing.T) {
+	kernel := []byte("kernel")
+	var downloads atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write(kernel)
+	}))
+	defer server.Close()
+	downloader := image.NewDownloader(image.WithHTTPClient(server.Client()), image.WithMaxRetries(1))
+
+	tftpRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tftpRoot, "undionly.kpxe"), []byte("ipxe"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(kernel)
+	spec := &v1.Spec{Vms: []v1.VMResource{
+		{Name: "node1", Spec: v1.VMSpec{Ip: "192.168.150.10", Network: "pxe"}},
+		{Name: "node2", Spec: v1.VMSpec{Networks: []string{"lan", "pxe"}}},
+		{Name: "other", Spec: v1.VMSpec{Network: "lan"}},
+	}}
+	envState := &v1.EnvironmentState{ID: "abc", Seed: "0123456789abcdef"}
+	iso := &IsolationConfig{OriginalCIDRPrefix: "192.168.150.", NewCIDRPrefix: "192.168.42."}
+	tftp := &v1.TFTPSpec{
+		Enabled:   true,
+		Root:      tftpRoot,
+		Artifacts: []v1.BootArtifactSpec{{Path: "boot/vmlinuz", Url: server.URL + "/vmlinuz", Sha256: hex.EncodeToString(sum[:])}},
+		Files: []v1.BootFileSpec{
+			{Path: "boot.ipxe", Content: "#!ipxe\nchain http://[[ .Server ]]:8080/[[ `${mac:hexhyp}` ]].ipxe\n"},
+			{Path: "[[ .VM.MACHyphen ]].ipxe", Content: "[[ .Network ]] [[ .VM.Name ]] [[ .VM.IP ]]"},
+		},
+	}
+	layout := paths.New(t.TempDir())
+
+	for range 2 {
+		boot, err := prepareBootRoot(context.Background(), downloader, layout, "pxe", spec, envState, &specpkg.TemplateContext{}, iso, tftp)
+		if err != nil {
+			t.Fatalf("prepareBootRoot() error = %v", err)
+		}
+		if want := layout.Env("abc").BootRoot("pxe"); boot.root != want {
+			t.Errorf("root = %q, want %q", boot.root, want)
+		}
+		if err := boot.render("192.168.42.1"); err != nil {
+			t.Fatalf("render() error = %v", err)
+		}
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("artifact downloaded %d times, want once", n)
+	}
+
+	root := layout.Env("abc").BootRoot("pxe")
+	mac1 := strings.ReplaceAll(seed.MAC(envState.Seed, "node1", 0), ":", "-")
+	mac2 := strings.ReplaceAll(seed.MAC(envState.Seed, "node2", 1), ":", "-")
+	for name, want := range map[string]string{
+		"undionly.kpxe": "ipxe",
+		"boot/vmlinuz":  "kernel",
+		"boot.ipxe":     "#!ipxe\nchain http://192.168.42.1:8080/${mac:hexhyp}.ipxe\n",
+		mac1 + ".ipxe":  "pxe node1 192.168.42.10",
+		mac2 + ".ipxe":  "pxe node2 ",
+	} {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 5 {
+		t.Errorf("boot root has %d entries, want 5", len(entries))
+	}
+
+	// A corrupt cache entry is downloaded again
+	cached := filepath.Join(layout.BootCacheDir(), func() string {
+		s := sha256.Sum256([]byte(server.URL + "/vmlinuz"))
+		return hex.EncodeToString(s[:])
+	}())
+	if err := os.Remove(filepath.Join(root, "boot/vmlinuz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cached, []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prepareBootRoot(context.Background(), downloader, layout, "pxe", spec, envState, &specpkg.TemplateContext{}, iso, tftp); err != nil {
+		t.Fatalf("prepareBootRoot() error = %v", err)
+	}
+	if n := downloads.Load(); n != 2 {
+		t.Errorf("artifact downloaded %d times, want twice", n)
+	}
+}
+
+func TestPrepareBootRootErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("kernel"))
+	}))
+	defer server.Close()
+	downloader := image.NewDownloader(image.WithHTTPClient(server.Client()), image.WithMaxRetries(1))
+	layout := paths.New(t.TempDir())
+	spec := &v1.Spec{Vms: []v1.VMResource{{Name: "node1", Spec: v1.VMSpec{Network: "pxe"}}}}
+	envState := &v1.EnvironmentState{ID: "abc", Seed: "0123456789abcdef"}
+	prepare := func(envState *v1.EnvironmentState, tftp *v1.TFTPSpec) (*bootFiles, error) {
+		return prepareBootRoot(context.Background(), downloader, layout, "pxe", spec, envState, &specpkg.TemplateContext{}, nil, tftp)
+	}
+
+	if boot, err := prepare(envState, &v1.TFTPSpec{Enabled: true, Root: "/srv/tftp"}); boot != nil || err != nil {
+		t.Errorf("prepareBootRoot() without artifacts or files = %v, %v, want nil", boot, err)
+	}
+
+	files := &v1.TFTPSpec{Enabled: true, Files: []v1.BootFileSpec{{Path: "boot.ipxe"}}}
+	if _, err := prepare(&v1.EnvironmentState{ID: "abc"}, files); err == nil || !strings.Contains(err.Error(), "environment seed") {
+		t.Errorf("prepareBootRoot() without a seed error = %v, want an environment seed error", err)
+	}
+
+	artifact := &v1.TFTPSpec{Enabled: true, Artifacts: []v1.BootArtifactSpec{{Path: "vmlinuz", Url: server.URL, Sha256: strings.Repeat("0", 64)}}}
+	if _, err := prepare(envState, artifact); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("prepareBootRoot() with a wrong checksum error = %v, want a checksum error", err)
+	}
+	if entries, _ := os.ReadDir(layout.BootCacheDir()); len(entries) != 0 {
+		t.Errorf("boot cache has %d entries after a failed download, want none", len(entries))
+	}
+
+	missing := &v1.TFTPSpec{Enabled: true, Files: []v1.BootFileSpec{{Path: "boot.ipxe", Content: "[[ .VM.Missing ]]"}}}
+	boot, err := prepare(envState, missing)
+	if err != nil {
+		t.Fatalf("prepareBootRoot() error = %v", err)
+	}
+	if err := boot.render("192.168.150.1"); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("render() error = %v, want an unknown field error", err)
+	}
+
+	escaping := &v1.TFTPSpec{Enabled: true, Files: []v1.BootFileSpec{{Path: "[[ .VM.Name ]]/../../x"}}}
+	if boot, err = prepare(envState, escaping); err != nil {
+		t.Fatalf("prepareBootRoot() error = %v", err)
+	}
+	if err := boot.render("192.168.150.1"); err == nil || !strings.Contains(err.Error(), "relative to the boot root") {
+		t.Errorf("render() error = %v, want a boot root error", err)
+	}
+}
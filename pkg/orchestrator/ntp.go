@@ -0,0 +1,47 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// injectNTP points the cloud-init of a VM at the NTP servers of the networks
+// it is attached to, so that guests of isolated networks keep their clock in
+// sync. Networks are the names of the rendered VM spec, before the isolation
+// prefix is applied. VMs without such a network are left untouched.
+func injectNTP(vmSpec *providerv1.VMSpec, rendered v1.VMSpec, templateCtx *specpkg.TemplateContext) {
+	networks := vmNetworks(rendered)
+	var servers []string
+	seen := make(map[string]bool)
+	for _, name := range networks {
+		server := templateCtx.Networks[name].NTPServer
+		if server == "" || seen[server] {
+			continue
+		}
+		seen[server] = true
+		servers = append(servers, server)
+	}
+	if len(servers) == 0 {
+		return
+	}
+
+	if vmSpec.CloudInit == nil {
+		vmSpec.CloudInit = &providerv1.CloudInitSpec{}
+	}
+	vmSpec.CloudInit.NTPServers = append(vmSpec.CloudInit.NTPServers, servers...)
+}
@@ -0,0 +1,56 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"reflect"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestInjectNTP(t *testing.T) {
+	ctx := specpkg.NewTemplateContext()
+	ctx.Networks["airgap"] = specpkg.NetworkTemplateData{IP: "10.0.0.1", NTPServer: "10.0.0.1"}
+	ctx.Networks["storage"] = specpkg.NetworkTemplateData{IP: "10.1.0.1", NTPServer: "10.1.0.1"}
+	ctx.Networks["nat"] = specpkg.NetworkTemplateData{IP: "10.2.0.1"}
+
+	// Provider-level names carry the isolation prefix, the rendered ones not
+	vmSpec := providerv1.VMSpec{Networks: []string{"p-airgap", "p-nat", "p-storage"}}
+	injectNTP(&vmSpec, v1.VMSpec{Networks: []string{"airgap", "nat", "storage"}}, ctx)
+	if vmSpec.CloudInit == nil {
+		t.Fatal("CloudInit = nil, want NTP servers")
+	}
+	want := []string{"10.0.0.1", "10.1.0.1"}
+	if !reflect.DeepEqual(vmSpec.CloudInit.NTPServers, want) {
+		t.Errorf("NTPServers = %v, want %v", vmSpec.CloudInit.NTPServers, want)
+	}
+
+	var legacy providerv1.VMSpec
+	injectNTP(&legacy, v1.VMSpec{Network: "airgap"}, ctx)
+	if legacy.CloudInit == nil || !reflect.DeepEqual(legacy.CloudInit.NTPServers, []string{"10.0.0.1"}) {
+		t.Errorf("legacy network: CloudInit = %+v, want NTP server 10.0.0.1", legacy.CloudInit)
+	}
+
+	var plain providerv1.VMSpec
+	injectNTP(&plain, v1.VMSpec{Network: "nat"}, ctx)
+	if plain.CloudInit != nil {
+		t.Errorf("CloudInit = %+v, want nil for a network without NTP", plain.CloudInit)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+// Statuses of an Operation.
+const (
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	OperationCancelled = "cancelled"
+)
+
+// OperationKindCreate is the kind of the operations of CreateAsync.
+const OperationKindCreate = "create"
+
+// operationRetention is how long the records of ended operations are kept.
+const operationRetention = 7 * 24 * time.Hour
+
+// ErrOperationNotFound is returned for unknown operation IDs.
+var ErrOperationNotFound = errors.New("operation not found")
+
+// Operation is the record of an asynchronous operation. Records are files of
+// the state directory, so that clients can poll an operation from any
+// process, and they outlive the server for operationRetention.
+type Operation struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	TestID string `json:"testID,omitempty"`
+	// Status is OperationRunning until the operation ends.
+	Status    string     `json:"status"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+	// Artifact is the outcome of a successful creation. Its Env, which may
+	// hold secrets, is not recorded in the state directory.
+	Artifact *v1.TestEnvArtifact `json:"artifact,omitempty"`
+	// Error is the error of a failed or cancelled operation.
+	Error string `json:"error,omitempty"`
+	// PID is the process running the operation. Running operations of
+	// processes that exited are reported as failed.
+	PID int `json:"pid"`
+}
+
+// Ended reports whether the operation is over.
+func (op *Operation) Ended() bool {
+	return op.Status != OperationRunning
+}
+
+// newOperationID generates the ID of an operation.
+func newOperationID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate operation ID: %w", err)
+	}
+	return "op-" + hex.EncodeToString(b), nil
+}
+
+// operationStore stores operation records in a directory.
+type operationStore struct {
+	dir string
+}
+
+// operationStore returns the store of the operation records of o.
+func (o *Orchestrator) operationStore() operationStore {
+	return operationStore{dir: paths.New(o.config.StateDir).OperationsDir()}
+}
+
+// path returns the record file of an operation.
+func (s operationStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// save writes a record atomically, without the env of its artifact.
+func (s operationStore) save(op *Operation) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create operations directory: %w", err)
+	}
+	record := *op
+	if op.Artifact != nil {
+		artifact := *op.Artifact
+		artifact.Env = nil
+		record.Artifact = &artifact
+	}
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(op.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save operation %q: %w", op.ID, err)
+	}
+	if err := os.Rename(tmp, s.path(op.ID)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to save operation %q: %w", op.ID, err)
+	}
+	return nil
+}
+
+// load reads a record. Running operations whose process exited are returned
+// as failed.
+func (s operationStore) load(id string) (*Operation, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid operation ID %q", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrOperationNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation %q: %w", id, err)
+	}
+	op := &Operation{}
+	if err := json.Unmarshal(data, op); err != nil {
+		return nil, fmt.Errorf("invalid operation %q: %w", id, err)
+	}
+	if !op.Ended() && !processAlive(op.PID) {
+		op.Status = OperationFailed
+		op.Error = fmt.Sprintf("interrupted: process %d exited", op.PID)
+	}
+	return op, nil
+}
+
+// list returns every record, most recent first, and removes the records of
+// operations that ended more than operationRetention ago.
+func (s operationStore) list() ([]*Operation, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operations directory: %w", err)
+	}
+	var ops []*Operation
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		op, err := s.load(id)
+		if err != nil {
+			log.Printf("Skipping operation %s: %v", id, err)
+			continue
+		}
+		if op.EndedAt != nil && time.Since(*op.EndedAt) > operationRetention {
+			_ = os.Remove(s.path(id))
+			continue
+		}
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].StartedAt.After(ops[j].StartedAt) })
+	return ops, nil
+}
+
+// processAlive reports whether a process runs.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// Operation returns the record of an operation started by this or another
+// orchestrator sharing the state directory. It only reads state and is
+// therefore available in read-only mode.
+func (o *Orchestrator) Operation(id string) (*Operation, error) {
+	return o.operationStore().load(id)
+}
+
+// Operations returns the records of the operations started in the last
+// operationRetention, most recent first.
+func (o *Orchestrator) Operations() ([]*Operation, error) {
+	return o.operationStore().list()
+}
+
+// CancelOperation cancels a running operation of this orchestrator. A
+// cancelled creation rolls back like a failed one. Operations of other
+// processes cannot be cancelled.
+func (o *Orchestrator) CancelOperation(id string) (*Operation, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("cancel rejected: %w", ErrReadOnly)
+	}
+	op, err := o.Operation(id)
+	if err != nil {
+		return nil, err
+	}
+	if op.Ended() {
+		return nil, fmt.Errorf("operation %q already %s", id, op.Status)
+	}
+	v, ok := o.asyncOps.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("operation %q runs in process %d, not in this one", id, op.PID)
+	}
+	v.(*asyncCreate).cancelRequested.Store(true)
+	v.(*asyncCreate).cancel()
+	log.Printf("Cancelled operation %s", id)
+	return op, nil
+}
+
+// WaitOperation blocks until an operation ends and returns its record. It
+// polls the record of operations of other processes. It returns
+// ErrWaitTimeout if ctx expires first.
+func (o *Orchestrator) WaitOperation(ctx context.Context, id string) (*Operation, error) {
+	if v, ok := o.asyncOps.Load(id); ok {
+		select {
+		case <-v.(*asyncCreate).done:
+		case <-ctx.Done():
+			return nil, waitError(ctx, id)
+		}
+	}
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+	for {
+		op, err := o.Operation(id)
+		if err != nil || op.Ended() {
+			return op, err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, waitError(ctx, id)
+		}
+	}
+}
+
+// waitError returns the error of a wait for an operation whose ctx is done.
+func waitError(ctx context.Context, id string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("operation %q: %w", id, ErrWaitTimeout)
+	}
+	return ctx.Err()
+}
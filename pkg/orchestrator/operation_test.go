@@ -0,0 +1,154 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOperationStore(t *testing.T) {
+	s := operationStore{dir: t.TempDir()}
+	now := time.Now().UTC()
+	old := now.Add(-operationRetention - time.Hour)
+	for _, op := range []*Operation{
+		{ID: "op-running", Status: OperationRunning, StartedAt: now, PID: os.Getpid()},
+		{ID: "op-interrupted", Status: OperationRunning, StartedAt: now.Add(-time.Minute)},
+		{ID: "op-expired", Status: OperationSucceeded, StartedAt: old, EndedAt: &old},
+	} {
+		if err := s.save(op); err != nil {
+			t.Fatalf("save() error = %v", err)
+		}
+	}
+
+	op, err := s.load("op-interrupted")
+	if err != nil || op.Status != OperationFailed || op.Error == "" {
+		t.Errorf("load() of an operation whose process exited = %+v, %v", op, err)
+	}
+	if _, err := s.load("op-missing"); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("load() error = %v, want %v", err, ErrOperationNotFound)
+	}
+	if _, err := s.load("../state/testenv-x"); err == nil {
+		t.Error("load() of a path should fail")
+	}
+
+	ops, err := s.list()
+	if err != nil {
+		t.Fatalf("list() error = %v", err)
+	}
+	if len(ops) != 2 || ops[0].ID != "op-running" || ops[1].ID != "op-interrupted" {
+		t.Errorf("list() = %+v, want op-running then op-interrupted", ops)
+	}
+	if _, err := os.Stat(s.path("op-expired")); !os.IsNotExist(err) {
+		t.Error("list() should remove expired operations")
+	}
+
+	// The env of an artifact may hold secrets and is not recorded
+	created := &Operation{ID: "op-created", Status: OperationSucceeded, StartedAt: now, EndedAt: &now,
+		Artifact: &v1.TestEnvArtifact{TestID: "test-1", Env: map[string]string{"TOKEN": "s3cr3t"}}}
+	if err := s.save(created); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	if created.Artifact.Env["TOKEN"] != "s3cr3t" {
+		t.Error("save() must not change the operation")
+	}
+	data, err := os.ReadFile(s.path("op-created"))
+	if err != nil || strings.Contains(string(data), "s3cr3t") {
+		t.Errorf("record = %s, %v, want it without the env", data, err)
+	}
+}
+
+func TestOrchestrator_CancelOperation(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+	store := o.operationStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	running := &asyncCreate{
+		op:     &Operation{ID: "op-local", Status: OperationRunning, PID: os.Getpid()},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	ended := time.Now()
+	for _, op := range []*Operation{
+		running.op,
+		{ID: "op-remote", Status: OperationRunning, PID: 1},
+		{ID: "op-ended", Status: OperationSucceeded, PID: os.Getpid(), EndedAt: &ended},
+	} {
+		if err := store.save(op); err != nil {
+			t.Fatalf("save() error = %v", err)
+		}
+	}
+	o.asyncOps.Store("op-local", running)
+
+	if _, err := o.CancelOperation("op-local"); err != nil {
+		t.Fatalf("CancelOperation() error = %v", err)
+	}
+	if ctx.Err() == nil || !running.cancelRequested.Load() {
+		t.Error("CancelOperation() should cancel the creation")
+	}
+	if _, err := o.CancelOperation("op-remote"); err == nil {
+		t.Error("CancelOperation() of another process should fail")
+	}
+	if _, err := o.CancelOperation("op-ended"); err == nil {
+		t.Error("CancelOperation() of an ended operation should fail")
+	}
+}
+
+func TestOrchestrator_WaitOperation(t *testing.T) {
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	defer func() { waitPollInterval = orig }()
+
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+	store := o.operationStore()
+
+	// An operation of another process still running
+	op := &Operation{ID: "op-remote", Status: OperationRunning, PID: 1}
+	if err := store.save(op); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := o.WaitOperation(ctx, "op-remote"); !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("WaitOperation() error = %v, want %v", err, ErrWaitTimeout)
+	}
+
+	ended := time.Now()
+	op.Status, op.EndedAt = OperationSucceeded, &ended
+	if err := store.save(op); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+	got, err := o.WaitOperation(context.Background(), "op-remote")
+	if err != nil || got.Status != OperationSucceeded {
+		t.Errorf("WaitOperation() = %+v, %v", got, err)
+	}
+}
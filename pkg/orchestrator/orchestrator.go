@@ -19,17 +19,26 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/notify"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/policy"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
 )
@@ -37,13 +46,17 @@ import (
 // IsolationConfig holds per-test-environment isolation parameters that ensure
 // parallel test runs do not collide on host-level resources (libvirt names, subnets).
 type IsolationConfig struct {
-	// NamePrefix is a short hash derived from testID, used to prefix all
-	// provider resource names (keys, networks, VMs).
+	// NamePrefix is a short hash derived from testID, after the tenant and
+	// the name prefix of the spec if any, used to prefix all provider
+	// resource names (keys, networks, VMs).
 	NamePrefix string
 	// OriginalCIDRPrefix is the original subnet prefix from the spec (e.g., "192.168.100.").
 	OriginalCIDRPrefix string
 	// NewCIDRPrefix is the derived unique subnet prefix (e.g., "192.168.142.").
 	NewCIDRPrefix string
+	// InheritedNetworks maps the networks of a parent environment to their
+	// provider-level names, which carry the parent's prefix.
+	InheritedNetworks map[string]string
 }
 
 // shortHash returns the first 8 hex characters of the SHA-256 hash of s.
@@ -61,10 +74,20 @@ func hashToOctet(testID string) int {
 	return int(val%252) + 2
 }
 
-// newIsolationConfig creates an IsolationConfig from a testID and the spec's network CIDR.
-func newIsolationConfig(testID string, specNetworks []v1.NetworkResource) *IsolationConfig {
+// newIsolationConfig creates an IsolationConfig from the tenant, a testID,
+// the spec's name prefix and the spec's network CIDR. The subnet of a tenant
+// also depends on its name, since tenants choose their testIDs
+// independently.
+func newIsolationConfig(tenant, testID, namePrefix string, specNetworks []v1.NetworkResource) *IsolationConfig {
 	prefix := shortHash(testID)
+	if namePrefix != "" {
+		prefix = namePrefix + "-" + prefix
+	}
 	octet := hashToOctet(testID)
+	if tenant != "" {
+		prefix = tenant + "-" + prefix
+		octet = hashToOctet(tenant + "/" + testID)
+	}
 
 	// Find the original CIDR prefix from the first network in the spec.
 	// We extract the first three octets to use as the replacement source.
@@ -92,19 +115,97 @@ func newIsolationConfig(testID string, specNetworks []v1.NetworkResource) *Isola
 type Config struct {
 	// StateDir is the directory for state files.
 	StateDir string
+	// StateKey, if set, encrypts state files with AES-256-GCM (see
+	// state.WithKey).
+	StateKey []byte
+	// StateHistory is the number of revisions of the state of each
+	// environment kept for Diff. Zero keeps state.DefaultHistoryLimit
+	// revisions; a negative value disables the history.
+	StateHistory int
 	// ImageCacheDir is the directory for caching VM base images.
 	// If empty, defaults to TESTENV_VM_IMAGE_CACHE_DIR env var or /tmp/testenv-vm/images/.
 	ImageCacheDir string
+	// HTTPClient downloads images and network boot artifacts. If nil,
+	// http.DefaultClient is used; tests set it to trust fixture servers
+	// (see pkg/imagetest).
+	HTTPClient *http.Client
 	// CleanupOnFailure indicates whether to rollback on failure.
 	CleanupOnFailure bool
+	// Admitter evaluates admission policies against the validated spec before
+	// creation. If nil, every spec is admitted.
+	Admitter policy.Admitter
+	// ReadOnly rejects every mutating operation (ErrReadOnly) so that the
+	// orchestrator can be exposed to observers (dashboards, agents) without
+	// mutation rights; dry runs and reads are still served.
+	ReadOnly bool
+	// ArtifactDir, if set, replaces CreateInput.TmpDir as the parent of
+	// environment artifact directories.
+	ArtifactDir string
+	// Catalog is the directory or git source of spec templates served by
+	// OpenCatalog.
+	Catalog string
+	// AgentBinary is the path of the guest agent binary (cmd/testenv-vm-agent)
+	// injected into VMs that set spec.agent.enabled.
+	AgentBinary string
+	// DefaultProviders are used by specs that declare no providers.
+	DefaultProviders []v1.ProviderConfig
+	// Quotas limits the environments this orchestrator creates.
+	Quotas Quotas
+	// Admission queues creations that do not fit the free capacity of a
+	// host.
+	Admission Admission
+	// CIDRPool is the IPv4 prefix from which networks with cidr auto are
+	// allocated a /24. If empty, DefaultCIDRPool is used.
+	CIDRPool string
+	// LockFile is the provider lockfile providers are pinned to when it
+	// exists (see provider.WithLockFile). If empty, providers are not
+	// pinned.
+	LockFile string
+	// Tenant namespaces the resources of this orchestrator on a host shared
+	// by several teams: provider-level names start with "<tenant>-", they
+	// carry the providerv1.LabelTenant label, and GC only collects the
+	// resources and subnets of the tenant. StateDir is expected to be the
+	// state directory of the tenant (see paths.Layout.Tenant).
+	Tenant string
+	// Tenants are the tenants of the host, whose resources GC leaves alone
+	// when Tenant is empty.
+	Tenants []string
+	// HostStateDir is the state directory shared by the tenants of a host,
+	// holding the CIDR pool and the admission queue. If empty, StateDir is
+	// used.
+	HostStateDir string
 }
 
+// ErrReadOnly is returned by mutating operations when Config.ReadOnly is set.
+var ErrReadOnly = errors.New("orchestrator is in read-only mode")
+
+// ErrInvalidSpec is wrapped by errors of specs that fail validation.
+var ErrInvalidSpec = errors.New("spec validation failed")
+
 // Orchestrator coordinates resource creation and deletion.
 type Orchestrator struct {
 	config   Config
 	manager  *provider.Manager
 	store    *state.Store
 	executor *Executor
+
+	// ops tracks in-flight Create, Update and Delete calls for Shutdown.
+	ops operations
+	// metrics counts operations for MetricsHandler.
+	metrics metrics
+	// slots limits the creations running at once.
+	slots *slotQueue
+	// captures maps the IDs of the captures started by StartCapture to
+	// their provider.
+	captures sync.Map
+	// asyncCreates maps testIDs to the last creation started for them by
+	// CreateAsync.
+	asyncCreates sync.Map
+	// asyncOps maps the IDs of the running operations of this orchestrator
+	// to their creation.
+	asyncOps sync.Map
+	// subscribers receives the lifecycle events of every environment.
+	subscribers subscribers
 }
 
 // CreateResult contains the results of Orchestrator.Create.
@@ -120,11 +221,21 @@ type CreateResult struct {
 
 // NewOrchestrator creates a new Orchestrator with the given configuration.
 func NewOrchestrator(config Config) (*Orchestrator, error) {
-	// Create provider manager
-	manager := provider.NewManager()
+	// Create provider manager, capturing provider stderr under StateDir
+	manager := provider.NewManager(
+		provider.WithLogDir(paths.New(config.StateDir).LogsDir()),
+		provider.WithLockFile(config.LockFile),
+	)
 
 	// Create state store with config.StateDir
-	store := state.NewStore(config.StateDir)
+	var storeOpts []state.Option
+	if config.StateKey != nil {
+		storeOpts = append(storeOpts, state.WithKey(config.StateKey))
+	}
+	if config.StateHistory != 0 {
+		storeOpts = append(storeOpts, state.WithHistoryLimit(config.StateHistory))
+	}
+	store := state.NewStore(config.StateDir, storeOpts...)
 
 	// Determine image cache directory
 	imageCacheDir := config.ImageCacheDir
@@ -136,48 +247,162 @@ func NewOrchestrator(config Config) (*Orchestrator, error) {
 	}
 
 	// Create image cache manager
-	imageMgr, err := image.NewCacheManager(imageCacheDir)
+	downloader := image.NewDownloader()
+	if config.HTTPClient != nil {
+		downloader = image.NewDownloader(image.WithHTTPClient(config.HTTPClient))
+	}
+	imageMgr, err := image.NewCacheManager(imageCacheDir, image.WithDownloader(downloader))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create image cache manager: %w", err)
 	}
 
 	// Create executor with manager, store, and image cache manager
 	executor := NewExecutor(manager, store, imageMgr)
+	executor.downloader = downloader
+	executor.agentBinary = config.AgentBinary
+	executor.tenant = config.Tenant
+	executor.stateDir = stateDirLabel(config.StateDir)
 
 	return &Orchestrator{
 		config:   config,
 		manager:  manager,
 		store:    store,
 		executor: executor,
+		slots:    newSlotQueue(config.Admission.MaxConcurrent),
 	}, nil
 }
 
 // Create creates a new test environment from the given input.
 // Returns CreateResult containing the artifact and a RuntimeProvisioner
 // for runtime VM creation during tests.
-func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*CreateResult, error) {
+func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (created *CreateResult, err error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("create rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create rejected: %w", err)
+	}
+	defer end()
+	o.metrics.inFlight.Add(1)
+	defer func() {
+		o.metrics.inFlight.Add(-1)
+		o.metrics.observeCreate(err)
+	}()
+
 	log.Printf("Creating test environment: testID=%s, stage=%s", input.TestID, input.Stage)
 
-	// 1. Parse spec from input.Spec using v1.SpecFromMap (generated)
-	testenvSpec, err := v1.SpecFromMap(input.Spec)
+	// 1. Parse spec from input.Spec using v1.SpecFromMap (generated),
+	// fetching it first when it references a git source
+	testenvSpec, specSource, err := o.parseSpec(ctx, input.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(testenvSpec.Providers) == 0 {
+		testenvSpec.Providers = append([]v1.ProviderConfig(nil), o.config.DefaultProviders...)
+	}
+
+	// 2. Resolve the environment ID. Requested IDs must not collide with
+	// existing state so that state files and resources map to a single run.
+	envID, requested, err := ResolveEnvironmentID(input, testenvSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve environment ID: %w", err)
+	}
+	unlock, err := o.store.Lock(ctx, envID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if requested {
+		if err := ensureEnvironmentIDAvailable(o.store, envID); err != nil {
+			return nil, err
+		}
+		log.Printf("Using requested environment ID: %s", envID)
+	}
+
+	// Build the notification dispatcher up front so misconfigured webhooks
+	// and notifiers fail before any resource is created.
+	dispatcher, err := o.dispatcher(testenvSpec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse spec: %w", err)
+		return nil, fmt.Errorf("invalid notification configuration: %w", err)
 	}
 
-	// 2. Generate isolation config for parallel test execution.
-	// This derives unique resource name prefixes and subnet from the testID.
-	isoConfig := newIsolationConfig(input.TestID, testenvSpec.Networks)
+	// Generate isolation config for parallel test execution.
+	// This derives unique resource name prefixes and subnet from the environment ID.
+	isoConfig := newIsolationConfig(o.config.Tenant, envID, testenvSpec.NamePrefix, testenvSpec.Networks)
 	log.Printf("Isolation config: prefix=%s, originalCIDR=%s, newCIDR=%s",
 		isoConfig.NamePrefix, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
 
 	// 3. Validate spec using spec.ValidateEarly (Phase 1)
 	templatedFields, err := spec.ValidateEarly(testenvSpec)
 	if err != nil {
-		return nil, fmt.Errorf("spec validation failed: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+
+	// Keys and networks of a parent environment are used as they are. VMs
+	// attaching to its networks get the parent's addresses unless the spec
+	// declares networks of its own.
+	inheritedKeys, inheritedNetworks, parentIso, err := o.parentResources(envID, testenvSpec)
+	if err != nil {
+		return nil, err
+	}
+	if parentIso != nil {
+		inheritNetworks(isoConfig, inheritedNetworks)
+		if len(testenvSpec.Networks) == 0 {
+			isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix = parentIso.OriginalCIDRPrefix, parentIso.NewCIDRPrefix
+		}
+	}
+
+	// The creation deadline runs from here, so it also covers provider
+	// startup; it only cancels admission and the execution of phases, not
+	// the cleanup and notifications that follow.
+	execCtx := ctx
+	createDeadline, _ := spec.CreateDeadline(testenvSpec) // validated above
+	if createDeadline > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeoutCause(ctx, createDeadline, ErrCreateDeadlineExceeded)
+		defer cancel()
+	}
+
+	if err := checkQuotas(o.store, envID, testenvSpec, o.config.Quotas); err != nil {
+		return nil, err
+	}
+	if err := checkSeed(o.store, envID, testenvSpec.Seed); err != nil {
+		return nil, err
+	}
+
+	// Run admission policies against the validated spec before anything is created.
+	if err := policy.Check(ctx, o.config.Admitter, &policy.Request{
+		EnvironmentID: envID,
+		TestID:        input.TestID,
+		Stage:         input.Stage,
+		Spec:          testenvSpec,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Wait for a slot when the server limits concurrent creations; the wait
+	// counts against the creation deadline.
+	requester := input.Metadata[MetadataClient]
+	releaseSlot, err := o.slots.acquire(execCtx, requester, func(position int) {
+		log.Printf("Creation of environment %q queued at position %d (client %q)", envID, position, requester)
+		dispatcher.Notify(ctx, newQueuedEvent(envID, input, requester, position))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creation queue interrupted: %w", err)
 	}
+	defer releaseSlot()
 
-	// 4. Create artifact directory: {input.TmpDir}/{input.TestID}/
-	artifactDir := filepath.Join(input.TmpDir, input.TestID)
+	// 4. Create artifact directory: {input.TmpDir}/{envID}/ unless overridden,
+	// or the artifacts directory of the environment when neither is set
+	artifactParent := input.TmpDir
+	if o.config.ArtifactDir != "" {
+		artifactParent = o.config.ArtifactDir
+	}
+	artifactDir := paths.New(o.config.StateDir).Env(envID).ArtifactsDir()
+	if artifactParent != "" {
+		artifactDir = filepath.Join(artifactParent, envID)
+	}
 	if err := os.MkdirAll(artifactDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create artifact directory %q: %w", artifactDir, err)
 	}
@@ -190,6 +415,34 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		}
 	}
 
+	// Verify advertised capabilities now that providers are running, including
+	// both ends of cross-provider template references.
+	if err := verifyProviderCapabilities(o.manager, testenvSpec); err != nil {
+		return nil, fmt.Errorf("capability check failed: %w", err)
+	}
+
+	// Fail before creating anything when the VMs cannot fit on a host.
+	capacity, err := planCapacity(testenvSpec, hostCapacities(o.manager))
+	if err != nil {
+		return nil, fmt.Errorf("capacity check failed: %w", err)
+	}
+	for _, c := range capacity {
+		for _, w := range c.Warnings {
+			log.Printf("Capacity warning for provider %q: %s", c.Provider, w)
+		}
+	}
+	if err := capacityError(capacity); err != nil {
+		return nil, err
+	}
+
+	// Queue while hosts lack free memory or creations of higher priority
+	// wait; the queue counts against the creation deadline.
+	release, err := o.admit(execCtx, envID, testenvSpec, capacity)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// 5. Build DAG using BuildDAG
 	dag, err := BuildDAG(testenvSpec)
 	if err != nil {
@@ -202,15 +455,37 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		return nil, fmt.Errorf("failed to compute execution phases: %w", err)
 	}
 
+	// The test CA is created before any resource so that every VM can
+	// trust it and every certificate be issued by it.
+	var ca spec.CATemplateData
+	if needsCA(testenvSpec) {
+		if ca, err = buildCA(testenvSpec, artifactDir); err != nil {
+			return nil, fmt.Errorf("failed to create the test CA: %w", err)
+		}
+	}
+
+	// Identifiers that would otherwise be random derive from a seed recorded
+	// in state, so that the environment can be reproduced from it.
+	envSeed := testenvSpec.Seed
+	if envSeed == "" {
+		if envSeed, err = seed.New(); err != nil {
+			return nil, err
+		}
+	}
+	log.Printf("Environment %s uses seed %s", envID, envSeed)
+
 	// 6. Create initial state (EnvironmentState with status=StatusCreating)
 	now := time.Now().UTC().Format(time.RFC3339)
 	envState := &v1.EnvironmentState{
-		ID:          input.TestID,
+		ID:          envID,
+		TestID:      input.TestID,
 		Stage:       input.Stage,
 		Status:      v1.StatusCreating,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		Spec:        testenvSpec,
+		SpecSource:  specSource,
+		Seed:        envSeed,
 		ArtifactDir: artifactDir,
 		Resources: v1.ResourceMap{
 			Keys:     make(map[string]*v1.ResourceState),
@@ -220,14 +495,36 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		ExecutionPlan: buildExecutionPlan(phases),
 		Errors:        []v1.ErrorRecord{},
 	}
+	for name, rs := range inheritedKeys {
+		envState.Resources.Keys[name] = rs
+	}
+	for name, rs := range inheritedNetworks {
+		envState.Resources.Networks[name] = rs
+	}
+
+	// Networks with cidr auto get a subnet of the CIDR pool, released on
+	// delete
+	if envState.CIDRs, err = o.allocateCIDRs(envID, testenvSpec.Networks); err != nil {
+		return nil, err
+	}
 
 	// 7. Save state
 	if err := o.store.Save(envState); err != nil {
+		if releaseErr := o.cidrPool().release(envID, nil); releaseErr != nil {
+			log.Printf("Failed to release CIDRs: %v", releaseErr)
+		}
 		return nil, fmt.Errorf("failed to save initial state: %w", err)
 	}
 
 	// 8. Create template context using spec.NewTemplateContext()
 	templateCtx := spec.NewTemplateContext()
+	templateCtx.CA = ca
+	for name, rs := range inheritedKeys {
+		o.executor.updateTemplateContext(templateCtx, v1.ResourceRef{Kind: "key", Name: name}, rs.State)
+	}
+	for name, rs := range inheritedNetworks {
+		o.executor.updateTemplateContext(templateCtx, v1.ResourceRef{Kind: "network", Name: name}, rs.State)
+	}
 
 	// 9. Populate template context Env from input.Env
 	if input.Env != nil {
@@ -237,13 +534,40 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 	}
 
 	// 10. Execute phases using executor.ExecuteCreate (with templated fields for Phase 2 validation)
-	result, err := o.executor.ExecuteCreate(ctx, testenvSpec, phases, templateCtx, envState, templatedFields, isoConfig)
+	result, err := o.executor.ExecuteCreate(execCtx, testenvSpec, phases, templateCtx, envState, templatedFields, isoConfig)
 	if err != nil {
 		return nil, fmt.Errorf("execution error: %w", err)
 	}
+	if !result.Success && errors.Is(context.Cause(execCtx), ErrCreateDeadlineExceeded) {
+		deadlineErr := deadlineError(createDeadline, result.Durations)
+		result.Errors = append(result.Errors, deadlineErr)
+		envState.Errors = append(envState.Errors, v1.ErrorRecord{
+			Operation: "create",
+			Error:     deadlineErr.Error(),
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+	}
+
+	// Emit access point client configs now that server IPs are known.
+	if result.Success {
+		if err := writeAccessClientConfigs(testenvSpec, templateCtx, artifactDir); err != nil {
+			result.Success = false
+			result.Errors = append(result.Errors, err)
+		}
+	}
+
+	// Map VM names to IPs in every VM now that all of them are ready.
+	if result.Success && testenvSpec.HostsFile {
+		if err := o.pushHosts(ctx, envState); err != nil {
+			result.Success = false
+			result.Errors = append(result.Errors, err)
+		}
+	}
 
 	// 11. If error and CleanupOnFailure: rollback, update state to failed, return error
 	if !result.Success {
+		// Cleanup must run even if the failure is a cancellation (e.g. shutdown).
+		ctx := context.WithoutCancel(ctx)
 		if o.config.CleanupOnFailure {
 			log.Printf("Execution failed, performing rollback")
 			rollbackErrors := o.executor.Rollback(ctx, envState, isoConfig)
@@ -264,6 +588,14 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		for _, e := range result.Errors {
 			errMsgs = append(errMsgs, e.Error())
 		}
+
+		failedEvent := newLifecycleEvent(notify.EventFailed, envState)
+		failedEvent.Errors = errMsgs
+		if len(envState.Errors) > 0 {
+			failedEvent.FailedResource = envState.Errors[0].Resource.Kind + "/" + envState.Errors[0].Resource.Name
+		}
+		dispatcher.Notify(ctx, failedEvent)
+
 		return nil, fmt.Errorf("create failed: %v", errMsgs)
 	}
 
@@ -274,6 +606,17 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		return nil, fmt.Errorf("failed to save ready state: %w", err)
 	}
 
+	// Record the topology diagram; it is informational, so failures are not fatal.
+	if err := writeTopology(envState); err != nil {
+		log.Printf("Failed to write topology diagram: %v", err)
+	}
+	if err := writeKnownHosts(envState); err != nil {
+		log.Printf("Failed to write known_hosts: %v", err)
+	}
+	if err := o.writeManifest(envState, templateCtx); err != nil {
+		log.Printf("Failed to write manifest: %v", err)
+	}
+
 	// 13. Build TestEnvArtifact
 	artifact := o.buildArtifact(input.TestID, envState, isoConfig)
 
@@ -289,7 +632,9 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 		return nil, fmt.Errorf("failed to create RuntimeProvisioner: %w", err)
 	}
 
-	log.Printf("Test environment created successfully: testID=%s", input.TestID)
+	dispatcher.Notify(ctx, newLifecycleEvent(notify.EventReady, envState))
+
+	log.Printf("Test environment created successfully: testID=%s, environmentID=%s", input.TestID, envID)
 	return &CreateResult{
 		Artifact:    artifact,
 		Provisioner: provisioner,
@@ -298,24 +643,52 @@ func (o *Orchestrator) Create(ctx context.Context, input *v1.CreateInput) (*Crea
 
 // Delete deletes a test environment.
 func (o *Orchestrator) Delete(ctx context.Context, input *v1.DeleteInput) error {
-	log.Printf("Deleting test environment: testID=%s", input.TestID)
+	if o.config.ReadOnly {
+		return fmt.Errorf("delete rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("delete rejected: %w", err)
+	}
+	defer end()
+	o.metrics.inFlight.Add(1)
+	defer o.metrics.inFlight.Add(-1)
+	o.metrics.deleteTotal.Add(1)
+
+	envID := environmentIDFromDeleteInput(input)
+	log.Printf("Deleting test environment: testID=%s, environmentID=%s", input.TestID, envID)
+
+	unlock, err := o.store.Lock(ctx, envID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	// 1. Load state from store using input.TestID
-	envState, err := o.store.Load(input.TestID)
+	// 1. Load state from store using the environment ID
+	envState, err := o.store.Load(envID)
 	if err != nil {
 		// 2. If not found, return success (already deleted)
 		if os.IsNotExist(err) {
-			log.Printf("State not found for testID %s, assuming already deleted", input.TestID)
+			log.Printf("State not found for environment %s, assuming already deleted", envID)
 			return nil
 		}
 		// Check if the error message indicates "not found"
 		if isNotFoundError(err) {
-			log.Printf("State not found for testID %s, assuming already deleted", input.TestID)
+			log.Printf("State not found for environment %s, assuming already deleted", envID)
 			return nil
 		}
 		return fmt.Errorf("failed to load state: %w", err)
 	}
 
+	// Child environments use keys and networks of this one
+	children, err := o.childEnvironments(envID)
+	if err != nil {
+		return fmt.Errorf("failed to list child environments: %w", err)
+	}
+	if len(children) > 0 {
+		return fmt.Errorf("delete rejected: %w: %s", ErrHasChildren, strings.Join(children, ", "))
+	}
+
 	// 3. Update state to StatusDestroying
 	envState.Status = v1.StatusDestroying
 	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
@@ -337,38 +710,83 @@ func (o *Orchestrator) Delete(ctx context.Context, input *v1.DeleteInput) error
 		}
 	}
 
-	// 5. Re-derive isolation config from testID (same deterministic hash)
+	// 5. Re-derive isolation config from the environment ID (same deterministic hash)
+	var namePrefix string
 	var networks []v1.NetworkResource
 	if envState.Spec != nil {
-		networks = envState.Spec.Networks
+		namePrefix, networks = envState.Spec.NamePrefix, envState.Spec.Networks
 	}
-	isoConfig := newIsolationConfig(input.TestID, networks)
+	isoConfig := newIsolationConfig(o.config.Tenant, envID, namePrefix, networks)
 
 	// 6. Execute delete in reverse order using executor.ExecuteDelete
-	if err := o.executor.ExecuteDelete(ctx, envState, isoConfig); err != nil {
-		log.Printf("Delete completed with errors: %v", err)
+	deleteErr := o.executor.ExecuteDelete(ctx, envState, isoConfig)
+	if deleteErr != nil {
+		log.Printf("Delete completed with errors: %v", deleteErr)
 		// Continue anyway - best effort
 	}
 
 	// 6. Delete state file
-	if err := o.store.Delete(input.TestID); err != nil {
+	if err := o.store.Delete(envID); err != nil {
 		log.Printf("Failed to delete state file: %v", err)
 		// Continue anyway - best effort
 	}
+	if err := o.cidrPool().release(envID, nil); err != nil {
+		log.Printf("Failed to release CIDRs: %v", err)
+		// Continue anyway - best effort
+	}
 
-	// 7. Remove artifact directory if exists
+	// 7. Remove artifact directory if exists, then the environment directory
+	// holding files providers and the executor wrote for it. The latter is
+	// kept when a resource was not destroyed, since it may still use its
+	// disks and keys.
 	if envState.ArtifactDir != "" {
 		if err := os.RemoveAll(envState.ArtifactDir); err != nil {
 			log.Printf("Failed to remove artifact directory %q: %v", envState.ArtifactDir, err)
 			// Continue anyway - best effort
 		}
 	}
+	envDir := paths.New(o.config.StateDir).Env(envID)
+	if deleteErr == nil && allDestroyed(envState) {
+		if err := envDir.Remove(); err != nil {
+			log.Printf("%v", err)
+			// Continue anyway - best effort
+		}
+	} else {
+		log.Printf("Keeping environment directory %q: not every resource was destroyed", envDir.Dir)
+	}
 
-	// 8. Return nil (best-effort, don't fail on cleanup errors)
-	log.Printf("Test environment deleted: testID=%s", input.TestID)
+	// 8. Notify webhooks, chat notifiers and subscribers (best-effort)
+	dispatcher, err := o.dispatcher(envState.Spec)
+	if err != nil {
+		// Subscribers are still told even if the spec's notifiers are invalid
+		log.Printf("Skipping destroyed notification to spec notifiers: %v", err)
+		dispatcher = notify.NewDispatcherFromNotifiers(&o.subscribers)
+	}
+	dispatcher.Notify(ctx, newLifecycleEvent(notify.EventDestroyed, envState))
+
+	// 9. Return nil (best-effort, don't fail on cleanup errors)
+	log.Printf("Test environment deleted: testID=%s, environmentID=%s", input.TestID, envID)
 	return nil
 }
 
+// allDestroyed reports whether every resource recorded in the state of an
+// environment reached StatusDestroyed.
+func allDestroyed(envState *v1.EnvironmentState) bool {
+	for _, resources := range []map[string]*v1.ResourceState{
+		envState.Resources.Keys,
+		envState.Resources.Networks,
+		envState.Resources.VMs,
+		envState.Resources.Services,
+	} {
+		for _, rs := range resources {
+			if rs.Status != v1.StatusDestroyed {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 // Close stops all providers.
 func (o *Orchestrator) Close() error {
 	return o.manager.StopAll()
@@ -403,6 +821,11 @@ func (o *Orchestrator) buildArtifact(testID string, envState *v1.EnvironmentStat
 		Env:              make(map[string]string),
 	}
 
+	// Record the environment ID so Delete can locate state and tests can
+	// trace resources back to the originating run.
+	artifact.Metadata[MetadataEnvironmentID] = envState.ID
+	artifact.Env["TESTENV_VM_ENVIRONMENT_ID"] = envState.ID
+
 	// Export isolation config as environment variables for test code
 	if isoConfig != nil {
 		artifact.Env["TESTENV_VM_NAME_PREFIX"] = isoConfig.NamePrefix
@@ -462,6 +885,98 @@ func (o *Orchestrator) buildArtifact(testID string, envState *v1.EnvironmentStat
 			fmt.Sprintf("testenv-vm://network/%s", name))
 	}
 
+	// Map topology diagrams
+	if envState.ArtifactDir != "" {
+		for key, file := range map[string]string{
+			"testenv-vm.topology.mermaid": topologyMermaidFile,
+			"testenv-vm.topology.svg":     topologySVGFile,
+		} {
+			if _, err := os.Stat(filepath.Join(envState.ArtifactDir, file)); err == nil {
+				artifact.Files[key] = file
+			}
+		}
+	}
+
+	// Map the hosts file pushed to the VMs
+	if envState.ArtifactDir != "" && envState.Spec != nil && envState.Spec.HostsFile {
+		if _, err := os.Stat(filepath.Join(envState.ArtifactDir, hostsFile)); err == nil {
+			artifact.Files["testenv-vm.hosts"] = hostsFile
+		}
+	}
+
+	// Map the known_hosts file of collected SSH host keys
+	if envState.ArtifactDir != "" {
+		path := filepath.Join(envState.ArtifactDir, knownHostsFile)
+		if _, err := os.Stat(path); err == nil {
+			artifact.Files["testenv-vm.known_hosts"] = knownHostsFile
+			artifact.Env["TESTENV_VM_KNOWN_HOSTS"] = path
+		}
+	}
+
+	// Map the environment manifest and its digest
+	if envState.ArtifactDir != "" {
+		if digest, err := readManifestDigest(envState.ArtifactDir); err == nil {
+			artifact.Files["testenv-vm.manifest"] = manifestFile
+			artifact.Metadata["testenv-vm.manifest.sha256"] = digest
+		}
+	}
+
+	// Map access point client configs
+	if envState.Spec != nil && envState.ArtifactDir != "" {
+		for _, access := range envState.Spec.Access {
+			file := accessClientConfigFile(access.Name)
+			path := filepath.Join(envState.ArtifactDir, file)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			artifact.Files[fmt.Sprintf("testenv-vm.access.%s", access.Name)] = file
+			artifact.Env[fmt.Sprintf("TESTENV_ACCESS_%s_CONFIG", toEnvVarName(access.Name))] = path
+		}
+	}
+
+	// Map the test CA and certificates
+	if envState.ArtifactDir != "" {
+		path := filepath.Join(envState.ArtifactDir, CertsDir, "ca.crt")
+		if _, err := os.Stat(path); err == nil {
+			artifact.Files["testenv-vm.ca.cert"] = filepath.Join(CertsDir, "ca.crt")
+			artifact.Env["TESTENV_CA_CERT"] = path
+		}
+	}
+	if envState.Spec != nil && envState.ArtifactDir != "" {
+		for _, cert := range envState.Spec.Certificates {
+			for suffix, file := range map[string]string{"cert": cert.Name + ".crt", "key": cert.Name + ".key"} {
+				path := filepath.Join(envState.ArtifactDir, CertsDir, file)
+				if _, err := os.Stat(path); err != nil {
+					continue
+				}
+				artifact.Files[fmt.Sprintf("testenv-vm.certificate.%s.%s", cert.Name, suffix)] = filepath.Join(CertsDir, file)
+				artifact.Env[fmt.Sprintf("TESTENV_CERT_%s_%s", toEnvVarName(cert.Name), strings.ToUpper(suffix))] = path
+			}
+		}
+	}
+
+	// Map service endpoints
+	for name, svcState := range envState.Resources.Services {
+		if endpoint := getString(svcState.State, serviceEndpointKey); endpoint != "" {
+			artifact.Metadata[fmt.Sprintf("testenv-vm.service.%s.endpoint", name)] = endpoint
+			artifact.Env[fmt.Sprintf("TESTENV_SERVICE_%s_ENDPOINT", toEnvVarName(name))] = endpoint
+		}
+		if accessKey := getString(svcState.State, serviceAccessKeyKey); accessKey != "" {
+			artifact.Env[fmt.Sprintf("TESTENV_SERVICE_%s_ACCESS_KEY", toEnvVarName(name))] = accessKey
+			artifact.Env[fmt.Sprintf("TESTENV_SERVICE_%s_SECRET_KEY", toEnvVarName(name))] = getString(svcState.State, serviceSecretKeyKey)
+		}
+		if output := getString(svcState.State, serviceOutputKey); output != "" {
+			if envState.ArtifactDir != "" {
+				if rel, err := filepath.Rel(envState.ArtifactDir, output); err == nil {
+					artifact.Files[fmt.Sprintf("testenv-vm.service.%s.log", name)] = rel
+				}
+			}
+			artifact.Env[fmt.Sprintf("TESTENV_SERVICE_%s_LOG", toEnvVarName(name))] = output
+		}
+		artifact.ManagedResources = append(artifact.ManagedResources,
+			fmt.Sprintf("testenv-vm://service/%s", name))
+	}
+
 	// Map VM info to metadata and env
 	for name, vmState := range envState.Resources.VMs {
 		if vmState.State != nil {
@@ -471,6 +986,12 @@ func (o *Orchestrator) buildArtifact(testID string, envState *v1.EnvironmentStat
 				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_IP", toEnvVarName(name))] = ip
 			}
 
+			// Extract the SSH port of VMs not reached on 22
+			if port := vmSSHPort(vmState.State); port != "22" {
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.sshPort", name)] = port
+				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_SSH_PORT", toEnvVarName(name))] = port
+			}
+
 			// Extract SSH command
 			if sshCmd, ok := vmState.State["sshCommand"].(string); ok && sshCmd != "" {
 				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_SSH", toEnvVarName(name))] = sshCmd
@@ -480,6 +1001,34 @@ func (o *Orchestrator) buildArtifact(testID string, envState *v1.EnvironmentStat
 			if mac, ok := vmState.State["mac"].(string); ok && mac != "" {
 				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.mac", name)] = mac
 			}
+
+			// Extract jump host
+			if proxyJump, ok := vmState.State["proxyJump"].(string); ok && proxyJump != "" {
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.proxyJump", name)] = proxyJump
+				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_PROXY_JUMP", toEnvVarName(name))] = proxyJump
+			}
+
+			// Extract the guest agent address and token
+			if ip, port := getString(vmState.State, "ip"), getString(vmState.State, agentPortKey); ip != "" && port != "" {
+				address := net.JoinHostPort(ip, port)
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.agent", name)] = address
+				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_AGENT", toEnvVarName(name))] = address
+				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_AGENT_TOKEN", toEnvVarName(name))] = getString(vmState.State, agentTokenKey)
+			}
+
+			// Extract the vsock context ID
+			if cid := getUint32(vmState.State, vsockCIDKey); cid != 0 {
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.vsockCID", name)] = strconv.FormatUint(uint64(cid), 10)
+				artifact.Env[fmt.Sprintf("TESTENV_VM_%s_VSOCK_CID", toEnvVarName(name))] = strconv.FormatUint(uint64(cid), 10)
+			}
+
+			// Extract SSH host keys, one per line
+			if hostKeys := stateStrings(vmState.State, "hostKeys"); len(hostKeys) > 0 {
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.hostKeys", name)] = strings.Join(hostKeys, "\n")
+			}
+			if fingerprints := stateStrings(vmState.State, "hostKeyFingerprints"); len(fingerprints) > 0 {
+				artifact.Metadata[fmt.Sprintf("testenv-vm.vm.%s.hostKeyFingerprints", name)] = strings.Join(fingerprints, ",")
+			}
 		}
 
 		// Add resource reference to managed resources
@@ -490,6 +1039,32 @@ func (o *Orchestrator) buildArtifact(testID string, envState *v1.EnvironmentStat
 	return artifact
 }
 
+// newLifecycleEvent builds a notification event for the environment.
+func newLifecycleEvent(eventType notify.EventType, envState *v1.EnvironmentState) notify.Event {
+	return notify.Event{
+		Type:          eventType,
+		EnvironmentID: envState.ID,
+		TestID:        envState.TestID,
+		Stage:         envState.Stage,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		ArtifactDir:   envState.ArtifactDir,
+	}
+}
+
+// newQueuedEvent builds the notification event of a creation waiting for a
+// slot; it has no state yet.
+func newQueuedEvent(envID string, input *v1.CreateInput, client string, position int) notify.Event {
+	return notify.Event{
+		Type:          notify.EventQueued,
+		EnvironmentID: envID,
+		TestID:        input.TestID,
+		Stage:         input.Stage,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		Client:        client,
+		Position:      position,
+	}
+}
+
 // toEnvVarName converts a resource name to an environment variable name.
 // It replaces hyphens and dots with underscores and converts to uppercase.
 func toEnvVarName(s string) string {
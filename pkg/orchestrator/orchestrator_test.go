@@ -19,11 +19,16 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/imagetest"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/policy"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
 )
 
 // newTestConfig creates a Config with temporary directories for testing.
@@ -82,6 +87,34 @@ func TestNewOrchestrator_EmptyConfig(t *testing.T) {
 	}
 }
 
+func TestNewOrchestrator_HTTPClient(t *testing.T) {
+	server := imagetest.NewServer(t)
+	img := server.Add(t, "tiny.qcow2")
+	config := newTestConfig(t)
+	config.HTTPClient = server.Client()
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	spec := &v1.Spec{Images: []v1.ImageResource{{Name: "tiny", Spec: img.Spec()}}}
+	templateCtx := specpkg.NewTemplateContext()
+	ref := v1.ResourceRef{Kind: "image", Name: "tiny"}
+	for range 2 {
+		if err := orchestrator.executor.createResource(context.Background(), ref, spec, templateCtx, &v1.EnvironmentState{ID: "env"}, nil, nil); err != nil {
+			t.Fatalf("createResource() error = %v", err)
+		}
+	}
+	if got := templateCtx.Images["tiny"]; got.SHA256 != img.SHA256 || got.Path == "" {
+		t.Errorf("image template data = %+v, want the checksum of the fixture", got)
+	}
+	if n := server.Requests(img.Name); n != 1 {
+		t.Errorf("fixture downloaded %d times, want once", n)
+	}
+}
+
 func TestOrchestrator_Close(t *testing.T) {
 	config := newTestConfig(t)
 
@@ -309,6 +342,62 @@ func TestOrchestrator_Delete_RemovesArtifactDir(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_Delete_EnvironmentDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources string
+		wantKept  bool
+	}{
+		{
+			name:      "every resource destroyed",
+			resources: `{"vms": {}}`,
+			wantKept:  false,
+		},
+		{
+			name:      "failed vm delete",
+			resources: `{"vms": {"web": {"provider": "missing", "status": "ready"}}}`,
+			wantKept:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t)
+			orchestrator, err := NewOrchestrator(config)
+			if err != nil {
+				t.Fatalf("NewOrchestrator() error = %v", err)
+			}
+			defer orchestrator.Close()
+
+			testID := "test-env-dir"
+			envDir := paths.New(config.StateDir).Env(testID)
+			if err := envDir.Create(); err != nil {
+				t.Fatalf("failed to create environment dir: %v", err)
+			}
+			stateSubDir := filepath.Join(config.StateDir, "state")
+			if err := os.MkdirAll(stateSubDir, 0755); err != nil {
+				t.Fatalf("failed to create state dir: %v", err)
+			}
+			stateContent := `{
+				"id": "test-env-dir",
+				"status": "ready",
+				"executionPlan": {"phases": [{"resources": [{"kind": "vm", "name": "web"}]}]},
+				"resources": ` + tt.resources + `
+			}`
+			if err := os.WriteFile(filepath.Join(stateSubDir, "testenv-"+testID+".json"), []byte(stateContent), 0644); err != nil {
+				t.Fatalf("failed to write state file: %v", err)
+			}
+
+			if err := orchestrator.Delete(context.Background(), &v1.DeleteInput{TestID: testID}); err != nil {
+				t.Errorf("Delete() error = %v", err)
+			}
+			_, err = os.Stat(envDir.Dir)
+			if kept := err == nil; kept != tt.wantKept {
+				t.Errorf("environment directory kept = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
 func TestBuildExecutionPlan(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -758,3 +847,74 @@ func TestOrchestrator_Config(t *testing.T) {
 		})
 	}
 }
+
+func TestOrchestrator_ReadOnly(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	// Seed state to verify Delete does not remove it
+	if err := orchestrator.store.Save(&v1.EnvironmentState{ID: "test-ro", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	ctx := context.Background()
+	_, err = orchestrator.Create(ctx, &v1.CreateInput{TestID: "test-new", Spec: map[string]any{}})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Create() error = %v, want ErrReadOnly", err)
+	}
+
+	err = orchestrator.Delete(ctx, &v1.DeleteInput{TestID: "test-ro"})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Delete() error = %v, want ErrReadOnly", err)
+	}
+	if !orchestrator.store.Exists("test-ro") {
+		t.Error("Delete() in read-only mode must not remove state")
+	}
+}
+
+// denyAllAdmitter rejects every spec with a fixed violation.
+type denyAllAdmitter struct{}
+
+func (denyAllAdmitter) Admit(_ context.Context, _ *policy.Request) ([]policy.Violation, error) {
+	return []policy.Violation{{Rule: "max-memory", Message: "VM memory must be <= 16GiB"}}, nil
+}
+
+func TestOrchestrator_Create_AdmissionDenied(t *testing.T) {
+	config := newTestConfig(t)
+	config.Admitter = denyAllAdmitter{}
+
+	orchestrator, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	_, err = orchestrator.Create(context.Background(), &v1.CreateInput{
+		TestID: "test-denied",
+		Stage:  "integration",
+		TmpDir: t.TempDir(),
+		Spec: map[string]any{
+			"providers": []any{
+				map[string]any{"name": "nonexistent", "engine": "/nonexistent/provider"},
+			},
+		},
+	})
+
+	var denied *policy.DeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("Create() error = %v, want *policy.DeniedError", err)
+	}
+	if len(denied.Violations) != 1 || denied.Violations[0].Rule != "max-memory" {
+		t.Errorf("Violations = %+v", denied.Violations)
+	}
+	// Admission runs before providers start and before state is written
+	if orchestrator.store.Exists("test-denied") {
+		t.Error("denied spec must not create state")
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// ErrHasChildren is returned when deleting an environment that child
+// environments still use.
+var ErrHasChildren = errors.New("environment has child environments")
+
+// parentResources returns copies of the key and network states a spec uses
+// from its parent environment, with their owner set, and the isolation
+// config of the parent. The parent must be ready and have every listed
+// resource, and VMs attaching to a network of the parent must use the
+// provider of that network. It returns nil maps when the spec has no parent.
+func (o *Orchestrator) parentResources(envID string, testenvSpec *v1.Spec) (keys, networks map[string]*v1.ResourceState, parentIso *IsolationConfig, err error) {
+	parentID := testenvSpec.Parent.EnvironmentId
+	if parentID == "" {
+		return nil, nil, nil, nil
+	}
+	if parentID == envID {
+		return nil, nil, nil, fmt.Errorf("environment %q cannot be its own parent", envID)
+	}
+	parent, err := o.store.Load(parentID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load parent environment %q: %w", parentID, err)
+	}
+	if parent.Status != v1.StatusReady {
+		return nil, nil, nil, fmt.Errorf("parent environment %q is %s, not %s", parentID, parent.Status, v1.StatusReady)
+	}
+
+	inherit := func(kind string, names []string, resources map[string]*v1.ResourceState) (map[string]*v1.ResourceState, error) {
+		copies := make(map[string]*v1.ResourceState, len(names))
+		for _, name := range names {
+			rs := resources[name]
+			if rs == nil || rs.Status != v1.StatusReady {
+				return nil, fmt.Errorf("parent environment %q has no ready %s %q", parentID, kind, name)
+			}
+			c := *rs
+			c.Owner = parentID
+			copies[name] = &c
+		}
+		return copies, nil
+	}
+	if keys, err = inherit("key", testenvSpec.Parent.Keys, parent.Resources.Keys); err != nil {
+		return nil, nil, nil, err
+	}
+	if networks, err = inherit("network", testenvSpec.Parent.Networks, parent.Resources.Networks); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, vm := range testenvSpec.Vms {
+		vmProvider := spec.ResolveResourceProvider(testenvSpec, vm.Provider)
+		for _, name := range append([]string{vm.Spec.Network}, vm.Spec.Networks...) {
+			if n := networks[name]; n != nil && n.Provider != vmProvider {
+				return nil, nil, nil, fmt.Errorf("vm %q (provider %q) cannot attach to network %q of parent environment %q managed by provider %q",
+					vm.Name, vmProvider, name, parentID, n.Provider)
+			}
+		}
+	}
+
+	var parentPrefix string
+	var parentNetworks []v1.NetworkResource
+	if parent.Spec != nil {
+		parentPrefix, parentNetworks = parent.Spec.NamePrefix, parent.Spec.Networks
+	}
+	return keys, networks, newIsolationConfig(o.config.Tenant, parentID, parentPrefix, parentNetworks), nil
+}
+
+// inheritNetworks records in isoConfig the provider-level names of the
+// networks owned by a parent environment.
+func inheritNetworks(isoConfig *IsolationConfig, networks map[string]*v1.ResourceState) {
+	for name, rs := range networks {
+		if rs.Owner == "" {
+			continue
+		}
+		if isoConfig.InheritedNetworks == nil {
+			isoConfig.InheritedNetworks = make(map[string]string)
+		}
+		isoConfig.InheritedNetworks[name] = getString(rs.State, "name")
+	}
+}
+
+// childEnvironments returns the IDs of the stored environments, not yet
+// destroyed, whose parent is environmentID.
+func (o *Orchestrator) childEnvironments(environmentID string) ([]string, error) {
+	ids, err := o.store.List()
+	if err != nil {
+		return nil, err
+	}
+	var children []string
+	for _, id := range ids {
+		envState, err := o.store.Load(id)
+		if err != nil || envState.Spec == nil {
+			continue
+		}
+		if envState.Spec.Parent.EnvironmentId == environmentID && envState.Status != v1.StatusDestroyed {
+			children = append(children, id)
+		}
+	}
+	sort.Strings(children)
+	return children, nil
+}
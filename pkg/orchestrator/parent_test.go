@@ -0,0 +1,173 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// saveParent stores a ready parent environment with a key and a network.
+func saveParent(t *testing.T, o *Orchestrator, status string) {
+	t.Helper()
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID:     "base-lab",
+		Status: status,
+		Spec: &v1.Spec{Networks: []v1.NetworkResource{
+			{Name: "lab-net", Spec: v1.NetworkSpec{Cidr: "10.10.0.0/24"}},
+		}},
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"lab-key": {
+				Provider: "local", Status: v1.StatusReady,
+				State: map[string]any{"publicKey": "ssh-ed25519 AAAA", "privateKeyPath": "/keys/lab"},
+			}},
+			Networks: map[string]*v1.ResourceState{"lab-net": {
+				Provider: "local", Status: v1.StatusReady,
+				State: map[string]any{"name": "1a2b3c4d-lab-net"},
+			}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// childSpec returns a spec adding a VM to the network and key of base-lab.
+func childSpec() *v1.Spec {
+	return &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "local", Engine: "go://local", Default: true}},
+		Parent:    v1.ParentSpec{EnvironmentId: "base-lab", Keys: []string{"lab-key"}, Networks: []string{"lab-net"}},
+		Vms: []v1.VMResource{{
+			Name: "extra",
+			Spec: v1.VMSpec{
+				Network: "lab-net",
+				CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "ci", SshAuthorizedKeys: []string{"{{ .Keys.lab-key.PublicKey }}"}}},
+				},
+			},
+		}},
+	}
+}
+
+func TestOrchestrator_ParentResources(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, _, _, err := o.parentResources("child", childSpec()); err == nil || !strings.Contains(err.Error(), "failed to load parent") {
+		t.Errorf("missing parent: error = %v", err)
+	}
+	saveParent(t, o, v1.StatusCreating)
+	if _, _, _, err := o.parentResources("child", childSpec()); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("parent not ready: error = %v", err)
+	}
+	saveParent(t, o, v1.StatusReady)
+
+	keys, networks, parentIso, err := o.parentResources("child", childSpec())
+	if err != nil {
+		t.Fatalf("parentResources() error = %v", err)
+	}
+	if k := keys["lab-key"]; k == nil || k.Owner != "base-lab" || getString(k.State, "privateKeyPath") != "/keys/lab" {
+		t.Errorf("keys = %+v", keys)
+	}
+	if n := networks["lab-net"]; n == nil || n.Owner != "base-lab" {
+		t.Errorf("networks = %+v", networks)
+	}
+	if parentIso == nil || parentIso.OriginalCIDRPrefix != "10.10.0." || parentIso.NamePrefix != shortHash("base-lab") {
+		t.Errorf("parent isolation = %+v", parentIso)
+	}
+
+	isoConfig := newIsolationConfig("", "child", "", nil)
+	inheritNetworks(isoConfig, networks)
+	if got := networkName(isoConfig, "lab-net"); got != "1a2b3c4d-lab-net" {
+		t.Errorf("networkName(lab-net) = %q", got)
+	}
+	if got := networkName(isoConfig, "own"); got != prefixedName(isoConfig, "own") {
+		t.Errorf("networkName(own) = %q", got)
+	}
+
+	tests := []struct {
+		name    string
+		envID   string
+		mutate  func(*v1.Spec)
+		wantErr string
+	}{
+		{name: "own parent", envID: "base-lab", mutate: func(*v1.Spec) {}, wantErr: "its own parent"},
+		{name: "unknown key", envID: "child", mutate: func(s *v1.Spec) { s.Parent.Keys = []string{"other"} }, wantErr: `no ready key "other"`},
+		{
+			name:  "other provider",
+			envID: "child",
+			mutate: func(s *v1.Spec) {
+				s.Providers = append(s.Providers, v1.ProviderConfig{Name: "cloud"})
+				s.Vms[0].Provider = "cloud"
+			},
+			wantErr: `managed by provider "local"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := childSpec()
+			tt.mutate(s)
+			if _, _, _, err := o.parentResources(tt.envID, s); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parentResources() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildDAG_Parent(t *testing.T) {
+	dag, err := BuildDAG(childSpec())
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+	if dag.NodeCount() != 1 || dag.EdgeCount() != 0 {
+		t.Errorf("nodes = %d, edges = %d, want only the vm", dag.NodeCount(), dag.EdgeCount())
+	}
+}
+
+func TestOrchestrator_DeleteParent(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	saveParent(t, o, v1.StatusReady)
+	if err := o.store.Save(&v1.EnvironmentState{ID: "child", Status: v1.StatusReady, Spec: childSpec()}); err != nil {
+		t.Fatal(err)
+	}
+	err = o.Delete(context.Background(), &v1.DeleteInput{TestID: "base-lab"})
+	if !errors.Is(err, ErrHasChildren) || !strings.Contains(err.Error(), "child") {
+		t.Fatalf("Delete() error = %v, want %v", err, ErrHasChildren)
+	}
+	if !o.store.Exists("base-lab") {
+		t.Error("parent state was deleted")
+	}
+
+	// Deleting the child releases the parent
+	if err := o.Delete(context.Background(), &v1.DeleteInput{TestID: "child"}); err != nil {
+		t.Fatalf("Delete(child) error = %v", err)
+	}
+	if err := o.Delete(context.Background(), &v1.DeleteInput{TestID: "base-lab"}); err != nil {
+		t.Fatalf("Delete(parent) error = %v", err)
+	}
+}
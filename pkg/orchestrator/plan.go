@@ -0,0 +1,101 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// PlanResult describes what Create would do with a spec, without creating
+// anything.
+type PlanResult struct {
+	// Plan is the ordered execution phases.
+	Plan *v1.ExecutionPlan `json:"plan"`
+	// Capacity compares requested VM resources with each provider's host.
+	Capacity []ProviderCapacity `json:"capacity"`
+	// Fits is false when a provider's host cannot hold the requested VMs.
+	Fits bool `json:"fits"`
+	// Changes lists the action required for each VM when an environment
+	// with the same ID already exists.
+	Changes []ResourceChange `json:"changes,omitempty"`
+}
+
+// Plan validates a spec, starts its providers, and returns the execution
+// phases together with a host capacity check. When the environment already
+// exists, the rendered VMs are compared with their recorded content hashes to
+// tell which ones would be updated, rebooted or replaced. It creates no
+// resources and is allowed in read-only mode.
+func (o *Orchestrator) Plan(input *v1.CreateInput) (*PlanResult, error) {
+	testenvSpec, _, err := o.parseSpec(context.Background(), input.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(testenvSpec.Providers) == 0 {
+		testenvSpec.Providers = append([]v1.ProviderConfig(nil), o.config.DefaultProviders...)
+	}
+	templatedFields, err := spec.ValidateEarly(testenvSpec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+
+	for _, providerCfg := range testenvSpec.Providers {
+		if info, ok := o.manager.GetInfo(providerCfg.Name); ok && info.Status == provider.StatusRunning {
+			continue
+		}
+		if err := o.manager.Start(providerCfg); err != nil {
+			return nil, fmt.Errorf("failed to start provider %q: %w", providerCfg.Name, err)
+		}
+	}
+	if err := verifyProviderCapabilities(o.manager, testenvSpec); err != nil {
+		return nil, fmt.Errorf("capability check failed: %w", err)
+	}
+
+	dag, err := BuildDAG(testenvSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DAG: %w", err)
+	}
+	phases, err := dag.TopologicalSort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute execution phases: %w", err)
+	}
+
+	capacity, err := planCapacity(testenvSpec, hostCapacities(o.manager))
+	if err != nil {
+		return nil, err
+	}
+	result := &PlanResult{
+		Plan:     buildExecutionPlan(phases),
+		Capacity: capacity,
+		Fits:     capacityError(capacity) == nil,
+	}
+
+	envID, _, err := ResolveEnvironmentID(input, testenvSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve environment ID: %w", err)
+	}
+	if envID != "" && o.store.Exists(envID) {
+		envState, err := o.store.Load(envID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load state of environment %q: %w", envID, err)
+		}
+		result.Changes = o.executor.planVMChanges(testenvSpec, envState, input.Env, templatedFields)
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// PowerOptions configures PowerVM.
+type PowerOptions struct {
+	// Force kills the VM on stop and resets it on reboot, as a crash or
+	// power loss would, instead of asking the guest.
+	Force bool
+	// Timeout bounds a graceful stop. Zero uses the provider default.
+	Timeout time.Duration
+}
+
+// PowerVM applies a power action (providerv1.PowerStart, PowerStop,
+// PowerReboot, PowerPause or PowerSave) to a VM of a stored environment with
+// the power tools of its provider, so tests can power-cycle VMs or simulate
+// crashes. Start also resumes a paused VM and restores a saved one. The
+// state reported by the provider, whose status tells whether the VM runs, is
+// recorded and returned. Waiting for the lock of the environment stops when
+// ctx is done.
+func (o *Orchestrator) PowerVM(ctx context.Context, environmentID, vmName, action string, opts PowerOptions) (*v1.ResourceState, error) {
+	tool, ok := providerv1.PowerTools[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown power action %q", action)
+	}
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("%s rejected: %w", action, ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s rejected: %w", action, err)
+	}
+	defer end()
+	unlock, err := o.store.Lock(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	vmState := envState.Resources.VMs[vmName]
+	if vmState == nil {
+		return nil, fmt.Errorf("vm %q not found in environment %q", vmName, environmentID)
+	}
+	if err := o.ensureProvider(envState, vmState.Provider); err != nil {
+		return nil, err
+	}
+	if !o.manager.SupportsOperation(vmState.Provider, "vm", action) {
+		return nil, fmt.Errorf("provider %q does not support %s of vm resources", vmState.Provider, action)
+	}
+
+	log.Printf("Calling %s on vm %q of environment %q (force: %t)", tool, vmName, environmentID, opts.Force)
+	result, err := o.manager.Call(vmState.Provider, tool, &providerv1.VMPowerRequest{
+		Name:           getString(vmState.State, "name"),
+		Force:          opts.Force,
+		TimeoutSeconds: int(opts.Timeout.Seconds()),
+	})
+	if err := operationError(tool, result, err); err != nil {
+		return nil, err
+	}
+	state, err := o.executor.convertResourceToMap(result.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vm state returned by %s: %w", tool, err)
+	}
+
+	vmState.State = state
+	vmState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	envState.UpdatedAt = vmState.UpdatedAt
+	if err := o.store.Save(envState); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+	return vmState, nil
+}
@@ -0,0 +1,71 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOrchestrator_PowerVMErrors(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	if _, err := o.PowerVM(context.Background(), "env", "web", providerv1.PowerStop, PowerOptions{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("PowerVM() error = %v, want ErrReadOnly", err)
+	}
+	_ = o.Close()
+
+	o, err = NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID:        "env-power",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		environmentID string
+		action        string
+		wantErr       string
+	}{
+		{name: "unknown action", environmentID: "env-power", action: "hibernate", wantErr: "unknown power action"},
+		{name: "missing environment", environmentID: "missing", action: providerv1.PowerStart, wantErr: "failed to load"},
+		{name: "missing vm", environmentID: "env-power", action: providerv1.PowerReboot, wantErr: "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.PowerVM(context.Background(), tt.environmentID, "web", tt.action, PowerOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("PowerVM() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// ErrQuotaExceeded is returned by Create when a spec exceeds Config.Quotas.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quotas limits what a single orchestrator may create. Zero values mean
+// unlimited.
+type Quotas struct {
+	// MaxEnvironments limits the number of environments with state on disk
+	// or being created.
+	MaxEnvironments int
+	// MaxVMs limits the number of VMs declared by one environment.
+	MaxVMs int
+	// MaxVCPUs limits the total vCPUs declared by one environment.
+	MaxVCPUs int
+	// MaxMemoryMB limits the total memory (in MB) declared by one environment.
+	MaxMemoryMB int
+}
+
+// checkQuotas verifies that creating testenvSpec as environment envID stays
+// within quotas. The caller holds the lock of envID, so two creations running
+// at once count each other: environments count from the moment their lock is
+// held, before they have state.
+func checkQuotas(store *state.Store, envID string, testenvSpec *v1.Spec, quotas Quotas) error {
+	if quotas.MaxEnvironments > 0 {
+		ids, err := store.List()
+		if err != nil {
+			return fmt.Errorf("failed to count environments: %w", err)
+		}
+		locked, err := store.Locked()
+		if err != nil {
+			return fmt.Errorf("failed to count environments: %w", err)
+		}
+		envs := make(map[string]bool)
+		for _, id := range append(ids, locked...) {
+			if id != envID {
+				envs[id] = true
+			}
+		}
+		if len(envs) >= quotas.MaxEnvironments {
+			return fmt.Errorf("%w: %d environments exist or are being created (max %d)", ErrQuotaExceeded, len(envs), quotas.MaxEnvironments)
+		}
+	}
+
+	if quotas.MaxVMs > 0 && len(testenvSpec.Vms) > quotas.MaxVMs {
+		return fmt.Errorf("%w: spec declares %d VMs (max %d)", ErrQuotaExceeded, len(testenvSpec.Vms), quotas.MaxVMs)
+	}
+
+	var vcpus, memory int
+	for _, vm := range testenvSpec.Vms {
+		vcpus += vm.Spec.Vcpus
+		memory += vm.Spec.Memory
+	}
+	if quotas.MaxVCPUs > 0 && vcpus > quotas.MaxVCPUs {
+		return fmt.Errorf("%w: spec declares %d vCPUs (max %d)", ErrQuotaExceeded, vcpus, quotas.MaxVCPUs)
+	}
+	if quotas.MaxMemoryMB > 0 && memory > quotas.MaxMemoryMB {
+		return fmt.Errorf("%w: spec declares %d MB of memory (max %d)", ErrQuotaExceeded, memory, quotas.MaxMemoryMB)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	store := state.NewStore(t.TempDir())
+	if err := store.Save(&v1.EnvironmentState{ID: "existing", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	testenvSpec := &v1.Spec{
+		Vms: []v1.VMResource{
+			{Name: "a", Spec: v1.VMSpec{Vcpus: 2, Memory: 2048}},
+			{Name: "b", Spec: v1.VMSpec{Vcpus: 2, Memory: 2048}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		quotas  Quotas
+		wantErr bool
+	}{
+		{name: "unlimited", quotas: Quotas{}},
+		{name: "within limits", quotas: Quotas{MaxEnvironments: 2, MaxVMs: 2, MaxVCPUs: 4, MaxMemoryMB: 4096}},
+		{name: "too many environments", quotas: Quotas{MaxEnvironments: 1}, wantErr: true},
+		{name: "too many VMs", quotas: Quotas{MaxVMs: 1}, wantErr: true},
+		{name: "too many vCPUs", quotas: Quotas{MaxVCPUs: 3}, wantErr: true},
+		{name: "too much memory", quotas: Quotas{MaxMemoryMB: 4095}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkQuotas(store, "new", testenvSpec, tt.quotas)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkQuotas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("checkQuotas() error = %v, want ErrQuotaExceeded", err)
+			}
+		})
+	}
+}
+
+func TestCheckQuotas_CountsCreations(t *testing.T) {
+	store := state.NewStore(t.TempDir())
+	if err := store.Save(&v1.EnvironmentState{ID: "existing", Status: v1.StatusReady}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	// A creation holds its lock before it saves any state
+	unlockOther, err := store.Lock(context.Background(), "creating")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlockOther()
+	unlock, err := store.Lock(context.Background(), "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	if err := checkQuotas(store, "new", &v1.Spec{}, Quotas{MaxEnvironments: 2}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("checkQuotas() error = %v, want ErrQuotaExceeded counting the other creation", err)
+	}
+	if err := checkQuotas(store, "new", &v1.Spec{}, Quotas{MaxEnvironments: 3}); err != nil {
+		t.Errorf("checkQuotas() error = %v, want the environment itself not counted", err)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer orchestrator.Close()
+
+	orchestrator.metrics.observeCreate(nil)
+	orchestrator.metrics.observeCreate(errors.New("boom"))
+	orchestrator.metrics.observeCreate(errors.New("boom"))
+
+	var buf bytes.Buffer
+	orchestrator.writeMetrics(&buf)
+	out := buf.String()
+	for _, want := range []string{
+		`testenv_vm_create_total{result="success"} 1`,
+		`testenv_vm_create_total{result="failure"} 2`,
+		"testenv_vm_operations_in_flight 0",
+		"testenv_vm_creates_queued 0",
+		"testenv_vm_environments 0",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out)
+		}
+	}
+}
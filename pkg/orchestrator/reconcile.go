@@ -0,0 +1,411 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Drift values of ResourceDrift.
+const (
+	// DriftNone means the provider reports the resource as recorded.
+	DriftNone = "in-sync"
+	// DriftChanged means the provider reports another status than recorded,
+	// e.g. a VM that crashed.
+	DriftChanged = "drifted"
+	// DriftMissing means the provider no longer lists the resource.
+	DriftMissing = "missing"
+	// DriftUnknown means the provider could not list the resource.
+	DriftUnknown = "unknown"
+)
+
+// ReconcileOptions configures Reconcile.
+type ReconcileOptions struct {
+	// Recreate deletes and creates again the drifted and missing resources,
+	// together with the resources depending on them.
+	Recreate bool
+	// Env holds the environment variables available to templates of the
+	// recreated resources.
+	Env map[string]string
+}
+
+// ResourceDrift is the drift of one recorded resource.
+type ResourceDrift struct {
+	Resource v1.ResourceRef `json:"resource"`
+	// Drift is one of DriftNone, DriftChanged, DriftMissing or DriftUnknown.
+	Drift string `json:"drift"`
+	// Detail explains the drift, or why it is unknown.
+	Detail string `json:"detail,omitempty"`
+}
+
+// ReconcileResult is the outcome of Reconcile.
+type ReconcileResult struct {
+	// Resources lists the recorded keys, networks and VMs, in that order
+	// and by name.
+	Resources []ResourceDrift `json:"resources"`
+	// Drifted counts the drifted and missing resources.
+	Drifted int `json:"drifted"`
+	// Recreated lists the resources deleted and created again, in creation
+	// order.
+	Recreated []v1.ResourceRef `json:"recreated,omitempty"`
+	// Artifact is the artifact of the environment after recreation.
+	Artifact *v1.TestEnvArtifact `json:"artifact,omitempty"`
+}
+
+// inventory is what a provider lists, by resource kind, then by name with
+// the reported status. errs holds the kinds the provider failed to list.
+type inventory struct {
+	listed map[string]map[string]string
+	errs   map[string]error
+}
+
+// Reconcile compares the recorded keys, networks and VMs of an environment
+// with what their providers list with key_list, network_list and vm_list.
+// Resources the provider no longer lists are marked missing, and those it
+// reports with another status than recorded, e.g. a crashed VM, drifted;
+// marked resources found as recorded again are marked ready. With Recreate,
+// the drifted and missing resources and those depending on them are deleted
+// and created again from the recorded spec, like Update replaces them.
+// Resources of a parent environment are not checked. In read-only mode
+// drift is reported without being marked.
+func (o *Orchestrator) Reconcile(ctx context.Context, environmentID string, opts ReconcileOptions) (*ReconcileResult, error) {
+	if o.config.ReadOnly && opts.Recreate {
+		return nil, fmt.Errorf("reconcile rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconcile rejected: %w", err)
+	}
+	defer end()
+
+	// Drift is only recorded outside read-only mode
+	if !o.config.ReadOnly {
+		unlock, err := o.store.Lock(ctx, environmentID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.Status != v1.StatusReady && envState.Status != v1.StatusFailed {
+		return nil, fmt.Errorf("environment %q is %s; only ready or failed environments can be reconciled", environmentID, envState.Status)
+	}
+
+	inventories := make(map[string]*inventory)
+	for _, rs := range recordedResources(envState) {
+		if _, ok := inventories[rs.ref.Provider]; !ok {
+			inventories[rs.ref.Provider] = o.listProvider(envState, rs.ref.Provider)
+		}
+	}
+	result := &ReconcileResult{Resources: detectDrift(envState, inventories)}
+	for _, d := range result.Resources {
+		if d.Drift == DriftChanged || d.Drift == DriftMissing {
+			result.Drifted++
+		}
+	}
+	if o.config.ReadOnly {
+		return result, nil
+	}
+	if o.executor.markDrift(envState, result.Resources) {
+		envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		if err := o.store.Save(envState); err != nil {
+			return nil, fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+	if !opts.Recreate || result.Drifted == 0 {
+		return result, nil
+	}
+
+	if envState.Spec == nil {
+		return nil, fmt.Errorf("environment %q has no recorded spec", environmentID)
+	}
+	if len(envState.Spec.Access) > 0 {
+		return nil, fmt.Errorf("cannot recreate resources of environment %q: environments with access points cannot be updated", environmentID)
+	}
+	templatedFields, err := specpkg.ValidateEarly(envState.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+	isoConfig, err := o.existingIsolation(envState, envState.Spec)
+	if err != nil {
+		return nil, err
+	}
+	deletions, creations, recreated, err := recreationPlan(envState, result.Resources)
+	if err != nil {
+		return nil, err
+	}
+	for _, providerCfg := range envState.Spec.Providers {
+		if err := o.ensureProvider(envState, providerCfg.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("Recreating %d resources of environment %q", len(recreated), environmentID)
+	envState.Status = v1.StatusUpdating
+	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := o.store.Save(envState); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+	fail := func(err error) (*ReconcileResult, error) {
+		envState.Status = v1.StatusFailed
+		envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		envState.Errors = append(envState.Errors, v1.ErrorRecord{
+			Operation: "reconcile",
+			Error:     err.Error(),
+			Timestamp: envState.UpdatedAt,
+		})
+		if saveErr := o.store.Save(envState); saveErr != nil {
+			log.Printf("Failed to save failed state: %v", saveErr)
+		}
+		return nil, fmt.Errorf("reconcile failed: %w", err)
+	}
+
+	if err := o.executor.executeDeletions(ctx, deletions, envState, isoConfig); err != nil {
+		return fail(err)
+	}
+	templateCtx := o.executor.templateContextFromState(envState.Spec, envState, opts.Env)
+	execResult, err := o.executor.ExecuteCreate(ctx, envState.Spec, creations, templateCtx, envState, templatedFields, isoConfig)
+	if err != nil {
+		return fail(err)
+	}
+	if !execResult.Success {
+		return fail(errors.Join(execResult.Errors...))
+	}
+	if err := o.markReady(envState, templateCtx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Environment %q reconciled", environmentID)
+	result.Recreated = recreated
+	result.Artifact = o.buildArtifact(envState.TestID, envState, isoConfig)
+	return result, nil
+}
+
+// recordedResource is a key, network or VM recorded in the state of an
+// environment.
+type recordedResource struct {
+	ref   v1.ResourceRef
+	state *v1.ResourceState
+}
+
+// recordedResources returns the keys, networks and VMs of an environment
+// that reconciliation checks, in that order and by name: those that are not
+// destroyed nor owned by a parent environment.
+func recordedResources(envState *v1.EnvironmentState) []recordedResource {
+	var recorded []recordedResource
+	for _, kind := range []struct {
+		name      string
+		resources map[string]*v1.ResourceState
+	}{
+		{"key", envState.Resources.Keys},
+		{"network", envState.Resources.Networks},
+		{"vm", envState.Resources.VMs},
+	} {
+		names := make([]string, 0, len(kind.resources))
+		for name, rs := range kind.resources {
+			if rs.Owner == "" && rs.Status != v1.StatusDestroyed {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rs := kind.resources[name]
+			recorded = append(recorded, recordedResource{
+				ref:   v1.ResourceRef{Kind: kind.name, Name: name, Provider: rs.Provider},
+				state: rs,
+			})
+		}
+	}
+	return recorded
+}
+
+// listProvider lists the keys, networks and VMs of a provider.
+func (o *Orchestrator) listProvider(envState *v1.EnvironmentState, providerName string) *inventory {
+	inv := &inventory{listed: make(map[string]map[string]string), errs: make(map[string]error)}
+	if err := o.ensureProvider(envState, providerName); err != nil {
+		for _, kind := range []string{"key", "network", "vm"} {
+			inv.errs[kind] = err
+		}
+		return inv
+	}
+	for _, kind := range []string{"key", "network", "vm"} {
+		tool := kind + "_list"
+		result, err := o.manager.Call(providerName, tool, &providerv1.ListRequest{})
+		if err := operationError(tool, result, err); err != nil {
+			inv.errs[kind] = err
+			continue
+		}
+		listed, err := decodeListed(result.Resource)
+		if err != nil {
+			inv.errs[kind] = fmt.Errorf("invalid result of %s: %w", tool, err)
+			continue
+		}
+		inv.listed[kind] = listed
+	}
+	return inv
+}
+
+// decodeListed returns the statuses of the resources of a list result by
+// name.
+func decodeListed(resource any) (map[string]string, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var items []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	listed := make(map[string]string, len(items))
+	for _, item := range items {
+		listed[item.Name] = item.Status
+	}
+	return listed, nil
+}
+
+// detectDrift compares the recorded resources of an environment with the
+// inventories of their providers. Resources are matched by the name their
+// provider recorded, and compared by the status it recorded.
+func detectDrift(envState *v1.EnvironmentState, inventories map[string]*inventory) []ResourceDrift {
+	drifts := make([]ResourceDrift, 0)
+	for _, r := range recordedResources(envState) {
+		d := ResourceDrift{Resource: r.ref, Drift: DriftNone}
+		name := getString(r.state.State, "name")
+		if name == "" {
+			name = r.ref.Name
+		}
+		inv := inventories[r.ref.Provider]
+		recordedStatus := getString(r.state.State, "status")
+		switch {
+		case inv == nil:
+			d.Drift, d.Detail = DriftUnknown, fmt.Sprintf("provider %q was not listed", r.ref.Provider)
+		case inv.errs[r.ref.Kind] != nil:
+			d.Drift, d.Detail = DriftUnknown, inv.errs[r.ref.Kind].Error()
+		default:
+			status, listed := inv.listed[r.ref.Kind][name]
+			switch {
+			case !listed:
+				d.Drift, d.Detail = DriftMissing, fmt.Sprintf("provider %q does not list %s %q", r.ref.Provider, r.ref.Kind, name)
+			case status != "" && recordedStatus != "" && status != recordedStatus:
+				d.Drift, d.Detail = DriftChanged, fmt.Sprintf("status is %q, recorded %q", status, recordedStatus)
+			}
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts
+}
+
+// markDrift sets the status of the drifted and missing resources, and marks
+// resources found as recorded again ready. It reports whether the state
+// changed.
+func (e *Executor) markDrift(envState *v1.EnvironmentState, drifts []ResourceDrift) bool {
+	changed := false
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, d := range drifts {
+		rs := e.getResourceState(envState, d.Resource)
+		if rs == nil {
+			continue
+		}
+		status, errMsg := rs.Status, rs.Error
+		switch d.Drift {
+		case DriftChanged:
+			status, errMsg = v1.StatusDrifted, d.Detail
+		case DriftMissing:
+			status, errMsg = v1.StatusMissing, d.Detail
+		case DriftNone:
+			if rs.Status == v1.StatusDrifted || rs.Status == v1.StatusMissing {
+				status, errMsg = v1.StatusReady, ""
+			}
+		}
+		if status != rs.Status || errMsg != rs.Error {
+			rs.Status, rs.Error, rs.UpdatedAt = status, errMsg, now
+			changed = true
+		}
+	}
+	return changed
+}
+
+// recreationPlan returns the deletion and creation phases recreating the
+// drifted and missing resources of the recorded spec and those depending on
+// them, and the recreated resources in creation order. Images and tunnels
+// are created again to fill the template context, as in Update. Resources
+// the spec does not declare, such as VMs created at runtime, are not
+// recreated.
+func recreationPlan(envState *v1.EnvironmentState, drifts []ResourceDrift) (deletions, creations [][]v1.ResourceRef, recreated []v1.ResourceRef, err error) {
+	dag, err := BuildDAG(envState.Spec)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to build DAG: %w", err)
+	}
+	phases, err := dag.TopologicalSort()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to compute execution phases: %w", err)
+	}
+
+	broken := make(map[string]bool)
+	for _, d := range drifts {
+		if d.Drift == DriftChanged || d.Drift == DriftMissing {
+			broken[nodeKey(d.Resource)] = true
+		}
+	}
+	var brokenRefs []v1.ResourceRef
+	for _, phase := range phases {
+		for _, ref := range phase {
+			if broken[nodeKey(ref)] {
+				brokenRefs = append(brokenRefs, ref)
+			}
+		}
+	}
+
+	var toDelete []v1.ResourceRef
+	for _, phase := range phases {
+		var creation []v1.ResourceRef
+		for _, ref := range phase {
+			affected := broken[nodeKey(ref)]
+			if !affected && ref.Kind != "image" && ref.Kind != "tunnel" {
+				for _, b := range brokenRefs {
+					if dag.DependsOn(ref, b) {
+						affected = true
+						break
+					}
+				}
+			}
+			if affected {
+				recreated = append(recreated, ref)
+				toDelete = append(toDelete, ref)
+			}
+			if affected || ref.Kind == "image" || ref.Kind == "tunnel" {
+				creation = append(creation, ref)
+			}
+		}
+		creations = appendPhase(creations, creation)
+	}
+	return deletionPhases(envState, toDelete), creations, recreated, nil
+}
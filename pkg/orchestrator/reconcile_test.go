@@ -0,0 +1,174 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// driftedEnvironment records a ready environment with a key, a network and
+// VMs web and db, whose recorded provider states report them running.
+func driftedEnvironment(t *testing.T, executor *Executor) *v1.EnvironmentState {
+	t.Helper()
+	envState := updatedEnvironment(t, executor, updateSpec("10.0.0.0/24", map[string]int{"web": 1024, "db": 1024}))
+	for name, rs := range envState.Resources.VMs {
+		rs.State = map[string]any{"name": "env-1-" + name, "status": "running"}
+	}
+	return envState
+}
+
+func driftsOf(drifts []ResourceDrift) map[string]string {
+	got := make(map[string]string, len(drifts))
+	for _, d := range drifts {
+		got[d.Resource.Kind+"/"+d.Resource.Name] = d.Drift
+	}
+	return got
+}
+
+func TestDetectDrift(t *testing.T) {
+	envState := driftedEnvironment(t, newTestExecutor(t))
+	envState.Resources.Networks["parent-net"] = &v1.ResourceState{Provider: "local", Status: v1.StatusReady, Owner: "lab"}
+	envState.Resources.Keys["gone"] = &v1.ResourceState{Provider: "local", Status: v1.StatusDestroyed}
+
+	inventories := map[string]*inventory{
+		"local": {
+			listed: map[string]map[string]string{
+				"key": {"ssh": ""},
+				"vm":  {"env-1-web": "running", "env-1-db": "stopped"},
+			},
+			errs: map[string]error{"network": errors.New("network_list failed")},
+		},
+	}
+	drifts := detectDrift(envState, inventories)
+	want := map[string]string{
+		"key/ssh":     DriftNone,
+		"network/net": DriftUnknown,
+		"vm/db":       DriftChanged,
+		"vm/web":      DriftNone,
+	}
+	if got := driftsOf(drifts); !reflect.DeepEqual(got, want) {
+		t.Errorf("detectDrift() = %v, want %v", got, want)
+	}
+
+	delete(inventories["local"].listed["vm"], "env-1-web")
+	if got := driftsOf(detectDrift(envState, inventories)); got["vm/web"] != DriftMissing {
+		t.Errorf("detectDrift() web = %q, want %q", got["vm/web"], DriftMissing)
+	}
+	if got := driftsOf(detectDrift(envState, nil)); got["key/ssh"] != DriftUnknown {
+		t.Errorf("detectDrift() without inventory = %q, want %q", got["key/ssh"], DriftUnknown)
+	}
+}
+
+func TestMarkDrift(t *testing.T) {
+	executor := newTestExecutor(t)
+	envState := driftedEnvironment(t, executor)
+	envState.Resources.Networks["net"].Status = v1.StatusMissing
+	envState.Resources.Networks["net"].Error = "gone"
+
+	drifts := []ResourceDrift{
+		{Resource: v1.ResourceRef{Kind: "network", Name: "net"}, Drift: DriftNone},
+		{Resource: v1.ResourceRef{Kind: "vm", Name: "web"}, Drift: DriftMissing, Detail: "not listed"},
+		{Resource: v1.ResourceRef{Kind: "vm", Name: "db"}, Drift: DriftUnknown, Detail: "list failed"},
+	}
+	if !executor.markDrift(envState, drifts) {
+		t.Fatal("markDrift() reported no change")
+	}
+	if net := envState.Resources.Networks["net"]; net.Status != v1.StatusReady || net.Error != "" {
+		t.Errorf("network = %s (%s), want ready again", net.Status, net.Error)
+	}
+	if web := envState.Resources.VMs["web"]; web.Status != v1.StatusMissing || web.Error != "not listed" {
+		t.Errorf("web = %s (%s), want missing", web.Status, web.Error)
+	}
+	if db := envState.Resources.VMs["db"]; db.Status != v1.StatusReady {
+		t.Errorf("db = %s, want unknown drift to keep the recorded status", db.Status)
+	}
+	if executor.markDrift(envState, drifts) {
+		t.Error("markDrift() reported a change when marking again")
+	}
+}
+
+func TestRecreationPlan(t *testing.T) {
+	envState := driftedEnvironment(t, newTestExecutor(t))
+
+	deletions, creations, recreated, err := recreationPlan(envState, []ResourceDrift{
+		{Resource: v1.ResourceRef{Kind: "vm", Name: "db"}, Drift: DriftChanged},
+		{Resource: v1.ResourceRef{Kind: "vm", Name: "web"}, Drift: DriftNone},
+	})
+	if err != nil {
+		t.Fatalf("recreationPlan() error = %v", err)
+	}
+	if got := refsOf([][]v1.ResourceRef{recreated}); !reflect.DeepEqual(got, []string{"vm/db"}) {
+		t.Errorf("recreationPlan() recreated = %v", got)
+	}
+	if got := refsOf(deletions); !reflect.DeepEqual(got, []string{"vm/db"}) {
+		t.Errorf("recreationPlan() deletions = %v", got)
+	}
+	if got := refsOf(creations); !reflect.DeepEqual(got, []string{"vm/db"}) {
+		t.Errorf("recreationPlan() creations = %v", got)
+	}
+
+	// A missing network takes the VMs attached to it along
+	_, creations, recreated, err = recreationPlan(envState, []ResourceDrift{
+		{Resource: v1.ResourceRef{Kind: "network", Name: "net"}, Drift: DriftMissing},
+	})
+	if err != nil {
+		t.Fatalf("recreationPlan() error = %v", err)
+	}
+	if got := refsOf([][]v1.ResourceRef{recreated}); len(got) != 3 || got[0] != "network/net" {
+		t.Errorf("recreationPlan() recreated = %v, want the network then both VMs", got)
+	}
+	if got := refsOf(creations); len(got) != 3 || strings.Contains(strings.Join(got, " "), "key/") {
+		t.Errorf("recreationPlan() creations = %v", got)
+	}
+}
+
+func TestOrchestrator_ReconcileRejected(t *testing.T) {
+	cfg := newTestConfig(t)
+	o, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	envState := driftedEnvironment(t, o.executor)
+	envState.Status = v1.StatusCreating
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Reconcile(context.Background(), "missing", ReconcileOptions{}); err == nil || !strings.Contains(err.Error(), "failed to load") {
+		t.Errorf("Reconcile() error = %v, want a load error", err)
+	}
+	if _, err := o.Reconcile(context.Background(), "env-1", ReconcileOptions{}); err == nil || !strings.Contains(err.Error(), "only ready or failed") {
+		t.Errorf("Reconcile() error = %v, want a status error", err)
+	}
+
+	cfg.ReadOnly = true
+	ro, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer ro.Close()
+	if _, err := ro.Reconcile(context.Background(), "env-1", ReconcileOptions{Recreate: true}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Reconcile() error = %v, want %v", err, ErrReadOnly)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+	"golang.org/x/crypto/ssh"
+)
+
+// rotateSuffix is appended to the key file paths while the new key pair is
+// pushed to the VMs.
+const rotateSuffix = ".rotating"
+
+// keyAuthorization is a user of a VM whose cloud-init authorized a key.
+type keyAuthorization struct {
+	VM   string
+	User string
+}
+
+// RotateKeyResult reports a key rotation.
+type RotateKeyResult struct {
+	// Key is the state of the key resource, with the new public key and
+	// fingerprint.
+	Key *v1.ResourceState
+	// OldFingerprint is the fingerprint of the retired key.
+	OldFingerprint string
+	// VMs lists the VMs the new public key was pushed to.
+	VMs []string
+	// Warnings lists VMs from which the old public key could not be removed.
+	Warnings []string
+}
+
+// RotateKey replaces the key pair of a key resource of a stored environment,
+// for long-lived environments whose keys must be renewed. A new pair of the
+// same type is generated, and its public key is appended over SSH to
+// authorized_keys of each VM user whose cloud-init authorized the old one.
+// The new pair then replaces the old key files and the state, so templates
+// and SSH clients use it, and the old public key is removed from the VMs.
+// If the new key cannot be pushed to every VM, nothing is changed.
+func (o *Orchestrator) RotateKey(ctx context.Context, environmentID, keyName string) (*RotateKeyResult, error) {
+	if o.config.ReadOnly {
+		return nil, fmt.Errorf("key rotation rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("key rotation rejected: %w", err)
+	}
+	defer end()
+	unlock, err := o.store.Lock(ctx, environmentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	keyState := envState.Resources.Keys[keyName]
+	if keyState == nil {
+		return nil, fmt.Errorf("key %q not found in environment %q", keyName, environmentID)
+	}
+	privateKeyPath := getString(keyState.State, "privateKeyPath")
+	publicKeyPath := getString(keyState.State, "publicKeyPath")
+	oldPublicKey := strings.TrimSpace(getString(keyState.State, "publicKey"))
+	if privateKeyPath == "" || oldPublicKey == "" {
+		return nil, fmt.Errorf("key %q has no recorded key files", keyName)
+	}
+	if publicKeyPath == "" {
+		publicKeyPath = privateKeyPath + ".pub"
+	}
+
+	privateKeyPEM, publicKey, err := newKeyPairLike(oldPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	newPublicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+	if err := os.WriteFile(privateKeyPath+rotateSuffix, privateKeyPEM, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(publicKeyPath+rotateSuffix, []byte(newPublicKey+"\n"), 0o644); err != nil {
+		_ = os.Remove(privateKeyPath + rotateSuffix)
+		return nil, fmt.Errorf("failed to write public key: %w", err)
+	}
+	discard := func() {
+		_ = os.Remove(privateKeyPath + rotateSuffix)
+		_ = os.Remove(publicKeyPath + rotateSuffix)
+	}
+
+	targets := o.keyAuthorizations(envState, oldPublicKey)
+	byVM := make(map[string][]string)
+	var vms []string
+	for _, t := range targets {
+		if _, ok := byVM[t.VM]; !ok {
+			vms = append(vms, t.VM)
+		}
+		byVM[t.VM] = append(byVM[t.VM], t.User)
+	}
+
+	// Push the new key with the old one.
+	var pushed []string
+	for _, vm := range vms {
+		log.Printf("Authorizing the new %q key on vm %q of environment %q", keyName, vm, environmentID)
+		if err := o.editAuthorizedKeys(ctx, envState, vm, byVM[vm], addAuthorizedKeyScript, newPublicKey); err != nil {
+			for _, done := range pushed {
+				_ = o.editAuthorizedKeys(ctx, envState, done, byVM[done], removeAuthorizedKeyScript, newPublicKey)
+			}
+			discard()
+			return nil, fmt.Errorf("failed to authorize the new key on vm %q: %w", vm, err)
+		}
+		pushed = append(pushed, vm)
+	}
+
+	if err := os.Rename(privateKeyPath+rotateSuffix, privateKeyPath); err != nil {
+		discard()
+		return nil, fmt.Errorf("failed to replace private key: %w", err)
+	}
+	if err := os.Rename(publicKeyPath+rotateSuffix, publicKeyPath); err != nil {
+		discard()
+		return nil, fmt.Errorf("failed to replace public key: %w", err)
+	}
+
+	result := &RotateKeyResult{
+		Key:            keyState,
+		OldFingerprint: getString(keyState.State, "fingerprint"),
+		VMs:            vms,
+	}
+	state := make(map[string]any, len(keyState.State))
+	for k, v := range keyState.State {
+		state[k] = v
+	}
+	state["publicKey"] = newPublicKey + "\n"
+	state["fingerprint"] = ssh.FingerprintSHA256(publicKey)
+	state["createdAt"] = time.Now().UTC().Format(time.RFC3339)
+	keyState.State = state
+	keyState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	envState.UpdatedAt = keyState.UpdatedAt
+	if err := o.store.Save(envState); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	// Retire the old key, connecting with the new one.
+	for _, vm := range vms {
+		if err := o.editAuthorizedKeys(ctx, envState, vm, byVM[vm], removeAuthorizedKeyScript, oldPublicKey); err != nil {
+			log.Printf("Warning: failed to remove the old %q key from vm %q: %v", keyName, vm, err)
+			result.Warnings = append(result.Warnings, fmt.Sprintf("vm %q: %v", vm, err))
+		}
+	}
+	return result, nil
+}
+
+// keyAuthorizations returns the users of the VMs of an environment whose
+// rendered cloud-init authorizes publicKey, sorted by VM.
+func (o *Orchestrator) keyAuthorizations(envState *v1.EnvironmentState, publicKey string) []keyAuthorization {
+	if envState.Spec == nil {
+		return nil
+	}
+	names := make([]string, 0, len(envState.Resources.VMs))
+	for name := range envState.Resources.VMs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templateCtx := o.executor.templateContextFromState(envState.Spec, envState, nil)
+	var targets []keyAuthorization
+	for _, name := range names {
+		vmSpec, err := o.executor.findVMSpec(envState.Spec, name)
+		if err != nil {
+			continue
+		}
+		rendered, err := o.executor.renderVMSpec(vmSpec, templateCtx)
+		if err != nil {
+			continue
+		}
+		for _, user := range rendered.Spec.CloudInit.Users {
+			for _, key := range user.SshAuthorizedKeys {
+				if sameAuthorizedKey(key, publicKey) {
+					targets = append(targets, keyAuthorization{VM: name, User: user.Name})
+					break
+				}
+			}
+		}
+	}
+	return targets
+}
+
+// sameAuthorizedKey reports whether two authorized_keys entries hold the same
+// key, ignoring their comments.
+func sameAuthorizedKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	return len(fa) >= 2 && len(fb) >= 2 && fa[0] == fb[0] && fa[1] == fb[1]
+}
+
+// editAuthorizedKeys runs script as root on a VM for each user, with the
+// user name and the type and data of a public key as arguments. The script
+// is piped in base64 so that FormatCmd does not have to quote it.
+func (o *Orchestrator) editAuthorizedKeys(ctx context.Context, envState *v1.EnvironmentState, vmName string, users []string, script, publicKey string) error {
+	c, err := o.vmClient(envState, vmName, true)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = c.Close() }()
+	execCtx := client.NewExecutionContext().WithPrivilegeEscalation(client.PrivilegeEscalationSudo())
+	encoded := base64.StdEncoding.EncodeToString([]byte(script))
+	key := strings.Join(strings.Fields(publicKey)[:2], " ")
+	for _, user := range users {
+		line := fmt.Sprintf("echo %s | base64 -d | sh -s -- %s %s", encoded, shellQuote(user), shellQuote(key))
+		_, stderr, err := c.RunWithContext(ctx, execCtx, "sh", "-c", line)
+		if err != nil {
+			if stderr = strings.TrimSpace(stderr); stderr != "" {
+				return fmt.Errorf("user %q: %w: %s", user, err, stderr)
+			}
+			return fmt.Errorf("user %q: %w", user, err)
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// addAuthorizedKeyScript appends the key $2 to authorized_keys of user $1
+// unless it is already there.
+const addAuthorizedKeyScript = `set -e
+home=$(getent passwd "$1" | cut -d: -f6)
+[ -n "$home" ] || { echo "unknown user $1" >&2; exit 1; }
+mkdir -p "$home/.ssh"
+f="$home/.ssh/authorized_keys"
+touch "$f"
+grep -qxF "$2" "$f" || printf '%s\n' "$2" >> "$f"
+chown "$1" "$home/.ssh" "$f"
+chmod 700 "$home/.ssh"
+chmod 600 "$f"`
+
+// removeAuthorizedKeyScript removes the key $2, whatever its comment, from
+// authorized_keys of user $1.
+const removeAuthorizedKeyScript = `set -e
+home=$(getent passwd "$1" | cut -d: -f6)
+f="$home/.ssh/authorized_keys"
+[ -n "$home" ] && [ -f "$f" ] || exit 0
+grep -vF "$2" "$f" > "$f.tmp" || true
+cat "$f.tmp" > "$f"
+rm -f "$f.tmp"`
+
+// newKeyPairLike generates a key pair of the type and size of an
+// authorized_keys entry, returning the OpenSSH private key in PEM format.
+func newKeyPairLike(authorizedKey string) ([]byte, ssh.PublicKey, error) {
+	old, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	switch old.Type() {
+	case ssh.KeyAlgoED25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return marshalKeyPair(priv, pub)
+	case ssh.KeyAlgoRSA:
+		bits := 4096
+		if cpk, ok := old.(ssh.CryptoPublicKey); ok {
+			if rsaKey, ok := cpk.CryptoPublicKey().(*rsa.PublicKey); ok {
+				bits = rsaKey.N.BitLen()
+			}
+		}
+		priv, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, nil, err
+		}
+		return marshalKeyPair(priv, &priv.PublicKey)
+	default:
+		return nil, nil, errors.New("unsupported key type " + old.Type())
+	}
+}
+
+// marshalKeyPair encodes a private key in OpenSSH PEM format and returns it
+// with the SSH form of its public key.
+func marshalKeyPair(priv, pub any) ([]byte, ssh.PublicKey, error) {
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(block), publicKey, nil
+}
@@ -0,0 +1,201 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestNewKeyPairLike(t *testing.T) {
+	_, ed, err := newKeyPairLike("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGCgQ5U2gsZ4Xe6nK1JkkPg5TZQ9Jh9bHUMGKlUmTU3E old")
+	if err != nil {
+		t.Fatalf("newKeyPairLike(ed25519) error = %v", err)
+	}
+	if ed.Type() != ssh.KeyAlgoED25519 {
+		t.Errorf("type = %q, want %q", ed.Type(), ssh.KeyAlgoED25519)
+	}
+
+	_, rsaKey, err := newKeyPairLike(string(ssh.MarshalAuthorizedKey(testRSAPublicKey(t, 2048))))
+	if err != nil {
+		t.Fatalf("newKeyPairLike(rsa) error = %v", err)
+	}
+	if bits := rsaKey.(ssh.CryptoPublicKey).CryptoPublicKey().(*rsa.PublicKey).N.BitLen(); bits != 2048 {
+		t.Errorf("rsa bits = %d, want 2048", bits)
+	}
+
+	if _, _, err := newKeyPairLike("not a key"); err == nil {
+		t.Error("newKeyPairLike(invalid) error = nil")
+	}
+}
+
+func TestSameAuthorizedKey(t *testing.T) {
+	if !sameAuthorizedKey("ssh-ed25519 AAAA one\n", "ssh-ed25519 AAAA") {
+		t.Error("keys differing by comment should match")
+	}
+	if sameAuthorizedKey("ssh-ed25519 AAAA", "ssh-ed25519 BBBB") {
+		t.Error("different keys should not match")
+	}
+	if sameAuthorizedKey("", "") {
+		t.Error("empty keys should not match")
+	}
+}
+
+func TestOrchestrator_RotateKey(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	if _, err := o.RotateKey(context.Background(), "env", "ssh"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RotateKey() error = %v, want ErrReadOnly", err)
+	}
+	_ = o.Close()
+
+	o, err = NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	dir := t.TempDir()
+	privateKeyPath := filepath.Join(dir, "ssh")
+	oldPEM, oldPub, err := newKeyPairLike("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGCgQ5U2gsZ4Xe6nK1JkkPg5TZQ9Jh9bHUMGKlUmTU3E")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(privateKeyPath, oldPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldAuthorized := string(ssh.MarshalAuthorizedKey(oldPub))
+	if err := os.WriteFile(privateKeyPath+".pub", []byte(oldAuthorized), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	envState := &v1.EnvironmentState{
+		ID: "env-rotate",
+		Spec: &v1.Spec{
+			Keys: []v1.KeyResource{{Name: "ssh", Spec: v1.KeySpec{Type: "ed25519"}}},
+			Vms: []v1.VMResource{
+				{Name: "web", Spec: v1.VMSpec{CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "ubuntu", SshAuthorizedKeys: []string{"{{ .Keys.ssh.PublicKey }}"}}},
+				}}},
+				{Name: "db", Spec: v1.VMSpec{CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "ubuntu", SshAuthorizedKeys: []string{"ssh-ed25519 other"}}},
+				}}},
+			},
+		},
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"ssh": {Status: v1.StatusReady, State: map[string]any{
+				"name":           "ssh",
+				"publicKey":      oldAuthorized,
+				"publicKeyPath":  privateKeyPath + ".pub",
+				"privateKeyPath": privateKeyPath,
+				"fingerprint":    ssh.FingerprintSHA256(oldPub),
+			}}},
+			VMs: map[string]*v1.ResourceState{
+				"web": {Status: v1.StatusReady, State: map[string]any{"name": "web"}},
+				"db":  {Status: v1.StatusReady, State: map[string]any{"name": "db"}},
+			},
+		},
+	}
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := o.keyAuthorizations(envState, oldAuthorized)
+	if len(targets) != 1 || targets[0] != (keyAuthorization{VM: "web", User: "ubuntu"}) {
+		t.Errorf("keyAuthorizations() = %v, want web/ubuntu", targets)
+	}
+
+	if _, err := o.RotateKey(context.Background(), "env-rotate", "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("RotateKey(missing) error = %v, want not found", err)
+	}
+
+	// web has no IP: the new key cannot be pushed and nothing changes.
+	if _, err := o.RotateKey(context.Background(), "env-rotate", "ssh"); err == nil || !strings.Contains(err.Error(), `vm "web"`) {
+		t.Fatalf("RotateKey() error = %v, want failure on vm web", err)
+	}
+	if got, _ := os.ReadFile(privateKeyPath + ".pub"); string(got) != oldAuthorized {
+		t.Error("public key file changed after a failed rotation")
+	}
+	if _, err := os.Stat(privateKeyPath + rotateSuffix); !os.IsNotExist(err) {
+		t.Errorf("temporary key left behind: %v", err)
+	}
+
+	// Without VMs authorizing it, the key is rotated locally.
+	envState.Spec.Vms = envState.Spec.Vms[1:]
+	delete(envState.Resources.VMs, "web")
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+	result, err := o.RotateKey(context.Background(), "env-rotate", "ssh")
+	if err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+	if result.OldFingerprint != ssh.FingerprintSHA256(oldPub) || len(result.VMs) != 0 {
+		t.Errorf("RotateKey() = %+v", result)
+	}
+	newAuthorized := getString(result.Key.State, "publicKey")
+	if sameAuthorizedKey(newAuthorized, oldAuthorized) {
+		t.Error("public key was not rotated")
+	}
+	if got, _ := os.ReadFile(privateKeyPath + ".pub"); !sameAuthorizedKey(string(got), newAuthorized) {
+		t.Errorf("public key file = %q, want %q", got, newAuthorized)
+	}
+	privateKey, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("rotated private key: %v", err)
+	}
+	if !sameAuthorizedKey(string(ssh.MarshalAuthorizedKey(signer.PublicKey())), newAuthorized) {
+		t.Error("private key does not match the new public key")
+	}
+	loaded, err := o.store.Load("env-rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := getString(loaded.Resources.Keys["ssh"].State, "fingerprint"); got != ssh.FingerprintSHA256(signer.PublicKey()) {
+		t.Errorf("stored fingerprint = %q", got)
+	}
+}
+
+func testRSAPublicKey(t *testing.T, bits int) ssh.PublicKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sshKey
+}
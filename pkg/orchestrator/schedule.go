@@ -0,0 +1,152 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/schedule"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// DefaultScheduleInterval is how often RunSchedules looks for due schedules.
+const DefaultScheduleInterval = 30 * time.Second
+
+// Schedules returns the store of schedule definitions in the state
+// directory.
+func (o *Orchestrator) Schedules() *schedule.Store {
+	return schedule.NewStore(o.config.StateDir)
+}
+
+// RunSchedule runs a schedule once: it deletes the environment created by its
+// previous run, then creates a new one from the spec file. The schedule is
+// updated with the outcome but not saved. It returns the new environment ID.
+func (o *Orchestrator) RunSchedule(ctx context.Context, s *schedule.Schedule, now time.Time) (string, error) {
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("schedule %q rejected: %w", s.Name, err)
+	}
+	defer end()
+	envID, err := o.runSchedule(ctx, s, now)
+	s.LastRun = now.UTC().Format(time.RFC3339)
+	s.LastError = ""
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	return envID, err
+}
+
+// runSchedule implements RunSchedule.
+func (o *Orchestrator) runSchedule(ctx context.Context, s *schedule.Schedule, now time.Time) (string, error) {
+	data, err := os.ReadFile(s.SpecFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read spec: %w", err)
+	}
+	parsed, err := spec.Parse(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse spec %q: %w", s.SpecFile, err)
+	}
+
+	// The previous instance goes first: a spec with a fixed environment ID
+	// would collide with it, and the host may not fit both.
+	if s.EnvironmentID != "" {
+		log.Printf("Schedule %q: deleting previous environment %s", s.Name, s.EnvironmentID)
+		if err := o.Delete(ctx, &v1.DeleteInput{
+			TestID:   s.EnvironmentID,
+			Metadata: map[string]string{MetadataEnvironmentID: s.EnvironmentID},
+		}); err != nil {
+			return "", fmt.Errorf("failed to delete previous environment %s: %w", s.EnvironmentID, err)
+		}
+		s.EnvironmentID = ""
+	}
+
+	testID := s.TestID(now)
+	log.Printf("Schedule %q: creating environment %s", s.Name, testID)
+	created, err := o.Create(ctx, &v1.CreateInput{TestID: testID, Stage: s.Stage, Spec: parsed.ToMap()})
+	if err != nil {
+		return "", err
+	}
+	s.EnvironmentID = testID
+	if id := created.Artifact.Metadata[MetadataEnvironmentID]; id != "" {
+		s.EnvironmentID = id
+	}
+	return s.EnvironmentID, nil
+}
+
+// RunSchedules runs the stored schedules as they become due until ctx is
+// done, checking every interval (DefaultScheduleInterval if zero). Runs are
+// sequential. Runs missed while no scheduler was running are skipped, so a
+// restart never triggers a burst of creations.
+func (o *Orchestrator) RunSchedules(ctx context.Context, interval time.Duration) error {
+	if o.config.ReadOnly {
+		return fmt.Errorf("scheduler rejected: %w", ErrReadOnly)
+	}
+	if interval <= 0 {
+		interval = DefaultScheduleInterval
+	}
+	store := o.Schedules()
+	started := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		schedules, err := store.List()
+		if err != nil {
+			log.Printf("Failed to list schedules: %v", err)
+		}
+		now := time.Now()
+		for _, s := range schedules {
+			if ctx.Err() != nil {
+				break
+			}
+			if !scheduleDue(s, started, now) {
+				continue
+			}
+			if envID, err := o.RunSchedule(ctx, s, now); err != nil {
+				log.Printf("Schedule %q failed: %v", s.Name, err)
+			} else {
+				log.Printf("Schedule %q: environment %s is ready", s.Name, envID)
+			}
+			if err := store.Save(s); err != nil {
+				log.Printf("Failed to save schedule %q: %v", s.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleDue reports whether a schedule has a run time in (since, now],
+// where since is its last run or the scheduler start, whichever is later.
+func scheduleDue(s *schedule.Schedule, started, now time.Time) bool {
+	since := started
+	if last, err := time.Parse(time.RFC3339, s.LastRun); err == nil && last.After(since) {
+		since = last
+	}
+	next, err := s.Next(since)
+	if err != nil || next.IsZero() {
+		return false
+	}
+	return !next.After(now)
+}
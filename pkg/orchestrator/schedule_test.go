@@ -0,0 +1,92 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/schedule"
+)
+
+func TestScheduleDue(t *testing.T) {
+	started := time.Date(2025, 1, 15, 2, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		lastRun string
+		now     time.Time
+		want    bool
+	}{
+		{name: "before first run", now: time.Date(2025, 1, 15, 2, 59, 0, 0, time.UTC)},
+		{name: "at run time", now: time.Date(2025, 1, 15, 3, 0, 10, 0, time.UTC), want: true},
+		{name: "already ran", lastRun: "2025-01-15T03:00:10Z", now: time.Date(2025, 1, 15, 3, 0, 40, 0, time.UTC)},
+		{name: "next day", lastRun: "2025-01-15T03:00:10Z", now: time.Date(2025, 1, 16, 3, 0, 0, 0, time.UTC), want: true},
+		{name: "missed before start", lastRun: "2025-01-10T03:00:00Z", now: time.Date(2025, 1, 15, 2, 31, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &schedule.Schedule{Name: "nightly", Cron: "0 3 * * *", LastRun: tt.lastRun}
+			if got := scheduleDue(s, started, tt.now); got != tt.want {
+				t.Errorf("scheduleDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_RunScheduleRecordsError(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	s := &schedule.Schedule{
+		Name:          "nightly",
+		Cron:          "@daily",
+		SpecFile:      filepath.Join(t.TempDir(), "missing.yaml"),
+		EnvironmentID: "nightly-20250114-0000",
+	}
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if _, err := o.RunSchedule(context.Background(), s, now); err == nil {
+		t.Fatal("RunSchedule() should fail without a spec file")
+	}
+	if s.LastRun != "2025-01-15T00:00:00Z" || !strings.Contains(s.LastError, "failed to read spec") {
+		t.Errorf("schedule = %+v, want last run and error recorded", s)
+	}
+	// The previous environment is only deleted once the spec is readable.
+	if s.EnvironmentID != "nightly-20250114-0000" {
+		t.Errorf("EnvironmentID = %q, want previous environment kept", s.EnvironmentID)
+	}
+}
+
+func TestOrchestrator_RunSchedulesReadOnly(t *testing.T) {
+	config := newTestConfig(t)
+	config.ReadOnly = true
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if err := o.RunSchedules(context.Background(), time.Second); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RunSchedules() error = %v, want ErrReadOnly", err)
+	}
+}
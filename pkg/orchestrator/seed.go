@@ -0,0 +1,50 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+// checkSeed rejects a seed set in the spec of environment envID while
+// another environment of store uses it: the UUIDs and MAC addresses of VMs
+// derive from the seed and their name only, so the VMs of both environments
+// would collide on the host.
+func checkSeed(store *state.Store, envID, envSeed string) error {
+	if envSeed == "" {
+		return nil
+	}
+	ids, err := store.List()
+	if err != nil {
+		return fmt.Errorf("failed to list environments: %w", err)
+	}
+	for _, id := range ids {
+		if id == envID {
+			continue
+		}
+		envState, err := store.Load(id)
+		if err != nil {
+			log.Printf("Failed to load environment %q: %v", id, err)
+			continue
+		}
+		if envState.Seed == envSeed {
+			return fmt.Errorf("seed %q is already used by environment %q, whose VMs would share UUIDs and MAC addresses with those of %q: delete it first or use another seed", envSeed, id, envID)
+		}
+	}
+	return nil
+}
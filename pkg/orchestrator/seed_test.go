@@ -0,0 +1,55 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/state"
+)
+
+func TestCheckSeed(t *testing.T) {
+	store := state.NewStore(t.TempDir())
+	if err := store.Save(&v1.EnvironmentState{ID: "env-1", Status: v1.StatusReady, Seed: "0123456789abcdef"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		envID   string
+		seed    string
+		wantErr string
+	}{
+		{name: "random seed", envID: "env-2"},
+		{name: "other seed", envID: "env-2", seed: "fedcba9876543210"},
+		{name: "same environment", envID: "env-1", seed: "0123456789abcdef"},
+		{name: "used seed", envID: "env-2", seed: "0123456789abcdef", wantErr: `already used by environment "env-1"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSeed(store, tt.envID, tt.seed)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkSeed() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkSeed() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/service"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Keys of the state of service resources.
+const (
+	servicePIDKey      = "pid"
+	serviceTypeKey     = "type"
+	serviceAddressKey  = "address"
+	servicePortKey     = "port"
+	serviceEndpointKey = "endpoint"
+	// The credentials of object-store services are recorded so that they
+	// survive restarts of the server and reach the artifact.
+	serviceAccessKeyKey = "accessKey"
+	serviceSecretKeyKey = "secretKey"
+	// serviceOutputKey records the file receiving the guest logs of
+	// log-collector services.
+	serviceOutputKey = "output"
+)
+
+// GuestLogsDir is the directory of the artifact directory receiving the
+// guest logs of log-collector services, one file per service.
+const GuestLogsDir = "guest-logs"
+
+// createService starts a service resource on the host, listening on the
+// gateway address of its network. Services are run by the orchestrator, not
+// by providers, so their state records no provider.
+func (e *Executor) createService(ref v1.ResourceRef, spec *v1.Spec, templateCtx *specpkg.TemplateContext, envState *v1.EnvironmentState) error {
+	svcRes, err := e.findServiceSpec(spec, ref.Name)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	rendered, err := e.renderServiceSpec(svcRes, templateCtx)
+	address := templateCtx.Networks[svcRes.Spec.Network].IP
+	e.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to render service spec: %w", err)
+	}
+	if address == "" {
+		return fmt.Errorf("service %q: network %q has no gateway address", ref.Name, svcRes.Spec.Network)
+	}
+
+	layout := e.store.Layout()
+	if spec.StateDir != "" {
+		layout = paths.New(spec.StateDir)
+	}
+	env := layout.Env(envState.ID)
+	port := rendered.Spec.Port
+	if port == 0 {
+		port = service.DefaultPort(rendered.Spec.Type)
+	}
+	config := service.Config{
+		Type:      rendered.Spec.Type,
+		Address:   address,
+		Port:      port,
+		Root:      rendered.Spec.Root,
+		Upstream:  rendered.Spec.Upstream,
+		AccessKey: rendered.Spec.AccessKey,
+		SecretKey: rendered.Spec.SecretKey,
+		Dir:       env.ServiceDir(ref.Name),
+		LogPath:   filepath.Join(env.LogsDir(), "service-"+ref.Name+".log"),
+	}
+	if config.Type == service.TypeObjectStore && (config.AccessKey == "" || config.SecretKey == "") {
+		accessKey, secretKey, err := service.NewCredentials()
+		if err != nil {
+			return err
+		}
+		if config.AccessKey == "" {
+			config.AccessKey = accessKey
+		}
+		if config.SecretKey == "" {
+			config.SecretKey = secretKey
+		}
+	}
+	if config.Type == service.TypeLogCollector {
+		logsDir := filepath.Join(envState.ArtifactDir, GuestLogsDir)
+		if envState.ArtifactDir == "" {
+			logsDir = env.LogsDir()
+		}
+		if err := os.MkdirAll(logsDir, 0o755); err != nil {
+			return fmt.Errorf("failed to create guest logs directory: %w", err)
+		}
+		config.Output = filepath.Join(logsDir, ref.Name+".log")
+	}
+	pid, err := service.Start(config)
+	if err != nil {
+		e.mu.Lock()
+		e.updateResourceState(envState, ref, "", v1.StatusFailed, nil, err.Error())
+		e.mu.Unlock()
+		return fmt.Errorf("failed to start service %q: %w", ref.Name, err)
+	}
+
+	resourceState := map[string]any{
+		servicePIDKey:      pid,
+		serviceTypeKey:     config.Type,
+		serviceAddressKey:  config.Address,
+		servicePortKey:     config.Port,
+		serviceEndpointKey: config.Endpoint(),
+	}
+	if config.Type == service.TypeObjectStore {
+		resourceState[serviceAccessKeyKey] = config.AccessKey
+		resourceState[serviceSecretKeyKey] = config.SecretKey
+	}
+	if config.Output != "" {
+		resourceState[serviceOutputKey] = config.Output
+	}
+	e.mu.Lock()
+	e.updateResourceState(envState, ref, "", v1.StatusReady, resourceState, "")
+	e.updateTemplateContext(templateCtx, ref, resourceState)
+	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	err = e.store.Save(envState)
+	e.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to save state after creating service/%s: %w", ref.Name, err)
+	}
+	return nil
+}
+
+// deleteService stops a service resource. A service that already exited is
+// considered deleted.
+func (e *Executor) deleteService(ref v1.ResourceRef, resourceState *v1.ResourceState, envState *v1.EnvironmentState) error {
+	if pid := int(getUint32(resourceState.State, servicePIDKey)); pid > 0 {
+		if err := service.Stop(pid); err != nil {
+			return err
+		}
+	}
+	e.mu.Lock()
+	e.updateResourceState(envState, ref, "", v1.StatusDestroyed, nil, "")
+	e.mu.Unlock()
+	return nil
+}
+
+// findServiceSpec finds a service resource by name in the spec.
+func (e *Executor) findServiceSpec(spec *v1.Spec, name string) (*v1.ServiceResource, error) {
+	for i := range spec.Services {
+		if spec.Services[i].Name == name {
+			return &spec.Services[i], nil
+		}
+	}
+	return nil, fmt.Errorf("service resource %q not found in spec", name)
+}
+
+// renderServiceSpec creates a deep copy and renders templates in a service spec.
+func (e *Executor) renderServiceSpec(original *v1.ServiceResource, templateCtx *specpkg.TemplateContext) (*v1.ServiceResource, error) {
+	data, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	var copy v1.ServiceResource
+	if err := json.Unmarshal(data, &copy); err != nil {
+		return nil, err
+	}
+	if err := specpkg.RenderSpec(&copy, templateCtx); err != nil {
+		return nil, err
+	}
+	return &copy, nil
+}
@@ -0,0 +1,236 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestBuildDAG_Services(t *testing.T) {
+	spec := &v1.Spec{
+		Networks: []v1.NetworkResource{{Name: "lab", Kind: "nat"}},
+		Vms:      []v1.VMResource{{Name: "fixtures", Spec: v1.VMSpec{Network: "lab"}}},
+		Services: []v1.ServiceResource{
+			{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Root: "/srv/{{ .VMs.fixtures.Name }}"}},
+		},
+	}
+	dag, err := BuildDAG(spec)
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+	serviceRef := v1.ResourceRef{Kind: "service", Name: "files"}
+	for _, dep := range []v1.ResourceRef{{Kind: "network", Name: "lab"}, {Kind: "vm", Name: "fixtures"}} {
+		if !dag.DependsOn(serviceRef, dep) {
+			t.Errorf("service should depend on %s %q", dep.Kind, dep.Name)
+		}
+	}
+}
+
+func TestExecutor_createDeleteService(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	_ = l.Close()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "fixture.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stateDir := t.TempDir()
+	spec := &v1.Spec{
+		StateDir: stateDir,
+		Networks: []v1.NetworkResource{{Name: "lab", Kind: "nat"}},
+		Services: []v1.ServiceResource{
+			{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Port: port, Root: root}},
+		},
+	}
+	ctx := specpkg.NewTemplateContext()
+	ctx.Networks["lab"] = specpkg.NetworkTemplateData{IP: "127.0.0.1"}
+	envState := &v1.EnvironmentState{ID: "env-1"}
+	e := newTestExecutor(t)
+	ref := v1.ResourceRef{Kind: "service", Name: "files"}
+
+	if err := e.createResource(context.Background(), ref, spec, ctx, envState, nil, nil); err != nil {
+		t.Fatalf("createResource() error = %v", err)
+	}
+	endpoint := "http://127.0.0.1:" + strconv.Itoa(port)
+	rs := envState.Resources.Services["files"]
+	if rs == nil || rs.Status != v1.StatusReady || getString(rs.State, serviceEndpointKey) != endpoint {
+		t.Fatalf("service state = %+v, want ready at %s", rs, endpoint)
+	}
+	if got := ctx.Services["files"]; got.Endpoint != endpoint || got.Port != port || got.Address != "127.0.0.1" {
+		t.Errorf("template data = %+v", got)
+	}
+
+	resp, err := http.Get(endpoint + "/fixture.txt")
+	if err != nil {
+		_ = e.deleteResource(context.Background(), ref, envState, nil)
+		t.Fatalf("GET fixture: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("GET fixture = %q, want hello", body)
+	}
+
+	if err := e.deleteResource(context.Background(), ref, envState, nil); err != nil {
+		t.Fatalf("deleteResource() error = %v", err)
+	}
+	if rs := envState.Resources.Services["files"]; rs == nil || rs.Status != v1.StatusDestroyed {
+		t.Errorf("service state after delete = %+v, want destroyed", rs)
+	}
+	if conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port))); err == nil {
+		_ = conn.Close()
+		t.Error("service still accepts connections after delete")
+	}
+	if _, err := os.Stat(filepath.Join(stateDir, "envs", "env-1", "logs", "service-files.log")); err != nil {
+		t.Errorf("service log not written: %v", err)
+	}
+}
+
+func TestExecutor_ExecuteDelete_TeardownStopsServices(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	engine := filepath.Join(t.TempDir(), "testenv-vm-provider-stub")
+	if out, err := exec.Command("go", "build", "-o", engine, "../../cmd/providers/testenv-vm-provider-stub").CombinedOutput(); err != nil {
+		t.Fatalf("failed to build the stub provider: %v\n%s", err, out)
+	}
+	e := newTestExecutor(t)
+	if err := e.manager.Start(v1.ProviderConfig{Name: "stub", Engine: engine}); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() { _ = e.manager.StopAll() }()
+	if !e.manager.SupportsTeardown("stub") {
+		t.Fatal("stub provider does not serve the teardown tool")
+	}
+	if _, err := e.manager.Call("stub", "key_create", &providerv1.KeyCreateRequest{Name: "ssh", Spec: providerv1.KeySpec{Type: "ed25519"}}); err != nil {
+		t.Fatalf("key_create error = %v", err)
+	}
+
+	// A host service in a process group of its own, as service.Start runs it
+	cmd := exec.Command("sleep", "60")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	defer func() { _ = cmd.Process.Kill() }()
+
+	envState := &v1.EnvironmentState{
+		ID: "env-1",
+		Resources: v1.ResourceMap{
+			Keys:     map[string]*v1.ResourceState{"ssh": {Provider: "stub", Status: v1.StatusReady}},
+			Services: map[string]*v1.ResourceState{"files": {Status: v1.StatusReady, State: map[string]any{servicePIDKey: cmd.Process.Pid}}},
+		},
+		ExecutionPlan: &v1.ExecutionPlan{Phases: []v1.Phase{
+			{Resources: []v1.ResourceRef{{Kind: "key", Name: "ssh"}}},
+			{Resources: []v1.ResourceRef{{Kind: "service", Name: "files"}}},
+		}},
+	}
+	if err := e.ExecuteDelete(context.Background(), envState, nil); err != nil {
+		t.Fatalf("ExecuteDelete() error = %v", err)
+	}
+	for name, rs := range map[string]*v1.ResourceState{"key": envState.Resources.Keys["ssh"], "service": envState.Resources.Services["files"]} {
+		if rs.Status != v1.StatusDestroyed {
+			t.Errorf("%s status = %q, want destroyed", name, rs.Status)
+		}
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Error("service process still runs after ExecuteDelete()")
+	}
+}
+
+func TestExecutor_createService_NoGateway(t *testing.T) {
+	spec := &v1.Spec{
+		Services: []v1.ServiceResource{{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab"}}},
+	}
+	e := newTestExecutor(t)
+	err := e.createService(v1.ResourceRef{Kind: "service", Name: "apt"}, spec, specpkg.NewTemplateContext(), &v1.EnvironmentState{ID: "env-1"})
+	if err == nil {
+		t.Error("createService() expected error for a network without gateway address")
+	}
+}
+
+func TestOrchestrator_buildArtifact_Services(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	artifact := o.buildArtifact("test-1", &v1.EnvironmentState{
+		ID: "test-1",
+		Resources: v1.ResourceMap{Services: map[string]*v1.ResourceState{
+			"registry-mirror": {State: map[string]any{serviceEndpointKey: "http://192.168.100.1:5000"}},
+			"s3": {State: map[string]any{
+				serviceEndpointKey:  "http://192.168.100.1:9000",
+				serviceAccessKeyKey: "tester",
+				serviceSecretKeyKey: "secret-key",
+			}},
+			"logs": {State: map[string]any{serviceOutputKey: "/artifacts/test-1/guest-logs/logs.log"}},
+		}},
+		ArtifactDir: "/artifacts/test-1",
+	}, nil)
+
+	if got := artifact.Env["TESTENV_SERVICE_REGISTRY_MIRROR_ENDPOINT"]; got != "http://192.168.100.1:5000" {
+		t.Errorf("service endpoint env = %q", got)
+	}
+	if got := artifact.Metadata["testenv-vm.service.registry-mirror.endpoint"]; got != "http://192.168.100.1:5000" {
+		t.Errorf("service endpoint metadata = %q", got)
+	}
+	if got := artifact.Env["TESTENV_SERVICE_S3_ACCESS_KEY"]; got != "tester" {
+		t.Errorf("service access key env = %q", got)
+	}
+	if got := artifact.Env["TESTENV_SERVICE_S3_SECRET_KEY"]; got != "secret-key" {
+		t.Errorf("service secret key env = %q", got)
+	}
+	if got := artifact.Env["TESTENV_SERVICE_LOGS_LOG"]; got != "/artifacts/test-1/guest-logs/logs.log" {
+		t.Errorf("service log env = %q", got)
+	}
+	if got := artifact.Files["testenv-vm.service.logs.log"]; got != "guest-logs/logs.log" {
+		t.Errorf("service log file = %q", got)
+	}
+	if _, ok := artifact.Env["TESTENV_SERVICE_REGISTRY_MIRROR_ACCESS_KEY"]; ok {
+		t.Error("services without credentials should not export an access key")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown is returned by the operations of the orchestrator once
+// Shutdown has begun.
+var ErrShuttingDown = errors.New("orchestrator is shutting down")
+
+// ErrOperationsInterrupted is returned by Shutdown when in-flight operations
+// did not finish within the grace period and had to be cancelled.
+var ErrOperationsInterrupted = errors.New("in-flight operations were interrupted")
+
+// operations tracks in-flight operations, such as Create, Delete or PowerVM,
+// so that Shutdown can drain or cancel them. The zero value is ready to use.
+type operations struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	draining bool
+	next     uint64
+	cancels  map[uint64]context.CancelFunc
+}
+
+// begin registers an operation and returns its context, which is cancelled if
+// Shutdown aborts in-flight operations. The returned func must be called when
+// the operation ends.
+func (ops *operations) begin(ctx context.Context) (context.Context, func(), error) {
+	ops.mu.Lock()
+	defer ops.mu.Unlock()
+
+	if ops.draining {
+		return nil, nil, ErrShuttingDown
+	}
+	if ops.cancels == nil {
+		ops.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	opCtx, cancel := context.WithCancel(ctx)
+	id := ops.next
+	ops.next++
+	ops.cancels[id] = cancel
+	ops.wg.Add(1)
+
+	return opCtx, func() {
+		ops.mu.Lock()
+		delete(ops.cancels, id)
+		ops.mu.Unlock()
+		cancel()
+		ops.wg.Done()
+	}, nil
+}
+
+// drain rejects new operations and returns a channel closed once every
+// in-flight operation has ended.
+func (ops *operations) drain() <-chan struct{} {
+	ops.mu.Lock()
+	ops.draining = true
+	ops.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ops.wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// cancelAll cancels every in-flight operation and returns how many there were.
+func (ops *operations) cancelAll() int {
+	ops.mu.Lock()
+	defer ops.mu.Unlock()
+
+	for _, cancel := range ops.cancels {
+		cancel()
+	}
+	return len(ops.cancels)
+}
+
+// Shutdown stops accepting operations and waits for in-flight
+// operations until ctx is done. Operations still running are then cancelled:
+// a creation stops at its next phase boundary, rolls back when
+// CleanupOnFailure is set, and records a failed state. Shutdown waits up to
+// abortTimeout for them to persist their state, then stops all providers.
+//
+// It returns nil when every operation finished on its own, and an error
+// wrapping ErrOperationsInterrupted otherwise.
+func (o *Orchestrator) Shutdown(ctx context.Context, abortTimeout time.Duration) error {
+	done := o.ops.drain()
+
+	var shutdownErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		n := o.ops.cancelAll()
+		log.Printf("Shutdown grace period expired, cancelling %d in-flight operation(s)", n)
+
+		select {
+		case <-done:
+			shutdownErr = fmt.Errorf("%w: %d operation(s) cancelled", ErrOperationsInterrupted, n)
+		case <-time.After(abortTimeout):
+			shutdownErr = fmt.Errorf("%w: %d operation(s) still running after %s",
+				ErrOperationsInterrupted, n, abortTimeout)
+		}
+	}
+
+	if err := o.Close(); err != nil {
+		return errors.Join(shutdownErr, fmt.Errorf("failed to stop providers: %w", err))
+	}
+	return shutdownErr
+}
@@ -0,0 +1,183 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestShutdown_RejectsNewOperations(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+
+	if err := orchestrator.Shutdown(context.Background(), time.Second); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	ctx := context.Background()
+	_, err = orchestrator.Create(ctx, &v1.CreateInput{TestID: "test-new", Spec: map[string]any{}})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Create() error = %v, want ErrShuttingDown", err)
+	}
+	err = orchestrator.Delete(ctx, &v1.DeleteInput{TestID: "test-new"})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Delete() error = %v, want ErrShuttingDown", err)
+	}
+
+	_, err = orchestrator.PowerVM(ctx, "test-new", "vm", "stop", PowerOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("PowerVM() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.ExecVM(ctx, "test-new", "vm", "true", ExecOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("ExecVM() error = %v, want ErrShuttingDown", err)
+	}
+	err = orchestrator.CopyToVM(ctx, "test-new", "vm", "/tmp/a", "/tmp/b", CopyOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("CopyToVM() error = %v, want ErrShuttingDown", err)
+	}
+	err = orchestrator.CopyFromVM(ctx, "test-new", "vm", "/tmp/b", "/tmp/a")
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("CopyFromVM() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.MigrateVM(ctx, "test-new", "vm", "qemu+ssh://host/system", MigrateOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("MigrateVM() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.RotateKey(ctx, "test-new", "key")
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("RotateKey() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.Fork(ctx, "test-new", 1, nil)
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Fork() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.StartCapture("test-new", CaptureOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("StartCapture() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.StopCapture("capture")
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("StopCapture() error = %v, want ErrShuttingDown", err)
+	}
+	_, err = orchestrator.GarbageCollect(ctx, GCOptions{})
+	if !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("GarbageCollect() error = %v, want ErrShuttingDown", err)
+	}
+}
+
+func TestShutdown_WaitsForInFlightOperations(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+
+	_, end, err := orchestrator.ops.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+
+	finished := make(chan error, 1)
+	go func() {
+		finished <- orchestrator.Shutdown(context.Background(), time.Second)
+	}()
+
+	select {
+	case err := <-finished:
+		t.Fatalf("Shutdown() returned before the operation ended: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	end()
+	if err := <-finished; err != nil {
+		t.Errorf("Shutdown() error = %v, want nil", err)
+	}
+}
+
+func TestShutdown_CancelsAfterGracePeriod(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+
+	opCtx, end, err := orchestrator.ops.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+	// Simulate an operation that rolls back once cancelled.
+	go func() {
+		<-opCtx.Done()
+		end()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = orchestrator.Shutdown(ctx, time.Second)
+	if !errors.Is(err, ErrOperationsInterrupted) {
+		t.Errorf("Shutdown() error = %v, want ErrOperationsInterrupted", err)
+	}
+}
+
+func TestShutdown_AbortTimeout(t *testing.T) {
+	orchestrator, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+
+	_, end, err := orchestrator.ops.begin(context.Background())
+	if err != nil {
+		t.Fatalf("begin() error = %v", err)
+	}
+	defer end()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = orchestrator.Shutdown(ctx, 10*time.Millisecond)
+	if !errors.Is(err, ErrOperationsInterrupted) {
+		t.Errorf("Shutdown() error = %v, want ErrOperationsInterrupted", err)
+	}
+}
+
+func TestExecuteCreate_StopsWhenCancelled(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	envState := &v1.EnvironmentState{ID: "test-cancel", Status: v1.StatusCreating}
+	plan := [][]v1.ResourceRef{{{Kind: "key", Name: "k1"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := executor.ExecuteCreate(ctx, &v1.Spec{}, plan, nil, envState, nil, nil)
+	if err != nil {
+		t.Fatalf("ExecuteCreate() error = %v", err)
+	}
+	if result.Success {
+		t.Fatal("ExecuteCreate() succeeded after cancellation")
+	}
+	if len(result.Errors) != 1 || !errors.Is(result.Errors[0], context.Canceled) {
+		t.Errorf("ExecuteCreate() errors = %v, want context.Canceled", result.Errors)
+	}
+	if envState.Status != v1.StatusFailed {
+		t.Errorf("state status = %s, want %s", envState.Status, v1.StatusFailed)
+	}
+}
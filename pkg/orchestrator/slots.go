@@ -0,0 +1,196 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// MetadataClient is the create input metadata key naming the client of a
+// creation. Creations waiting for a slot of the server take turns between
+// clients, so one client queuing many creations does not starve the others.
+const MetadataClient = "testenv-vm.client"
+
+// slotQueue limits the creations running at once in a server. Waiting
+// creations are granted slots round-robin between clients, and in arrival
+// order for each client. A nil queue has no limit.
+type slotQueue struct {
+	limit int
+
+	mu      sync.Mutex
+	running int
+	// clients have waiting tickets, in the order they are served.
+	clients []string
+	waiting map[string][]*slotTicket
+}
+
+// slotTicket is a creation waiting for a slot.
+type slotTicket struct {
+	client string
+	// granted is closed when the ticket gets a slot.
+	granted chan struct{}
+	// positions receives the latest position of the ticket when it changes.
+	positions chan int
+	position  int
+}
+
+// newSlotQueue returns a queue running at most limit creations at once, or
+// any number when limit is not positive.
+func newSlotQueue(limit int) *slotQueue {
+	return &slotQueue{limit: limit, waiting: make(map[string][]*slotTicket)}
+}
+
+// acquire waits for a slot for a creation of client and returns the function
+// releasing it. report is called with the 1-based position of the creation
+// when it has to wait, and again each time the position changes. It fails
+// with the cause of ctx when ctx is done first.
+func (q *slotQueue) acquire(ctx context.Context, client string, report func(position int)) (func(), error) {
+	if q == nil || q.limit <= 0 {
+		return func() {}, nil
+	}
+	q.mu.Lock()
+	if q.running < q.limit && len(q.clients) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	t := &slotTicket{client: client, granted: make(chan struct{}), positions: make(chan int, 1)}
+	if len(q.waiting[client]) == 0 {
+		q.clients = append(q.clients, client)
+	}
+	q.waiting[client] = append(q.waiting[client], t)
+	q.updatePositions()
+	q.mu.Unlock()
+
+	for {
+		select {
+		case <-t.granted:
+			return q.releaseFunc(), nil
+		case position := <-t.positions:
+			report(position)
+		case <-ctx.Done():
+			q.cancel(t)
+			return nil, context.Cause(ctx)
+		}
+	}
+}
+
+// releaseFunc returns a function releasing a slot once.
+func (q *slotQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.running--
+			q.grant()
+		})
+	}
+}
+
+// cancel removes a ticket whose creation stopped waiting, or releases its
+// slot if it was granted meanwhile.
+func (q *slotQueue) cancel(t *slotTicket) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-t.granted:
+		q.running--
+		q.grant()
+		return
+	default:
+	}
+	tickets := slices.DeleteFunc(q.waiting[t.client], func(other *slotTicket) bool { return other == t })
+	q.setWaiting(t.client, tickets)
+	q.updatePositions()
+}
+
+// grant hands free slots to waiting tickets: the first ticket of the client
+// at the head of the rotation, which then moves to its end. q.mu is held.
+func (q *slotQueue) grant() {
+	granted := false
+	for q.running < q.limit && len(q.clients) > 0 {
+		client := q.clients[0]
+		tickets := q.waiting[client]
+		q.clients = q.clients[1:]
+		q.setWaiting(client, tickets[1:])
+		q.running++
+		close(tickets[0].granted)
+		granted = true
+	}
+	if granted {
+		q.updatePositions()
+	}
+}
+
+// setWaiting records the tickets of a client, adding it at the end of the
+// rotation when it has tickets and is not in it, and dropping it when it has
+// none. q.mu is held.
+func (q *slotQueue) setWaiting(client string, tickets []*slotTicket) {
+	if len(tickets) == 0 {
+		delete(q.waiting, client)
+		q.clients = slices.DeleteFunc(q.clients, func(c string) bool { return c == client })
+		return
+	}
+	q.waiting[client] = tickets
+	if !slices.Contains(q.clients, client) {
+		q.clients = append(q.clients, client)
+	}
+}
+
+// updatePositions computes the position of every waiting ticket in grant
+// order and sends the ones that changed. q.mu is held.
+func (q *slotQueue) updatePositions() {
+	position := 0
+	for round := 0; ; round++ {
+		served := false
+		for _, client := range q.clients {
+			tickets := q.waiting[client]
+			if round >= len(tickets) {
+				continue
+			}
+			served = true
+			position++
+			if t := tickets[round]; t.position != position {
+				t.position = position
+				// Only the latest position matters
+				select {
+				case <-t.positions:
+				default:
+				}
+				t.positions <- position
+			}
+		}
+		if !served {
+			return
+		}
+	}
+}
+
+// queued returns the number of waiting creations.
+func (q *slotQueue) queued() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, tickets := range q.waiting {
+		n += len(tickets)
+	}
+	return n
+}
@@ -0,0 +1,155 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitQueued waits until q has n waiting creations.
+func waitQueued(t *testing.T, q *slotQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for q.queued() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued() = %d, want %d", q.queued(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSlotQueue_Unlimited(t *testing.T) {
+	for _, q := range []*slotQueue{nil, newSlotQueue(0)} {
+		for range 3 {
+			release, err := q.acquire(context.Background(), "ci", func(int) { t.Error("unlimited queue should not report positions") })
+			if err != nil {
+				t.Fatalf("acquire() error = %v", err)
+			}
+			defer release()
+		}
+		if q.queued() != 0 {
+			t.Errorf("queued() = %d, want 0", q.queued())
+		}
+	}
+}
+
+func TestSlotQueue_FairPerClient(t *testing.T) {
+	q := newSlotQueue(1)
+	release, err := q.acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	// Client a queues three creations before b and c queue one each
+	var (
+		mu        sync.Mutex
+		order     []string
+		positions = make(map[string][]int)
+		wg        sync.WaitGroup
+	)
+	enqueue := func(client, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := q.acquire(context.Background(), client, func(position int) {
+				mu.Lock()
+				positions[name] = append(positions[name], position)
+				mu.Unlock()
+			})
+			if err != nil {
+				t.Errorf("acquire(%s) error = %v", name, err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			r()
+		}()
+	}
+	for i, name := range []string{"a1", "a2", "a3"} {
+		enqueue("a", name)
+		waitQueued(t, q, i+1)
+	}
+	enqueue("b", "b1")
+	waitQueued(t, q, 4)
+	enqueue("c", "c1")
+	waitQueued(t, q, 5)
+	// a3 queued third, then b1 and c1 overtook it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		a3 := slices.Clone(positions["a3"])
+		mu.Unlock()
+		if len(a3) > 0 && a3[len(a3)-1] == 5 {
+			if a3[0] != 3 {
+				t.Errorf("positions of a3 = %v, want 3 first", a3)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("positions of a3 = %v, want 5 last", a3)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	release()
+	release() // releasing twice frees a single slot
+	wg.Wait()
+
+	if want := []string{"a1", "b1", "c1", "a2", "a3"}; !slices.Equal(order, want) {
+		t.Errorf("grant order = %v, want %v", order, want)
+	}
+	if got := positions["c1"]; len(got) == 0 || got[0] != 3 {
+		t.Errorf("positions of c1 = %v, want to start at 3", got)
+	}
+}
+
+func TestSlotQueue_Cancel(t *testing.T) {
+	q := newSlotQueue(1)
+	release, err := q.acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	cause := errors.New("deadline")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := q.acquire(ctx, "b", func(int) {})
+		done <- err
+	}()
+	waitQueued(t, q, 1)
+	cancel(cause)
+	if err := <-done; !errors.Is(err, cause) {
+		t.Errorf("acquire() error = %v, want the cause of the context", err)
+	}
+	if q.queued() != 0 {
+		t.Errorf("cancelled creation should leave the queue, queued() = %d", q.queued())
+	}
+
+	release()
+	next, err := q.acquire(context.Background(), "c", func(int) { t.Error("free slot should not queue") })
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	next()
+}
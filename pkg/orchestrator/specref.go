@@ -0,0 +1,121 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/gitsource"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// specRefOverrides are the fields that may be set alongside spec.specRef.
+// They override the values of the fetched spec.
+var specRefOverrides = map[string]bool{
+	"specRef":               true,
+	"environmentId":         true,
+	"environmentIdTemplate": true,
+	"seed":                  true,
+}
+
+// parseSpec decodes the spec of a create input. When it sets specRef, the
+// referenced spec is fetched and returned together with its source.
+func (o *Orchestrator) parseSpec(ctx context.Context, m map[string]any) (*v1.Spec, *v1.SpecSource, error) {
+	if err := spec.CheckUnknownFields(m); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	testenvSpec, err := v1.SpecFromMap(m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if testenvSpec.SpecRef == "" {
+		return testenvSpec, nil, nil
+	}
+
+	var extra []string
+	for key := range m {
+		if !specRefOverrides[key] {
+			extra = append(extra, key)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		return nil, nil, fmt.Errorf("invalid spec: %s cannot be set together with specRef", strings.Join(extra, ", "))
+	}
+
+	fetched, source, err := o.fetchSpec(ctx, testenvSpec.SpecRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	if testenvSpec.EnvironmentId != "" {
+		fetched.EnvironmentId = testenvSpec.EnvironmentId
+	}
+	if testenvSpec.EnvironmentIdTemplate != "" {
+		fetched.EnvironmentIdTemplate = testenvSpec.EnvironmentIdTemplate
+	}
+	if testenvSpec.Seed != "" {
+		fetched.Seed = testenvSpec.Seed
+	}
+	return fetched, source, nil
+}
+
+// fetchSpec fetches and parses the spec file referenced by ref into the git
+// cache of the state directory. The ref must be set: a tag or commit pins the
+// spec, while a branch is resolved to its current commit. Either way the spec
+// is read from the resolved commit, which is recorded in the source.
+func (o *Orchestrator) fetchSpec(ctx context.Context, ref string) (*v1.Spec, *v1.SpecSource, error) {
+	src, err := gitsource.Parse(ref)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid specRef: %w", err)
+	}
+	if src.Path == "" {
+		return nil, nil, fmt.Errorf("invalid specRef %q: missing path of the spec file", ref)
+	}
+	if src.Ref == "" {
+		return nil, nil, fmt.Errorf("invalid specRef %q: missing ?ref= pinning a tag or commit", ref)
+	}
+
+	checkout, err := gitsource.Fetch(ctx, src, paths.New(o.config.StateDir).GitCacheDir())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch specRef: %w", err)
+	}
+	data, err := checkout.ReadFile(ctx, src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read spec of %s at %s: %w", ref, checkout.Commit, err)
+	}
+	fetched, err := spec.Parse(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse spec of %s at %s: %w", ref, checkout.Commit, err)
+	}
+	if fetched.SpecRef != "" {
+		return nil, nil, fmt.Errorf("invalid spec of %s: a fetched spec cannot set specRef", ref)
+	}
+
+	sum := sha256.Sum256(data)
+	log.Printf("Fetched spec %s at commit %s", ref, checkout.Commit)
+	return fetched, &v1.SpecSource{
+		Ref:    ref,
+		Commit: checkout.Commit,
+		SHA256: hex.EncodeToString(sum[:]),
+	}, nil
+}
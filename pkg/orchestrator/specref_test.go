@@ -0,0 +1,123 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newSpecRepo creates a repository holding envs/ci.yaml tagged v1 and returns
+// its path and commit.
+func newSpecRepo(t *testing.T, content string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "envs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "envs", "ci.yaml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "spec")
+	git("tag", "v1")
+	return dir, git("rev-parse", "HEAD")
+}
+
+func TestParseSpecRef(t *testing.T) {
+	repo, commit := newSpecRepo(t, "environmentId: from-repo\nkeys:\n  - name: ci\n    spec:\n      type: ed25519\n")
+	o := &Orchestrator{config: Config{StateDir: t.TempDir()}}
+	ref := "git+file://" + repo + "//envs/ci.yaml?ref=v1"
+
+	got, source, err := o.parseSpec(context.Background(), map[string]any{"specRef": ref, "environmentId": "override"})
+	if err != nil {
+		t.Fatalf("parseSpec() error = %v", err)
+	}
+	if got.EnvironmentId != "override" || len(got.Keys) != 1 || got.Keys[0].Name != "ci" {
+		t.Errorf("parseSpec() spec = %+v, want fetched keys and overridden ID", got)
+	}
+	if source == nil || source.Ref != ref || source.Commit != commit || len(source.SHA256) != 64 {
+		t.Errorf("parseSpec() source = %+v, want commit %s", source, commit)
+	}
+
+	// A commit pin resolves from the cache.
+	pinned := "git+file://" + repo + "//envs/ci.yaml?ref=" + commit
+	if _, source, err := o.parseSpec(context.Background(), map[string]any{"specRef": pinned}); err != nil || source.Commit != commit {
+		t.Errorf("parseSpec(commit) = %+v, %v", source, err)
+	}
+}
+
+func TestParseSpecRefErrors(t *testing.T) {
+	repo, _ := newSpecRepo(t, "specRef: git+https://example.com/other.git//ci.yaml?ref=v1\n")
+	o := &Orchestrator{config: Config{StateDir: t.TempDir()}}
+
+	tests := []struct {
+		name string
+		spec map[string]any
+		want string
+	}{
+		{
+			name: "other fields",
+			spec: map[string]any{"specRef": "git+file://" + repo + "//envs/ci.yaml?ref=v1", "keys": []any{}},
+			want: "keys cannot be set together with specRef",
+		},
+		{
+			name: "unpinned",
+			spec: map[string]any{"specRef": "git+file://" + repo + "//envs/ci.yaml"},
+			want: "missing ?ref=",
+		},
+		{
+			name: "missing path",
+			spec: map[string]any{"specRef": "git+file://" + repo + "?ref=v1"},
+			want: "missing path",
+		},
+		{
+			name: "nested specRef",
+			spec: map[string]any{"specRef": "git+file://" + repo + "//envs/ci.yaml?ref=v1"},
+			want: "cannot set specRef",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := o.parseSpec(context.Background(), tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseSpec() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// staticIPVMs returns the VMs of spec with a static IP on network, their
+// first network.
+func staticIPVMs(spec *v1.Spec, network string) []v1.VMResource {
+	var vms []v1.VMResource
+	for _, vm := range spec.Vms {
+		if networks := vmNetworks(vm.Spec); vm.Spec.Ip != "" && len(networks) > 0 && networks[0] == network {
+			vms = append(vms, vm)
+		}
+	}
+	return vms
+}
+
+// reserveStaticIPs adds to the DHCP server of a network the reservations of
+// the VMs with a static IP on it, for the MAC addresses the seed of the
+// environment gives their first NIC (see seed.Apply). The IPs of all
+// reservations are rewritten for isolation like the network CIDR.
+func reserveStaticIPs(
+	netSpec *providerv1.NetworkSpec,
+	network string,
+	spec *v1.Spec,
+	envState *v1.EnvironmentState,
+	templateCtx *specpkg.TemplateContext,
+	isoConfig *IsolationConfig,
+) error {
+	vms := staticIPVMs(spec, network)
+	if len(vms) > 0 && envState.Seed == "" {
+		return fmt.Errorf("vm %q: static IPs need the MAC addresses derived from an environment seed, which this environment predates", vms[0].Name)
+	}
+	for _, vm := range vms {
+		ip, err := specpkg.RenderString(vm.Spec.Ip, templateCtx)
+		if err != nil {
+			return fmt.Errorf("vm %q: failed to render ip: %w", vm.Name, err)
+		}
+		if netSpec.DHCP == nil {
+			// Unconfigured DHCP defaults to enabled
+			netSpec.DHCP = &providerv1.DHCPSpec{Enabled: true}
+		}
+		netSpec.DHCP.StaticLeases = append(netSpec.DHCP.StaticLeases, providerv1.StaticLease{
+			MAC:      seed.MAC(envState.Seed, vm.Name, 0),
+			IP:       ip,
+			Hostname: vm.Name,
+		})
+	}
+	if netSpec.DHCP != nil && isoConfig != nil && isoConfig.OriginalCIDRPrefix != isoConfig.NewCIDRPrefix {
+		for i, lease := range netSpec.DHCP.StaticLeases {
+			netSpec.DHCP.StaticLeases[i].IP = strings.ReplaceAll(lease.IP, isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix)
+		}
+	}
+	return nil
+}
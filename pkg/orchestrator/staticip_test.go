@@ -0,0 +1,91 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/seed"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestReserveStaticIPs(t *testing.T) {
+	spec := &v1.Spec{Vms: []v1.VMResource{
+		{Name: "web", Spec: v1.VMSpec{Ip: "192.168.100.10", Networks: []string{"lan", "wan"}}},
+		{Name: "db", Spec: v1.VMSpec{Ip: "192.168.100.11", Network: "lan"}},
+		{Name: "edge", Spec: v1.VMSpec{Ip: "10.0.0.10", Networks: []string{"wan", "lan"}}},
+		{Name: "client", Spec: v1.VMSpec{Networks: []string{"lan"}}},
+	}}
+	envState := &v1.EnvironmentState{Seed: "0123456789abcdef"}
+	iso := &IsolationConfig{OriginalCIDRPrefix: "192.168.100.", NewCIDRPrefix: "192.168.42."}
+
+	// Declared reservations are rewritten too; unconfigured DHCP is enabled
+	netSpec := providerv1.NetworkSpec{DHCP: &providerv1.DHCPSpec{Enabled: true, StaticLeases: []providerv1.StaticLease{
+		{MAC: "52:54:00:12:34:56", IP: "192.168.100.20"},
+	}}}
+	if err := reserveStaticIPs(&netSpec, "lan", spec, envState, &specpkg.TemplateContext{}, iso); err != nil {
+		t.Fatalf("reserveStaticIPs() error = %v", err)
+	}
+	want := []providerv1.StaticLease{
+		{MAC: "52:54:00:12:34:56", IP: "192.168.42.20"},
+		{MAC: seed.MAC(envState.Seed, "web", 0), IP: "192.168.42.10", Hostname: "web"},
+		{MAC: seed.MAC(envState.Seed, "db", 0), IP: "192.168.42.11", Hostname: "db"},
+	}
+	if !reflect.DeepEqual(netSpec.DHCP.StaticLeases, want) {
+		t.Errorf("StaticLeases = %+v, want %+v", netSpec.DHCP.StaticLeases, want)
+	}
+
+	netSpec = providerv1.NetworkSpec{}
+	if err := reserveStaticIPs(&netSpec, "wan", spec, envState, &specpkg.TemplateContext{}, nil); err != nil {
+		t.Fatalf("reserveStaticIPs() error = %v", err)
+	}
+	if netSpec.DHCP == nil || !netSpec.DHCP.Enabled || len(netSpec.DHCP.StaticLeases) != 1 || netSpec.DHCP.StaticLeases[0].IP != "10.0.0.10" {
+		t.Errorf("DHCP = %+v, want enabled with the reservation of edge", netSpec.DHCP)
+	}
+
+	netSpec = providerv1.NetworkSpec{}
+	if err := reserveStaticIPs(&netSpec, "other", spec, envState, &specpkg.TemplateContext{}, iso); err != nil || netSpec.DHCP != nil {
+		t.Errorf("reserveStaticIPs() without static IPs = %+v, %v, want unconfigured DHCP", netSpec.DHCP, err)
+	}
+
+	err := reserveStaticIPs(&providerv1.NetworkSpec{}, "lan", spec, &v1.EnvironmentState{}, &specpkg.TemplateContext{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "environment seed") {
+		t.Errorf("reserveStaticIPs() without a seed error = %v, want an environment seed error", err)
+	}
+}
+
+func TestExecutor_convertStaticIPs(t *testing.T) {
+	executor := newTestExecutor(t)
+
+	vm := executor.convertVMSpec(v1.VMSpec{Memory: 1024, Vcpus: 1, Ip: "192.168.100.10", Networks: []string{"lan"}})
+	if vm.IP != "192.168.100.10" {
+		t.Errorf("VMSpec.IP = %q, want 192.168.100.10", vm.IP)
+	}
+
+	network := executor.convertNetworkSpec(v1.NetworkSpec{Cidr: "192.168.100.1/24", Dhcp: &v1.DHCPSpec{
+		Enabled: true,
+		Hosts:   []v1.DHCPHostSpec{{Mac: "52:54:00:12:34:56", Ip: "192.168.100.20", Hostname: "printer"}},
+	}})
+	want := []providerv1.StaticLease{{MAC: "52:54:00:12:34:56", IP: "192.168.100.20", Hostname: "printer"}}
+	if network.DHCP == nil || !reflect.DeepEqual(network.DHCP.StaticLeases, want) {
+		t.Errorf("DHCP = %+v, want the static lease of printer", network.DHCP)
+	}
+}
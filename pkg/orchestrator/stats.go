@@ -0,0 +1,231 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/agent"
+)
+
+// Sources of VM stats.
+const (
+	// StatsSourceAgent is the guest agent of the VM, used when it has one.
+	StatsSourceAgent = "agent"
+	// StatsSourceProvider is the vm_stats tool of the provider of the VM.
+	StatsSourceProvider = "provider"
+)
+
+// agentStatsTimeout bounds a call to a guest agent beyond the sampling
+// interval.
+const agentStatsTimeout = 10 * time.Second
+
+// VMStatsResult is the resource usage of one VM of an environment.
+type VMStatsResult struct {
+	// VM is the VM name as declared in the spec.
+	VM string `json:"vm"`
+	// Provider is the provider of the VM.
+	Provider string `json:"provider"`
+	// Stats is set when the usage of the VM was sampled.
+	Stats *providerv1.VMStats `json:"stats,omitempty"`
+	// Source tells where Stats come from: StatsSourceAgent or
+	// StatsSourceProvider.
+	Source string `json:"source,omitempty"`
+	// Error is set otherwise.
+	Error string `json:"error,omitempty"`
+}
+
+// StatsTotals sums the resource usage of the VMs of an environment.
+type StatsTotals struct {
+	VCPUs int `json:"vcpus"`
+	// CPUPercent is the CPU usage relative to all vCPUs of the environment.
+	CPUPercent          float64 `json:"cpuPercent"`
+	MemoryMB            int     `json:"memoryMB"`
+	MemoryRSSMB         int     `json:"memoryRSSMB"`
+	MemoryUsedMB        int     `json:"memoryUsedMB"`
+	DiskReadBytes       int64   `json:"diskReadBytes"`
+	DiskWriteBytes      int64   `json:"diskWriteBytes"`
+	DiskAllocationBytes uint64  `json:"diskAllocationBytes"`
+	DiskCapacityBytes   uint64  `json:"diskCapacityBytes"`
+	DiskPhysicalBytes   uint64  `json:"diskPhysicalBytes"`
+	RxBytes             int64   `json:"rxBytes"`
+	TxBytes             int64   `json:"txBytes"`
+}
+
+// EnvironmentStats is a snapshot of the resource usage of an environment.
+type EnvironmentStats struct {
+	EnvironmentID string `json:"environmentID"`
+	// Timestamp is when the snapshot was taken (RFC3339).
+	Timestamp string `json:"timestamp"`
+	// VMs holds one result per VM, by VM name.
+	VMs []VMStatsResult `json:"vms"`
+	// Totals sums the VMs with stats.
+	Totals StatsTotals `json:"totals"`
+}
+
+// Stats returns the resource usage of the VMs of a stored environment, or
+// of the named ones, as reported by their guest agent when they have one
+// and by the vm_stats tool of their providers otherwise, or if the agent
+// fails. The VMs are sampled concurrently over the same interval (the
+// provider default if zero), so their CPU usage can be compared. A VM whose
+// provider fails or lacks the tool gets an error in its result.
+func (o *Orchestrator) Stats(environmentID string, vmNames []string, interval time.Duration) (*EnvironmentStats, error) {
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	vmNames = slices.Clone(vmNames)
+	if len(vmNames) == 0 {
+		for name := range envState.Resources.VMs {
+			vmNames = append(vmNames, name)
+		}
+	}
+	slices.Sort(vmNames)
+	for _, name := range vmNames {
+		if envState.Resources.VMs[name] == nil {
+			return nil, fmt.Errorf("vm %q not found in environment %q", name, environmentID)
+		}
+	}
+
+	// Providers are started before sampling, once each
+	providerErrs := make(map[string]error)
+	for _, name := range vmNames {
+		provider := envState.Resources.VMs[name].Provider
+		if _, ok := providerErrs[provider]; !ok {
+			providerErrs[provider] = o.ensureProvider(envState, provider)
+		}
+	}
+
+	stats := &EnvironmentStats{EnvironmentID: environmentID, VMs: make([]VMStatsResult, len(vmNames))}
+	var wg sync.WaitGroup
+	for i, name := range vmNames {
+		vmState := envState.Resources.VMs[name]
+		stats.VMs[i] = VMStatsResult{VM: name, Provider: vmState.Provider}
+		wg.Add(1)
+		go func(r *VMStatsResult, vmName string, ac *agent.Client) {
+			defer wg.Done()
+			// The guest agent is preferred: it sees the usage from inside
+			if ac != nil {
+				err := sampleAgentStats(r, vmName, ac, interval)
+				if err == nil {
+					return
+				}
+				log.Printf("Failed to get stats of vm %q from its agent, falling back to provider: %v", r.VM, err)
+			}
+			if err := providerErrs[r.Provider]; err != nil {
+				r.Error = err.Error()
+				return
+			}
+			o.sampleProviderStats(r, vmName, interval)
+		}(&stats.VMs[i], getString(vmState.State, "name"), agentClient(vmState))
+	}
+	wg.Wait()
+
+	stats.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	stats.Totals = sumStats(stats.VMs)
+	return stats, nil
+}
+
+// sampleProviderStats fills a result with the usage reported by the
+// vm_stats tool of the provider of the VM.
+func (o *Orchestrator) sampleProviderStats(r *VMStatsResult, vmName string, interval time.Duration) {
+	if !o.manager.SupportsOperation(r.Provider, "vm", "stats") {
+		r.Error = fmt.Sprintf("provider %q does not support vm stats", r.Provider)
+		return
+	}
+	result, err := o.manager.Call(r.Provider, providerv1.VMStatsTool, &providerv1.VMStatsRequest{
+		Name:       vmName,
+		IntervalMs: int(interval.Milliseconds()),
+	})
+	if err := operationError(providerv1.VMStatsTool, result, err); err != nil {
+		r.Error = err.Error()
+		return
+	}
+	if r.Stats, err = decodeVMStats(result.Resource); err != nil {
+		r.Error = err.Error()
+		return
+	}
+	r.Source = StatsSourceProvider
+}
+
+// sampleAgentStats fills a result with the usage reported by the guest
+// agent of the VM.
+func sampleAgentStats(r *VMStatsResult, vmName string, ac *agent.Client, interval time.Duration) error {
+	if interval <= 0 {
+		interval = providerv1.DefaultStatsIntervalMs * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), interval+agentStatsTimeout)
+	defer cancel()
+	s, err := ac.Metrics(ctx, interval)
+	if err != nil {
+		return err
+	}
+	s.Name = vmName
+	r.Stats = s
+	r.Source = StatsSourceAgent
+	return nil
+}
+
+// decodeVMStats decodes the resource returned by vm_stats.
+func decodeVMStats(resource any) (*providerv1.VMStats, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var stats providerv1.VMStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, fmt.Errorf("invalid vm stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// sumStats sums the usage of the VMs with stats.
+func sumStats(results []VMStatsResult) StatsTotals {
+	var totals StatsTotals
+	var busyVCPUs float64
+	for _, r := range results {
+		s := r.Stats
+		if s == nil {
+			continue
+		}
+		totals.VCPUs += s.VCPUs
+		busyVCPUs += s.CPUPercent * float64(s.VCPUs)
+		totals.MemoryMB += s.MemoryMB
+		totals.MemoryRSSMB += s.MemoryRSSMB
+		totals.MemoryUsedMB += s.MemoryUsedMB
+		for _, d := range s.Disks {
+			totals.DiskReadBytes += d.ReadBytes
+			totals.DiskWriteBytes += d.WriteBytes
+			totals.DiskAllocationBytes += d.AllocationBytes
+			totals.DiskCapacityBytes += d.CapacityBytes
+			totals.DiskPhysicalBytes += d.PhysicalBytes
+		}
+		for _, i := range s.Interfaces {
+			totals.RxBytes += i.RxBytes
+			totals.TxBytes += i.TxBytes
+		}
+	}
+	if totals.VCPUs > 0 {
+		totals.CPUPercent = busyVCPUs / float64(totals.VCPUs)
+	}
+	return totals
+}
@@ -0,0 +1,93 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestSumStats(t *testing.T) {
+	totals := sumStats([]VMStatsResult{
+		{VM: "a", Stats: &providerv1.VMStats{
+			VCPUs: 1, CPUPercent: 100, MemoryMB: 1024, MemoryRSSMB: 900,
+			Disks:      []providerv1.DiskStats{{ReadBytes: 10, WriteBytes: 20, AllocationBytes: 30, CapacityBytes: 50, PhysicalBytes: 40}},
+			Interfaces: []providerv1.InterfaceStats{{RxBytes: 1, TxBytes: 2}, {RxBytes: 3, TxBytes: 4}},
+		}},
+		{VM: "b", Stats: &providerv1.VMStats{VCPUs: 3, CPUPercent: 20, MemoryMB: 2048, MemoryUsedMB: 512}},
+		{VM: "c", Error: "provider failed"},
+	})
+
+	want := StatsTotals{
+		VCPUs: 4, CPUPercent: 40, MemoryMB: 3072, MemoryRSSMB: 900, MemoryUsedMB: 512,
+		DiskReadBytes: 10, DiskWriteBytes: 20, DiskAllocationBytes: 30, DiskCapacityBytes: 50, DiskPhysicalBytes: 40,
+		RxBytes: 4, TxBytes: 6,
+	}
+	if totals != want {
+		t.Errorf("sumStats() = %+v, want %+v", totals, want)
+	}
+}
+
+func TestDecodeVMStats(t *testing.T) {
+	stats, err := decodeVMStats(map[string]any{
+		"name":       "web",
+		"vcpus":      2,
+		"cpuPercent": 12.5,
+		"disks":      []any{map[string]any{"device": "vda", "readBytes": 4096}},
+	})
+	if err != nil {
+		t.Fatalf("decodeVMStats() error = %v", err)
+	}
+	if stats.Name != "web" || stats.VCPUs != 2 || stats.CPUPercent != 12.5 || stats.Disks[0].ReadBytes != 4096 {
+		t.Errorf("decodeVMStats() = %+v", stats)
+	}
+}
+
+func TestOrchestrator_StatsErrors(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	if _, err := o.Stats("missing", nil, 0); err == nil {
+		t.Error("Stats() expected error for a missing environment")
+	}
+
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID: "env-stats",
+		Resources: v1.ResourceMap{VMs: map[string]*v1.ResourceState{
+			"web": {Provider: "undeclared", State: map[string]any{"name": "web"}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Stats("env-stats", []string{"db"}, 0); err == nil || !strings.Contains(err.Error(), `vm "db" not found`) {
+		t.Errorf("Stats() error = %v, want vm not found", err)
+	}
+
+	stats, err := o.Stats("env-stats", nil, 0)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if len(stats.VMs) != 1 || stats.VMs[0].VM != "web" || !strings.Contains(stats.VMs[0].Error, "undeclared") {
+		t.Errorf("Stats() VMs = %+v, want an error for the undeclared provider", stats.VMs)
+	}
+}
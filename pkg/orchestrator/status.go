@@ -0,0 +1,114 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+// ProviderStatus describes a configured provider and what it can do.
+type ProviderStatus struct {
+	// Name is the provider name.
+	Name string `json:"name"`
+	// Engine is the provider engine.
+	Engine string `json:"engine"`
+	// Status is running, stopped or failed.
+	Status string `json:"status"`
+	// Capabilities are the capabilities reported by the provider.
+	Capabilities *providerv1.CapabilitiesResponse `json:"capabilities,omitempty"`
+	// Error explains why the provider could not be started.
+	Error string `json:"error,omitempty"`
+}
+
+// ProviderStatuses returns the providers of a stored environment, or the
+// default providers of the configuration when environmentID is empty, with
+// their capabilities. Providers that are not running are started. A
+// provider that fails to start is reported with its error.
+func (o *Orchestrator) ProviderStatuses(environmentID string) ([]ProviderStatus, error) {
+	providers, err := o.statusProviders(environmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]ProviderStatus, 0, len(providers))
+	for _, p := range providers {
+		status := ProviderStatus{Name: p.Name, Engine: p.Engine, Status: provider.StatusStopped}
+		if err := o.startProvider(p); err != nil {
+			status.Error = err.Error()
+		} else if status.Capabilities, err = o.manager.Capabilities(p.Name); err != nil {
+			status.Error = err.Error()
+		}
+		if info, ok := o.manager.GetInfo(p.Name); ok {
+			status.Status = info.Status
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// RefreshCapabilities fetches the capabilities of a provider of a stored
+// environment, or of a default provider when environmentID is empty,
+// bypassing the capabilities cache.
+func (o *Orchestrator) RefreshCapabilities(environmentID, name string) (*providerv1.CapabilitiesResponse, error) {
+	providers, err := o.statusProviders(environmentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		if p.Name != name {
+			continue
+		}
+		if err := o.startProvider(p); err != nil {
+			return nil, err
+		}
+		return o.manager.RefreshCapabilities(name)
+	}
+	if environmentID == "" {
+		return nil, fmt.Errorf("provider %q is not a default provider", name)
+	}
+	return nil, fmt.Errorf("provider %q not found in environment %q", name, environmentID)
+}
+
+// statusProviders returns the providers of a stored environment, or the
+// default providers when environmentID is empty.
+func (o *Orchestrator) statusProviders(environmentID string) ([]v1.ProviderConfig, error) {
+	if environmentID == "" {
+		return o.config.DefaultProviders, nil
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.Spec == nil {
+		return nil, nil
+	}
+	return envState.Spec.Providers, nil
+}
+
+// startProvider starts a provider unless it is already running in this
+// process.
+func (o *Orchestrator) startProvider(p v1.ProviderConfig) error {
+	if info, ok := o.manager.GetInfo(p.Name); ok && info.Status == provider.StatusRunning {
+		return nil
+	}
+	if err := o.manager.Start(p); err != nil {
+		return fmt.Errorf("failed to start provider %q: %w", p.Name, err)
+	}
+	return nil
+}
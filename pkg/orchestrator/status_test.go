@@ -0,0 +1,49 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+)
+
+func TestOrchestrator_ProviderStatuses(t *testing.T) {
+	config := newTestConfig(t)
+	config.DefaultProviders = []v1.ProviderConfig{{Name: "broken", Engine: "/nonexistent/binary/path"}}
+	o, err := NewOrchestrator(config)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	statuses, err := o.ProviderStatuses("")
+	if err != nil {
+		t.Fatalf("ProviderStatuses() error = %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "broken" || statuses[0].Status != provider.StatusFailed || statuses[0].Error == "" {
+		t.Errorf("ProviderStatuses() = %+v, want the failed default provider with its error", statuses)
+	}
+
+	if _, err := o.ProviderStatuses("missing"); err == nil {
+		t.Error("ProviderStatuses() of an unknown environment should fail")
+	}
+	if _, err := o.RefreshCapabilities("", "other"); err == nil {
+		t.Error("RefreshCapabilities() of an unknown provider should fail")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"sync"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/notify"
+)
+
+// subscribers is the notifier delivering lifecycle events to the functions
+// registered with Subscribe.
+type subscribers struct {
+	mu   sync.RWMutex
+	next int
+	fns  map[int]func(notify.Event)
+}
+
+// Accepts implements notify.Notifier; subscribers receive every event.
+func (s *subscribers) Accepts(notify.EventType) bool {
+	return true
+}
+
+// Notify implements notify.Notifier by calling every subscriber in turn.
+func (s *subscribers) Notify(_ context.Context, event notify.Event) error {
+	s.mu.RLock()
+	fns := make([]func(notify.Event), 0, len(s.fns))
+	for _, fn := range s.fns {
+		fns = append(fns, fn)
+	}
+	s.mu.RUnlock()
+	for _, fn := range fns {
+		fn(event)
+	}
+	return nil
+}
+
+func (s *subscribers) add(fn func(notify.Event)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fns == nil {
+		s.fns = make(map[int]func(notify.Event))
+	}
+	id := s.next
+	s.next++
+	s.fns[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.fns, id)
+	}
+}
+
+// Subscribe calls fn with the queued, ready, failed and destroyed events of
+// every environment this orchestrator creates or deletes, alongside the
+// webhooks and notifiers of their specs. fn runs on the goroutine
+// delivering the event and must not block. The returned function
+// unsubscribes fn.
+func (o *Orchestrator) Subscribe(fn func(notify.Event)) (unsubscribe func()) {
+	return o.subscribers.add(fn)
+}
+
+// dispatcher returns the dispatcher of the notifiers of spec and of the
+// subscribers.
+func (o *Orchestrator) dispatcher(spec *v1.Spec) (*notify.Dispatcher, error) {
+	d, err := notify.NewDispatcher(spec)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(&o.subscribers)
+	return d, nil
+}
@@ -0,0 +1,51 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/notify"
+)
+
+func TestOrchestrator_Subscribe(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	var first, second []notify.Event
+	unsubscribe := o.Subscribe(func(e notify.Event) { first = append(first, e) })
+	o.Subscribe(func(e notify.Event) { second = append(second, e) })
+
+	d, err := o.dispatcher(nil)
+	if err != nil {
+		t.Fatalf("dispatcher() error = %v", err)
+	}
+	d.Notify(context.Background(), notify.Event{Type: notify.EventReady, EnvironmentID: "env-1"})
+	unsubscribe()
+	d.Notify(context.Background(), notify.Event{Type: notify.EventDestroyed, EnvironmentID: "env-1"})
+
+	if len(first) != 1 || first[0].Type != notify.EventReady {
+		t.Errorf("unsubscribed subscriber got %+v, want only the ready event", first)
+	}
+	if len(second) != 2 || second[1].Type != notify.EventDestroyed {
+		t.Errorf("subscriber got %+v, want the ready and destroyed events", second)
+	}
+}
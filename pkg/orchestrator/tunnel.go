@@ -0,0 +1,149 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/wireguard"
+)
+
+const (
+	// defaultTunnelAddress is the transfer network used when spec.address is unset.
+	defaultTunnelAddress = "10.200.0.0/30"
+	// defaultPersistentKeepalive keeps NAT mappings open on the dialing side.
+	defaultPersistentKeepalive = 25
+)
+
+// buildTunnel generates key pairs and wg-quick configs for both ends of a
+// tunnel. The tunnel spec must already be rendered; network CIDRs are read
+// from the template context (falling back to the spec) so that isolation
+// rewrites are honored.
+func buildTunnel(
+	tunnel *v1.TunnelResource,
+	spec *v1.Spec,
+	templateCtx *specpkg.TemplateContext,
+) (specpkg.TunnelTemplateData, error) {
+	localCIDR, err := tunnelNetworkCIDR(tunnel.Spec.LocalNetwork, spec, templateCtx)
+	if err != nil {
+		return specpkg.TunnelTemplateData{}, err
+	}
+	remoteCIDR, err := tunnelNetworkCIDR(tunnel.Spec.RemoteNetwork, spec, templateCtx)
+	if err != nil {
+		return specpkg.TunnelTemplateData{}, err
+	}
+
+	address := tunnel.Spec.Address
+	if address == "" {
+		address = defaultTunnelAddress
+	}
+	ends, err := wireguard.HostAddresses(address, 2)
+	if err != nil {
+		return specpkg.TunnelTemplateData{}, err
+	}
+	localRoute, _ := wireguard.HostRoute(ends[0])
+	remoteRoute, _ := wireguard.HostRoute(ends[1])
+
+	listenPort := tunnel.Spec.ListenPort
+	if listenPort == 0 {
+		listenPort = wireguard.DefaultListenPort
+	}
+	keepalive := tunnel.Spec.PersistentKeepalive
+	if keepalive == 0 {
+		keepalive = defaultPersistentKeepalive
+	}
+
+	endpoint := tunnel.Spec.Endpoint
+	if endpoint != "" {
+		if _, _, splitErr := net.SplitHostPort(endpoint); splitErr != nil {
+			endpoint = net.JoinHostPort(endpoint, strconv.Itoa(listenPort))
+		}
+	}
+
+	local, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		return specpkg.TunnelTemplateData{}, err
+	}
+	remote, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		return specpkg.TunnelTemplateData{}, err
+	}
+
+	// The local end dials out (it usually sits behind NAT on the hypervisor);
+	// the remote end listens and learns the local endpoint from the handshake.
+	localConfig := wireguard.Config{
+		PrivateKey: local.PrivateKey,
+		Address:    ends[0],
+		Peers: []wireguard.Peer{{
+			PublicKey:           remote.PublicKey,
+			AllowedIPs:          []string{remoteCIDR, remoteRoute},
+			Endpoint:            endpoint,
+			PersistentKeepalive: keepalive,
+		}},
+	}
+	remoteConfig := wireguard.Config{
+		PrivateKey: remote.PrivateKey,
+		Address:    ends[1],
+		ListenPort: listenPort,
+		Peers: []wireguard.Peer{{
+			PublicKey:  local.PublicKey,
+			AllowedIPs: []string{localCIDR, localRoute},
+		}},
+	}
+
+	return specpkg.TunnelTemplateData{
+		LocalPrivateKey:  local.PrivateKey,
+		LocalPublicKey:   local.PublicKey,
+		RemotePrivateKey: remote.PrivateKey,
+		RemotePublicKey:  remote.PublicKey,
+		LocalAddress:     ends[0],
+		RemoteAddress:    ends[1],
+		ListenPort:       listenPort,
+		Endpoint:         endpoint,
+		LocalConfig:      localConfig.Render(),
+		RemoteConfig:     remoteConfig.Render(),
+	}, nil
+}
+
+// tunnelNetworkCIDR returns the masked CIDR of a network resource, preferring
+// the value reported by its provider.
+func tunnelNetworkCIDR(name string, spec *v1.Spec, templateCtx *specpkg.TemplateContext) (string, error) {
+	cidr := ""
+	if data, ok := templateCtx.Networks[name]; ok {
+		cidr = data.CIDR
+	}
+	if cidr == "" {
+		for _, n := range spec.Networks {
+			if n.Name == name {
+				cidr = n.Spec.Cidr
+				break
+			}
+		}
+	}
+	if cidr == "" {
+		return "", fmt.Errorf("network %q has no CIDR to route through the tunnel", name)
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return "", fmt.Errorf("network %q: invalid CIDR %q: %w", name, cidr, err)
+	}
+	return prefix.Masked().String(), nil
+}
@@ -0,0 +1,143 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func TestBuildTunnel(t *testing.T) {
+	spec := &v1.Spec{
+		Networks: []v1.NetworkResource{
+			{Name: "lab", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: "192.168.100.1/24"}},
+			{Name: "vpc", Kind: "vpc", Spec: v1.NetworkSpec{Cidr: "10.0.0.0/16"}},
+		},
+	}
+	tunnel := &v1.TunnelResource{
+		Name: "site",
+		Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc", Endpoint: "203.0.113.10"},
+	}
+
+	t.Run("defaults and endpoint port", func(t *testing.T) {
+		data, err := buildTunnel(tunnel, spec, specpkg.NewTemplateContext())
+		if err != nil {
+			t.Fatalf("buildTunnel() error = %v", err)
+		}
+		if data.LocalAddress != "10.200.0.1/30" || data.RemoteAddress != "10.200.0.2/30" {
+			t.Errorf("addresses = %q, %q", data.LocalAddress, data.RemoteAddress)
+		}
+		if data.ListenPort != 51820 {
+			t.Errorf("ListenPort = %d, want 51820", data.ListenPort)
+		}
+		if data.Endpoint != "203.0.113.10:51820" {
+			t.Errorf("Endpoint = %q", data.Endpoint)
+		}
+		if data.LocalPublicKey == data.RemotePublicKey {
+			t.Error("expected distinct key pairs")
+		}
+		for _, want := range []string{
+			"PrivateKey = " + data.LocalPrivateKey,
+			"PublicKey = " + data.RemotePublicKey,
+			"AllowedIPs = 10.0.0.0/16, 10.200.0.2/32",
+			"Endpoint = 203.0.113.10:51820",
+			"PersistentKeepalive = 25",
+		} {
+			if !strings.Contains(data.LocalConfig, want) {
+				t.Errorf("LocalConfig missing %q:\n%s", want, data.LocalConfig)
+			}
+		}
+		for _, want := range []string{
+			"PrivateKey = " + data.RemotePrivateKey,
+			"ListenPort = 51820",
+			"PublicKey = " + data.LocalPublicKey,
+			"AllowedIPs = 192.168.100.0/24, 10.200.0.1/32",
+		} {
+			if !strings.Contains(data.RemoteConfig, want) {
+				t.Errorf("RemoteConfig missing %q:\n%s", want, data.RemoteConfig)
+			}
+		}
+		if strings.Contains(data.RemoteConfig, "Endpoint") {
+			t.Errorf("RemoteConfig should not dial out:\n%s", data.RemoteConfig)
+		}
+	})
+
+	t.Run("provider CIDR takes precedence", func(t *testing.T) {
+		ctx := specpkg.NewTemplateContext()
+		ctx.Networks["vpc"] = specpkg.NetworkTemplateData{CIDR: "10.1.0.0/16"}
+		data, err := buildTunnel(tunnel, spec, ctx)
+		if err != nil {
+			t.Fatalf("buildTunnel() error = %v", err)
+		}
+		if !strings.Contains(data.LocalConfig, "AllowedIPs = 10.1.0.0/16") {
+			t.Errorf("LocalConfig should route provider CIDR:\n%s", data.LocalConfig)
+		}
+	})
+
+	t.Run("missing CIDR", func(t *testing.T) {
+		noCIDR := &v1.Spec{Networks: []v1.NetworkResource{{Name: "lab"}, {Name: "vpc"}}}
+		if _, err := buildTunnel(tunnel, noCIDR, specpkg.NewTemplateContext()); err == nil {
+			t.Error("expected error for network without CIDR")
+		}
+	})
+}
+
+func TestBuildDAG_Tunnels(t *testing.T) {
+	spec := &v1.Spec{
+		Networks: []v1.NetworkResource{
+			{Name: "lab", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: "192.168.100.1/24"}},
+			{Name: "vpc", Kind: "vpc", Spec: v1.NetworkSpec{Cidr: "10.0.0.0/16"}},
+		},
+		Vms: []v1.VMResource{
+			{Name: "gw", Spec: v1.VMSpec{Network: "vpc"}},
+			{
+				Name: "local-gw",
+				Spec: v1.VMSpec{
+					Network: "lab",
+					CloudInit: v1.CloudInitSpec{
+						WriteFiles: []v1.WriteFileSpec{{Path: "/etc/wireguard/wg0.conf", Content: "{{ .Tunnels.site.LocalConfig }}"}},
+					},
+				},
+			},
+		},
+		Tunnels: []v1.TunnelResource{
+			{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc", Endpoint: "{{ .VMs.gw.IP }}"}},
+		},
+	}
+
+	dag, err := BuildDAG(spec)
+	if err != nil {
+		t.Fatalf("BuildDAG() error = %v", err)
+	}
+
+	tunnelRef := v1.ResourceRef{Kind: "tunnel", Name: "site"}
+	for _, dep := range []v1.ResourceRef{
+		{Kind: "network", Name: "lab"},
+		{Kind: "network", Name: "vpc"},
+		{Kind: "vm", Name: "gw"},
+	} {
+		if !dag.DependsOn(tunnelRef, dep) {
+			t.Errorf("tunnel should depend on %s %q", dep.Kind, dep.Name)
+		}
+	}
+	if !dag.DependsOn(v1.ResourceRef{Kind: "vm", Name: "local-gw"}, tunnelRef) {
+		t.Error("local gateway VM should depend on tunnel")
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/policy"
+	specpkg "github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// UpdateOptions configures Update.
+type UpdateOptions struct {
+	// DryRun computes the changes without applying them.
+	DryRun bool
+}
+
+// UpdateResult is the outcome of Update.
+type UpdateResult struct {
+	// Changes lists the action of every resource of the new spec, then the
+	// resources it no longer declares.
+	Changes []ResourceChange `json:"changes"`
+	// Applied is false for a dry run.
+	Applied bool `json:"applied"`
+	// Artifact is the artifact of the updated environment.
+	Artifact *v1.TestEnvArtifact `json:"artifact,omitempty"`
+}
+
+// updatePlan is the delta between an environment and a new spec.
+type updatePlan struct {
+	// changes lists the action of every resource.
+	changes []ResourceChange
+	// deletions are the resources to delete or replace, in deletion order.
+	deletions [][]v1.ResourceRef
+	// creations are the resources to create or replace, in creation order,
+	// together with the images and tunnels, which only fill the template
+	// context.
+	creations [][]v1.ResourceRef
+	// phases are all the phases of the new spec.
+	phases [][]v1.ResourceRef
+	// refreshed are the VMs whose recorded hashes only need an update.
+	refreshed []v1.ResourceRef
+}
+
+// Update applies a new spec to an existing environment without recreating
+// it. The spec is compared with the recorded state: VMs by the content
+// hashes of their rendered create requests, keys, networks, services and
+// certificates by their declaration. Only the resources that are new,
+// changed or gone are created, replaced or deleted, and a replacement also
+// replaces the resources depending on it. VMs whose domain configuration
+// changed are replaced, as providers cannot redefine a running domain.
+// The new spec goes through the quotas and admission policies of Create.
+// Environments with access points cannot be updated: their generated keys
+// are not recorded. The parent of an environment cannot change.
+func (o *Orchestrator) Update(ctx context.Context, environmentID string, input *v1.CreateInput, opts UpdateOptions) (*UpdateResult, error) {
+	if o.config.ReadOnly && !opts.DryRun {
+		return nil, fmt.Errorf("update rejected: %w", ErrReadOnly)
+	}
+	ctx, end, err := o.ops.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("update rejected: %w", err)
+	}
+	defer end()
+
+	// A dry run only reads the state
+	if !opts.DryRun {
+		unlock, err := o.store.Lock(ctx, environmentID)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	if envState.Status != v1.StatusReady && envState.Status != v1.StatusFailed {
+		return nil, fmt.Errorf("environment %q is %s; only ready or failed environments can be updated", environmentID, envState.Status)
+	}
+	if envState.Spec == nil {
+		return nil, fmt.Errorf("environment %q has no recorded spec", environmentID)
+	}
+
+	newSpec, specSource, err := o.parseSpec(ctx, input.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(newSpec.Providers) == 0 {
+		newSpec.Providers = append([]v1.ProviderConfig(nil), o.config.DefaultProviders...)
+	}
+	if id, requested, err := ResolveEnvironmentID(input, newSpec); err != nil {
+		return nil, fmt.Errorf("failed to resolve environment ID: %w", err)
+	} else if requested && id != environmentID {
+		return nil, fmt.Errorf("spec names environment %q, not %q", id, environmentID)
+	}
+	templatedFields, err := specpkg.ValidateEarly(newSpec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSpec, err)
+	}
+	if err := checkUpdatable(envState.Spec, newSpec); err != nil {
+		return nil, fmt.Errorf("cannot update environment %q: %w", environmentID, err)
+	}
+
+	// The quotas and admission policies of Create apply to the new spec,
+	// also on a dry run so that the plan reports a rejection. The
+	// environment already counts against MaxEnvironments.
+	quotas := o.config.Quotas
+	quotas.MaxEnvironments = 0
+	if err := checkQuotas(o.store, environmentID, newSpec, quotas); err != nil {
+		return nil, err
+	}
+	if err := policy.Check(ctx, o.config.Admitter, &policy.Request{
+		EnvironmentID: environmentID,
+		TestID:        envState.TestID,
+		Stage:         envState.Stage,
+		Spec:          newSpec,
+	}); err != nil {
+		return nil, err
+	}
+
+	isoConfig, err := o.existingIsolation(envState, newSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := o.executor.planUpdate(newSpec, envState, input.Env, templatedFields, isoConfig)
+	if err != nil {
+		return nil, err
+	}
+	result := &UpdateResult{Changes: plan.changes}
+	if opts.DryRun {
+		return result, nil
+	}
+
+	for _, providerCfg := range newSpec.Providers {
+		if err := o.manager.Start(providerCfg); err != nil {
+			return nil, fmt.Errorf("failed to start provider %q: %w", providerCfg.Name, err)
+		}
+	}
+	if err := verifyProviderCapabilities(o.manager, newSpec); err != nil {
+		return nil, fmt.Errorf("capability check failed: %w", err)
+	}
+
+	log.Printf("Updating environment %q", environmentID)
+	envState.Status = v1.StatusUpdating
+	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := o.store.Save(envState); err != nil {
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	// From here on, a failure leaves the environment failed with the
+	// resources applied so far; updating again resumes from there.
+	fail := func(err error) (*UpdateResult, error) {
+		envState.Status = v1.StatusFailed
+		envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+		envState.Errors = append(envState.Errors, v1.ErrorRecord{
+			Operation: "update",
+			Error:     err.Error(),
+			Timestamp: envState.UpdatedAt,
+		})
+		if saveErr := o.store.Save(envState); saveErr != nil {
+			log.Printf("Failed to save failed state: %v", saveErr)
+		}
+		return nil, fmt.Errorf("update failed: %w", err)
+	}
+
+	if err := o.executor.executeDeletions(ctx, plan.deletions, envState, isoConfig); err != nil {
+		return fail(err)
+	}
+	for _, c := range plan.changes {
+		if c.Action == ActionDelete {
+			removeResourceState(envState, c.Resource)
+		}
+	}
+	cidrs, err := o.allocateCIDRs(environmentID, newSpec.Networks)
+	if err != nil {
+		return fail(err)
+	}
+	envState.CIDRs = cidrs
+
+	// The spec and plan are recorded before creating, so that deleting a
+	// partially updated environment covers the new resources.
+	envState.Spec = newSpec
+	envState.SpecSource = specSource
+	envState.ExecutionPlan = buildExecutionPlan(plan.phases)
+	templateCtx := o.executor.templateContextFromState(newSpec, envState, input.Env)
+	execResult, err := o.executor.ExecuteCreate(ctx, newSpec, plan.creations, templateCtx, envState, templatedFields, isoConfig)
+	if err != nil {
+		return fail(err)
+	}
+	if !execResult.Success {
+		return fail(errors.Join(execResult.Errors...))
+	}
+	for _, ref := range plan.refreshed {
+		hashes, err := o.executor.desiredVMHashes(ref, newSpec, templateCtx, templatedFields, isoConfig)
+		if err != nil {
+			return fail(fmt.Errorf("failed to hash vm %q: %w", ref.Name, err))
+		}
+		envState.Resources.VMs[ref.Name].Hashes = hashes
+	}
+
+	if newSpec.HostsFile {
+		if err := o.pushHosts(ctx, envState); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := o.markReady(envState, templateCtx); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Environment %q updated", environmentID)
+	result.Applied = true
+	result.Artifact = o.buildArtifact(envState.TestID, envState, isoConfig)
+	return result, nil
+}
+
+// existingIsolation returns the isolation of an existing environment
+// created again from spec: the networks keep their recorded subnets, and
+// without networks of its own the environment keeps its parent's.
+func (o *Orchestrator) existingIsolation(envState *v1.EnvironmentState, spec *v1.Spec) (*IsolationConfig, error) {
+	isoConfig := newIsolationConfig(o.config.Tenant, envState.ID, spec.NamePrefix, spec.Networks)
+	_, _, parentIso, err := o.parentResources(envState.ID, spec)
+	if err != nil {
+		return nil, err
+	}
+	inheritNetworks(isoConfig, envState.Resources.Networks)
+	if parentIso != nil && len(spec.Networks) == 0 {
+		isoConfig.OriginalCIDRPrefix, isoConfig.NewCIDRPrefix = parentIso.OriginalCIDRPrefix, parentIso.NewCIDRPrefix
+	}
+	return isoConfig, nil
+}
+
+// markReady saves an environment as ready and writes its topology diagram,
+// known_hosts file and manifest again.
+func (o *Orchestrator) markReady(envState *v1.EnvironmentState, templateCtx *specpkg.TemplateContext) error {
+	envState.Status = v1.StatusReady
+	envState.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	if err := o.store.Save(envState); err != nil {
+		return fmt.Errorf("failed to save ready state: %w", err)
+	}
+	if err := writeTopology(envState); err != nil {
+		log.Printf("Failed to write topology diagram: %v", err)
+	}
+	if err := writeKnownHosts(envState); err != nil {
+		log.Printf("Failed to write known_hosts: %v", err)
+	}
+	if err := o.writeManifest(envState, templateCtx); err != nil {
+		log.Printf("Failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// checkUpdatable rejects spec changes Update cannot apply.
+func checkUpdatable(oldSpec, newSpec *v1.Spec) error {
+	if !reflect.DeepEqual(oldSpec.Parent, newSpec.Parent) {
+		return errors.New("the parent environment cannot change")
+	}
+	if oldSpec.NamePrefix != newSpec.NamePrefix {
+		return errors.New("the name prefix cannot change")
+	}
+	if len(oldSpec.Access) > 0 || len(newSpec.Access) > 0 {
+		return errors.New("environments with access points cannot be updated")
+	}
+	return nil
+}
+
+// planUpdate computes the delta between an environment and a new spec.
+func (e *Executor) planUpdate(
+	newSpec *v1.Spec,
+	envState *v1.EnvironmentState,
+	env map[string]string,
+	templatedFields *specpkg.TemplatedFields,
+	isoConfig *IsolationConfig,
+) (*updatePlan, error) {
+	dag, err := BuildDAG(newSpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DAG: %w", err)
+	}
+	phases, err := dag.TopologicalSort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute execution phases: %w", err)
+	}
+
+	templateCtx := e.templateContextFromState(newSpec, envState, env)
+	vmChanges := make(map[string]ResourceChange)
+	for _, c := range e.planVMChanges(newSpec, envState, env, templatedFields) {
+		vmChanges[c.Resource.Name] = c
+	}
+
+	// Action of every resource of the new spec, in creation order
+	changes := make(map[string]*ResourceChange)
+	var order []string
+	for _, phase := range phases {
+		for _, ref := range phase {
+			c := &ResourceChange{Resource: ref, Action: ActionNone}
+			switch ref.Kind {
+			case "vm":
+				*c = vmChanges[ref.Name]
+				if c.Action == ActionReboot {
+					c.Action = ActionReplace
+					c.Reasons = append(c.Reasons, "providers cannot redefine a running domain")
+				}
+			case "key", "network", "service":
+				existing := e.getResourceState(envState, ref)
+				switch {
+				case existing == nil || existing.Status != v1.StatusReady:
+					c.Action = ActionCreate
+				case !sameDeclaration(e.specResource(envState.Spec, ref), e.specResource(newSpec, ref)):
+					c.Action, c.Reasons = ActionReplace, []string{"declaration changed"}
+				}
+			case "certificate":
+				if _, issued := templateCtx.Certificates[ref.Name]; !issued {
+					c.Action = ActionCreate
+				} else if !sameDeclaration(e.specResource(envState.Spec, ref), e.specResource(newSpec, ref)) {
+					c.Action, c.Reasons = ActionReplace, []string{"declaration changed"}
+				}
+			}
+			key := nodeKey(ref)
+			changes[key] = c
+			order = append(order, key)
+		}
+	}
+
+	// Replacing a resource replaces what depends on it
+	var replaced []v1.ResourceRef
+	for _, key := range order {
+		if changes[key].Action == ActionReplace {
+			replaced = append(replaced, changes[key].Resource)
+		}
+	}
+	for _, key := range order {
+		c := changes[key]
+		if c.Action != ActionNone && c.Action != ActionUpdate {
+			continue
+		}
+		for _, r := range replaced {
+			if dag.DependsOn(c.Resource, r) && c.Resource.Kind != "image" && c.Resource.Kind != "tunnel" {
+				c.Action = ActionReplace
+				c.Reasons = append(c.Reasons, fmt.Sprintf("depends on replaced %s/%s", r.Kind, r.Name))
+				break
+			}
+		}
+	}
+
+	plan := &updatePlan{phases: phases}
+	for _, key := range order {
+		plan.changes = append(plan.changes, *changes[key])
+	}
+	plan.changes = append(plan.changes, removedResources(newSpec, envState)...)
+
+	var toDelete []v1.ResourceRef
+	for _, c := range plan.changes {
+		if c.Action == ActionDelete || (c.Action == ActionReplace && e.getResourceState(envState, c.Resource) != nil) {
+			toDelete = append(toDelete, c.Resource)
+		}
+	}
+	plan.deletions = deletionPhases(envState, toDelete)
+
+	// Images and tunnels are not recorded, so they are always run again to
+	// fill the template context; both are idempotent.
+	for _, phase := range phases {
+		var creations []v1.ResourceRef
+		for _, ref := range phase {
+			c := changes[nodeKey(ref)]
+			switch {
+			case c.Action == ActionCreate || c.Action == ActionReplace || ref.Kind == "image" || ref.Kind == "tunnel":
+				creations = append(creations, ref)
+			case c.Action == ActionUpdate:
+				plan.refreshed = append(plan.refreshed, ref)
+			}
+		}
+		plan.creations = appendPhase(plan.creations, creations)
+	}
+	return plan, nil
+}
+
+// deletionPhases orders the deletion of refs: in the recorded plan of the
+// environment in reverse, after the resources it does not list, such as VMs
+// created at runtime.
+func deletionPhases(envState *v1.EnvironmentState, refs []v1.ResourceRef) [][]v1.ResourceRef {
+	toDelete := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		toDelete[nodeKey(ref)] = true
+	}
+	var recorded [][]v1.ResourceRef
+	if envState.ExecutionPlan != nil {
+		for _, phase := range envState.ExecutionPlan.Phases {
+			recorded = append(recorded, phase.Resources)
+		}
+	}
+	listed := make(map[string]bool)
+	for _, phase := range recorded {
+		for _, ref := range phase {
+			listed[nodeKey(ref)] = true
+		}
+	}
+	var unlisted []v1.ResourceRef
+	for _, ref := range refs {
+		if !listed[nodeKey(ref)] {
+			unlisted = append(unlisted, ref)
+		}
+	}
+	phases := appendPhase(nil, unlisted)
+	for i := len(recorded) - 1; i >= 0; i-- {
+		var phase []v1.ResourceRef
+		for _, ref := range recorded[i] {
+			if toDelete[nodeKey(ref)] {
+				phase = append(phase, ref)
+			}
+		}
+		phases = appendPhase(phases, phase)
+	}
+	return phases
+}
+
+// removedResources returns the deletion of the recorded keys, networks, VMs
+// and services the spec no longer declares. Resources of a parent
+// environment are never deleted.
+func removedResources(newSpec *v1.Spec, envState *v1.EnvironmentState) []ResourceChange {
+	declared := make(map[string]bool)
+	for _, k := range newSpec.Keys {
+		declared["key:"+k.Name] = true
+	}
+	for _, n := range newSpec.Networks {
+		declared["network:"+n.Name] = true
+	}
+	for _, vm := range newSpec.Vms {
+		declared["vm:"+vm.Name] = true
+	}
+	for _, svc := range newSpec.Services {
+		declared["service:"+svc.Name] = true
+	}
+
+	var removed []ResourceChange
+	for _, kind := range []struct {
+		name      string
+		resources map[string]*v1.ResourceState
+	}{
+		{"vm", envState.Resources.VMs},
+		{"service", envState.Resources.Services},
+		{"network", envState.Resources.Networks},
+		{"key", envState.Resources.Keys},
+	} {
+		var names []string
+		for name, rs := range kind.resources {
+			if rs.Owner == "" && rs.Status != v1.StatusDestroyed && !declared[kind.name+":"+name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			removed = append(removed, ResourceChange{
+				Resource: v1.ResourceRef{Kind: kind.name, Name: name, Provider: kind.resources[name].Provider},
+				Action:   ActionDelete,
+			})
+		}
+	}
+	return removed
+}
+
+// executeDeletions deletes the resources of phases, one phase after the
+// other and the resources of a phase in parallel. It stops after the first
+// phase with errors, as later phases may depend on its resources.
+func (e *Executor) executeDeletions(ctx context.Context, phases [][]v1.ResourceRef, envState *v1.EnvironmentState, isoConfig *IsolationConfig) error {
+	for _, phase := range phases {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var errs []error
+		for _, ref := range phase {
+			wg.Add(1)
+			go func(r v1.ResourceRef) {
+				defer wg.Done()
+				if err := e.deleteResource(ctx, r, envState, isoConfig); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("failed to delete %s/%s: %w", r.Kind, r.Name, err))
+					mu.Unlock()
+				}
+			}(ref)
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
+
+// specResource returns the declaration of a resource in spec, or nil.
+func (e *Executor) specResource(spec *v1.Spec, ref v1.ResourceRef) any {
+	var resource any
+	var err error
+	switch ref.Kind {
+	case "key":
+		resource, err = e.findKeySpec(spec, ref.Name)
+	case "network":
+		var network *v1.NetworkResource
+		if network, err = e.findNetworkSpec(spec, ref.Name); err == nil {
+			// The static IPs of VMs are reserved in the network
+			var staticIPs []string
+			for _, vm := range staticIPVMs(spec, ref.Name) {
+				staticIPs = append(staticIPs, vm.Name+"="+vm.Spec.Ip)
+			}
+			resource = struct {
+				Network   *v1.NetworkResource
+				StaticIPs []string `json:",omitempty"`
+			}{network, staticIPs}
+		}
+	case "service":
+		resource, err = e.findServiceSpec(spec, ref.Name)
+	case "certificate":
+		resource, err = findCertificateSpec(spec, ref.Name)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	return resource
+}
+
+// sameDeclaration reports whether two declarations have the same JSON
+// encoding.
+func sameDeclaration(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	ha, errA := contentHash(a)
+	hb, errB := contentHash(b)
+	return errA == nil && errB == nil && ha == hb
+}
+
+// removeResourceState forgets a deleted resource.
+func removeResourceState(envState *v1.EnvironmentState, ref v1.ResourceRef) {
+	switch ref.Kind {
+	case "key":
+		delete(envState.Resources.Keys, ref.Name)
+	case "network":
+		delete(envState.Resources.Networks, ref.Name)
+	case "vm":
+		delete(envState.Resources.VMs, ref.Name)
+	case "service":
+		delete(envState.Resources.Services, ref.Name)
+	}
+}
+
+// appendPhase appends phase to phases unless it is empty.
+func appendPhase(phases [][]v1.ResourceRef, phase []v1.ResourceRef) [][]v1.ResourceRef {
+	if len(phase) == 0 {
+		return phases
+	}
+	return append(phases, phase)
+}
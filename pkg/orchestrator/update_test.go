@@ -0,0 +1,373 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/policy"
+)
+
+// updateSpec returns a spec with a key, a network and VMs attached to it.
+func updateSpec(cidr string, vms map[string]int) *v1.Spec {
+	s := &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "local", Engine: "go://local", Default: true}},
+		Keys:      []v1.KeyResource{{Name: "ssh", Spec: v1.KeySpec{Type: "ed25519"}}},
+		Networks:  []v1.NetworkResource{{Name: "net", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: cidr}}},
+	}
+	for _, name := range []string{"web", "db", "old", "new"} {
+		memory, ok := vms[name]
+		if !ok {
+			continue
+		}
+		s.Vms = append(s.Vms, v1.VMResource{
+			Name: name,
+			Spec: v1.VMSpec{
+				Memory:  memory,
+				Vcpus:   1,
+				Network: "net",
+				Disk:    v1.DiskSpec{Size: "10G"},
+				CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "test", SshAuthorizedKeys: []string{"{{ .Keys.ssh.PublicKey }}"}}},
+				},
+			},
+		})
+	}
+	return s
+}
+
+// updatedEnvironment records a ready environment for spec as Create would.
+func updatedEnvironment(t *testing.T, executor *Executor, spec *v1.Spec) *v1.EnvironmentState {
+	t.Helper()
+	dag, err := BuildDAG(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases, err := dag.TopologicalSort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	envState := &v1.EnvironmentState{
+		ID:            "env-1",
+		Status:        v1.StatusReady,
+		Spec:          spec,
+		ExecutionPlan: buildExecutionPlan(phases),
+		Resources: v1.ResourceMap{
+			Keys:     map[string]*v1.ResourceState{"ssh": {Provider: "local", Status: v1.StatusReady, State: map[string]any{"publicKey": "ssh-ed25519 AAAA"}}},
+			Networks: map[string]*v1.ResourceState{"net": {Provider: "local", Status: v1.StatusReady, State: map[string]any{"name": "net"}}},
+			VMs:      map[string]*v1.ResourceState{},
+		},
+	}
+	templateCtx := executor.templateContextFromState(spec, envState, nil)
+	isoConfig := newIsolationConfig("", envState.ID, "", spec.Networks)
+	for _, vm := range spec.Vms {
+		hashes, err := executor.desiredVMHashes(v1.ResourceRef{Kind: "vm", Name: vm.Name}, spec, templateCtx, nil, isoConfig)
+		if err != nil {
+			t.Fatalf("desiredVMHashes(%s) error = %v", vm.Name, err)
+		}
+		envState.Resources.VMs[vm.Name] = &v1.ResourceState{Provider: "local", Status: v1.StatusReady, Hashes: hashes}
+	}
+	return envState
+}
+
+func actionsOf(plan *updatePlan) map[string]string {
+	got := make(map[string]string, len(plan.changes))
+	for _, c := range plan.changes {
+		got[c.Resource.Kind+"/"+c.Resource.Name] = c.Action
+	}
+	return got
+}
+
+func refsOf(phases [][]v1.ResourceRef) []string {
+	var refs []string
+	for _, phase := range phases {
+		for _, ref := range phase {
+			refs = append(refs, ref.Kind+"/"+ref.Name)
+		}
+	}
+	return refs
+}
+
+func TestPlanUpdate(t *testing.T) {
+	executor := newTestExecutor(t)
+	original := updateSpec("10.0.0.0/24", map[string]int{"web": 1024, "db": 1024, "old": 1024})
+	envState := updatedEnvironment(t, executor, original)
+
+	t.Run("vms", func(t *testing.T) {
+		desired := updateSpec("10.0.0.0/24", map[string]int{"web": 2048, "db": 1024, "new": 1024})
+		plan, err := executor.planUpdate(desired, envState, nil, nil, newIsolationConfig("", envState.ID, "", desired.Networks))
+		if err != nil {
+			t.Fatalf("planUpdate() error = %v", err)
+		}
+		want := map[string]string{
+			"key/ssh": ActionNone, "network/net": ActionNone,
+			"vm/web": ActionReplace, "vm/db": ActionNone, "vm/new": ActionCreate, "vm/old": ActionDelete,
+		}
+		got := actionsOf(plan)
+		for ref, action := range want {
+			if got[ref] != action {
+				t.Errorf("%s: action = %q, want %q", ref, got[ref], action)
+			}
+		}
+		if deletions := strings.Join(refsOf(plan.deletions), ","); deletions != "vm/old,vm/web" && deletions != "vm/web,vm/old" {
+			t.Errorf("deletions = %s, want vm/old and vm/web", deletions)
+		}
+		if creations := strings.Join(refsOf(plan.creations), ","); creations != "vm/web,vm/new" && creations != "vm/new,vm/web" {
+			t.Errorf("creations = %s, want vm/web and vm/new", creations)
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		plan, err := executor.planUpdate(original, envState, nil, nil, newIsolationConfig("", envState.ID, "", original.Networks))
+		if err != nil {
+			t.Fatalf("planUpdate() error = %v", err)
+		}
+		for ref, action := range actionsOf(plan) {
+			if action != ActionNone {
+				t.Errorf("%s: unchanged spec planned %q", ref, action)
+			}
+		}
+		if len(plan.deletions) != 0 || len(plan.creations) != 0 {
+			t.Errorf("unchanged spec deletes %v and creates %v", refsOf(plan.deletions), refsOf(plan.creations))
+		}
+	})
+
+	t.Run("network", func(t *testing.T) {
+		desired := updateSpec("10.0.1.0/24", map[string]int{"web": 1024, "db": 1024, "old": 1024})
+		plan, err := executor.planUpdate(desired, envState, nil, nil, newIsolationConfig("", envState.ID, "", desired.Networks))
+		if err != nil {
+			t.Fatalf("planUpdate() error = %v", err)
+		}
+		got := actionsOf(plan)
+		for _, ref := range []string{"network/net", "vm/web", "vm/db", "vm/old"} {
+			if got[ref] != ActionReplace {
+				t.Errorf("%s: action = %q, want %q", ref, got[ref], ActionReplace)
+			}
+		}
+		if got["key/ssh"] != ActionNone {
+			t.Errorf("key/ssh: action = %q, want %q", got["key/ssh"], ActionNone)
+		}
+		// VMs are deleted before the network, and created after it
+		deletions := refsOf(plan.deletions)
+		if deletions[len(deletions)-1] != "network/net" {
+			t.Errorf("deletions = %v, want network/net last", deletions)
+		}
+		if creations := refsOf(plan.creations); creations[0] != "network/net" {
+			t.Errorf("creations = %v, want network/net first", creations)
+		}
+	})
+}
+
+func TestCheckUpdatable(t *testing.T) {
+	base := &v1.Spec{Parent: v1.ParentSpec{EnvironmentId: "lab"}}
+	if err := checkUpdatable(base, &v1.Spec{Parent: v1.ParentSpec{EnvironmentId: "lab"}}); err != nil {
+		t.Errorf("checkUpdatable() error = %v", err)
+	}
+	if err := checkUpdatable(base, &v1.Spec{Parent: v1.ParentSpec{EnvironmentId: "other"}}); err == nil {
+		t.Error("checkUpdatable() should reject a new parent")
+	}
+	withAccess := &v1.Spec{Parent: base.Parent, Access: []v1.AccessResource{{Name: "vpn"}}}
+	if err := checkUpdatable(base, withAccess); err == nil {
+		t.Error("checkUpdatable() should reject access points")
+	}
+	if err := checkUpdatable(base, &v1.Spec{Parent: base.Parent, NamePrefix: "ci-1"}); err == nil {
+		t.Error("checkUpdatable() should reject a new name prefix")
+	}
+}
+
+func TestNewIsolationConfig_NamePrefix(t *testing.T) {
+	isoConfig := newIsolationConfig("", "env-1", "ci-1234", nil)
+	if want := "ci-1234-" + shortHash("env-1"); isoConfig.NamePrefix != want {
+		t.Errorf("NamePrefix = %q, want %q", isoConfig.NamePrefix, want)
+	}
+	if got, want := prefixedName(isoConfig, "web"), "ci-1234-"+shortHash("env-1")+"-web"; got != want {
+		t.Errorf("prefixedName() = %q, want %q", got, want)
+	}
+}
+
+func TestNewIsolationConfig_Tenant(t *testing.T) {
+	isoConfig := newIsolationConfig("storage", "env-1", "ci-1234", nil)
+	if want := "storage-ci-1234-" + shortHash("env-1"); isoConfig.NamePrefix != want {
+		t.Errorf("NamePrefix = %q, want %q", isoConfig.NamePrefix, want)
+	}
+	if want := fmt.Sprintf("192.168.%d.", hashToOctet("storage/env-1")); isoConfig.NewCIDRPrefix != want {
+		t.Errorf("NewCIDRPrefix = %q, want %q", isoConfig.NewCIDRPrefix, want)
+	}
+	if other := newIsolationConfig("network", "env-1", "ci-1234", nil); other.NamePrefix == isoConfig.NamePrefix {
+		t.Errorf("tenants share the name prefix %q", other.NamePrefix)
+	}
+}
+
+func TestOrchestrator_UpdateDryRun(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	original := updateSpec("10.0.0.0/24", map[string]int{"web": 1024, "db": 1024})
+	if err := o.store.Save(updatedEnvironment(t, o.executor, original)); err != nil {
+		t.Fatal(err)
+	}
+	desired := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+
+	result, err := o.Update(context.Background(), "env-1", &v1.CreateInput{Spec: desired.ToMap()}, UpdateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if result.Applied {
+		t.Error("dry run was applied")
+	}
+	got := make(map[string]string)
+	for _, c := range result.Changes {
+		got[c.Resource.Kind+"/"+c.Resource.Name] = c.Action
+	}
+	if got["vm/web"] != ActionNone || got["vm/db"] != ActionDelete {
+		t.Errorf("Update() changes = %v", got)
+	}
+	stored, err := o.store.Load("env-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Spec.Vms) != 2 || stored.Resources.VMs["db"] == nil {
+		t.Error("dry run modified the state")
+	}
+}
+
+func TestOrchestrator_UpdateRejected(t *testing.T) {
+	cfg := newTestConfig(t)
+	o, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	spec := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+	envState := updatedEnvironment(t, o.executor, spec)
+	if err := o.store.Save(envState); err != nil {
+		t.Fatal(err)
+	}
+	creating := *envState
+	creating.ID, creating.Status = "env-2", v1.StatusCreating
+	if err := o.store.Save(&creating); err != nil {
+		t.Fatal(err)
+	}
+	renamed := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+	renamed.EnvironmentId = "other"
+
+	tests := []struct {
+		name string
+		id   string
+		spec *v1.Spec
+		want string
+	}{
+		{name: "missing", id: "missing", spec: spec, want: "failed to load"},
+		{name: "creating", id: "env-2", spec: spec, want: "only ready or failed"},
+		{name: "renamed", id: "env-1", spec: renamed, want: `names environment "other"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := o.Update(context.Background(), tt.id, &v1.CreateInput{Spec: tt.spec.ToMap()}, UpdateOptions{DryRun: true})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Update() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	cfg.ReadOnly = true
+	ro, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer ro.Close()
+	if _, err := ro.Update(context.Background(), "env-1", &v1.CreateInput{Spec: spec.ToMap()}, UpdateOptions{}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Update() error = %v, want %v", err, ErrReadOnly)
+	}
+	if _, err := ro.Update(context.Background(), "env-1", &v1.CreateInput{Spec: spec.ToMap()}, UpdateOptions{DryRun: true}); err != nil {
+		t.Errorf("Update() dry run in read-only mode error = %v", err)
+	}
+}
+
+func TestOrchestrator_UpdateAdmission(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Quotas = Quotas{MaxEnvironments: 1, MaxMemoryMB: 2048}
+	o, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	spec := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+	if err := o.store.Save(updatedEnvironment(t, o.executor, spec)); err != nil {
+		t.Fatal(err)
+	}
+	// The environment itself does not exceed MaxEnvironments
+	if _, err := o.Update(context.Background(), "env-1", &v1.CreateInput{Spec: spec.ToMap()}, UpdateOptions{DryRun: true}); err != nil {
+		t.Errorf("Update() dry run within quotas error = %v", err)
+	}
+	desired := updateSpec("10.0.0.0/24", map[string]int{"web": 1024, "db": 2048})
+	if _, err := o.Update(context.Background(), "env-1", &v1.CreateInput{Spec: desired.ToMap()}, UpdateOptions{DryRun: true}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Update() dry run over quota error = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	cfg.Quotas = Quotas{}
+	cfg.Admitter = denyAllAdmitter{}
+	denying, err := NewOrchestrator(cfg)
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer denying.Close()
+	var denied *policy.DeniedError
+	if _, err := denying.Update(context.Background(), "env-1", &v1.CreateInput{Spec: spec.ToMap()}, UpdateOptions{DryRun: true}); !errors.As(err, &denied) {
+		t.Errorf("Update() dry run error = %v, want *policy.DeniedError", err)
+	}
+}
+
+func TestOrchestrator_UpdateLocked(t *testing.T) {
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	spec := updateSpec("10.0.0.0/24", map[string]int{"web": 1024})
+	if err := o.store.Save(updatedEnvironment(t, o.executor, spec)); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := o.store.Lock(context.Background(), "env-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	desired := updateSpec("10.0.0.0/24", map[string]int{"web": 2048})
+	if _, err := o.Update(ctx, "env-1", &v1.CreateInput{Spec: desired.ToMap()}, UpdateOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Update() of a locked environment error = %v, want context.DeadlineExceeded", err)
+	}
+	// A dry run does not wait for the lock
+	if _, err := o.Update(context.Background(), "env-1", &v1.CreateInput{Spec: desired.ToMap()}, UpdateOptions{DryRun: true}); err != nil {
+		t.Errorf("Update() dry run of a locked environment error = %v", err)
+	}
+}
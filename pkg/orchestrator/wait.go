@@ -0,0 +1,344 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/client"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/provider"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Conditions accepted by WaitVM. "port:<n>" and "file:<path>" take an
+// argument.
+const (
+	// WaitRunning waits until the provider reports the VM as running.
+	WaitRunning = "running"
+	// WaitSSH waits until a command can be run over SSH.
+	WaitSSH = "ssh"
+	// WaitCloudInitDone waits until cloud-init has finished.
+	WaitCloudInitDone = "cloud-init-done"
+	// WaitPortPrefix waits until a TCP port of the VM accepts connections.
+	WaitPortPrefix = "port:"
+	// WaitFilePrefix waits until a path exists in the guest.
+	WaitFilePrefix = "file:"
+)
+
+// DefaultWaitTimeout bounds WaitVM when no timeout is given.
+const DefaultWaitTimeout = 5 * time.Minute
+
+// cloudInitDoneFile is written by cloud-init once every boot stage ran.
+const cloudInitDoneFile = "/var/lib/cloud/instance/boot-finished"
+
+// ErrWaitTimeout is returned when a wait condition does not hold in time.
+var ErrWaitTimeout = errors.New("timed out waiting for condition")
+
+// waitPollInterval is the delay between two evaluations of a condition.
+var waitPollInterval = 2 * time.Second
+
+// waitCondition is a parsed wait condition.
+type waitCondition struct {
+	kind string
+	port int
+	path string
+}
+
+// parseWaitCondition parses running, ssh, cloud-init-done, port:<n> and
+// file:<absolute path>.
+func parseWaitCondition(s string) (waitCondition, error) {
+	switch {
+	case s == WaitRunning || s == WaitSSH || s == WaitCloudInitDone:
+		return waitCondition{kind: s}, nil
+	case strings.HasPrefix(s, WaitPortPrefix):
+		port, err := strconv.Atoi(strings.TrimPrefix(s, WaitPortPrefix))
+		if err != nil || port < 1 || port > 65535 {
+			return waitCondition{}, fmt.Errorf("invalid condition %q: port must be between 1 and 65535", s)
+		}
+		return waitCondition{kind: WaitPortPrefix, port: port}, nil
+	case strings.HasPrefix(s, WaitFilePrefix):
+		path := strings.TrimPrefix(s, WaitFilePrefix)
+		if !strings.HasPrefix(path, "/") {
+			return waitCondition{}, fmt.Errorf("invalid condition %q: path must be absolute", s)
+		}
+		return waitCondition{kind: WaitFilePrefix, path: path}, nil
+	default:
+		return waitCondition{}, fmt.Errorf("unknown condition %q (supported: %s, %s, %s, %s<port>, %s<path>)",
+			s, WaitRunning, WaitSSH, WaitCloudInitDone, WaitPortPrefix, WaitFilePrefix)
+	}
+}
+
+// WaitVM blocks until condition holds for a VM of a stored environment, the
+// timeout expires, or ctx is done. A zero timeout means DefaultWaitTimeout.
+// Ports are dialed directly, without the VM's jump host. It only reads state
+// and is therefore available in read-only mode.
+func (o *Orchestrator) WaitVM(ctx context.Context, environmentID, vmName, condition string, timeout time.Duration) error {
+	cond, err := parseWaitCondition(condition)
+	if err != nil {
+		return err
+	}
+	envState, err := o.store.Load(environmentID)
+	if err != nil {
+		return fmt.Errorf("failed to load environment %q: %w", environmentID, err)
+	}
+	vmState := envState.Resources.VMs[vmName]
+	if vmState == nil {
+		return fmt.Errorf("vm %q not found in environment %q", vmName, environmentID)
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultWaitTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var check func(context.Context) error
+	switch cond.kind {
+	case WaitRunning:
+		if err := o.ensureProvider(envState, vmState.Provider); err != nil {
+			return err
+		}
+		check = func(context.Context) error { return o.checkVMRunning(vmState) }
+	case WaitPortPrefix:
+		check = func(ctx context.Context) error { return checkVMPort(ctx, vmState, cond.port) }
+	default:
+		// The ssh condition tests SSH itself, the others may use the agent
+		c, err := o.vmClient(envState, vmName, cond.kind != WaitSSH)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = c.Close() }()
+		check = func(ctx context.Context) error {
+			switch cond.kind {
+			case WaitSSH:
+				_, _, err := c.Run(ctx, "true")
+				return err
+			case WaitCloudInitDone:
+				return checkFileExists(ctx, c, cloudInitDoneFile)
+			default:
+				return checkFileExists(ctx, c, cond.path)
+			}
+		}
+	}
+
+	return pollCondition(ctx, check)
+}
+
+// pollCondition evaluates check until it succeeds or ctx is done. The last
+// error is reported on timeout.
+func pollCondition(ctx context.Context, check func(context.Context) error) error {
+	for {
+		lastErr := check(ctx)
+		if lastErr == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %v", ErrWaitTimeout, lastErr)
+			}
+			return ctx.Err()
+		case <-time.After(waitPollInterval):
+		}
+	}
+}
+
+// ensureProvider starts the provider of a stored environment unless it is
+// already running in this process.
+func (o *Orchestrator) ensureProvider(envState *v1.EnvironmentState, name string) error {
+	if info, ok := o.manager.GetInfo(name); ok && info.Status == provider.StatusRunning {
+		return nil
+	}
+	if envState.Spec != nil {
+		for _, p := range envState.Spec.Providers {
+			if p.Name == name {
+				if err := o.manager.Start(p); err != nil {
+					return fmt.Errorf("failed to start provider %q: %w", name, err)
+				}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("provider %q not found in environment %q", name, envState.ID)
+}
+
+// checkVMRunning asks the provider for the current status of a VM.
+func (o *Orchestrator) checkVMRunning(vmState *v1.ResourceState) error {
+	status, err := o.providerVMStatus(vmState)
+	if err != nil {
+		return err
+	}
+	if status != "running" {
+		return fmt.Errorf("vm status is %q", status)
+	}
+	return nil
+}
+
+// providerVMStatus returns the status of a VM as reported by vm_get of its
+// provider.
+func (o *Orchestrator) providerVMStatus(vmState *v1.ResourceState) (string, error) {
+	result, err := o.manager.Call(vmState.Provider, "vm_get", &providerv1.GetRequest{Name: getString(vmState.State, "name")})
+	if err != nil {
+		return "", err
+	}
+	if !result.Success {
+		if result.Error != nil {
+			return "", errors.New(result.Error.Message)
+		}
+		return "", errors.New("vm_get failed")
+	}
+	resource, err := o.executor.convertResourceToMap(result.Resource)
+	if err != nil {
+		return "", err
+	}
+	return getString(resource, "status"), nil
+}
+
+// checkVMPort dials a TCP port on the recorded IP of a VM.
+func checkVMPort(ctx context.Context, vmState *v1.ResourceState, port int) error {
+	ip := getString(vmState.State, "ip")
+	if ip == "" {
+		return errors.New("vm has no recorded IP")
+	}
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkFileExists fails unless path exists in the guest.
+func checkFileExists(ctx context.Context, c *client.Client, path string) error {
+	exists, err := c.FileExists(ctx, path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	return nil
+}
+
+// vmClient returns a client of a VM of a stored environment. With
+// preferAgent, commands go through the guest agent of the VM when it has
+// one; they use SSH otherwise.
+func (o *Orchestrator) vmClient(envState *v1.EnvironmentState, vmName string, preferAgent bool) (*client.Client, error) {
+	var opts []client.ClientOption
+	if ac := agentClient(envState.Resources.VMs[vmName]); preferAgent && ac != nil {
+		opts = append(opts, client.WithSSHRunner(client.NewAgentRunner(ac)))
+	}
+	return client.NewClient(&stateClientProvider{executor: o.executor, envState: envState}, vmName, opts...)
+}
+
+// sshPortKey is the VM state key of the SSH port of VMs not reached on 22.
+const sshPortKey = "sshPort"
+
+// vmSSHPort returns the SSH port recorded in the state of a VM, "22" by
+// default.
+func vmSSHPort(state map[string]any) string {
+	if port := getUint32(state, sshPortKey); port != 0 {
+		return strconv.FormatUint(uint64(port), 10)
+	}
+	return "22"
+}
+
+// stateClientProvider resolves SSH connection information of VMs from a
+// stored environment state. The user and private key come from the rendered
+// SSH readiness check, then the first cloud-init user and the first key.
+// Recorded host keys are verified. VMs with a guest agent need no key.
+type stateClientProvider struct {
+	executor *Executor
+	envState *v1.EnvironmentState
+}
+
+// GetVMInfo implements client.ClientProvider.
+func (p *stateClientProvider) GetVMInfo(vmName string) (*client.VMInfo, error) {
+	vmState := p.envState.Resources.VMs[vmName]
+	if vmState == nil {
+		return nil, fmt.Errorf("vm %q not found in state", vmName)
+	}
+	ip := getString(vmState.State, "ip")
+	if ip == "" {
+		return nil, fmt.Errorf("vm %q has no recorded IP", vmName)
+	}
+
+	var user, keyPath string
+	if p.envState.Spec != nil {
+		if vmSpec, err := p.executor.findVMSpec(p.envState.Spec, vmName); err == nil {
+			templateCtx := p.executor.templateContextFromState(p.envState.Spec, p.envState, nil)
+			if rendered, err := p.executor.renderVMSpec(vmSpec, templateCtx); err == nil {
+				vmSpec = rendered
+			}
+			user = vmSpec.Spec.Readiness.Ssh.User
+			if user == "" && len(vmSpec.Spec.CloudInit.Users) > 0 {
+				user = vmSpec.Spec.CloudInit.Users[0].Name
+			}
+			if !spec.IsTemplated(vmSpec.Spec.Readiness.Ssh.PrivateKey) {
+				keyPath = vmSpec.Spec.Readiness.Ssh.PrivateKey
+			}
+		}
+	}
+	if user == "" {
+		user = "root"
+	}
+	if keyPath == "" {
+		names := make([]string, 0, len(p.envState.Resources.Keys))
+		for name := range p.envState.Resources.Keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if path := getString(p.envState.Resources.Keys[name].State, "privateKeyPath"); path != "" {
+				keyPath = path
+				break
+			}
+		}
+	}
+	if keyPath == "" {
+		if getString(vmState.State, agentTokenKey) != "" {
+			// Commands go through the guest agent, which needs no key
+			return &client.VMInfo{Host: ip, Port: vmSSHPort(vmState.State), User: user}, nil
+		}
+		return nil, fmt.Errorf("no SSH key found for vm %q", vmName)
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+
+	proxyJump, err := client.ParseProxyJump(getString(vmState.State, "proxyJump"), user, key)
+	if err != nil {
+		return nil, fmt.Errorf("vm %q: %w", vmName, err)
+	}
+	return &client.VMInfo{
+		Host:       ip,
+		Port:       vmSSHPort(vmState.State),
+		User:       user,
+		PrivateKey: key,
+		ProxyJump:  proxyJump,
+		HostKeys:   stateStrings(vmState.State, "hostKeys"),
+	}, nil
+}
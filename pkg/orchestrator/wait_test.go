@@ -0,0 +1,150 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    waitCondition
+		wantErr bool
+	}{
+		{in: "running", want: waitCondition{kind: WaitRunning}},
+		{in: "ssh", want: waitCondition{kind: WaitSSH}},
+		{in: "cloud-init-done", want: waitCondition{kind: WaitCloudInitDone}},
+		{in: "port:6443", want: waitCondition{kind: WaitPortPrefix, port: 6443}},
+		{in: "file:/var/lib/ready", want: waitCondition{kind: WaitFilePrefix, path: "/var/lib/ready"}},
+		{in: "port:0", wantErr: true},
+		{in: "port:http", wantErr: true},
+		{in: "file:relative", wantErr: true},
+		{in: "healthy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseWaitCondition(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWaitCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseWaitCondition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_WaitVMPort(t *testing.T) {
+	orig := waitPollInterval
+	waitPollInterval = 10 * time.Millisecond
+	defer func() { waitPollInterval = orig }()
+
+	o, err := NewOrchestrator(newTestConfig(t))
+	if err != nil {
+		t.Fatalf("NewOrchestrator() error = %v", err)
+	}
+	defer o.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+
+	if err := o.store.Save(&v1.EnvironmentState{
+		ID: "env-wait",
+		Resources: v1.ResourceMap{
+			VMs: map[string]*v1.ResourceState{"vm": {State: map[string]any{"ip": "127.0.0.1"}}},
+		},
+	}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	condition := "port:" + strconv.Itoa(port)
+
+	// Nothing listens yet
+	err = o.WaitVM(context.Background(), "env-wait", "vm", condition, 50*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expected ErrWaitTimeout, got %v", err)
+	}
+
+	// The port opens while waiting
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { _ = l.Close() })
+	}()
+	if err := o.WaitVM(context.Background(), "env-wait", "vm", condition, 5*time.Second); err != nil {
+		t.Errorf("WaitVM() error = %v", err)
+	}
+
+	if err := o.WaitVM(context.Background(), "env-wait", "missing", condition, time.Second); err == nil {
+		t.Error("expected error for unknown VM")
+	}
+	if err := o.WaitVM(context.Background(), "env-wait", "vm", "bogus", time.Second); err == nil {
+		t.Error("expected error for unknown condition")
+	}
+}
+
+func TestStateClientProvider(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id")
+	if err := os.WriteFile(keyPath, []byte("key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envState := &v1.EnvironmentState{
+		Spec: &v1.Spec{Vms: []v1.VMResource{{
+			Name: "vm",
+			Spec: v1.VMSpec{
+				CloudInit: v1.CloudInitSpec{Users: []v1.UserSpec{{Name: "ubuntu"}}},
+				Readiness: v1.ReadinessSpec{Ssh: v1.SSHReadinessSpec{PrivateKey: "{{ .Keys.ssh.PrivateKeyPath }}"}},
+			},
+		}}},
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"ssh": {State: map[string]any{"privateKeyPath": keyPath}}},
+			VMs: map[string]*v1.ResourceState{"vm": {State: map[string]any{
+				"ip":       "10.0.0.2",
+				"hostKeys": []any{"ssh-ed25519 AAAA"},
+			}}},
+		},
+	}
+	p := &stateClientProvider{executor: newTestExecutor(t), envState: envState}
+
+	info, err := p.GetVMInfo("vm")
+	if err != nil {
+		t.Fatalf("GetVMInfo() error = %v", err)
+	}
+	if info.Host != "10.0.0.2" || info.User != "ubuntu" || string(info.PrivateKey) != "key" || len(info.HostKeys) != 1 {
+		t.Errorf("unexpected VMInfo: %+v", info)
+	}
+	if _, err := p.GetVMInfo("missing"); err == nil {
+		t.Error("expected error for unknown VM")
+	}
+}
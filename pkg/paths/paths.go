@@ -0,0 +1,280 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package paths defines the on-disk layout below a testenv-vm state
+// directory. The orchestrator, the providers and the CLIs resolve every file
+// they write through it:
+//
+//	<root>/state/testenv-<id>.json   environment state files
+//	<root>/state/testenv-<id>.lock   locks of operations on an environment
+//	<root>/logs/<provider>.log       provider stderr
+//	<root>/schedules/<name>.json     scheduled environment definitions
+//	<root>/admission/<id>.json       creations queued for host capacity
+//	<root>/operations/<id>.json      records of asynchronous operations
+//	<root>/ipam/<subnet>.json        subnets allocated from the CIDR pool
+//	<root>/cache/git/<hash>/         clones of git sources
+//	<root>/cache/netboot/<hash>      downloaded network boot artifacts
+//	<root>/envs/<id>/artifacts/      artifacts, unless overridden
+//	<root>/envs/<id>/keys/           SSH key pairs
+//	<root>/envs/<id>/disks/          VM disk images
+//	<root>/envs/<id>/cloudinit/      cloud-init ISOs
+//	<root>/envs/<id>/logs/           logs of the environment's resources
+//	<root>/envs/<id>/services/<name>/ config and cache of host-run services
+//	<root>/envs/<id>/netboot/<network>/ boot root served over TFTP
+//	<root>/tenants/<tenant>/          state directory of a tenant
+//
+// State files stay in a single directory so that environments can be listed
+// without walking envs/. Removing envs/<id> removes every file of an
+// environment.
+//
+// The state directory of a tenant is laid out like <root>, except that its
+// CIDR pool and admission queue are those of <root>, shared by every tenant
+// of the host.
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Directory and file names of the layout.
+const (
+	stateSubdir      = "state"
+	historySubdir    = "history"
+	logsSubdir       = "logs"
+	schedulesSubdir  = "schedules"
+	admissionSubdir  = "admission"
+	operationsSubdir = "operations"
+	ipamSubdir       = "ipam"
+	gitCacheSubdir   = "cache/git"
+	bootCacheSubdir  = "cache/netboot"
+	envsSubdir       = "envs"
+	artifactsSubdir  = "artifacts"
+	keysSubdir       = "keys"
+	disksSubdir      = "disks"
+	cloudInitSubdir  = "cloudinit"
+	servicesSubdir   = "services"
+	netbootSubdir    = "netboot"
+	tenantsSubdir    = "tenants"
+
+	stateFilePrefix = "testenv-"
+	stateFileSuffix = ".json"
+	lockFileSuffix  = ".lock"
+)
+
+// Layout is the layout below a state directory.
+type Layout struct {
+	// Root is the state directory.
+	Root string
+}
+
+// New returns the layout below root.
+func New(root string) Layout {
+	return Layout{Root: root}
+}
+
+// StateDir returns the directory holding the state files of all environments.
+func (l Layout) StateDir() string {
+	return filepath.Join(l.Root, stateSubdir)
+}
+
+// StateFile returns the state file of an environment.
+func (l Layout) StateFile(envID string) string {
+	return filepath.Join(l.StateDir(), stateFilePrefix+envID+stateFileSuffix)
+}
+
+// LockFile returns the file locked by operations on an environment.
+func (l Layout) LockFile(envID string) string {
+	return filepath.Join(l.StateDir(), stateFilePrefix+envID+lockFileSuffix)
+}
+
+// HistoryDir returns the directory holding the revisions of the state of
+// an environment, one file per save.
+func (l Layout) HistoryDir(envID string) string {
+	return filepath.Join(l.StateDir(), historySubdir, envID)
+}
+
+// EnvIDFromStateFile returns the environment ID of a state file name, e.g.
+// "abc" for "testenv-abc.json". It reports false for other file names.
+func EnvIDFromStateFile(name string) (string, bool) {
+	if !strings.HasPrefix(name, stateFilePrefix) || !strings.HasSuffix(name, stateFileSuffix) {
+		return "", false
+	}
+	envID := strings.TrimSuffix(strings.TrimPrefix(name, stateFilePrefix), stateFileSuffix)
+	return envID, envID != ""
+}
+
+// EnvIDFromLockFile returns the environment ID of a lock file name, e.g.
+// "abc" for "testenv-abc.lock". It reports false for other file names.
+func EnvIDFromLockFile(name string) (string, bool) {
+	if !strings.HasPrefix(name, stateFilePrefix) || !strings.HasSuffix(name, lockFileSuffix) {
+		return "", false
+	}
+	envID := strings.TrimSuffix(strings.TrimPrefix(name, stateFilePrefix), lockFileSuffix)
+	return envID, envID != ""
+}
+
+// LogsDir returns the directory holding provider logs.
+func (l Layout) LogsDir() string {
+	return filepath.Join(l.Root, logsSubdir)
+}
+
+// SchedulesDir returns the directory holding schedule definitions.
+func (l Layout) SchedulesDir() string {
+	return filepath.Join(l.Root, schedulesSubdir)
+}
+
+// AdmissionDir returns the directory holding the creations queued for host
+// capacity.
+func (l Layout) AdmissionDir() string {
+	return filepath.Join(l.Root, admissionSubdir)
+}
+
+// OperationsDir returns the directory holding the records of asynchronous
+// operations.
+func (l Layout) OperationsDir() string {
+	return filepath.Join(l.Root, operationsSubdir)
+}
+
+// IPAMDir returns the directory holding the subnets allocated to networks
+// from the CIDR pool.
+func (l Layout) IPAMDir() string {
+	return filepath.Join(l.Root, ipamSubdir)
+}
+
+// GitCacheDir returns the directory holding clones of git sources.
+func (l Layout) GitCacheDir() string {
+	return filepath.Join(l.Root, filepath.FromSlash(gitCacheSubdir))
+}
+
+// BootCacheDir returns the directory holding downloaded network boot
+// artifacts, shared by the environments.
+func (l Layout) BootCacheDir() string {
+	return filepath.Join(l.Root, filepath.FromSlash(bootCacheSubdir))
+}
+
+// Tenant returns the layout below the state directory of a tenant.
+func (l Layout) Tenant(name string) Layout {
+	return New(filepath.Join(l.Root, tenantsSubdir, name))
+}
+
+// EnvsDir returns the directory holding one directory per environment.
+func (l Layout) EnvsDir() string {
+	return filepath.Join(l.Root, envsSubdir)
+}
+
+// Env returns the layout of an environment.
+func (l Layout) Env(envID string) Env {
+	return Env{Dir: filepath.Join(l.EnvsDir(), envID)}
+}
+
+// Environments returns the sorted IDs of the environments that have a
+// directory, whether or not they still have a state file.
+func (l Layout) Environments() ([]string, error) {
+	entries, err := os.ReadDir(l.EnvsDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environments directory: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Env is the layout of one environment.
+type Env struct {
+	// Dir is the directory of the environment.
+	Dir string
+}
+
+// ArtifactsDir returns the default artifact directory.
+func (e Env) ArtifactsDir() string {
+	return filepath.Join(e.Dir, artifactsSubdir)
+}
+
+// KeysDir returns the directory holding SSH key pairs.
+func (e Env) KeysDir() string {
+	return filepath.Join(e.Dir, keysSubdir)
+}
+
+// DisksDir returns the directory holding VM disk images.
+func (e Env) DisksDir() string {
+	return filepath.Join(e.Dir, disksSubdir)
+}
+
+// CloudInitDir returns the directory holding cloud-init ISOs.
+func (e Env) CloudInitDir() string {
+	return filepath.Join(e.Dir, cloudInitSubdir)
+}
+
+// LogsDir returns the directory holding logs of the environment's resources.
+func (e Env) LogsDir() string {
+	return filepath.Join(e.Dir, logsSubdir)
+}
+
+// ServiceDir returns the directory holding the configuration and cache of a
+// host-run service.
+func (e Env) ServiceDir(name string) string {
+	return filepath.Join(e.Dir, servicesSubdir, name)
+}
+
+// BootRoot returns the directory served over TFTP to the network boot
+// clients of a network.
+func (e Env) BootRoot(network string) string {
+	return filepath.Join(e.Dir, netbootSubdir, network)
+}
+
+// Dirs returns every subdirectory of the environment.
+func (e Env) Dirs() []string {
+	return []string{e.ArtifactsDir(), e.KeysDir(), e.DisksDir(), e.CloudInitDir(), e.LogsDir()}
+}
+
+// Disk returns the disk image of a VM.
+func (e Env) Disk(vmName string) string {
+	return filepath.Join(e.DisksDir(), vmName+".qcow2")
+}
+
+// CloudInitISO returns the cloud-init ISO of a VM.
+func (e Env) CloudInitISO(vmName string) string {
+	return filepath.Join(e.CloudInitDir(), vmName+".iso")
+}
+
+// Create creates the directory of the environment and its subdirectories.
+func (e Env) Create() error {
+	for _, dir := range append([]string{e.Dir}, e.Dirs()...) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// Remove removes the directory of the environment and everything in it.
+func (e Env) Remove() error {
+	if err := os.RemoveAll(e.Dir); err != nil {
+		return fmt.Errorf("failed to remove environment directory %s: %w", e.Dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	l := New("/var/lib/testenv-vm")
+	env := l.Env("abc")
+
+	tests := map[string]struct{ got, want string }{
+		"state file": {l.StateFile("abc"), "/var/lib/testenv-vm/state/testenv-abc.json"},
+		"lock file":  {l.LockFile("abc"), "/var/lib/testenv-vm/state/testenv-abc.lock"},
+		"history":    {l.HistoryDir("abc"), "/var/lib/testenv-vm/state/history/abc"},
+		"logs":       {l.LogsDir(), "/var/lib/testenv-vm/logs"},
+		"schedules":  {l.SchedulesDir(), "/var/lib/testenv-vm/schedules"},
+		"admission":  {l.AdmissionDir(), "/var/lib/testenv-vm/admission"},
+		"operations": {l.OperationsDir(), "/var/lib/testenv-vm/operations"},
+		"ipam":       {l.IPAMDir(), "/var/lib/testenv-vm/ipam"},
+		"git cache":  {l.GitCacheDir(), "/var/lib/testenv-vm/cache/git"},
+		"boot cache": {l.BootCacheDir(), "/var/lib/testenv-vm/cache/netboot"},
+		"env":        {env.Dir, "/var/lib/testenv-vm/envs/abc"},
+		"artifacts":  {env.ArtifactsDir(), "/var/lib/testenv-vm/envs/abc/artifacts"},
+		"keys":       {env.KeysDir(), "/var/lib/testenv-vm/envs/abc/keys"},
+		"disk":       {env.Disk("web"), "/var/lib/testenv-vm/envs/abc/disks/web.qcow2"},
+		"iso":        {env.CloudInitISO("web"), "/var/lib/testenv-vm/envs/abc/cloudinit/web.iso"},
+		"env logs":   {env.LogsDir(), "/var/lib/testenv-vm/envs/abc/logs"},
+		"service":    {env.ServiceDir("mirror"), "/var/lib/testenv-vm/envs/abc/services/mirror"},
+		"boot root":  {env.BootRoot("pxe"), "/var/lib/testenv-vm/envs/abc/netboot/pxe"},
+		"tenant":     {l.Tenant("storage").StateFile("abc"), "/var/lib/testenv-vm/tenants/storage/state/testenv-abc.json"},
+	}
+	for name, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestEnvCreateRemove(t *testing.T) {
+	l := New(t.TempDir())
+
+	ids, err := l.Environments()
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("Environments() = %v, %v; want none", ids, err)
+	}
+
+	for _, id := range []string{"b", "a"} {
+		if err := l.Env(id).Create(); err != nil {
+			t.Fatalf("Create(%s) error = %v", id, err)
+		}
+	}
+	for _, dir := range l.Env("a").Dirs() {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("%s was not created: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(l.Env("a").DisksDir(), "vm.qcow2"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, _ = l.Environments()
+	if !reflect.DeepEqual(ids, []string{"a", "b"}) {
+		t.Errorf("Environments() = %v, want [a b]", ids)
+	}
+
+	if err := l.Env("a").Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(l.Env("a").Dir); !os.IsNotExist(err) {
+		t.Errorf("environment directory still exists: %v", err)
+	}
+	ids, _ = l.Environments()
+	if !reflect.DeepEqual(ids, []string{"b"}) {
+		t.Errorf("Environments() = %v, want [b]", ids)
+	}
+}
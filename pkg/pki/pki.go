@@ -0,0 +1,224 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pki issues the test certificate authorities and TLS certificates
+// of environments. Keys are ECDSA P-256 and every file is PEM encoded, so
+// certificates are cheap to generate on each environment creation.
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultCACommonName is the common name of CAs created without one.
+	DefaultCACommonName = "testenv-vm test CA"
+	// DefaultCAValidity is the validity of CAs created without one.
+	DefaultCAValidity = 365 * 24 * time.Hour
+	// DefaultValidity is the validity of certificates issued without one.
+	DefaultValidity = 30 * 24 * time.Hour
+)
+
+// backdate is subtracted from NotBefore so that guests whose clock lags
+// behind the host accept new certificates.
+const backdate = time.Hour
+
+// CA is a certificate authority with its PEM-encoded certificate and key.
+type CA struct {
+	// CertPEM is the PEM-encoded CA certificate.
+	CertPEM []byte
+	// KeyPEM is the PEM-encoded PKCS #8 CA private key.
+	KeyPEM []byte
+
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// Certificate is an issued certificate with its PEM-encoded key.
+type Certificate struct {
+	// CertPEM is the PEM-encoded certificate.
+	CertPEM []byte
+	// KeyPEM is the PEM-encoded PKCS #8 private key.
+	KeyPEM []byte
+}
+
+// Request describes a certificate to issue.
+type Request struct {
+	// CommonName is the subject common name. It defaults to the first DNS
+	// name.
+	CommonName string
+	// DNSNames and IPAddresses are the subject alternative names.
+	DNSNames    []string
+	IPAddresses []net.IP
+	// Validity defaults to DefaultValidity.
+	Validity time.Duration
+	// Client issues a client certificate instead of a server certificate.
+	Client bool
+}
+
+// NewCA creates a self-signed CA. An empty commonName and a zero validity
+// use DefaultCACommonName and DefaultCAValidity.
+func NewCA(commonName string, validity time.Duration) (*CA, error) {
+	if commonName == "" {
+		commonName = DefaultCACommonName
+	}
+	if validity <= 0 {
+		validity = DefaultCAValidity
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-backdate),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{CertPEM: encodeCert(der), KeyPEM: keyPEM, cert: cert, key: key}, nil
+}
+
+// ParseCA loads a CA from its PEM-encoded certificate and ECDSA key.
+func ParseCA(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid CA certificate: no CERTIFICATE block")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate: %w", err)
+	}
+	if !cert.IsCA {
+		return nil, errors.New("invalid CA certificate: not a CA")
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("invalid CA key: no PEM block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("invalid CA key: does not match the CA certificate")
+	}
+	return &CA{CertPEM: certPEM, KeyPEM: keyPEM, cert: cert, key: key}, nil
+}
+
+// Issue creates a certificate signed by the CA. It needs a common name or
+// a DNS name, and its validity is capped by the one of the CA.
+func (ca *CA) Issue(req Request) (*Certificate, error) {
+	commonName := req.CommonName
+	if commonName == "" && len(req.DNSNames) > 0 {
+		commonName = req.DNSNames[0]
+	}
+	if commonName == "" {
+		return nil, errors.New("a common name or a DNS name is required")
+	}
+	validity := req.Validity
+	if validity <= 0 {
+		validity = DefaultValidity
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	usage := x509.ExtKeyUsageServerAuth
+	if req.Client {
+		usage = x509.ExtKeyUsageClientAuth
+	}
+	now := time.Now()
+	notAfter := now.Add(validity)
+	if notAfter.After(ca.cert.NotAfter) {
+		notAfter = ca.cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     req.DNSNames,
+		IPAddresses:  req.IPAddresses,
+		NotBefore:    now.Add(-backdate),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{CertPEM: encodeCert(der), KeyPEM: keyPEM}, nil
+}
+
+// newSerial returns a random 128-bit serial number.
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// encodeCert PEM-encodes a DER certificate.
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// encodeKey PEM-encodes a private key in PKCS #8.
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
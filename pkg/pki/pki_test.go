@@ -0,0 +1,141 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseCert(t *testing.T, certPEM []byte) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatal("no PEM block")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestIssue(t *testing.T) {
+	ca, err := NewCA("", 0)
+	if err != nil {
+		t.Fatalf("NewCA() error = %v", err)
+	}
+	caCert := parseCert(t, ca.CertPEM)
+	if caCert.Subject.CommonName != DefaultCACommonName || !caCert.IsCA {
+		t.Errorf("CA subject = %q, IsCA = %t", caCert.Subject.CommonName, caCert.IsCA)
+	}
+
+	cert, err := ca.Issue(Request{
+		DNSNames:    []string{"web.test"},
+		IPAddresses: []net.IP{net.ParseIP("192.168.100.10")},
+	})
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	leaf := parseCert(t, cert.CertPEM)
+	if leaf.Subject.CommonName != "web.test" {
+		t.Errorf("CommonName = %q, want web.test", leaf.Subject.CommonName)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	for _, host := range []string{"web.test", "192.168.100.10"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("Verify(%s) error = %v", host, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "db.test", Roots: roots}); err == nil {
+		t.Error("Verify(db.test) succeeded")
+	}
+	if !strings.Contains(string(cert.KeyPEM), "PRIVATE KEY") {
+		t.Errorf("KeyPEM = %q", cert.KeyPEM)
+	}
+
+	client, err := ca.Issue(Request{CommonName: "alice", Client: true})
+	if err != nil {
+		t.Fatalf("Issue(client) error = %v", err)
+	}
+	if usage := parseCert(t, client.CertPEM).ExtKeyUsage; len(usage) != 1 || usage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("client ExtKeyUsage = %v", usage)
+	}
+
+	if _, err := ca.Issue(Request{}); err == nil {
+		t.Error("Issue() without names succeeded")
+	}
+}
+
+func TestIssue_ValidityCappedByCA(t *testing.T) {
+	ca, err := NewCA("short", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ca.Issue(Request{CommonName: "web", Validity: 48 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := parseCert(t, cert.CertPEM).NotAfter, parseCert(t, ca.CertPEM).NotAfter; got.After(want) {
+		t.Errorf("NotAfter = %v, after the CA's %v", got, want)
+	}
+}
+
+func TestParseCA(t *testing.T) {
+	ca, err := NewCA("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseCA(ca.CertPEM, ca.KeyPEM)
+	if err != nil {
+		t.Fatalf("ParseCA() error = %v", err)
+	}
+	if _, err := parsed.Issue(Request{CommonName: "web"}); err != nil {
+		t.Errorf("Issue() error = %v", err)
+	}
+
+	other, err := NewCA("other", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := ca.Issue(Request{CommonName: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cert    []byte
+		key     []byte
+		wantErr string
+	}{
+		{"not PEM", []byte("x"), ca.KeyPEM, "no CERTIFICATE block"},
+		{"not a CA", leaf.CertPEM, leaf.KeyPEM, "not a CA"},
+		{"mismatched key", ca.CertPEM, other.KeyPEM, "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCA(tt.cert, tt.key); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCA() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
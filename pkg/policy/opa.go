@@ -0,0 +1,186 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy provides admission hooks that evaluate a validated spec
+// against centrally managed guardrails before any resource is created.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultOPATimeout bounds a single OPA query.
+const defaultOPATimeout = 10 * time.Second
+
+// OPA evaluates admission by querying an Open Policy Agent data API endpoint,
+// e.g. http://opa:8181/v1/data/testenv/admission.
+//
+// The query input is {"environmentId", "testId", "stage", "spec"}. The result
+// document may be any of:
+//   - a boolean: false denies with a generic message;
+//   - an array of deny messages (strings or {"rule", "msg"} objects);
+//   - an object with an optional "allow" boolean and/or "deny" array as above.
+type OPA struct {
+	url        string
+	httpClient *http.Client
+}
+
+// OPAOption is a functional option for configuring an OPA admitter.
+type OPAOption func(*OPA)
+
+// WithOPAHTTPClient sets a custom HTTP client.
+// This is primarily used for testing with httptest servers.
+func WithOPAHTTPClient(client *http.Client) OPAOption {
+	return func(o *OPA) {
+		o.httpClient = client
+	}
+}
+
+// NewOPA creates an OPA admitter querying the given data API URL.
+func NewOPA(endpoint string, opts ...OPAOption) (*OPA, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPA URL %q: %w", endpoint, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("OPA URL %q must use http or https", endpoint)
+	}
+
+	o := &OPA{
+		url:        endpoint,
+		httpClient: &http.Client{Timeout: defaultOPATimeout},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o, nil
+}
+
+// opaInput is the document sent as "input" to OPA.
+type opaInput struct {
+	EnvironmentID string         `json:"environmentId"`
+	TestID        string         `json:"testId"`
+	Stage         string         `json:"stage"`
+	Spec          map[string]any `json:"spec"`
+}
+
+// Admit queries OPA and converts the result into violations.
+func (o *OPA) Admit(ctx context.Context, req *Request) ([]Violation, error) {
+	input := opaInput{
+		EnvironmentID: req.EnvironmentID,
+		TestID:        req.TestID,
+		Stage:         req.Stage,
+		Spec:          map[string]any{},
+	}
+	if req.Spec != nil {
+		input.Spec = req.Spec.ToMap()
+	}
+
+	body, err := json.Marshal(map[string]any{"input": input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OPA input: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OPA request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("OPA request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OPA response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OPA returned %s: %s", resp.Status, string(respBody))
+	}
+
+	var decoded struct {
+		Result *json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode OPA response: %w", err)
+	}
+	if decoded.Result == nil {
+		// OPA omits "result" when the queried document is undefined, which
+		// almost always indicates a wrong policy path.
+		return nil, fmt.Errorf("OPA policy at %s is undefined", o.url)
+	}
+
+	return parseOPAResult(*decoded.Result)
+}
+
+// parseOPAResult interprets the supported result shapes.
+func parseOPAResult(raw json.RawMessage) ([]Violation, error) {
+	var allowed bool
+	if err := json.Unmarshal(raw, &allowed); err == nil {
+		if allowed {
+			return nil, nil
+		}
+		return []Violation{{Message: "denied by policy"}}, nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return parseDenyList(list)
+	}
+
+	var obj struct {
+		Allow *bool             `json:"allow"`
+		Deny  []json.RawMessage `json:"deny"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("unsupported OPA result: %s", string(raw))
+	}
+
+	violations, err := parseDenyList(obj.Deny)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Allow != nil && !*obj.Allow && len(violations) == 0 {
+		violations = append(violations, Violation{Message: "denied by policy"})
+	}
+	return violations, nil
+}
+
+// parseDenyList converts deny entries (strings or {"rule","msg"} objects) into violations.
+func parseDenyList(list []json.RawMessage) ([]Violation, error) {
+	violations := make([]Violation, 0, len(list))
+	for _, item := range list {
+		var msg string
+		if err := json.Unmarshal(item, &msg); err == nil {
+			violations = append(violations, Violation{Message: msg})
+			continue
+		}
+		var v Violation
+		if err := json.Unmarshal(item, &v); err != nil || v.Message == "" {
+			return nil, fmt.Errorf("unsupported OPA deny entry: %s", string(item))
+		}
+		violations = append(violations, v)
+	}
+	return violations, nil
+}
@@ -0,0 +1,196 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestOPA_Admit(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		status         int
+		wantViolations []Violation
+		wantErr        string
+	}{
+		{
+			name:     "boolean allow",
+			response: `{"result": true}`,
+		},
+		{
+			name:           "boolean deny",
+			response:       `{"result": false}`,
+			wantViolations: []Violation{{Message: "denied by policy"}},
+		},
+		{
+			name:           "deny message list",
+			response:       `{"result": ["memory too large", "image source not internal"]}`,
+			wantViolations: []Violation{{Message: "memory too large"}, {Message: "image source not internal"}},
+		},
+		{
+			name:     "object with empty deny",
+			response: `{"result": {"allow": true, "deny": []}}`,
+		},
+		{
+			name:           "object with rule-level violations",
+			response:       `{"result": {"deny": [{"rule": "max-memory", "msg": "vm web: 32768MiB > 16384MiB"}]}}`,
+			wantViolations: []Violation{{Rule: "max-memory", Message: "vm web: 32768MiB > 16384MiB"}},
+		},
+		{
+			name:           "object allow false without reasons",
+			response:       `{"result": {"allow": false}}`,
+			wantViolations: []Violation{{Message: "denied by policy"}},
+		},
+		{
+			name:     "undefined result",
+			response: `{}`,
+			wantErr:  "undefined",
+		},
+		{
+			name:     "server error",
+			response: `boom`,
+			status:   http.StatusInternalServerError,
+			wantErr:  "500",
+		},
+		{
+			name:     "unsupported result",
+			response: `{"result": 42}`,
+			wantErr:  "unsupported OPA result",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				_, _ = io.WriteString(w, tt.response)
+			}))
+			defer server.Close()
+
+			opa, err := NewOPA(server.URL + "/v1/data/testenv/admission")
+			if err != nil {
+				t.Fatalf("NewOPA() error = %v", err)
+			}
+
+			got, err := opa.Admit(context.Background(), &Request{Spec: &v1.Spec{}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Admit() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Admit() error = %v", err)
+			}
+			if len(got) != len(tt.wantViolations) {
+				t.Fatalf("Admit() = %+v, want %+v", got, tt.wantViolations)
+			}
+			for i := range got {
+				if got[i] != tt.wantViolations[i] {
+					t.Errorf("violation[%d] = %+v, want %+v", i, got[i], tt.wantViolations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOPA_Admit_SendsInput(t *testing.T) {
+	var received struct {
+		Input map[string]any `json:"input"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		_, _ = io.WriteString(w, `{"result": true}`)
+	}))
+	defer server.Close()
+
+	opa, err := NewOPA(server.URL)
+	if err != nil {
+		t.Fatalf("NewOPA() error = %v", err)
+	}
+
+	spec := &v1.Spec{Vms: []v1.VMResource{{Name: "web", Spec: v1.VMSpec{Memory: 2048}}}}
+	if _, err := opa.Admit(context.Background(), &Request{EnvironmentID: "ci-1", Stage: "e2e", Spec: spec}); err != nil {
+		t.Fatalf("Admit() error = %v", err)
+	}
+
+	if received.Input["environmentId"] != "ci-1" || received.Input["stage"] != "e2e" {
+		t.Errorf("input = %v", received.Input)
+	}
+	specMap, ok := received.Input["spec"].(map[string]any)
+	if !ok {
+		t.Fatalf("input.spec missing: %v", received.Input)
+	}
+	if _, ok := specMap["vms"]; !ok {
+		t.Errorf("input.spec.vms missing: %v", specMap)
+	}
+}
+
+func TestNewOPA_InvalidURL(t *testing.T) {
+	if _, err := NewOPA("unix:///var/run/opa.sock"); err == nil {
+		t.Error("NewOPA() expected error for non-HTTP scheme")
+	}
+}
+
+// staticAdmitter returns fixed results for Check tests.
+type staticAdmitter struct {
+	violations []Violation
+	err        error
+}
+
+func (s staticAdmitter) Admit(_ context.Context, _ *Request) ([]Violation, error) {
+	return s.violations, s.err
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	req := &Request{}
+
+	if err := Check(ctx, nil, req); err != nil {
+		t.Errorf("Check(nil) error = %v", err)
+	}
+	if err := Check(ctx, staticAdmitter{}, req); err != nil {
+		t.Errorf("Check(allow) error = %v", err)
+	}
+
+	err := Check(ctx, staticAdmitter{violations: []Violation{{Rule: "r1", Message: "m1"}, {Message: "m2"}}}, req)
+	var denied *DeniedError
+	if !errors.As(err, &denied) {
+		t.Fatalf("Check(deny) error = %v, want *DeniedError", err)
+	}
+	if !strings.Contains(err.Error(), "r1: m1") || !strings.Contains(err.Error(), "m2") {
+		t.Errorf("DeniedError message = %q", err.Error())
+	}
+
+	err = Check(ctx, staticAdmitter{err: errors.New("unreachable")}, req)
+	if err == nil || errors.As(err, &denied) {
+		t.Errorf("Check(error) = %v, want evaluation error", err)
+	}
+}
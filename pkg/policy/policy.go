@@ -0,0 +1,91 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy provides admission hooks that evaluate a validated spec
+// against centrally managed guardrails before any resource is created.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Request is the admission input: the validated spec plus identifying context.
+type Request struct {
+	// EnvironmentID is the resolved environment ID.
+	EnvironmentID string `json:"environmentId"`
+	// TestID is the forge testID.
+	TestID string `json:"testId"`
+	// Stage is the test stage name.
+	Stage string `json:"stage"`
+	// Spec is the validated spec.
+	Spec *v1.Spec `json:"-"`
+}
+
+// Violation describes a single failed policy rule.
+type Violation struct {
+	// Rule is the identifier of the rule that failed, if known.
+	Rule string `json:"rule,omitempty"`
+	// Message is the human-readable reason.
+	Message string `json:"msg"`
+}
+
+// String formats the violation as "rule: message" or just "message".
+func (v Violation) String() string {
+	if v.Rule == "" {
+		return v.Message
+	}
+	return v.Rule + ": " + v.Message
+}
+
+// Admitter evaluates admission policies against a spec.
+type Admitter interface {
+	// Admit returns the violations found. An error means the policy could not
+	// be evaluated; callers should treat it as a denial (fail closed).
+	Admit(ctx context.Context, req *Request) ([]Violation, error)
+}
+
+// DeniedError is returned by Check when at least one rule is violated.
+type DeniedError struct {
+	Violations []Violation
+}
+
+// Error lists every violation, one per line.
+func (e *DeniedError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = "  - " + v.String()
+	}
+	return fmt.Sprintf("spec denied by admission policy (%d violations):\n%s",
+		len(e.Violations), strings.Join(msgs, "\n"))
+}
+
+// Check runs the admitter and converts violations into a *DeniedError.
+// A nil admitter admits everything.
+func Check(ctx context.Context, a Admitter, req *Request) error {
+	if a == nil {
+		return nil
+	}
+	violations, err := a.Admit(ctx, req)
+	if err != nil {
+		return fmt.Errorf("admission policy evaluation failed: %w", err)
+	}
+	if len(violations) > 0 {
+		return &DeniedError{Violations: violations}
+	}
+	return nil
+}
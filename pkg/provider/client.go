@@ -118,6 +118,8 @@ type Client struct {
 	routerDone chan struct{} // Signals responseRouter exit
 	routerErr  error         // Error from responseRouter
 
+	server mcpImplementation // Server info reported during initialization
+
 	timeout time.Duration
 }
 
@@ -190,6 +192,7 @@ func (c *Client) initialize() error {
 	if err := c.callSync("initialize", params, &result); err != nil {
 		return fmt.Errorf("initialize request failed: %w", err)
 	}
+	c.server = result.ServerInfo
 
 	// Send initialized notification (no response expected)
 	if err := c.notify("notifications/initialized", nil); err != nil {
@@ -452,6 +455,12 @@ func (c *Client) CallWithContext(ctx context.Context, tool string, input any) (*
 	return &opResult, nil
 }
 
+// ServerVersion returns the version the provider reported during the MCP
+// initialization, or "" if it reported none.
+func (c *Client) ServerVersion() string {
+	return c.server.Version
+}
+
 // Capabilities retrieves the provider's capabilities.
 func (c *Client) Capabilities() (*providerv1.CapabilitiesResponse, error) {
 	result, err := c.Call("provider_capabilities", nil)
@@ -477,6 +486,62 @@ func (c *Client) Capabilities() (*providerv1.CapabilitiesResponse, error) {
 	return &caps, nil
 }
 
+// CallBatch runs calls with one batch tool call. The provider must report
+// CapabilitiesResponse.Batch. Results are returned in call order.
+func (c *Client) CallBatch(calls []providerv1.BatchCall) ([]providerv1.OperationResult, error) {
+	result, err := c.Call(providerv1.BatchTool, &providerv1.BatchRequest{Calls: calls})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		if result.Error != nil {
+			return nil, fmt.Errorf("batch call failed: %s", result.Error.Message)
+		}
+		return nil, fmt.Errorf("batch call failed")
+	}
+
+	data, err := json.Marshal(result.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch response: %w", err)
+	}
+	var resp providerv1.BatchResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch response: %w", err)
+	}
+	if len(resp.Results) != len(calls) {
+		return nil, fmt.Errorf("batch returned %d results for %d calls", len(resp.Results), len(calls))
+	}
+	return resp.Results, nil
+}
+
+// CallTeardown deletes resources with one environment teardown tool call.
+// The provider must report CapabilitiesResponse.Teardown.
+func (c *Client) CallTeardown(req *providerv1.TeardownRequest) ([]providerv1.TeardownResult, error) {
+	result, err := c.Call(providerv1.TeardownTool, req)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		if result.Error != nil {
+			return nil, fmt.Errorf("teardown call failed: %s", result.Error.Message)
+		}
+		return nil, fmt.Errorf("teardown call failed")
+	}
+
+	data, err := json.Marshal(result.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal teardown response: %w", err)
+	}
+	var resp providerv1.TeardownResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal teardown response: %w", err)
+	}
+	if len(resp.Results) != req.Len() {
+		return nil, fmt.Errorf("teardown returned %d results for %d resources", len(resp.Results), req.Len())
+	}
+	return resp.Results, nil
+}
+
 // Close terminates the provider process and cleans up resources.
 func (c *Client) Close() error {
 	var errs []error
@@ -454,6 +454,9 @@ func TestInitializeRaceCondition(t *testing.T) {
 	if !client.initialized {
 		t.Error("expected initialized=true after initialize()")
 	}
+	if got := client.ServerVersion(); got != "1.0.0" {
+		t.Errorf("ServerVersion() = %q, want 1.0.0", got)
+	}
 }
 
 // TestIsRunning tests the IsRunning method.
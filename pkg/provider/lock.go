@@ -0,0 +1,273 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// LockFileName is the default path of the provider lockfile.
+const LockFileName = "testenv-vm.lock"
+
+// LockVersion is the version of the lockfile format.
+const LockVersion = 1
+
+var (
+	// ErrEngineNotLocked is returned for engines missing from the lockfile.
+	ErrEngineNotLocked = errors.New("provider engine is not locked")
+	// ErrDigestMismatch is returned when a provider differs from the one
+	// recorded in the lockfile.
+	ErrDigestMismatch = errors.New("provider digest does not match the lockfile")
+)
+
+// Lock records the exact providers resolved for the engines of specs, so
+// that runs keep using them as provider releases move.
+type Lock struct {
+	// Version is the version of the lockfile format.
+	Version int `json:"version"`
+	// Providers are the locked engines, sorted by engine.
+	Providers []LockedProvider `json:"providers"`
+}
+
+// LockedProvider is the resolution of a provider engine.
+type LockedProvider struct {
+	// Engine is the engine as written in specs.
+	Engine string `json:"engine"`
+	// Module is the Go module of a go:// engine.
+	Module string `json:"module,omitempty"`
+	// Version is the exact module version go:// engines run at.
+	Version string `json:"version,omitempty"`
+	// Path is the resolved path of a binary engine.
+	Path string `json:"path,omitempty"`
+	// Digest is the sha256 of a binary engine ("sha256:<hex>") or the
+	// go.sum hash of the module of a go:// engine ("h1:<base64>"). Local
+	// go:// packages are built from the checkout and have none.
+	Digest string `json:"digest,omitempty"`
+}
+
+// goModule is the output of go mod download -json.
+type goModule struct {
+	Path    string
+	Version string
+	Sum     string
+	Error   string
+}
+
+// goModDownload downloads a module@version to the module cache and returns
+// its exact version and go.sum hash. It is a variable for tests.
+var goModDownload = func(moduleVersion string) (goModule, error) {
+	cmd := exec.Command("go", "mod", "download", "-json", moduleVersion)
+	// Outside of any module, so that go.mod and go.work files are ignored
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GOWORK=off")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	runErr := cmd.Run()
+	var m goModule
+	if err := json.Unmarshal(stdout.Bytes(), &m); err != nil {
+		if runErr != nil {
+			return goModule{}, fmt.Errorf("go mod download %s: %w", moduleVersion, runErr)
+		}
+		return goModule{}, fmt.Errorf("go mod download %s: invalid output: %w", moduleVersion, err)
+	}
+	if m.Error != "" {
+		return goModule{}, fmt.Errorf("go mod download %s: %s", moduleVersion, m.Error)
+	}
+	return m, nil
+}
+
+// ReadLock reads a lockfile. The error wraps os.ErrNotExist when it does
+// not exist.
+func ReadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lock Lock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid lockfile %s: %w", path, err)
+	}
+	if lock.Version != LockVersion {
+		return nil, fmt.Errorf("lockfile %s has version %d, want %d", path, lock.Version, LockVersion)
+	}
+	return &lock, nil
+}
+
+// WriteLock atomically writes a lockfile, with its providers sorted by
+// engine.
+func WriteLock(path string, lock *Lock) error {
+	lock.Version = LockVersion
+	slices.SortFunc(lock.Providers, func(a, b LockedProvider) int { return strings.Compare(a.Engine, b.Engine) })
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal lockfile: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
+
+// Find returns the locked provider of an engine.
+func (l *Lock) Find(engine string) (LockedProvider, bool) {
+	for _, p := range l.Providers {
+		if p.Engine == engine {
+			return p, true
+		}
+	}
+	return LockedProvider{}, false
+}
+
+// Set adds or replaces the locked provider of an engine.
+func (l *Lock) Set(p LockedProvider) {
+	for i := range l.Providers {
+		if l.Providers[i].Engine == p.Engine {
+			l.Providers[i] = p
+			return
+		}
+	}
+	l.Providers = append(l.Providers, p)
+}
+
+// LockEngine resolves an engine to the exact provider it runs now: the
+// version and module hash of go:// engines, whose version defaults to
+// latest, or the path and sha256 of binary engines.
+func LockEngine(engine string) (LockedProvider, error) {
+	locked := LockedProvider{Engine: engine}
+	if strings.HasPrefix(engine, "go://") {
+		pkgPath, version := stripVersion(strings.TrimPrefix(engine, "go://"))
+		if !isExternalModule(pkgPath) {
+			return locked, nil
+		}
+		if version == "" {
+			version = "@latest"
+		}
+		m, err := downloadPackageModule(pkgPath, version)
+		if err != nil {
+			return LockedProvider{}, err
+		}
+		locked.Module, locked.Version, locked.Digest = m.Path, m.Version, m.Sum
+		return locked, nil
+	}
+
+	path, err := binaryPath(engine)
+	if err != nil {
+		return LockedProvider{}, err
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return LockedProvider{}, err
+	}
+	locked.Path, locked.Digest = path, digest
+	return locked, nil
+}
+
+// Pin returns the engine to run for an engine of a spec: go:// engines run
+// at their locked version. The module or binary must match the digest
+// recorded in the lock.
+func (l *Lock) Pin(engine string) (string, error) {
+	locked, ok := l.Find(engine)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrEngineNotLocked, engine)
+	}
+	if strings.HasPrefix(engine, "go://") {
+		pkgPath, _ := stripVersion(strings.TrimPrefix(engine, "go://"))
+		if locked.Version == "" {
+			return engine, nil
+		}
+		m, err := goModDownload(locked.Module + "@" + locked.Version)
+		if err != nil {
+			return "", err
+		}
+		if m.Sum != locked.Digest {
+			return "", fmt.Errorf("%w: %s@%s has hash %s, locked %s", ErrDigestMismatch, locked.Module, locked.Version, m.Sum, locked.Digest)
+		}
+		return "go://" + pkgPath + "@" + locked.Version, nil
+	}
+
+	path, err := binaryPath(engine)
+	if err != nil {
+		return "", err
+	}
+	digest, err := fileDigest(path)
+	if err != nil {
+		return "", err
+	}
+	if digest != locked.Digest {
+		return "", fmt.Errorf("%w: %s has digest %s, locked %s", ErrDigestMismatch, path, digest, locked.Digest)
+	}
+	return engine, nil
+}
+
+// downloadPackageModule downloads the module providing a package, trying
+// the package path then each of its parents as the module path.
+func downloadPackageModule(pkgPath, version string) (goModule, error) {
+	var firstErr error
+	for modPath := pkgPath; strings.Contains(modPath, "/"); modPath = filepath.Dir(modPath) {
+		m, err := goModDownload(modPath + version)
+		if err == nil {
+			return m, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return goModule{}, firstErr
+}
+
+// binaryPath returns the absolute path of a binary engine, looked up in
+// PATH like resolveBinaryEngine.
+func binaryPath(engine string) (string, error) {
+	path, err := exec.LookPath(engine)
+	if err != nil {
+		if _, statErr := os.Stat(engine); statErr != nil {
+			return "", fmt.Errorf("binary %q not found: %w", engine, statErr)
+		}
+		path = engine
+	}
+	return filepath.Abs(path)
+}
+
+// fileDigest returns the sha256 of a file as "sha256:<hex>".
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open provider binary: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash provider binary: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,131 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestLock_BinaryEngine(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "provider")
+	if err := os.WriteFile(binary, []byte("v1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	locked, err := LockEngine(binary)
+	if err != nil {
+		t.Fatalf("LockEngine() error = %v", err)
+	}
+	if locked.Path != binary || !strings.HasPrefix(locked.Digest, "sha256:") {
+		t.Errorf("LockEngine() = %+v", locked)
+	}
+
+	path := filepath.Join(dir, LockFileName)
+	if _, err := ReadLock(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadLock() error = %v, want os.ErrNotExist", err)
+	}
+	if err := WriteLock(path, &Lock{Providers: []LockedProvider{locked}}); err != nil {
+		t.Fatalf("WriteLock() error = %v", err)
+	}
+	lock, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() error = %v", err)
+	}
+	if engine, err := lock.Pin(binary); err != nil || engine != binary {
+		t.Errorf("Pin() = %q, %v, want the engine", engine, err)
+	}
+	if _, err := lock.Pin("/usr/bin/other-provider"); !errors.Is(err, ErrEngineNotLocked) {
+		t.Errorf("Pin() error = %v, want ErrEngineNotLocked", err)
+	}
+
+	// A rebuilt binary no longer matches
+	if err := os.WriteFile(binary, []byte("v2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lock.Pin(binary); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Pin() error = %v, want ErrDigestMismatch", err)
+	}
+}
+
+func TestLock_GoEngine(t *testing.T) {
+	sums := map[string]string{"v1.2.0": "h1:one=", "v1.3.0": "h1:two="}
+	latest := "v1.2.0"
+	download := goModDownload
+	t.Cleanup(func() { goModDownload = download })
+	goModDownload = func(moduleVersion string) (goModule, error) {
+		path, version, _ := strings.Cut(moduleVersion, "@")
+		if path != "example.com/providers" {
+			return goModule{}, errors.New("not a module")
+		}
+		if version == "latest" {
+			version = latest
+		}
+		return goModule{Path: path, Version: version, Sum: sums[version]}, nil
+	}
+
+	const engine = "go://example.com/providers/cmd/libvirt"
+	locked, err := LockEngine(engine)
+	if err != nil {
+		t.Fatalf("LockEngine() error = %v", err)
+	}
+	want := LockedProvider{Engine: engine, Module: "example.com/providers", Version: "v1.2.0", Digest: "h1:one="}
+	if locked != want {
+		t.Errorf("LockEngine() = %+v, want %+v", locked, want)
+	}
+
+	// The locked version is run after a new release
+	latest = "v1.3.0"
+	lock := &Lock{Providers: []LockedProvider{locked}}
+	if pinned, err := lock.Pin(engine); err != nil || pinned != engine+"@v1.2.0" {
+		t.Errorf("Pin() = %q, %v, want %q", pinned, err, engine+"@v1.2.0")
+	}
+
+	sums["v1.2.0"] = "h1:tampered="
+	if _, err := lock.Pin(engine); !errors.Is(err, ErrDigestMismatch) {
+		t.Errorf("Pin() error = %v, want ErrDigestMismatch", err)
+	}
+
+	// Local packages are built from the checkout
+	local, err := LockEngine("go://cmd/providers/stub")
+	if err != nil || local.Digest != "" || local.Version != "" {
+		t.Errorf("LockEngine() = %+v, %v, want no version", local, err)
+	}
+}
+
+func TestManager_StartPinsEngine(t *testing.T) {
+	dir := t.TempDir()
+	lockFile := filepath.Join(dir, LockFileName)
+	if err := WriteLock(lockFile, &Lock{}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(WithLockFile(lockFile))
+	err := m.Start(v1.ProviderConfig{Name: "stub", Engine: "/usr/bin/unlocked-provider"})
+	if !errors.Is(err, ErrEngineNotLocked) {
+		t.Errorf("Start() error = %v, want ErrEngineNotLocked", err)
+	}
+	if info, ok := m.GetInfo("stub"); !ok || info.Status != StatusFailed {
+		t.Errorf("provider info = %+v, want failed", info)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLogSize caps the size of a provider log file. Once reached, the file
+// is rotated to RotatedLogPath, replacing the previous rotated file.
+const maxLogSize = 10 << 20
+
+// logNameReplacer replaces path separators in provider names.
+var logNameReplacer = strings.NewReplacer("/", "_", `\`, "_")
+
+// LogPath returns the stderr log file of a provider inside logDir. Path
+// separators in name are replaced, so that the file stays in logDir whatever
+// the provider name of the spec.
+func LogPath(logDir, name string) string {
+	return filepath.Join(logDir, logNameReplacer.Replace(name)+".log")
+}
+
+// RotatedLogPath returns the previous stderr log file of a provider, rotated
+// when the log file reached its maximum size.
+func RotatedLogPath(logDir, name string) string {
+	return LogPath(logDir, name) + ".1"
+}
+
+// ReadLogs returns the captured stderr of a provider from logDir, including
+// its rotated log file. When tail is positive only the last tail lines are
+// returned. Logs are read from disk, so they remain available after the
+// provider (or the process that ran it) has exited.
+func ReadLogs(logDir, name string, tail int) (string, error) {
+	if logDir == "" {
+		return "", fmt.Errorf("provider logs are not captured (no log directory configured)")
+	}
+	data, err := os.ReadFile(LogPath(logDir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no logs found for provider %q", name)
+		}
+		return "", fmt.Errorf("failed to read logs of provider %q: %w", name, err)
+	}
+	if rotated, err := os.ReadFile(RotatedLogPath(logDir, name)); err == nil {
+		data = append(rotated, data...)
+	}
+	if tail <= 0 {
+		return string(data), nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// stderrWriter attributes each stderr line of a provider process to the
+// provider (name and pid) and writes it to the provider log file and to the
+// orchestrator stderr.
+type stderrWriter struct {
+	name    string
+	cmd     *exec.Cmd
+	file    io.WriteCloser
+	console io.Writer
+	now     func() time.Time
+	// path is the log file, size its current size and maxSize the size at
+	// which it is rotated.
+	path    string
+	size    int64
+	maxSize int64
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// newStderrWriter creates a writer for the stderr of cmd. If logDir is empty
+// lines are only forwarded to console.
+func newStderrWriter(name string, cmd *exec.Cmd, logDir string, console io.Writer) (*stderrWriter, error) {
+	w := &stderrWriter{name: name, cmd: cmd, console: console, now: time.Now, maxSize: maxLogSize}
+	if logDir == "" {
+		return w, nil
+	}
+	if err := os.MkdirAll(logDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create provider log directory: %w", err)
+	}
+	w.path = LogPath(logDir, name)
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open provider log file: %w", err)
+	}
+	if info, err := f.Stat(); err == nil {
+		w.size = info.Size()
+	}
+	w.file = f
+	return w, nil
+}
+
+// Write buffers p and emits every complete line.
+func (w *stderrWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Close flushes a trailing partial line and closes the log file.
+func (w *stderrWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+		return err
+	}
+	return nil
+}
+
+// emit writes one attributed line. Write errors are ignored: losing a log
+// line must not break the provider.
+func (w *stderrWriter) emit(line []byte) {
+	pid := 0
+	if w.cmd != nil && w.cmd.Process != nil {
+		pid = w.cmd.Process.Pid
+	}
+	prefix := fmt.Sprintf("[provider=%s pid=%d] ", w.name, pid)
+	if w.console != nil {
+		_, _ = fmt.Fprintf(w.console, "%s%s\n", prefix, line)
+	}
+	if w.file == nil {
+		return
+	}
+	entry := fmt.Sprintf("%s %s%s\n", w.now().UTC().Format(time.RFC3339), prefix, line)
+	if w.size+int64(len(entry)) > w.maxSize {
+		w.rotate()
+	}
+	if w.file != nil {
+		n, _ := io.WriteString(w.file, entry)
+		w.size += int64(n)
+	}
+}
+
+// rotate moves the log file to its rotated path and starts a new one. The
+// log file is dropped if it cannot be reopened.
+func (w *stderrWriter) rotate() {
+	_ = w.file.Close()
+	w.file, w.size = nil, 0
+	_ = os.Rename(w.path, w.path+".1")
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return
+	}
+	w.file = f
+}
@@ -0,0 +1,161 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStderrWriter(t *testing.T) {
+	dir := t.TempDir()
+	var console bytes.Buffer
+	cmd := &exec.Cmd{Process: &os.Process{Pid: 42}}
+
+	w, err := newStderrWriter("stub", cmd, dir, &console)
+	if err != nil {
+		t.Fatalf("newStderrWriter failed: %v", err)
+	}
+	w.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	_, _ = w.Write([]byte("first li"))
+	_, _ = w.Write([]byte("ne\nsecond line\ntrailing"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	wantConsole := "[provider=stub pid=42] first line\n" +
+		"[provider=stub pid=42] second line\n" +
+		"[provider=stub pid=42] trailing\n"
+	if console.String() != wantConsole {
+		t.Errorf("console output = %q, want %q", console.String(), wantConsole)
+	}
+
+	data, err := os.ReadFile(LogPath(dir, "stub"))
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %d: %q", len(lines), data)
+	}
+	if lines[0] != "2025-01-02T03:04:05Z [provider=stub pid=42] first line" {
+		t.Errorf("unexpected log line: %q", lines[0])
+	}
+}
+
+func TestStderrWriterNoLogDir(t *testing.T) {
+	var console bytes.Buffer
+	w, err := newStderrWriter("stub", nil, "", &console)
+	if err != nil {
+		t.Fatalf("newStderrWriter failed: %v", err)
+	}
+	_, _ = w.Write([]byte("hello\n"))
+	_ = w.Close()
+
+	if console.String() != "[provider=stub pid=0] hello\n" {
+		t.Errorf("unexpected console output: %q", console.String())
+	}
+}
+
+func TestReadLogs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(LogPath(dir, "stub"), []byte("a\nb\nc\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		tail int
+		want string
+	}{
+		{name: "all", tail: 0, want: "a\nb\nc\n"},
+		{name: "tail", tail: 2, want: "b\nc\n"},
+		{name: "tail larger than file", tail: 10, want: "a\nb\nc\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadLogs(dir, "stub", tt.tail)
+			if err != nil {
+				t.Fatalf("ReadLogs failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadLogs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadLogs(dir, "missing", 0); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+	if _, err := ReadLogs("", "stub", 0); err == nil {
+		t.Error("expected error without log directory")
+	}
+}
+
+func TestLogPath(t *testing.T) {
+	for _, name := range []string{"../../etc/cron.d/x", `..\x`, ".."} {
+		if got := LogPath("/state/logs", name); filepath.Dir(got) != "/state/logs" {
+			t.Errorf("LogPath(%q) = %q, want a file of the log directory", name, got)
+		}
+	}
+}
+
+func TestStderrWriterRotates(t *testing.T) {
+	dir := t.TempDir()
+	w, err := newStderrWriter("stub", nil, dir, nil)
+	if err != nil {
+		t.Fatalf("newStderrWriter failed: %v", err)
+	}
+	w.maxSize = 100
+
+	for _, line := range []string{"first", "second", "third"} {
+		_, _ = w.Write([]byte(line + "\n"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	for _, path := range []string{LogPath(dir, "stub"), RotatedLogPath(dir, "stub")} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", path, err)
+		}
+		if info.Size() > w.maxSize {
+			t.Errorf("%s size = %d, want at most %d", path, info.Size(), w.maxSize)
+		}
+	}
+	got, err := ReadLogs(dir, "stub", 2)
+	if err != nil {
+		t.Fatalf("ReadLogs failed: %v", err)
+	}
+	if !strings.Contains(got, "second") || !strings.Contains(got, "third") {
+		t.Errorf("ReadLogs = %q, want the last lines across the rotated file", got)
+	}
+}
+
+func TestManagerWithLogDir(t *testing.T) {
+	m := NewManager(WithLogDir("/tmp/logs"))
+	if m.LogDir() != "/tmp/logs" {
+		t.Errorf("LogDir = %q, want /tmp/logs", m.LogDir())
+	}
+}
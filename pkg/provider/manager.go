@@ -16,6 +16,7 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -48,19 +49,66 @@ type ProviderInfo struct {
 	Capabilities *providerv1.CapabilitiesResponse
 	// Status is the current provider status: running, stopped, failed.
 	Status string
+	// LogPath is the file capturing the provider stderr, if any.
+	LogPath string
+
+	// stderr attributes and records the provider stderr.
+	stderr *stderrWriter
 }
 
 // Manager manages provider lifecycle and communication.
 type Manager struct {
 	providers map[string]*ProviderInfo
+	logDir    string
+	lockFile  string
 	mu        sync.RWMutex
+
+	// capabilities caches capabilities by provider name, version and
+	// configuration, so restarting the same provider needs no capabilities
+	// call.
+	capabilities map[string]*providerv1.CapabilitiesResponse
+}
+
+// ManagerOption configures a Manager.
+type ManagerOption func(*Manager)
+
+// WithLogDir captures the stderr of every provider into <dir>/<name>.log.
+// Without it, stderr lines are only forwarded to the orchestrator stderr.
+func WithLogDir(dir string) ManagerOption {
+	return func(m *Manager) {
+		m.logDir = dir
+	}
+}
+
+// WithLockFile pins providers to the lockfile at path when it exists: go://
+// engines run at their locked version and every engine must match its
+// locked digest. See Lock.Pin.
+func WithLockFile(path string) ManagerOption {
+	return func(m *Manager) {
+		m.lockFile = path
+	}
 }
 
 // NewManager creates a new provider manager.
-func NewManager() *Manager {
-	return &Manager{
-		providers: make(map[string]*ProviderInfo),
+func NewManager(opts ...ManagerOption) *Manager {
+	m := &Manager{
+		providers:    make(map[string]*ProviderInfo),
+		capabilities: make(map[string]*providerv1.CapabilitiesResponse),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
+}
+
+// LogDir returns the directory provider logs are written to ("" if disabled).
+func (m *Manager) LogDir() string {
+	return m.logDir
+}
+
+// Logs returns the captured stderr of a provider. See ReadLogs.
+func (m *Manager) Logs(name string, tail int) (string, error) {
+	return ReadLogs(m.logDir, name, tail)
 }
 
 // Start starts a provider process based on its configuration.
@@ -78,8 +126,16 @@ func (m *Manager) Start(config v1.ProviderConfig) error {
 
 	log.Printf("Starting provider %q with engine %q", config.Name, config.Engine)
 
-	// Resolve engine to command
-	cmd, err := resolveEngine(config.Engine)
+	// Resolve engine to command, at the version of the lockfile
+	engine, err := m.pinEngine(config.Engine)
+	if err != nil {
+		m.providers[config.Name] = &ProviderInfo{
+			Config: config,
+			Status: StatusFailed,
+		}
+		return fmt.Errorf("failed to pin engine of provider %q: %w", config.Name, err)
+	}
+	cmd, err := resolveEngine(engine)
 	if err != nil {
 		m.providers[config.Name] = &ProviderInfo{
 			Config: config,
@@ -88,27 +144,53 @@ func (m *Manager) Start(config v1.ProviderConfig) error {
 		return fmt.Errorf("failed to resolve engine for provider %q: %w", config.Name, err)
 	}
 
-	// Create MCP client (this starts the process and performs handshake)
-	client, err := NewClient(cmd)
+	// Capture stderr with provider attribution
+	stderr, err := newStderrWriter(config.Name, cmd, m.logDir, os.Stderr)
 	if err != nil {
 		m.providers[config.Name] = &ProviderInfo{
 			Config: config,
 			Status: StatusFailed,
 		}
+		return fmt.Errorf("failed to capture logs of provider %q: %w", config.Name, err)
+	}
+	cmd.Stderr = stderr
+	logPath := ""
+	if m.logDir != "" {
+		logPath = LogPath(m.logDir, config.Name)
+	}
+
+	// Create MCP client (this starts the process and performs handshake)
+	client, err := NewClient(cmd)
+	if err != nil {
+		_ = stderr.Close()
+		m.providers[config.Name] = &ProviderInfo{
+			Config:  config,
+			Status:  StatusFailed,
+			LogPath: logPath,
+		}
 		return fmt.Errorf("failed to start provider %q: %w", config.Name, err)
 	}
 
-	// Fetch provider capabilities
-	capabilities, err := client.Capabilities()
+	// Fetch provider capabilities unless this version and configuration
+	// were seen before
+	capabilities, cached := m.capabilities[capabilitiesKey(config, client.ServerVersion())]
+	if !cached {
+		capabilities, err = client.Capabilities()
+	}
 	if err != nil {
 		// Close the client on failure
 		_ = client.Close()
+		_ = stderr.Close()
 		m.providers[config.Name] = &ProviderInfo{
-			Config: config,
-			Status: StatusFailed,
+			Config:  config,
+			Status:  StatusFailed,
+			LogPath: logPath,
 		}
 		return fmt.Errorf("failed to fetch capabilities for provider %q: %w", config.Name, err)
 	}
+	if version := client.ServerVersion(); version != "" {
+		m.capabilities[capabilitiesKey(config, version)] = capabilities
+	}
 
 	// Store provider info
 	m.providers[config.Name] = &ProviderInfo{
@@ -116,6 +198,8 @@ func (m *Manager) Start(config v1.ProviderConfig) error {
 		Client:       client,
 		Capabilities: capabilities,
 		Status:       StatusRunning,
+		LogPath:      logPath,
+		stderr:       stderr,
 	}
 
 	log.Printf("Provider %q started successfully (version: %s)", config.Name, capabilities.Version)
@@ -144,6 +228,9 @@ func (m *Manager) Stop(name string) error {
 			return fmt.Errorf("failed to stop provider %q: %w", name, err)
 		}
 	}
+	if info.stderr != nil {
+		_ = info.stderr.Close()
+	}
 
 	info.Status = StatusStopped
 	log.Printf("Provider %q stopped", name)
@@ -203,6 +290,74 @@ func (m *Manager) Call(provider, tool string, input interface{}) (*providerv1.Op
 	return client.Call(tool, input)
 }
 
+// CallBatch runs calls against a provider and returns one result per call,
+// in call order. Providers reporting the batch capability receive the calls
+// in chunks of at most providerv1.MaxBatchCalls; other providers receive
+// them as concurrent individual calls.
+func (m *Manager) CallBatch(provider string, calls []providerv1.BatchCall) ([]providerv1.OperationResult, error) {
+	client, err := m.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, ok := m.GetInfo(provider); ok && info.Capabilities != nil && info.Capabilities.Batch {
+		results := make([]providerv1.OperationResult, 0, len(calls))
+		for start := 0; start < len(calls); start += providerv1.MaxBatchCalls {
+			end := min(start+providerv1.MaxBatchCalls, len(calls))
+			chunk, err := client.CallBatch(calls[start:end])
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, chunk...)
+		}
+		return results, nil
+	}
+
+	results := make([]providerv1.OperationResult, len(calls))
+	errs := make([]error, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call providerv1.BatchCall) {
+			defer wg.Done()
+			result, err := client.Call(call.Tool, call.Input)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", call.Tool, err)
+				return
+			}
+			results[i] = *result
+		}(i, call)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// SupportsTeardown reports whether a provider serves the environment
+// teardown tool.
+func (m *Manager) SupportsTeardown(provider string) bool {
+	info, ok := m.GetInfo(provider)
+	return ok && info.Capabilities != nil && info.Capabilities.Teardown
+}
+
+// Teardown deletes resources of a provider with one environment teardown
+// call and returns one result per resource. The provider must report
+// providerv1.CapabilitiesResponse.Teardown.
+func (m *Manager) Teardown(provider string, req *providerv1.TeardownRequest) ([]providerv1.TeardownResult, error) {
+	if !m.SupportsTeardown(provider) {
+		return nil, fmt.Errorf("provider %s does not support %s", provider, providerv1.TeardownTool)
+	}
+	client, err := m.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTeardown(req)
+}
+
 // GetInfo returns the ProviderInfo for a provider by name.
 func (m *Manager) GetInfo(name string) (*ProviderInfo, bool) {
 	m.mu.RLock()
@@ -224,6 +379,26 @@ func (m *Manager) List() []string {
 	return names
 }
 
+// pinEngine returns the engine to run for the engine of a provider, pinned
+// by the lockfile. Engines run as written when there is no lockfile.
+func (m *Manager) pinEngine(engine string) (string, error) {
+	if m.lockFile == "" {
+		return engine, nil
+	}
+	lock, err := ReadLock(m.lockFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return engine, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	pinned, err := lock.Pin(engine)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w; run testenv-vmctl providers update to refresh it", m.lockFile, err)
+	}
+	return pinned, nil
+}
+
 // resolveEngine resolves an engine specification to an exec.Cmd.
 // Supported formats:
 //   - go://github.com/user/repo/cmd/tool@version - External Go module (always uses go run, defaults to @latest if no version)
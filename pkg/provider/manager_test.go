@@ -24,6 +24,7 @@ import (
 	"sync"
 	"testing"
 
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 )
 
@@ -727,3 +728,22 @@ func TestIsExternalModule(t *testing.T) {
 		})
 	}
 }
+
+// TestManagerTeardownRequiresCapability tests SupportsTeardown and Teardown.
+func TestManagerTeardownRequiresCapability(t *testing.T) {
+	m := NewManager()
+	m.mu.Lock()
+	m.providers["plain"] = &ProviderInfo{Capabilities: &providerv1.CapabilitiesResponse{Batch: true}}
+	m.providers["teardown"] = &ProviderInfo{Capabilities: &providerv1.CapabilitiesResponse{Teardown: true}}
+	m.mu.Unlock()
+
+	if m.SupportsTeardown("plain") || m.SupportsTeardown("missing") {
+		t.Error("SupportsTeardown() = true for a provider without the capability")
+	}
+	if !m.SupportsTeardown("teardown") {
+		t.Error("SupportsTeardown() = false for a provider with the capability")
+	}
+	if _, err := m.Teardown("plain", &providerv1.TeardownRequest{VMs: []string{"vm1"}}); err == nil {
+		t.Error("Teardown() expected error for a provider without the capability")
+	}
+}
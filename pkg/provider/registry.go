@@ -16,6 +16,11 @@
 package provider
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 )
@@ -39,6 +44,70 @@ func (m *Manager) RegisterCapabilities(name string, caps *providerv1.Capabilitie
 	info.Capabilities = caps
 }
 
+// Capabilities returns the capabilities of a provider. Capabilities fetched
+// when the provider started are returned without calling it again; they are
+// only fetched when none are known yet.
+func (m *Manager) Capabilities(name string) (*providerv1.CapabilitiesResponse, error) {
+	m.mu.RLock()
+	info, exists := m.providers[name]
+	var caps *providerv1.CapabilitiesResponse
+	if exists {
+		caps = info.Capabilities
+	}
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("provider %q not found", name)
+	}
+	if caps != nil {
+		return caps, nil
+	}
+	return m.RefreshCapabilities(name)
+}
+
+// RefreshCapabilities fetches the capabilities of a running provider again
+// and replaces the cached ones, e.g. after the provider host changed.
+func (m *Manager) RefreshCapabilities(name string) (*providerv1.CapabilitiesResponse, error) {
+	m.mu.RLock()
+	info, exists := m.providers[name]
+	var client *Client
+	if exists && info.Status == StatusRunning {
+		client = info.Client
+	}
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("provider %q not found", name)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("provider %q is not running", name)
+	}
+	caps, err := client.Capabilities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch capabilities for provider %q: %w", name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	info.Capabilities = caps
+	if version := client.ServerVersion(); version != "" {
+		m.capabilities[capabilitiesKey(info.Config, version)] = caps
+	}
+	return caps, nil
+}
+
+// capabilitiesKey is the key of a provider version in the capabilities cache.
+// It includes a hash of the provider configuration, since the capabilities of
+// a provider may depend on its spec (e.g. the libvirt host it connects to).
+func capabilitiesKey(config v1.ProviderConfig, version string) string {
+	data, _ := json.Marshal(struct {
+		Engine string
+		Spec   map[string]interface{}
+	}{config.Engine, config.Spec})
+	sum := sha256.Sum256(data)
+	return config.Name + "@" + version + "#" + hex.EncodeToString(sum[:8])
+}
+
 // SupportsResource checks if a provider supports a specific resource kind.
 // Returns true if the provider has the resource kind in its capabilities.
 func (m *Manager) SupportsResource(provider string, kind string) bool {
@@ -92,6 +161,42 @@ func (m *Manager) SupportsOperation(provider, kind, operation string) bool {
 	return false
 }
 
+// SupportsVMFeature checks if a provider advertises a VM feature (see
+// providerv1.VMFeatureVsock).
+func (m *Manager) SupportsVMFeature(provider, feature string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info, exists := m.providers[provider]
+	if !exists || info.Capabilities == nil {
+		return false
+	}
+	for _, res := range info.Capabilities.Resources {
+		if res.Kind == "vm" {
+			for _, f := range res.VMFeatures {
+				if f == feature {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// Version returns the version reported by a provider, or "unknown" if its
+// capabilities are not known.
+func (m *Manager) Version(provider string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	info, exists := m.providers[provider]
+	if !exists || info.Capabilities == nil || info.Capabilities.Version == "" {
+		return "unknown"
+	}
+	return info.Capabilities.Version
+}
+
 // GetProviderForResource selects the best provider for a resource kind.
 // The selection logic is:
 // 1. First, check if any provider in the list explicitly supports the kind
@@ -18,6 +18,9 @@
 package provider
 
 import (
+	"bufio"
+	"encoding/json"
+	"fmt"
 	"testing"
 
 	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
@@ -189,6 +192,48 @@ func TestSupportsOperation(t *testing.T) {
 	}
 }
 
+// TestSupportsVMFeature tests the SupportsVMFeature and Version methods.
+func TestSupportsVMFeature(t *testing.T) {
+	m := NewManager()
+	m.RegisterCapabilities("libvirt", &providerv1.CapabilitiesResponse{
+		ProviderName: "libvirt",
+		Version:      "v1.2.0",
+		Resources: []providerv1.ResourceCapability{
+			{Kind: "vm", Operations: []string{"create"}, VMFeatures: []string{providerv1.VMFeatureVsock}},
+		},
+	})
+	m.RegisterCapabilities("old", &providerv1.CapabilitiesResponse{
+		ProviderName: "old",
+		Resources:    []providerv1.ResourceCapability{{Kind: "vm", Operations: []string{"create"}}},
+	})
+
+	tests := []struct {
+		name     string
+		provider string
+		feature  string
+		expected bool
+	}{
+		{"advertised feature", "libvirt", providerv1.VMFeatureVsock, true},
+		{"other feature", "libvirt", providerv1.VMFeatureNICOptions, false},
+		{"no features", "old", providerv1.VMFeatureVsock, false},
+		{"non-existent provider", "non-existent", providerv1.VMFeatureVsock, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := m.SupportsVMFeature(tc.provider, tc.feature); got != tc.expected {
+				t.Errorf("SupportsVMFeature(%q, %q) = %v, want %v", tc.provider, tc.feature, got, tc.expected)
+			}
+		})
+	}
+
+	if got := m.Version("libvirt"); got != "v1.2.0" {
+		t.Errorf("Version(libvirt) = %q, want v1.2.0", got)
+	}
+	if got := m.Version("old"); got != "unknown" {
+		t.Errorf("Version(old) = %q, want unknown", got)
+	}
+}
+
 // TestGetProviderForResource tests the GetProviderForResource method.
 func TestGetProviderForResource(t *testing.T) {
 	m := NewManager()
@@ -477,3 +522,73 @@ func TestGetProviderForResourcePriority(t *testing.T) {
 		t.Errorf("expected first-provider (first in list), got %s", result)
 	}
 }
+
+// TestManagerCapabilities tests that Capabilities serves known capabilities
+// and RefreshCapabilities fetches them again.
+func TestManagerCapabilities(t *testing.T) {
+	client, stdinReader, stdoutWriter, cleanup := newTestClientWithRouter(t)
+	defer cleanup()
+	client.server = mcpImplementation{Name: "test", Version: "2.0.0"}
+
+	calls := 0
+	go func() {
+		scanner := bufio.NewScanner(stdinReader)
+		for scanner.Scan() {
+			var req jsonrpcRequest
+			if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+				continue
+			}
+			calls++
+			opResult, _ := json.Marshal(providerv1.SuccessResult(providerv1.CapabilitiesResponse{ProviderName: "test", Version: "2.0.0"}))
+			mcpResult, _ := json.Marshal(mcpToolCallResult{Content: []mcpContent{{Type: "text", Text: string(opResult)}}})
+			fmt.Fprintf(stdoutWriter, `{"jsonrpc":"2.0","id":%d,"result":%s}`+"\n", req.ID, mcpResult)
+		}
+	}()
+
+	m := NewManager()
+	cached := &providerv1.CapabilitiesResponse{ProviderName: "test", Version: "1.0.0"}
+	m.providers["test"] = &ProviderInfo{Client: client, Capabilities: cached, Status: StatusRunning}
+
+	if caps, err := m.Capabilities("test"); err != nil || caps != cached {
+		t.Fatalf("Capabilities() = %+v, %v, want the cached capabilities", caps, err)
+	}
+
+	caps, err := m.RefreshCapabilities("test")
+	if err != nil {
+		t.Fatalf("RefreshCapabilities() error = %v", err)
+	}
+	if caps.Version != "2.0.0" || calls != 1 {
+		t.Errorf("RefreshCapabilities() = %+v after %d calls, want version 2.0.0 after 1 call", caps, calls)
+	}
+	if info, _ := m.GetInfo("test"); info.Capabilities != caps {
+		t.Error("RefreshCapabilities() did not replace the provider capabilities")
+	}
+	if m.capabilities[capabilitiesKey(v1.ProviderConfig{}, "2.0.0")] != caps {
+		t.Error("RefreshCapabilities() did not cache the capabilities of the provider version")
+	}
+
+	if _, err := m.Capabilities("missing"); err == nil {
+		t.Error("Capabilities() of an unknown provider should fail")
+	}
+	m.RegisterCapabilities("stopped", cached)
+	if _, err := m.RefreshCapabilities("stopped"); err == nil {
+		t.Error("RefreshCapabilities() of a stopped provider should fail")
+	}
+}
+
+func TestCapabilitiesKey(t *testing.T) {
+	config := v1.ProviderConfig{Name: "libvirt", Engine: "go://libvirt", Spec: map[string]interface{}{"uri": "qemu:///system"}}
+	key := capabilitiesKey(config, "1.0.0")
+
+	other := config
+	other.Spec = map[string]interface{}{"uri": "qemu+ssh://host/system"}
+	if capabilitiesKey(other, "1.0.0") == key {
+		t.Error("a provider with another spec must not share the cached capabilities")
+	}
+	if capabilitiesKey(config, "1.1.0") == key {
+		t.Error("another provider version must not share the cached capabilities")
+	}
+	if capabilitiesKey(config, "1.0.0") != key {
+		t.Error("the same provider configuration must share the cached capabilities")
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors maps the predefined cron schedules to their expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField describes the bounds of one field of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// maxSearch bounds Next; a valid expression always fires within 5 years
+// (e.g. "0 0 29 2 *").
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Cron is a parsed cron expression: minute, hour, day of month, month and
+// day of week, in the local time of the times passed to Next.
+type Cron struct {
+	expr   string
+	fields [5]uint64
+	// domStar and dowStar record unrestricted day fields. When both day
+	// fields are restricted, a day matches if either matches.
+	domStar, dowStar bool
+}
+
+// ParseCron parses a five-field cron expression ("30 2 * * 1-5") or one of
+// @yearly, @monthly, @weekly, @daily, @midnight and @hourly. Fields accept
+// "*", values, ranges ("1-5"), lists ("1,15") and steps ("*/10", "0-30/5").
+// Sunday is 0 or 7 in the day of week field.
+func ParseCron(expr string) (*Cron, error) {
+	normalized := strings.TrimSpace(expr)
+	if d, ok := descriptors[normalized]; ok {
+		normalized = d
+	}
+	parts := strings.Fields(normalized)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(parts))
+	}
+
+	c := &Cron{expr: expr}
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		c.fields[i] = bits
+	}
+	// Sunday is both 0 and 7.
+	if c.fields[4]&(1<<7) != 0 {
+		c.fields[4] |= 1
+	}
+	c.domStar = parts[2] == "*"
+	c.dowStar = parts[4] == "*"
+	return c, nil
+}
+
+// String returns the expression as parsed.
+func (c *Cron) String() string {
+	return c.expr
+}
+
+// parseCronField parses one comma-separated field into a bit set.
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		lo, hi, step := f.min, f.max, 1
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("%s: invalid step %q", f.name, stepPart)
+			}
+			step = n
+		}
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(from, f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(to, f); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("%s: invalid range %q", f.name, rangePart)
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue parses a single value within the bounds of a field.
+func parseCronValue(s string, f cronField) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: %q is not between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time strictly after t matching the expression,
+// truncated to the minute, or the zero time if none is found.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !c.has(3, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.has(1, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !c.has(0, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// has reports whether value is set in field i.
+func (c *Cron) has(i, value int) bool {
+	return c.fields[i]&(1<<uint(value)) != 0
+}
+
+// matchDay applies the day of month and day of week fields.
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.has(2, t.Day())
+	dow := c.has(4, int(t.Weekday()))
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schedule persists schedule definitions that recreate an
+// environment from a spec on a cron expression, e.g. a nightly lab. Each
+// definition is a JSON file in paths.Layout.SchedulesDir and also records the
+// outcome of its last run.
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+// namePattern restricts schedule names, which are used in file names and
+// environment IDs.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,39}$`)
+
+// Schedule recreates an environment from a spec file on a cron expression.
+type Schedule struct {
+	// Name identifies the schedule.
+	Name string `json:"name"`
+	// Cron is the cron expression, see ParseCron.
+	Cron string `json:"cron"`
+	// SpecFile is the absolute path of the spec, read again on every run.
+	SpecFile string `json:"specFile"`
+	// Stage is passed to create as the test stage.
+	Stage string `json:"stage,omitempty"`
+	// CreatedAt is the RFC3339 time the schedule was saved first.
+	CreatedAt string `json:"createdAt,omitempty"`
+	// LastRun is the RFC3339 time of the last run.
+	LastRun string `json:"lastRun,omitempty"`
+	// EnvironmentID is the environment created by the last successful run;
+	// the next run deletes it first.
+	EnvironmentID string `json:"environmentID,omitempty"`
+	// LastError is the error of the last run, if it failed.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Validate checks the name, cron expression and spec file of a schedule.
+func (s *Schedule) Validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("invalid schedule name %q: must start with an alphanumeric character, contain only [a-zA-Z0-9._-] and be at most 40 characters", s.Name)
+	}
+	if _, err := ParseCron(s.Cron); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(s.SpecFile) {
+		return fmt.Errorf("spec file %q must be an absolute path", s.SpecFile)
+	}
+	return nil
+}
+
+// Next returns the first run time of the schedule strictly after t.
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	c, err := ParseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Next(t), nil
+}
+
+// TestID returns the forge testID of the run starting at t, e.g.
+// "nightly-20250102-0300". It is the environment ID unless the spec
+// requests one.
+func (s *Schedule) TestID(t time.Time) string {
+	return s.Name + "-" + t.UTC().Format("20060102-1504")
+}
+
+// Store persists schedules below a state directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns the store of the state directory stateDir.
+func NewStore(stateDir string) *Store {
+	return &Store{dir: paths.New(stateDir).SchedulesDir()}
+}
+
+// path returns the file of a schedule.
+func (st *Store) path(name string) string {
+	return filepath.Join(st.dir, name+".json")
+}
+
+// Save validates and writes a schedule, replacing any schedule with the same
+// name. The write is atomic.
+func (st *Store) Save(s *Schedule) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(st.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create schedules directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule %q: %w", s.Name, err)
+	}
+	tmp := st.path(s.Name) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write schedule %q: %w", s.Name, err)
+	}
+	if err := os.Rename(tmp, st.path(s.Name)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write schedule %q: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Load reads a schedule. The error wraps os.ErrNotExist if there is none.
+func (st *Store) Load(name string) (*Schedule, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid schedule name %q", name)
+	}
+	data, err := os.ReadFile(st.path(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedule %q: %w", name, err)
+	}
+	s := &Schedule{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse schedule %q: %w", name, err)
+	}
+	return s, nil
+}
+
+// List returns all schedules sorted by name.
+func (st *Store) List() ([]*Schedule, error) {
+	entries, err := os.ReadDir(st.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules directory: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	schedules := make([]*Schedule, 0, len(names))
+	for _, name := range names {
+		s, err := st.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// Delete removes a schedule. Deleting a missing schedule is not an error.
+func (st *Store) Delete(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid schedule name %q", name)
+	}
+	if err := os.Remove(st.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete schedule %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	valid := Schedule{Name: "nightly", Cron: "0 3 * * *", SpecFile: "/srv/lab/spec.yaml"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := map[string]func(s *Schedule){
+		"empty name":    func(s *Schedule) { s.Name = "" },
+		"path name":     func(s *Schedule) { s.Name = "../nightly" },
+		"invalid cron":  func(s *Schedule) { s.Cron = "nightly" },
+		"relative spec": func(s *Schedule) { s.SpecFile = "spec.yaml" },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := valid
+			mutate(&s)
+			if err := s.Validate(); err == nil {
+				t.Error("Validate() should fail")
+			}
+		})
+	}
+}
+
+func TestScheduleTestID(t *testing.T) {
+	s := Schedule{Name: "nightly"}
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	if got := s.TestID(at); got != "nightly-20250102-0204" {
+		t.Errorf("TestID() = %q", got)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	st := NewStore(dir)
+
+	if schedules, err := st.List(); err != nil || len(schedules) != 0 {
+		t.Fatalf("List() = %v, %v, want empty", schedules, err)
+	}
+
+	for _, name := range []string{"weekly", "nightly"} {
+		if err := st.Save(&Schedule{Name: name, Cron: "@daily", SpecFile: "/srv/spec.yaml"}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "schedules", "nightly.json")); err != nil {
+		t.Errorf("schedule file not written: %v", err)
+	}
+	if err := st.Save(&Schedule{Name: "broken", Cron: "never", SpecFile: "/srv/spec.yaml"}); err == nil {
+		t.Error("Save() of an invalid schedule should fail")
+	}
+
+	schedules, err := st.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(schedules) != 2 || schedules[0].Name != "nightly" || schedules[1].Name != "weekly" {
+		t.Errorf("List() = %+v, want nightly and weekly", schedules)
+	}
+
+	if err := st.Delete("weekly"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := st.Delete("weekly"); err != nil {
+		t.Errorf("Delete() of a missing schedule error = %v", err)
+	}
+	if _, err := st.Load("weekly"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load() error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secrets resolves secret references used in specs so that secret
+// values never appear in spec files, state files, or provider requests.
+//
+// A reference has the form "<scheme>:<name>":
+//   - env:NAME reads the environment variable NAME;
+//   - file:PATH reads the file at PATH, trimming one trailing newline;
+//   - keyring:NAME looks up the secret stored in the desktop keyring (Secret
+//     Service) with the attributes service=testenv-vm and name=NAME, using
+//     secret-tool.
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Supported reference schemes.
+const (
+	SchemeEnv     = "env"
+	SchemeFile    = "file"
+	SchemeKeyring = "keyring"
+)
+
+// KeyringService is the service attribute of the keyring secrets of
+// testenv-vm, e.g. stored with
+// "secret-tool store --label=... service testenv-vm name NAME".
+const KeyringService = "testenv-vm"
+
+// Ref is a parsed secret reference.
+type Ref struct {
+	// Scheme is the secret source (env, file or keyring).
+	Scheme string
+	// Name is the environment variable name, file path or keyring name.
+	Name string
+}
+
+// String returns the reference in "<scheme>:<name>" form.
+func (r Ref) String() string {
+	return r.Scheme + ":" + r.Name
+}
+
+// ParseRef parses a secret reference without resolving it.
+func ParseRef(ref string) (Ref, error) {
+	scheme, name, ok := strings.Cut(ref, ":")
+	if !ok || name == "" {
+		return Ref{}, fmt.Errorf("invalid secret reference %q: expected env:NAME, file:PATH or keyring:NAME", ref)
+	}
+	switch scheme {
+	case SchemeEnv, SchemeFile, SchemeKeyring:
+		return Ref{Scheme: scheme, Name: name}, nil
+	default:
+		return Ref{}, fmt.Errorf("invalid secret reference %q: unsupported scheme %q", ref, scheme)
+	}
+}
+
+// Resolve parses the reference and returns the secret value.
+// An empty value is an error.
+func Resolve(ref string) (string, error) {
+	r, err := ParseRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	var value string
+	switch r.Scheme {
+	case SchemeEnv:
+		value = os.Getenv(r.Name)
+		if value == "" {
+			return "", fmt.Errorf("secret %s: environment variable is not set", r)
+		}
+	case SchemeFile:
+		data, err := os.ReadFile(r.Name)
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", r, err)
+		}
+		value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		if value == "" {
+			return "", fmt.Errorf("secret %s: file is empty", r)
+		}
+	case SchemeKeyring:
+		out, err := exec.Command("secret-tool", "lookup", "service", KeyringService, "name", r.Name).Output()
+		if err != nil {
+			return "", fmt.Errorf("secret %s: keyring lookup failed: %w", r, err)
+		}
+		value = strings.TrimSuffix(string(out), "\n")
+		if value == "" {
+			return "", fmt.Errorf("secret %s: not found in keyring", r)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,112 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    Ref
+		wantErr string
+	}{
+		{ref: "env:DISK_PASS", want: Ref{Scheme: SchemeEnv, Name: "DISK_PASS"}},
+		{ref: "file:/run/secrets/disk", want: Ref{Scheme: SchemeFile, Name: "/run/secrets/disk"}},
+		{ref: "keyring:state-key", want: Ref{Scheme: SchemeKeyring, Name: "state-key"}},
+		{ref: "DISK_PASS", wantErr: "expected env:NAME, file:PATH or keyring:NAME"},
+		{ref: "env:", wantErr: "expected env:NAME, file:PATH or keyring:NAME"},
+		{ref: "vault:kv/disk", wantErr: "unsupported scheme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseRef(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseRef() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRef() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRef() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("TESTENV_SECRET_SET", "s3cret")
+	t.Setenv("TESTENV_SECRET_EMPTY", "")
+
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "pass")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fake secret-tool knows a single secret
+	secretTool := filepath.Join(dir, "secret-tool")
+	script := "#!/bin/sh\n[ \"$*\" = \"lookup service testenv-vm name state-key\" ] && echo from-keyring\n"
+	if err := os.WriteFile(secretTool, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "env:TESTENV_SECRET_SET", want: "s3cret"},
+		{ref: "env:TESTENV_SECRET_EMPTY", wantErr: "is not set"},
+		{ref: "file:" + secretFile, want: "from-file"},
+		{ref: "file:" + emptyFile, wantErr: "file is empty"},
+		{ref: "file:" + filepath.Join(dir, "missing"), wantErr: "no such file"},
+		{ref: "keyring:state-key", want: "from-keyring"},
+		{ref: "keyring:missing", wantErr: "keyring lookup failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := Resolve(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package seed derives the identifiers of an environment that would
+// otherwise be random, such as MAC addresses and VM UUIDs, from a seed
+// recorded in its state, so that a failing environment can be created again
+// with the same identifiers.
+package seed
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+// New returns a random seed: 8 bytes, hex-encoded.
+func New() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate seed: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// derive returns the SHA-256 of the seed and the parts identifying a value,
+// separated by NUL bytes so that distinct parts never collide.
+func derive(seed string, parts ...string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(seed))
+	for _, p := range parts {
+		h.Write([]byte{0})
+		h.Write([]byte(p))
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// MAC returns the MAC address of the index-th NIC of a VM, in the
+// 52:54:00 range QEMU uses.
+func MAC(seed, vm string, index int) string {
+	b := derive(seed, "mac", vm, strconv.Itoa(index))
+	return fmt.Sprintf("52:54:00:%02x:%02x:%02x", b[0], b[1], b[2])
+}
+
+// UUID returns the UUID of a VM, formatted as an RFC 9562 version 8 UUID.
+func UUID(seed, vm string) string {
+	b := derive(seed, "uuid", vm)
+	b[6] = b[6]&0x0f | 0x80
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Apply sets the UUID and the MAC addresses of a VM spec from the seed of its
+// environment. Environments recorded without a seed keep provider-assigned
+// values.
+func Apply(seed, vm string, spec *providerv1.VMSpec) {
+	if seed == "" {
+		return
+	}
+	nics := len(spec.Networks)
+	if nics == 0 && spec.Network != "" {
+		nics = 1
+	}
+	spec.UUID = UUID(seed, vm)
+	spec.MACAddresses = make([]string, nics)
+	for i := range spec.MACAddresses {
+		spec.MACAddresses[i] = MAC(seed, vm, i)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package seed
+
+import (
+	"regexp"
+	"testing"
+
+	providerv1 "github.com/alexandremahdhaoui/testenv-vm/api/provider/v1"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	b, _ := New()
+	if len(a) != 16 || a == b {
+		t.Errorf("New() = %q, %q, want distinct 16-character seeds", a, b)
+	}
+}
+
+func TestMAC(t *testing.T) {
+	mac := MAC("42", "web", 0)
+	if !regexp.MustCompile(`^52:54:00(:[0-9a-f]{2}){3}$`).MatchString(mac) {
+		t.Errorf("MAC() = %q, want a 52:54:00 address", mac)
+	}
+	if MAC("42", "web", 0) != mac {
+		t.Error("MAC() is not deterministic")
+	}
+	for _, other := range []string{MAC("43", "web", 0), MAC("42", "db", 0), MAC("42", "web", 1)} {
+		if other == mac {
+			t.Errorf("MAC() = %q for distinct inputs", other)
+		}
+	}
+}
+
+func TestUUID(t *testing.T) {
+	uuid := UUID("42", "web")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("UUID() = %q, want a version 8 UUID", uuid)
+	}
+	if UUID("42", "web") != uuid || UUID("43", "web") == uuid || UUID("42", "db") == uuid {
+		t.Error("UUID() must depend on the seed and the VM only")
+	}
+}
+
+func TestApply(t *testing.T) {
+	spec := providerv1.VMSpec{Networks: []string{"a", "b"}}
+	Apply("42", "web", &spec)
+	if spec.UUID != UUID("42", "web") || len(spec.MACAddresses) != 2 || spec.MACAddresses[1] != MAC("42", "web", 1) {
+		t.Errorf("Apply() = %q, %v", spec.UUID, spec.MACAddresses)
+	}
+
+	legacy := providerv1.VMSpec{Network: "a"}
+	Apply("42", "web", &legacy)
+	if len(legacy.MACAddresses) != 1 {
+		t.Errorf("Apply() MACs = %v, want one for the deprecated network", legacy.MACAddresses)
+	}
+
+	unseeded := providerv1.VMSpec{Network: "a"}
+	Apply("", "web", &unseeded)
+	if unseeded.UUID != "" || unseeded.MACAddresses != nil {
+		t.Errorf("Apply() without seed = %+v", unseeded)
+	}
+}
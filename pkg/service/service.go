@@ -0,0 +1,323 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package service runs helper services on the host for test environments: a
+// container registry mirror, an apt cache, an HTTP file server, an
+// S3-compatible object store and a syslog collector, bound to the gateway
+// address of a managed network so that its VMs can reach them.
+//
+// Services are started in their own session and outlive the process that
+// started them, like the environment they belong to; they are stopped by
+// process ID. Each type wraps a well-known program that must be installed
+// on the host.
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Supported service types.
+const (
+	// TypeRegistryMirror is a pull-through cache of a container registry,
+	// served by the distribution registry ("registry" binary).
+	TypeRegistryMirror = "registry-mirror"
+	// TypeAptCache is an apt package cache served by apt-cacher-ng.
+	TypeAptCache = "apt-cache"
+	// TypeHTTPFileServer serves the files of a host directory, using the
+	// http.server module of python3.
+	TypeHTTPFileServer = "http-file-server"
+	// TypeObjectStore is an S3-compatible object store served by MinIO.
+	TypeObjectStore = "object-store"
+	// TypeLogCollector receives the syslog of guests over TCP and UDP and
+	// writes it to a single file, using rsyslogd.
+	TypeLogCollector = "log-collector"
+)
+
+// DefaultUpstream is the registry mirrored by registry-mirror services.
+const DefaultUpstream = "https://registry-1.docker.io"
+
+// Timings of service processes.
+const (
+	// StartTimeout bounds the wait for a service to accept connections.
+	StartTimeout = 15 * time.Second
+	// StopTimeout is how long a service may take to exit after SIGTERM
+	// before it is killed.
+	StopTimeout = 5 * time.Second
+
+	pollInterval = 100 * time.Millisecond
+)
+
+// Types returns the supported service types.
+func Types() []string {
+	return []string{TypeRegistryMirror, TypeAptCache, TypeHTTPFileServer, TypeObjectStore, TypeLogCollector}
+}
+
+// DefaultPort returns the port a service of the given type listens on when
+// none is configured, or 0 for an unknown type.
+func DefaultPort(typ string) int {
+	switch typ {
+	case TypeRegistryMirror:
+		return 5000
+	case TypeAptCache:
+		return 3142
+	case TypeHTTPFileServer:
+		return 8080
+	case TypeObjectStore:
+		return 9000
+	case TypeLogCollector:
+		return 5140
+	}
+	return 0
+}
+
+// Config describes a service to start.
+type Config struct {
+	// Type is one of the supported service types.
+	Type string
+	// Address is the IP address the service binds.
+	Address string
+	// Port is the TCP port of the service. Zero means DefaultPort.
+	Port int
+	// Root is the directory served by http-file-server services.
+	Root string
+	// Upstream is the registry mirrored by registry-mirror services. Empty
+	// means DefaultUpstream.
+	Upstream string
+	// AccessKey and SecretKey are the credentials of object-store services.
+	AccessKey string
+	SecretKey string
+	// Output is the file receiving the logs of log-collector services.
+	Output string
+	// Dir holds the configuration and cache of the service.
+	Dir string
+	// LogPath receives the output of the service. Empty discards it.
+	LogPath string
+}
+
+// port returns the configured port or the default port of the type.
+func (c Config) port() int {
+	if c.Port != 0 {
+		return c.Port
+	}
+	return DefaultPort(c.Type)
+}
+
+// Endpoint returns the URL of the service. Log collectors are not HTTP
+// services: their endpoint uses the tcp scheme.
+func (c Config) Endpoint() string {
+	scheme := "http://"
+	if c.Type == TypeLogCollector {
+		scheme = "tcp://"
+	}
+	return scheme + net.JoinHostPort(c.Address, strconv.Itoa(c.port()))
+}
+
+// program is the command line of a service and the files it reads.
+type program struct {
+	name string
+	args []string
+	// env is added to the environment of the service.
+	env []string
+	// files are written into the service directory beforehand, keyed by
+	// name.
+	files map[string]string
+}
+
+// command returns the program running a service.
+func command(c Config) (program, error) {
+	listen := net.JoinHostPort(c.Address, strconv.Itoa(c.port()))
+	switch c.Type {
+	case TypeRegistryMirror:
+		upstream := c.Upstream
+		if upstream == "" {
+			upstream = DefaultUpstream
+		}
+		config := fmt.Sprintf(`version: 0.1
+storage:
+  filesystem:
+    rootdirectory: %s
+  delete:
+    enabled: true
+http:
+  addr: %s
+proxy:
+  remoteurl: %s
+`, filepath.Join(c.Dir, "data"), listen, upstream)
+		return program{
+			name:  "registry",
+			args:  []string{"serve", filepath.Join(c.Dir, "config.yml")},
+			files: map[string]string{"config.yml": config},
+		}, nil
+
+	case TypeAptCache:
+		return program{name: "apt-cacher-ng", args: []string{
+			"ForeGround=1",
+			"BindAddress=" + c.Address,
+			"Port=" + strconv.Itoa(c.port()),
+			"CacheDir=" + filepath.Join(c.Dir, "cache"),
+			"LogDir=" + c.Dir,
+			"PidFile=" + filepath.Join(c.Dir, "apt-cacher-ng.pid"),
+			"SocketPath=" + filepath.Join(c.Dir, "apt-cacher-ng.socket"),
+		}}, nil
+
+	case TypeHTTPFileServer:
+		if c.Root == "" {
+			return program{}, errors.New("http-file-server requires a root directory")
+		}
+		return program{
+			name: "python3",
+			args: []string{"-m", "http.server", strconv.Itoa(c.port()), "--bind", c.Address, "--directory", c.Root},
+		}, nil
+
+	case TypeObjectStore:
+		if c.AccessKey == "" || c.SecretKey == "" {
+			return program{}, errors.New("object-store requires an access key and a secret key")
+		}
+		return program{
+			name: "minio",
+			args: []string{"server", filepath.Join(c.Dir, "data"), "--address", listen, "--quiet"},
+			env:  []string{"MINIO_ROOT_USER=" + c.AccessKey, "MINIO_ROOT_PASSWORD=" + c.SecretKey},
+		}, nil
+
+	case TypeLogCollector:
+		if c.Output == "" {
+			return program{}, errors.New("log-collector requires an output file")
+		}
+		// Lines are stamped with their reception time, a single clock for
+		// all guests, so the file is ordered by time across VMs
+		config := fmt.Sprintf(`global(workDirectory="%[1]s")
+module(load="imtcp")
+module(load="imudp")
+input(type="imtcp" address="%[2]s" port="%[3]d")
+input(type="imudp" address="%[2]s" port="%[3]d")
+template(name="testenv" type="string" string="%%timegenerated:::date-rfc3339%% %%hostname%% %%syslogtag%%%%msg:::sp-if-no-1st-sp%%%%msg:::drop-last-lf%%\n")
+*.* action(type="omfile" file="%[4]s" template="testenv")
+`, c.Dir, c.Address, c.port(), c.Output)
+		return program{
+			name:  "rsyslogd",
+			args:  []string{"-n", "-f", filepath.Join(c.Dir, "rsyslog.conf"), "-i", filepath.Join(c.Dir, "rsyslogd.pid")},
+			files: map[string]string{"rsyslog.conf": config},
+		}, nil
+	}
+	return program{}, fmt.Errorf("unsupported service type %q", c.Type)
+}
+
+// NewCredentials generates random credentials for an object-store service.
+func NewCredentials() (accessKey, secretKey string, err error) {
+	buf := make([]byte, 30)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate credentials: %w", err)
+	}
+	return "testenv" + hex.EncodeToString(buf[:6]), base64.RawURLEncoding.EncodeToString(buf[6:]), nil
+}
+
+// Start starts a service and waits until it accepts connections. It returns
+// the process ID of the service, to be passed to Stop.
+func Start(c Config) (int, error) {
+	prog, err := command(c)
+	if err != nil {
+		return 0, err
+	}
+	name := prog.name
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return 0, fmt.Errorf("%s service requires %s: %w", c.Type, name, err)
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create service directory: %w", err)
+	}
+	for file, content := range prog.files {
+		if err := os.WriteFile(filepath.Join(c.Dir, file), []byte(content), 0o644); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+
+	cmd := exec.Command(path, prog.args...)
+	cmd.Dir = c.Dir
+	cmd.Env = append(os.Environ(), prog.env...)
+	// A session of its own keeps the service alive when the caller exits,
+	// and lets Stop signal its children too
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if c.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(c.LogPath), 0o755); err != nil {
+			return 0, fmt.Errorf("failed to create log directory: %w", err)
+		}
+		logFile, err := os.OpenFile(c.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open service log: %w", err)
+		}
+		defer func() { _ = logFile.Close() }()
+		cmd.Stdout, cmd.Stderr = logFile, logFile
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	listen := net.JoinHostPort(c.Address, strconv.Itoa(c.port()))
+	deadline := time.Now().Add(StartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("%s exited before accepting connections on %s: %v", name, listen, err)
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", listen, pollInterval); err == nil {
+			_ = conn.Close()
+			return cmd.Process.Pid, nil
+		}
+		if time.Now().After(deadline) {
+			_ = Stop(cmd.Process.Pid)
+			return 0, fmt.Errorf("%s did not accept connections on %s within %s", name, listen, StartTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Stop stops the service started with the given process ID, and the
+// processes it spawned. Stopping a service that already exited succeeds.
+func Stop(pid int) error {
+	if pid <= 0 {
+		return fmt.Errorf("invalid process ID %d", pid)
+	}
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return nil
+		}
+		return fmt.Errorf("failed to stop service %d: %w", pid, err)
+	}
+	deadline := time.Now().Add(StopTimeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(-pid, 0); errors.Is(err, syscall.ESRCH) {
+			return nil
+		}
+		time.Sleep(pollInterval)
+	}
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return fmt.Errorf("failed to kill service %d: %w", pid, err)
+	}
+	return nil
+}
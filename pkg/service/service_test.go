@@ -0,0 +1,189 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package service
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	dir := "/state/envs/abc/services/svc"
+
+	prog, err := command(Config{Type: TypeRegistryMirror, Address: "10.0.0.1", Dir: dir})
+	if err != nil {
+		t.Fatalf("command(registry-mirror) error = %v", err)
+	}
+	if prog.name != "registry" || strings.Join(prog.args, " ") != "serve "+dir+"/config.yml" {
+		t.Errorf("command(registry-mirror) = %s %q", prog.name, prog.args)
+	}
+	for _, want := range []string{"addr: 10.0.0.1:5000", "remoteurl: " + DefaultUpstream, "rootdirectory: " + dir + "/data"} {
+		if !strings.Contains(prog.files["config.yml"], want) {
+			t.Errorf("registry config should contain %q, got:\n%s", want, prog.files["config.yml"])
+		}
+	}
+
+	prog, err = command(Config{Type: TypeAptCache, Address: "10.0.0.1", Port: 3000, Dir: dir})
+	if err != nil {
+		t.Fatalf("command(apt-cache) error = %v", err)
+	}
+	joined := strings.Join(prog.args, " ")
+	if prog.name != "apt-cacher-ng" || !strings.Contains(joined, "BindAddress=10.0.0.1") || !strings.Contains(joined, "Port=3000") || !strings.Contains(joined, "CacheDir="+dir+"/cache") {
+		t.Errorf("command(apt-cache) = %s %q", prog.name, prog.args)
+	}
+
+	prog, err = command(Config{Type: TypeHTTPFileServer, Address: "10.0.0.1", Root: "/srv/files", Dir: dir})
+	if err != nil {
+		t.Fatalf("command(http-file-server) error = %v", err)
+	}
+	if want := "-m http.server 8080 --bind 10.0.0.1 --directory /srv/files"; prog.name != "python3" || strings.Join(prog.args, " ") != want {
+		t.Errorf("command(http-file-server) = %s %q, want python3 %s", prog.name, prog.args, want)
+	}
+
+	prog, err = command(Config{Type: TypeObjectStore, Address: "10.0.0.1", AccessKey: "user", SecretKey: "password", Dir: dir})
+	if err != nil {
+		t.Fatalf("command(object-store) error = %v", err)
+	}
+	if want := "server " + dir + "/data --address 10.0.0.1:9000 --quiet"; prog.name != "minio" || strings.Join(prog.args, " ") != want {
+		t.Errorf("command(object-store) = %s %q, want minio %s", prog.name, prog.args, want)
+	}
+	if want := "MINIO_ROOT_USER=user MINIO_ROOT_PASSWORD=password"; strings.Join(prog.env, " ") != want {
+		t.Errorf("command(object-store) env = %q, want %s", prog.env, want)
+	}
+
+	prog, err = command(Config{Type: TypeLogCollector, Address: "10.0.0.1", Output: "/artifacts/guest-logs/logs.log", Dir: dir})
+	if err != nil {
+		t.Fatalf("command(log-collector) error = %v", err)
+	}
+	if want := "-n -f " + dir + "/rsyslog.conf -i " + dir + "/rsyslogd.pid"; prog.name != "rsyslogd" || strings.Join(prog.args, " ") != want {
+		t.Errorf("command(log-collector) = %s %q, want rsyslogd %s", prog.name, prog.args, want)
+	}
+	for _, want := range []string{
+		`input(type="imtcp" address="10.0.0.1" port="5140")`,
+		`input(type="imudp" address="10.0.0.1" port="5140")`,
+		`string="%timegenerated:::date-rfc3339% %hostname% %syslogtag%`,
+		`file="/artifacts/guest-logs/logs.log"`,
+	} {
+		if !strings.Contains(prog.files["rsyslog.conf"], want) {
+			t.Errorf("rsyslog config should contain %q, got:\n%s", want, prog.files["rsyslog.conf"])
+		}
+	}
+
+	if _, err := command(Config{Type: TypeHTTPFileServer, Address: "10.0.0.1"}); err == nil {
+		t.Error("command(http-file-server) expected error without root")
+	}
+	if _, err := command(Config{Type: TypeLogCollector, Address: "10.0.0.1"}); err == nil {
+		t.Error("command(log-collector) expected error without output")
+	}
+	if _, err := command(Config{Type: TypeObjectStore, Address: "10.0.0.1"}); err == nil {
+		t.Error("command(object-store) expected error without credentials")
+	}
+	if _, err := command(Config{Type: "ftp", Address: "10.0.0.1"}); err == nil {
+		t.Error("command() expected error for an unsupported type")
+	}
+}
+
+func TestNewCredentials(t *testing.T) {
+	accessKey, secretKey, err := NewCredentials()
+	if err != nil {
+		t.Fatalf("NewCredentials() error = %v", err)
+	}
+	// MinIO requires at least 3 characters for the user and 8 for the password.
+	if len(accessKey) < 3 || len(secretKey) < 8 {
+		t.Errorf("NewCredentials() = %q, %q, too short", accessKey, secretKey)
+	}
+	other, _, _ := NewCredentials()
+	if other == accessKey {
+		t.Error("NewCredentials() should not repeat")
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	if got := (Config{Type: TypeAptCache, Address: "10.0.0.1"}).Endpoint(); got != "http://10.0.0.1:3142" {
+		t.Errorf("Endpoint() = %q, want the default port", got)
+	}
+	if got := (Config{Type: TypeRegistryMirror, Address: "fd00::1", Port: 5001}).Endpoint(); got != "http://[fd00::1]:5001" {
+		t.Errorf("Endpoint() = %q", got)
+	}
+	if got := (Config{Type: TypeLogCollector, Address: "10.0.0.1"}).Endpoint(); got != "tcp://10.0.0.1:5140" {
+		t.Errorf("Endpoint() = %q, want the tcp scheme", got)
+	}
+}
+
+// freePort returns a TCP port of the loopback address nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestStartStop(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available")
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "fixture.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	c := Config{Type: TypeHTTPFileServer, Address: "127.0.0.1", Port: freePort(t), Root: root, Dir: dir, LogPath: filepath.Join(dir, "logs", "files.log")}
+
+	pid, err := Start(c)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	resp, err := http.Get(c.Endpoint() + "/fixture.txt")
+	if err != nil {
+		_ = Stop(pid)
+		t.Fatalf("GET fixture: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("GET fixture = %q, want hello", body)
+	}
+
+	if err := Stop(pid); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if conn, err := net.Dial("tcp", net.JoinHostPort(c.Address, strconv.Itoa(c.Port))); err == nil {
+		_ = conn.Close()
+		t.Error("service still accepts connections after Stop()")
+	}
+	if err := Stop(pid); err != nil {
+		t.Errorf("Stop() of a stopped service error = %v", err)
+	}
+}
+
+func TestStart_MissingProgram(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := Start(Config{Type: TypeAptCache, Address: "127.0.0.1", Dir: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "requires apt-cacher-ng") {
+		t.Errorf("Start() error = %v, want missing program", err)
+	}
+}
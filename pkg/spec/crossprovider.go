@@ -0,0 +1,249 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spec provides parsing, validation, and template rendering for
+// testenv-vm specifications.
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// templateFieldRefPattern matches {{ .Keys.name.Field }}-style references and
+// captures the category, resource name, and field.
+var templateFieldRefPattern = regexp.MustCompile(`\{\{\s*\.(Keys|Networks|VMs)\.([^.}\s]+)\.(\w+)`)
+
+// templateDataTypes maps a resource kind to the template data type exposed for it.
+var templateDataTypes = map[string]reflect.Type{
+	"key":     reflect.TypeOf(KeyTemplateData{}),
+	"network": reflect.TypeOf(NetworkTemplateData{}),
+	"vm":      reflect.TypeOf(VMTemplateData{}),
+}
+
+// providerLocalFields lists template fields that identify a resource inside
+// the provider that created it. They are meaningless to any other provider,
+// so cross-provider references to them are rejected.
+var providerLocalFields = map[string]map[string]bool{
+	"network": {"Name": true, "UUID": true},
+}
+
+// CrossProviderRef is a template reference from a resource managed by one
+// provider to an output of a resource managed by another provider, e.g. a
+// cloud VM consuming the public key of a locally generated key.
+type CrossProviderRef struct {
+	// Consumer is the resource whose spec contains the template reference.
+	Consumer v1.ResourceRef
+	// Producer is the referenced resource.
+	Producer v1.ResourceRef
+	// Field is the referenced template field (e.g. "PublicKey").
+	Field string
+}
+
+// String formats the reference for logs and error messages.
+func (r CrossProviderRef) String() string {
+	return fmt.Sprintf("%s %q (provider %q) -> %s %q (provider %q).%s",
+		r.Consumer.Kind, r.Consumer.Name, r.Consumer.Provider,
+		r.Producer.Kind, r.Producer.Name, r.Producer.Provider, r.Field)
+}
+
+// ResolveResourceProvider returns the provider that manages a resource whose
+// spec declares the given provider. Empty values fall back to
+// spec.DefaultProvider, then to the provider marked default, then to the only
+// provider. It returns "" when no provider can be determined.
+func ResolveResourceProvider(spec *v1.Spec, provider string) string {
+	if provider != "" {
+		return provider
+	}
+	if spec.DefaultProvider != "" {
+		return spec.DefaultProvider
+	}
+	for _, p := range spec.Providers {
+		if p.Default {
+			return p.Name
+		}
+	}
+	if len(spec.Providers) == 1 {
+		return spec.Providers[0].Name
+	}
+	return ""
+}
+
+// FindCrossProviderRefs returns every template reference whose consumer and
+// producer are managed by different providers. Image references are never
+// cross-provider since images are handled by the orchestrator.
+func FindCrossProviderRefs(spec *v1.Spec) []CrossProviderRef {
+	owners := resourceProviders(spec)
+
+	var result []CrossProviderRef
+	collect := func(consumer v1.ResourceRef, resource interface{}) {
+		for _, ref := range extractTemplateFieldRefs(resource) {
+			producerProvider, ok := owners[ref.Producer.Kind+":"+ref.Producer.Name]
+			if !ok || producerProvider == "" || consumer.Provider == "" || producerProvider == consumer.Provider {
+				continue
+			}
+			ref.Consumer = consumer
+			ref.Producer.Provider = producerProvider
+			result = append(result, ref)
+		}
+	}
+
+	for _, k := range spec.Keys {
+		collect(v1.ResourceRef{Kind: "key", Name: k.Name, Provider: owners["key:"+k.Name]}, k)
+	}
+	for _, n := range spec.Networks {
+		collect(v1.ResourceRef{Kind: "network", Name: n.Name, Provider: owners["network:"+n.Name]}, n)
+	}
+	for _, vm := range spec.Vms {
+		collect(v1.ResourceRef{Kind: "vm", Name: vm.Name, Provider: owners["vm:"+vm.Name]}, vm)
+	}
+
+	return result
+}
+
+// validateCrossProviderRefs verifies references that cross provider
+// boundaries. Values (public keys, IPs, CIDRs, ...) may flow between
+// providers through the template context, but:
+//   - the referenced field must exist, since a typo would otherwise only
+//     surface after the producer was created;
+//   - provider-local identifiers (network names and UUIDs) cannot be consumed
+//     by another provider;
+//   - literal vm.network(s) references must stay within a single provider,
+//     because the VM provider looks networks up in its own inventory.
+//
+// network.attachTo may cross providers: it names a host-level interface.
+func validateCrossProviderRefs(spec *v1.Spec) error {
+	var is issues
+	checkCrossProviderRefs(&is, spec)
+	return is.err()
+}
+
+// checkCrossProviderRefs reports every reference validateCrossProviderRefs
+// fails on.
+func checkCrossProviderRefs(is *issues, spec *v1.Spec) {
+	for _, ref := range FindCrossProviderRefs(spec) {
+		dataType, ok := templateDataTypes[ref.Producer.Kind]
+		if !ok {
+			continue
+		}
+		path := resourcePath(spec, ref.Consumer.Kind, ref.Consumer.Name)
+		if _, exists := dataType.FieldByName(ref.Field); !exists {
+			is.errorf(path, CodeReference, "%s %q: cross-provider reference to unknown field %q of %s %q",
+				ref.Consumer.Kind, ref.Consumer.Name, ref.Field, ref.Producer.Kind, ref.Producer.Name)
+			continue
+		}
+		if providerLocalFields[ref.Producer.Kind][ref.Field] {
+			is.errorf(path, CodeReference, "%s %q: field %q of %s %q is local to provider %q and cannot be used by provider %q",
+				ref.Consumer.Kind, ref.Consumer.Name, ref.Field, ref.Producer.Kind, ref.Producer.Name,
+				ref.Producer.Provider, ref.Consumer.Provider)
+		}
+	}
+
+	owners := resourceProviders(spec)
+	for i, vm := range spec.Vms {
+		netNames := vm.Spec.Networks
+		if len(netNames) == 0 && vm.Spec.Network != "" {
+			netNames = []string{vm.Spec.Network}
+		}
+		for _, netName := range netNames {
+			if netName == "" || IsTemplated(netName) {
+				continue
+			}
+			vmProvider := owners["vm:"+vm.Name]
+			networkProvider, ok := owners["network:"+netName]
+			if !ok || vmProvider == "" || networkProvider == "" || vmProvider == networkProvider {
+				continue
+			}
+			is.errorf(fmt.Sprintf("vms[%d].spec.networks", i), CodeReference, "vm %q (provider %q) references network %q managed by provider %q; "+
+				"VMs can only attach to networks of their own provider",
+				vm.Name, vmProvider, netName, networkProvider)
+		}
+	}
+}
+
+// resourceProviders maps "kind:name" to the resolved provider of every key,
+// network, and VM in the spec.
+func resourceProviders(spec *v1.Spec) map[string]string {
+	owners := make(map[string]string, len(spec.Keys)+len(spec.Networks)+len(spec.Vms))
+	for _, k := range spec.Keys {
+		owners["key:"+k.Name] = ResolveResourceProvider(spec, k.Provider)
+	}
+	for _, n := range spec.Networks {
+		owners["network:"+n.Name] = ResolveResourceProvider(spec, n.Provider)
+	}
+	for _, vm := range spec.Vms {
+		owners["vm:"+vm.Name] = ResolveResourceProvider(spec, vm.Provider)
+	}
+	return owners
+}
+
+// extractTemplateFieldRefs returns the field-level template references found
+// in a resource. Only Producer.Kind, Producer.Name, and Field are set.
+func extractTemplateFieldRefs(resource interface{}) []CrossProviderRef {
+	var refs []CrossProviderRef
+	seen := make(map[string]bool)
+
+	walkStrings(reflect.ValueOf(resource), func(s string) {
+		for _, match := range templateFieldRefPattern.FindAllStringSubmatch(s, -1) {
+			var kind string
+			switch match[1] {
+			case "Keys":
+				kind = "key"
+			case "Networks":
+				kind = "network"
+			case "VMs":
+				kind = "vm"
+			}
+			key := kind + ":" + match[2] + "." + match[3]
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			refs = append(refs, CrossProviderRef{
+				Producer: v1.ResourceRef{Kind: kind, Name: match[2]},
+				Field:    match[3],
+			})
+		}
+	})
+
+	return refs
+}
+
+// walkStrings calls fn for every string reachable from v.
+func walkStrings(v reflect.Value, fn func(string)) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), fn)
+		}
+	case reflect.String:
+		fn(v.String())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			walkStrings(v.Field(i), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkStrings(iter.Value(), fn)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// hybridSpec returns a spec where a cloud VM consumes a key generated locally.
+func hybridSpec(userKey string) *v1.Spec {
+	return &v1.Spec{
+		Providers: []v1.ProviderConfig{
+			{Name: "local", Engine: "go://local", Default: true},
+			{Name: "aws", Engine: "go://aws"},
+		},
+		Keys: []v1.KeyResource{
+			{Name: "deploy-key", Spec: v1.KeySpec{Type: "ed25519"}},
+		},
+		Networks: []v1.NetworkResource{
+			{Name: "vpc", Kind: "vpc", Provider: "aws"},
+			{Name: "lab", Kind: "nat", Spec: v1.NetworkSpec{Cidr: "192.168.50.0/24"}},
+		},
+		Vms: []v1.VMResource{
+			{
+				Name:     "cloud-vm",
+				Provider: "aws",
+				Spec: v1.VMSpec{
+					Memory:  1024,
+					Vcpus:   1,
+					Network: "vpc",
+					CloudInit: v1.CloudInitSpec{
+						Users: []v1.UserSpec{{Name: "ci", SshAuthorizedKeys: []string{userKey}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestResolveResourceProvider(t *testing.T) {
+	spec := &v1.Spec{Providers: []v1.ProviderConfig{{Name: "a"}, {Name: "b", Default: true}}}
+	if got := ResolveResourceProvider(spec, "a"); got != "a" {
+		t.Errorf("explicit provider = %q, want a", got)
+	}
+	if got := ResolveResourceProvider(spec, ""); got != "b" {
+		t.Errorf("default-marked provider = %q, want b", got)
+	}
+	spec.DefaultProvider = "a"
+	if got := ResolveResourceProvider(spec, ""); got != "a" {
+		t.Errorf("defaultProvider = %q, want a", got)
+	}
+	if got := ResolveResourceProvider(&v1.Spec{Providers: []v1.ProviderConfig{{Name: "only"}}}, ""); got != "only" {
+		t.Errorf("single provider = %q, want only", got)
+	}
+}
+
+func TestFindCrossProviderRefs(t *testing.T) {
+	spec := hybridSpec("{{ .Keys.deploy-key.PublicKey }}")
+	spec.Vms = append(spec.Vms, v1.VMResource{
+		Name: "local-vm",
+		Spec: v1.VMSpec{
+			Memory:  1024,
+			Vcpus:   1,
+			Network: "lab",
+			CloudInit: v1.CloudInitSpec{
+				Users: []v1.UserSpec{{Name: "ci", SshAuthorizedKeys: []string{"{{ .Keys.deploy-key.PublicKey }}"}}},
+			},
+		},
+	})
+
+	refs := FindCrossProviderRefs(spec)
+	if len(refs) != 1 {
+		t.Fatalf("FindCrossProviderRefs() = %v, want exactly the cloud VM reference", refs)
+	}
+	ref := refs[0]
+	if ref.Consumer.Name != "cloud-vm" || ref.Consumer.Provider != "aws" {
+		t.Errorf("Consumer = %+v", ref.Consumer)
+	}
+	if ref.Producer.Kind != "key" || ref.Producer.Name != "deploy-key" || ref.Producer.Provider != "local" {
+		t.Errorf("Producer = %+v", ref.Producer)
+	}
+	if ref.Field != "PublicKey" {
+		t.Errorf("Field = %q, want PublicKey", ref.Field)
+	}
+}
+
+func TestValidate_CrossProviderRefs(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      *v1.Spec
+		errSubstr string
+	}{
+		{
+			name: "value reference across providers passes",
+			spec: hybridSpec("{{ .Keys.deploy-key.PublicKey }}"),
+		},
+		{
+			name:      "unknown field fails",
+			spec:      hybridSpec("{{ .Keys.deploy-key.PubKey }}"),
+			errSubstr: `cross-provider reference to unknown field "PubKey"`,
+		},
+		{
+			name: "provider-local network identifier fails",
+			spec: func() *v1.Spec {
+				s := hybridSpec("{{ .Keys.deploy-key.PublicKey }}")
+				s.Vms[0].Spec.CloudInit.Hostname = "{{ .Networks.lab.UUID }}"
+				return s
+			}(),
+			errSubstr: `is local to provider "local"`,
+		},
+		{
+			name: "network CIDR across providers passes",
+			spec: func() *v1.Spec {
+				s := hybridSpec("{{ .Keys.deploy-key.PublicKey }}")
+				s.Vms[0].Spec.CloudInit.Runcmd = []string{"ip route add {{ .Networks.lab.CIDR }} dev wg0"}
+				return s
+			}(),
+		},
+		{
+			name: "VM attached to another provider's network fails",
+			spec: func() *v1.Spec {
+				s := hybridSpec("{{ .Keys.deploy-key.PublicKey }}")
+				s.Vms[0].Spec.Network = "lab"
+				return s
+			}(),
+			errSubstr: `VMs can only attach to networks of their own provider`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.spec)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("Validate() error = %v, want containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
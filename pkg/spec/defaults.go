@@ -0,0 +1,74 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"slices"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ApplyDefaults fills unset VM and network fields from spec.Defaults. Values
+// set on a resource always take precedence. Readiness checks are defaulted
+// one by one: a VM that configures its own ssh, tcp, or cloudInit check keeps
+// it. ApplyDefaults is idempotent and is called by ValidateEarly.
+func ApplyDefaults(spec *v1.Spec) {
+	vmDefaults := spec.Defaults.Vm
+	for i := range spec.Vms {
+		vm := &spec.Vms[i].Spec
+		if vm.Memory == 0 {
+			vm.Memory = vmDefaults.Memory
+		}
+		if vm.Vcpus == 0 {
+			vm.Vcpus = vmDefaults.Vcpus
+		}
+		if vm.Disk.Size == "" {
+			vm.Disk.Size = vmDefaults.Disk.Size
+		}
+		if vm.Disk.BaseImage == "" {
+			vm.Disk.BaseImage = vmDefaults.Disk.BaseImage
+		}
+		if vm.Readiness.Ssh == (v1.SSHReadinessSpec{}) {
+			vm.Readiness.Ssh = vmDefaults.Readiness.Ssh
+		}
+		if vm.Readiness.Tcp == (v1.TCPReadinessSpec{}) {
+			vm.Readiness.Tcp = vmDefaults.Readiness.Tcp
+		}
+		if vm.Readiness.CloudInit == (v1.CloudInitReadinessSpec{}) {
+			vm.Readiness.CloudInit = vmDefaults.Readiness.CloudInit
+		}
+	}
+
+	netDefaults := spec.Defaults.Network
+	for i := range spec.Networks {
+		network := &spec.Networks[i]
+		if network.Kind == "" {
+			network.Kind = netDefaults.Kind
+		}
+		if network.Spec.Mtu == 0 {
+			network.Spec.Mtu = netDefaults.Mtu
+		}
+		if network.Spec.Dhcp == nil && netDefaults.Dhcp != nil {
+			dhcp := *netDefaults.Dhcp
+			dhcp.DnsServers = slices.Clone(dhcp.DnsServers)
+			network.Spec.Dhcp = &dhcp
+		}
+		if network.Spec.Dns == nil && netDefaults.Dns != nil {
+			dns := *netDefaults.Dns
+			dns.Servers = slices.Clone(dns.Servers)
+			network.Spec.Dns = &dns
+		}
+	}
+}
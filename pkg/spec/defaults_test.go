@@ -0,0 +1,102 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	s := &v1.Spec{
+		Defaults: v1.DefaultsSpec{
+			Vm: v1.VMDefaultsSpec{
+				Memory: 2048,
+				Vcpus:  2,
+				Disk:   v1.DiskDefaultsSpec{Size: "20G", BaseImage: "ubuntu:24.04"},
+				Readiness: v1.ReadinessSpec{
+					Ssh:       v1.SSHReadinessSpec{Enabled: true, User: "ubuntu", Timeout: "5m"},
+					CloudInit: v1.CloudInitReadinessSpec{Enabled: true},
+				},
+			},
+			Network: v1.NetworkDefaultsSpec{
+				Kind: "bridge",
+				Mtu:  9000,
+				Dns:  &v1.DNSSpec{Enabled: true, Servers: []string{"1.1.1.1"}},
+			},
+		},
+		Vms: []v1.VMResource{
+			{Name: "plain"},
+			{Name: "custom", Spec: v1.VMSpec{
+				Memory:    8192,
+				Disk:      v1.DiskSpec{Size: "50G"},
+				Readiness: v1.ReadinessSpec{Ssh: v1.SSHReadinessSpec{Enabled: true, User: "root"}},
+			}},
+		},
+		Networks: []v1.NetworkResource{
+			{Name: "a"},
+			{Name: "b", Kind: "libvirt", Spec: v1.NetworkSpec{Mtu: 1500, Dns: &v1.DNSSpec{Enabled: false}}},
+		},
+	}
+
+	ApplyDefaults(s)
+
+	plain := s.Vms[0].Spec
+	if plain.Memory != 2048 || plain.Vcpus != 2 || plain.Disk.Size != "20G" || plain.Disk.BaseImage != "ubuntu:24.04" {
+		t.Errorf("plain VM not defaulted: %+v", plain)
+	}
+	if plain.Readiness.Ssh.User != "ubuntu" || !plain.Readiness.CloudInit.Enabled {
+		t.Errorf("plain VM readiness not defaulted: %+v", plain.Readiness)
+	}
+
+	custom := s.Vms[1].Spec
+	if custom.Memory != 8192 || custom.Vcpus != 2 || custom.Disk.Size != "50G" {
+		t.Errorf("custom VM overrides lost: %+v", custom)
+	}
+	if custom.Readiness.Ssh.User != "root" || custom.Readiness.Ssh.Timeout != "" {
+		t.Errorf("custom VM ssh readiness should be kept as is, got %+v", custom.Readiness.Ssh)
+	}
+	if !custom.Readiness.CloudInit.Enabled {
+		t.Errorf("custom VM cloud-init readiness not defaulted: %+v", custom.Readiness.CloudInit)
+	}
+
+	a, b := s.Networks[0], s.Networks[1]
+	if a.Kind != "bridge" || a.Spec.Mtu != 9000 || a.Spec.Dns == nil || !a.Spec.Dns.Enabled {
+		t.Errorf("network a not defaulted: %+v", a)
+	}
+	if a.Spec.Dns == s.Defaults.Network.Dns {
+		t.Error("network a should not share the default DNS spec")
+	}
+	if b.Kind != "libvirt" || b.Spec.Mtu != 1500 || b.Spec.Dns.Enabled {
+		t.Errorf("network b overrides lost: %+v", b)
+	}
+}
+
+func TestValidateEarlyAppliesDefaults(t *testing.T) {
+	s := &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "stub", Engine: "go://stub", Default: true}},
+		Defaults:  v1.DefaultsSpec{Vm: v1.VMDefaultsSpec{Memory: 1024, Vcpus: 1}},
+		Vms:       []v1.VMResource{{Name: "vm1", Spec: v1.VMSpec{Disk: v1.DiskSpec{Size: "10G"}}}},
+	}
+	if _, err := ValidateEarly(s); err != nil {
+		t.Fatalf("ValidateEarly failed: %v", err)
+	}
+	if s.Vms[0].Spec.Memory != 1024 || s.Vms[0].Spec.Vcpus != 1 {
+		t.Errorf("defaults not applied: %+v", s.Vms[0].Spec)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"regexp"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// MaxDataDisks is the number of data disks a VM may have: they take the
+// virtio-blk targets vdb to vdz.
+const MaxDataDisks = 24
+
+// Data disk formats.
+const (
+	DiskFormatQCOW2 = "qcow2"
+	DiskFormatRaw   = "raw"
+)
+
+// diskSizePattern matches the sizes qemu-img accepts, such as 512M or 100G.
+var diskSizePattern = regexp.MustCompile(`^[1-9][0-9]*[KMGT]?$`)
+
+// ValidateDataDisks validates the data disks of VMs. It ensures:
+// - A VM has at most MaxDataDisks data disks
+// - Each disk has a valid size
+// - The format is qcow2 or raw
+func ValidateDataDisks(vms []v1.VMResource) error {
+	var is issues
+	checkDataDisks(&is, vms)
+	return is.err()
+}
+
+// checkDataDisks reports every problem ValidateDataDisks fails on.
+func checkDataDisks(is *issues, vms []v1.VMResource) {
+	for i, vm := range vms {
+		if len(vm.Spec.Disks) > MaxDataDisks {
+			is.errorf(fmt.Sprintf("vms[%d].spec.disks", i), CodeInvalid,
+				"vm %q: at most %d data disks are supported (got %d)", vm.Name, MaxDataDisks, len(vm.Spec.Disks))
+		}
+		for j, d := range vm.Spec.Disks {
+			path := fmt.Sprintf("vms[%d].spec.disks[%d]", i, j)
+			switch {
+			case d.Size == "":
+				is.errorf(path+".size", CodeRequired, "vm %q: disks[%d].size is required", vm.Name, j)
+			case !diskSizePattern.MatchString(d.Size) && !IsTemplated(d.Size):
+				is.errorf(path+".size", CodeInvalid, "vm %q: disks[%d].size %q must be a number with an optional K, M, G or T suffix", vm.Name, j, d.Size)
+			}
+			if d.Format != "" && d.Format != DiskFormatQCOW2 && d.Format != DiskFormatRaw {
+				is.errorf(path+".format", CodeInvalid, "vm %q: disks[%d].format must be qcow2 or raw (got %q)", vm.Name, j, d.Format)
+			}
+		}
+	}
+}
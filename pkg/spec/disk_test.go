@@ -0,0 +1,72 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateDataDisks(t *testing.T) {
+	tests := []struct {
+		name      string
+		disks     []v1.DataDiskSpec
+		errSubstr string
+	}{
+		{name: "no data disks passes"},
+		{
+			name:  "qcow2 and raw disks pass",
+			disks: []v1.DataDiskSpec{{Size: "100G"}, {Size: "512M", Format: "raw", BaseImage: "/images/seed.img"}},
+		},
+		{
+			name:      "missing size fails",
+			disks:     []v1.DataDiskSpec{{Format: "raw"}},
+			errSubstr: "disks[0].size is required",
+		},
+		{
+			name:      "invalid size fails",
+			disks:     []v1.DataDiskSpec{{Size: "10GB"}},
+			errSubstr: `disks[0].size "10GB" must be a number`,
+		},
+		{
+			name:      "unknown format fails",
+			disks:     []v1.DataDiskSpec{{Size: "10G", Format: "vmdk"}},
+			errSubstr: `disks[0].format must be qcow2 or raw (got "vmdk")`,
+		},
+		{
+			name:      "too many disks fail",
+			disks:     make([]v1.DataDiskSpec, MaxDataDisks+1),
+			errSubstr: "at most 24 data disks are supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDataDisks([]v1.VMResource{{Name: "osd", Spec: v1.VMSpec{Disks: tt.disks}}})
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateDataDisks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateDataDisks() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
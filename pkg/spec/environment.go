@@ -0,0 +1,177 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/secrets"
+)
+
+const (
+	// GuestEnvironmentFile is where cloudInit.environment is staged in the guest.
+	GuestEnvironmentFile = "/etc/testenv-vm/environment"
+	// GuestEnvironmentCommand appends the staged variables to /etc/environment,
+	// which pam_env applies to every login and SSH session.
+	GuestEnvironmentCommand = "cat " + GuestEnvironmentFile + " >> /etc/environment"
+	// GuestSecretEnvironmentFile is where cloudInit.secretEnvironment is
+	// staged in the guest. It is root-only and never reaches /etc/environment,
+	// which every guest user can read.
+	GuestSecretEnvironmentFile = "/etc/testenv-vm/secret-environment"
+	// redactedValue replaces secret values in logs.
+	redactedValue = "<redacted>"
+)
+
+// envKeyPattern matches portable environment variable names.
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// GuestEnvironment is the rendered content of cloudInit.environment and
+// cloudInit.secretEnvironment.
+type GuestEnvironment struct {
+	// Content is the /etc/environment fragment (KEY="value" lines) of the
+	// plain variables.
+	Content string
+	// Secret is the ~/.ssh/environment content (KEY=value lines) of the
+	// secret variables.
+	Secret string
+	// SecretKeys are the sorted names of the secret variables.
+	SecretKeys []string
+	// Redacted lists every variable with secret values replaced, safe to log.
+	Redacted string
+}
+
+// GuestSecretEnvironmentCommands returns the commands that hand the staged
+// secrets to their consumers: the file is copied, mode 0600, to the
+// ~/.ssh/environment of root and of each user (the uid 1000 default user
+// when users is empty), and sshd is allowed to apply exactly the secret
+// variables from it to the SSH sessions of these users.
+func GuestSecretEnvironmentCommands(env GuestEnvironment, users []string) []string {
+	consumers := "root $(getent passwd 1000 | cut -d: -f1)"
+	if len(users) > 0 {
+		quoted := make([]string, 0, len(users))
+		for _, user := range users {
+			quoted = append(quoted, shellQuote(user))
+		}
+		consumers = "root " + strings.Join(quoted, " ")
+	}
+	return []string{
+		"for u in " + consumers + "; do " +
+			`h=$(getent passwd "$u" | cut -d: -f6) && [ -n "$h" ] && ` +
+			`install -d -m 0700 -o "$u" "$h/.ssh" && ` +
+			`install -m 0600 -o "$u" ` + GuestSecretEnvironmentFile + ` "$h/.ssh/environment"; done`,
+		"sed -i '1i PermitUserEnvironment " + strings.Join(env.SecretKeys, ",") + "' /etc/ssh/sshd_config",
+		"systemctl reload ssh || systemctl reload sshd || rc-service sshd reload || true",
+	}
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// validateCloudInitEnvironment checks variable names, secret reference syntax,
+// and that no variable is declared as both plain and secret.
+func validateCloudInitEnvironment(ci v1.CloudInitSpec) error {
+	for key, value := range ci.Environment {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("cloudInit.environment: invalid variable name %q", key)
+		}
+		if !IsTemplated(value) {
+			if err := validateEnvValue(key, value); err != nil {
+				return fmt.Errorf("cloudInit.environment: %w", err)
+			}
+		}
+	}
+	for key, ref := range ci.SecretEnvironment {
+		if !envKeyPattern.MatchString(key) {
+			return fmt.Errorf("cloudInit.secretEnvironment: invalid variable name %q", key)
+		}
+		if _, ok := ci.Environment[key]; ok {
+			return fmt.Errorf("cloudInit: variable %q is declared in both environment and secretEnvironment", key)
+		}
+		if IsTemplated(ref) {
+			continue
+		}
+		if _, err := secrets.ParseRef(ref); err != nil {
+			return fmt.Errorf("cloudInit.secretEnvironment %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// validateEnvValue rejects values that cannot be represented in
+// /etc/environment. The value itself is never included in the error.
+func validateEnvValue(key, value string) error {
+	if strings.ContainsAny(value, "\"\n\r\x00") {
+		return fmt.Errorf("value of %q must not contain double quotes, newlines or NUL bytes", key)
+	}
+	return nil
+}
+
+// RenderGuestEnvironment resolves secret references and renders the
+// variables of a rendered cloud-init spec. It returns an empty
+// GuestEnvironment when no variables are declared.
+func RenderGuestEnvironment(ci v1.CloudInitSpec) (GuestEnvironment, error) {
+	if err := validateCloudInitEnvironment(ci); err != nil {
+		return GuestEnvironment{}, err
+	}
+
+	values := make(map[string]string, len(ci.Environment)+len(ci.SecretEnvironment))
+	secret := make(map[string]bool, len(ci.SecretEnvironment))
+	for key, value := range ci.Environment {
+		values[key] = value
+	}
+	for key, ref := range ci.SecretEnvironment {
+		value, err := secrets.Resolve(ref)
+		if err != nil {
+			return GuestEnvironment{}, fmt.Errorf("cloudInit.secretEnvironment %q: %w", key, err)
+		}
+		values[key] = value
+		secret[key] = true
+	}
+	if len(values) == 0 {
+		return GuestEnvironment{}, nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var env GuestEnvironment
+	var content, secretContent, redacted strings.Builder
+	for _, key := range keys {
+		if err := validateEnvValue(key, values[key]); err != nil {
+			return GuestEnvironment{}, fmt.Errorf("cloudInit: %w", err)
+		}
+		if secret[key] {
+			fmt.Fprintf(&secretContent, "%s=%s\n", key, values[key])
+			fmt.Fprintf(&redacted, "%s=\"%s\"\n", key, redactedValue)
+			env.SecretKeys = append(env.SecretKeys, key)
+		} else {
+			fmt.Fprintf(&content, "%s=\"%s\"\n", key, values[key])
+			fmt.Fprintf(&redacted, "%s=\"%s\"\n", key, values[key])
+		}
+	}
+	env.Content = content.String()
+	env.Secret = secretContent.String()
+	env.Redacted = redacted.String()
+	return env, nil
+}
@@ -0,0 +1,132 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestRenderGuestEnvironment(t *testing.T) {
+	t.Setenv("TESTENV_VM_TEST_API_TOKEN", "s3cr3t")
+
+	env, err := RenderGuestEnvironment(v1.CloudInitSpec{
+		Environment:       map[string]string{"APP_ENV": "test", "API_URL": "http://10.0.0.1"},
+		SecretEnvironment: map[string]string{"API_TOKEN": "env:TESTENV_VM_TEST_API_TOKEN"},
+	})
+	if err != nil {
+		t.Fatalf("RenderGuestEnvironment() error = %v", err)
+	}
+
+	wantContent := "API_URL=\"http://10.0.0.1\"\nAPP_ENV=\"test\"\n"
+	if env.Content != wantContent {
+		t.Errorf("Content = %q, want %q", env.Content, wantContent)
+	}
+	if env.Secret != "API_TOKEN=s3cr3t\n" {
+		t.Errorf("Secret = %q, want %q", env.Secret, "API_TOKEN=s3cr3t\n")
+	}
+	if len(env.SecretKeys) != 1 || env.SecretKeys[0] != "API_TOKEN" {
+		t.Errorf("SecretKeys = %v, want [API_TOKEN]", env.SecretKeys)
+	}
+	if strings.Contains(env.Redacted, "s3cr3t") {
+		t.Errorf("Redacted leaks the secret: %q", env.Redacted)
+	}
+	if !strings.Contains(env.Redacted, `API_TOKEN="<redacted>"`) || !strings.Contains(env.Redacted, `APP_ENV="test"`) {
+		t.Errorf("unexpected Redacted: %q", env.Redacted)
+	}
+}
+
+func TestRenderGuestEnvironment_Empty(t *testing.T) {
+	env, err := RenderGuestEnvironment(v1.CloudInitSpec{})
+	if err != nil {
+		t.Fatalf("RenderGuestEnvironment() error = %v", err)
+	}
+	if env.Content != "" {
+		t.Errorf("Content = %q, want empty", env.Content)
+	}
+}
+
+func TestRenderGuestEnvironment_Errors(t *testing.T) {
+	t.Setenv("TESTENV_VM_TEST_MULTILINE", "line1\nline2")
+
+	tests := []struct {
+		name    string
+		ci      v1.CloudInitSpec
+		wantErr string
+	}{
+		{
+			name:    "invalid name",
+			ci:      v1.CloudInitSpec{Environment: map[string]string{"1BAD": "x"}},
+			wantErr: "invalid variable name",
+		},
+		{
+			name:    "quote in value",
+			ci:      v1.CloudInitSpec{Environment: map[string]string{"A": `say "hi"`}},
+			wantErr: "double quotes",
+		},
+		{
+			name:    "unset secret",
+			ci:      v1.CloudInitSpec{SecretEnvironment: map[string]string{"A": "env:TESTENV_VM_TEST_UNSET_VARIABLE"}},
+			wantErr: "not set",
+		},
+		{
+			name:    "multiline secret",
+			ci:      v1.CloudInitSpec{SecretEnvironment: map[string]string{"A": "env:TESTENV_VM_TEST_MULTILINE"}},
+			wantErr: "newlines",
+		},
+		{
+			name: "declared twice",
+			ci: v1.CloudInitSpec{
+				Environment:       map[string]string{"A": "x"},
+				SecretEnvironment: map[string]string{"A": "env:X"},
+			},
+			wantErr: "both",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RenderGuestEnvironment(tt.ci)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("RenderGuestEnvironment() error = %v, want error containing %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "line1") {
+				t.Errorf("error leaks the secret value: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateVMs_CloudInitEnvironment(t *testing.T) {
+	vm := func(ci v1.CloudInitSpec) []v1.VMResource {
+		return []v1.VMResource{{Name: "vm1", Spec: v1.VMSpec{Memory: 512, Vcpus: 1, CloudInit: ci}}}
+	}
+
+	if err := ValidateVMs(vm(v1.CloudInitSpec{
+		Environment:       map[string]string{"HOST": "{{ .VMs.db.IP }}"},
+		SecretEnvironment: map[string]string{"TOKEN": "file:/run/secrets/token"},
+	})); err != nil {
+		t.Errorf("ValidateVMs() error = %v", err)
+	}
+
+	if err := ValidateVMs(vm(v1.CloudInitSpec{
+		SecretEnvironment: map[string]string{"TOKEN": "vault:token"},
+	})); err == nil {
+		t.Error("ValidateVMs() accepted an unsupported secret scheme")
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// maxInterfaceName is the longest interface name Linux accepts.
+const maxInterfaceName = 15
+
+// Bonding modes of guest bonds.
+var bondModes = map[string]bool{
+	"": true, "balance-rr": true, "active-backup": true, "balance-xor": true,
+	"broadcast": true, "802.3ad": true, "balance-tlb": true, "balance-alb": true,
+}
+
+// guestInterface holds the addressing of an ethernet, bond or VLAN interface.
+type guestInterface struct {
+	path        string
+	name        string
+	addresses   []string
+	gateway4    string
+	routes      []v1.GuestRouteSpec
+	nameservers []string
+	mtu         int
+}
+
+// ValidateGuestNetworks validates the networkConfig of VMs. It ensures:
+// - It is not combined with cloudInit.networkConfig
+// - Interface names are set, unique and valid Linux interface names
+// - Ethernets name a network the VM is attached to, at most once
+// - NICs with an MTU or disabled offloads have an ethernet naming their network
+// - Bonds and VLANs reference the interfaces they are built on
+// - Addresses, gateways, routes, nameservers and MTUs are valid
+func ValidateGuestNetworks(vms []v1.VMResource) error {
+	var is issues
+	checkGuestNetworks(&is, vms)
+	return is.err()
+}
+
+// checkGuestNetworks reports every problem ValidateGuestNetworks fails on.
+func checkGuestNetworks(is *issues, vms []v1.VMResource) {
+	for i, vm := range vms {
+		nc := vm.Spec.NetworkConfig
+		if len(nc.Ethernets) == 0 && len(nc.Bonds) == 0 && len(nc.Vlans) == 0 {
+			continue
+		}
+		base := fmt.Sprintf("vms[%d].spec.networkConfig", i)
+		if len(vm.Spec.CloudInit.NetworkConfig.Ethernets) > 0 {
+			is.errorf(base, CodeConflict, "vm %q: networkConfig cannot be combined with cloudInit.networkConfig", vm.Name)
+		}
+
+		attached := make(map[string]bool)
+		templated := false
+		vmNetworks := vm.Spec.Networks
+		if len(vmNetworks) == 0 && vm.Spec.Network != "" {
+			vmNetworks = []string{vm.Spec.Network}
+		}
+		for _, n := range vmNetworks {
+			attached[n] = true
+			templated = templated || IsTemplated(n)
+		}
+
+		names := make(map[string]bool)
+		checkName := func(path, field, name string) {
+			switch {
+			case name == "":
+				is.errorf(path, CodeRequired, "vm %q: networkConfig.%s is required", vm.Name, field)
+			case names[name]:
+				is.errorf(path, CodeDuplicate, "vm %q: duplicate guest interface %q", vm.Name, name)
+			case len(name) > maxInterfaceName && !IsTemplated(name):
+				is.errorf(path, CodeInvalid, "vm %q: guest interface name %q is longer than %d characters", vm.Name, name, maxInterfaceName)
+			}
+			names[name] = true
+		}
+
+		var ifaces []guestInterface
+		ethernets := make(map[string]bool)
+		networks := make(map[string]bool)
+		for j, eth := range nc.Ethernets {
+			path := fmt.Sprintf("%s.ethernets[%d]", base, j)
+			checkName(path+".name", fmt.Sprintf("ethernets[%d].name", j), eth.Name)
+			ethernets[eth.Name] = true
+			switch {
+			case eth.Network == "":
+			case eth.Match != "":
+				is.errorf(path+".match", CodeConflict, "vm %q: ethernet %q cannot set both network and match", vm.Name, eth.Name)
+			case networks[eth.Network]:
+				is.errorf(path+".network", CodeDuplicate, "vm %q: duplicate ethernet for network %q", vm.Name, eth.Network)
+			case !attached[eth.Network] && !templated:
+				is.errorf(path+".network", CodeReference, "vm %q: ethernet network %q is not one of the networks of the vm", vm.Name, eth.Network)
+			}
+			networks[eth.Network] = true
+			ifaces = append(ifaces, guestInterface{path, eth.Name, eth.Addresses, eth.Gateway4, eth.Routes, eth.Nameservers.Addresses, eth.Mtu})
+		}
+
+		// The guest settings of a NIC are applied to the ethernet naming its
+		// network
+		for j, nic := range vm.Spec.Nics {
+			if (nic.Mtu == 0 && len(nic.DisableOffloads) == 0) || networks[nic.Network] || IsTemplated(nic.Network) {
+				continue
+			}
+			is.errorf(fmt.Sprintf("vms[%d].spec.nics[%d]", i, j), CodeConflict,
+				"vm %q: nic mtu and disableOffloads of network %q require an ethernet naming it in networkConfig", vm.Name, nic.Network)
+		}
+
+		bonds := make(map[string]bool)
+		members := make(map[string]string)
+		for j, bond := range nc.Bonds {
+			path := fmt.Sprintf("%s.bonds[%d]", base, j)
+			checkName(path+".name", fmt.Sprintf("bonds[%d].name", j), bond.Name)
+			bonds[bond.Name] = true
+			if len(bond.Interfaces) == 0 {
+				is.errorf(path+".interfaces", CodeRequired, "vm %q: bond %q has no interfaces", vm.Name, bond.Name)
+			}
+			for _, member := range bond.Interfaces {
+				if owner, ok := members[member]; ok {
+					is.errorf(path+".interfaces", CodeConflict, "vm %q: ethernet %q is already a member of bond %q", vm.Name, member, owner)
+				} else if !ethernets[member] {
+					is.errorf(path+".interfaces", CodeReference, "vm %q: bond %q interface %q is not one of the ethernets", vm.Name, bond.Name, member)
+				}
+				members[member] = bond.Name
+			}
+			if !bondModes[bond.Mode] {
+				is.errorf(path+".mode", CodeInvalid, "vm %q: unknown bonding mode %q", vm.Name, bond.Mode)
+			}
+			ifaces = append(ifaces, guestInterface{path, bond.Name, bond.Addresses, bond.Gateway4, bond.Routes, bond.Nameservers.Addresses, bond.Mtu})
+		}
+
+		for j, vlan := range nc.Vlans {
+			path := fmt.Sprintf("%s.vlans[%d]", base, j)
+			checkName(path+".name", fmt.Sprintf("vlans[%d].name", j), vlan.Name)
+			if vlan.Id < 1 || vlan.Id > 4094 {
+				is.errorf(path+".id", CodeInvalid, "vm %q: vlan %q id must be between 1 and 4094 (got %d)", vm.Name, vlan.Name, vlan.Id)
+			}
+			switch {
+			case vlan.Link == "":
+				is.errorf(path+".link", CodeRequired, "vm %q: vlan %q link is required", vm.Name, vlan.Name)
+			case !ethernets[vlan.Link] && !bonds[vlan.Link]:
+				is.errorf(path+".link", CodeReference, "vm %q: vlan %q link %q is not one of the ethernets or bonds", vm.Name, vlan.Name, vlan.Link)
+			}
+			ifaces = append(ifaces, guestInterface{path, vlan.Name, vlan.Addresses, vlan.Gateway4, vlan.Routes, vlan.Nameservers.Addresses, vlan.Mtu})
+		}
+
+		for _, iface := range ifaces {
+			checkGuestAddressing(is, vm.Name, iface)
+		}
+	}
+}
+
+// checkGuestAddressing reports the invalid addresses, gateway, routes,
+// nameservers and MTU of a guest interface. Templated values are skipped.
+func checkGuestAddressing(is *issues, vmName string, iface guestInterface) {
+	for _, addr := range iface.addresses {
+		if _, _, err := net.ParseCIDR(addr); err != nil && !IsTemplated(addr) {
+			is.errorf(iface.path+".addresses", CodeInvalid, "vm %q: interface %q address %q is not in CIDR notation", vmName, iface.name, addr)
+		}
+	}
+	if iface.gateway4 != "" && !isIPv4(iface.gateway4) {
+		is.errorf(iface.path+".gateway4", CodeInvalid, "vm %q: interface %q gateway4 %q is not an IPv4 address", vmName, iface.name, iface.gateway4)
+	}
+	for k, r := range iface.routes {
+		path := fmt.Sprintf("%s.routes[%d]", iface.path, k)
+		if _, _, err := net.ParseCIDR(r.To); err != nil && r.To != "default" && !IsTemplated(r.To) {
+			is.errorf(path+".to", CodeInvalid, "vm %q: interface %q route destination %q must be default or in CIDR notation", vmName, iface.name, r.To)
+		}
+		if !isIPv4(r.Via) {
+			is.errorf(path+".via", CodeInvalid, "vm %q: interface %q route via %q is not an IPv4 address", vmName, iface.name, r.Via)
+		}
+	}
+	for _, ns := range iface.nameservers {
+		if net.ParseIP(ns) == nil && !IsTemplated(ns) {
+			is.errorf(iface.path+".nameservers.addresses", CodeInvalid, "vm %q: interface %q nameserver %q is not an IP address", vmName, iface.name, ns)
+		}
+	}
+	if iface.mtu != 0 && (iface.mtu < MinMTU || iface.mtu > MaxMTU) {
+		is.errorf(iface.path+".mtu", CodeInvalid, "vm %q: interface %q mtu must be between %d and %d (got %d)", vmName, iface.name, MinMTU, MaxMTU, iface.mtu)
+	}
+}
+
+// isIPv4 reports whether s is an IPv4 address or a template.
+func isIPv4(s string) bool {
+	ip := net.ParseIP(s)
+	return IsTemplated(s) || (ip != nil && ip.To4() != nil)
+}
@@ -0,0 +1,160 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateGuestNetworks(t *testing.T) {
+	vm := func(nc v1.GuestNetworkConfigSpec) []v1.VMResource {
+		return []v1.VMResource{{
+			Name: "router",
+			Spec: v1.VMSpec{Networks: []string{"lan", "storage"}, NetworkConfig: nc},
+		}}
+	}
+	static := v1.GuestEthernetSpec{
+		Name:        "lan0",
+		Network:     "lan",
+		Addresses:   []string{"192.168.100.10/24"},
+		Gateway4:    "192.168.100.1",
+		Routes:      []v1.GuestRouteSpec{{To: "10.0.0.0/8", Via: "192.168.100.254", Metric: 100}},
+		Nameservers: v1.GuestNameserversSpec{Addresses: []string{"192.168.100.1"}, Search: []string{"lab.test"}},
+	}
+
+	tests := []struct {
+		name      string
+		vms       []v1.VMResource
+		errSubstr string
+	}{
+		{name: "no networkConfig passes", vms: vm(v1.GuestNetworkConfigSpec{})},
+		{name: "static addressing passes", vms: vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{static}})},
+		{
+			name: "bond with a vlan passes",
+			vms: vm(v1.GuestNetworkConfigSpec{
+				Ethernets: []v1.GuestEthernetSpec{{Name: "lan0", Network: "lan"}, {Name: "storage0", Network: "storage"}},
+				Bonds:     []v1.GuestBondSpec{{Name: "bond0", Interfaces: []string{"lan0", "storage0"}, Mode: "802.3ad"}},
+				Vlans:     []v1.GuestVLANSpec{{Name: "vlan10", Id: 10, Link: "bond0", Addresses: []string{"10.10.0.2/24"}}},
+			}),
+		},
+		{
+			name: "cloudInit.networkConfig conflicts",
+			vms: []v1.VMResource{{Name: "router", Spec: v1.VMSpec{
+				NetworkConfig: v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0"}}},
+				CloudInit:     v1.CloudInitSpec{NetworkConfig: v1.CloudInitNetworkConfig{Ethernets: []v1.CloudInitEthernetConfig{{Name: "eth0"}}}},
+			}}},
+			errSubstr: "networkConfig cannot be combined with cloudInit.networkConfig",
+		},
+		{
+			name: "nic settings with a matching ethernet pass",
+			vms: []v1.VMResource{{Name: "router", Spec: v1.VMSpec{
+				Networks:      []string{"lan"},
+				Nics:          []v1.VMNICSpec{{Network: "lan", Mtu: 1400, DisableOffloads: []string{"tso"}}},
+				NetworkConfig: v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "lan0", Network: "lan"}}},
+			}}},
+		},
+		{
+			name: "nic settings without a matching ethernet fail",
+			vms: []v1.VMResource{{Name: "router", Spec: v1.VMSpec{
+				Networks:      []string{"lan", "storage"},
+				Nics:          []v1.VMNICSpec{{Network: "storage", Mtu: 1400}},
+				NetworkConfig: v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "lan0", Network: "lan"}}},
+			}}},
+			errSubstr: `nic mtu and disableOffloads of network "storage" require an ethernet naming it`,
+		},
+		{
+			name:      "missing name fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Network: "lan"}}}),
+			errSubstr: "networkConfig.ethernets[0].name is required",
+		},
+		{
+			name:      "duplicate name fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0"}}, Vlans: []v1.GuestVLANSpec{{Name: "eth0", Id: 10, Link: "eth0"}}}),
+			errSubstr: `duplicate guest interface "eth0"`,
+		},
+		{
+			name:      "long name fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "storage-backend0"}}}),
+			errSubstr: "is longer than 15 characters",
+		},
+		{
+			name:      "unattached network fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "wan0", Network: "wan"}}}),
+			errSubstr: `ethernet network "wan" is not one of the networks of the vm`,
+		},
+		{
+			name:      "network and match fail",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "lan0", Network: "lan", Match: "en*"}}}),
+			errSubstr: "cannot set both network and match",
+		},
+		{
+			name:      "unknown bond member fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Bonds: []v1.GuestBondSpec{{Name: "bond0", Interfaces: []string{"eth0"}}}}),
+			errSubstr: `bond "bond0" interface "eth0" is not one of the ethernets`,
+		},
+		{
+			name: "unknown bonding mode fails",
+			vms: vm(v1.GuestNetworkConfigSpec{
+				Ethernets: []v1.GuestEthernetSpec{{Name: "eth0"}},
+				Bonds:     []v1.GuestBondSpec{{Name: "bond0", Interfaces: []string{"eth0"}, Mode: "lacp"}},
+			}),
+			errSubstr: `unknown bonding mode "lacp"`,
+		},
+		{
+			name:      "vlan id out of range fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0"}}, Vlans: []v1.GuestVLANSpec{{Name: "vlan0", Link: "eth0"}}}),
+			errSubstr: "id must be between 1 and 4094",
+		},
+		{
+			name:      "unknown vlan link fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Vlans: []v1.GuestVLANSpec{{Name: "vlan10", Id: 10, Link: "bond0"}}}),
+			errSubstr: `vlan "vlan10" link "bond0" is not one of the ethernets or bonds`,
+		},
+		{
+			name:      "address without prefix fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0", Addresses: []string{"192.168.100.10"}}}}),
+			errSubstr: `address "192.168.100.10" is not in CIDR notation`,
+		},
+		{
+			name:      "invalid route fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0", Routes: []v1.GuestRouteSpec{{To: "10.0.0.0/8", Via: "gw"}}}}}),
+			errSubstr: `route via "gw" is not an IPv4 address`,
+		},
+		{
+			name:      "invalid mtu fails",
+			vms:       vm(v1.GuestNetworkConfigSpec{Ethernets: []v1.GuestEthernetSpec{{Name: "eth0", Mtu: 20000}}}),
+			errSubstr: "mtu must be between 68 and 9216",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGuestNetworks(tt.vms)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateGuestNetworks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateGuestNetworks() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+var (
+	// pciAddressPattern matches a PCI address, with or without its domain.
+	pciAddressPattern = regexp.MustCompile(`^([0-9a-fA-F]{4}:)?[0-9a-fA-F]{2}:[0-9a-fA-F]{2}\.[0-7]$`)
+	// uuidPattern matches a UUID.
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// checkHostDevices reports the problems of the PCI and mediated devices of a
+// VM: addresses and UUIDs must be well-formed, mdevs need a uuid or a type,
+// and a device cannot be passed to two VMs. owners maps the devices seen so
+// far to their VM.
+func checkHostDevices(is *issues, path string, vm v1.VMResource, owners map[string]string) {
+	claim := func(field, device string) {
+		if owner, ok := owners[device]; ok {
+			is.errorf(field, CodeDuplicate, "vm %q: device %s is already passed through to vm %q", vm.Name, device, owner)
+			return
+		}
+		owners[device] = vm.Name
+	}
+
+	for j, addr := range vm.Spec.Devices.PciPassthrough {
+		field := fmt.Sprintf("%s.devices.pciPassthrough[%d]", path, j)
+		switch {
+		case IsTemplated(addr):
+		case !pciAddressPattern.MatchString(addr):
+			is.errorf(field, CodeInvalid, "vm %q: %q is not a PCI address such as 0000:01:00.0", vm.Name, addr)
+		default:
+			addr = strings.ToLower(addr)
+			if len(addr) == len("01:00.0") {
+				addr = "0000:" + addr
+			}
+			claim(field, addr)
+		}
+	}
+
+	for j, m := range vm.Spec.Devices.Mdevs {
+		field := fmt.Sprintf("%s.devices.mdevs[%d]", path, j)
+		switch {
+		case m.Uuid != "" && m.Type != "":
+			is.errorf(field, CodeInvalid, "vm %q: mdev uuid and type are mutually exclusive", vm.Name)
+		case m.Uuid == "" && m.Type == "":
+			is.errorf(field, CodeRequired, "vm %q: mdev uuid or type is required", vm.Name)
+		case m.Uuid != "" && m.Parent != "":
+			is.errorf(field+".parent", CodeInvalid, "vm %q: mdev parent only applies to a type", vm.Name)
+		case m.Uuid != "" && !IsTemplated(m.Uuid):
+			if !uuidPattern.MatchString(m.Uuid) {
+				is.errorf(field+".uuid", CodeInvalid, "vm %q: mdev uuid %q is not a UUID", vm.Name, m.Uuid)
+			} else {
+				claim(field+".uuid", "mdev "+strings.ToLower(m.Uuid))
+			}
+		case m.Parent != "" && !IsTemplated(m.Parent) && !pciAddressPattern.MatchString(m.Parent):
+			is.errorf(field+".parent", CodeInvalid, "vm %q: mdev parent %q is not a PCI address", vm.Name, m.Parent)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateVMsHostDevices(t *testing.T) {
+	vm := func(name string, devices v1.VMDevicesSpec) v1.VMResource {
+		return v1.VMResource{Name: name, Spec: v1.VMSpec{Memory: 1024, Vcpus: 1, Devices: devices}}
+	}
+	const uuid = "4b20d080-1b54-4048-85b3-a6a62d165c01"
+
+	tests := []struct {
+		name      string
+		vms       []v1.VMResource
+		errSubstr string
+	}{
+		{
+			name: "devices pass",
+			vms: []v1.VMResource{
+				vm("gpu", v1.VMDevicesSpec{
+					PciPassthrough: []string{"0000:01:00.0", "02:00.1", "{{ .Env.GPU }}"},
+					Mdevs:          []v1.MdevSpec{{Uuid: uuid}, {Type: "nvidia-63", Parent: "0000:3b:00.0"}, {Type: "nvidia-63"}},
+				}),
+				vm("nic", v1.VMDevicesSpec{PciPassthrough: []string{"0000:01:00.1"}, Mdevs: []v1.MdevSpec{{Type: "nvidia-63"}}}),
+			},
+		},
+		{
+			name:      "invalid address fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{PciPassthrough: []string{"01:00"}})},
+			errSubstr: `"01:00" is not a PCI address`,
+		},
+		{
+			name: "device of two vms fails",
+			vms: []v1.VMResource{
+				vm("gpu", v1.VMDevicesSpec{PciPassthrough: []string{"01:00.0"}}),
+				vm("other", v1.VMDevicesSpec{PciPassthrough: []string{"0000:01:00.0"}}),
+			},
+			errSubstr: `device 0000:01:00.0 is already passed through to vm "gpu"`,
+		},
+		{
+			name:      "mdev of two vms fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Uuid: uuid}, {Uuid: strings.ToUpper(uuid)}}})},
+			errSubstr: "is already passed through",
+		},
+		{
+			name:      "mdev without uuid or type fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Parent: "0000:01:00.0"}}})},
+			errSubstr: "mdev uuid or type is required",
+		},
+		{
+			name:      "mdev with uuid and type fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Uuid: uuid, Type: "nvidia-63"}}})},
+			errSubstr: "mutually exclusive",
+		},
+		{
+			name:      "mdev with uuid and parent fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Uuid: uuid, Parent: "0000:01:00.0"}}})},
+			errSubstr: "parent only applies to a type",
+		},
+		{
+			name:      "invalid uuid fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Uuid: "gpu-slice"}}})},
+			errSubstr: `mdev uuid "gpu-slice" is not a UUID`,
+		},
+		{
+			name:      "invalid parent fails",
+			vms:       []v1.VMResource{vm("gpu", v1.VMDevicesSpec{Mdevs: []v1.MdevSpec{{Type: "nvidia-63", Parent: "gpu0"}}})},
+			errSubstr: `mdev parent "gpu0" is not a PCI address`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVMs(tt.vms)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateVMs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateVMs() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
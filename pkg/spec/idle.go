@@ -0,0 +1,122 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Idle policy defaults.
+const (
+	DefaultIdleCPUPercent = 5
+	DefaultIdleSSHPort    = 22
+)
+
+// Actions powering idle VMs down.
+const (
+	IdleActionStop = "stop"
+	IdleActionSave = "save"
+)
+
+// IdleAfter returns the time after which idle VMs are powered down, or zero
+// when the spec has no idle policy.
+func IdleAfter(spec *v1.Spec) (time.Duration, error) {
+	return parsePositiveDuration("idle.after", spec.Idle.After)
+}
+
+// IdleAction returns the action powering idle VMs down.
+func IdleAction(spec *v1.Spec) string {
+	if spec.Idle.Action == "" {
+		return IdleActionStop
+	}
+	return spec.Idle.Action
+}
+
+// ValidateIdle validates the idle policy of a spec. It ensures:
+// - After is a positive duration, set whenever the policy is
+// - The CPU threshold, action and ports are valid
+// - Wake proxies name a VM of the spec and distinct host addresses
+func ValidateIdle(spec *v1.Spec) error {
+	var is issues
+	checkIdle(&is, spec)
+	return is.err()
+}
+
+// checkIdle reports every problem ValidateIdle fails on.
+func checkIdle(is *issues, spec *v1.Spec) {
+	idle := spec.Idle
+	if idle.After == "" {
+		if idle.Action != "" || idle.CpuPercent != 0 || idle.SshPort != 0 || len(idle.Wake) > 0 {
+			is.errorf("idle.after", CodeRequired, "idle.after is required when an idle policy is set")
+		}
+		return
+	}
+	if _, err := IdleAfter(spec); err != nil {
+		is.errorf("idle.after", CodeInvalid, "%s", err)
+	}
+	if idle.CpuPercent < 0 || idle.CpuPercent > 100 {
+		is.errorf("idle.cpuPercent", CodeInvalid, "idle.cpuPercent must be between 0 and 100 (got %d)", idle.CpuPercent)
+	}
+	if idle.Action != "" && idle.Action != IdleActionStop && idle.Action != IdleActionSave {
+		is.errorf("idle.action", CodeInvalid, "idle.action must be stop or save (got %q)", idle.Action)
+	}
+	if idle.SshPort < 0 || idle.SshPort > 65535 {
+		is.errorf("idle.sshPort", CodeInvalid, "idle.sshPort must be a valid port (got %d)", idle.SshPort)
+	}
+
+	vms := make(map[string]bool, len(spec.Vms))
+	for _, vm := range spec.Vms {
+		vms[vm.Name] = true
+	}
+	listen := make(map[string]bool)
+	for i, w := range idle.Wake {
+		path := fmt.Sprintf("idle.wake[%d]", i)
+		switch {
+		case w.Vm == "":
+			is.errorf(path+".vm", CodeRequired, "idle.wake[%d].vm is required", i)
+		case !vms[w.Vm]:
+			is.errorf(path+".vm", CodeReference, "idle.wake[%d]: vm %q not found", i, w.Vm)
+		}
+		if err := validateListenAddress(w.Listen); err != nil {
+			is.errorf(path+".listen", CodeInvalid, "idle.wake[%d]: %s", i, err)
+		} else if listen[w.Listen] {
+			is.errorf(path+".listen", CodeDuplicate, "idle.wake[%d]: duplicate listen address %q", i, w.Listen)
+		}
+		listen[w.Listen] = true
+		if w.Port < 0 || w.Port > 65535 {
+			is.errorf(path+".port", CodeInvalid, "idle.wake[%d]: port must be a valid port (got %d)", i, w.Port)
+		}
+	}
+}
+
+// validateListenAddress checks a host:port address to listen on.
+func validateListenAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", address, err)
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		return fmt.Errorf("listen address %q must use an IP address", address)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("listen address %q must have a port between 1 and 65535", address)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateIdle(t *testing.T) {
+	vms := []v1.VMResource{{Name: "web"}}
+
+	tests := []struct {
+		name      string
+		idle      v1.IdleSpec
+		errSubstr string
+	}{
+		{name: "no policy passes"},
+		{
+			name: "full policy passes",
+			idle: v1.IdleSpec{
+				After: "30m", CpuPercent: 10, Action: "save", SshPort: 2022,
+				Wake: []v1.IdleWakeSpec{{Vm: "web", Listen: "127.0.0.1:2222"}},
+			},
+		},
+		{
+			name:      "options without after fail",
+			idle:      v1.IdleSpec{Action: "stop"},
+			errSubstr: "idle.after is required",
+		},
+		{
+			name:      "invalid after fails",
+			idle:      v1.IdleSpec{After: "soon"},
+			errSubstr: `idle.after "soon" is not a valid duration`,
+		},
+		{
+			name:      "cpu percent out of range fails",
+			idle:      v1.IdleSpec{After: "1h", CpuPercent: 120},
+			errSubstr: "idle.cpuPercent must be between 0 and 100",
+		},
+		{
+			name:      "unknown action fails",
+			idle:      v1.IdleSpec{After: "1h", Action: "hibernate"},
+			errSubstr: "idle.action must be stop or save",
+		},
+		{
+			name:      "unknown vm fails",
+			idle:      v1.IdleSpec{After: "1h", Wake: []v1.IdleWakeSpec{{Vm: "db", Listen: "127.0.0.1:2222"}}},
+			errSubstr: `vm "db" not found`,
+		},
+		{
+			name:      "host name fails",
+			idle:      v1.IdleSpec{After: "1h", Wake: []v1.IdleWakeSpec{{Vm: "web", Listen: "localhost:2222"}}},
+			errSubstr: "must use an IP address",
+		},
+		{
+			name: "duplicate listen address fails",
+			idle: v1.IdleSpec{After: "1h", Wake: []v1.IdleWakeSpec{
+				{Vm: "web", Listen: "127.0.0.1:2222"},
+				{Vm: "web", Listen: "127.0.0.1:2222", Port: 80},
+			}},
+			errSubstr: `duplicate listen address "127.0.0.1:2222"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIdle(&v1.Spec{Vms: vms, Idle: tt.idle})
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateIdle() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateIdle() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestIdleAfter(t *testing.T) {
+	after, err := IdleAfter(&v1.Spec{Idle: v1.IdleSpec{After: "45m"}})
+	if err != nil || after != 45*time.Minute {
+		t.Errorf("IdleAfter() = %v, %v, want 45m", after, err)
+	}
+	if after, err := IdleAfter(&v1.Spec{}); err != nil || after != 0 {
+		t.Errorf("IdleAfter() = %v, %v, want 0", after, err)
+	}
+	if got := IdleAction(&v1.Spec{}); got != IdleActionStop {
+		t.Errorf("IdleAction() = %q, want %q", got, IdleActionStop)
+	}
+}
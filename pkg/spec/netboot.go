@@ -0,0 +1,98 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Boot files are Go templates with "[[" and "]]" delimiters, so that the
+// "{{ }}" actions of the spec are rendered first and iPXE scripts keep their
+// "${ }" variables.
+const (
+	BootTemplateLeftDelim  = "[["
+	BootTemplateRightDelim = "]]"
+)
+
+// sha256Pattern matches a hex-encoded SHA256 checksum.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// IsPerVMBootFile reports whether a boot file is rendered once per VM of its
+// network: its path is a template.
+func IsPerVMBootFile(path string) bool {
+	return strings.Contains(path, BootTemplateLeftDelim)
+}
+
+// ParseBootTemplate parses the path or content of a boot file.
+func ParseBootTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Delims(BootTemplateLeftDelim, BootTemplateRightDelim).Option("missingkey=error").Parse(text)
+}
+
+// checkTFTP reports the problems of the boot options, artifacts and files of
+// the TFTP server of a network: boot option codes must be DHCP option codes,
+// artifacts need an HTTPS URL, and the paths of artifacts and files must stay
+// within the boot root without colliding.
+func checkTFTP(is *issues, path, network string, tftp *v1.TFTPSpec) {
+	for key := range tftp.DhcpBootOptions {
+		if code, err := strconv.Atoi(key); err != nil || code < 1 || code > 254 {
+			is.errorf(path+".dhcpBootOptions", CodeInvalid, "network %q: dhcpBootOptions key %q is not a DHCP option code (1-254)", network, key)
+		}
+	}
+
+	paths := make(map[string]bool)
+	checkPath := func(field, p string) {
+		switch {
+		case p == "":
+			is.errorf(field+".path", CodeRequired, "network %q: path is required", network)
+		case IsTemplated(p) || IsPerVMBootFile(p):
+		case !filepath.IsLocal(p):
+			is.errorf(field+".path", CodeInvalid, "network %q: path %q must be relative to the boot root", network, p)
+		case paths[filepath.Clean(p)]:
+			is.errorf(field+".path", CodeDuplicate, "network %q: duplicate boot file %q", network, p)
+		}
+		paths[filepath.Clean(p)] = true
+	}
+	for i, a := range tftp.Artifacts {
+		field := fmt.Sprintf("%s.artifacts[%d]", path, i)
+		if IsPerVMBootFile(a.Path) {
+			is.errorf(field+".path", CodeInvalid, "network %q: artifact path %q cannot be rendered per VM", network, a.Path)
+		}
+		checkPath(field, a.Path)
+		if u, err := url.Parse(a.Url); !IsTemplated(a.Url) && (err != nil || u.Scheme != "https" || u.Host == "") {
+			is.errorf(field+".url", CodeInvalid, "network %q: artifact url %q must be an HTTPS URL", network, a.Url)
+		}
+		if a.Sha256 != "" && !IsTemplated(a.Sha256) && !sha256Pattern.MatchString(a.Sha256) {
+			is.errorf(field+".sha256", CodeInvalid, "network %q: artifact sha256 %q is not a hex-encoded SHA256 checksum", network, a.Sha256)
+		}
+	}
+	for i, f := range tftp.Files {
+		field := fmt.Sprintf("%s.files[%d]", path, i)
+		checkPath(field, f.Path)
+		if _, err := ParseBootTemplate(f.Path, f.Path); err != nil {
+			is.errorf(field+".path", CodeInvalid, "network %q: %v", network, err)
+		}
+		if _, err := ParseBootTemplate(f.Path, f.Content); err != nil {
+			is.errorf(field+".content", CodeInvalid, "network %q: %v", network, err)
+		}
+	}
+}
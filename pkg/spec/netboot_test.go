@@ -0,0 +1,115 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateNetworksTFTP(t *testing.T) {
+	network := func(tftp v1.TFTPSpec) []v1.NetworkResource {
+		tftp.Enabled = true
+		return []v1.NetworkResource{{
+			Name: "pxe",
+			Kind: "dnsmasq",
+			Spec: v1.NetworkSpec{Cidr: "192.168.150.0/24", Tftp: &tftp},
+		}}
+	}
+	kernel := v1.BootArtifactSpec{
+		Path:   "boot/vmlinuz",
+		Url:    "https://example.com/vmlinuz",
+		Sha256: strings.Repeat("ab", 32),
+	}
+
+	tests := []struct {
+		name      string
+		networks  []v1.NetworkResource
+		errSubstr string
+	}{
+		{name: "boot file only passes", networks: network(v1.TFTPSpec{Root: "/srv/tftp", BootFile: "undionly.kpxe"})},
+		{
+			name: "artifacts and files pass",
+			networks: network(v1.TFTPSpec{
+				BootFile:        "undionly.kpxe",
+				BootFileEfi:     "ipxe.efi",
+				DhcpBootOptions: map[string]string{"43": "01:04:00:00:00:00"},
+				Artifacts:       []v1.BootArtifactSpec{kernel, {Path: "ipxe.efi", Url: "https://{{ .Env.MIRROR }}/ipxe.efi"}},
+				Files: []v1.BootFileSpec{
+					{Path: "boot.ipxe", Content: "#!ipxe\nchain http://[[ .Server ]]:8080/[[ `${mac:hexhyp}` ]].ipxe\n"},
+					{Path: "[[ .VM.MACHyphen ]].ipxe", Content: "#!ipxe\nkernel http://[[ .Server ]]:8080/boot/vmlinuz hostname=[[ .VM.Name ]]\nboot\n"},
+				},
+			}),
+		},
+		{
+			name:      "unknown option code fails",
+			networks:  network(v1.TFTPSpec{DhcpBootOptions: map[string]string{"vendor": "x"}}),
+			errSubstr: `dhcpBootOptions key "vendor" is not a DHCP option code`,
+		},
+		{
+			name:      "missing path fails",
+			networks:  network(v1.TFTPSpec{Artifacts: []v1.BootArtifactSpec{{Url: kernel.Url}}}),
+			errSubstr: "path is required",
+		},
+		{
+			name:      "escaping path fails",
+			networks:  network(v1.TFTPSpec{Files: []v1.BootFileSpec{{Path: "../boot.ipxe"}}}),
+			errSubstr: `path "../boot.ipxe" must be relative to the boot root`,
+		},
+		{
+			name:      "duplicate path fails",
+			networks:  network(v1.TFTPSpec{Artifacts: []v1.BootArtifactSpec{kernel}, Files: []v1.BootFileSpec{{Path: "boot//vmlinuz"}}}),
+			errSubstr: `duplicate boot file "boot//vmlinuz"`,
+		},
+		{
+			name:      "per-VM artifact fails",
+			networks:  network(v1.TFTPSpec{Artifacts: []v1.BootArtifactSpec{{Path: "[[ .VM.Name ]]", Url: kernel.Url}}}),
+			errSubstr: "cannot be rendered per VM",
+		},
+		{
+			name:      "http url fails",
+			networks:  network(v1.TFTPSpec{Artifacts: []v1.BootArtifactSpec{{Path: "vmlinuz", Url: "http://example.com/vmlinuz"}}}),
+			errSubstr: "must be an HTTPS URL",
+		},
+		{
+			name:      "invalid checksum fails",
+			networks:  network(v1.TFTPSpec{Artifacts: []v1.BootArtifactSpec{{Path: "vmlinuz", Url: kernel.Url, Sha256: "abc"}}}),
+			errSubstr: "is not a hex-encoded SHA256 checksum",
+		},
+		{
+			name:      "invalid template fails",
+			networks:  network(v1.TFTPSpec{Files: []v1.BootFileSpec{{Path: "boot.ipxe", Content: "[[ .Server "}}}),
+			errSubstr: "unclosed action",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNetworks(tt.networks)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateNetworks() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateNetworks() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// MTU bounds of networks and NICs. MaxMTU allows jumbo frames.
+const (
+	MinMTU     = 68
+	MaxMTU     = 9216
+	DefaultMTU = 1500
+)
+
+// NIC models VMs may use.
+var nicModels = map[string]bool{"": true, "virtio": true, "e1000": true}
+
+// Offloads NICs may turn off in the guest.
+var nicOffloads = map[string]bool{"tso": true, "gso": true, "gro": true, "lro": true}
+
+// ValidateNICs validates the NIC options of VMs. It ensures:
+// - Each NIC names a network the VM is attached to, at most once
+// - The model and offloads are supported
+// - The MTU is valid and at most the MTU of the network
+func ValidateNICs(networks []v1.NetworkResource, vms []v1.VMResource) error {
+	var is issues
+	checkNICs(&is, networks, vms)
+	return is.err()
+}
+
+// checkNICs reports every problem ValidateNICs fails on.
+func checkNICs(is *issues, networks []v1.NetworkResource, vms []v1.VMResource) {
+	networkMTUs := make(map[string]int, len(networks))
+	for _, n := range networks {
+		networkMTUs[n.Name] = n.Spec.Mtu
+		if networkMTUs[n.Name] == 0 {
+			networkMTUs[n.Name] = DefaultMTU
+		}
+	}
+
+	for i, vm := range vms {
+		attached := make(map[string]bool)
+		templated := false
+		vmNetworks := vm.Spec.Networks
+		if len(vmNetworks) == 0 && vm.Spec.Network != "" {
+			vmNetworks = []string{vm.Spec.Network}
+		}
+		for _, n := range vmNetworks {
+			attached[n] = true
+			templated = templated || IsTemplated(n)
+		}
+
+		seen := make(map[string]bool)
+		for j, nic := range vm.Spec.Nics {
+			path := fmt.Sprintf("vms[%d].spec.nics[%d]", i, j)
+			switch {
+			case nic.Network == "":
+				is.errorf(path+".network", CodeRequired, "vm %q: nics[%d].network is required", vm.Name, j)
+			case seen[nic.Network]:
+				is.errorf(path+".network", CodeDuplicate, "vm %q: duplicate nic for network %q", vm.Name, nic.Network)
+			case !attached[nic.Network] && !templated:
+				is.errorf(path+".network", CodeReference, "vm %q: nic network %q is not one of the networks of the vm", vm.Name, nic.Network)
+			}
+			seen[nic.Network] = true
+
+			if !nicModels[nic.Model] {
+				is.errorf(path+".model", CodeInvalid, "vm %q: nic model must be virtio or e1000 (got %q)", vm.Name, nic.Model)
+			}
+			for _, o := range nic.DisableOffloads {
+				if !nicOffloads[o] {
+					is.errorf(path+".disableOffloads", CodeInvalid, "vm %q: unknown offload %q (supported: tso, gso, gro, lro)", vm.Name, o)
+				}
+			}
+
+			if nic.Mtu == 0 {
+				continue
+			}
+			if nic.Mtu < MinMTU || nic.Mtu > MaxMTU {
+				is.errorf(path+".mtu", CodeInvalid, "vm %q: nic mtu must be between %d and %d (got %d)", vm.Name, MinMTU, MaxMTU, nic.Mtu)
+			} else if max, ok := networkMTUs[nic.Network]; ok && nic.Mtu > max {
+				is.errorf(path+".mtu", CodeConflict, "vm %q: nic mtu %d exceeds the mtu %d of network %q", vm.Name, nic.Mtu, max, nic.Network)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateNICs(t *testing.T) {
+	networks := []v1.NetworkResource{
+		{Name: "jumbo", Kind: "bridge", Spec: v1.NetworkSpec{Mtu: 9000}},
+		{Name: "lan", Kind: "bridge"},
+	}
+	vm := func(nics ...v1.VMNICSpec) []v1.VMResource {
+		return []v1.VMResource{{
+			Name: "web",
+			Spec: v1.VMSpec{Networks: []string{"jumbo", "lan"}, Nics: nics},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		vms       []v1.VMResource
+		errSubstr string
+	}{
+		{name: "no nics passes", vms: vm()},
+		{
+			name: "jumbo frames on a jumbo network pass",
+			vms:  vm(v1.VMNICSpec{Network: "jumbo", Model: "e1000", Mtu: 9000, DisableOffloads: []string{"tso", "gro"}}),
+		},
+		{
+			name:      "mtu above the network mtu fails",
+			vms:       vm(v1.VMNICSpec{Network: "lan", Mtu: 9000}),
+			errSubstr: `nic mtu 9000 exceeds the mtu 1500 of network "lan"`,
+		},
+		{
+			name:      "mtu out of range fails",
+			vms:       vm(v1.VMNICSpec{Network: "jumbo", Mtu: 20}),
+			errSubstr: "nic mtu must be between 68 and 9216",
+		},
+		{
+			name:      "unattached network fails",
+			vms:       vm(v1.VMNICSpec{Network: "wan"}),
+			errSubstr: `nic network "wan" is not one of the networks of the vm`,
+		},
+		{
+			name:      "duplicate network fails",
+			vms:       vm(v1.VMNICSpec{Network: "lan"}, v1.VMNICSpec{Network: "lan"}),
+			errSubstr: `duplicate nic for network "lan"`,
+		},
+		{
+			name:      "unknown model fails",
+			vms:       vm(v1.VMNICSpec{Network: "lan", Model: "rtl8139"}),
+			errSubstr: "nic model must be virtio or e1000",
+		},
+		{
+			name:      "unknown offload fails",
+			vms:       vm(v1.VMNICSpec{Network: "lan", DisableOffloads: []string{"tx"}}),
+			errSubstr: `unknown offload "tx"`,
+		},
+		{
+			name: "legacy network field is attached",
+			vms: []v1.VMResource{{
+				Name: "web",
+				Spec: v1.VMSpec{Network: "lan", Nics: []v1.VMNICSpec{{Network: "lan", Mtu: 1400}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNICs(networks, tt.vms)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateNICs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateNICs() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ValidateParent validates the parent of a spec. It ensures:
+// - environmentId is set when keys or networks of the parent are listed
+// - Listed names are non-empty and unique
+// - Listed names do not collide with keys and networks of the spec
+//
+// Whether the parent exists, is ready and has the listed resources is only
+// known when the environment is created.
+func ValidateParent(spec *v1.Spec) error {
+	var is issues
+	checkParent(&is, spec)
+	return is.err()
+}
+
+// checkParent reports every problem ValidateParent fails on.
+func checkParent(is *issues, spec *v1.Spec) {
+	parent := spec.Parent
+	if parent.EnvironmentId == "" {
+		if len(parent.Keys) > 0 || len(parent.Networks) > 0 {
+			is.errorf("parent.environmentId", CodeRequired, "parent: environmentId is required when keys or networks are listed")
+		}
+		return
+	}
+
+	own := map[string]map[string]bool{"key": {}, "network": {}}
+	for _, k := range spec.Keys {
+		own["key"][k.Name] = true
+	}
+	for _, n := range spec.Networks {
+		own["network"][n.Name] = true
+	}
+	for _, list := range []struct {
+		kind, field string
+		names       []string
+	}{
+		{"key", "keys", parent.Keys},
+		{"network", "networks", parent.Networks},
+	} {
+		seen := make(map[string]bool)
+		for i, name := range list.names {
+			path := fmt.Sprintf("parent.%s[%d]", list.field, i)
+			switch {
+			case name == "":
+				is.errorf(path, CodeRequired, "parent: %s at index %d: name is required", list.kind, i)
+			case seen[name]:
+				is.errorf(path, CodeDuplicate, "parent: duplicate %s name %q", list.kind, name)
+			case own[list.kind][name]:
+				is.errorf(path, CodeConflict, "parent: %s %q is also declared by this environment", list.kind, name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+// ParentResourceNames returns the names of the keys and networks a spec uses
+// from its parent, by kind.
+func ParentResourceNames(spec *v1.Spec) map[string]map[string]bool {
+	names := map[string]map[string]bool{"key": {}, "network": {}}
+	if spec.Parent.EnvironmentId == "" {
+		return names
+	}
+	for _, k := range spec.Parent.Keys {
+		names["key"][k] = true
+	}
+	for _, n := range spec.Parent.Networks {
+		names["network"][n] = true
+	}
+	return names
+}
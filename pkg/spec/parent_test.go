@@ -0,0 +1,106 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// childSpec returns a spec adding a VM to the network and key of a parent.
+func childSpec() *v1.Spec {
+	return &v1.Spec{
+		Providers: []v1.ProviderConfig{{Name: "local", Engine: "go://local", Default: true}},
+		Parent:    v1.ParentSpec{EnvironmentId: "base-lab", Keys: []string{"lab-key"}, Networks: []string{"lab-net"}},
+		Vms: []v1.VMResource{{
+			Name: "extra",
+			Spec: v1.VMSpec{
+				Memory:  1024,
+				Vcpus:   1,
+				Network: "lab-net",
+				CloudInit: v1.CloudInitSpec{
+					Users: []v1.UserSpec{{Name: "ci", SshAuthorizedKeys: []string{"{{ .Keys.lab-key.PublicKey }}"}}},
+				},
+			},
+		}},
+	}
+}
+
+func TestValidateParent(t *testing.T) {
+	if err := Validate(childSpec()); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(*v1.Spec)
+		wantErr string
+	}{
+		{
+			name:    "missing environment ID",
+			mutate:  func(s *v1.Spec) { s.Parent.EnvironmentId = "" },
+			wantErr: "environmentId is required",
+		},
+		{
+			name:    "empty name",
+			mutate:  func(s *v1.Spec) { s.Parent.Keys = append(s.Parent.Keys, "") },
+			wantErr: "name is required",
+		},
+		{
+			name:    "duplicate name",
+			mutate:  func(s *v1.Spec) { s.Parent.Networks = append(s.Parent.Networks, "lab-net") },
+			wantErr: `duplicate network name "lab-net"`,
+		},
+		{
+			name: "declared by the child",
+			mutate: func(s *v1.Spec) {
+				s.Keys = []v1.KeyResource{{Name: "lab-key", Spec: v1.KeySpec{Type: "ed25519"}}}
+			},
+			wantErr: `key "lab-key" is also declared`,
+		},
+		{
+			name:    "network not listed",
+			mutate:  func(s *v1.Spec) { s.Parent.Networks = nil },
+			wantErr: `network "lab-net" not found`,
+		},
+		{
+			name:    "key not listed",
+			mutate:  func(s *v1.Spec) { s.Parent.Keys = nil },
+			wantErr: `non-existent key "lab-key"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(childSpecWith(tt.mutate))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+			if report := ValidateReport(childSpecWith(tt.mutate)); report.Valid {
+				t.Error("ValidateReport() is valid, want an error")
+			}
+		})
+	}
+}
+
+// childSpecWith returns childSpec changed by mutate.
+func childSpecWith(mutate func(*v1.Spec)) *v1.Spec {
+	s := childSpec()
+	mutate(s)
+	return s
+}
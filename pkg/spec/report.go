@@ -0,0 +1,359 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Issue codes of a validation report.
+const (
+	// CodeRequired reports a missing required field.
+	CodeRequired = "required"
+	// CodeInvalid reports a value that is not allowed.
+	CodeInvalid = "invalid"
+	// CodeDuplicate reports a name used twice.
+	CodeDuplicate = "duplicate"
+	// CodeConflict reports settings that cannot be combined.
+	CodeConflict = "conflict"
+	// CodeReference reports a reference to an unknown or unusable resource.
+	CodeReference = "reference"
+	// CodeUnknownField reports a field that is not part of the schema.
+	CodeUnknownField = "unknown-field"
+	// CodeUnusedImage warns about an image that nothing references.
+	CodeUnusedImage = "unused-image"
+	// CodeUnusedNetwork warns about a network that nothing attaches to.
+	CodeUnusedNetwork = "unused-network"
+	// CodeIgnoredField warns about a field that has no effect.
+	CodeIgnoredField = "ignored-field"
+)
+
+// Issue severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a single finding of a validation report.
+type Issue struct {
+	// Path locates the offending field, e.g. "vms[2].spec.memory".
+	Path string `json:"path"`
+	// Code classifies the issue, e.g. "required" or "unused-image".
+	Code string `json:"code"`
+	// Severity is "error" or "warning". Only errors make a spec invalid.
+	Severity string `json:"severity"`
+	// Message is the error message Validate would return.
+	Message string `json:"message"`
+	// Line and Column locate unknown fields of YAML specs.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+// Report is the outcome of ValidateReport.
+type Report struct {
+	// Valid is true when the report holds no error.
+	Valid bool `json:"valid"`
+	// Issues are the errors and warnings in spec order.
+	Issues []Issue `json:"issues"`
+}
+
+// Errors returns the issues of severity error.
+func (r *Report) Errors() []Issue {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns the issues of severity warning.
+func (r *Report) Warnings() []Issue {
+	return r.filter(SeverityWarning)
+}
+
+func (r *Report) filter(severity string) []Issue {
+	var out []Issue
+	for _, i := range r.Issues {
+		if i.Severity == severity {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// issues accumulates the findings of the check functions. The Validate
+// functions return the first error, ValidateReport returns them all.
+type issues struct {
+	list []Issue
+}
+
+// errorf records an error.
+func (is *issues) errorf(path, code, format string, args ...any) {
+	is.list = append(is.list, Issue{Path: path, Code: code, Severity: SeverityError, Message: fmt.Sprintf(format, args...)})
+}
+
+// warnf records a warning.
+func (is *issues) warnf(path, code, format string, args ...any) {
+	is.list = append(is.list, Issue{Path: path, Code: code, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the first error, or nil.
+func (is *issues) err() error {
+	for _, i := range is.list {
+		if i.Severity == SeverityError {
+			return errors.New(i.Message)
+		}
+	}
+	return nil
+}
+
+// ParseReport validates a YAML spec like Parse followed by ValidateReport.
+// Unknown fields are reported with their line and column; a spec that cannot
+// be decoded is not validated further.
+func ParseReport(data []byte) *Report {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		var is issues
+		is.errorf("", CodeInvalid, "invalid YAML: %v", err)
+		return newReport(is)
+	}
+	if len(doc.Content) == 0 {
+		return ValidateReport(&v1.Spec{})
+	}
+	root := doc.Content[0]
+	unknown := checkNode(root, reflect.TypeOf(v1.Spec{}), "")
+
+	var m map[string]any
+	if err := root.Decode(&m); err != nil {
+		var is issues
+		is.errorf("", CodeInvalid, "invalid spec: %v", err)
+		return newReport(is)
+	}
+	return reportFromMap(unknown, m)
+}
+
+// ReportFromMap validates a spec received as a map, as passed to create.
+// Unknown fields and decoding errors are reported as well; a spec that
+// cannot be decoded is not validated further.
+func ReportFromMap(m map[string]any) *Report {
+	return reportFromMap(CheckUnknownFields(m), m)
+}
+
+// reportFromMap reports the unknown fields found in a spec, then decodes and
+// validates it.
+func reportFromMap(unknown error, m map[string]any) *Report {
+	var is issues
+	if unknown != nil {
+		for _, e := range flattenErrors(unknown) {
+			issue := Issue{Code: CodeUnknownField, Severity: SeverityError, Message: e.Error()}
+			var ufe *UnknownFieldError
+			if errors.As(e, &ufe) {
+				issue.Path = strings.TrimPrefix(ufe.Path+"."+ufe.Field, ".")
+				issue.Line, issue.Column = ufe.Line, ufe.Column
+			}
+			is.list = append(is.list, issue)
+		}
+	}
+	s, err := v1.SpecFromMap(m)
+	if err != nil {
+		is.errorf("", CodeInvalid, "failed to parse spec: %v", err)
+		return newReport(is)
+	}
+	report := ValidateReport(s)
+	report.Issues = append(is.list, report.Issues...)
+	report.Valid = report.Valid && is.err() == nil
+	return report
+}
+
+// flattenErrors returns the leaves of joined errors.
+func flattenErrors(err error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var out []error
+	for _, e := range joined.Unwrap() {
+		out = append(out, flattenErrors(e)...)
+	}
+	return out
+}
+
+// ValidateReport validates a spec like ValidateEarly but reports every error
+// instead of the first one, together with lint warnings about settings that
+// are valid but most likely unintended. Spec-level defaults are applied to
+// the spec first.
+func ValidateReport(spec *v1.Spec) *Report {
+	var is issues
+	if spec == nil {
+		is.errorf("", CodeRequired, "spec cannot be nil")
+		return newReport(is)
+	}
+
+	ApplyDefaults(spec)
+
+	checkProviders(&is, spec.Providers)
+	providerNames := make(map[string]bool)
+	for _, p := range spec.Providers {
+		providerNames[p.Name] = true
+	}
+	if spec.DefaultProvider != "" && !providerNames[spec.DefaultProvider] {
+		is.errorf("defaultProvider", CodeReference, "defaultProvider %q does not match any defined provider", spec.DefaultProvider)
+	} else if spec.DefaultProvider != "" {
+		for i, p := range spec.Providers {
+			if p.Default && p.Name != spec.DefaultProvider {
+				is.errorf(fmt.Sprintf("providers[%d].default", i), CodeConflict,
+					"provider %q is marked as default, but defaultProvider is set to %q (these must match)", p.Name, spec.DefaultProvider)
+			}
+		}
+	}
+
+	if _, err := CreateDeadline(spec); err != nil {
+		is.errorf("createDeadline", CodeInvalid, "%s", err)
+	}
+	if _, err := ExpiresAfter(spec); err != nil {
+		is.errorf("expiresAfter", CodeInvalid, "%s", err)
+	}
+	if err := ValidateNamePrefix(spec); err != nil {
+		is.errorf("namePrefix", CodeInvalid, "%s", err)
+	}
+	if err := ValidateLabels(spec.Labels); err != nil {
+		is.errorf("labels", CodeInvalid, "%s", err)
+	}
+
+	checkParent(&is, spec)
+	checkKeys(&is, spec.Keys)
+	checkNetworks(&is, spec.Networks)
+	checkVMs(&is, spec.Vms)
+	checkNICs(&is, spec.Networks, spec.Vms)
+	checkDataDisks(&is, spec.Vms)
+	checkStaticIPs(&is, spec.Networks, spec.Vms)
+	checkGuestNetworks(&is, spec.Vms)
+	checkRawUserData(&is, spec)
+	checkIdle(&is, spec)
+	checkImages(&is, spec)
+	checkTunnels(&is, spec.Tunnels, spec.Networks)
+	checkAccess(&is, spec.Access, spec.Networks, spec.Vms)
+	checkServices(&is, spec.Services, spec.Networks)
+	checkCertificates(&is, spec.Ca, spec.Certificates, spec.Vms)
+	checkProviderRefs(&is, spec, providerNames)
+	checkTemplateRefsExist(&is, spec)
+	checkCrossProviderRefs(&is, spec)
+	checkResourceRefs(&is, spec, NewTemplatedFields())
+
+	lintSpec(&is, spec)
+	return newReport(is)
+}
+
+// newReport builds a report from accumulated issues.
+func newReport(is issues) *Report {
+	report := &Report{Valid: is.err() == nil, Issues: is.list}
+	if report.Issues == nil {
+		report.Issues = []Issue{}
+	}
+	return report
+}
+
+// lintSpec warns about images and networks that nothing uses, and fields
+// that are ignored.
+func lintSpec(is *issues, spec *v1.Spec) {
+	referenced := make(map[string]bool)
+	for _, ref := range ExtractTemplateRefs(spec) {
+		referenced[ref.Kind+":"+ref.Name] = true
+	}
+
+	for i, img := range spec.Images {
+		if img.Name == "" || referenced["image:"+img.Name] || (img.Spec.Alias != "" && referenced["image:"+img.Spec.Alias]) {
+			continue
+		}
+		is.warnf(fmt.Sprintf("images[%d]", i), CodeUnusedImage, "image %q is downloaded but never referenced", img.Name)
+	}
+
+	// Templated VM networks may resolve to any network.
+	templated := false
+	for _, n := range spec.Networks {
+		if n.Spec.AttachTo != "" {
+			referenced["network:"+n.Spec.AttachTo] = true
+		}
+	}
+	for _, vm := range spec.Vms {
+		for _, n := range append([]string{vm.Spec.Network}, vm.Spec.Networks...) {
+			templated = templated || IsTemplated(n)
+			referenced["network:"+n] = true
+		}
+	}
+	for _, t := range spec.Tunnels {
+		referenced["network:"+t.Spec.LocalNetwork] = true
+		referenced["network:"+t.Spec.RemoteNetwork] = true
+	}
+	for _, a := range spec.Access {
+		referenced["network:"+a.Spec.Network] = true
+	}
+	for _, svc := range spec.Services {
+		referenced["network:"+svc.Spec.Network] = true
+	}
+	for i, n := range spec.Networks {
+		if templated || n.Name == "" || referenced["network:"+n.Name] {
+			continue
+		}
+		is.warnf(fmt.Sprintf("networks[%d]", i), CodeUnusedNetwork, "network %q has no VM, network, tunnel, access point or service attached", n.Name)
+	}
+
+	for i, vm := range spec.Vms {
+		if vm.Spec.Network != "" && len(vm.Spec.Networks) > 0 {
+			is.warnf(fmt.Sprintf("vms[%d].spec.network", i), CodeIgnoredField, "vm %q: network is ignored because networks is set", vm.Name)
+		}
+	}
+}
+
+// resourcePath returns the path of a named key, network or VM, or "" if the
+// spec has none.
+func resourcePath(spec *v1.Spec, kind, name string) string {
+	var names []string
+	var field string
+	switch kind {
+	case "key":
+		field = "keys"
+		for _, k := range spec.Keys {
+			names = append(names, k.Name)
+		}
+	case "network":
+		field = "networks"
+		for _, n := range spec.Networks {
+			names = append(names, n.Name)
+		}
+	case "vm":
+		field = "vms"
+		for _, vm := range spec.Vms {
+			names = append(names, vm.Name)
+		}
+	}
+	for i, n := range names {
+		if n == name {
+			return fmt.Sprintf("%s[%d]", field, i)
+		}
+	}
+	return ""
+}
+
+// jsonName returns the JSON name of a struct field.
+func jsonName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
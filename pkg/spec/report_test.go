@@ -0,0 +1,184 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		yaml      string
+		wantValid bool
+		// want lists the expected issues as "severity path code".
+		want []string
+	}{
+		{
+			name: "valid spec",
+			yaml: `
+providers:
+  - name: p
+    engine: go://test
+networks:
+  - name: net
+    kind: bridge
+vms:
+  - name: vm
+    spec:
+      memory: 512
+      vcpus: 1
+      network: net
+`,
+			wantValid: true,
+		},
+		{
+			name: "all errors are reported",
+			yaml: `
+providers:
+  - name: p
+    engine: go://test
+networks:
+  - name: net
+    kind: bridge
+vms:
+  - name: vm1
+    spec:
+      memory: 0
+      vcpus: 1
+      network: net
+  - name: vm2
+    spec:
+      memory: 512
+      vcpus: 0
+      network: net
+`,
+			want: []string{
+				"error vms[0].spec.memory invalid",
+				"error vms[1].spec.vcpus invalid",
+			},
+		},
+		{
+			name: "unknown fields are reported with their line",
+			yaml: `
+providers:
+  - name: p
+    engine: go://test
+    enigne: typo
+`,
+			want: []string{"error providers[0].enigne unknown-field"},
+		},
+		{
+			name: "lint warnings do not invalidate the spec",
+			yaml: `
+providers:
+  - name: p
+    engine: go://test
+images:
+  - name: unused
+    spec:
+      source: https://example.com/disk.qcow2
+      sha256: abababababababababababababababababababababababababababababababab
+networks:
+  - name: net
+    kind: bridge
+  - name: lonely
+    kind: bridge
+vms:
+  - name: vm
+    spec:
+      memory: 512
+      vcpus: 1
+      network: lonely
+      networks: [net]
+`,
+			wantValid: true,
+			want: []string{
+				"warning images[0] unused-image",
+				"warning vms[0].spec.network ignored-field",
+			},
+		},
+		{
+			name: "invalid YAML",
+			yaml: "providers: [",
+			want: []string{"error  invalid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ParseReport([]byte(tt.yaml))
+			if report.Valid != tt.wantValid {
+				t.Errorf("Valid = %v, want %v (issues: %+v)", report.Valid, tt.wantValid, report.Issues)
+			}
+			var got []string
+			for _, i := range report.Issues {
+				got = append(got, i.Severity+" "+i.Path+" "+i.Code)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("issues = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("issue %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseReportUnknownFieldLocation(t *testing.T) {
+	report := ParseReport([]byte("providers:\n  - name: p\n    engine: go://test\nvmz: []\n"))
+	errs := report.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("Errors() = %+v, want one unknown field", errs)
+	}
+	if errs[0].Path != "vmz" || errs[0].Line != 4 || errs[0].Column != 1 {
+		t.Errorf("issue = %+v, want path vmz at 4:1", errs[0])
+	}
+}
+
+func TestReportFromMap(t *testing.T) {
+	report := ReportFromMap(map[string]any{
+		"providers": []any{map[string]any{"name": "p", "engine": "go://test"}},
+		"vms": []any{map[string]any{
+			"name":   "vm",
+			"spec":   map[string]any{"memory": 512, "vcpus": 1, "network": "missing"},
+			"labelz": map[string]any{},
+		}},
+	})
+	if report.Valid {
+		t.Fatal("Valid = true, want false")
+	}
+	codes := make(map[string]string)
+	for _, i := range report.Errors() {
+		codes[i.Path] = i.Code
+	}
+	if codes["vms[0].labelz"] != CodeUnknownField {
+		t.Errorf("missing unknown field issue, got %+v", report.Issues)
+	}
+	if codes["vms[0].spec.network"] != CodeReference {
+		t.Errorf("missing reference issue, got %+v", report.Issues)
+	}
+}
+
+func TestValidateReportNil(t *testing.T) {
+	report := ValidateReport(nil)
+	if report.Valid || len(report.Errors()) != 1 || report.Errors()[0].Code != CodeRequired {
+		t.Errorf("ValidateReport(nil) = %+v", report)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"net"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ValidateStaticIPs validates the static IPs of VMs and the DHCP host
+// reservations of networks. It ensures:
+// - Reservations have a valid MAC address and an IPv4 address in the cidr
+// - The first network of a VM with an ip is declared with a fixed cidr
+// - The ip is a host address of that cidr, reserved through DHCP
+// - MAC addresses and IPs are reserved at most once per network
+func ValidateStaticIPs(networks []v1.NetworkResource, vms []v1.VMResource) error {
+	var is issues
+	checkStaticIPs(&is, networks, vms)
+	return is.err()
+}
+
+// checkStaticIPs reports every problem ValidateStaticIPs fails on.
+func checkStaticIPs(is *issues, networks []v1.NetworkResource, vms []v1.VMResource) {
+	type reserved struct {
+		ips  map[string]string
+		macs map[string]bool
+	}
+	byName := make(map[string]*v1.NetworkResource, len(networks))
+	reservations := make(map[string]*reserved, len(networks))
+	for i := range networks {
+		n := &networks[i]
+		byName[n.Name] = n
+		r := &reserved{ips: make(map[string]string), macs: make(map[string]bool)}
+		reservations[n.Name] = r
+		if n.Spec.Dhcp == nil {
+			continue
+		}
+		for j, h := range n.Spec.Dhcp.Hosts {
+			path := fmt.Sprintf("networks[%d].spec.dhcp.hosts[%d]", i, j)
+			if n.Spec.Cidr == "auto" {
+				is.errorf(path, CodeInvalid, "network %q: DHCP hosts cannot be set when cidr is auto", n.Name)
+				continue
+			}
+			switch _, err := net.ParseMAC(h.Mac); {
+			case h.Mac == "":
+				is.errorf(path+".mac", CodeRequired, "network %q: dhcp.hosts[%d].mac is required", n.Name, j)
+			case IsTemplated(h.Mac):
+			case err != nil:
+				is.errorf(path+".mac", CodeInvalid, "network %q: dhcp.hosts[%d].mac %q is not a MAC address", n.Name, j, h.Mac)
+			case r.macs[h.Mac]:
+				is.errorf(path+".mac", CodeDuplicate, "network %q: MAC address %s is reserved twice", n.Name, h.Mac)
+			}
+			r.macs[h.Mac] = true
+			if h.Ip == "" {
+				is.errorf(path+".ip", CodeRequired, "network %q: dhcp.hosts[%d].ip is required", n.Name, j)
+				continue
+			}
+			if err := checkHostIP(n, h.Ip); err != nil {
+				is.errorf(path+".ip", CodeInvalid, "network %q: dhcp.hosts[%d].ip: %v", n.Name, j, err)
+			} else if owner, ok := r.ips[h.Ip]; ok {
+				is.errorf(path+".ip", CodeDuplicate, "network %q: %s is already reserved for %s", n.Name, h.Ip, owner)
+			}
+			r.ips[h.Ip] = h.Mac
+		}
+	}
+
+	for i, vm := range vms {
+		if vm.Spec.Ip == "" {
+			continue
+		}
+		path := fmt.Sprintf("vms[%d].spec.ip", i)
+		vmNetworks := vm.Spec.Networks
+		if len(vmNetworks) == 0 && vm.Spec.Network != "" {
+			vmNetworks = []string{vm.Spec.Network}
+		}
+		if len(vmNetworks) == 0 {
+			is.errorf(path, CodeInvalid, "vm %q: ip requires a network", vm.Name)
+			continue
+		}
+		if IsTemplated(vmNetworks[0]) {
+			continue
+		}
+		n, ok := byName[vmNetworks[0]]
+		switch {
+		case !ok:
+			is.errorf(path, CodeReference, "vm %q: ip requires its first network %q to be declared in the spec", vm.Name, vmNetworks[0])
+			continue
+		case n.Spec.Cidr == "auto":
+			is.errorf(path, CodeInvalid, "vm %q: ip cannot be set on network %q whose cidr is auto", vm.Name, n.Name)
+			continue
+		case n.Spec.Dhcp != nil && !n.Spec.Dhcp.Enabled:
+			is.errorf(path, CodeInvalid, "vm %q: ip requires DHCP on network %q to reserve it", vm.Name, n.Name)
+			continue
+		}
+		if err := checkHostIP(n, vm.Spec.Ip); err != nil {
+			is.errorf(path, CodeInvalid, "vm %q: ip: %v", vm.Name, err)
+			continue
+		}
+		r := reservations[n.Name]
+		if owner, ok := r.ips[vm.Spec.Ip]; ok {
+			is.errorf(path, CodeDuplicate, "vm %q: %s is already reserved for %s on network %q", vm.Name, vm.Spec.Ip, owner, n.Name)
+		}
+		r.ips[vm.Spec.Ip] = fmt.Sprintf("vm %q", vm.Name)
+	}
+}
+
+// checkHostIP checks that ip is an IPv4 host address of the cidr of network
+// n, other than its gateway. Templated values are not checked.
+func checkHostIP(n *v1.NetworkResource, ip string) error {
+	if IsTemplated(ip) || IsTemplated(n.Spec.Cidr) {
+		return nil
+	}
+	addr := net.ParseIP(ip).To4()
+	if addr == nil {
+		return fmt.Errorf("%q is not an IPv4 address", ip)
+	}
+	_, ipNet, err := net.ParseCIDR(n.Spec.Cidr)
+	if err != nil {
+		// Reported by the network checks
+		return nil
+	}
+	if !ipNet.Contains(addr) {
+		return fmt.Errorf("%s is not in the network cidr %s", ip, n.Spec.Cidr)
+	}
+	network := ipNet.IP.To4()
+	broadcast := make(net.IP, len(network))
+	for i := range network {
+		broadcast[i] = network[i] | ^ipNet.Mask[i]
+	}
+	gateway := net.ParseIP(n.Spec.Gateway).To4()
+	if gateway == nil {
+		// Providers default the gateway to the first host address
+		gateway = make(net.IP, len(network))
+		copy(gateway, network)
+		gateway[len(gateway)-1]++
+	}
+	switch {
+	case addr.Equal(network), addr.Equal(broadcast):
+		return fmt.Errorf("%s is not a host address of %s", ip, n.Spec.Cidr)
+	case addr.Equal(gateway):
+		return fmt.Errorf("%s is the gateway of the network", ip)
+	}
+	return nil
+}
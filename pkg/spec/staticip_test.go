@@ -0,0 +1,143 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateStaticIPs(t *testing.T) {
+	network := func(cidr string, dhcp *v1.DHCPSpec) v1.NetworkResource {
+		return v1.NetworkResource{Name: "net", Spec: v1.NetworkSpec{Cidr: cidr, Dhcp: dhcp}}
+	}
+	vm := func(name, ip string, networks ...string) v1.VMResource {
+		return v1.VMResource{Name: name, Spec: v1.VMSpec{Ip: ip, Networks: networks}}
+	}
+	hosts := func(hosts ...v1.DHCPHostSpec) *v1.DHCPSpec {
+		return &v1.DHCPSpec{Enabled: true, Hosts: hosts}
+	}
+
+	tests := []struct {
+		name      string
+		networks  []v1.NetworkResource
+		vms       []v1.VMResource
+		errSubstr string
+	}{
+		{
+			name:     "static IPs and reservations pass",
+			networks: []v1.NetworkResource{network("192.168.100.1/24", hosts(v1.DHCPHostSpec{Mac: "52:54:00:12:34:56", Ip: "192.168.100.20"}))},
+			vms:      []v1.VMResource{vm("web", "192.168.100.10", "net"), vm("db", "192.168.100.11", "net")},
+		},
+		{
+			name:     "templated ip passes",
+			networks: []v1.NetworkResource{network("192.168.100.1/24", nil)},
+			vms:      []v1.VMResource{vm("web", "{{ .Env.WEB_IP }}", "net")},
+		},
+		{
+			name:      "ip outside the cidr fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", nil)},
+			vms:       []v1.VMResource{vm("web", "10.0.0.10", "net")},
+			errSubstr: "10.0.0.10 is not in the network cidr",
+		},
+		{
+			name:      "gateway fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", nil)},
+			vms:       []v1.VMResource{vm("web", "192.168.100.1", "net")},
+			errSubstr: "is the gateway of the network",
+		},
+		{
+			name:      "broadcast address fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", nil)},
+			vms:       []v1.VMResource{vm("web", "192.168.100.255", "net")},
+			errSubstr: "is not a host address",
+		},
+		{
+			name:      "IPv6 fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", nil)},
+			vms:       []v1.VMResource{vm("web", "fd00::10", "net")},
+			errSubstr: "is not an IPv4 address",
+		},
+		{
+			name:      "duplicate ip fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", hosts(v1.DHCPHostSpec{Mac: "52:54:00:12:34:56", Ip: "192.168.100.10"}))},
+			vms:       []v1.VMResource{vm("web", "192.168.100.10", "net")},
+			errSubstr: "192.168.100.10 is already reserved for 52:54:00:12:34:56",
+		},
+		{
+			name:      "undeclared network fails",
+			vms:       []v1.VMResource{vm("web", "192.168.100.10", "parent-net")},
+			errSubstr: `first network "parent-net" to be declared`,
+		},
+		{
+			name:      "vm without network fails",
+			vms:       []v1.VMResource{vm("web", "192.168.100.10")},
+			errSubstr: "ip requires a network",
+		},
+		{
+			name:      "cidr auto fails",
+			networks:  []v1.NetworkResource{network("auto", nil)},
+			vms:       []v1.VMResource{vm("web", "192.168.100.10", "net")},
+			errSubstr: "whose cidr is auto",
+		},
+		{
+			name:      "DHCP disabled fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", &v1.DHCPSpec{})},
+			vms:       []v1.VMResource{vm("web", "192.168.100.10", "net")},
+			errSubstr: "ip requires DHCP",
+		},
+		{
+			name:      "invalid MAC fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", hosts(v1.DHCPHostSpec{Mac: "52:54:00", Ip: "192.168.100.10"}))},
+			errSubstr: `mac "52:54:00" is not a MAC address`,
+		},
+		{
+			name: "duplicate MAC fails",
+			networks: []v1.NetworkResource{network("192.168.100.1/24", hosts(
+				v1.DHCPHostSpec{Mac: "52:54:00:12:34:56", Ip: "192.168.100.10"},
+				v1.DHCPHostSpec{Mac: "52:54:00:12:34:56", Ip: "192.168.100.11"},
+			))},
+			errSubstr: "MAC address 52:54:00:12:34:56 is reserved twice",
+		},
+		{
+			name:      "reservation without ip fails",
+			networks:  []v1.NetworkResource{network("192.168.100.1/24", hosts(v1.DHCPHostSpec{Mac: "52:54:00:12:34:56"}))},
+			errSubstr: "dhcp.hosts[0].ip is required",
+		},
+		{
+			name:      "reservation on cidr auto fails",
+			networks:  []v1.NetworkResource{network("auto", hosts(v1.DHCPHostSpec{Mac: "52:54:00:12:34:56", Ip: "10.0.0.2"}))},
+			errSubstr: "DHCP hosts cannot be set when cidr is auto",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStaticIPs(tt.networks, tt.vms)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateStaticIPs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateStaticIPs() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
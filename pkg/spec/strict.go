@@ -0,0 +1,263 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ExtensionPrefix marks keys that strict decoding ignores, so that YAML
+// anchors can be declared next to the fields that use them, e.g.
+// "x-vm: &vm {memory: 2048}".
+const ExtensionPrefix = "x-"
+
+// mergeKey is the YAML merge key ("<<: *anchor").
+const mergeKey = "<<"
+
+// UnknownFieldError reports a key that is not part of the spec schema.
+type UnknownFieldError struct {
+	// Path locates the mapping holding the key, e.g. "vms[0].spec".
+	Path string
+	// Field is the unknown key.
+	Field string
+	// Line and Column locate the key in the YAML source; zero when the spec
+	// was not decoded from YAML.
+	Line, Column int
+	// Suggestion is a known field with a similar name, if any.
+	Suggestion string
+}
+
+// Error implements error.
+func (e *UnknownFieldError) Error() string {
+	var sb strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %d, column %d: ", e.Line, e.Column)
+	}
+	fmt.Fprintf(&sb, "unknown field %q", e.Field)
+	if e.Path != "" {
+		fmt.Fprintf(&sb, " in %s", e.Path)
+	}
+	if e.Suggestion != "" {
+		fmt.Fprintf(&sb, " (did you mean %q?)", e.Suggestion)
+	}
+	return sb.String()
+}
+
+// Parse strictly decodes a YAML spec. Unknown fields are reported with their
+// line and column. Anchors, aliases, and merge keys are resolved, and keys
+// starting with ExtensionPrefix are ignored.
+func Parse(data []byte) (*v1.Spec, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &v1.Spec{}, nil
+	}
+	root := doc.Content[0]
+	if err := checkNode(root, reflect.TypeOf(v1.Spec{}), ""); err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := root.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return v1.SpecFromMap(m)
+}
+
+// CheckUnknownFields reports keys of a decoded spec that are not part of the
+// schema. Use it for specs received as maps (e.g. from forge), where line
+// numbers are no longer available.
+func CheckUnknownFields(m map[string]any) error {
+	if len(m) == 0 {
+		return nil
+	}
+	var node yaml.Node
+	if err := node.Encode(m); err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	// Encoded nodes carry no source position.
+	clearPositions(&node)
+	return checkNode(&node, reflect.TypeOf(v1.Spec{}), "")
+}
+
+// checkNode walks node against the Go type t and returns every unknown key.
+func checkNode(node *yaml.Node, t reflect.Type, path string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if node.Kind == yaml.AliasNode {
+		return checkNode(node.Alias, t, path)
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil // type mismatches are reported by the decoder
+		}
+		fields := jsonFields(t)
+		var errs []error
+		for _, pair := range mappingPairs(node) {
+			key, value := pair[0], pair[1]
+			if strings.HasPrefix(key.Value, ExtensionPrefix) {
+				continue
+			}
+			field, ok := fields[key.Value]
+			if !ok {
+				errs = append(errs, &UnknownFieldError{
+					Path:       path,
+					Field:      key.Value,
+					Line:       key.Line,
+					Column:     key.Column,
+					Suggestion: suggestField(key.Value, fields),
+				})
+				continue
+			}
+			if err := checkNode(value, field, joinPath(path, key.Value)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		var errs []error
+		for i, item := range node.Content {
+			if err := checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	default:
+		// Maps (providerSpec, environment, ...) and scalars are free-form.
+		return nil
+	}
+}
+
+// mappingPairs returns the key/value pairs of a mapping node, expanding merge
+// keys. Keys set directly take precedence over merged ones.
+func mappingPairs(node *yaml.Node) [][2]*yaml.Node {
+	var direct, merged [][2]*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value != mergeKey {
+			direct = append(direct, [2]*yaml.Node{key, value})
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, src := range sources {
+			for src.Kind == yaml.AliasNode {
+				src = src.Alias
+			}
+			if src.Kind == yaml.MappingNode {
+				merged = append(merged, mappingPairs(src)...)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(direct))
+	for _, p := range direct {
+		seen[p[0].Value] = true
+	}
+	for _, p := range merged {
+		if !seen[p[0].Value] {
+			seen[p[0].Value] = true
+			direct = append(direct, p)
+		}
+	}
+	return direct
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestField returns the known field closest to name, if it is at most two
+// edits away.
+func suggestField(name string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	best, bestDist := "", 3
+	for _, n := range names {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(n)); d < bestDist {
+			best, bestDist = n, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// joinPath appends a field name to a dotted path.
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// clearPositions zeroes the line and column of node and its children.
+func clearPositions(node *yaml.Node) {
+	node.Line, node.Column = 0, 0
+	for _, child := range node.Content {
+		clearPositions(child)
+	}
+}
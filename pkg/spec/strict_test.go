@@ -0,0 +1,131 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseUnknownField(t *testing.T) {
+	data := `providers:
+  - name: stub
+    engine: go://stub
+vms:
+  - name: vm1
+    spec:
+      memory: 1024
+      vcups: 2
+`
+	_, err := Parse([]byte(data))
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+	var ufe *UnknownFieldError
+	if !errors.As(err, &ufe) {
+		t.Fatalf("expected UnknownFieldError, got %T: %v", err, err)
+	}
+	if ufe.Field != "vcups" || ufe.Path != "vms[0].spec" || ufe.Line != 8 || ufe.Column != 7 || ufe.Suggestion != "vcpus" {
+		t.Errorf("unexpected error: %+v", ufe)
+	}
+	want := `line 8, column 7: unknown field "vcups" in vms[0].spec (did you mean "vcpus"?)`
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestParseAnchorsAndExtensions(t *testing.T) {
+	data := `x-vm: &vm
+  memory: 1024
+  vcpus: 2
+  disk:
+    size: 10G
+providers:
+  - name: stub
+    engine: go://stub
+    spec:
+      anything: goes
+vms:
+  - name: vm1
+    spec: *vm
+  - name: vm2
+    spec:
+      <<: *vm
+      memory: 2048
+`
+	s, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(s.Vms) != 2 {
+		t.Fatalf("expected 2 VMs, got %d", len(s.Vms))
+	}
+	if s.Vms[0].Spec.Vcpus != 2 || s.Vms[0].Spec.Disk.Size != "10G" {
+		t.Errorf("alias not resolved: %+v", s.Vms[0].Spec)
+	}
+	if s.Vms[1].Spec.Memory != 2048 || s.Vms[1].Spec.Vcpus != 2 {
+		t.Errorf("merge key not resolved: %+v", s.Vms[1].Spec)
+	}
+}
+
+func TestParseUnknownFieldInMergedAnchor(t *testing.T) {
+	data := `x-vm: &vm
+  memroy: 1024
+vms:
+  - name: vm1
+    spec:
+      <<: *vm
+`
+	_, err := Parse([]byte(data))
+	var ufe *UnknownFieldError
+	if !errors.As(err, &ufe) || ufe.Field != "memroy" || ufe.Line != 2 {
+		t.Fatalf("expected unknown field at the anchor definition, got %v", err)
+	}
+}
+
+func TestCheckUnknownFields(t *testing.T) {
+	m := map[string]any{
+		"networks": []any{
+			map[string]any{"name": "n1", "kind": "bridge", "spec": map[string]any{"cdir": "10.0.0.0/24"}},
+		},
+		"vms": []any{
+			map[string]any{"name": "vm1", "spec": map[string]any{"cloudInit": map[string]any{"environment": map[string]any{"ANY_KEY": "v"}}}},
+		},
+		"stateDir": "/tmp/state",
+		"bogus":    true,
+	}
+	err := CheckUnknownFields(m)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		`unknown field "cdir" in networks[0].spec (did you mean "cidr"?)`,
+		`unknown field "bogus"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err.Error(), want)
+		}
+	}
+	if strings.Contains(err.Error(), "line") {
+		t.Errorf("map input should not report positions: %v", err)
+	}
+
+	if err := CheckUnknownFields(map[string]any{"stateDir": "/tmp"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
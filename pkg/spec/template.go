@@ -37,6 +37,18 @@ type TemplateContext struct {
 	VMs map[string]VMTemplateData
 	// Images contains template data for image resources, keyed by resource name.
 	Images map[string]ImageTemplateData
+	// Tunnels contains template data for tunnel resources, keyed by resource name.
+	Tunnels map[string]TunnelTemplateData
+	// Access contains template data for access resources, keyed by resource name.
+	Access map[string]AccessTemplateData
+	// Services contains template data for service resources, keyed by resource name.
+	Services map[string]ServiceTemplateData
+	// Certificates contains template data for certificate resources, keyed by resource name.
+	Certificates map[string]CertificateTemplateData
+	// CA is the test certificate authority of the environment, empty when
+	// the spec declares neither a CA nor certificates.
+	// Note: Like DefaultBaseImage, it is NOT a resource reference.
+	CA CATemplateData
 	// DefaultBaseImage is the path to the default base image if configured.
 	// Note: This is a plain string value, NOT a resource reference.
 	// References like {{ .DefaultBaseImage }} should NOT be extracted as ResourceRefs.
@@ -69,6 +81,12 @@ type NetworkTemplateData struct {
 	InterfaceName string
 	// UUID is the provider-specific unique identifier.
 	UUID string
+	// NTPServer is the address of the network NTP server, empty when the
+	// network has none.
+	NTPServer string
+	// BootRoot is the directory holding the boot artifacts and files of
+	// the network, empty when its TFTP server declares none.
+	BootRoot string
 }
 
 // VMTemplateData contains the template-accessible fields for a VM resource.
@@ -89,22 +107,110 @@ type ImageTemplateData struct {
 	Path string
 	// Name is the image resource name from the spec.
 	Name string
+	// SHA256 is the hex-encoded digest of the cached image file.
+	SHA256 string
+}
+
+// TunnelTemplateData contains the template-accessible fields for a tunnel resource.
+// "Local" refers to the gateway on localNetwork, "Remote" to the one on remoteNetwork.
+type TunnelTemplateData struct {
+	// LocalPrivateKey is the local gateway WireGuard private key.
+	LocalPrivateKey string
+	// LocalPublicKey is the local gateway WireGuard public key.
+	LocalPublicKey string
+	// RemotePrivateKey is the remote gateway WireGuard private key.
+	RemotePrivateKey string
+	// RemotePublicKey is the remote gateway WireGuard public key.
+	RemotePublicKey string
+	// LocalAddress is the local tunnel address in CIDR notation.
+	LocalAddress string
+	// RemoteAddress is the remote tunnel address in CIDR notation.
+	RemoteAddress string
+	// ListenPort is the remote gateway UDP port.
+	ListenPort int
+	// Endpoint is the remote gateway host:port dialed by the local end.
+	Endpoint string
+	// LocalConfig is the wg-quick config for the local gateway.
+	LocalConfig string
+	// RemoteConfig is the wg-quick config for the remote gateway.
+	RemoteConfig string
+}
+
+// AccessTemplateData contains the template-accessible fields for an access resource.
+type AccessTemplateData struct {
+	// ServerPublicKey is the server WireGuard public key.
+	ServerPublicKey string
+	// ClientPrivateKey is the client WireGuard private key.
+	ClientPrivateKey string
+	// ClientPublicKey is the client WireGuard public key.
+	ClientPublicKey string
+	// ServerAddress is the server VPN address in CIDR notation.
+	ServerAddress string
+	// ClientAddress is the client VPN address in CIDR notation.
+	ClientAddress string
+	// ListenPort is the server UDP port.
+	ListenPort int
+	// ServerConfig is the wg-quick config installed on the server VM.
+	ServerConfig string
+}
+
+// ServiceTemplateData contains the template-accessible fields for a service resource.
+type ServiceTemplateData struct {
+	// Address is the IP address the service listens on.
+	Address string
+	// Port is the TCP port of the service.
+	Port int
+	// Endpoint is the URL of the service (e.g., "http://192.168.100.1:5000").
+	Endpoint string
+	// AccessKey and SecretKey are the credentials of object-store services.
+	AccessKey string
+	SecretKey string
+}
+
+// CATemplateData contains the template-accessible fields of the test CA.
+type CATemplateData struct {
+	// Cert is the PEM-encoded CA certificate.
+	Cert string
+	// Key is the PEM-encoded CA private key.
+	Key string
+	// CertPath is the file path to the CA certificate.
+	CertPath string
+	// KeyPath is the file path to the CA private key.
+	KeyPath string
+}
+
+// CertificateTemplateData contains the template-accessible fields for a certificate resource.
+type CertificateTemplateData struct {
+	// Cert is the PEM-encoded certificate.
+	Cert string
+	// Key is the PEM-encoded private key.
+	Key string
+	// CACert is the PEM-encoded certificate of the issuing CA.
+	CACert string
+	// CertPath is the file path to the certificate.
+	CertPath string
+	// KeyPath is the file path to the private key.
+	KeyPath string
 }
 
 // NewTemplateContext creates a new empty TemplateContext with initialized maps.
 func NewTemplateContext() *TemplateContext {
 	return &TemplateContext{
-		Keys:     make(map[string]KeyTemplateData),
-		Networks: make(map[string]NetworkTemplateData),
-		VMs:      make(map[string]VMTemplateData),
-		Images:   make(map[string]ImageTemplateData),
-		Env:      make(map[string]string),
+		Keys:         make(map[string]KeyTemplateData),
+		Networks:     make(map[string]NetworkTemplateData),
+		VMs:          make(map[string]VMTemplateData),
+		Images:       make(map[string]ImageTemplateData),
+		Tunnels:      make(map[string]TunnelTemplateData),
+		Access:       make(map[string]AccessTemplateData),
+		Services:     make(map[string]ServiceTemplateData),
+		Certificates: make(map[string]CertificateTemplateData),
+		Env:          make(map[string]string),
 	}
 }
 
 // hyphenKeyPattern matches template expressions like .Keys.name-with-hyphens.Field
 // and converts them to use index function: (index .Keys "name-with-hyphens").Field
-var hyphenKeyPattern = regexp.MustCompile(`\.(Keys|Networks|VMs|Images)\.([a-zA-Z0-9][a-zA-Z0-9_-]*[a-zA-Z0-9_-])\.(\w+)`)
+var hyphenKeyPattern = regexp.MustCompile(`\.(Keys|Networks|VMs|Images|Tunnels|Access|Services|Certificates)\.([a-zA-Z0-9][a-zA-Z0-9_-]*[a-zA-Z0-9_-])\.(\w+)`)
 
 // preprocessTemplate converts dot notation with hyphens to use index function.
 // For example: {{ .Keys.test-key.PublicKey }} -> {{ (index .Keys "test-key").PublicKey }}
@@ -373,6 +479,14 @@ func extractFromString(s string, refs *[]v1.ResourceRef, seen map[string]bool) {
 			kind = "vm"
 		case "Images":
 			kind = "image"
+		case "Tunnels":
+			kind = "tunnel"
+		case "Access":
+			kind = "access"
+		case "Services":
+			kind = "service"
+		case "Certificates":
+			kind = "certificate"
 		default:
 			// Skip unknown categories (e.g., Env, DefaultBaseImage)
 			// DefaultBaseImage is a plain string, not a resource reference
@@ -0,0 +1,97 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// userDataHeaders are the first bytes cloud-init recognizes user-data by.
+var userDataHeaders = []string{"#", "Content-Type:"}
+
+// ValidateRawUserData validates the raw cloud-init user-data of VMs. It
+// ensures:
+// - The user-data starts with a format header, e.g. #cloud-config or #!
+// - The VM sets no field the generated user-data is made from
+// - No feature injecting commands through cloud-init targets the VM
+func ValidateRawUserData(spec *v1.Spec) error {
+	var is issues
+	checkRawUserData(&is, spec)
+	return is.err()
+}
+
+// checkRawUserData reports every problem ValidateRawUserData fails on.
+func checkRawUserData(is *issues, spec *v1.Spec) {
+	servers := make(map[string]string)
+	for _, a := range spec.Access {
+		servers[a.Spec.Vm] = a.Name
+	}
+	trusting := make(map[string]bool)
+	for _, vm := range spec.Ca.Vms {
+		trusting[vm] = true
+	}
+	for _, c := range spec.Certificates {
+		trusting[c.Spec.Vm] = true
+	}
+	for i, vm := range spec.Vms {
+		raw := vm.Spec.CloudInit.RawUserData
+		if raw == "" {
+			continue
+		}
+		path := fmt.Sprintf("vms[%d].spec.cloudInit.rawUserData", i)
+		if !IsTemplated(raw) && !hasUserDataHeader(raw) {
+			is.errorf(path, CodeInvalid, "vm %q: cloudInit.rawUserData must start with a cloud-init header such as #cloud-config, #! or Content-Type:", vm.Name)
+		}
+
+		ci := vm.Spec.CloudInit
+		for _, c := range []struct {
+			field string
+			set   bool
+		}{
+			{"cloudInit.users", len(ci.Users) > 0},
+			{"cloudInit.packages", len(ci.Packages) > 0},
+			{"cloudInit.writeFiles", len(ci.WriteFiles) > 0},
+			{"cloudInit.runcmd", len(ci.Runcmd) > 0},
+			{"cloudInit.environment", len(ci.Environment) > 0},
+			{"cloudInit.secretEnvironment", len(ci.SecretEnvironment) > 0},
+			{"agent", vm.Spec.Agent != nil && vm.Spec.Agent.Enabled},
+			{"disk.fstrim", vm.Spec.Disk.Fstrim},
+		} {
+			if c.set {
+				is.errorf(path, CodeConflict, "vm %q: cloudInit.rawUserData cannot be combined with %s", vm.Name, c.field)
+			}
+		}
+		if access, ok := servers[vm.Name]; ok {
+			is.errorf(path, CodeConflict, "vm %q: cloudInit.rawUserData cannot be set on the server of access %q", vm.Name, access)
+		}
+		if trusting[vm.Name] {
+			is.errorf(path, CodeConflict, "vm %q: cloudInit.rawUserData cannot be set on a VM trusting the test CA or receiving a certificate", vm.Name)
+		}
+	}
+}
+
+// hasUserDataHeader reports whether user-data starts with a header cloud-init
+// recognizes.
+func hasUserDataHeader(userData string) bool {
+	for _, h := range userDataHeaders {
+		if strings.HasPrefix(userData, h) {
+			return true
+		}
+	}
+	return false
+}
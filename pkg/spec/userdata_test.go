@@ -0,0 +1,103 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestValidateRawUserData(t *testing.T) {
+	vm := func(ci v1.CloudInitSpec) v1.VMResource {
+		return v1.VMResource{Name: "web", Spec: v1.VMSpec{CloudInit: ci}}
+	}
+	const raw = "#cloud-config\ngrowpart: {mode: auto}\n"
+
+	tests := []struct {
+		name      string
+		spec      v1.Spec
+		errSubstr string
+	}{
+		{
+			name: "raw user-data with hostname passes",
+			spec: v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{Hostname: "web", RawUserData: raw})}},
+		},
+		{
+			name: "script passes",
+			spec: v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: "#!/bin/sh\necho hi\n"})}},
+		},
+		{
+			name: "templated user-data passes",
+			spec: v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: "{{ .Env.USER_DATA }}"})}},
+		},
+		{
+			name:      "missing header fails",
+			spec:      v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: "packages: [curl]\n"})}},
+			errSubstr: "must start with a cloud-init header",
+		},
+		{
+			name:      "structured fields fail",
+			spec:      v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: raw, Packages: []string{"curl"}})}},
+			errSubstr: "cannot be combined with cloudInit.packages",
+		},
+		{
+			name:      "environment fails",
+			spec:      v1.Spec{Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: raw, Environment: map[string]string{"A": "b"}})}},
+			errSubstr: "cannot be combined with cloudInit.environment",
+		},
+		{
+			name: "agent fails",
+			spec: v1.Spec{Vms: []v1.VMResource{{Name: "web", Spec: v1.VMSpec{
+				CloudInit: v1.CloudInitSpec{RawUserData: raw},
+				Agent:     &v1.VMAgentSpec{Enabled: true},
+			}}}},
+			errSubstr: "cannot be combined with agent",
+		},
+		{
+			name: "access server fails",
+			spec: v1.Spec{
+				Vms:    []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: raw})},
+				Access: []v1.AccessResource{{Name: "vpn", Spec: v1.AccessSpec{Vm: "web"}}},
+			},
+			errSubstr: `cannot be set on the server of access "vpn"`,
+		},
+		{
+			name: "CA trust fails",
+			spec: v1.Spec{
+				Vms: []v1.VMResource{vm(v1.CloudInitSpec{RawUserData: raw})},
+				Ca:  v1.CASpec{Vms: []string{"web"}},
+			},
+			errSubstr: "trusting the test CA",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRawUserData(&tt.spec)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Errorf("ValidateRawUserData() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateRawUserData() error = %v, want error containing %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
@@ -18,10 +18,19 @@ package spec
 
 import (
 	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
 	"github.com/alexandremahdhaoui/testenv-vm/pkg/image"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/secrets"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/service"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/wireguard"
 )
 
 // ValidKeyTypes defines the allowed key types.
@@ -59,12 +68,15 @@ func NewTemplatedFields() *TemplatedFields {
 // It validates structure, syntax, and verifies template references point to
 // resources that exist in the spec. Templated fields are marked for Phase 2
 // validation after template rendering.
+// Spec-level defaults are applied to the spec first (see ApplyDefaults).
 // Returns TemplatedFields indicating which fields need Phase 2 validation.
 func ValidateEarly(spec *v1.Spec) (*TemplatedFields, error) {
 	if spec == nil {
 		return nil, fmt.Errorf("spec cannot be nil")
 	}
 
+	ApplyDefaults(spec)
+
 	templatedFields := NewTemplatedFields()
 
 	// Validate providers first (other validations depend on provider names)
@@ -92,6 +104,25 @@ func ValidateEarly(spec *v1.Spec) (*TemplatedFields, error) {
 		}
 	}
 
+	// Validate the creation deadline and expiry
+	if _, err := CreateDeadline(spec); err != nil {
+		return nil, err
+	}
+	if _, err := ExpiresAfter(spec); err != nil {
+		return nil, err
+	}
+	if err := ValidateNamePrefix(spec); err != nil {
+		return nil, err
+	}
+	if err := ValidateLabels(spec.Labels); err != nil {
+		return nil, err
+	}
+
+	// Validate the parent environment reference
+	if err := ValidateParent(spec); err != nil {
+		return nil, fmt.Errorf("parent validation failed: %w", err)
+	}
+
 	// Validate keys
 	if err := ValidateKeys(spec.Keys); err != nil {
 		return nil, fmt.Errorf("keys validation failed: %w", err)
@@ -107,11 +138,61 @@ func ValidateEarly(spec *v1.Spec) (*TemplatedFields, error) {
 		return nil, fmt.Errorf("vms validation failed: %w", err)
 	}
 
+	// Validate NIC options against the networks
+	if err := ValidateNICs(spec.Networks, spec.Vms); err != nil {
+		return nil, fmt.Errorf("nics validation failed: %w", err)
+	}
+
+	// Validate the data disks of the VMs
+	if err := ValidateDataDisks(spec.Vms); err != nil {
+		return nil, fmt.Errorf("disks validation failed: %w", err)
+	}
+
+	// Validate the static IPs of VMs and DHCP reservations
+	if err := ValidateStaticIPs(spec.Networks, spec.Vms); err != nil {
+		return nil, fmt.Errorf("static IP validation failed: %w", err)
+	}
+
+	// Validate the guest network config of VMs
+	if err := ValidateGuestNetworks(spec.Vms); err != nil {
+		return nil, fmt.Errorf("guest network validation failed: %w", err)
+	}
+
+	// Validate the raw cloud-init user-data of VMs
+	if err := ValidateRawUserData(spec); err != nil {
+		return nil, fmt.Errorf("raw user-data validation failed: %w", err)
+	}
+
+	// Validate the idle policy
+	if err := ValidateIdle(spec); err != nil {
+		return nil, fmt.Errorf("idle validation failed: %w", err)
+	}
+
 	// Validate images
 	if err := validateImages(spec); err != nil {
 		return nil, fmt.Errorf("images validation failed: %w", err)
 	}
 
+	// Validate tunnels
+	if err := ValidateTunnels(spec.Tunnels, spec.Networks); err != nil {
+		return nil, fmt.Errorf("tunnels validation failed: %w", err)
+	}
+
+	// Validate access points
+	if err := ValidateAccess(spec.Access, spec.Networks, spec.Vms); err != nil {
+		return nil, fmt.Errorf("access validation failed: %w", err)
+	}
+
+	// Validate services
+	if err := ValidateServices(spec.Services, spec.Networks); err != nil {
+		return nil, fmt.Errorf("services validation failed: %w", err)
+	}
+
+	// Validate the CA and certificates
+	if err := ValidateCertificates(spec.Ca, spec.Certificates, spec.Vms); err != nil {
+		return nil, fmt.Errorf("certificates validation failed: %w", err)
+	}
+
 	// Validate cross-references: provider references in resources
 	if err := validateProviderRefs(spec, providerNames); err != nil {
 		return nil, err
@@ -122,6 +203,11 @@ func ValidateEarly(spec *v1.Spec) (*TemplatedFields, error) {
 		return nil, err
 	}
 
+	// Validate references that cross provider boundaries
+	if err := validateCrossProviderRefs(spec); err != nil {
+		return nil, err
+	}
+
 	// Validate cross-references: resource references (network.AttachTo, vm.Network)
 	// Modified to skip templated fields and mark them for Phase 2 validation
 	if err := validateResourceRefs(spec, templatedFields); err != nil {
@@ -140,6 +226,76 @@ func Validate(spec *v1.Spec) error {
 	return err
 }
 
+// CreateDeadline returns the maximum duration of the creation of an
+// environment, or zero when the spec sets none.
+func CreateDeadline(spec *v1.Spec) (time.Duration, error) {
+	return parsePositiveDuration("createDeadline", spec.CreateDeadline)
+}
+
+// ExpiresAfter returns the age after which an environment is expired, or
+// zero when the spec sets none.
+func ExpiresAfter(spec *v1.Spec) (time.Duration, error) {
+	return parsePositiveDuration("expiresAfter", spec.ExpiresAfter)
+}
+
+// namePrefixPattern matches the name prefixes of environments: short enough
+// to keep provider names within their length limits.
+var namePrefixPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,14}[a-z0-9])?$`)
+
+// ValidateNamePrefix checks the optional name prefix of a spec.
+func ValidateNamePrefix(spec *v1.Spec) error {
+	if spec.NamePrefix == "" || namePrefixPattern.MatchString(spec.NamePrefix) {
+		return nil
+	}
+	return fmt.Errorf("namePrefix %q must be at most 16 lowercase letters, digits and hyphens, starting and ending with a letter or digit", spec.NamePrefix)
+}
+
+// Patterns of the user labels of a spec, which providers store in XML
+// metadata, extended attributes and ISO identifiers.
+var (
+	labelKeyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// reservedLabelPrefix is the prefix of the labels set by the orchestrator.
+const reservedLabelPrefix = "testenv-vm."
+
+// ValidateLabels checks the user labels of a spec.
+func ValidateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if strings.HasPrefix(k, reservedLabelPrefix) {
+			return fmt.Errorf("label %q uses the reserved prefix %q", k, reservedLabelPrefix)
+		}
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("label key %q must be at most 63 letters, digits, '.', '_', '/' and '-', starting and ending with a letter or digit", k)
+		}
+		if !labelValuePattern.MatchString(labels[k]) {
+			return fmt.Errorf("value %q of label %q must be at most 63 letters, digits, '.', '_' and '-', starting and ending with a letter or digit", labels[k], k)
+		}
+	}
+	return nil
+}
+
+// parsePositiveDuration parses the optional duration of a spec field.
+func parsePositiveDuration(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s %q is not a valid duration: %w", field, value, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive (got %q)", field, value)
+	}
+	return d, nil
+}
+
 // ValidateProviders validates provider configurations.
 // It ensures:
 // - At least one provider is defined
@@ -147,25 +303,35 @@ func Validate(spec *v1.Spec) error {
 // - Each provider has name and engine fields
 // - A default provider exists (either via DefaultProvider field or a provider marked default)
 func ValidateProviders(providers []v1.ProviderConfig) error {
+	var is issues
+	checkProviders(&is, providers)
+	return is.err()
+}
+
+// checkProviders reports every problem ValidateProviders fails on.
+func checkProviders(is *issues, providers []v1.ProviderConfig) {
 	if len(providers) == 0 {
-		return fmt.Errorf("at least one provider must be defined")
+		is.errorf("providers", CodeRequired, "at least one provider must be defined")
+		return
 	}
 
 	seen := make(map[string]bool)
 	defaultCount := 0
 
 	for i, p := range providers {
+		path := fmt.Sprintf("providers[%d]", i)
 		// Check required fields
 		if p.Name == "" {
-			return fmt.Errorf("provider at index %d: name is required", i)
+			is.errorf(path+".name", CodeRequired, "provider at index %d: name is required", i)
+			continue
 		}
 		if p.Engine == "" {
-			return fmt.Errorf("provider %q: engine is required", p.Name)
+			is.errorf(path+".engine", CodeRequired, "provider %q: engine is required", p.Name)
 		}
 
 		// Check for duplicate names
 		if seen[p.Name] {
-			return fmt.Errorf("provider %q: duplicate provider name", p.Name)
+			is.errorf(path+".name", CodeDuplicate, "provider %q: duplicate provider name", p.Name)
 		}
 		seen[p.Name] = true
 
@@ -179,13 +345,11 @@ func ValidateProviders(providers []v1.ProviderConfig) error {
 	// A default is required if there's more than one provider
 	// (with one provider, it's implicitly the default)
 	if len(providers) > 1 && defaultCount == 0 {
-		return fmt.Errorf("multiple providers defined but no default provider specified (set 'default: true' on one provider)")
+		is.errorf("providers", CodeRequired, "multiple providers defined but no default provider specified (set 'default: true' on one provider)")
 	}
 	if defaultCount > 1 {
-		return fmt.Errorf("multiple providers marked as default (only one provider can be default)")
+		is.errorf("providers", CodeConflict, "multiple providers marked as default (only one provider can be default)")
 	}
-
-	return nil
 }
 
 // ValidateKeys validates key resource configurations.
@@ -194,31 +358,39 @@ func ValidateProviders(providers []v1.ProviderConfig) error {
 // - Each key has a name field
 // - Key type is one of: rsa, ed25519, ecdsa
 func ValidateKeys(keys []v1.KeyResource) error {
+	var is issues
+	checkKeys(&is, keys)
+	return is.err()
+}
+
+// checkKeys reports every problem ValidateKeys fails on.
+func checkKeys(is *issues, keys []v1.KeyResource) {
 	seen := make(map[string]bool)
 
 	for i, k := range keys {
+		path := fmt.Sprintf("keys[%d]", i)
 		// Check required fields
 		if k.Name == "" {
-			return fmt.Errorf("key at index %d: name is required", i)
+			is.errorf(path+".name", CodeRequired, "key at index %d: name is required", i)
+			continue
 		}
 
 		// Check for duplicate names
 		if seen[k.Name] {
-			return fmt.Errorf("key %q: duplicate key name", k.Name)
+			is.errorf(path+".name", CodeDuplicate, "key %q: duplicate key name", k.Name)
 		}
 		seen[k.Name] = true
 
 		// Validate key type
 		if k.Spec.Type == "" {
-			return fmt.Errorf("key %q: spec.type is required", k.Name)
+			is.errorf(path+".spec.type", CodeRequired, "key %q: spec.type is required", k.Name)
+			continue
 		}
 		keyType := strings.ToLower(k.Spec.Type)
 		if !ValidKeyTypes[keyType] {
-			return fmt.Errorf("key %q: invalid key type %q (must be one of: rsa, ed25519, ecdsa)", k.Name, k.Spec.Type)
+			is.errorf(path+".spec.type", CodeInvalid, "key %q: invalid key type %q (must be one of: rsa, ed25519, ecdsa)", k.Name, k.Spec.Type)
 		}
 	}
-
-	return nil
 }
 
 // ValidateNetworks validates network resource configurations.
@@ -226,107 +398,598 @@ func ValidateKeys(keys []v1.KeyResource) error {
 // - Resource names are unique within networks
 // - Each network has name and kind fields
 // - CIDR is required for networks with DHCP enabled
+// - Networks with cidr auto set no gateway or DHCP range
+// - The MTU is between MinMTU and MaxMTU
+// - DNS records have a valid name, type and value
+// - NTP is not enabled on bridge networks and its servers are valid hosts
+// - TFTP boot artifacts and files have valid, distinct paths in the boot root
 func ValidateNetworks(networks []v1.NetworkResource) error {
+	var is issues
+	checkNetworks(&is, networks)
+	return is.err()
+}
+
+// checkNetworks reports every problem ValidateNetworks fails on.
+func checkNetworks(is *issues, networks []v1.NetworkResource) {
 	seen := make(map[string]bool)
 
 	for i, n := range networks {
+		path := fmt.Sprintf("networks[%d]", i)
 		// Check required fields
 		if n.Name == "" {
-			return fmt.Errorf("network at index %d: name is required", i)
+			is.errorf(path+".name", CodeRequired, "network at index %d: name is required", i)
+			continue
 		}
 		if n.Kind == "" {
-			return fmt.Errorf("network %q: kind is required", n.Name)
+			is.errorf(path+".kind", CodeRequired, "network %q: kind is required", n.Name)
 		}
 
 		// Check for duplicate names
 		if seen[n.Name] {
-			return fmt.Errorf("network %q: duplicate network name", n.Name)
+			is.errorf(path+".name", CodeDuplicate, "network %q: duplicate network name", n.Name)
 		}
 		seen[n.Name] = true
 
 		// If DHCP is enabled, CIDR is required
 		if n.Spec.Dhcp != nil && n.Spec.Dhcp.Enabled && n.Spec.Cidr == "" {
-			return fmt.Errorf("network %q: cidr is required when DHCP is enabled", n.Name)
+			is.errorf(path+".spec.cidr", CodeRequired, "network %q: cidr is required when DHCP is enabled", n.Name)
+		}
+
+		if n.Spec.Mtu != 0 && (n.Spec.Mtu < MinMTU || n.Spec.Mtu > MaxMTU) {
+			is.errorf(path+".spec.mtu", CodeInvalid, "network %q: mtu must be between %d and %d (got %d)", n.Name, MinMTU, MaxMTU, n.Spec.Mtu)
+		}
+
+		// The subnet of a network with cidr auto is only known once
+		// allocated, so addresses in it cannot be set
+		if n.Spec.Cidr == "auto" {
+			if n.Spec.Gateway != "" {
+				is.errorf(path+".spec.gateway", CodeInvalid, "network %q: gateway cannot be set when cidr is auto", n.Name)
+			}
+			if n.Spec.Dhcp != nil && (n.Spec.Dhcp.RangeStart != "" || n.Spec.Dhcp.RangeEnd != "") {
+				is.errorf(path+".spec.dhcp", CodeInvalid, "network %q: the DHCP range cannot be set when cidr is auto", n.Name)
+			}
+		}
+
+		if n.Spec.Dns != nil {
+			for j, r := range n.Spec.Dns.Records {
+				if err := validateDNSRecord(r); err != nil {
+					is.errorf(fmt.Sprintf("%s.spec.dns.records[%d]", path, j), CodeInvalid, "network %q: %v", n.Name, err)
+				}
+			}
+		}
+
+		if n.Spec.Tftp != nil {
+			checkTFTP(is, path+".spec.tftp", n.Name, n.Spec.Tftp)
+		}
+
+		if n.Spec.Ntp != nil && n.Spec.Ntp.Enabled {
+			if n.Kind == "bridge" {
+				is.errorf(path+".spec.ntp.enabled", CodeInvalid, "network %q: ntp is not supported on bridge networks", n.Name)
+			}
+			for j, server := range n.Spec.Ntp.Servers {
+				if IsTemplated(server) || net.ParseIP(server) != nil {
+					continue
+				}
+				if server == "" || len(server) > 253 || !dnsNamePattern.MatchString(server) {
+					is.errorf(fmt.Sprintf("%s.spec.ntp.servers[%d]", path, j), CodeInvalid, "network %q: ntp server %q is not a host name or IP address", n.Name, server)
+				}
+			}
 		}
 	}
+}
 
+// dnsNamePattern matches a DNS name: dot-separated labels of letters,
+// digits, hyphens and underscores, with an optional trailing dot.
+var dnsNamePattern = regexp.MustCompile(`^([A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?\.)*[A-Za-z0-9_]([A-Za-z0-9_-]{0,61}[A-Za-z0-9_])?\.?$`)
+
+// validateDNSRecord validates a DNS record of a network. Templated values
+// are checked once rendered, by the provider.
+func validateDNSRecord(r v1.DNSRecordSpec) error {
+	if r.Name == "" {
+		return fmt.Errorf("dns record name is required")
+	}
+	if !IsTemplated(r.Name) && (len(r.Name) > 253 || !dnsNamePattern.MatchString(r.Name)) {
+		return fmt.Errorf("dns record name %q is not a valid DNS name", r.Name)
+	}
+	if r.Value == "" {
+		return fmt.Errorf("dns record %q: value is required", r.Name)
+	}
+	if IsTemplated(r.Value) {
+		return nil
+	}
+	switch r.Type {
+	case "", "A":
+		if ip := net.ParseIP(r.Value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("dns record %q: A value must be an IPv4 address (got %q)", r.Name, r.Value)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(r.Value); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("dns record %q: AAAA value must be an IPv6 address (got %q)", r.Name, r.Value)
+		}
+	case "CNAME":
+		if !dnsNamePattern.MatchString(r.Value) {
+			return fmt.Errorf("dns record %q: CNAME value %q is not a valid DNS name", r.Name, r.Value)
+		}
+	case "TXT":
+	default:
+		return fmt.Errorf("dns record %q: type must be A, AAAA, CNAME or TXT (got %q)", r.Name, r.Type)
+	}
 	return nil
 }
 
+// maxVsockCID is the highest guest context ID; 0xffffffff means any CID.
+const maxVsockCID = 0xfffffffe
+
 // ValidateVMs validates VM resource configurations.
 // It ensures:
 // - Resource names are unique within VMs
 // - Each VM has a name field
 // - Memory and VCPUs are positive values
+// - Security options use a supported model and do not conflict
+// - The guest agent port is a valid TCP port
+// - The vsock CID is not reserved
+// - PCI and mediated devices are well-formed and passed to a single VM
+// - Encrypted disks reference their passphrase with a valid secret ref
+// - cloudInit environment variables have valid names, values, and secret refs
 func ValidateVMs(vms []v1.VMResource) error {
+	var is issues
+	checkVMs(&is, vms)
+	return is.err()
+}
+
+// checkVMs reports every problem ValidateVMs fails on.
+func checkVMs(is *issues, vms []v1.VMResource) {
 	seen := make(map[string]bool)
+	devices := make(map[string]string)
 
 	for i, vm := range vms {
+		path := fmt.Sprintf("vms[%d]", i)
 		// Check required fields
 		if vm.Name == "" {
-			return fmt.Errorf("vm at index %d: name is required", i)
+			is.errorf(path+".name", CodeRequired, "vm at index %d: name is required", i)
+			continue
 		}
 
 		// Check for duplicate names
 		if seen[vm.Name] {
-			return fmt.Errorf("vm %q: duplicate vm name", vm.Name)
+			is.errorf(path+".name", CodeDuplicate, "vm %q: duplicate vm name", vm.Name)
 		}
 		seen[vm.Name] = true
 
 		// Validate memory is positive
 		if vm.Spec.Memory <= 0 {
-			return fmt.Errorf("vm %q: memory must be a positive value (got %d)", vm.Name, vm.Spec.Memory)
+			is.errorf(path+".spec.memory", CodeInvalid, "vm %q: memory must be a positive value (got %d)", vm.Name, vm.Spec.Memory)
 		}
 
 		// Validate VCPUs is positive
 		if vm.Spec.Vcpus <= 0 {
-			return fmt.Errorf("vm %q: vcpus must be a positive value (got %d)", vm.Name, vm.Spec.Vcpus)
+			is.errorf(path+".spec.vcpus", CodeInvalid, "vm %q: vcpus must be a positive value (got %d)", vm.Name, vm.Spec.Vcpus)
+		}
+
+		if err := validateVMSecurity(vm.Spec.Security); err != nil {
+			is.errorf(path+".spec.security", CodeInvalid, "vm %q: %v", vm.Name, err)
+		}
+
+		if vm.Spec.Agent != nil && (vm.Spec.Agent.Port < 0 || vm.Spec.Agent.Port > 65535) {
+			is.errorf(path+".spec.agent.port", CodeInvalid, "vm %q: agent.port must be between 1 and 65535 (got %d)", vm.Name, vm.Spec.Agent.Port)
+		}
+
+		if vsock := vm.Spec.Devices.Vsock; vsock != nil && vsock.Cid != 0 && (vsock.Cid < 3 || vsock.Cid > maxVsockCID) {
+			is.errorf(path+".spec.devices.vsock.cid", CodeInvalid, "vm %q: devices.vsock.cid must be between 3 and %d (got %d)", vm.Name, maxVsockCID, vsock.Cid)
+		}
+		checkHostDevices(is, path+".spec", vm, devices)
+
+		if err := validateDiskEncryption(vm.Spec.Disk.Encryption); err != nil {
+			is.errorf(path+".spec.disk.encryption", CodeInvalid, "vm %q: %v", vm.Name, err)
+		}
+
+		if err := validateCloudInitEnvironment(vm.Spec.CloudInit); err != nil {
+			is.errorf(path+".spec.cloudInit", CodeInvalid, "vm %q: %v", vm.Name, err)
 		}
 	}
+}
 
+// validateDiskEncryption validates the optional disk encryption configuration.
+// Only the reference syntax is checked here: the secret itself is resolved by
+// the provider so its value never enters the spec or state.
+func validateDiskEncryption(enc *v1.DiskEncryptionSpec) error {
+	if enc == nil || !enc.Enabled {
+		return nil
+	}
+	if enc.PassphraseSecretRef == "" {
+		return fmt.Errorf("disk.encryption.passphraseSecretRef is required when encryption is enabled")
+	}
+	if IsTemplated(enc.PassphraseSecretRef) {
+		return nil
+	}
+	if _, err := secrets.ParseRef(enc.PassphraseSecretRef); err != nil {
+		return fmt.Errorf("disk.encryption: %w", err)
+	}
+	return nil
+}
+
+// validateVMSecurity validates the optional security driver configuration.
+func validateVMSecurity(sec *v1.VMSecuritySpec) error {
+	if sec == nil {
+		return nil
+	}
+	switch sec.Model {
+	case "", "selinux", "apparmor":
+	default:
+		return fmt.Errorf("security.model must be selinux or apparmor (got %q)", sec.Model)
+	}
+	if sec.Disabled && (sec.Model != "" || sec.Label != "") {
+		return fmt.Errorf("security.disabled cannot be combined with security.model or security.label")
+	}
+	if strings.ContainsAny(sec.Label, "<>&'\"") {
+		return fmt.Errorf("security.label contains invalid characters: %q", sec.Label)
+	}
+	return nil
+}
+
+// ValidateTunnels validates tunnel resource configurations.
+// It ensures:
+// - Resource names are unique within tunnels
+// - The tunnel type is supported
+// - localNetwork and remoteNetwork reference two distinct existing networks
+// - The transfer CIDR has room for both ends and the listen port is valid
+func ValidateTunnels(tunnels []v1.TunnelResource, networks []v1.NetworkResource) error {
+	var is issues
+	checkTunnels(&is, tunnels, networks)
+	return is.err()
+}
+
+// checkTunnels reports every problem ValidateTunnels fails on.
+func checkTunnels(is *issues, tunnels []v1.TunnelResource, networks []v1.NetworkResource) {
+	networkNames := make(map[string]bool, len(networks))
+	for _, n := range networks {
+		networkNames[n.Name] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, t := range tunnels {
+		path := fmt.Sprintf("tunnels[%d]", i)
+		if t.Name == "" {
+			is.errorf(path+".name", CodeRequired, "tunnel at index %d: name is required", i)
+			continue
+		}
+		if seen[t.Name] {
+			is.errorf(path+".name", CodeDuplicate, "tunnel %q: duplicate tunnel name", t.Name)
+		}
+		seen[t.Name] = true
+
+		if t.Spec.Type != "" && t.Spec.Type != "wireguard" {
+			is.errorf(path+".spec.type", CodeInvalid, "tunnel %q: unsupported type %q (supported: wireguard)", t.Name, t.Spec.Type)
+		}
+
+		for _, side := range []struct{ field, name string }{
+			{"localNetwork", t.Spec.LocalNetwork},
+			{"remoteNetwork", t.Spec.RemoteNetwork},
+		} {
+			if side.name == "" {
+				is.errorf(path+".spec."+side.field, CodeRequired, "tunnel %q: %s is required", t.Name, side.field)
+			} else if !networkNames[side.name] {
+				is.errorf(path+".spec."+side.field, CodeReference, "tunnel %q: %s %q not found", t.Name, side.field, side.name)
+			}
+		}
+		if t.Spec.LocalNetwork != "" && t.Spec.LocalNetwork == t.Spec.RemoteNetwork {
+			is.errorf(path+".spec.remoteNetwork", CodeConflict, "tunnel %q: localNetwork and remoteNetwork must differ", t.Name)
+		}
+
+		if t.Spec.Address != "" {
+			if _, err := wireguard.HostAddresses(t.Spec.Address, 2); err != nil {
+				is.errorf(path+".spec.address", CodeInvalid, "tunnel %q: address: %v", t.Name, err)
+			}
+		}
+		if t.Spec.ListenPort < 0 || t.Spec.ListenPort > 65535 {
+			is.errorf(path+".spec.listenPort", CodeInvalid, "tunnel %q: listenPort must be between 1 and 65535 (got %d)", t.Name, t.Spec.ListenPort)
+		}
+		if t.Spec.PersistentKeepalive < 0 {
+			is.errorf(path+".spec.persistentKeepalive", CodeInvalid, "tunnel %q: persistentKeepalive cannot be negative", t.Name)
+		}
+	}
+}
+
+// ValidateAccess validates access resource configurations.
+// It ensures:
+// - Resource names are unique within access points
+// - The access type is supported
+// - network and vm reference existing resources, and the VM is attached to the network
+// - The VPN CIDR has room for the server and the client, and the listen port is valid
+func ValidateAccess(access []v1.AccessResource, networks []v1.NetworkResource, vms []v1.VMResource) error {
+	var is issues
+	checkAccess(&is, access, networks, vms)
+	return is.err()
+}
+
+// checkAccess reports every problem ValidateAccess fails on.
+func checkAccess(is *issues, access []v1.AccessResource, networks []v1.NetworkResource, vms []v1.VMResource) {
+	networkNames := make(map[string]bool, len(networks))
+	for _, n := range networks {
+		networkNames[n.Name] = true
+	}
+	vmsByName := make(map[string]v1.VMResource, len(vms))
+	for _, vm := range vms {
+		vmsByName[vm.Name] = vm
+	}
+
+	seen := make(map[string]bool)
+	servers := make(map[string]string)
+	for i, a := range access {
+		path := fmt.Sprintf("access[%d]", i)
+		if a.Name == "" {
+			is.errorf(path+".name", CodeRequired, "access at index %d: name is required", i)
+			continue
+		}
+		if seen[a.Name] {
+			is.errorf(path+".name", CodeDuplicate, "access %q: duplicate access name", a.Name)
+		}
+		seen[a.Name] = true
+
+		if a.Spec.Type != "" && a.Spec.Type != "wireguard" {
+			is.errorf(path+".spec.type", CodeInvalid, "access %q: unsupported type %q (supported: wireguard)", a.Name, a.Spec.Type)
+		}
+
+		networkOK := false
+		if a.Spec.Network == "" {
+			is.errorf(path+".spec.network", CodeRequired, "access %q: network is required", a.Name)
+		} else if !networkNames[a.Spec.Network] {
+			is.errorf(path+".spec.network", CodeReference, "access %q: network %q not found", a.Name, a.Spec.Network)
+		} else {
+			networkOK = true
+		}
+
+		if a.Spec.Vm == "" {
+			is.errorf(path+".spec.vm", CodeRequired, "access %q: vm is required", a.Name)
+		} else if vm, ok := vmsByName[a.Spec.Vm]; !ok {
+			is.errorf(path+".spec.vm", CodeReference, "access %q: vm %q not found", a.Name, a.Spec.Vm)
+		} else {
+			if other, ok := servers[a.Spec.Vm]; ok {
+				is.errorf(path+".spec.vm", CodeConflict, "access %q: vm %q already serves access %q", a.Name, a.Spec.Vm, other)
+			} else {
+				servers[a.Spec.Vm] = a.Name
+			}
+
+			vmNetworks := vm.Spec.Networks
+			if len(vmNetworks) == 0 && vm.Spec.Network != "" {
+				vmNetworks = []string{vm.Spec.Network}
+			}
+			attached := false
+			for _, n := range vmNetworks {
+				if n == a.Spec.Network || IsTemplated(n) {
+					attached = true
+					break
+				}
+			}
+			if networkOK && !attached {
+				is.errorf(path+".spec.vm", CodeReference, "access %q: vm %q is not attached to network %q", a.Name, a.Spec.Vm, a.Spec.Network)
+			}
+		}
+
+		if a.Spec.Address != "" {
+			if _, err := wireguard.HostAddresses(a.Spec.Address, 2); err != nil {
+				is.errorf(path+".spec.address", CodeInvalid, "access %q: address: %v", a.Name, err)
+			}
+		}
+		if a.Spec.ListenPort < 0 || a.Spec.ListenPort > 65535 {
+			is.errorf(path+".spec.listenPort", CodeInvalid, "access %q: listenPort must be between 1 and 65535 (got %d)", a.Name, a.Spec.ListenPort)
+		}
+	}
+}
+
+// ValidateServices validates service resource configurations.
+// It ensures:
+// - Resource names are unique within services
+// - The service type is supported
+// - network references an existing network and the port is valid
+// - http-file-server services have an absolute root
+// - Only registry-mirror services set upstream
+// - Only object-store services set credentials, long enough for MinIO
+func ValidateServices(services []v1.ServiceResource, networks []v1.NetworkResource) error {
+	var is issues
+	checkServices(&is, services, networks)
+	return is.err()
+}
+
+// checkServices reports every problem ValidateServices fails on.
+func checkServices(is *issues, services []v1.ServiceResource, networks []v1.NetworkResource) {
+	networkNames := make(map[string]bool, len(networks))
+	for _, n := range networks {
+		networkNames[n.Name] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, svc := range services {
+		path := fmt.Sprintf("services[%d]", i)
+		if svc.Name == "" {
+			is.errorf(path+".name", CodeRequired, "service at index %d: name is required", i)
+			continue
+		}
+		if seen[svc.Name] {
+			is.errorf(path+".name", CodeDuplicate, "service %q: duplicate service name", svc.Name)
+		}
+		seen[svc.Name] = true
+
+		if svc.Spec.Type == "" {
+			is.errorf(path+".spec.type", CodeRequired, "service %q: type is required", svc.Name)
+		} else if service.DefaultPort(svc.Spec.Type) == 0 {
+			is.errorf(path+".spec.type", CodeInvalid, "service %q: unsupported type %q (supported: %s)", svc.Name, svc.Spec.Type, strings.Join(service.Types(), ", "))
+		}
+
+		if svc.Spec.Network == "" {
+			is.errorf(path+".spec.network", CodeRequired, "service %q: network is required", svc.Name)
+		} else if !networkNames[svc.Spec.Network] {
+			is.errorf(path+".spec.network", CodeReference, "service %q: network %q not found", svc.Name, svc.Spec.Network)
+		}
+		if svc.Spec.Port < 0 || svc.Spec.Port > 65535 {
+			is.errorf(path+".spec.port", CodeInvalid, "service %q: port must be between 1 and 65535 (got %d)", svc.Name, svc.Spec.Port)
+		}
+
+		if svc.Spec.Type == service.TypeHTTPFileServer {
+			if svc.Spec.Root == "" {
+				is.errorf(path+".spec.root", CodeRequired, "service %q: root is required for http-file-server", svc.Name)
+			} else if !IsTemplated(svc.Spec.Root) && !filepath.IsAbs(svc.Spec.Root) {
+				is.errorf(path+".spec.root", CodeInvalid, "service %q: root must be an absolute path (got %q)", svc.Name, svc.Spec.Root)
+			}
+		}
+		if svc.Spec.Upstream != "" && svc.Spec.Type != service.TypeRegistryMirror {
+			is.errorf(path+".spec.upstream", CodeInvalid, "service %q: upstream is only supported by registry-mirror", svc.Name)
+		}
+		if svc.Spec.Type != service.TypeObjectStore {
+			if svc.Spec.AccessKey != "" || svc.Spec.SecretKey != "" {
+				is.errorf(path+".spec", CodeInvalid, "service %q: accessKey and secretKey are only supported by object-store", svc.Name)
+			}
+			continue
+		}
+		if k := svc.Spec.AccessKey; k != "" && !IsTemplated(k) && len(k) < 3 {
+			is.errorf(path+".spec.accessKey", CodeInvalid, "service %q: accessKey must be at least 3 characters", svc.Name)
+		}
+		if k := svc.Spec.SecretKey; k != "" && !IsTemplated(k) && len(k) < 8 {
+			is.errorf(path+".spec.secretKey", CodeInvalid, "service %q: secretKey must be at least 8 characters", svc.Name)
+		}
+	}
+}
+
+// ValidateCertificates validates the CA and certificate resource
+// configurations.
+// It ensures:
+// - Validities are positive Go durations
+// - VMs trusting the CA or receiving a certificate exist
+// - Resource names are unique within certificates
+// - IP addresses are valid unless templated and installation paths absolute
+// - Certificates installed in a VM do not refer to it (a dependency cycle)
+func ValidateCertificates(ca v1.CASpec, certificates []v1.CertificateResource, vms []v1.VMResource) error {
+	var is issues
+	checkCertificates(&is, ca, certificates, vms)
+	return is.err()
+}
+
+// checkCertificates reports every problem ValidateCertificates fails on.
+func checkCertificates(is *issues, ca v1.CASpec, certificates []v1.CertificateResource, vms []v1.VMResource) {
+	vmNames := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		vmNames[vm.Name] = true
+	}
+
+	if err := checkValidity(ca.Validity); err != nil {
+		is.errorf("ca.validity", CodeInvalid, "ca: validity: %v", err)
+	}
+	for i, vm := range ca.Vms {
+		if !vmNames[vm] {
+			is.errorf(fmt.Sprintf("ca.vms[%d]", i), CodeReference, "ca: vm %q not found", vm)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range certificates {
+		path := fmt.Sprintf("certificates[%d]", i)
+		if c.Name == "" {
+			is.errorf(path+".name", CodeRequired, "certificate at index %d: name is required", i)
+			continue
+		}
+		if seen[c.Name] {
+			is.errorf(path+".name", CodeDuplicate, "certificate %q: duplicate certificate name", c.Name)
+		}
+		seen[c.Name] = true
+
+		for j, ip := range c.Spec.IpAddresses {
+			if !IsTemplated(ip) && net.ParseIP(ip) == nil {
+				is.errorf(fmt.Sprintf("%s.spec.ipAddresses[%d]", path, j), CodeInvalid, "certificate %q: invalid IP address %q", c.Name, ip)
+			}
+		}
+		if err := checkValidity(c.Spec.Validity); err != nil {
+			is.errorf(path+".spec.validity", CodeInvalid, "certificate %q: validity: %v", c.Name, err)
+		}
+		if c.Spec.Path != "" && !filepath.IsAbs(c.Spec.Path) {
+			is.errorf(path+".spec.path", CodeInvalid, "certificate %q: path must be absolute (got %q)", c.Name, c.Spec.Path)
+		}
+		if c.Spec.Vm == "" {
+			continue
+		}
+		if !vmNames[c.Spec.Vm] {
+			is.errorf(path+".spec.vm", CodeReference, "certificate %q: vm %q not found", c.Name, c.Spec.Vm)
+			continue
+		}
+		for _, ref := range ExtractTemplateRefs(c) {
+			if ref.Kind == "vm" && ref.Name == c.Spec.Vm {
+				is.errorf(path+".spec", CodeConflict, "certificate %q: installed in vm %q, so its templates cannot refer to that vm", c.Name, c.Spec.Vm)
+			}
+		}
+	}
+}
+
+// checkValidity fails unless validity is empty or a positive Go duration.
+func checkValidity(validity string) error {
+	if validity == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(validity)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("must be positive (got %s)", validity)
+	}
 	return nil
 }
 
 // validateProviderRefs validates that all provider references in resources
 // refer to existing provider names.
 func validateProviderRefs(spec *v1.Spec, providerNames map[string]bool) error {
+	var is issues
+	checkProviderRefs(&is, spec, providerNames)
+	return is.err()
+}
+
+// checkProviderRefs reports every reference validateProviderRefs fails on.
+func checkProviderRefs(is *issues, spec *v1.Spec, providerNames map[string]bool) {
 	// Check keys
-	for _, k := range spec.Keys {
+	for i, k := range spec.Keys {
 		if k.Provider != "" && !providerNames[k.Provider] {
-			return fmt.Errorf("key %q: provider %q not found", k.Name, k.Provider)
+			is.errorf(fmt.Sprintf("keys[%d].provider", i), CodeReference, "key %q: provider %q not found", k.Name, k.Provider)
 		}
 	}
 
 	// Check networks
-	for _, n := range spec.Networks {
+	for i, n := range spec.Networks {
 		if n.Provider != "" && !providerNames[n.Provider] {
-			return fmt.Errorf("network %q: provider %q not found", n.Name, n.Provider)
+			is.errorf(fmt.Sprintf("networks[%d].provider", i), CodeReference, "network %q: provider %q not found", n.Name, n.Provider)
 		}
 	}
 
 	// Check VMs
-	for _, vm := range spec.Vms {
+	for i, vm := range spec.Vms {
 		if vm.Provider != "" && !providerNames[vm.Provider] {
-			return fmt.Errorf("vm %q: provider %q not found", vm.Name, vm.Provider)
+			is.errorf(fmt.Sprintf("vms[%d].provider", i), CodeReference, "vm %q: provider %q not found", vm.Name, vm.Provider)
 		}
 	}
-
-	return nil
 }
 
 // validateResourceRefs validates cross-references between resources.
 // It checks that network.AttachTo and vm.Network reference existing networks.
 // Templated fields are skipped and marked in templatedFields for Phase 2 validation.
 func validateResourceRefs(spec *v1.Spec, templatedFields *TemplatedFields) error {
-	// Build network name set
+	var is issues
+	checkResourceRefs(&is, spec, templatedFields)
+	return is.err()
+}
+
+// checkResourceRefs reports every reference validateResourceRefs fails on.
+func checkResourceRefs(is *issues, spec *v1.Spec, templatedFields *TemplatedFields) {
+	// Build network name set; VMs may also attach to networks of the parent
 	networkNames := make(map[string]bool)
 	for _, n := range spec.Networks {
 		networkNames[n.Name] = true
 	}
+	vmNetworkNames := ParentResourceNames(spec)["network"]
+	for name := range networkNames {
+		vmNetworkNames[name] = true
+	}
 
 	// Check network AttachTo references
-	for _, n := range spec.Networks {
+	for i, n := range spec.Networks {
 		if n.Spec.AttachTo != "" {
+			path := fmt.Sprintf("networks[%d].spec.attachTo", i)
 			if IsTemplated(n.Spec.AttachTo) {
 				// Mark for Phase 2 validation
 				templatedFields.NetworkAttachTo[n.Name] = true
@@ -334,19 +997,20 @@ func validateResourceRefs(spec *v1.Spec, templatedFields *TemplatedFields) error
 			}
 			// Literal value - validate now
 			if n.Spec.AttachTo == n.Name {
-				return fmt.Errorf("network %q: attachTo cannot reference itself", n.Name)
-			}
-			if !networkNames[n.Spec.AttachTo] {
-				return fmt.Errorf("network %q: attachTo references non-existent network %q", n.Name, n.Spec.AttachTo)
+				is.errorf(path, CodeReference, "network %q: attachTo cannot reference itself", n.Name)
+			} else if !networkNames[n.Spec.AttachTo] {
+				is.errorf(path, CodeReference, "network %q: attachTo references non-existent network %q", n.Name, n.Spec.AttachTo)
 			}
 		}
 	}
 
 	// Check VM network references.
 	// Networks takes precedence over Network for validation.
-	for _, vm := range spec.Vms {
+	for i, vm := range spec.Vms {
+		path := fmt.Sprintf("vms[%d].spec.networks", i)
 		netNames := vm.Spec.Networks
 		if len(netNames) == 0 && vm.Spec.Network != "" {
+			path = fmt.Sprintf("vms[%d].spec.network", i)
 			netNames = []string{vm.Spec.Network}
 		}
 		for _, netName := range netNames {
@@ -359,13 +1023,11 @@ func validateResourceRefs(spec *v1.Spec, templatedFields *TemplatedFields) error
 				continue
 			}
 			// Literal value - validate now
-			if !networkNames[netName] {
-				return fmt.Errorf("vm %q: network %q not found", vm.Name, netName)
+			if !vmNetworkNames[netName] {
+				is.errorf(path, CodeReference, "vm %q: network %q not found", vm.Name, netName)
 			}
 		}
 	}
-
-	return nil
 }
 
 // validateImages validates image resource configurations.
@@ -377,35 +1039,40 @@ func validateResourceRefs(spec *v1.Spec, templatedFields *TemplatedFields) error
 // - No duplicate image names
 // - Aliases don't conflict with other names/aliases
 func validateImages(spec *v1.Spec) error {
+	var is issues
+	checkImages(&is, spec)
+	return is.err()
+}
+
+// checkImages reports every problem validateImages fails on.
+func checkImages(is *issues, spec *v1.Spec) {
 	seen := make(map[string]bool)      // tracks image names
 	aliases := make(map[string]string) // maps alias -> image name that owns it
 
 	for i, img := range spec.Images {
+		path := fmt.Sprintf("images[%d]", i)
 		// Check required name
 		if img.Name == "" {
-			return fmt.Errorf("image at index %d: name is required", i)
+			is.errorf(path+".name", CodeRequired, "image at index %d: name is required", i)
+			continue
 		}
 
 		// Check for duplicate names
 		if seen[img.Name] {
-			return fmt.Errorf("duplicate image name: %q", img.Name)
+			is.errorf(path+".name", CodeDuplicate, "duplicate image name: %q", img.Name)
 		}
 		seen[img.Name] = true
 
 		// Check required source
 		if img.Spec.Source == "" {
-			return fmt.Errorf("image %q: source is required", img.Name)
-		}
-
-		// Validate source is either well-known or HTTPS URL
-		if !image.IsWellKnown(img.Spec.Source) {
+			is.errorf(path+".spec.source", CodeRequired, "image %q: source is required", img.Name)
+		} else if !image.IsWellKnown(img.Spec.Source) {
 			// Not a well-known image - must be an HTTPS URL
 			if !strings.HasPrefix(img.Spec.Source, "https://") {
-				return fmt.Errorf("image %q: source %q must be well-known reference or HTTPS URL", img.Name, img.Spec.Source)
-			}
-			// Custom HTTPS URLs require SHA256 checksum
-			if img.Spec.Sha256 == "" {
-				return fmt.Errorf("image %q: custom URL requires sha256 checksum", img.Name)
+				is.errorf(path+".spec.source", CodeInvalid, "image %q: source %q must be well-known reference or HTTPS URL", img.Name, img.Spec.Source)
+			} else if img.Spec.Sha256 == "" {
+				// Custom HTTPS URLs require SHA256 checksum
+				is.errorf(path+".spec.sha256", CodeRequired, "image %q: custom URL requires sha256 checksum", img.Name)
 			}
 		}
 
@@ -413,21 +1080,21 @@ func validateImages(spec *v1.Spec) error {
 		if img.Spec.Alias != "" {
 			// Check if alias conflicts with an image name
 			if seen[img.Spec.Alias] {
-				return fmt.Errorf("image %q: alias %q conflicts with another image name", img.Name, img.Spec.Alias)
-			}
-			// Check if alias conflicts with another alias
-			if owner, ok := aliases[img.Spec.Alias]; ok {
-				return fmt.Errorf("image %q: alias %q conflicts with alias from image %q", img.Name, img.Spec.Alias, owner)
+				is.errorf(path+".spec.alias", CodeConflict, "image %q: alias %q conflicts with another image name", img.Name, img.Spec.Alias)
+			} else if owner, ok := aliases[img.Spec.Alias]; ok {
+				// Check if alias conflicts with another alias
+				is.errorf(path+".spec.alias", CodeConflict, "image %q: alias %q conflicts with alias from image %q", img.Name, img.Spec.Alias, owner)
+			} else {
+				aliases[img.Spec.Alias] = img.Name
 			}
-			aliases[img.Spec.Alias] = img.Name
 		}
 	}
 
 	// Second pass: check that no image name conflicts with an alias from a previous image
 	// (alias declared before the name was processed)
-	for _, img := range spec.Images {
+	for i, img := range spec.Images {
 		if owner, ok := aliases[img.Name]; ok && owner != img.Name {
-			return fmt.Errorf("image %q: name conflicts with alias from image %q", img.Name, owner)
+			is.errorf(fmt.Sprintf("images[%d].name", i), CodeConflict, "image %q: name conflicts with alias from image %q", img.Name, owner)
 		}
 	}
 
@@ -435,11 +1102,9 @@ func validateImages(spec *v1.Spec) error {
 	if spec.ImageCacheDir != "" {
 		// Basic validation: must not be empty after trim
 		if strings.TrimSpace(spec.ImageCacheDir) == "" {
-			return fmt.Errorf("imageCacheDir cannot be whitespace-only")
+			is.errorf("imageCacheDir", CodeInvalid, "imageCacheDir cannot be whitespace-only")
 		}
 	}
-
-	return nil
 }
 
 // validateTemplateRefsExist validates that all template references in the spec
@@ -447,54 +1112,82 @@ func validateImages(spec *v1.Spec) error {
 // {{ .Networks.typo.InterfaceName }} early, before any resources are created.
 // Note: .Env references are skipped as they come from runtime input.
 func validateTemplateRefsExist(spec *v1.Spec) error {
-	// Build resource name sets
-	keyNames := make(map[string]bool)
+	var is issues
+	checkTemplateRefsExist(&is, spec)
+	return is.err()
+}
+
+// checkTemplateRefsExist reports every reference validateTemplateRefsExist
+// fails on, at the path of the top-level field or list item holding it.
+func checkTemplateRefsExist(is *issues, spec *v1.Spec) {
+	names := map[string]map[string]bool{
+		"key":         {},
+		"network":     {},
+		"vm":          {},
+		"image":       {},
+		"tunnel":      {},
+		"access":      {},
+		"service":     {},
+		"certificate": {},
+	}
 	for _, k := range spec.Keys {
-		keyNames[k.Name] = true
+		names["key"][k.Name] = true
 	}
-	networkNames := make(map[string]bool)
 	for _, n := range spec.Networks {
-		networkNames[n.Name] = true
+		names["network"][n.Name] = true
+	}
+	// Keys and networks of the parent are referenced like the spec's own
+	for kind, inherited := range ParentResourceNames(spec) {
+		for name := range inherited {
+			names[kind][name] = true
+		}
 	}
-	vmNames := make(map[string]bool)
 	for _, vm := range spec.Vms {
-		vmNames[vm.Name] = true
+		names["vm"][vm.Name] = true
 	}
-	// Build image names set (include both name and alias)
-	imageNames := make(map[string]bool)
+	// Images are referenced by name or alias
 	for _, img := range spec.Images {
-		imageNames[img.Name] = true
+		names["image"][img.Name] = true
 		if img.Spec.Alias != "" {
-			imageNames[img.Spec.Alias] = true
+			names["image"][img.Spec.Alias] = true
 		}
 	}
+	for _, t := range spec.Tunnels {
+		names["tunnel"][t.Name] = true
+	}
+	for _, a := range spec.Access {
+		names["access"][a.Name] = true
+	}
+	for _, svc := range spec.Services {
+		names["service"][svc.Name] = true
+	}
+	for _, c := range spec.Certificates {
+		names["certificate"][c.Name] = true
+	}
 
-	// Extract all template refs from spec
-	refs := ExtractTemplateRefs(spec)
-
-	// Validate each ref exists
-	for _, ref := range refs {
-		switch ref.Kind {
-		case "key":
-			if !keyNames[ref.Name] {
-				return fmt.Errorf("template reference to non-existent key %q", ref.Name)
-			}
-		case "network":
-			if !networkNames[ref.Name] {
-				return fmt.Errorf("template reference to non-existent network %q", ref.Name)
-			}
-		case "vm":
-			if !vmNames[ref.Name] {
-				return fmt.Errorf("template reference to non-existent vm %q", ref.Name)
+	// Extract the template refs of each top-level field, or of each item of
+	// top-level lists, so that findings carry a path.
+	v := reflect.ValueOf(spec).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := jsonName(v.Type().Field(i))
+		value := v.Field(i)
+		items := []reflect.Value{value}
+		paths := []string{field}
+		if value.Kind() == reflect.Slice {
+			items, paths = nil, nil
+			for j := 0; j < value.Len(); j++ {
+				items = append(items, value.Index(j))
+				paths = append(paths, fmt.Sprintf("%s[%d]", field, j))
 			}
-		case "image":
-			if !imageNames[ref.Name] {
-				return fmt.Errorf("template reference to non-existent image %q", ref.Name)
+		}
+		for j, item := range items {
+			for _, ref := range ExtractTemplateRefs(item.Interface()) {
+				if known, ok := names[ref.Kind]; ok && !known[ref.Name] {
+					is.errorf(paths[j], CodeReference, "template reference to non-existent %s %q", ref.Kind, ref.Name)
+				}
 			}
 		}
 	}
-
-	return nil
 }
 
 // ValidateResourceRefsLate performs Phase 2 validation on a rendered resource.
@@ -565,8 +1258,8 @@ func ValidateResourceRefsLate(
 			return nil // Empty is valid (optional field)
 		}
 
-		// Build network name set
-		networkNames := make(map[string]bool)
+		// Build network name set, including networks of the parent
+		networkNames := ParentResourceNames(fullSpec)["network"]
 		for _, n := range fullSpec.Networks {
 			networkNames[n.Name] = true
 		}
@@ -99,6 +99,81 @@ func TestValidate(t *testing.T) {
 			wantErr:   true,
 			errSubstr: "is marked as default, but defaultProvider is set to",
 		},
+		{
+			name: "valid createDeadline passes",
+			spec: &v1.Spec{
+				Providers:      []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				CreateDeadline: "15m",
+			},
+		},
+		{
+			name: "invalid createDeadline fails",
+			spec: &v1.Spec{
+				Providers:      []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				CreateDeadline: "fifteen minutes",
+			},
+			wantErr:   true,
+			errSubstr: "is not a valid duration",
+		},
+		{
+			name: "negative createDeadline fails",
+			spec: &v1.Spec{
+				Providers:      []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				CreateDeadline: "-1m",
+			},
+			wantErr:   true,
+			errSubstr: "createDeadline must be positive",
+		},
+		{
+			name: "invalid expiresAfter fails",
+			spec: &v1.Spec{
+				Providers:    []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				ExpiresAfter: "2 hours",
+			},
+			wantErr:   true,
+			errSubstr: "expiresAfter \"2 hours\" is not a valid duration",
+		},
+		{
+			name: "valid namePrefix passes",
+			spec: &v1.Spec{
+				Providers:  []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				NamePrefix: "ci-1234",
+			},
+		},
+		{
+			name: "invalid namePrefix fails",
+			spec: &v1.Spec{
+				Providers:  []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				NamePrefix: "CI_job",
+			},
+			wantErr:   true,
+			errSubstr: "namePrefix \"CI_job\" must be at most 16",
+		},
+		{
+			name: "valid labels pass",
+			spec: &v1.Spec{
+				Providers: []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				Labels:    map[string]string{"team": "storage", "example.com/cost-center": "cc_42"},
+			},
+		},
+		{
+			name: "reserved label prefix fails",
+			spec: &v1.Spec{
+				Providers: []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				Labels:    map[string]string{"testenv-vm.stage": "e2e"},
+			},
+			wantErr:   true,
+			errSubstr: "uses the reserved prefix",
+		},
+		{
+			name: "invalid label value fails",
+			spec: &v1.Spec{
+				Providers: []v1.ProviderConfig{{Name: "provider1", Engine: "go://test"}},
+				Labels:    map[string]string{"team": "a b"},
+			},
+			wantErr:   true,
+			errSubstr: "value \"a b\" of label \"team\"",
+		},
 	}
 
 	for _, tt := range tests {
@@ -359,6 +434,65 @@ func TestValidateNetworks(t *testing.T) {
 			wantErr:   true,
 			errSubstr: "cidr is required when DHCP is enabled",
 		},
+		{
+			name: "jumbo MTU passes",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "bridge", Spec: v1.NetworkSpec{Mtu: 9000}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "MTU above jumbo frames fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "bridge", Spec: v1.NetworkSpec{Mtu: 65535}},
+			},
+			wantErr:   true,
+			errSubstr: "mtu must be between 68 and 9216",
+		},
+		{
+			name: "auto CIDR with DHCP passes",
+			networks: []v1.NetworkResource{
+				{
+					Name: "net1",
+					Kind: "bridge",
+					Spec: v1.NetworkSpec{
+						Cidr: "auto",
+						Dhcp: &v1.DHCPSpec{Enabled: true},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "auto CIDR with gateway fails",
+			networks: []v1.NetworkResource{
+				{
+					Name: "net1",
+					Kind: "bridge",
+					Spec: v1.NetworkSpec{Cidr: "auto", Gateway: "10.200.0.1"},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "gateway cannot be set when cidr is auto",
+		},
+		{
+			name: "auto CIDR with DHCP range fails",
+			networks: []v1.NetworkResource{
+				{
+					Name: "net1",
+					Kind: "bridge",
+					Spec: v1.NetworkSpec{
+						Cidr: "auto",
+						Dhcp: &v1.DHCPSpec{
+							Enabled:    true,
+							RangeStart: "10.200.0.100",
+						},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "DHCP range cannot be set when cidr is auto",
+		},
 		{
 			name: "DHCP with CIDR passes",
 			networks: []v1.NetworkResource{
@@ -392,6 +526,82 @@ func TestValidateNetworks(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "DNS records pass",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Dns: &v1.DNSSpec{Records: []v1.DNSRecordSpec{
+					{Name: "api.example.com", Value: "192.168.100.50"},
+					{Name: "api6.example.com", Type: "AAAA", Value: "fd00::50"},
+					{Name: "www.example.com", Type: "CNAME", Value: "api.example.com"},
+					{Name: "_acme.example.com", Type: "TXT", Value: "token=abc"},
+					{Name: "mock.example.com", Value: "{{ .Env.MOCK_IP }}"},
+				}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "NTP servers pass",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "isolated", Spec: v1.NetworkSpec{Ntp: &v1.NTPSpec{Enabled: true, Servers: []string{"pool.ntp.org", "10.0.0.5"}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "NTP on bridge network fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "bridge", Spec: v1.NetworkSpec{Ntp: &v1.NTPSpec{Enabled: true}}},
+			},
+			wantErr:   true,
+			errSubstr: "ntp is not supported on bridge networks",
+		},
+		{
+			name: "NTP server with invalid name fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Ntp: &v1.NTPSpec{Enabled: true, Servers: []string{"pool ntp.org"}}}},
+			},
+			wantErr:   true,
+			errSubstr: "is not a host name or IP address",
+		},
+		{
+			name: "DNS record with invalid name fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Dns: &v1.DNSSpec{Records: []v1.DNSRecordSpec{
+					{Name: "api example.com", Value: "192.168.100.50"},
+				}}}},
+			},
+			wantErr:   true,
+			errSubstr: "not a valid DNS name",
+		},
+		{
+			name: "DNS A record with IPv6 value fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Dns: &v1.DNSSpec{Records: []v1.DNSRecordSpec{
+					{Name: "api.example.com", Value: "fd00::50"},
+				}}}},
+			},
+			wantErr:   true,
+			errSubstr: "must be an IPv4 address",
+		},
+		{
+			name: "DNS record with unknown type fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Dns: &v1.DNSSpec{Records: []v1.DNSRecordSpec{
+					{Name: "example.com", Type: "MX", Value: "mail.example.com"},
+				}}}},
+			},
+			wantErr:   true,
+			errSubstr: "type must be A, AAAA, CNAME or TXT",
+		},
+		{
+			name: "DNS record without value fails",
+			networks: []v1.NetworkResource{
+				{Name: "net1", Kind: "nat", Spec: v1.NetworkSpec{Dns: &v1.DNSSpec{Records: []v1.DNSRecordSpec{
+					{Name: "api.example.com"},
+				}}}},
+			},
+			wantErr:   true,
+			errSubstr: "value is required",
+		},
 	}
 
 	for _, tt := range tests {
@@ -526,6 +736,161 @@ func TestValidateVMs(t *testing.T) {
 			wantErr:   true,
 			errSubstr: "duplicate vm name",
 		},
+		{
+			name: "apparmor profile passes",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory:   1024,
+						Vcpus:    2,
+						Security: &v1.VMSecuritySpec{Model: "apparmor", Label: "libvirt-virtiofs"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "agent with default port passes",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory: 1024,
+						Vcpus:  2,
+						Agent:  &v1.VMAgentSpec{Enabled: true},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "agent with invalid port fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory: 1024,
+						Vcpus:  2,
+						Agent:  &v1.VMAgentSpec{Enabled: true, Port: 70000},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "agent.port must be between 1 and 65535",
+		},
+		{
+			name: "vsock with automatic cid passes",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory:  1024,
+						Vcpus:   2,
+						Devices: v1.VMDevicesSpec{Vsock: &v1.VsockSpec{}},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "vsock with reserved cid fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory:  1024,
+						Vcpus:   2,
+						Devices: v1.VMDevicesSpec{Vsock: &v1.VsockSpec{Cid: 2}},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "devices.vsock.cid must be between 3 and 4294967294",
+		},
+		{
+			name: "unknown security model fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory:   1024,
+						Vcpus:    2,
+						Security: &v1.VMSecuritySpec{Model: "smack"},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "security.model must be selinux or apparmor",
+		},
+		{
+			name: "disabled with label fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory:   1024,
+						Vcpus:    2,
+						Security: &v1.VMSecuritySpec{Disabled: true, Label: "system_u:system_r:svirt_t:s0"},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "security.disabled cannot be combined",
+		},
+		{
+			name: "encrypted disk with env secret ref passes",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory: 1024,
+						Vcpus:  2,
+						Disk: v1.DiskSpec{
+							Size:       "10G",
+							Encryption: &v1.DiskEncryptionSpec{Enabled: true, PassphraseSecretRef: "env:DISK_PASSPHRASE"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "encrypted disk without secret ref fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory: 1024,
+						Vcpus:  2,
+						Disk: v1.DiskSpec{
+							Size:       "10G",
+							Encryption: &v1.DiskEncryptionSpec{Enabled: true},
+						},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "passphraseSecretRef is required",
+		},
+		{
+			name: "encrypted disk with invalid secret ref fails",
+			vms: []v1.VMResource{
+				{
+					Name: "vm1",
+					Spec: v1.VMSpec{
+						Memory: 1024,
+						Vcpus:  2,
+						Disk: v1.DiskSpec{
+							Size:       "10G",
+							Encryption: &v1.DiskEncryptionSpec{Enabled: true, PassphraseSecretRef: "hunter2"},
+						},
+					},
+				},
+			},
+			wantErr:   true,
+			errSubstr: "invalid secret reference",
+		},
 	}
 
 	for _, tt := range tests {
@@ -1683,3 +2048,322 @@ func TestValidateImages_WithCustomRegistry(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCertificates(t *testing.T) {
+	vms := []v1.VMResource{{Name: "web"}, {Name: "client"}}
+	tests := []struct {
+		name         string
+		ca           v1.CASpec
+		certificates []v1.CertificateResource
+		errSubstr    string
+	}{
+		{
+			name: "valid certificates pass",
+			ca:   v1.CASpec{Validity: "720h", Vms: []string{"client"}},
+			certificates: []v1.CertificateResource{
+				{Name: "web", Spec: v1.CertificateSpec{DnsNames: []string{"web.test"}, IpAddresses: []string{"192.168.100.10"}, Vm: "web", Path: "/etc/ssl/web"}},
+				{Name: "client", Spec: v1.CertificateSpec{IpAddresses: []string{"{{ .VMs.web.IP }}"}, Client: true, Validity: "1h", Vm: "client"}},
+			},
+		},
+		{name: "invalid CA validity fails", ca: v1.CASpec{Validity: "-1h"}, errSubstr: "must be positive"},
+		{name: "unknown CA vm fails", ca: v1.CASpec{Vms: []string{"db"}}, errSubstr: `vm "db" not found`},
+		{
+			name: "duplicate name fails",
+			certificates: []v1.CertificateResource{
+				{Name: "web", Spec: v1.CertificateSpec{}},
+				{Name: "web", Spec: v1.CertificateSpec{}},
+			},
+			errSubstr: "duplicate certificate name",
+		},
+		{
+			name:         "invalid IP fails",
+			certificates: []v1.CertificateResource{{Name: "web", Spec: v1.CertificateSpec{IpAddresses: []string{"web.test"}}}},
+			errSubstr:    "invalid IP address",
+		},
+		{
+			name:         "relative path fails",
+			certificates: []v1.CertificateResource{{Name: "web", Spec: v1.CertificateSpec{Vm: "web", Path: "certs"}}},
+			errSubstr:    "path must be absolute",
+		},
+		{
+			name:         "unknown vm fails",
+			certificates: []v1.CertificateResource{{Name: "web", Spec: v1.CertificateSpec{Vm: "db"}}},
+			errSubstr:    `vm "db" not found`,
+		},
+		{
+			name:         "reference to the installing vm fails",
+			certificates: []v1.CertificateResource{{Name: "web", Spec: v1.CertificateSpec{IpAddresses: []string{"{{ .VMs.web.IP }}"}, Vm: "web"}}},
+			errSubstr:    "cannot refer to that vm",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCertificates(tt.ca, tt.certificates, vms)
+			if tt.errSubstr == "" {
+				if err != nil {
+					t.Fatalf("ValidateCertificates() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateCertificates() error = %v, want substring %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestValidateTunnels(t *testing.T) {
+	networks := []v1.NetworkResource{{Name: "lab"}, {Name: "vpc"}}
+	tests := []struct {
+		name      string
+		tunnels   []v1.TunnelResource
+		wantErr   bool
+		errSubstr string
+	}{
+		{
+			name:    "valid tunnel passes",
+			tunnels: []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc"}}},
+		},
+		{
+			name: "valid tunnel with all fields passes",
+			tunnels: []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{
+				Type: "wireguard", LocalNetwork: "lab", RemoteNetwork: "vpc",
+				Address: "10.99.0.0/30", ListenPort: 51821, Endpoint: "{{ .VMs.gw.IP }}", PersistentKeepalive: 10,
+			}}},
+		},
+		{
+			name: "duplicate name fails",
+			tunnels: []v1.TunnelResource{
+				{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc"}},
+				{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "vpc", RemoteNetwork: "lab"}},
+			},
+			wantErr:   true,
+			errSubstr: "duplicate tunnel name",
+		},
+		{
+			name:      "unsupported type fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{Type: "ipsec", LocalNetwork: "lab", RemoteNetwork: "vpc"}}},
+			wantErr:   true,
+			errSubstr: "unsupported type",
+		},
+		{
+			name:      "missing remote network fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab"}}},
+			wantErr:   true,
+			errSubstr: "remoteNetwork is required",
+		},
+		{
+			name:      "unknown network fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "other"}}},
+			wantErr:   true,
+			errSubstr: `remoteNetwork "other" not found`,
+		},
+		{
+			name:      "same network on both ends fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "lab"}}},
+			wantErr:   true,
+			errSubstr: "must differ",
+		},
+		{
+			name:      "address too small fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc", Address: "10.99.0.1/32"}}},
+			wantErr:   true,
+			errSubstr: "address",
+		},
+		{
+			name:      "invalid listen port fails",
+			tunnels:   []v1.TunnelResource{{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab", RemoteNetwork: "vpc", ListenPort: 70000}}},
+			wantErr:   true,
+			errSubstr: "listenPort",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTunnels(tt.tunnels, networks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTunnels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateTunnels() error = %v, want substring %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestValidateAccess(t *testing.T) {
+	networks := []v1.NetworkResource{{Name: "lab"}, {Name: "other"}}
+	vms := []v1.VMResource{
+		{Name: "vpn", Spec: v1.VMSpec{Network: "lab"}},
+		{Name: "multi", Spec: v1.VMSpec{Networks: []string{"other", "lab"}}},
+	}
+	tests := []struct {
+		name      string
+		access    []v1.AccessResource
+		wantErr   bool
+		errSubstr string
+	}{
+		{
+			name:   "valid access passes",
+			access: []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Network: "lab", Vm: "vpn"}}},
+		},
+		{
+			name: "valid access on multi-network VM passes",
+			access: []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{
+				Type: "wireguard", Network: "lab", Vm: "multi", Address: "10.50.0.0/29", ListenPort: 4500,
+			}}},
+		},
+		{
+			name: "duplicate name fails",
+			access: []v1.AccessResource{
+				{Name: "dev", Spec: v1.AccessSpec{Network: "lab", Vm: "vpn"}},
+				{Name: "dev", Spec: v1.AccessSpec{Network: "lab", Vm: "multi"}},
+			},
+			wantErr:   true,
+			errSubstr: "duplicate access name",
+		},
+		{
+			name: "VM serving two access points fails",
+			access: []v1.AccessResource{
+				{Name: "a", Spec: v1.AccessSpec{Network: "lab", Vm: "multi"}},
+				{Name: "b", Spec: v1.AccessSpec{Network: "other", Vm: "multi"}},
+			},
+			wantErr:   true,
+			errSubstr: "already serves",
+		},
+		{
+			name:      "unsupported type fails",
+			access:    []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Type: "openvpn", Network: "lab", Vm: "vpn"}}},
+			wantErr:   true,
+			errSubstr: "unsupported type",
+		},
+		{
+			name:      "unknown network fails",
+			access:    []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Network: "missing", Vm: "vpn"}}},
+			wantErr:   true,
+			errSubstr: `network "missing" not found`,
+		},
+		{
+			name:      "missing vm fails",
+			access:    []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Network: "lab"}}},
+			wantErr:   true,
+			errSubstr: "vm is required",
+		},
+		{
+			name:      "VM not on network fails",
+			access:    []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Network: "other", Vm: "vpn"}}},
+			wantErr:   true,
+			errSubstr: "not attached",
+		},
+		{
+			name:      "address too small fails",
+			access:    []v1.AccessResource{{Name: "dev", Spec: v1.AccessSpec{Network: "lab", Vm: "vpn", Address: "10.50.0.0/31"}}},
+			wantErr:   true,
+			errSubstr: "address",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAccess(tt.access, networks, vms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAccess() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateAccess() error = %v, want substring %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestValidateServices(t *testing.T) {
+	networks := []v1.NetworkResource{{Name: "lab"}}
+	tests := []struct {
+		name      string
+		services  []v1.ServiceResource
+		wantErr   bool
+		errSubstr string
+	}{
+		{
+			name: "valid services pass",
+			services: []v1.ServiceResource{
+				{Name: "mirror", Spec: v1.ServiceSpec{Type: "registry-mirror", Network: "lab", Upstream: "https://quay.io"}},
+				{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab", Port: 3000}},
+				{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Root: "/srv/fixtures"}},
+				{Name: "env-files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Root: "{{ .Env.FIXTURES }}"}},
+				{Name: "s3", Spec: v1.ServiceSpec{Type: "object-store", Network: "lab"}},
+				{Name: "s3-creds", Spec: v1.ServiceSpec{Type: "object-store", Network: "lab", AccessKey: "tester", SecretKey: "{{ .Env.S3_SECRET }}"}},
+			},
+		},
+		{
+			name: "duplicate name fails",
+			services: []v1.ServiceResource{
+				{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab"}},
+				{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab", Port: 3000}},
+			},
+			wantErr:   true,
+			errSubstr: "duplicate service name",
+		},
+		{
+			name:      "unsupported type fails",
+			services:  []v1.ServiceResource{{Name: "ftp", Spec: v1.ServiceSpec{Type: "ftp", Network: "lab"}}},
+			wantErr:   true,
+			errSubstr: "unsupported type",
+		},
+		{
+			name:      "unknown network fails",
+			services:  []v1.ServiceResource{{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "missing"}}},
+			wantErr:   true,
+			errSubstr: `network "missing" not found`,
+		},
+		{
+			name:      "invalid port fails",
+			services:  []v1.ServiceResource{{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab", Port: 70000}}},
+			wantErr:   true,
+			errSubstr: "port must be between",
+		},
+		{
+			name:      "file server without root fails",
+			services:  []v1.ServiceResource{{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab"}}},
+			wantErr:   true,
+			errSubstr: "root is required",
+		},
+		{
+			name:      "relative root fails",
+			services:  []v1.ServiceResource{{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Root: "fixtures"}}},
+			wantErr:   true,
+			errSubstr: "absolute path",
+		},
+		{
+			name:      "upstream on apt cache fails",
+			services:  []v1.ServiceResource{{Name: "apt", Spec: v1.ServiceSpec{Type: "apt-cache", Network: "lab", Upstream: "https://quay.io"}}},
+			wantErr:   true,
+			errSubstr: "only supported by registry-mirror",
+		},
+		{
+			name:      "credentials on file server fail",
+			services:  []v1.ServiceResource{{Name: "files", Spec: v1.ServiceSpec{Type: "http-file-server", Network: "lab", Root: "/srv", AccessKey: "tester"}}},
+			wantErr:   true,
+			errSubstr: "only supported by object-store",
+		},
+		{
+			name:      "short secret key fails",
+			services:  []v1.ServiceResource{{Name: "s3", Spec: v1.ServiceSpec{Type: "object-store", Network: "lab", SecretKey: "short"}}},
+			wantErr:   true,
+			errSubstr: "at least 8 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateServices(tt.services, networks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("ValidateServices() error = %v, want substring %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specbuilder
+
+import (
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// ProviderOption configures a provider added by WithProvider.
+type ProviderOption func(*v1.ProviderConfig)
+
+// ProviderDefault marks the provider as the default one.
+func ProviderDefault() ProviderOption {
+	return func(p *v1.ProviderConfig) {
+		p.Default = true
+	}
+}
+
+// ProviderSpec sets the provider-specific configuration.
+func ProviderSpec(spec map[string]any) ProviderOption {
+	return func(p *v1.ProviderConfig) {
+		p.Spec = spec
+	}
+}
+
+// KeyOption configures a key added by WithKey.
+type KeyOption func(*v1.KeyResource)
+
+// KeyType sets the key type: rsa, ed25519 or ecdsa.
+func KeyType(keyType string) KeyOption {
+	return func(k *v1.KeyResource) {
+		k.Spec.Type = keyType
+	}
+}
+
+// KeyBits sets the key size of rsa and ecdsa keys.
+func KeyBits(bits int) KeyOption {
+	return func(k *v1.KeyResource) {
+		k.Spec.Bits = bits
+	}
+}
+
+// KeyProvider creates the key with the given provider instead of the
+// default one.
+func KeyProvider(provider string) KeyOption {
+	return func(k *v1.KeyResource) {
+		k.Provider = provider
+	}
+}
+
+// ImageOption configures an image added by WithImage.
+type ImageOption func(*v1.ImageResource)
+
+// ImageSHA256 sets the expected checksum of the image, required for URLs.
+func ImageSHA256(sum string) ImageOption {
+	return func(img *v1.ImageResource) {
+		img.Spec.Sha256 = sum
+	}
+}
+
+// ImageAlias sets an alternative name for template references.
+func ImageAlias(alias string) ImageOption {
+	return func(img *v1.ImageResource) {
+		img.Spec.Alias = alias
+	}
+}
+
+// NetworkOption configures a network added by WithNetwork.
+type NetworkOption func(*v1.NetworkResource)
+
+// NetworkCIDR sets the CIDR of the network, e.g. "192.168.100.1/24".
+func NetworkCIDR(cidr string) NetworkOption {
+	return func(n *v1.NetworkResource) {
+		n.Spec.Cidr = cidr
+	}
+}
+
+// NetworkDHCP enables DHCP on the network for the given address range.
+func NetworkDHCP(rangeStart, rangeEnd string) NetworkOption {
+	return func(n *v1.NetworkResource) {
+		n.Spec.Dhcp = &v1.DHCPSpec{Enabled: true, RangeStart: rangeStart, RangeEnd: rangeEnd}
+	}
+}
+
+// NetworkAttachTo attaches the network to another one, e.g. a bridge to a
+// libvirt network.
+func NetworkAttachTo(network string) NetworkOption {
+	return func(n *v1.NetworkResource) {
+		n.Spec.AttachTo = NetworkName(network)
+	}
+}
+
+// NetworkProvider creates the network with the given provider instead of
+// the default one.
+func NetworkProvider(provider string) NetworkOption {
+	return func(n *v1.NetworkResource) {
+		n.Provider = provider
+	}
+}
+
+// VMOption configures a VM added by WithVM.
+type VMOption func(*v1.VMResource)
+
+// Memory sets the memory of the VM in MB.
+func Memory(mb int) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.Memory = mb
+	}
+}
+
+// VCPUs sets the number of virtual CPUs of the VM.
+func VCPUs(n int) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.Vcpus = n
+	}
+}
+
+// DiskSize sets the disk size of the VM, e.g. "20G".
+func DiskSize(size string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.Disk.Size = size
+	}
+}
+
+// BaseImage sets the base image of the VM's disk: a well-known reference, an
+// HTTPS URL, or an image reference built with ImagePath.
+func BaseImage(image string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.Disk.BaseImage = image
+	}
+}
+
+// OnNetworks attaches the VM to networks of the spec, in order.
+func OnNetworks(networks ...string) VMOption {
+	return func(vm *v1.VMResource) {
+		for _, n := range networks {
+			vm.Spec.Networks = append(vm.Spec.Networks, NetworkName(n))
+		}
+	}
+}
+
+// SSHUser adds a cloud-init user with passwordless sudo that authorizes key,
+// and waits for SSH as that user before the VM is ready.
+func SSHUser(user, key string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.CloudInit.Users = append(vm.Spec.CloudInit.Users, v1.UserSpec{
+			Name:              user,
+			Sudo:              "ALL=(ALL) NOPASSWD:ALL",
+			SshAuthorizedKeys: []string{PublicKey(key)},
+		})
+		vm.Spec.Readiness.Ssh = v1.SSHReadinessSpec{
+			Enabled:    true,
+			User:       user,
+			PrivateKey: PrivateKeyPath(key),
+		}
+	}
+}
+
+// Packages adds packages installed by cloud-init.
+func Packages(packages ...string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.CloudInit.Packages = append(vm.Spec.CloudInit.Packages, packages...)
+	}
+}
+
+// Runcmd adds commands run by cloud-init on first boot.
+func Runcmd(commands ...string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Spec.CloudInit.Runcmd = append(vm.Spec.CloudInit.Runcmd, commands...)
+	}
+}
+
+// VMProvider creates the VM with the given provider instead of the default
+// one.
+func VMProvider(provider string) VMOption {
+	return func(vm *v1.VMResource) {
+		vm.Provider = provider
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package specbuilder builds testenv-vm specs in Go, for tests and for
+// environments defined in code rather than YAML:
+//
+//	s, err := specbuilder.New().
+//		WithProvider("libvirt", "go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-libvirt").
+//		WithKey("vm-ssh").
+//		WithNetwork("test-net", "bridge", specbuilder.NetworkCIDR("192.168.100.1/24")).
+//		WithVM("test-vm", specbuilder.Memory(2048), specbuilder.OnNetworks("test-net"), specbuilder.SSHUser("ubuntu", "vm-ssh")).
+//		Build()
+//
+// Resources reference each other through template references, so the
+// resulting spec has the same dependencies as its YAML equivalent.
+package specbuilder
+
+import (
+	"fmt"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+// Defaults of resources added by the builder.
+const (
+	// DefaultKeyType is the type of keys added by WithKey.
+	DefaultKeyType = "ed25519"
+	// DefaultMemory is the memory in MB of VMs added by WithVM.
+	DefaultMemory = 1024
+	// DefaultVCPUs is the number of vCPUs of VMs added by WithVM.
+	DefaultVCPUs = 1
+	// DefaultDiskSize is the disk size of VMs added by WithVM.
+	DefaultDiskSize = "10G"
+)
+
+// Builder builds a spec. Its methods return the builder so calls can be
+// chained; resources are appended in call order.
+type Builder struct {
+	spec v1.Spec
+}
+
+// New returns an empty builder.
+func New() *Builder {
+	return &Builder{}
+}
+
+// WithEnvironmentID sets the requested environment ID.
+func (b *Builder) WithEnvironmentID(id string) *Builder {
+	b.spec.EnvironmentId = id
+	return b
+}
+
+// WithDefaultBaseImage sets the base image of VMs that set none.
+func (b *Builder) WithDefaultBaseImage(image string) *Builder {
+	b.spec.DefaultBaseImage = image
+	return b
+}
+
+// WithProvider adds a provider. With several providers, one must be marked
+// with ProviderDefault.
+func (b *Builder) WithProvider(name, engine string, opts ...ProviderOption) *Builder {
+	p := v1.ProviderConfig{Name: name, Engine: engine}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	b.spec.Providers = append(b.spec.Providers, p)
+	return b
+}
+
+// WithKey adds an SSH key of type DefaultKeyType unless KeyType is given.
+func (b *Builder) WithKey(name string, opts ...KeyOption) *Builder {
+	k := v1.KeyResource{Name: name, Spec: v1.KeySpec{Type: DefaultKeyType}}
+	for _, opt := range opts {
+		opt(&k)
+	}
+	b.spec.Keys = append(b.spec.Keys, k)
+	return b
+}
+
+// WithImage adds an image downloaded from source, a well-known reference or
+// an HTTPS URL.
+func (b *Builder) WithImage(name, source string, opts ...ImageOption) *Builder {
+	img := v1.ImageResource{Name: name, Spec: v1.ImageSpec{Source: source}}
+	for _, opt := range opts {
+		opt(&img)
+	}
+	b.spec.Images = append(b.spec.Images, img)
+	return b
+}
+
+// WithNetwork adds a network of the given kind, e.g. "bridge" or "nat".
+func (b *Builder) WithNetwork(name, kind string, opts ...NetworkOption) *Builder {
+	n := v1.NetworkResource{Name: name, Kind: kind}
+	for _, opt := range opts {
+		opt(&n)
+	}
+	b.spec.Networks = append(b.spec.Networks, n)
+	return b
+}
+
+// WithVM adds a VM with DefaultMemory, DefaultVCPUs and DefaultDiskSize
+// unless overridden by opts.
+func (b *Builder) WithVM(name string, opts ...VMOption) *Builder {
+	vm := v1.VMResource{
+		Name: name,
+		Spec: v1.VMSpec{
+			Memory: DefaultMemory,
+			Vcpus:  DefaultVCPUs,
+			Disk:   v1.DiskSpec{Size: DefaultDiskSize},
+		},
+	}
+	for _, opt := range opts {
+		opt(&vm)
+	}
+	b.spec.Vms = append(b.spec.Vms, vm)
+	return b
+}
+
+// Build validates the spec and returns a copy of it. The builder can be
+// used again afterwards.
+func (b *Builder) Build() (*v1.Spec, error) {
+	s, err := v1.SpecFromMap(b.spec.ToMap())
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy spec: %w", err)
+	}
+	if err := spec.Validate(s); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	return s, nil
+}
+
+// MustBuild is like Build but panics if the spec is invalid. It is meant
+// for tests and package-level definitions.
+func (b *Builder) MustBuild() *v1.Spec {
+	s, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Map validates the spec and returns it as a map, as passed to create.
+func (b *Builder) Map() (map[string]any, error) {
+	s, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return s.ToMap(), nil
+}
+
+// PublicKey returns the template reference to the public key of a key.
+func PublicKey(key string) string {
+	return fmt.Sprintf("{{ .Keys.%s.PublicKey }}", key)
+}
+
+// PrivateKeyPath returns the template reference to the private key file of
+// a key.
+func PrivateKeyPath(key string) string {
+	return fmt.Sprintf("{{ .Keys.%s.PrivateKeyPath }}", key)
+}
+
+// NetworkName returns the template reference to the name of a network.
+func NetworkName(network string) string {
+	return fmt.Sprintf("{{ .Networks.%s.Name }}", network)
+}
+
+// ImagePath returns the template reference to the local path of an image.
+func ImagePath(image string) string {
+	return fmt.Sprintf("{{ .Images.%s.Path }}", image)
+}
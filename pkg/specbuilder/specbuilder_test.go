@@ -0,0 +1,133 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package specbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/spec"
+)
+
+func newLab() *Builder {
+	return New().
+		WithProvider("libvirt", "go://test").
+		WithKey("vm-ssh").
+		WithNetwork("net", "bridge", NetworkCIDR("192.168.100.1/24"), NetworkDHCP("192.168.100.10", "192.168.100.50")).
+		WithVM("vm", Memory(2048), VCPUs(2), OnNetworks("net"), SSHUser("ubuntu", "vm-ssh"), Packages("curl"))
+}
+
+func TestBuild(t *testing.T) {
+	s, err := newLab().Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if len(s.Keys) != 1 || s.Keys[0].Spec.Type != DefaultKeyType {
+		t.Errorf("Keys = %+v, want one %s key", s.Keys, DefaultKeyType)
+	}
+	vm := s.Vms[0].Spec
+	if vm.Memory != 2048 || vm.Vcpus != 2 || vm.Disk.Size != DefaultDiskSize {
+		t.Errorf("VM = %+v, want 2048 MB, 2 vCPUs and a %s disk", vm, DefaultDiskSize)
+	}
+	if len(vm.Networks) != 1 || vm.Networks[0] != "{{ .Networks.net.Name }}" {
+		t.Errorf("Networks = %q, want a reference to net", vm.Networks)
+	}
+	if !vm.Readiness.Ssh.Enabled || vm.Readiness.Ssh.PrivateKey != "{{ .Keys.vm-ssh.PrivateKeyPath }}" {
+		t.Errorf("Readiness.Ssh = %+v, want SSH with the vm-ssh key", vm.Readiness.Ssh)
+	}
+
+	// Template references create dependencies like in YAML specs.
+	refs := make(map[string]bool)
+	for _, ref := range spec.ExtractTemplateRefs(s) {
+		refs[ref.Kind+"/"+ref.Name] = true
+	}
+	if !refs["key/vm-ssh"] || !refs["network/net"] {
+		t.Errorf("ExtractTemplateRefs() = %v, want key/vm-ssh and network/net", refs)
+	}
+}
+
+func TestBuildReturnsCopies(t *testing.T) {
+	b := newLab()
+	first := b.MustBuild()
+	first.Vms[0].Spec.Memory = 1
+
+	second := b.WithVM("other").MustBuild()
+	if second.Vms[0].Spec.Memory != 2048 {
+		t.Errorf("Memory = %d, want 2048: Build must not share state", second.Vms[0].Spec.Memory)
+	}
+	if len(second.Vms) != 2 || second.Vms[1].Spec.Memory != DefaultMemory {
+		t.Errorf("Vms = %+v, want the default VM appended", second.Vms)
+	}
+}
+
+func TestBuildInvalid(t *testing.T) {
+	tests := []struct {
+		name      string
+		builder   *Builder
+		errSubstr string
+	}{
+		{
+			name:      "no provider",
+			builder:   New().WithVM("vm"),
+			errSubstr: "at least one provider",
+		},
+		{
+			name:      "unknown network",
+			builder:   New().WithProvider("p", "go://test").WithVM("vm", OnNetworks("missing")),
+			errSubstr: "missing",
+		},
+		{
+			name: "two providers without default",
+			builder: New().
+				WithProvider("a", "go://a").
+				WithProvider("b", "go://b"),
+			errSubstr: "no default provider",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.errSubstr) {
+				t.Errorf("Build() error = %v, want it to contain %q", err, tt.errSubstr)
+			}
+		})
+	}
+}
+
+func TestMustBuildPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustBuild() did not panic on an invalid spec")
+		}
+	}()
+	New().MustBuild()
+}
+
+func TestMap(t *testing.T) {
+	m, err := New().
+		WithProvider("a", "go://a", ProviderDefault()).
+		WithProvider("b", "go://b").
+		WithKey("k", KeyType("rsa"), KeyBits(4096), KeyProvider("b")).
+		Map()
+	if err != nil {
+		t.Fatalf("Map() error = %v", err)
+	}
+	if report := spec.ReportFromMap(m); !report.Valid || len(report.Issues) != 0 {
+		t.Errorf("ReportFromMap() = %+v, want a valid spec without issues", report)
+	}
+}
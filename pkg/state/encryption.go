@@ -0,0 +1,118 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedHeader starts encrypted state files, followed by the format
+// version and a newline. Files without it are plaintext JSON.
+const encryptedHeader = "testenv-vm-encrypted-state "
+
+// encryptionVersion is the version of the encrypted format written by Save:
+// the header, a 12-byte nonce, then the AES-256-GCM sealed JSON state,
+// authenticated with the header and the environment ID.
+const encryptionVersion = "v1"
+
+// KeySize is the size of state encryption keys.
+const KeySize = 32
+
+// ErrEncrypted is returned when loading an encrypted state file without a
+// key.
+var ErrEncrypted = errors.New("state file is encrypted and no state key is configured")
+
+// ParseKey decodes a base64-encoded state encryption key, as generated by
+// "openssl rand -base64 32".
+func ParseKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("state key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("state key is %d bytes, want %d", len(key), KeySize)
+	}
+	return key, nil
+}
+
+// isEncrypted reports whether data is an encrypted state file.
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedHeader))
+}
+
+// encrypt seals the state of environment id with key.
+func encrypt(key []byte, id string, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := []byte(encryptedHeader + encryptionVersion + "\n")
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	out := append(header, nonce...)
+	return aead.Seal(out, nonce, plaintext, additionalData(header, id)), nil
+}
+
+// decrypt opens an encrypted state file of environment id with key.
+func decrypt(key []byte, id string, data []byte) ([]byte, error) {
+	line, rest, ok := bytes.Cut(data, []byte("\n"))
+	if !ok {
+		return nil, errors.New("truncated encrypted state header")
+	}
+	if version := string(line[len(encryptedHeader):]); version != encryptionVersion {
+		return nil, fmt.Errorf("unsupported encrypted state version %q", version)
+	}
+	if key == nil {
+		return nil, ErrEncrypted
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("truncated encrypted state")
+	}
+	header := data[:len(line)+1]
+	nonce, ciphertext := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData(header, id))
+	if err != nil {
+		return nil, errors.New("failed to decrypt state: wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// additionalData binds a sealed state to its header and environment, so a
+// state file cannot be passed off as another environment's.
+func additionalData(header []byte, id string) []byte {
+	return append(append([]byte(nil), header...), id...)
+}
+
+// newAEAD returns the AES-256-GCM cipher of key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
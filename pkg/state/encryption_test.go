@@ -0,0 +1,135 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// newTestKey returns a random state key.
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestParseKey(t *testing.T) {
+	key := newTestKey(t)
+	got, err := ParseKey(base64.StdEncoding.EncodeToString(key) + "\n")
+	if err != nil || !bytes.Equal(got, key) {
+		t.Errorf("ParseKey() = %x, %v, want %x", got, err, key)
+	}
+	for _, encoded := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, err := ParseKey(encoded); err == nil {
+			t.Errorf("ParseKey(%q) should fail", encoded)
+		}
+	}
+}
+
+func TestEncryptedSaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t)
+	store := NewStore(dir, WithKey(key))
+
+	if err := store.Save(createTestState("enc")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(store.statePath("enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), encryptedHeader+encryptionVersion+"\n") {
+		t.Errorf("state file should start with the versioned header, got %q", data[:32])
+	}
+	if bytes.Contains(data, []byte("192.168.100.10")) {
+		t.Error("state file should not contain plaintext")
+	}
+	info, err := os.Stat(store.statePath("enc"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("encrypted state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := store.Load("enc")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Resources.VMs["test-vm"].State["ip"] != "192.168.100.10" {
+		t.Errorf("Load() = %+v, want the saved state", loaded)
+	}
+
+	if _, err := NewStore(dir).Load("enc"); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Load() without a key error = %v, want ErrEncrypted", err)
+	}
+	if _, err := NewStore(dir, WithKey(newTestKey(t))).Load("enc"); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("Load() with another key error = %v, want wrong key", err)
+	}
+
+	// A state file copied to another environment does not decrypt
+	if err := os.WriteFile(store.statePath("other"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("other"); err == nil {
+		t.Error("Load() of a state file of another environment should fail")
+	}
+}
+
+func TestEncryptedLoadReadsPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewStore(dir).Save(createTestState("legacy")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	store := NewStore(dir, WithKey(newTestKey(t)))
+	loaded, err := store.Load("legacy")
+	if err != nil {
+		t.Fatalf("Load() of a plaintext state error = %v", err)
+	}
+	if err := store.Save(loaded); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(store.statePath("legacy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(data) {
+		t.Error("plaintext state should be encrypted when saved again")
+	}
+}
+
+func TestDecryptUnsupportedVersion(t *testing.T) {
+	key := newTestKey(t)
+	data, err := encrypt(key, "env", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(encryptionVersion+"\n"), []byte("v9\n"), 1)
+	if _, err := decrypt(key, "env", data); err == nil || !strings.Contains(err.Error(), `unsupported encrypted state version "v9"`) {
+		t.Errorf("decrypt() error = %v, want unsupported version", err)
+	}
+	if _, err := decrypt(key, "env", []byte(encryptedHeader+"v1")); err == nil {
+		t.Error("decrypt() of a truncated header should fail")
+	}
+}
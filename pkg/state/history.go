@@ -0,0 +1,162 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// DefaultHistoryLimit is the number of revisions of the state of an
+// environment kept by default.
+const DefaultHistoryLimit = 10
+
+// revisionSuffix ends the name of revision files, e.g. "12.json".
+const revisionSuffix = ".json"
+
+// Revision is a saved state of an environment kept in its history.
+type Revision struct {
+	// Number is 1 for the first save of the environment and increases by
+	// one with each save.
+	Number int `json:"revision"`
+	// SavedAt is when the revision was saved (RFC3339).
+	SavedAt string `json:"savedAt"`
+}
+
+// History returns the revisions kept for the given testID, oldest first.
+// Only the last revisions are kept (see WithHistoryLimit), so the first one
+// is not revision 1 once older ones were pruned. It returns an empty slice
+// if the environment has no history.
+func (s *Store) History(testID string) ([]Revision, error) {
+	if testID == "" {
+		return nil, fmt.Errorf("cannot read history with empty testID")
+	}
+	numbers, err := s.revisions(testID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]Revision, 0, len(numbers))
+	for _, n := range numbers {
+		info, err := os.Stat(s.revisionPath(testID, n))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Pruned by a concurrent save
+				continue
+			}
+			return nil, fmt.Errorf("failed to read revision %d of %q: %w", n, testID, err)
+		}
+		history = append(history, Revision{
+			Number:  n,
+			SavedAt: info.ModTime().UTC().Format(time.RFC3339),
+		})
+	}
+	return history, nil
+}
+
+// LoadRevision reads a revision of the state of the given testID from its
+// history.
+func (s *Store) LoadRevision(testID string, revision int) (*v1.EnvironmentState, error) {
+	if testID == "" {
+		return nil, fmt.Errorf("cannot load state with empty testID")
+	}
+
+	path := s.revisionPath(testID, revision)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("revision %d not found in the history of %q: %w", revision, testID, err)
+		}
+		return nil, fmt.Errorf("failed to read revision file %q: %w", path, err)
+	}
+	return s.decode(testID, path, data)
+}
+
+// saveRevision records data, the content of the state file of testID just
+// saved, as its next revision and prunes the revisions beyond the limit.
+// Revisions are encrypted like the state file, with the same permissions.
+func (s *Store) saveRevision(testID string, data []byte, perm os.FileMode) error {
+	if s.historyLimit == 0 {
+		return nil
+	}
+	historyDir := s.layout.HistoryDir(testID)
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory %q: %w", historyDir, err)
+	}
+	numbers, err := s.revisions(testID)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(numbers) > 0 {
+		next = numbers[len(numbers)-1] + 1
+	}
+
+	targetPath := s.revisionPath(testID, next)
+	tempPath := targetPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, perm); err != nil {
+		return fmt.Errorf("failed to write temporary revision file %q: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, targetPath); err != nil {
+		_ = os.Remove(tempPath)
+		return fmt.Errorf("failed to rename revision file from %q to %q: %w", tempPath, targetPath, err)
+	}
+
+	numbers = append(numbers, next)
+	for _, n := range numbers[:max(len(numbers)-s.historyLimit, 0)] {
+		if err := os.Remove(s.revisionPath(testID, n)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to prune revision %d: %w", n, err)
+		}
+	}
+	return nil
+}
+
+// revisions returns the numbers of the revisions kept for testID in
+// ascending order.
+func (s *Store) revisions(testID string) ([]int, error) {
+	historyDir := s.layout.HistoryDir(testID)
+	entries, err := os.ReadDir(historyDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history directory %q: %w", historyDir, err)
+	}
+
+	var numbers []int
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), revisionSuffix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if n, err := strconv.Atoi(name); err == nil && n > 0 {
+			numbers = append(numbers, n)
+		}
+	}
+	slices.Sort(numbers)
+	return numbers, nil
+}
+
+// revisionPath returns the file of a revision of testID.
+func (s *Store) revisionPath(testID string, revision int) string {
+	return filepath.Join(s.layout.HistoryDir(testID), strconv.Itoa(revision)+revisionSuffix)
+}
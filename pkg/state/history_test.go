@@ -0,0 +1,111 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestHistory(t *testing.T) {
+	store := NewStore(t.TempDir(), WithHistoryLimit(2))
+
+	if history, err := store.History("env"); err != nil || len(history) != 0 {
+		t.Fatalf("History() of an unsaved environment = %v, %v, want none", history, err)
+	}
+	for _, status := range []string{v1.StatusCreating, v1.StatusReady, v1.StatusFailed} {
+		s := createTestState("env")
+		s.Status = status
+		if err := store.Save(s); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	history, err := store.History("env")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Number != 2 || history[1].Number != 3 || history[1].SavedAt == "" {
+		t.Fatalf("History() = %+v, want revisions 2 and 3", history)
+	}
+	s, err := store.LoadRevision("env", 2)
+	if err != nil || s.Status != v1.StatusReady {
+		t.Errorf("LoadRevision(2) = %+v, %v, want the ready state", s, err)
+	}
+	if _, err := store.LoadRevision("env", 1); err == nil {
+		t.Error("LoadRevision() of a pruned revision succeeded")
+	}
+
+	if err := store.Delete("env"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(store.Layout().HistoryDir("env")); !os.IsNotExist(err) {
+		t.Errorf("history directory left after Delete(): %v", err)
+	}
+
+	// The history outlives a state file removed by other means
+	if err := store.Save(createTestState("env")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := os.Remove(store.Layout().StateFile("env")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("env"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := os.Stat(store.Layout().HistoryDir("env")); !os.IsNotExist(err) {
+		t.Errorf("history directory left after Delete() of a missing state: %v", err)
+	}
+}
+
+func TestHistory_Encrypted(t *testing.T) {
+	key := make([]byte, KeySize)
+	store := NewStore(t.TempDir(), WithKey(key))
+	if err := store.Save(createTestState("env")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	path := filepath.Join(store.Layout().HistoryDir("env"), "1.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(data) {
+		t.Error("revision saved in plaintext with a key")
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("revision mode = %v, want 0600", info.Mode().Perm())
+	}
+	if s, err := store.LoadRevision("env", 1); err != nil || s.ID != "env" {
+		t.Errorf("LoadRevision() = %+v, %v", s, err)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	store := NewStore(t.TempDir(), WithHistoryLimit(0))
+	if err := store.Save(createTestState("env")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if history, err := store.History("env"); err != nil || len(history) != 0 {
+		t.Errorf("History() with the history disabled = %v, %v, want none", history, err)
+	}
+}
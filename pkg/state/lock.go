@@ -0,0 +1,128 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
+)
+
+// lockPollInterval is how often Lock retries a lock held by another
+// operation.
+var lockPollInterval = 100 * time.Millisecond
+
+// Lock takes the exclusive lock of an environment, waiting while another
+// operation of this or another process holds it, until ctx is done. The
+// returned function releases it. Operations that read, change and save the
+// state of an environment hold its lock so that concurrent operations on
+// one environment do not overwrite each other's state; operations on
+// different environments do not wait for each other. Releasing the lock of
+// an environment without state, deleted or never saved, removes its lock
+// file; an operation that was waiting on the removed file then locks the
+// file at the lock path again, so that it never holds the lock alongside an
+// operation that opened the recreated file.
+func (s *Store) Lock(ctx context.Context, testID string) (func(), error) {
+	if testID == "" {
+		return nil, fmt.Errorf("cannot lock state with empty testID")
+	}
+	if err := os.MkdirAll(s.stateDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %q: %w", s.stateDir(), err)
+	}
+
+	lockPath := s.layout.LockFile(testID)
+	for {
+		f, err := lockFile(ctx, testID, lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if held, err := f.Stat(); err == nil {
+			if current, err := os.Stat(lockPath); err == nil && os.SameFile(held, current) {
+				return func() {
+					if !s.Exists(testID) {
+						_ = os.Remove(lockPath)
+					}
+					_ = unix.Flock(int(f.Fd()), unix.LOCK_UN)
+					_ = f.Close()
+				}, nil
+			}
+		}
+		// The lock file was removed while waiting for it
+		_ = f.Close()
+	}
+}
+
+// lockFile opens the lock file of an environment at lockPath and locks it,
+// waiting while another operation holds it, until ctx is done.
+func lockFile(ctx context.Context, testID, lockPath string) (*os.File, error) {
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %q: %w", lockPath, err)
+	}
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, unix.EWOULDBLOCK) {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock environment %q: %w", testID, err)
+		}
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+			return nil, fmt.Errorf("environment %q is locked by another operation: %w", testID, context.Cause(ctx))
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Locked returns the IDs of the environments whose lock is held by an
+// operation of this or another process, such as a creation that has not
+// saved any state yet.
+func (s *Store) Locked() ([]string, error) {
+	entries, err := os.ReadDir(s.stateDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read state directory %q: %w", s.stateDir(), err)
+	}
+
+	locked := []string{}
+	for _, entry := range entries {
+		testID, ok := paths.EnvIDFromLockFile(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		f, err := os.Open(s.layout.LockFile(testID))
+		if err != nil {
+			continue
+		}
+		if err := unix.Flock(int(f.Fd()), unix.LOCK_SH|unix.LOCK_NB); errors.Is(err, unix.EWOULDBLOCK) {
+			locked = append(locked, testID)
+		} else if err == nil {
+			_ = unix.Flock(int(f.Fd()), unix.LOCK_UN)
+		}
+		_ = f.Close()
+	}
+	return locked, nil
+}
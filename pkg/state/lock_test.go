@@ -0,0 +1,141 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if err := store.Save(createTestState("env1")); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	unlock, err := store.Lock(context.Background(), "env1")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	// Another environment is not blocked
+	unlockOther, err := store.Lock(context.Background(), "env2")
+	if err != nil {
+		t.Fatalf("Lock() of another environment error = %v", err)
+	}
+	unlockOther()
+	if _, err := os.Stat(store.Layout().LockFile("env2")); !os.IsNotExist(err) {
+		t.Errorf("lock file of an environment without state was kept: %v", err)
+	}
+
+	// The same environment waits until the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := store.Lock(ctx, "env1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() of a locked environment error = %v, want context.DeadlineExceeded", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		unlock, err := store.Lock(context.Background(), "env1")
+		if err != nil {
+			t.Errorf("Lock() after release error = %v", err)
+			unlock = func() {}
+		}
+		acquired <- unlock
+	}()
+	unlock()
+	select {
+	case unlock := <-acquired:
+		unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock() did not acquire the released lock")
+	}
+	if _, err := os.Stat(store.Layout().LockFile("env1")); err != nil {
+		t.Errorf("lock file of an environment with state was removed: %v", err)
+	}
+
+	if _, err := store.Lock(context.Background(), ""); err == nil {
+		t.Error("Lock() with empty testID succeeded, want error")
+	}
+}
+
+func TestLock_RemovedWhileWaiting(t *testing.T) {
+	store := NewStore(t.TempDir())
+	unlock, err := store.Lock(context.Background(), "env1")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		unlock, err := store.Lock(context.Background(), "env1")
+		if err != nil {
+			t.Errorf("Lock() after release error = %v", err)
+			unlock = func() {}
+		}
+		acquired <- unlock
+	}()
+	// Let the waiter open the lock file that the release removes
+	time.Sleep(50 * time.Millisecond)
+	unlock()
+	var unlockWaiter func()
+	select {
+	case unlockWaiter = <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Lock() did not acquire the released lock")
+	}
+	defer unlockWaiter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := store.Lock(ctx, "env1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Lock() while the waiter holds the lock error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestLocked(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if locked, err := store.Locked(); err != nil || len(locked) != 0 {
+		t.Fatalf("Locked() = %v, %v, want none", locked, err)
+	}
+
+	unlock, err := store.Lock(context.Background(), "creating")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	// A lock file left by a crashed process is not held
+	if err := os.WriteFile(store.Layout().LockFile("crashed"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	locked, err := store.Locked()
+	if err != nil {
+		t.Fatalf("Locked() error = %v", err)
+	}
+	if len(locked) != 1 || locked[0] != "creating" {
+		t.Errorf("Locked() = %v, want [creating]", locked)
+	}
+
+	unlock()
+	if locked, err := store.Locked(); err != nil || len(locked) != 0 {
+		t.Errorf("Locked() after unlock = %v, %v, want none", locked, err)
+	}
+}
@@ -13,55 +13,83 @@
 // limitations under the License.
 
 // Package state provides persistent storage for test environment state.
-// State files are stored as JSON on disk for reliable cleanup across restarts.
+// State files are stored as JSON on disk for reliable cleanup across restarts,
+// optionally encrypted with AES-256-GCM (see WithKey).
 package state
 
 import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
-)
-
-const (
-	// stateSubdir is the subdirectory within baseDir for state files.
-	stateSubdir = "state"
-	// stateFilePrefix is the prefix for state files.
-	stateFilePrefix = "testenv-"
-	// stateFileSuffix is the suffix for state files.
-	stateFileSuffix = ".json"
+	"github.com/alexandremahdhaoui/testenv-vm/pkg/paths"
 )
 
 // Store manages persistent state storage for test environments.
-// State files are stored at {baseDir}/state/testenv-{testID}.json.
+// State files are stored at {baseDir}/state/testenv-{testID}.json (see
+// paths.Layout.StateFile).
 type Store struct {
-	baseDir string
+	layout paths.Layout
+	// key encrypts saved state files when set.
+	key []byte
+	// historyLimit is the number of revisions kept per environment; zero
+	// disables the history.
+	historyLimit int
+}
+
+// Option is a functional option for configuring a Store.
+type Option func(*Store)
+
+// WithKey encrypts the state files saved by the store with key, a KeySize
+// key (see ParseKey). Plaintext state files written without a key remain
+// readable and are encrypted when next saved.
+func WithKey(key []byte) Option {
+	return func(s *Store) {
+		s.key = key
+	}
+}
+
+// WithHistoryLimit keeps the last limit revisions of the state of each
+// environment instead of DefaultHistoryLimit; zero disables the history.
+func WithHistoryLimit(limit int) Option {
+	return func(s *Store) {
+		s.historyLimit = max(limit, 0)
+	}
 }
 
 // NewStore creates a new Store with the specified base directory.
 // The base directory is where all state files will be stored.
-func NewStore(baseDir string) *Store {
-	return &Store{
-		baseDir: baseDir,
+func NewStore(baseDir string, opts ...Option) *Store {
+	s := &Store{
+		layout:       paths.New(baseDir),
+		historyLimit: DefaultHistoryLimit,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Layout returns the on-disk layout below the base directory.
+func (s *Store) Layout() paths.Layout {
+	return s.layout
 }
 
 // stateDir returns the directory path for state files.
 func (s *Store) stateDir() string {
-	return filepath.Join(s.baseDir, stateSubdir)
+	return s.layout.StateDir()
 }
 
 // statePath returns the file path for a given testID.
 func (s *Store) statePath(testID string) string {
-	return filepath.Join(s.stateDir(), stateFilePrefix+testID+stateFileSuffix)
+	return s.layout.StateFile(testID)
 }
 
 // Save persists the environment state to disk.
 // It uses atomic writes (write to temp file, then rename) to prevent corruption.
-// Directories are created if they don't exist.
+// Directories are created if they don't exist. The saved state is also kept
+// as the next revision of the history of the environment (see History).
 func (s *Store) Save(state *v1.EnvironmentState) error {
 	if state == nil {
 		return fmt.Errorf("cannot save nil state")
@@ -81,12 +109,19 @@ func (s *Store) Save(state *v1.EnvironmentState) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal state to JSON: %w", err)
 	}
+	perm := os.FileMode(0644)
+	if s.key != nil {
+		if data, err = encrypt(s.key, state.ID, data); err != nil {
+			return fmt.Errorf("failed to encrypt state: %w", err)
+		}
+		perm = 0600
+	}
 
 	// Write to a temporary file first for atomic operation
 	targetPath := s.statePath(state.ID)
 	tempPath := targetPath + ".tmp"
 
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+	if err := os.WriteFile(tempPath, data, perm); err != nil {
 		return fmt.Errorf("failed to write temporary state file %q: %w", tempPath, err)
 	}
 
@@ -97,6 +132,9 @@ func (s *Store) Save(state *v1.EnvironmentState) error {
 		return fmt.Errorf("failed to rename state file from %q to %q: %w", tempPath, targetPath, err)
 	}
 
+	if err := s.saveRevision(state.ID, data, perm); err != nil {
+		return fmt.Errorf("failed to record state history: %w", err)
+	}
 	return nil
 }
 
@@ -115,32 +153,45 @@ func (s *Store) Load(testID string) (*v1.EnvironmentState, error) {
 		}
 		return nil, fmt.Errorf("failed to read state file %q: %w", statePath, err)
 	}
+	return s.decode(testID, statePath, data)
+}
+
+// decode decrypts, if needed, and parses the content of a state file of
+// testID read from path.
+func (s *Store) decode(testID, path string, data []byte) (*v1.EnvironmentState, error) {
+	var err error
+	if isEncrypted(data) {
+		if data, err = decrypt(s.key, testID, data); err != nil {
+			return nil, fmt.Errorf("failed to read state file %q: %w", path, err)
+		}
+	}
 
 	var state v1.EnvironmentState
 	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state file %q: %w", statePath, err)
+		return nil, fmt.Errorf("failed to parse state file %q: %w", path, err)
 	}
 
 	return &state, nil
 }
 
-// Delete removes the state file for the given testID.
+// Delete removes the state file and the history for the given testID.
 // It returns an error if the file cannot be deleted, but does not error
-// if the file does not exist.
+// if the file does not exist; the history is removed either way.
 func (s *Store) Delete(testID string) error {
 	if testID == "" {
 		return fmt.Errorf("cannot delete state with empty testID")
 	}
 
 	statePath := s.statePath(testID)
-	if err := os.Remove(statePath); err != nil {
-		if os.IsNotExist(err) {
-			// Not an error if the file doesn't exist
-			return nil
-		}
+	// Not an error if the file doesn't exist
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete state file %q: %w", statePath, err)
 	}
 
+	historyDir := s.layout.HistoryDir(testID)
+	if err := os.RemoveAll(historyDir); err != nil {
+		return fmt.Errorf("failed to delete state history %q: %w", historyDir, err)
+	}
 	return nil
 }
 
@@ -167,13 +218,8 @@ func (s *Store) List() ([]string, error) {
 
 		name := entry.Name()
 		// Check if the file matches the expected pattern
-		if strings.HasPrefix(name, stateFilePrefix) && strings.HasSuffix(name, stateFileSuffix) {
-			// Extract testID from filename
-			testID := strings.TrimPrefix(name, stateFilePrefix)
-			testID = strings.TrimSuffix(testID, stateFileSuffix)
-			if testID != "" {
-				testIDs = append(testIDs, testID)
-			}
+		if testID, ok := paths.EnvIDFromStateFile(name); ok {
+			testIDs = append(testIDs, testID)
 		}
 	}
 
@@ -0,0 +1,259 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package terraform exports the resources of an environment as Terraform or
+// OpenTofu configuration for the dmacvicar/libvirt provider. Every exported
+// resource is paired with an import block (Terraform >= 1.5, OpenTofu >= 1.6)
+// so that "plan" adopts the existing networks and domains instead of
+// recreating them.
+package terraform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// libvirtEngineMarker identifies libvirt provider engines, e.g.
+// "go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-libvirt".
+const libvirtEngineMarker = "libvirt"
+
+// networkModes maps libvirt provider network kinds to libvirt_network modes.
+var networkModes = map[string]string{
+	"nat":      "nat",
+	"isolated": "none",
+	"bridge":   "bridge",
+}
+
+// FromState renders the libvirt resources of an environment. Keys are
+// exported as locals holding the public key and the private key path.
+// Resources of other providers, and resources without a recorded UUID, are
+// listed as comments.
+func FromState(envState *v1.EnvironmentState) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Generated by testenv-vm from environment %s.\n", envState.ID)
+	b.WriteString("# Run \"tofu plan\" or \"terraform plan\" to import the resources below.\n\n")
+	b.WriteString(`terraform {
+  required_providers {
+    libvirt = {
+      source = "dmacvicar/libvirt"
+    }
+  }
+}
+
+variable "libvirt_uri" {
+  type    = string
+  default = "qemu:///system"
+}
+
+provider "libvirt" {
+  uri = var.libvirt_uri
+}
+`)
+
+	if envState.Spec == nil {
+		return b.String()
+	}
+	spec := envState.Spec
+	libvirt := libvirtProviders(spec)
+
+	writeKeys(&b, spec, envState, libvirt)
+
+	networkIDs := make(map[string]string)
+	for _, n := range sortedNetworks(spec.Networks) {
+		rs := envState.Resources.Networks[n.Name]
+		if skip := skipReason(rs, resourceProvider(n.Provider, spec), libvirt); skip != "" {
+			fmt.Fprintf(&b, "\n# network %s: %s\n", n.Name, skip)
+			continue
+		}
+		id := identifier(n.Name)
+		networkIDs[n.Name] = id
+		fmt.Fprintf(&b, "\nimport {\n  to = libvirt_network.%s\n  id = %s\n}\n", id, quote(stateString(rs.State, "uuid")))
+		fmt.Fprintf(&b, "\nresource \"libvirt_network\" %s {\n", quote(id))
+		fmt.Fprintf(&b, "  name = %s\n", quote(stateNameOr(rs, n.Name)))
+		if mode, ok := networkModes[stateString(rs.State, "kind")]; ok {
+			fmt.Fprintf(&b, "  mode = %s\n", quote(mode))
+		}
+		if bridge := stateString(rs.State, "interfaceName"); bridge != "" {
+			fmt.Fprintf(&b, "  bridge = %s\n", quote(bridge))
+		}
+		if cidr := stateString(rs.State, "cidr"); cidr != "" {
+			fmt.Fprintf(&b, "  addresses = [%s]\n", quote(cidr))
+		}
+		b.WriteString("}\n")
+	}
+
+	vms := append([]v1.VMResource(nil), spec.Vms...)
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+	for _, vm := range vms {
+		rs := envState.Resources.VMs[vm.Name]
+		if skip := skipReason(rs, resourceProvider(vm.Provider, spec), libvirt); skip != "" {
+			fmt.Fprintf(&b, "\n# vm %s: %s\n", vm.Name, skip)
+			continue
+		}
+		id := identifier(vm.Name)
+		fmt.Fprintf(&b, "\nimport {\n  to = libvirt_domain.%s\n  id = %s\n}\n", id, quote(stateString(rs.State, "uuid")))
+		fmt.Fprintf(&b, "\nresource \"libvirt_domain\" %s {\n", quote(id))
+		fmt.Fprintf(&b, "  name   = %s\n", quote(stateNameOr(rs, vm.Name)))
+		fmt.Fprintf(&b, "  memory = %d\n", vm.Spec.Memory)
+		fmt.Fprintf(&b, "  vcpu   = %d\n", vm.Spec.Vcpus)
+
+		providerState, _ := rs.State["providerState"].(map[string]any)
+		if disk := stateString(providerState, "diskPath"); disk != "" {
+			fmt.Fprintf(&b, "\n  disk {\n    file = %s\n  }\n", quote(disk))
+		}
+		if iso := stateString(providerState, "cloudInitISO"); iso != "" {
+			fmt.Fprintf(&b, "\n  disk {\n    file = %s\n  }\n", quote(iso))
+		}
+
+		networks := vm.Spec.Networks
+		if len(networks) == 0 && vm.Spec.Network != "" {
+			networks = []string{vm.Spec.Network}
+		}
+		macs, _ := rs.State["macs"].(map[string]any)
+		for i, network := range networks {
+			b.WriteString("\n  network_interface {\n")
+			if netID, ok := networkIDs[network]; ok {
+				fmt.Fprintf(&b, "    network_id = libvirt_network.%s.id\n", netID)
+			} else {
+				fmt.Fprintf(&b, "    network_name = %s\n", quote(stateNameOr(envState.Resources.Networks[network], network)))
+			}
+			mac := stateString(macs, stateNameOr(envState.Resources.Networks[network], network))
+			if mac == "" && i == 0 {
+				mac = stateString(rs.State, "mac")
+			}
+			if mac != "" {
+				fmt.Fprintf(&b, "    mac        = %s\n", quote(mac))
+			}
+			b.WriteString("  }\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// writeKeys renders the keys of libvirt providers as locals.
+func writeKeys(b *strings.Builder, spec *v1.Spec, envState *v1.EnvironmentState, libvirt map[string]bool) {
+	keys := append([]v1.KeyResource(nil), spec.Keys...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+
+	var entries []string
+	for _, key := range keys {
+		rs := envState.Resources.Keys[key.Name]
+		if rs == nil || !libvirt[resourceProvider(key.Provider, spec)] {
+			continue
+		}
+		entries = append(entries, fmt.Sprintf("    %s = {\n      public_key       = %s\n      private_key_path = %s\n    }\n",
+			identifier(key.Name),
+			quote(strings.TrimSpace(stateString(rs.State, "publicKey"))),
+			quote(stateString(rs.State, "privateKeyPath"))))
+	}
+	if len(entries) == 0 {
+		return
+	}
+	b.WriteString("\nlocals {\n  ssh_keys = {\n")
+	b.WriteString(strings.Join(entries, ""))
+	b.WriteString("  }\n}\n")
+}
+
+// libvirtProviders returns the names of the providers running a libvirt engine.
+func libvirtProviders(spec *v1.Spec) map[string]bool {
+	names := make(map[string]bool)
+	for _, p := range spec.Providers {
+		if strings.Contains(p.Engine, libvirtEngineMarker) {
+			names[p.Name] = true
+		}
+	}
+	return names
+}
+
+// resourceProvider returns the provider of a resource, defaulting to the
+// default provider of the spec.
+func resourceProvider(name string, spec *v1.Spec) string {
+	if name != "" {
+		return name
+	}
+	if spec.DefaultProvider != "" {
+		return spec.DefaultProvider
+	}
+	for _, p := range spec.Providers {
+		if p.Default {
+			return p.Name
+		}
+	}
+	if len(spec.Providers) == 1 {
+		return spec.Providers[0].Name
+	}
+	return ""
+}
+
+// skipReason explains why a resource cannot be exported, or returns "".
+func skipReason(rs *v1.ResourceState, provider string, libvirt map[string]bool) string {
+	switch {
+	case !libvirt[provider]:
+		return fmt.Sprintf("provider %q has no Terraform mapping, skipped", provider)
+	case rs == nil || rs.Status != v1.StatusReady:
+		return "not ready, skipped"
+	case stateString(rs.State, "uuid") == "":
+		return "no UUID recorded, skipped"
+	}
+	return ""
+}
+
+// sortedNetworks returns networks ordered by name.
+func sortedNetworks(networks []v1.NetworkResource) []v1.NetworkResource {
+	sorted := append([]v1.NetworkResource(nil), networks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// stateNameOr returns the name recorded by the provider, which includes the
+// isolation prefix, or fallback.
+func stateNameOr(rs *v1.ResourceState, fallback string) string {
+	if rs != nil {
+		if name := stateString(rs.State, "name"); name != "" {
+			return name
+		}
+	}
+	return fallback
+}
+
+// stateString returns a string value from a state map.
+func stateString(state map[string]any, key string) string {
+	s, _ := state[key].(string)
+	return s
+}
+
+// identifierPattern matches characters that are not valid in identifiers.
+var identifierPattern = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// identifier returns a Terraform identifier for a resource name.
+func identifier(name string) string {
+	id := identifierPattern.ReplaceAllString(name, "_")
+	if id == "" || !(id[0] == '_' || (id[0] >= 'a' && id[0] <= 'z') || (id[0] >= 'A' && id[0] <= 'Z')) {
+		id = "r_" + id
+	}
+	return id
+}
+
+// quote renders s as an HCL string literal, escaping template sequences.
+func quote(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
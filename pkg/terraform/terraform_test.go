@@ -0,0 +1,86 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func TestFromState(t *testing.T) {
+	envState := &v1.EnvironmentState{
+		ID: "env-1",
+		Spec: &v1.Spec{
+			Providers: []v1.ProviderConfig{
+				{Name: "libvirt", Engine: "go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-libvirt", Default: true},
+				{Name: "stub", Engine: "go://github.com/alexandremahdhaoui/testenv-vm/cmd/providers/testenv-vm-provider-stub"},
+			},
+			Keys:     []v1.KeyResource{{Name: "ssh"}},
+			Networks: []v1.NetworkResource{{Name: "net", Kind: "isolated"}},
+			Vms: []v1.VMResource{
+				{Name: "1web", Spec: v1.VMSpec{Memory: 1024, Vcpus: 2, Networks: []string{"net"}}},
+				{Name: "other", Provider: "stub"},
+				{Name: "pending"},
+			},
+		},
+		Resources: v1.ResourceMap{
+			Keys: map[string]*v1.ResourceState{"ssh": {Status: v1.StatusReady, State: map[string]any{
+				"publicKey": "ssh-ed25519 AAAA test\n", "privateKeyPath": "/keys/ssh",
+			}}},
+			Networks: map[string]*v1.ResourceState{"net": {Status: v1.StatusReady, State: map[string]any{
+				"name": "env-1-net", "kind": "isolated", "uuid": "net-uuid", "cidr": "10.0.0.0/24", "interfaceName": "virbr9",
+			}}},
+			VMs: map[string]*v1.ResourceState{
+				"1web": {Status: v1.StatusReady, State: map[string]any{
+					"name": "env-1-web", "uuid": "vm-uuid",
+					"macs":          map[string]any{"env-1-net": "52:54:00:00:00:01"},
+					"providerState": map[string]any{"diskPath": "/disks/web.qcow2", "cloudInitISO": "/ci/web.iso"},
+				}},
+				"other":   {Status: v1.StatusReady, State: map[string]any{"uuid": "x"}},
+				"pending": {Status: v1.StatusCreating},
+			},
+		},
+	}
+
+	out := FromState(envState)
+	for _, want := range []string{
+		"import {\n  to = libvirt_network.net\n  id = \"net-uuid\"\n}",
+		`name = "env-1-net"`,
+		`mode = "none"`,
+		`addresses = ["10.0.0.0/24"]`,
+		"import {\n  to = libvirt_domain.r_1web\n  id = \"vm-uuid\"\n}",
+		"memory = 1024",
+		`file = "/disks/web.qcow2"`,
+		"network_id = libvirt_network.net.id",
+		`mac        = "52:54:00:00:00:01"`,
+		`public_key       = "ssh-ed25519 AAAA test"`,
+		`# vm other: provider "stub" has no Terraform mapping, skipped`,
+		"# vm pending: not ready, skipped",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote(`a "${b}" %{c}`); got != `"a \"$${b}\" %%{c}"` {
+		t.Errorf("quote() = %s", got)
+	}
+}
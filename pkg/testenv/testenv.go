@@ -0,0 +1,151 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testenv annotates Go tests with the environment they run against,
+// so that the report of a failed test links to the diagnostics of its
+// environment: its ID, the IPs of its VMs and its artifact files.
+package testenv
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// metadataEnvironmentID is the artifact metadata holding the environment ID
+// (see orchestrator.MetadataEnvironmentID).
+const metadataEnvironmentID = "testenv-vm.environmentId"
+
+// Event is an annotation of a test: a name in the namespace of the artifact,
+// e.g. "testenv-vm.vm.web.ip", and its value.
+type Event struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Option configures Annotate.
+type Option func(*annotator)
+
+type annotator struct {
+	always      bool
+	artifactDir string
+	junitDir    string
+}
+
+// WithAlways logs the events of passing tests too. By default they are
+// only logged when the test fails.
+func WithAlways() Option {
+	return func(a *annotator) {
+		a.always = true
+	}
+}
+
+// WithArtifactDir resolves the artifact files, relative to the artifact
+// directory, against dir.
+func WithArtifactDir(dir string) Option {
+	return func(a *annotator) {
+		a.artifactDir = dir
+	}
+}
+
+// WithJUnitProperties also writes the events as the JUnit properties of the
+// test case to <dir>/<test name>.xml, for CI reporters that merge them into
+// the test report.
+func WithJUnitProperties(dir string) Option {
+	return func(a *annotator) {
+		a.junitDir = dir
+	}
+}
+
+// Events returns the annotations of an artifact: its environment ID first,
+// then the IPs of its VMs and its files, by name. Key files are left out.
+func Events(artifact *v1.TestEnvArtifact) []Event {
+	var events []Event
+	for name, value := range artifact.Metadata {
+		if strings.HasPrefix(name, "testenv-vm.vm.") && strings.HasSuffix(name, ".ip") {
+			events = append(events, Event{Name: name, Value: value})
+		}
+	}
+	for name, path := range artifact.Files {
+		if !strings.HasPrefix(name, "testenv-vm.key.") {
+			events = append(events, Event{Name: name, Value: path})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Name < events[j].Name })
+	if id := artifact.Metadata[metadataEnvironmentID]; id != "" {
+		events = append([]Event{{Name: metadataEnvironmentID, Value: id}}, events...)
+	}
+	return events
+}
+
+// Annotate logs the events of artifact with t.Log when t fails, once it and
+// its subtests have completed.
+func Annotate(t testing.TB, artifact *v1.TestEnvArtifact, opts ...Option) {
+	t.Helper()
+	a := &annotator{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	events := Events(artifact)
+	if a.artifactDir != "" {
+		for i, e := range events {
+			if _, ok := artifact.Files[e.Name]; ok && !filepath.IsAbs(e.Value) {
+				events[i].Value = filepath.Join(a.artifactDir, e.Value)
+			}
+		}
+	}
+
+	t.Cleanup(func() {
+		if !t.Failed() && !a.always {
+			return
+		}
+		for _, e := range events {
+			t.Logf("%s=%s", e.Name, e.Value)
+		}
+		if a.junitDir != "" {
+			if err := writeProperties(a.junitDir, t.Name(), events); err != nil {
+				t.Logf("testenv: %v", err)
+			}
+		}
+	})
+}
+
+// junitTestCase is a JUnit test case holding only properties.
+type junitTestCase struct {
+	XMLName    xml.Name `xml:"testcase"`
+	Name       string   `xml:"name,attr"`
+	Properties []Event  `xml:"properties>property"`
+}
+
+// writeProperties writes the events of a test as JUnit properties.
+func writeProperties(dir, testName string, events []Event) error {
+	data, err := xml.MarshalIndent(junitTestCase{Name: testName, Properties: events}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit properties: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create JUnit properties directory: %w", err)
+	}
+	path := filepath.Join(dir, strings.ReplaceAll(testName, "/", "_")+".xml")
+	if err := os.WriteFile(path, append([]byte(xml.Header), data...), 0o644); err != nil {
+		return fmt.Errorf("failed to write JUnit properties: %w", err)
+	}
+	return nil
+}
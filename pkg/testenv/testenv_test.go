@@ -0,0 +1,132 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testenv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// fakeTB records the logs and cleanups of a test.
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper()           {}
+func (f *fakeTB) Name() string      { return "TestWeb/ping" }
+func (f *fakeTB) Failed() bool      { return f.failed }
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+func (f *fakeTB) Logf(format string, args ...any) {
+	f.logs = append(f.logs, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func testArtifact() *v1.TestEnvArtifact {
+	return &v1.TestEnvArtifact{
+		Files: map[string]string{
+			"testenv-vm.key.ssh":      "keys/ssh",
+			"testenv-vm.known_hosts":  "known_hosts",
+			"testenv-vm.topology.svg": "/abs/topology.svg",
+		},
+		Metadata: map[string]string{
+			"testenv-vm.environmentId":  "abc123",
+			"testenv-vm.vm.web.ip":      "10.0.0.2",
+			"testenv-vm.vm.web.sshPort": "22",
+			"testenv-vm.vm.db.ip":       "10.0.0.3",
+		},
+	}
+}
+
+func TestEvents(t *testing.T) {
+	got := Events(testArtifact())
+	want := []Event{
+		{Name: "testenv-vm.environmentId", Value: "abc123"},
+		{Name: "testenv-vm.known_hosts", Value: "known_hosts"},
+		{Name: "testenv-vm.topology.svg", Value: "/abs/topology.svg"},
+		{Name: "testenv-vm.vm.db.ip", Value: "10.0.0.3"},
+		{Name: "testenv-vm.vm.web.ip", Value: "10.0.0.2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Events() = %v, want %v", got, want)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	t.Run("passing test is not annotated", func(t *testing.T) {
+		tb := &fakeTB{}
+		Annotate(tb, testArtifact())
+		tb.finish()
+		if len(tb.logs) != 0 {
+			t.Errorf("logs = %v, want none", tb.logs)
+		}
+	})
+
+	t.Run("passing test is annotated with WithAlways", func(t *testing.T) {
+		tb := &fakeTB{}
+		Annotate(tb, testArtifact(), WithAlways())
+		tb.finish()
+		if len(tb.logs) != 5 {
+			t.Errorf("logs = %v, want 5 entries", tb.logs)
+		}
+	})
+
+	t.Run("failed test is annotated", func(t *testing.T) {
+		dir := t.TempDir()
+		tb := &fakeTB{failed: true}
+		Annotate(tb, testArtifact(), WithArtifactDir("/artifacts"), WithJUnitProperties(dir))
+		tb.finish()
+
+		want := []string{
+			"testenv-vm.environmentId=abc123",
+			"testenv-vm.known_hosts=/artifacts/known_hosts",
+			"testenv-vm.topology.svg=/abs/topology.svg",
+			"testenv-vm.vm.db.ip=10.0.0.3",
+			"testenv-vm.vm.web.ip=10.0.0.2",
+		}
+		if !reflect.DeepEqual(tb.logs, want) {
+			t.Errorf("logs = %v, want %v", tb.logs, want)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, "TestWeb_ping.xml"))
+		if err != nil {
+			t.Fatalf("failed to read JUnit properties: %v", err)
+		}
+		for _, s := range []string{
+			`<testcase name="TestWeb/ping">`,
+			`<property name="testenv-vm.environmentId" value="abc123"></property>`,
+			`<property name="testenv-vm.known_hosts" value="/artifacts/known_hosts"></property>`,
+		} {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("JUnit properties %s do not contain %s", data, s)
+			}
+		}
+	})
+}
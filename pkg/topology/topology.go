@@ -0,0 +1,244 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package topology renders the networks and VMs of an environment as a
+// diagram (Mermaid flowchart or standalone SVG) for quick visual review.
+package topology
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+// Topology is the graph of an environment: networks, the VMs attached to
+// them, and links between networks.
+type Topology struct {
+	// EnvironmentID identifies the environment the topology was built from.
+	EnvironmentID string
+	// Networks are sorted by name.
+	Networks []Network
+	// VMs are sorted by name.
+	VMs []VM
+	// Links connect networks (bridge attachments and tunnels).
+	Links []Link
+}
+
+// Network is a network node.
+type Network struct {
+	Name string
+	Kind string
+	CIDR string
+	IP   string
+}
+
+// VM is a VM node with its network attachments.
+type VM struct {
+	Name     string
+	IP       string
+	Networks []string
+}
+
+// Link is an edge between two networks.
+type Link struct {
+	From  string
+	To    string
+	Label string
+}
+
+// FromState builds the topology of an environment from its spec and the
+// values recorded by providers (IPs). Resources that are in the spec but not
+// in the state (e.g. creation failed) are still shown, without IPs.
+func FromState(envState *v1.EnvironmentState) *Topology {
+	t := &Topology{EnvironmentID: envState.ID}
+	if envState.Spec == nil {
+		return t
+	}
+	spec := envState.Spec
+
+	for _, n := range spec.Networks {
+		node := Network{Name: n.Name, Kind: n.Kind, CIDR: n.Spec.Cidr}
+		if rs := envState.Resources.Networks[n.Name]; rs != nil {
+			node.IP = stateString(rs, "ip")
+			if cidr := stateString(rs, "cidr"); cidr != "" {
+				node.CIDR = cidr
+			}
+		}
+		t.Networks = append(t.Networks, node)
+		if n.Spec.AttachTo != "" {
+			t.Links = append(t.Links, Link{From: n.Name, To: n.Spec.AttachTo, Label: "attachTo"})
+		}
+	}
+
+	for _, vm := range spec.Vms {
+		node := VM{Name: vm.Name, Networks: vm.Spec.Networks}
+		if len(node.Networks) == 0 && vm.Spec.Network != "" {
+			node.Networks = []string{vm.Spec.Network}
+		}
+		if rs := envState.Resources.VMs[vm.Name]; rs != nil {
+			node.IP = stateString(rs, "ip")
+		}
+		t.VMs = append(t.VMs, node)
+	}
+
+	for _, tunnel := range spec.Tunnels {
+		t.Links = append(t.Links, Link{From: tunnel.Spec.LocalNetwork, To: tunnel.Spec.RemoteNetwork, Label: "tunnel " + tunnel.Name})
+	}
+
+	sort.Slice(t.Networks, func(i, j int) bool { return t.Networks[i].Name < t.Networks[j].Name })
+	sort.Slice(t.VMs, func(i, j int) bool { return t.VMs[i].Name < t.VMs[j].Name })
+	return t
+}
+
+// stateString returns a string value from a resource state map.
+func stateString(rs *v1.ResourceState, key string) string {
+	if rs.State == nil {
+		return ""
+	}
+	s, _ := rs.State[key].(string)
+	return s
+}
+
+// mermaidIDPattern matches characters that are not valid in Mermaid node IDs.
+var mermaidIDPattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// mermaidID returns a Mermaid node ID for a resource.
+func mermaidID(kind, name string) string {
+	return kind + "_" + mermaidIDPattern.ReplaceAllString(name, "_")
+}
+
+// mermaidLabel escapes a label for use inside a quoted Mermaid node.
+func mermaidLabel(lines ...string) string {
+	var parts []string
+	for _, l := range lines {
+		if l != "" {
+			parts = append(parts, strings.ReplaceAll(l, `"`, "#quot;"))
+		}
+	}
+	return strings.Join(parts, "<br/>")
+}
+
+// Mermaid renders the topology as a Mermaid flowchart.
+func (t *Topology) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TB\n")
+
+	for _, n := range t.Networks {
+		detail := n.CIDR
+		if n.IP != "" {
+			detail = strings.TrimSpace(detail + " gw " + n.IP)
+		}
+		fmt.Fprintf(&b, "  %s[(\"%s\")]\n", mermaidID("net", n.Name), mermaidLabel("network "+n.Name, n.Kind, detail))
+	}
+	for _, vm := range t.VMs {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", mermaidID("vm", vm.Name), mermaidLabel("vm "+vm.Name, vm.IP))
+	}
+	for _, vm := range t.VMs {
+		for _, n := range vm.Networks {
+			fmt.Fprintf(&b, "  %s --- %s\n", mermaidID("vm", vm.Name), mermaidID("net", n))
+		}
+	}
+	for _, l := range t.Links {
+		fmt.Fprintf(&b, "  %s -.-|\"%s\"| %s\n", mermaidID("net", l.From), mermaidLabel(l.Label), mermaidID("net", l.To))
+	}
+
+	return b.String()
+}
+
+// SVG layout constants.
+const (
+	svgMargin     = 20
+	svgNodeWidth  = 180
+	svgNodeHeight = 44
+	svgColumnGap  = 30
+	svgRowGap     = 90
+)
+
+// SVG renders the topology as a standalone SVG document: networks on the top
+// row, VMs below, with a line for every attachment and dashed lines for links.
+func (t *Topology) SVG() string {
+	columns := len(t.Networks)
+	if len(t.VMs) > columns {
+		columns = len(t.VMs)
+	}
+	if columns == 0 {
+		columns = 1
+	}
+	width := 2*svgMargin + columns*svgNodeWidth + (columns-1)*svgColumnGap
+	height := 2*svgMargin + 2*svgNodeHeight + svgRowGap
+
+	netX := make(map[string]int, len(t.Networks))
+	for i, n := range t.Networks {
+		netX[n.Name] = svgMargin + i*(svgNodeWidth+svgColumnGap)
+	}
+	netY := svgMargin
+	vmY := svgMargin + svgNodeHeight + svgRowGap
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", width, height)
+	fmt.Fprintf(&b, "  <title>%s</title>\n", html.EscapeString("testenv-vm topology "+t.EnvironmentID))
+
+	// Edges first so that nodes are drawn on top of them.
+	for i, vm := range t.VMs {
+		x := svgMargin + i*(svgNodeWidth+svgColumnGap) + svgNodeWidth/2
+		for _, n := range vm.Networks {
+			nx, ok := netX[n]
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, `  <line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#555"/>`+"\n",
+				x, vmY, nx+svgNodeWidth/2, netY+svgNodeHeight)
+		}
+	}
+	for _, l := range t.Links {
+		fx, okFrom := netX[l.From]
+		tx, okTo := netX[l.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		y := netY + svgNodeHeight/2
+		fmt.Fprintf(&b, `  <line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#c60" stroke-dasharray="6 4"><title>%s</title></line>`+"\n",
+			fx+svgNodeWidth/2, y, tx+svgNodeWidth/2, y, html.EscapeString(l.Label))
+	}
+
+	for _, n := range t.Networks {
+		detail := n.CIDR
+		if n.IP != "" {
+			detail = strings.TrimSpace(detail + " gw " + n.IP)
+		}
+		writeSVGNode(&b, netX[n.Name], netY, "#dbeafe", "network "+n.Name, detail)
+	}
+	for i, vm := range t.VMs {
+		writeSVGNode(&b, svgMargin+i*(svgNodeWidth+svgColumnGap), vmY, "#dcfce7", "vm "+vm.Name, vm.IP)
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// writeSVGNode draws a labeled box.
+func writeSVGNode(b *strings.Builder, x, y int, fill, title, detail string) {
+	fmt.Fprintf(b, `  <rect x="%d" y="%d" width="%d" height="%d" rx="6" fill="%s" stroke="#333"/>`+"\n",
+		x, y, svgNodeWidth, svgNodeHeight, fill)
+	fmt.Fprintf(b, `  <text x="%d" y="%d" text-anchor="middle" font-weight="bold">%s</text>`+"\n",
+		x+svgNodeWidth/2, y+18, html.EscapeString(title))
+	if detail != "" {
+		fmt.Fprintf(b, `  <text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n",
+			x+svgNodeWidth/2, y+34, html.EscapeString(detail))
+	}
+}
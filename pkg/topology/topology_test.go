@@ -0,0 +1,125 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topology
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	v1 "github.com/alexandremahdhaoui/testenv-vm/api/v1"
+)
+
+func newTestState() *v1.EnvironmentState {
+	return &v1.EnvironmentState{
+		ID: "env-1",
+		Spec: &v1.Spec{
+			Networks: []v1.NetworkResource{
+				{Name: "vpc", Kind: "vpc", Spec: v1.NetworkSpec{Cidr: "10.0.0.0/16"}},
+				{Name: "lab-net", Kind: "bridge", Spec: v1.NetworkSpec{Cidr: "192.168.100.1/24"}},
+			},
+			Vms: []v1.VMResource{
+				{Name: "web", Spec: v1.VMSpec{Network: "lab-net"}},
+				{Name: "router", Spec: v1.VMSpec{Networks: []string{"lab-net", "vpc"}}},
+			},
+			Tunnels: []v1.TunnelResource{
+				{Name: "site", Spec: v1.TunnelSpec{LocalNetwork: "lab-net", RemoteNetwork: "vpc"}},
+			},
+		},
+		Resources: v1.ResourceMap{
+			Networks: map[string]*v1.ResourceState{
+				"lab-net": {State: map[string]any{"ip": "192.168.142.1", "cidr": "192.168.142.0/24"}},
+			},
+			VMs: map[string]*v1.ResourceState{
+				"web": {State: map[string]any{"ip": "192.168.142.10"}},
+			},
+		},
+	}
+}
+
+func TestFromState(t *testing.T) {
+	topo := FromState(newTestState())
+
+	if len(topo.Networks) != 2 || topo.Networks[0].Name != "lab-net" {
+		t.Fatalf("Networks = %+v, want sorted lab-net, vpc", topo.Networks)
+	}
+	if topo.Networks[0].CIDR != "192.168.142.0/24" || topo.Networks[0].IP != "192.168.142.1" {
+		t.Errorf("lab-net should use provider values, got %+v", topo.Networks[0])
+	}
+	if topo.Networks[1].CIDR != "10.0.0.0/16" {
+		t.Errorf("vpc should fall back to spec CIDR, got %+v", topo.Networks[1])
+	}
+	if len(topo.VMs) != 2 || topo.VMs[0].Name != "router" || len(topo.VMs[0].Networks) != 2 {
+		t.Errorf("VMs = %+v", topo.VMs)
+	}
+	if topo.VMs[1].IP != "192.168.142.10" || topo.VMs[1].Networks[0] != "lab-net" {
+		t.Errorf("web = %+v", topo.VMs[1])
+	}
+	if len(topo.Links) != 1 || topo.Links[0].Label != "tunnel site" {
+		t.Errorf("Links = %+v", topo.Links)
+	}
+}
+
+func TestFromState_NilSpec(t *testing.T) {
+	topo := FromState(&v1.EnvironmentState{ID: "env-1"})
+	if len(topo.Networks) != 0 || len(topo.VMs) != 0 {
+		t.Errorf("expected empty topology, got %+v", topo)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	got := FromState(newTestState()).Mermaid()
+	for _, want := range []string{
+		"flowchart TB\n",
+		`net_lab_net[("network lab-net<br/>bridge<br/>192.168.142.0/24 gw 192.168.142.1")]`,
+		`vm_web["vm web<br/>192.168.142.10"]`,
+		"vm_router --- net_lab_net",
+		"vm_router --- net_vpc",
+		`net_lab_net -.-|"tunnel site"| net_vpc`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Mermaid() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	state := newTestState()
+	state.Spec.Vms[0].Name = "web<&>"
+	got := FromState(state).SVG()
+
+	// The document must be well-formed XML.
+	dec := xml.NewDecoder(strings.NewReader(got))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("SVG() is not well-formed: %v\n%s", err, got)
+			}
+			break
+		}
+	}
+
+	for _, want := range []string{"network lab-net", "vm router", "vm web&lt;&amp;&gt;", "stroke-dasharray"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SVG() missing %q", want)
+		}
+	}
+	if n := strings.Count(got, "<rect"); n != 4 {
+		t.Errorf("SVG() has %d nodes, want 4", n)
+	}
+}
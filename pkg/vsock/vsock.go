@@ -0,0 +1,53 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vsock implements stream sockets over virtio-vsock, the
+// host<->guest channel of VMs that declare devices.vsock. A vsock address is
+// a context ID (CID) and a port: the host has CID 2 (CIDHost) and each guest
+// its own CID. The channel does not depend on guest networking.
+package vsock
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Well-known context IDs.
+const (
+	// CIDLocal addresses the local machine (loopback).
+	CIDLocal = 1
+	// CIDHost addresses the host from a guest.
+	CIDHost = 2
+	// MinGuestCID is the lowest CID a guest can have.
+	MinGuestCID = 3
+)
+
+// ErrUnsupported is returned on platforms without vsock.
+var ErrUnsupported = errors.New("vsock is only supported on linux")
+
+// Addr is a vsock address.
+type Addr struct {
+	CID  uint32
+	Port uint32
+}
+
+// Network implements net.Addr.
+func (a *Addr) Network() string {
+	return "vsock"
+}
+
+// String implements net.Addr.
+func (a *Addr) String() string {
+	return fmt.Sprintf("vsock://%d:%d", a.CID, a.Port)
+}
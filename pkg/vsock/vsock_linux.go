@@ -0,0 +1,213 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Dial connects to port of the VM with the given CID. The connection is
+// aborted when ctx is done.
+func Dial(ctx context.Context, cid, port uint32) (net.Conn, error) {
+	remote := &Addr{CID: cid, Port: port}
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, opError("dial", remote, err)
+	}
+	err = unix.Connect(fd, &unix.SockaddrVM{CID: cid, Port: port})
+	if err != nil && !errors.Is(err, unix.EINPROGRESS) {
+		_ = unix.Close(fd)
+		return nil, opError("dial", remote, err)
+	}
+	f := os.NewFile(uintptr(fd), remote.String())
+	if err != nil {
+		if err := waitConnect(ctx, f); err != nil {
+			_ = f.Close()
+			return nil, opError("dial", remote, err)
+		}
+	}
+	return newConn(f, remote)
+}
+
+// waitConnect waits for the non-blocking connect of f to complete.
+func waitConnect(ctx context.Context, f *os.File) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := f.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { _ = f.SetWriteDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var connectErr error
+	waited := false
+	err = raw.Write(func(fd uintptr) bool {
+		// The first call happens before waiting for the socket
+		if !waited {
+			waited = true
+			return false
+		}
+		status, err := unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			connectErr = err
+			return true
+		}
+		switch errno := unix.Errno(status); errno {
+		case unix.EINPROGRESS, unix.EALREADY, unix.EINTR:
+			return false
+		case 0:
+			// Spurious wake-up: the socket is not connected yet
+			_, err := unix.Getpeername(int(fd))
+			return !errors.Is(err, unix.ENOTCONN)
+		default:
+			connectErr = errno
+			return true
+		}
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	if connectErr != nil {
+		return connectErr
+	}
+	return f.SetWriteDeadline(time.Time{})
+}
+
+// Listen listens on port of the local machine, for connections from the
+// host and from local processes. Port 0 picks a free port.
+func Listen(port uint32) (net.Listener, error) {
+	if port == 0 {
+		port = unix.VMADDR_PORT_ANY
+	}
+	addr := &Addr{CID: unix.VMADDR_CID_ANY, Port: port}
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, opError("listen", addr, err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrVM{CID: unix.VMADDR_CID_ANY, Port: port}); err != nil {
+		_ = unix.Close(fd)
+		return nil, opError("listen", addr, err)
+	}
+	if err := unix.Listen(fd, unix.SOMAXCONN); err != nil {
+		_ = unix.Close(fd)
+		return nil, opError("listen", addr, err)
+	}
+	if sa, err := unix.Getsockname(fd); err == nil {
+		addr = toAddr(sa)
+	}
+	return &listener{file: os.NewFile(uintptr(fd), addr.String()), addr: addr}, nil
+}
+
+// listener is a vsock net.Listener.
+type listener struct {
+	file   *os.File
+	addr   *Addr
+	closed atomic.Bool
+}
+
+// Accept implements net.Listener.
+func (l *listener) Accept() (net.Conn, error) {
+	raw, err := l.file.SyscallConn()
+	if err != nil {
+		return nil, opError("accept", l.addr, net.ErrClosed)
+	}
+	var nfd int
+	var remote unix.Sockaddr
+	var acceptErr error
+	err = raw.Read(func(fd uintptr) bool {
+		nfd, remote, acceptErr = unix.Accept4(int(fd), unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC)
+		return !errors.Is(acceptErr, unix.EAGAIN)
+	})
+	if l.closed.Load() {
+		err = net.ErrClosed
+	}
+	if err == nil {
+		err = acceptErr
+	}
+	if err != nil {
+		return nil, opError("accept", l.addr, err)
+	}
+	return newConn(os.NewFile(uintptr(nfd), l.addr.String()), toAddr(remote))
+}
+
+// Close implements net.Listener.
+func (l *listener) Close() error {
+	l.closed.Store(true)
+	return l.file.Close()
+}
+
+// Addr implements net.Listener.
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// conn is a vsock net.Conn. The file provides I/O and deadlines.
+type conn struct {
+	*os.File
+	local, remote *Addr
+}
+
+// newConn wraps a connected socket.
+func newConn(f *os.File, remote *Addr) (net.Conn, error) {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	local := &Addr{}
+	_ = raw.Control(func(fd uintptr) {
+		if sa, err := unix.Getsockname(int(fd)); err == nil {
+			local = toAddr(sa)
+		}
+	})
+	return &conn{File: f, local: local, remote: remote}, nil
+}
+
+// LocalAddr implements net.Conn.
+func (c *conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr implements net.Conn.
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// toAddr converts a socket address into an Addr.
+func toAddr(sa unix.Sockaddr) *Addr {
+	if vm, ok := sa.(*unix.SockaddrVM); ok {
+		return &Addr{CID: vm.CID, Port: vm.Port}
+	}
+	return &Addr{}
+}
+
+// opError wraps a socket error like the net package does.
+func opError(op string, addr *Addr, err error) error {
+	return &net.OpError{Op: op, Net: "vsock", Addr: addr, Err: err}
+}
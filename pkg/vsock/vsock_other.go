@@ -0,0 +1,34 @@
+//go:build !linux
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"context"
+	"net"
+)
+
+// Dial connects to port of the VM with the given CID. It is only supported
+// on linux.
+func Dial(ctx context.Context, cid, port uint32) (net.Conn, error) {
+	return nil, ErrUnsupported
+}
+
+// Listen listens on port of the local machine. It is only supported on
+// linux.
+func Listen(port uint32) (net.Listener, error) {
+	return nil, ErrUnsupported
+}
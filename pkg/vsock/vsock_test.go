@@ -0,0 +1,143 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vsock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// listenLoopback listens on a free port, skipping the test when vsock
+// loopback is unavailable.
+func listenLoopback(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := Listen(0)
+	if err != nil {
+		t.Skipf("vsock unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	conn, err := Dial(ctx, CIDLocal, l.Addr().(*Addr).Port)
+	if err != nil {
+		t.Skipf("vsock loopback unavailable: %v", err)
+	}
+	_ = conn.Close()
+	// Drain the probe connection
+	if c, err := l.Accept(); err == nil {
+		_ = c.Close()
+	}
+	return l
+}
+
+func TestAddr(t *testing.T) {
+	a := &Addr{CID: 3, Port: 10050}
+	if a.Network() != "vsock" || a.String() != "vsock://3:10050" {
+		t.Errorf("Addr = %s %s", a.Network(), a)
+	}
+}
+
+func TestDialListen(t *testing.T) {
+	l := listenLoopback(t)
+	port := l.Addr().(*Addr).Port
+
+	done := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer func() { _ = c.Close() }()
+		_, err = io.Copy(c, io.LimitReader(c, 5))
+		done <- err
+	}()
+
+	conn, err := Dial(context.Background(), CIDLocal, port)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if remote := conn.RemoteAddr().(*Addr); remote.CID != CIDLocal || remote.Port != port {
+		t.Errorf("RemoteAddr() = %s", remote)
+	}
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("Read() = %q, %v", buf, err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("server error = %v", err)
+	}
+}
+
+func TestDial_Refused(t *testing.T) {
+	l := listenLoopback(t)
+	port := l.Addr().(*Addr).Port
+	_ = l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := Dial(ctx, CIDLocal, port)
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		t.Errorf("Dial() error = %v, want a dial error", err)
+	}
+}
+
+func TestListener_Close(t *testing.T) {
+	l, err := Listen(0)
+	if err != nil {
+		t.Skipf("vsock unavailable: %v", err)
+	}
+	if l.Addr().(*Addr).Port == 0 {
+		t.Error("Listen(0) should pick a port")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = l.Close()
+	}()
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept() error = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestDial_UnknownCID(t *testing.T) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM, 0)
+	if err != nil {
+		t.Skipf("vsock unavailable: %v", err)
+	}
+	_ = unix.Close(fd)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := Dial(ctx, 0xfffffff0, 1); err == nil {
+		t.Error("Dial() expected error for an unknown CID")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Dial() returned after %s, want the context deadline", elapsed)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wireguard generates WireGuard key pairs and wg-quick configuration
+// files. It has no dependency on the wg tools; configs are applied by the
+// guests that consume them (typically through cloud-init write_files).
+package wireguard
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// DefaultListenPort is the conventional WireGuard UDP port.
+const DefaultListenPort = 51820
+
+// KeyPair is a base64-encoded Curve25519 key pair as used by wg(8).
+type KeyPair struct {
+	PrivateKey string
+	PublicKey  string
+}
+
+// GenerateKeyPair creates a new random key pair.
+func GenerateKeyPair() (KeyPair, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate wireguard key: %w", err)
+	}
+	return KeyPair{
+		PrivateKey: base64.StdEncoding.EncodeToString(priv.Bytes()),
+		PublicKey:  base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()),
+	}, nil
+}
+
+// Config is a wg-quick configuration: one interface and its peers.
+type Config struct {
+	// PrivateKey is the interface private key.
+	PrivateKey string
+	// Address is the interface address in CIDR notation (e.g. "10.200.0.1/30").
+	Address string
+	// ListenPort is the UDP listen port. Zero lets the kernel pick one.
+	ListenPort int
+	// PostUp are commands run by wg-quick after the interface is up.
+	PostUp []string
+	// PostDown are commands run by wg-quick after the interface is down.
+	PostDown []string
+	// Peers are the remote ends.
+	Peers []Peer
+}
+
+// Peer is a [Peer] section.
+type Peer struct {
+	// PublicKey is the peer public key.
+	PublicKey string
+	// AllowedIPs are the prefixes routed to this peer.
+	AllowedIPs []string
+	// Endpoint is the peer host:port. Empty for peers that dial in.
+	Endpoint string
+	// PersistentKeepalive is the keepalive interval in seconds (0 disables).
+	PersistentKeepalive int
+}
+
+// Render returns the config in wg-quick INI format.
+func (c Config) Render() string {
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "PrivateKey = %s\n", c.PrivateKey)
+	if c.Address != "" {
+		fmt.Fprintf(&b, "Address = %s\n", c.Address)
+	}
+	if c.ListenPort > 0 {
+		fmt.Fprintf(&b, "ListenPort = %d\n", c.ListenPort)
+	}
+	for _, cmd := range c.PostUp {
+		fmt.Fprintf(&b, "PostUp = %s\n", cmd)
+	}
+	for _, cmd := range c.PostDown {
+		fmt.Fprintf(&b, "PostDown = %s\n", cmd)
+	}
+	for _, p := range c.Peers {
+		b.WriteString("\n[Peer]\n")
+		fmt.Fprintf(&b, "PublicKey = %s\n", p.PublicKey)
+		if len(p.AllowedIPs) > 0 {
+			fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(p.AllowedIPs, ", "))
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "Endpoint = %s\n", p.Endpoint)
+		}
+		if p.PersistentKeepalive > 0 {
+			fmt.Fprintf(&b, "PersistentKeepalive = %d\n", p.PersistentKeepalive)
+		}
+	}
+	return b.String()
+}
+
+// HostAddresses returns the first n host addresses of prefix in CIDR notation
+// with the prefix length of the network (e.g. 10.200.0.0/30, 2 ->
+// ["10.200.0.1/30", "10.200.0.2/30"]).
+func HostAddresses(prefix string, n int) ([]string, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", prefix, err)
+	}
+	p = p.Masked()
+
+	addrs := make([]string, 0, n)
+	addr := p.Addr()
+	for i := 0; i < n; i++ {
+		addr = addr.Next()
+		if !addr.IsValid() || !p.Contains(addr) || isBroadcast(p, addr) {
+			return nil, fmt.Errorf("CIDR %q has fewer than %d host addresses", prefix, n)
+		}
+		addrs = append(addrs, netip.PrefixFrom(addr, p.Bits()).String())
+	}
+	return addrs, nil
+}
+
+// isBroadcast reports whether addr is the IPv4 broadcast address of p.
+func isBroadcast(p netip.Prefix, addr netip.Addr) bool {
+	if !addr.Is4() || p.Bits() >= 31 {
+		return false
+	}
+	return !p.Contains(addr.Next())
+}
+
+// HostRoute returns addr (with or without prefix length) as a single-host prefix.
+func HostRoute(addr string) (string, error) {
+	if p, err := netip.ParsePrefix(addr); err == nil {
+		return netip.PrefixFrom(p.Addr(), p.Addr().BitLen()).String(), nil
+	}
+	a, err := netip.ParseAddr(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	return netip.PrefixFrom(a, a.BitLen()).String(), nil
+}
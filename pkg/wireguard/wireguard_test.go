@@ -0,0 +1,134 @@
+//go:build unit
+
+// Copyright 2025 Alexandre Mahdhaoui
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wireguard
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	kp, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+
+	privBytes, err := base64.StdEncoding.DecodeString(kp.PrivateKey)
+	if err != nil || len(privBytes) != 32 {
+		t.Fatalf("private key is not 32 base64 bytes: %q", kp.PrivateKey)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(privBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes()); got != kp.PublicKey {
+		t.Errorf("public key does not match private key: %s != %s", got, kp.PublicKey)
+	}
+
+	other, _ := GenerateKeyPair()
+	if other.PrivateKey == kp.PrivateKey {
+		t.Error("GenerateKeyPair() returned the same key twice")
+	}
+}
+
+func TestConfig_Render(t *testing.T) {
+	cfg := Config{
+		PrivateKey: "cHJpdg==",
+		Address:    "10.200.0.1/30",
+		ListenPort: 51820,
+		Peers: []Peer{{
+			PublicKey:           "cHVi",
+			AllowedIPs:          []string{"10.0.0.0/16", "10.200.0.2/32"},
+			Endpoint:            "203.0.113.10:51820",
+			PersistentKeepalive: 25,
+		}},
+	}
+
+	want := `[Interface]
+PrivateKey = cHJpdg==
+Address = 10.200.0.1/30
+ListenPort = 51820
+
+[Peer]
+PublicKey = cHVi
+AllowedIPs = 10.0.0.0/16, 10.200.0.2/32
+Endpoint = 203.0.113.10:51820
+PersistentKeepalive = 25
+`
+	if got := cfg.Render(); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+
+	cfg.Peers[0].Endpoint = ""
+	if strings.Contains(cfg.Render(), "Endpoint") {
+		t.Error("Render() should omit empty Endpoint")
+	}
+
+	cfg.PostUp = []string{"sysctl -w net.ipv4.ip_forward=1"}
+	cfg.PostDown = []string{"true"}
+	if !strings.Contains(cfg.Render(), "ListenPort = 51820\nPostUp = sysctl -w net.ipv4.ip_forward=1\nPostDown = true\n\n[Peer]") {
+		t.Errorf("Render() should emit PostUp/PostDown in [Interface]:\n%s", cfg.Render())
+	}
+}
+
+func TestHostAddresses(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		n       int
+		want    []string
+		wantErr bool
+	}{
+		{prefix: "10.200.0.0/30", n: 2, want: []string{"10.200.0.1/30", "10.200.0.2/30"}},
+		{prefix: "10.200.0.1/30", n: 2, want: []string{"10.200.0.1/30", "10.200.0.2/30"}},
+		{prefix: "10.8.0.0/24", n: 3, want: []string{"10.8.0.1/24", "10.8.0.2/24", "10.8.0.3/24"}},
+		{prefix: "fd00::/126", n: 2, want: []string{"fd00::1/126", "fd00::2/126"}},
+		{prefix: "10.200.0.0/30", n: 3, wantErr: true},
+		{prefix: "10.200.0.0/32", n: 1, wantErr: true},
+		{prefix: "bogus", n: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := HostAddresses(tt.prefix, tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HostAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HostAddresses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostRoute(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.200.0.2/30": "10.200.0.2/32",
+		"10.200.0.2":    "10.200.0.2/32",
+		"fd00::2/126":   "fd00::2/128",
+	} {
+		got, err := HostRoute(in)
+		if err != nil || got != want {
+			t.Errorf("HostRoute(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := HostRoute("nope"); err == nil {
+		t.Error("HostRoute() expected error for invalid address")
+	}
+}